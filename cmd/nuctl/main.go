@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/nuclio/nuclio/cmd/nuctl/app"
+	"github.com/nuclio/nuclio/pkg/nuctl/command"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -27,6 +28,30 @@ import (
 
 func main() {
 	if err := app.Run(); err != nil {
+
+		// no need for a stack when the platform simply doesn't support the operation
+		switch rootCauseErr := errors.RootCause(err).(type) {
+		case *command.UnsupportedOperationError, *command.UnsupportedValueError:
+			os.Stderr.WriteString(rootCauseErr.Error() + "\n") // nolint: errcheck
+			os.Exit(command.ExitCodeUnsupportedOperation)
+
+		// the differences were already printed
+		case *command.FunctionDiffersError:
+			os.Exit(command.ExitCodeFunctionDiffers)
+
+		// the problems were already printed
+		case *command.InvalidFunctionConfigError:
+			os.Exit(command.ExitCodeInvalidFunctionConfig)
+
+		// the differences were already printed
+		case *command.ResponsesDifferError:
+			os.Exit(command.ExitCodeResponsesDiffer)
+
+		// the command's output was already printed
+		case *command.CommandExitError:
+			os.Exit(rootCauseErr.ExitCode)
+		}
+
 		if errWithCode, ok := err.(*nuclio.ErrorWithStatusCode); ok && errWithCode != nil {
 			os.Stdout.WriteString(errWithCode.Error())
 		} else {
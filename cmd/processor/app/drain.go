@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+)
+
+// interval between checks whether the events in flight were handled
+const drainPollInterval = 100 * time.Millisecond
+
+// Drain stops the triggers from receiving events and waits, for up to the function's termination grace period,
+// for the events in flight to be handled. stopping a trigger commits the offsets / acks of the events it handled.
+// the workers are stopped once drained. it may be called more than once (e.g. by the platform before terminating
+// the processor, and then on SIGTERM), returning once the processor drained
+func (p *Processor) Drain() {
+	p.drainOnce.Do(func() {
+		gracePeriod := p.configuration.Spec.GetTerminationGracePeriod()
+		deadline := time.Now().Add(gracePeriod)
+
+		// report not ready, so that no more requests are routed to the processor
+		atomic.StoreInt32(&p.draining, 1)
+
+		p.logger.InfoWith("Draining", "gracePeriod", gracePeriod.String())
+
+		triggers := p.GetTriggers()
+		if !p.stopTriggers(triggers, deadline) {
+			p.logger.WarnWith("Timed out waiting for triggers to stop", "gracePeriod", gracePeriod.String())
+		}
+
+		// triggers that don't wait for their events in flight when stopped are waited for here
+		for p.getInflightEvents() > 0 && time.Now().Before(deadline) {
+			time.Sleep(drainPollInterval)
+		}
+
+		if inflightEvents := p.getInflightEvents(); inflightEvents > 0 {
+			p.logger.WarnWith("Timed out waiting for events in flight to be handled",
+				"inflightEvents", inflightEvents,
+				"gracePeriod", gracePeriod.String())
+		}
+
+		for _, workerInstance := range p.GetWorkers() {
+			if err := workerInstance.Stop(); err != nil {
+				p.logger.WarnWith("Failed to stop worker", "err", err.Error())
+			}
+		}
+
+		p.logger.Info("Drained")
+	})
+}
+
+// stopTriggers stops the triggers in parallel, returning whether they all stopped before the deadline
+func (p *Processor) stopTriggers(triggers []trigger.Trigger, deadline time.Time) bool {
+	triggersStopped := make(chan struct{})
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(triggers))
+
+	for _, triggerInstance := range triggers {
+		go func(triggerInstance trigger.Trigger) {
+			defer waitGroup.Done()
+
+			if _, err := triggerInstance.Stop(false); err != nil {
+				p.logger.WarnWith("Failed to stop trigger",
+					"trigger", triggerInstance.GetName(),
+					"err", err.Error())
+			}
+		}(triggerInstance)
+	}
+
+	go func() {
+		waitGroup.Wait()
+		close(triggersStopped)
+	}()
+
+	select {
+	case <-triggersStopped:
+		return true
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
+
+// getInflightEvents returns the number of workers handling an event
+func (p *Processor) getInflightEvents() int {
+	inflightEvents := 0
+
+	for _, workerInstance := range p.GetWorkers() {
+		if workerInstance.GetEventTime() != nil {
+			inflightEvents++
+		}
+	}
+
+	return inflightEvents
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// SetTriggerPaused stops the trigger (and its workers) so that it no longer consumes events, or creates and starts
+// it again, leaving the rest of the processor running. only the running configuration changes - the platform
+// persists the paused state in the function's configuration, which the processor reloads (and reads on restart)
+func (p *Processor) SetTriggerPaused(triggerName string, paused bool) error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
+	triggerConfiguration, found := p.configuration.Spec.Triggers[triggerName]
+	if !found {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Trigger not found: %s", triggerName))
+	}
+
+	if triggerConfiguration.Paused == paused {
+		return nil
+	}
+
+	triggerConfiguration.Paused = paused
+
+	// the triggers of the running configuration are shared with whoever read them, so they're copied
+	pausedConfiguration := *p.configuration
+	pausedConfiguration.Spec.Triggers = map[string]functionconfig.Trigger{}
+
+	for name, configuration := range p.configuration.Spec.Triggers {
+		pausedConfiguration.Spec.Triggers[name] = configuration
+	}
+
+	pausedConfiguration.Spec.Triggers[triggerName] = triggerConfiguration
+
+	if err := p.reloadTriggers(&pausedConfiguration, false); err != nil {
+		return errors.Wrapf(err, "Failed to reload trigger %s", triggerName)
+	}
+
+	p.configuration = &pausedConfiguration
+
+	p.logger.InfoWith("Set trigger paused", "name", triggerName, "paused", paused)
+
+	return nil
+}
+
+// GetPausedTriggerNames returns the names of the triggers which are paused, sorted
+func (p *Processor) GetPausedTriggerNames() []string {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
+	var pausedTriggerNames []string
+
+	for triggerName, triggerConfiguration := range p.configuration.Spec.Triggers {
+		if triggerConfiguration.Paused {
+			pausedTriggerNames = append(pausedTriggerNames, triggerName)
+		}
+	}
+
+	sort.Strings(pausedTriggerNames)
+
+	return pausedTriggerNames
+}
@@ -17,8 +17,14 @@ limitations under the License.
 package app
 
 import (
+	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -28,7 +34,10 @@ import (
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/config"
 	"github.com/nuclio/nuclio/pkg/processor/healthcheck"
+	"github.com/nuclio/nuclio/pkg/processor/inspector"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	// load all runtimes
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/dotnetcore"
@@ -40,10 +49,13 @@ import (
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/shell"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 	"github.com/nuclio/nuclio/pkg/processor/timeout"
+	"github.com/nuclio/nuclio/pkg/processor/tracing"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	// load all triggers
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/cron"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/grpc"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/http"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/jetstream"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kafka"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kickstart"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kinesis"
@@ -54,10 +66,15 @@ import (
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/poller/v3ioitempoller"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/pubsub"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/rabbitmq"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/sns"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/sqs"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/v3iostream"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/websocket"
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
 	"github.com/nuclio/nuclio/pkg/processor/util/clock"
 	"github.com/nuclio/nuclio/pkg/processor/webadmin"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
+	"github.com/nuclio/nuclio/pkg/secretprovider"
 	// load all sinks
 	_ "github.com/nuclio/nuclio/pkg/sinks"
 
@@ -66,18 +83,37 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+const defaultHTTPTriggerName = "http"
+
 // Processor is responsible to process events
 type Processor struct {
 	logger                logger.Logger
 	functionLogger        logger.Logger
+	configurationPath     string
+	configuration         *processor.Configuration
 	triggers              []trigger.Trigger
+	triggersLock          sync.RWMutex
 	webAdminServer        *webadmin.Server
 	healthCheckServer     *healthcheck.Server
 	metricSinks           []metricsink.MetricSink
 	namedWorkerAllocators map[string]worker.Allocator
 	eventTimeoutWatcher   *timeout.EventTimeoutWatcher
-	startComplete         bool
-	stop                  chan bool
+	admissionController   *admission.Controller
+	tracer                *tracing.Tracer
+	eventRecorder         *recording.Recorder
+	invocationInspector   *inspector.Inspector
+	asyncInvoker          *invocation.Invoker
+	reloadLock            sync.Mutex
+
+	// the environment variables read from external secret stores, and their current values
+	secretResolver          *secretprovider.Resolver
+	externalSecretEnv       []functionconfig.ExternalSecretEnvVar
+	externalSecretEnvValues map[string]string
+
+	startComplete bool
+	stop          chan bool
+	drainOnce     sync.Once
+	draining      int32
 }
 
 // NewProcessor returns a new Processor
@@ -85,6 +121,7 @@ func NewProcessor(configurationPath string, platformConfigurationPath string) (*
 	var err error
 
 	newProcessor := &Processor{
+		configurationPath:     configurationPath,
 		namedWorkerAllocators: map[string]worker.Allocator{},
 		stop:                  make(chan bool, 1),
 	}
@@ -95,7 +132,12 @@ func NewProcessor(configurationPath string, platformConfigurationPath string) (*
 		return nil, errors.Wrap(err, "Failed to get platform configuration")
 	}
 
-	processorConfiguration, err := newProcessor.readConfiguration(configurationPath)
+	configurationContents, err := ioutil.ReadFile(configurationPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read configuration file")
+	}
+
+	processorConfiguration, err := newProcessor.readConfiguration(configurationContents)
 	if err != nil {
 		return nil, err
 	}
@@ -120,17 +162,67 @@ func NewProcessor(configurationPath string, platformConfigurationPath string) (*
 
 	// save platform configuration in process configuration
 	processorConfiguration.PlatformConfig = platformConfiguration
+	newProcessor.configuration = processorConfiguration
 
 	if processorConfiguration.Spec.EventTimeout != "" {
 		clock.SetResolution(1 * time.Second)
 	}
 
 	// create and start the health check server before creating anything else, so it can serve probes ASAP
-	newProcessor.healthCheckServer, err = newProcessor.createAndStartHealthCheckServer(platformConfiguration)
+	newProcessor.healthCheckServer, err = newProcessor.createAndStartHealthCheckServer(platformConfiguration,
+		&processorConfiguration.Spec)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create and start health check server")
 	}
 
+	// limit the events handled by all triggers together, if required
+	if processorConfiguration.Spec.MaxInflightEvents > 0 {
+		queueTimeout, err := processorConfiguration.Spec.GetQueueTimeout()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get queue timeout")
+		}
+
+		newProcessor.admissionController = admission.NewController(processorConfiguration.Spec.MaxInflightEvents,
+			processorConfiguration.Spec.QueueSize,
+			queueTimeout)
+	}
+
+	// trace the handling of events, if configured
+	newProcessor.tracer, err = tracing.NewTracer(newProcessor.logger,
+		&platformConfiguration.Tracing,
+		map[string]interface{}{
+			"service.name":      processorConfiguration.Meta.Name,
+			"service.namespace": processorConfiguration.Meta.Namespace,
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create tracer")
+	}
+
+	// record a sample of the handled events, if configured
+	newProcessor.eventRecorder, err = recording.NewRecorder(newProcessor.logger,
+		processorConfiguration.Spec.EventRecording,
+		processorConfiguration.Meta.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create event recorder")
+	}
+
+	// retain a summary of the last invocations, if configured
+	newProcessor.invocationInspector = inspector.NewInspector(newProcessor.logger,
+		processorConfiguration.Spec.InvocationInspector)
+
+	// deliver the invocations the function enqueues for other functions
+	newProcessor.asyncInvoker, err = invocation.NewInvoker(newProcessor.logger,
+		processorConfiguration.Spec.AsyncInvocation,
+		invocation.NewHTTPSender(processorConfiguration.PlatformConfig.Kind, processorConfiguration.Meta.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create async invoker")
+	}
+
+	// read the environment variables kept in external secret stores, before the runtimes inherit the environment
+	if err := newProcessor.resolveExternalSecretEnv(processorConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve external secret environment variables")
+	}
+
 	// create triggers
 	newProcessor.triggers, err = newProcessor.createTriggers(processorConfiguration)
 	if err != nil {
@@ -172,7 +264,7 @@ func (p *Processor) Start() error {
 	p.logger.DebugWith("Starting triggers", "triggers", p.triggers)
 
 	// iterate over all triggers and start them
-	for _, trigger := range p.triggers {
+	for _, trigger := range p.GetTriggers() {
 		if err := trigger.Start(nil); err != nil {
 			p.logger.ErrorWith("Failed to start trigger",
 				"kind", trigger.GetKind(),
@@ -195,30 +287,75 @@ func (p *Processor) Start() error {
 		}
 	}
 
+	// apply changes of the configuration file without restarting, if enabled
+	configurationReloadInterval, err := p.getConfigurationReloadInterval()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get configuration reload interval")
+	}
+
+	if configurationReloadInterval > 0 {
+		go p.watchConfiguration(configurationReloadInterval)
+	}
+
+	// re-read the external secrets, to pick up rotated ones
+	if p.secretResolver != nil {
+		go p.watchExternalSecrets(p.secretResolver.GetRefreshInterval())
+	}
+
 	// indicate that we're done starting
 	p.startComplete = true
 
 	p.logger.Debug("Processor started")
 
-	<-p.stop // Wait for stop
-	p.logger.Info("Processor quitting")
+	// drain when asked to terminate (e.g. when the replica is scaled down)
+	terminationSignals := make(chan os.Signal, 1)
+	signal.Notify(terminationSignals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(terminationSignals)
+
+	select {
+	case <-p.stop:
+		p.logger.Info("Processor quitting")
 
-	time.Sleep(5 * time.Second) // Give triggers etc time to finish
+		// export the spans, write the records of the events handled so far and stop delivering invocations
+		p.tracer.Stop()
+		p.eventRecorder.Stop()
+		p.asyncInvoker.Stop()
+
+		time.Sleep(5 * time.Second) // Give triggers etc time to finish
+
+	case terminationSignal := <-terminationSignals:
+		p.logger.InfoWith("Processor terminating", "signal", terminationSignal.String())
+
+		p.Drain()
+
+		// export the spans, write the records of the events handled so far and stop delivering invocations
+		p.tracer.Stop()
+		p.eventRecorder.Stop()
+		p.asyncInvoker.Stop()
+	}
 
 	return nil
 }
 
 // GetTriggers returns triggers
 func (p *Processor) GetTriggers() []trigger.Trigger {
+	p.triggersLock.RLock()
+	defer p.triggersLock.RUnlock()
+
 	return p.triggers
 }
 
+// GetInvocations returns the invocations the inspector retains, oldest first (none if it's disabled)
+func (p *Processor) GetInvocations() []inspector.Invocation {
+	return p.invocationInspector.GetInvocations()
+}
+
 // GetWorkers returns workers
 func (p *Processor) GetWorkers() []*worker.Worker {
 	var workers []*worker.Worker
 
 	// iterate over the processor's triggers
-	for _, trigger := range p.triggers {
+	for _, trigger := range p.GetTriggers() {
 		workers = append(workers, trigger.GetWorkers()...)
 	}
 
@@ -234,15 +371,51 @@ func (p *Processor) GetStatus() status.Status {
 		return status.Initializing
 	}
 
-	// if any worker isn't ready yet, return initializing
+	if atomic.LoadInt32(&p.draining) == 1 {
+		return status.Draining
+	}
+
+	// if any worker's wrapper stopped responding and can't be restarted anymore, return error - which fails the
+	// processor's liveness. if any worker isn't ready yet (or failed otherwise), return initializing
+	processorStatus := status.Ready
 	for _, worker := range workers {
-		if worker.GetStatus() != status.Ready {
-			return status.Initializing
+		switch worker.GetStatus() {
+		case status.Ready:
+		case status.Error:
+			if livenessReporter, isLivenessReporter := worker.GetRuntime().(runtime.LivenessReporter); isLivenessReporter &&
+				livenessReporter.GetLivenessStatus().Failed {
+				return status.Error
+			}
+
+			processorStatus = status.Initializing
+		default:
+			processorStatus = status.Initializing
+		}
+	}
+
+	return processorStatus
+}
+
+// GetRuntimeLivenessStatus returns the restarts of the workers' wrappers due to failed liveness checks, or nil
+// if the runtime doesn't check its wrappers' liveness
+func (p *Processor) GetRuntimeLivenessStatus() *functionconfig.RuntimeLivenessStatus {
+	var runtimeLivenessStatus *functionconfig.RuntimeLivenessStatus
+
+	for _, worker := range p.GetWorkers() {
+		livenessReporter, isLivenessReporter := worker.GetRuntime().(runtime.LivenessReporter)
+		if !isLivenessReporter {
+			continue
+		}
+
+		if runtimeLivenessStatus == nil {
+			runtimeLivenessStatus = &functionconfig.RuntimeLivenessStatus{}
 		}
+
+		workerLivenessStatus := livenessReporter.GetLivenessStatus()
+		runtimeLivenessStatus.Add(&workerLivenessStatus)
 	}
 
-	// otherwise we're ready
-	return status.Ready
+	return runtimeLivenessStatus
 }
 
 // Stop stops the processor
@@ -250,7 +423,7 @@ func (p *Processor) Stop() {
 	p.stop <- true
 }
 
-func (p *Processor) readConfiguration(configurationPath string) (*processor.Configuration, error) {
+func (p *Processor) readConfiguration(configurationContents []byte) (*processor.Configuration, error) {
 	var processorConfiguration processor.Configuration
 
 	processorConfigurationReader, err := processorconfig.NewReader()
@@ -258,12 +431,7 @@ func (p *Processor) readConfiguration(configurationPath string) (*processor.Conf
 		return nil, errors.Wrap(err, "Failed to create configuration file reader")
 	}
 
-	functionconfigFile, err := os.Open(configurationPath)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to open configuration file")
-	}
-
-	if err := processorConfigurationReader.Read(functionconfigFile, &processorConfiguration); err != nil {
+	if err := processorConfigurationReader.Read(bytes.NewReader(configurationContents), &processorConfiguration); err != nil {
 		return nil, errors.Wrap(err, "Failed to open configuration file")
 	}
 
@@ -280,28 +448,14 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 	for triggerName, triggerConfiguration := range processorConfiguration.Spec.Triggers {
 		triggerName, triggerConfiguration := triggerName, triggerConfiguration
 
-		// skipping cron triggers when platform kind is "kube" - k8s cron jobs will be created instead
-		if triggerConfiguration.Kind == "cron" && platformKind == "kube" {
-			p.logger.DebugWith("Skipping cron trigger creation inside the processor",
-				"triggerName", triggerName,
-				"platformKind", platformKind)
-
+		if !p.shouldCreateTrigger(triggerName, &triggerConfiguration, platformKind) {
 			continue
 		}
 
 		errGroup.Go(func() error {
 
 			// create an event source based on event source configuration and runtime configuration
-			triggerInstance, err := trigger.RegistrySingleton.NewTrigger(p.logger,
-				triggerConfiguration.Kind,
-				triggerName,
-				&triggerConfiguration,
-				&runtime.Configuration{
-					Configuration:  processorConfiguration,
-					FunctionLogger: p.functionLogger,
-				},
-				p.namedWorkerAllocators)
-
+			triggerInstance, err := p.createTrigger(triggerName, &triggerConfiguration, processorConfiguration)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to create triggers")
 			}
@@ -331,6 +485,54 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 	return triggers, nil
 }
 
+func (p *Processor) shouldCreateTrigger(triggerName string,
+	triggerConfiguration *functionconfig.Trigger,
+	platformKind string) bool {
+
+	if triggerConfiguration.Disabled {
+		p.logger.DebugWith("Skipping disabled trigger", "triggerName", triggerName)
+
+		return false
+	}
+
+	if triggerConfiguration.Paused {
+		p.logger.InfoWith("Skipping paused trigger", "triggerName", triggerName)
+
+		return false
+	}
+
+	// skipping cron triggers when platform kind is "kube" - k8s cron jobs will be created instead
+	if triggerConfiguration.Kind == "cron" && platformKind == "kube" {
+		p.logger.DebugWith("Skipping cron trigger creation inside the processor",
+			"triggerName", triggerName,
+			"platformKind", platformKind)
+
+		return false
+	}
+
+	return true
+}
+
+func (p *Processor) createTrigger(triggerName string,
+	triggerConfiguration *functionconfig.Trigger,
+	processorConfiguration *processor.Configuration) (trigger.Trigger, error) {
+
+	return trigger.RegistrySingleton.NewTrigger(p.logger,
+		triggerConfiguration.Kind,
+		triggerName,
+		triggerConfiguration,
+		&runtime.Configuration{
+			Configuration:       processorConfiguration,
+			FunctionLogger:      p.functionLogger,
+			AdmissionController: p.admissionController,
+			Tracer:              p.tracer,
+			EventRecorder:       p.eventRecorder,
+			InvocationInspector: p.invocationInspector,
+			AsyncInvoker:        p.asyncInvoker,
+		},
+		p.namedWorkerAllocators)
+}
+
 func (p *Processor) createDefaultTriggers(processorConfiguration *processor.Configuration,
 	existingTriggers []trigger.Trigger) ([]trigger.Trigger, error) {
 	createdTriggers := []trigger.Trigger{}
@@ -340,6 +542,11 @@ func (p *Processor) createDefaultTriggers(processorConfiguration *processor.Conf
 		return createdTriggers, nil
 	}
 
+	// a disabled http trigger isn't replaced by the default one
+	if len(functionconfig.GetTriggersByKind(processorConfiguration.Spec.Triggers, "http")) > 0 {
+		return createdTriggers, nil
+	}
+
 	httpTrigger, err := p.createDefaultHTTPTrigger(processorConfiguration)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create default HTTP event source")
@@ -359,25 +566,21 @@ func (p *Processor) hasHTTPTrigger(triggers []trigger.Trigger) bool {
 }
 
 func (p *Processor) createDefaultHTTPTrigger(processorConfiguration *processor.Configuration) (trigger.Trigger, error) {
-	defaultHTTPTriggerConfiguration := functionconfig.Trigger{
+	defaultHTTPTriggerConfiguration := p.getDefaultHTTPTriggerConfiguration()
+
+	p.logger.DebugWith("Creating default HTTP event source",
+		"configuration", &defaultHTTPTriggerConfiguration)
+
+	return p.createTrigger(defaultHTTPTriggerName, &defaultHTTPTriggerConfiguration, processorConfiguration)
+}
+
+func (p *Processor) getDefaultHTTPTriggerConfiguration() functionconfig.Trigger {
+	return functionconfig.Trigger{
 		Class:      "sync",
 		Kind:       "http",
 		MaxWorkers: 1,
 		URL:        common.GetEnvOrDefaultString("NUCLIO_DEFAULT_HTTP_TRIGGER_URL", ":8080"),
 	}
-
-	p.logger.DebugWith("Creating default HTTP event source",
-		"configuration", &defaultHTTPTriggerConfiguration)
-
-	return trigger.RegistrySingleton.NewTrigger(p.logger,
-		"http",
-		"http",
-		&defaultHTTPTriggerConfiguration,
-		&runtime.Configuration{
-			Configuration:  processorConfiguration,
-			FunctionLogger: p.functionLogger,
-		},
-		p.namedWorkerAllocators)
 }
 
 func (p *Processor) createWebAdminServer(platformConfiguration *platformconfig.Config) (*webadmin.Server, error) {
@@ -396,7 +599,8 @@ func (p *Processor) createWebAdminServer(platformConfiguration *platformconfig.C
 	return webadmin.NewServer(p.logger, p, &platformConfiguration.WebAdmin)
 }
 
-func (p *Processor) createAndStartHealthCheckServer(platformConfiguration *platformconfig.Config) (*healthcheck.Server, error) {
+func (p *Processor) createAndStartHealthCheckServer(platformConfiguration *platformconfig.Config,
+	functionSpec *functionconfig.Spec) (*healthcheck.Server, error) {
 
 	// if enabled not passed, default to true
 	if platformConfiguration.HealthCheck.Enabled == nil {
@@ -409,7 +613,7 @@ func (p *Processor) createAndStartHealthCheckServer(platformConfiguration *platf
 	}
 
 	// create the server
-	server, err := healthcheck.NewServer(p.logger, p, &platformConfiguration.HealthCheck)
+	server, err := healthcheck.NewServer(p.logger, p, &platformConfiguration.HealthCheck, functionSpec)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create health check server")
 	}
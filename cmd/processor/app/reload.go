@@ -0,0 +1,296 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"k8s.io/api/core/v1"
+)
+
+// getConfigurationReloadInterval returns how often the configuration file is checked for changes, 0 if it isn't
+func (p *Processor) getConfigurationReloadInterval() (time.Duration, error) {
+	return time.ParseDuration(common.GetEnvOrDefaultString("NUCLIO_PROCESSOR_CONFIG_RELOAD_INTERVAL", "5s"))
+}
+
+// watchConfiguration reloads the configuration whenever its file changes (e.g. when the config map it's mounted
+// from is updated). the file is polled, since config maps are mounted through symlinks which are replaced
+func (p *Processor) watchConfiguration(interval time.Duration) {
+	configurationContents, err := ioutil.ReadFile(p.configurationPath)
+	if err != nil {
+		p.logger.WarnWith("Failed to read configuration file, not watching it", "err", err.Error())
+		return
+	}
+
+	p.logger.DebugWith("Watching configuration file", "path", p.configurationPath, "interval", interval)
+
+	for range time.Tick(interval) {
+		newConfigurationContents, err := ioutil.ReadFile(p.configurationPath)
+		if err != nil {
+			p.logger.WarnWith("Failed to read configuration file", "err", err.Error())
+			continue
+		}
+
+		if bytes.Equal(configurationContents, newConfigurationContents) {
+			continue
+		}
+
+		configurationContents = newConfigurationContents
+
+		if err := p.reloadConfiguration(configurationContents); err != nil {
+			p.logger.WarnWith("Failed to reload configuration", "err", errors.GetErrorStackString(err, 10))
+		}
+	}
+}
+
+// reloadConfiguration applies the changes of the configuration which don't require a restart: of the environment
+// variables, triggers (re-creating only those which changed), data bindings, runtime attributes and the logger's
+// level. changes of the rest are logged and ignored
+func (p *Processor) reloadConfiguration(configurationContents []byte) error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
+	newConfiguration, err := p.readConfiguration(configurationContents)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read configuration")
+	}
+
+	currentConfiguration := p.configuration
+
+	changedFields, err := currentConfiguration.Spec.GetChangesRequiringRestart(&newConfiguration.Spec)
+	if err != nil {
+		return errors.Wrap(err, "Failed to compare configurations")
+	}
+
+	if len(changedFields) > 0 {
+		p.logger.WarnWith("Configuration changes require a restart, not applying them", "fields", changedFields)
+	}
+
+	reloadedConfiguration := *currentConfiguration
+	reloadedConfiguration.Spec = *currentConfiguration.Spec.WithHotReloadableFields(&newConfiguration.Spec)
+
+	if reflect.DeepEqual(currentConfiguration.Spec, reloadedConfiguration.Spec) {
+		return nil
+	}
+
+	p.logger.InfoWith("Reloading configuration")
+
+	if !reflect.DeepEqual(currentConfiguration.Spec.LoggerSinks, reloadedConfiguration.Spec.LoggerSinks) {
+		p.reloadLoggerLevel(&reloadedConfiguration)
+	}
+
+	// workers' runtimes are created with the environment, data bindings and runtime attributes, so all the
+	// triggers (and their workers) are re-created when they change
+	recreateAllTriggers := p.reloadEnv(currentConfiguration.Spec.Env, reloadedConfiguration.Spec.Env) ||
+		!reflect.DeepEqual(currentConfiguration.Spec.DataBindings, reloadedConfiguration.Spec.DataBindings) ||
+		!reflect.DeepEqual(currentConfiguration.Spec.RuntimeAttributes, reloadedConfiguration.Spec.RuntimeAttributes)
+
+	if err := p.reloadTriggers(&reloadedConfiguration, recreateAllTriggers); err != nil {
+		return errors.Wrap(err, "Failed to reload triggers")
+	}
+
+	p.configuration = &reloadedConfiguration
+
+	p.logger.InfoWith("Configuration reloaded", "triggers", len(p.GetTriggers()))
+
+	return nil
+}
+
+// reloadTriggers stops the triggers which were removed or changed and creates and starts those which were
+// added or changed
+func (p *Processor) reloadTriggers(newConfiguration *processor.Configuration, recreateAll bool) error {
+	currentTriggerConfigurations := p.getTriggerConfigurations(p.configuration)
+	newTriggerConfigurations := p.getTriggerConfigurations(newConfiguration)
+
+	var reloadedTriggers []trigger.Trigger
+	runningTriggerNames := map[string]bool{}
+
+	// whatever happens, the processor reports the triggers which are running
+	defer func() {
+		p.triggersLock.Lock()
+		p.triggers = reloadedTriggers
+		p.triggersLock.Unlock()
+	}()
+
+	for _, triggerInstance := range p.GetTriggers() {
+		triggerName := triggerInstance.GetName()
+		currentTriggerConfiguration := currentTriggerConfigurations[triggerName]
+		newTriggerConfiguration, found := newTriggerConfigurations[triggerName]
+
+		if found && !recreateAll && reflect.DeepEqual(currentTriggerConfiguration, newTriggerConfiguration) {
+			reloadedTriggers = append(reloadedTriggers, triggerInstance)
+			runningTriggerNames[triggerName] = true
+
+			continue
+		}
+
+		p.logger.InfoWith("Stopping trigger", "name", triggerName)
+
+		if err := p.stopTrigger(triggerInstance, &currentTriggerConfiguration); err != nil {
+			reloadedTriggers = append(reloadedTriggers, triggerInstance)
+
+			return errors.Wrapf(err, "Failed to stop trigger %s", triggerName)
+		}
+	}
+
+	for triggerName, triggerConfiguration := range newTriggerConfigurations {
+		triggerConfiguration := triggerConfiguration
+
+		if runningTriggerNames[triggerName] {
+			continue
+		}
+
+		p.logger.InfoWith("Creating trigger", "name", triggerName, "kind", triggerConfiguration.Kind)
+
+		triggerInstance, err := p.createTrigger(triggerName, &triggerConfiguration, newConfiguration)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create trigger %s", triggerName)
+		}
+
+		// unknown triggers are ignored
+		if triggerInstance == nil {
+			continue
+		}
+
+		if err := triggerInstance.Start(nil); err != nil {
+			return errors.Wrapf(err, "Failed to start trigger %s", triggerName)
+		}
+
+		reloadedTriggers = append(reloadedTriggers, triggerInstance)
+	}
+
+	return nil
+}
+
+// getTriggerConfigurations returns the configurations of the triggers the processor runs, by name - including
+// the default HTTP trigger's if it's created
+func (p *Processor) getTriggerConfigurations(
+	processorConfiguration *processor.Configuration) map[string]functionconfig.Trigger {
+
+	triggerConfigurations := map[string]functionconfig.Trigger{}
+
+	for triggerName, triggerConfiguration := range processorConfiguration.Spec.Triggers {
+		if p.shouldCreateTrigger(triggerName, &triggerConfiguration, processorConfiguration.PlatformConfig.Kind) {
+			triggerConfigurations[triggerName] = triggerConfiguration
+		}
+	}
+
+	if len(functionconfig.GetTriggersByKind(processorConfiguration.Spec.Triggers, "http")) == 0 {
+		triggerConfigurations[defaultHTTPTriggerName] = p.getDefaultHTTPTriggerConfiguration()
+	}
+
+	return triggerConfigurations
+}
+
+// stopTrigger stops the trigger and its workers, unless they're allocated to other triggers as well
+func (p *Processor) stopTrigger(triggerInstance trigger.Trigger, triggerConfiguration *functionconfig.Trigger) error {
+	if _, err := triggerInstance.Stop(false); err != nil {
+		return errors.Wrap(err, "Failed to stop trigger")
+	}
+
+	if triggerConfiguration.WorkerAllocatorName != "" {
+		return nil
+	}
+
+	for _, workerInstance := range triggerInstance.GetWorkers() {
+		if err := workerInstance.Stop(); err != nil {
+			p.logger.WarnWith("Failed to stop worker", "trigger", triggerInstance.GetName(), "err", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// reloadEnv applies the changes of the environment variables to the processor's environment, which the runtimes
+// created from now on inherit. returns whether any were applied
+func (p *Processor) reloadEnv(currentEnv []v1.EnvVar, newEnv []v1.EnvVar) bool {
+	currentValues, currentReferences := p.getEnvValues(currentEnv)
+	newValues, newReferences := p.getEnvValues(newEnv)
+
+	// values referenced from secrets and such are resolved by the platform, when the processor starts
+	if !reflect.DeepEqual(currentReferences, newReferences) {
+		p.logger.Warn("Changes of environment variables referencing other resources require a restart, " +
+			"not applying them")
+	}
+
+	changed := false
+
+	for envName, envValue := range newValues {
+		if currentValue, found := currentValues[envName]; !found || currentValue != envValue {
+			os.Setenv(envName, envValue) // nolint: errcheck
+			changed = true
+		}
+	}
+
+	for envName := range currentValues {
+		if _, found := newValues[envName]; !found {
+			os.Unsetenv(envName) // nolint: errcheck
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// returns the values of the environment variables, and the variables referencing other resources
+func (p *Processor) getEnvValues(env []v1.EnvVar) (map[string]string, []v1.EnvVar) {
+	envValues := map[string]string{}
+	var envReferences []v1.EnvVar
+
+	for _, envVar := range env {
+		if envVar.ValueFrom != nil {
+			envReferences = append(envReferences, envVar)
+		} else {
+			envValues[envVar.Name] = envVar.Value
+		}
+	}
+
+	return envValues, envReferences
+}
+
+// reloadLoggerLevel sets the level of the processor's logger to that of the function's logger sink. the level of
+// several sinks (a mux logger) can't be changed
+func (p *Processor) reloadLoggerLevel(newConfiguration *processor.Configuration) {
+	loggerSinks, err := newConfiguration.PlatformConfig.GetFunctionLoggerSinks(&newConfiguration.Config)
+	if err != nil {
+		p.logger.WarnWith("Failed to get logger sinks, not reloading them", "err", err.Error())
+		return
+	}
+
+	zapLogger, isZapLogger := p.logger.(*nucliozap.NuclioZap)
+	if !isZapLogger || len(loggerSinks) != 1 {
+		p.logger.Warn("Changes of the logger sinks other than the level of a single sink require a restart, " +
+			"not applying them")
+		return
+	}
+
+	for _, loggerSink := range loggerSinks {
+		p.logger.InfoWith("Setting logger level", "level", loggerSink.Level)
+		zapLogger.SetLevel(nucliozap.GetLevelByName(loggerSink.Level))
+	}
+}
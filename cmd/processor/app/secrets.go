@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/secretprovider"
+
+	"github.com/nuclio/errors"
+)
+
+// resolveExternalSecretEnv sets the function's external secret environment variables in the processor's
+// environment, which the runtimes inherit. the values are never written to the configuration. variables of
+// providers the platform configuration doesn't have were resolved by the platform when it created the
+// container (e.g. locally), and must already be set
+func (p *Processor) resolveExternalSecretEnv(processorConfiguration *processor.Configuration) error {
+	if len(processorConfiguration.Spec.ExternalSecretEnv) == 0 {
+		return nil
+	}
+
+	secretResolver, err := secretprovider.NewResolver(p.logger,
+		processorConfiguration.PlatformConfig.SecretProviders)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create secret resolver")
+	}
+
+	for _, envVar := range processorConfiguration.Spec.ExternalSecretEnv {
+		if envVar.ValueFrom.ExternalSecret != nil && secretResolver.HasProvider(envVar.ValueFrom.ExternalSecret.Provider) {
+			p.externalSecretEnv = append(p.externalSecretEnv, envVar)
+			continue
+		}
+
+		if _, found := os.LookupEnv(envVar.Name); !found {
+			return errors.Errorf("Environment variable %s references a secret provider the platform configuration "+
+				"doesn't have, and wasn't set by the platform",
+				envVar.Name)
+		}
+	}
+
+	if len(p.externalSecretEnv) == 0 {
+		return nil
+	}
+
+	p.secretResolver = secretResolver
+
+	envValues, err := p.secretResolver.Resolve(p.externalSecretEnv)
+	if err != nil {
+		return err
+	}
+
+	p.setExternalSecretEnv(envValues)
+
+	return nil
+}
+
+// watchExternalSecrets re-reads the external secrets every interval, and re-creates the triggers (and with them
+// the runtimes, with the new environment) when any were rotated
+func (p *Processor) watchExternalSecrets(interval time.Duration) {
+	p.logger.DebugWith("Watching external secrets", "interval", interval)
+
+	for range time.Tick(interval) {
+		envValues, err := p.secretResolver.Resolve(p.externalSecretEnv)
+		if err != nil {
+			p.logger.WarnWith("Failed to re-read external secrets", "err", errors.GetErrorStackString(err, 10))
+			continue
+		}
+
+		if reflect.DeepEqual(envValues, p.externalSecretEnvValues) {
+			continue
+		}
+
+		p.reloadLock.Lock()
+
+		p.setExternalSecretEnv(envValues)
+
+		if err := p.reloadTriggers(p.configuration, true); err != nil {
+			p.logger.WarnWith("Failed to re-create triggers with rotated secrets",
+				"err", errors.GetErrorStackString(err, 10))
+		}
+
+		p.reloadLock.Unlock()
+	}
+}
+
+func (p *Processor) setExternalSecretEnv(envValues map[string]string) {
+	var changedEnvNames []string
+
+	for envName, envValue := range envValues {
+		if currentValue, found := p.externalSecretEnvValues[envName]; !found || currentValue != envValue {
+			os.Setenv(envName, envValue) // nolint: errcheck
+			changedEnvNames = append(changedEnvNames, envName)
+		}
+	}
+
+	p.externalSecretEnvValues = envValues
+
+	// only the names are logged, never the values
+	sort.Strings(changedEnvNames)
+	p.logger.InfoWith("Set environment variables from external secrets", "names", changedEnvNames)
+}
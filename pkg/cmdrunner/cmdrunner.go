@@ -19,8 +19,10 @@ package cmdrunner
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -43,6 +45,9 @@ type RunOptions struct {
 	Env               map[string]string
 	LogRedactions     []string
 	CaptureOutputMode CaptureOutputMode
+
+	// set by RunStream
+	outputLineHandler func(line string)
 }
 
 type RunResult struct {
@@ -56,6 +61,10 @@ type CmdRunner interface {
 
 	// Run runs a command, given runOptions
 	Run(runOptions *RunOptions, format string, vars ...interface{}) (RunResult, error)
+
+	// RunStream runs a command like Run, but also calls outputLineHandler with each line of output
+	// as it is produced
+	RunStream(runOptions *RunOptions, outputLineHandler func(line string), format string, vars ...interface{}) (RunResult, error)
 }
 
 type ShellRunner struct {
@@ -134,6 +143,28 @@ func (sr *ShellRunner) Run(runOptions *RunOptions, format string, vars ...interf
 	return runResult, nil
 }
 
+func (sr *ShellRunner) RunStream(runOptions *RunOptions,
+	outputLineHandler func(line string),
+	format string,
+	vars ...interface{}) (RunResult, error) {
+
+	// don't modify the caller's options
+	streamRunOptions := RunOptions{}
+	if runOptions != nil {
+		streamRunOptions = *runOptions
+	}
+
+	streamRunOptions.outputLineHandler = outputLineHandler
+
+	return sr.Run(&streamRunOptions, format, vars...)
+}
+
+// Quote returns the argument as a single quoted shell word, so that it's passed to the command as is - whatever
+// quotes or shell metacharacters it contains
+func Quote(argument string) string {
+	return "'" + strings.Replace(argument, "'", `'\''`, -1) + "'"
+}
+
 func (sr *ShellRunner) SetShell(shell string) {
 	sr.shell = shell
 }
@@ -152,6 +183,10 @@ func (sr *ShellRunner) runAndCaptureOutput(cmd *exec.Cmd,
 	runOptions *RunOptions,
 	runResult *RunResult) error {
 
+	if runOptions.outputLineHandler != nil {
+		return sr.runAndStreamOutput(cmd, runOptions, runResult)
+	}
+
 	switch runOptions.CaptureOutputMode {
 
 	case CaptureOutputModeCombined:
@@ -174,3 +209,75 @@ func (sr *ShellRunner) runAndCaptureOutput(cmd *exec.Cmd,
 
 	return fmt.Errorf("Invalid output capture mode: %d", runOptions.CaptureOutputMode)
 }
+
+func (sr *ShellRunner) runAndStreamOutput(cmd *exec.Cmd,
+	runOptions *RunOptions,
+	runResult *RunResult) error {
+	var stdOut, stdErr bytes.Buffer
+
+	// stdout and stderr are copied from different goroutines, make sure lines are handled one at a time
+	handlerLock := sync.Mutex{}
+	outputLineHandler := func(line string) {
+		handlerLock.Lock()
+		defer handlerLock.Unlock()
+
+		runOptions.outputLineHandler(common.Redact(runOptions.LogRedactions, line))
+	}
+
+	stdoutLineWriter := &lineWriter{handler: outputLineHandler}
+	stderrLineWriter := &lineWriter{handler: outputLineHandler}
+
+	switch runOptions.CaptureOutputMode {
+	case CaptureOutputModeCombined:
+
+		// a single writer, so that the captured output is ordered like the streamed lines
+		combinedWriter := io.MultiWriter(&stdOut, stdoutLineWriter)
+		cmd.Stdout = combinedWriter
+		cmd.Stderr = combinedWriter
+	case CaptureOutputModeStdout:
+		cmd.Stdout = io.MultiWriter(&stdOut, stdoutLineWriter)
+		cmd.Stderr = io.MultiWriter(&stdErr, stderrLineWriter)
+	default:
+		return fmt.Errorf("Invalid output capture mode: %d", runOptions.CaptureOutputMode)
+	}
+
+	err := cmd.Run()
+
+	// flush trailing output which doesn't end with a newline
+	stdoutLineWriter.flush()
+	stderrLineWriter.flush()
+
+	runResult.Output = common.Redact(runOptions.LogRedactions, stdOut.String())
+	runResult.Stderr = common.Redact(runOptions.LogRedactions, stdErr.String())
+
+	return err
+}
+
+// lineWriter calls a handler for each complete line written to it
+type lineWriter struct {
+	handler func(line string)
+	partial []byte
+}
+
+func (lw *lineWriter) Write(data []byte) (int, error) {
+	lw.partial = append(lw.partial, data...)
+
+	for {
+		newlineIndex := bytes.IndexByte(lw.partial, '\n')
+		if newlineIndex == -1 {
+			break
+		}
+
+		lw.handler(string(lw.partial[:newlineIndex]))
+		lw.partial = lw.partial[newlineIndex+1:]
+	}
+
+	return len(data), nil
+}
+
+func (lw *lineWriter) flush() {
+	if len(lw.partial) > 0 {
+		lw.handler(string(lw.partial))
+		lw.partial = nil
+	}
+}
@@ -102,6 +102,21 @@ func (suite *CmdRunnerTestSuite) TestStdin() {
 	suite.Require().True(strings.HasPrefix(runResult.Output, stdinValue))
 }
 
+func (suite *CmdRunnerTestSuite) TestQuote() {
+	for _, argument := range []string{
+		"plain",
+		"with spaces",
+		"it's",
+		"'; touch /tmp/injected; echo '",
+		"$(whoami) `whoami` \"double\" \\",
+		"",
+	} {
+		runResult, err := suite.commandRunner.Run(nil, "printf '%%s' %s", Quote(argument))
+		suite.Require().NoError(err)
+		suite.Require().Equal(argument, runResult.Output)
+	}
+}
+
 func TestCmdRunnerTestSuite(t *testing.T) {
 	suite.Run(t, new(CmdRunnerTestSuite))
 }
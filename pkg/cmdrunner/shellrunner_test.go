@@ -4,7 +4,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
@@ -67,6 +69,44 @@ func (suite *ShellRunnerTestSuite) TestRunAndCaptureOutputStdoutReturnsStdoutAnd
 	suite.Require().Equal("foo3\n", runResult.Stderr)
 }
 
+func (suite *ShellRunnerTestSuite) TestRunStreamCallsHandlerIncrementally() {
+	var streamedLines []string
+	var lineTimes []time.Time
+
+	startTime := time.Now()
+	runResult, err := suite.shellRunner.RunStream(&RunOptions{
+		CaptureOutputMode: CaptureOutputModeStdout,
+		LogRedactions:     []string{"secret"},
+	}, func(line string) {
+		streamedLines = append(streamedLines, line)
+		lineTimes = append(lineTimes, time.Now())
+	}, `echo "line1" ; sleep 0.5 ; echo "line2 secret" ; sleep 0.5 ; printf "line3"`)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]string{"line1", "line2 [redacted]", "line3"}, streamedLines)
+
+	// the first line must have been handled well before the command finished
+	suite.Require().True(lineTimes[0].Sub(startTime) < 500*time.Millisecond)
+	suite.Require().True(lineTimes[2].Sub(lineTimes[0]) >= 900*time.Millisecond)
+
+	// streamed and captured output are the same
+	suite.Require().Equal("line1\nline2 [redacted]\nline3", runResult.Output)
+	suite.Require().Equal(strings.Join(streamedLines, "\n"), runResult.Output)
+}
+
+func (suite *ShellRunnerTestSuite) TestRunStreamCombinedOutput() {
+	var streamedLines []string
+
+	runResult, err := suite.shellRunner.RunStream(nil, func(line string) {
+		streamedLines = append(streamedLines, line)
+	}, `echo "foo1" ; sleep 0.1 ; echo "foo2">&2`)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]string{"foo1", "foo2"}, streamedLines)
+	suite.Require().Equal("foo1\nfoo2\n", runResult.Output)
+	suite.Require().Empty(runResult.Stderr)
+}
+
 func (suite *ShellRunnerTestSuite) TestRunAndCaptureOutputCombinedRedactsStrings() {
 	cmd := exec.Command(suite.shellRunner.shell, "-c", `echo "foo1 foo2 secret" ; echo "foo3password">&2`)
 	suite.runOptions.CaptureOutputMode = CaptureOutputModeCombined
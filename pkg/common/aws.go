@@ -7,42 +7,102 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/nuclio/errors"
 )
 
+// AWSSessionConfiguration holds what's needed to create a session for an AWS service
+type AWSSessionConfiguration struct {
+	Region string
+
+	// an endpoint overriding the service's, for VPC endpoints or compatible services (e.g. localstack)
+	Endpoint string
+
+	// static credentials. when not given, the default credentials chain is used - environment variables,
+	// the shared credentials file and the IAM role of the pod (web identity) or instance
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// a role to assume with the credentials
+	RoleARN string
+}
+
+// NewAWSSession creates a session from the given configuration
+func NewAWSSession(sessionConfiguration *AWSSessionConfiguration) (*session.Session, error) {
+	awsConfig := &aws.Config{
+		Region: aws.String(sessionConfiguration.Region),
+	}
+
+	if sessionConfiguration.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(sessionConfiguration.AccessKeyID,
+			sessionConfiguration.SecretAccessKey,
+			sessionConfiguration.SessionToken)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	if sessionConfiguration.RoleARN != "" {
+		sess.Config.Credentials = stscreds.NewCredentials(sess, sessionConfiguration.RoleARN)
+	}
+
+	// the endpoint is set last, so that the role is assumed through STS rather than through it
+	if sessionConfiguration.Endpoint != "" {
+		sess = sess.Copy(&aws.Config{Endpoint: aws.String(sessionConfiguration.Endpoint)})
+	}
+
+	return sess, nil
+}
+
 type S3Client interface {
-	Download(file *os.File, bucket, itemKey, region, accessKeyID, secretAccessKey, sessionToken string) error
+	Download(file *os.File, bucket, itemKey, region, endpoint, accessKeyID, secretAccessKey, sessionToken string) error
 }
 
 type AbstractS3Client struct {
 	S3Client
 }
 
-func (asc AbstractS3Client) Download(file *os.File, bucket, itemKey, region, accessKeyID, secretAccessKey, sessionToken string) error {
+// Download downloads an item from a bucket. when an endpoint is given, the bucket is of an S3-compatible store
+// (e.g. MinIO) rather than of AWS S3
+func (asc AbstractS3Client) Download(file *os.File, bucket, itemKey, region, endpoint, accessKeyID, secretAccessKey, sessionToken string) error {
 	itemKey = filepath.Clean(itemKey)
 
 	pathInsideBucket, item := path.Split(itemKey)
 	bucketAndPath := path.Join(bucket, pathInsideBucket) + "/"
 
-	sess, err := session.NewSession(&aws.Config{
+	sessionConfig := &aws.Config{
 		Region:      aws.String("us-east-1"), // default region (some valid region must be mentioned)
 		Credentials: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, sessionToken),
-	})
+	}
+
+	// S3-compatible stores are usually addressed by path, as their buckets have no DNS names
+	if endpoint != "" {
+		sessionConfig.Endpoint = aws.String(endpoint)
+		sessionConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	sess, err := session.NewSession(sessionConfig)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create AWS session")
 	}
 
-	// get the bucket's region in case it wasn't given
-	if region == "" {
+	// get the bucket's region in case it wasn't given (S3-compatible stores don't resolve one, the default is used)
+	if region == "" && endpoint == "" {
 		region, err = s3manager.GetBucketRegion(aws.BackgroundContext(), sess, bucketAndPath, "")
 		if err != nil {
 			return errors.Wrap(err, "Failed to get bucket region")
 		}
 	}
-	sess.Config.Region = aws.String(region)
+
+	if region != "" {
+		sess.Config.Region = aws.String(region)
+	}
 
 	downloader := s3manager.NewDownloader(sess)
 	_, err = downloader.Download(file,
@@ -19,6 +19,7 @@ package common
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"math"
 	"os"
 	"path"
@@ -389,3 +390,19 @@ func MatchStringPatterns(patterns []string, s string) (bool, error) {
 	}
 	return false, nil
 }
+
+// FormatBytes renders a byte count in human readable, decimal units (e.g. 1.5MB)
+func FormatBytes(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	divisor, exponent := int64(unit), 0
+	for remainder := bytes / unit; remainder >= unit; remainder /= unit {
+		divisor *= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(divisor), "kMGTPE"[exponent])
+}
@@ -353,6 +353,25 @@ func (suite *StripPrefixesTestSuite) TestPositive() {
 	suite.Require().Equal("prefix_something_1", stripped)
 }
 
+type FormatBytesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *FormatBytesTestSuite) TestFormat() {
+	for _, testCase := range []struct {
+		bytes    int64
+		expected string
+	}{
+		{bytes: 0, expected: "0B"},
+		{bytes: 999, expected: "999B"},
+		{bytes: 1000, expected: "1.0kB"},
+		{bytes: 1500000, expected: "1.5MB"},
+		{bytes: 2300000000, expected: "2.3GB"},
+	} {
+		suite.Require().Equal(testCase.expected, FormatBytes(testCase.bytes))
+	}
+}
+
 func TestHelperTestSuite(t *testing.T) {
 	suite.Run(t, new(RetryUntilSuccessfulTestSuite))
 	suite.Run(t, new(RetryUntilSuccessfulOnErrorPatternsTestSuite))
@@ -361,4 +380,5 @@ func TestHelperTestSuite(t *testing.T) {
 	suite.Run(t, new(IsDirTestSuite))
 	suite.Run(t, new(IsFileTestSuite))
 	suite.Run(t, new(StripPrefixesTestSuite))
+	suite.Run(t, new(FormatBytesTestSuite))
 }
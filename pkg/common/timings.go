@@ -0,0 +1,107 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// names of the phases of a function deploy
+const (
+	PhaseContextArchiving = "context archiving"
+	PhaseBaseImagePull    = "base image pull"
+	PhaseOnbuild          = "onbuild"
+	PhaseImageBuild       = "image build"
+	PhaseImagePush        = "image push"
+	PhasePlatformApply    = "platform apply"
+	PhasePodScheduling    = "pod scheduling"
+	PhaseReadinessWait    = "readiness wait"
+)
+
+// PhaseTiming is the duration of a single named phase
+type PhaseTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+
+	// whether the phase failed (only known for measured phases)
+	Failed bool `json:"failed,omitempty"`
+}
+
+// PhaseTimings records the durations of named phases in the order they completed. All methods
+// are no-ops on a nil receiver, so code can measure phases whether or not anyone asked for them
+type PhaseTimings struct {
+	lock   sync.Mutex
+	phases []PhaseTiming
+
+	// if set, called with each phase as it's recorded
+	RecordHandler func(phaseTiming PhaseTiming)
+
+	// if set, called with the name of each measured phase as it starts
+	StartHandler func(name string)
+}
+
+// Measure runs the given function and records its duration under the given phase name
+func (pt *PhaseTimings) Measure(name string, phaseFunc func() error) error {
+	if pt != nil && pt.StartHandler != nil {
+		pt.StartHandler(name)
+	}
+
+	startTime := time.Now()
+	err := phaseFunc()
+	pt.record(PhaseTiming{
+		Name:     name,
+		Duration: time.Since(startTime),
+		Failed:   err != nil,
+	})
+
+	return err
+}
+
+// Record records the duration of a phase
+func (pt *PhaseTimings) Record(name string, duration time.Duration) {
+	pt.record(PhaseTiming{
+		Name:     name,
+		Duration: duration,
+	})
+}
+
+func (pt *PhaseTimings) record(phaseTiming PhaseTiming) {
+	if pt == nil {
+		return
+	}
+
+	pt.lock.Lock()
+	pt.phases = append(pt.phases, phaseTiming)
+	pt.lock.Unlock()
+
+	if pt.RecordHandler != nil {
+		pt.RecordHandler(phaseTiming)
+	}
+}
+
+// GetPhases returns the recorded phases
+func (pt *PhaseTimings) GetPhases() []PhaseTiming {
+	if pt == nil {
+		return nil
+	}
+
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	return append([]PhaseTiming{}, pt.phases...)
+}
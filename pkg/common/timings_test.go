@@ -0,0 +1,96 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type PhaseTimingsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PhaseTimingsTestSuite) TestMeasure() {
+	phaseTimings := &PhaseTimings{}
+
+	err := phaseTimings.Measure(PhaseImageBuild, func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// failed phases are recorded too, and their error is returned
+	err = phaseTimings.Measure(PhaseImagePush, func() error {
+		return errors.New("push failed")
+	})
+	suite.Require().Error(err)
+
+	phases := phaseTimings.GetPhases()
+	suite.Require().Len(phases, 2)
+	suite.Require().Equal(PhaseImageBuild, phases[0].Name)
+	suite.Require().True(phases[0].Duration >= 10*time.Millisecond)
+	suite.Require().Equal(PhaseImagePush, phases[1].Name)
+	suite.Require().False(phases[0].Failed)
+	suite.Require().True(phases[1].Failed)
+}
+
+func (suite *PhaseTimingsTestSuite) TestNilPhaseTimings() {
+	var phaseTimings *PhaseTimings
+
+	called := false
+	err := phaseTimings.Measure(PhaseImageBuild, func() error {
+		called = true
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(called)
+	suite.Require().Empty(phaseTimings.GetPhases())
+}
+
+func (suite *PhaseTimingsTestSuite) TestRecordHandler() {
+	var handledPhases []string
+	phaseTimings := &PhaseTimings{
+		RecordHandler: func(phaseTiming PhaseTiming) {
+			handledPhases = append(handledPhases, phaseTiming.Name)
+		},
+	}
+
+	phaseTimings.Record(PhaseImageBuild, time.Second)
+	phaseTimings.Record(PhaseImagePush, time.Second)
+
+	suite.Require().Equal([]string{PhaseImageBuild, PhaseImagePush}, handledPhases)
+}
+
+func (suite *PhaseTimingsTestSuite) TestStartHandler() {
+	var events []string
+	phaseTimings := &PhaseTimings{
+		StartHandler: func(name string) {
+			events = append(events, "started "+name)
+		},
+		RecordHandler: func(phaseTiming PhaseTiming) {
+			events = append(events, "recorded "+phaseTiming.Name)
+		},
+	}
+
+	err := phaseTimings.Measure(PhaseImageBuild, func() error {
+		events = append(events, "measured")
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// phases that are only recorded don't start
+	phaseTimings.Record(PhaseImagePush, time.Second)
+
+	suite.Require().Equal([]string{
+		"started " + PhaseImageBuild,
+		"measured",
+		"recorded " + PhaseImageBuild,
+		"recorded " + PhaseImagePush,
+	}, events)
+}
+
+func TestPhaseTimingsTestSuite(t *testing.T) {
+	suite.Run(t, new(PhaseTimingsTestSuite))
+}
@@ -0,0 +1,63 @@
+package containerimagebuilderpusher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildCacheMount is a directory that persists across builds of functions of the same runtime, holding the
+// downloads of a package manager (e.g. pip, npm, Maven)
+type BuildCacheMount struct {
+	ID   string
+	Path string
+}
+
+// the directories package managers cache their downloads in, in the images each runtime is built in
+var buildCachePathsByRuntime = map[string][]string{
+	"python":     {"/root/.cache/pip"},
+	"pypy":       {"/root/.cache/pip"},
+	"nodejs":     {"/root/.npm"},
+	"java":       {"/root/.m2", "/root/.gradle"},
+	"golang":     {"/go/pkg/mod", "/root/.cache/go-build"},
+	"dotnetcore": {"/root/.nuget/packages"},
+	"ruby":       {"/usr/local/bundle/cache"},
+}
+
+// GetBuildCacheMounts returns the cache mounts of the package managers used when building functions of
+// the runtime, keyed by it
+func GetBuildCacheMounts(runtimeName string) []BuildCacheMount {
+	var buildCacheMounts []BuildCacheMount
+
+	for _, buildCachePath := range buildCachePathsByRuntime[runtimeName] {
+		buildCacheMounts = append(buildCacheMounts, BuildCacheMount{
+			ID: fmt.Sprintf("nuclio-%s-%s",
+				runtimeName,
+				strings.Replace(strings.Trim(buildCachePath, "/"), "/", "-", -1)),
+			Path: buildCachePath,
+		})
+	}
+
+	return buildCacheMounts
+}
+
+// addBuildCacheMountsToDockerfile has each RUN instruction of the Dockerfile mount the build cache
+// (BuildKit cache mounts)
+func addBuildCacheMountsToDockerfile(dockerfileContents string, buildCacheMounts []BuildCacheMount) string {
+	var mountFlags []string
+	for _, buildCacheMount := range buildCacheMounts {
+		mountFlags = append(mountFlags, fmt.Sprintf("--mount=type=cache,id=%s,target=%s",
+			buildCacheMount.ID,
+			buildCacheMount.Path))
+	}
+
+	dockerfileLines := strings.Split(dockerfileContents, "\n")
+	for lineIndex, dockerfileLine := range dockerfileLines {
+		if strings.HasPrefix(dockerfileLine, "RUN ") {
+			dockerfileLines[lineIndex] = fmt.Sprintf("RUN %s %s",
+				strings.Join(mountFlags, " "),
+				strings.TrimPrefix(dockerfileLine, "RUN "))
+		}
+	}
+
+	return strings.Join(dockerfileLines, "\n")
+}
@@ -0,0 +1,71 @@
+package containerimagebuilderpusher
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type buildCacheTestSuite struct {
+	suite.Suite
+}
+
+func (suite *buildCacheTestSuite) TestGetBuildCacheMounts() {
+	suite.Require().Equal([]BuildCacheMount{
+		{ID: "nuclio-java-root-.m2", Path: "/root/.m2"},
+		{ID: "nuclio-java-root-.gradle", Path: "/root/.gradle"},
+	}, GetBuildCacheMounts("java"))
+
+	suite.Require().Empty(GetBuildCacheMounts("shell"))
+}
+
+func (suite *buildCacheTestSuite) TestAddBuildCacheMountsToDockerfile() {
+	dockerfileContents := `FROM python:3.7
+RUN pip install requests \
+    && echo done
+COPY handler /opt/nuclio
+`
+
+	suite.Require().Equal(`FROM python:3.7
+RUN --mount=type=cache,id=nuclio-python-root-.cache-pip,target=/root/.cache/pip pip install requests \
+    && echo done
+COPY handler /opt/nuclio
+`, addBuildCacheMountsToDockerfile(dockerfileContents, GetBuildCacheMounts("python")))
+}
+
+func (suite *buildCacheTestSuite) TestKanikoJobSpecMountsBuildCache() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	buildOptions := &BuildOptions{
+		Image:            "my-function:latest",
+		ContextDir:       "/tmp/staging",
+		DockerfileInfo:   &runtime.ProcessorDockerfileInfo{DockerfilePath: "/tmp/staging/Dockerfile.processor"},
+		BuildCacheMounts: GetBuildCacheMounts("nodejs"),
+	}
+
+	kaniko, err := NewKaniko(loggerInstance, nil, &ContainerBuilderConfiguration{JobPrefix: "kanikojob"})
+	suite.Require().NoError(err)
+
+	// without a PVC, the build cache isn't mounted
+	kanikoJobSpec := kaniko.getKanikoJobSpec("default", buildOptions, "bundle.tar.gz")
+	suite.Require().Len(kanikoJobSpec.Spec.Template.Spec.Volumes, 1)
+
+	kaniko.builderConfiguration.BuildCachePVC = "nuclio-build-cache"
+	kanikoJobSpec = kaniko.getKanikoJobSpec("default", buildOptions, "bundle.tar.gz")
+
+	volumes := kanikoJobSpec.Spec.Template.Spec.Volumes
+	suite.Require().Len(volumes, 2)
+	suite.Require().Equal("nuclio-build-cache", volumes[1].PersistentVolumeClaim.ClaimName)
+
+	volumeMounts := kanikoJobSpec.Spec.Template.Spec.Containers[0].VolumeMounts
+	suite.Require().Equal("/root/.npm", volumeMounts[len(volumeMounts)-1].MountPath)
+	suite.Require().Equal("nuclio-nodejs-root-.npm", volumeMounts[len(volumeMounts)-1].SubPath)
+}
+
+func TestBuildCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(buildCacheTestSuite))
+}
@@ -22,4 +22,7 @@ type BuilderPusher interface {
 
 	// GetDefaultRegistryCredentialsSecretName returns secret with credentials to push/pull from docker registry
 	GetDefaultRegistryCredentialsSecretName() string
+
+	// PruneBuildCache removes the package manager caches persisted across builds
+	PruneBuildCache(namespace string) error
 }
@@ -6,7 +6,11 @@ import (
 	"os"
 	"path"
 
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher/registryauth"
 	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 
 	"github.com/nuclio/errors"
@@ -22,6 +26,7 @@ type Docker struct {
 	dockerClient         dockerclient.Client
 	logger               logger.Logger
 	builderConfiguration *ContainerBuilderConfiguration
+	registryAuthProvider registryauth.Provider
 }
 
 func NewDocker(logger logger.Logger, builderConfiguration *ContainerBuilderConfiguration) (*Docker, error) {
@@ -31,28 +36,62 @@ func NewDocker(logger logger.Logger, builderConfiguration *ContainerBuilderConfi
 		return nil, errors.Wrap(err, "Failed to create docker client")
 	}
 
+	registryAuthProvider, err := registryauth.NewProvider(logger,
+		registryauth.Kind(builderConfiguration.RegistryAuthProvider))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry auth provider")
+	}
+
 	dockerBuilder := &Docker{
 		dockerClient:         dockerClient,
 		logger:               logger,
 		builderConfiguration: builderConfiguration,
+		registryAuthProvider: registryAuthProvider,
 	}
 
 	return dockerBuilder, nil
 }
 
 func (d *Docker) BuildAndPushContainerImage(buildOptions *BuildOptions, namespace string) error {
+	if len(buildOptions.Platforms) > 0 {
+
+		// multi-platform builds copy the artifacts from stages of the build, so there's nothing to prepare
+		if buildOptions.ContextOnly {
+			return nil
+		}
 
+		if err := d.mountBuildCache(buildOptions); err != nil {
+			return errors.Wrap(err, "Failed to mount build cache")
+		}
+
+		return d.buildAndPushMultiPlatformContainerImage(buildOptions)
+	}
+
+	// the base image pull and onbuild phases are measured as the artifacts are gathered
 	err := d.gatherArtifactsForSingleStageDockerfile(buildOptions)
 	if err != nil {
 		return errors.Wrap(err, "Failed to build image artifacts")
 	}
 
-	err = d.buildContainerImage(buildOptions)
+	if buildOptions.ContextOnly {
+		d.logger.InfoWith("Prepared docker build context", "contextDir", buildOptions.ContextDir)
+		return nil
+	}
+
+	if err := d.mountBuildCache(buildOptions); err != nil {
+		return errors.Wrap(err, "Failed to mount build cache")
+	}
+
+	err = buildOptions.PhaseTimings.Measure(common.PhaseImageBuild, func() error {
+		return d.buildContainerImage(buildOptions)
+	})
 	if err != nil {
 		return errors.Wrap(err, "Failed to build docker image")
 	}
 
-	err = d.pushContainerImage(buildOptions.Image, buildOptions.RegistryURL)
+	err = buildOptions.PhaseTimings.Measure(common.PhaseImagePush, func() error {
+		return d.pushContainerImage(buildOptions.Image, buildOptions.RegistryURL)
+	})
 	if err != nil {
 		return errors.Wrap(err, "Failed to push docker image into registry")
 	}
@@ -68,6 +107,54 @@ func (d *Docker) BuildAndPushContainerImage(buildOptions *BuildOptions, namespac
 	return nil
 }
 
+// buildAndPushMultiPlatformContainerImage builds the image for each of the platforms, pushing them along with
+// a manifest list referencing them. the onbuild artifacts are copied from stages of the build rather than
+// gathered beforehand, since each platform needs its own
+func (d *Docker) buildAndPushMultiPlatformContainerImage(buildOptions *BuildOptions) error {
+	if buildOptions.RegistryURL == "" {
+		return errors.New("A registry is required to push a multi-platform image to")
+	}
+
+	if buildOptions.OutputImageFile != "" {
+		return errors.New("A multi-platform image can't be saved to an output image file")
+	}
+
+	image := fmt.Sprintf("%s/%s", buildOptions.RegistryURL, buildOptions.Image)
+
+	// the image is pushed as part of the build
+	if err := d.logInToRegistry(buildOptions.RegistryURL); err != nil {
+		return errors.Wrap(err, "Failed to log in to registry")
+	}
+
+	d.logger.InfoWith("Building and pushing multi-platform docker image",
+		"image", image,
+		"platforms", buildOptions.Platforms)
+
+	err := buildOptions.PhaseTimings.Measure(common.PhaseImageBuild, func() error {
+		return d.dockerClient.Build(&dockerclient.BuildOptions{
+			ContextDir:        buildOptions.ContextDir,
+			Image:             image,
+			DockerfilePath:    buildOptions.DockerfileInfo.DockerfilePath,
+			NoCache:           buildOptions.NoCache,
+			CacheFrom:         buildOptions.CacheFrom,
+			Network:           buildOptions.Network,
+			BuildArgs:         buildOptions.BuildArgs,
+			Labels:            buildOptions.Labels,
+			OutputLineHandler: buildOptions.OutputLineHandler,
+			Platforms:         buildOptions.Platforms,
+			Secrets:           getDockerBuildSecrets(buildOptions.BuildSecrets),
+		})
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to build multi-platform docker image")
+	}
+
+	d.logger.InfoWith("Multi-platform docker image was successfully built and pushed into docker registry",
+		"image", image)
+
+	return nil
+}
+
 func (d *Docker) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
 
 	// Currently docker builder doesn't utilize multistage docker builds
@@ -78,6 +165,41 @@ func (d *Docker) GetDefaultRegistryCredentialsSecretName() string {
 	return d.builderConfiguration.DefaultRegistryCredentialsSecretName
 }
 
+// PruneBuildCache removes the cache mounts BuildKit persisted. cache mounts aren't labeled, so those of builds
+// other than nuclio's are removed as well
+func (d *Docker) PruneBuildCache(namespace string) error {
+	d.logger.InfoWith("Pruning build cache mounts")
+
+	return d.dockerClient.PruneBuildCacheMounts()
+}
+
+// mountBuildCache has the RUN instructions of the Dockerfile mount the build cache mounts, which BuildKit
+// persists across builds. the onbuild stages are triggered by a plain build of the onbuild image, so they
+// don't get them
+func (d *Docker) mountBuildCache(buildOptions *BuildOptions) error {
+	if len(buildOptions.BuildCacheMounts) == 0 {
+		return nil
+	}
+
+	dockerfilePath := buildOptions.DockerfileInfo.DockerfilePath
+
+	dockerfileContents, err := ioutil.ReadFile(dockerfilePath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read Dockerfile %s", dockerfilePath)
+	}
+
+	dockerfileContents = []byte(addBuildCacheMountsToDockerfile(string(dockerfileContents),
+		buildOptions.BuildCacheMounts))
+
+	if err := ioutil.WriteFile(dockerfilePath, dockerfileContents, 0644); err != nil {
+		return errors.Wrapf(err, "Failed to write Dockerfile %s", dockerfilePath)
+	}
+
+	d.logger.DebugWith("Mounted build cache", "buildCacheMounts", buildOptions.BuildCacheMounts)
+
+	return nil
+}
+
 func (d *Docker) TransformOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) (map[string]string, error) {
 
 	// maps between a _relative_ path in staging to the path in the image
@@ -105,27 +227,83 @@ func (d *Docker) buildContainerImage(buildOptions *BuildOptions) error {
 	d.logger.InfoWith("Building docker image", "image", buildOptions.Image)
 
 	return d.dockerClient.Build(&dockerclient.BuildOptions{
-		ContextDir:     buildOptions.ContextDir,
-		Image:          buildOptions.Image,
-		DockerfilePath: buildOptions.DockerfileInfo.DockerfilePath,
-		NoCache:        buildOptions.NoCache,
-		BuildArgs:      buildOptions.BuildArgs,
+		ContextDir:        buildOptions.ContextDir,
+		Image:             buildOptions.Image,
+		DockerfilePath:    buildOptions.DockerfileInfo.DockerfilePath,
+		NoCache:           buildOptions.NoCache,
+		CacheFrom:         buildOptions.CacheFrom,
+		Network:           buildOptions.Network,
+		BuildArgs:         buildOptions.BuildArgs,
+		Labels:            buildOptions.Labels,
+		OutputLineHandler: buildOptions.OutputLineHandler,
+		Secrets:           getDockerBuildSecrets(buildOptions.BuildSecrets),
+		BuildKit:          isBuildKitRequired(buildOptions),
+		OS:                buildOptions.OS,
 	})
 
 }
 
+// isBuildKitRequired returns whether the Dockerfile's RUN instructions mount anything, which requires BuildKit
+func isBuildKitRequired(buildOptions *BuildOptions) bool {
+	return len(buildOptions.BuildCacheMounts) > 0 ||
+		len(buildOptions.BuildSecrets) > 0 ||
+		buildOptions.BuildContextMounted
+}
+
+// getDockerBuildSecrets returns the secrets as values of docker build's --secret option. a secret several
+// hook stages mount is given once
+func getDockerBuildSecrets(buildSecrets []functionconfig.BuildSecret) []string {
+	var dockerBuildSecrets []string
+
+	for _, buildSecret := range buildSecrets {
+		dockerBuildSecret := fmt.Sprintf("id=%s,src=%s", buildSecret.ID, buildSecret.Src)
+		if buildSecret.Env != "" {
+			dockerBuildSecret = fmt.Sprintf("id=%s,env=%s", buildSecret.ID, buildSecret.Env)
+		}
+
+		if !common.StringInSlice(dockerBuildSecret, dockerBuildSecrets) {
+			dockerBuildSecrets = append(dockerBuildSecrets, dockerBuildSecret)
+		}
+	}
+
+	return dockerBuildSecrets
+}
+
 func (d *Docker) pushContainerImage(image string, registryURL string) error {
 	d.logger.InfoWith("Pushing docker image into registry",
 		"image", image,
 		"registry", registryURL)
 
 	if registryURL != "" {
+		if err := d.logInToRegistry(registryURL); err != nil {
+			return errors.Wrap(err, "Failed to log in to registry")
+		}
+
 		return d.dockerClient.PushImage(image, registryURL)
 	}
 
 	return nil
 }
 
+// logInToRegistry logs in to the registry with credentials of the registry auth provider, if one is configured.
+// the provider caches the credentials, so this only hits the cloud provider once they're about to expire
+func (d *Docker) logInToRegistry(registryURL string) error {
+	if d.registryAuthProvider == nil {
+		return nil
+	}
+
+	credentials, err := d.registryAuthProvider.GetCredentials(registryURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get registry credentials")
+	}
+
+	return d.dockerClient.LogIn(&dockerclient.LogInOptions{
+		URL:      dockercreds.GetRegistryHost(registryURL),
+		Username: credentials.Username,
+		Password: credentials.Password,
+	})
+}
+
 func (d *Docker) saveContainerImage(buildOptions *BuildOptions) error {
 	if buildOptions.OutputImageFile != "" {
 		d.logger.InfoWith("Archiving built docker image", "OutputImageFile", buildOptions.OutputImageFile)
@@ -162,7 +340,9 @@ func (d *Docker) gatherArtifactsForSingleStageDockerfile(buildOptions *BuildOpti
 
 		// to facilitate good ux, pull images that we're going to need (and log it) before copying
 		// objects from them. this also prevents docker spewing out errors about an image not existing
-		if err := d.ensureImagesExist(buildOptions, []string{onbuildArtifact.Image}); err != nil {
+		if err := buildOptions.PhaseTimings.Measure(common.PhaseBaseImagePull, func() error {
+			return d.ensureImagesExist(buildOptions, []string{onbuildArtifact.Image})
+		}); err != nil {
 			return errors.Wrap(err, "Failed to ensure required images exist")
 		}
 
@@ -171,23 +351,30 @@ func (d *Docker) gatherArtifactsForSingleStageDockerfile(buildOptions *BuildOpti
 			onbuildArtifactPaths[source] = path.Join(artifactsDir, path.Base(source))
 		}
 
-		if onbuildArtifact.ExternalImage {
+		err := buildOptions.PhaseTimings.Measure(common.PhaseOnbuild, func() error {
+			if onbuildArtifact.ExternalImage {
 
-			// For existing images - just copy the artifacts
-			err := d.dockerClient.CopyObjectsFromImage(onbuildArtifact.Image, onbuildArtifactPaths, false)
-			if err != nil {
-				return errors.Wrap(err, "Failed to copy artifact from external image")
+				// For existing images - just copy the artifacts
+				err := d.dockerClient.CopyObjectsFromImage(onbuildArtifact.Image, onbuildArtifactPaths, false)
+				if err != nil {
+					return errors.Wrap(err, "Failed to copy artifact from external image")
+				}
 			}
-		}
 
-		// build an image to trigger the onbuild stuff. then extract the artifacts
-		err := d.buildFromAndCopyObjectsFromContainer(onbuildArtifact.Image,
-			buildOptions.ContextDir,
-			onbuildArtifactPaths,
-			buildOptions.BuildArgs)
+			// build an image to trigger the onbuild stuff. then extract the artifacts
+			err := d.buildFromAndCopyObjectsFromContainer(onbuildArtifact.Image,
+				buildOptions.ContextDir,
+				onbuildArtifactPaths,
+				buildOptions.BuildArgs)
+
+			if err != nil {
+				return errors.Wrap(err, "Failed to copy objects from onbuild")
+			}
 
+			return nil
+		})
 		if err != nil {
-			return errors.Wrap(err, "Failed to copy objects from onbuild")
+			return err
 		}
 	}
 
@@ -12,10 +12,13 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher/registryauth"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/rs/xid"
 	batch_v1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,6 +30,7 @@ type Kaniko struct {
 	logger               logger.Logger
 	builderConfiguration *ContainerBuilderConfiguration
 	jobNameRegex         *regexp.Regexp
+	registryAuthProvider registryauth.Provider
 }
 
 func NewKaniko(logger logger.Logger, kubeClientSet kubernetes.Interface,
@@ -43,18 +47,59 @@ func NewKaniko(logger logger.Logger, kubeClientSet kubernetes.Interface,
 		return nil, errors.New("Failed to compile job name regex")
 	}
 
+	registryAuthProvider, err := registryauth.NewProvider(logger,
+		registryauth.Kind(builderConfiguration.RegistryAuthProvider))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry auth provider")
+	}
+
 	kanikoBuilder := &Kaniko{
 		logger:               logger,
 		kubeClientSet:        kubeClientSet,
 		builderConfiguration: builderConfiguration,
 		jobNameRegex:         jobNameRegex,
+		registryAuthProvider: registryAuthProvider,
 	}
 
 	return kanikoBuilder, nil
 }
 
 func (k *Kaniko) BuildAndPushContainerImage(buildOptions *BuildOptions, namespace string) error {
-	bundleFilename, assetPath, err := k.createContainerBuildBundle(buildOptions.Image, buildOptions.ContextDir, buildOptions.TempDir)
+	var bundleFilename, assetPath string
+
+	// the kaniko version used can't build for platforms other than the node's, nor push manifest lists
+	if len(buildOptions.Platforms) > 0 {
+		return errors.New("Multi-platform builds aren't supported by the kaniko builder")
+	}
+
+	// kaniko runs in linux pods, and can only build linux images
+	if buildOptions.OS == functionconfig.BuildOSWindows {
+		return errors.New("Windows images aren't supported by the kaniko builder")
+	}
+
+	// the onbuild artifacts are copied from stages of the build, so the context is ready as is
+	if buildOptions.ContextOnly {
+		return nil
+	}
+
+	// kaniko doesn't support BuildKit's secret mounts
+	if len(buildOptions.BuildSecrets) > 0 {
+		return errors.New("Build secrets are only supported by the docker builder")
+	}
+
+	// nor its bind mounts, which offline builds resolve packages from the build cache through
+	if buildOptions.BuildContextMounted {
+		return errors.New("Offline builds with a build cache dir are only supported by the docker builder")
+	}
+
+	err := buildOptions.PhaseTimings.Measure(common.PhaseContextArchiving, func() error {
+		var err error
+
+		bundleFilename, assetPath, err = k.createContainerBuildBundle(buildOptions.Image,
+			buildOptions.ContextDir,
+			buildOptions.TempDir)
+		return err
+	})
 	if err != nil {
 		return errors.Wrap(err, "Failed to create container build bundle")
 	}
@@ -62,6 +107,23 @@ func (k *Kaniko) BuildAndPushContainerImage(buildOptions *BuildOptions, namespac
 	// Remove bundle file from NGINX assets once we are done
 	defer os.Remove(assetPath) // nolint: errcheck
 
+	// with a registry auth provider, kaniko pushes with fresh credentials held in a secret created for the build,
+	// rather than with those of a (possibly expired) registry credentials secret
+	if k.registryAuthProvider != nil {
+		secretName, err := k.createRegistryCredentialsSecret(namespace, buildOptions.RegistryURL)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create registry credentials secret")
+		}
+
+		defer k.deleteSecret(namespace, secretName) // nolint: errcheck
+
+		buildOptions.SecretName = secretName
+	}
+
+	if len(buildOptions.BuildCacheMounts) > 0 && k.builderConfiguration.BuildCachePVC == "" {
+		k.logger.WarnWith("Build cache requested, but no build cache PVC is configured - building without it")
+	}
+
 	// Generate kaniko job spec
 	kanikoJobSpec := k.getKanikoJobSpec(namespace, buildOptions, bundleFilename)
 
@@ -74,34 +136,14 @@ func (k *Kaniko) BuildAndPushContainerImage(buildOptions *BuildOptions, namespac
 	// Cleanup
 	defer k.deleteJob(namespace, kanikoJob.Name) // nolint: errcheck
 
-	// Wait for kaniko to finish
-	return k.waitForKanikoJobCompletion(namespace, kanikoJob.Name, buildOptions.BuildTimeoutSeconds)
+	// Wait for kaniko to finish. kaniko pushes the image as part of the job, so the push isn't measured separately
+	return buildOptions.PhaseTimings.Measure(common.PhaseImageBuild, func() error {
+		return k.waitForKanikoJobCompletion(namespace, kanikoJob.Name, buildOptions.BuildTimeoutSeconds)
+	})
 }
 
 func (k *Kaniko) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
-	onbuildStages := make([]string, len(onbuildArtifacts))
-	stage := 0
-
-	for _, artifact := range onbuildArtifacts {
-		if artifact.ExternalImage {
-			continue
-		}
-
-		stage++
-		if len(artifact.Name) == 0 {
-			artifact.Name = fmt.Sprintf("onbuildStage-%d", stage)
-		}
-
-		baseImage := fmt.Sprintf("FROM %s AS %s", artifact.Image, artifact.Name)
-		onbuildDockerfileContents := fmt.Sprintf(`%s
-ARG NUCLIO_LABEL
-ARG NUCLIO_ARCH
-`, baseImage)
-
-		onbuildStages = append(onbuildStages, onbuildDockerfileContents)
-	}
-
-	return onbuildStages, nil
+	return GetMultiStageOnbuildStages(onbuildArtifacts), nil
 }
 
 func (k *Kaniko) GetDefaultRegistryCredentialsSecretName() string {
@@ -109,25 +151,7 @@ func (k *Kaniko) GetDefaultRegistryCredentialsSecretName() string {
 }
 
 func (k *Kaniko) TransformOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) (map[string]string, error) {
-
-	stagedArtifactPaths := make(map[string]string)
-	for _, artifact := range onbuildArtifacts {
-		for source, destination := range artifact.Paths {
-			var transformedSource string
-			if artifact.ExternalImage {
-
-				// Using external image as "stage"
-				// Example: COPY --from=nginx:latest /etc/nginx/nginx.conf /nginx.conf
-				transformedSource = fmt.Sprintf("--from=%s %s", artifact.Image, source)
-			} else {
-
-				// Using previously build image with index `artifactIndex` as "stage"
-				transformedSource = fmt.Sprintf("--from=%s %s", artifact.Name, source)
-			}
-			stagedArtifactPaths[transformedSource] = destination
-		}
-	}
-	return stagedArtifactPaths, nil
+	return TransformMultiStageOnbuildArtifactPaths(onbuildArtifacts), nil
 }
 
 func (k *Kaniko) GetBaseImageRegistry(registry string) string {
@@ -301,9 +325,88 @@ func (k *Kaniko) getKanikoJobSpec(namespace string, buildOptions *BuildOptions,
 		})
 	}
 
+	// mount the build cache from the PVC. the stages (including the onbuild ones) run in the executor's own
+	// filesystem, so they all get it, and kaniko leaves mounted directories out of the image
+	if len(buildOptions.BuildCacheMounts) > 0 && k.builderConfiguration.BuildCachePVC != "" {
+		for _, buildCacheMount := range buildOptions.BuildCacheMounts {
+			kanikoJobSpec.Spec.Template.Spec.Containers[0].VolumeMounts =
+				append(kanikoJobSpec.Spec.Template.Spec.Containers[0].VolumeMounts, v1.VolumeMount{
+					Name:      "build-cache",
+					MountPath: buildCacheMount.Path,
+					SubPath:   buildCacheMount.ID,
+				})
+		}
+
+		kanikoJobSpec.Spec.Template.Spec.Volumes = append(kanikoJobSpec.Spec.Template.Spec.Volumes, v1.Volume{
+			Name: "build-cache",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: k.builderConfiguration.BuildCachePVC,
+				},
+			},
+		})
+	}
+
 	return kanikoJobSpec
 }
 
+// PruneBuildCache empties the build cache PVC, through a job mounting it
+func (k *Kaniko) PruneBuildCache(namespace string) error {
+	if k.builderConfiguration.BuildCachePVC == "" {
+		return errors.New("No build cache PVC is configured")
+	}
+
+	completions := int32(1)
+	backoffLimit := int32(0)
+	jobName := fmt.Sprintf("%s-prune-build-cache-%s", k.builderConfiguration.JobPrefix, xid.New().String())
+
+	pruneJob, err := k.kubeClientSet.BatchV1().Jobs(namespace).Create(&batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      jobName,
+			Namespace: namespace,
+		},
+		Spec: batch_v1.JobSpec{
+			Completions:  &completions,
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:    "prune-build-cache",
+							Image:   k.builderConfiguration.BusyBoxImage,
+							Command: []string{"sh", "-c", "rm -rf /build-cache/* /build-cache/.[!.]*"},
+							VolumeMounts: []v1.VolumeMount{
+								{
+									Name:      "build-cache",
+									MountPath: "/build-cache",
+								},
+							},
+						},
+					},
+					Volumes: []v1.Volume{
+						{
+							Name: "build-cache",
+							VolumeSource: v1.VolumeSource{
+								PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+									ClaimName: k.builderConfiguration.BuildCachePVC,
+								},
+							},
+						},
+					},
+					RestartPolicy: v1.RestartPolicyNever,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to create build cache prune job")
+	}
+
+	defer k.deleteJob(namespace, pruneJob.Name) // nolint: errcheck
+
+	return k.waitForKanikoJobCompletion(namespace, pruneJob.Name, 5*60)
+}
+
 func (k *Kaniko) compileJobName(image string) string {
 
 	functionName := strings.Replace(image, "/", "", -1)
@@ -442,3 +545,41 @@ func (k *Kaniko) deleteJob(namespace string, jobName string) error {
 	}
 	return nil
 }
+
+// createRegistryCredentialsSecret creates a docker config secret with credentials of the registry auth provider
+func (k *Kaniko) createRegistryCredentialsSecret(namespace string, registryURL string) (string, error) {
+	credentials, err := k.registryAuthProvider.GetCredentials(registryURL)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get registry credentials")
+	}
+
+	dockerConfigJSON, err := registryauth.CreateDockerConfigJSON(registryURL, credentials)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create docker config")
+	}
+
+	secret, err := k.kubeClientSet.CoreV1().Secrets(namespace).Create(&v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-registry-auth-%s", k.builderConfiguration.JobPrefix, xid.New().String()),
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: dockerConfigJSON,
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create secret")
+	}
+
+	return secret.Name, nil
+}
+
+func (k *Kaniko) deleteSecret(namespace string, secretName string) error {
+	k.logger.DebugWith("Deleting registry credentials secret", "namespace", namespace, "secret", secretName)
+
+	if err := k.kubeClientSet.CoreV1().Secrets(namespace).Delete(secretName, &meta_v1.DeleteOptions{}); err != nil {
+		return errors.Wrap(err, "Failed to delete registry credentials secret")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package containerimagebuilderpusher
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+)
+
+// GetMultiStageOnbuildStages returns a Dockerfile stage for each onbuild artifact built from an image, so
+// that its artifacts are copied from the stage rather than gathered before the build
+func GetMultiStageOnbuildStages(onbuildArtifacts []runtime.Artifact) []string {
+	onbuildStages := make([]string, len(onbuildArtifacts))
+	stage := 0
+
+	for _, artifact := range onbuildArtifacts {
+		if artifact.ExternalImage {
+			continue
+		}
+
+		stage++
+		if len(artifact.Name) == 0 {
+			artifact.Name = fmt.Sprintf("onbuildStage-%d", stage)
+		}
+
+		baseImage := fmt.Sprintf("FROM %s AS %s", artifact.Image, artifact.Name)
+		onbuildDockerfileContents := fmt.Sprintf(`%s
+ARG NUCLIO_LABEL
+ARG NUCLIO_ARCH
+`, baseImage)
+
+		onbuildStages = append(onbuildStages, onbuildDockerfileContents)
+	}
+
+	return onbuildStages
+}
+
+// TransformMultiStageOnbuildArtifactPaths maps the onbuild artifacts' paths to COPY sources of the stages
+// returned by GetMultiStageOnbuildStages (or of the external images themselves)
+func TransformMultiStageOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) map[string]string {
+	stagedArtifactPaths := make(map[string]string)
+	for _, artifact := range onbuildArtifacts {
+		for source, destination := range artifact.Paths {
+			var transformedSource string
+			if artifact.ExternalImage {
+
+				// Using external image as "stage"
+				// Example: COPY --from=nginx:latest /etc/nginx/nginx.conf /nginx.conf
+				transformedSource = fmt.Sprintf("--from=%s %s", artifact.Image, source)
+			} else {
+
+				// Using previously build image with index `artifactIndex` as "stage"
+				transformedSource = fmt.Sprintf("--from=%s %s", artifact.Name, source)
+			}
+			stagedArtifactPaths[transformedSource] = destination
+		}
+	}
+	return stagedArtifactPaths
+}
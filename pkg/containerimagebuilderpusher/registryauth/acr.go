@@ -0,0 +1,136 @@
+package registryauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultAzureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureManagementResource  = "https://management.azure.com/"
+
+	// the username with which an ACR refresh token is passed to the registry
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+)
+
+// acrProvider obtains an AAD access token of the managed identity of the environment from the Azure
+// instance metadata service, and exchanges it with the registry for an ACR refresh token
+type acrProvider struct {
+	logger         logger.Logger
+	httpClient     *http.Client
+	imdsTokenURL   string
+	clientID       string
+	exchangeScheme string
+	now            func() time.Time
+}
+
+func newACRProvider(parentLogger logger.Logger) *acrProvider {
+	return &acrProvider{
+		logger:       parentLogger,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		imdsTokenURL: defaultAzureIMDSTokenURL,
+
+		// selects a user assigned identity, when there's more than one
+		clientID:       os.Getenv("AZURE_CLIENT_ID"),
+		exchangeScheme: "https",
+		now:            time.Now,
+	}
+}
+
+func (ap *acrProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	registryHost := dockercreds.GetRegistryHost(registryURL)
+
+	accessToken, expiresAt, err := ap.getAccessToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get access token of managed identity")
+	}
+
+	refreshToken, err := ap.exchangeAccessToken(registryHost, accessToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to exchange access token for an ACR refresh token")
+	}
+
+	return &Credentials{
+		Username:  acrUsername,
+		Password:  refreshToken,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (ap *acrProvider) getAccessToken() (string, time.Time, error) {
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", azureManagementResource)
+
+	if ap.clientID != "" {
+		query.Set("client_id", ap.clientID)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?%s", ap.imdsTokenURL, query.Encode()), nil)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "Failed to create metadata service request")
+	}
+
+	request.Header.Set("Metadata", "true")
+
+	// expires_on is given as a string of seconds since the epoch
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}{}
+
+	if err := doJSONRequest(ap.httpClient, request, &token); err != nil {
+		return "", time.Time{}, err
+	}
+
+	if token.AccessToken == "" {
+		return "", time.Time{}, errors.New("Metadata service returned no access token")
+	}
+
+	expiresAt := ap.now().Add(time.Hour)
+	if expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64); err == nil {
+		expiresAt = time.Unix(expiresOn, 0)
+	}
+
+	return token.AccessToken, expiresAt, nil
+}
+
+func (ap *acrProvider) exchangeAccessToken(registryHost string, accessToken string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", registryHost)
+	form.Set("access_token", accessToken)
+
+	request, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s://%s/oauth2/exchange", ap.exchangeScheme, registryHost),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create exchange request")
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{}
+
+	if err := doJSONRequest(ap.httpClient, request, &token); err != nil {
+		return "", err
+	}
+
+	if token.RefreshToken == "" {
+		return "", errors.New("Registry returned no refresh token")
+	}
+
+	return token.RefreshToken, nil
+}
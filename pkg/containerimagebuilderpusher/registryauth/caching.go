@@ -0,0 +1,60 @@
+package registryauth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/logger"
+)
+
+// credentials are refreshed this long before they expire, so that they don't expire mid-push
+const defaultRefreshMargin = 10 * time.Minute
+
+// cachingProvider caches the credentials of each registry, obtaining new ones once they're about to expire
+type cachingProvider struct {
+	logger        logger.Logger
+	provider      Provider
+	refreshMargin time.Duration
+	credentials   map[string]*Credentials
+	lock          sync.Mutex
+	now           func() time.Time
+}
+
+func newCachingProvider(parentLogger logger.Logger, provider Provider) *cachingProvider {
+	return &cachingProvider{
+		logger:        parentLogger,
+		provider:      provider,
+		refreshMargin: defaultRefreshMargin,
+		credentials:   map[string]*Credentials{},
+		now:           time.Now,
+	}
+}
+
+func (cp *cachingProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	registryHost := dockercreds.GetRegistryHost(registryURL)
+
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if credentials, found := cp.credentials[registryHost]; found {
+		if cp.now().Add(cp.refreshMargin).Before(credentials.ExpiresAt) {
+			return credentials, nil
+		}
+	}
+
+	credentials, err := cp.provider.GetCredentials(registryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.logger.DebugWith("Obtained registry credentials",
+		"registry", registryHost,
+		"username", credentials.Username,
+		"expiresAt", credentials.ExpiresAt)
+
+	cp.credentials[registryHost] = credentials
+
+	return credentials, nil
+}
@@ -0,0 +1,86 @@
+package registryauth
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// ecrProvider obtains an authorization token of ECR with the AWS credentials of the environment (e.g. the
+// IAM role of the node, or of the service account through IRSA). tokens are valid for 12 hours
+type ecrProvider struct {
+	logger       logger.Logger
+	newECRClient func(region string) (ecriface.ECRAPI, error)
+}
+
+func newECRProvider(parentLogger logger.Logger) *ecrProvider {
+	return &ecrProvider{
+		logger:       parentLogger,
+		newECRClient: newECRClient,
+	}
+}
+
+func (ep *ecrProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	registryHost := dockercreds.GetRegistryHost(registryURL)
+
+	matches := ecrRegistryHostRegex.FindStringSubmatch(registryHost)
+	if matches == nil {
+		return nil, errors.Errorf("Registry %s is not an ECR registry", registryURL)
+	}
+
+	registryID, region := matches[1], matches[3]
+
+	ecrClient, err := ep.newECRClient(region)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create ECR client")
+	}
+
+	output, err := ecrClient.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registryID)},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get ECR authorization token")
+	}
+
+	if len(output.AuthorizationData) == 0 || output.AuthorizationData[0].AuthorizationToken == nil {
+		return nil, errors.New("ECR returned no authorization token")
+	}
+
+	authorizationData := output.AuthorizationData[0]
+
+	// the token is the base64 of username:password
+	decodedToken, err := base64.StdEncoding.DecodeString(aws.StringValue(authorizationData.AuthorizationToken))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode ECR authorization token")
+	}
+
+	tokenParts := strings.SplitN(string(decodedToken), ":", 2)
+	if len(tokenParts) != 2 {
+		return nil, errors.New("ECR authorization token is malformed")
+	}
+
+	return &Credentials{
+		Username:  tokenParts[0],
+		Password:  tokenParts[1],
+		ExpiresAt: aws.TimeValue(authorizationData.ExpiresAt),
+	}, nil
+}
+
+func newECRClient(region string) (ecriface.ECRAPI, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	return ecr.New(sess), nil
+}
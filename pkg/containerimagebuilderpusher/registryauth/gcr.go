@@ -0,0 +1,92 @@
+package registryauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultGCEMetadataHost = "metadata.google.internal"
+
+	// the username with which an OAuth2 access token is passed to GCR and Artifact Registry
+	gcrUsername = "oauth2accesstoken"
+)
+
+// gcrProvider obtains an access token of the service account of the environment from the GCE metadata
+// server (e.g. the node's service account, or that bound to the pod through workload identity)
+type gcrProvider struct {
+	logger      logger.Logger
+	httpClient  *http.Client
+	metadataURL string
+	now         func() time.Time
+}
+
+func newGCRProvider(parentLogger logger.Logger) *gcrProvider {
+
+	// GCE_METADATA_HOST is the override honoured by Google's client libraries
+	metadataHost := os.Getenv("GCE_METADATA_HOST")
+	if metadataHost == "" {
+		metadataHost = defaultGCEMetadataHost
+	}
+
+	return &gcrProvider{
+		logger:      parentLogger,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		metadataURL: fmt.Sprintf("http://%s/computeMetadata/v1/instance/service-accounts/default/token", metadataHost),
+		now:         time.Now,
+	}
+}
+
+func (gp *gcrProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	request, err := http.NewRequest(http.MethodGet, gp.metadataURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create metadata server request")
+	}
+
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+
+	if err := doJSONRequest(gp.httpClient, request, &token); err != nil {
+		return nil, errors.Wrap(err, "Failed to get access token from metadata server")
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.New("Metadata server returned no access token")
+	}
+
+	return &Credentials{
+		Username:  gcrUsername,
+		Password:  token.AccessToken,
+		ExpiresAt: gp.now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// doJSONRequest performs the request, decoding the JSON body of a successful response into result
+func doJSONRequest(httpClient *http.Client, request *http.Request, result interface{}) error {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Got unexpected status code: %d", response.StatusCode)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "Failed to decode response")
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package registryauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type mockECRClient struct {
+	ecriface.ECRAPI
+	input *ecr.GetAuthorizationTokenInput
+}
+
+func (mec *mockECRClient) GetAuthorizationToken(input *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error) {
+	mec.input = input
+
+	return &ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []*ecr.AuthorizationData{
+			{
+				AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:ecr-password"))),
+				ExpiresAt:          aws.Time(time.Unix(1700000000, 0)),
+			},
+		},
+	}, nil
+}
+
+type mockProvider struct {
+	calls       int
+	credentials *Credentials
+}
+
+func (mp *mockProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	mp.calls++
+	return mp.credentials, nil
+}
+
+type registryAuthTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+	now    time.Time
+}
+
+func (suite *registryAuthTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.now = time.Unix(1600000000, 0)
+}
+
+func (suite *registryAuthTestSuite) TestDetectKind() {
+	for _, testCase := range []struct {
+		registryURL  string
+		expectedKind Kind
+	}{
+		{registryURL: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", expectedKind: KindECR},
+		{registryURL: "https://123456789012.dkr.ecr.us-east-1.amazonaws.com/my-repo", expectedKind: KindECR},
+		{registryURL: "gcr.io/my-project", expectedKind: KindGCR},
+		{registryURL: "eu.gcr.io/my-project", expectedKind: KindGCR},
+		{registryURL: "europe-west1-docker.pkg.dev/my-project/my-repo", expectedKind: KindGCR},
+		{registryURL: "myregistry.azurecr.io", expectedKind: KindACR},
+		{registryURL: "docker.io/iguazio", expectedKind: KindNone},
+		{registryURL: "registry.example.com/gcr.io", expectedKind: KindNone},
+	} {
+		suite.Require().Equal(testCase.expectedKind, DetectKind(testCase.registryURL), testCase.registryURL)
+	}
+}
+
+func (suite *registryAuthTestSuite) TestNewProvider() {
+	provider, err := NewProvider(suite.logger, KindNone)
+	suite.Require().NoError(err)
+	suite.Require().Nil(provider)
+
+	provider, err = NewProvider(suite.logger, "ECR")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(provider)
+
+	_, err = NewProvider(suite.logger, "quay")
+	suite.Require().Error(err)
+}
+
+func (suite *registryAuthTestSuite) TestECRProvider() {
+	mockClient := &mockECRClient{}
+
+	var clientRegion string
+	provider := newECRProvider(suite.logger)
+	provider.newECRClient = func(region string) (ecriface.ECRAPI, error) {
+		clientRegion = region
+		return mockClient, nil
+	}
+
+	credentials, err := provider.GetCredentials("123456789012.dkr.ecr.eu-west-1.amazonaws.com/my-repo")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Credentials{
+		Username:  "AWS",
+		Password:  "ecr-password",
+		ExpiresAt: time.Unix(1700000000, 0),
+	}, credentials)
+	suite.Require().Equal("eu-west-1", clientRegion)
+	suite.Require().Equal("123456789012", aws.StringValue(mockClient.input.RegistryIds[0]))
+
+	_, err = provider.GetCredentials("gcr.io/my-project")
+	suite.Require().Error(err)
+}
+
+func (suite *registryAuthTestSuite) TestGCRProvider() {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Metadata-Flavor") != "Google" {
+			responseWriter.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		responseWriter.Write([]byte(`{"access_token": "gcr-token", "expires_in": 3599, "token_type": "Bearer"}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	provider := newGCRProvider(suite.logger)
+	provider.metadataURL = server.URL
+	provider.now = func() time.Time { return suite.now }
+
+	credentials, err := provider.GetCredentials("gcr.io/my-project")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Credentials{
+		Username:  "oauth2accesstoken",
+		Password:  "gcr-token",
+		ExpiresAt: suite.now.Add(3599 * time.Second),
+	}, credentials)
+}
+
+func (suite *registryAuthTestSuite) TestACRProvider() {
+	var exchangeForm map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			suite.Require().Equal("true", request.Header.Get("Metadata"))
+			suite.Require().Equal("my-client-id", request.URL.Query().Get("client_id"))
+
+			responseWriter.Write([]byte(`{"access_token": "aad-token", "expires_on": "1600003600"}`)) // nolint: errcheck
+		case "/oauth2/exchange":
+			suite.Require().NoError(request.ParseForm())
+
+			exchangeForm = map[string]string{}
+			for key := range request.PostForm {
+				exchangeForm[key] = request.PostForm.Get(key)
+			}
+
+			responseWriter.Write([]byte(`{"refresh_token": "acr-refresh-token"}`)) // nolint: errcheck
+		default:
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registryHost := dockercreds.GetRegistryHost(server.URL)
+
+	provider := newACRProvider(suite.logger)
+	provider.imdsTokenURL = server.URL + "/metadata/identity/oauth2/token"
+	provider.clientID = "my-client-id"
+	provider.exchangeScheme = "http"
+
+	credentials, err := provider.GetCredentials(registryHost)
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Credentials{
+		Username:  "00000000-0000-0000-0000-000000000000",
+		Password:  "acr-refresh-token",
+		ExpiresAt: time.Unix(1600003600, 0),
+	}, credentials)
+	suite.Require().Equal(map[string]string{
+		"grant_type":   "access_token",
+		"service":      registryHost,
+		"access_token": "aad-token",
+	}, exchangeForm)
+}
+
+func (suite *registryAuthTestSuite) TestCachingProvider() {
+	provider := &mockProvider{
+		credentials: &Credentials{
+			Username:  "user",
+			Password:  "password",
+			ExpiresAt: suite.now.Add(time.Hour),
+		},
+	}
+
+	cachingProvider := newCachingProvider(suite.logger, provider)
+	cachingProvider.now = func() time.Time { return suite.now }
+
+	for _, registryURL := range []string{"gcr.io/my-project", "https://gcr.io/other-project"} {
+		credentials, err := cachingProvider.GetCredentials(registryURL)
+		suite.Require().NoError(err)
+		suite.Require().Equal("password", credentials.Password)
+	}
+
+	// both are of the same registry
+	suite.Require().Equal(1, provider.calls)
+
+	// credentials that are about to expire are refreshed
+	suite.now = suite.now.Add(time.Hour - defaultRefreshMargin)
+
+	_, err := cachingProvider.GetCredentials("gcr.io/my-project")
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, provider.calls)
+}
+
+func (suite *registryAuthTestSuite) TestCreateDockerConfigJSON() {
+	encodedDockerConfig, err := CreateDockerConfigJSON("https://gcr.io/my-project", &Credentials{
+		Username: "oauth2accesstoken",
+		Password: "gcr-token",
+	})
+	suite.Require().NoError(err)
+
+	dockerConfig := dockercreds.DockerConfig{}
+	suite.Require().NoError(json.Unmarshal(encodedDockerConfig, &dockerConfig))
+
+	expectedAuth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", "oauth2accesstoken", "gcr-token")))
+	suite.Require().Equal(expectedAuth, dockerConfig.Auths["gcr.io"].Auth)
+}
+
+func TestRegistryAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(registryAuthTestSuite))
+}
@@ -0,0 +1,127 @@
+package registryauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// Kind is the kind of registry a provider obtains credentials for
+type Kind string
+
+const (
+	KindNone Kind = ""
+	KindAuto Kind = "auto"
+	KindECR  Kind = "ecr"
+	KindGCR  Kind = "gcr"
+	KindACR  Kind = "acr"
+)
+
+var (
+	ecrRegistryHostRegex = regexp.MustCompile(`^(\d+)\.dkr\.ecr(-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+	gcrRegistryHostRegex = regexp.MustCompile(`^([a-z0-9-]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`)
+	acrRegistryHostRegex = regexp.MustCompile(`^[a-z0-9]+\.azurecr\.(io|cn|us)$`)
+)
+
+// Credentials are short-lived credentials of a registry
+type Credentials struct {
+	Username  string
+	Password  string
+	ExpiresAt time.Time
+}
+
+// Provider obtains credentials of a registry from the identity of the machine it runs on (e.g. an IAM role
+// or a workload identity), rather than from static credentials
+type Provider interface {
+
+	// GetCredentials returns credentials for the given registry
+	GetCredentials(registryURL string) (*Credentials, error)
+}
+
+// NewProvider creates a provider of the given kind, whose credentials are cached until shortly before
+// they expire. returns nil if no kind is given
+func NewProvider(parentLogger logger.Logger, kind Kind) (Provider, error) {
+	var provider Provider
+
+	switch Kind(strings.ToLower(string(kind))) {
+	case KindNone:
+		return nil, nil
+	case KindAuto:
+		provider = newAutoProvider(parentLogger)
+	case KindECR:
+		provider = newECRProvider(parentLogger)
+	case KindGCR:
+		provider = newGCRProvider(parentLogger)
+	case KindACR:
+		provider = newACRProvider(parentLogger)
+	default:
+		return nil, errors.Errorf("Unknown registry auth provider: %s (expected one of ecr, gcr, acr or auto)", kind)
+	}
+
+	return newCachingProvider(parentLogger, provider), nil
+}
+
+// DetectKind returns the kind of the given registry by its host, or KindNone if it isn't one of the
+// supported cloud registries
+func DetectKind(registryURL string) Kind {
+	registryHost := dockercreds.GetRegistryHost(registryURL)
+
+	switch {
+	case ecrRegistryHostRegex.MatchString(registryHost):
+		return KindECR
+	case gcrRegistryHostRegex.MatchString(registryHost):
+		return KindGCR
+	case acrRegistryHostRegex.MatchString(registryHost):
+		return KindACR
+	}
+
+	return KindNone
+}
+
+// CreateDockerConfigJSON returns a docker config.json holding the given credentials of the registry, as
+// used by kaniko and by kubernetes secrets of type kubernetes.io/dockerconfigjson
+func CreateDockerConfigJSON(registryURL string, credentials *Credentials) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", credentials.Username, credentials.Password)))
+
+	return json.Marshal(&dockercreds.DockerConfig{
+		Auths: map[string]dockercreds.DockerConfigAuth{
+			dockercreds.GetRegistryHost(registryURL): {
+				Auth: auth,
+			},
+		},
+	})
+}
+
+// autoProvider delegates to the provider of the registry's kind, as detected by its host
+type autoProvider struct {
+	logger    logger.Logger
+	providers map[Kind]Provider
+}
+
+func newAutoProvider(parentLogger logger.Logger) *autoProvider {
+	return &autoProvider{
+		logger: parentLogger,
+		providers: map[Kind]Provider{
+			KindECR: newECRProvider(parentLogger),
+			KindGCR: newGCRProvider(parentLogger),
+			KindACR: newACRProvider(parentLogger),
+		},
+	}
+}
+
+func (ap *autoProvider) GetCredentials(registryURL string) (*Credentials, error) {
+	kind := DetectKind(registryURL)
+	if kind == KindNone {
+		return nil, errors.Errorf("Failed to detect the kind of registry %s", registryURL)
+	}
+
+	return ap.providers[kind].GetCredentials(registryURL)
+}
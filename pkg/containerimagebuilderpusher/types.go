@@ -1,6 +1,10 @@
 package containerimagebuilderpusher
 
-import "github.com/nuclio/nuclio/pkg/processor/build/runtime"
+import (
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+)
 
 // BuildOptions are options for building a container image
 type BuildOptions struct {
@@ -9,12 +13,33 @@ type BuildOptions struct {
 	TempDir             string
 	DockerfileInfo      *runtime.ProcessorDockerfileInfo
 	NoCache             bool
+	CacheFrom           []string
+	Network             string
+	Platforms           []string
+	OS                  string
 	NoBaseImagePull     bool
 	BuildArgs           map[string]string
+	Labels              map[string]string
 	RegistryURL         string
 	SecretName          string
 	OutputImageFile     string
 	BuildTimeoutSeconds int64
+	OutputLineHandler   func(line string)
+	PhaseTimings        *common.PhaseTimings
+
+	// persistent package manager caches to mount into the build, if the builder supports it
+	BuildCacheMounts []BuildCacheMount
+
+	// secrets the Dockerfile's RUN instructions mount (BuildKit secret mounts), if the builder supports it
+	BuildSecrets []functionconfig.BuildSecret
+
+	// if set, the Dockerfile's RUN instructions bind-mount parts of the build context (BuildKit bind mounts),
+	// if the builder supports it
+	BuildContextMounted bool
+
+	// if set, only the build context is prepared (e.g. onbuild artifacts gathered into it) - nothing is
+	// built or pushed, so that the image can be built externally
+	ContextOnly bool
 }
 
 type ContainerBuilderConfiguration struct {
@@ -29,4 +54,11 @@ type ContainerBuilderConfiguration struct {
 	CacheRepo                            string
 	InsecurePushRegistry                 bool
 	InsecurePullRegistry                 bool
+
+	// BuildCachePVC is the persistent volume claim kaniko builds mount package manager caches from
+	BuildCachePVC string
+
+	// RegistryAuthProvider obtains short-lived registry credentials from the environment's identity
+	// (one of ecr, gcr, acr or auto) instead of using a registry credentials secret
+	RegistryAuthProvider string
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/restful"
 
+	"github.com/go-chi/chi"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -148,7 +149,10 @@ func (fr *functionResource) Create(request *http.Request) (id string, attributes
 	waitForFunction := request.Header.Get("x-nuclio-wait-function-action") == "true"
 
 	// validation finished successfully - store and deploy the given function
-	if responseErr = fr.storeAndDeployFunction(functionInfo, authConfig, waitForFunction); responseErr != nil {
+	if responseErr = fr.storeAndDeployFunction(functionInfo,
+		authConfig,
+		fr.getRequestIdentity(request),
+		waitForFunction); responseErr != nil {
 		return
 	}
 
@@ -191,7 +195,10 @@ func (fr *functionResource) Update(request *http.Request, id string) (attributes
 
 	waitForFunction := request.Header.Get("x-nuclio-wait-function-action") == "true"
 
-	if responseErr = fr.storeAndDeployFunction(functionInfo, authConfig, waitForFunction); responseErr != nil {
+	if responseErr = fr.storeAndDeployFunction(functionInfo,
+		authConfig,
+		fr.getRequestIdentity(request),
+		waitForFunction); responseErr != nil {
 		return
 	}
 
@@ -209,6 +216,16 @@ func (fr *functionResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
 			Method:    http.MethodDelete,
 			RouteFunc: fr.deleteFunction,
 		},
+		{
+			Pattern:   "/{id}/pause",
+			Method:    http.MethodPost,
+			RouteFunc: fr.pauseFunction,
+		},
+		{
+			Pattern:   "/{id}/resume",
+			Method:    http.MethodPost,
+			RouteFunc: fr.resumeFunction,
+		},
 	}, nil
 }
 
@@ -228,7 +245,10 @@ func (fr *functionResource) export(function platform.Function) restful.Attribute
 	return attributes
 }
 
-func (fr *functionResource) storeAndDeployFunction(functionInfo *functionInfo, authConfig *platform.AuthConfig, waitForFunction bool) error {
+func (fr *functionResource) storeAndDeployFunction(functionInfo *functionInfo,
+	authConfig *platform.AuthConfig,
+	identity *platform.Identity,
+	waitForFunction bool) error {
 
 	creationStateUpdatedTimeout := 45 * time.Second
 
@@ -274,8 +294,12 @@ func (fr *functionResource) storeAndDeployFunction(functionInfo *functionInfo, a
 			CreationStateUpdated:       creationStateUpdatedChan,
 			AuthConfig:                 authConfig,
 			DependantImagesRegistryURL: fr.GetServer().(*dashboard.Server).GetDependantImagesRegistryURL(),
+			Identity:                   identity,
 		})
 
+		// the function is built from an uploaded source once, and redeployed from its image
+		removeUploadedFunctionSource(functionInfo.Spec.Build.Path)
+
 		if err != nil {
 			fr.Logger.WarnWith("Failed to deploy function", "err", err)
 			errDeployingChan <- err
@@ -337,6 +361,7 @@ func (fr *functionResource) deleteFunction(request *http.Request) (*restful.Cust
 
 	deleteFunctionOptions := platform.DeleteFunctionOptions{
 		AuthConfig: authConfig,
+		Identity:   fr.getRequestIdentity(request),
 	}
 
 	deleteFunctionOptions.FunctionConfig.Meta = *functionInfo.Meta
@@ -356,6 +381,57 @@ func (fr *functionResource) deleteFunction(request *http.Request) (*restful.Cust
 	}, err
 }
 
+func (fr *functionResource) pauseFunction(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	return fr.setFunctionPaused(request, true)
+}
+
+func (fr *functionResource) resumeFunction(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	return fr.setFunctionPaused(request, false)
+}
+
+// setFunctionPaused pauses or resumes the function whose name is in the path, in the namespace of the
+// x-nuclio-function-namespace header
+func (fr *functionResource) setFunctionPaused(request *http.Request, pause bool) (*restful.CustomRouteFuncResponse, error) {
+	authConfig, err := fr.getRequestAuthConfig(request)
+	if err != nil {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusInternalServerError,
+		}, err
+	}
+
+	functionName := chi.URLParam(request, "id")
+	functionNamespace := fr.getNamespaceFromRequest(request)
+
+	if pause {
+		err = fr.getPlatform().PauseFunction(&platform.PauseFunctionOptions{
+			Name:       functionName,
+			Namespace:  functionNamespace,
+			AuthConfig: authConfig,
+		})
+	} else {
+		err = fr.getPlatform().ResumeFunction(&platform.ResumeFunctionOptions{
+			Name:       functionName,
+			Namespace:  functionNamespace,
+			AuthConfig: authConfig,
+		})
+	}
+
+	// errors with a status code (e.g. the function wasn't found) are returned with it
+	if err != nil {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusInternalServerError,
+		}, err
+	}
+
+	return &restful.CustomRouteFuncResponse{
+		ResourceType: "function",
+		Single:       true,
+		StatusCode:   http.StatusNoContent,
+	}, nil
+}
+
 func (fr *functionResource) functionToAttributes(function platform.Function) restful.Attributes {
 	functionConfig := function.GetConfig()
 	functionConfig.CleanFunctionSpec()
@@ -0,0 +1,132 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/nuclio/nuclio/pkg/dashboard"
+	"github.com/nuclio/nuclio/pkg/restful"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/satori/go.uuid"
+)
+
+// the largest function source archive which may be uploaded
+const maxFunctionSourceSize = 512 * 1024 * 1024
+
+// function sources uploaded for remote builds (e.g. nuctl deploy --build-remotely) are kept here until the
+// function is built from them
+var functionSourcesDir = path.Join(os.TempDir(), "nuclio-function-sources")
+
+type functionSourceResource struct {
+	*resource
+}
+
+// returns a list of custom routes for the resource
+func (fsr *functionSourceResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
+	return []restful.CustomRoute{
+		{
+			Pattern:   "/",
+			Method:    http.MethodPost,
+			RouteFunc: fsr.uploadFunctionSource,
+		},
+	}, nil
+}
+
+// uploadFunctionSource stores the archive in the body, named by the x-nuclio-source-file-name header (whose
+// extension identifies the kind of archive), and responds with the path functions are built from it with
+// (spec.build.path)
+func (fsr *functionSourceResource) uploadFunctionSource(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	sourceFileName := path.Base(request.Header.Get("x-nuclio-source-file-name"))
+	if sourceFileName == "." || sourceFileName == "/" {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusBadRequest,
+		}, nuclio.NewErrBadRequest("Source file name must be provided (x-nuclio-source-file-name)")
+	}
+
+	if err := os.MkdirAll(functionSourcesDir, 0755); err != nil {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusInternalServerError,
+		}, errors.Wrap(err, "Failed to create function sources directory")
+	}
+
+	sourcePath := filepath.Join(functionSourcesDir, fmt.Sprintf("%s-%s", uuid.NewV4().String(), sourceFileName))
+
+	if err := fsr.writeFunctionSource(sourcePath, http.MaxBytesReader(nil, request.Body, maxFunctionSourceSize)); err != nil {
+		os.Remove(sourcePath) // nolint: errcheck
+
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusBadRequest,
+		}, nuclio.WrapErrBadRequest(errors.Wrap(err, "Failed to store function source"))
+	}
+
+	fsr.Logger.DebugWith("Stored function source", "path", sourcePath)
+
+	return &restful.CustomRouteFuncResponse{
+		ResourceType: "functionSource",
+		Resources: map[string]restful.Attributes{
+			"functionSource": {
+				"path": sourcePath,
+			},
+		},
+		Single:     true,
+		StatusCode: http.StatusCreated,
+	}, nil
+}
+
+func (fsr *functionSourceResource) writeFunctionSource(sourcePath string, reader io.Reader) error {
+	sourceFile, err := os.Create(sourcePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create file")
+	}
+
+	if _, err := io.Copy(sourceFile, reader); err != nil {
+		sourceFile.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to write file")
+	}
+
+	return sourceFile.Close()
+}
+
+// removeUploadedFunctionSource removes a function source which was uploaded to be built from, once it has been
+func removeUploadedFunctionSource(sourcePath string) {
+	if sourcePath == "" || filepath.Dir(sourcePath) != functionSourcesDir {
+		return
+	}
+
+	os.Remove(sourcePath) // nolint: errcheck
+}
+
+// register the resource
+var functionSourceResourceInstance = &functionSourceResource{
+	resource: newResource("api/function_sources", []restful.ResourceMethod{}),
+}
+
+func init() {
+	functionSourceResourceInstance.Resource = functionSourceResourceInstance
+	functionSourceResourceInstance.Register(dashboard.DashboardResourceRegistrySingleton)
+}
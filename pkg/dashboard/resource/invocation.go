@@ -82,6 +82,7 @@ func (tr *invocationResource) handleRequest(responseWriter http.ResponseWriter,
 		Headers:   request.Header,
 		Body:      requestBody,
 		Via:       invokeVia,
+		Identity:  tr.getRequestIdentity(request),
 	})
 
 	if err != nil {
@@ -21,10 +21,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
-	"sync"
 
 	"github.com/nuclio/nuclio/pkg/dashboard"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/restful"
 
@@ -132,7 +133,7 @@ func (pr *projectResource) Create(request *http.Request) (id string, attributes
 			return "", nil, responseErr
 		}
 
-		return pr.importProject(projectImportInfo, authConfig)
+		return pr.importProject(projectImportInfo, authConfig, pr.getRequestIdentity(request))
 	}
 
 	projectInfo, responseErr := pr.getProjectInfoFromRequest(request, true)
@@ -247,8 +248,9 @@ func (pr *projectResource) createProject(projectInfoInstance *projectInfo) (id s
 	return
 }
 
-func (pr *projectResource) importProject(projectImportInfoInstance *projectImportInfo, authConfig *platform.AuthConfig) (id string,
-	attributes restful.Attributes, responseErr error) {
+func (pr *projectResource) importProject(projectImportInfoInstance *projectImportInfo,
+	authConfig *platform.AuthConfig,
+	identity *platform.Identity) (id string, attributes restful.Attributes, responseErr error) {
 
 	pr.Logger.InfoWith("Importing project", "projectName", projectImportInfoInstance.Project.Meta.Name)
 	pr.Logger.DebugWith("Checking if project exists", "projectName", projectImportInfoInstance.Project.Meta.Name)
@@ -276,7 +278,7 @@ func (pr *projectResource) importProject(projectImportInfoInstance *projectImpor
 		}
 	}
 
-	failedFunctions := pr.importProjectFunctions(projectImportInfoInstance, authConfig)
+	failedFunctions := pr.importProjectFunctions(projectImportInfoInstance, authConfig, identity)
 	failedFunctionEvents := pr.importProjectFunctionEvents(projectImportInfoInstance, failedFunctions)
 
 	attributes = restful.Attributes{
@@ -296,51 +298,67 @@ func (pr *projectResource) importProject(projectImportInfoInstance *projectImpor
 }
 
 func (pr *projectResource) importProjectFunctions(projectImportInfoInstance *projectImportInfo,
-	authConfig *platform.AuthConfig) []restful.Attributes {
+	authConfig *platform.AuthConfig,
+	identity *platform.Identity) []restful.Attributes {
 
 	pr.Logger.InfoWith("Importing project functions", "project", projectImportInfoInstance.Project.Meta.Name)
 
-	functionCreateChan := make(chan restful.Attributes, len(projectImportInfoInstance.Functions))
-	var functionCreateWaitGroup sync.WaitGroup
-	functionCreateWaitGroup.Add(len(projectImportInfoInstance.Functions))
+	var functionNames []string
+	for functionName := range projectImportInfoInstance.Functions {
+		functionNames = append(functionNames, functionName)
+	}
+	sort.Strings(functionNames)
 
-	var failedFunctions []restful.Attributes
-	for functionName, function := range projectImportInfoInstance.Functions {
-		go func(functionName string, function *functionInfo, wg *sync.WaitGroup) {
-			function.Meta.Namespace = projectImportInfoInstance.Project.Meta.Namespace
-			if function.Meta.Labels == nil {
-				function.Meta.Labels = map[string]string{}
-			}
-			function.Meta.Labels["nuclio.io/project-name"] = projectImportInfoInstance.Project.Meta.Name
-
-			if err := pr.importFunction(function, authConfig); err != nil {
-				pr.Logger.WarnWith("Failed importing function upon project import ",
-					"functionName", functionName,
-					"err", err,
-					"projectName", projectImportInfoInstance.Project.Meta.Name)
-				functionCreateChan <- restful.Attributes{
-					"function": functionName,
-					"error":    err.Error(),
-				}
-			}
+	var functionConfigs []*functionconfig.Config
+	functionsByName := map[string]*functionInfo{}
+	for _, functionName := range functionNames {
+		function := projectImportInfoInstance.Functions[functionName]
+		function.Meta.Namespace = projectImportInfoInstance.Project.Meta.Namespace
+		if function.Meta.Labels == nil {
+			function.Meta.Labels = map[string]string{}
+		}
+		function.Meta.Labels["nuclio.io/project-name"] = projectImportInfoInstance.Project.Meta.Name
 
-			wg.Done()
-		}(functionName, function, &functionCreateWaitGroup)
+		functionsByName[function.Meta.Name] = function
+		functionConfigs = append(functionConfigs, &functionconfig.Config{
+			Meta: *function.Meta,
+			Spec: *function.Spec,
+		})
 	}
 
-	functionCreateWaitGroup.Wait()
-	close(functionCreateChan)
+	// all the functions are imported at once, but those depending on others (spec.dependsOn) only once their
+	// dependencies are ready
+	importErrs, err := platform.NewDependencyScheduler(pr.Logger,
+		pr.getPlatform(),
+		len(functionConfigs)).Run(functionConfigs, func(functionConfig *functionconfig.Config) error {
+		return pr.importFunction(functionsByName[functionConfig.Meta.Name], authConfig, identity)
+	})
 
-	for creationError := range functionCreateChan {
-		if creationError != nil {
-			failedFunctions = append(failedFunctions, creationError)
+	var failedFunctions []restful.Attributes
+	for functionIndex, functionName := range functionNames {
+		importErr := err
+		if importErrs != nil {
+			importErr = importErrs[functionIndex]
+		}
+
+		if importErr != nil {
+			pr.Logger.WarnWith("Failed importing function upon project import ",
+				"functionName", functionName,
+				"err", importErr,
+				"projectName", projectImportInfoInstance.Project.Meta.Name)
+			failedFunctions = append(failedFunctions, restful.Attributes{
+				"function": functionName,
+				"error":    importErr.Error(),
+			})
 		}
 	}
 
 	return failedFunctions
 }
 
-func (pr *projectResource) importFunction(function *functionInfo, authConfig *platform.AuthConfig) error {
+func (pr *projectResource) importFunction(function *functionInfo,
+	authConfig *platform.AuthConfig,
+	identity *platform.Identity) error {
 	pr.Logger.InfoWith("Importing project function",
 		"function", function.Meta.Name,
 		"project", function.Meta.Labels["nuclio.io/project-name"])
@@ -356,7 +374,7 @@ func (pr *projectResource) importFunction(function *functionInfo, authConfig *pl
 	}
 
 	// validation finished successfully - store and deploy the given function
-	err = functionResourceInstance.storeAndDeployFunction(function, authConfig, false)
+	err = functionResourceInstance.storeAndDeployFunction(function, authConfig, identity, false)
 	if err != nil {
 		return err
 	}
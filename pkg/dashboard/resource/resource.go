@@ -28,6 +28,12 @@ import (
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// the header authenticating proxies forward the user in, and the user recorded if none is forwarded
+const (
+	remoteUserHeader = "X-Remote-User"
+	anonymousUser    = "anonymous"
+)
+
 type resource struct {
 	*restful.AbstractResource
 	defaultNamespace string
@@ -76,6 +82,20 @@ func (r *resource) getRequestAuthConfig(request *http.Request) (*platform.AuthCo
 	return nil, nil
 }
 
+// getRequestIdentity returns who performs the request, to be recorded in the audit log. the dashboard doesn't
+// authenticate users itself, so it trusts the user the authenticating proxy in front of it forwards
+func (r *resource) getRequestIdentity(request *http.Request) *platform.Identity {
+	user := request.Header.Get(remoteUserHeader)
+	if user == "" {
+		user = anonymousUser
+	}
+
+	return &platform.Identity{
+		User:   user,
+		Source: "dashboard",
+	}
+}
+
 func (r *resource) getListenAddress() string {
 	return r.GetServer().(*dashboard.Server).ListenAddress
 }
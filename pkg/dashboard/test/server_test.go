@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/nuclio/nuclio/test/compare"
 
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
 	"github.com/satori/go.uuid"
 	"github.com/stretchr/testify/mock"
@@ -526,6 +528,45 @@ func (suite *functionTestSuite) TestDeleteNoNamespace() {
 	suite.sendRequestNoNamespace("DELETE")
 }
 
+func (suite *functionTestSuite) TestPauseResumeSuccessful() {
+	verifyPauseFunction := func(pauseFunctionOptions *platform.PauseFunctionOptions) bool {
+		suite.Require().Equal("f1", pauseFunctionOptions.Name)
+		suite.Require().Equal("f1Namespace", pauseFunctionOptions.Namespace)
+
+		return true
+	}
+
+	verifyResumeFunction := func(resumeFunctionOptions *platform.ResumeFunctionOptions) bool {
+		suite.Require().Equal("f1", resumeFunctionOptions.Name)
+		suite.Require().Equal("f1Namespace", resumeFunctionOptions.Namespace)
+
+		return true
+	}
+
+	suite.mockPlatform.
+		On("PauseFunction", mock.MatchedBy(verifyPauseFunction)).
+		Return(nil).
+		Once()
+
+	suite.mockPlatform.
+		On("ResumeFunction", mock.MatchedBy(verifyResumeFunction)).
+		Return(nuclio.NewErrConflict("Function f1 isn't paused (state: ready)")).
+		Once()
+
+	headers := map[string]string{
+		"x-nuclio-function-namespace": "f1Namespace",
+	}
+
+	expectedStatusCode := http.StatusNoContent
+	suite.sendRequest("POST", "/api/functions/f1/pause", headers, nil, &expectedStatusCode, nil)
+
+	// the platform's error is returned with its status code
+	expectedStatusCode = http.StatusConflict
+	suite.sendRequest("POST", "/api/functions/f1/resume", headers, nil, &expectedStatusCode, nil)
+
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
 func (suite *functionTestSuite) TestInvokeSuccessful() {
 	functionName := "f1"
 	functionNamespace := "f1Namespace"
@@ -2415,6 +2456,34 @@ func (suite *miscTestSuite) TestGetFrontendSpec() {
 	suite.mockPlatform.AssertExpectations(suite.T())
 }
 
+func (suite *miscTestSuite) TestUploadFunctionSource() {
+	expectedStatusCode := http.StatusCreated
+	_, responseBody := suite.sendRequest("POST",
+		"/api/function_sources",
+		map[string]string{"x-nuclio-source-file-name": "my-function.tar.gz"},
+		bytes.NewBufferString("archive contents"),
+		&expectedStatusCode,
+		func(response map[string]interface{}) bool {
+			return strings.HasSuffix(response["path"].(string), "-my-function.tar.gz")
+		})
+
+	sourcePath := responseBody["path"].(string)
+	defer os.Remove(sourcePath) // nolint: errcheck
+
+	sourceContents, err := ioutil.ReadFile(sourcePath)
+	suite.Require().NoError(err)
+	suite.Require().Equal("archive contents", string(sourceContents))
+
+	// the archive's name identifies its kind, so it must be given
+	expectedStatusCode = http.StatusBadRequest
+	suite.sendRequest("POST",
+		"/api/function_sources",
+		nil,
+		bytes.NewBufferString("archive contents"),
+		&expectedStatusCode,
+		nil)
+}
+
 func TestDashboardTestSuite(t *testing.T) {
 	suite.Run(t, new(functionTestSuite))
 	suite.Run(t, new(projectTestSuite))
@@ -17,6 +17,7 @@ limitations under the License.
 package dockerclient
 
 import (
+	"io"
 	"time"
 )
 
@@ -38,6 +39,9 @@ type Client interface {
 	// RemoveImage will remove (delete) a local image
 	RemoveImage(imageName string) error
 
+	// GetImages returns a list of local images which match a certain criteria
+	GetImages(options *GetImageOptions) ([]Image, error)
+
 	// RunContainer will run a container based on an image and run options
 	RunContainer(imageName string, runOptions *RunOptions) (string, error)
 
@@ -47,6 +51,9 @@ type Client interface {
 	// RemoveContainer removes a container given a container ID
 	RemoveContainer(containerID string) error
 
+	// RenameContainer renames a container given a container ID
+	RenameContainer(containerID string, name string) error
+
 	// StopContainer removes a container given a container ID
 	StopContainer(containerID string) error
 
@@ -56,6 +63,12 @@ type Client interface {
 	// GetContainerLogs returns raw logs from a given container ID
 	GetContainerLogs(containerID string) (string, error)
 
+	// GetContainerLogStream returns the logs of a given container ID as they are written. the caller must close it
+	GetContainerLogStream(containerID string, options *ContainerLogsOptions) (io.ReadCloser, error)
+
+	// GetContainerStats returns the current resource usage of the given containers, by container ID
+	GetContainerStats(containerIDs []string) (map[string]ContainerStats, error)
+
 	// GetContainers returns a list of container IDs which match a certain criteria
 	GetContainers(*GetContainerOptions) ([]Container, error)
 
@@ -74,9 +87,24 @@ type Client interface {
 	// DeleteNetwork deletes a docker network
 	DeleteNetwork(networkName string) error
 
+	// NetworkExists returns true if a docker network of the given name exists
+	NetworkExists(networkName string) (bool, error)
+
+	// ConnectNetwork attaches a container to a docker network
+	ConnectNetwork(networkName string, containerID string) error
+
+	// DisconnectNetwork detaches a container from a docker network
+	DisconnectNetwork(networkName string, containerID string) error
+
 	// Save saves a docker image as tar in specified path
 	Save(imageName string, outPath string) error
 
 	// Load loads a docker image from tar as cached image
 	Load(inPath string) error
+
+	// PruneBuildCacheMounts removes the BuildKit cache mounts builds persisted
+	PruneBuildCacheMounts() error
+
+	// GetServerOS returns the operating system of the containers the docker daemon runs (linux or windows)
+	GetServerOS() (string, error)
 }
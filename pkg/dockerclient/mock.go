@@ -17,6 +17,7 @@ limitations under the License.
 package dockerclient
 
 import (
+	"io"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -60,9 +61,15 @@ func (mdc *MockDockerClient) RemoveImage(imageName string) error {
 	return nil
 }
 
+// GetImages returns a list of local images which match a certain criteria
+func (mdc *MockDockerClient) GetImages(options *GetImageOptions) ([]Image, error) {
+	return nil, nil
+}
+
 // RunContainer will run a container based on an image and run options
 func (mdc *MockDockerClient) RunContainer(imageName string, runOptions *RunOptions) (string, error) {
-	return "", nil
+	args := mdc.Called(imageName, runOptions)
+	return args.String(0), args.Error(1)
 }
 
 // ExecInContainer will run a command in a container
@@ -72,7 +79,14 @@ func (mdc *MockDockerClient) ExecInContainer(containerID string, execOptions *Ex
 
 // RemoveContainer removes a container given a container ID
 func (mdc *MockDockerClient) RemoveContainer(containerID string) error {
-	return nil
+	args := mdc.Called(containerID)
+	return args.Error(0)
+}
+
+// RenameContainer renames a container given a container ID
+func (mdc *MockDockerClient) RenameContainer(containerID string, name string) error {
+	args := mdc.Called(containerID, name)
+	return args.Error(0)
 }
 
 // StopContainer stops a container given a container ID
@@ -90,9 +104,22 @@ func (mdc *MockDockerClient) GetContainerLogs(containerID string) (string, error
 	return "", nil
 }
 
+// GetContainerLogStream returns the logs of a given container ID as they are written
+func (mdc *MockDockerClient) GetContainerLogStream(containerID string, options *ContainerLogsOptions) (io.ReadCloser, error) {
+	args := mdc.Called(containerID, options)
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+// GetContainerStats returns the current resource usage of the given containers, by container ID
+func (mdc *MockDockerClient) GetContainerStats(containerIDs []string) (map[string]ContainerStats, error) {
+	args := mdc.Called(containerIDs)
+	return args.Get(0).(map[string]ContainerStats), args.Error(1)
+}
+
 // GetContainers returns a list of container IDs which match a certain criteria
 func (mdc *MockDockerClient) GetContainers(options *GetContainerOptions) ([]Container, error) {
-	return nil, nil
+	args := mdc.Called(options)
+	return args.Get(0).([]Container), args.Error(1)
 }
 
 // GetContainerEvents returns a list of container events which occurred within a time range
@@ -123,6 +150,24 @@ func (mdc *MockDockerClient) DeleteNetwork(networkName string) error {
 	return args.Error(0)
 }
 
+// NetworkExists returns true if a docker network of the given name exists
+func (mdc *MockDockerClient) NetworkExists(networkName string) (bool, error) {
+	args := mdc.Called(networkName)
+	return args.Bool(0), args.Error(1)
+}
+
+// ConnectNetwork attaches a container to a docker network
+func (mdc *MockDockerClient) ConnectNetwork(networkName string, containerID string) error {
+	args := mdc.Called(networkName, containerID)
+	return args.Error(0)
+}
+
+// DisconnectNetwork detaches a container from a docker network
+func (mdc *MockDockerClient) DisconnectNetwork(networkName string, containerID string) error {
+	args := mdc.Called(networkName, containerID)
+	return args.Error(0)
+}
+
 // Save saves a docker image in path
 func (mdc *MockDockerClient) Save(imageName string, outPath string) error {
 	args := mdc.Called(imageName, outPath)
@@ -134,3 +179,14 @@ func (mdc *MockDockerClient) Load(inPath string) error {
 	args := mdc.Called(inPath)
 	return args.Error(0)
 }
+
+// PruneBuildCacheMounts removes the BuildKit cache mounts builds persisted
+func (mdc *MockDockerClient) PruneBuildCacheMounts() error {
+	args := mdc.Called()
+	return args.Error(0)
+}
+
+// GetServerOS returns the operating system of the containers the docker daemon runs (linux or windows)
+func (mdc *MockDockerClient) GetServerOS() (string, error) {
+	return "linux", nil
+}
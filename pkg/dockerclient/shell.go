@@ -18,8 +18,10 @@ package dockerclient
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -82,16 +84,61 @@ func (c *ShellClient) Build(buildOptions *BuildOptions) error {
 		buildOptions.DockerfilePath = path.Join(buildOptions.ContextDir, "Dockerfile")
 	}
 
+	// windows images can only be built by a daemon running windows containers, and vice versa
+	if buildOptions.OS != "" {
+		serverOS, err := c.GetServerOS()
+		if err != nil {
+			return errors.Wrap(err, "Failed to get the operating system of the docker daemon")
+		}
+
+		if serverOS != buildOptions.OS {
+			return errors.Errorf("Can't build a %s image - docker runs %s containers "+
+				"(on Docker Desktop, switch to %s containers)",
+				buildOptions.OS,
+				serverOS,
+				buildOptions.OS)
+		}
+	}
+
 	buildArgs := ""
 	for buildArgName, buildArgValue := range buildOptions.BuildArgs {
 		buildArgs += fmt.Sprintf("--build-arg %s=%s ", buildArgName, buildArgValue)
 	}
 
+	for labelName, labelValue := range buildOptions.Labels {
+		buildArgs += fmt.Sprintf("--label %s='%s' ", labelName, c.replaceSingleQuotes(labelValue))
+	}
+
+	for _, secret := range buildOptions.Secrets {
+		buildArgs += fmt.Sprintf("--secret %s ", secret)
+	}
+
 	cacheOption := ""
 	if buildOptions.NoCache {
 		cacheOption = "--no-cache"
 	}
 
+	// buildx resolves cache images from the registry by itself
+	if len(buildOptions.Platforms) > 0 {
+		for _, cacheFromImage := range buildOptions.CacheFrom {
+			cacheOption += fmt.Sprintf(" --cache-from %s", cacheFromImage)
+		}
+
+		return c.build(buildOptions, buildArgs, cacheOption)
+	}
+
+	// docker only uses cache images that are present locally. one that can't be pulled only means a slower build
+	for _, cacheFromImage := range buildOptions.CacheFrom {
+		if err := c.PullImage(cacheFromImage); err != nil {
+			c.logger.WarnWith("Failed to pull cache image, building without it",
+				"image", cacheFromImage,
+				"err", err.Error())
+			continue
+		}
+
+		cacheOption += fmt.Sprintf(" --cache-from %s", cacheFromImage)
+	}
+
 	return c.build(buildOptions, buildArgs, cacheOption)
 }
 
@@ -152,6 +199,54 @@ func (c *ShellClient) RemoveImage(imageName string) error {
 	return err
 }
 
+// GetImages returns a list of local images which match a certain criteria
+func (c *ShellClient) GetImages(options *GetImageOptions) ([]Image, error) {
+	c.logger.DebugWith("Getting images", "options", options)
+
+	labelFilterArgument := ""
+	for labelName, labelValue := range options.Labels {
+		labelFilterArgument += fmt.Sprintf(`--filter "label=%s=%s" `,
+			labelName,
+			labelValue)
+	}
+
+	runResult, err := c.runCommand(nil,
+		"docker images --quiet --no-trunc %s",
+		labelFilterArgument)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get images")
+	}
+
+	// an image is listed once per tag
+	var imageIDs []string
+	for _, imageID := range strings.Fields(runResult.Output) {
+		if !common.StringSliceContainsString(imageIDs, imageID) {
+			imageIDs = append(imageIDs, imageID)
+		}
+	}
+
+	if len(imageIDs) == 0 {
+		return []Image{}, nil
+	}
+
+	runResult, err = c.runCommand(nil,
+		"docker image inspect %s",
+		strings.Join(imageIDs, " "))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect images")
+	}
+
+	var images []Image
+
+	// parse the result
+	if err := json.Unmarshal([]byte(runResult.Output), &images); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse inspect response")
+	}
+
+	return images, nil
+}
+
 // RunContainer will run a container based on an image and run options
 func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (string, error) {
 	portsArgument := ""
@@ -193,7 +288,11 @@ func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (st
 
 	netArgument := ""
 	if runOptions.Network != "" {
-		netArgument = fmt.Sprintf("--net %s", runOptions.Network)
+		netArgument = fmt.Sprintf("--net %s ", runOptions.Network)
+	}
+
+	for _, extraHost := range runOptions.ExtraHosts {
+		netArgument += fmt.Sprintf("--add-host %s ", extraHost)
 	}
 
 	labelArgument := ""
@@ -210,6 +309,11 @@ func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (st
 		}
 	}
 
+	// env files keep their values off the command line
+	for _, envFile := range runOptions.EnvFiles {
+		envArgument += fmt.Sprintf("--env-file %s ", envFile)
+	}
+
 	volumeArgument := ""
 	if runOptions.Volumes != nil {
 		for volumeHostPath, volumeContainerPath := range runOptions.Volumes {
@@ -217,9 +321,34 @@ func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (st
 		}
 	}
 
+	for volumeHostPath, volumeContainerPath := range runOptions.ReadOnlyVolumes {
+		volumeArgument += fmt.Sprintf("--volume %s:%s:ro ", volumeHostPath, volumeContainerPath)
+	}
+
+	securityArgument := ""
+	if runOptions.User != "" {
+		securityArgument += fmt.Sprintf("--user %s ", runOptions.User)
+	}
+
+	if runOptions.ReadOnly {
+		securityArgument += "--read-only "
+	}
+
+	for _, tmpfsPath := range runOptions.TmpfsPaths {
+		securityArgument += fmt.Sprintf("--tmpfs %s ", tmpfsPath)
+	}
+
+	for _, capability := range runOptions.CapDrop {
+		securityArgument += fmt.Sprintf("--cap-drop %s ", capability)
+	}
+
+	for _, securityOpt := range runOptions.SecurityOpts {
+		securityArgument += fmt.Sprintf("--security-opt '%s' ", c.replaceSingleQuotes(securityOpt))
+	}
+
 	runResult, err := c.cmdRunner.Run(
 		&cmdrunner.RunOptions{LogRedactions: c.redactedValues},
-		"docker run %s %s %s %s %s %s %s %s %s %s %s",
+		"docker run %s %s %s %s %s %s %s %s %s %s %s %s",
 		restartPolicy,
 		detach,
 		removeContainer,
@@ -229,6 +358,7 @@ func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (st
 		labelArgument,
 		envArgument,
 		volumeArgument,
+		securityArgument,
 		imageName,
 		runOptions.Command)
 
@@ -315,6 +445,12 @@ func (c *ShellClient) RemoveContainer(containerID string) error {
 	return err
 }
 
+// RenameContainer renames a container given a container ID
+func (c *ShellClient) RenameContainer(containerID string, name string) error {
+	_, err := c.runCommand(nil, "docker rename %s %s", containerID, name)
+	return err
+}
+
 // StopContainer stops a container given a container ID
 func (c *ShellClient) StopContainer(containerID string) error {
 	_, err := c.runCommand(nil, "docker stop %s", containerID)
@@ -338,6 +474,100 @@ func (c *ShellClient) GetContainerLogs(containerID string) (string, error) {
 	return runResult.Output, err
 }
 
+// GetContainerStats returns the current resource usage of the given containers, by container ID
+func (c *ShellClient) GetContainerStats(containerIDs []string) (map[string]ContainerStats, error) {
+	containerStats := map[string]ContainerStats{}
+	if len(containerIDs) == 0 {
+		return containerStats, nil
+	}
+
+	runResult, err := c.runCommand(nil,
+		`docker stats --no-stream --format "{{json .}}" %s`,
+		strings.Join(containerIDs, " "))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get container stats")
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(runResult.Output), "\n") {
+		if line == "" {
+			continue
+		}
+
+		encodedStats := struct {
+			ID       string
+			CPUPerc  string
+			MemUsage string
+		}{}
+
+		if err := json.Unmarshal([]byte(line), &encodedStats); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse container stats: %s", line)
+		}
+
+		cpuPercent, err := strconv.ParseFloat(strings.TrimSuffix(encodedStats.CPUPerc, "%"), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse container CPU usage: %s", encodedStats.CPUPerc)
+		}
+
+		// formatted as "<usage> / <limit>"
+		var memoryBytes int64
+		if encodedStats.MemUsage != "" {
+			memoryBytes, err = c.parseSize(strings.TrimSpace(strings.Split(encodedStats.MemUsage, "/")[0]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse container memory usage: %s", encodedStats.MemUsage)
+			}
+		}
+
+		// docker reports the short ID
+		for _, containerID := range containerIDs {
+			if encodedStats.ID != "" && strings.HasPrefix(containerID, encodedStats.ID) {
+				containerStats[containerID] = ContainerStats{
+					CPUPercent:  cpuPercent,
+					MemoryBytes: memoryBytes,
+				}
+			}
+		}
+	}
+
+	return containerStats, nil
+}
+
+// GetContainerLogStream returns the logs of a given container ID as they are written, stdout and stderr
+// interlaced. the stream ends when docker logs exits (immediately, unless following)
+func (c *ShellClient) GetContainerLogStream(containerID string, options *ContainerLogsOptions) (io.ReadCloser, error) {
+	logsOptions := ""
+	if options != nil {
+		if options.Follow {
+			logsOptions += " --follow"
+		}
+
+		if options.Since > 0 {
+			logsOptions += fmt.Sprintf(" --since %s", options.Since)
+		}
+	}
+
+	runOptions := &cmdrunner.RunOptions{
+		CaptureOutputMode: cmdrunner.CaptureOutputModeCombined,
+	}
+
+	logsReader, logsWriter := io.Pipe()
+
+	go func() {
+		_, err := c.runCommandStream(runOptions, func(line string) {
+
+			// once the reader is closed there's no one to write to, drop the rest of the logs
+			logsWriter.Write([]byte(line + "\n")) // nolint: errcheck
+		}, "docker logs%s %s", logsOptions, containerID)
+
+		if err != nil {
+			err = errors.Wrap(err, "Failed to read container logs")
+		}
+
+		logsWriter.CloseWithError(err) // nolint: errcheck
+	}()
+
+	return logsReader, nil
+}
+
 // AwaitContainerHealth blocks until the given container is healthy or the timeout passes
 func (c *ShellClient) AwaitContainerHealth(containerID string, timeout *time.Duration) error {
 	timedOut := false
@@ -427,6 +657,10 @@ func (c *ShellClient) GetContainers(options *GetContainerOptions) ([]Container,
 		nameFilterArgument = fmt.Sprintf(`--filter "name=^/%s$" `, options.Name)
 	}
 
+	if options.ID != "" {
+		nameFilterArgument += fmt.Sprintf(`--filter "id=%s" `, options.ID)
+	}
+
 	labelFilterArgument := ""
 	for labelName, labelValue := range options.Labels {
 		labelFilterArgument += fmt.Sprintf(`--filter "label=%s=%s" `,
@@ -506,6 +740,37 @@ func (c *ShellClient) DeleteNetwork(networkName string) error {
 	return err
 }
 
+// NetworkExists returns true if a docker network of the given name exists
+func (c *ShellClient) NetworkExists(networkName string) (bool, error) {
+	runResult, err := c.runCommand(nil, `docker network ls --filter name='^%s$' --format '{{.Name}}'`, networkName)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to list networks")
+	}
+
+	// the name filter matches by regex, so make sure the name itself was listed
+	for _, listedNetworkName := range strings.Split(runResult.Output, "\n") {
+		if strings.TrimSpace(listedNetworkName) == networkName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ConnectNetwork attaches a container to a docker network
+func (c *ShellClient) ConnectNetwork(networkName string, containerID string) error {
+	_, err := c.runCommand(nil, `docker network connect %s %s`, networkName, containerID)
+
+	return err
+}
+
+// DisconnectNetwork detaches a container from a docker network
+func (c *ShellClient) DisconnectNetwork(networkName string, containerID string) error {
+	_, err := c.runCommand(nil, `docker network disconnect %s %s`, networkName, containerID)
+
+	return err
+}
+
 func (c *ShellClient) Save(imageName string, outPath string) error {
 	_, err := c.runCommand(nil, `docker save --output %s %s`, outPath, imageName)
 
@@ -518,6 +783,23 @@ func (c *ShellClient) Load(inPath string) error {
 	return err
 }
 
+// PruneBuildCacheMounts removes the BuildKit cache mounts builds persisted
+func (c *ShellClient) PruneBuildCacheMounts() error {
+	_, err := c.runCommand(nil, `docker builder prune --force --filter type=exec.cachemount`)
+
+	return err
+}
+
+// GetServerOS returns the operating system of the containers the docker daemon runs (linux or windows)
+func (c *ShellClient) GetServerOS() (string, error) {
+	runResult, err := c.runCommand(nil, `docker version --format "{{.Server.Os}}"`)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get docker server version")
+	}
+
+	return strings.TrimSpace(runResult.Output), nil
+}
+
 func (c *ShellClient) runCommand(runOptions *cmdrunner.RunOptions, format string, vars ...interface{}) (cmdrunner.RunResult, error) {
 
 	// if user
@@ -541,6 +823,27 @@ func (c *ShellClient) runCommand(runOptions *cmdrunner.RunOptions, format string
 	return runResult, err
 }
 
+// runCommandStream runs a command, passing its output line by line to outputLineHandler (if given)
+func (c *ShellClient) runCommandStream(runOptions *cmdrunner.RunOptions,
+	outputLineHandler func(line string),
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+
+	if outputLineHandler == nil {
+		return c.runCommand(runOptions, format, vars...)
+	}
+
+	if runOptions == nil {
+		runOptions = &cmdrunner.RunOptions{
+			CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+		}
+	}
+
+	runOptions.LogRedactions = append(runOptions.LogRedactions, c.redactedValues...)
+
+	return c.cmdRunner.RunStream(runOptions, outputLineHandler, format, vars...)
+}
+
 func (c *ShellClient) getLastNonEmptyLine(lines []string, offset int) string {
 
 	numLines := len(lines)
@@ -566,10 +869,13 @@ func (c *ShellClient) replaceSingleQuotes(input string) string {
 	return strings.Replace(input, "'", "’", -1)
 }
 
-func (c *ShellClient) resolveDockerBuildNetwork() string {
+func (c *ShellClient) resolveDockerBuildNetwork(network string) string {
 
-	// may contain none as a value
-	networkInterface := os.Getenv("NUCLIO_DOCKER_BUILD_NETWORK")
+	// the network given for the build takes precedence. the environment may contain none as a value
+	networkInterface := network
+	if networkInterface == "" {
+		networkInterface = os.Getenv("NUCLIO_DOCKER_BUILD_NETWORK")
+	}
 	if networkInterface == "" {
 		networkInterface = common.GetEnvOrDefaultString("NUCLIO_BUILD_USE_HOST_NET", "host")
 	}
@@ -585,6 +891,30 @@ func (c *ShellClient) resolveDockerBuildNetwork() string {
 	}
 }
 
+// resolveDockerBuildCommand returns the command building the image. a multi-platform build is done by buildx,
+// which pushes the platforms' images along with the manifest list referencing them
+func (c *ShellClient) resolveDockerBuildCommand(platforms []string, buildKit bool) string {
+	if len(platforms) == 0 {
+		if buildKit {
+			return "DOCKER_BUILDKIT=1 docker build --progress=plain"
+		}
+
+		return "docker build"
+	}
+
+	return fmt.Sprintf("docker buildx build --progress=plain --platform %s --push", strings.Join(platforms, ","))
+}
+
+// resolveDockerBuildRemoveOption returns the option removing the build's intermediate containers. buildx doesn't
+// leave any behind
+func (c *ShellClient) resolveDockerBuildRemoveOption(platforms []string) string {
+	if len(platforms) == 0 {
+		return "--force-rm"
+	}
+
+	return ""
+}
+
 func (c *ShellClient) build(buildOptions *BuildOptions, buildArgs string, cacheOption string) error {
 	var lastBuildErr error
 	retryOnErrorMessages := []string{
@@ -607,9 +937,12 @@ func (c *ShellClient) build(buildOptions *BuildOptions, buildArgs string, cacheO
 		c.buildRetryInterval,
 		retryOnErrorMessages,
 		func() string { // nolint: errcheck
-			runResults, err := c.runCommand(runOptions,
-				"docker build %s --force-rm -t %s -f %s %s %s .",
-				c.resolveDockerBuildNetwork(),
+			runResults, err := c.runCommandStream(runOptions,
+				buildOptions.OutputLineHandler,
+				"%s %s %s -t %s -f %s %s %s .",
+				c.resolveDockerBuildCommand(buildOptions.Platforms, buildOptions.BuildKit),
+				c.resolveDockerBuildNetwork(buildOptions.Network),
+				c.resolveDockerBuildRemoveOption(buildOptions.Platforms),
 				buildOptions.Image,
 				buildOptions.DockerfilePath,
 				cacheOption,
@@ -625,3 +958,39 @@ func (c *ShellClient) build(buildOptions *BuildOptions, buildArgs string, cacheO
 		})
 	return lastBuildErr
 }
+
+// parses sizes as docker formats them (e.g. 12.5MiB, 1.2kB)
+func (c *ShellClient) parseSize(size string) (int64, error) {
+	unitMultipliers := map[string]float64{
+		"B":   1,
+		"KiB": 1 << 10,
+		"MiB": 1 << 20,
+		"GiB": 1 << 30,
+		"TiB": 1 << 40,
+		"kB":  1e3,
+		"KB":  1e3,
+		"MB":  1e6,
+		"GB":  1e9,
+		"TB":  1e12,
+	}
+
+	unitIndex := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	if unitIndex <= 0 {
+		return 0, errors.Errorf("Invalid size: %s", size)
+	}
+
+	multiplier, found := unitMultipliers[size[unitIndex:]]
+	if !found {
+		return 0, errors.Errorf("Unknown size unit: %s", size[unitIndex:])
+	}
+
+	value, err := strconv.ParseFloat(size[:unitIndex], 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid size: %s", size)
+	}
+
+	return int64(value * multiplier), nil
+}
@@ -17,11 +17,16 @@ limitations under the License.
 package dockerclient
 
 import (
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/mock"
@@ -33,6 +38,10 @@ type mockCmdRunner struct {
 	expectedStdout   string
 	expectedStderr   string
 	expectedExitCode int
+
+	// the commands run, and those which should fail
+	runCommands     []string
+	failingCommands map[string]bool
 }
 
 func newMockCmdRunner(expectedStdout, expectedStderr string, expectedErrorCode int) *mockCmdRunner {
@@ -48,6 +57,13 @@ func (mcr *mockCmdRunner) Run(options *cmdrunner.RunOptions, format string, vars
 		options = &cmdrunner.RunOptions{}
 	}
 
+	command := fmt.Sprintf(format, vars...)
+	mcr.runCommands = append(mcr.runCommands, command)
+
+	if mcr.failingCommands[command] {
+		return cmdrunner.RunResult{ExitCode: 1}, errors.Errorf("Command failed: %s", command)
+	}
+
 	return cmdrunner.RunResult{
 			ExitCode: mcr.expectedExitCode,
 			Output:   common.Redact(options.LogRedactions, mcr.expectedStdout),
@@ -55,6 +71,21 @@ func (mcr *mockCmdRunner) Run(options *cmdrunner.RunOptions, format string, vars
 		nil
 }
 
+func (mcr *mockCmdRunner) RunStream(options *cmdrunner.RunOptions,
+	outputLineHandler func(line string),
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+	runResult, err := mcr.Run(options, format, vars...)
+
+	if runResult.Output != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(runResult.Output, "\n"), "\n") {
+			outputLineHandler(line)
+		}
+	}
+
+	return runResult, err
+}
+
 type CmdClientTestSuite struct {
 	suite.Suite
 	logger      logger.Logger
@@ -157,6 +188,194 @@ func (suite *CmdClientTestSuite) TestShellClientRunContainerRedactsOutput() {
 	suite.Require().Equal("helloworld[redacted]", output)
 }
 
+func (suite *CmdClientTestSuite) TestShellClientRunContainerEnvFilesAndReadOnlyVolumes() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+	cmdRunner.expectedStdout = "containerid"
+
+	_, err := suite.shellClient.RunContainer("alpine",
+		&RunOptions{
+			EnvFiles:        []string{"/etc/nuclio/secrets/credentials.env"},
+			ReadOnlyVolumes: map[string]string{"/etc/nuclio/secrets/certificates": "/etc/certs"},
+		})
+	suite.Require().NoError(err)
+
+	runCommand := cmdRunner.runCommands[len(cmdRunner.runCommands)-1]
+	suite.Require().Contains(runCommand, "--env-file /etc/nuclio/secrets/credentials.env ")
+	suite.Require().Contains(runCommand, "--volume /etc/nuclio/secrets/certificates:/etc/certs:ro ")
+}
+
+func (suite *CmdClientTestSuite) TestShellClientRunContainerNetworkAndExtraHosts() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+	cmdRunner.expectedStdout = "containerid"
+
+	_, err := suite.shellClient.RunContainer("alpine",
+		&RunOptions{
+			Network:    "my-stack_default",
+			ExtraHosts: []string{"db.local:10.0.0.5", "host.docker.internal:host-gateway"},
+		})
+	suite.Require().NoError(err)
+
+	runCommand := cmdRunner.runCommands[len(cmdRunner.runCommands)-1]
+	suite.Require().Contains(runCommand,
+		"--net my-stack_default --add-host db.local:10.0.0.5 --add-host host.docker.internal:host-gateway ")
+}
+
+func (suite *CmdClientTestSuite) TestShellClientNetworkExists() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+
+	// the name filter is a regex, so a listed network of another name doesn't count
+	cmdRunner.expectedStdout = "my-stack_default\n"
+	networkExists, err := suite.shellClient.NetworkExists("my-stack_default")
+	suite.Require().NoError(err)
+	suite.Require().True(networkExists)
+
+	networkExists, err = suite.shellClient.NetworkExists("my-stack.default")
+	suite.Require().NoError(err)
+	suite.Require().False(networkExists)
+
+	cmdRunner.expectedStdout = ""
+	networkExists, err = suite.shellClient.NetworkExists("my-stack_default")
+	suite.Require().NoError(err)
+	suite.Require().False(networkExists)
+}
+
+func (suite *CmdClientTestSuite) TestShellClientBuildCacheFrom() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+	cmdRunner.failingCommands = map[string]bool{
+		"docker pull registry.example.com/missing-cache:latest": true,
+	}
+
+	err := suite.shellClient.Build(&BuildOptions{
+		Image:      "my-function:latest",
+		ContextDir: "/tmp/context",
+		CacheFrom: []string{
+			"registry.example.com/base-cache:latest",
+			"registry.example.com/missing-cache:latest",
+			"registry.example.com/function-cache:latest",
+		},
+	})
+	suite.Require().NoError(err)
+
+	var buildCommand string
+	for _, runCommand := range cmdRunner.runCommands {
+		if strings.HasPrefix(runCommand, "docker build ") {
+			buildCommand = runCommand
+		}
+	}
+
+	// the cache images are passed in order, without the one that couldn't be pulled
+	suite.Require().Contains(buildCommand,
+		"--cache-from registry.example.com/base-cache:latest --cache-from registry.example.com/function-cache:latest")
+	suite.Require().NotContains(buildCommand, "missing-cache")
+}
+
+func (suite *CmdClientTestSuite) TestShellClientBuildNetwork() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+
+	err := suite.shellClient.Build(&BuildOptions{
+		Image:      "my-function:latest",
+		ContextDir: "/tmp/context",
+		Network:    "none",
+	})
+	suite.Require().NoError(err)
+
+	lastCommand := cmdRunner.runCommands[len(cmdRunner.runCommands)-1]
+	suite.Require().True(strings.HasPrefix(lastCommand, "docker build --network none "), lastCommand)
+}
+
+func (suite *CmdClientTestSuite) TestShellClientBuildLabels() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+
+	err := suite.shellClient.Build(&BuildOptions{
+		Image:      "my-function:latest",
+		ContextDir: "/tmp/context",
+		Labels: map[string]string{
+			"nuclio.io/description": "the user's function; rm -rf /",
+		},
+	})
+	suite.Require().NoError(err)
+
+	// the value is quoted, so that the shell doesn't split or interpret it
+	lastCommand := cmdRunner.runCommands[len(cmdRunner.runCommands)-1]
+	suite.Require().Contains(lastCommand, "--label nuclio.io/description='the user’s function; rm -rf /' ")
+}
+
+func (suite *CmdClientTestSuite) TestShellClientBuildPlatforms() {
+	cmdRunner := suite.shellClient.cmdRunner.(*mockCmdRunner)
+
+	err := suite.shellClient.Build(&BuildOptions{
+		Image:      "registry.example.com/my-function:latest",
+		ContextDir: "/tmp/context",
+		Network:    "host",
+		Platforms:  []string{"linux/amd64", "linux/arm64"},
+		CacheFrom:  []string{"registry.example.com/function-cache:latest"},
+	})
+	suite.Require().NoError(err)
+
+	// the cache image isn't pulled, buildx resolves it from the registry
+	for _, runCommand := range cmdRunner.runCommands {
+		suite.Require().False(strings.HasPrefix(runCommand, "docker pull "), runCommand)
+	}
+
+	buildCommand := cmdRunner.runCommands[len(cmdRunner.runCommands)-1]
+	suite.Require().True(strings.HasPrefix(buildCommand,
+		"docker buildx build --progress=plain --platform linux/amd64,linux/arm64 --push --network host "), buildCommand)
+	suite.Require().Contains(buildCommand, "-t registry.example.com/my-function:latest ")
+	suite.Require().Contains(buildCommand, "--cache-from registry.example.com/function-cache:latest")
+	suite.Require().NotContains(buildCommand, "--force-rm")
+}
+
+func (suite *CmdClientTestSuite) TestShellClientGetContainerLogStream() {
+	suite.shellClient.cmdRunner.(*mockCmdRunner).expectedStdout = "first line\nsecond line\n"
+
+	logStream, err := suite.shellClient.GetContainerLogStream("some-container", &ContainerLogsOptions{
+		Follow: true,
+		Since:  10 * time.Minute,
+	})
+	suite.Require().NoError(err)
+
+	logs, err := ioutil.ReadAll(logStream)
+	suite.Require().NoError(err)
+	suite.Require().NoError(logStream.Close())
+
+	suite.Require().Equal("first line\nsecond line\n", string(logs))
+
+	runCommands := suite.shellClient.cmdRunner.(*mockCmdRunner).runCommands
+	suite.Require().Equal("docker logs --follow --since 10m0s some-container", runCommands[len(runCommands)-1])
+}
+
+func (suite *CmdClientTestSuite) TestShellClientGetContainerStats() {
+	suite.shellClient.cmdRunner.(*mockCmdRunner).expectedStdout = `{"ID":"0123456789ab","CPUPerc":"12.50%","MemPerc":"1.00%","MemUsage":"20MiB / 1.952GiB"}
+{"ID":"ba9876543210","CPUPerc":"0.00%","MemPerc":"1.00%","MemUsage":"1.5kB / 1.952GiB"}
+`
+
+	containerStats, err := suite.shellClient.GetContainerStats([]string{
+		"0123456789abcdef",
+		"ba9876543210fedc",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]ContainerStats{
+		"0123456789abcdef": {CPUPercent: 12.5, MemoryBytes: 20 * 1024 * 1024},
+		"ba9876543210fedc": {CPUPercent: 0, MemoryBytes: 1500},
+	}, containerStats)
+
+	runCommands := suite.shellClient.cmdRunner.(*mockCmdRunner).runCommands
+	suite.Require().Equal(`docker stats --no-stream --format "{{json .}}" 0123456789abcdef ba9876543210fedc`,
+		runCommands[len(runCommands)-1])
+}
+
+func (suite *CmdClientTestSuite) TestShellClientGetContainerLogStreamFailure() {
+	suite.shellClient.cmdRunner.(*mockCmdRunner).failingCommands = map[string]bool{
+		"docker logs some-container": true,
+	}
+
+	logStream, err := suite.shellClient.GetContainerLogStream("some-container", nil)
+	suite.Require().NoError(err)
+
+	_, err = ioutil.ReadAll(logStream)
+	suite.Require().Error(err)
+}
+
 func TestCmdRunnerTestSuite(t *testing.T) {
 	suite.Run(t, new(CmdClientTestSuite))
 }
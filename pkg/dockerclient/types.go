@@ -18,6 +18,7 @@ package dockerclient
 
 import (
 	"encoding/json"
+	"time"
 )
 
 type RestartPolicyName string
@@ -43,6 +44,30 @@ type BuildOptions struct {
 	DockerfilePath string
 	NoCache        bool
 	BuildArgs      map[string]string
+	Labels         map[string]string
+
+	// images whose layers may be used as cache for the build, in order of preference
+	CacheFrom []string
+
+	// the network to build in (host, default or none). if empty, NUCLIO_DOCKER_BUILD_NETWORK decides
+	Network string
+
+	// if set, called with each line of the build's output as it is produced
+	OutputLineHandler func(line string)
+
+	// if set, the image is built for each of the platforms (e.g. linux/arm64) with buildx and pushed as a
+	// manifest list. a multi-platform image can't be loaded locally, so the image must name a registry
+	Platforms []string
+
+	// secrets the Dockerfile's RUN instructions may mount, as values of docker build's --secret option
+	// (e.g. id=token,src=/path/to/token). mounting secrets requires BuildKit
+	Secrets []string
+
+	// if set, the image is built with BuildKit (e.g. for the Dockerfile to use cache mounts). buildx always is
+	BuildKit bool
+
+	// if set, the operating system the image is built for (linux or windows), which the daemon must run
+	OS string
 }
 
 // RunOptions are options for running a docker image
@@ -52,6 +77,8 @@ type RunOptions struct {
 	Env              map[string]string
 	Labels           map[string]string
 	Volumes          map[string]string
+	ReadOnlyVolumes  map[string]string
+	EnvFiles         []string
 	Remove           bool
 	Command          string
 	Stdout           *string
@@ -60,6 +87,22 @@ type RunOptions struct {
 	ImageMayNotExist bool
 	Network          string
 	RestartPolicy    *RestartPolicy
+
+	// ExtraHosts are added to the container's /etc/hosts (host:ip)
+	ExtraHosts []string
+
+	// User runs the container as a user other than the image's (uid or uid:gid)
+	User string
+
+	// ReadOnly mounts the container's root filesystem as read-only. paths which must remain writable are mounted
+	// as tmpfs (TmpfsPaths)
+	ReadOnly   bool
+	TmpfsPaths []string
+
+	// CapDrop are capabilities dropped from the container, and SecurityOpts are security options (e.g.
+	// seccomp=unconfined)
+	CapDrop      []string
+	SecurityOpts []string
 }
 
 // ExecOptions are options for executing a command in a container
@@ -75,6 +118,42 @@ type GetContainerOptions struct {
 	Name    string
 	Labels  map[string]string
 	Stopped bool
+
+	// if set, only the container whose ID (or ID prefix) this is
+	ID string
+}
+
+// ContainerLogsOptions are options for reading container logs
+type ContainerLogsOptions struct {
+
+	// keep reading logs as they are written, until the container stops
+	Follow bool
+
+	// if set, only logs written within this duration
+	Since time.Duration
+}
+
+// ContainerStats is the current resource usage of a container
+type ContainerStats struct {
+
+	// percentage of a single CPU used, so may exceed 100 on multi-core hosts
+	CPUPercent float64
+
+	// memory used, in bytes
+	MemoryBytes int64
+}
+
+// GetImageOptions are options for image search
+type GetImageOptions struct {
+	Labels map[string]string
+}
+
+// Image contains the relevant part of the response of "docker image inspect"
+type Image struct {
+	ID       string `json:"Id"`
+	RepoTags []string
+	Created  time.Time
+	Size     int64
 }
 
 // ContainerJSONBase contains response of Engine API:
@@ -0,0 +1,198 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercreds
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+
+	"github.com/nuclio/errors"
+)
+
+// the key under which docker stores the credentials of docker hub
+const dockerHubServerURL = "https://index.docker.io/v1/"
+
+// DockerConfig is the part of a docker CLI config.json that holds registry credentials
+type DockerConfig struct {
+	Auths       map[string]DockerConfigAuth `json:"auths,omitempty"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+}
+
+// DockerConfigAuth is a single entry under "auths". credentials are either in auth, as base64 of
+// username:password, or in username and password
+type DockerConfigAuth struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// GetDefaultDockerConfigPath returns the path of the config.json the docker CLI uses
+func GetDefaultDockerConfigPath() string {
+	if dockerConfigDir := os.Getenv("DOCKER_CONFIG"); dockerConfigDir != "" {
+		return filepath.Join(dockerConfigDir, "config.json")
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(homeDir, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads a docker CLI config.json
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read docker config @ %s", path)
+	}
+
+	dockerConfig := DockerConfig{}
+	if err := json.Unmarshal(contents, &dockerConfig); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse docker config @ %s", path)
+	}
+
+	return &dockerConfig, nil
+}
+
+// GetRegistryCredentials returns the credentials of the given registry (e.g. "registry.example.com:5000/project"),
+// resolving them the way the docker CLI does - from the registry's credential helper, the credentials store,
+// or the "auths" entry, in that order. Returns nil if there are no credentials for the registry
+func (dc *DockerConfig) GetRegistryCredentials(cmdRunner cmdrunner.CmdRunner, registry string) (*Credentials, error) {
+	registryHost := GetRegistryHost(registry)
+	serverURL := registryHost
+	if registryHost == GetRegistryHost(dockerHubServerURL) {
+		serverURL = dockerHubServerURL
+	}
+
+	for credHelperRegistry, credHelper := range dc.CredHelpers {
+		if GetRegistryHost(credHelperRegistry) == registryHost {
+			return getCredHelperCredentials(cmdRunner, credHelper, serverURL)
+		}
+	}
+
+	if dc.CredsStore != "" {
+		credentials, err := getCredHelperCredentials(cmdRunner, dc.CredsStore, serverURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get credentials from the credentials store")
+		}
+
+		if credentials != nil {
+			return credentials, nil
+		}
+	}
+
+	for authRegistry, auth := range dc.Auths {
+		if GetRegistryHost(authRegistry) != registryHost {
+			continue
+		}
+
+		credentials := &Credentials{
+			URL:      authRegistry,
+			Username: auth.Username,
+			Password: auth.Password,
+		}
+
+		if auth.Auth != "" {
+			decodedAuth, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to decode auth of %s", authRegistry)
+			}
+
+			usernameAndPassword := strings.SplitN(string(decodedAuth), ":", 2)
+			if len(usernameAndPassword) != 2 {
+				return nil, errors.Errorf("Auth of %s isn't in the form of username:password", authRegistry)
+			}
+
+			credentials.Username = usernameAndPassword[0]
+			credentials.Password = usernameAndPassword[1]
+		}
+
+		// an entry without credentials only marks that they're in the credentials store
+		if credentials.Username == "" {
+			return nil, nil
+		}
+
+		return credentials, nil
+	}
+
+	return nil, nil
+}
+
+func getCredHelperCredentials(cmdRunner cmdrunner.CmdRunner, credHelper string, serverURL string) (*Credentials, error) {
+	credHelperBinary := "docker-credential-" + credHelper
+	if _, err := exec.LookPath(credHelperBinary); err != nil {
+		return nil, errors.Errorf("Docker credential helper %s wasn't found in PATH", credHelperBinary)
+	}
+
+	runResult, err := cmdRunner.Run(&cmdrunner.RunOptions{
+		Stdin:             &serverURL,
+		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+	}, "%s get", credHelperBinary)
+	if err != nil {
+
+		// helpers fail with this message when they have nothing for the server
+		if strings.Contains(runResult.Output+runResult.Stderr, "credentials not found") {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "Docker credential helper %s failed", credHelperBinary)
+	}
+
+	helperCredentials := struct {
+		ServerURL string
+		Username  string
+		Secret    string
+	}{}
+
+	if err := json.Unmarshal([]byte(runResult.Output), &helperCredentials); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the output of docker credential helper %s", credHelperBinary)
+	}
+
+	return &Credentials{
+		URL:      serverURL,
+		Username: helperCredentials.Username,
+		Password: helperCredentials.Secret,
+	}, nil
+}
+
+// GetRegistryHost returns the host (and port) of a registry URL or image repository, which is how
+// config.json entries are matched
+func GetRegistryHost(registry string) string {
+	registryHost := strings.ToLower(registry)
+	registryHost = strings.TrimPrefix(registryHost, "https://")
+	registryHost = strings.TrimPrefix(registryHost, "http://")
+
+	if slashIndex := strings.Index(registryHost, "/"); slashIndex != -1 {
+		registryHost = registryHost[:slashIndex]
+	}
+
+	switch registryHost {
+	case "docker.io", "registry-1.docker.io", "index.docker.io":
+		return "index.docker.io"
+	}
+
+	return registryHost
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercreds
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// a credential helper that has credentials for a single server
+const credHelperScript = `#!/bin/sh
+read serverURL
+if [ "$serverURL" = "helped.example.com" ]; then
+  echo '{"ServerURL": "helped.example.com", "Username": "helper-user", "Secret": "helper-secret"}'
+else
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+`
+
+type DockerConfigTestSuite struct {
+	suite.Suite
+	cmdRunner        cmdrunner.CmdRunner
+	tempDir          string
+	dockerConfigPath string
+	originalPath     string
+}
+
+func (suite *DockerConfigTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.cmdRunner, err = cmdrunner.NewShellRunner(loggerInstance)
+	suite.Require().NoError(err)
+
+	suite.tempDir, err = ioutil.TempDir("", "dockerconfig-test-")
+	suite.Require().NoError(err)
+
+	// make the credential helper available
+	err = ioutil.WriteFile(filepath.Join(suite.tempDir, "docker-credential-fixture"), []byte(credHelperScript), 0755)
+	suite.Require().NoError(err)
+
+	suite.originalPath = os.Getenv("PATH")
+	os.Setenv("PATH", suite.tempDir+string(os.PathListSeparator)+suite.originalPath) // nolint: errcheck
+
+	suite.dockerConfigPath = filepath.Join(suite.tempDir, "config.json")
+	err = ioutil.WriteFile(suite.dockerConfigPath, []byte(`{
+	"auths": {
+		"https://index.docker.io/v1/": {
+			"auth": "`+base64.StdEncoding.EncodeToString([]byte("hub-user:hub:password"))+`"
+		},
+		"registry.example.com:5000": {
+			"username": "plain-user",
+			"password": "plain-password"
+		},
+		"stored.example.com": {}
+	},
+	"credHelpers": {
+		"helped.example.com": "fixture",
+		"missing.example.com": "missing"
+	}
+}`), 0644)
+	suite.Require().NoError(err)
+}
+
+func (suite *DockerConfigTestSuite) TearDownTest() {
+	os.Setenv("PATH", suite.originalPath) // nolint: errcheck
+	os.RemoveAll(suite.tempDir)           // nolint: errcheck
+}
+
+func (suite *DockerConfigTestSuite) TestGetRegistryCredentials() {
+	dockerConfig, err := LoadDockerConfig(suite.dockerConfigPath)
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		registry            string
+		expectedCredentials *Credentials
+	}{
+		{
+			registry: "docker.io/my-user",
+			expectedCredentials: &Credentials{
+				URL:      "https://index.docker.io/v1/",
+				Username: "hub-user",
+				Password: "hub:password",
+			},
+		},
+		{
+			registry: "https://registry.example.com:5000/my-project",
+			expectedCredentials: &Credentials{
+				URL:      "registry.example.com:5000",
+				Username: "plain-user",
+				Password: "plain-password",
+			},
+		},
+		{
+			registry: "helped.example.com",
+			expectedCredentials: &Credentials{
+				URL:      "helped.example.com",
+				Username: "helper-user",
+				Password: "helper-secret",
+			},
+		},
+
+		// the port is part of the registry
+		{registry: "registry.example.com"},
+
+		// the credentials are in a store that isn't configured
+		{registry: "stored.example.com"},
+		{registry: "unknown.example.com"},
+	} {
+		credentials, err := dockerConfig.GetRegistryCredentials(suite.cmdRunner, testCase.registry)
+		suite.Require().NoError(err, testCase.registry)
+		suite.Require().Equal(testCase.expectedCredentials, credentials, testCase.registry)
+	}
+}
+
+func (suite *DockerConfigTestSuite) TestCredentialsStore() {
+	dockerConfig, err := LoadDockerConfig(suite.dockerConfigPath)
+	suite.Require().NoError(err)
+
+	dockerConfig.CredsStore = "fixture"
+
+	credentials, err := dockerConfig.GetRegistryCredentials(suite.cmdRunner, "stored.example.com")
+	suite.Require().NoError(err)
+	suite.Require().Nil(credentials)
+
+	// the store takes precedence over auths, which fill in for what isn't in it
+	dockerConfig.CredHelpers = nil
+	credentials, err = dockerConfig.GetRegistryCredentials(suite.cmdRunner, "helped.example.com")
+	suite.Require().NoError(err)
+	suite.Require().Equal("helper-user", credentials.Username)
+
+	credentials, err = dockerConfig.GetRegistryCredentials(suite.cmdRunner, "registry.example.com:5000")
+	suite.Require().NoError(err)
+	suite.Require().Equal("plain-user", credentials.Username)
+}
+
+func (suite *DockerConfigTestSuite) TestMissingCredHelper() {
+	dockerConfig, err := LoadDockerConfig(suite.dockerConfigPath)
+	suite.Require().NoError(err)
+
+	_, err = dockerConfig.GetRegistryCredentials(suite.cmdRunner, "missing.example.com")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "docker-credential-missing wasn't found in PATH")
+}
+
+func TestDockerConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(DockerConfigTestSuite))
+}
@@ -52,9 +52,10 @@ func (r *Reader) Read(reader io.Reader, configType string, config *Config) error
 		return errors.Wrap(err, "Failed to read configuration file")
 	}
 
-	// load codeEntry config into a Config struct
+	// load codeEntry config into a Config struct. the YAML is resolved before it's converted to JSON, so
+	// anchors, aliases and merge keys (<<) are supported, and an alias to an undefined anchor fails here
 	if err := yaml.Unmarshal(bodyBytes, &codeEntryConfig); err != nil {
-		return errors.Wrap(err, "Failed to write configuration")
+		return errors.Wrap(err, "Failed to parse configuration")
 	}
 
 	// enrich config with env vars existing only in codeEntry config
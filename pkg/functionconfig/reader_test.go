@@ -20,6 +20,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
@@ -168,6 +169,69 @@ spec:
 	suite.Require().Equal(51, config.Spec.TargetCPU, "Bad target cpu")
 }
 
+func (suite *ReaderTestSuite) TestAnchorsAndMergeKeys() {
+	configData := `
+metadata:
+  name: anchored
+spec:
+  runtime: python
+  handler: reverser:handler
+  triggers:
+    first: &sharedTrigger
+      kind: http
+      maxWorkers: 4
+      attributes: &sharedAttributes
+        port: 32001
+        cors:
+          enabled: true
+          allowOrigin: "*"
+    second:
+      <<: *sharedTrigger
+      maxWorkers: 8
+      attributes:
+        <<: *sharedAttributes
+        port: 32002
+`
+
+	config := Config{}
+	err := suite.reader.Read(strings.NewReader(configData), "processor", &config)
+	suite.Require().NoError(err, "Can't reader configuration")
+
+	expectedCORS := map[string]interface{}{
+		"enabled":     true,
+		"allowOrigin": "*",
+	}
+
+	firstTrigger := config.Spec.Triggers["first"]
+	suite.Require().Equal("http", firstTrigger.Kind)
+	suite.Require().Equal(4, firstTrigger.MaxWorkers)
+	suite.Require().Equal(float64(32001), firstTrigger.Attributes["port"])
+	suite.Require().Equal(expectedCORS, firstTrigger.Attributes["cors"])
+
+	// keys next to a merge key override the merged ones, at every level of nesting
+	secondTrigger := config.Spec.Triggers["second"]
+	suite.Require().Equal("http", secondTrigger.Kind)
+	suite.Require().Equal(8, secondTrigger.MaxWorkers)
+	suite.Require().Equal(float64(32002), secondTrigger.Attributes["port"])
+	suite.Require().Equal(expectedCORS, secondTrigger.Attributes["cors"])
+}
+
+func (suite *ReaderTestSuite) TestDanglingAlias() {
+	configData := `
+metadata:
+  name: dangling
+spec:
+  runtime: python
+  triggers:
+    http: *missingTrigger
+`
+
+	config := Config{}
+	err := suite.reader.Read(strings.NewReader(configData), "processor", &config)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "unknown anchor 'missingTrigger' referenced")
+}
+
 func (suite *ReaderTestSuite) TestToDeployOptions() {
 	suite.T().Skip("TODO")
 	//	flatConfigurationContents := `
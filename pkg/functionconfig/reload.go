@@ -0,0 +1,89 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionconfig
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/nuclio/errors"
+)
+
+// WithHotReloadableFields returns a copy of the spec with the fields a running processor applies when its
+// configuration changes (rather than requiring a restart) taken from the new spec: the environment variables,
+// triggers, data bindings, runtime attributes and logger sinks (their level)
+func (s *Spec) WithHotReloadableFields(newSpec *Spec) *Spec {
+	reloadedSpec := *s
+
+	reloadedSpec.Description = newSpec.Description
+	reloadedSpec.Env = newSpec.Env
+	reloadedSpec.Triggers = newSpec.Triggers
+	reloadedSpec.DataBindings = newSpec.DataBindings
+	reloadedSpec.RuntimeAttributes = newSpec.RuntimeAttributes
+	reloadedSpec.LoggerSinks = newSpec.LoggerSinks
+
+	return &reloadedSpec
+}
+
+// GetChangesRequiringRestart returns the names of the fields that differ between the specs and which a running
+// processor can't apply (e.g. the runtime or the handler), sorted. none means the new spec can be hot-reloaded
+func (s *Spec) GetChangesRequiringRestart(newSpec *Spec) ([]string, error) {
+	encodedFields, err := s.WithHotReloadableFields(newSpec).encodeFields()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode spec")
+	}
+
+	newEncodedFields, err := newSpec.encodeFields()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode new spec")
+	}
+
+	var changedFields []string
+
+	for fieldName, fieldValue := range encodedFields {
+		if !reflect.DeepEqual(fieldValue, newEncodedFields[fieldName]) {
+			changedFields = append(changedFields, fieldName)
+		}
+	}
+
+	for fieldName := range newEncodedFields {
+		if _, found := encodedFields[fieldName]; !found {
+			changedFields = append(changedFields, fieldName)
+		}
+	}
+
+	sort.Strings(changedFields)
+
+	return changedFields, nil
+}
+
+// encodes the spec's fields by their JSON names, so that fields which are set differently but encode the same
+// (e.g. nil and empty) are equal
+func (s *Spec) encodeFields() (map[string]interface{}, error) {
+	encodedSpec, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedFields := map[string]interface{}{}
+	if err := json.Unmarshal(encodedSpec, &encodedFields); err != nil {
+		return nil, err
+	}
+
+	return encodedFields, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type ReloadTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ReloadTestSuite) TestGetChangesRequiringRestart() {
+	spec := Spec{
+		Runtime: "python:3.6",
+		Handler: "main:handler",
+		Env:     []v1.EnvVar{{Name: "SOME_ENV", Value: "value"}},
+		Triggers: map[string]Trigger{
+			"http": {Kind: "http", MaxWorkers: 1},
+		},
+	}
+
+	// hot-reloadable changes
+	newSpec := spec
+	newSpec.Env = []v1.EnvVar{{Name: "SOME_ENV", Value: "other-value"}, {Name: "OTHER_ENV", Value: "value"}}
+	newSpec.Triggers = map[string]Trigger{
+		"http": {Kind: "http", MaxWorkers: 4},
+		"cron": {Kind: "cron", Attributes: map[string]interface{}{"interval": "1m"}},
+	}
+	newSpec.LoggerSinks = []LoggerSink{{Level: "info"}}
+	newSpec.RuntimeAttributes = map[string]interface{}{"arguments": "-v"}
+
+	changedFields, err := spec.GetChangesRequiringRestart(&newSpec)
+	suite.Require().NoError(err)
+	suite.Require().Empty(changedFields)
+
+	// changes requiring a restart, on top of them
+	newSpec.Handler = "main:other_handler"
+	newSpec.Resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}
+	newSpec.ImageHash = "1234"
+
+	changedFields, err = spec.GetChangesRequiringRestart(&newSpec)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"handler", "imageHash", "resources"}, changedFields)
+
+	// the spec with the hot-reloadable fields reloaded keeps the rest
+	reloadedSpec := spec.WithHotReloadableFields(&newSpec)
+	suite.Require().Equal("main:handler", reloadedSpec.Handler)
+	suite.Require().Empty(reloadedSpec.ImageHash)
+	suite.Require().Equal(newSpec.Env, reloadedSpec.Env)
+	suite.Require().Equal(newSpec.Triggers, reloadedSpec.Triggers)
+}
+
+func TestReloadTestSuite(t *testing.T) {
+	suite.Run(t, new(ReloadTestSuite))
+}
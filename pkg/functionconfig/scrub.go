@@ -0,0 +1,200 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionconfig
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/errors"
+)
+
+// ScrubbedSecretPrefix prefixes the references sensitive values are replaced with when scrubbed, followed by
+// the key the value is restored by
+const ScrubbedSecretPrefix = "$scrubbed:"
+
+// DefaultSensitiveKeyPattern matches the names of environment variables and attributes whose values are
+// considered sensitive
+var DefaultSensitiveKeyPattern = regexp.MustCompile(
+	`(?i)(password|passwd|secret|token|credential|authorization|api_?key|private_?key|access_?key(_?value)?$)`)
+
+// references are restored wherever they are, regardless of the pattern the values were scrubbed by
+var anyKeyPattern = regexp.MustCompile("")
+
+// IsScrubbedSecret returns whether the value is a reference to a scrubbed value
+func IsScrubbedSecret(value string) bool {
+	return strings.HasPrefix(value, ScrubbedSecretPrefix)
+}
+
+// ScrubSecrets replaces the sensitive values in the configuration with references to them, and returns the
+// values by the key they are restored by (see RestoreSecrets). sensitive values are:
+//   - values of environment variables whose names match the pattern
+//   - passwords and secrets (e.g. v3io access keys) of triggers, their dead-letter targets, data bindings and
+//     the event recording
+//   - URLs (of triggers, data bindings and registries) that embed a password
+//   - attributes (of triggers, data bindings and the code entry) and v3io volume options whose keys match the
+//     pattern
+func (c *Config) ScrubSecrets(sensitiveKeyPattern *regexp.Regexp) map[string]string {
+	scrubbedValues := map[string]string{}
+
+	c.visitSecrets(sensitiveKeyPattern, func(key string, value string) string {
+		if value == "" || IsScrubbedSecret(value) {
+			return value
+		}
+
+		scrubbedValues[key] = value
+
+		return ScrubbedSecretPrefix + key
+	})
+
+	return scrubbedValues
+}
+
+// RestoreSecrets replaces the references to scrubbed values with the values, by key. fails if any of the
+// values is missing
+func (c *Config) RestoreSecrets(values map[string]string) error {
+	var missingKeys []string
+
+	c.visitSecrets(anyKeyPattern, func(key string, value string) string {
+		if !IsScrubbedSecret(value) {
+			return value
+		}
+
+		restoredValue, found := values[strings.TrimPrefix(value, ScrubbedSecretPrefix)]
+		if !found {
+			missingKeys = append(missingKeys, strings.TrimPrefix(value, ScrubbedSecretPrefix))
+			return value
+		}
+
+		return restoredValue
+	})
+
+	if len(missingKeys) > 0 {
+		sort.Strings(missingKeys)
+		return errors.Errorf("Missing values of scrubbed secrets: %s", strings.Join(missingKeys, ", "))
+	}
+
+	return nil
+}
+
+// HasScrubbedSecrets returns whether the configuration references scrubbed values
+func (c *Config) HasScrubbedSecrets() bool {
+	var hasScrubbedSecrets bool
+
+	c.visitSecrets(anyKeyPattern, func(key string, value string) string {
+		hasScrubbedSecrets = hasScrubbedSecrets || IsScrubbedSecret(value)
+		return value
+	})
+
+	return hasScrubbedSecrets
+}
+
+// visitSecrets calls the visitor with the key and value of each sensitive value, replacing the value with
+// what it returns
+func (c *Config) visitSecrets(sensitiveKeyPattern *regexp.Regexp, visitor func(key string, value string) string) {
+	for envIndex, envVar := range c.Spec.Env {
+		if envVar.ValueFrom == nil && sensitiveKeyPattern.MatchString(envVar.Name) {
+			c.Spec.Env[envIndex].Value = visitor("env."+envVar.Name, envVar.Value)
+		}
+	}
+
+	visitURL := func(key string, value string) string {
+		if IsScrubbedSecret(value) {
+			return visitor(key, value)
+		}
+
+		if parsedURL, err := url.Parse(value); err == nil && parsedURL.User != nil {
+			if _, hasPassword := parsedURL.User.Password(); hasPassword {
+				return visitor(key, value)
+			}
+		}
+
+		return value
+	}
+
+	for triggerName, trigger := range c.Spec.Triggers {
+		keyPrefix := fmt.Sprintf("triggers.%s.", triggerName)
+
+		trigger.Password = visitor(keyPrefix+"password", trigger.Password)
+		trigger.Secret = visitor(keyPrefix+"secret", trigger.Secret)
+		trigger.URL = visitURL(keyPrefix+"url", trigger.URL)
+
+		if trigger.DeadLetter != nil {
+			trigger.DeadLetter.Secret = visitor(keyPrefix+"deadLetter.secret", trigger.DeadLetter.Secret)
+		}
+
+		visitSensitiveAttributes(sensitiveKeyPattern, keyPrefix+"attributes.", trigger.Attributes, visitor)
+
+		c.Spec.Triggers[triggerName] = trigger
+	}
+
+	for dataBindingName, dataBinding := range c.Spec.DataBindings {
+		keyPrefix := fmt.Sprintf("dataBindings.%s.", dataBindingName)
+
+		dataBinding.Secret = visitor(keyPrefix+"secret", dataBinding.Secret)
+		dataBinding.URL = visitURL(keyPrefix+"url", dataBinding.URL)
+
+		visitSensitiveAttributes(sensitiveKeyPattern, keyPrefix+"attributes.", dataBinding.Attributes, visitor)
+
+		c.Spec.DataBindings[dataBindingName] = dataBinding
+	}
+
+	// v3io volumes hold the access key in their options
+	for _, volume := range c.Spec.Volumes {
+		if volume.Volume.FlexVolume == nil {
+			continue
+		}
+
+		for optionKey, optionValue := range volume.Volume.FlexVolume.Options {
+			if sensitiveKeyPattern.MatchString(optionKey) {
+				volume.Volume.FlexVolume.Options[optionKey] = visitor(
+					fmt.Sprintf("volumes.%s.%s", volume.Volume.Name, optionKey),
+					optionValue)
+			}
+		}
+	}
+
+	if c.Spec.EventRecording != nil {
+		c.Spec.EventRecording.Secret = visitor("eventRecording.secret", c.Spec.EventRecording.Secret)
+	}
+
+	c.Spec.Build.Registry = visitURL("build.registry", c.Spec.Build.Registry)
+	c.Spec.RunRegistry = visitURL("runRegistry", c.Spec.RunRegistry)
+
+	visitSensitiveAttributes(sensitiveKeyPattern, "build.codeEntryAttributes.", c.Spec.Build.CodeEntryAttributes, visitor)
+}
+
+// visits the string attributes whose keys match the pattern, recursing into nested attributes
+func visitSensitiveAttributes(sensitiveKeyPattern *regexp.Regexp,
+	keyPrefix string,
+	attributes map[string]interface{},
+	visitor func(key string, value string) string) {
+
+	for attributeKey, attributeValue := range attributes {
+		switch typedAttributeValue := attributeValue.(type) {
+		case string:
+			if sensitiveKeyPattern.MatchString(attributeKey) {
+				attributes[attributeKey] = visitor(keyPrefix+attributeKey, typedAttributeValue)
+			}
+		case map[string]interface{}:
+			visitSensitiveAttributes(sensitiveKeyPattern, keyPrefix+attributeKey+".", typedAttributeValue, visitor)
+		}
+	}
+}
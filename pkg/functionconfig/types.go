@@ -18,10 +18,14 @@ package functionconfig
 
 import (
 	"fmt"
+	"math"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/nuclio/errors"
 	"github.com/v3io/scaler-types"
 	"k8s.io/api/core/v1"
 )
@@ -53,6 +57,41 @@ type Volume struct {
 	VolumeMount v1.VolumeMount `json:"volumeMount,omitempty"`
 }
 
+// EnvFromSecret exposes each of a secret's keys as an environment variable of the function. on the local
+// platform, the secret is an env file
+type EnvFromSecret struct {
+	Name string `json:"name"`
+}
+
+// ExternalSecretEnvVar is an environment variable of the function whose value is read from a secret of an
+// external store (e.g. Vault) when the function's container starts. the value is never kept in the function's
+// configuration or image
+type ExternalSecretEnvVar struct {
+	Name      string                     `json:"name"`
+	ValueFrom ExternalSecretEnvVarSource `json:"valueFrom"`
+}
+
+// ExternalSecretEnvVarSource is where the value of an external secret environment variable is read from
+type ExternalSecretEnvVarSource struct {
+	ExternalSecret *ExternalSecretRef `json:"externalSecret,omitempty"`
+}
+
+// ExternalSecretRef references a secret of one of the secret providers of the platform configuration, by name.
+// when a key is given, the secret is a JSON object (as are Vault's) and the value is that of the key
+type ExternalSecretRef struct {
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+	Key      string `json:"key,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// VolumeFromSecret mounts a secret in the function's container, a file per key. on the local platform, the
+// secret is a directory
+type VolumeFromSecret struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+}
+
 // Trigger holds configuration for a trigger
 type Trigger struct {
 	Class                                 string            `json:"class"`
@@ -74,10 +113,219 @@ type Trigger struct {
 	TotalTasks        int `json:"total_tasks,omitempty"`
 	MaxTaskAllocation int `json:"max_task_allocation,omitempty"`
 
+	// where events the function failed to process are published, if anywhere
+	DeadLetter *DeadLetter `json:"deadLetter,omitempty"`
+
+	// if set, events the function failed to process are retried with an exponential backoff
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// if set, how long the handling of an event may take (e.g. 30s) before the handler is cancelled. defaults
+	// to spec.eventTimeout
+	EventTimeout string `json:"eventTimeout,omitempty"`
+
+	// if set, the records of a stream trigger are delivered in batches rather than one by one
+	Batch *Batch `json:"batch,omitempty"`
+
+	// if set, the number of workers handling events adapts to the load, up to MaxWorkers
+	WorkerAutoscaling *WorkerAutoscaling `json:"workerAutoscaling,omitempty"`
+
+	// if set, how the trigger parses events as CloudEvents (and emits responses as such)
+	CloudEvents *CloudEvents `json:"cloudEvents,omitempty"`
+
+	// if set, the trigger doesn't consume events until it's resumed, while the rest of the function keeps
+	// running. set by pausing the trigger at runtime (nuctl trigger pause)
+	Paused bool `json:"paused,omitempty"`
+
+	// if set, the rate at which the trigger dispatches events to its workers is limited
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+
 	// General attributes
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
+// the kinds of targets dead-lettered events can be published to
+const (
+	DeadLetterKindKafka      = "kafka"
+	DeadLetterKindV3ioStream = "v3ioStream"
+	DeadLetterKindHTTP       = "http"
+)
+
+// DeadLetterKinds are the kinds of dead-letter targets
+var DeadLetterKinds = []string{DeadLetterKindKafka, DeadLetterKindV3ioStream, DeadLetterKindHTTP}
+
+// DeadLetter is the target a trigger publishes an event to once the function failed to process it, after
+// retrying it up to MaxRetries times
+type DeadLetter struct {
+	Kind string `json:"kind"`
+
+	// the kafka brokers (comma separated), the v3io web API or the HTTP endpoint
+	URL string `json:"url"`
+
+	// the kafka topic
+	Topic string `json:"topic,omitempty"`
+
+	// the v3io stream, and the access key to it
+	ContainerName string `json:"containerName,omitempty"`
+	StreamPath    string `json:"streamPath,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+const (
+	DefaultRetryPolicyInitialInterval = 100 * time.Millisecond
+	DefaultRetryPolicyMultiplier      = 2
+	DefaultRetryPolicyMaxInterval     = 10 * time.Second
+)
+
+// RetryPolicy retries an event the function failed to process up to MaxRetries times, waiting InitialInterval
+// before the first retry and Multiplier times longer before each of the following ones, up to MaxInterval.
+// Only the failures matching RetryableStatusCodes or RetryableErrors are retried, if either is set - an error
+// matches if its status code or message (a substring of it) is listed, and a response matches if its status
+// code is listed. Otherwise, every error is retried
+type RetryPolicy struct {
+	MaxRetries           int      `json:"maxRetries"`
+	InitialInterval      string   `json:"initialInterval,omitempty"`
+	Multiplier           float64  `json:"multiplier,omitempty"`
+	MaxInterval          string   `json:"maxInterval,omitempty"`
+	RetryableStatusCodes []int    `json:"retryableStatusCodes,omitempty"`
+	RetryableErrors      []string `json:"retryableErrors,omitempty"`
+}
+
+// GetInitialInterval returns the interval before the first retry
+func (rp *RetryPolicy) GetInitialInterval() (time.Duration, error) {
+	return parseDurationOrDefault(rp.InitialInterval, DefaultRetryPolicyInitialInterval)
+}
+
+// GetMaxInterval returns the longest interval between retries
+func (rp *RetryPolicy) GetMaxInterval() (time.Duration, error) {
+	return parseDurationOrDefault(rp.MaxInterval, DefaultRetryPolicyMaxInterval)
+}
+
+// GetMultiplier returns the factor each interval is longer than the previous one by
+func (rp *RetryPolicy) GetMultiplier() float64 {
+	if rp.Multiplier == 0 {
+		return DefaultRetryPolicyMultiplier
+	}
+
+	return rp.Multiplier
+}
+
+func parseDurationOrDefault(duration string, defaultDuration time.Duration) (time.Duration, error) {
+	if duration == "" {
+		return defaultDuration, nil
+	}
+
+	return time.ParseDuration(duration)
+}
+
+// BatchTriggerKinds are the kinds of triggers which support batching
+var BatchTriggerKinds = []string{"kafka-cluster", "kinesis", "v3ioStream"}
+
+// Batch configures a stream trigger to aggregate records into a single event, whose body is a JSON array of
+// the records. A batch is delivered once it holds MaxSize records, or MaxWaitMs passed since its first record
+// was read, and its records are acknowledged only if the function handled it successfully
+type Batch struct {
+	MaxSize   int `json:"maxSize"`
+	MaxWaitMs int `json:"maxWaitMs,omitempty"`
+}
+
+// WorkerAutoscalingTriggerKinds are the kinds of triggers which support worker autoscaling
+var WorkerAutoscalingTriggerKinds = []string{"http", "websocket", "grpc", "nats", "jetstream", "mqtt", "sqs", "sns"}
+
+const (
+	DefaultWorkerAutoscalingEvaluationIntervalSeconds = 5
+	DefaultWorkerAutoscalingMaxLatencyIncreasePercent = 100
+)
+
+// WorkerAutoscaling makes a trigger allocate only some of its workers, adjusting their number every
+// EvaluationIntervalSeconds - workers are added while events wait for one, and removed when they're idle.
+// Workers aren't added (and are removed) while the average handler latency is more than
+// MaxLatencyIncreasePercent above the lowest observed, as more concurrency then only slows events down
+type WorkerAutoscaling struct {
+	MinWorkers                int `json:"minWorkers,omitempty"`
+	EvaluationIntervalSeconds int `json:"evaluationIntervalSeconds,omitempty"`
+	MaxLatencyIncreasePercent int `json:"maxLatencyIncreasePercent,omitempty"`
+}
+
+const (
+	CloudEventsModeAuto       = "auto"
+	CloudEventsModeStructured = "structured"
+	CloudEventsModeBinary     = "binary"
+	CloudEventsModeDisabled   = "disabled"
+)
+
+// CloudEventsModes are the modes in which a trigger parses CloudEvents
+var CloudEventsModes = []string{CloudEventsModeAuto, CloudEventsModeStructured, CloudEventsModeBinary, CloudEventsModeDisabled}
+
+// CloudEventsResponseModes are the modes in which an http trigger emits responses as CloudEvents
+var CloudEventsResponseModes = []string{CloudEventsModeStructured, CloudEventsModeBinary}
+
+// CloudEventsResponseTriggerKinds are the kinds of triggers which can emit responses as CloudEvents
+var CloudEventsResponseTriggerKinds = []string{"http"}
+
+// CloudEvents configures how a trigger handles CloudEvents (https://cloudevents.io, 1.0 and 0.1). In auto mode
+// (the default), an event is parsed as a structured CloudEvent if its content type is application/cloudevents+json,
+// and as a binary one if it has CloudEvents headers (ce-specversion, or ce_specversion for kafka). In structured and
+// binary modes every event is parsed as such, failing the events which aren't CloudEvents, and in disabled mode
+// events are passed to the handler as they are. Handlers find the event's attributes in its ID, type, version,
+// timestamp and content type, and in its ce-<attribute> headers (in structured mode as well)
+type CloudEvents struct {
+	Mode string `json:"mode,omitempty"`
+
+	// if set (structured or binary), responses are emitted as CloudEvents of ResponseType from ResponseSource.
+	// streamed responses are always emitted as binary CloudEvents
+	ResponseMode   string `json:"responseMode,omitempty"`
+	ResponseType   string `json:"responseType,omitempty"`
+	ResponseSource string `json:"responseSource,omitempty"`
+}
+
+// GetMode returns the mode in which events are parsed, auto by default
+func (ce *CloudEvents) GetMode() string {
+	if ce == nil || ce.Mode == "" {
+		return CloudEventsModeAuto
+	}
+
+	return ce.Mode
+}
+
+const (
+	RateLimitKeyIP           = "ip"
+	RateLimitKeyHeaderPrefix = "header:"
+)
+
+// RateLimitKeyTriggerKinds are the kinds of triggers which can limit the rate per caller
+var RateLimitKeyTriggerKinds = []string{"http"}
+
+// RateLimit limits the rate at which a trigger dispatches events to its workers with a token bucket, refilled
+// with RPS tokens per second up to Burst (by default, RPS rounded up). Each event takes a token (a batch event
+// takes one per record) - http triggers respond with 429 to requests for which there's no token, and other
+// triggers wait for one, which pauses the consumption of stream triggers. If Key is set (ip, or header:<name>),
+// http triggers keep a bucket per caller - per remote IP address or per value of the header
+type RateLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst,omitempty"`
+	Key   string  `json:"key,omitempty"`
+}
+
+// GetBurst returns the number of tokens the bucket holds when full
+func (rl *RateLimit) GetBurst() int {
+	if rl.Burst == 0 {
+		return int(math.Ceil(rl.RPS))
+	}
+
+	return rl.Burst
+}
+
+// GetKeyHeaderName returns the name of the header whose values callers are told apart by, if any
+func (rl *RateLimit) GetKeyHeaderName() string {
+	if !strings.HasPrefix(rl.Key, RateLimitKeyHeaderPrefix) {
+		return ""
+	}
+
+	return strings.TrimPrefix(rl.Key, RateLimitKeyHeaderPrefix)
+}
+
 // GetTriggersByKind returns a map of triggers by their kind
 func GetTriggersByKind(triggers map[string]Trigger, kind string) map[string]Trigger {
 	matchingTrigger := map[string]Trigger{}
@@ -131,6 +379,18 @@ func GetIngressesFromTriggers(triggers map[string]Trigger) map[string]Ingress {
 				}
 				ingress.TLS = ingressTLS
 
+				if tlsIssuer, ok := encodedIngressMap["tlsIssuer"].(string); ok {
+					ingress.TLSIssuer = tlsIssuer
+				}
+
+				if tlsIssuerKind, ok := encodedIngressMap["tlsIssuerKind"].(string); ok {
+					ingress.TLSIssuerKind = tlsIssuerKind
+				}
+
+				if rewriteTarget, ok := encodedIngressMap["rewriteTarget"].(string); ok {
+					ingress.RewriteTarget = rewriteTarget
+				}
+
 				ingresses[encodedIngressName] = ingress
 			}
 		}
@@ -140,13 +400,28 @@ func GetIngressesFromTriggers(triggers map[string]Trigger) map[string]Ingress {
 }
 
 // Ingress holds configuration for an ingress - an entity that can route HTTP requests
-// to the function
+// to the function. the host and TLS secret name may hold {function}, {project} and {namespace} placeholders
 type Ingress struct {
 	Host  string     `json:"host,omitempty"`
 	Paths []string   `json:"paths,omitempty"`
 	TLS   IngressTLS `json:"tls,omitempty"`
+
+	// TLSIssuer names the cert-manager issuer of the ingress's certificate, of TLSIssuerKind (Issuer or
+	// ClusterIssuer, the default). cert-manager stores it in the ingress's TLS secret
+	TLSIssuer     string `json:"tlsIssuer,omitempty"`
+	TLSIssuerKind string `json:"tlsIssuerKind,omitempty"`
+
+	// RewriteTarget is what the ingress controller rewrites the paths of requests to (e.g. /$2 for a path of
+	// /my-function(/|$)(.*)). the ingresses of a function share it
+	RewriteTarget string `json:"rewriteTarget,omitempty"`
 }
 
+// ingress TLS issuer kinds
+const (
+	IngressTLSIssuerKindIssuer        = "Issuer"
+	IngressTLSIssuerKindClusterIssuer = "ClusterIssuer"
+)
+
 // IngressTLS holds configuration for an ingress's TLS
 type IngressTLS struct {
 	Hosts      []string `json:"hosts,omitempty"`
@@ -170,12 +445,114 @@ type Directive struct {
 	Value string `json:"value,omitempty"`
 }
 
+// BuildHooks are commands run at defined points of the build of the function's image
+type BuildHooks struct {
+
+	// run before the processor, the runtime and the handler are copied into the image
+	PreCopy *BuildHook `json:"preCopy,omitempty"`
+
+	// run after the handler was copied and its dependencies were installed (i.e. after the runtime's directives)
+	PostInstall *BuildHook `json:"postInstall,omitempty"`
+
+	// run last, right before the image's command is set
+	PreFinal *BuildHook `json:"preFinal,omitempty"`
+}
+
+// BuildHook is a stage of commands run while building the function's image
+type BuildHook struct {
+
+	// each command is a RUN instruction. a command ending with a backslash continues in the next one
+	Commands []string `json:"commands,omitempty"`
+
+	// build args which the commands (and those of later stages) can read, passed to the build
+	Args map[string]string `json:"args,omitempty"`
+
+	// secrets mounted (as BuildKit secrets) while the stage's commands run, without being stored in the image
+	Secrets []BuildSecret `json:"secrets,omitempty"`
+}
+
+// BuildSecret is a secret which the commands of a build hook read from /run/secrets/<id>. its contents are
+// taken from either a file or an environment variable of the builder
+type BuildSecret struct {
+	ID  string `json:"id,omitempty"`
+	Src string `json:"src,omitempty"`
+	Env string `json:"env,omitempty"`
+}
+
+// GetStages returns the hook stages by their names, omitting the ones that aren't set
+func (bh *BuildHooks) GetStages() map[string]*BuildHook {
+	stages := map[string]*BuildHook{}
+
+	for stageName, stage := range map[string]*BuildHook{
+		BuildHookStagePreCopy:     bh.PreCopy,
+		BuildHookStagePostInstall: bh.PostInstall,
+		BuildHookStagePreFinal:    bh.PreFinal,
+	} {
+		if stage != nil {
+			stages[stageName] = stage
+		}
+	}
+
+	return stages
+}
+
+// GetSecrets returns the secrets of all stages
+func (bh *BuildHooks) GetSecrets() []BuildSecret {
+	var secrets []BuildSecret
+
+	for _, stage := range []*BuildHook{bh.PreCopy, bh.PostInstall, bh.PreFinal} {
+		if stage != nil {
+			secrets = append(secrets, stage.Secrets...)
+		}
+	}
+
+	return secrets
+}
+
+// the stages of the image build hooks run at
+const (
+	BuildHookStagePreCopy     = "preCopy"
+	BuildHookStagePostInstall = "postInstall"
+	BuildHookStagePreFinal    = "preFinal"
+)
+
 type Metric struct {
 	SourceType     string `json:"sourceType,omitempty"`
 	ThresholdValue int64  `json:"thresholdValue,omitempty"`
 	WindowSize     string `json:"windowSize,omitempty"`
 }
 
+// the docker networks a function may be built in
+const (
+	BuildNetworkHost    = "host"
+	BuildNetworkDefault = "default"
+	BuildNetworkNone    = "none"
+)
+
+// BuildNetworks are the docker networks a function may be built in
+var BuildNetworks = []string{BuildNetworkHost, BuildNetworkDefault, BuildNetworkNone}
+
+// the formats of the SBOM (software bill of materials) generated for a function's image
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSPDX      = "spdx"
+)
+
+// SBOMFormats are the formats of the SBOM generated for a function's image
+var SBOMFormats = []string{SBOMFormatCycloneDX, SBOMFormatSPDX}
+
+// the operating systems a function's image may be built for
+const (
+	BuildOSLinux   = "linux"
+	BuildOSWindows = "windows"
+)
+
+// BuildOSes are the operating systems a function's image may be built for
+var BuildOSes = []string{BuildOSLinux, BuildOSWindows}
+
+// WindowsRuntimes are the runtimes which have windows onbuild images
+var WindowsRuntimes = []string{"dotnetcore", "shell"}
+
 type BuildMode string
 
 const (
@@ -194,21 +571,46 @@ type Build struct {
 	Image               string                 `json:"image,omitempty"`
 	NoBaseImagesPull    bool                   `json:"noBaseImagesPull,omitempty"`
 	NoCache             bool                   `json:"noCache,omitempty"`
+	CacheFrom           []string               `json:"cacheFrom,omitempty"`
+	Network             string                 `json:"network,omitempty"`
+	Platforms           []string               `json:"platforms,omitempty"`
+	OS                  string                 `json:"os,omitempty"`
 	NoCleanup           bool                   `json:"noCleanup,omitempty"`
 	BaseImage           string                 `json:"baseImage,omitempty"`
 	Commands            []string               `json:"commands,omitempty"`
 	Directives          map[string][]Directive `json:"directives,omitempty"`
+	Hooks               *BuildHooks            `json:"hooks,omitempty"`
 	ScriptPaths         []string               `json:"scriptPaths,omitempty"`
 	AddedObjectPaths    map[string]string      `json:"addedPaths,omitempty"`
 	Dependencies        []string               `json:"dependencies,omitempty"`
 	OnbuildImage        string                 `json:"onbuildImage,omitempty"`
 	Offline             bool                   `json:"offline,omitempty"`
+	CacheDir            string                 `json:"cacheDir,omitempty"`
+	PersistentCache     bool                   `json:"persistentCache,omitempty"`
 	RuntimeAttributes   map[string]interface{} `json:"runtimeAttributes,omitempty"`
 	CodeEntryType       string                 `json:"codeEntryType,omitempty"`
 	CodeEntryAttributes map[string]interface{} `json:"codeEntryAttributes,omitempty"`
 	Timestamp           int64                  `json:"timestamp,omitempty"`
 	BuildTimeoutSeconds *int64                 `json:"buildTimeoutSeconds,omitempty"`
 	Mode                BuildMode              `json:"mode,omitempty"`
+
+	// if set, an SBOM of the built image is generated in this format (with syft) and written to SBOMPath
+	SBOMFormat string `json:"sbomFormat,omitempty"`
+	SBOMPath   string `json:"sbomPath,omitempty"`
+
+	// if set, the pushed image (and its SBOM, if generated) is signed with cosign - with SigningKey if given
+	// (a path or a KMS / kubernetes secret URI), or keyless otherwise
+	Sign       bool   `json:"sign,omitempty"`
+	SigningKey string `json:"signingKey,omitempty"`
+}
+
+// GetOS returns the operating system the function's image is built for, linux unless set
+func (b *Build) GetOS() string {
+	if b.OS == "" {
+		return BuildOSLinux
+	}
+
+	return b.OS
 }
 
 // Spec holds all parameters related to a function's configuration
@@ -229,6 +631,9 @@ type Spec struct {
 	DataBindings            map[string]DataBinding  `json:"dataBindings,omitempty"`
 	Triggers                map[string]Trigger      `json:"triggers,omitempty"`
 	Volumes                 []Volume                `json:"volumes,omitempty"`
+	EnvFromSecrets          []EnvFromSecret         `json:"envFromSecrets,omitempty"`
+	ExternalSecretEnv       []ExternalSecretEnvVar  `json:"externalSecretEnv,omitempty"`
+	VolumesFromSecrets      []VolumeFromSecret      `json:"volumesFromSecrets,omitempty"`
 	Version                 int                     `json:"version,omitempty"`
 	Alias                   string                  `json:"alias,omitempty"`
 	Build                   Build                   `json:"build,omitempty"`
@@ -239,17 +644,527 @@ type Spec struct {
 	DealerURI               string                  `json:"dealerURI,omitempty"`
 	Platform                Platform                `json:"platform,omitempty"`
 	ReadinessTimeoutSeconds int                     `json:"readinessTimeoutSeconds,omitempty"`
+	Readiness               *Readiness              `json:"readiness,omitempty"`
+	Warmup                  *Warmup                 `json:"warmup,omitempty"`
 	Avatar                  string                  `json:"avatar,omitempty"`
 	ServiceType             v1.ServiceType          `json:"serviceType,omitempty"`
 	ImagePullPolicy         v1.PullPolicy           `json:"imagePullPolicy,omitempty"`
 	ServiceAccount          string                  `json:"serviceAccount,omitempty"`
 	ScaleToZero             *ScaleToZeroSpec        `json:"scaleToZero,omitempty"`
 
+	// Sidecars are containers run alongside the processor in the function's pods (e.g. proxies, agents).
+	// they may mount the function's volumes by name. honoured by the kube platform
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+
+	// MaxInflightEvents limits the events handled by all the triggers of a replica at the same time (0 is
+	// unlimited). events beyond it wait in a queue of QueueSize for up to QueueTimeout, and are rejected beyond it
+	MaxInflightEvents int    `json:"maxInflightEvents,omitempty"`
+	QueueSize         int    `json:"queueSize,omitempty"`
+	QueueTimeout      string `json:"queueTimeout,omitempty"`
+
+	// RuntimeLiveness enables pinging the wrapper process of the Python, NodeJS and Java runtimes and
+	// restarting it if it stops responding
+	RuntimeLiveness *RuntimeLiveness `json:"runtimeLiveness,omitempty"`
+
+	// TerminationGracePeriodSeconds is the time a replica has to drain once it's asked to terminate (e.g. when
+	// scaled down) - to stop receiving events, finish handling those in flight and commit their offsets
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// FunctionReferences maps aliases to other functions, as <project>/<function> (or <function>, in the
+	// function's project). the platform resolves each to the function's internal URL, in the env var
+	// NUCLIO_FUNCTION_URL_<ALIAS>
+	FunctionReferences map[string]string `json:"functionReferences,omitempty"`
+
+	// GPU requests GPUs for each replica, which may share them with other replicas. honoured by the kube platform
+	GPU *GPU `json:"gpu,omitempty"`
+
+	// SchedulingPreset names a set of node selectors and tolerations in the platform configuration
+	// (schedulingPresets) which the function's replicas are scheduled with. honoured by the kube platform
+	SchedulingPreset string `json:"schedulingPreset,omitempty"`
+
+	// NodeSelector are labels of the nodes the function's replicas are scheduled to, on top of those of its
+	// scheduling preset. honoured by the kube platform
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// EventRecording records a sample of the events the function handles, along with its responses, so that
+	// they can be replayed against another version of the function (nuctl replay)
+	EventRecording *EventRecording `json:"eventRecording,omitempty"`
+
+	// InvocationInspector retains a summary of the last invocations of the function (a sample of them), so that
+	// failures can be inspected after the fact (nuctl get invocations)
+	InvocationInspector *InvocationInspector `json:"invocationInspector,omitempty"`
+
+	// AsyncInvocation configures the delivery of the invocations the function enqueues for other functions
+	// without waiting for their responses (e.g. with context.platform.call_function_async)
+	AsyncInvocation *AsyncInvocation `json:"asyncInvocation,omitempty"`
+
+	// AutoScale scales the function on the consumer lag of its stream triggers rather than on CPU. honoured by
+	// the kube platform
+	AutoScale *AutoScale `json:"autoScale,omitempty"`
+
+	// SecurityContext hardens the processor's container (e.g. for clusters which reject containers running as
+	// root or writing to their root filesystem). honoured by the kube and local platforms
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+
+	// DependsOn are resources in the function's namespace which must be ready before it's deployed, as
+	// <function> (or function/<function>) or apigateway/<api gateway>. honoured by operations deploying several
+	// functions (nuctl import, nuctl deploy --file and the dashboard's project import)
+	DependsOn []string `json:"dependsOn,omitempty"`
+
 	// We're letting users write "20s" and not the default marshalled time.Duration
 	// (Which is in nanoseconds)
 	EventTimeout string `json:"eventTimeout"`
 }
 
+// the kinds of the dependencies of a function's readiness
+const (
+	ReadinessDependencyKindTCP     = "tcp"
+	ReadinessDependencyKindHTTP    = "http"
+	ReadinessDependencyKindHandler = "handler"
+)
+
+var ReadinessDependencyKinds = []string{
+	ReadinessDependencyKindTCP,
+	ReadinessDependencyKindHTTP,
+	ReadinessDependencyKindHandler,
+}
+
+// Readiness holds the dependencies of the function (e.g. the brokers or databases it uses) which must be reachable
+// for the processor to report ready, on top of its workers being ready. the processor checks them periodically, so
+// a replica whose dependencies become unreachable stops reporting ready until they're reachable again. it's
+// honoured by both the kube readiness probe and the local platform's readiness wait
+type Readiness struct {
+	Dependencies []ReadinessDependency `json:"dependencies,omitempty"`
+
+	// the time between consecutive checks of each dependency (default 5)
+	PeriodSeconds int `json:"periodSeconds,omitempty"`
+
+	// the time a check has to pass (default 2)
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// defaults of readiness dependency checks
+const (
+	DefaultReadinessPeriodSeconds  = 5
+	DefaultReadinessTimeoutSeconds = 2
+)
+
+// GetPeriod returns the time between consecutive checks of each dependency
+func (r *Readiness) GetPeriod() time.Duration {
+	if r.PeriodSeconds == 0 {
+		return DefaultReadinessPeriodSeconds * time.Second
+	}
+
+	return time.Duration(r.PeriodSeconds) * time.Second
+}
+
+// GetTimeout returns the time a check has to pass
+func (r *Readiness) GetTimeout() time.Duration {
+	if r.TimeoutSeconds == 0 {
+		return DefaultReadinessTimeoutSeconds * time.Second
+	}
+
+	return time.Duration(r.TimeoutSeconds) * time.Second
+}
+
+// ReadinessDependency is a dependency the function must reach to be ready - a TCP address which must accept
+// connections (tcp), a URL which must respond with a 2xx status (http), or a path of the function's own HTTP
+// trigger, which lets the handler decide whether the function is ready (handler)
+type ReadinessDependency struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Address string `json:"address,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// Warmup is a set of requests the platform sends a function's replicas once they're ready, so that runtimes
+// which compile lazily (e.g. Java, .NET) don't serve slow first requests
+type Warmup struct {
+
+	// Path is requested from the function's HTTP trigger - with a POST if there's a body, a GET otherwise
+	Path string `json:"path,omitempty"`
+	Body string `json:"body,omitempty"`
+
+	// Count is the number of requests sent to each replica (default 1), Concurrency how many of them are
+	// sent at the same time (default 1)
+	Count       int `json:"count,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// RuntimeLiveness configures the periodic pings the processor sends each of the runtime's wrapper processes.
+// a wrapper which doesn't respond within the timeout (e.g. due to a hung handler) is restarted, and once a
+// worker's wrapper was restarted more than MaxRestarts times the processor reports itself as not alive
+type RuntimeLiveness struct {
+
+	// IntervalSeconds is the time between consecutive pings (default 10)
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+
+	// TimeoutSeconds is the time the wrapper has to respond (default 30). since a wrapper handles a
+	// single event at a time, it must be longer than the longest running event
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+
+	// MaxRestarts is the number of times a worker's wrapper may be restarted (default 3)
+	MaxRestarts int `json:"maxRestarts,omitempty"`
+}
+
+// Runtime liveness defaults
+const (
+	DefaultRuntimeLivenessIntervalSeconds = 10
+	DefaultRuntimeLivenessTimeoutSeconds  = 30
+	DefaultRuntimeLivenessMaxRestarts     = 3
+)
+
+// GetInterval returns the time between consecutive pings
+func (rl *RuntimeLiveness) GetInterval() time.Duration {
+	if rl.IntervalSeconds == 0 {
+		return DefaultRuntimeLivenessIntervalSeconds * time.Second
+	}
+
+	return time.Duration(rl.IntervalSeconds) * time.Second
+}
+
+// GetTimeout returns the time the wrapper has to respond to a ping
+func (rl *RuntimeLiveness) GetTimeout() time.Duration {
+	if rl.TimeoutSeconds == 0 {
+		return DefaultRuntimeLivenessTimeoutSeconds * time.Second
+	}
+
+	return time.Duration(rl.TimeoutSeconds) * time.Second
+}
+
+// GetMaxRestarts returns the number of times a worker's wrapper may be restarted
+func (rl *RuntimeLiveness) GetMaxRestarts() int {
+	if rl.MaxRestarts == 0 {
+		return DefaultRuntimeLivenessMaxRestarts
+	}
+
+	return rl.MaxRestarts
+}
+
+// GPU is the GPUs a replica gets - either whole GPUs, or a fraction of one which it shares with other replicas
+type GPU struct {
+
+	// Count is a whole number of GPUs, or a fraction of a GPU (e.g. 0.25)
+	Count float64 `json:"count,omitempty"`
+
+	// Sharing is how a fraction of a GPU is shared (default timeSlicing)
+	Sharing GPUSharing `json:"sharing,omitempty"`
+}
+
+// GPUSharing is how GPUs are shared between replicas, as set up by the nvidia device plugin on the nodes
+type GPUSharing string
+
+const (
+	GPUSharingTimeSlicing GPUSharing = "timeSlicing"
+	GPUSharingMPS         GPUSharing = "mps"
+)
+
+// GPUSharings are the supported ways of sharing GPUs
+var GPUSharings = []string{string(GPUSharingTimeSlicing), string(GPUSharingMPS)}
+
+// IsShared returns whether the replica gets a fraction of a GPU, rather than whole GPUs
+func (g *GPU) IsShared() bool {
+	return g.Count != math.Trunc(g.Count)
+}
+
+// GetSharing returns how the GPU is shared
+func (g *GPU) GetSharing() GPUSharing {
+	if g.Sharing == "" {
+		return GPUSharingTimeSlicing
+	}
+
+	return g.Sharing
+}
+
+// IsCUDAImage returns whether the image is CUDA-enabled, going by the conventions of CUDA images' names - those
+// based on nvidia/cuda, or tagged with cuda or gpu (e.g. pytorch/pytorch:1.6.0-cuda10.1-cudnn7-runtime,
+// tensorflow/tensorflow:2.3.0-gpu)
+func IsCUDAImage(image string) bool {
+	image = strings.ToLower(image)
+
+	if strings.Contains(image, "cuda") || strings.Contains(image, "nvidia/") {
+		return true
+	}
+
+	tagIndex := strings.LastIndex(image, ":")
+	return tagIndex > strings.LastIndex(image, "/") && strings.Contains(image[tagIndex:], "gpu")
+}
+
+// the kinds of stores events are recorded to
+const (
+	EventRecordingKindLocalDir = "localDir"
+	EventRecordingKindS3       = "s3"
+	EventRecordingKindV3io     = "v3io"
+)
+
+// EventRecordingKinds are the kinds of stores events are recorded to
+var EventRecordingKinds = []string{EventRecordingKindLocalDir, EventRecordingKindS3, EventRecordingKindV3io}
+
+// EventRecording is the store the processor records events to, each event (with the function's response to it)
+// as a JSON object under Path - a directory (localDir), a key prefix in Bucket (s3) or a path in the v3io
+// container (v3io)
+type EventRecording struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+
+	// the fraction of events recorded, between 0 and 1 (default 1)
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+
+	// if set, only the events of these triggers are recorded
+	Triggers []string `json:"triggers,omitempty"`
+
+	// bodies (of events and responses) beyond this size are truncated (default 1MiB)
+	MaxBodySize int `json:"maxBodySize,omitempty"`
+
+	// the bucket, with credentials from the default chain (e.g. the pod's IAM role)
+	Bucket   string `json:"bucket,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// the v3io web API, container and access key
+	URL           string `json:"url,omitempty"`
+	ContainerName string `json:"containerName,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+}
+
+// DefaultEventRecordingMaxBodySize is the size bodies are truncated to, if not configured
+const DefaultEventRecordingMaxBodySize = 1024 * 1024
+
+// GetSampleRatio returns the fraction of events recorded
+func (er *EventRecording) GetSampleRatio() float64 {
+	if er.SampleRatio == nil {
+		return 1
+	}
+
+	return *er.SampleRatio
+}
+
+// GetMaxBodySize returns the size bodies are truncated to
+func (er *EventRecording) GetMaxBodySize() int {
+	if er.MaxBodySize == 0 {
+		return DefaultEventRecordingMaxBodySize
+	}
+
+	return er.MaxBodySize
+}
+
+// InvocationInspector is how many of the function's invocations are retained, and how much of each
+type InvocationInspector struct {
+
+	// the number of invocations retained, by each replica and by the platform (default 100)
+	MaxInvocations int `json:"maxInvocations,omitempty"`
+
+	// the fraction of invocations retained, between 0 and 1 (default 1)
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+
+	// responses (and errors) beyond this size are truncated (default 1KiB)
+	MaxBodySize int `json:"maxBodySize,omitempty"`
+}
+
+// the number of invocations retained and the size their responses are truncated to, if not configured, and
+// their limits - the platform may keep the invocations in a single kubernetes config map
+const (
+	DefaultInvocationInspectorMaxInvocations = 100
+	MaxInvocationInspectorMaxInvocations     = 1000
+	DefaultInvocationInspectorMaxBodySize    = 1024
+	MaxInvocationInspectorMaxBodySize        = 16 * 1024
+)
+
+// GetMaxInvocations returns the number of invocations retained
+func (ii *InvocationInspector) GetMaxInvocations() int {
+	if ii.MaxInvocations == 0 {
+		return DefaultInvocationInspectorMaxInvocations
+	}
+
+	return ii.MaxInvocations
+}
+
+// GetSampleRatio returns the fraction of invocations retained
+func (ii *InvocationInspector) GetSampleRatio() float64 {
+	if ii.SampleRatio == nil {
+		return 1
+	}
+
+	return *ii.SampleRatio
+}
+
+// GetMaxBodySize returns the size responses are truncated to
+func (ii *InvocationInspector) GetMaxBodySize() int {
+	if ii.MaxBodySize == 0 {
+		return DefaultInvocationInspectorMaxBodySize
+	}
+
+	return ii.MaxBodySize
+}
+
+// the guarantees with which asynchronous invocations are delivered
+const (
+	AsyncInvocationDeliveryBestEffort = "bestEffort"
+	AsyncInvocationDeliveryPersisted  = "persisted"
+)
+
+var AsyncInvocationDeliveries = []string{AsyncInvocationDeliveryBestEffort, AsyncInvocationDeliveryPersisted}
+
+// AsyncInvocation configures how the processor delivers the invocations the function enqueues. bestEffort
+// (the default) queues them in memory, so they're lost if the queue is full, the delivery fails or the processor
+// stops. persisted writes each invocation to a file under Path (which should be a volume) before enqueuing it,
+// retries failed deliveries and delivers the invocations left over by a previous processor on start
+type AsyncInvocation struct {
+	Delivery string `json:"delivery,omitempty"`
+	Path     string `json:"path,omitempty"`
+
+	// the number of invocations which may be queued (default 1024)
+	QueueSize int `json:"queueSize,omitempty"`
+
+	// the number of invocations delivered concurrently (default 4)
+	Workers int `json:"workers,omitempty"`
+
+	// the number of times a persisted invocation is retried before it's moved aside (default 5)
+	MaxRetries *int `json:"maxRetries,omitempty"`
+}
+
+// defaults of async invocations
+const (
+	DefaultAsyncInvocationQueueSize  = 1024
+	DefaultAsyncInvocationWorkers    = 4
+	DefaultAsyncInvocationMaxRetries = 5
+)
+
+// GetDelivery returns the delivery guarantee of async invocations
+func (ai *AsyncInvocation) GetDelivery() string {
+	if ai == nil || ai.Delivery == "" {
+		return AsyncInvocationDeliveryBestEffort
+	}
+
+	return ai.Delivery
+}
+
+// GetQueueSize returns the number of invocations which may be queued
+func (ai *AsyncInvocation) GetQueueSize() int {
+	if ai == nil || ai.QueueSize == 0 {
+		return DefaultAsyncInvocationQueueSize
+	}
+
+	return ai.QueueSize
+}
+
+// GetWorkers returns the number of invocations delivered concurrently
+func (ai *AsyncInvocation) GetWorkers() int {
+	if ai == nil || ai.Workers == 0 {
+		return DefaultAsyncInvocationWorkers
+	}
+
+	return ai.Workers
+}
+
+// GetMaxRetries returns the number of times a persisted invocation is retried
+func (ai *AsyncInvocation) GetMaxRetries() int {
+	if ai == nil || ai.MaxRetries == nil {
+		return DefaultAsyncInvocationMaxRetries
+	}
+
+	return *ai.MaxRetries
+}
+
+// the ways a function is scaled on its consumer lag
+const (
+	AutoScaleModeNuclio = "nuclio"
+	AutoScaleModeKEDA   = "keda"
+)
+
+var AutoScaleModes = []string{AutoScaleModeNuclio, AutoScaleModeKEDA}
+
+// the kinds of triggers whose consumer lag a function can be scaled on, per mode. in nuclio mode, the lag is
+// the one the processor reports, so only triggers which report it are supported. in keda mode, KEDA reads the
+// lag of the consumer group from the brokers
+var AutoScaleTriggerKinds = map[string][]string{
+	AutoScaleModeNuclio: {"kafka-cluster", "kafka", "jetstream", "v3ioStream"},
+	AutoScaleModeKEDA:   {"kafka-cluster", "kafka"},
+}
+
+// AutoScale scales the replicas of a function so that each handles up to LagThreshold messages of consumer lag.
+// in nuclio mode (the default), the function's HPA targets the consumer lag metric its processors report, and
+// the controller wakes a function scaled to zero once its consumer group has lag. in keda mode, a KEDA
+// ScaledObject is created in place of the HPA, and KEDA scales the function (from and to zero, too)
+type AutoScale struct {
+	LagThreshold int64  `json:"lagThreshold,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+
+	// how often the lag is checked (keda mode, default 30s)
+	PollingInterval string `json:"pollingInterval,omitempty"`
+
+	// how long the lag must be zero before the function is scaled to zero (keda mode, default 5m)
+	CooldownPeriod string `json:"cooldownPeriod,omitempty"`
+}
+
+// defaults of lag based scaling
+const (
+	DefaultAutoScalePollingInterval = 30 * time.Second
+	DefaultAutoScaleCooldownPeriod  = 5 * time.Minute
+)
+
+// GetMode returns the way the function is scaled
+func (as *AutoScale) GetMode() string {
+	if as == nil || as.Mode == "" {
+		return AutoScaleModeNuclio
+	}
+
+	return as.Mode
+}
+
+// GetPollingInterval returns how often the lag is checked
+func (as *AutoScale) GetPollingInterval() time.Duration {
+	if as == nil || as.PollingInterval == "" {
+		return DefaultAutoScalePollingInterval
+	}
+
+	pollingInterval, err := time.ParseDuration(as.PollingInterval)
+	if err != nil {
+		return DefaultAutoScalePollingInterval
+	}
+
+	return pollingInterval
+}
+
+// GetCooldownPeriod returns how long the lag must be zero before the function is scaled to zero
+func (as *AutoScale) GetCooldownPeriod() time.Duration {
+	if as == nil || as.CooldownPeriod == "" {
+		return DefaultAutoScaleCooldownPeriod
+	}
+
+	cooldownPeriod, err := time.ParseDuration(as.CooldownPeriod)
+	if err != nil {
+		return DefaultAutoScaleCooldownPeriod
+	}
+
+	return cooldownPeriod
+}
+
+// the seccomp profiles of a function's container (other than localhost/<profile>)
+const (
+	SeccompProfileRuntimeDefault = "runtime/default"
+	SeccompProfileUnconfined     = "unconfined"
+)
+
+// SeccompProfileLocalhostPrefix prefixes a profile on the node (kube) or the host (local)
+const SeccompProfileLocalhostPrefix = "localhost/"
+
+// SecurityContext is the security posture of the processor's container. when ReadOnlyRootFilesystem is set, the
+// platform mounts a writable /tmp, which is where the processor and runtimes write their temporary files
+type SecurityContext struct {
+	RunAsUser              *int64 `json:"runAsUser,omitempty"`
+	RunAsGroup             *int64 `json:"runAsGroup,omitempty"`
+	ReadOnlyRootFilesystem bool   `json:"readOnlyRootFilesystem,omitempty"`
+
+	// capabilities dropped from the container (e.g. ALL, NET_RAW)
+	DropCapabilities []string `json:"dropCapabilities,omitempty"`
+
+	// runtime/default, unconfined or localhost/<profile> (a path relative to the kubelet's seccomp profile
+	// directory on kube, and the path of a profile read by the docker CLI deploying the function on local)
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+}
+
 type ScaleToZeroSpec struct {
 	ScaleResources []ScaleResource `json:"scaleResources,omitempty"`
 }
@@ -306,6 +1221,60 @@ func (s *Spec) GetHTTPPort() int {
 	return 0
 }
 
+// DefaultHTTPListenPort is the port an HTTP trigger listens on within the container when its URL doesn't
+// specify one
+const DefaultHTTPListenPort = 8080
+
+// GetHTTPListenPort returns the port the function's HTTP trigger listens on within the container, as opposed to
+// GetHTTPPort, the port it's exposed on
+func (s *Spec) GetHTTPListenPort() int {
+
+	// a function has a single HTTP trigger
+	for _, trigger := range GetTriggersByKind(s.Triggers, "http") {
+		if _, encodedPort, err := net.SplitHostPort(trigger.URL); err == nil {
+			if httpListenPort, err := strconv.Atoi(encodedPort); err == nil {
+				return httpListenPort
+			}
+		}
+	}
+
+	return DefaultHTTPListenPort
+}
+
+// DefaultGRPCPort is the port a gRPC trigger listens on when its URL doesn't specify one
+const DefaultGRPCPort = 50051
+
+// GetGRPCPorts returns the ports the function's gRPC triggers listen on, sorted
+func (s *Spec) GetGRPCPorts() []int {
+	var grpcPorts []int
+	addedGRPCPorts := map[int]bool{}
+
+	for _, trigger := range GetTriggersByKind(s.Triggers, "grpc") {
+		grpcPort := DefaultGRPCPort
+
+		if trigger.URL != "" {
+			_, encodedPort, err := net.SplitHostPort(trigger.URL)
+			if err != nil {
+				continue
+			}
+
+			grpcPort, err = strconv.Atoi(encodedPort)
+			if err != nil {
+				continue
+			}
+		}
+
+		if !addedGRPCPorts[grpcPort] {
+			addedGRPCPorts[grpcPort] = true
+			grpcPorts = append(grpcPorts, grpcPort)
+		}
+	}
+
+	sort.Ints(grpcPorts)
+
+	return grpcPorts
+}
+
 // GetEventTimeout returns the event timeout as time.Duration
 func (s *Spec) GetEventTimeout() (time.Duration, error) {
 	timeout, err := time.ParseDuration(s.EventTimeout)
@@ -316,11 +1285,208 @@ func (s *Spec) GetEventTimeout() (time.Duration, error) {
 	return timeout, err
 }
 
+// GetEventTimeout returns how long the handling of the trigger's events may take, zero if it isn't limited
+func (t *Trigger) GetEventTimeout() (time.Duration, error) {
+	if t.EventTimeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(t.EventTimeout)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("eventTimeout <= 0 (%s)", timeout)
+	}
+
+	return timeout, err
+}
+
+// DefaultTerminationGracePeriodSeconds is the time a replica has to drain when
+// spec.terminationGracePeriodSeconds isn't set, same as kubernetes' default
+const DefaultTerminationGracePeriodSeconds = 30
+
+// GetTerminationGracePeriod returns the time a replica has to drain once it's asked to terminate
+func (s *Spec) GetTerminationGracePeriod() time.Duration {
+	if s.TerminationGracePeriodSeconds == nil {
+		return DefaultTerminationGracePeriodSeconds * time.Second
+	}
+
+	return time.Duration(*s.TerminationGracePeriodSeconds) * time.Second
+}
+
+// FunctionReferenceEnvPrefix prefixes the env vars holding the URLs of the functions a function references
+const FunctionReferenceEnvPrefix = "NUCLIO_FUNCTION_URL_"
+
+// FunctionReference is another function, referenced by a function through an alias
+type FunctionReference struct {
+	Alias        string
+	ProjectName  string
+	FunctionName string
+}
+
+// GetEnvName returns the name of the env var holding the URL of the referenced function - the alias in
+// upper case, with anything other than letters and digits replaced by underscores
+func (fr *FunctionReference) GetEnvName() string {
+	return FunctionReferenceEnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, fr.Alias)
+}
+
+// GetFunctionReferences returns the functions the function references, ordered by alias. references without
+// a project are to functions in projectName
+func (s *Spec) GetFunctionReferences(projectName string) ([]FunctionReference, error) {
+	var aliases []string
+	for alias := range s.FunctionReferences {
+		aliases = append(aliases, alias)
+	}
+
+	// the env vars are given in a consistent order, so that the pod spec doesn't change between resolutions
+	sort.Strings(aliases)
+
+	var functionReferences []FunctionReference
+	for _, alias := range aliases {
+		functionReference, err := newFunctionReference(alias, s.FunctionReferences[alias], projectName)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid function reference")
+		}
+
+		functionReferences = append(functionReferences, *functionReference)
+	}
+
+	return functionReferences, nil
+}
+
+func newFunctionReference(alias string, reference string, projectName string) (*FunctionReference, error) {
+	functionReference := FunctionReference{
+		Alias:        alias,
+		ProjectName:  projectName,
+		FunctionName: reference,
+	}
+
+	if slashIndex := strings.Index(reference, "/"); slashIndex != -1 {
+		functionReference.ProjectName = reference[:slashIndex]
+		functionReference.FunctionName = reference[slashIndex+1:]
+	}
+
+	if alias == "" {
+		return nil, errors.Errorf("Alias of %s must not be empty", reference)
+	}
+
+	if functionReference.ProjectName == "" ||
+		functionReference.FunctionName == "" ||
+		strings.Contains(functionReference.FunctionName, "/") {
+		return nil, errors.Errorf("%s must be <project>/<function> or <function>, got %s", alias, reference)
+	}
+
+	return &functionReference, nil
+}
+
+// the kinds of resources a function may depend on
+const (
+	DependencyKindFunction   = "function"
+	DependencyKindAPIGateway = "apigateway"
+)
+
+// Dependency is a resource which must be ready before the function depending on it is deployed
+type Dependency struct {
+	Kind string
+	Name string
+}
+
+func (d Dependency) String() string {
+	return d.Kind + "/" + d.Name
+}
+
+// GetDependencies returns the resources the function depends on, in the order they're declared
+func (s *Spec) GetDependencies() ([]Dependency, error) {
+	var dependencies []Dependency
+	for _, dependsOn := range s.DependsOn {
+		dependency, err := newDependency(dependsOn)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid dependency")
+		}
+
+		dependencies = append(dependencies, *dependency)
+	}
+
+	return dependencies, nil
+}
+
+func newDependency(dependsOn string) (*Dependency, error) {
+	dependency := Dependency{
+		Kind: DependencyKindFunction,
+		Name: dependsOn,
+	}
+
+	if slashIndex := strings.Index(dependsOn, "/"); slashIndex != -1 {
+		dependency.Kind = dependsOn[:slashIndex]
+		dependency.Name = dependsOn[slashIndex+1:]
+	}
+
+	if dependency.Kind != DependencyKindFunction && dependency.Kind != DependencyKindAPIGateway {
+		return nil, errors.Errorf("Dependency %s must be on a function or an apigateway", dependsOn)
+	}
+
+	if dependency.Name == "" || strings.Contains(dependency.Name, "/") {
+		return nil, errors.Errorf("Dependency must be <function>, function/<function> or apigateway/<name>, got %s",
+			dependsOn)
+	}
+
+	return &dependency, nil
+}
+
+// DefaultQueueTimeout is the time events wait in the queue for admission when spec.queueTimeout isn't set
+const DefaultQueueTimeout = 10 * time.Second
+
+// GetQueueTimeout returns the time events wait in the queue for admission as time.Duration, or the default
+// if it isn't set
+func (s *Spec) GetQueueTimeout() (time.Duration, error) {
+	if s.QueueTimeout == "" {
+		return DefaultQueueTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(s.QueueTimeout)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("queueTimeout <= 0 (%s)", timeout)
+	}
+
+	return timeout, err
+}
+
 const (
-	FunctionAnnotationSkipBuild  = "skip-build"
-	FunctionAnnotationSkipDeploy = "skip-deploy"
+	FunctionAnnotationSkipBuild         = "skip-build"
+	FunctionAnnotationSkipDeploy        = "skip-deploy"
+	FunctionAnnotationAppliedConfigHash = "applied-config-hash"
+
+	// documents an environment variable, suffixed by the variable's name
+	FunctionAnnotationEnvDescriptionPrefix = "nuclio.io/env-description."
+
+	// mark a function as slated for removal, optionally with why and when
+	FunctionAnnotationDeprecated         = "nuclio.io/deprecated"
+	FunctionAnnotationDeprecationMessage = "nuclio.io/deprecation-message"
+	FunctionAnnotationDeprecationDate    = "nuclio.io/deprecation-date"
+
+	// the group of related functions the function was deployed as part of
+	FunctionAnnotationGroup = "nuclio.io/function-group"
+
+	// marks the image the function runs as CUDA-enabled, when it isn't named like one (see IsCUDAImage)
+	FunctionAnnotationCUDAImage = "nuclio.io/cuda-image"
 )
 
+// DeprecationDateLayout is the layout of the deprecation date annotation
+const DeprecationDateLayout = "2006-01-02"
+
+// Deprecation describes why and when a deprecated function is going to be removed
+type Deprecation struct {
+	Message string `json:"message,omitempty"`
+	Date    string `json:"date,omitempty"`
+}
+
 // Meta identifies a function
 type Meta struct {
 	Name        string            `json:"name,omitempty"`
@@ -350,6 +1516,52 @@ func (m *Meta) RemoveSkipBuildAnnotation() {
 	delete(m.Annotations, FunctionAnnotationSkipBuild)
 }
 
+// SetDeprecation marks the function as deprecated. the message and date (YYYY-MM-DD) are optional
+func (m *Meta) SetDeprecation(message string, date string) error {
+	if date != "" {
+		if _, err := time.Parse(DeprecationDateLayout, date); err != nil {
+			return errors.Errorf("Invalid deprecation date %s, must be in the form of YYYY-MM-DD", date)
+		}
+	}
+
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+
+	m.RemoveDeprecation()
+	m.Annotations[FunctionAnnotationDeprecated] = strconv.FormatBool(true)
+
+	if message != "" {
+		m.Annotations[FunctionAnnotationDeprecationMessage] = message
+	}
+
+	if date != "" {
+		m.Annotations[FunctionAnnotationDeprecationDate] = date
+	}
+
+	return nil
+}
+
+// RemoveDeprecation unmarks the function as deprecated
+func (m *Meta) RemoveDeprecation() {
+	delete(m.Annotations, FunctionAnnotationDeprecated)
+	delete(m.Annotations, FunctionAnnotationDeprecationMessage)
+	delete(m.Annotations, FunctionAnnotationDeprecationDate)
+}
+
+// GetDeprecation returns the deprecation of a function, or nil if it isn't deprecated
+func GetDeprecation(annotations map[string]string) *Deprecation {
+	deprecated, _ := strconv.ParseBool(annotations[FunctionAnnotationDeprecated])
+	if !deprecated {
+		return nil
+	}
+
+	return &Deprecation{
+		Message: annotations[FunctionAnnotationDeprecationMessage],
+		Date:    annotations[FunctionAnnotationDeprecationDate],
+	}
+}
+
 func ShouldSkipDeploy(annotations map[string]string) bool {
 	var skipFunctionDeploy bool
 	if skipFunctionBuildDeploy, ok := annotations[FunctionAnnotationSkipDeploy]; ok {
@@ -415,11 +1627,17 @@ func (c *Config) scrubFunctionData() {
 	// scrub namespace from function meta
 	c.Meta.Namespace = ""
 
-	// remove secrets and passwords from triggers
+	// remove secrets and passwords from triggers, unless already replaced by references to them
 	newTriggers := c.Spec.Triggers
 	for triggerName, trigger := range newTriggers {
-		trigger.Password = ""
-		trigger.Secret = ""
+		if !IsScrubbedSecret(trigger.Password) {
+			trigger.Password = ""
+		}
+
+		if !IsScrubbedSecret(trigger.Secret) {
+			trigger.Secret = ""
+		}
+
 		newTriggers[triggerName] = trigger
 	}
 	c.Spec.Triggers = newTriggers
@@ -440,6 +1658,9 @@ const (
 	FunctionStateError                            FunctionState = "error"
 	FunctionStateScaledToZero                     FunctionState = "scaledToZero"
 	FunctionStateImported                         FunctionState = "imported"
+
+	// the function was paused (spec.disable) - it has no replicas, and isn't invoked until it's resumed
+	FunctionStatePaused FunctionState = "paused"
 )
 
 func FunctionStateInSlice(a FunctionState, list []FunctionState) bool {
@@ -458,6 +1679,23 @@ type Status struct {
 	Logs        []map[string]interface{} `json:"logs,omitempty"`
 	HTTPPort    int                      `json:"httpPort,omitempty"`
 	ScaleToZero *ScaleToZeroStatus       `json:"scaleToZero,omitempty"`
+	Warmup      *WarmupStatus            `json:"warmup,omitempty"`
+	Image       *ImageStatus             `json:"image,omitempty"`
+
+	// restarts of the function's wrappers which failed their runtime liveness checks
+	RuntimeLiveness *RuntimeLivenessStatus `json:"runtimeLiveness,omitempty"`
+
+	// when the function was last deployed (and became ready), kept as it's scaled
+	LastDeployed *time.Time `json:"lastDeployed,omitempty"`
+}
+
+// ImageStatus identifies the image the function was built into, and its signature if it was signed
+type ImageStatus struct {
+	Digest    string `json:"digest,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
+	// the commit the function's code was checked out at, when built from a git repository
+	SourceCommit string `json:"sourceCommit,omitempty"`
 }
 
 type ScaleToZeroStatus struct {
@@ -465,6 +1703,45 @@ type ScaleToZeroStatus struct {
 	LastScaleEventTime *time.Time              `json:"lastScaleEventTime,omitempty"`
 }
 
+// WarmupStatus holds the results of the last warmup of one of the function's replicas
+type WarmupStatus struct {
+	Replica              string     `json:"replica,omitempty"`
+	Time                 *time.Time `json:"time,omitempty"`
+	Invocations          int        `json:"invocations,omitempty"`
+	Failures             int        `json:"failures,omitempty"`
+	DurationMilliseconds int64      `json:"durationMilliseconds,omitempty"`
+	LastError            string     `json:"lastError,omitempty"`
+}
+
+// RuntimeLivenessStatus holds the restarts of wrappers which didn't respond to runtime liveness pings, across
+// the function's replicas
+type RuntimeLivenessStatus struct {
+	Restarts        int        `json:"restarts,omitempty"`
+	LastRestartTime *time.Time `json:"lastRestartTime,omitempty"`
+	LastError       string     `json:"lastError,omitempty"`
+
+	// set once a wrapper stopped responding after it was restarted MaxRestarts times, failing its replica
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Add accounts for the restarts of another wrapper (or replica) in the status. the error reported is that of
+// a failed wrapper, if any, and otherwise that of the wrapper which was restarted last
+func (rls *RuntimeLivenessStatus) Add(other *RuntimeLivenessStatus) {
+	rls.Restarts += other.Restarts
+
+	otherRestartedLast := other.LastRestartTime != nil &&
+		(rls.LastRestartTime == nil || other.LastRestartTime.After(*rls.LastRestartTime))
+	if otherRestartedLast {
+		rls.LastRestartTime = other.LastRestartTime
+	}
+
+	if (other.Failed && !rls.Failed) || (other.Failed == rls.Failed && otherRestartedLast) {
+		rls.LastError = other.LastError
+	}
+
+	rls.Failed = rls.Failed || other.Failed
+}
+
 // DeepCopyInto copies to appease k8s
 func (s *Status) DeepCopyInto(out *Status) {
 
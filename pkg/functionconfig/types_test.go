@@ -18,6 +18,7 @@ package functionconfig
 
 import (
 	"testing"
+	"time"
 
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
@@ -91,6 +92,126 @@ func (suite *ReaderTestSuite) TestFunctionMetaSkipBuildAnnotationTrue() {
 	}
 }
 
+func (suite *TypesTestSuite) TestFunctionMetaDeprecation() {
+	functionMeta := Meta{}
+	suite.Require().Nil(GetDeprecation(functionMeta.Annotations))
+
+	err := functionMeta.SetDeprecation("use my-function-v2", "2020-12-31")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Deprecation{
+		Message: "use my-function-v2",
+		Date:    "2020-12-31",
+	}, GetDeprecation(functionMeta.Annotations))
+
+	// deprecating again replaces the previous message and date
+	err = functionMeta.SetDeprecation("", "")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Deprecation{}, GetDeprecation(functionMeta.Annotations))
+
+	functionMeta.RemoveDeprecation()
+	suite.Require().Nil(GetDeprecation(functionMeta.Annotations))
+	suite.Require().Empty(functionMeta.Annotations)
+
+	err = functionMeta.SetDeprecation("", "31/12/2020")
+	suite.Require().Error(err)
+	suite.Require().Nil(GetDeprecation(functionMeta.Annotations))
+}
+
+func (suite *TypesTestSuite) TestGetHTTPListenPort() {
+	for _, testCase := range []struct {
+		name                   string
+		triggers               map[string]Trigger
+		expectedHTTPListenPort int
+	}{
+		{
+			name:                   "noTriggers",
+			expectedHTTPListenPort: DefaultHTTPListenPort,
+		},
+		{
+			name: "noURL",
+			triggers: map[string]Trigger{
+				"http": {Kind: "http", Attributes: map[string]interface{}{"port": 30000}},
+			},
+			expectedHTTPListenPort: DefaultHTTPListenPort,
+		},
+		{
+			name: "URL",
+			triggers: map[string]Trigger{
+				"http": {Kind: "http", URL: "0.0.0.0:9090", Attributes: map[string]interface{}{"port": 30000}},
+				"grpc": {Kind: "grpc", URL: ":9000"},
+			},
+			expectedHTTPListenPort: 9090,
+		},
+		{
+			name: "URLWithoutPort",
+			triggers: map[string]Trigger{
+				"http": {Kind: "http", URL: "0.0.0.0"},
+			},
+			expectedHTTPListenPort: DefaultHTTPListenPort,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			spec := Spec{Triggers: testCase.triggers}
+			suite.Require().Equal(testCase.expectedHTTPListenPort, spec.GetHTTPListenPort())
+		})
+	}
+}
+
+func (suite *TypesTestSuite) TestGetGRPCPorts() {
+	spec := Spec{
+		Triggers: map[string]Trigger{
+			"http":        {Kind: "http", Attributes: map[string]interface{}{"port": 30000}},
+			"grpc":        {Kind: "grpc"},
+			"grpc-custom": {Kind: "grpc", URL: "0.0.0.0:9000"},
+			"grpc-same":   {Kind: "grpc", URL: ":50051"},
+		},
+	}
+
+	suite.Require().Equal([]int{9000, DefaultGRPCPort}, spec.GetGRPCPorts())
+
+	// no grpc triggers, no ports
+	suite.Require().Empty((&Spec{}).GetGRPCPorts())
+}
+
+func (suite *TypesTestSuite) TestAddRuntimeLivenessStatus() {
+	firstRestartTime := time.Now()
+	lastRestartTime := firstRestartTime.Add(time.Minute)
+
+	runtimeLivenessStatus := RuntimeLivenessStatus{}
+	runtimeLivenessStatus.Add(&RuntimeLivenessStatus{
+		Restarts:        1,
+		LastRestartTime: &firstRestartTime,
+		LastError:       "first",
+	})
+	runtimeLivenessStatus.Add(&RuntimeLivenessStatus{
+		Restarts:        2,
+		LastRestartTime: &lastRestartTime,
+		LastError:       "last",
+	})
+
+	// the error of the wrapper restarted last is reported
+	suite.Require().Equal(RuntimeLivenessStatus{
+		Restarts:        3,
+		LastRestartTime: &lastRestartTime,
+		LastError:       "last",
+	}, runtimeLivenessStatus)
+
+	// unless another wrapper failed, even if it was restarted earlier
+	runtimeLivenessStatus.Add(&RuntimeLivenessStatus{
+		Restarts:        3,
+		LastRestartTime: &firstRestartTime,
+		LastError:       "failed",
+		Failed:          true,
+	})
+
+	suite.Require().Equal(RuntimeLivenessStatus{
+		Restarts:        6,
+		LastRestartTime: &lastRestartTime,
+		LastError:       "failed",
+		Failed:          true,
+	}, runtimeLivenessStatus)
+}
+
 func TestTypesTestSuite(t *testing.T) {
 	suite.Run(t, new(TypesTestSuite))
 }
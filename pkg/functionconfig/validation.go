@@ -0,0 +1,1234 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"k8s.io/api/core/v1"
+)
+
+// a platform images are built for, e.g. linux/amd64 or linux/arm/v7
+var buildPlatformRegex = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// the names of build args, which build hooks declare
+var buildArgNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ReservedFunctionNames can't be used by functions, as their resources (e.g. the nuclio-<name> deployment)
+// would collide with those of nuclio's own services
+var ReservedFunctionNames = []string{"autoscaler", "controller", "dashboard", "dlx"}
+
+type attributeType string
+
+const (
+	attributeTypeString attributeType = "string"
+	attributeTypeNumber attributeType = "number"
+	attributeTypeBool   attributeType = "boolean"
+	attributeTypeList   attributeType = "list"
+	attributeTypeMap    attributeType = "map"
+)
+
+// the types of well-known trigger attributes by trigger kind, so that a misconfigured attribute is reported
+// before deploying rather than when the processor starts. attributes that aren't listed aren't checked
+var triggerAttributeTypes = map[string]map[string]attributeType{
+	"http": {
+		"port":           attributeTypeNumber,
+		"ingresses":      attributeTypeMap,
+		"readBufferSize": attributeTypeNumber,
+		"cors":           attributeTypeMap,
+		"authentication": attributeTypeMap,
+	},
+	"cron": {
+		"schedule":        attributeTypeString,
+		"interval":        attributeTypeString,
+		"event":           attributeTypeMap,
+		"persist":         attributeTypeBool,
+		"catchUp":         attributeTypeBool,
+		"maxCatchUpTicks": attributeTypeNumber,
+	},
+	"kafka-cluster": {
+		"brokers":           attributeTypeList,
+		"topics":            attributeTypeList,
+		"consumerGroup":     attributeTypeString,
+		"initialOffset":     attributeTypeString,
+		"sessionTimeout":    attributeTypeString,
+		"heartbeatInterval": attributeTypeString,
+		"maxWaitTime":       attributeTypeString,
+		"fetchMin":          attributeTypeNumber,
+		"fetchDefault":      attributeTypeNumber,
+		"fetchMax":          attributeTypeNumber,
+	},
+	"rabbit-mq": {
+		"exchangeName": attributeTypeString,
+		"queueName":    attributeTypeString,
+		"topics":       attributeTypeList,
+		"queueType":    attributeTypeString,
+		"prefetch":     attributeTypeNumber,
+	},
+	"nats": {
+		"topic":     attributeTypeString,
+		"queueName": attributeTypeString,
+	},
+	"sqs": {
+		"queueURL":            attributeTypeString,
+		"queueName":           attributeTypeString,
+		"waitTimeSeconds":     attributeTypeNumber,
+		"maxNumberOfMessages": attributeTypeNumber,
+		"visibilityTimeout":   attributeTypeString,
+		"retryDelay":          attributeTypeString,
+	},
+	"kinesis": {
+		"streamName":      attributeTypeString,
+		"shards":          attributeTypeList,
+		"iteratorType":    attributeTypeString,
+		"pollingPeriod":   attributeTypeString,
+		"consumerName":    attributeTypeString,
+		"checkpointTable": attributeTypeString,
+		"applicationName": attributeTypeString,
+		"leaseDuration":   attributeTypeString,
+	},
+}
+
+// FieldError is a single problem in a function configuration
+type FieldError struct {
+
+	// JSON path of the field (e.g. spec.resources.requests.cpu)
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (fe *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+}
+
+// ValidationError holds all the problems found in a function configuration
+type ValidationError struct {
+	FieldErrors []FieldError `json:"fieldErrors"`
+}
+
+func (ve *ValidationError) Error() string {
+	lines := []string{fmt.Sprintf("Function configuration has %d error(s):", len(ve.FieldErrors))}
+
+	for _, fieldError := range ve.FieldErrors {
+		lines = append(lines, "  "+fieldError.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (ve *ValidationError) add(field string, format string, args ...interface{}) {
+	ve.FieldErrors = append(ve.FieldErrors, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks the configuration for problems that don't depend on the platform, returning a
+// *ValidationError holding all of them (rather than just the first) or nil if there are none
+func (c *Config) Validate() error {
+	validationError := &ValidationError{}
+
+	if c.Meta.Name == "" {
+		validationError.add("metadata.name", "must be set")
+	} else if common.StringInSlice(c.Meta.Name, ReservedFunctionNames) {
+		validationError.add("metadata.name", "is reserved, got %s", c.Meta.Name)
+	}
+
+	c.Spec.validateReplicas(validationError)
+	c.Spec.validateResources(validationError)
+	c.Spec.validateTriggers(validationError)
+	c.Spec.validateEnv(validationError)
+	c.Spec.validateVolumes(validationError)
+	c.Spec.validateSidecars(validationError)
+	c.Spec.validateFunctionReferences(validationError)
+	c.validateDependsOn(validationError)
+	c.validateGPU(validationError)
+
+	if c.Spec.EventRecording != nil {
+		c.Spec.EventRecording.validate("spec.eventRecording", validationError)
+	}
+
+	if c.Spec.InvocationInspector != nil {
+		c.Spec.InvocationInspector.validate("spec.invocationInspector", validationError)
+	}
+
+	if c.Spec.AsyncInvocation != nil {
+		c.Spec.AsyncInvocation.validate("spec.asyncInvocation", validationError)
+	}
+
+	if c.Spec.Readiness != nil {
+		c.Spec.Readiness.validate("spec.readiness", validationError)
+	}
+
+	if c.Spec.AutoScale != nil {
+		c.Spec.AutoScale.validate("spec.autoScale", c.Spec.Triggers, validationError)
+	}
+
+	if c.Spec.SecurityContext != nil {
+		c.Spec.SecurityContext.validate("spec.securityContext", validationError)
+	}
+
+	if c.Spec.TargetCPU != 0 && (c.Spec.TargetCPU < 1 || c.Spec.TargetCPU > 100) {
+		validationError.add("spec.targetCPU", "must be a percentage between 1 and 100, got %d", c.Spec.TargetCPU)
+	}
+
+	if c.Spec.ReadinessTimeoutSeconds < 0 {
+		validationError.add("spec.readinessTimeoutSeconds", "must not be negative")
+	}
+
+	if c.Spec.EventTimeout != "" {
+		if _, err := c.Spec.GetEventTimeout(); err != nil {
+			validationError.add("spec.eventTimeout", "invalid duration %s", c.Spec.EventTimeout)
+		}
+	}
+
+	c.Spec.validateAdmission(validationError)
+
+	if c.Spec.RuntimeLiveness != nil {
+		for _, livenessField := range []struct {
+			field string
+			value int
+		}{
+			{"spec.runtimeLiveness.intervalSeconds", c.Spec.RuntimeLiveness.IntervalSeconds},
+			{"spec.runtimeLiveness.timeoutSeconds", c.Spec.RuntimeLiveness.TimeoutSeconds},
+			{"spec.runtimeLiveness.maxRestarts", c.Spec.RuntimeLiveness.MaxRestarts},
+		} {
+			if livenessField.value < 0 {
+				validationError.add(livenessField.field, "must not be negative")
+			}
+		}
+	}
+
+	if c.Spec.TerminationGracePeriodSeconds != nil && *c.Spec.TerminationGracePeriodSeconds < 0 {
+		validationError.add("spec.terminationGracePeriodSeconds", "must not be negative")
+	}
+
+	if c.Spec.Build.Network != "" && !common.StringInSlice(c.Spec.Build.Network, BuildNetworks) {
+		validationError.add("spec.build.network",
+			"must be one of %s, got %s",
+			strings.Join(BuildNetworks, ", "),
+			c.Spec.Build.Network)
+	}
+
+	if c.Spec.Build.SBOMFormat != "" && !common.StringInSlice(c.Spec.Build.SBOMFormat, SBOMFormats) {
+		validationError.add("spec.build.sbomFormat",
+			"must be one of %s, got %s",
+			strings.Join(SBOMFormats, ", "),
+			c.Spec.Build.SBOMFormat)
+	}
+
+	if c.Spec.Build.SigningKey != "" && !c.Spec.Build.Sign {
+		validationError.add("spec.build.signingKey", "requires spec.build.sign")
+	}
+
+	if c.Spec.Build.Hooks != nil {
+		c.Spec.Build.Hooks.validate("spec.build.hooks", validationError)
+	}
+
+	c.validateBuildOS(validationError)
+
+	for platformIndex, buildPlatform := range c.Spec.Build.Platforms {
+		if !buildPlatformRegex.MatchString(buildPlatform) {
+			validationError.add(fmt.Sprintf("spec.build.platforms[%d]", platformIndex),
+				"must be of the form os/arch[/variant], got %s",
+				buildPlatform)
+		}
+	}
+
+	if len(validationError.FieldErrors) != 0 {
+		return validationError
+	}
+
+	return nil
+}
+
+func (s *Spec) validateReplicas(validationError *ValidationError) {
+	for _, replicasField := range []struct {
+		field    string
+		replicas *int
+	}{
+		{"spec.replicas", s.Replicas},
+		{"spec.minReplicas", s.MinReplicas},
+		{"spec.maxReplicas", s.MaxReplicas},
+	} {
+		if replicasField.replicas != nil && *replicasField.replicas < 0 {
+			validationError.add(replicasField.field, "must not be negative")
+		}
+	}
+
+	if s.MinReplicas != nil && s.MaxReplicas != nil && *s.MinReplicas > *s.MaxReplicas {
+		validationError.add("spec.minReplicas", "must be less than or equal to spec.maxReplicas (%d)", *s.MaxReplicas)
+	}
+}
+
+func (s *Spec) validateFunctionReferences(validationError *ValidationError) {
+	var aliases []string
+	for alias := range s.FunctionReferences {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	envNames := map[string]string{}
+	for _, env := range s.Env {
+		envNames[env.Name] = "spec.env"
+	}
+
+	for _, alias := range aliases {
+		aliasField := fmt.Sprintf("spec.functionReferences.%s", alias)
+
+		// the project doesn't matter here, only the form of the reference
+		functionReference, err := newFunctionReference(alias, s.FunctionReferences[alias], "default")
+		if err != nil {
+			validationError.add(aliasField, "must be <project>/<function> or <function>, got %s", s.FunctionReferences[alias])
+			continue
+		}
+
+		// aliases which differ only in case or punctuation would be given the same env var
+		envName := functionReference.GetEnvName()
+		if conflictingField, found := envNames[envName]; found {
+			validationError.add(aliasField, "its env var %s is already set by %s", envName, conflictingField)
+			continue
+		}
+
+		envNames[envName] = aliasField
+	}
+}
+
+func (c *Config) validateDependsOn(validationError *ValidationError) {
+	dependencyFields := map[Dependency]string{}
+
+	for dependencyIndex, dependsOn := range c.Spec.DependsOn {
+		dependencyField := fmt.Sprintf("spec.dependsOn[%d]", dependencyIndex)
+
+		dependency, err := newDependency(dependsOn)
+		if err != nil {
+			validationError.add(dependencyField,
+				"must be <function>, function/<function> or apigateway/<name>, got %s",
+				dependsOn)
+			continue
+		}
+
+		if dependency.Kind == DependencyKindFunction && dependency.Name == c.Meta.Name {
+			validationError.add(dependencyField, "a function can't depend on itself")
+			continue
+		}
+
+		if duplicateField, found := dependencyFields[*dependency]; found {
+			validationError.add(dependencyField, "duplicates %s", duplicateField)
+			continue
+		}
+
+		dependencyFields[*dependency] = dependencyField
+	}
+}
+
+func (c *Config) validateGPU(validationError *ValidationError) {
+	gpu := c.Spec.GPU
+	if gpu == nil {
+		return
+	}
+
+	if gpu.Count <= 0 {
+		validationError.add("spec.gpu.count", "must be positive, got %v", gpu.Count)
+	} else if gpu.Count > 1 && gpu.IsShared() {
+		validationError.add("spec.gpu.count", "must be a whole number, or a fraction of a GPU, got %v", gpu.Count)
+	}
+
+	if gpu.Sharing != "" && !common.StringInSlice(string(gpu.Sharing), GPUSharings) {
+		validationError.add("spec.gpu.sharing",
+			"must be one of %s, got %s",
+			strings.Join(GPUSharings, ", "),
+			gpu.Sharing)
+	}
+
+	for _, resourceList := range []v1.ResourceList{c.Spec.Resources.Requests, c.Spec.Resources.Limits} {
+		if _, found := resourceList["nvidia.com/gpu"]; found {
+			validationError.add("spec.gpu", "can't be given along with nvidia.com/gpu resources")
+			break
+		}
+	}
+
+	// GPUs are of no use to functions whose image lacks CUDA. it's either the base image the function is built
+	// on, or the image it's deployed from (when its code entry type is image)
+	if c.Meta.Annotations[FunctionAnnotationCUDAImage] == "true" {
+		return
+	}
+
+	switch {
+	case c.Spec.Build.BaseImage != "":
+		if !IsCUDAImage(c.Spec.Build.BaseImage) {
+			validationError.add("spec.build.baseImage",
+				"must be a CUDA-enabled image for functions requesting GPUs (or annotated with %s), got %s",
+				FunctionAnnotationCUDAImage,
+				c.Spec.Build.BaseImage)
+		}
+	case c.Spec.Build.CodeEntryType == "image" && c.Spec.Image != "":
+		if !IsCUDAImage(c.Spec.Image) {
+			validationError.add("spec.image",
+				"must be a CUDA-enabled image for functions requesting GPUs (or annotated with %s), got %s",
+				FunctionAnnotationCUDAImage,
+				c.Spec.Image)
+		}
+	default:
+		validationError.add("spec.build.baseImage",
+			"must be set to a CUDA-enabled image for functions requesting GPUs (or annotated with %s)",
+			FunctionAnnotationCUDAImage)
+	}
+}
+
+func (s *Spec) validateAdmission(validationError *ValidationError) {
+	if s.MaxInflightEvents < 0 {
+		validationError.add("spec.maxInflightEvents", "must not be negative")
+	}
+
+	if s.QueueSize < 0 {
+		validationError.add("spec.queueSize", "must not be negative")
+	}
+
+	if s.QueueSize > 0 && s.MaxInflightEvents == 0 {
+		validationError.add("spec.queueSize", "requires spec.maxInflightEvents")
+	}
+
+	if _, err := s.GetQueueTimeout(); err != nil {
+		validationError.add("spec.queueTimeout", "invalid duration %s", s.QueueTimeout)
+	}
+}
+
+func (s *Spec) validateResources(validationError *ValidationError) {
+	for _, resourceListName := range []string{"requests", "limits"} {
+		resourceList := s.Resources.Requests
+		if resourceListName == "limits" {
+			resourceList = s.Resources.Limits
+		}
+
+		for _, resourceName := range getSortedResourceNames(resourceList) {
+			quantity := resourceList[resourceName]
+			if quantity.Sign() < 0 {
+				validationError.add(fmt.Sprintf("spec.resources.%s.%s", resourceListName, resourceName),
+					"must not be negative, got %s",
+					quantity.String())
+			}
+		}
+	}
+
+	for _, resourceName := range getSortedResourceNames(s.Resources.Requests) {
+		request := s.Resources.Requests[resourceName]
+		limit, found := s.Resources.Limits[resourceName]
+
+		if found && request.Cmp(limit) > 0 {
+			validationError.add(fmt.Sprintf("spec.resources.requests.%s", resourceName),
+				"must be less than or equal to the limit (%s), got %s",
+				limit.String(),
+				request.String())
+		}
+	}
+}
+
+func (s *Spec) validateTriggers(validationError *ValidationError) {
+	var triggerNames []string
+	for triggerName := range s.Triggers {
+		triggerNames = append(triggerNames, triggerName)
+	}
+	sort.Strings(triggerNames)
+
+	var httpTriggerNames []string
+	for _, triggerName := range triggerNames {
+		trigger := s.Triggers[triggerName]
+		triggerField := fmt.Sprintf("spec.triggers.%s", triggerName)
+
+		if trigger.Kind == "" {
+			validationError.add(triggerField+".kind", "must be set")
+		}
+
+		if trigger.MaxWorkers < 0 {
+			validationError.add(triggerField+".maxWorkers", "must not be negative")
+		}
+
+		validateTriggerAttributes(triggerField+".attributes", trigger, validationError)
+
+		if trigger.Kind == "http" {
+			httpTriggerNames = append(httpTriggerNames, triggerName)
+		}
+
+		if trigger.DeadLetter != nil {
+			trigger.DeadLetter.validate(triggerField+".deadLetter", validationError)
+		}
+
+		if trigger.RetryPolicy != nil {
+			trigger.RetryPolicy.validate(triggerField+".retryPolicy", validationError)
+
+			// a dead-letter target's own retries would retry each event again, with no backoff
+			if trigger.DeadLetter != nil && trigger.DeadLetter.MaxRetries != 0 {
+				validationError.add(triggerField+".deadLetter.maxRetries",
+					"must not be set along with retryPolicy, set retryPolicy.maxRetries instead")
+			}
+		}
+
+		if trigger.Batch != nil {
+			trigger.Batch.validate(triggerField+".batch", trigger.Kind, validationError)
+		}
+
+		if _, err := trigger.GetEventTimeout(); err != nil {
+			validationError.add(triggerField+".eventTimeout", "must be a positive duration, got %s", trigger.EventTimeout)
+		}
+
+		if trigger.WorkerAutoscaling != nil {
+			trigger.WorkerAutoscaling.validate(triggerField+".workerAutoscaling",
+				trigger.Kind,
+				trigger.MaxWorkers,
+				validationError)
+		}
+
+		if trigger.CloudEvents != nil {
+			trigger.CloudEvents.validate(triggerField+".cloudEvents", trigger.Kind, validationError)
+		}
+
+		if trigger.RateLimit != nil {
+			trigger.RateLimit.validate(triggerField+".rateLimit", trigger.Kind, validationError)
+		}
+	}
+
+	if len(httpTriggerNames) > 1 {
+		validationError.add("spec.triggers",
+			"at most one http trigger is allowed, got %s",
+			strings.Join(httpTriggerNames, ", "))
+	}
+}
+
+func validateTriggerAttributes(attributesField string, trigger Trigger, validationError *ValidationError) {
+	attributeTypes := triggerAttributeTypes[trigger.Kind]
+
+	var attributeNames []string
+	for attributeName := range trigger.Attributes {
+		attributeNames = append(attributeNames, attributeName)
+	}
+	sort.Strings(attributeNames)
+
+	for _, attributeName := range attributeNames {
+		expectedType, found := attributeTypes[attributeName]
+		if !found {
+			continue
+		}
+
+		if actualType := getAttributeType(trigger.Attributes[attributeName]); actualType != expectedType {
+			validationError.add(attributesField+"."+attributeName,
+				"must be a %s, got a %s",
+				expectedType,
+				actualType)
+		}
+	}
+
+	if trigger.Kind == "http" {
+		if ingresses, ok := trigger.Attributes["ingresses"].(map[string]interface{}); ok {
+			validateIngresses(attributesField+".ingresses", ingresses, validationError)
+		}
+	}
+}
+
+// validateIngresses verifies each ingress is well formed, and that no two ingresses route the same host and path
+func validateIngresses(ingressesField string, ingresses map[string]interface{}, validationError *ValidationError) {
+	var ingressNames []string
+	for ingressName := range ingresses {
+		ingressNames = append(ingressNames, ingressName)
+	}
+	sort.Strings(ingressNames)
+
+	ingressNamesByHostPath := map[string]string{}
+
+	for _, ingressName := range ingressNames {
+		ingressField := ingressesField + "." + ingressName
+
+		ingress, ok := ingresses[ingressName].(map[string]interface{})
+		if !ok {
+			validationError.add(ingressField, "must be a map, got a %s", getAttributeType(ingresses[ingressName]))
+			continue
+		}
+
+		host := ""
+		if encodedHost, found := ingress["host"]; found {
+			if host, ok = encodedHost.(string); !ok {
+				validationError.add(ingressField+".host", "must be a string, got a %s", getAttributeType(encodedHost))
+				continue
+			}
+		}
+
+		paths, err := getStringList(ingress["paths"])
+		if err != nil {
+			validationError.add(ingressField+".paths", "must be a list of strings")
+			continue
+		}
+
+		for _, path := range paths {
+			hostPath := host + path
+			if conflictingIngressName, found := ingressNamesByHostPath[hostPath]; found {
+				validationError.add(ingressField+".paths",
+					"%s conflicts with ingress %s",
+					hostPath,
+					conflictingIngressName)
+				continue
+			}
+
+			ingressNamesByHostPath[hostPath] = ingressName
+		}
+	}
+}
+
+func getAttributeType(value interface{}) attributeType {
+	switch value.(type) {
+	case string:
+		return attributeTypeString
+	case int, int32, int64, uint, uint32, uint64, float32, float64:
+		return attributeTypeNumber
+	case bool:
+		return attributeTypeBool
+	case []interface{}, []string:
+		return attributeTypeList
+	case map[string]interface{}:
+		return attributeTypeMap
+	default:
+		return attributeType(fmt.Sprintf("%T", value))
+	}
+}
+
+func getStringList(value interface{}) ([]string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return typedValue, nil
+	case []interface{}:
+		var stringList []string
+		for _, element := range typedValue {
+			stringElement, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", element)
+			}
+
+			stringList = append(stringList, stringElement)
+		}
+
+		return stringList, nil
+	default:
+		return nil, fmt.Errorf("expected a list, got %T", value)
+	}
+}
+
+func (dl *DeadLetter) validate(deadLetterField string, validationError *ValidationError) {
+	if !common.StringInSlice(dl.Kind, DeadLetterKinds) {
+		validationError.add(deadLetterField+".kind",
+			"must be one of %s, got %s",
+			strings.Join(DeadLetterKinds, ", "),
+			dl.Kind)
+	}
+
+	if dl.URL == "" {
+		validationError.add(deadLetterField+".url", "must be set")
+	}
+
+	switch dl.Kind {
+	case DeadLetterKindKafka:
+		if dl.Topic == "" {
+			validationError.add(deadLetterField+".topic", "must be set for a kafka dead-letter")
+		}
+	case DeadLetterKindV3ioStream:
+		if dl.ContainerName == "" {
+			validationError.add(deadLetterField+".containerName", "must be set for a v3io stream dead-letter")
+		}
+
+		if dl.StreamPath == "" {
+			validationError.add(deadLetterField+".streamPath", "must be set for a v3io stream dead-letter")
+		}
+	}
+
+	if dl.MaxRetries < 0 {
+		validationError.add(deadLetterField+".maxRetries", "must not be negative")
+	}
+}
+
+func (rp *RetryPolicy) validate(retryPolicyField string, validationError *ValidationError) {
+	if rp.MaxRetries < 0 {
+		validationError.add(retryPolicyField+".maxRetries", "must not be negative")
+	}
+
+	initialInterval, err := rp.GetInitialInterval()
+	if err != nil {
+		validationError.add(retryPolicyField+".initialInterval", "must be a duration, got %s", rp.InitialInterval)
+	} else if initialInterval < 0 {
+		validationError.add(retryPolicyField+".initialInterval", "must not be negative")
+	}
+
+	maxInterval, err := rp.GetMaxInterval()
+	if err != nil {
+		validationError.add(retryPolicyField+".maxInterval", "must be a duration, got %s", rp.MaxInterval)
+	} else if maxInterval < initialInterval {
+		validationError.add(retryPolicyField+".maxInterval",
+			"must not be shorter than initialInterval (%s), got %s",
+			initialInterval,
+			maxInterval)
+	}
+
+	if rp.Multiplier != 0 && rp.Multiplier < 1 {
+		validationError.add(retryPolicyField+".multiplier", "must be at least 1, got %g", rp.Multiplier)
+	}
+
+	for statusCodeIndex, statusCode := range rp.RetryableStatusCodes {
+		if statusCode < 100 || statusCode > 599 {
+			validationError.add(fmt.Sprintf("%s.retryableStatusCodes[%d]", retryPolicyField, statusCodeIndex),
+				"must be an HTTP status code, got %d",
+				statusCode)
+		}
+	}
+}
+
+func (er *EventRecording) validate(eventRecordingField string, validationError *ValidationError) {
+	if !common.StringInSlice(er.Kind, EventRecordingKinds) {
+		validationError.add(eventRecordingField+".kind",
+			"must be one of %s, got %s",
+			strings.Join(EventRecordingKinds, ", "),
+			er.Kind)
+	}
+
+	switch er.Kind {
+	case EventRecordingKindLocalDir:
+		if er.Path == "" {
+			validationError.add(eventRecordingField+".path", "must be set for a localDir recording")
+		}
+	case EventRecordingKindS3:
+		if er.Bucket == "" {
+			validationError.add(eventRecordingField+".bucket", "must be set for an s3 recording")
+		}
+	case EventRecordingKindV3io:
+		if er.URL == "" {
+			validationError.add(eventRecordingField+".url", "must be set for a v3io recording")
+		}
+
+		if er.ContainerName == "" {
+			validationError.add(eventRecordingField+".containerName", "must be set for a v3io recording")
+		}
+	}
+
+	if sampleRatio := er.GetSampleRatio(); sampleRatio <= 0 || sampleRatio > 1 {
+		validationError.add(eventRecordingField+".sampleRatio", "must be above 0 and at most 1, got %g", sampleRatio)
+	}
+
+	if er.MaxBodySize < 0 {
+		validationError.add(eventRecordingField+".maxBodySize", "must not be negative")
+	}
+}
+
+func (ii *InvocationInspector) validate(invocationInspectorField string, validationError *ValidationError) {
+	if ii.MaxInvocations < 0 || ii.MaxInvocations > MaxInvocationInspectorMaxInvocations {
+		validationError.add(invocationInspectorField+".maxInvocations",
+			"must be between 0 and %d, got %d",
+			MaxInvocationInspectorMaxInvocations,
+			ii.MaxInvocations)
+	}
+
+	if sampleRatio := ii.GetSampleRatio(); sampleRatio <= 0 || sampleRatio > 1 {
+		validationError.add(invocationInspectorField+".sampleRatio",
+			"must be above 0 and at most 1, got %g",
+			sampleRatio)
+	}
+
+	if ii.MaxBodySize < 0 || ii.MaxBodySize > MaxInvocationInspectorMaxBodySize {
+		validationError.add(invocationInspectorField+".maxBodySize",
+			"must be between 0 and %d, got %d",
+			MaxInvocationInspectorMaxBodySize,
+			ii.MaxBodySize)
+	}
+}
+
+func (r *Readiness) validate(readinessField string, validationError *ValidationError) {
+	if r.PeriodSeconds < 0 {
+		validationError.add(readinessField+".periodSeconds", "must not be negative")
+	}
+
+	if r.TimeoutSeconds < 0 {
+		validationError.add(readinessField+".timeoutSeconds", "must not be negative")
+	}
+
+	dependencyNames := map[string]bool{}
+
+	for dependencyIndex, dependency := range r.Dependencies {
+		dependencyField := fmt.Sprintf("%s.dependencies[%d]", readinessField, dependencyIndex)
+
+		if dependency.Name == "" {
+			validationError.add(dependencyField+".name", "must be set")
+		} else if dependencyNames[dependency.Name] {
+			validationError.add(dependencyField+".name", "must be unique, %s is repeated", dependency.Name)
+		}
+
+		dependencyNames[dependency.Name] = true
+
+		switch dependency.Kind {
+		case ReadinessDependencyKindTCP:
+			if _, _, err := net.SplitHostPort(dependency.Address); err != nil {
+				validationError.add(dependencyField+".address", "must be a host:port address, got %q", dependency.Address)
+			}
+		case ReadinessDependencyKindHTTP:
+			if parsedURL, err := url.Parse(dependency.URL); err != nil || parsedURL.Host == "" ||
+				(parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+				validationError.add(dependencyField+".url", "must be an http or https URL, got %q", dependency.URL)
+			}
+		case ReadinessDependencyKindHandler:
+			if dependency.Path == "" {
+				validationError.add(dependencyField+".path", "must be set for a handler dependency")
+			}
+		default:
+			validationError.add(dependencyField+".kind",
+				"must be one of %s, got %s",
+				strings.Join(ReadinessDependencyKinds, ", "),
+				dependency.Kind)
+		}
+	}
+}
+
+func (ai *AsyncInvocation) validate(asyncInvocationField string, validationError *ValidationError) {
+	if !common.StringInSlice(ai.GetDelivery(), AsyncInvocationDeliveries) {
+		validationError.add(asyncInvocationField+".delivery",
+			"must be one of %s, got %s",
+			strings.Join(AsyncInvocationDeliveries, ", "),
+			ai.Delivery)
+	}
+
+	if ai.GetDelivery() == AsyncInvocationDeliveryPersisted && ai.Path == "" {
+		validationError.add(asyncInvocationField+".path", "must be set for persisted delivery")
+	}
+
+	if ai.QueueSize < 0 {
+		validationError.add(asyncInvocationField+".queueSize", "must not be negative")
+	}
+
+	if ai.Workers < 0 {
+		validationError.add(asyncInvocationField+".workers", "must not be negative")
+	}
+
+	if ai.GetMaxRetries() < 0 {
+		validationError.add(asyncInvocationField+".maxRetries", "must not be negative")
+	}
+}
+
+func (as *AutoScale) validate(autoScaleField string, triggers map[string]Trigger, validationError *ValidationError) {
+	if as.LagThreshold <= 0 {
+		validationError.add(autoScaleField+".lagThreshold", "must be positive")
+	}
+
+	if !common.StringInSlice(as.GetMode(), AutoScaleModes) {
+		validationError.add(autoScaleField+".mode",
+			"must be one of %s, got %s",
+			strings.Join(AutoScaleModes, ", "),
+			as.Mode)
+
+		return
+	}
+
+	for _, durationField := range []struct {
+		field string
+		value string
+	}{
+		{autoScaleField + ".pollingInterval", as.PollingInterval},
+		{autoScaleField + ".cooldownPeriod", as.CooldownPeriod},
+	} {
+		if durationField.value == "" {
+			continue
+		}
+
+		if duration, err := time.ParseDuration(durationField.value); err != nil || duration <= 0 {
+			validationError.add(durationField.field, "must be a positive duration, got %s", durationField.value)
+		}
+	}
+
+	// the function must have a trigger whose lag it can be scaled on
+	triggerKinds := AutoScaleTriggerKinds[as.GetMode()]
+	for _, trigger := range triggers {
+		if common.StringInSlice(trigger.Kind, triggerKinds) {
+			return
+		}
+	}
+
+	validationError.add(autoScaleField,
+		"requires a %s trigger in %s mode",
+		strings.Join(triggerKinds, ", "),
+		as.GetMode())
+}
+
+func (sc *SecurityContext) validate(securityContextField string, validationError *ValidationError) {
+	for _, idField := range []struct {
+		field string
+		value *int64
+	}{
+		{securityContextField + ".runAsUser", sc.RunAsUser},
+		{securityContextField + ".runAsGroup", sc.RunAsGroup},
+	} {
+		if idField.value != nil && *idField.value < 0 {
+			validationError.add(idField.field, "must not be negative, got %d", *idField.value)
+		}
+	}
+
+	// docker runs a container as a group only along with a user
+	if sc.RunAsGroup != nil && sc.RunAsUser == nil {
+		validationError.add(securityContextField+".runAsGroup", "requires runAsUser")
+	}
+
+	for capabilityIndex, capability := range sc.DropCapabilities {
+		if capability == "" || strings.ContainsAny(capability, " \t") {
+			validationError.add(fmt.Sprintf("%s.dropCapabilities[%d]", securityContextField, capabilityIndex),
+				"must be a capability name, got %q",
+				capability)
+		}
+	}
+
+	switch {
+	case sc.SeccompProfile == "",
+		sc.SeccompProfile == SeccompProfileRuntimeDefault,
+		sc.SeccompProfile == SeccompProfileUnconfined:
+	case strings.HasPrefix(sc.SeccompProfile, SeccompProfileLocalhostPrefix) &&
+		len(sc.SeccompProfile) > len(SeccompProfileLocalhostPrefix):
+	default:
+		validationError.add(securityContextField+".seccompProfile",
+			"must be %s, %s or %s<profile>, got %s",
+			SeccompProfileRuntimeDefault,
+			SeccompProfileUnconfined,
+			SeccompProfileLocalhostPrefix,
+			sc.SeccompProfile)
+	}
+}
+
+// validateBuildOS validates that functions built for windows only use what windows images support
+func (c *Config) validateBuildOS(validationError *ValidationError) {
+	if c.Spec.Build.OS == "" {
+		return
+	}
+
+	if !common.StringInSlice(c.Spec.Build.OS, BuildOSes) {
+		validationError.add("spec.build.os",
+			"must be one of %s, got %s",
+			strings.Join(BuildOSes, ", "),
+			c.Spec.Build.OS)
+		return
+	}
+
+	if c.Spec.Build.OS != BuildOSWindows {
+		return
+	}
+
+	// the runtime may be versioned (e.g. shell:1.0)
+	runtimeName := strings.Split(c.Spec.Runtime, ":")[0]
+	if !common.StringInSlice(runtimeName, WindowsRuntimes) {
+		validationError.add("spec.build.os",
+			"windows is only supported by the %s runtimes, got %s",
+			strings.Join(WindowsRuntimes, ", "),
+			c.Spec.Runtime)
+	}
+
+	if len(c.Spec.Build.Platforms) != 0 {
+		validationError.add("spec.build.platforms", "isn't supported when building for windows")
+	}
+
+	if c.Spec.Build.PersistentCache {
+		validationError.add("spec.build.persistentCache", "isn't supported when building for windows")
+	}
+
+	// build secrets are mounted by BuildKit, which doesn't build windows images
+	if c.Spec.Build.Hooks != nil && len(c.Spec.Build.Hooks.GetSecrets()) != 0 {
+		validationError.add("spec.build.hooks", "secrets aren't supported when building for windows")
+	}
+}
+
+func (bh *BuildHooks) validate(buildHooksField string, validationError *ValidationError) {
+
+	// a secret is mounted by its ID, so the stages may share one, but only with the same source
+	secretsByID := map[string]BuildSecret{}
+
+	for _, stageName := range []string{
+		BuildHookStagePreCopy,
+		BuildHookStagePostInstall,
+		BuildHookStagePreFinal,
+	} {
+		stage, found := bh.GetStages()[stageName]
+		if !found {
+			continue
+		}
+
+		stageField := fmt.Sprintf("%s.%s", buildHooksField, stageName)
+
+		for commandIndex, command := range stage.Commands {
+			if strings.TrimSpace(command) == "@nuclio.postCopy" {
+				validationError.add(fmt.Sprintf("%s.commands[%d]", stageField, commandIndex),
+					"@nuclio.postCopy is only supported in spec.build.commands")
+			}
+		}
+
+		for argName := range stage.Args {
+			if !buildArgNameRegex.MatchString(argName) {
+				validationError.add(stageField+".args", "invalid build arg name %s", argName)
+			} else if strings.HasPrefix(argName, "NUCLIO_") {
+				validationError.add(stageField+".args", "build arg %s is reserved", argName)
+			}
+		}
+
+		for secretIndex, secret := range stage.Secrets {
+			secretField := fmt.Sprintf("%s.secrets[%d]", stageField, secretIndex)
+
+			if secret.ID == "" {
+				validationError.add(secretField+".id", "must be set")
+			}
+
+			if (secret.Src == "") == (secret.Env == "") {
+				validationError.add(secretField, "exactly one of src and env must be set")
+			}
+
+			if existingSecret, found := secretsByID[secret.ID]; found && existingSecret != secret {
+				validationError.add(secretField+".id", "%s is used by another secret, with another source", secret.ID)
+			}
+
+			secretsByID[secret.ID] = secret
+		}
+	}
+}
+
+func (b *Batch) validate(batchField string, triggerKind string, validationError *ValidationError) {
+	if !common.StringInSlice(triggerKind, BatchTriggerKinds) {
+		validationError.add(batchField,
+			"is only supported by %s triggers, got %s",
+			strings.Join(BatchTriggerKinds, ", "),
+			triggerKind)
+	}
+
+	if b.MaxSize <= 0 {
+		validationError.add(batchField+".maxSize", "must be positive")
+	}
+
+	if b.MaxWaitMs < 0 {
+		validationError.add(batchField+".maxWaitMs", "must not be negative")
+	}
+}
+
+func (ce *CloudEvents) validate(cloudEventsField string, triggerKind string, validationError *ValidationError) {
+	if ce.Mode != "" && !common.StringInSlice(ce.Mode, CloudEventsModes) {
+		validationError.add(cloudEventsField+".mode",
+			"must be one of %s, got %s",
+			strings.Join(CloudEventsModes, ", "),
+			ce.Mode)
+	}
+
+	if ce.ResponseMode == "" {
+		if ce.ResponseType != "" || ce.ResponseSource != "" {
+			validationError.add(cloudEventsField+".responseMode", "must be set along with responseType or responseSource")
+		}
+
+		return
+	}
+
+	if !common.StringInSlice(ce.ResponseMode, CloudEventsResponseModes) {
+		validationError.add(cloudEventsField+".responseMode",
+			"must be one of %s, got %s",
+			strings.Join(CloudEventsResponseModes, ", "),
+			ce.ResponseMode)
+	}
+
+	if !common.StringInSlice(triggerKind, CloudEventsResponseTriggerKinds) {
+		validationError.add(cloudEventsField+".responseMode",
+			"is only supported by %s triggers, got %s",
+			strings.Join(CloudEventsResponseTriggerKinds, ", "),
+			triggerKind)
+	}
+}
+
+func (wa *WorkerAutoscaling) validate(workerAutoscalingField string,
+	triggerKind string,
+	maxWorkers int,
+	validationError *ValidationError) {
+	if !common.StringInSlice(triggerKind, WorkerAutoscalingTriggerKinds) {
+		validationError.add(workerAutoscalingField,
+			"is only supported by %s triggers, got %s",
+			strings.Join(WorkerAutoscalingTriggerKinds, ", "),
+			triggerKind)
+	}
+
+	if wa.MinWorkers < 0 {
+		validationError.add(workerAutoscalingField+".minWorkers", "must not be negative")
+	} else if maxWorkers > 0 && wa.MinWorkers > maxWorkers {
+		validationError.add(workerAutoscalingField+".minWorkers",
+			"must be less than or equal to maxWorkers (%d), got %d",
+			maxWorkers,
+			wa.MinWorkers)
+	}
+
+	if wa.EvaluationIntervalSeconds < 0 {
+		validationError.add(workerAutoscalingField+".evaluationIntervalSeconds", "must not be negative")
+	}
+
+	if wa.MaxLatencyIncreasePercent < 0 {
+		validationError.add(workerAutoscalingField+".maxLatencyIncreasePercent", "must not be negative")
+	}
+}
+
+func (rl *RateLimit) validate(rateLimitField string, triggerKind string, validationError *ValidationError) {
+	if rl.RPS <= 0 {
+		validationError.add(rateLimitField+".rps", "must be positive")
+	}
+
+	if rl.Burst < 0 {
+		validationError.add(rateLimitField+".burst", "must not be negative")
+	}
+
+	if rl.Key == "" {
+		return
+	}
+
+	if rl.Key != RateLimitKeyIP && rl.GetKeyHeaderName() == "" {
+		validationError.add(rateLimitField+".key",
+			"must be either %s or %s<name>, got %s",
+			RateLimitKeyIP,
+			RateLimitKeyHeaderPrefix,
+			rl.Key)
+	}
+
+	if !common.StringInSlice(triggerKind, RateLimitKeyTriggerKinds) {
+		validationError.add(rateLimitField+".key",
+			"is only supported by %s triggers, got %s",
+			strings.Join(RateLimitKeyTriggerKinds, ", "),
+			triggerKind)
+	}
+}
+
+func (s *Spec) validateEnv(validationError *ValidationError) {
+	envNames := map[string]bool{}
+
+	for envIndex, envVar := range s.Env {
+		if envVar.Name == "" {
+			validationError.add(fmt.Sprintf("spec.env[%d].name", envIndex), "must be set")
+		}
+
+		envNames[envVar.Name] = true
+	}
+
+	for envIndex, envVar := range s.ExternalSecretEnv {
+		envField := fmt.Sprintf("spec.externalSecretEnv[%d]", envIndex)
+
+		if envVar.Name == "" {
+			validationError.add(envField+".name", "must be set")
+		} else if envNames[envVar.Name] {
+			validationError.add(envField+".name", "%s is already set by spec.env", envVar.Name)
+		}
+
+		externalSecret := envVar.ValueFrom.ExternalSecret
+		if externalSecret == nil {
+			validationError.add(envField+".valueFrom.externalSecret", "must be set")
+			continue
+		}
+
+		if externalSecret.Provider == "" {
+			validationError.add(envField+".valueFrom.externalSecret.provider", "must be set")
+		}
+
+		if externalSecret.Name == "" {
+			validationError.add(envField+".valueFrom.externalSecret.name", "must be set")
+		}
+	}
+}
+
+func (s *Spec) validateVolumes(validationError *ValidationError) {
+	for volumeIndex, volume := range s.Volumes {
+		volumeField := fmt.Sprintf("spec.volumes[%d]", volumeIndex)
+
+		if volume.Volume.Name == "" {
+			validationError.add(volumeField+".volume.name", "must be set")
+		}
+
+		if volume.VolumeMount.MountPath == "" {
+			validationError.add(volumeField+".volumeMount.mountPath", "must be set")
+		}
+	}
+
+	for envFromSecretIndex, envFromSecret := range s.EnvFromSecrets {
+		if envFromSecret.Name == "" {
+			validationError.add(fmt.Sprintf("spec.envFromSecrets[%d].name", envFromSecretIndex), "must be set")
+		}
+	}
+
+	for volumeFromSecretIndex, volumeFromSecret := range s.VolumesFromSecrets {
+		volumeFromSecretField := fmt.Sprintf("spec.volumesFromSecrets[%d]", volumeFromSecretIndex)
+
+		if volumeFromSecret.Name == "" {
+			validationError.add(volumeFromSecretField+".name", "must be set")
+		}
+
+		if !path.IsAbs(volumeFromSecret.MountPath) {
+			validationError.add(volumeFromSecretField+".mountPath",
+				"must be an absolute path, got %s",
+				volumeFromSecret.MountPath)
+		}
+	}
+}
+
+func (s *Spec) validateSidecars(validationError *ValidationError) {
+	volumeNames := map[string]bool{}
+	for _, volume := range s.Volumes {
+		volumeNames[volume.Volume.Name] = true
+	}
+
+	// the processor's container is named nuclio
+	containerNames := map[string]bool{"nuclio": true}
+
+	for sidecarIndex, sidecar := range s.Sidecars {
+		sidecarField := fmt.Sprintf("spec.sidecars[%d]", sidecarIndex)
+
+		if sidecar.Name == "" {
+			validationError.add(sidecarField+".name", "must be set")
+		} else if containerNames[sidecar.Name] {
+			validationError.add(sidecarField+".name", "must be unique (and not nuclio), got %s", sidecar.Name)
+		}
+
+		containerNames[sidecar.Name] = true
+
+		if sidecar.Image == "" {
+			validationError.add(sidecarField+".image", "must be set")
+		}
+
+		for volumeMountIndex, volumeMount := range sidecar.VolumeMounts {
+			if !volumeNames[volumeMount.Name] {
+				validationError.add(fmt.Sprintf("%s.volumeMounts[%d].name", sidecarField, volumeMountIndex),
+					"must be the name of one of the function's volumes, got %s",
+					volumeMount.Name)
+			}
+		}
+	}
+}
+
+func getSortedResourceNames(resourceList v1.ResourceList) []v1.ResourceName {
+	var resourceNames []v1.ResourceName
+	for resourceName := range resourceList {
+		resourceNames = append(resourceNames, resourceName)
+	}
+
+	sort.Slice(resourceNames, func(i, j int) bool {
+		return resourceNames[i] < resourceNames[j]
+	})
+
+	return resourceNames
+}
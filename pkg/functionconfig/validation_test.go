@@ -0,0 +1,1005 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type ValidationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ValidationTestSuite) TestValidConfig() {
+	replicas := 2
+
+	config := Config{
+		Meta: Meta{
+			Name: "valid",
+		},
+		Spec: Spec{
+			MinReplicas: &replicas,
+			MaxReplicas: &replicas,
+			TargetCPU:   75,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			},
+			Triggers: map[string]Trigger{
+				"http": {Kind: "http", MaxWorkers: 4},
+				"cron": {Kind: "cron", EventTimeout: "10s"},
+			},
+			EventTimeout:      "30s",
+			MaxInflightEvents: 8,
+			QueueSize:         16,
+			QueueTimeout:      "5s",
+		},
+	}
+
+	suite.Require().NoError(config.Validate())
+}
+
+func (suite *ValidationTestSuite) TestAllErrorsReported() {
+	minReplicas := 3
+	maxReplicas := 2
+	var terminationGracePeriodSeconds int64 = -1
+
+	config := Config{
+		Spec: Spec{
+			MinReplicas: &minReplicas,
+			MaxReplicas: &maxReplicas,
+			TargetCPU:   200,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("-1Gi"),
+				},
+				Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			},
+			Triggers: map[string]Trigger{
+				"first-http":  {Kind: "http"},
+				"second-http": {Kind: "http", MaxWorkers: -1},
+				"unknown":     {},
+				"stream": {
+					Kind:       "kafka-cluster",
+					DeadLetter: &DeadLetter{Kind: DeadLetterKindKafka, URL: "kafka:9092", MaxRetries: -1},
+					Batch:      &Batch{MaxWaitMs: -1},
+
+					// the dead-letter target mustn't retry events too
+					RetryPolicy: &RetryPolicy{MaxRetries: 3},
+				},
+				"timer": {
+					Kind:              "cron",
+					MaxWorkers:        2,
+					Batch:             &Batch{MaxSize: 10},
+					EventTimeout:      "0s",
+					WorkerAutoscaling: &WorkerAutoscaling{MinWorkers: 3, EvaluationIntervalSeconds: -1},
+					RetryPolicy: &RetryPolicy{
+						MaxRetries:           -1,
+						InitialInterval:      "soon",
+						Multiplier:           0.5,
+						RetryableStatusCodes: []int{503, 42},
+					},
+				},
+			},
+			Env:                []v1.EnvVar{{Name: "SOME_ENV", Value: "value"}, {Value: "nameless"}},
+			Volumes:            []Volume{{Volume: v1.Volume{Name: "volume-1"}}},
+			EnvFromSecrets:     []EnvFromSecret{{Name: "credentials"}, {}},
+			VolumesFromSecrets: []VolumeFromSecret{{Name: "certificates", MountPath: "certs"}},
+			EventTimeout:       "forever",
+			QueueSize:          10,
+			QueueTimeout:       "-1s",
+			RuntimeLiveness:    &RuntimeLiveness{TimeoutSeconds: -5},
+			Build: Build{
+				Network:    "bridge",
+				Platforms:  []string{"linux/arm64", "arm64"},
+				SBOMFormat: "xml",
+				SigningKey: "cosign.key",
+			},
+
+			TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	validationError, ok := err.(*ValidationError)
+	suite.Require().True(ok, "Expected a validation error, got: %s", err.Error())
+
+	var fields []string
+	for _, fieldError := range validationError.FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"metadata.name",
+		"spec.minReplicas",
+		"spec.resources.requests.memory",
+		"spec.resources.requests.cpu",
+		"spec.triggers.second-http.maxWorkers",
+		"spec.triggers.stream.deadLetter.topic",
+		"spec.triggers.stream.deadLetter.maxRetries",
+		"spec.triggers.stream.deadLetter.maxRetries",
+		"spec.triggers.stream.batch.maxSize",
+		"spec.triggers.stream.batch.maxWaitMs",
+		"spec.triggers.timer.retryPolicy.maxRetries",
+		"spec.triggers.timer.retryPolicy.initialInterval",
+		"spec.triggers.timer.retryPolicy.multiplier",
+		"spec.triggers.timer.retryPolicy.retryableStatusCodes[1]",
+		"spec.triggers.timer.batch",
+		"spec.triggers.timer.eventTimeout",
+		"spec.triggers.timer.workerAutoscaling",
+		"spec.triggers.timer.workerAutoscaling.minWorkers",
+		"spec.triggers.timer.workerAutoscaling.evaluationIntervalSeconds",
+		"spec.triggers.unknown.kind",
+		"spec.triggers",
+		"spec.env[1].name",
+		"spec.volumes[0].volumeMount.mountPath",
+		"spec.envFromSecrets[1].name",
+		"spec.volumesFromSecrets[0].mountPath",
+		"spec.targetCPU",
+		"spec.eventTimeout",
+		"spec.queueSize",
+		"spec.queueTimeout",
+		"spec.runtimeLiveness.timeoutSeconds",
+		"spec.terminationGracePeriodSeconds",
+		"spec.build.network",
+		"spec.build.sbomFormat",
+		"spec.build.signingKey",
+		"spec.build.platforms[1]",
+	}, fields)
+
+	// every problem is rendered in the error message
+	for _, fieldError := range validationError.FieldErrors {
+		suite.Require().Contains(err.Error(), fieldError.Field+": "+fieldError.Message)
+	}
+
+	suite.Require().Contains(err.Error(), "spec.resources.requests.cpu: must be less than or equal to the limit (1), got 2")
+	suite.Require().Contains(err.Error(), "spec.triggers: at most one http trigger is allowed, got first-http, second-http")
+	suite.Require().Contains(err.Error(), "spec.build.network: must be one of host, default, none, got bridge")
+	suite.Require().Contains(err.Error(), "spec.build.platforms[1]: must be of the form os/arch[/variant], got arm64")
+}
+
+func (suite *ValidationTestSuite) TestSidecars() {
+	config := Config{
+		Meta: Meta{
+			Name: "sidecars",
+		},
+		Spec: Spec{
+			Volumes: []Volume{
+				{
+					Volume:      v1.Volume{Name: "shared"},
+					VolumeMount: v1.VolumeMount{Name: "shared", MountPath: "/shared"},
+				},
+			},
+			Sidecars: []v1.Container{
+				{
+					Name:         "envoy",
+					Image:        "envoyproxy/envoy:v1.16.0",
+					VolumeMounts: []v1.VolumeMount{{Name: "shared", MountPath: "/etc/envoy"}},
+				},
+				{Name: "agent", Image: "my-registry/agent:1.0.0"},
+			},
+		},
+	}
+
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.Sidecars = []v1.Container{
+		{Name: "nuclio", Image: "my-registry/agent:1.0.0"},
+		{Name: "agent"},
+		{Name: "agent", Image: "my-registry/agent:1.0.0"},
+		{Image: "my-registry/agent:1.0.0", VolumeMounts: []v1.VolumeMount{{Name: "unknown", MountPath: "/data"}}},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.sidecars[0].name",
+		"spec.sidecars[1].image",
+		"spec.sidecars[2].name",
+		"spec.sidecars[3].name",
+		"spec.sidecars[3].volumeMounts[0].name",
+	}, fields)
+	suite.Require().Contains(err.Error(), "spec.sidecars[2].name: must be unique (and not nuclio), got agent")
+}
+
+func (suite *ValidationTestSuite) TestBuildOS() {
+	config := Config{
+		Meta: Meta{
+			Name: "windows",
+		},
+		Spec: Spec{
+			Runtime: "dotnetcore",
+			Build: Build{
+				OS: BuildOSWindows,
+			},
+		},
+	}
+
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.Runtime = "shell:1.0"
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.Runtime = "python:3.7"
+	config.Spec.Build.Platforms = []string{"windows/amd64"}
+	config.Spec.Build.PersistentCache = true
+	config.Spec.Build.Hooks = &BuildHooks{
+		PreCopy: &BuildHook{
+			Secrets: []BuildSecret{{ID: "token", Env: "TOKEN"}},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.build.os",
+		"spec.build.platforms",
+		"spec.build.persistentCache",
+		"spec.build.hooks",
+	}, fields)
+
+	config.Spec.Build = Build{OS: "darwin"}
+	err = config.Validate()
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "spec.build.os: must be one of linux, windows, got darwin")
+}
+
+func (suite *ValidationTestSuite) TestExternalSecretEnv() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Env: []v1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+			ExternalSecretEnv: []ExternalSecretEnvVar{
+				{
+					Name: "DB_PASSWORD",
+					ValueFrom: ExternalSecretEnvVarSource{
+						ExternalSecret: &ExternalSecretRef{Provider: "vault", Name: "db", Key: "password"},
+					},
+				},
+			},
+		},
+	}
+
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.ExternalSecretEnv = []ExternalSecretEnvVar{
+		{
+			Name: "LOG_LEVEL",
+			ValueFrom: ExternalSecretEnvVarSource{
+				ExternalSecret: &ExternalSecretRef{Provider: "vault", Name: "logging"},
+			},
+		},
+		{Name: "DB_PASSWORD"},
+		{ValueFrom: ExternalSecretEnvVarSource{ExternalSecret: &ExternalSecretRef{}}},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.externalSecretEnv[0].name",
+		"spec.externalSecretEnv[1].valueFrom.externalSecret",
+		"spec.externalSecretEnv[2].name",
+		"spec.externalSecretEnv[2].valueFrom.externalSecret.provider",
+		"spec.externalSecretEnv[2].valueFrom.externalSecret.name",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestTriggerAttributesAndIngresses() {
+	config := Config{
+		Meta: Meta{
+			Name: "dashboard",
+		},
+		Spec: Spec{
+			Triggers: map[string]Trigger{
+				"http": {
+					Kind: "http",
+					Attributes: map[string]interface{}{
+						"port": "8080",
+						"ingresses": map[string]interface{}{
+							"first": map[string]interface{}{
+								"host":  "example.com",
+								"paths": []interface{}{"/api", "/"},
+							},
+							"second": map[string]interface{}{
+								"host":  "example.com",
+								"paths": []string{"/api"},
+							},
+							"third": map[string]interface{}{
+								"paths": "/",
+							},
+						},
+					},
+				},
+				"stream": {
+					Kind: "kafka-cluster",
+					Attributes: map[string]interface{}{
+						"topics":        "events",
+						"consumerGroup": "group",
+						"fetchMin":      float64(1),
+						"custom":        true,
+					},
+				},
+			},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"metadata.name",
+		"spec.triggers.http.attributes.port",
+		"spec.triggers.http.attributes.ingresses.second.paths",
+		"spec.triggers.http.attributes.ingresses.third.paths",
+		"spec.triggers.stream.attributes.topics",
+	}, fields)
+
+	suite.Require().Contains(err.Error(), "metadata.name: is reserved, got dashboard")
+	suite.Require().Contains(err.Error(), "spec.triggers.http.attributes.port: must be a number, got a string")
+	suite.Require().Contains(err.Error(),
+		"spec.triggers.http.attributes.ingresses.second.paths: example.com/api conflicts with ingress first")
+}
+
+func (suite *ValidationTestSuite) TestFunctionReferences() {
+	config := Config{
+		Meta: Meta{
+			Name: "caller",
+		},
+		Spec: Spec{
+			Env: []v1.EnvVar{{Name: "NUCLIO_FUNCTION_URL_TAKEN", Value: "http://example.com"}},
+			FunctionReferences: map[string]string{
+				"billing":       "payments/billing",
+				"mailer":        "mailer",
+				"my-mailer":     "mailer",
+				"my_mailer":     "mailer",
+				"missing-name":  "payments/",
+				"nested":        "a/b/c",
+				"taken":         "other",
+				"empty-project": "/billing",
+			},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.functionReferences.empty-project",
+		"spec.functionReferences.missing-name",
+		"spec.functionReferences.my_mailer",
+		"spec.functionReferences.nested",
+		"spec.functionReferences.taken",
+	}, fields)
+
+	suite.Require().Contains(err.Error(),
+		"spec.functionReferences.my_mailer: its env var NUCLIO_FUNCTION_URL_MY_MAILER is already set by spec.functionReferences.my-mailer")
+	suite.Require().Contains(err.Error(),
+		"spec.functionReferences.taken: its env var NUCLIO_FUNCTION_URL_TAKEN is already set by spec.env")
+
+	// references without a project are to functions in the function's project
+	config.Spec.FunctionReferences = map[string]string{
+		"mailer":  "mailer",
+		"billing": "payments/billing",
+	}
+	suite.Require().NoError(config.Validate())
+
+	functionReferences, err := config.Spec.GetFunctionReferences("shop")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]FunctionReference{
+		{Alias: "billing", ProjectName: "payments", FunctionName: "billing"},
+		{Alias: "mailer", ProjectName: "shop", FunctionName: "mailer"},
+	}, functionReferences)
+	suite.Require().Equal("NUCLIO_FUNCTION_URL_BILLING", functionReferences[0].GetEnvName())
+}
+
+func (suite *ValidationTestSuite) TestDependsOn() {
+	config := Config{
+		Meta: Meta{
+			Name: "orders",
+		},
+		Spec: Spec{
+			DependsOn: []string{
+				"inventory",
+				"orders",
+				"function/inventory",
+				"apigateway/",
+				"project/payments",
+				"apigateway/a/b",
+			},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.dependsOn[1]",
+		"spec.dependsOn[2]",
+		"spec.dependsOn[3]",
+		"spec.dependsOn[4]",
+		"spec.dependsOn[5]",
+	}, fields)
+
+	suite.Require().Contains(err.Error(), "spec.dependsOn[1]: a function can't depend on itself")
+	suite.Require().Contains(err.Error(), "spec.dependsOn[2]: duplicates spec.dependsOn[0]")
+
+	// dependencies without a kind are on functions
+	config.Spec.DependsOn = []string{"inventory", "apigateway/orders"}
+	suite.Require().NoError(config.Validate())
+
+	dependencies, err := config.Spec.GetDependencies()
+	suite.Require().NoError(err)
+	suite.Require().Equal([]Dependency{
+		{Kind: DependencyKindFunction, Name: "inventory"},
+		{Kind: DependencyKindAPIGateway, Name: "orders"},
+	}, dependencies)
+}
+
+func (suite *ValidationTestSuite) TestGPU() {
+	config := Config{
+		Meta: Meta{
+			Name: "inference",
+		},
+		Spec: Spec{
+			GPU: &GPU{Count: 0.5, Sharing: GPUSharingMPS},
+			Build: Build{
+				BaseImage: "nvidia/cuda:10.2-cudnn7-runtime-ubuntu18.04",
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.GPU = &GPU{Count: 2}
+	config.Spec.Build.BaseImage = "tensorflow/tensorflow:2.3.0-gpu"
+	suite.Require().NoError(config.Validate())
+
+	// functions deployed from images must be deployed from CUDA-enabled ones
+	config.Spec.Build = Build{CodeEntryType: "image"}
+	config.Spec.Image = "my-registry:5000/inference:latest"
+	err := config.Validate()
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "spec.image: must be a CUDA-enabled image")
+
+	// unless annotated as such
+	config.Meta.Annotations = map[string]string{FunctionAnnotationCUDAImage: "true"}
+	suite.Require().NoError(config.Validate())
+
+	config.Meta.Annotations = nil
+	config.Spec.Build = Build{}
+	config.Spec.GPU = &GPU{Count: 1.5, Sharing: "exclusive"}
+	config.Spec.Resources.Limits = v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}
+
+	err = config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.gpu.count",
+		"spec.gpu.sharing",
+		"spec.gpu",
+		"spec.build.baseImage",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestEventRecording() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			EventRecording: &EventRecording{
+				Kind: EventRecordingKindLocalDir,
+				Path: "/var/lib/nuclio/recordings",
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(1.0, config.Spec.EventRecording.GetSampleRatio())
+	suite.Require().Equal(DefaultEventRecordingMaxBodySize, config.Spec.EventRecording.GetMaxBodySize())
+
+	sampleRatio := 1.5
+	config.Spec.EventRecording = &EventRecording{
+		Kind:        EventRecordingKindV3io,
+		SampleRatio: &sampleRatio,
+		MaxBodySize: -1,
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.eventRecording.url",
+		"spec.eventRecording.containerName",
+		"spec.eventRecording.sampleRatio",
+		"spec.eventRecording.maxBodySize",
+	}, fields)
+
+	config.Spec.EventRecording = &EventRecording{Kind: "kafka"}
+	suite.Require().Error(config.Validate())
+}
+
+func (suite *ValidationTestSuite) TestInvocationInspector() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			InvocationInspector: &InvocationInspector{},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(DefaultInvocationInspectorMaxInvocations, config.Spec.InvocationInspector.GetMaxInvocations())
+	suite.Require().Equal(1.0, config.Spec.InvocationInspector.GetSampleRatio())
+	suite.Require().Equal(DefaultInvocationInspectorMaxBodySize, config.Spec.InvocationInspector.GetMaxBodySize())
+
+	sampleRatio := 0.0
+	config.Spec.InvocationInspector = &InvocationInspector{
+		MaxInvocations: MaxInvocationInspectorMaxInvocations + 1,
+		SampleRatio:    &sampleRatio,
+		MaxBodySize:    -1,
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.invocationInspector.maxInvocations",
+		"spec.invocationInspector.sampleRatio",
+		"spec.invocationInspector.maxBodySize",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestCloudEvents() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Triggers: map[string]Trigger{
+				"http": {
+					Kind: "http",
+					CloudEvents: &CloudEvents{
+						ResponseMode: CloudEventsModeStructured,
+						ResponseType: "com.example.response",
+					},
+				},
+				"stream": {
+					Kind:        "kafka-cluster",
+					CloudEvents: &CloudEvents{Mode: CloudEventsModeBinary},
+				},
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(CloudEventsModeAuto, config.Spec.Triggers["http"].CloudEvents.GetMode())
+
+	config.Spec.Triggers = map[string]Trigger{
+		"http": {
+			Kind:        "http",
+			CloudEvents: &CloudEvents{Mode: "json", ResponseMode: "json"},
+		},
+		"stream": {
+			Kind:        "kafka-cluster",
+			CloudEvents: &CloudEvents{ResponseMode: CloudEventsModeBinary},
+		},
+		"timer": {
+			Kind:        "cron",
+			CloudEvents: &CloudEvents{ResponseSource: "/my-function"},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.triggers.http.cloudEvents.mode",
+		"spec.triggers.http.cloudEvents.responseMode",
+		"spec.triggers.stream.cloudEvents.responseMode",
+		"spec.triggers.timer.cloudEvents.responseMode",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestRateLimit() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Triggers: map[string]Trigger{
+				"http": {
+					Kind:      "http",
+					RateLimit: &RateLimit{RPS: 10, Burst: 20, Key: "header:X-Api-Key"},
+				},
+				"stream": {
+					Kind:      "kafka-cluster",
+					RateLimit: &RateLimit{RPS: 0.5},
+				},
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal("X-Api-Key", config.Spec.Triggers["http"].RateLimit.GetKeyHeaderName())
+	suite.Require().Equal(1, config.Spec.Triggers["stream"].RateLimit.GetBurst())
+
+	config.Spec.Triggers = map[string]Trigger{
+		"http": {
+			Kind:      "http",
+			RateLimit: &RateLimit{RPS: 10, Burst: -1, Key: "header:"},
+		},
+		"stream": {
+			Kind:      "kafka-cluster",
+			RateLimit: &RateLimit{RPS: 10, Key: RateLimitKeyIP},
+		},
+		"timer": {
+			Kind:      "cron",
+			RateLimit: &RateLimit{},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.triggers.http.rateLimit.burst",
+		"spec.triggers.http.rateLimit.key",
+		"spec.triggers.stream.rateLimit.key",
+		"spec.triggers.timer.rateLimit.rps",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestReadiness() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Readiness: &Readiness{
+				Dependencies: []ReadinessDependency{
+					{Name: "kafka", Kind: ReadinessDependencyKindTCP, Address: "kafka:9092"},
+					{Name: "search", Kind: ReadinessDependencyKindHTTP, URL: "http://elasticsearch:9200/_cluster/health"},
+					{Name: "model", Kind: ReadinessDependencyKindHandler, Path: "/ready"},
+				},
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(5*time.Second, config.Spec.Readiness.GetPeriod())
+	suite.Require().Equal(2*time.Second, config.Spec.Readiness.GetTimeout())
+
+	config.Spec.Readiness = &Readiness{
+		PeriodSeconds: -1,
+		Dependencies: []ReadinessDependency{
+			{Name: "kafka", Kind: ReadinessDependencyKindTCP, Address: "kafka"},
+			{Name: "kafka", Kind: ReadinessDependencyKindHTTP, URL: "elasticsearch:9200"},
+			{Kind: ReadinessDependencyKindHandler},
+			{Name: "redis", Kind: "redis"},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.readiness.periodSeconds",
+		"spec.readiness.dependencies[0].address",
+		"spec.readiness.dependencies[1].name",
+		"spec.readiness.dependencies[1].url",
+		"spec.readiness.dependencies[2].name",
+		"spec.readiness.dependencies[2].path",
+		"spec.readiness.dependencies[3].kind",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestAsyncInvocation() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			AsyncInvocation: &AsyncInvocation{},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(AsyncInvocationDeliveryBestEffort, config.Spec.AsyncInvocation.GetDelivery())
+	suite.Require().Equal(DefaultAsyncInvocationQueueSize, config.Spec.AsyncInvocation.GetQueueSize())
+	suite.Require().Equal(DefaultAsyncInvocationWorkers, config.Spec.AsyncInvocation.GetWorkers())
+	suite.Require().Equal(DefaultAsyncInvocationMaxRetries, config.Spec.AsyncInvocation.GetMaxRetries())
+
+	maxRetries := -1
+	config.Spec.AsyncInvocation = &AsyncInvocation{
+		Delivery:   AsyncInvocationDeliveryPersisted,
+		QueueSize:  -1,
+		Workers:    -1,
+		MaxRetries: &maxRetries,
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.asyncInvocation.path",
+		"spec.asyncInvocation.queueSize",
+		"spec.asyncInvocation.workers",
+		"spec.asyncInvocation.maxRetries",
+	}, fields)
+
+	config.Spec.AsyncInvocation = &AsyncInvocation{Delivery: "exactlyOnce"}
+	suite.Require().Error(config.Validate())
+}
+
+func (suite *ValidationTestSuite) TestAutoScale() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Triggers: map[string]Trigger{
+				"orders": {Kind: "kafka-cluster"},
+			},
+			AutoScale: &AutoScale{
+				LagThreshold: 100,
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(AutoScaleModeNuclio, config.Spec.AutoScale.GetMode())
+	suite.Require().Equal(DefaultAutoScalePollingInterval, config.Spec.AutoScale.GetPollingInterval())
+	suite.Require().Equal(DefaultAutoScaleCooldownPeriod, config.Spec.AutoScale.GetCooldownPeriod())
+
+	config.Spec.AutoScale = &AutoScale{
+		LagThreshold:    100,
+		Mode:            AutoScaleModeKEDA,
+		PollingInterval: "10s",
+		CooldownPeriod:  "1m",
+	}
+	suite.Require().NoError(config.Validate())
+	suite.Require().Equal(10*time.Second, config.Spec.AutoScale.GetPollingInterval())
+	suite.Require().Equal(time.Minute, config.Spec.AutoScale.GetCooldownPeriod())
+
+	// jetstream triggers report their lag to the processor, but KEDA can't read it
+	config.Spec.Triggers = map[string]Trigger{
+		"orders": {Kind: "jetstream"},
+	}
+	config.Spec.AutoScale = &AutoScale{
+		Mode:            AutoScaleModeKEDA,
+		PollingInterval: "soon",
+		CooldownPeriod:  "-1m",
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.autoScale.lagThreshold",
+		"spec.autoScale.pollingInterval",
+		"spec.autoScale.cooldownPeriod",
+		"spec.autoScale",
+	}, fields)
+
+	config.Spec.AutoScale = &AutoScale{LagThreshold: 100}
+	suite.Require().NoError(config.Validate())
+
+	// v3io streams report their lag through the processor, but KEDA can't read it
+	config.Spec.Triggers = map[string]Trigger{
+		"orders": {Kind: "v3ioStream"},
+	}
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.AutoScale = &AutoScale{LagThreshold: 100, Mode: AutoScaleModeKEDA}
+	suite.Require().Error(config.Validate())
+
+	config.Spec.AutoScale = &AutoScale{LagThreshold: 100, Mode: "hpa"}
+	suite.Require().Error(config.Validate())
+}
+
+func (suite *ValidationTestSuite) TestSecurityContext() {
+	runAsUser := int64(1000)
+	runAsGroup := int64(-1)
+
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			SecurityContext: &SecurityContext{
+				RunAsUser:              &runAsUser,
+				ReadOnlyRootFilesystem: true,
+				DropCapabilities:       []string{"ALL"},
+				SeccompProfile:         SeccompProfileRuntimeDefault,
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.SecurityContext.SeccompProfile = "localhost/profiles/nuclio.json"
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.SecurityContext = &SecurityContext{
+		RunAsGroup:       &runAsGroup,
+		DropCapabilities: []string{"NET_RAW", ""},
+		SeccompProfile:   "localhost/",
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.securityContext.runAsGroup",
+		"spec.securityContext.runAsGroup",
+		"spec.securityContext.dropCapabilities[1]",
+		"spec.securityContext.seccompProfile",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestBuildHooks() {
+	config := Config{
+		Meta: Meta{
+			Name: "my-function",
+		},
+		Spec: Spec{
+			Build: Build{
+				Hooks: &BuildHooks{
+					PreCopy: &BuildHook{
+						Commands: []string{"apt-get update && apt-get install -y libpq-dev"},
+						Args:     map[string]string{"INDEX_HOST": "pypi.example.com"},
+						Secrets:  []BuildSecret{{ID: "index-token", Env: "INDEX_TOKEN"}},
+					},
+					PostInstall: &BuildHook{
+						Commands: []string{"pip download --dest /wheels private-package"},
+						Secrets:  []BuildSecret{{ID: "index-token", Env: "INDEX_TOKEN"}},
+					},
+				},
+			},
+		},
+	}
+	suite.Require().NoError(config.Validate())
+
+	config.Spec.Build.Hooks.PreFinal = &BuildHook{
+		Commands: []string{"@nuclio.postCopy"},
+		Args:     map[string]string{"NUCLIO_LABEL": "latest"},
+		Secrets: []BuildSecret{
+			{ID: "index-token", Src: "/run/token"},
+			{Src: "/run/other-token", Env: "OTHER_TOKEN"},
+		},
+	}
+
+	err := config.Validate()
+	suite.Require().Error(err)
+
+	var fields []string
+	for _, fieldError := range err.(*ValidationError).FieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+
+	suite.Require().Equal([]string{
+		"spec.build.hooks.preFinal.commands[0]",
+		"spec.build.hooks.preFinal.args",
+		"spec.build.hooks.preFinal.secrets[0].id",
+		"spec.build.hooks.preFinal.secrets[1].id",
+		"spec.build.hooks.preFinal.secrets[1]",
+	}, fields)
+}
+
+func (suite *ValidationTestSuite) TestIsCUDAImage() {
+	for _, image := range []string{
+		"nvidia/cuda:11.0-base",
+		"my-registry.io/nvidia/cuda:11.0-base",
+		"pytorch/pytorch:1.6.0-cuda10.1-cudnn7-runtime",
+		"tensorflow/tensorflow:2.3.0-gpu",
+	} {
+		suite.Require().True(IsCUDAImage(image), image)
+	}
+
+	for _, image := range []string{
+		"python:3.7",
+		"gpu-registry:5000/python:3.7",
+		"tensorflow/tensorflow:2.3.0",
+	} {
+		suite.Require().False(IsCUDAImage(image), image)
+	}
+}
+
+func TestValidationTestSuite(t *testing.T) {
+	suite.Run(t, new(ValidationTestSuite))
+}
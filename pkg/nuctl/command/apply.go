@@ -0,0 +1,275 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	nuctl_common "github.com/nuclio/nuclio/pkg/nuctl/command/common"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type applyOutcome string
+
+const (
+	applyOutcomeCreated   applyOutcome = "created"
+	applyOutcomeUpdated   applyOutcome = "updated"
+	applyOutcomeUnchanged applyOutcome = "unchanged"
+)
+
+type applyCommandeer struct {
+	cmd                        *cobra.Command
+	rootCommandeer             *RootCommandeer
+	functionConfigPath         string
+	templateValues             stringSliceFlag
+	templateValueFiles         stringSliceFlag
+	allowMissingTemplateValues bool
+	renderedFunctionConfig     []byte
+}
+
+func newApplyCommandeer(rootCommandeer *RootCommandeer) *applyCommandeer {
+	commandeer := &applyCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "apply -f function-config [function-name]",
+		Short: "Create or update a function from a configuration file, doing nothing if it is unchanged",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.functionConfigPath == "" {
+				return errors.New("Function configuration file must be provided (-f)")
+			}
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			functionConfig, err := commandeer.readFunctionConfig()
+			if err != nil {
+				return errors.Wrap(err, "Failed to read function configuration")
+			}
+
+			// function name may be overridden by a positional argument
+			if len(args) == 1 {
+				functionConfig.Meta.Name = args[0]
+			}
+
+			if functionConfig.Meta.Name == "" {
+				return errors.New("Function name must be provided either in the configuration file or as an argument")
+			}
+
+			outcome, err := commandeer.apply(functionConfig)
+			if err != nil {
+				return errors.Wrap(err, "Failed to apply function")
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Function %s %s\n", functionConfig.Meta.Name, outcome) // nolint: errcheck
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.functionConfigPath, "file", "f", "", "Path to a function-configuration file")
+	cmd.Flags().Var(&commandeer.templateValues, "set", "Value to substitute for a variable of the function config file, which is a Go template (key=value), may be repeated")
+	cmd.Flags().Var(&commandeer.templateValueFiles, "set-file", "File whose contents to substitute for a variable of the function config file (key=path), may be repeated")
+	cmd.Flags().BoolVar(&commandeer.allowMissingTemplateValues, "allow-missing", false, "Render variables of the function config file that have no value as empty, rather than fail")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (a *applyCommandeer) readFunctionConfig() (*functionconfig.Config, error) {
+	if len(a.templateValues) == 0 && len(a.templateValueFiles) == 0 {
+		return readFunctionConfigFile(a.functionConfigPath, a.rootCommandeer.namespace)
+	}
+
+	functionBody, err := readFunctionConfigFileBody(a.functionConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the builder is given the rendered contents, while the function records the path of the template
+	a.renderedFunctionConfig, err = renderFunctionConfigTemplate(functionBody,
+		a.templateValues,
+		a.templateValueFiles,
+		a.allowMissingTemplateValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to render function config file")
+	}
+
+	return parseFunctionConfig(a.renderedFunctionConfig, a.functionConfigPath, a.rootCommandeer.namespace)
+}
+
+// readFunctionConfigFile reads a function configuration file, populated with defaults the way it would be deployed
+func readFunctionConfigFile(functionConfigPath string, namespace string) (*functionconfig.Config, error) {
+	functionBody, err := readFunctionConfigFileBody(functionConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFunctionConfig(functionBody, functionConfigPath, namespace)
+}
+
+func readFunctionConfigFileBody(functionConfigPath string) ([]byte, error) {
+	functionConfigFile, err := nuctl_common.OpenFile(functionConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed opening function config file")
+	}
+
+	defer functionConfigFile.Close() // nolint: errcheck
+
+	functionBody, err := ioutil.ReadAll(functionConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed reading function config file")
+	}
+
+	return functionBody, nil
+}
+
+// parseFunctionConfig parses the contents of the function configuration file at the given path
+func parseFunctionConfig(functionBody []byte,
+	functionConfigPath string,
+	namespace string) (*functionconfig.Config, error) {
+	unmarshalFunc, err := nuctl_common.GetUnmarshalFunc(functionBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed identifying function config file format")
+	}
+
+	functionConfig := functionconfig.NewConfig()
+	if err := unmarshalFunc(functionBody, functionConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed parsing function config file")
+	}
+
+	functionConfig.Meta.Namespace = namespace
+	functionConfig.Spec.Build.FunctionConfigPath = functionConfigPath
+	functionConfig.Meta.RemoveSkipBuildAnnotation()
+	functionConfig.Meta.RemoveSkipDeployAnnotation()
+	populateFunctionConfigDefaults(functionConfig)
+
+	return functionConfig, nil
+}
+
+func (a *applyCommandeer) apply(functionConfig *functionconfig.Config) (applyOutcome, error) {
+	desiredConfigHash, err := getFunctionConfigHash(functionConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to hash function configuration")
+	}
+
+	functions, err := a.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionConfig.Meta.Name,
+		Namespace: functionConfig.Meta.Namespace,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get functions")
+	}
+
+	outcome := applyOutcomeCreated
+	if len(functions) > 0 {
+		outcome = applyOutcomeUpdated
+
+		function := functions[0]
+		if err := function.Initialize(nil); err != nil {
+			a.rootCommandeer.loggerInstance.DebugWith("Failed to initialize function", "err", err.Error())
+		}
+
+		// a function that was applied with the same configuration and is healthy needs no redeploy
+		if function.GetConfig().Meta.Annotations[functionconfig.FunctionAnnotationAppliedConfigHash] == desiredConfigHash &&
+			function.GetStatus().State == functionconfig.FunctionStateReady {
+			return applyOutcomeUnchanged, nil
+		}
+	}
+
+	// record the applied configuration, so that the next apply can compare against it
+	if functionConfig.Meta.Annotations == nil {
+		functionConfig.Meta.Annotations = map[string]string{}
+	}
+	functionConfig.Meta.Annotations[functionconfig.FunctionAnnotationAppliedConfigHash] = desiredConfigHash
+
+	a.rootCommandeer.loggerInstance.DebugWith("Applying function",
+		"outcome", outcome,
+		"functionConfig", functionConfig)
+
+	if _, err := a.rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:                 a.rootCommandeer.loggerInstance,
+		FunctionConfig:         *functionConfig,
+		FunctionConfigContents: a.renderedFunctionConfig,
+	}); err != nil {
+		return "", errors.Wrap(err, "Failed to deploy function")
+	}
+
+	return outcome, nil
+}
+
+// getFunctionConfigHash returns a hash of the normalized function configuration
+func getFunctionConfigHash(functionConfig *functionconfig.Config) (string, error) {
+	normalizedConfig, err := normalizeFunctionConfig(functionConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to normalize function configuration")
+	}
+
+	// json encodes map keys in sorted order, so the encoding is stable
+	encodedNormalizedConfig, err := json.Marshal(normalizedConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode normalized function configuration")
+	}
+
+	configHash := sha256.Sum256(encodedNormalizedConfig)
+
+	return hex.EncodeToString(configHash[:]), nil
+}
+
+// normalizeFunctionConfig returns a copy of the function configuration without the fields that are populated
+// by the platform or that are specific to the cluster
+func normalizeFunctionConfig(functionConfig *functionconfig.Config) (*functionconfig.Config, error) {
+	normalizedConfig := functionconfig.Config{}
+
+	// deep copy through json, so that the given configuration is left untouched
+	encodedConfig, err := json.Marshal(functionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode function configuration")
+	}
+
+	if err := json.Unmarshal(encodedConfig, &normalizedConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode function configuration")
+	}
+
+	normalizedConfig.CleanFunctionSpec()
+	normalizedConfig.Spec.ImageHash = ""
+	normalizedConfig.Spec.Build.Timestamp = 0
+	normalizedConfig.Spec.Build.Mode = ""
+
+	// where the configuration was read from isn't part of it. the same file may be applied by another path,
+	// and a templated file may have been rendered to a temporary one
+	normalizedConfig.Spec.Build.FunctionConfigPath = ""
+	delete(normalizedConfig.Meta.Annotations, functionconfig.FunctionAnnotationAppliedConfigHash)
+	if len(normalizedConfig.Meta.Annotations) == 0 {
+		normalizedConfig.Meta.Annotations = nil
+	}
+
+	return &normalizedConfig, nil
+}
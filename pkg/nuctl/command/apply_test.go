@@ -0,0 +1,233 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type applyTestSuite struct {
+	suite.Suite
+	mockPlatform *mockplatform.Platform
+	commandeer   *applyCommandeer
+}
+
+func (suite *applyTestSuite) SetupTest() {
+	var err error
+
+	suite.mockPlatform = &mockplatform.Platform{}
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.platform = suite.mockPlatform
+	rootCommandeer.loggerInstance, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.commandeer = newApplyCommandeer(rootCommandeer)
+}
+
+func (suite *applyTestSuite) TestApplyCreatesMissingFunction() {
+	functionConfig := suite.newFunctionConfig()
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{}, nil).
+		Once()
+
+	suite.mockPlatform.
+		On("CreateFunction", mock.MatchedBy(func(createFunctionOptions *platform.CreateFunctionOptions) bool {
+			return createFunctionOptions.FunctionConfig.Meta.Annotations[functionconfig.FunctionAnnotationAppliedConfigHash] != ""
+		})).
+		Return(&platform.CreateFunctionResult{}, nil).
+		Once()
+
+	outcome, err := suite.commandeer.apply(functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeCreated, outcome)
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *applyTestSuite) TestApplyUpdatesChangedFunction() {
+	liveConfig := suite.newFunctionConfig()
+	suite.setAppliedConfigHash(liveConfig)
+
+	// change the desired configuration
+	functionConfig := suite.newFunctionConfig()
+	functionConfig.Spec.Handler = "main:Other"
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{suite.newFunction(liveConfig, functionconfig.FunctionStateReady)}, nil).
+		Once()
+
+	suite.mockPlatform.
+		On("CreateFunction", mock.Anything).
+		Return(&platform.CreateFunctionResult{}, nil).
+		Once()
+
+	outcome, err := suite.commandeer.apply(functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeUpdated, outcome)
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *applyTestSuite) TestApplyUnchangedFunction() {
+	liveConfig := suite.newFunctionConfig()
+	suite.setAppliedConfigHash(liveConfig)
+
+	// status and platform populated fields are ignored
+	liveConfig.Spec.Image = "some-registry/processor-test:latest"
+	liveConfig.Spec.Build.Timestamp = 1234
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{suite.newFunction(liveConfig, functionconfig.FunctionStateReady)}, nil).
+		Once()
+
+	outcome, err := suite.commandeer.apply(suite.newFunctionConfig())
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeUnchanged, outcome)
+
+	// no deploy should have happened
+	suite.mockPlatform.AssertNotCalled(suite.T(), "CreateFunction", mock.Anything)
+}
+
+func (suite *applyTestSuite) TestApplyRedeploysUnhealthyFunction() {
+	liveConfig := suite.newFunctionConfig()
+	suite.setAppliedConfigHash(liveConfig)
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{suite.newFunction(liveConfig, functionconfig.FunctionStateError)}, nil).
+		Once()
+
+	suite.mockPlatform.
+		On("CreateFunction", mock.Anything).
+		Return(&platform.CreateFunctionResult{}, nil).
+		Once()
+
+	outcome, err := suite.commandeer.apply(suite.newFunctionConfig())
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeUpdated, outcome)
+}
+
+func (suite *applyTestSuite) TestApplyTemplatedFunctionTwice() {
+	tempDir, err := ioutil.TempDir("", "apply-test")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	// the same template, by two paths
+	functionConfigTemplate := []byte(`metadata:
+  name: test
+spec:
+  runtime: golang
+  handler: {{ .handler }}
+`)
+	var functionConfigPaths []string
+	for _, fileName := range []string{"function.yaml", "function-copy.yaml"} {
+		functionConfigPath := filepath.Join(tempDir, fileName)
+		suite.Require().NoError(ioutil.WriteFile(functionConfigPath, functionConfigTemplate, 0644))
+		functionConfigPaths = append(functionConfigPaths, functionConfigPath)
+	}
+
+	suite.commandeer.templateValues = stringSliceFlag{"handler=main:Handler"}
+
+	// the first apply creates the function, giving the builder the rendered configuration
+	var appliedFunctionConfig functionconfig.Config
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{}, nil).
+		Once()
+
+	suite.mockPlatform.
+		On("CreateFunction", mock.MatchedBy(func(createFunctionOptions *platform.CreateFunctionOptions) bool {
+			appliedFunctionConfig = createFunctionOptions.FunctionConfig
+			return string(createFunctionOptions.FunctionConfigContents) != string(functionConfigTemplate)
+		})).
+		Return(&platform.CreateFunctionResult{}, nil).
+		Once()
+
+	suite.commandeer.functionConfigPath = functionConfigPaths[0]
+	functionConfig, err := suite.commandeer.readFunctionConfig()
+	suite.Require().NoError(err)
+	suite.Require().Equal("main:Handler", functionConfig.Spec.Handler)
+	suite.Require().Equal(functionConfigPaths[0], functionConfig.Spec.Build.FunctionConfigPath)
+
+	outcome, err := suite.commandeer.apply(functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeCreated, outcome)
+	suite.Require().Contains(string(suite.commandeer.renderedFunctionConfig), "handler: main:Handler")
+
+	// the second apply finds the function as it was applied, and leaves it be
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{suite.newFunction(&appliedFunctionConfig, functionconfig.FunctionStateReady)}, nil).
+		Once()
+
+	suite.commandeer.functionConfigPath = functionConfigPaths[1]
+	functionConfig, err = suite.commandeer.readFunctionConfig()
+	suite.Require().NoError(err)
+
+	outcome, err = suite.commandeer.apply(functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal(applyOutcomeUnchanged, outcome)
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *applyTestSuite) newFunctionConfig() *functionconfig.Config {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "test"
+	functionConfig.Spec.Runtime = "golang"
+	functionConfig.Spec.Handler = "main:Handler"
+	populateFunctionConfigDefaults(functionConfig)
+
+	return functionConfig
+}
+
+func (suite *applyTestSuite) setAppliedConfigHash(functionConfig *functionconfig.Config) {
+	configHash, err := getFunctionConfigHash(functionConfig)
+	suite.Require().NoError(err)
+
+	functionConfig.Meta.Annotations = map[string]string{
+		functionconfig.FunctionAnnotationAppliedConfigHash: configHash,
+	}
+}
+
+func (suite *applyTestSuite) newFunction(functionConfig *functionconfig.Config,
+	state functionconfig.FunctionState) platform.Function {
+	return &platform.AbstractFunction{
+		Config: *functionConfig,
+		Status: functionconfig.Status{
+			State: state,
+		},
+	}
+}
+
+func TestApplyTestSuite(t *testing.T) {
+	suite.Run(t, new(applyTestSuite))
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+// a function deployed blue/green keeps its name, and alternates between two slots, annotated on it. the new
+// version is deployed as a standby alongside it, labeled with the function's name, which serves the function's
+// ingresses and API gateways while the function is updated to the new version
+const (
+	blueGreenFunctionLabel  = "nuclio.io/blue-green-function"
+	blueGreenSlotAnnotation = "nuclio.io/blue-green-slot"
+	blueGreenSlotBlue       = "blue"
+	blueGreenSlotGreen      = "green"
+	blueGreenStandbySuffix  = "-standby"
+)
+
+// deployBlueGreen deploys the function as a standby alongside the live version, without ingresses. once the
+// standby is ready and healthy, the function's ingresses and API gateways are switched over to it, and the
+// function is updated to the new version in place - so that its name and service are kept. the traffic is then
+// switched back to the function and the standby is deleted. if anything fails before the function is updated,
+// the standby is deleted and the live version keeps serving
+func (d *deployCommandeer) deployBlueGreen(cmd *cobra.Command) error {
+	rootCommandeer := d.rootCommandeer
+
+	// local functions can't be updated in place, which switching over requires
+	if err := rootCommandeer.validateOperationPlatform("deploy --blue-green",
+		d.blueGreenSupportedPlatforms); err != nil {
+		return err
+	}
+
+	liveFunction, err := d.getLiveBlueGreenFunction()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get live version of function")
+	}
+
+	desiredTriggers := d.functionConfig.Spec.Triggers
+
+	// nothing serves the function yet, so there's nothing to switch over from
+	if liveFunction == nil {
+		functionConfig := d.getBlueGreenFunctionConfig(d.functionName, blueGreenSlotBlue)
+
+		if err := d.createBlueGreenFunction(cmd, &functionConfig); err != nil {
+			return d.abortBlueGreen(&functionConfig, nil, errors.Wrap(err, "Failed to deploy function"))
+		}
+
+		if _, err := d.checkBlueGreenFunctionHealth(d.functionName); err != nil {
+			return d.abortBlueGreen(&functionConfig, nil, errors.Wrap(err, "Function is unhealthy"))
+		}
+
+		rootCommandeer.loggerInstance.InfoWith("Function deployed blue/green",
+			"name", d.functionName,
+			"slot", blueGreenSlotBlue)
+
+		return nil
+	}
+
+	liveFunctionConfig := liveFunction.GetConfig()
+
+	newSlot := blueGreenSlotBlue
+	if liveFunctionConfig.Meta.Annotations[blueGreenSlotAnnotation] == blueGreenSlotBlue {
+		newSlot = blueGreenSlotGreen
+	}
+
+	standbyConfig := d.getBlueGreenFunctionConfig(d.functionName+blueGreenStandbySuffix, newSlot)
+	standbyConfig.Meta.Labels[blueGreenFunctionLabel] = d.functionName
+	standbyConfig.Spec.Triggers = getTriggersWithoutIngresses(desiredTriggers)
+
+	rootCommandeer.loggerInstance.InfoWith("Deploying new version of function as standby",
+		"name", standbyConfig.Meta.Name,
+		"slot", newSlot)
+
+	if err := d.createBlueGreenFunction(cmd, &standbyConfig); err != nil {
+		return d.abortBlueGreen(&standbyConfig, nil, errors.Wrap(err, "Failed to deploy new version"))
+	}
+
+	standbyFunction, err := d.checkBlueGreenFunctionHealth(standbyConfig.Meta.Name)
+	if err != nil {
+		return d.abortBlueGreen(&standbyConfig, nil, errors.Wrap(err, "New version is unhealthy"))
+	}
+
+	// the standby takes over the traffic while the function is updated. from here on, aborting restores the
+	// function as it was
+	standbyFunctionConfig := standbyFunction.GetConfig()
+
+	if err := d.updateBlueGreenFunctionTriggers(standbyFunctionConfig, desiredTriggers); err != nil {
+		return d.abortBlueGreen(&standbyConfig, nil, errors.Wrap(err, "Failed to switch ingresses to new version"))
+	}
+
+	if err := d.updateBlueGreenFunctionTriggers(liveFunctionConfig,
+		getTriggersWithoutIngresses(liveFunctionConfig.Spec.Triggers)); err != nil {
+		return d.abortBlueGreen(&standbyConfig,
+			liveFunctionConfig,
+			errors.Wrap(err, "Failed to switch ingresses from live version"))
+	}
+
+	if err := d.switchAPIGatewayUpstreams(d.functionName, standbyConfig.Meta.Name); err != nil {
+		return d.abortBlueGreen(&standbyConfig,
+			liveFunctionConfig,
+			errors.Wrap(err, "Failed to switch API gateways to new version"))
+	}
+
+	// update the function to the new version, which its service then serves too
+	updatedFunctionMeta := liveFunctionConfig.Meta
+	updatedFunctionMeta.Annotations = map[string]string{}
+	for annotationName, annotationValue := range liveFunctionConfig.Meta.Annotations {
+		updatedFunctionMeta.Annotations[annotationName] = annotationValue
+	}
+	updatedFunctionMeta.Annotations[blueGreenSlotAnnotation] = newSlot
+
+	updatedFunctionSpec := standbyFunctionConfig.Spec
+	updatedFunctionSpec.Triggers = desiredTriggers
+
+	if err := rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+		FunctionMeta: &updatedFunctionMeta,
+		FunctionSpec: &updatedFunctionSpec,
+	}); err != nil {
+		return d.abortBlueGreen(&standbyConfig,
+			liveFunctionConfig,
+			errors.Wrap(err, "Failed to update function to new version"))
+	}
+
+	if _, err := d.checkBlueGreenFunctionHealth(d.functionName); err != nil {
+		return d.abortBlueGreen(&standbyConfig,
+			liveFunctionConfig,
+			errors.Wrap(err, "Function is unhealthy once updated to new version"))
+	}
+
+	// the function serves the new version, so the traffic is switched back to it
+	if err := d.switchAPIGatewayUpstreams(standbyConfig.Meta.Name, d.functionName); err != nil {
+		return errors.Wrapf(err, "Function %s was updated, but failed to switch API gateways back to it from %s",
+			d.functionName,
+			standbyConfig.Meta.Name)
+	}
+
+	if err := rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+		FunctionConfig: standbyConfig,
+	}); err != nil {
+		return errors.Wrapf(err, "Function %s was updated, but failed to delete standby %s",
+			d.functionName,
+			standbyConfig.Meta.Name)
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Function deployed blue/green",
+		"name", d.functionName,
+		"slot", newSlot)
+
+	return nil
+}
+
+// getLiveBlueGreenFunction returns the function that is serving, or nil if there's none. fails if there's a
+// standby left by a previous switch over that didn't complete, which may still be serving
+func (d *deployCommandeer) getLiveBlueGreenFunction() (platform.Function, error) {
+	namespace := d.rootCommandeer.namespace
+
+	standbyFunctions, err := d.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      d.functionName + blueGreenStandbySuffix,
+		Namespace: namespace,
+		Labels:    fmt.Sprintf("%s=%s", blueGreenFunctionLabel, d.functionName),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(standbyFunctions) > 0 {
+		return nil, errors.Errorf("Standby %s of function %s exists (a previous switch over didn't complete), "+
+			"delete it once the function serves its ingresses and API gateways",
+			standbyFunctions[0].GetConfig().Meta.Name,
+			d.functionName)
+	}
+
+	functions, err := d.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      d.functionName,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nil, nil
+	}
+
+	return functions[0], nil
+}
+
+// getBlueGreenFunctionConfig returns the configuration to deploy, by the given name and annotated with the slot
+func (d *deployCommandeer) getBlueGreenFunctionConfig(name string, slot string) functionconfig.Config {
+	functionConfig := d.functionConfig
+	functionConfig.Meta.Name = name
+
+	functionConfig.Meta.Labels = map[string]string{}
+	for labelName, labelValue := range d.functionConfig.Meta.Labels {
+		functionConfig.Meta.Labels[labelName] = labelValue
+	}
+
+	functionConfig.Meta.Annotations = map[string]string{}
+	for annotationName, annotationValue := range d.functionConfig.Meta.Annotations {
+		functionConfig.Meta.Annotations[annotationName] = annotationValue
+	}
+	functionConfig.Meta.Annotations[blueGreenSlotAnnotation] = slot
+
+	return functionConfig
+}
+
+func (d *deployCommandeer) createBlueGreenFunction(cmd *cobra.Command, functionConfig *functionconfig.Config) error {
+	_, err := d.rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:                    d.rootCommandeer.loggerInstance,
+		FunctionConfig:            *functionConfig,
+		InputImageFile:            d.inputImageFile,
+		BuildRetries:              d.buildRetries,
+		BuildRetryOnTransientOnly: d.buildRetryOnTransientOnly,
+		BuildOutputLineHandler: func(line string) {
+			fmt.Fprintln(cmd.OutOrStdout(), line) // nolint: errcheck
+		},
+	})
+
+	return err
+}
+
+// updateBlueGreenFunctionTriggers updates the function's triggers, leaving the rest of its spec as is
+func (d *deployCommandeer) updateBlueGreenFunctionTriggers(functionConfig *functionconfig.Config,
+	triggers map[string]functionconfig.Trigger) error {
+	functionSpec := functionConfig.Spec
+	functionSpec.Triggers = triggers
+
+	return d.rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+		FunctionMeta: &functionConfig.Meta,
+		FunctionSpec: &functionSpec,
+	})
+}
+
+// switchAPIGatewayUpstreams routes the API gateways of the namespace which route to one function to another
+// instead, keeping the percentage of their canary upstreams
+func (d *deployCommandeer) switchAPIGatewayUpstreams(fromFunctionName string, toFunctionName string) error {
+	apiGateways, err := d.rootCommandeer.platform.GetAPIGateways(&platform.GetAPIGatewaysOptions{
+		Meta: platform.APIGatewayMeta{
+			Namespace: d.rootCommandeer.namespace,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		apiGatewayConfig := *apiGateway.GetConfig()
+
+		switched := false
+		upstreams := make([]platform.APIGatewayUpstreamSpec, len(apiGatewayConfig.Spec.Upstreams))
+		for upstreamIndex, upstream := range apiGatewayConfig.Spec.Upstreams {
+			if upstream.NuclioFunction != nil && upstream.NuclioFunction.Name == fromFunctionName {
+				upstream.NuclioFunction = &platform.NuclioFunctionAPIGatewaySpec{Name: toFunctionName}
+				switched = true
+			}
+
+			upstreams[upstreamIndex] = upstream
+		}
+
+		if !switched {
+			continue
+		}
+
+		apiGatewayConfig.Spec.Upstreams = upstreams
+
+		d.rootCommandeer.loggerInstance.InfoWith("Switching API gateway upstream",
+			"apiGateway", apiGatewayConfig.Meta.Name,
+			"from", fromFunctionName,
+			"to", toFunctionName)
+
+		if err := d.rootCommandeer.platform.UpdateAPIGateway(&platform.UpdateAPIGatewayOptions{
+			APIGatewayConfig: apiGatewayConfig,
+		}); err != nil {
+			return errors.Wrapf(err, "Failed to update API gateway %s", apiGatewayConfig.Meta.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkBlueGreenFunctionHealth makes sure the deployed version is ready, and that it responds successfully
+// to a request to the health check path
+func (d *deployCommandeer) checkBlueGreenFunctionHealth(functionName string) (platform.Function, error) {
+	functions, err := d.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: d.rootCommandeer.namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nil, errors.New("Function wasn't found after being deployed")
+	}
+
+	if state := functions[0].GetStatus().State; state != functionconfig.FunctionStateReady {
+		return nil, errors.Errorf("Function isn't ready (state: %s)", state)
+	}
+
+	invocationResult, err := d.rootCommandeer.platform.CreateFunctionInvocation(&platform.CreateFunctionInvocationOptions{
+		Name:      functionName,
+		Namespace: d.rootCommandeer.namespace,
+		Path:      d.blueGreenHealthPath,
+		Method:    http.MethodGet,
+		Headers:   http.Header{},
+		Via:       platform.InvokeViaAny,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to invoke health check")
+	}
+
+	if invocationResult.StatusCode < http.StatusOK || invocationResult.StatusCode >= http.StatusMultipleChoices {
+		return nil, errors.Errorf("Health check of %s responded with status %d",
+			d.blueGreenHealthPath,
+			invocationResult.StatusCode)
+	}
+
+	return functions[0], nil
+}
+
+// abortBlueGreen restores the live version as it was, if given, switches the API gateways back to it and deletes
+// the new version. returns the error that aborted the deploy
+func (d *deployCommandeer) abortBlueGreen(newConfig *functionconfig.Config,
+	liveFunctionConfig *functionconfig.Config,
+	abortErr error) error {
+	d.rootCommandeer.loggerInstance.WarnWith("Blue/green deploy failed, deleting new version",
+		"name", newConfig.Meta.Name,
+		"err", abortErr.Error())
+
+	if liveFunctionConfig != nil {
+		if err := d.rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+			FunctionMeta: &liveFunctionConfig.Meta,
+			FunctionSpec: &liveFunctionConfig.Spec,
+		}); err != nil {
+			d.rootCommandeer.loggerInstance.WarnWith("Failed to restore live version",
+				"name", liveFunctionConfig.Meta.Name,
+				"err", err.Error())
+		}
+
+		if err := d.switchAPIGatewayUpstreams(newConfig.Meta.Name, liveFunctionConfig.Meta.Name); err != nil {
+			d.rootCommandeer.loggerInstance.WarnWith("Failed to switch API gateways back to live version",
+				"name", liveFunctionConfig.Meta.Name,
+				"err", err.Error())
+
+			// the new version must keep serving the API gateways
+			return abortErr
+		}
+	}
+
+	if err := d.rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+		FunctionConfig: *newConfig,
+	}); err != nil {
+		d.rootCommandeer.loggerInstance.WarnWith("Failed to delete new version",
+			"name", newConfig.Meta.Name,
+			"err", err.Error())
+	}
+
+	return abortErr
+}
+
+// getTriggersWithoutIngresses returns a copy of the triggers, with the ingresses of the http triggers removed
+func getTriggersWithoutIngresses(triggers map[string]functionconfig.Trigger) map[string]functionconfig.Trigger {
+	if triggers == nil {
+		return nil
+	}
+
+	triggersWithoutIngresses := map[string]functionconfig.Trigger{}
+
+	for triggerName, trigger := range triggers {
+		if _, hasIngresses := trigger.Attributes["ingresses"]; trigger.Kind == "http" && hasIngresses {
+			attributes := map[string]interface{}{}
+			for attributeName, attributeValue := range trigger.Attributes {
+				if attributeName != "ingresses" {
+					attributes[attributeName] = attributeValue
+				}
+			}
+
+			trigger.Attributes = attributes
+		}
+
+		triggersWithoutIngresses[triggerName] = trigger
+	}
+
+	return triggersWithoutIngresses
+}
@@ -18,8 +18,11 @@ package command
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
@@ -38,6 +41,11 @@ type buildCommandeer struct {
 	encodedRuntimeAttributes   string
 	encodedCodeEntryAttributes string
 	outputImageFile            string
+	outputDockerfile           string
+	outputContextDir           string
+	buildRetries               int
+	buildRetryOnTransientOnly  bool
+	registryCredentials        registryCredentials
 }
 
 func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
@@ -57,6 +65,10 @@ func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
 				commandeer.functionConfig.Meta.Name = args[0]
 			}
 
+			if err := validateBuildNetwork(commandeer.functionConfig.Spec.Build.Network); err != nil {
+				return err
+			}
+
 			// initialize root
 			if err := rootCommandeer.initialize(); err != nil {
 				return errors.Wrap(err, "Failed to initialize root")
@@ -74,9 +86,8 @@ func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
 				return errors.Wrap(err, "Failed to decode build runtime attributes")
 			}
 
-			if commandeer.functionConfig.Spec.Build.Offline {
-				rootCommandeer.loggerInstance.Debug("Offline flag is passed, setting no-pull as well")
-				commandeer.functionConfig.Spec.Build.NoBaseImagesPull = true
+			if err := prepareOfflineBuild(&commandeer.functionConfig.Spec.Build); err != nil {
+				return err
 			}
 
 			// decode the JSON build code entry attributes
@@ -85,18 +96,62 @@ func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
 				return errors.Wrap(err, "Failed to decode code entry attributes")
 			}
 
-			_, err := rootCommandeer.platform.CreateFunctionBuild(&platform.CreateFunctionBuildOptions{
-				Logger:          rootCommandeer.loggerInstance,
-				FunctionConfig:  commandeer.functionConfig,
-				PlatformName:    rootCommandeer.platform.GetName(),
-				OutputImageFile: commandeer.outputImageFile,
+			if err := commandeer.registryCredentials.logIn(rootCommandeer,
+				commandeer.functionConfig.Spec.Build.Registry); err != nil {
+				return errors.Wrap(err, "Failed to log in to registry")
+			}
+
+			buildResult, err := rootCommandeer.platform.CreateFunctionBuild(&platform.CreateFunctionBuildOptions{
+				Logger:                    rootCommandeer.loggerInstance,
+				FunctionConfig:            commandeer.functionConfig,
+				PlatformName:              rootCommandeer.platform.GetName(),
+				OutputImageFile:           commandeer.outputImageFile,
+				OutputDockerfile:          commandeer.outputDockerfile,
+				OutputContextDir:          commandeer.outputContextDir,
+				BuildRetries:              commandeer.buildRetries,
+				BuildRetryOnTransientOnly: commandeer.buildRetryOnTransientOnly,
 			})
-			return err
+			if err != nil {
+				return err
+			}
+
+			if buildResult.BuildAttempts > 1 {
+				rootCommandeer.loggerInstance.InfoWith("Function built after retrying",
+					"attempts", buildResult.BuildAttempts)
+			}
+
+			if rootCommandeer.isJSONOutput() {
+				result := map[string]interface{}{
+					"name":          commandeer.functionConfig.Meta.Name,
+					"image":         buildResult.Image,
+					"buildAttempts": buildResult.BuildAttempts,
+				}
+
+				if buildResult.ImageStatus != nil {
+					result["digest"] = buildResult.ImageStatus.Digest
+					result["signature"] = buildResult.ImageStatus.Signature
+				}
+
+				// let whoever builds the image know where everything is
+				if buildResult.BuildArgs != nil {
+					result["dockerfile"] = commandeer.outputDockerfile
+					result["contextDir"] = commandeer.outputContextDir
+					result["buildArgs"] = buildResult.BuildArgs
+				}
+
+				return rootCommandeer.renderResult(cmd.OutOrStdout(), result)
+			}
+
+			return nil
 		},
 	}
 
 	addBuildFlags(cmd, &commandeer.functionConfig.Spec.Build, &commandeer.functionConfigPath, &commandeer.runtime, &commandeer.handler, &commandeer.commands, &commandeer.encodedRuntimeAttributes, &commandeer.encodedCodeEntryAttributes)
 	cmd.Flags().StringVarP(&commandeer.outputImageFile, "output-image-file", "", "", "Path to output container image of the build")
+	cmd.Flags().StringVar(&commandeer.outputDockerfile, "output-dockerfile", "", "Path to write the processor Dockerfile to, instead of building the image")
+	cmd.Flags().StringVar(&commandeer.outputContextDir, "output-context", "", "Empty directory to assemble the build context in (with the onbuild artifacts staged), instead of building the image")
+	addBuildRetryFlags(cmd, &commandeer.buildRetries, &commandeer.buildRetryOnTransientOnly)
+	addRegistryCredentialsFlags(cmd, &commandeer.registryCredentials)
 
 	commandeer.cmd = cmd
 
@@ -113,11 +168,56 @@ func addBuildFlags(cmd *cobra.Command, functionBuild *functionconfig.Build, func
 	cmd.Flags().StringVarP(handler, "handler", "", "", "Name of a function handler")
 	cmd.Flags().BoolVarP(&functionBuild.NoBaseImagesPull, "no-pull", "", false, "Don't pull base images - use local versions")
 	cmd.Flags().BoolVarP(&functionBuild.NoCleanup, "no-cleanup", "", false, "Don't clean up temporary directories")
+	cmd.Flags().Var((*stringSliceFlag)(&functionBuild.CacheFrom), "cache-from", "Image to use as a build cache, may be repeated (docker builds)")
+	cmd.Flags().StringSliceVar(&functionBuild.Platforms, "platforms", nil, "Comma-separated platforms to build a multi-architecture image for, e.g. linux/amd64,linux/arm64 (docker builds, pushes to the registry)")
+	cmd.Flags().StringVar(&functionBuild.OS, "build-os", "", fmt.Sprintf("Operating system to build the image for, one of %s (default - linux)", strings.Join(functionconfig.BuildOSes, ", ")))
+	cmd.Flags().StringVar(&functionBuild.Network, "build-network", "", fmt.Sprintf("Network to build in, one of %s (docker builds)", strings.Join(functionconfig.BuildNetworks, ", ")))
 	cmd.Flags().StringVarP(&functionBuild.BaseImage, "base-image", "", "", "Name of the base image (default - per-runtime default)")
 	cmd.Flags().Var(commands, "build-command", "Commands to run when building the processor image")
 	cmd.Flags().StringVarP(&functionBuild.OnbuildImage, "onbuild-image", "", "", "The runtime onbuild image used to build the processor image")
-	cmd.Flags().BoolVarP(&functionBuild.Offline, "offline", "", false, "Don't assume internet connectivity exists")
+	cmd.Flags().BoolVarP(&functionBuild.Offline, "offline", "", false, "Don't assume internet connectivity exists (implies --no-pull)")
+	cmd.Flags().BoolVar(&functionBuild.PersistentCache, "build-cache", false, "Persist the runtime's package manager caches (pip, npm, Maven, Go modules) across builds")
+	cmd.Flags().StringVar(&functionBuild.CacheDir, "build-cache-dir", "", "Directory offline builds resolve packages from - wheels in pip/, an npm cache in npm/ and a go module download cache in go/ (with --offline)")
 	cmd.Flags().StringVar(encodedRuntimeAttributes, "build-runtime-attrs", "{}", "JSON-encoded build runtime attributes for the function")
 	cmd.Flags().StringVar(encodedCodeEntryAttributes, "build-code-entry-attrs", "{}", "JSON-encoded build code entry attributes for the function")
 	cmd.Flags().StringVar(&functionBuild.CodeEntryType, "code-entry-type", "", "Type of code entry (for example, \"url\", \"github\", \"image\")")
+	cmd.Flags().StringVar(&functionBuild.SBOMFormat, "sbom", "", fmt.Sprintf("Generate an SBOM of the built image with syft, in one of the formats: %s", strings.Join(functionconfig.SBOMFormats, ", ")))
+	cmd.Flags().StringVar(&functionBuild.SBOMPath, "sbom-file", "", "Path to write the SBOM to (default - <function name>.<format>.json)")
+	cmd.Flags().BoolVar(&functionBuild.Sign, "sign", false, "Sign the pushed image (and attest its SBOM) with cosign, keyless unless --sign-key is given")
+	cmd.Flags().StringVar(&functionBuild.SigningKey, "sign-key", "", "Key to sign the image with - a path, or a KMS or k8s:// secret URI (with --sign)")
+}
+
+// validateBuildNetwork fails on a network that isn't supported, before anything is built
+func validateBuildNetwork(network string) error {
+	if network != "" && !common.StringInSlice(network, functionconfig.BuildNetworks) {
+		return errors.Errorf("Invalid build network %s, must be one of: %s",
+			network,
+			strings.Join(functionconfig.BuildNetworks, ", "))
+	}
+
+	return nil
+}
+
+// prepareOfflineBuild validates the build cache of an offline build, and keeps it from pulling base images
+func prepareOfflineBuild(functionBuild *functionconfig.Build) error {
+	if !functionBuild.Offline {
+		if functionBuild.CacheDir != "" {
+			return errors.New("--build-cache-dir can only be used with --offline")
+		}
+
+		return nil
+	}
+
+	if functionBuild.CacheDir != "" && !common.IsDir(functionBuild.CacheDir) {
+		return errors.Errorf("Build cache dir doesn't exist: %s", functionBuild.CacheDir)
+	}
+
+	functionBuild.NoBaseImagesPull = true
+
+	return nil
+}
+
+func addBuildRetryFlags(cmd *cobra.Command, buildRetries *int, buildRetryOnTransientOnly *bool) {
+	cmd.Flags().IntVar(buildRetries, "retry-build", 0, "Number of times to retry a failed build (e.g. on flaky dependency fetches)")
+	cmd.Flags().BoolVar(buildRetryOnTransientOnly, "retry-build-transient-only", false, "Only retry the build on errors that seem transient (network errors, and errors of Docker registries and the Kubernetes API server)")
 }
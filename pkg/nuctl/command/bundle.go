@@ -0,0 +1,484 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+// a bundle is a tar archive holding a project, its functions, function events and the API gateways routing to
+// its functions, described by a manifest
+const (
+	bundleManifestVersion       = 1
+	bundleManifestFileName      = "manifest.yaml"
+	bundleProjectFileName       = "project.yaml"
+	bundleFunctionsDir          = "functions"
+	bundleFunctionEventsDir     = "functionevents"
+	bundleAPIGatewaysDir        = "apigateways"
+	bundleResourceFileExtension = ".yaml"
+)
+
+type bundleManifest struct {
+	Version        int      `json:"version"`
+	Project        string   `json:"project"`
+	Functions      []string `json:"functions,omitempty"`
+	FunctionEvents []string `json:"functionEvents,omitempty"`
+	APIGateways    []string `json:"apiGateways,omitempty"`
+}
+
+type exportBundleCommandeer struct {
+	*exportProjectCommandeer
+	projectName string
+	outputPath  string
+}
+
+func newExportBundleCommandeer(exportCommandeer *exportCommandeer) *exportBundleCommandeer {
+	commandeer := &exportBundleCommandeer{
+		exportProjectCommandeer: &exportProjectCommandeer{
+			exportCommandeer: exportCommandeer,
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bundle --project name --output bundle.tar",
+		Short: "Export a project with all its functions, function events and API gateways to a single tar archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.projectName == "" {
+				return errors.New("Project name must be provided (--project)")
+			}
+
+			if commandeer.outputPath == "" {
+				return errors.New("Output path must be provided (--output)")
+			}
+
+			// initialize root
+			if err := exportCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			return commandeer.exportProjectBundle(commandeer.projectName, commandeer.outputPath)
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.projectName, "project", "", "Name of the project to export")
+	cmd.Flags().StringVarP(&commandeer.outputPath, "output", "o", "", "Path of the bundle file to write (gzip compressed if it ends with .gz or .tgz)")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type importBundleCommandeer struct {
+	*importProjectCommandeer
+}
+
+func newImportBundleCommandeer(importCommandeer *importCommandeer) *importBundleCommandeer {
+	commandeer := &importBundleCommandeer{
+		importProjectCommandeer: &importProjectCommandeer{
+			importCommandeer: importCommandeer,
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bundle path-to-bundle-file",
+		Short: "Import a project with all its functions, function events and API gateways from a bundle created by export bundle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// initialize root
+			if err := importCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if err := importCommandeer.validateConcurrency(); err != nil {
+				return err
+			}
+
+			if err := importCommandeer.readFunctionConfigOverrides(); err != nil {
+				return errors.Wrap(err, "Failed to read overrides")
+			}
+
+			bundleFile, err := os.Open(args[0])
+			if err != nil {
+				return errors.Wrap(err, "Failed to open bundle file")
+			}
+
+			defer bundleFile.Close() // nolint: errcheck
+
+			return commandeer.importProjectBundle(bundleFile)
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// exportProjectBundle writes a project with all its functions, function events and API gateways to a bundle file
+func (e *exportProjectCommandeer) exportProjectBundle(projectName string, outputPath string) error {
+	projects, err := e.rootCommandeer.platform.GetProjects(&platform.GetProjectsOptions{
+		Meta: platform.ProjectMeta{
+			Name:      projectName,
+			Namespace: e.rootCommandeer.namespace,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get projects")
+	}
+
+	if len(projects) == 0 {
+		return nuclio.NewErrNotFound("Project not found")
+	}
+
+	projectConfig := projects[0].GetConfig()
+	functions, functionEvents, err := e.exportProjectFunctionsAndFunctionEvents(projectConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to gather functions and function events")
+	}
+
+	apiGateways, err := e.exportProjectAPIGateways(projectConfig, functions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to gather API gateways")
+	}
+
+	// the bundle is imported into whatever namespace the importer uses
+	bundledProjectConfig := *projectConfig
+	bundledProjectConfig.Meta.Namespace = ""
+
+	bundleFile, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create bundle file")
+	}
+
+	defer bundleFile.Close() // nolint: errcheck
+
+	var bundleWriter io.Writer = bundleFile
+
+	if strings.HasSuffix(outputPath, ".gz") || strings.HasSuffix(outputPath, ".tgz") {
+		gzipWriter := gzip.NewWriter(bundleFile)
+		defer gzipWriter.Close() // nolint: errcheck
+
+		bundleWriter = gzipWriter
+	}
+
+	if err := writeBundle(bundleWriter, &ProjectImportConfig{
+		Project:        &bundledProjectConfig,
+		Functions:      functions,
+		FunctionEvents: functionEvents,
+		APIGateways:    apiGateways,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to write bundle")
+	}
+
+	e.rootCommandeer.loggerInstance.InfoWith("Project exported",
+		"project", projectName,
+		"functions", len(functions),
+		"functionEvents", len(functionEvents),
+		"apiGateways", len(apiGateways),
+		"path", outputPath)
+
+	return nil
+}
+
+// importProjectBundle imports the project, functions, function events and API gateways of a bundle
+func (i *importProjectCommandeer) importProjectBundle(reader io.Reader) error {
+	projectImportConfig, err := readBundle(reader)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read bundle")
+	}
+
+	// validate everything up front, so that a bad bundle doesn't leave a partially imported project
+	if err := validateBundle(projectImportConfig); err != nil {
+		return errors.Wrap(err, "Invalid bundle")
+	}
+
+	projectImportConfig.Project.Meta.Namespace = i.rootCommandeer.namespace
+
+	return i.importProjects(map[string]*ProjectImportConfig{
+		projectImportConfig.Project.Meta.Name: projectImportConfig,
+	})
+}
+
+// isBundle returns whether the contents are of a bundle, compressed or not, rather than an exported project
+func isBundle(contents []byte) bool {
+
+	// gzip's magic number
+	if bytes.HasPrefix(contents, []byte{0x1f, 0x8b}) {
+		return true
+	}
+
+	// tar's magic, in the first entry's header
+	return len(contents) > 262 && bytes.HasPrefix(contents[257:], []byte("ustar"))
+}
+
+// writeBundle writes a project, its functions, function events and API gateways as a tar archive
+func writeBundle(writer io.Writer, projectImportConfig *ProjectImportConfig) error {
+	tarWriter := tar.NewWriter(writer)
+
+	manifest := bundleManifest{
+		Version: bundleManifestVersion,
+		Project: projectImportConfig.Project.Meta.Name,
+	}
+
+	// sorted, so that the bundle is stable for the same resources
+	for functionName := range projectImportConfig.Functions {
+		manifest.Functions = append(manifest.Functions, functionName)
+	}
+	sort.Strings(manifest.Functions)
+
+	for functionEventName := range projectImportConfig.FunctionEvents {
+		manifest.FunctionEvents = append(manifest.FunctionEvents, functionEventName)
+	}
+	sort.Strings(manifest.FunctionEvents)
+
+	for apiGatewayName := range projectImportConfig.APIGateways {
+		manifest.APIGateways = append(manifest.APIGateways, apiGatewayName)
+	}
+	sort.Strings(manifest.APIGateways)
+
+	if err := writeBundleEntry(tarWriter, bundleManifestFileName, manifest); err != nil {
+		return errors.Wrap(err, "Failed to write manifest")
+	}
+
+	if err := writeBundleEntry(tarWriter, bundleProjectFileName, projectImportConfig.Project); err != nil {
+		return errors.Wrap(err, "Failed to write project")
+	}
+
+	for _, functionName := range manifest.Functions {
+		if err := writeBundleEntry(tarWriter,
+			path.Join(bundleFunctionsDir, functionName+bundleResourceFileExtension),
+			projectImportConfig.Functions[functionName]); err != nil {
+			return errors.Wrapf(err, "Failed to write function %s", functionName)
+		}
+	}
+
+	for _, functionEventName := range manifest.FunctionEvents {
+		if err := writeBundleEntry(tarWriter,
+			path.Join(bundleFunctionEventsDir, functionEventName+bundleResourceFileExtension),
+			projectImportConfig.FunctionEvents[functionEventName]); err != nil {
+			return errors.Wrapf(err, "Failed to write function event %s", functionEventName)
+		}
+	}
+
+	for _, apiGatewayName := range manifest.APIGateways {
+		if err := writeBundleEntry(tarWriter,
+			path.Join(bundleAPIGatewaysDir, apiGatewayName+bundleResourceFileExtension),
+			projectImportConfig.APIGateways[apiGatewayName]); err != nil {
+			return errors.Wrapf(err, "Failed to write API gateway %s", apiGatewayName)
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+// readBundle reads a bundle written by writeBundle, verifying it against its manifest
+func readBundle(reader io.Reader) (*ProjectImportConfig, error) {
+	var manifest *bundleManifest
+
+	projectImportConfig := &ProjectImportConfig{
+		Functions:      map[string]*functionconfig.Config{},
+		FunctionEvents: map[string]*platform.FunctionEventConfig{},
+		APIGateways:    map[string]*platform.APIGatewayConfig{},
+	}
+
+	// bundles may be gzip compressed
+	bufferedReader := bufio.NewReader(reader)
+	if magic, err := bufferedReader.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read compressed bundle")
+		}
+
+		defer gzipReader.Close() // nolint: errcheck
+
+		reader = gzipReader
+	} else {
+		reader = bufferedReader
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read bundle entry")
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entryBody, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read bundle entry %s", header.Name)
+		}
+
+		entryDir, entryFileName := path.Split(header.Name)
+		resourceName := strings.TrimSuffix(entryFileName, bundleResourceFileExtension)
+
+		switch {
+		case header.Name == bundleManifestFileName:
+			manifest = &bundleManifest{}
+			err = yaml.Unmarshal(entryBody, manifest)
+
+		case header.Name == bundleProjectFileName:
+			projectImportConfig.Project = &platform.ProjectConfig{}
+			err = yaml.Unmarshal(entryBody, projectImportConfig.Project)
+
+		case entryDir == bundleFunctionsDir+"/":
+			functionConfig := &functionconfig.Config{}
+			err = yaml.Unmarshal(entryBody, functionConfig)
+			projectImportConfig.Functions[resourceName] = functionConfig
+
+		case entryDir == bundleFunctionEventsDir+"/":
+			functionEventConfig := &platform.FunctionEventConfig{}
+			err = yaml.Unmarshal(entryBody, functionEventConfig)
+			projectImportConfig.FunctionEvents[resourceName] = functionEventConfig
+
+		case entryDir == bundleAPIGatewaysDir+"/":
+			apiGatewayConfig := &platform.APIGatewayConfig{}
+			err = yaml.Unmarshal(entryBody, apiGatewayConfig)
+			projectImportConfig.APIGateways[resourceName] = apiGatewayConfig
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse bundle entry %s", header.Name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, errors.New("Bundle has no manifest")
+	}
+
+	if manifest.Version != bundleManifestVersion {
+		return nil, errors.Errorf("Unsupported bundle version %d", manifest.Version)
+	}
+
+	if projectImportConfig.Project == nil {
+		return nil, errors.New("Bundle has no project")
+	}
+
+	for _, functionName := range manifest.Functions {
+		if _, found := projectImportConfig.Functions[functionName]; !found {
+			return nil, errors.Errorf("Function %s is listed in the manifest but missing from the bundle", functionName)
+		}
+	}
+
+	for _, functionEventName := range manifest.FunctionEvents {
+		if _, found := projectImportConfig.FunctionEvents[functionEventName]; !found {
+			return nil, errors.Errorf("Function event %s is listed in the manifest but missing from the bundle",
+				functionEventName)
+		}
+	}
+
+	for _, apiGatewayName := range manifest.APIGateways {
+		if _, found := projectImportConfig.APIGateways[apiGatewayName]; !found {
+			return nil, errors.Errorf("API gateway %s is listed in the manifest but missing from the bundle",
+				apiGatewayName)
+		}
+	}
+
+	return projectImportConfig, nil
+}
+
+// validateBundle verifies the bundled resources are complete and reference each other consistently
+func validateBundle(projectImportConfig *ProjectImportConfig) error {
+	if projectImportConfig.Project.Meta.Name == "" {
+		return errors.New("Project has no name")
+	}
+
+	for functionName, functionConfig := range projectImportConfig.Functions {
+		if functionConfig.Meta.Name != functionName {
+			return errors.Errorf("Function %s has a mismatching name: %s", functionName, functionConfig.Meta.Name)
+		}
+
+		if functionConfig.Spec.Runtime == "" && functionConfig.Spec.Image == "" {
+			return errors.Errorf("Function %s has neither a runtime nor an image", functionName)
+		}
+	}
+
+	// function events are imported after functions, and must belong to one of them
+	for functionEventName, functionEventConfig := range projectImportConfig.FunctionEvents {
+		functionName := functionEventConfig.Meta.Labels["nuclio.io/function-name"]
+		if _, found := projectImportConfig.Functions[functionName]; !found {
+			return errors.Errorf("Function event %s references function %s, which isn't in the bundle",
+				functionEventName,
+				functionName)
+		}
+	}
+
+	// as are API gateways, which must route to bundled functions only
+	for apiGatewayName, apiGatewayConfig := range projectImportConfig.APIGateways {
+		if apiGatewayConfig.Meta.Name != apiGatewayName {
+			return errors.Errorf("API gateway %s has a mismatching name: %s", apiGatewayName, apiGatewayConfig.Meta.Name)
+		}
+
+		for _, functionName := range getAPIGatewayUpstreamFunctionNames(apiGatewayConfig) {
+			if _, found := projectImportConfig.Functions[functionName]; !found {
+				return errors.Errorf("API gateway %s routes to function %s, which isn't in the bundle",
+					apiGatewayName,
+					functionName)
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeBundleEntry(tarWriter *tar.Writer, name string, resource interface{}) error {
+	encodedResource, err := yaml.Marshal(resource)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode bundle entry")
+	}
+
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(encodedResource)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return errors.Wrap(err, "Failed to write bundle entry header")
+	}
+
+	if _, err := tarWriter.Write(encodedResource); err != nil {
+		return errors.Wrap(err, "Failed to write bundle entry")
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type bundleTestSuite struct {
+	suite.Suite
+}
+
+func (suite *bundleTestSuite) TestWriteAndReadBundle() {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Spec.Runtime = "python:3.6"
+	functionConfig.Spec.Handler = "main:handler"
+
+	bundle := &ProjectImportConfig{
+		Project: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{
+				Name: "my-project",
+			},
+			Spec: platform.ProjectSpec{
+				Description: "some project",
+			},
+		},
+		Functions: map[string]*functionconfig.Config{
+			functionConfig.Meta.Name: functionConfig,
+		},
+		FunctionEvents: map[string]*platform.FunctionEventConfig{
+			"my-event": {
+				Meta: platform.FunctionEventMeta{
+					Name: "my-event",
+					Labels: map[string]string{
+						"nuclio.io/function-name": "my-function",
+					},
+				},
+				Spec: platform.FunctionEventSpec{
+					Body: "some body",
+				},
+			},
+		},
+		APIGateways: map[string]*platform.APIGatewayConfig{
+			"my-api-gateway": {
+				Meta: platform.APIGatewayMeta{
+					Name: "my-api-gateway",
+				},
+				Spec: platform.APIGatewaySpec{
+					Host: "my-host.com",
+					Upstreams: []platform.APIGatewayUpstreamSpec{
+						{
+							Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+							NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "my-function"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	bundleBuffer := bytes.Buffer{}
+	err := writeBundle(&bundleBuffer, bundle)
+	suite.Require().NoError(err)
+
+	readBundle, err := readBundle(&bundleBuffer)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(bundle.Project, readBundle.Project)
+	suite.Require().Equal("main:handler", readBundle.Functions["my-function"].Spec.Handler)
+	suite.Require().Equal(bundle.FunctionEvents, readBundle.FunctionEvents)
+	suite.Require().Equal(bundle.APIGateways, readBundle.APIGateways)
+}
+
+func (suite *bundleTestSuite) TestReadCompressedBundle() {
+	bundle := &ProjectImportConfig{
+		Project: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{
+				Name: "my-project",
+			},
+		},
+		Functions:      map[string]*functionconfig.Config{},
+		FunctionEvents: map[string]*platform.FunctionEventConfig{},
+	}
+
+	bundleBuffer := bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(&bundleBuffer)
+	err := writeBundle(gzipWriter, bundle)
+	suite.Require().NoError(err)
+	suite.Require().NoError(gzipWriter.Close())
+
+	suite.Require().True(isBundle(bundleBuffer.Bytes()))
+
+	readBundle, err := readBundle(&bundleBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Equal(bundle.Project, readBundle.Project)
+}
+
+func (suite *bundleTestSuite) TestIsBundle() {
+	bundleBuffer := bytes.Buffer{}
+	err := writeBundle(&bundleBuffer, &ProjectImportConfig{
+		Project: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{
+				Name: "my-project",
+			},
+		},
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().True(isBundle(bundleBuffer.Bytes()))
+	suite.Require().False(isBundle([]byte("project:\n  metadata:\n    name: my-project\n")))
+}
+
+func (suite *bundleTestSuite) TestReadBundleMissingFunction() {
+	bundleBuffer := bytes.Buffer{}
+	tarWriter := tar.NewWriter(&bundleBuffer)
+
+	err := writeBundleEntry(tarWriter, bundleManifestFileName, bundleManifest{
+		Version:   bundleManifestVersion,
+		Project:   "my-project",
+		Functions: []string{"my-function"},
+	})
+	suite.Require().NoError(err)
+
+	err = writeBundleEntry(tarWriter, bundleProjectFileName, platform.ProjectConfig{})
+	suite.Require().NoError(err)
+	suite.Require().NoError(tarWriter.Close())
+
+	_, err = readBundle(&bundleBuffer)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Function my-function is listed in the manifest")
+}
+
+func (suite *bundleTestSuite) TestValidateBundle() {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Spec.Runtime = "python:3.6"
+
+	bundle := &ProjectImportConfig{
+		Project: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{
+				Name: "my-project",
+			},
+		},
+		Functions: map[string]*functionconfig.Config{
+			functionConfig.Meta.Name: functionConfig,
+		},
+		FunctionEvents: map[string]*platform.FunctionEventConfig{
+			"my-event": {
+				Meta: platform.FunctionEventMeta{
+					Name: "my-event",
+					Labels: map[string]string{
+						"nuclio.io/function-name": "my-function",
+					},
+				},
+			},
+		},
+	}
+
+	suite.Require().NoError(validateBundle(bundle))
+
+	// function events must reference a bundled function
+	bundle.FunctionEvents["my-event"].Meta.Labels["nuclio.io/function-name"] = "other-function"
+	err := validateBundle(bundle)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "references function other-function")
+
+	// API gateways must route to bundled functions
+	bundle.FunctionEvents["my-event"].Meta.Labels["nuclio.io/function-name"] = "my-function"
+	bundle.APIGateways = map[string]*platform.APIGatewayConfig{
+		"my-api-gateway": {
+			Meta: platform.APIGatewayMeta{
+				Name: "my-api-gateway",
+			},
+			Spec: platform.APIGatewaySpec{
+				Upstreams: []platform.APIGatewayUpstreamSpec{
+					{
+						Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+						NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "other-function"},
+					},
+				},
+			},
+		},
+	}
+
+	err = validateBundle(bundle)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "routes to function other-function")
+}
+
+func TestBundleTestSuite(t *testing.T) {
+	suite.Run(t, new(bundleTestSuite))
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+// the canary of a function is deployed as another function alongside it, named after the function and labeled
+// with its name. its ingresses are those of the function, which the nginx ingress controller splits traffic
+// between by the weight annotated on the canary's ingress
+const (
+	canaryFunctionLabel         = "nuclio.io/canary-function"
+	canaryFunctionSuffix        = "-canary"
+	nginxCanaryAnnotation       = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+// deployCanary deploys the function as a canary alongside the live version, sending it the given percentage
+// of the traffic to the live version's ingresses. if the canary fails to deploy, it is deleted
+func (d *deployCommandeer) deployCanary(cmd *cobra.Command) error {
+	rootCommandeer := d.rootCommandeer
+
+	if err := rootCommandeer.validateOperationPlatform("deploy --canary", d.canarySupportedPlatforms); err != nil {
+		return err
+	}
+
+	liveFunction, err := getCanaryLiveFunction(rootCommandeer, d.functionName)
+	if err != nil {
+		return err
+	}
+
+	canaryTriggers, err := getCanaryTriggers(d.functionConfig.Spec.Triggers,
+		liveFunction.GetConfig().Spec.Triggers,
+		d.canaryWeight)
+	if err != nil {
+		return errors.Wrap(err, "Failed to split traffic to canary")
+	}
+
+	canaryConfig := d.functionConfig
+	canaryConfig.Meta.Name = d.functionName + canaryFunctionSuffix
+	canaryConfig.Meta.Labels = map[string]string{}
+	for labelName, labelValue := range d.functionConfig.Meta.Labels {
+		canaryConfig.Meta.Labels[labelName] = labelValue
+	}
+	canaryConfig.Meta.Labels[canaryFunctionLabel] = d.functionName
+	canaryConfig.Spec.Triggers = canaryTriggers
+
+	rootCommandeer.loggerInstance.InfoWith("Deploying canary of function",
+		"name", canaryConfig.Meta.Name,
+		"weight", d.canaryWeight)
+
+	if _, err := rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:                    rootCommandeer.loggerInstance,
+		FunctionConfig:            canaryConfig,
+		InputImageFile:            d.inputImageFile,
+		BuildRetries:              d.buildRetries,
+		BuildRetryOnTransientOnly: d.buildRetryOnTransientOnly,
+		BuildOutputLineHandler: func(line string) {
+			fmt.Fprintln(cmd.OutOrStdout(), line) // nolint: errcheck
+		},
+	}); err != nil {
+		rootCommandeer.loggerInstance.WarnWith("Canary deploy failed, deleting canary",
+			"name", canaryConfig.Meta.Name,
+			"err", err.Error())
+
+		if deleteErr := rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+			FunctionConfig: canaryConfig,
+		}); deleteErr != nil {
+			rootCommandeer.loggerInstance.WarnWith("Failed to delete canary",
+				"name", canaryConfig.Meta.Name,
+				"err", deleteErr.Error())
+		}
+
+		return errors.Wrap(err, "Failed to deploy canary")
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Canary deployed, promote or roll it back once done",
+		"name", canaryConfig.Meta.Name,
+		"weight", d.canaryWeight)
+
+	return nil
+}
+
+// getCanaryTriggers returns the triggers of a canary - its own, with the ingresses of the live version's http
+// trigger, annotated with the weight of the traffic to send to the canary
+func getCanaryTriggers(triggers map[string]functionconfig.Trigger,
+	liveTriggers map[string]functionconfig.Trigger,
+	weight int) (map[string]functionconfig.Trigger, error) {
+
+	var liveHTTPTriggerName string
+	var liveHTTPTrigger functionconfig.Trigger
+
+	for triggerName, trigger := range functionconfig.GetTriggersByKind(liveTriggers, "http") {
+		if _, hasIngresses := trigger.Attributes["ingresses"]; hasIngresses {
+			liveHTTPTriggerName, liveHTTPTrigger = triggerName, trigger
+			break
+		}
+	}
+
+	if liveHTTPTriggerName == "" {
+		return nil, errors.New("The live version of the function has no http trigger ingresses to split")
+	}
+
+	canaryTriggers := map[string]functionconfig.Trigger{}
+	for triggerName, trigger := range triggers {
+		if trigger.Kind != "http" {
+			canaryTriggers[triggerName] = trigger
+		}
+	}
+
+	// the canary's own http trigger, if it has one, but with the live ingresses
+	canaryHTTPTriggerName, canaryHTTPTrigger := liveHTTPTriggerName, liveHTTPTrigger
+	for triggerName, trigger := range functionconfig.GetTriggersByKind(triggers, "http") {
+		canaryHTTPTriggerName, canaryHTTPTrigger = triggerName, trigger
+		break
+	}
+
+	attributes := map[string]interface{}{}
+	for attributeName, attributeValue := range canaryHTTPTrigger.Attributes {
+		attributes[attributeName] = attributeValue
+	}
+	attributes["ingresses"] = liveHTTPTrigger.Attributes["ingresses"]
+	canaryHTTPTrigger.Attributes = attributes
+
+	annotations := map[string]string{}
+	for annotationName, annotationValue := range canaryHTTPTrigger.Annotations {
+		annotations[annotationName] = annotationValue
+	}
+	annotations[nginxCanaryAnnotation] = "true"
+	annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(weight)
+	canaryHTTPTrigger.Annotations = annotations
+
+	canaryTriggers[canaryHTTPTriggerName] = canaryHTTPTrigger
+
+	return canaryTriggers, nil
+}
+
+// getTriggersWithoutCanaryAnnotations returns a copy of the triggers, without the annotations which split
+// traffic to a canary
+func getTriggersWithoutCanaryAnnotations(triggers map[string]functionconfig.Trigger) map[string]functionconfig.Trigger {
+	triggersWithoutCanaryAnnotations := map[string]functionconfig.Trigger{}
+
+	for triggerName, trigger := range triggers {
+		if trigger.Annotations != nil {
+			annotations := map[string]string{}
+			for annotationName, annotationValue := range trigger.Annotations {
+				if annotationName != nginxCanaryAnnotation && annotationName != nginxCanaryWeightAnnotation {
+					annotations[annotationName] = annotationValue
+				}
+			}
+
+			trigger.Annotations = annotations
+		}
+
+		triggersWithoutCanaryAnnotations[triggerName] = trigger
+	}
+
+	return triggersWithoutCanaryAnnotations
+}
+
+func getCanaryLiveFunction(rootCommandeer *RootCommandeer, functionName string) (platform.Function, error) {
+	functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: rootCommandeer.namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function %s must be deployed before a canary of it", functionName))
+	}
+
+	return functions[0], nil
+}
+
+func getCanaryFunction(rootCommandeer *RootCommandeer, functionName string) (platform.Function, error) {
+	canaryFunction, err := findCanaryFunction(rootCommandeer, functionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if canaryFunction == nil {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function %s has no canary", functionName))
+	}
+
+	return canaryFunction, nil
+}
+
+// findCanaryFunction returns the canary of the function, or nil if it has none
+func findCanaryFunction(rootCommandeer *RootCommandeer, functionName string) (platform.Function, error) {
+	functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName + canaryFunctionSuffix,
+		Namespace: rootCommandeer.namespace,
+		Labels:    fmt.Sprintf("%s=%s", canaryFunctionLabel, functionName),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nil, nil
+	}
+
+	return functions[0], nil
+}
+
+type promoteCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newPromoteCommandeer(rootCommandeer *RootCommandeer) *promoteCommandeer {
+	commandeer := &promoteCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote canaries",
+	}
+
+	cmd.AddCommand(
+		newPromoteFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type promoteFunctionCommandeer struct {
+	*promoteCommandeer
+
+	// the ingress controller splits the traffic to canaries, so they're only deployed on kubernetes
+	supportedPlatforms []string
+}
+
+func newPromoteFunctionCommandeer(promoteCommandeer *promoteCommandeer) *promoteFunctionCommandeer {
+	commandeer := &promoteFunctionCommandeer{
+		promoteCommandeer:  promoteCommandeer,
+		supportedPlatforms: kubePlatforms,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Update a function to its canary (deploy --canary), which is then deleted",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCommandeer := promoteCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initializeForOperation("promote function",
+				commandeer.supportedPlatforms); err != nil {
+				return err
+			}
+
+			liveFunction, err := getCanaryLiveFunction(rootCommandeer, args[0])
+			if err != nil {
+				return err
+			}
+
+			canaryFunction, err := getCanaryFunction(rootCommandeer, args[0])
+			if err != nil {
+				return err
+			}
+
+			// the live version takes the canary's spec, and its ingresses then get all the traffic
+			liveFunctionConfig := liveFunction.GetConfig()
+			promotedSpec := canaryFunction.GetConfig().Spec
+			promotedSpec.Triggers = getTriggersWithoutCanaryAnnotations(promotedSpec.Triggers)
+
+			if err := rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+				FunctionMeta: &liveFunctionConfig.Meta,
+				FunctionSpec: &promotedSpec,
+			}); err != nil {
+				return errors.Wrap(err, "Failed to update function to its canary")
+			}
+
+			if err := rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+				FunctionConfig: *canaryFunction.GetConfig(),
+			}); err != nil {
+				return errors.Wrap(err, "Function was updated to its canary, but failed to delete the canary")
+			}
+
+			rootCommandeer.loggerInstance.InfoWith("Canary promoted", "name", args[0])
+
+			return nil
+		},
+	}
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
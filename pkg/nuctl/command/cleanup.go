@@ -0,0 +1,106 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/nuclio/pkg/platform/local"
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type cleanupCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	remove         bool
+
+	// only the local platform runs function containers itself
+	supportedPlatforms []string
+}
+
+func newCleanupCommandeer(rootCommandeer *RootCommandeer) *cleanupCommandeer {
+	commandeer := &cleanupCommandeer{
+		rootCommandeer:     rootCommandeer,
+		supportedPlatforms: []string{"local"},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Report (and remove) function containers left behind without a registered function (local platform)",
+		Long: `Report function containers left behind without a registered function, e.g. by deploys that failed
+or were interrupted. Only containers labeled by nuclio as a function's container are considered`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := rootCommandeer.initializeForOperation("cleanup", commandeer.supportedPlatforms); err != nil {
+				return err
+			}
+
+			localPlatform := rootCommandeer.platform.(*local.Platform)
+
+			orphanContainers, err := localPlatform.GetOrphanContainers(rootCommandeer.namespace)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get orphan containers")
+			}
+
+			commandeer.renderOrphanContainers(cmd, orphanContainers)
+
+			if !commandeer.remove || len(orphanContainers) == 0 {
+				return nil
+			}
+
+			if err := localPlatform.RemoveOrphanContainers(orphanContainers); err != nil {
+				return errors.Wrap(err, "Failed to remove orphan containers")
+			}
+
+			cmd.Printf("Removed %d orphan containers\n", len(orphanContainers))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&commandeer.remove, "remove", false, "Remove the orphan containers")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *cleanupCommandeer) renderOrphanContainers(cmd *cobra.Command, orphanContainers []local.OrphanContainer) {
+	if len(orphanContainers) == 0 {
+		cmd.Println("No orphan containers found")
+		return
+	}
+
+	var records [][]string
+	for _, orphanContainer := range orphanContainers {
+		containerID := orphanContainer.ID
+		if len(containerID) > 12 {
+			containerID = containerID[:12]
+		}
+
+		records = append(records, []string{
+			containerID,
+			orphanContainer.Name,
+			orphanContainer.FunctionName,
+			orphanContainer.Status,
+		})
+	}
+
+	renderer.NewRenderer(cmd.OutOrStdout()).RenderTable([]string{"Container ID", "Name", "Function", "Status"}, records)
+	cmd.Printf("Found %d orphan containers\n", len(orphanContainers))
+}
@@ -0,0 +1,131 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/errors"
+)
+
+// SourceChecksumAlgorithm is the only algorithm with which source checksums are computed
+const SourceChecksumAlgorithm = "sha256"
+
+// ParseSourceChecksum normalizes an expected checksum, given as <hex> or sha256:<hex>, to lowercase hex
+func ParseSourceChecksum(checksum string) (string, error) {
+	if separatorIndex := strings.Index(checksum, ":"); separatorIndex != -1 {
+		if algorithm := checksum[:separatorIndex]; algorithm != SourceChecksumAlgorithm {
+			return "", errors.Errorf("Unsupported checksum algorithm %s (only %s is supported)",
+				algorithm,
+				SourceChecksumAlgorithm)
+		}
+
+		checksum = checksum[separatorIndex+1:]
+	}
+
+	checksum = strings.ToLower(checksum)
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return "", errors.Errorf("Invalid %s checksum %s", SourceChecksumAlgorithm, checksum)
+	}
+
+	return checksum, nil
+}
+
+// ComputeSourceChecksum returns the hex sha256 of a local function source. The checksum of a file (e.g. an
+// archive) is that of its contents. The checksum of a directory is that of a manifest with a
+// "<sha256 of contents>  <relative path>" line per file, sorted by path (as sha256sum would print it, with
+// links hashed by their target path). .git directories are ignored, so that clones of the same commit
+// have the same checksum
+func ComputeSourceChecksum(sourcePath string) (string, error) {
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to stat source")
+	}
+
+	if !sourceInfo.IsDir() {
+		return computeFileChecksum(sourcePath)
+	}
+
+	fileChecksums := map[string]string{}
+	if err := filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve relative path")
+		}
+
+		// links are part of the source as links, regardless of what they point at
+		if info.Mode()&os.ModeSymlink != 0 {
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read link %s", relativePath)
+			}
+
+			fileChecksums[filepath.ToSlash(relativePath)] = ComputeChecksum([]byte(linkTarget))
+			return nil
+		}
+
+		fileChecksum, err := computeFileChecksum(path)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to compute checksum of %s", relativePath)
+		}
+
+		fileChecksums[filepath.ToSlash(relativePath)] = fileChecksum
+
+		return nil
+	}); err != nil {
+		return "", errors.Wrap(err, "Failed to walk source directory")
+	}
+
+	var relativePaths []string
+	for relativePath := range fileChecksums {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	sort.Strings(relativePaths)
+
+	manifest := strings.Builder{}
+	for _, relativePath := range relativePaths {
+		fmt.Fprintf(&manifest, "%s  %s\n", fileChecksums[relativePath], relativePath) // nolint: errcheck
+	}
+
+	return ComputeChecksum([]byte(manifest.String())), nil
+}
+
+// ComputeChecksum returns the hex sha256 of the given contents
+func ComputeChecksum(contents []byte) string {
+	checksum := sha256.Sum256(contents)
+
+	return hex.EncodeToString(checksum[:])
+}
+
+func computeFileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to open file")
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.Wrap(err, "Failed to read file")
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type checksumTestSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func (suite *checksumTestSuite) SetupTest() {
+	var err error
+
+	suite.tempDir, err = ioutil.TempDir("", "checksum-test-")
+	suite.Require().NoError(err)
+}
+
+func (suite *checksumTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *checksumTestSuite) TestParseSourceChecksum() {
+	checksum := ComputeChecksum([]byte("some source"))
+
+	for _, testCase := range []struct {
+		checksum      string
+		expectedError bool
+	}{
+		{checksum: checksum},
+		{checksum: "sha256:" + checksum},
+		{checksum: "SHA256:" + checksum, expectedError: true},
+		{checksum: "md5:" + checksum, expectedError: true},
+		{checksum: checksum[:10], expectedError: true},
+		{checksum: "not-hex", expectedError: true},
+	} {
+		parsedChecksum, err := ParseSourceChecksum(testCase.checksum)
+		if testCase.expectedError {
+			suite.Require().Error(err, testCase.checksum)
+			continue
+		}
+
+		suite.Require().NoError(err)
+		suite.Require().Equal(checksum, parsedChecksum)
+	}
+}
+
+func (suite *checksumTestSuite) TestComputeSourceChecksum() {
+	sourceFilePath := filepath.Join(suite.tempDir, "main.py")
+	err := ioutil.WriteFile(sourceFilePath, []byte("some source"), 0644)
+	suite.Require().NoError(err)
+
+	// a file's checksum is that of its contents
+	checksum, err := ComputeSourceChecksum(sourceFilePath)
+	suite.Require().NoError(err)
+	suite.Require().Equal(ComputeChecksum([]byte("some source")), checksum)
+
+	err = os.MkdirAll(filepath.Join(suite.tempDir, "lib", ".git"), 0755)
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(filepath.Join(suite.tempDir, "lib", "util.py"), []byte("some util"), 0644)
+	suite.Require().NoError(err)
+
+	// a directory's checksum is that of its sha256sum-like manifest
+	checksum, err = ComputeSourceChecksum(suite.tempDir)
+	suite.Require().NoError(err)
+	suite.Require().Equal(ComputeChecksum([]byte(
+		ComputeChecksum([]byte("some util"))+"  lib/util.py\n"+
+			ComputeChecksum([]byte("some source"))+"  main.py\n")), checksum)
+
+	// .git is ignored
+	err = ioutil.WriteFile(filepath.Join(suite.tempDir, "lib", ".git", "HEAD"), []byte("ref"), 0644)
+	suite.Require().NoError(err)
+
+	unchangedChecksum, err := ComputeSourceChecksum(suite.tempDir)
+	suite.Require().NoError(err)
+	suite.Require().Equal(checksum, unchangedChecksum)
+
+	// any change to the source changes the checksum
+	err = ioutil.WriteFile(filepath.Join(suite.tempDir, "lib", "util.py"), []byte("tampered util"), 0644)
+	suite.Require().NoError(err)
+
+	changedChecksum, err := ComputeSourceChecksum(suite.tempDir)
+	suite.Require().NoError(err)
+	suite.Require().NotEqual(checksum, changedChecksum)
+
+	_, err = ComputeSourceChecksum(filepath.Join(suite.tempDir, "missing"))
+	suite.Require().Error(err)
+}
+
+func TestChecksumTestSuite(t *testing.T) {
+	suite.Run(t, new(checksumTestSuite))
+}
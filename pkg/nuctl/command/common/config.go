@@ -0,0 +1,106 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+)
+
+// Config is nuctl's configuration file, holding contexts - named defaults of the flags commands are run with
+// (e.g. the platform and namespace) - one of which is the current, similarly to a kubeconfig
+type Config struct {
+	CurrentContext string              `json:"currentContext,omitempty"`
+	Contexts       map[string]*Context `json:"contexts,omitempty"`
+}
+
+// Context holds defaults of the flags commands are run with. flags given explicitly, or through their
+// environment variables, take precedence
+type Context struct {
+	Platform     string `json:"platform,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Project      string `json:"project,omitempty"`
+	Registry     string `json:"registry,omitempty"`
+	RunRegistry  string `json:"runRegistry,omitempty"`
+	Kubeconfig   string `json:"kubeconfig,omitempty"`
+	KubeContext  string `json:"kubeContext,omitempty"`
+	DashboardURL string `json:"dashboardURL,omitempty"`
+}
+
+// GetConfigPath returns the path of nuctl's configuration file - NUCTL_CONFIG, or ~/.nuctl/config
+func GetConfigPath() string {
+	if configPath := os.Getenv("NUCTL_CONFIG"); configPath != "" {
+		return configPath
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(homeDir, ".nuctl", "config")
+}
+
+// ReadConfig reads the configuration file, which is empty if it doesn't exist
+func ReadConfig(path string) (*Config, error) {
+	config := Config{}
+
+	encodedConfig, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) || path == "" {
+			return &config, nil
+		}
+
+		return nil, errors.Wrap(err, "Failed to read file")
+	}
+
+	if err := yaml.Unmarshal(encodedConfig, &config); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse %s", path)
+	}
+
+	return &config, nil
+}
+
+// Write writes the configuration file, readable by the user alone (it may hold registry URLs with credentials)
+func (c *Config) Write(path string) error {
+	if path == "" {
+		return errors.New("Configuration path is unknown, set NUCTL_CONFIG")
+	}
+
+	encodedConfig, err := yaml.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode configuration")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "Failed to create configuration directory")
+	}
+
+	return ioutil.WriteFile(path, encodedConfig, 0600)
+}
+
+// GetContext returns the context by name, failing if there's no such context
+func (c *Config) GetContext(name string) (*Context, error) {
+	context, found := c.Contexts[name]
+	if !found {
+		return nil, errors.Errorf("Context %s not found", name)
+	}
+
+	return context, nil
+}
+
+// GetContextNames returns the names of the contexts, sorted
+func (c *Config) GetContextNames() []string {
+	var contextNames []string
+
+	for contextName := range c.Contexts {
+		contextNames = append(contextNames, contextName)
+	}
+
+	sort.Strings(contextNames)
+
+	return contextNames
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type configTestSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func (suite *configTestSuite) SetupTest() {
+	var err error
+
+	suite.tempDir, err = ioutil.TempDir("", "nuctl-config-")
+	suite.Require().NoError(err)
+}
+
+func (suite *configTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *configTestSuite) TestReadMissingConfig() {
+	config, err := ReadConfig(path.Join(suite.tempDir, "config"))
+	suite.Require().NoError(err)
+	suite.Require().Empty(config.CurrentContext)
+	suite.Require().Empty(config.GetContextNames())
+
+	_, err = config.GetContext("dev")
+	suite.Require().Error(err)
+}
+
+func (suite *configTestSuite) TestWriteRead() {
+	configPath := path.Join(suite.tempDir, "nuctl", "config")
+
+	config := &Config{
+		CurrentContext: "prod",
+		Contexts: map[string]*Context{
+			"prod": {Platform: "kube", Namespace: "nuclio", Registry: "my-registry"},
+			"dev":  {Platform: "local", Project: "my-project"},
+		},
+	}
+
+	err := config.Write(configPath)
+	suite.Require().NoError(err)
+
+	// the configuration may hold credentials in the registry URL
+	fileInfo, err := os.Stat(configPath)
+	suite.Require().NoError(err)
+	suite.Require().Equal(os.FileMode(0600), fileInfo.Mode().Perm())
+
+	readConfig, err := ReadConfig(configPath)
+	suite.Require().NoError(err)
+	suite.Require().Equal(config, readConfig)
+	suite.Require().Equal([]string{"dev", "prod"}, readConfig.GetContextNames())
+
+	context, err := readConfig.GetContext("dev")
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-project", context.Project)
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(configTestSuite))
+}
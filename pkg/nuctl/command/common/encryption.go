@@ -0,0 +1,129 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	exportCipher = "aes-256-gcm"
+	exportKDF    = "scrypt"
+)
+
+// EncryptedExport wraps an export encrypted with a key, so that it can be stored where it may be read (e.g. git)
+type EncryptedExport struct {
+	Encrypted *EncryptedData `json:"encrypted"`
+}
+
+// EncryptedData is data encrypted with a key derived from a passphrase
+type EncryptedData struct {
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"`
+	Salt   string `json:"salt"`
+	Nonce  string `json:"nonce"`
+	Data   string `json:"data"`
+}
+
+// EncryptExport encrypts the export with AES-256-GCM, keyed by the passphrase (through scrypt)
+func EncryptExport(export []byte, passphrase string) (*EncryptedExport, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate salt")
+	}
+
+	aead, err := createExportAEAD(passphrase, salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate nonce")
+	}
+
+	return &EncryptedExport{
+		Encrypted: &EncryptedData{
+			Cipher: exportCipher,
+			KDF:    exportKDF,
+			Salt:   base64.StdEncoding.EncodeToString(salt),
+			Nonce:  base64.StdEncoding.EncodeToString(nonce),
+			Data:   base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, export, nil)),
+		},
+	}, nil
+}
+
+// ParseEncryptedExport returns the encrypted export the input holds (as JSON or YAML), or nil if it's not one
+func ParseEncryptedExport(input []byte) *EncryptedExport {
+	encryptedExport := EncryptedExport{}
+
+	// yaml is a superset of json
+	if err := yaml.Unmarshal(input, &encryptedExport); err != nil ||
+		encryptedExport.Encrypted == nil ||
+		encryptedExport.Encrypted.Cipher == "" {
+		return nil
+	}
+
+	return &encryptedExport
+}
+
+// DecryptExport decrypts an export encrypted by EncryptExport with the same passphrase
+func DecryptExport(encryptedExport *EncryptedExport, passphrase string) ([]byte, error) {
+	encryptedData := encryptedExport.Encrypted
+	if encryptedData.Cipher != exportCipher || encryptedData.KDF != exportKDF {
+		return nil, errors.Errorf("Unsupported encryption: %s (key derived with %s)",
+			encryptedData.Cipher,
+			encryptedData.KDF)
+	}
+
+	var decodedFields [3][]byte
+	for fieldIndex, field := range []string{encryptedData.Salt, encryptedData.Nonce, encryptedData.Data} {
+		decodedField, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode encrypted export")
+		}
+
+		decodedFields[fieldIndex] = decodedField
+	}
+
+	salt, nonce, data := decodedFields[0], decodedFields[1], decodedFields[2]
+
+	aead, err := createExportAEAD(passphrase, salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher")
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("Invalid nonce in encrypted export")
+	}
+
+	export, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.New("Failed to decrypt export, is the key correct?")
+	}
+
+	return export, nil
+}
+
+func createExportAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("Key must not be empty")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to derive key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create block cipher")
+	}
+
+	return cipher.NewGCM(block)
+}
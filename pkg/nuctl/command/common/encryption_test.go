@@ -0,0 +1,61 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/suite"
+)
+
+type encryptionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *encryptionTestSuite) TestEncryptDecryptExport() {
+	export := []byte("my-function:\n  metadata:\n    name: my-function\n")
+
+	encryptedExport, err := EncryptExport(export, "my-key")
+	suite.Require().NoError(err)
+	suite.Require().Equal("aes-256-gcm", encryptedExport.Encrypted.Cipher)
+	suite.Require().NotContains(encryptedExport.Encrypted.Data, "my-function")
+
+	// the encrypted export is parsed back from its rendered form
+	encodedEncryptedExport, err := yaml.Marshal(encryptedExport)
+	suite.Require().NoError(err)
+
+	parsedEncryptedExport := ParseEncryptedExport(encodedEncryptedExport)
+	suite.Require().Equal(encryptedExport, parsedEncryptedExport)
+
+	decryptedExport, err := DecryptExport(parsedEncryptedExport, "my-key")
+	suite.Require().NoError(err)
+	suite.Require().Equal(export, decryptedExport)
+
+	// a wrong key fails to decrypt
+	_, err = DecryptExport(parsedEncryptedExport, "other-key")
+	suite.Require().Error(err)
+
+	// each encryption is salted differently
+	otherEncryptedExport, err := EncryptExport(export, "my-key")
+	suite.Require().NoError(err)
+	suite.Require().NotEqual(encryptedExport.Encrypted.Data, otherEncryptedExport.Encrypted.Data)
+}
+
+func (suite *encryptionTestSuite) TestParseEncryptedExportNotEncrypted() {
+	for _, input := range []string{
+		"my-function:\n  metadata:\n    name: my-function\n",
+		`{"my-function": {"metadata": {"name": "my-function"}}}`,
+		"encrypted:\n  metadata:\n    name: encrypted\n",
+		"not yaml: [",
+	} {
+		suite.Require().Nil(ParseEncryptedExport([]byte(input)), input)
+	}
+}
+
+func (suite *encryptionTestSuite) TestEncryptEmptyKey() {
+	_, err := EncryptExport([]byte("export"), "")
+	suite.Require().Error(err)
+}
+
+func TestEncryptionTestSuite(t *testing.T) {
+	suite.Run(t, new(encryptionTestSuite))
+}
@@ -0,0 +1,170 @@
+package common
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/nuclio/errors"
+	"k8s.io/api/core/v1"
+)
+
+var (
+	envVarNameRegex      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	envVarReferenceRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// ReadEnvFile reads the environment variables of an env file (e.g. .env), in order
+func ReadEnvFile(path string) ([]v1.EnvVar, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read env file")
+	}
+
+	envVars, err := ParseEnvFile(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse env file %s", path)
+	}
+
+	return envVars, nil
+}
+
+// ParseEnvFile parses the body of an env file. Each line is NAME=VALUE, optionally prefixed by "export". Blank
+// lines and lines starting with # are ignored, as is a comment (" #") after an unquoted value. Values may be
+// single quoted, taken as is, or double quoted, with \n, \t, \" and \\ escapes
+func ParseEnvFile(body []byte) ([]v1.EnvVar, error) {
+	var envVars []v1.EnvVar
+
+	for lineIndex, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		nameAndValue := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(nameAndValue[0])
+		if len(nameAndValue) != 2 || !envVarNameRegex.MatchString(name) {
+			return nil, errors.Errorf("Line %d must be in the form of NAME=VALUE", lineIndex+1)
+		}
+
+		value, err := parseEnvFileValue(strings.TrimSpace(nameAndValue[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse the value of %s on line %d", name, lineIndex+1)
+		}
+
+		envVars = append(envVars, v1.EnvVar{Name: name, Value: value})
+	}
+
+	return envVars, nil
+}
+
+// ExpandEnvVarReferences replaces each ${NAME} in a value with the value of the environment variable NAME, as
+// returned by lookup. Fails if a referenced variable isn't set
+func ExpandEnvVarReferences(value string, lookup func(string) (string, bool)) (string, error) {
+	var missingNames []string
+
+	expandedValue := envVarReferenceRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envVarReferenceRegex.FindStringSubmatch(reference)[1]
+
+		referencedValue, found := lookup(name)
+		if !found {
+			missingNames = append(missingNames, name)
+		}
+
+		return referencedValue
+	})
+
+	if len(missingNames) > 0 {
+		return "", errors.Errorf("Environment variables aren't set: %s", strings.Join(missingNames, ", "))
+	}
+
+	return expandedValue, nil
+}
+
+// SetEnvVar sets an environment variable, replacing the variable of the same name if there is one
+func SetEnvVar(envVars []v1.EnvVar, envVar v1.EnvVar) []v1.EnvVar {
+	for envVarIndex := range envVars {
+		if envVars[envVarIndex].Name == envVar.Name {
+			envVars[envVarIndex] = envVar
+			return envVars
+		}
+	}
+
+	return append(envVars, envVar)
+}
+
+func parseEnvFileValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch quote := value[0]; quote {
+	case '\'', '"':
+		closingQuoteIndex := findClosingQuote(value, quote)
+		if closingQuoteIndex == -1 {
+			return "", errors.New("Unterminated quoted value")
+		}
+
+		if rest := strings.TrimSpace(value[closingQuoteIndex+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", errors.Errorf("Unexpected characters after the quoted value: %s", rest)
+		}
+
+		quotedValue := value[1:closingQuoteIndex]
+		if quote == '\'' {
+			return quotedValue, nil
+		}
+
+		return unescapeDoubleQuotedValue(quotedValue), nil
+	default:
+
+		// an unquoted value ends at a comment
+		if commentIndex := strings.Index(value, " #"); commentIndex != -1 {
+			value = value[:commentIndex]
+		}
+
+		return strings.TrimSpace(value), nil
+	}
+}
+
+func findClosingQuote(value string, quote byte) int {
+	for charIndex := 1; charIndex < len(value); charIndex++ {
+		switch value[charIndex] {
+		case '\\':
+			if quote == '"' {
+				charIndex++
+			}
+		case quote:
+			return charIndex
+		}
+	}
+
+	return -1
+}
+
+func unescapeDoubleQuotedValue(value string) string {
+	var unescapedValue strings.Builder
+
+	for charIndex := 0; charIndex < len(value); charIndex++ {
+		if value[charIndex] != '\\' || charIndex == len(value)-1 {
+			unescapedValue.WriteByte(value[charIndex])
+			continue
+		}
+
+		charIndex++
+		switch value[charIndex] {
+		case 'n':
+			unescapedValue.WriteByte('\n')
+		case 't':
+			unescapedValue.WriteByte('\t')
+		case '"', '\\':
+			unescapedValue.WriteByte(value[charIndex])
+		default:
+			unescapedValue.WriteByte('\\')
+			unescapedValue.WriteByte(value[charIndex])
+		}
+	}
+
+	return unescapedValue.String()
+}
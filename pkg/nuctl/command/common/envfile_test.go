@@ -0,0 +1,99 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type envFileTestSuite struct {
+	suite.Suite
+}
+
+func (suite *envFileTestSuite) TestParseEnvFile() {
+	envVars, err := ParseEnvFile([]byte(`
+# database
+DB_HOST=postgres.local
+export DB_PORT = 5432
+DB_PASSWORD='pa$$ "word" #1'
+GREETING="hello\n\"world\"" # a comment
+LOG_LEVEL=debug # a comment
+EMPTY=
+URL=http://host/#anchor
+`))
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "DB_HOST", Value: "postgres.local"},
+		{Name: "DB_PORT", Value: "5432"},
+		{Name: "DB_PASSWORD", Value: `pa$$ "word" #1`},
+		{Name: "GREETING", Value: "hello\n\"world\""},
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "EMPTY", Value: ""},
+		{Name: "URL", Value: "http://host/#anchor"},
+	}, envVars)
+}
+
+func (suite *envFileTestSuite) TestParseEnvFileInvalid() {
+	for _, body := range []string{
+		"NO_VALUE",
+		"1ST=value",
+		"QUOTED=\"unterminated",
+		"QUOTED='value' trailing",
+	} {
+		_, err := ParseEnvFile([]byte(body))
+		suite.Require().Error(err, body)
+	}
+}
+
+func (suite *envFileTestSuite) TestReadEnvFile() {
+	tempDir, err := ioutil.TempDir("", "envfile-test")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	envFilePath := path.Join(tempDir, ".env")
+	suite.Require().NoError(ioutil.WriteFile(envFilePath, []byte("A=1\nB=2\n"), 0600))
+
+	envVars, err := ReadEnvFile(envFilePath)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}, envVars)
+
+	_, err = ReadEnvFile(path.Join(tempDir, "missing.env"))
+	suite.Require().Error(err)
+}
+
+func (suite *envFileTestSuite) TestExpandEnvVarReferences() {
+	lookup := func(name string) (string, bool) {
+		value, found := map[string]string{"USER": "admin", "EMPTY": ""}[name]
+		return value, found
+	}
+
+	expandedValue, err := ExpandEnvVarReferences("${USER}@host/${EMPTY}db?x=$USER", lookup)
+	suite.Require().NoError(err)
+	suite.Require().Equal("admin@host/db?x=$USER", expandedValue)
+
+	_, err = ExpandEnvVarReferences("${USER}:${PASSWORD}", lookup)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "PASSWORD")
+}
+
+func (suite *envFileTestSuite) TestSetEnvVar() {
+	envVars := []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}
+
+	envVars = SetEnvVar(envVars, v1.EnvVar{Name: "A", Value: "3"})
+	envVars = SetEnvVar(envVars, v1.EnvVar{Name: "C", Value: "4"})
+
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "A", Value: "3"},
+		{Name: "B", Value: "2"},
+		{Name: "C", Value: "4"},
+	}, envVars)
+}
+
+func TestEnvFileTestSuite(t *testing.T) {
+	suite.Run(t, new(envFileTestSuite))
+}
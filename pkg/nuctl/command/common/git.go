@@ -0,0 +1,176 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// GitSource is a function path pointing at a remote git repository, in the form of
+// <repository-url>[#<ref>[:<subdir>]]
+type GitSource struct {
+	URL    string
+	Ref    string
+	Subdir string
+}
+
+// ParseGitSource returns a git source if the given function path refers to a remote git repository
+func ParseGitSource(functionPath string) (*GitSource, bool) {
+	repositoryURL := functionPath
+	fragment := ""
+
+	if fragmentIndex := strings.Index(functionPath, "#"); fragmentIndex != -1 {
+		repositoryURL = functionPath[:fragmentIndex]
+		fragment = functionPath[fragmentIndex+1:]
+	}
+
+	if !isGitRepositoryURL(repositoryURL) {
+		return nil, false
+	}
+
+	gitSource := &GitSource{
+		URL: repositoryURL,
+	}
+
+	// fragment is ref[:subdir]
+	refAndSubdir := strings.SplitN(fragment, ":", 2)
+	gitSource.Ref = refAndSubdir[0]
+	if len(refAndSubdir) == 2 {
+		gitSource.Subdir = strings.Trim(refAndSubdir[1], "/")
+	}
+
+	return gitSource, true
+}
+
+// CloneGitSource clones the git source into a temporary directory and returns the path from which the
+// function should be built, along with the directory to remove once the build is done
+func CloneGitSource(parentLogger logger.Logger,
+	cmdRunner cmdrunner.CmdRunner,
+	gitSource *GitSource,
+	token string) (string, string, error) {
+
+	// git would read these as options
+	if strings.HasPrefix(gitSource.URL, "-") {
+		return "", "", errors.Errorf("Invalid git repository URL: %s", gitSource.URL)
+	}
+
+	if strings.HasPrefix(gitSource.Ref, "-") {
+		return "", "", errors.Errorf("Invalid git ref: %s", gitSource.Ref)
+	}
+
+	cloneDir, err := ioutil.TempDir("", "nuctl-git-")
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed to create temporary directory for git clone")
+	}
+
+	repositoryURL, err := gitSource.authenticatedURL(token)
+	if err != nil {
+		os.RemoveAll(cloneDir) // nolint: errcheck
+		return "", "", errors.Wrap(err, "Failed to resolve git repository URL")
+	}
+
+	runOptions := &cmdrunner.RunOptions{
+		LogRedactions: []string{},
+		Env: map[string]string{
+
+			// never block on a credentials prompt
+			"GIT_TERMINAL_PROMPT": "0",
+			"PATH":                os.Getenv("PATH"),
+			"HOME":                os.Getenv("HOME"),
+		},
+	}
+	if token != "" {
+		runOptions.LogRedactions = append(runOptions.LogRedactions, token)
+	}
+
+	parentLogger.InfoWith("Cloning git repository", "url", gitSource.URL, "ref", gitSource.Ref)
+
+	if _, err := cmdRunner.Run(runOptions,
+		"git clone --quiet -- %s %s",
+		cmdrunner.Quote(repositoryURL),
+		cmdrunner.Quote(cloneDir)); err != nil {
+		os.RemoveAll(cloneDir) // nolint: errcheck
+		return "", "", errors.Wrapf(err, "Failed to clone git repository %s", gitSource.URL)
+	}
+
+	if gitSource.Ref != "" {
+		runOptions.WorkingDir = &cloneDir
+		if _, err := cmdRunner.Run(runOptions,
+			"git checkout --quiet %s --",
+			cmdrunner.Quote(gitSource.Ref)); err != nil {
+			os.RemoveAll(cloneDir) // nolint: errcheck
+			return "", "", errors.Wrapf(err, "Failed to check out git ref %s", gitSource.Ref)
+		}
+	}
+
+	functionPath, err := getGitSubdirPath(cloneDir, gitSource.Subdir)
+	if err != nil {
+		os.RemoveAll(cloneDir) // nolint: errcheck
+		return "", "", err
+	}
+
+	if _, err := os.Stat(functionPath); err != nil {
+		os.RemoveAll(cloneDir) // nolint: errcheck
+		return "", "", errors.Wrapf(err, "Subdirectory %s does not exist in git repository", gitSource.Subdir)
+	}
+
+	return functionPath, cloneDir, nil
+}
+
+// getGitSubdirPath returns the path of the subdirectory in the cloned repository, making sure it doesn't escape
+// the repository (e.g. "../other" or a sibling directory sharing the clone directory's prefix)
+func getGitSubdirPath(cloneDir string, subdir string) (string, error) {
+	subdirPath := filepath.Join(cloneDir, filepath.FromSlash(subdir))
+
+	relativePath, err := filepath.Rel(cloneDir, subdirPath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(os.PathSeparator)) {
+		return "", errors.Errorf("Invalid subdirectory in git path: %s", subdir)
+	}
+
+	return subdirPath, nil
+}
+
+func (gs *GitSource) authenticatedURL(token string) (string, error) {
+	if token == "" || !strings.HasPrefix(gs.URL, "https://") {
+		return gs.URL, nil
+	}
+
+	parsedURL, err := url.Parse(gs.URL)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse git repository URL")
+	}
+
+	parsedURL.User = url.UserPassword("x-access-token", token)
+
+	return parsedURL.String(), nil
+}
+
+func isGitRepositoryURL(repositoryURL string) bool {
+
+	// scp-like syntax (git@github.com:org/repo.git)
+	if strings.HasPrefix(repositoryURL, "git@") {
+		return true
+	}
+
+	for _, scheme := range []string{"git://", "ssh://"} {
+		if strings.HasPrefix(repositoryURL, scheme) {
+			return true
+		}
+	}
+
+	// http(s) and local file URLs must point at a .git repository, to not collide with archive URLs
+	for _, scheme := range []string{"https://", "http://", "file://"} {
+		if strings.HasPrefix(repositoryURL, scheme) {
+			return strings.HasSuffix(strings.TrimRight(repositoryURL, "/"), ".git")
+		}
+	}
+
+	return false
+}
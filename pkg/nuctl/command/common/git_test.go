@@ -0,0 +1,194 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type gitTestSuite struct {
+	suite.Suite
+	logger    logger.Logger
+	cmdRunner cmdrunner.CmdRunner
+	tempDir   string
+}
+
+func (suite *gitTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+	suite.cmdRunner, err = cmdrunner.NewShellRunner(suite.logger)
+	suite.Require().NoError(err)
+
+	suite.tempDir, err = ioutil.TempDir("", "git-test-")
+	suite.Require().NoError(err)
+}
+
+func (suite *gitTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *gitTestSuite) TestParseGitSource() {
+	for _, testCase := range []struct {
+		functionPath string
+		isGitSource  bool
+		expected     GitSource
+	}{
+		{
+			functionPath: "https://github.com/org/repo.git#branch:sub/dir",
+			isGitSource:  true,
+			expected:     GitSource{URL: "https://github.com/org/repo.git", Ref: "branch", Subdir: "sub/dir"},
+		},
+		{
+			functionPath: "git@github.com:org/repo.git#v1.0.0",
+			isGitSource:  true,
+			expected:     GitSource{URL: "git@github.com:org/repo.git", Ref: "v1.0.0"},
+		},
+		{
+			functionPath: "file:///tmp/repo.git",
+			isGitSource:  true,
+			expected:     GitSource{URL: "file:///tmp/repo.git"},
+		},
+		{functionPath: "https://github.com/org/repo/archive/master.zip"},
+		{functionPath: "/local/path/to/function"},
+	} {
+		gitSource, isGitSource := ParseGitSource(testCase.functionPath)
+		suite.Require().Equal(testCase.isGitSource, isGitSource, testCase.functionPath)
+		if isGitSource {
+			suite.Require().Equal(testCase.expected, *gitSource)
+		}
+	}
+}
+
+func (suite *gitTestSuite) TestCloneGitSource() {
+	repositoryDir := suite.createBareRepository()
+
+	gitSource, isGitSource := ParseGitSource("file://" + repositoryDir + "#feature:functions/echo")
+	suite.Require().True(isGitSource)
+
+	functionPath, cloneDir, err := CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(cloneDir) // nolint: errcheck
+
+	suite.Require().Equal(filepath.Join(cloneDir, "functions", "echo"), functionPath)
+
+	// file only exists on the feature branch
+	handler, err := ioutil.ReadFile(filepath.Join(functionPath, "echo.py"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("def handler(context, event):\n    return event.body\n", string(handler))
+}
+
+func (suite *gitTestSuite) TestCloneGitSourceFailures() {
+	repositoryDir := suite.createBareRepository()
+
+	// repository doesn't exist
+	gitSource, _ := ParseGitSource("file://" + filepath.Join(suite.tempDir, "missing.git"))
+	_, _, err := CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to clone git repository")
+
+	// ref doesn't exist
+	gitSource, _ = ParseGitSource("file://" + repositoryDir + "#no-such-branch")
+	_, _, err = CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to check out git ref")
+
+	// subdirectory doesn't exist
+	gitSource, _ = ParseGitSource("file://" + repositoryDir + "#feature:no/such/dir")
+	_, _, err = CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+
+	// ref would be read as an option
+	gitSource, _ = ParseGitSource("file://" + repositoryDir + "#--upload-pack=touch")
+	_, _, err = CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Invalid git ref")
+
+	// quotes in the ref are passed to git as is, rather than to the shell
+	injectedPath := filepath.Join(suite.tempDir, "injected")
+	gitSource, _ = ParseGitSource("file://" + repositoryDir + "#feature'; touch " + injectedPath + "; echo '")
+	_, _, err = CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to check out git ref")
+	suite.Require().NoFileExists(injectedPath)
+
+	// subdirectory escapes the repository
+	gitSource, _ = ParseGitSource("file://" + repositoryDir + "#feature:../escaped")
+	_, _, err = CloneGitSource(suite.logger, suite.cmdRunner, gitSource, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Invalid subdirectory in git path")
+}
+
+func (suite *gitTestSuite) TestGetGitSubdirPath() {
+	cloneDir := filepath.Join(suite.tempDir, "nuctl-git-1")
+
+	for _, testCase := range []struct {
+		subdir       string
+		expectedPath string
+	}{
+		{subdir: "", expectedPath: cloneDir},
+		{subdir: "functions/echo", expectedPath: filepath.Join(cloneDir, "functions", "echo")},
+		{subdir: "functions/../echo", expectedPath: filepath.Join(cloneDir, "echo")},
+		{subdir: "..dir", expectedPath: filepath.Join(cloneDir, "..dir")},
+
+		// escape the repository
+		{subdir: ".."},
+		{subdir: "../other"},
+		{subdir: "functions/../../other"},
+
+		// a sibling sharing the clone directory's prefix
+		{subdir: "../nuctl-git-12/functions"},
+	} {
+		subdirPath, err := getGitSubdirPath(cloneDir, testCase.subdir)
+		if testCase.expectedPath == "" {
+			suite.Require().Error(err, testCase.subdir)
+			continue
+		}
+
+		suite.Require().NoError(err, testCase.subdir)
+		suite.Require().Equal(testCase.expectedPath, subdirPath)
+	}
+}
+
+func (suite *gitTestSuite) createBareRepository() string {
+	repositoryDir := filepath.Join(suite.tempDir, "repo.git")
+	workDir := filepath.Join(suite.tempDir, "work")
+
+	functionDir := filepath.Join(workDir, "functions", "echo")
+	suite.Require().NoError(os.MkdirAll(functionDir, 0755))
+
+	for _, command := range []string{
+		"git init --quiet --bare " + repositoryDir,
+		"git init --quiet " + workDir,
+		"git -C " + workDir + " commit --quiet --allow-empty -m initial",
+		"git -C " + workDir + " checkout --quiet -b feature",
+		"printf 'def handler(context, event):\\n    return event.body\\n' > " + filepath.Join(functionDir, "echo.py"),
+		"git -C " + workDir + " add -A",
+		"git -C " + workDir + " commit --quiet -m echo",
+		"git -C " + workDir + " push --quiet --all " + repositoryDir,
+	} {
+		_, err := suite.cmdRunner.Run(&cmdrunner.RunOptions{
+			Env: map[string]string{
+				"PATH":                os.Getenv("PATH"),
+				"GIT_AUTHOR_NAME":     "test",
+				"GIT_AUTHOR_EMAIL":    "test@nuclio.io",
+				"GIT_COMMITTER_NAME":  "test",
+				"GIT_COMMITTER_EMAIL": "test@nuclio.io",
+			},
+		}, command)
+		suite.Require().NoError(err)
+	}
+
+	return repositoryDir
+}
+
+func TestGitTestSuite(t *testing.T) {
+	suite.Run(t, new(gitTestSuite))
+}
@@ -1,12 +1,17 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
+	"text/template"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
 	"github.com/ghodss/yaml"
@@ -37,6 +42,122 @@ func FormatFunctionIngresses(function platform.Function) string {
 	return formattedIngresses
 }
 
+// FilterFunctionsByGroup returns the functions that were deployed as part of the given group
+func FilterFunctionsByGroup(functions []platform.Function, group string) []platform.Function {
+	var groupFunctions []platform.Function
+
+	for _, function := range functions {
+		if function.GetConfig().Meta.Annotations[functionconfig.FunctionAnnotationGroup] == group {
+			groupFunctions = append(groupFunctions, function)
+		}
+	}
+
+	return groupFunctions
+}
+
+// FilterFunctionsByLabels returns the functions that have all the given labels
+func FilterFunctionsByLabels(functions []platform.Function, labels map[string]string) []platform.Function {
+	var labeledFunctions []platform.Function
+
+	for _, function := range functions {
+		if functionHasLabels(function, labels) {
+			labeledFunctions = append(labeledFunctions, function)
+		}
+	}
+
+	return labeledFunctions
+}
+
+// FormatLabelSelector formats labels as a selector of the form lbl1=val1[,lbl2=val2,...], sorted by name
+func FormatLabelSelector(labels map[string]string) string {
+	var selector []string
+
+	for labelName, labelValue := range labels {
+		selector = append(selector, fmt.Sprintf("%s=%s", labelName, labelValue))
+	}
+
+	sort.Strings(selector)
+
+	return strings.Join(selector, ",")
+}
+
+func functionHasLabels(function platform.Function, labels map[string]string) bool {
+	functionLabels := function.GetConfig().Meta.Labels
+
+	for labelName, labelValue := range labels {
+		if functionLabels[labelName] != labelValue {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the fields functions can be sorted by
+const (
+	FunctionSortByName    = "name"
+	FunctionSortByState   = "state"
+	FunctionSortByCreated = "created"
+)
+
+// ValidateFunctionSortBy returns an error if functions can't be sorted by the given field
+func ValidateFunctionSortBy(sortBy string) error {
+	switch sortBy {
+	case FunctionSortByName, FunctionSortByState, FunctionSortByCreated:
+		return nil
+	}
+
+	return errors.Errorf("Functions can be sorted by %s, %s or %s, not %s",
+		FunctionSortByName,
+		FunctionSortByState,
+		FunctionSortByCreated,
+		sortBy)
+}
+
+// SortFunctions sorts the functions by the given field (ascending, unless reversed). ties are broken by name,
+// so the order is deterministic. functions are created when they're built, so that's what "created" sorts by
+func SortFunctions(functions []platform.Function, sortBy string, reverse bool) error {
+	var less func(first platform.Function, second platform.Function) bool
+
+	switch sortBy {
+	case FunctionSortByName:
+
+		// left to the tie break
+		less = func(first platform.Function, second platform.Function) bool {
+			return false
+		}
+	case FunctionSortByState:
+		less = func(first platform.Function, second platform.Function) bool {
+			return first.GetStatus().State < second.GetStatus().State
+		}
+	case FunctionSortByCreated:
+		less = func(first platform.Function, second platform.Function) bool {
+			return first.GetConfig().Spec.Build.Timestamp < second.GetConfig().Spec.Build.Timestamp
+		}
+	default:
+		return ValidateFunctionSortBy(sortBy)
+	}
+
+	sort.SliceStable(functions, func(firstIdx, secondIdx int) bool {
+		first, second := functions[firstIdx], functions[secondIdx]
+		if reverse {
+			first, second = second, first
+		}
+
+		if less(first, second) {
+			return true
+		}
+
+		if less(second, first) {
+			return false
+		}
+
+		return first.GetConfig().Meta.Name < second.GetConfig().Meta.Name
+	})
+
+	return nil
+}
+
 func ReadFromInOrStdin(r io.Reader) ([]byte, error) {
 	switch in := r.(type) {
 	case *os.File:
@@ -88,3 +209,26 @@ func GetUnmarshalFunc(bytes []byte) (func(data []byte, v interface{}) error, err
 
 	return nil, errors.New("Input is neither json nor yaml")
 }
+
+// RenderFunctionConfig substitutes the values in a function config file that is a Go template (e.g. {{ .key }}).
+// variables without a value fail the rendering, unless missing values are allowed - rendering them empty
+func RenderFunctionConfig(functionConfigBody []byte, values map[string]string, allowMissing bool) ([]byte, error) {
+	missingKeyOption := "missingkey=error"
+	if allowMissing {
+		missingKeyOption = "missingkey=zero"
+	}
+
+	functionConfigTemplate, err := template.New("functionConfig").
+		Option(missingKeyOption).
+		Parse(string(functionConfigBody))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse function config template")
+	}
+
+	var renderedFunctionConfig bytes.Buffer
+	if err := functionConfigTemplate.Execute(&renderedFunctionConfig, values); err != nil {
+		return nil, errors.Wrap(err, "Failed to render function config template")
+	}
+
+	return renderedFunctionConfig.Bytes(), nil
+}
@@ -0,0 +1,321 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+)
+
+// FunctionConfigOverrides are fields of function configs to override, in the form of a partial function config
+// (e.g. {"spec": {"build": {"registry": "my-registry"}}}). Environment variables are overridden by name, rather
+// than as a whole. Strings are Go templates of the function's Name, Namespace and Project, and may read
+// environment variables with {{ env "NAME" }}
+type FunctionConfigOverrides map[string]interface{}
+
+// a value given with --set, which is converted to the type of the value it overrides
+type setValue string
+
+// NewFunctionConfigOverrides creates overrides from a values file (a partial function config, in YAML) and
+// values given as path=value (e.g. spec.build.registry=my-registry, spec.env.LOG_LEVEL=debug), which are
+// applied after the values file
+func NewFunctionConfigOverrides(valuesFileBody []byte, values []string) (FunctionConfigOverrides, error) {
+	overrides := FunctionConfigOverrides{}
+
+	if len(valuesFileBody) > 0 {
+		if err := yaml.Unmarshal(valuesFileBody, &overrides); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse values file")
+		}
+	}
+
+	for _, value := range values {
+		pathAndValue := strings.SplitN(value, "=", 2)
+		if len(pathAndValue) != 2 || pathAndValue[0] == "" {
+			return nil, errors.Errorf("Value %s not in the format of path=value", value)
+		}
+
+		if err := overrides.set(strings.Split(pathAndValue[0], "."), pathAndValue[1]); err != nil {
+			return nil, errors.Wrapf(err, "Failed to set %s", pathAndValue[0])
+		}
+	}
+
+	return overrides, nil
+}
+
+// Apply overrides the fields of the function config, failing if a field doesn't exist
+func (o FunctionConfigOverrides) Apply(functionConfig *functionconfig.Config) error {
+	if len(o) == 0 {
+		return nil
+	}
+
+	renderedOverrides, err := o.render(map[string]string{
+		"Name":      functionConfig.Meta.Name,
+		"Namespace": functionConfig.Meta.Namespace,
+		"Project":   functionConfig.Meta.Labels["nuclio.io/project-name"],
+	})
+	if err != nil {
+		return err
+	}
+
+	encodedFunctionConfig, err := json.Marshal(functionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode function config")
+	}
+
+	functionConfigFields := map[string]interface{}{}
+	if err := json.Unmarshal(encodedFunctionConfig, &functionConfigFields); err != nil {
+		return errors.Wrap(err, "Failed to decode function config")
+	}
+
+	if err := mergeOverrides(functionConfigFields, renderedOverrides.(map[string]interface{}), ""); err != nil {
+		return err
+	}
+
+	encodedFunctionConfig, err = json.Marshal(functionConfigFields)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode overridden function config")
+	}
+
+	// fields which don't exist are most likely typos, so fail rather than ignore them
+	overriddenFunctionConfig := functionconfig.Config{}
+	decoder := json.NewDecoder(bytes.NewReader(encodedFunctionConfig))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&overriddenFunctionConfig); err != nil {
+		return errors.Wrap(err, "Failed to override function config")
+	}
+
+	*functionConfig = overriddenFunctionConfig
+
+	return nil
+}
+
+func (o FunctionConfigOverrides) set(path []string, value string) error {
+	fields := map[string]interface{}(o)
+
+	// environment variables are a list, so they're set by name - spec.env.NAME=value
+	if len(path) == 3 && path[0] == "spec" && path[1] == "env" {
+		spec, err := getOrCreateFields(fields, "spec")
+		if err != nil {
+			return err
+		}
+
+		env, _ := spec["env"].([]interface{})
+		spec["env"] = append(env, map[string]interface{}{"name": path[2], "value": value})
+
+		return nil
+	}
+
+	for _, fieldName := range path[:len(path)-1] {
+		var err error
+
+		if fields, err = getOrCreateFields(fields, fieldName); err != nil {
+			return err
+		}
+	}
+
+	fields[path[len(path)-1]] = setValue(value)
+
+	return nil
+}
+
+// render renders the strings of the overrides as templates of the given data
+func (o FunctionConfigOverrides) render(data map[string]string) (interface{}, error) {
+	var renderValue func(value interface{}) (interface{}, error)
+
+	renderString := func(value string) (string, error) {
+		valueTemplate, err := template.New("value").
+			Option("missingkey=error").
+			Funcs(template.FuncMap{"env": os.Getenv}).
+			Parse(value)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to parse template %s", value)
+		}
+
+		var renderedValue bytes.Buffer
+		if err := valueTemplate.Execute(&renderedValue, data); err != nil {
+			return "", errors.Wrapf(err, "Failed to render template %s", value)
+		}
+
+		return renderedValue.String(), nil
+	}
+
+	renderValue = func(value interface{}) (interface{}, error) {
+		switch typedValue := value.(type) {
+		case string:
+			return renderString(typedValue)
+
+		case setValue:
+			renderedValue, err := renderString(string(typedValue))
+			return setValue(renderedValue), err
+
+		case map[string]interface{}:
+			renderedFields := map[string]interface{}{}
+			for fieldName, fieldValue := range typedValue {
+				renderedFieldValue, err := renderValue(fieldValue)
+				if err != nil {
+					return nil, err
+				}
+
+				renderedFields[fieldName] = renderedFieldValue
+			}
+
+			return renderedFields, nil
+
+		case []interface{}:
+			var renderedItems []interface{}
+			for _, item := range typedValue {
+				renderedItem, err := renderValue(item)
+				if err != nil {
+					return nil, err
+				}
+
+				renderedItems = append(renderedItems, renderedItem)
+			}
+
+			return renderedItems, nil
+
+		default:
+			return value, nil
+		}
+	}
+
+	return renderValue(map[string]interface{}(o))
+}
+
+func getOrCreateFields(fields map[string]interface{}, fieldName string) (map[string]interface{}, error) {
+	if fields[fieldName] == nil {
+		fields[fieldName] = map[string]interface{}{}
+	}
+
+	childFields, isMap := fields[fieldName].(map[string]interface{})
+	if !isMap {
+		return nil, errors.Errorf("%s is not an object", fieldName)
+	}
+
+	return childFields, nil
+}
+
+func mergeOverrides(fields map[string]interface{}, overrides map[string]interface{}, path string) error {
+	for fieldName, overrideValue := range overrides {
+		fieldPath := strings.TrimPrefix(path+"."+fieldName, ".")
+
+		switch typedOverrideValue := overrideValue.(type) {
+		case map[string]interface{}:
+			if fields[fieldName] == nil {
+				fields[fieldName] = map[string]interface{}{}
+			}
+
+			childFields, isMap := fields[fieldName].(map[string]interface{})
+			if !isMap {
+				return errors.Errorf("Can't override %s with an object", fieldPath)
+			}
+
+			if err := mergeOverrides(childFields, typedOverrideValue, fieldPath); err != nil {
+				return err
+			}
+
+		case []interface{}:
+			if fieldPath == "spec.env" {
+				env, _ := fields[fieldName].([]interface{})
+				fields[fieldName] = mergeEnv(env, typedOverrideValue)
+			} else {
+				fields[fieldName] = typedOverrideValue
+			}
+
+		case setValue:
+
+			// values given as strings are converted to the type of the field they override (e.g. spec.replicas=3)
+			fieldKind := getFieldKind(reflect.TypeOf(functionconfig.Config{}), strings.Split(fieldPath, "."))
+			if fieldKind == reflect.String || fieldKind == reflect.Invalid {
+				fields[fieldName] = string(typedOverrideValue)
+				break
+			}
+
+			var convertedValue interface{}
+			if err := yaml.Unmarshal([]byte(typedOverrideValue), &convertedValue); err != nil {
+				return errors.Wrapf(err, "Failed to convert the value of %s", fieldPath)
+			}
+
+			fields[fieldName] = convertedValue
+
+		default:
+			fields[fieldName] = overrideValue
+		}
+	}
+
+	return nil
+}
+
+// getFieldKind returns the kind of the field at the given JSON path of the type, or reflect.Invalid if there's none
+func getFieldKind(fieldType reflect.Type, path []string) reflect.Kind {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	if len(path) == 0 {
+		return fieldType.Kind()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Map:
+		return getFieldKind(fieldType.Elem(), path[1:])
+
+	case reflect.Struct:
+		for fieldIndex := 0; fieldIndex < fieldType.NumField(); fieldIndex++ {
+			field := fieldType.Field(fieldIndex)
+			fieldName := strings.Split(field.Tag.Get("json"), ",")[0]
+
+			// the fields of embedded structs are encoded as fields of the struct embedding them
+			if field.Anonymous && fieldName == "" {
+				if fieldKind := getFieldKind(field.Type, path); fieldKind != reflect.Invalid {
+					return fieldKind
+				}
+
+				continue
+			}
+
+			if fieldName == "" {
+				fieldName = field.Name
+			}
+
+			if fieldName == path[0] {
+				return getFieldKind(field.Type, path[1:])
+			}
+		}
+	}
+
+	return reflect.Invalid
+}
+
+// mergeEnv overrides the environment variables by name, adding those which don't exist
+func mergeEnv(env []interface{}, overrideEnv []interface{}) []interface{} {
+	for _, overrideEnvVar := range overrideEnv {
+		overrideEnvVarFields, _ := overrideEnvVar.(map[string]interface{})
+
+		overridden := false
+		for envVarIndex, envVar := range env {
+			envVarFields, _ := envVar.(map[string]interface{})
+
+			if envVarFields != nil && overrideEnvVarFields != nil &&
+				envVarFields["name"] == overrideEnvVarFields["name"] {
+				env[envVarIndex] = overrideEnvVar
+				overridden = true
+				break
+			}
+		}
+
+		if !overridden {
+			env = append(env, overrideEnvVar)
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type overridesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *overridesTestSuite) TestApply() {
+	os.Setenv("NUCTL_TEST_KAFKA_URL", "kafka.prod:9092") // nolint: errcheck
+	defer os.Unsetenv("NUCTL_TEST_KAFKA_URL")            // nolint: errcheck
+
+	overrides, err := NewFunctionConfigOverrides([]byte(`
+spec:
+  build:
+    registry: prod-registry
+  resources:
+    limits:
+      cpu: 2
+  env:
+  - name: ENVIRONMENT
+    value: production
+  - name: SERVICE_URL
+    value: http://{{ .Name }}.{{ .Namespace }}.svc
+  triggers:
+    stream:
+      url: '{{ env "NUCTL_TEST_KAFKA_URL" }}'
+`), []string{
+		"spec.replicas=3",
+		"spec.env.LOG_LEVEL=info",
+		"spec.triggers.stream.attributes.consumerGroup={{ .Project }}-consumers",
+		"metadata.labels.version=2",
+	})
+	suite.Require().NoError(err)
+
+	functionConfig := suite.newFunctionConfig()
+
+	err = overrides.Apply(functionConfig)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("prod-registry", functionConfig.Spec.Build.Registry)
+	suite.Require().Equal("2", functionConfig.Spec.Resources.Limits.Cpu().String())
+	suite.Require().Equal(3, *functionConfig.Spec.Replicas)
+	suite.Require().Equal("2", functionConfig.Meta.Labels["version"])
+	suite.Require().Equal("my-project", functionConfig.Meta.Labels["nuclio.io/project-name"])
+
+	// environment variables are overridden by name, and added if they don't exist
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "ENVIRONMENT", Value: "production"},
+		{Name: "LOG_LEVEL", Value: "info"},
+		{Name: "SERVICE_URL", Value: "http://my-function.prod.svc"},
+	}, functionConfig.Spec.Env)
+
+	// the rest of the trigger is left as is
+	trigger := functionConfig.Spec.Triggers["stream"]
+	suite.Require().Equal("kafka.prod:9092", trigger.URL)
+	suite.Require().Equal("kafka-cluster", trigger.Kind)
+	suite.Require().Equal("my-project-consumers", trigger.Attributes["consumerGroup"])
+	suite.Require().Equal([]interface{}{"orders"}, trigger.Attributes["topics"])
+}
+
+func (suite *overridesTestSuite) TestInvalidOverrides() {
+	for _, testCase := range []struct {
+		name           string
+		valuesFileBody string
+		values         []string
+	}{
+		{name: "invalidFormat", values: []string{"spec.replicas"}},
+		{name: "unknownField", values: []string{"spec.build.registy=prod-registry"}},
+		{name: "notAnObject", values: []string{"spec.build.registry.url=prod-registry"}},
+		{name: "invalidType", values: []string{"spec.replicas=three"}},
+		{name: "missingTemplateValue", values: []string{"spec.description={{ .Owner }}"}},
+		{name: "invalidValuesFile", valuesFileBody: "spec: [registry"},
+	} {
+		suite.Run(testCase.name, func() {
+			overrides, err := NewFunctionConfigOverrides([]byte(testCase.valuesFileBody), testCase.values)
+			if err == nil {
+				err = overrides.Apply(suite.newFunctionConfig())
+			}
+
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func (suite *overridesTestSuite) newFunctionConfig() *functionconfig.Config {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Meta.Namespace = "prod"
+	functionConfig.Meta.Labels = map[string]string{"nuclio.io/project-name": "my-project"}
+	functionConfig.Spec.Build.Registry = "staging-registry"
+	functionConfig.Spec.Env = []v1.EnvVar{
+		{Name: "ENVIRONMENT", Value: "staging"},
+		{Name: "LOG_LEVEL", Value: "debug"},
+	}
+	functionConfig.Spec.Triggers = map[string]functionconfig.Trigger{
+		"stream": {
+			Kind: "kafka-cluster",
+			URL:  "kafka.staging:9092",
+			Attributes: map[string]interface{}{
+				"topics":        []interface{}{"orders"},
+				"consumerGroup": "staging-consumers",
+			},
+		},
+	}
+
+	return functionConfig
+}
+
+func TestOverridesTestSuite(t *testing.T) {
+	suite.Run(t, new(overridesTestSuite))
+}
@@ -1,16 +1,23 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/renderer"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"k8s.io/client-go/util/jsonpath"
 )
 
 const (
@@ -18,18 +25,127 @@ const (
 	OutputFormatWide = "wide"
 	OutputFormatJSON = "json"
 	OutputFormatYAML = "yaml"
+
+	// followed by a template (e.g. jsonpath={.name}), rendered for each function
+	OutputFormatJSONPathPrefix = "jsonpath="
 )
 
+// ValidateYAMLIndent validates the indentation width given for the output. 0 is the default indentation
+func ValidateYAMLIndent(format string, yamlIndent int) error {
+	if yamlIndent == 0 {
+		return nil
+	}
+
+	if format != OutputFormatYAML {
+		return errors.Errorf("--indent can only be used with \"%s\" output", OutputFormatYAML)
+	}
+
+	if yamlIndent < renderer.MinYAMLIndent || yamlIndent > renderer.MaxYAMLIndent {
+		return errors.Errorf("--indent must be between %d and %d", renderer.MinYAMLIndent, renderer.MaxYAMLIndent)
+	}
+
+	return nil
+}
+
 func RenderFunctions(logger logger.Logger,
 	functions []platform.Function,
 	format string,
+	yamlIndent int,
 	writer io.Writer,
 	renderCallback func(functions []platform.Function, renderer func(interface{}) error) error) error {
 
+	initializeFunctions(logger, functions)
+
+	if strings.HasPrefix(format, OutputFormatJSONPathPrefix) {
+		return renderFunctionsJSONPath(functions, strings.TrimPrefix(format, OutputFormatJSONPathPrefix), writer)
+	}
+
+	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		var functionRecords [][]string
+
+		// for each field
+		for _, function := range functions {
+			functionRecords = append(functionRecords, getFunctionRecord(function, format))
+		}
+
+		rendererInstance.RenderTable(getFunctionRecordHeader(format), functionRecords)
+	case OutputFormatYAML:
+		return renderCallback(functions, rendererInstance.RenderYAML)
+	case OutputFormatJSON:
+		return renderCallback(functions, rendererInstance.RenderJSON)
+	}
+
+	return nil
+}
+
+// ContextFunctions are the functions of a single kubeconfig context
+type ContextFunctions struct {
+	Context   string                   `json:"context"`
+	Functions []*functionconfig.Config `json:"functions"`
+}
+
+// RenderContextsFunctions renders the functions of several kubeconfig contexts, by the given order of contexts
+func RenderContextsFunctions(logger logger.Logger,
+	contexts []string,
+	functionsByContext map[string][]platform.Function,
+	format string,
+	yamlIndent int,
+	writer io.Writer) error {
+
+	for _, context := range contexts {
+		initializeFunctions(logger, functionsByContext[context])
+	}
+
+	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		var functionRecords [][]string
+
+		for _, context := range contexts {
+			for _, function := range functionsByContext[context] {
+				functionRecords = append(functionRecords,
+					append([]string{context}, getFunctionRecord(function, format)...))
+			}
+		}
+
+		rendererInstance.RenderTable(append([]string{"Context"}, getFunctionRecordHeader(format)...), functionRecords)
+	case OutputFormatYAML, OutputFormatJSON:
+		var contextsFunctions []ContextFunctions
+
+		for _, context := range contexts {
+			contextFunctions := ContextFunctions{
+				Context:   context,
+				Functions: []*functionconfig.Config{},
+			}
+
+			for _, function := range functionsByContext[context] {
+				contextFunctions.Functions = append(contextFunctions.Functions, function.GetConfig())
+			}
+
+			contextsFunctions = append(contextsFunctions, contextFunctions)
+		}
+
+		if format == OutputFormatYAML {
+			return rendererInstance.RenderYAML(contextsFunctions)
+		}
+
+		return rendererInstance.RenderJSON(contextsFunctions)
+	}
+
+	return nil
+}
+
+// initializeFunctions makes sure the functions are initialized, in parallel
+func initializeFunctions(logger logger.Logger, functions []platform.Function) {
 	waitGroup := sync.WaitGroup{}
 	waitGroup.Add(len(functions))
 
-	// iterate over each function and make sure it's initialized
 	for _, function := range functions {
 		go func(function platform.Function) {
 			if err := function.Initialize(nil); err != nil {
@@ -39,57 +155,436 @@ func RenderFunctions(logger logger.Logger,
 		}(function)
 	}
 	waitGroup.Wait()
+}
+
+// ValidateFunctionOutputFormat validates the format functions are rendered in, parsing its template if it has one
+func ValidateFunctionOutputFormat(format string) error {
+	switch format {
+	case OutputFormatText, OutputFormatWide, OutputFormatYAML, OutputFormatJSON:
+		return nil
+	}
+
+	if !strings.HasPrefix(format, OutputFormatJSONPathPrefix) {
+		return errors.Errorf("Unsupported output format: %s", format)
+	}
+
+	if _, err := parseJSONPathTemplate(strings.TrimPrefix(format, OutputFormatJSONPathPrefix)); err != nil {
+		return errors.Wrap(err, "Failed to parse jsonpath template")
+	}
 
+	return nil
+}
+
+// FunctionSummary holds what's rendered for a function in the wide view, and what jsonpath templates select from
+// (along with its configuration and status)
+type FunctionSummary struct {
+	Namespace         string     `json:"namespace"`
+	Name              string     `json:"name"`
+	Project           string     `json:"project"`
+	State             string     `json:"state"`
+	NodePort          int        `json:"nodePort,omitempty"`
+	AvailableReplicas int        `json:"availableReplicas"`
+	DesiredReplicas   int        `json:"desiredReplicas"`
+	Ready             bool       `json:"ready"`
+	ImageDigest       string     `json:"imageDigest,omitempty"`
+	LastDeployed      *time.Time `json:"lastDeployed,omitempty"`
+	InternalURL       string     `json:"internalURL,omitempty"`
+	ExternalURL       string     `json:"externalURL,omitempty"`
+
+	Config *functionconfig.Config `json:"config"`
+	Status *functionconfig.Status `json:"status"`
+}
+
+// GetFunctionSummary summarizes the function. URLs the platform can't tell are left empty
+func GetFunctionSummary(function platform.Function) *FunctionSummary {
+	availableReplicas, desiredReplicas := function.GetReplicas()
+
+	functionSummary := &FunctionSummary{
+		Namespace:         function.GetConfig().Meta.Namespace,
+		Name:              function.GetConfig().Meta.Name,
+		Project:           function.GetConfig().Meta.Labels["nuclio.io/project-name"],
+		State:             string(function.GetStatus().State),
+		NodePort:          function.GetStatus().HTTPPort,
+		AvailableReplicas: availableReplicas,
+		DesiredReplicas:   desiredReplicas,
+		LastDeployed:      function.GetStatus().LastDeployed,
+		Config:            function.GetConfig(),
+		Status:            function.GetStatus(),
+	}
+
+	// a function is ready once all of its replicas are
+	functionSummary.Ready = function.GetStatus().State == functionconfig.FunctionStateReady &&
+		availableReplicas >= desiredReplicas
+
+	if function.GetStatus().Image != nil {
+		functionSummary.ImageDigest = function.GetStatus().Image.Digest
+	}
+
+	if invokeURL, err := function.GetInvokeURL(platform.InvokeViaDomainName); err == nil && invokeURL != "" {
+		functionSummary.InternalURL = "http://" + invokeURL
+	}
+
+	// without a node port, functions aren't reachable from outside the cluster
+	if functionSummary.NodePort != 0 {
+		if invokeURL, err := function.GetInvokeURL(platform.InvokeViaExternalIP); err == nil && invokeURL != "" {
+			functionSummary.ExternalURL = "http://" + invokeURL
+		}
+	}
+
+	return functionSummary
+}
+
+func getFunctionRecordHeader(format string) []string {
+	header := []string{"Namespace", "Name", "Project", "State", "Node Port", "Replicas"}
+	if format == OutputFormatWide {
+		header = append(header, []string{
+			"Ready",
+			"Image Digest",
+			"Last Deployed",
+			"Internal URL",
+			"External URL",
+			"Labels",
+			"Ingresses",
+		}...)
+	}
+
+	return header
+}
+
+func getFunctionRecord(function platform.Function, format string) []string {
+	functionSummary := GetFunctionSummary(function)
+
+	functionName := functionSummary.Name
+	if functionconfig.GetDeprecation(function.GetConfig().Meta.Annotations) != nil {
+		functionName += " (deprecated)"
+	}
+
+	// get its fields
+	functionFields := []string{
+		functionSummary.Namespace,
+		functionName,
+		functionSummary.Project,
+		functionSummary.State,
+		strconv.Itoa(functionSummary.NodePort),
+		fmt.Sprintf("%d/%d", functionSummary.AvailableReplicas, functionSummary.DesiredReplicas),
+	}
+
+	// add fields for wide view
+	if format == OutputFormatWide {
+		lastDeployed := ""
+		if functionSummary.LastDeployed != nil {
+			lastDeployed = functionSummary.LastDeployed.Format(time.RFC3339)
+		}
+
+		functionFields = append(functionFields, []string{
+			strconv.FormatBool(functionSummary.Ready),
+			functionSummary.ImageDigest,
+			lastDeployed,
+			functionSummary.InternalURL,
+			functionSummary.ExternalURL,
+			common.StringMapToString(function.GetConfig().Meta.Labels),
+			FormatFunctionIngresses(function),
+		}...)
+	}
+
+	return functionFields
+}
+
+// renderFunctionsJSONPath renders the template for the summary of each function, a line per function
+func renderFunctionsJSONPath(functions []platform.Function, template string, writer io.Writer) error {
+	jsonPath, err := parseJSONPathTemplate(template)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse jsonpath template")
+	}
+
+	for _, function := range functions {
+
+		// templates select by the JSON field names, rather than those of the structs
+		encodedFunctionSummary, err := json.Marshal(GetFunctionSummary(function))
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode function summary")
+		}
+
+		var functionSummary interface{}
+		if err := json.Unmarshal(encodedFunctionSummary, &functionSummary); err != nil {
+			return errors.Wrap(err, "Failed to decode function summary")
+		}
+
+		if err := jsonPath.Execute(writer, functionSummary); err != nil {
+			return errors.Wrapf(err, "Failed to render function %s", function.GetConfig().Meta.Name)
+		}
+
+		if _, err := io.WriteString(writer, "\n"); err != nil {
+			return errors.Wrap(err, "Failed to write function")
+		}
+	}
+
+	return nil
+}
+
+func parseJSONPathTemplate(template string) (*jsonpath.JSONPath, error) {
+	jsonPath := jsonpath.New("function")
+
+	// missing fields (e.g. URLs the platform couldn't tell) are rendered empty, rather than failing
+	jsonPath.AllowMissingKeys(true)
+
+	if err := jsonPath.Parse(template); err != nil {
+		return nil, err
+	}
+
+	return jsonPath, nil
+}
+
+// RenderAuditRecords renders audit records, oldest first. wide output adds the paths of the changed fields
+func RenderAuditRecords(records []platform.AuditRecord, format string, yamlIndent int, writer io.Writer) error {
 	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
 
 	switch format {
 	case OutputFormatText, OutputFormatWide:
-		header := []string{"Namespace", "Name", "Project", "State", "Node Port", "Replicas"}
+		header := []string{"Time", "Operation", "Function", "User", "Source", "Changes"}
 		if format == OutputFormatWide {
-			header = append(header, []string{
-				"Labels",
-				"Ingresses",
-			}...)
+			header = append(header, "Changed Fields")
 		}
 
-		var functionRecords [][]string
+		var recordFields [][]string
 
-		// for each field
-		for _, function := range functions {
-			availableReplicas, specifiedReplicas := function.GetReplicas()
+		for _, record := range records {
+			fields := []string{
+				record.Time.Format(time.RFC3339),
+				string(record.Operation),
+				record.Function,
+				record.User,
+				record.Source,
+				strconv.Itoa(len(record.Changes)),
+			}
 
-			// get its fields
-			functionFields := []string{
-				function.GetConfig().Meta.Namespace,
-				function.GetConfig().Meta.Name,
-				function.GetConfig().Meta.Labels["nuclio.io/project-name"],
-				string(function.GetStatus().State),
-				strconv.Itoa(function.GetStatus().HTTPPort),
-				fmt.Sprintf("%d/%d", availableReplicas, specifiedReplicas),
+			if format == OutputFormatWide {
+				var changedPaths []string
+				for _, change := range record.Changes {
+					changedPaths = append(changedPaths, change.Path)
+				}
+
+				fields = append(fields, strings.Join(changedPaths, ", "))
+			}
+
+			recordFields = append(recordFields, fields)
+		}
+
+		rendererInstance.RenderTable(header, recordFields)
+	case OutputFormatYAML:
+		return rendererInstance.RenderYAML(records)
+	case OutputFormatJSON:
+		return rendererInstance.RenderJSON(records)
+	}
+
+	return nil
+}
+
+// RenderFunctionRevisions renders the revisions of a function, oldest first
+func RenderFunctionRevisions(revisions []platform.FunctionRevision,
+	format string,
+	yamlIndent int,
+	writer io.Writer) error {
+
+	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		header := []string{"Revision", "Deployed", "Image"}
+		if format == OutputFormatWide {
+			header = append(header, "Image Digest")
+		}
+
+		var revisionRecords [][]string
+
+		for _, revision := range revisions {
+			revisionFields := []string{
+				strconv.Itoa(revision.Revision),
+				revision.Deployed.Format(time.RFC3339),
+				revision.Config.Spec.Image,
 			}
 
-			// add fields for wide view
 			if format == OutputFormatWide {
-				functionFields = append(functionFields, []string{
-					common.StringMapToString(function.GetConfig().Meta.Labels),
-					FormatFunctionIngresses(function),
-				}...)
+				revisionFields = append(revisionFields, revision.ImageDigest)
 			}
 
-			// add to records
-			functionRecords = append(functionRecords, functionFields)
+			revisionRecords = append(revisionRecords, revisionFields)
 		}
 
-		rendererInstance.RenderTable(header, functionRecords)
+		rendererInstance.RenderTable(header, revisionRecords)
 	case OutputFormatYAML:
-		return renderCallback(functions, rendererInstance.RenderYAML)
+		return rendererInstance.RenderYAML(revisions)
 	case OutputFormatJSON:
-		return renderCallback(functions, rendererInstance.RenderJSON)
+		return rendererInstance.RenderJSON(revisions)
+	}
+
+	return nil
+}
+
+// RenderFunctionInvocations renders the invocations of a function, oldest first
+func RenderFunctionInvocations(invocations []platform.FunctionInvocation,
+	format string,
+	yamlIndent int,
+	writer io.Writer) error {
+
+	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		header := []string{"Time", "Trigger", "Request", "Status", "Duration"}
+		if format == OutputFormatWide {
+			header = append(header, "Replica", "Error", "Response")
+		}
+
+		var invocationRecords [][]string
+
+		for _, invocation := range invocations {
+			request := strings.TrimSpace(fmt.Sprintf("%s %s", invocation.Method, invocation.Path))
+			if request == "" {
+				request = "-"
+			}
+
+			invocationFields := []string{
+				invocation.Time.Format(time.RFC3339),
+				fmt.Sprintf("%s:%s", invocation.TriggerKind, invocation.TriggerName),
+				request,
+				strconv.Itoa(invocation.StatusCode),
+				invocation.Duration.String(),
+			}
+
+			if format == OutputFormatWide {
+				response := invocation.Response
+				if invocation.ResponseTruncated {
+					response += "..."
+				}
+
+				invocationFields = append(invocationFields, invocation.Replica, invocation.Error, response)
+			}
+
+			invocationRecords = append(invocationRecords, invocationFields)
+		}
+
+		rendererInstance.RenderTable(header, invocationRecords)
+	case OutputFormatYAML:
+		return rendererInstance.RenderYAML(invocations)
+	case OutputFormatJSON:
+		return rendererInstance.RenderJSON(invocations)
+	}
+
+	return nil
+}
+
+// RenderFunctionStreamStatus renders the shards each replica's stream triggers consume - in text, a row per
+// trigger with its total lag, and in wide, a row per shard
+func RenderFunctionStreamStatus(functionStreamStatus []platform.FunctionReplicaStreamStatus,
+	format string,
+	yamlIndent int,
+	writer io.Writer) error {
+
+	rendererInstance := renderer.NewRenderer(writer)
+	rendererInstance.SetYAMLIndent(yamlIndent)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		header := []string{"Replica", "Trigger", "Shards", "Lag"}
+		if format == OutputFormatWide {
+			header = []string{"Replica", "Trigger", "Shard", "Lag", "Committed Sequence Number"}
+		}
+
+		var streamStatusRecords [][]string
+
+		for _, replicaStreamStatus := range functionStreamStatus {
+
+			// replicas whose processors didn't report their triggers (e.g. still starting) are listed as such
+			if len(replicaStreamStatus.Triggers) == 0 {
+				streamStatusRecords = append(streamStatusRecords,
+					append([]string{replicaStreamStatus.Replica}, make([]string, len(header)-1)...))
+				continue
+			}
+
+			var triggerNames []string
+			for triggerName := range replicaStreamStatus.Triggers {
+				triggerNames = append(triggerNames, triggerName)
+			}
+
+			sort.Strings(triggerNames)
+
+			for _, triggerName := range triggerNames {
+				shards := replicaStreamStatus.Triggers[triggerName]
+
+				if format == OutputFormatWide {
+					for _, shard := range shards {
+						streamStatusRecords = append(streamStatusRecords, []string{
+							replicaStreamStatus.Replica,
+							triggerName,
+							strconv.Itoa(shard.ShardID),
+							strconv.FormatUint(shard.Lag, 10),
+							strconv.FormatUint(shard.CommittedSequenceNumber, 10),
+						})
+					}
+
+					continue
+				}
+
+				var shardIDs []string
+				var lag uint64
+				for _, shard := range shards {
+					shardIDs = append(shardIDs, strconv.Itoa(shard.ShardID))
+					lag += shard.Lag
+				}
+
+				streamStatusRecords = append(streamStatusRecords, []string{
+					replicaStreamStatus.Replica,
+					triggerName,
+					strings.Join(shardIDs, ","),
+					strconv.FormatUint(lag, 10),
+				})
+			}
+		}
+
+		rendererInstance.RenderTable(header, streamStatusRecords)
+	case OutputFormatYAML:
+		return rendererInstance.RenderYAML(functionStreamStatus)
+	case OutputFormatJSON:
+		return rendererInstance.RenderJSON(functionStreamStatus)
 	}
 
 	return nil
 }
 
+// RenderFunctionDeprecations writes a warning for each deprecated function, with its message and removal
+// date, and returns the number of deprecated functions
+func RenderFunctionDeprecations(functions []platform.Function, writer io.Writer) int {
+	deprecatedFunctions := 0
+
+	for _, function := range functions {
+		functionConfig := function.GetConfig()
+
+		deprecation := functionconfig.GetDeprecation(functionConfig.Meta.Annotations)
+		if deprecation == nil {
+			continue
+		}
+
+		deprecatedFunctions++
+
+		notice := fmt.Sprintf("WARNING: Function %s is deprecated", functionConfig.Meta.Name)
+		if deprecation.Date != "" {
+			notice += fmt.Sprintf(" (removal date: %s)", deprecation.Date)
+		}
+
+		if deprecation.Message != "" {
+			notice += ": " + deprecation.Message
+		}
+
+		fmt.Fprintln(writer, notice) // nolint: errcheck
+	}
+
+	return deprecatedFunctions
+}
+
 func RenderFunctionEvents(functionEvents []platform.FunctionEvent,
 	format string,
 	writer io.Writer,
@@ -190,3 +685,155 @@ func RenderProjects(projects []platform.Project,
 
 	return nil
 }
+
+func RenderAPIGateways(apiGateways []platform.APIGateway,
+	format string,
+	writer io.Writer,
+	renderCallback func(apiGateways []platform.APIGateway, renderer func(interface{}) error) error) error {
+
+	rendererInstance := renderer.NewRenderer(writer)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		header := []string{"Namespace", "Name", "Host", "Path", "Authentication", "Functions", "State"}
+		if format == OutputFormatWide {
+			header = append(header, []string{
+				"Description",
+				"Last Error",
+			}...)
+		}
+
+		var apiGatewayRecords [][]string
+
+		// for each field
+		for _, apiGateway := range apiGateways {
+			apiGatewayConfig := apiGateway.GetConfig()
+
+			// get its fields
+			apiGatewayFields := []string{
+				apiGatewayConfig.Meta.Namespace,
+				apiGatewayConfig.Meta.Name,
+				apiGatewayConfig.Spec.Host,
+				apiGatewayConfig.Spec.Path,
+				string(apiGatewayConfig.Spec.AuthenticationMode),
+				FormatAPIGatewayUpstreams(apiGatewayConfig),
+				string(apiGatewayConfig.Status.State),
+			}
+
+			// add fields for wide view
+			if format == OutputFormatWide {
+				apiGatewayFields = append(apiGatewayFields, []string{
+					apiGatewayConfig.Spec.Description,
+					apiGatewayConfig.Status.LastError,
+				}...)
+			}
+
+			// add to records
+			apiGatewayRecords = append(apiGatewayRecords, apiGatewayFields)
+		}
+
+		rendererInstance.RenderTable(header, apiGatewayRecords)
+	case OutputFormatYAML:
+		return renderCallback(apiGateways, rendererInstance.RenderYAML)
+	case OutputFormatJSON:
+		return renderCallback(apiGateways, rendererInstance.RenderJSON)
+	}
+
+	return nil
+}
+
+// FormatAPIGatewayUpstreams returns the functions an API gateway routes to, with the canary's percentage
+// (e.g. "my-function, my-function-v2 (10%)")
+func FormatAPIGatewayUpstreams(apiGatewayConfig *platform.APIGatewayConfig) string {
+	var upstreams []string
+
+	for upstreamIndex, upstream := range apiGatewayConfig.Spec.Upstreams {
+		if upstream.NuclioFunction == nil {
+			continue
+		}
+
+		formattedUpstream := upstream.NuclioFunction.Name
+		if upstreamIndex > 0 {
+			formattedUpstream += fmt.Sprintf(" (%d%%)", upstream.Percentage)
+		}
+
+		upstreams = append(upstreams, formattedUpstream)
+	}
+
+	return strings.Join(upstreams, ", ")
+}
+
+// FunctionEnvDescription describes a single environment variable of a function
+type FunctionEnvDescription struct {
+	Function    string `json:"function"`
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RenderFunctionsEnv renders the environment variables of functions, along with their description
+// as documented in the function's annotations
+func RenderFunctionsEnv(logger logger.Logger,
+	functions []platform.Function,
+	format string,
+	writer io.Writer) error {
+
+	var envDescriptions []FunctionEnvDescription
+
+	for _, function := range functions {
+		if err := function.Initialize(nil); err != nil {
+			logger.DebugWith("Failed to initialize function", "err", err.Error())
+		}
+
+		functionConfig := function.GetConfig()
+		for _, envVar := range functionConfig.Spec.Env {
+			value := envVar.Value
+
+			// don't resolve values taken from secrets / config maps
+			if envVar.ValueFrom != nil {
+				value = "<from reference>"
+			}
+
+			envDescriptions = append(envDescriptions, FunctionEnvDescription{
+				Function: functionConfig.Meta.Name,
+				Name:     envVar.Name,
+				Value:    value,
+				Description: functionConfig.Meta.Annotations[functionconfig.FunctionAnnotationEnvDescriptionPrefix+
+					envVar.Name],
+			})
+		}
+
+		// the names of the variables set from a secret are only known to the platform
+		for _, envFromSecret := range functionConfig.Spec.EnvFromSecrets {
+			envDescriptions = append(envDescriptions, FunctionEnvDescription{
+				Function: functionConfig.Meta.Name,
+				Name:     "*",
+				Value:    fmt.Sprintf("<from secret %s>", envFromSecret.Name),
+			})
+		}
+	}
+
+	rendererInstance := renderer.NewRenderer(writer)
+
+	switch format {
+	case OutputFormatText, OutputFormatWide:
+		var envRecords [][]string
+
+		for _, envDescription := range envDescriptions {
+			envRecords = append(envRecords, []string{
+				envDescription.Function,
+				envDescription.Name,
+				envDescription.Value,
+				envDescription.Description,
+			})
+		}
+
+		rendererInstance.RenderTable([]string{"Function", "Name", "Value", "Description"}, envRecords)
+	case OutputFormatYAML:
+		return rendererInstance.RenderYAML(envDescriptions)
+	case OutputFormatJSON:
+		return rendererInstance.RenderJSON(envDescriptions)
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type renderersTestSuite struct {
+	suite.Suite
+}
+
+// invokableFunction is a function with replicas and invocation URLs
+type invokableFunction struct {
+	platform.AbstractFunction
+}
+
+func (f *invokableFunction) GetReplicas() (int, int) {
+	return 1, 2
+}
+
+func (f *invokableFunction) GetInvokeURL(invokeViaType platform.InvokeViaType) (string, error) {
+	switch invokeViaType {
+	case platform.InvokeViaDomainName:
+		return "nuclio-" + f.Config.Meta.Name + ".default.svc.cluster.local:8080", nil
+	case platform.InvokeViaExternalIP:
+		return "10.0.0.1:" + strconv.Itoa(f.Status.HTTPPort), nil
+	}
+
+	return "", errors.New("Unsupported")
+}
+
+func (suite *renderersTestSuite) TestRenderFunctionsSummary() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	lastDeployed := time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	newFunction := func(name string, httpPort int) platform.Function {
+		return &invokableFunction{
+			AbstractFunction: platform.AbstractFunction{
+				Config: functionconfig.Config{
+					Meta: functionconfig.Meta{
+						Name:      name,
+						Namespace: "default",
+						Labels:    map[string]string{"nuclio.io/project-name": "my-project"},
+					},
+				},
+				Status: functionconfig.Status{
+					State:        functionconfig.FunctionStateReady,
+					HTTPPort:     httpPort,
+					Image:        &functionconfig.ImageStatus{Digest: "sha256:0123"},
+					LastDeployed: &lastDeployed,
+				},
+			},
+		}
+	}
+
+	functions := []platform.Function{newFunction("f1", 31000), newFunction("f2", 0)}
+
+	outputBuffer := bytes.Buffer{}
+	err = RenderFunctions(loggerInstance, functions, OutputFormatWide, 0, &outputBuffer, nil)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "LAST DEPLOYED")
+	suite.Require().Contains(outputBuffer.String(),
+		"| 1/2      | false | sha256:0123  | 2020-10-15T12:00:00Z | http://nuclio-f1.default.svc.cluster.local:8080 | http://10.0.0.1:31000 |")
+
+	// functions without a node port have no external URL
+	suite.Require().Contains(outputBuffer.String(), "| http://nuclio-f2.default.svc.cluster.local:8080 |                       |")
+
+	outputBuffer.Reset()
+	err = RenderFunctions(loggerInstance,
+		functions,
+		OutputFormatJSONPathPrefix+"{.name} {.externalURL} {.status.image.digest} {.config.metadata.namespace} {.lastDeployed}",
+		0,
+		&outputBuffer,
+		nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(`f1 http://10.0.0.1:31000 sha256:0123 default 2020-10-15T12:00:00Z
+f2  sha256:0123 default 2020-10-15T12:00:00Z
+`, outputBuffer.String())
+}
+
+func (suite *renderersTestSuite) TestValidateFunctionOutputFormat() {
+	for _, format := range []string{OutputFormatText, OutputFormatWide, "jsonpath={.name}"} {
+		suite.Require().NoError(ValidateFunctionOutputFormat(format), format)
+	}
+
+	for _, format := range []string{"csv", "jsonpath={.name"} {
+		suite.Require().Error(ValidateFunctionOutputFormat(format), format)
+	}
+}
+
+func (suite *renderersTestSuite) TestRenderFunctionsEnv() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	function := &platform.AbstractFunction{
+		Config: functionconfig.Config{
+			Meta: functionconfig.Meta{
+				Name: "test",
+				Annotations: map[string]string{
+					functionconfig.FunctionAnnotationEnvDescriptionPrefix + "TIMEOUT": "Request timeout in seconds",
+				},
+			},
+			Spec: functionconfig.Spec{
+				Env: []v1.EnvVar{
+					{Name: "TIMEOUT", Value: "30"},
+					{Name: "UNDOCUMENTED", Value: "value"},
+					{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{}},
+				},
+				EnvFromSecrets: []functionconfig.EnvFromSecret{{Name: "credentials"}},
+			},
+		},
+	}
+
+	outputBuffer := bytes.Buffer{}
+	err = RenderFunctionsEnv(loggerInstance, []platform.Function{function}, OutputFormatJSON, &outputBuffer)
+	suite.Require().NoError(err)
+
+	var envDescriptions []FunctionEnvDescription
+	err = json.Unmarshal(outputBuffer.Bytes(), &envDescriptions)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]FunctionEnvDescription{
+		{Function: "test", Name: "TIMEOUT", Value: "30", Description: "Request timeout in seconds"},
+		{Function: "test", Name: "UNDOCUMENTED", Value: "value"},
+		{Function: "test", Name: "PASSWORD", Value: "<from reference>"},
+		{Function: "test", Name: "*", Value: "<from secret credentials>"},
+	}, envDescriptions)
+
+	// text output contains the description
+	outputBuffer.Reset()
+	err = RenderFunctionsEnv(loggerInstance, []platform.Function{function}, OutputFormatText, &outputBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "Request timeout in seconds")
+}
+
+func (suite *renderersTestSuite) TestRenderContextsFunctions() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	newFunction := func(name string) platform.Function {
+		return &platform.AbstractFunction{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:      name,
+					Namespace: "default",
+				},
+			},
+		}
+	}
+
+	contexts := []string{"prod", "staging", "unreachable"}
+	functionsByContext := map[string][]platform.Function{
+		"prod":    {newFunction("f1"), newFunction("f2")},
+		"staging": {newFunction("f1")},
+	}
+
+	outputBuffer := bytes.Buffer{}
+	err = RenderContextsFunctions(loggerInstance, contexts, functionsByContext, OutputFormatJSON, 0, &outputBuffer)
+	suite.Require().NoError(err)
+
+	var contextsFunctions []ContextFunctions
+	err = json.Unmarshal(outputBuffer.Bytes(), &contextsFunctions)
+	suite.Require().NoError(err)
+
+	// contexts are rendered in order, including those without functions
+	suite.Require().Len(contextsFunctions, 3)
+	suite.Require().Equal("prod", contextsFunctions[0].Context)
+	suite.Require().Len(contextsFunctions[0].Functions, 2)
+	suite.Require().Equal("staging", contextsFunctions[1].Context)
+	suite.Require().Equal("f1", contextsFunctions[1].Functions[0].Meta.Name)
+	suite.Require().Empty(contextsFunctions[2].Functions)
+
+	// text output has a context column
+	outputBuffer.Reset()
+	err = RenderContextsFunctions(loggerInstance, contexts, functionsByContext, OutputFormatText, 0, &outputBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "CONTEXT")
+	suite.Require().Contains(outputBuffer.String(), "staging | default   | f1")
+}
+
+func (suite *renderersTestSuite) TestRenderFunctionRevisions() {
+	deployed := time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	revisions := []platform.FunctionRevision{
+		{Revision: 1, Deployed: deployed, ImageDigest: "sha256:0123"},
+		{Revision: 2, Deployed: deployed.Add(time.Hour)},
+	}
+	revisions[0].Config.Spec.Image = "my-function:1.0.0"
+	revisions[1].Config.Spec.Image = "my-function:2.0.0"
+
+	outputBuffer := bytes.Buffer{}
+	err := RenderFunctionRevisions(revisions, OutputFormatWide, 0, &outputBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "1 | 2020-10-15T12:00:00Z | my-function:1.0.0 | sha256:0123")
+	suite.Require().Contains(outputBuffer.String(), "2 | 2020-10-15T13:00:00Z | my-function:2.0.0 |")
+
+	outputBuffer.Reset()
+	err = RenderFunctionRevisions(revisions, OutputFormatJSON, 0, &outputBuffer)
+	suite.Require().NoError(err)
+
+	var renderedRevisions []platform.FunctionRevision
+	err = json.Unmarshal(outputBuffer.Bytes(), &renderedRevisions)
+	suite.Require().NoError(err)
+	suite.Require().Equal(revisions, renderedRevisions)
+}
+
+func (suite *renderersTestSuite) TestRenderFunctionStreamStatus() {
+	functionStreamStatus := []platform.FunctionReplicaStreamStatus{
+		{
+			Replica: "my-function-1",
+			Triggers: map[string][]platform.StreamShardStatus{
+				"stream": {
+					{ShardID: 0, Lag: 5, CommittedSequenceNumber: 100},
+					{ShardID: 1, Lag: 2, CommittedSequenceNumber: 200},
+				},
+			},
+		},
+		{
+			Replica: "my-function-2",
+			Triggers: map[string][]platform.StreamShardStatus{
+				"stream": {
+					{ShardID: 2, Lag: 0, CommittedSequenceNumber: 300},
+				},
+			},
+		},
+		{
+			Replica: "my-function-3",
+		},
+	}
+
+	// a row per trigger, with its total lag
+	outputBuffer := bytes.Buffer{}
+	err := RenderFunctionStreamStatus(functionStreamStatus, OutputFormatText, 0, &outputBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "my-function-1 | stream  | 0,1    |   7")
+	suite.Require().Contains(outputBuffer.String(), "my-function-2 | stream  |      2 |   0")
+	suite.Require().Contains(outputBuffer.String(), "my-function-3 |")
+
+	// a row per shard
+	outputBuffer.Reset()
+	err = RenderFunctionStreamStatus(functionStreamStatus, OutputFormatWide, 0, &outputBuffer)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), "my-function-1 | stream  |     0 |   5 |                       100")
+	suite.Require().Contains(outputBuffer.String(), "my-function-1 | stream  |     1 |   2 |                       200")
+	suite.Require().Contains(outputBuffer.String(), "my-function-2 | stream  |     2 |   0 |                       300")
+
+	outputBuffer.Reset()
+	err = RenderFunctionStreamStatus(functionStreamStatus, OutputFormatJSON, 0, &outputBuffer)
+	suite.Require().NoError(err)
+
+	var renderedStreamStatus []platform.FunctionReplicaStreamStatus
+	err = json.Unmarshal(outputBuffer.Bytes(), &renderedStreamStatus)
+	suite.Require().NoError(err)
+	suite.Require().Equal(functionStreamStatus, renderedStreamStatus)
+}
+
+func TestRenderersTestSuite(t *testing.T) {
+	suite.Run(t, new(renderersTestSuite))
+}
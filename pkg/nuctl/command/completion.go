@@ -0,0 +1,512 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// commands whose argument is the name of a resource are annotated with one of these, so that the names are completed
+const (
+	completionAnnotationFunctionName   = "nuctl.io/complete-function-name"
+	completionAnnotationProjectName    = "nuctl.io/complete-project-name"
+	completionAnnotationAPIGatewayName = "nuctl.io/complete-apigateway-name"
+)
+
+// the completion of names gives up on a platform that doesn't respond in time
+const completionNamesTimeout = 5 * time.Second
+
+// completedResource is a kind of resource whose names are completed. the completion scripts get the names by
+// running a hidden "completion <kind>-names" command
+type completedResource struct {
+	kind       string
+	annotation string
+	getNames   func(rootCommandeer *RootCommandeer) ([]string, error)
+}
+
+var completedResources = []completedResource{
+	{
+		kind:       "function",
+		annotation: completionAnnotationFunctionName,
+		getNames: func(rootCommandeer *RootCommandeer) ([]string, error) {
+			functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+				Namespace: rootCommandeer.namespace,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var names []string
+			for _, function := range functions {
+				names = append(names, function.GetConfig().Meta.Name)
+			}
+
+			return names, nil
+		},
+	},
+	{
+		kind:       "project",
+		annotation: completionAnnotationProjectName,
+		getNames: func(rootCommandeer *RootCommandeer) ([]string, error) {
+			projects, err := rootCommandeer.platform.GetProjects(&platform.GetProjectsOptions{
+				Meta: platform.ProjectMeta{
+					Namespace: rootCommandeer.namespace,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var names []string
+			for _, project := range projects {
+				names = append(names, project.GetConfig().Meta.Name)
+			}
+
+			return names, nil
+		},
+	},
+	{
+		kind:       "apigateway",
+		annotation: completionAnnotationAPIGatewayName,
+		getNames: func(rootCommandeer *RootCommandeer) ([]string, error) {
+			apiGateways, err := rootCommandeer.platform.GetAPIGateways(&platform.GetAPIGatewaysOptions{
+				Meta: platform.APIGatewayMeta{
+					Namespace: rootCommandeer.namespace,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var names []string
+			for _, apiGateway := range apiGateways {
+				names = append(names, apiGateway.GetConfig().Meta.Name)
+			}
+
+			return names, nil
+		},
+	},
+}
+
+// getCompletedResource returns the resource whose names complete the command's argument, if any
+func getCompletedResource(cmd *cobra.Command) *completedResource {
+	for resourceIdx := range completedResources {
+		if _, found := cmd.Annotations[completedResources[resourceIdx].annotation]; found {
+			return &completedResources[resourceIdx]
+		}
+	}
+
+	return nil
+}
+
+type completionCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newCompletionCommandeer(rootCommandeer *RootCommandeer) *completionCommandeer {
+	commandeer := &completionCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Output a shell completion script",
+		Long: `Output a shell completion script. The names of functions, projects and API gateways are completed in bash,
+fish and powershell, by listing them on the platform`,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCmd := rootCommandeer.cmd
+
+			switch args[0] {
+			case "bash":
+				rootCmd.BashCompletionFunction = commandeer.getBashCompletionFunction()
+				return rootCmd.GenBashCompletion(cmd.OutOrStdout())
+			case "zsh":
+				return rootCmd.GenZshCompletion(cmd.OutOrStdout())
+			case "fish":
+				return commandeer.genFishCompletion(cmd.OutOrStdout())
+			case "powershell":
+				return commandeer.genPowerShellCompletion(cmd.OutOrStdout())
+			default:
+				return errors.Errorf("Unsupported shell %s, must be one of: bash, zsh, fish, powershell", args[0])
+			}
+		},
+	}
+
+	// used by the completion scripts, not meant to be run directly
+	for resourceIdx := range completedResources {
+		resource := &completedResources[resourceIdx]
+
+		cmd.AddCommand(&cobra.Command{
+			Use:    resource.kind + "-names",
+			Short:  fmt.Sprintf("List the names of the %ss, one per line", resource.kind),
+			Hidden: true,
+			Args:   cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				commandeer.outputNames(cmd.OutOrStdout(), resource)
+
+				// failing would only get in the way of the user typing the command
+				return nil
+			},
+		})
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// outputNames writes the names of the resources, or nothing if they can't be listed in time
+func (c *completionCommandeer) outputNames(writer io.Writer, resource *completedResource) {
+	resourceNames := make(chan []string, 1)
+
+	go func() {
+
+		// logs would be taken as completions
+		c.rootCommandeer.jsonLogSink = ioutil.Discard
+
+		if err := c.rootCommandeer.initialize(); err != nil {
+			resourceNames <- nil
+			return
+		}
+
+		names, err := resource.getNames(c.rootCommandeer)
+		if err != nil {
+			resourceNames <- nil
+			return
+		}
+
+		resourceNames <- names
+	}()
+
+	select {
+	case names := <-resourceNames:
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(writer, name) // nolint: errcheck
+		}
+	case <-time.After(completionNamesTimeout):
+	}
+}
+
+// getBashCompletionFunction returns the custom completion function of the bash script, which cobra calls for
+// the arguments of commands. it completes resource names for the commands annotated as taking one
+func (c *completionCommandeer) getBashCompletionFunction() string {
+	rootName := c.rootCommandeer.cmd.Name()
+	commandNamesByKind := map[string][]string{}
+
+	walkCommands(c.rootCommandeer.cmd, func(cmd *cobra.Command) {
+		if resource := getCompletedResource(cmd); resource != nil {
+
+			// the way cobra's bash script names the commands
+			commandName := strings.Replace(cmd.CommandPath(), " ", "_", -1)
+			commandNamesByKind[resource.kind] = append(commandNamesByKind[resource.kind],
+				strings.Replace(commandName, ":", "__", -1))
+		}
+	})
+
+	if len(commandNamesByKind) == 0 {
+		return ""
+	}
+
+	script := strings.Builder{}
+	cases := strings.Builder{}
+
+	for _, resource := range completedResources {
+		commandNames, found := commandNamesByKind[resource.kind]
+		if !found {
+			continue
+		}
+
+		script.WriteString(fmt.Sprintf(`__%[1]s_get_%[2]s_names()
+{
+    local %[1]s_out
+    if %[1]s_out=$(%[1]s completion %[2]s-names 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${%[1]s_out[*]}" -- "$cur" ) )
+    fi
+}
+
+`, rootName, resource.kind))
+
+		cases.WriteString(fmt.Sprintf(`        %[3]s)
+            __%[1]s_get_%[2]s_names
+            return
+            ;;
+`, rootName, resource.kind, strings.Join(commandNames, " | ")))
+	}
+
+	script.WriteString(fmt.Sprintf(`__%[1]s_custom_func()
+{
+    case ${last_command} in
+%[2]s        *)
+            ;;
+    esac
+}
+`, rootName, cases.String()))
+
+	return script.String()
+}
+
+// genFishCompletion writes a fish completion script for the commands and their flags. cobra doesn't generate
+// fish scripts, so it's done here
+func (c *completionCommandeer) genFishCompletion(writer io.Writer) error {
+	rootCmd := c.rootCommandeer.cmd
+	rootName := rootCmd.Name()
+	script := strings.Builder{}
+
+	script.WriteString(fmt.Sprintf("# fish completion for %s\n\n", rootName))
+	for _, resource := range completedResources {
+		script.WriteString(fmt.Sprintf("function __%s_%s_names\n", rootName, resource.kind))
+		script.WriteString(fmt.Sprintf("    %s completion %s-names 2>/dev/null\n", rootName, resource.kind))
+		script.WriteString("end\n\n")
+	}
+
+	// arguments aren't files
+	script.WriteString(fmt.Sprintf("complete -c %s -f\n", rootName))
+
+	// global flags are always available
+	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			script.WriteString(fmt.Sprintf("complete -c %s%s\n", rootName, getFishFlagCompletion(flag)))
+		}
+	})
+
+	walkCommands(rootCmd, func(cmd *cobra.Command) {
+		condition := getFishCommandCondition(cmd)
+
+		// the subcommands, until one of them is given
+		var subcommandNames []string
+		for _, subcommand := range cmd.Commands() {
+			if subcommand.IsAvailableCommand() {
+				subcommandNames = append(subcommandNames, subcommand.Name())
+				subcommandNames = append(subcommandNames, subcommand.Aliases...)
+			}
+		}
+
+		for _, subcommand := range cmd.Commands() {
+			if !subcommand.IsAvailableCommand() {
+				continue
+			}
+
+			subcommandCondition := "__fish_use_subcommand"
+			if cmd != rootCmd {
+				subcommandCondition = fmt.Sprintf("%s; and not __fish_seen_subcommand_from %s",
+					condition,
+					strings.Join(subcommandNames, " "))
+			}
+
+			script.WriteString(fmt.Sprintf("complete -c %s -n '%s' -a %s -d %s\n",
+				rootName,
+				subcommandCondition,
+				subcommand.Name(),
+				quoteFishString(subcommand.Short)))
+		}
+
+		if cmd == rootCmd {
+			return
+		}
+
+		cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+			if !flag.Hidden {
+				script.WriteString(fmt.Sprintf("complete -c %s -n '%s'%s\n", rootName, condition, getFishFlagCompletion(flag)))
+			}
+		})
+
+		if resource := getCompletedResource(cmd); resource != nil {
+			script.WriteString(fmt.Sprintf("complete -c %s -n '%s' -a '(__%s_%s_names)'\n",
+				rootName,
+				condition,
+				rootName,
+				resource.kind))
+		}
+	})
+
+	_, err := io.WriteString(writer, script.String())
+	return err
+}
+
+// genPowerShellCompletion writes a powershell completion script for the commands and their flags. like fish,
+// cobra doesn't generate powershell scripts. the script holds a table of the commands, and resolves the command
+// typed so far through it
+func (c *completionCommandeer) genPowerShellCompletion(writer io.Writer) error {
+	rootCmd := c.rootCommandeer.cmd
+	rootName := rootCmd.Name()
+	commandTable := strings.Builder{}
+
+	walkCommands(rootCmd, func(cmd *cobra.Command) {
+
+		// each of the names of a subcommand resolves to its name
+		var subcommands []string
+		for _, subcommand := range cmd.Commands() {
+			if !subcommand.IsAvailableCommand() {
+				continue
+			}
+
+			for _, subcommandName := range append([]string{subcommand.Name()}, subcommand.Aliases...) {
+				subcommands = append(subcommands, fmt.Sprintf("%s = %s",
+					quotePowerShellString(subcommandName),
+					quotePowerShellString(subcommand.Name())))
+			}
+		}
+
+		var flags []string
+		visitFlag := func(flag *pflag.Flag) {
+			if flag.Hidden {
+				return
+			}
+
+			flags = append(flags, quotePowerShellString("--"+flag.Name))
+			if flag.Shorthand != "" {
+				flags = append(flags, quotePowerShellString("-"+flag.Shorthand))
+			}
+		}
+
+		cmd.LocalFlags().VisitAll(visitFlag)
+		cmd.InheritedFlags().VisitAll(visitFlag)
+
+		namesCommand := ""
+		if resource := getCompletedResource(cmd); resource != nil {
+			namesCommand = resource.kind + "-names"
+		}
+
+		commandTable.WriteString(fmt.Sprintf("        %s = @{ Subcommands = @{ %s }; Flags = @(%s); Names = %s }\n",
+			quotePowerShellString(cmd.CommandPath()),
+			strings.Join(subcommands, "; "),
+			strings.Join(flags, ", "),
+			quotePowerShellString(namesCommand)))
+	})
+
+	_, err := io.WriteString(writer, fmt.Sprintf(`# powershell completion for %[1]s
+
+Register-ArgumentCompleter -Native -CommandName %[2]s -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $commands = @{
+%[3]s    }
+
+    # resolve the command typed so far, up to the word being completed
+    $command = %[2]s
+    foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {
+        if ($element.Extent.EndOffset -ge $cursorPosition) {
+            break
+        }
+
+        $subcommand = $commands[$command].Subcommands["$element"]
+        if ($subcommand) {
+            $command = "$command $subcommand"
+        }
+    }
+
+    if ($wordToComplete.StartsWith('-')) {
+        $completions = $commands[$command].Flags
+    } else {
+        $completions = @($commands[$command].Subcommands.Values | Sort-Object -Unique)
+        if ($commands[$command].Names) {
+            $completions += @(& %[2]s completion $commands[$command].Names 2>$null)
+        }
+    }
+
+    $completions | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`, rootName, quotePowerShellString(rootName), commandTable.String()))
+
+	return err
+}
+
+// getFishCommandCondition returns a fish condition that holds once the command (and its parents) were given
+func getFishCommandCondition(cmd *cobra.Command) string {
+	var conditions []string
+
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		commandNames := append([]string{cmd.Name()}, cmd.Aliases...)
+		conditions = append([]string{"__fish_seen_subcommand_from " + strings.Join(commandNames, " ")}, conditions...)
+	}
+
+	return strings.Join(conditions, "; and ")
+}
+
+func getFishFlagCompletion(flag *pflag.Flag) string {
+	flagCompletion := " -l " + flag.Name
+	if flag.Shorthand != "" {
+		flagCompletion += " -s " + flag.Shorthand
+	}
+
+	// flags other than booleans take a value
+	if flag.Value.Type() != "bool" {
+		flagCompletion += " -r"
+	}
+
+	return flagCompletion + " -d " + quoteFishString(flag.Usage)
+}
+
+func quoteFishString(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+}
+
+func quotePowerShellString(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// walkCommands calls the visitor with the command and all the commands under it that are available to users
+func walkCommands(cmd *cobra.Command, visitor func(cmd *cobra.Command)) {
+	visitor(cmd)
+
+	for _, subcommand := range cmd.Commands() {
+		if subcommand.IsAvailableCommand() {
+			walkCommands(subcommand, visitor)
+		}
+	}
+}
+
+// completeFunctionName marks the command as taking a function name as its argument, for shell completion
+func completeFunctionName(cmd *cobra.Command) {
+	annotateCompletion(cmd, completionAnnotationFunctionName)
+}
+
+// completeProjectName marks the command as taking a project name as its argument, for shell completion
+func completeProjectName(cmd *cobra.Command) {
+	annotateCompletion(cmd, completionAnnotationProjectName)
+}
+
+// completeAPIGatewayName marks the command as taking an API gateway name as its argument, for shell completion
+func completeAPIGatewayName(cmd *cobra.Command) {
+	annotateCompletion(cmd, completionAnnotationAPIGatewayName)
+}
+
+func annotateCompletion(cmd *cobra.Command, annotation string) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+
+	cmd.Annotations[annotation] = "true"
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type configCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newConfigCommandeer(rootCommandeer *RootCommandeer) *configCommandeer {
+	commandeer := &configCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage contexts - named defaults of the platform, namespace, project and registry (in ~/.nuctl/config, or NUCTL_CONFIG)",
+	}
+
+	setContextCommand := newConfigSetContextCommandeer(commandeer).cmd
+	useContextCommand := newConfigUseContextCommandeer(commandeer).cmd
+	getContextsCommand := newConfigGetContextsCommandeer(commandeer).cmd
+	currentContextCommand := newConfigCurrentContextCommandeer(commandeer).cmd
+	deleteContextCommand := newConfigDeleteContextCommandeer(commandeer).cmd
+
+	cmd.AddCommand(
+		setContextCommand,
+		useContextCommand,
+		getContextsCommand,
+		currentContextCommand,
+		deleteContextCommand,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// updateConfig reads the configuration, lets the given function modify it and writes it back
+func (c *configCommandeer) updateConfig(modifier func(config *common.Config) error) error {
+	configPath := common.GetConfigPath()
+
+	config, err := common.ReadConfig(configPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read configuration")
+	}
+
+	if err := modifier(config); err != nil {
+		return err
+	}
+
+	if err := config.Write(configPath); err != nil {
+		return errors.Wrap(err, "Failed to write configuration")
+	}
+
+	return nil
+}
+
+type configSetContextCommandeer struct {
+	*configCommandeer
+	context common.Context
+}
+
+func newConfigSetContextCommandeer(configCommandeer *configCommandeer) *configSetContextCommandeer {
+	commandeer := &configSetContextCommandeer{
+		configCommandeer: configCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "set-context name",
+		Short: "Create a context, or set the given fields of an existing one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName := args[0]
+
+			if err := commandeer.updateConfig(func(config *common.Config) error {
+				if config.Contexts == nil {
+					config.Contexts = map[string]*common.Context{}
+				}
+
+				context, found := config.Contexts[contextName]
+				if !found {
+					context = &common.Context{}
+					config.Contexts[contextName] = context
+				}
+
+				// only the fields which were given are set, so that an empty value clears a field
+				for flagName, field := range map[string]*string{
+					"platform":      &context.Platform,
+					"namespace":     &context.Namespace,
+					"project":       &context.Project,
+					"registry":      &context.Registry,
+					"run-registry":  &context.RunRegistry,
+					"kubeconfig":    &context.Kubeconfig,
+					"kube-context":  &context.KubeContext,
+					"dashboard-url": &context.DashboardURL,
+				} {
+					if flag := cmd.Flags().Lookup(flagName); flag.Changed {
+						*field = flag.Value.String()
+					}
+				}
+
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			cmd.Printf("Context %s set\n", contextName)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.context.Platform, "platform", "", "Platform - \"kube\" or \"local\"")
+	cmd.Flags().StringVarP(&commandeer.context.Namespace, "namespace", "n", "", "Namespace")
+	cmd.Flags().StringVar(&commandeer.context.Project, "project", "", "Project functions are deployed to")
+	cmd.Flags().StringVarP(&commandeer.context.Registry, "registry", "r", "", "URL of a container registry")
+	cmd.Flags().StringVar(&commandeer.context.RunRegistry, "run-registry", "", "URL of a registry for pulling images, if differs from --registry")
+	cmd.Flags().StringVarP(&commandeer.context.Kubeconfig, "kubeconfig", "k", "", "Path to a Kubernetes configuration file")
+	cmd.Flags().StringVar(&commandeer.context.KubeContext, "kube-context", "", "Context of the Kubernetes configuration file (default - its current context)")
+	cmd.Flags().StringVar(&commandeer.context.DashboardURL, "dashboard-url", "", "URL of the nuclio dashboard")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type configUseContextCommandeer struct {
+	*configCommandeer
+}
+
+func newConfigUseContextCommandeer(configCommandeer *configCommandeer) *configUseContextCommandeer {
+	commandeer := &configUseContextCommandeer{
+		configCommandeer: configCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "use-context name",
+		Short: "Set the current context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName := args[0]
+
+			if err := commandeer.updateConfig(func(config *common.Config) error {
+				if _, err := config.GetContext(contextName); err != nil {
+					return err
+				}
+
+				config.CurrentContext = contextName
+
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			cmd.Printf("Switched to context %s\n", contextName)
+
+			return nil
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type configGetContextsCommandeer struct {
+	*configCommandeer
+}
+
+func newConfigGetContextsCommandeer(configCommandeer *configCommandeer) *configGetContextsCommandeer {
+	commandeer := &configGetContextsCommandeer{
+		configCommandeer: configCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "get-contexts",
+		Short: "Display the contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := common.ReadConfig(common.GetConfigPath())
+			if err != nil {
+				return errors.Wrap(err, "Failed to read configuration")
+			}
+
+			if commandeer.rootCommandeer.isJSONOutput() {
+				if config.Contexts == nil {
+					config.Contexts = map[string]*common.Context{}
+				}
+
+				return commandeer.rootCommandeer.renderResult(cmd.OutOrStdout(), config)
+			}
+
+			if len(config.Contexts) == 0 {
+				cmd.Println("No contexts found")
+				return nil
+			}
+
+			var records [][]string
+			for _, contextName := range config.GetContextNames() {
+				context := config.Contexts[contextName]
+
+				current := ""
+				if contextName == config.CurrentContext {
+					current = "*"
+				}
+
+				records = append(records, []string{
+					current,
+					contextName,
+					context.Platform,
+					context.Namespace,
+					context.Project,
+					context.Registry,
+					context.DashboardURL,
+				})
+			}
+
+			renderer.NewRenderer(cmd.OutOrStdout()).RenderTable([]string{
+				"Current",
+				"Name",
+				"Platform",
+				"Namespace",
+				"Project",
+				"Registry",
+				"Dashboard URL",
+			}, records)
+
+			return nil
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type configCurrentContextCommandeer struct {
+	*configCommandeer
+}
+
+func newConfigCurrentContextCommandeer(configCommandeer *configCommandeer) *configCurrentContextCommandeer {
+	commandeer := &configCurrentContextCommandeer{
+		configCommandeer: configCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "current-context",
+		Short: "Display the name of the current context",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := common.ReadConfig(common.GetConfigPath())
+			if err != nil {
+				return errors.Wrap(err, "Failed to read configuration")
+			}
+
+			if config.CurrentContext == "" {
+				return errors.New("Current context is not set, set it with nuctl config use-context")
+			}
+
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), config.CurrentContext)
+			return err
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type configDeleteContextCommandeer struct {
+	*configCommandeer
+}
+
+func newConfigDeleteContextCommandeer(configCommandeer *configCommandeer) *configDeleteContextCommandeer {
+	commandeer := &configDeleteContextCommandeer{
+		configCommandeer: configCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "delete-context name",
+		Short: "Delete a context (unsetting the current context, if it's the one)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName := args[0]
+
+			if err := commandeer.updateConfig(func(config *common.Config) error {
+				if _, err := config.GetContext(contextName); err != nil {
+					return err
+				}
+
+				delete(config.Contexts, contextName)
+
+				if config.CurrentContext == contextName {
+					config.CurrentContext = ""
+				}
+
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			cmd.Printf("Context %s deleted\n", contextName)
+
+			return nil
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// name of the http trigger created by nuctl deploy --http-cors-* when the function has none
+const defaultCORSHTTPTriggerName = "http"
+
+var corsAllowedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// httpCORSOptions are the CORS settings given to nuctl deploy. Empty fields keep the trigger's defaults
+type httpCORSOptions struct {
+	allowOrigin  string
+	allowMethods []string
+	allowHeaders []string
+}
+
+func (hco *httpCORSOptions) isEmpty() bool {
+	return hco.allowOrigin == "" && len(hco.allowMethods) == 0 && len(hco.allowHeaders) == 0
+}
+
+// applyHTTPCORS validates the CORS options and sets them on the function's http trigger, creating one
+// if the function has none. the trigger's other CORS settings are left as they are
+func applyHTTPCORS(options *httpCORSOptions, triggers *map[string]functionconfig.Trigger) error {
+	if options.isEmpty() {
+		return nil
+	}
+
+	corsAttributes := map[string]interface{}{
+		"enabled": true,
+	}
+
+	if options.allowOrigin != "" {
+		if err := validateCORSOrigin(options.allowOrigin); err != nil {
+			return errors.Wrap(err, "Invalid allowed origin")
+		}
+
+		corsAttributes["allowOrigin"] = options.allowOrigin
+	}
+
+	if len(options.allowMethods) != 0 {
+		allowMethods, err := normalizeCORSMethods(options.allowMethods)
+		if err != nil {
+			return errors.Wrap(err, "Invalid allowed methods")
+		}
+
+		corsAttributes["allowMethods"] = allowMethods
+	}
+
+	if len(options.allowHeaders) != 0 {
+		allowHeaders, err := normalizeCORSHeaders(options.allowHeaders)
+		if err != nil {
+			return errors.Wrap(err, "Invalid allowed headers")
+		}
+
+		corsAttributes["allowHeaders"] = allowHeaders
+	}
+
+	if *triggers == nil {
+		*triggers = map[string]functionconfig.Trigger{}
+	}
+
+	httpTriggers := functionconfig.GetTriggersByKind(*triggers, "http")
+	if len(httpTriggers) == 0 {
+		httpTriggers[defaultCORSHTTPTriggerName] = functionconfig.Trigger{
+			Class:      "sync",
+			Kind:       "http",
+			Name:       defaultCORSHTTPTriggerName,
+			MaxWorkers: 1,
+		}
+	}
+
+	for triggerName, httpTrigger := range httpTriggers {
+		attributes := map[string]interface{}{}
+		for attributeName, attributeValue := range httpTrigger.Attributes {
+			attributes[attributeName] = attributeValue
+		}
+
+		// merge into the trigger's CORS settings
+		mergedCORSAttributes := map[string]interface{}{}
+		if existingCORSAttributes, isMap := attributes["cors"].(map[string]interface{}); isMap {
+			for corsAttributeName, corsAttributeValue := range existingCORSAttributes {
+				mergedCORSAttributes[corsAttributeName] = corsAttributeValue
+			}
+		}
+
+		for corsAttributeName, corsAttributeValue := range corsAttributes {
+			mergedCORSAttributes[corsAttributeName] = corsAttributeValue
+		}
+
+		attributes["cors"] = mergedCORSAttributes
+		httpTrigger.Attributes = attributes
+		(*triggers)[triggerName] = httpTrigger
+	}
+
+	return nil
+}
+
+func validateCORSOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	parsedOrigin, err := url.Parse(origin)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse origin %s", origin)
+	}
+
+	// an origin is a scheme and a host (with an optional port), nothing more
+	if parsedOrigin.Scheme == "" || parsedOrigin.Host == "" {
+		return errors.Errorf("Origin %s must be \"*\" or in the form of scheme://host[:port]", origin)
+	}
+
+	if (parsedOrigin.Path != "" && parsedOrigin.Path != "/") || parsedOrigin.RawQuery != "" || parsedOrigin.Fragment != "" {
+		return errors.Errorf("Origin %s must not have a path, query or fragment", origin)
+	}
+
+	return nil
+}
+
+func normalizeCORSMethods(methods []string) ([]string, error) {
+	var normalizedMethods []string
+
+	for _, method := range splitCommaSeparatedValues(methods) {
+		normalizedMethod := strings.ToUpper(method)
+
+		allowed := false
+		for _, allowedMethod := range corsAllowedMethods {
+			if normalizedMethod == allowedMethod {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return nil, errors.Errorf("Unknown HTTP method %s, must be one of: %s",
+				method,
+				strings.Join(corsAllowedMethods, ", "))
+		}
+
+		normalizedMethods = append(normalizedMethods, normalizedMethod)
+	}
+
+	return normalizedMethods, nil
+}
+
+func normalizeCORSHeaders(headers []string) ([]string, error) {
+	var normalizedHeaders []string
+
+	for _, header := range splitCommaSeparatedValues(headers) {
+		if !isHTTPToken(header) {
+			return nil, errors.Errorf("Header name %s contains invalid characters", header)
+		}
+
+		normalizedHeaders = append(normalizedHeaders, http.CanonicalHeaderKey(header))
+	}
+
+	return normalizedHeaders, nil
+}
+
+// splitCommaSeparatedValues flattens values that may each hold several comma separated values
+func splitCommaSeparatedValues(values []string) []string {
+	var splitValues []string
+
+	for _, value := range values {
+		for _, splitValue := range strings.Split(value, ",") {
+			if splitValue = strings.TrimSpace(splitValue); splitValue != "" {
+				splitValues = append(splitValues, splitValue)
+			}
+		}
+	}
+
+	return splitValues
+}
+
+// isHTTPToken returns whether the value is a valid token per RFC 7230 (e.g. a header name)
+func isHTTPToken(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, char := range value {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", char):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -18,9 +18,17 @@ package command
 
 import (
 	"encoding/json"
-
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/dashboard/functiontemplates"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
+	"github.com/ghodss/yaml"
 	"github.com/nuclio/errors"
 	"github.com/spf13/cobra"
 )
@@ -43,10 +51,14 @@ func newCreateCommandeer(rootCommandeer *RootCommandeer) *createCommandeer {
 
 	createProjectCommand := newCreateProjectCommandeer(commandeer).cmd
 	createFunctionEventCommand := newCreateFunctionEventCommandeer(commandeer).cmd
+	createFunctionCommand := newCreateFunctionCommandeer(commandeer).cmd
+	createAPIGatewayCommand := newCreateAPIGatewayCommandeer(commandeer).cmd
 
 	cmd.AddCommand(
 		createProjectCommand,
 		createFunctionEventCommand,
+		createFunctionCommand,
+		createAPIGatewayCommand,
 	)
 
 	commandeer.cmd = cmd
@@ -57,6 +69,7 @@ func newCreateCommandeer(rootCommandeer *RootCommandeer) *createCommandeer {
 type createProjectCommandeer struct {
 	*createCommandeer
 	projectConfig platform.ProjectConfig
+	specFilePath  string
 }
 
 func newCreateProjectCommandeer(createCommandeer *createCommandeer) *createProjectCommandeer {
@@ -83,6 +96,20 @@ func newCreateProjectCommandeer(createCommandeer *createCommandeer) *createProje
 			commandeer.projectConfig.Meta.Name = args[0]
 			commandeer.projectConfig.Meta.Namespace = createCommandeer.rootCommandeer.namespace
 
+			if commandeer.specFilePath != "" {
+				specFileProjectSpec, err := readProjectSpecFile(commandeer.specFilePath)
+				if err != nil {
+					return errors.Wrap(err, "Failed to read project spec file")
+				}
+
+				// flags take precedence over the spec file
+				applyProjectSpec(&commandeer.projectConfig.Spec, specFileProjectSpec, cmd)
+			}
+
+			if err := commandeer.projectConfig.Spec.Validate(); err != nil {
+				return errors.Wrap(err, "Invalid project spec")
+			}
+
 			return createCommandeer.rootCommandeer.platform.CreateProject(&platform.CreateProjectOptions{
 				ProjectConfig: commandeer.projectConfig,
 			})
@@ -92,12 +119,47 @@ func newCreateProjectCommandeer(createCommandeer *createCommandeer) *createProje
 	cmd.Flags().StringVar(&commandeer.projectConfig.Spec.DisplayName, "display-name", "", "Project display name, if different than name")
 	cmd.Flags().MarkDeprecated("display-name", "will be removed on the next major version release") // nolint: errcheck
 	cmd.Flags().StringVar(&commandeer.projectConfig.Spec.Description, "description", "", "Project description")
+	cmd.Flags().StringVar(&commandeer.specFilePath, "spec-file", "", "Path to a YAML/JSON project spec, with the defaults and quotas of the project's functions")
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
+// readProjectSpecFile reads a project spec (e.g. its function defaults and quotas) from a YAML or JSON file
+func readProjectSpecFile(specFilePath string) (*platform.ProjectSpec, error) {
+	specFileContents, err := ioutil.ReadFile(specFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", specFilePath)
+	}
+
+	projectSpec := platform.ProjectSpec{}
+	if err := yaml.Unmarshal(specFileContents, &projectSpec); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode %s", specFilePath)
+	}
+
+	return &projectSpec, nil
+}
+
+// applyProjectSpec sets the fields the spec file sets in the project spec, other than those given as flags
+func applyProjectSpec(projectSpec *platform.ProjectSpec, specFileProjectSpec *platform.ProjectSpec, cmd *cobra.Command) {
+	if specFileProjectSpec.DisplayName != "" && !cmd.Flags().Changed("display-name") {
+		projectSpec.DisplayName = specFileProjectSpec.DisplayName
+	}
+
+	if specFileProjectSpec.Description != "" && !cmd.Flags().Changed("description") {
+		projectSpec.Description = specFileProjectSpec.Description
+	}
+
+	if specFileProjectSpec.Defaults != nil {
+		projectSpec.Defaults = specFileProjectSpec.Defaults
+	}
+
+	if specFileProjectSpec.Quotas != nil {
+		projectSpec.Quotas = specFileProjectSpec.Quotas
+	}
+}
+
 type createFunctionEventCommandeer struct {
 	*createCommandeer
 	functionEventConfig platform.FunctionEventConfig
@@ -159,3 +221,309 @@ func newCreateFunctionEventCommandeer(createCommandeer *createCommandeer) *creat
 
 	return commandeer
 }
+
+type createAPIGatewayCommandeer struct {
+	*createCommandeer
+	apiGatewayConfig   platform.APIGatewayConfig
+	authenticationMode string
+	basicAuthUsername  string
+	basicAuthPassword  string
+	functionName       string
+	canaryFunction     string
+	canaryPercentage   int
+}
+
+func newCreateAPIGatewayCommandeer(createCommandeer *createCommandeer) *createAPIGatewayCommandeer {
+	commandeer := &createAPIGatewayCommandeer{
+		createCommandeer: createCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "apigateway name",
+		Aliases: []string{"agw"},
+		Short:   "Create API gateways",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 1 {
+				return errors.New("API gateway create requires an identifier")
+			}
+
+			if commandeer.functionName == "" {
+				return errors.New("API gateway must route to a function")
+			}
+
+			// initialize root
+			if err := createCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			commandeer.apiGatewayConfig.Meta.Name = args[0]
+			commandeer.apiGatewayConfig.Meta.Namespace = createCommandeer.rootCommandeer.namespace
+			commandeer.apiGatewayConfig.Spec.AuthenticationMode = platform.APIGatewayAuthenticationMode(commandeer.authenticationMode)
+
+			if commandeer.basicAuthUsername != "" || commandeer.basicAuthPassword != "" {
+				commandeer.apiGatewayConfig.Spec.Authentication = &platform.APIGatewayAuthenticationSpec{
+					BasicAuth: &platform.APIGatewayBasicAuth{
+						Username: commandeer.basicAuthUsername,
+						Password: commandeer.basicAuthPassword,
+					},
+				}
+			}
+
+			commandeer.apiGatewayConfig.Spec.Upstreams = []platform.APIGatewayUpstreamSpec{
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: commandeer.functionName},
+				},
+			}
+
+			if commandeer.canaryFunction != "" {
+				commandeer.apiGatewayConfig.Spec.Upstreams = append(commandeer.apiGatewayConfig.Spec.Upstreams,
+					platform.APIGatewayUpstreamSpec{
+						Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+						NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: commandeer.canaryFunction},
+						Percentage:     commandeer.canaryPercentage,
+					})
+			}
+
+			return createCommandeer.rootCommandeer.platform.CreateAPIGateway(&platform.CreateAPIGatewayOptions{
+				APIGatewayConfig: commandeer.apiGatewayConfig,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.apiGatewayConfig.Spec.Host, "host", "", "Host the API gateway serves (optional - default all hosts)")
+	cmd.Flags().StringVar(&commandeer.apiGatewayConfig.Spec.Path, "path", "", "Path the API gateway serves, stripped from the requests passed to the function (optional)")
+	cmd.Flags().StringVar(&commandeer.apiGatewayConfig.Spec.Description, "description", "", "API gateway description")
+	cmd.Flags().StringVar(&commandeer.authenticationMode, "authentication-mode", string(platform.APIGatewayAuthenticationModeNone), "Authentication mode - \"none\" or \"basicAuth\"")
+	cmd.Flags().StringVar(&commandeer.basicAuthUsername, "basic-auth-username", "", "Username for basic authentication")
+	cmd.Flags().StringVar(&commandeer.basicAuthPassword, "basic-auth-password", "", "Password for basic authentication")
+	cmd.Flags().StringVar(&commandeer.functionName, "function", "", "Function to route the requests to")
+	cmd.Flags().StringVar(&commandeer.canaryFunction, "canary-function", "", "Function to route some of the requests to (optional)")
+	cmd.Flags().IntVar(&commandeer.canaryPercentage, "canary-percentage", 0, "Percentage of the requests to route to the canary function")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// the extensions of the handler files scaffolded for each runtime
+var scaffoldRuntimeFileExtensions = map[string]string{
+	"golang":     "go",
+	"python":     "py",
+	"pypy":       "py",
+	"nodejs":     "js",
+	"dotnetcore": "cs",
+	"java":       "java",
+	"ruby":       "rb",
+	"shell":      "sh",
+}
+
+const scaffoldFunctionConfigFileName = "function.yaml"
+
+type createFunctionCommandeer struct {
+	*createCommandeer
+	scaffold                 bool
+	runtime                  string
+	templateName             string
+	templateValues           stringSliceFlag
+	functionTemplatesOptions functionTemplatesOptions
+	outputDir                string
+	projectName              string
+}
+
+func newCreateFunctionCommandeer(createCommandeer *createCommandeer) *createFunctionCommandeer {
+	commandeer := &createFunctionCommandeer{
+		createCommandeer: createCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name (--scaffold | --template name)",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Create a function from a function template - deployed, or as a handler and function.yaml on disk",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 1 {
+				return errors.New("Function create requires an identifier")
+			}
+
+			// without --scaffold the function is deployed, which requires an explicit template
+			if !commandeer.scaffold {
+				if !cmd.Flags().Changed("template") {
+					return errors.New("Function create requires --scaffold or --template, use deploy to create functions from code")
+				}
+
+				// initialize root
+				if err := createCommandeer.rootCommandeer.initialize(); err != nil {
+					return errors.Wrap(err, "Failed to initialize root")
+				}
+
+				return commandeer.deployFunctionFromTemplate(cmd, args[0])
+			}
+
+			if commandeer.runtime == "" {
+				return errors.New("Runtime must be specified (e.g. --runtime python)")
+			}
+
+			outputDir := commandeer.outputDir
+			if outputDir == "" {
+				outputDir = args[0]
+			}
+
+			return commandeer.scaffoldFunction(cmd, args[0], outputDir)
+		},
+	}
+
+	cmd.Flags().BoolVar(&commandeer.scaffold, "scaffold", false, "Write the function's handler and function.yaml to the output directory")
+	cmd.Flags().StringVar(&commandeer.runtime, "runtime", "", "Runtime of the function (e.g. python, golang, dotnetcore)")
+	cmd.Flags().StringVar(&commandeer.templateName, "template", "helloworld", "Name of the function template to start from (see nuctl get templates)")
+	cmd.Flags().Var(&commandeer.templateValues, "set", "Value of a parameter of the function template (key=value), may be repeated")
+	cmd.Flags().StringVar(&commandeer.outputDir, "output-dir", "", "Directory to write the files to (default is a directory named after the function)")
+	cmd.Flags().StringVar(&commandeer.projectName, "project-name", "", "Name of the project the deployed function belongs to")
+	addFunctionTemplatesFlags(cmd, &commandeer.functionTemplatesOptions)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *createFunctionCommandeer) deployFunctionFromTemplate(cmd *cobra.Command, name string) error {
+	functionTemplate, functionConfig, err := c.getRenderedFunctionTemplate()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function template")
+	}
+
+	functionConfig.Meta = functionconfig.Meta{
+		Name:      name,
+		Namespace: c.rootCommandeer.namespace,
+		Labels:    map[string]string{},
+	}
+
+	if c.projectName != "" {
+		functionConfig.Meta.Labels["nuclio.io/project-name"] = c.projectName
+	}
+
+	// keep the version the user asked for, if any
+	if c.runtime != "" {
+		functionConfig.Spec.Runtime = c.runtime
+	}
+
+	c.rootCommandeer.loggerInstance.DebugWith("Deploying function from template",
+		"template", functionTemplate.Name,
+		"functionConfig", functionConfig)
+
+	if _, err := c.rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:         c.rootCommandeer.loggerInstance,
+		FunctionConfig: *functionConfig,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to deploy function")
+	}
+
+	cmd.Printf("Function %s deployed from template %s\n", name, getFunctionTemplateName(functionTemplate))
+
+	return nil
+}
+
+func (c *createFunctionCommandeer) scaffoldFunction(cmd *cobra.Command, name string, outputDir string) error {
+	functionTemplate, renderedFunctionConfig, err := c.getRenderedFunctionTemplate()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function template")
+	}
+
+	functionConfig := *renderedFunctionConfig
+	functionConfig.Meta = functionconfig.Meta{
+		Name: name,
+	}
+
+	// the source code is written next to the function.yaml, rather than inlined in it
+	functionConfig.Spec.Build.FunctionSourceCode = ""
+
+	// keep the version the user asked for, if any
+	functionConfig.Spec.Runtime = c.runtime
+
+	runtimeName, _ := functionConfig.Spec.GetRuntimeNameAndVersion()
+	handlerFileName, handler := getScaffoldHandlerFileName(runtimeName, functionConfig.Spec.Handler)
+	functionConfig.Spec.Handler = handler
+
+	encodedFunctionConfig, err := yaml.Marshal(&functionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode function config")
+	}
+
+	files := map[string][]byte{
+		handlerFileName:                []byte(functionTemplate.SourceCode),
+		scaffoldFunctionConfigFileName: encodedFunctionConfig,
+	}
+
+	// don't write anything if something would be overwritten
+	for fileName := range files {
+		if filePath := filepath.Join(outputDir, fileName); common.FileExists(filePath) {
+			return errors.Errorf("File %s already exists", filePath)
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create output directory %s", outputDir)
+	}
+
+	for _, fileName := range []string{handlerFileName, scaffoldFunctionConfigFileName} {
+		filePath := filepath.Join(outputDir, fileName)
+
+		if err := ioutil.WriteFile(filePath, files[fileName], 0644); err != nil {
+			return errors.Wrapf(err, "Failed to write %s", filePath)
+		}
+
+		cmd.Printf("Wrote %s\n", filePath)
+	}
+
+	return nil
+}
+
+// getRenderedFunctionTemplate returns the requested function template and its function config, rendered with
+// the given values
+func (c *createFunctionCommandeer) getRenderedFunctionTemplate() (*functiontemplates.FunctionTemplate,
+	*functionconfig.Config,
+	error) {
+	loggerInstance, err := c.rootCommandeer.createLogger()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to create logger")
+	}
+
+	functionTemplates, err := fetchFunctionTemplates(loggerInstance, &c.functionTemplatesOptions)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to fetch function templates")
+	}
+
+	functionTemplate, err := findFunctionTemplate(functionTemplates, c.templateName, c.runtime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	functionConfig, err := renderFunctionTemplate(functionTemplate, c.templateValues)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to render function template")
+	}
+
+	return functionTemplate, functionConfig, nil
+}
+
+// getScaffoldHandlerFileName returns the name of the handler file, by the handler's module, and the handler
+// (which is given a module if it has none)
+func getScaffoldHandlerFileName(runtimeName string, handler string) (string, string) {
+	handlerParts := strings.SplitN(handler, ":", 2)
+	if len(handlerParts) == 1 {
+		handlerParts = []string{"main", handler}
+		handler = strings.Join(handlerParts, ":")
+	}
+
+	fileName := handlerParts[0]
+
+	// e.g. shell handlers are given with their extension
+	if extension, found := scaffoldRuntimeFileExtensions[runtimeName]; found && filepath.Ext(fileName) == "" {
+		fileName += "." + extension
+	}
+
+	return fileName, handler
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package command
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
 
 	"github.com/nuclio/errors"
@@ -43,11 +48,15 @@ func newDeleteCommandeer(rootCommandeer *RootCommandeer) *deleteCommandeer {
 	deleteFunctionCommand := newDeleteFunctionCommandeer(commandeer).cmd
 	deleteProjectCommand := newDeleteProjectCommandeer(commandeer).cmd
 	deleteFunctionEventCommand := newDeleteFunctionEventCommandeer(commandeer).cmd
+	deleteAPIGatewayCommand := newDeleteAPIGatewayCommandeer(commandeer).cmd
+	deleteInvocationsCommand := newDeleteInvocationsCommandeer(commandeer).cmd
 
 	cmd.AddCommand(
 		deleteFunctionCommand,
 		deleteProjectCommand,
 		deleteFunctionEventCommand,
+		deleteAPIGatewayCommand,
+		deleteInvocationsCommand,
 	)
 
 	commandeer.cmd = cmd
@@ -55,9 +64,39 @@ func newDeleteCommandeer(rootCommandeer *RootCommandeer) *deleteCommandeer {
 	return commandeer
 }
 
+// deleteResult is the result of a delete with --output json
+type deleteResult struct {
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace"`
+	Deleted   []string          `json:"deleted"`
+	Failed    map[string]string `json:"failed,omitempty"`
+
+	// with --dry-run, nothing is deleted and Deleted holds what would have been
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// renderDeleted renders the result of deleting a single resource, if the output is JSON
+func (d *deleteCommandeer) renderDeleted(cmd *cobra.Command, kind string, namespace string, name string) error {
+	if !d.rootCommandeer.isJSONOutput() {
+		return nil
+	}
+
+	return d.rootCommandeer.renderResult(cmd.OutOrStdout(), &deleteResult{
+		Kind:      kind,
+		Namespace: namespace,
+		Deleted:   []string{name},
+	})
+}
+
 type deleteFunctionCommandeer struct {
 	*deleteCommandeer
 	functionConfig functionconfig.Config
+	group          string
+	labelSelector  string
+	projectName    string
+	all            bool
+	force          bool
+	dryRun         bool
 }
 
 func newDeleteFunctionCommandeer(deleteCommandeer *deleteCommandeer) *deleteFunctionCommandeer {
@@ -67,11 +106,29 @@ func newDeleteFunctionCommandeer(deleteCommandeer *deleteCommandeer) *deleteFunc
 	}
 
 	cmd := &cobra.Command{
-		Use:     "functions [name[:version]]",
+		Use:     "functions [[namespace/]name[:version]]",
 		Aliases: []string{"fu", "fn", "function"},
 		Short:   "(or function) Delete functions",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
+			// functions are deleted either by name or by selection
+			if commandeer.isSelection() {
+				if err := commandeer.validateSelection(args); err != nil {
+					return err
+				}
+
+				// initialize root
+				if err := deleteCommandeer.rootCommandeer.initialize(); err != nil {
+					return errors.Wrap(err, "Failed to initialize root")
+				}
+
+				return commandeer.deleteSelectedFunctions(cmd)
+			}
+
+			if commandeer.dryRun {
+				return errors.New("--dry-run requires selecting functions by --group, --label-selector, --project or --all")
+			}
+
 			// if we got positional arguments
 			if len(args) != 1 {
 				return errors.New("Function delete requires an identifier")
@@ -82,20 +139,171 @@ func newDeleteFunctionCommandeer(deleteCommandeer *deleteCommandeer) *deleteFunc
 				return errors.Wrap(err, "Failed to initialize root")
 			}
 
-			commandeer.functionConfig.Meta.Name = args[0]
-			commandeer.functionConfig.Meta.Namespace = deleteCommandeer.rootCommandeer.namespace
+			namespace, name, err := deleteCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			commandeer.functionConfig.Meta.Name = name
+			commandeer.functionConfig.Meta.Namespace = namespace
 
-			return deleteCommandeer.rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+			if err := deleteCommandeer.rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
 				FunctionConfig: commandeer.functionConfig,
-			})
+			}); err != nil {
+				return err
+			}
+
+			return deleteCommandeer.renderDeleted(cmd, "function", namespace, name)
 		},
 	}
 
+	cmd.Flags().StringVar(&commandeer.group, "group", "", "Delete all the functions deployed as part of this group (deploy --function-group)")
+	cmd.Flags().StringVarP(&commandeer.labelSelector, "label-selector", "l", "", "Delete all the functions with these labels (lbl1=val1[,lbl2=val2,...]); requires --force")
+	cmd.Flags().StringVar(&commandeer.projectName, "project", "", "Delete all the functions of this project; requires --force")
+	cmd.Flags().BoolVar(&commandeer.all, "all", false, "Delete all the functions in the namespace; requires --force")
+	cmd.Flags().BoolVar(&commandeer.force, "force", false, "Delete the functions selected by --label-selector, --project or --all")
+	cmd.Flags().BoolVar(&commandeer.dryRun, "dry-run", false, "List the selected functions without deleting them")
+
+	completeFunctionName(cmd)
+
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
+// isSelection returns whether functions are selected by flags rather than by name
+func (d *deleteFunctionCommandeer) isSelection() bool {
+	return d.group != "" || d.labelSelector != "" || d.projectName != "" || d.all
+}
+
+func (d *deleteFunctionCommandeer) validateSelection(args []string) error {
+	if len(args) != 0 {
+		return errors.New("Function delete requires either an identifier or a selection (--group, --label-selector, --project or --all), not both")
+	}
+
+	if d.all && (d.group != "" || d.labelSelector != "") {
+		return errors.New("--all can't be given along with --group or --label-selector")
+	}
+
+	if d.force && d.dryRun {
+		return errors.New("--force and --dry-run are mutually exclusive")
+	}
+
+	// a group is deployed together, so it may be deleted together. any wider selection must be confirmed
+	if !d.force && !d.dryRun && (d.labelSelector != "" || d.projectName != "" || d.all) {
+		return errors.New("Deleting functions by --label-selector, --project or --all requires --force (use --dry-run to list the functions that would be deleted)")
+	}
+
+	return nil
+}
+
+// getLabelSelector returns the labels the selected functions must have
+func (d *deleteFunctionCommandeer) getLabelSelector() (map[string]string, error) {
+	labels := map[string]string{}
+
+	if d.labelSelector != "" {
+		for _, label := range strings.Split(d.labelSelector, ",") {
+			labelNameAndValue := strings.SplitN(label, "=", 2)
+			if len(labelNameAndValue) != 2 || labelNameAndValue[0] == "" {
+				return nil, errors.Errorf("Label selector must be of the form lbl1=val1[,lbl2=val2,...], got %s", d.labelSelector)
+			}
+
+			labels[labelNameAndValue[0]] = labelNameAndValue[1]
+		}
+	}
+
+	if d.projectName != "" {
+		labels["nuclio.io/project-name"] = d.projectName
+	}
+
+	return labels, nil
+}
+
+// deleteSelectedFunctions deletes all the selected functions. a function that fails to be deleted doesn't
+// prevent deleting the others
+func (d *deleteFunctionCommandeer) deleteSelectedFunctions(cmd *cobra.Command) error {
+	rootCommandeer := d.rootCommandeer
+
+	labels, err := d.getLabelSelector()
+	if err != nil {
+		return err
+	}
+
+	functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Namespace: rootCommandeer.namespace,
+		Labels:    common.FormatLabelSelector(labels),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get functions")
+	}
+
+	result := deleteResult{
+		Kind:      "function",
+		Namespace: rootCommandeer.namespace,
+		Deleted:   []string{},
+		Failed:    map[string]string{},
+		DryRun:    d.dryRun,
+	}
+
+	// not all platforms filter by any label, so the selection is applied here as well
+	functions = common.FilterFunctionsByLabels(functions, labels)
+
+	if d.group != "" {
+		functions = common.FilterFunctionsByGroup(functions, d.group)
+	}
+
+	if len(functions) == 0 {
+		if rootCommandeer.isJSONOutput() {
+			return rootCommandeer.renderResult(cmd.OutOrStdout(), &result)
+		}
+
+		if d.group != "" && len(labels) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "No functions found in group %s\n", d.group) // nolint: errcheck
+			return nil
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), "No matching functions found") // nolint: errcheck
+		return nil
+	}
+
+	sort.Slice(functions, func(first, second int) bool {
+		return functions[first].GetConfig().Meta.Name < functions[second].GetConfig().Meta.Name
+	})
+
+	for _, function := range functions {
+		functionName := function.GetConfig().Meta.Name
+
+		if d.dryRun {
+			fmt.Fprintf(rootCommandeer.getProgressWriter(cmd), "Function %s would be deleted\n", functionName) // nolint: errcheck
+			result.Deleted = append(result.Deleted, functionName)
+			continue
+		}
+
+		if err := rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+			FunctionConfig: *function.GetConfig(),
+		}); err != nil {
+			fmt.Fprintf(rootCommandeer.getProgressWriter(cmd), "Function %s failed to delete: %s\n", functionName, err.Error()) // nolint: errcheck
+			result.Failed[functionName] = err.Error()
+			continue
+		}
+
+		fmt.Fprintf(rootCommandeer.getProgressWriter(cmd), "Function %s deleted\n", functionName) // nolint: errcheck
+		result.Deleted = append(result.Deleted, functionName)
+	}
+
+	if rootCommandeer.isJSONOutput() {
+		if err := rootCommandeer.renderResult(cmd.OutOrStdout(), &result); err != nil {
+			return errors.Wrap(err, "Failed to render result")
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		return errors.Errorf("Failed to delete %d of %d functions", len(result.Failed), len(functions))
+	}
+
+	return nil
+}
+
 type deleteProjectCommandeer struct {
 	*deleteCommandeer
 	projectMeta platform.ProjectMeta
@@ -107,7 +315,7 @@ func newDeleteProjectCommandeer(deleteCommandeer *deleteCommandeer) *deleteProje
 	}
 
 	cmd := &cobra.Command{
-		Use:     "projects name",
+		Use:     "projects [namespace/]name",
 		Aliases: []string{"proj", "prj", "project"},
 		Short:   "(or project) Delete projects",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -122,15 +330,26 @@ func newDeleteProjectCommandeer(deleteCommandeer *deleteCommandeer) *deleteProje
 				return errors.Wrap(err, "Failed to initialize root")
 			}
 
-			commandeer.projectMeta.Name = args[0]
-			commandeer.projectMeta.Namespace = deleteCommandeer.rootCommandeer.namespace
+			namespace, name, err := deleteCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			commandeer.projectMeta.Name = name
+			commandeer.projectMeta.Namespace = namespace
 
-			return deleteCommandeer.rootCommandeer.platform.DeleteProject(&platform.DeleteProjectOptions{
+			if err := deleteCommandeer.rootCommandeer.platform.DeleteProject(&platform.DeleteProjectOptions{
 				Meta: commandeer.projectMeta,
-			})
+			}); err != nil {
+				return err
+			}
+
+			return deleteCommandeer.renderDeleted(cmd, "project", namespace, name)
 		},
 	}
 
+	completeProjectName(cmd)
+
 	commandeer.cmd = cmd
 
 	return commandeer
@@ -165,12 +384,119 @@ func newDeleteFunctionEventCommandeer(deleteCommandeer *deleteCommandeer) *delet
 			commandeer.functionEventMeta.Name = args[0]
 			commandeer.functionEventMeta.Namespace = deleteCommandeer.rootCommandeer.namespace
 
-			return deleteCommandeer.rootCommandeer.platform.DeleteFunctionEvent(&platform.DeleteFunctionEventOptions{
+			if err := deleteCommandeer.rootCommandeer.platform.DeleteFunctionEvent(&platform.DeleteFunctionEventOptions{
 				Meta: commandeer.functionEventMeta,
-			})
+			}); err != nil {
+				return err
+			}
+
+			return deleteCommandeer.renderDeleted(cmd,
+				"functionevent",
+				commandeer.functionEventMeta.Namespace,
+				commandeer.functionEventMeta.Name)
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type deleteAPIGatewayCommandeer struct {
+	*deleteCommandeer
+	apiGatewayMeta platform.APIGatewayMeta
+}
+
+func newDeleteAPIGatewayCommandeer(deleteCommandeer *deleteCommandeer) *deleteAPIGatewayCommandeer {
+	commandeer := &deleteAPIGatewayCommandeer{
+		deleteCommandeer: deleteCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "apigateways [namespace/]name",
+		Aliases: []string{"agw", "apigateway"},
+		Short:   "(or apigateway) Delete API gateway",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 1 {
+				return errors.New("API gateway delete requires an identifier")
+			}
+
+			// initialize root
+			if err := deleteCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			namespace, name, err := deleteCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			commandeer.apiGatewayMeta.Name = name
+			commandeer.apiGatewayMeta.Namespace = namespace
+
+			if err := deleteCommandeer.rootCommandeer.platform.DeleteAPIGateway(&platform.DeleteAPIGatewayOptions{
+				Meta: commandeer.apiGatewayMeta,
+			}); err != nil {
+				return err
+			}
+
+			return deleteCommandeer.renderDeleted(cmd, "apigateway", namespace, name)
 		},
 	}
 
+	completeAPIGatewayName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type deleteInvocationsCommandeer struct {
+	*deleteCommandeer
+}
+
+func newDeleteInvocationsCommandeer(deleteCommandeer *deleteCommandeer) *deleteInvocationsCommandeer {
+	commandeer := &deleteInvocationsCommandeer{
+		deleteCommandeer: deleteCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "invocations [namespace/]function",
+		Aliases: []string{"inv", "invocation"},
+		Short:   "(or invocation) Purge the invocations retained by a function's invocation inspector",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 1 {
+				return errors.New("Invocations delete requires a function name")
+			}
+
+			// initialize root
+			if err := deleteCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			namespace, name, err := deleteCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := deleteCommandeer.rootCommandeer.platform.DeleteFunctionInvocations(
+				&platform.DeleteFunctionInvocationsOptions{
+					Name:      name,
+					Namespace: namespace,
+				}); err != nil {
+				return err
+			}
+
+			return deleteCommandeer.renderDeleted(cmd, "invocations", namespace, name)
+		},
+	}
+
+	completeFunctionName(cmd)
+
 	commandeer.cmd = cmd
 
 	return commandeer
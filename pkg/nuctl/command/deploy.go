@@ -17,17 +17,24 @@ limitations under the License.
 package command
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	nuctl_common "github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/abstract"
+	"github.com/nuclio/nuclio/pkg/processor/build"
+	"github.com/nuclio/nuclio/pkg/renderer"
 
 	"github.com/nuclio/errors"
 	"github.com/spf13/cobra"
@@ -35,6 +42,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+// the name of the readiness dependency --readiness-check-path adds
+const readinessCheckDependencyName = "readiness-check"
+
 type deployCommandeer struct {
 	cmd                             *cobra.Command
 	rootCommandeer                  *RootCommandeer
@@ -42,6 +52,10 @@ type deployCommandeer struct {
 	functionBuild                   functionconfig.Build
 	functionName                    string
 	functionConfigPath              string
+	templateValues                  stringSliceFlag
+	templateValueFiles              stringSliceFlag
+	allowMissingTemplateValues      bool
+	renderedFunctionConfig          []byte
 	description                     string
 	disable                         bool
 	publish                         bool
@@ -51,7 +65,17 @@ type deployCommandeer struct {
 	targetCPU                       int
 	runRegistry                     string
 	readinessTimeoutSeconds         int
+	readinessCheckPath              string
+	readinessCheckPeriodSeconds     int
+	warmupPath                      string
+	warmupBody                      string
+	warmupCount                     int
+	warmupConcurrency               int
 	volumes                         stringSliceFlag
+	secretFileMounts                stringSliceFlag
+	secretMountsSupportedPlatforms  []string
+	envFromSecrets                  stringSliceFlag
+	volumesFromSecrets              stringSliceFlag
 	commands                        stringSliceFlag
 	encodedDataBindings             string
 	encodedTriggers                 string
@@ -60,8 +84,21 @@ type deployCommandeer struct {
 	projectName                     string
 	resourceLimits                  stringSliceFlag
 	resourceRequests                stringSliceFlag
+	resourcePreset                  string
+	gpuCount                        float64
+	gpuSharing                      string
+	schedulingPreset                string
+	fromImage                       string
+	measure                         bool
+	jsonEvents                      bool
+	watch                           bool
 	encodedEnv                      stringSliceFlag
+	envFiles                        stringSliceFlag
+	expandEnv                       bool
+	encodedFunctionReferences       stringSliceFlag
 	encodedFunctionPlatformConfig   string
+	network                         string
+	extraHosts                      stringSliceFlag
 	encodedBuildRuntimeAttributes   string
 	encodedBuildCodeEntryAttributes string
 	inputImageFile                  string
@@ -69,25 +106,227 @@ type deployCommandeer struct {
 	replicas                        int
 	minReplicas                     int
 	maxReplicas                     int
+	reportFilePath                  string
+	buildRetries                    int
+	buildRetryOnTransientOnly       bool
+	pruneOldImages                  int
+	pruneImagesSupportedPlatforms   []string
+	httpCORSAllowOrigin             string
+	httpCORSAllowMethods            stringSliceFlag
+	httpCORSAllowHeaders            stringSliceFlag
+	deprecate                       bool
+	deprecationMessage              string
+	deprecationDate                 string
+	functionGroup                   string
+	sourceChecksum                  string
+	registryCredentials             registryCredentials
+	blueGreen                       bool
+	blueGreenHealthPath             string
+	blueGreenSupportedPlatforms     []string
+	canaryWeight                    int
+	canarySupportedPlatforms        []string
+	stackOnly                       stringSliceFlag
+	stackSkip                       stringSliceFlag
+	stackConcurrency                int
+	buildRemotely                   bool
+	dashboardURL                    string
+	interactive                     bool
+}
+
+// deployReport is a structured record of a deploy's outcome, written when --report-file is given and as the
+// result of the deploy with --output json
+type deployReport struct {
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace"`
+	State     string              `json:"state,omitempty"`
+	Image     string              `json:"image,omitempty"`
+	Digest    string              `json:"digest,omitempty"`
+	Signature string              `json:"signature,omitempty"`
+	Attempts  int                 `json:"buildAttempts,omitempty"`
+	URL       string              `json:"url,omitempty"`
+	Timings   deployReportTimings `json:"timings"`
+	Phases    []deployReportPhase `json:"phases,omitempty"`
+	Warnings  []string            `json:"warnings,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type deployReportTimings struct {
+	BuildSeconds     float64 `json:"buildSeconds"`
+	ReadinessSeconds float64 `json:"readinessSeconds"`
+	TotalSeconds     float64 `json:"totalSeconds"`
+}
+
+type deployReportPhase struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
 }
 
 func newDeployCommandeer(rootCommandeer *RootCommandeer) *deployCommandeer {
 	commandeer := &deployCommandeer{
 		rootCommandeer: rootCommandeer,
 		functionConfig: *functionconfig.NewConfig(),
+
+		// switching over to a new version requires updating functions in place
+		blueGreenSupportedPlatforms: kubePlatforms,
+
+		// the ingress controller splits the traffic to canaries
+		canarySupportedPlatforms: kubePlatforms,
+
+		// only the local platform keeps the function images around
+		pruneImagesSupportedPlatforms: []string{"local"},
+
+		// secret volumes are mounted by kubernetes
+		secretMountsSupportedPlatforms: kubePlatforms,
 	}
 
 	cmd := &cobra.Command{
-		Use:   "deploy function-name",
+		Use:   "deploy function-name | deploy -f stack-manifest",
 		Short: "Build and deploy a function, or deploy from an existing image",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 
+			if rootCommandeer.output != nuctl_common.OutputFormatText && rootCommandeer.output != nuctl_common.OutputFormatJSON {
+				return errors.Errorf("Invalid output format %s, must be one of: %s, %s",
+					rootCommandeer.output,
+					nuctl_common.OutputFormatText,
+					nuctl_common.OutputFormatJSON)
+			}
+
+			if commandeer.pruneOldImages < 0 {
+				return errors.New("Number of images to keep when pruning must not be negative")
+			}
+
+			if commandeer.functionConfigPath != "" && isDeployStack(commandeer.functionConfigPath) {
+				if len(args) > 0 {
+					return errors.New("Function name can't be provided when deploying a stack")
+				}
+
+				if commandeer.blueGreen || cmd.Flags().Changed("canary") || commandeer.watch || commandeer.jsonEvents ||
+					commandeer.measure || commandeer.reportFilePath != "" || commandeer.pruneOldImages > 0 ||
+					len(commandeer.templateValues) > 0 || len(commandeer.templateValueFiles) > 0 ||
+					commandeer.buildRemotely || commandeer.interactive {
+					return errors.New("--blue-green, --canary, --watch, --json-events, --measure, --report-file, " +
+						"--prune-old-images, --set, --set-file, --build-remotely and --interactive can't be used " +
+						"when deploying a stack")
+				}
+
+				if err := rootCommandeer.initialize(); err != nil {
+					return errors.Wrap(err, "Failed to initialize root")
+				}
+
+				return commandeer.deployStack(cmd)
+			}
+
+			if len(commandeer.stackOnly) > 0 || len(commandeer.stackSkip) > 0 {
+				return errors.New("--only and --skip can only be used when deploying a stack (--file)")
+			}
+
+			// prompt for what wasn't given, the name first since much depends on whether it was
+			var wizard *deployWizard
+			if commandeer.interactive {
+				if commandeer.jsonEvents || rootCommandeer.isJSONOutput() {
+					return errors.New("--interactive can't be used with --json-events or --output json")
+				}
+
+				wizard = newDeployWizard(cmd.InOrStdin(), cmd.OutOrStdout())
+				if len(args) == 0 {
+					functionName, err := wizard.promptFunctionName()
+					if err != nil {
+						return errors.Wrap(err, "Failed to prompt for the function name")
+					}
+
+					args = []string{functionName}
+				}
+			}
+
+			if commandeer.blueGreen {
+				if len(args) != 1 {
+					return errors.New("Function name must be provided for a blue/green deploy")
+				}
+
+				if commandeer.measure || commandeer.reportFilePath != "" || commandeer.pruneOldImages > 0 {
+					return errors.New("--measure, --report-file and --prune-old-images can't be used with --blue-green")
+				}
+			}
+
+			if commandeer.buildRemotely {
+				if len(args) != 1 {
+					return errors.New("Function name must be provided to build remotely")
+				}
+
+				if commandeer.dashboardURL == "" {
+					return errors.New("Dashboard URL must be provided to build remotely " +
+						"(--dashboard-url, NUCTL_DASHBOARD_URL or the context's dashboard URL)")
+				}
+
+				if commandeer.blueGreen || cmd.Flags().Changed("canary") || commandeer.watch ||
+					commandeer.jsonEvents || commandeer.measure || commandeer.reportFilePath != "" ||
+					commandeer.pruneOldImages > 0 || commandeer.inputImageFile != "" || rootCommandeer.isJSONOutput() {
+					return errors.New("--blue-green, --canary, --watch, --json-events, --measure, --report-file, " +
+						"--prune-old-images, --input-image-file and --output json can't be used with --build-remotely")
+				}
+			}
+
+			if cmd.Flags().Changed("canary") {
+				if commandeer.canaryWeight < 1 || commandeer.canaryWeight > 99 {
+					return errors.New("Percentage of traffic to send to the canary must be between 1 and 99")
+				}
+
+				if len(args) != 1 {
+					return errors.New("Function name must be provided for a canary deploy")
+				}
+
+				if commandeer.blueGreen || commandeer.measure || commandeer.reportFilePath != "" ||
+					commandeer.pruneOldImages > 0 {
+					return errors.New("--blue-green, --measure, --report-file and --prune-old-images can't be used with --canary")
+				}
+			}
+
+			if (len(commandeer.templateValues) > 0 || len(commandeer.templateValueFiles) > 0) &&
+				commandeer.functionConfigPath == "" {
+				return errors.New("--set and --set-file require a function config file (--file)")
+			}
+
+			if rootCommandeer.isJSONOutput() && (commandeer.blueGreen || commandeer.canaryWeight > 0) {
+				return errors.New("--output json can't be used with --blue-green or --canary")
+			}
+
+			if commandeer.watch && (commandeer.blueGreen || commandeer.canaryWeight > 0 || commandeer.jsonEvents ||
+				rootCommandeer.isJSONOutput()) {
+				return errors.New("--watch can't be used with --blue-green, --canary, --json-events or --output json")
+			}
+
+			// the events are the only output, logs included
+			var eventWriter *deployEventWriter
+			if commandeer.jsonEvents {
+				if commandeer.measure || commandeer.blueGreen || commandeer.canaryWeight > 0 ||
+					rootCommandeer.isJSONOutput() {
+					return errors.New("--json-events can't be used with --measure, --blue-green, --canary or --output json")
+				}
+
+				eventWriter = newDeployEventWriter(cmd.OutOrStdout())
+				rootCommandeer.jsonLogSink = eventWriter
+			}
+
 			// initialize root
 			if err := rootCommandeer.initialize(); err != nil {
 				return errors.Wrap(err, "Failed to initialize root")
 			}
 
+			if commandeer.pruneOldImages > 0 {
+				if err := rootCommandeer.validateOperationPlatform("deploy --prune-old-images",
+					commandeer.pruneImagesSupportedPlatforms); err != nil {
+					return err
+				}
+			}
+
+			if len(commandeer.secretFileMounts) > 0 {
+				if err := rootCommandeer.validateOperationPlatform("deploy --mount-secret-as-file",
+					commandeer.secretMountsSupportedPlatforms); err != nil {
+					return err
+				}
+			}
+
 			var importedFunction platform.Function
 
 			// update build stuff
@@ -117,6 +356,17 @@ func newDeployCommandeer(rootCommandeer *RootCommandeer) *deployCommandeer {
 					return errors.Wrap(err, "Failed reading function config file")
 				}
 
+				if len(commandeer.templateValues) > 0 || len(commandeer.templateValueFiles) > 0 {
+					functionBody, err = commandeer.renderFunctionConfig(functionBody)
+					if err != nil {
+						return errors.Wrap(err, "Failed to render function config file")
+					}
+
+					// the builder reads the config file too, so it's given the rendered contents. the function
+					// keeps recording the path of the template
+					commandeer.renderedFunctionConfig = functionBody
+				}
+
 				unmarshalFunc, err := nuctl_common.GetUnmarshalFunc(functionBody)
 				if err != nil {
 					return errors.Wrap(err, "Failed identifying function config file format")
@@ -134,6 +384,12 @@ func newDeployCommandeer(rootCommandeer *RootCommandeer) *deployCommandeer {
 			// if the spec was brought from a file or from an already imported function.
 			commandeer.populateDeploymentDefaults()
 
+			if wizard != nil {
+				if err := wizard.promptFunctionSpec(commandeer); err != nil {
+					return errors.Wrap(err, "Failed to prompt for the function configuration")
+				}
+			}
+
 			// Override basic fields from the config
 			commandeer.functionConfig.Meta.Name = commandeer.functionName
 			commandeer.functionConfig.Meta.Namespace = rootCommandeer.namespace
@@ -151,23 +407,186 @@ func newDeployCommandeer(rootCommandeer *RootCommandeer) *deployCommandeer {
 				return errors.Wrap(err, "Failed config with complex args")
 			}
 
+			if err := commandeer.enrichConfigWithFromImage(); err != nil {
+				return errors.Wrap(err, "Failed to deploy from image")
+			}
+
+			// report all the problems in the configuration at once, rather than have the platform fail on the first
+			if err := commandeer.functionConfig.Validate(); err != nil {
+				return errors.Wrap(err, "Invalid function configuration")
+			}
+
+			if err := prepareOfflineBuild(&commandeer.functionConfig.Spec.Build); err != nil {
+				return err
+			}
+
 			// Ensure the skip-annotations never exist on deploy
 			commandeer.functionConfig.Meta.RemoveSkipBuildAnnotation()
 			commandeer.functionConfig.Meta.RemoveSkipDeployAnnotation()
 
+			// if the path points at a remote git repository, clone it and build from the clone
+			cloneDir, err := commandeer.resolveGitFunctionPath()
+			if err != nil {
+				return errors.Wrap(err, "Failed to fetch function source from git")
+			}
+			if cloneDir != "" && !commandeer.functionConfig.Spec.Build.NoCleanup {
+				defer os.RemoveAll(cloneDir) // nolint: errcheck
+			}
+
+			if err := commandeer.verifySourceChecksum(); err != nil {
+				return errors.Wrap(err, "Failed to verify function source")
+			}
+
+			// the dashboard builds the function in-cluster and pushes it to its registry, unless given another
+			if commandeer.buildRemotely {
+				return commandeer.deployRemotely(cmd)
+			}
+
+			// prebuilt images aren't pushed
+			if commandeer.functionConfig.Spec.Build.Mode != functionconfig.NeverBuild {
+				if err := commandeer.registryCredentials.logIn(rootCommandeer,
+					commandeer.functionConfig.Spec.Build.Registry); err != nil {
+					return errors.Wrap(err, "Failed to log in to registry")
+				}
+			}
+
+			if commandeer.blueGreen {
+				return commandeer.deployBlueGreen(cmd)
+			}
+
+			if commandeer.canaryWeight > 0 {
+				return commandeer.deployCanary(cmd)
+			}
+
 			commandeer.rootCommandeer.loggerInstance.DebugWith("Deploying function", "functionConfig", commandeer.functionConfig)
-			_, err = rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+			var phaseTimings *common.PhaseTimings
+			if commandeer.measure {
+				phaseTimings = &common.PhaseTimings{}
+			}
+
+			buildOutputLineHandler := func(line string) {
+				fmt.Fprintln(rootCommandeer.getProgressWriter(cmd), line) // nolint: errcheck
+			}
+
+			if eventWriter != nil {
+				phaseTimings = &common.PhaseTimings{
+					RecordHandler: eventWriter.writePhase,
+				}
+				buildOutputLineHandler = eventWriter.writeBuildOutput
+
+				eventWriter.writeState(functionconfig.FunctionStateBuilding, "", 0, nil)
+			}
+
+			var watcher *deployWatcher
+			if commandeer.watch {
+				watcher = newDeployWatcher(cmd.OutOrStdout(),
+					rootCommandeer.platform,
+					commandeer.functionConfig.Meta.Name,
+					commandeer.functionConfig.Meta.Namespace)
+
+				phaseTimings = watcher.getPhaseTimings()
+				buildOutputLineHandler = watcher.writeBuildOutput
+			}
+
+			deployStartTime := time.Now()
+			createFunctionResult, err := rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
 				Logger:         rootCommandeer.loggerInstance,
 				FunctionConfig: commandeer.functionConfig,
 				InputImageFile: commandeer.inputImageFile,
+				PhaseTimings:   phaseTimings,
+				PruneOldImages: commandeer.pruneOldImages,
+
+				FunctionConfigContents: commandeer.renderedFunctionConfig,
+
+				BuildRetries:              commandeer.buildRetries,
+				BuildRetryOnTransientOnly: commandeer.buildRetryOnTransientOnly,
+
+				// stream the build output as it happens, rather than after the build is done
+				BuildOutputLineHandler: buildOutputLineHandler,
 			})
 
+			if watcher != nil {
+				watcher.stop(err)
+			}
+
+			if eventWriter != nil {
+				if err != nil {
+					eventWriter.writeState(functionconfig.FunctionStateError, "", 0, err)
+				} else {
+					eventWriter.writeState(functionconfig.FunctionStateReady,
+						createFunctionResult.Image,
+						createFunctionResult.Port,
+						nil)
+				}
+			}
+
+			if err == nil && createFunctionResult.BuildAttempts > 1 {
+				rootCommandeer.loggerInstance.InfoWith("Function built after retrying",
+					"attempts", createFunctionResult.BuildAttempts)
+			}
+
+			if err == nil && commandeer.pruneOldImages > 0 {
+				rootCommandeer.loggerInstance.InfoWith("Pruned old function images",
+					"images", createFunctionResult.PrunedImages,
+					"reclaimed", common.FormatBytes(createFunctionResult.ReclaimedImageBytes))
+			}
+
+			// measurements of a failed deploy show where it got stuck, so render them regardless of the outcome.
+			// with JSON output, they're part of the result
+			if commandeer.measure && !rootCommandeer.isJSONOutput() {
+				if renderErr := commandeer.renderPhaseTimings(cmd.OutOrStdout(),
+					phaseTimings.GetPhases(),
+					time.Since(deployStartTime)); renderErr != nil {
+					rootCommandeer.loggerInstance.WarnWith("Failed to render deploy phase timings",
+						"err", renderErr.Error())
+				}
+			}
+
+			// write the report regardless of the outcome, failed deploys are of most interest
+			if commandeer.reportFilePath != "" {
+				if reportErr := commandeer.writeReport(commandeer.createReport(createFunctionResult,
+					err,
+					phaseTimings,
+					time.Since(deployStartTime))); reportErr != nil {
+					rootCommandeer.loggerInstance.WarnWith("Failed to write deploy report",
+						"path", commandeer.reportFilePath,
+						"err", reportErr.Error())
+				}
+			}
+
+			// the error is rendered as the result of the command
+			if err == nil && rootCommandeer.isJSONOutput() {
+				return rootCommandeer.renderResult(cmd.OutOrStdout(), commandeer.createReport(createFunctionResult,
+					err,
+					phaseTimings,
+					time.Since(deployStartTime)))
+			}
+
 			return err
 		},
 	}
 
 	addDeployFlags(cmd, commandeer)
 	cmd.Flags().StringVarP(&commandeer.inputImageFile, "input-image-file", "", "", "Path to input of docker archive")
+	cmd.Flags().StringVar(&commandeer.sourceChecksum, "source-checksum", "", "Expected sha256 checksum ([sha256:]<hex>) of the function source (archive, file, directory or --source), verified before building")
+	cmd.Flags().StringVar(&commandeer.reportFilePath, "report-file", "", "Path to which a JSON report of the deploy (timings, state, image, URL and warnings) is written")
+	addBuildRetryFlags(cmd, &commandeer.buildRetries, &commandeer.buildRetryOnTransientOnly)
+	addRegistryCredentialsFlags(cmd, &commandeer.registryCredentials)
+	cmd.Flags().IntVar(&commandeer.pruneOldImages, "prune-old-images", 0, "After a successful deploy, remove all but the N most recent images of the function (local platform only)")
+	cmd.Flags().BoolVar(&commandeer.blueGreen, "blue-green", false, "Deploy a new version as a standby alongside the live one, switch the ingresses and API gateways over to it once it's healthy and update the function to it in place (the slot is annotated as "+blueGreenSlotAnnotation+")")
+	cmd.Flags().IntVar(&commandeer.canaryWeight, "canary", 0, "Deploy a canary alongside the function, sending it this percentage (1-99) of the traffic to the function's ingresses, until it's promoted or rolled back (kube only, using nginx ingress canaries)")
+	cmd.Flags().StringVar(&commandeer.blueGreenHealthPath, "blue-green-health-path", "/", "Path the new version must respond to successfully (GET) before switching over to it (with --blue-green)")
+	cmd.Flags().BoolVar(&commandeer.measure, "measure", false, "Print the duration of each deploy phase (context archiving, build, push, platform apply, readiness wait)")
+	cmd.Flags().BoolVar(&commandeer.watch, "watch", false, "Show the progress of the deploy as it happens - its stages, the build output and the processor logs. If the deploy fails, the output of the failing stage is shown again")
+	cmd.Flags().BoolVar(&commandeer.jsonEvents, "json-events", false, "Write the progress of the deploy (state transitions, phases, build output and logs) to stdout as JSON, one event per line, until the function is ready")
+	cmd.Flags().Var(&commandeer.stackOnly, "only", "Deploy only these functions of the stack (--file of a stack manifest), may be comma-separated or repeated")
+	cmd.Flags().Var(&commandeer.stackSkip, "skip", "Don't deploy these functions of the stack (--file of a stack manifest), may be comma-separated or repeated")
+	cmd.Flags().BoolVar(&commandeer.buildRemotely, "build-remotely", false, "Upload the function source to the dashboard, which builds the function in-cluster and deploys it - no local docker daemon or registry access is needed")
+	cmd.Flags().StringVar(&commandeer.dashboardURL, "dashboard-url", os.Getenv("NUCTL_DASHBOARD_URL"), "URL of the nuclio dashboard functions are built by (with --build-remotely, env: NUCTL_DASHBOARD_URL)")
+	cmd.Flags().BoolVar(&commandeer.interactive, "interactive", false, "Prompt for the function name, source, runtime, handler and triggers that weren't given, for first-time users")
+	cmd.Flags().IntVar(&commandeer.stackConcurrency, "concurrency", defaultDeployStackConcurrency, "Maximal number of functions of the stack (--file of a stack manifest) to deploy concurrently")
+
+	completeFunctionName(cmd)
 
 	commandeer.cmd = cmd
 
@@ -181,6 +600,9 @@ func addDeployFlags(cmd *cobra.Command,
 	cmd.Flags().StringVar(&commandeer.description, "desc", "", "Function description")
 	cmd.Flags().StringVarP(&commandeer.encodedLabels, "labels", "l", "", "Additional function labels (lbl1=val1[,lbl2=val2,...])")
 	cmd.Flags().VarP(&commandeer.encodedEnv, "env", "e", "Environment variables env1=val1")
+	cmd.Flags().Var(&commandeer.envFiles, "env-file", "Set the environment variables of an env file (e.g. .env), may be repeated - later files override earlier ones, and --env overrides them all")
+	cmd.Flags().BoolVar(&commandeer.expandEnv, "env-expand", false, "Replace ${VAR} in the values of --env and --env-file with the variables of nuctl's environment")
+	cmd.Flags().Var(&commandeer.encodedFunctionReferences, "function-ref", "Functions the function calls, resolved to their URLs in NUCLIO_FUNCTION_URL_<ALIAS> (alias=[project/]function)")
 	cmd.Flags().BoolVarP(&commandeer.disable, "disable", "d", false, "Start the function as disabled (don't run yet)")
 	cmd.Flags().IntVarP(&commandeer.replicas, "replicas", "", -1, "Set to any non-negative integer to use a static number of replicas")
 	cmd.Flags().IntVar(&commandeer.minReplicas, "min-replicas", -1, "Minimal number of function replicas")
@@ -189,15 +611,41 @@ func addDeployFlags(cmd *cobra.Command,
 	cmd.Flags().BoolVar(&commandeer.publish, "publish", false, "Publish the function")
 	cmd.Flags().StringVar(&commandeer.encodedDataBindings, "data-bindings", "", "JSON-encoded data bindings for the function")
 	cmd.Flags().StringVar(&commandeer.encodedTriggers, "triggers", "", "JSON-encoded triggers for the function")
+	cmd.Flags().StringVar(&commandeer.httpCORSAllowOrigin, "http-cors-allow-origin", "", "Origin allowed by the http trigger's CORS policy (\"*\" or scheme://host[:port])")
+	cmd.Flags().Var(&commandeer.httpCORSAllowMethods, "http-cors-allow-methods", "HTTP methods allowed by the http trigger's CORS policy (GET[,POST,...])")
+	cmd.Flags().Var(&commandeer.httpCORSAllowHeaders, "http-cors-allow-headers", "Headers allowed by the http trigger's CORS policy (hdr1[,hdr2,...])")
 	cmd.Flags().StringVar(&commandeer.encodedFunctionPlatformConfig, "platform-config", "", "JSON-encoded platform specific configuration")
+	cmd.Flags().StringVar(&commandeer.network, "network", "", "Existing docker network the function's container joins, e.g. that of a docker-compose stack (local platform)")
+	cmd.Flags().Var(&commandeer.extraHosts, "add-host", "Add a host to the function container's /etc/hosts (host:ip), may be repeated (local platform)")
 	cmd.Flags().StringVar(&commandeer.image, "run-image", "", "Name of an existing image to deploy (default - build a new image to deploy)")
+	cmd.Flags().StringVar(&commandeer.fromImage, "from-image", "", "Name of a prebuilt processor image to deploy, skipping the build entirely")
 	cmd.Flags().StringVar(&commandeer.runRegistry, "run-registry", "", "URL of a registry for pulling the image, if differs from -r/--registry (env: NUCTL_RUN_REGISTRY)")
 	cmd.Flags().StringVar(&commandeer.encodedRuntimeAttributes, "runtime-attrs", "", "JSON-encoded runtime attributes for the function")
 	cmd.Flags().IntVar(&commandeer.readinessTimeoutSeconds, "readiness-timeout", -1, "maximum wait time for the function to be ready")
+	cmd.Flags().StringVar(&commandeer.readinessCheckPath, "readiness-check-path", "", "Path of the function's HTTP trigger it must respond to successfully (GET) to be ready (added as a handler readiness dependency)")
+	cmd.Flags().IntVar(&commandeer.readinessCheckPeriodSeconds, "readiness-check-period", 0, "Seconds between checks of the function's readiness dependencies (default 5)")
+	cmd.Flags().StringVar(&commandeer.warmupPath, "warmup-path", "", "Path requested from each replica of the function once it's ready, to warm it up")
+	cmd.Flags().StringVar(&commandeer.warmupBody, "warmup-body", "", "Body of the warmup requests (sent with POST when set)")
+	cmd.Flags().IntVar(&commandeer.warmupCount, "warmup-count", 0, "Number of warmup requests sent to each replica (default 1)")
+	cmd.Flags().IntVar(&commandeer.warmupConcurrency, "warmup-concurrency", 0, "Number of warmup requests sent at the same time (default 1)")
 	cmd.Flags().StringVar(&commandeer.projectName, "project-name", "", "name of project to which this function belongs to")
+	cmd.Flags().Var(&commandeer.templateValues, "set", "Value to substitute for a variable of the function config file, which is a Go template (key=value), may be repeated")
+	cmd.Flags().Var(&commandeer.templateValueFiles, "set-file", "Like --set, with the value read from a file (key=path), may be repeated")
+	cmd.Flags().BoolVar(&commandeer.allowMissingTemplateValues, "allow-missing", false, "Render variables of the function config file that have no value as empty, rather than fail")
 	cmd.Flags().Var(&commandeer.volumes, "volume", "Volumes for the deployment function (src1=dest1[,src2=dest2,...])")
+	cmd.Flags().Var(&commandeer.secretFileMounts, "mount-secret-as-file", "Mount a secret's keys as files under an absolute path, read only (secret-name:/mount/path), may be repeated (kube platform)")
+	cmd.Flags().Var(&commandeer.envFromSecrets, "env-from-secret", "Set an environment variable per key of a secret (an env file under the secrets directory on the local platform), may be repeated")
+	cmd.Flags().Var(&commandeer.volumesFromSecrets, "volume-from-secret", "Mount a secret under an absolute path, read only (secret-name:/mount/path, a directory under the secrets directory on the local platform), may be repeated")
 	cmd.Flags().Var(&commandeer.resourceLimits, "resource-limit", "Limits resources in the format of resource-name=quantity (e.g. cpu=3)")
 	cmd.Flags().Var(&commandeer.resourceRequests, "resource-request", "Requests resources in the format of resource-name=quantity (e.g. cpu=3)")
+	cmd.Flags().StringVar(&commandeer.resourcePreset, "preset", "", "Named resource requests and limits (one of small, medium, large), overridden by --resource-limit/--resource-request")
+	cmd.Flags().Float64Var(&commandeer.gpuCount, "gpu", 0, "Number of GPUs each replica gets, or a fraction of a GPU it shares with other replicas (e.g. 0.25), on a CUDA-enabled base image (kube platform)")
+	cmd.Flags().StringVar(&commandeer.gpuSharing, "gpu-sharing", "", "How a fraction of a GPU is shared - timeSlicing or mps (default timeSlicing, with --gpu)")
+	cmd.Flags().StringVar(&commandeer.schedulingPreset, "scheduling-preset", "", "Named node selectors and tolerations from the platform configuration's schedulingPresets (kube platform)")
+	cmd.Flags().BoolVar(&commandeer.deprecate, "deprecate", false, "Mark the function as deprecated (slated for removal)")
+	cmd.Flags().StringVar(&commandeer.deprecationMessage, "deprecation-message", "", "Why the function is deprecated and what to use instead (with --deprecate)")
+	cmd.Flags().StringVar(&commandeer.deprecationDate, "deprecation-date", "", "Date (YYYY-MM-DD) on which the function is to be removed (with --deprecate)")
+	cmd.Flags().StringVar(&commandeer.functionGroup, "function-group", "", "Group of related functions the function is part of, to get and delete them together (get/delete functions --group)")
 	cmd.Flags().StringVar(&commandeer.loggerLevel, "logger-level", "", "One of debug, info, warn, error. By default, uses platform configuration")
 }
 
@@ -268,6 +716,109 @@ func parseVolumes(volumes stringSliceFlag) ([]functionconfig.Volume, error) {
 	return originVolumes, nil
 }
 
+// renderFunctionConfig substitutes the values given with --set and --set-file in the function config file
+func (d *deployCommandeer) renderFunctionConfig(functionConfigBody []byte) ([]byte, error) {
+	return renderFunctionConfigTemplate(functionConfigBody,
+		d.templateValues,
+		d.templateValueFiles,
+		d.allowMissingTemplateValues)
+}
+
+// renderFunctionConfigTemplate renders a function config file with the given values (key=value) and the
+// contents of the given files (key=path)
+func renderFunctionConfigTemplate(functionConfigBody []byte,
+	templateValues stringSliceFlag,
+	templateValueFiles stringSliceFlag,
+	allowMissingTemplateValues bool) ([]byte, error) {
+	values, err := parseTemplateValues(templateValues)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, templateValueFile := range templateValueFiles {
+		keyAndPath := strings.SplitN(templateValueFile, "=", 2)
+		if len(keyAndPath) != 2 || keyAndPath[0] == "" || keyAndPath[1] == "" {
+			return nil, errors.Errorf("Value file %s not in the format of key=path", templateValueFile)
+		}
+
+		value, err := ioutil.ReadFile(keyAndPath[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read value of %s", keyAndPath[0])
+		}
+
+		values[keyAndPath[0]] = string(value)
+	}
+
+	return nuctl_common.RenderFunctionConfig(functionConfigBody, values, allowMissingTemplateValues)
+}
+
+func parseVolumesFromSecrets(encodedVolumesFromSecrets stringSliceFlag) ([]functionconfig.VolumeFromSecret, error) {
+	var volumesFromSecrets []functionconfig.VolumeFromSecret
+	for _, encodedVolumeFromSecret := range encodedVolumesFromSecrets {
+		secretNameAndMountPath := strings.SplitN(encodedVolumeFromSecret, ":", 2)
+		if len(secretNameAndMountPath) != 2 || secretNameAndMountPath[0] == "" || secretNameAndMountPath[1] == "" {
+			return nil, errors.Errorf("Volume from secret %s not in the format of secret-name:/mount/path",
+				encodedVolumeFromSecret)
+		}
+
+		if !path.IsAbs(secretNameAndMountPath[1]) {
+			return nil, errors.Errorf("Mount path %s of secret %s must be absolute",
+				secretNameAndMountPath[1],
+				secretNameAndMountPath[0])
+		}
+
+		volumesFromSecrets = append(volumesFromSecrets, functionconfig.VolumeFromSecret{
+			Name:      secretNameAndMountPath[0],
+			MountPath: secretNameAndMountPath[1],
+		})
+	}
+
+	return volumesFromSecrets, nil
+}
+
+func parseSecretFileMounts(secretFileMounts stringSliceFlag) ([]functionconfig.Volume, error) {
+	var secretVolumes []functionconfig.Volume
+	for secretFileMountIndex, secretFileMount := range secretFileMounts {
+
+		// split to secret name and mount path
+		secretNameAndMountPath := strings.SplitN(secretFileMount, ":", 2)
+		if len(secretNameAndMountPath) != 2 || secretNameAndMountPath[0] == "" || secretNameAndMountPath[1] == "" {
+			return nil, errors.Errorf("Secret mount %s not in the format of secret-name:/mount/path", secretFileMount)
+		}
+
+		secretName := secretNameAndMountPath[0]
+		mountPath := secretNameAndMountPath[1]
+
+		// the path is in the function's container, so it's never relative to where nuctl runs
+		if !path.IsAbs(mountPath) {
+			return nil, errors.Errorf("Mount path %s of secret %s must be absolute", mountPath, secretName)
+		}
+
+		// generate simple volume name, distinct from those of --volume
+		volumeName := fmt.Sprintf("secret-volume-%v", secretFileMountIndex+1)
+
+		secretVolumes = append(secretVolumes,
+			functionconfig.Volume{
+				Volume: v1.Volume{
+					Name: volumeName,
+					VolumeSource: v1.VolumeSource{
+						Secret: &v1.SecretVolumeSource{
+							SecretName: secretName,
+						},
+					},
+				},
+				VolumeMount: v1.VolumeMount{
+					Name:      volumeName,
+					MountPath: mountPath,
+					ReadOnly:  true,
+				},
+			},
+		)
+	}
+
+	return secretVolumes, nil
+}
+
 // If user runs deploy with a function name of a function that was already imported, this checks if that function
 // exists and is imported. If so, returns that function, otherwise returns nil.
 func (d *deployCommandeer) getImportedFunction(functionName string) (platform.Function, error) {
@@ -307,24 +858,212 @@ func (d *deployCommandeer) prepareFunctionConfigForRedeploy(importedFunction pla
 	return *functionConfig
 }
 
+func (d *deployCommandeer) createReport(createFunctionResult *platform.CreateFunctionResult,
+	deployErr error,
+	phaseTimings *common.PhaseTimings,
+	totalDuration time.Duration) *deployReport {
+
+	report := deployReport{
+		Name:      d.functionConfig.Meta.Name,
+		Namespace: d.functionConfig.Meta.Namespace,
+		Image:     d.functionConfig.Spec.Image,
+		Timings: deployReportTimings{
+			TotalSeconds: totalDuration.Seconds(),
+		},
+	}
+
+	if deployErr != nil {
+		report.Error = errors.RootCause(deployErr).Error()
+	}
+
+	if createFunctionResult != nil {
+		report.Timings.BuildSeconds = createFunctionResult.BuildDuration.Seconds()
+		report.Timings.ReadinessSeconds = createFunctionResult.DeployDuration.Seconds()
+
+		if createFunctionResult.Image != "" {
+			report.Image = createFunctionResult.Image
+		}
+
+		report.Attempts = createFunctionResult.BuildAttempts
+
+		if createFunctionResult.ImageStatus != nil {
+			report.Digest = createFunctionResult.ImageStatus.Digest
+			report.Signature = createFunctionResult.ImageStatus.Signature
+		}
+
+		if createFunctionResult.Image == "" {
+			report.Warnings = append(report.Warnings, "Build was skipped, an existing image was deployed")
+		}
+	}
+
+	// populate the final state and URL from the deployed function
+	functions, err := d.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      d.functionConfig.Meta.Name,
+		Namespace: d.functionConfig.Meta.Namespace,
+	})
+	if err != nil || len(functions) == 0 {
+		report.Warnings = append(report.Warnings, "Failed to get deployed function")
+	} else {
+		function := functions[0]
+		if err := function.Initialize(nil); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Failed to initialize function: %s", err.Error()))
+		}
+
+		report.State = string(function.GetStatus().State)
+		if report.Image == "" {
+			report.Image = function.GetConfig().Spec.Image
+		}
+
+		if invokeURL, err := function.GetInvokeURL(platform.InvokeViaAny); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Failed to get invoke URL: %s", err.Error()))
+		} else {
+			report.URL = invokeURL
+		}
+
+		if function.GetStatus().State != functionconfig.FunctionStateReady && deployErr == nil {
+			report.Warnings = append(report.Warnings, "Function is not ready")
+		}
+	}
+
+	if phaseTimings != nil {
+		for _, phase := range phaseTimings.GetPhases() {
+			report.Phases = append(report.Phases, deployReportPhase{
+				Name:    phase.Name,
+				Seconds: phase.Duration.Seconds(),
+			})
+		}
+	}
+
+	return &report
+}
+
+func (d *deployCommandeer) writeReport(report *deployReport) error {
+	encodedReport, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode deploy report")
+	}
+
+	return ioutil.WriteFile(d.reportFilePath, encodedReport, 0644)
+}
+
+func (d *deployCommandeer) renderPhaseTimings(writer io.Writer,
+	phases []common.PhaseTiming,
+	totalDuration time.Duration) error {
+	rendererInstance := renderer.NewRenderer(writer)
+
+	var records [][]string
+	for _, phase := range phases {
+		records = append(records, []string{phase.Name, phase.Duration.Round(time.Millisecond).String()})
+	}
+
+	records = append(records, []string{"total", totalDuration.Round(time.Millisecond).String()})
+	rendererInstance.RenderTable([]string{"Phase", "Duration"}, records)
+
+	return nil
+}
+
+// resolveGitFunctionPath clones the function's source if the build path is a git URL
+// (<repository-url>[#<ref>[:<subdir>]]), replacing the path with the local clone. Returns the
+// directory of the clone, or an empty string if the path is not a git URL
+func (d *deployCommandeer) resolveGitFunctionPath() (string, error) {
+	gitSource, isGitSource := nuctl_common.ParseGitSource(d.functionConfig.Spec.Build.Path)
+	if !isGitSource {
+		return "", nil
+	}
+
+	cmdRunner, err := cmdrunner.NewShellRunner(d.rootCommandeer.loggerInstance)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create command runner")
+	}
+
+	functionPath, cloneDir, err := nuctl_common.CloneGitSource(d.rootCommandeer.loggerInstance,
+		cmdRunner,
+		gitSource,
+		common.GetEnvOrDefaultString("NUCTL_GIT_TOKEN", os.Getenv("GITHUB_TOKEN")))
+	if err != nil {
+		return "", err
+	}
+
+	d.functionConfig.Spec.Build.Path = functionPath
+
+	return cloneDir, nil
+}
+
+// verifySourceChecksum makes sure the function source is the one expected by --source-checksum, so that
+// tampered or corrupted sources are never built
+func (d *deployCommandeer) verifySourceChecksum() error {
+	if d.sourceChecksum == "" {
+		return nil
+	}
+
+	expectedChecksum, err := nuctl_common.ParseSourceChecksum(d.sourceChecksum)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse source checksum")
+	}
+
+	var sourceChecksum string
+	functionBuild := d.functionConfig.Spec.Build
+
+	switch {
+	case functionBuild.FunctionSourceCode != "":
+		functionSourceCode, err := base64.StdEncoding.DecodeString(functionBuild.FunctionSourceCode)
+		if err != nil {
+			return errors.Wrap(err, "Failed to decode function source code")
+		}
+
+		sourceChecksum = nuctl_common.ComputeChecksum(functionSourceCode)
+	case functionBuild.Path == "":
+		return errors.New("--source-checksum requires a function source (--path or --source)")
+	case common.IsURL(functionBuild.Path) || functionBuild.CodeEntryType == build.S3EntryType:
+		return errors.New("--source-checksum can't be used with a remote path, as it's only downloaded by the builder")
+	default:
+		sourcePath := functionBuild.Path
+		if common.IsLocalFileURL(sourcePath) {
+			sourcePath = common.GetPathFromLocalFileURL(sourcePath)
+		}
+
+		sourceChecksum, err = nuctl_common.ComputeSourceChecksum(sourcePath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to compute source checksum")
+		}
+	}
+
+	if sourceChecksum != expectedChecksum {
+		return errors.Errorf("Source checksum mismatch (expected %s:%s, got %s:%s)",
+			nuctl_common.SourceChecksumAlgorithm,
+			expectedChecksum,
+			nuctl_common.SourceChecksumAlgorithm,
+			sourceChecksum)
+	}
+
+	d.rootCommandeer.loggerInstance.DebugWith("Verified function source checksum", "checksum", sourceChecksum)
+
+	return nil
+}
+
 func (d *deployCommandeer) populateDeploymentDefaults() {
-	if d.functionConfig.Spec.TargetCPU == 0 {
-		d.functionConfig.Spec.TargetCPU = abstract.DefaultTargetCPU
+	populateFunctionConfigDefaults(&d.functionConfig)
+}
+
+// populateFunctionConfigDefaults populates initial defaults in the function spec, considering existing values
+func populateFunctionConfigDefaults(functionConfig *functionconfig.Config) {
+	if functionConfig.Spec.TargetCPU == 0 {
+		functionConfig.Spec.TargetCPU = abstract.DefaultTargetCPU
 	}
-	if d.functionConfig.Spec.RunRegistry == "" {
-		d.functionConfig.Spec.RunRegistry = os.Getenv("NUCTL_RUN_REGISTRY")
+	if functionConfig.Spec.RunRegistry == "" {
+		functionConfig.Spec.RunRegistry = os.Getenv("NUCTL_RUN_REGISTRY")
 	}
-	if d.functionConfig.Spec.ReadinessTimeoutSeconds == 0 {
-		d.functionConfig.Spec.ReadinessTimeoutSeconds = abstract.DefaultReadinessTimeoutSeconds
+	if functionConfig.Spec.ReadinessTimeoutSeconds == 0 {
+		functionConfig.Spec.ReadinessTimeoutSeconds = abstract.DefaultReadinessTimeoutSeconds
 	}
-	if d.functionConfig.Spec.DataBindings == nil {
-		d.functionConfig.Spec.DataBindings = map[string]functionconfig.DataBinding{}
+	if functionConfig.Spec.DataBindings == nil {
+		functionConfig.Spec.DataBindings = map[string]functionconfig.DataBinding{}
 	}
-	if d.functionConfig.Spec.Triggers == nil {
-		d.functionConfig.Spec.Triggers = map[string]functionconfig.Trigger{}
+	if functionConfig.Spec.Triggers == nil {
+		functionConfig.Spec.Triggers = map[string]functionconfig.Trigger{}
 	}
-	if d.functionConfig.Spec.RuntimeAttributes == nil {
-		d.functionConfig.Spec.RuntimeAttributes = map[string]interface{}{}
+	if functionConfig.Spec.RuntimeAttributes == nil {
+		functionConfig.Spec.RuntimeAttributes = map[string]interface{}{}
 	}
 }
 
@@ -357,6 +1096,31 @@ func (d *deployCommandeer) enrichConfigWithStringArgs() {
 	}
 }
 
+// enrichConfigWithFromImage points the function at a prebuilt image and makes sure it is never built
+func (d *deployCommandeer) enrichConfigWithFromImage() error {
+	if d.fromImage == "" {
+		return nil
+	}
+
+	// anything that requires a build contradicts deploying a prebuilt image
+	if d.functionConfig.Spec.Build.Path != "" {
+		return errors.New("--from-image can't be used with --path")
+	}
+
+	if d.functionConfig.Spec.Build.FunctionSourceCode != "" {
+		return errors.New("--from-image can't be used with --source")
+	}
+
+	if d.image != "" && d.image != d.fromImage {
+		return errors.New("--from-image can't be used with a different --run-image")
+	}
+
+	d.functionConfig.Spec.Image = d.fromImage
+	d.functionConfig.Spec.Build.Mode = functionconfig.NeverBuild
+
+	return nil
+}
+
 func (d *deployCommandeer) enrichConfigWithBoolArgs() {
 	if d.disable {
 		d.functionConfig.Spec.Disable = d.disable
@@ -393,6 +1157,82 @@ func (d *deployCommandeer) enrichConfigWithIntArgs() {
 	if d.readinessTimeoutSeconds >= 0 {
 		d.functionConfig.Spec.ReadinessTimeoutSeconds = d.readinessTimeoutSeconds
 	}
+
+	d.enrichConfigWithReadinessCheck()
+
+	if d.warmupPath != "" {
+		d.functionConfig.Spec.Warmup = &functionconfig.Warmup{
+			Path:        d.warmupPath,
+			Body:        d.warmupBody,
+			Count:       d.warmupCount,
+			Concurrency: d.warmupConcurrency,
+		}
+	}
+}
+
+// getFlagEnvVars returns the environment variables of the env files, in order, followed by those of the env flags
+func (d *deployCommandeer) getFlagEnvVars() ([]v1.EnvVar, error) {
+	var envVars []v1.EnvVar
+
+	for _, envFilePath := range d.envFiles {
+		envFileVars, err := nuctl_common.ReadEnvFile(envFilePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read env file %s", envFilePath)
+		}
+
+		envVars = append(envVars, envFileVars...)
+	}
+
+	for _, encodedEnvNameAndValue := range d.encodedEnv {
+		envNameAndValue := strings.SplitN(encodedEnvNameAndValue, "=", 2)
+		if len(envNameAndValue) != 2 {
+			return nil, errors.Errorf("Environment variable must be in the form of name=value: %s",
+				encodedEnvNameAndValue)
+		}
+
+		envVars = append(envVars, v1.EnvVar{
+			Name:  envNameAndValue[0],
+			Value: envNameAndValue[1],
+		})
+	}
+
+	return envVars, nil
+}
+
+// enrichConfigWithReadinessCheck adds the readiness check path as a handler dependency of the function's readiness,
+// replacing the one added by a previous deployment
+func (d *deployCommandeer) enrichConfigWithReadinessCheck() {
+	if d.readinessCheckPath == "" && d.readinessCheckPeriodSeconds <= 0 {
+		return
+	}
+
+	if d.functionConfig.Spec.Readiness == nil {
+		d.functionConfig.Spec.Readiness = &functionconfig.Readiness{}
+	}
+
+	if d.readinessCheckPeriodSeconds > 0 {
+		d.functionConfig.Spec.Readiness.PeriodSeconds = d.readinessCheckPeriodSeconds
+	}
+
+	if d.readinessCheckPath == "" {
+		return
+	}
+
+	readinessCheckDependency := functionconfig.ReadinessDependency{
+		Name: readinessCheckDependencyName,
+		Kind: functionconfig.ReadinessDependencyKindHandler,
+		Path: d.readinessCheckPath,
+	}
+
+	for dependencyIndex, dependency := range d.functionConfig.Spec.Readiness.Dependencies {
+		if dependency.Name == readinessCheckDependencyName {
+			d.functionConfig.Spec.Readiness.Dependencies[dependencyIndex] = readinessCheckDependency
+			return
+		}
+	}
+
+	d.functionConfig.Spec.Readiness.Dependencies = append(d.functionConfig.Spec.Readiness.Dependencies,
+		readinessCheckDependency)
 }
 
 func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
@@ -403,6 +1243,32 @@ func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
 	}
 	d.functionConfig.Spec.Volumes = append(d.functionConfig.Spec.Volumes, volumes...)
 
+	// parse secrets mounted as files
+	secretVolumes, err := parseSecretFileMounts(d.secretFileMounts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse secret mounts")
+	}
+	d.functionConfig.Spec.Volumes = append(d.functionConfig.Spec.Volumes, secretVolumes...)
+
+	// parse secrets the function consumes by reference
+	for _, envFromSecret := range d.envFromSecrets {
+		d.functionConfig.Spec.EnvFromSecrets = append(d.functionConfig.Spec.EnvFromSecrets,
+			functionconfig.EnvFromSecret{Name: envFromSecret})
+	}
+
+	volumesFromSecrets, err := parseVolumesFromSecrets(d.volumesFromSecrets)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse volumes from secrets")
+	}
+	d.functionConfig.Spec.VolumesFromSecrets = append(d.functionConfig.Spec.VolumesFromSecrets, volumesFromSecrets...)
+
+	// apply the resource preset first, so that explicit resource limits and requests take precedence
+	if d.resourcePreset != "" {
+		if err := applyResourcePreset(d.resourcePreset, &d.functionConfig.Spec.Resources); err != nil {
+			return errors.Wrap(err, "Failed to apply resource preset")
+		}
+	}
+
 	// parse resource limits
 	if err := parseResourceAllocations(d.resourceLimits,
 		&d.functionConfig.Spec.Resources.Limits); err != nil {
@@ -415,6 +1281,19 @@ func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
 		return errors.Wrap(err, "Failed to parse resource requests")
 	}
 
+	if d.gpuCount != 0 {
+		d.functionConfig.Spec.GPU = &functionconfig.GPU{
+			Count:   d.gpuCount,
+			Sharing: functionconfig.GPUSharing(d.gpuSharing),
+		}
+	} else if d.gpuSharing != "" {
+		return errors.New("--gpu-sharing can only be used with --gpu")
+	}
+
+	if d.schedulingPreset != "" {
+		d.functionConfig.Spec.SchedulingPreset = d.schedulingPreset
+	}
+
 	// decode the JSON data bindings
 	if d.encodedDataBindings != "" {
 		if err := json.Unmarshal([]byte(d.encodedDataBindings),
@@ -431,6 +1310,15 @@ func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
 		}
 	}
 
+	// set the CORS policy on the http trigger, after triggers were decoded so that it applies to them
+	if err := applyHTTPCORS(&httpCORSOptions{
+		allowOrigin:  d.httpCORSAllowOrigin,
+		allowMethods: d.httpCORSAllowMethods,
+		allowHeaders: d.httpCORSAllowHeaders,
+	}, &d.functionConfig.Spec.Triggers); err != nil {
+		return errors.Wrap(err, "Failed to apply HTTP CORS configuration")
+	}
+
 	// decode the JSON function platform configuration
 	if d.encodedFunctionPlatformConfig != "" {
 		if err := json.Unmarshal([]byte(d.encodedFunctionPlatformConfig),
@@ -439,6 +1327,22 @@ func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
 		}
 	}
 
+	// set the local platform's network attributes, after the platform configuration was decoded so that
+	// they apply to it
+	if d.network != "" || len(d.extraHosts) > 0 {
+		if d.functionConfig.Spec.Platform.Attributes == nil {
+			d.functionConfig.Spec.Platform.Attributes = map[string]interface{}{}
+		}
+
+		if d.network != "" {
+			d.functionConfig.Spec.Platform.Attributes["network"] = d.network
+		}
+
+		if len(d.extraHosts) > 0 {
+			d.functionConfig.Spec.Platform.Attributes["extraHosts"] = []string(d.extraHosts)
+		}
+	}
+
 	// decode the JSON runtime attributes
 	if d.encodedRuntimeAttributes != "" {
 		if err := json.Unmarshal([]byte(d.encodedRuntimeAttributes),
@@ -476,18 +1380,52 @@ func (d *deployCommandeer) enrichConfigWithComplexArgs() error {
 		d.functionConfig.Meta.Labels["nuclio.io/project-name"] = d.projectName
 	}
 
-	// decode env
-	for _, encodedEnvNameAndValue := range d.encodedEnv {
-		envNameAndValue := strings.SplitN(encodedEnvNameAndValue, "=", 2)
-		if len(envNameAndValue) != 2 {
-			return errors.Errorf("Environment variable must be in the form of name=value: %s",
-				encodedEnvNameAndValue)
+	// mark the function as deprecated
+	if d.deprecate {
+		if err := d.functionConfig.Meta.SetDeprecation(d.deprecationMessage, d.deprecationDate); err != nil {
+			return errors.Wrap(err, "Failed to deprecate function")
 		}
+	} else if d.deprecationMessage != "" || d.deprecationDate != "" {
+		return errors.New("--deprecation-message and --deprecation-date can only be used with --deprecate")
+	}
 
-		d.functionConfig.Spec.Env = append(d.functionConfig.Spec.Env, v1.EnvVar{
-			Name:  envNameAndValue[0],
-			Value: envNameAndValue[1],
-		})
+	if d.functionGroup != "" {
+		if d.functionConfig.Meta.Annotations == nil {
+			d.functionConfig.Meta.Annotations = map[string]string{}
+		}
+
+		d.functionConfig.Meta.Annotations[functionconfig.FunctionAnnotationGroup] = d.functionGroup
+	}
+
+	// set the env of the env files and then the env flags, each overriding the variables set before it by name
+	envVars, err := d.getFlagEnvVars()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get environment variables")
+	}
+
+	for _, envVar := range envVars {
+		if d.expandEnv {
+			if envVar.Value, err = nuctl_common.ExpandEnvVarReferences(envVar.Value, os.LookupEnv); err != nil {
+				return errors.Wrapf(err, "Failed to expand the value of environment variable %s", envVar.Name)
+			}
+		}
+
+		d.functionConfig.Spec.Env = nuctl_common.SetEnvVar(d.functionConfig.Spec.Env, envVar)
+	}
+
+	// decode function references
+	for _, encodedFunctionReference := range d.encodedFunctionReferences {
+		aliasAndReference := strings.SplitN(encodedFunctionReference, "=", 2)
+		if len(aliasAndReference) != 2 {
+			return errors.Errorf("Function reference must be in the form of alias=[project/]function: %s",
+				encodedFunctionReference)
+		}
+
+		if d.functionConfig.Spec.FunctionReferences == nil {
+			d.functionConfig.Spec.FunctionReferences = map[string]string{}
+		}
+
+		d.functionConfig.Spec.FunctionReferences[aliasAndReference[0]] = aliasAndReference[1]
 	}
 
 	return nil
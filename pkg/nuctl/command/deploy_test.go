@@ -17,10 +17,23 @@ limitations under the License.
 package command
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/fake"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
 
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"k8s.io/api/core/v1"
 )
@@ -54,6 +67,162 @@ func (suite *deployTestSuite) TestParseInvalidResourceAllocation() {
 	suite.Require().Error(err, "Parse resources should not succeed")
 }
 
+func (suite *deployTestSuite) TestResourcePreset() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.resourcePreset = "small"
+
+	// explicit resource flags take precedence over the preset
+	commandeer.resourceLimits = stringSliceFlag{"memory=512Mi"}
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().NoError(err)
+
+	resources := commandeer.functionConfig.Spec.Resources
+	suite.Require().Equal("25m", resources.Requests.Cpu().String())
+	suite.Require().Equal("64Mi", resources.Requests.Memory().String())
+	suite.Require().Equal("500m", resources.Limits.Cpu().String())
+	suite.Require().Equal("512Mi", resources.Limits.Memory().String())
+}
+
+func (suite *deployTestSuite) TestUnknownResourcePreset() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.resourcePreset = "huge"
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "large, medium, small")
+}
+
+func (suite *deployTestSuite) TestGPU() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.gpuCount = 0.5
+	commandeer.gpuSharing = "mps"
+	commandeer.schedulingPreset = "ml"
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().NoError(err)
+	suite.Require().Equal(&functionconfig.GPU{Count: 0.5, Sharing: functionconfig.GPUSharingMPS},
+		commandeer.functionConfig.Spec.GPU)
+	suite.Require().Equal("ml", commandeer.functionConfig.Spec.SchedulingPreset)
+
+	commandeer = newDeployCommandeer(NewRootCommandeer())
+	commandeer.gpuSharing = "mps"
+	suite.Require().Error(commandeer.enrichConfigWithComplexArgs())
+}
+
+func (suite *deployTestSuite) TestReadinessCheck() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.functionConfig.Spec.Readiness = &functionconfig.Readiness{
+		Dependencies: []functionconfig.ReadinessDependency{
+			{Name: "db", Kind: functionconfig.ReadinessDependencyKindTCP, Address: "db:5432"},
+			{Name: readinessCheckDependencyName, Kind: functionconfig.ReadinessDependencyKindHandler, Path: "/old"},
+		},
+		TimeoutSeconds: 3,
+	}
+	commandeer.readinessCheckPath = "/ready"
+	commandeer.readinessCheckPeriodSeconds = 1
+
+	// the readiness check is a handler dependency, replacing the one of a previous deployment
+	commandeer.enrichConfigWithReadinessCheck()
+	suite.Require().Equal(&functionconfig.Readiness{
+		Dependencies: []functionconfig.ReadinessDependency{
+			{Name: "db", Kind: functionconfig.ReadinessDependencyKindTCP, Address: "db:5432"},
+			{Name: readinessCheckDependencyName, Kind: functionconfig.ReadinessDependencyKindHandler, Path: "/ready"},
+		},
+		PeriodSeconds:  1,
+		TimeoutSeconds: 3,
+	}, commandeer.functionConfig.Spec.Readiness)
+
+	// without the flags, the readiness is left as is
+	commandeer = newDeployCommandeer(NewRootCommandeer())
+	commandeer.enrichConfigWithReadinessCheck()
+	suite.Require().Nil(commandeer.functionConfig.Spec.Readiness)
+}
+
+func (suite *deployTestSuite) TestHTTPCORS() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.encodedTriggers = `{"my-http": {"kind": "http", "maxWorkers": 4}, "my-cron": {"kind": "cron"}}`
+	commandeer.httpCORSAllowOrigin = "https://example.com"
+	commandeer.httpCORSAllowMethods = stringSliceFlag{"get,post", "Options"}
+	commandeer.httpCORSAllowHeaders = stringSliceFlag{"content-type", "X-My-Header"}
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().NoError(err)
+
+	triggers := commandeer.functionConfig.Spec.Triggers
+	suite.Require().Len(triggers, 2)
+	suite.Require().Nil(triggers["my-cron"].Attributes)
+	suite.Require().Equal(4, triggers["my-http"].MaxWorkers)
+	suite.Require().Equal(map[string]interface{}{
+		"enabled":      true,
+		"allowOrigin":  "https://example.com",
+		"allowMethods": []string{"GET", "POST", "OPTIONS"},
+		"allowHeaders": []string{"Content-Type", "X-My-Header"},
+	}, triggers["my-http"].Attributes["cors"])
+}
+
+func (suite *deployTestSuite) TestHTTPCORSPartialUpdate() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.encodedTriggers = `{"my-http": {"kind": "http", "attributes": {"port": 30000, "cors": {
+		"enabled": true,
+		"allowOrigin": "https://example.com",
+		"allowMethods": ["GET"],
+		"allowCredentials": true,
+		"preflightMaxAgeSeconds": 600
+	}}}}`
+	commandeer.httpCORSAllowHeaders = stringSliceFlag{"X-My-Header"}
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().NoError(err)
+
+	// only the given setting changed
+	attributes := commandeer.functionConfig.Spec.Triggers["my-http"].Attributes
+	suite.Require().Equal(float64(30000), attributes["port"])
+	suite.Require().Equal(map[string]interface{}{
+		"enabled":                true,
+		"allowOrigin":            "https://example.com",
+		"allowMethods":           []interface{}{"GET"},
+		"allowHeaders":           []string{"X-My-Header"},
+		"allowCredentials":       true,
+		"preflightMaxAgeSeconds": float64(600),
+	}, attributes["cors"])
+}
+
+func (suite *deployTestSuite) TestHTTPCORSCreatesHTTPTrigger() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+	commandeer.httpCORSAllowOrigin = "*"
+
+	err := commandeer.enrichConfigWithComplexArgs()
+	suite.Require().NoError(err)
+
+	httpTriggers := functionconfig.GetTriggersByKind(commandeer.functionConfig.Spec.Triggers, "http")
+	suite.Require().Len(httpTriggers, 1)
+	suite.Require().Equal(map[string]interface{}{
+		"enabled":     true,
+		"allowOrigin": "*",
+	}, httpTriggers[defaultCORSHTTPTriggerName].Attributes["cors"])
+}
+
+func (suite *deployTestSuite) TestInvalidHTTPCORS() {
+	for _, testCase := range []struct {
+		name    string
+		options httpCORSOptions
+	}{
+		{name: "OriginWithoutScheme", options: httpCORSOptions{allowOrigin: "example.com"}},
+		{name: "OriginWithPath", options: httpCORSOptions{allowOrigin: "https://example.com/path"}},
+		{name: "UnknownMethod", options: httpCORSOptions{allowMethods: []string{"GET", "FETCH"}}},
+		{name: "InvalidHeader", options: httpCORSOptions{allowHeaders: []string{"X My Header"}}},
+	} {
+		suite.Run(testCase.name, func() {
+			triggers := map[string]functionconfig.Trigger{}
+
+			err := applyHTTPCORS(&testCase.options, &triggers)
+			suite.Require().Error(err)
+			suite.Require().Empty(triggers)
+		})
+	}
+}
+
 func (suite *deployTestSuite) TestParseValidVolume() {
 	var volumesList []functionconfig.Volume
 
@@ -73,6 +242,209 @@ func (suite *deployTestSuite) TestParseInvalidVolume() {
 	suite.Require().Error(err, "Parse src is invalid, should not succeed")
 }
 
+func (suite *deployTestSuite) TestParseSecretFileMounts() {
+	secretVolumes, err := parseSecretFileMounts(stringSliceFlag{"tls-certs:/etc/tls", "kubeconfig:/root/.kube"})
+	suite.Require().NoError(err)
+	suite.Require().Len(secretVolumes, 2)
+
+	suite.Require().Equal("tls-certs", secretVolumes[0].Volume.Secret.SecretName)
+	suite.Require().Equal("/etc/tls", secretVolumes[0].VolumeMount.MountPath)
+	suite.Require().Equal(secretVolumes[0].Volume.Name, secretVolumes[0].VolumeMount.Name)
+	suite.Require().True(secretVolumes[0].VolumeMount.ReadOnly)
+	suite.Require().NotEqual(secretVolumes[0].Volume.Name, secretVolumes[1].Volume.Name)
+
+	for _, invalidSecretFileMount := range []string{
+		"tls-certs",
+		"tls-certs:",
+		":/etc/tls",
+		"tls-certs:etc/tls",
+	} {
+		_, err = parseSecretFileMounts(stringSliceFlag{invalidSecretFileMount})
+		suite.Require().Error(err, invalidSecretFileMount)
+	}
+}
+
+func (suite *deployTestSuite) TestParseVolumesFromSecrets() {
+	volumesFromSecrets, err := parseVolumesFromSecrets(stringSliceFlag{"tls-certs:/etc/tls"})
+	suite.Require().NoError(err)
+	suite.Require().Equal([]functionconfig.VolumeFromSecret{
+		{Name: "tls-certs", MountPath: "/etc/tls"},
+	}, volumesFromSecrets)
+
+	for _, invalidVolumeFromSecret := range []string{
+		"tls-certs",
+		":/etc/tls",
+		"tls-certs:etc/tls",
+	} {
+		_, err = parseVolumesFromSecrets(stringSliceFlag{invalidVolumeFromSecret})
+		suite.Require().Error(err, invalidVolumeFromSecret)
+	}
+}
+
+func (suite *deployTestSuite) TestPrepareOfflineBuild() {
+	buildCacheDir, err := ioutil.TempDir("", "build-cache-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(buildCacheDir) // nolint: errcheck
+
+	functionBuild := functionconfig.Build{Offline: true, CacheDir: buildCacheDir}
+	suite.Require().NoError(prepareOfflineBuild(&functionBuild))
+	suite.Require().True(functionBuild.NoBaseImagesPull)
+
+	// the cache is only used by offline builds
+	err = prepareOfflineBuild(&functionconfig.Build{CacheDir: buildCacheDir})
+	suite.Require().Error(err)
+
+	err = prepareOfflineBuild(&functionconfig.Build{Offline: true, CacheDir: buildCacheDir + "-missing"})
+	suite.Require().Error(err)
+}
+
+func (suite *deployTestSuite) TestWriteReport() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	reportFile, err := ioutil.TempFile("", "report-*.json")
+	suite.Require().NoError(err)
+	defer os.Remove(reportFile.Name()) // nolint: errcheck
+
+	mockPlatform := &mockplatform.Platform{}
+	mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{
+			&platform.AbstractFunction{
+				Config: functionconfig.Config{
+					Spec: functionconfig.Spec{
+						Image: "processor-test:latest",
+					},
+				},
+				Status: functionconfig.Status{
+					State: functionconfig.FunctionStateReady,
+				},
+			},
+		}, nil).
+		Once()
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.platform = mockPlatform
+	rootCommandeer.loggerInstance = loggerInstance
+
+	commandeer := newDeployCommandeer(rootCommandeer)
+	commandeer.functionConfig.Meta.Name = "test"
+	commandeer.reportFilePath = reportFile.Name()
+
+	err = commandeer.writeReport(commandeer.createReport(&platform.CreateFunctionResult{
+		CreateFunctionBuildResult: platform.CreateFunctionBuildResult{
+			Image: "processor-test:latest",
+		},
+		BuildDuration:  3 * time.Second,
+		DeployDuration: 2 * time.Second,
+	}, nil, nil, 6*time.Second))
+	suite.Require().NoError(err)
+
+	encodedReport, err := ioutil.ReadFile(reportFile.Name())
+	suite.Require().NoError(err)
+
+	report := deployReport{}
+	err = json.Unmarshal(encodedReport, &report)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("test", report.Name)
+	suite.Require().Equal(string(functionconfig.FunctionStateReady), report.State)
+	suite.Require().Equal("processor-test:latest", report.Image)
+	suite.Require().Equal(float64(3), report.Timings.BuildSeconds)
+	suite.Require().Equal(float64(2), report.Timings.ReadinessSeconds)
+	suite.Require().Equal(float64(6), report.Timings.TotalSeconds)
+	suite.Require().Empty(report.Error)
+}
+
+func (suite *deployTestSuite) TestRenderPhaseTimings() {
+	commandeer := newDeployCommandeer(NewRootCommandeer())
+
+	phases := []common.PhaseTiming{
+		{Name: common.PhaseImageBuild, Duration: 3 * time.Second},
+		{Name: common.PhaseReadinessWait, Duration: 1500 * time.Millisecond},
+	}
+
+	outputBuffer := bytes.Buffer{}
+	err := commandeer.renderPhaseTimings(&outputBuffer, phases, 5*time.Second)
+	suite.Require().NoError(err)
+	suite.Require().Contains(outputBuffer.String(), common.PhaseImageBuild)
+	suite.Require().Contains(outputBuffer.String(), "1.5s")
+}
+
+func (suite *deployTestSuite) TestReportPhaseTimings() {
+	mockPlatform := &mockplatform.Platform{}
+	mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{}, nil).
+		Once()
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.platform = mockPlatform
+
+	commandeer := newDeployCommandeer(rootCommandeer)
+	commandeer.functionConfig.Meta.Name = "test"
+
+	phaseTimings := &common.PhaseTimings{}
+	phaseTimings.Record(common.PhaseImageBuild, 3*time.Second)
+	phaseTimings.Record(common.PhaseReadinessWait, 1500*time.Millisecond)
+
+	// with JSON output, the phases are part of the deploy's result
+	report := commandeer.createReport(&platform.CreateFunctionResult{}, nil, phaseTimings, 5*time.Second)
+	suite.Require().Len(report.Phases, 2)
+	suite.Require().Equal(common.PhaseReadinessWait, report.Phases[1].Name)
+	suite.Require().Equal(1.5, report.Phases[1].Seconds)
+	suite.Require().Equal(5.0, report.Timings.TotalSeconds)
+}
+
+func (suite *deployTestSuite) TestDeployWatcher() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	fake.ResetSharedPlatform()
+	fakePlatform, err := fake.GetSharedPlatform(loggerInstance)
+	suite.Require().NoError(err)
+
+	_, err = fakePlatform.CreateFunction(&platform.CreateFunctionOptions{
+		FunctionConfig: functionconfig.Config{
+			Meta: functionconfig.Meta{
+				Name: "my-function",
+			},
+		},
+	})
+	suite.Require().NoError(err)
+
+	err = fakePlatform.SetFunctionLogs("", "my-function", "processor started\n")
+	suite.Require().NoError(err)
+
+	outputBuffer := bytes.Buffer{}
+	watcher := newDeployWatcher(&outputBuffer, fakePlatform, "my-function", "")
+	phaseTimings := watcher.getPhaseTimings()
+
+	err = phaseTimings.Measure(common.PhaseImageBuild, func() error {
+		watcher.writeBuildOutput("Step 1/2")
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// the processor logs are followed once the function is starting
+	deployErr := phaseTimings.Measure(common.PhaseReadinessWait, func() error {
+		suite.Require().Eventually(func() bool {
+			watcher.lock.Lock()
+			defer watcher.lock.Unlock()
+
+			return strings.Contains(outputBuffer.String(), "processor started")
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return errors.New("Function isn't ready")
+	})
+	watcher.stop(deployErr)
+
+	// the output of the failing stage alone is repeated
+	failedStageOutput := outputBuffer.String()[strings.Index(outputBuffer.String(), "> Stage readiness wait failed"):]
+	suite.Require().Contains(failedStageOutput, "processor started")
+	suite.Require().NotContains(failedStageOutput, "Step 1/2")
+}
+
 func TestDeployTestSuite(t *testing.T) {
 	suite.Run(t, new(deployTestSuite))
 }
@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+)
+
+// the types of deploy events
+const (
+	deployEventTypeState       = "state"
+	deployEventTypePhase       = "phase"
+	deployEventTypeBuildOutput = "buildOutput"
+	deployEventTypeLog         = "log"
+)
+
+// deployEvent is a single step in the progress of a deploy, written as a line of JSON with --json-events
+type deployEvent struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+
+	// state events
+	State    functionconfig.FunctionState `json:"state,omitempty"`
+	Image    string                       `json:"image,omitempty"`
+	HTTPPort int                          `json:"httpPort,omitempty"`
+	Error    string                       `json:"error,omitempty"`
+
+	// phase events
+	Phase           string  `json:"phase,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+
+	// build output events
+	Line string `json:"line,omitempty"`
+
+	// log events - the log record as the logger encoded it
+	Log json.RawMessage `json:"log,omitempty"`
+}
+
+// deployEventWriter writes deploy events to a writer, one per line. it's safe for concurrent use, as
+// logs, build output and phases are reported from wherever they happen
+type deployEventWriter struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+
+	// a log record may be written in more than one part
+	pendingLog bytes.Buffer
+}
+
+func newDeployEventWriter(writer io.Writer) *deployEventWriter {
+	return &deployEventWriter{
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (dew *deployEventWriter) writeState(state functionconfig.FunctionState,
+	image string,
+	httpPort int,
+	deployErr error) {
+	event := deployEvent{
+		Type:     deployEventTypeState,
+		State:    state,
+		Image:    image,
+		HTTPPort: httpPort,
+	}
+
+	if deployErr != nil {
+		event.Error = deployErr.Error()
+	}
+
+	dew.writeEvent(&event)
+}
+
+func (dew *deployEventWriter) writePhase(phaseTiming common.PhaseTiming) {
+	dew.writeEvent(&deployEvent{
+		Type:            deployEventTypePhase,
+		Phase:           phaseTiming.Name,
+		DurationSeconds: phaseTiming.Duration.Seconds(),
+	})
+}
+
+func (dew *deployEventWriter) writeBuildOutput(line string) {
+	dew.writeEvent(&deployEvent{
+		Type: deployEventTypeBuildOutput,
+		Line: line,
+	})
+}
+
+// Write implements io.Writer for the logger, which writes JSON log records terminated by a newline
+func (dew *deployEventWriter) Write(buffer []byte) (int, error) {
+	dew.lock.Lock()
+	dew.pendingLog.Write(buffer) // nolint: errcheck
+	dew.lock.Unlock()
+
+	for {
+		dew.lock.Lock()
+		pendingLog := dew.pendingLog.Bytes()
+		newlineIndex := bytes.IndexByte(pendingLog, '\n')
+		if newlineIndex < 0 {
+			dew.lock.Unlock()
+			break
+		}
+
+		logRecord := append([]byte{}, bytes.TrimSpace(pendingLog[:newlineIndex])...)
+		dew.pendingLog.Next(newlineIndex + 1)
+		dew.lock.Unlock()
+
+		if len(logRecord) == 0 {
+			continue
+		}
+
+		event := deployEvent{
+			Type: deployEventTypeLog,
+			Log:  logRecord,
+		}
+
+		// shouldn't happen, but a record that isn't JSON would break the line
+		if !json.Valid(logRecord) {
+			encodedLogRecord, _ := json.Marshal(string(logRecord))
+			event.Log = encodedLogRecord
+		}
+
+		dew.writeEvent(&event)
+	}
+
+	return len(buffer), nil
+}
+
+func (dew *deployEventWriter) writeEvent(event *deployEvent) {
+	event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+
+	dew.lock.Lock()
+	defer dew.lock.Unlock()
+
+	dew.encoder.Encode(event) // nolint: errcheck
+}
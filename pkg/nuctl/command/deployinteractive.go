@@ -0,0 +1,337 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	interactiveDefaultRuntime     = "python:3.6"
+	interactiveDefaultTriggerKind = "http"
+	interactiveNoTriggers         = "none"
+)
+
+// the runtimes deploy --interactive offers, with the handler each defaults to
+var interactiveRuntimes = []struct {
+	name    string
+	handler string
+}{
+	{"python:3.6", "main:handler"},
+	{"python:2.7", "main:handler"},
+	{"pypy", "main:handler"},
+	{"golang", "main:Handler"},
+	{"nodejs", "main:handler"},
+	{"java", "Handler"},
+	{"dotnetcore", "nuclio:main"},
+	{"shell", "main.sh:main"},
+	{"ruby", "main:main"},
+}
+
+// the trigger kinds deploy --interactive offers
+var interactiveTriggerKinds = []string{"http", "cron"}
+
+// deployWizard prompts for what a deploy needs with deploy --interactive - the function's name, source, runtime,
+// handler and triggers - so that first-time users don't have to know the flags. what was given with flags, in
+// the function config file or by the imported function isn't prompted for
+type deployWizard struct {
+	reader *bufio.Reader
+	writer io.Writer
+
+	// the flags the answers amount to, shown once done so that the deploy can be repeated without prompts
+	answeredFlags []string
+}
+
+func newDeployWizard(reader io.Reader, writer io.Writer) *deployWizard {
+	return &deployWizard{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+	}
+}
+
+func (dw *deployWizard) promptFunctionName() (string, error) {
+	return dw.promptRequired("Function name", "")
+}
+
+// promptFunctionSpec prompts for the source, runtime, handler and triggers the deploy wasn't given
+func (dw *deployWizard) promptFunctionSpec(d *deployCommandeer) error {
+	functionSpec := &d.functionConfig.Spec
+
+	// prebuilt images have no source, runtime or handler
+	if d.fromImage == "" && d.image == "" {
+		if d.functionBuild.Path == "" && d.functionBuild.FunctionSourceCode == "" && d.functionConfigPath == "" {
+			functionPath, err := dw.prompt("Path to the function's source code", ".")
+			if err != nil {
+				return err
+			}
+
+			d.functionBuild.Path = functionPath
+			dw.answeredFlags = append(dw.answeredFlags, "--path "+functionPath)
+		}
+
+		if d.runtime == "" && functionSpec.Runtime == "" {
+			var runtimeNames []string
+			for _, runtime := range interactiveRuntimes {
+				runtimeNames = append(runtimeNames, runtime.name)
+			}
+
+			runtimeName, err := dw.promptChoice("Runtime", runtimeNames, interactiveDefaultRuntime)
+			if err != nil {
+				return err
+			}
+
+			d.runtime = runtimeName
+			dw.answeredFlags = append(dw.answeredFlags, "--runtime "+runtimeName)
+		}
+
+		if d.handler == "" && functionSpec.Handler == "" {
+			runtimeName := d.runtime
+			if runtimeName == "" {
+				runtimeName = functionSpec.Runtime
+			}
+
+			handler, err := dw.promptRequired("Handler", dw.getDefaultHandler(runtimeName))
+			if err != nil {
+				return err
+			}
+
+			d.handler = handler
+			dw.answeredFlags = append(dw.answeredFlags, "--handler "+handler)
+		}
+	}
+
+	if d.encodedTriggers == "" && len(functionSpec.Triggers) == 0 {
+		triggers, err := dw.promptTriggers()
+		if err != nil {
+			return err
+		}
+
+		if len(triggers) > 0 {
+			functionSpec.Triggers = triggers
+			dw.answeredFlags = append(dw.answeredFlags, "--triggers '"+dw.encodeTriggers(triggers)+"'")
+		}
+	}
+
+	if len(dw.answeredFlags) > 0 {
+		fmt.Fprintf(dw.writer, // nolint: errcheck
+			"\nTo deploy the same way without prompts, add these flags: %s\n\n",
+			strings.Join(dw.answeredFlags, " "))
+	}
+
+	return nil
+}
+
+func (dw *deployWizard) promptTriggers() (map[string]functionconfig.Trigger, error) {
+	triggerKinds, err := dw.promptList("Triggers",
+		append(append([]string{}, interactiveTriggerKinds...), interactiveNoTriggers),
+		interactiveDefaultTriggerKind)
+	if err != nil {
+		return nil, err
+	}
+
+	triggers := map[string]functionconfig.Trigger{}
+	for _, triggerKind := range triggerKinds {
+		if triggerKind == interactiveNoTriggers {
+			continue
+		}
+
+		trigger := functionconfig.Trigger{
+			Kind:       triggerKind,
+			Name:       triggerKind,
+			MaxWorkers: 1,
+			Attributes: map[string]interface{}{},
+		}
+
+		switch triggerKind {
+		case "http":
+			port, err := dw.promptValid("HTTP port (empty for any free port)", "", func(answer string) error {
+				if answer == "" {
+					return nil
+				}
+
+				if port, err := strconv.Atoi(answer); err != nil || port < 1 || port > 65535 {
+					return errors.New("Port must be a number between 1 and 65535")
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// validated above
+			if port, err := strconv.Atoi(port); err == nil {
+				trigger.Attributes["port"] = port
+			}
+
+		case "cron":
+			interval, err := dw.promptValid("Interval of the cron trigger", "10s", func(answer string) error {
+				if _, err := time.ParseDuration(answer); err != nil {
+					return errors.New("Interval must be a duration (e.g. 10s, 1m)")
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			trigger.Attributes["interval"] = interval
+		}
+
+		triggers[trigger.Name] = trigger
+	}
+
+	return triggers, nil
+}
+
+// prompt writes the question and returns the answer, or the default value when the answer is empty
+func (dw *deployWizard) prompt(question string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(dw.writer, "%s [%s]: ", question, defaultValue) // nolint: errcheck
+	} else {
+		fmt.Fprintf(dw.writer, "%s: ", question) // nolint: errcheck
+	}
+
+	line, err := dw.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", errors.New("Input ended before all the questions were answered")
+		}
+
+		return "", errors.Wrap(err, "Failed to read answer")
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+
+	return defaultValue, nil
+}
+
+// promptValid prompts until the answer is valid
+func (dw *deployWizard) promptValid(question string,
+	defaultValue string,
+	validate func(answer string) error) (string, error) {
+	for {
+		answer, err := dw.prompt(question, defaultValue)
+		if err != nil {
+			return "", err
+		}
+
+		validationErr := validate(answer)
+		if validationErr == nil {
+			return answer, nil
+		}
+
+		fmt.Fprintln(dw.writer, validationErr.Error()) // nolint: errcheck
+	}
+}
+
+// promptRequired prompts until answered, with the default value (if any) taken as the answer
+func (dw *deployWizard) promptRequired(question string, defaultValue string) (string, error) {
+	return dw.promptValid(question, defaultValue, func(answer string) error {
+		if answer == "" {
+			return errors.New("An answer is required")
+		}
+
+		return nil
+	})
+}
+
+func (dw *deployWizard) promptChoice(question string, choices []string, defaultChoice string) (string, error) {
+	return dw.promptValid(fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", ")),
+		defaultChoice,
+		func(answer string) error {
+			return dw.validateChoice(answer, choices)
+		})
+}
+
+// promptList prompts for comma-separated choices
+func (dw *deployWizard) promptList(question string, choices []string, defaultChoice string) ([]string, error) {
+	answer, err := dw.promptValid(fmt.Sprintf("%s, comma-separated (%s)", question, strings.Join(choices, ", ")),
+		defaultChoice,
+		func(answer string) error {
+			for _, choice := range strings.Split(answer, ",") {
+				if err := dw.validateChoice(strings.TrimSpace(choice), choices); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var answers []string
+	for _, choice := range strings.Split(answer, ",") {
+		answers = append(answers, strings.TrimSpace(choice))
+	}
+
+	return answers, nil
+}
+
+func (dw *deployWizard) validateChoice(answer string, choices []string) error {
+	for _, choice := range choices {
+		if answer == choice {
+			return nil
+		}
+	}
+
+	return errors.Errorf("Must be one of: %s", strings.Join(choices, ", "))
+}
+
+func (dw *deployWizard) getDefaultHandler(runtimeName string) string {
+	for _, runtime := range interactiveRuntimes {
+		if runtime.name == runtimeName {
+			return runtime.handler
+		}
+	}
+
+	return ""
+}
+
+// encodeTriggers encodes the triggers the way --triggers takes them, leaving out the fields that weren't set
+func (dw *deployWizard) encodeTriggers(triggers map[string]functionconfig.Trigger) string {
+	encodedTriggers := map[string]interface{}{}
+	for triggerName, trigger := range triggers {
+		encodedTrigger := map[string]interface{}{
+			"kind":       trigger.Kind,
+			"maxWorkers": trigger.MaxWorkers,
+		}
+
+		if len(trigger.Attributes) > 0 {
+			encodedTrigger["attributes"] = trigger.Attributes
+		}
+
+		encodedTriggers[triggerName] = encodedTrigger
+	}
+
+	triggersJSON, _ := json.Marshal(encodedTriggers) // nolint: errcheck
+	return string(triggersJSON)
+}
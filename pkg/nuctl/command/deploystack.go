@@ -0,0 +1,457 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	nuctl_common "github.com/nuclio/nuclio/pkg/nuctl/command/common"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+const defaultDeployStackConcurrency = 4
+
+// deployStack is a manifest of functions deployed together (nuctl deploy -f stack.yaml), along with the build
+// and registry settings they share
+type deployStack struct {
+	Project     string                `json:"project,omitempty"`
+	RunRegistry string                `json:"runRegistry,omitempty"`
+	Build       deployStackBuild      `json:"build,omitempty"`
+	Functions   []deployStackFunction `json:"functions"`
+}
+
+// deployStackBuild holds the build settings of the functions of a stack, for those that don't set their own
+type deployStackBuild struct {
+	Registry          string `json:"registry,omitempty"`
+	BaseImageRegistry string `json:"baseImageRegistry,omitempty"`
+	BaseImage         string `json:"baseImage,omitempty"`
+	OnbuildImage      string `json:"onbuildImage,omitempty"`
+	NoBaseImagesPull  bool   `json:"noBaseImagesPull,omitempty"`
+	NoCache           bool   `json:"noCache,omitempty"`
+	Offline           bool   `json:"offline,omitempty"`
+}
+
+// deployStackFunction is a function of a stack, configured by a function config file or an inline spec. paths
+// are relative to the manifest
+type deployStackFunction struct {
+	Name string               `json:"name"`
+	File string               `json:"file,omitempty"`
+	Path string               `json:"path,omitempty"`
+	Spec *functionconfig.Spec `json:"spec,omitempty"`
+}
+
+// isDeployStack returns whether a file is a stack manifest rather than a function config - the former lists
+// functions, the latter has a spec. files which can't be read or parsed aren't, and fail as function configs
+func isDeployStack(filePath string) bool {
+	body, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+
+	unmarshalFunc, err := nuctl_common.GetUnmarshalFunc(body)
+	if err != nil {
+		return false
+	}
+
+	fields := map[string]interface{}{}
+	if err := unmarshalFunc(body, &fields); err != nil {
+		return false
+	}
+
+	_, hasFunctions := fields["functions"]
+	_, hasSpec := fields["spec"]
+
+	return hasFunctions && !hasSpec
+}
+
+func readDeployStack(stackPath string) (*deployStack, error) {
+	stackBody, err := ioutil.ReadFile(stackPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read stack manifest")
+	}
+
+	unmarshalFunc, err := nuctl_common.GetUnmarshalFunc(stackBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed identifying stack manifest format")
+	}
+
+	stack := &deployStack{}
+	if err := unmarshalFunc(stackBody, stack); err != nil {
+		return nil, errors.Wrap(err, "Failed parsing stack manifest")
+	}
+
+	if len(stack.Functions) == 0 {
+		return nil, errors.New("Stack manifest must list at least one function")
+	}
+
+	functionNames := map[string]bool{}
+	for _, stackFunction := range stack.Functions {
+		if stackFunction.Name == "" {
+			return nil, errors.New("Each function of the stack must have a name")
+		}
+
+		if functionNames[stackFunction.Name] {
+			return nil, errors.Errorf("Function %s is listed more than once", stackFunction.Name)
+		}
+
+		if stackFunction.File != "" && stackFunction.Spec != nil {
+			return nil, errors.Errorf("Function %s must have either a file or a spec, not both", stackFunction.Name)
+		}
+
+		functionNames[stackFunction.Name] = true
+	}
+
+	return stack, nil
+}
+
+// deployStack deploys the functions of the stack manifest at the function config path, no more than the
+// configured concurrency at a time, and renders the outcome of each. a failed deploy doesn't stop the others
+func (d *deployCommandeer) deployStack(cmd *cobra.Command) error {
+	stack, err := readDeployStack(d.functionConfigPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read stack")
+	}
+
+	stackFunctions, err := d.filterStackFunctions(stack.Functions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to filter stack functions")
+	}
+
+	// resolve all the configurations before deploying anything, so that a broken one doesn't leave the
+	// stack half deployed
+	var functionConfigs []*functionconfig.Config
+	for stackFunctionIndex := range stackFunctions {
+		functionConfig, err := d.createStackFunctionConfig(cmd, stack, &stackFunctions[stackFunctionIndex])
+		if err != nil {
+			return errors.Wrapf(err, "Failed to configure function %s", stackFunctions[stackFunctionIndex].Name)
+		}
+
+		functionConfigs = append(functionConfigs, functionConfig)
+	}
+
+	// prebuilt images aren't pushed
+	loggedInRegistries := map[string]bool{}
+	for _, functionConfig := range functionConfigs {
+		registry := functionConfig.Spec.Build.Registry
+		if functionConfig.Spec.Build.Mode == functionconfig.NeverBuild || loggedInRegistries[registry] {
+			continue
+		}
+
+		if err := d.registryCredentials.logIn(d.rootCommandeer, registry); err != nil {
+			return errors.Wrapf(err, "Failed to log in to registry %s", registry)
+		}
+
+		loggedInRegistries[registry] = true
+	}
+
+	reports, err := d.deployStackFunctions(functionConfigs)
+	if err != nil {
+		return errors.Wrap(err, "Failed to order functions by their dependencies")
+	}
+
+	var failedFunctionNames []string
+	for _, report := range reports {
+		if report.Error != "" {
+			failedFunctionNames = append(failedFunctionNames, report.Name)
+		}
+	}
+
+	if d.rootCommandeer.isJSONOutput() {
+		if err := d.rootCommandeer.renderResult(cmd.OutOrStdout(), reports); err != nil {
+			return errors.Wrap(err, "Failed to render stack deploy result")
+		}
+	} else {
+		d.renderStackReports(cmd, reports)
+	}
+
+	if len(failedFunctionNames) > 0 {
+		return errors.Errorf("Failed to deploy %d of %d functions: %v",
+			len(failedFunctionNames),
+			len(reports),
+			failedFunctionNames)
+	}
+
+	return nil
+}
+
+// filterStackFunctions returns the functions of the stack selected by --only and --skip, which must name
+// functions of the stack
+func (d *deployCommandeer) filterStackFunctions(stackFunctions []deployStackFunction) ([]deployStackFunction, error) {
+	stackFunctionNames := map[string]bool{}
+	for _, stackFunction := range stackFunctions {
+		stackFunctionNames[stackFunction.Name] = true
+	}
+
+	parseNames := func(flagName string, values []string) (map[string]bool, error) {
+		names := map[string]bool{}
+
+		for _, value := range values {
+			for _, name := range strings.Split(value, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+
+				if !stackFunctionNames[name] {
+					return nil, errors.Errorf("--%s names function %s, which isn't in the stack", flagName, name)
+				}
+
+				names[name] = true
+			}
+		}
+
+		return names, nil
+	}
+
+	onlyNames, err := parseNames("only", d.stackOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	skipNames, err := parseNames("skip", d.stackSkip)
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredStackFunctions []deployStackFunction
+	for _, stackFunction := range stackFunctions {
+		if len(onlyNames) > 0 && !onlyNames[stackFunction.Name] {
+			continue
+		}
+
+		if skipNames[stackFunction.Name] {
+			continue
+		}
+
+		filteredStackFunctions = append(filteredStackFunctions, stackFunction)
+	}
+
+	if len(filteredStackFunctions) == 0 {
+		return nil, errors.New("No functions of the stack are left to deploy")
+	}
+
+	return filteredStackFunctions, nil
+}
+
+func (d *deployCommandeer) createStackFunctionConfig(cmd *cobra.Command,
+	stack *deployStack,
+	stackFunction *deployStackFunction) (*functionconfig.Config, error) {
+	var err error
+
+	stackDir := filepath.Dir(d.functionConfigPath)
+	functionConfig := functionconfig.NewConfig()
+
+	switch {
+	case stackFunction.File != "":
+		functionConfig, err = readFunctionConfigFile(resolveStackPath(stackDir, stackFunction.File),
+			d.rootCommandeer.namespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read function config file")
+		}
+	case stackFunction.Spec != nil:
+		functionConfig.Spec = *stackFunction.Spec
+	}
+
+	functionConfig.Meta.Name = stackFunction.Name
+	functionConfig.Meta.Namespace = d.rootCommandeer.namespace
+
+	if stackFunction.Path != "" {
+		functionConfig.Spec.Build.Path = resolveStackPath(stackDir, stackFunction.Path)
+	}
+
+	// the settings of the stack apply to functions which don't have their own, while those given on the
+	// command line apply to all of them
+	if functionConfig.Meta.Labels == nil {
+		functionConfig.Meta.Labels = map[string]string{}
+	}
+
+	switch {
+	case d.projectName != "":
+		functionConfig.Meta.Labels["nuclio.io/project-name"] = d.projectName
+	case functionConfig.Meta.Labels["nuclio.io/project-name"] == "" && stack.Project != "":
+		functionConfig.Meta.Labels["nuclio.io/project-name"] = stack.Project
+	}
+
+	registryFlagValue := ""
+	if cmd.Flags().Changed("registry") {
+		registryFlagValue = d.functionBuild.Registry
+	}
+
+	functionBuild := &functionConfig.Spec.Build
+	for _, setting := range []struct {
+		value      *string
+		stackValue string
+		flagValue  string
+	}{
+		{&functionBuild.Registry, stack.Build.Registry, registryFlagValue},
+		{&functionConfig.Spec.RunRegistry, stack.RunRegistry, d.runRegistry},
+		{&functionBuild.BaseImageRegistry, stack.Build.BaseImageRegistry, ""},
+		{&functionBuild.BaseImage, stack.Build.BaseImage, ""},
+		{&functionBuild.OnbuildImage, stack.Build.OnbuildImage, ""},
+	} {
+		switch {
+		case setting.flagValue != "":
+			*setting.value = setting.flagValue
+		case *setting.value == "":
+			*setting.value = setting.stackValue
+		}
+	}
+
+	// the registry flag defaults to NUCTL_REGISTRY, which is the last resort
+	if functionBuild.Registry == "" {
+		functionBuild.Registry = d.functionBuild.Registry
+	}
+
+	functionBuild.NoBaseImagesPull = functionBuild.NoBaseImagesPull || stack.Build.NoBaseImagesPull
+	functionBuild.NoCache = functionBuild.NoCache || stack.Build.NoCache
+	functionBuild.Offline = functionBuild.Offline || stack.Build.Offline
+
+	functionConfig.Meta.RemoveSkipBuildAnnotation()
+	functionConfig.Meta.RemoveSkipDeployAnnotation()
+	populateFunctionConfigDefaults(functionConfig)
+
+	if err := functionConfig.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid function configuration")
+	}
+
+	if err := prepareOfflineBuild(functionBuild); err != nil {
+		return nil, err
+	}
+
+	return functionConfig, nil
+}
+
+// deployStackFunctions deploys the functions, each once the resources it depends on are ready, returning a
+// report of each deploy in the order of the functions
+func (d *deployCommandeer) deployStackFunctions(functionConfigs []*functionconfig.Config) ([]deployReport, error) {
+	concurrency := d.stackConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultDeployStackConcurrency
+	}
+
+	functionIndexes := map[string]int{}
+	for functionConfigIndex, functionConfig := range functionConfigs {
+		functionIndexes[functionConfig.Meta.Name] = functionConfigIndex
+	}
+
+	reports := make([]deployReport, len(functionConfigs))
+	deployErrs, err := platform.NewDependencyScheduler(d.rootCommandeer.loggerInstance,
+		d.rootCommandeer.platform,
+		concurrency).Run(functionConfigs, func(functionConfig *functionconfig.Config) error {
+		report := d.deployStackFunction(functionConfig)
+		reports[functionIndexes[functionConfig.Meta.Name]] = report
+
+		if report.Error != "" {
+			return errors.New(report.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// functions whose dependencies weren't ready weren't deployed
+	for functionConfigIndex, functionConfig := range functionConfigs {
+		if deployErrs[functionConfigIndex] != nil && reports[functionConfigIndex].Name == "" {
+			reports[functionConfigIndex] = deployReport{
+				Name:      functionConfig.Meta.Name,
+				Namespace: functionConfig.Meta.Namespace,
+				State:     string(functionconfig.FunctionStateError),
+				Error:     errors.RootCause(deployErrs[functionConfigIndex]).Error(),
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+func (d *deployCommandeer) deployStackFunction(functionConfig *functionconfig.Config) deployReport {
+	functionLogger := d.rootCommandeer.loggerInstance.GetChild(functionConfig.Meta.Name)
+	functionLogger.InfoWith("Deploying function", "name", functionConfig.Meta.Name)
+
+	deployStartTime := time.Now()
+	createFunctionResult, err := d.rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:         functionLogger,
+		FunctionConfig: *functionConfig,
+
+		BuildRetries:              d.buildRetries,
+		BuildRetryOnTransientOnly: d.buildRetryOnTransientOnly,
+
+		// the outputs of builds running at the same time would interleave, so only log them
+		BuildOutputLineHandler: func(line string) {
+			functionLogger.Debug(line)
+		},
+	})
+
+	report := deployReport{
+		Name:      functionConfig.Meta.Name,
+		Namespace: functionConfig.Meta.Namespace,
+		State:     string(functionconfig.FunctionStateReady),
+		Timings: deployReportTimings{
+			TotalSeconds: time.Since(deployStartTime).Seconds(),
+		},
+	}
+
+	if createFunctionResult != nil {
+		report.Image = createFunctionResult.Image
+		report.Attempts = createFunctionResult.BuildAttempts
+	}
+
+	if err != nil {
+		functionLogger.WarnWith("Failed to deploy function", "err", errors.RootCause(err).Error())
+
+		report.State = string(functionconfig.FunctionStateError)
+		report.Error = errors.RootCause(err).Error()
+	}
+
+	return report
+}
+
+func (d *deployCommandeer) renderStackReports(cmd *cobra.Command, reports []deployReport) {
+	var records [][]string
+	for _, report := range reports {
+		records = append(records, []string{
+			report.Name,
+			report.State,
+			report.Image,
+			(time.Duration(report.Timings.TotalSeconds * float64(time.Second))).Round(time.Second).String(),
+			report.Error,
+		})
+	}
+
+	renderer.NewRenderer(cmd.OutOrStdout()).RenderTable([]string{"Name", "State", "Image", "Duration", "Error"},
+		records)
+}
+
+// resolveStackPath resolves a path of the stack manifest relative to its directory, leaving absolute paths and
+// URLs (e.g. of git repositories or archives) as they are
+func resolveStackPath(stackDir string, stackPath string) string {
+	if filepath.IsAbs(stackPath) || strings.Contains(stackPath, "://") {
+		return stackPath
+	}
+
+	return filepath.Join(stackDir, stackPath)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/mgutz/ansi"
+)
+
+// the number of output lines of the failing stage that are repeated when a watched deploy fails
+const maxFailedStageLines = 100
+
+// how long to wait before trying to follow the processor logs again, when the function has no replica yet
+const followProcessorLogsInterval = time.Second
+
+// deployWatcher writes the progress of a deploy with --watch - the stages as they start and complete, the build
+// output and the processor logs. when the deploy fails, the output of the failing stage is repeated after it
+type deployWatcher struct {
+	lock     sync.Mutex
+	writer   io.Writer
+	platform platform.Platform
+
+	functionName string
+	namespace    string
+
+	// the output lines of the current stage, up to maxFailedStageLines
+	stageLines  []string
+	failedStage *common.PhaseTiming
+
+	// following the processor logs
+	following          bool
+	stopFollowing      chan struct{}
+	followingWaitGroup sync.WaitGroup
+	processorLogs      io.ReadCloser
+}
+
+func newDeployWatcher(writer io.Writer,
+	platformInstance platform.Platform,
+	functionName string,
+	namespace string) *deployWatcher {
+	return &deployWatcher{
+		writer:        writer,
+		platform:      platformInstance,
+		functionName:  functionName,
+		namespace:     namespace,
+		stopFollowing: make(chan struct{}),
+	}
+}
+
+func (dw *deployWatcher) getPhaseTimings() *common.PhaseTimings {
+	return &common.PhaseTimings{
+		StartHandler:  dw.writeStageStart,
+		RecordHandler: dw.writeStageEnd,
+	}
+}
+
+func (dw *deployWatcher) writeStageStart(name string) {
+	dw.lock.Lock()
+	dw.stageLines = nil
+	fmt.Fprintf(dw.writer, "%s %s\n", ansi.Color("*", "blue+h"), name) // nolint: errcheck
+	dw.lock.Unlock()
+
+	// the processor logs are of interest once the function's pods are being started
+	if name == common.PhasePodScheduling || name == common.PhaseReadinessWait {
+		dw.startFollowingProcessorLogs()
+	}
+}
+
+func (dw *deployWatcher) writeStageEnd(phaseTiming common.PhaseTiming) {
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	duration := phaseTiming.Duration.Round(time.Millisecond)
+
+	if phaseTiming.Failed {
+		dw.failedStage = &phaseTiming
+		fmt.Fprintf(dw.writer, "%s %s failed (%s)\n", ansi.Color("x", "red+h"), phaseTiming.Name, duration) // nolint: errcheck
+		return
+	}
+
+	fmt.Fprintf(dw.writer, "%s %s (%s)\n", ansi.Color("v", "green+h"), phaseTiming.Name, duration) // nolint: errcheck
+}
+
+func (dw *deployWatcher) writeBuildOutput(line string) {
+	dw.writeLine("  " + line)
+}
+
+func (dw *deployWatcher) writeLine(line string) {
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	// a failed stage keeps its lines
+	if dw.failedStage == nil {
+		dw.stageLines = append(dw.stageLines, line)
+		if len(dw.stageLines) > maxFailedStageLines {
+			dw.stageLines = dw.stageLines[1:]
+		}
+	}
+
+	fmt.Fprintln(dw.writer, line) // nolint: errcheck
+}
+
+func (dw *deployWatcher) startFollowingProcessorLogs() {
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	// already following (the stages that start it may both happen)
+	if dw.following {
+		return
+	}
+
+	dw.following = true
+	dw.followingWaitGroup.Add(1)
+
+	go dw.followProcessorLogs()
+}
+
+// followProcessorLogs writes the processor logs until the watcher is stopped or the logs end (e.g. the
+// processor exited). until the function has a replica, the logs can't be followed
+func (dw *deployWatcher) followProcessorLogs() {
+	defer dw.followingWaitGroup.Done()
+
+	for {
+		processorLogs, err := dw.platform.GetFunctionLogs(&platform.GetFunctionLogsOptions{
+			Name:      dw.functionName,
+			Namespace: dw.namespace,
+			Follow:    true,
+		})
+
+		if err == nil {
+			dw.lock.Lock()
+			dw.processorLogs = processorLogs
+			dw.lock.Unlock()
+
+			// stopping closes the logs, ending the scan
+			select {
+			case <-dw.stopFollowing:
+				processorLogs.Close() // nolint: errcheck
+				return
+			default:
+			}
+
+			scanner := bufio.NewScanner(processorLogs)
+			for scanner.Scan() {
+				dw.writeLine(ansi.Color("  [processor] ", "cyan") + scanner.Text())
+			}
+
+			return
+		}
+
+		select {
+		case <-dw.stopFollowing:
+			return
+		case <-time.After(followProcessorLogsInterval):
+		}
+	}
+}
+
+// stop stops following the processor logs and, if the deploy failed, repeats the output of the failing stage
+func (dw *deployWatcher) stop(deployErr error) {
+	close(dw.stopFollowing)
+
+	dw.lock.Lock()
+	if dw.processorLogs != nil {
+		dw.processorLogs.Close() // nolint: errcheck
+	}
+	dw.lock.Unlock()
+
+	dw.followingWaitGroup.Wait()
+
+	if deployErr == nil {
+		return
+	}
+
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	// the deploy may have failed outside of a stage (e.g. a bad configuration)
+	if dw.failedStage == nil {
+		return
+	}
+
+	failedStageHeader := fmt.Sprintf("> Stage %s failed, its last output:", dw.failedStage.Name)
+	fmt.Fprintf(dw.writer, "\n%s\n", ansi.Color(failedStageHeader, "red+h")) // nolint: errcheck
+
+	for _, line := range dw.stageLines {
+		fmt.Fprintln(dw.writer, line) // nolint: errcheck
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+type deprecateCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newDeprecateCommandeer(rootCommandeer *RootCommandeer) *deprecateCommandeer {
+	commandeer := &deprecateCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "deprecate",
+		Short: "Mark resources as deprecated",
+	}
+
+	cmd.AddCommand(
+		newDeprecateFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type deprecateFunctionCommandeer struct {
+	*deprecateCommandeer
+	message string
+	date    string
+	undo    bool
+
+	// deprecation is set on the live function, which requires updating functions in place
+	supportedPlatforms []string
+}
+
+func newDeprecateFunctionCommandeer(deprecateCommandeer *deprecateCommandeer) *deprecateFunctionCommandeer {
+	commandeer := &deprecateFunctionCommandeer{
+		deprecateCommandeer: deprecateCommandeer,
+		supportedPlatforms:  kubePlatforms,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Mark a deployed function as deprecated, optionally with a message and a removal date",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.undo && (commandeer.message != "" || commandeer.date != "") {
+				return errors.New("--message and --date can't be used with --undo")
+			}
+
+			// initialize root
+			if err := deprecateCommandeer.rootCommandeer.initializeForOperation("deprecate function",
+				commandeer.supportedPlatforms); err != nil {
+				return err
+			}
+
+			return commandeer.deprecateFunction(args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.message, "message", "", "Why the function is deprecated and what to use instead")
+	cmd.Flags().StringVar(&commandeer.date, "date", "", "Date (YYYY-MM-DD) on which the function is to be removed")
+	cmd.Flags().BoolVar(&commandeer.undo, "undo", false, "Unmark the function as deprecated")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (d *deprecateFunctionCommandeer) deprecateFunction(functionName string) error {
+	rootCommandeer := d.rootCommandeer
+
+	functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: rootCommandeer.namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	// only the annotations are updated, so that the function isn't redeployed
+	functionMeta := functions[0].GetConfig().Meta
+
+	functionMeta.Annotations = map[string]string{}
+	for annotationKey, annotationValue := range functions[0].GetConfig().Meta.Annotations {
+		functionMeta.Annotations[annotationKey] = annotationValue
+	}
+
+	if d.undo {
+		functionMeta.RemoveDeprecation()
+	} else if err := functionMeta.SetDeprecation(d.message, d.date); err != nil {
+		return errors.Wrap(err, "Failed to deprecate function")
+	}
+
+	if err := rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+		FunctionMeta: &functionMeta,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to update function")
+	}
+
+	if d.undo {
+		rootCommandeer.loggerInstance.InfoWith("Function is no longer deprecated", "name", functionName)
+	} else {
+		rootCommandeer.loggerInstance.InfoWith("Function deprecated", "name", functionName)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+type diffCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newDiffCommandeer(rootCommandeer *RootCommandeer) *diffCommandeer {
+	commandeer := &diffCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the differences between deployed resources and their desired configuration",
+	}
+
+	cmd.AddCommand(
+		newDiffFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type diffFunctionCommandeer struct {
+	*diffCommandeer
+	functionConfigPath string
+	contextLines       int
+	exitCode           bool
+}
+
+func newDiffFunctionCommandeer(diffCommandeer *diffCommandeer) *diffFunctionCommandeer {
+	commandeer := &diffFunctionCommandeer{
+		diffCommandeer: diffCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name -f function-config",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Show how a deployed function differs from a configuration file, as a unified diff",
+		Long: fmt.Sprintf(`Show how a deployed function differs from a configuration file, as a unified diff.
+
+Fields populated by the platform are ignored. Exits with %d if there are differences (unless
+--exit-code=false), so that it can be used to gate deployments or detect live changes in CI`, ExitCodeFunctionDiffers),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.functionConfigPath == "" {
+				return errors.New("Function configuration file must be provided (-f)")
+			}
+
+			// initialize root
+			if err := diffCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			unifiedDiff, err := commandeer.diffFunction(args[0])
+			if err != nil {
+				return errors.Wrap(err, "Failed to diff function")
+			}
+
+			if unifiedDiff == "" {
+				return nil
+			}
+
+			cmd.OutOrStdout().Write([]byte(unifiedDiff)) // nolint: errcheck
+
+			if !commandeer.exitCode {
+				return nil
+			}
+
+			return &FunctionDiffersError{
+				Name: args[0],
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.functionConfigPath, "file", "f", "", "Path to the desired function configuration file")
+	cmd.Flags().IntVar(&commandeer.contextLines, "context", 3, "Number of unchanged lines to show around each difference")
+	cmd.Flags().BoolVar(&commandeer.exitCode, "exit-code", true, fmt.Sprintf("Exit with %d if there are differences, rather than only showing them", ExitCodeFunctionDiffers))
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// diffFunction returns a unified diff of the live function's configuration and the desired one, or an empty
+// string if they're the same. a function that doesn't exist is diffed as an empty configuration
+func (d *diffFunctionCommandeer) diffFunction(functionName string) (string, error) {
+	desiredConfig, err := readFunctionConfigFile(d.functionConfigPath, d.rootCommandeer.namespace)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read function configuration")
+	}
+
+	desiredConfig.Meta.Name = functionName
+
+	functions, err := d.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: d.rootCommandeer.namespace,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get functions")
+	}
+
+	liveLines := []string{}
+	if len(functions) > 0 {
+		liveLines, err = d.getDiffableLines(functions[0].GetConfig(), desiredConfig)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to encode live function configuration")
+		}
+	}
+
+	desiredLines, err := d.getDiffableLines(desiredConfig, desiredConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode desired function configuration")
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        liveLines,
+		B:        desiredLines,
+		FromFile: "live/" + functionName,
+		ToFile:   d.functionConfigPath,
+		Context:  d.contextLines,
+	})
+}
+
+// getDiffableLines returns the lines of the normalized function configuration as YAML, which has its keys sorted
+func (d *diffFunctionCommandeer) getDiffableLines(functionConfig *functionconfig.Config,
+	desiredConfig *functionconfig.Config) ([]string, error) {
+
+	normalizedConfig, err := normalizeFunctionConfig(functionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to normalize function configuration")
+	}
+
+	// the namespace is given
+	normalizedConfig.Meta.Namespace = ""
+
+	// the image is the one built for the function, unless one is explicitly desired
+	if desiredConfig.Spec.Image == "" {
+		normalizedConfig.Spec.Image = ""
+	}
+
+	encodedConfig, err := yaml.Marshal(normalizedConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode function configuration")
+	}
+
+	return difflib.SplitLines(string(encodedConfig)), nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/nuctl/doctor"
+	"github.com/nuclio/nuclio/pkg/platform/factory"
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type doctorCommandeer struct {
+	cmd                 *cobra.Command
+	rootCommandeer      *RootCommandeer
+	registry            string
+	registryCredentials registryCredentials
+	runtimes            stringSliceFlag
+}
+
+func newDoctorCommandeer(rootCommandeer *RootCommandeer) *doctorCommandeer {
+	commandeer := &doctorCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment functions are built and deployed in",
+		Long: `Check the environment functions are built and deployed in, printing how to fix the problems found:
+  - the docker daemon is reachable, and recent enough
+  - the platform configuration (platform, container builder, kubeconfig and context) is valid
+  - the kube context's cluster is reachable, has nuclio installed and permits deploying functions (kube platform)
+  - the registry is reachable, and accepts the credentials
+  - the onbuild images functions are built with are available`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// the checks cover the platform, so it's not created (which may fail on one of them)
+			rootCommandeer.loggerInstance, err = rootCommandeer.createLogger()
+			if err != nil {
+				return errors.Wrap(err, "Failed to create logger")
+			}
+
+			cmdRunner, err := cmdrunner.NewShellRunner(rootCommandeer.loggerInstance)
+			if err != nil {
+				return errors.Wrap(err, "Failed to create command runner")
+			}
+
+			options := &doctor.Options{
+				PlatformName:   rootCommandeer.platformName,
+				Namespace:      rootCommandeer.namespace,
+				KubeconfigPath: factory.GetKubeconfigPath(&rootCommandeer.kubeConfiguration),
+				KubeContext:    rootCommandeer.kubeConfiguration.KubeContext,
+				Registry:       commandeer.registry,
+				Runtimes:       commandeer.runtimes,
+			}
+
+			if commandeer.registry != "" {
+				options.RegistryCredentials, err = commandeer.registryCredentials.resolve(rootCommandeer.loggerInstance,
+					cmdRunner,
+					commandeer.registry)
+				if err != nil {
+					return errors.Wrap(err, "Failed to resolve registry credentials")
+				}
+			}
+
+			doctorInstance := doctor.NewDoctor(rootCommandeer.loggerInstance,
+				cmdRunner,
+				factory.GetContainerBuilderConfiguration(&rootCommandeer.kubeConfiguration))
+
+			results := doctorInstance.Run(options)
+
+			if rootCommandeer.isJSONOutput() {
+				if err := rootCommandeer.renderResult(cmd.OutOrStdout(), results); err != nil {
+					return errors.Wrap(err, "Failed to render results")
+				}
+			} else {
+				commandeer.renderResults(cmd, results)
+			}
+
+			if failedChecks := doctor.GetFailedChecks(results); failedChecks > 0 {
+				return errors.Errorf("%d of %d checks failed", failedChecks, len(results))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.registry, "registry", "r", os.Getenv("NUCTL_REGISTRY"), "URL of the container registry functions are pushed to (env: NUCTL_REGISTRY)")
+	cmd.Flags().Var(&commandeer.runtimes, "runtime", "Runtime whose onbuild images are checked, may be repeated (default - all runtimes)")
+	addRegistryCredentialsFlags(cmd, &commandeer.registryCredentials)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (d *doctorCommandeer) renderResults(cmd *cobra.Command, results []doctor.CheckResult) {
+	var records [][]string
+	for _, result := range results {
+		records = append(records, []string{result.Name, string(result.Status), result.Message})
+	}
+
+	renderer.NewRenderer(cmd.OutOrStdout()).RenderTable([]string{"Check", "Status", "Details"}, records)
+
+	var remediations []doctor.CheckResult
+	for _, result := range results {
+		if result.Remediation != "" {
+			remediations = append(remediations, result)
+		}
+	}
+
+	if len(remediations) == 0 {
+		return
+	}
+
+	cmd.Println("\nTo fix:")
+	for _, result := range remediations {
+		cmd.Printf("  - %s: %s\n", result.Name, result.Remediation)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+// the command run when none is given - the container's bash if it has one, or its sh
+var defaultExecCommand = []string{"sh", "-c", "command -v bash >/dev/null && exec bash || exec sh"}
+
+// CommandExitError is returned when a command run by nuctl exits with a non zero exit code, which nuctl
+// exits with too
+type CommandExitError struct {
+	ExitCode int
+}
+
+func (e *CommandExitError) Error() string {
+	return fmt.Sprintf("Command exited with exit code %d", e.ExitCode)
+}
+
+type execCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newExecCommandeer(rootCommandeer *RootCommandeer) *execCommandeer {
+	commandeer := &execCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "exec",
+		Short: "Run commands inside resources",
+	}
+
+	cmd.AddCommand(
+		newExecFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type execFunctionCommandeer struct {
+	*execCommandeer
+	getFunctionExecCommandOptions platform.GetFunctionExecCommandOptions
+	projectName                   string
+}
+
+func newExecFunctionCommandeer(execCommandeer *execCommandeer) *execFunctionCommandeer {
+	commandeer := &execFunctionCommandeer{
+		execCommandeer: execCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name [-- command [args...]]",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Run a shell, or a command, inside a function's container",
+		Long: `Run a shell, or the command given after --, inside the container of a function's replica (a pod on kube,
+the function's container on local). A terminal is allocated when nuctl's input is a terminal, and nuctl
+exits with the command's exit code.
+
+A function with more than one replica requires choosing one with --replica. On kube, kubectl must be
+installed. For example:
+
+    nuctl exec function my-function
+    nuctl exec function my-function -- ls -l /opt/nuclio`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if argsLenAtDash := cmd.ArgsLenAtDash(); argsLenAtDash > 1 || (argsLenAtDash == -1 && len(args) > 1) {
+				return errors.New("Expected a single function name, and the command to run after --")
+			}
+
+			rootCommandeer := execCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			commandeer.getFunctionExecCommandOptions.Name = args[0]
+			commandeer.getFunctionExecCommandOptions.Namespace = rootCommandeer.namespace
+			commandeer.getFunctionExecCommandOptions.Command = args[1:]
+
+			if len(commandeer.getFunctionExecCommandOptions.Command) == 0 {
+				commandeer.getFunctionExecCommandOptions.Command = defaultExecCommand
+			}
+
+			if err := commandeer.verifyFunctionProject(); err != nil {
+				return err
+			}
+
+			// allocate a terminal only if there's one to attach to it
+			if stdinFile, isFile := cmd.InOrStdin().(*os.File); isFile {
+				if stdinInfo, err := stdinFile.Stat(); err == nil && stdinInfo.Mode()&os.ModeCharDevice != 0 {
+					commandeer.getFunctionExecCommandOptions.TTY = true
+				}
+			}
+
+			execCommand, err := rootCommandeer.platform.GetFunctionExecCommand(&commandeer.getFunctionExecCommandOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get function exec command")
+			}
+
+			return commandeer.runCommand(cmd, execCommand)
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.getFunctionExecCommandOptions.Replica, "replica", "", "The replica to run the command in - a pod name on kube, the container name on local")
+	cmd.Flags().StringVar(&commandeer.projectName, "project-name", "", "Only run the command if the function belongs to this project")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (e *execFunctionCommandeer) verifyFunctionProject() error {
+	if e.projectName == "" {
+		return nil
+	}
+
+	functions, err := e.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      e.getFunctionExecCommandOptions.Name,
+		Namespace: e.getFunctionExecCommandOptions.Namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	if functionProjectName := functions[0].GetConfig().Meta.Labels["nuclio.io/project-name"]; functionProjectName != e.projectName {
+		return errors.Errorf("Function %s belongs to project %s, not %s",
+			e.getFunctionExecCommandOptions.Name,
+			functionProjectName,
+			e.projectName)
+	}
+
+	return nil
+}
+
+func (e *execFunctionCommandeer) runCommand(cmd *cobra.Command, execCommand []string) error {
+	command := exec.Command(execCommand[0], execCommand[1:]...)
+	command.Stdin = cmd.InOrStdin()
+	command.Stdout = cmd.OutOrStdout()
+	command.Stderr = cmd.ErrOrStderr()
+
+	if err := command.Run(); err != nil {
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			return &CommandExitError{ExitCode: exitErr.ExitCode()}
+		}
+
+		return errors.Wrapf(err, "Failed to run %s", execCommand[0])
+	}
+
+	return nil
+}
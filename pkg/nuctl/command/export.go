@@ -1,12 +1,16 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/renderer"
 
+	"github.com/ghodss/yaml"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/spf13/cobra"
@@ -29,10 +33,12 @@ func newExportCommandeer(rootCommandeer *RootCommandeer) *exportCommandeer {
 
 	exportFunctionCommand := newExportFunctionCommandeer(commandeer).cmd
 	exportProjectCommand := newExportProjectCommandeer(commandeer).cmd
+	exportBundleCommand := newExportBundleCommandeer(commandeer).cmd
 
 	cmd.AddCommand(
 		exportFunctionCommand,
 		exportProjectCommand,
+		exportBundleCommand,
 	)
 
 	commandeer.cmd = cmd
@@ -44,7 +50,15 @@ type exportFunctionCommandeer struct {
 	*exportCommandeer
 	getFunctionsOptions platform.GetFunctionsOptions
 	output              string
+	yamlIndent          int
 	noScrub             bool
+	includeEvents       bool
+	scrubSecrets        bool
+	secretsOutputPath   string
+	encryptKey          string
+
+	// the values scrubbed from each function, by function name
+	scrubbedSecrets map[string]map[string]string
 }
 
 func newExportFunctionCommandeer(exportCommandeer *exportCommandeer) *exportFunctionCommandeer {
@@ -65,6 +79,18 @@ func newExportFunctionCommandeer(exportCommandeer *exportCommandeer) *exportFunc
 				commandeer.getFunctionsOptions.Name = args[0]
 			}
 
+			if err := common.ValidateYAMLIndent(commandeer.output, commandeer.yamlIndent); err != nil {
+				return err
+			}
+
+			if commandeer.secretsOutputPath != "" && !commandeer.scrubSecrets {
+				return errors.New("--secrets-output can only be used with --scrub-secrets")
+			}
+
+			if cmd.Flags().Changed("encrypt-key") && commandeer.encryptKey == "" {
+				return errors.New("--encrypt-key must not be empty")
+			}
+
 			// initialize root
 			if err := exportCommandeer.rootCommandeer.initialize(); err != nil {
 				return errors.Wrap(err, "Failed to initialize root")
@@ -85,17 +111,53 @@ func newExportFunctionCommandeer(exportCommandeer *exportCommandeer) *exportFunc
 				return nil
 			}
 
-			// render the functions
-			return common.RenderFunctions(commandeer.rootCommandeer.loggerInstance,
+			commandeer.scrubbedSecrets = map[string]map[string]string{}
+
+			// render the functions, to be encrypted if asked to
+			var renderedFunctions bytes.Buffer
+			if err := common.RenderFunctions(commandeer.rootCommandeer.loggerInstance,
 				functions,
 				commandeer.output,
-				cmd.OutOrStdout(),
-				commandeer.renderFunctionConfig)
+				commandeer.yamlIndent,
+				&renderedFunctions,
+				commandeer.renderFunctionConfig); err != nil {
+				return err
+			}
+
+			if commandeer.secretsOutputPath != "" {
+				if err := commandeer.writeScrubbedSecrets(); err != nil {
+					return errors.Wrap(err, "Failed to write scrubbed secrets")
+				}
+			}
+
+			if commandeer.encryptKey == "" {
+				_, err = cmd.OutOrStdout().Write(renderedFunctions.Bytes())
+				return err
+			}
+
+			encryptedExport, err := common.EncryptExport(renderedFunctions.Bytes(), commandeer.encryptKey)
+			if err != nil {
+				return errors.Wrap(err, "Failed to encrypt export")
+			}
+
+			rendererInstance := renderer.NewRenderer(cmd.OutOrStdout())
+			if commandeer.output == common.OutputFormatJSON {
+				return rendererInstance.RenderJSON(encryptedExport)
+			}
+
+			return rendererInstance.RenderYAML(encryptedExport)
 		},
 	}
 
 	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatYAML, "Output format - \"yaml\", or \"json\"")
+	cmd.PersistentFlags().IntVar(&commandeer.yamlIndent, "indent", 0, "Indentation width of \"yaml\" output (1-8, default 2)")
 	cmd.PersistentFlags().BoolVar(&commandeer.noScrub, "no-scrub", false, "Allow function sensitive data to be exported")
+	cmd.PersistentFlags().BoolVar(&commandeer.includeEvents, "include-events", false, "Export the functions' events along with them, for import functions")
+	cmd.PersistentFlags().BoolVar(&commandeer.scrubSecrets, "scrub-secrets", false, "Replace sensitive values (e.g. passwords, access keys, tokens in env vars) with references, which import functions --secrets-file restores")
+	cmd.PersistentFlags().StringVar(&commandeer.secretsOutputPath, "secrets-output", "", "Write the values scrubbed by --scrub-secrets to this file, for import functions --secrets-file")
+	cmd.PersistentFlags().StringVar(&commandeer.encryptKey, "encrypt-key", "", "Encrypt the export (AES-256-GCM) with a key derived from this passphrase, for import functions --decrypt-key")
+
+	completeFunctionName(cmd)
 
 	commandeer.cmd = cmd
 
@@ -106,10 +168,38 @@ func (e *exportFunctionCommandeer) renderFunctionConfig(functions []platform.Fun
 	functionConfigs := map[string]*functionconfig.Config{}
 	for _, function := range functions {
 		functionConfig := function.GetConfig()
+
+		// before the default scrubbing, which removes some of the values
+		if e.scrubSecrets {
+			scrubbedSecrets := functionConfig.ScrubSecrets(functionconfig.DefaultSensitiveKeyPattern)
+			if len(scrubbedSecrets) > 0 {
+				e.scrubbedSecrets[functionConfig.Meta.Name] = scrubbedSecrets
+			}
+		}
+
 		functionConfig.PrepareFunctionForExport(e.noScrub)
 		functionConfigs[functionConfig.Meta.Name] = functionConfig
 	}
 
+	// with their events, the functions are exported as a whole, however many there are
+	if e.includeEvents {
+		functionEventConfigs := map[string]*platform.FunctionEventConfig{}
+		for _, functionConfig := range functionConfigs {
+			if err := e.exportFunctionEvents(functionConfig, functionEventConfigs); err != nil {
+				return errors.Wrap(err, "Failed to export function events")
+			}
+		}
+
+		if err := renderer(&FunctionImportConfig{
+			Functions:      functionConfigs,
+			FunctionEvents: functionEventConfigs,
+		}); err != nil {
+			return errors.Wrap(err, "Failed to render function config")
+		}
+
+		return nil
+	}
+
 	var err error
 	if len(functions) == 1 {
 		err = renderer(functionConfigs[functions[0].GetConfig().Meta.Name])
@@ -123,10 +213,21 @@ func (e *exportFunctionCommandeer) renderFunctionConfig(functions []platform.Fun
 	return nil
 }
 
+// writeScrubbedSecrets writes the scrubbed values, by function name, readable by the user alone
+func (e *exportFunctionCommandeer) writeScrubbedSecrets() error {
+	encodedScrubbedSecrets, err := yaml.Marshal(e.scrubbedSecrets)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode scrubbed secrets")
+	}
+
+	return ioutil.WriteFile(e.secretsOutputPath, encodedScrubbedSecrets, 0600)
+}
+
 type exportProjectCommandeer struct {
 	*exportCommandeer
 	getProjectsOptions platform.GetProjectsOptions
 	output             string
+	outputArchivePath  string
 }
 
 func newExportProjectCommandeer(exportCommandeer *exportCommandeer) *exportProjectCommandeer {
@@ -137,7 +238,7 @@ func newExportProjectCommandeer(exportCommandeer *exportCommandeer) *exportProje
 	cmd := &cobra.Command{
 		Use:     "projects [name]",
 		Aliases: []string{"proj", "prj", "project"},
-		Short:   "(or project) Export project with all it's functions and function events",
+		Short:   "(or project) Export project with all it's functions, function events and API gateways",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if we got positional arguments
@@ -152,6 +253,16 @@ func newExportProjectCommandeer(exportCommandeer *exportCommandeer) *exportProje
 				return errors.Wrap(err, "Failed to initialize root")
 			}
 
+			// a single project is written to an archive, like export bundle does
+			if commandeer.outputArchivePath != "" {
+				if commandeer.getProjectsOptions.Meta.Name == "" {
+					return errors.New("Project name must be provided to export it to an archive")
+				}
+
+				return commandeer.exportProjectBundle(commandeer.getProjectsOptions.Meta.Name,
+					commandeer.outputArchivePath)
+			}
+
 			// get namespace
 			commandeer.getProjectsOptions.Meta.Namespace = exportCommandeer.rootCommandeer.namespace
 
@@ -174,13 +285,16 @@ func newExportProjectCommandeer(exportCommandeer *exportCommandeer) *exportProje
 	}
 
 	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatYAML, "Output format - \"yaml\", or \"json\"")
+	cmd.Flags().StringVar(&commandeer.outputArchivePath, "output-archive", "", "Write the project with its functions, function events and API gateways to this tar archive rather than the output (gzip compressed if it ends with .gz or .tgz), for import projects")
+
+	completeProjectName(cmd)
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
-func (e *exportProjectCommandeer) getFunctionEvents(functionConfig *functionconfig.Config) ([]platform.FunctionEvent, error) {
+func (e *exportCommandeer) getFunctionEvents(functionConfig *functionconfig.Config) ([]platform.FunctionEvent, error) {
 	getFunctionEventOptions := platform.GetFunctionEventsOptions{
 		Meta: platform.FunctionEventMeta{
 			Name:      "",
@@ -199,6 +313,23 @@ func (e *exportProjectCommandeer) getFunctionEvents(functionConfig *functionconf
 	return functionEvents, nil
 }
 
+// exportFunctionEvents adds the events of the function to the given events, by name, without their namespace
+func (e *exportCommandeer) exportFunctionEvents(functionConfig *functionconfig.Config,
+	functionEventConfigs map[string]*platform.FunctionEventConfig) error {
+	functionEvents, err := e.getFunctionEvents(functionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function events")
+	}
+
+	for _, functionEvent := range functionEvents {
+		functionEventConfig := functionEvent.GetConfig()
+		functionEventConfig.Meta.Namespace = ""
+		functionEventConfigs[functionEventConfig.Meta.Name] = functionEventConfig
+	}
+
+	return nil
+}
+
 func (e *exportProjectCommandeer) exportProjectFunctionsAndFunctionEvents(projectConfig *platform.ProjectConfig) (
 	map[string]*functionconfig.Config, map[string]*platform.FunctionEventConfig, error) {
 	getFunctionOptions := &platform.GetFunctionsOptions{
@@ -218,14 +349,8 @@ func (e *exportProjectCommandeer) exportProjectFunctionsAndFunctionEvents(projec
 		}
 		functionConfig := function.GetConfig()
 
-		functionEvents, err := e.getFunctionEvents(functionConfig)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "Failed to get function events")
-		}
-		for _, functionEvent := range functionEvents {
-			functionEventConfig := functionEvent.GetConfig()
-			functionEventConfig.Meta.Namespace = ""
-			functionEventMap[functionEventConfig.Meta.Name] = functionEventConfig
+		if err := e.exportFunctionEvents(functionConfig, functionEventMap); err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to export function events")
 		}
 
 		functionConfig.PrepareFunctionForExport(false)
@@ -235,16 +360,73 @@ func (e *exportProjectCommandeer) exportProjectFunctionsAndFunctionEvents(projec
 	return functionMap, functionEventMap, nil
 }
 
+// exportProjectAPIGateways returns the API gateways routing only to the given functions of the project, by name,
+// without their namespace and status. API gateways aren't labeled with a project, so those routing to functions
+// of other projects aren't exported
+func (e *exportProjectCommandeer) exportProjectAPIGateways(projectConfig *platform.ProjectConfig,
+	functions map[string]*functionconfig.Config) (map[string]*platform.APIGatewayConfig, error) {
+	apiGateways, err := e.rootCommandeer.platform.GetAPIGateways(&platform.GetAPIGatewaysOptions{
+		Meta: platform.APIGatewayMeta{
+			Namespace: projectConfig.Meta.Namespace,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	apiGatewayMap := map[string]*platform.APIGatewayConfig{}
+
+	for _, apiGateway := range apiGateways {
+		apiGatewayConfig := *apiGateway.GetConfig()
+
+		routesToProjectFunctions := true
+		for _, upstreamFunctionName := range getAPIGatewayUpstreamFunctionNames(&apiGatewayConfig) {
+			if _, found := functions[upstreamFunctionName]; !found {
+				routesToProjectFunctions = false
+			}
+		}
+
+		if !routesToProjectFunctions {
+			continue
+		}
+
+		apiGatewayConfig.Meta.Namespace = ""
+		apiGatewayConfig.Status = platform.APIGatewayStatus{}
+		apiGatewayMap[apiGatewayConfig.Meta.Name] = &apiGatewayConfig
+	}
+
+	return apiGatewayMap, nil
+}
+
+// getAPIGatewayUpstreamFunctionNames returns the names of the functions the API gateway routes to
+func getAPIGatewayUpstreamFunctionNames(apiGatewayConfig *platform.APIGatewayConfig) []string {
+	var functionNames []string
+
+	for _, upstream := range apiGatewayConfig.Spec.Upstreams {
+		if upstream.NuclioFunction != nil {
+			functionNames = append(functionNames, upstream.NuclioFunction.Name)
+		}
+	}
+
+	return functionNames
+}
+
 func (e *exportProjectCommandeer) exportProject(projectConfig *platform.ProjectConfig) (map[string]interface{}, error) {
 	functions, functionEvents, err := e.exportProjectFunctionsAndFunctionEvents(projectConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	apiGateways, err := e.exportProjectAPIGateways(projectConfig, functions)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
 		"project":        projectConfig,
 		"functions":      functions,
 		"functionEvents": functionEvents,
+		"apiGateways":    apiGateways,
 	}, nil
 }
 
@@ -254,7 +436,7 @@ func (e *exportProjectCommandeer) renderProjectConfig(projects []platform.Projec
 		projectConfig := project.GetConfig()
 		projectExport, err := e.exportProject(projectConfig)
 		if err != nil {
-			return errors.Wrap(err, "Failed to gather functions and function events")
+			return errors.Wrap(err, "Failed to gather functions, function events and API gateways")
 		}
 		projectConfigs[projectConfig.Meta.Name] = projectExport
 	}
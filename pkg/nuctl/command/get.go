@@ -17,8 +17,18 @@ limitations under the License.
 package command
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/factory"
+	"github.com/nuclio/nuclio/pkg/platform/kube"
+	"github.com/nuclio/nuclio/pkg/renderer"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -28,11 +38,15 @@ import (
 type getCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
+
+	// the platforms resources can be listed across all namespaces on (get --all-namespaces)
+	allNamespacesSupportedPlatforms []string
 }
 
 func newGetCommandeer(rootCommandeer *RootCommandeer) *getCommandeer {
 	commandeer := &getCommandeer{
-		rootCommandeer: rootCommandeer,
+		rootCommandeer:                  rootCommandeer,
+		allNamespacesSupportedPlatforms: kubePlatforms,
 	}
 
 	cmd := &cobra.Command{
@@ -43,11 +57,19 @@ func newGetCommandeer(rootCommandeer *RootCommandeer) *getCommandeer {
 	getFunctionCommand := newGetFunctionCommandeer(commandeer).cmd
 	getProjectCommand := newGetProjectCommandeer(commandeer).cmd
 	getFunctionEventCommand := newGetFunctionEventCommandeer(commandeer).cmd
+	getAPIGatewayCommand := newGetAPIGatewayCommandeer(commandeer).cmd
+	getTemplatesCommand := newGetTemplatesCommandeer(commandeer).cmd
+	getAuditCommand := newGetAuditCommandeer(commandeer).cmd
+	getInvocationsCommand := newGetInvocationsCommandeer(commandeer).cmd
 
 	cmd.AddCommand(
 		getFunctionCommand,
 		getProjectCommand,
 		getFunctionEventCommand,
+		getAPIGatewayCommand,
+		getTemplatesCommand,
+		getAuditCommand,
+		getInvocationsCommand,
 	)
 
 	commandeer.cmd = cmd
@@ -55,15 +77,59 @@ func newGetCommandeer(rootCommandeer *RootCommandeer) *getCommandeer {
 	return commandeer
 }
 
+// initialize initializes the root - for getting the resources of all namespaces, if requested, which only
+// some platforms support
+func (g *getCommandeer) initialize(operation string, allNamespaces bool) error {
+	if allNamespaces {
+		return g.rootCommandeer.initializeForOperation(operation+" --all-namespaces",
+			g.allNamespacesSupportedPlatforms)
+	}
+
+	if err := g.rootCommandeer.initialize(); err != nil {
+		return errors.Wrap(err, "Failed to initialize root")
+	}
+
+	return nil
+}
+
 type getFunctionCommandeer struct {
 	*getCommandeer
 	getFunctionsOptions platform.GetFunctionsOptions
 	output              string
+	yamlIndent          int
+	group               string
+	sortBy              string
+	reverse             bool
+	describeEnv         bool
+	allContexts         bool
+	warnDeprecated      bool
+	failOnDeprecated    bool
+	checkSecretRefs     bool
+	watch               bool
+	watchInterval       time.Duration
+	untilSettled        bool
+	revisions           bool
+	streamStatus        bool
+
+	// the functions of every context are listed through the kube platform, as are references to secrets
+	// and configmaps resolved
+	allContextsSupportedPlatforms     []string
+	checkSecretRefsSupportedPlatforms []string
+}
+
+// the states a function stays in until it's changed, which --until-settled waits for
+var settledFunctionStates = []functionconfig.FunctionState{
+	functionconfig.FunctionStateReady,
+	functionconfig.FunctionStateError,
+	functionconfig.FunctionStateScaledToZero,
+	functionconfig.FunctionStateImported,
 }
 
 func newGetFunctionCommandeer(getCommandeer *getCommandeer) *getFunctionCommandeer {
 	commandeer := &getFunctionCommandeer{
-		getCommandeer: getCommandeer,
+		getCommandeer:                     getCommandeer,
+		allContextsSupportedPlatforms:     []string{"kube"},
+		checkSecretRefsSupportedPlatforms: []string{"kube"},
 	}
 
 	cmd := &cobra.Command{
@@ -79,16 +145,73 @@ func newGetFunctionCommandeer(getCommandeer *getCommandeer) *getFunctionCommande
 				commandeer.getFunctionsOptions.Name = args[0]
 			}
 
+			if err := common.ValidateYAMLIndent(commandeer.output, commandeer.yamlIndent); err != nil {
+				return err
+			}
+
+			if err := commandeer.validateOutput(); err != nil {
+				return err
+			}
+
+			if commandeer.sortBy != "" {
+				if err := common.ValidateFunctionSortBy(commandeer.sortBy); err != nil {
+					return err
+				}
+			} else if commandeer.reverse {
+				return errors.New("--reverse can only be used with --sort-by")
+			}
+
+			if err := commandeer.validateWatch(); err != nil {
+				return err
+			}
+
+			if err := commandeer.validateRevisions(); err != nil {
+				return err
+			}
+
+			if err := commandeer.validateStreamStatus(); err != nil {
+				return err
+			}
+
+			if commandeer.allContexts {
+				if commandeer.checkSecretRefs {
+					return errors.New("--check-secret-refs can't be used with --context-all")
+				}
+
+				return commandeer.getAllContextsFunctions(cmd)
+			}
+
 			// initialize root
-			if err := getCommandeer.rootCommandeer.initialize(); err != nil {
-				return errors.Wrap(err, "Failed to initialize root")
+			if err := getCommandeer.initialize("get functions",
+				commandeer.getFunctionsOptions.AllNamespaces); err != nil {
+				return err
+			}
+
+			// resolving references to secrets and configmaps requires access to them
+			if commandeer.checkSecretRefs {
+				if err := getCommandeer.rootCommandeer.validateOperationPlatform("get functions --check-secret-refs",
+					commandeer.checkSecretRefsSupportedPlatforms); err != nil {
+					return err
+				}
 			}
 
 			commandeer.getFunctionsOptions.Namespace = getCommandeer.rootCommandeer.namespace
 
-			functions, err := getCommandeer.rootCommandeer.platform.GetFunctions(&commandeer.getFunctionsOptions)
+			if commandeer.watch {
+				return commandeer.watchFunctions(cmd)
+			}
+
+			if commandeer.revisions {
+				return commandeer.renderFunctionRevisions(cmd)
+			}
+
+			if commandeer.streamStatus {
+				return commandeer.renderFunctionStreamStatus(cmd)
+			}
+
+			functions, err := commandeer.getFunctions()
 			if err != nil {
-				return errors.Wrap(err, "Failed to get functions")
+				return err
 			}
 
 			if len(functions) == 0 {
@@ -99,23 +222,495 @@ func newGetFunctionCommandeer(getCommandeer *getCommandeer) *getFunctionCommande
 				return nil
 			}
 
+			if commandeer.describeEnv {
+				return common.RenderFunctionsEnv(commandeer.rootCommandeer.loggerInstance,
+					functions,
+					commandeer.output,
+					cmd.OutOrStdout())
+			}
+
 			// render the functions
-			return common.RenderFunctions(commandeer.rootCommandeer.loggerInstance,
+			if err := common.RenderFunctions(commandeer.rootCommandeer.loggerInstance,
 				functions,
 				commandeer.output,
+				commandeer.yamlIndent,
 				cmd.OutOrStdout(),
-				commandeer.renderFunctionConfig)
+				commandeer.renderFunctionConfig); err != nil {
+				return err
+			}
+
+			if commandeer.checkSecretRefs {
+				kubePlatform := getCommandeer.rootCommandeer.platform.(*kube.Platform)
+				if err := commandeer.checkFunctionsReferences(cmd, kubePlatform, functions); err != nil {
+					return err
+				}
+			}
+
+			return commandeer.checkDeprecatedFunctions(cmd, functions)
 		},
 	}
 
 	cmd.PersistentFlags().StringVarP(&commandeer.getFunctionsOptions.Labels, "labels", "l", "", "Function labels (lbl1=val1[,lbl2=val2,...])")
-	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", \"json\", or \"jsonpath=<template>\" (e.g. jsonpath={.name} {.externalURL})")
+	cmd.PersistentFlags().StringVar(&commandeer.group, "group", "", "Only the functions deployed as part of this group (deploy --function-group)")
+	cmd.PersistentFlags().StringVar(&commandeer.sortBy, "sort-by", "", "Sort the functions by \"name\", \"state\", or \"created\" (ties are sorted by name)")
+	cmd.PersistentFlags().BoolVar(&commandeer.reverse, "reverse", false, "Sort the functions in descending order (with --sort-by)")
+	cmd.PersistentFlags().IntVar(&commandeer.yamlIndent, "indent", 0, "Indentation width of \"yaml\" output (1-8, default 2)")
+	cmd.PersistentFlags().BoolVar(&commandeer.describeEnv, "describe-env", false, fmt.Sprintf("List the functions' environment variables, described by \"%s<name>\" annotations", functionconfig.FunctionAnnotationEnvDescriptionPrefix))
+	cmd.PersistentFlags().BoolVar(&commandeer.allContexts, "context-all", false, "List the functions of all the contexts in the kubeconfig")
+	cmd.PersistentFlags().BoolVarP(&commandeer.getFunctionsOptions.AllNamespaces, "all-namespaces", "A", false, "List the functions of all namespaces (kube only)")
+	cmd.PersistentFlags().BoolVar(&commandeer.warnDeprecated, "warn-deprecated", false, "Warn about deprecated functions, with their deprecation message and removal date")
+	cmd.PersistentFlags().BoolVar(&commandeer.failOnDeprecated, "fail-on-deprecated", false, "Fail if any of the functions are deprecated (implies --warn-deprecated)")
+	cmd.PersistentFlags().BoolVar(&commandeer.checkSecretRefs, "check-secret-refs", false, "Fail if any of the secrets, configmaps or keys in them that the functions reference don't exist (kube only)")
+	cmd.PersistentFlags().BoolVarP(&commandeer.watch, "watch", "w", false, "Keep watching the functions, rendering them again whenever one of them changes state")
+	cmd.PersistentFlags().DurationVar(&commandeer.watchInterval, "watch-interval", 2*time.Second, "How often the functions are polled for changes (with --watch)")
+	cmd.PersistentFlags().BoolVar(&commandeer.revisions, "revisions", false, "List the revisions the function was deployed with (see rollback function --to-revision)")
+	cmd.PersistentFlags().BoolVar(&commandeer.streamStatus, "stream-status", false, "Show the shards each of the function's replicas consumes through its stream triggers (e.g. v3io stream), with their lag")
+	cmd.PersistentFlags().BoolVar(&commandeer.untilSettled, "until-settled", false, fmt.Sprintf("Stop watching once all the functions are in a settled state (%s), failing if any of them is in error (with --watch)", joinFunctionStates(settledFunctionStates)))
+
+	completeFunctionName(cmd)
+
+	cmd.AddCommand(
+		newGetFunctionLogsCommandeer(getCommandeer).cmd,
+	)
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
+// getAllContextsFunctions lists the functions of every context in the kubeconfig. contexts that fail are
+// reported, but don't prevent listing the functions of the others
+func (g *getFunctionCommandeer) getAllContextsFunctions(cmd *cobra.Command) error {
+	var err error
+
+	rootCommandeer := g.rootCommandeer
+	if err := rootCommandeer.validateGivenPlatform("get functions --context-all",
+		g.allContextsSupportedPlatforms); err != nil {
+		return err
+	}
+
+	if g.describeEnv {
+		return errors.New("--describe-env can't be used with --context-all")
+	}
+
+	// only the logger is initialized, each context has a platform of its own
+	rootCommandeer.loggerInstance, err = rootCommandeer.createLogger()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create logger")
+	}
+
+	kubeconfigPath := factory.GetKubeconfigPath(&rootCommandeer.kubeConfiguration)
+	contexts, err := kube.GetKubeconfigContexts(kubeconfigPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get kubeconfig contexts")
+	}
+
+	functionsByContext := map[string][]platform.Function{}
+	contextErrors := map[string]error{}
+	lock := sync.Mutex{}
+
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(contexts))
+
+	for _, context := range contexts {
+		go func(context string) {
+			defer waitGroup.Done()
+
+			functions, err := g.getContextFunctions(kubeconfigPath, context)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				contextErrors[context] = err
+				return
+			}
+
+			functionsByContext[context] = functions
+		}(context)
+	}
+	waitGroup.Wait()
+
+	functionsFound := false
+	for _, functions := range functionsByContext {
+		functionsFound = functionsFound || len(functions) > 0
+	}
+
+	if functionsFound {
+		if err := common.RenderContextsFunctions(rootCommandeer.loggerInstance,
+			contexts,
+			functionsByContext,
+			g.output,
+			g.yamlIndent,
+			cmd.OutOrStdout()); err != nil {
+			return errors.Wrap(err, "Failed to render functions")
+		}
+	} else if len(contextErrors) < len(contexts) {
+		cmd.OutOrStdout().Write([]byte("No functions found")) // nolint: errcheck
+	}
+
+	if len(contextErrors) == 0 {
+		var functions []platform.Function
+		for _, context := range contexts {
+			functions = append(functions, functionsByContext[context]...)
+		}
+
+		return g.checkDeprecatedFunctions(cmd, functions)
+	}
+
+	for _, context := range contexts {
+		if contextErr, failed := contextErrors[context]; failed {
+			// the whole chain of causes, outermost first, to tell why the context failed
+			var errorMessages []string
+			errorStack := errors.GetErrorStack(contextErr, -1)
+			for errorIdx := len(errorStack) - 1; errorIdx >= 0; errorIdx-- {
+				errorMessages = append(errorMessages, errorStack[errorIdx].Error())
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get functions of context %s: %s\n", // nolint: errcheck
+				context,
+				strings.Join(errorMessages, ": "))
+		}
+	}
+
+	return errors.Errorf("Failed to get functions of %d out of %d contexts", len(contextErrors), len(contexts))
+}
+
+func (g *getFunctionCommandeer) getContextFunctions(kubeconfigPath string, context string) ([]platform.Function, error) {
+	kubeConfiguration := g.rootCommandeer.kubeConfiguration
+	kubeConfiguration.KubeconfigPath = kubeconfigPath
+	kubeConfiguration.KubeContext = context
+
+	contextPlatform, err := factory.CreatePlatform(g.rootCommandeer.loggerInstance,
+		"kube",
+		&kubeConfiguration,
+		g.rootCommandeer.namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create platform")
+	}
+
+	getFunctionsOptions := g.getFunctionsOptions
+	getFunctionsOptions.Namespace = g.rootCommandeer.namespace
+	if getFunctionsOptions.Namespace == "" {
+		getFunctionsOptions.Namespace = contextPlatform.ResolveDefaultNamespace("")
+	}
+
+	functions, err := contextPlatform.GetFunctions(&getFunctionsOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if g.group != "" {
+		functions = common.FilterFunctionsByGroup(functions, g.group)
+	}
+
+	if g.sortBy != "" {
+		if err := common.SortFunctions(functions, g.sortBy, g.reverse); err != nil {
+			return nil, err
+		}
+	}
+
+	return functions, nil
+}
+
+func (g *getFunctionCommandeer) validateRevisions() error {
+	if !g.revisions {
+		return nil
+	}
+
+	if g.getFunctionsOptions.Name == "" {
+		return errors.New("--revisions requires a function name")
+	}
+
+	if g.watch || g.allContexts || g.describeEnv || g.getFunctionsOptions.AllNamespaces {
+		return errors.New("--revisions can't be used with --watch, --context-all, --all-namespaces or --describe-env")
+	}
+
+	return nil
+}
+
+// renderFunctionRevisions renders the revisions of the function, oldest first
+func (g *getFunctionCommandeer) renderFunctionRevisions(cmd *cobra.Command) error {
+	revisions, err := g.rootCommandeer.platform.GetFunctionRevisions(&platform.GetFunctionRevisionsOptions{
+		Name:      g.getFunctionsOptions.Name,
+		Namespace: g.getFunctionsOptions.Namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function revisions")
+	}
+
+	if len(revisions) == 0 {
+		cmd.OutOrStdout().Write([]byte("No revisions found")) // nolint: errcheck
+		return nil
+	}
+
+	return common.RenderFunctionRevisions(revisions, g.output, g.yamlIndent, cmd.OutOrStdout())
+}
+
+func (g *getFunctionCommandeer) validateStreamStatus() error {
+	if !g.streamStatus {
+		return nil
+	}
+
+	if g.getFunctionsOptions.Name == "" {
+		return errors.New("--stream-status requires a function name")
+	}
+
+	if g.watch || g.allContexts || g.describeEnv || g.revisions || g.getFunctionsOptions.AllNamespaces {
+		return errors.New("--stream-status can't be used with --watch, --context-all, --all-namespaces, " +
+			"--describe-env or --revisions")
+	}
+
+	return nil
+}
+
+// renderFunctionStreamStatus renders the shards the function's replicas consume, by replica
+func (g *getFunctionCommandeer) renderFunctionStreamStatus(cmd *cobra.Command) error {
+	functionStreamStatus, err := g.rootCommandeer.platform.GetFunctionStreamStatus(&platform.GetFunctionStreamStatusOptions{
+		Name:      g.getFunctionsOptions.Name,
+		Namespace: g.getFunctionsOptions.Namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function stream status")
+	}
+
+	if len(functionStreamStatus) == 0 {
+		cmd.OutOrStdout().Write([]byte("No replicas found")) // nolint: errcheck
+		return nil
+	}
+
+	return common.RenderFunctionStreamStatus(functionStreamStatus, g.output, g.yamlIndent, cmd.OutOrStdout())
+}
+
+// checkDeprecatedFunctions warns about the deprecated functions (to stderr, so as not to interfere with
+// yaml/json output) and fails if any were found, as requested
+func (g *getFunctionCommandeer) checkDeprecatedFunctions(cmd *cobra.Command, functions []platform.Function) error {
+	if !g.warnDeprecated && !g.failOnDeprecated {
+		return nil
+	}
+
+	deprecatedFunctions := common.RenderFunctionDeprecations(functions, cmd.ErrOrStderr())
+	if g.failOnDeprecated && deprecatedFunctions > 0 {
+		return errors.Errorf("%d of %d functions are deprecated", deprecatedFunctions, len(functions))
+	}
+
+	return nil
+}
+
+// checkFunctionsReferences reports the references of the functions to secrets and configmaps that don't exist
+// (to stderr, so as not to interfere with yaml/json output) and fails if any were found. values are never output
+func (g *getFunctionCommandeer) checkFunctionsReferences(cmd *cobra.Command,
+	kubePlatform *kube.Platform,
+	functions []platform.Function) error {
+	danglingReferences := 0
+
+	for _, function := range functions {
+		functionReferences, err := kubePlatform.GetFunctionDanglingReferences(function.GetConfig())
+		if err != nil {
+			return errors.Wrapf(err, "Failed to check references of function %s", function.GetConfig().Meta.Name)
+		}
+
+		for _, reference := range functionReferences {
+			referenceName := reference.Name
+			if reference.Key != "" {
+				referenceName = fmt.Sprintf("%s (key %s)", reference.Name, reference.Key)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Function %s references missing %s %s in %s\n", // nolint: errcheck
+				function.GetConfig().Meta.Name,
+				reference.Kind,
+				referenceName,
+				reference.Source)
+		}
+
+		danglingReferences += len(functionReferences)
+	}
+
+	if danglingReferences > 0 {
+		return errors.Errorf("Found %d dangling secret/configmap references", danglingReferences)
+	}
+
+	return nil
+}
+
+func (g *getFunctionCommandeer) getFunctions() ([]platform.Function, error) {
+	functions, err := g.rootCommandeer.platform.GetFunctions(&g.getFunctionsOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if g.group != "" {
+		functions = common.FilterFunctionsByGroup(functions, g.group)
+	}
+
+	if g.sortBy != "" {
+		if err := common.SortFunctions(functions, g.sortBy, g.reverse); err != nil {
+			return nil, err
+		}
+	}
+
+	return functions, nil
+}
+
+func (g *getFunctionCommandeer) validateWatch() error {
+	if !g.watch {
+		if g.untilSettled {
+			return errors.New("--until-settled can only be used with --watch")
+		}
+
+		return nil
+	}
+
+	if g.watchInterval <= 0 {
+		return errors.New("--watch-interval must be positive")
+	}
+
+	switch {
+	case g.allContexts:
+		return errors.New("--context-all can't be used with --watch")
+	case g.describeEnv:
+		return errors.New("--describe-env can't be used with --watch")
+	case g.checkSecretRefs:
+		return errors.New("--check-secret-refs can't be used with --watch")
+	}
+
+	return nil
+}
+
+// watchFunctions renders the functions, and renders them again whenever one of them changes (e.g. transitions
+// from building to ready) or is added or deleted. it polls the platform until interrupted or, with
+// --until-settled, until all the functions are in a settled state
+func (g *getFunctionCommandeer) watchFunctions(cmd *cobra.Command) error {
+	var lastFunctionsState string
+	rendered := false
+
+	for {
+		functions, err := g.getFunctions()
+		if err != nil {
+			return err
+		}
+
+		functionsState := g.getFunctionsState(functions)
+		if !rendered || functionsState != lastFunctionsState {
+			if rendered {
+				fmt.Fprintln(cmd.OutOrStdout()) // nolint: errcheck
+			}
+
+			if err := g.renderWatchedFunctions(cmd, functions); err != nil {
+				return err
+			}
+
+			lastFunctionsState = functionsState
+			rendered = true
+		}
+
+		if g.untilSettled {
+			if settled, err := g.functionsSettled(functions); settled {
+				return err
+			}
+		}
+
+		time.Sleep(g.watchInterval)
+	}
+}
+
+func (g *getFunctionCommandeer) renderWatchedFunctions(cmd *cobra.Command, functions []platform.Function) error {
+	if len(functions) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No functions found") // nolint: errcheck
+		return nil
+	}
+
+	return common.RenderFunctions(g.rootCommandeer.loggerInstance,
+		functions,
+		g.output,
+		g.yamlIndent,
+		cmd.OutOrStdout(),
+		g.renderFunctionConfig)
+}
+
+// getFunctionsState returns a string which changes whenever the functions, or the state of one of them, change
+func (g *getFunctionCommandeer) getFunctionsState(functions []platform.Function) string {
+	var functionStates []string
+
+	for _, function := range functions {
+		functionConfig := function.GetConfig()
+		functionStatus := function.GetStatus()
+
+		functionStates = append(functionStates, fmt.Sprintf("%s/%s:%s:%s:%d",
+			functionConfig.Meta.Namespace,
+			functionConfig.Meta.Name,
+			functionStatus.State,
+			functionStatus.Message,
+			functionStatus.HTTPPort))
+	}
+
+	return strings.Join(functionStates, "\n")
+}
+
+// functionsSettled returns whether all the functions are in a settled state, and an error if any of them is in
+// error. a function that wasn't found yet (e.g. its deploy just started) isn't settled
+func (g *getFunctionCommandeer) functionsSettled(functions []platform.Function) (bool, error) {
+	if len(functions) == 0 {
+		return false, nil
+	}
+
+	var failedFunctionNames []string
+	for _, function := range functions {
+		functionState := function.GetStatus().State
+
+		if !functionconfig.FunctionStateInSlice(functionState, settledFunctionStates) {
+			return false, nil
+		}
+
+		if functionState == functionconfig.FunctionStateError {
+			failedFunctionNames = append(failedFunctionNames, function.GetConfig().Meta.Name)
+		}
+	}
+
+	if len(failedFunctionNames) > 0 {
+		return true, errors.Errorf("Functions in error state: %s", strings.Join(failedFunctionNames, ", "))
+	}
+
+	return true, nil
+}
+
+func joinFunctionStates(functionStates []functionconfig.FunctionState) string {
+	var functionStateNames []string
+	for _, functionState := range functionStates {
+		functionStateNames = append(functionStateNames, string(functionState))
+	}
+
+	return strings.Join(functionStateNames, ", ")
+}
+
+// validateOutput validates the output format, which jsonpath templates are only supported for when listing functions
+func (g *getFunctionCommandeer) validateOutput() error {
+	if err := common.ValidateFunctionOutputFormat(g.output); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(g.output, common.OutputFormatJSONPathPrefix) {
+		return nil
+	}
+
+	for _, unsupportedFlag := range []struct {
+		name  string
+		value bool
+	}{
+		{"--context-all", g.allContexts},
+		{"--describe-env", g.describeEnv},
+		{"--revisions", g.revisions},
+		{"--stream-status", g.streamStatus},
+	} {
+		if unsupportedFlag.value {
+			return errors.Errorf("jsonpath output can't be used with %s", unsupportedFlag.name)
+		}
+	}
+
+	return nil
+}
+
 func (g *getFunctionCommandeer) renderFunctionConfig(functions []platform.Function, renderer func(interface{}) error) error {
 	for _, function := range functions {
 		if err := renderer(function.GetConfig()); err != nil {
@@ -151,8 +746,8 @@ func newGetProjectCommandeer(getCommandeer *getCommandeer) *getProjectCommandeer
 			}
 
 			// initialize root
-			if err := getCommandeer.rootCommandeer.initialize(); err != nil {
-				return errors.Wrap(err, "Failed to initialize root")
+			if err := getCommandeer.initialize("get projects", commandeer.getProjectsOptions.AllNamespaces); err != nil {
+				return err
 			}
 
 			// get namespace
@@ -177,6 +772,9 @@ func newGetProjectCommandeer(getCommandeer *getCommandeer) *getProjectCommandeer
 	}
 
 	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	cmd.PersistentFlags().BoolVarP(&commandeer.getProjectsOptions.AllNamespaces, "all-namespaces", "A", false, "List the projects of all namespaces (kube only)")
+
+	completeProjectName(cmd)
 
 	commandeer.cmd = cmd
 
@@ -266,3 +864,332 @@ func (g *getFunctionEventCommandeer) renderFunctionEventConfig(functionEvents []
 
 	return nil
 }
+
+type getAPIGatewayCommandeer struct {
+	*getCommandeer
+	getAPIGatewaysOptions platform.GetAPIGatewaysOptions
+	output                string
+}
+
+func newGetAPIGatewayCommandeer(getCommandeer *getCommandeer) *getAPIGatewayCommandeer {
+	commandeer := &getAPIGatewayCommandeer{
+		getCommandeer: getCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "apigateways name",
+		Aliases: []string{"agw", "apigateway"},
+		Short:   "(or apigateway) Display API gateway information",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 0 {
+
+				// second argument is a resource name
+				commandeer.getAPIGatewaysOptions.Meta.Name = args[0]
+			}
+
+			// initialize root
+			if err := getCommandeer.initialize("get apigateways",
+				commandeer.getAPIGatewaysOptions.AllNamespaces); err != nil {
+				return err
+			}
+
+			commandeer.getAPIGatewaysOptions.Meta.Namespace = getCommandeer.rootCommandeer.namespace
+
+			apiGateways, err := getCommandeer.rootCommandeer.platform.GetAPIGateways(&commandeer.getAPIGatewaysOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get API gateways")
+			}
+
+			if len(apiGateways) == 0 {
+				if commandeer.getAPIGatewaysOptions.Meta.Name != "" {
+					return nuclio.NewErrNotFound("No API gateways found")
+				}
+				cmd.OutOrStdout().Write([]byte("No API gateways found")) // nolint: errcheck
+				return nil
+			}
+
+			// render the API gateways
+			return common.RenderAPIGateways(apiGateways, commandeer.output, cmd.OutOrStdout(), commandeer.renderAPIGatewayConfig)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	cmd.PersistentFlags().BoolVarP(&commandeer.getAPIGatewaysOptions.AllNamespaces, "all-namespaces", "A", false, "List the API gateways of all namespaces")
+
+	completeAPIGatewayName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (g *getAPIGatewayCommandeer) renderAPIGatewayConfig(apiGateways []platform.APIGateway, renderer func(interface{}) error) error {
+	for _, apiGateway := range apiGateways {
+		if err := renderer(apiGateway.GetConfig()); err != nil {
+			return errors.Wrap(err, "Failed to render API gateway config")
+		}
+	}
+
+	return nil
+}
+
+type getAuditCommandeer struct {
+	*getCommandeer
+	getAuditRecordsOptions platform.GetAuditRecordsOptions
+	since                  time.Duration
+	output                 string
+	yamlIndent             int
+}
+
+func newGetAuditCommandeer(getCommandeer *getCommandeer) *getAuditCommandeer {
+	commandeer := &getAuditCommandeer{
+		getCommandeer: getCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Display the operations performed on functions (deploy, import, update, delete, invoke)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.since < 0 {
+				return errors.New("--since must not be negative")
+			}
+
+			switch commandeer.output {
+			case common.OutputFormatText, common.OutputFormatWide, common.OutputFormatYAML, common.OutputFormatJSON:
+			default:
+				return errors.Errorf("Unsupported output format: %s", commandeer.output)
+			}
+
+			if err := common.ValidateYAMLIndent(commandeer.output, commandeer.yamlIndent); err != nil {
+				return errors.Wrap(err, "Invalid indentation")
+			}
+
+			// initialize root
+			if err := getCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			commandeer.getAuditRecordsOptions.Namespace = getCommandeer.rootCommandeer.namespace
+			if commandeer.since > 0 {
+				commandeer.getAuditRecordsOptions.Since = time.Now().Add(-commandeer.since)
+			}
+
+			records, err := getCommandeer.rootCommandeer.platform.GetAuditRecords(&commandeer.getAuditRecordsOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get audit records")
+			}
+
+			if len(records) == 0 {
+				cmd.OutOrStdout().Write([]byte("No audit records found")) // nolint: errcheck
+				return nil
+			}
+
+			return common.RenderAuditRecords(records, commandeer.output, commandeer.yamlIndent, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.getAuditRecordsOptions.Name, "function", "", "Only the operations performed on this function")
+	cmd.Flags().DurationVar(&commandeer.since, "since", 0, "Only the operations performed within this duration (e.g. 24h)")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	cmd.Flags().IntVar(&commandeer.yamlIndent, "indent", 0, "Indentation width of \"yaml\" output (1-8, default 2)")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type getInvocationsCommandeer struct {
+	*getCommandeer
+	getFunctionInvocationsOptions platform.GetFunctionInvocationsOptions
+	since                         time.Duration
+	output                        string
+	yamlIndent                    int
+}
+
+func newGetInvocationsCommandeer(getCommandeer *getCommandeer) *getInvocationsCommandeer {
+	commandeer := &getInvocationsCommandeer{
+		getCommandeer: getCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "invocations [namespace/]function",
+		Aliases: []string{"inv", "invocation"},
+		Short:   "(or invocation) Display the last invocations of a function, as retained by its invocation inspector",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("Getting invocations requires a function name")
+			}
+
+			if commandeer.since < 0 {
+				return errors.New("--since must not be negative")
+			}
+
+			switch commandeer.output {
+			case common.OutputFormatText, common.OutputFormatWide, common.OutputFormatYAML, common.OutputFormatJSON:
+			default:
+				return errors.Errorf("Unsupported output format: %s", commandeer.output)
+			}
+
+			if err := common.ValidateYAMLIndent(commandeer.output, commandeer.yamlIndent); err != nil {
+				return errors.Wrap(err, "Invalid indentation")
+			}
+
+			// initialize root
+			if err := getCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			namespace, name, err := getCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			commandeer.getFunctionInvocationsOptions.Name = name
+			commandeer.getFunctionInvocationsOptions.Namespace = namespace
+			if commandeer.since > 0 {
+				commandeer.getFunctionInvocationsOptions.Since = time.Now().Add(-commandeer.since)
+			}
+
+			invocations, err := getCommandeer.rootCommandeer.platform.GetFunctionInvocations(
+				&commandeer.getFunctionInvocationsOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get function invocations")
+			}
+
+			if len(invocations) == 0 {
+				cmd.OutOrStdout().Write([]byte("No invocations found")) // nolint: errcheck
+				return nil
+			}
+
+			return common.RenderFunctionInvocations(invocations,
+				commandeer.output,
+				commandeer.yamlIndent,
+				cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().DurationVar(&commandeer.since, "since", 0, "Only the invocations within this duration (e.g. 10m)")
+	cmd.Flags().BoolVar(&commandeer.getFunctionInvocationsOptions.FailedOnly, "failed", false, "Only the invocations that failed (errors and 5xx responses)")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	cmd.Flags().IntVar(&commandeer.yamlIndent, "indent", 0, "Indentation width of \"yaml\" output (1-8, default 2)")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type getTemplatesCommandeer struct {
+	*getCommandeer
+	functionTemplatesOptions functionTemplatesOptions
+	runtime                  string
+	output                   string
+}
+
+func newGetTemplatesCommandeer(getCommandeer *getCommandeer) *getTemplatesCommandeer {
+	commandeer := &getTemplatesCommandeer{
+		getCommandeer: getCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "templates [name]",
+		Aliases: []string{"tmpl", "template"},
+		Short:   "(or template) Display the function templates that functions can be created from",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loggerInstance, err := getCommandeer.rootCommandeer.createLogger()
+			if err != nil {
+				return errors.Wrap(err, "Failed to create logger")
+			}
+
+			functionTemplates, err := fetchFunctionTemplates(loggerInstance, &commandeer.functionTemplatesOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to fetch function templates")
+			}
+
+			requestedRuntimeName, _ := (&functionconfig.Spec{Runtime: commandeer.runtime}).GetRuntimeNameAndVersion()
+
+			var templateInfos []functionTemplateInfo
+			for _, functionTemplate := range functionTemplates {
+				templateInfo := getFunctionTemplateInfo(functionTemplate)
+
+				if len(args) != 0 && templateInfo.Name != args[0] {
+					continue
+				}
+
+				if commandeer.runtime != "" {
+					runtimeName, _ := (&functionconfig.Spec{Runtime: templateInfo.Runtime}).GetRuntimeNameAndVersion()
+					if runtimeName != requestedRuntimeName {
+						continue
+					}
+				}
+
+				templateInfos = append(templateInfos, templateInfo)
+			}
+
+			if len(templateInfos) == 0 {
+				if len(args) != 0 {
+					return nuclio.NewErrNotFound("No function templates found")
+				}
+				cmd.OutOrStdout().Write([]byte("No function templates found")) // nolint: errcheck
+				return nil
+			}
+
+			sort.SliceStable(templateInfos, func(i, j int) bool {
+				if templateInfos[i].Name != templateInfos[j].Name {
+					return templateInfos[i].Name < templateInfos[j].Name
+				}
+				return templateInfos[i].Runtime < templateInfos[j].Runtime
+			})
+
+			return commandeer.renderTemplates(cmd, templateInfos)
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.runtime, "runtime", "", "Only display the templates of this runtime")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"wide\", \"yaml\", or \"json\"")
+	addFunctionTemplatesFlags(cmd, &commandeer.functionTemplatesOptions)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (g *getTemplatesCommandeer) renderTemplates(cmd *cobra.Command, templateInfos []functionTemplateInfo) error {
+	rendererInstance := renderer.NewRenderer(cmd.OutOrStdout())
+
+	switch g.output {
+	case common.OutputFormatYAML:
+		return rendererInstance.RenderYAML(templateInfos)
+	case common.OutputFormatJSON:
+		return rendererInstance.RenderJSON(templateInfos)
+	}
+
+	header := []string{"Name", "Runtime", "Display Name"}
+	if g.output == common.OutputFormatWide {
+		header = append(header, "Parameters")
+	}
+
+	var records [][]string
+	for _, templateInfo := range templateInfos {
+		record := []string{templateInfo.Name, templateInfo.Runtime, templateInfo.DisplayName}
+
+		if g.output == common.OutputFormatWide {
+			var parameters []string
+			for parameterName, defaultValue := range templateInfo.Parameters {
+				parameters = append(parameters, fmt.Sprintf("%s=%s", parameterName, defaultValue))
+			}
+			sort.Strings(parameters)
+
+			record = append(record, strings.Join(parameters, ", "))
+		}
+
+		records = append(records, record)
+	}
+
+	rendererInstance.RenderTable(header, records)
+
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type getTestSuite struct {
+	suite.Suite
+	mockPlatform *mockplatform.Platform
+	commandeer   *getFunctionCommandeer
+	output       *bytes.Buffer
+}
+
+func (suite *getTestSuite) SetupTest() {
+	var err error
+
+	suite.mockPlatform = &mockplatform.Platform{}
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.platform = suite.mockPlatform
+	rootCommandeer.loggerInstance, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.commandeer = newGetFunctionCommandeer(newGetCommandeer(rootCommandeer))
+	suite.commandeer.watchInterval = time.Millisecond
+
+	suite.output = &bytes.Buffer{}
+	suite.commandeer.cmd.SetOutput(suite.output)
+}
+
+func (suite *getTestSuite) TestWatchUntilSettled() {
+	suite.commandeer.watch = true
+	suite.commandeer.untilSettled = true
+
+	// the function isn't found at first, then it's built, deployed and becomes ready
+	suite.expectGetFunctions()
+	suite.expectGetFunctions(functionconfig.FunctionStateBuilding)
+	suite.expectGetFunctions(functionconfig.FunctionStateBuilding)
+	suite.expectGetFunctions(functionconfig.FunctionStateConfiguringResources)
+	suite.expectGetFunctions(functionconfig.FunctionStateReady)
+
+	err := suite.commandeer.watchFunctions(suite.commandeer.cmd)
+	suite.Require().NoError(err)
+
+	// the functions are rendered once for each change
+	output := suite.output.String()
+	suite.Require().Equal(1, strings.Count(output, "No functions found"))
+	suite.Require().Equal(1, strings.Count(output, string(functionconfig.FunctionStateBuilding)))
+	suite.Require().Equal(1, strings.Count(output, string(functionconfig.FunctionStateConfiguringResources)))
+	suite.Require().Equal(1, strings.Count(output, string(functionconfig.FunctionStateReady)))
+
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *getTestSuite) TestWatchUntilSettledFailsOnError() {
+	suite.commandeer.watch = true
+	suite.commandeer.untilSettled = true
+
+	suite.expectGetFunctions(functionconfig.FunctionStateBuilding)
+	suite.expectGetFunctions(functionconfig.FunctionStateError)
+
+	err := suite.commandeer.watchFunctions(suite.commandeer.cmd)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Functions in error state: my-function")
+}
+
+func (suite *getTestSuite) TestValidateWatch() {
+	suite.commandeer.untilSettled = true
+	suite.Require().Error(suite.commandeer.validateWatch())
+
+	suite.commandeer.watch = true
+	suite.Require().NoError(suite.commandeer.validateWatch())
+
+	suite.commandeer.allContexts = true
+	suite.Require().Error(suite.commandeer.validateWatch())
+}
+
+func (suite *getTestSuite) TestValidateOutput() {
+	suite.commandeer.output = "jsonpath={.name} {.internalURL}"
+	suite.Require().NoError(suite.commandeer.validateOutput())
+
+	suite.commandeer.revisions = true
+	suite.Require().Error(suite.commandeer.validateOutput())
+
+	suite.commandeer.revisions = false
+	suite.commandeer.output = "jsonpath={.name"
+	suite.Require().Error(suite.commandeer.validateOutput())
+}
+
+func (suite *getTestSuite) expectGetFunctions(functionStates ...functionconfig.FunctionState) {
+	functions := []platform.Function{}
+	for _, functionState := range functionStates {
+		functions = append(functions, &platform.AbstractFunction{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{Name: "my-function", Namespace: "default"},
+			},
+			Status: functionconfig.Status{State: functionState},
+		})
+	}
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return(functions, nil).
+		Once()
+}
+
+func TestGetTestSuite(t *testing.T) {
+	suite.Run(t, new(getTestSuite))
+}
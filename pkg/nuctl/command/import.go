@@ -1,19 +1,42 @@
 package command
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
 
+	"github.com/ghodss/yaml"
 	"github.com/nuclio/errors"
 	uuid "github.com/satori/go.uuid"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
 )
 
+// importing is bound by the rate at which the platform accepts new resources, so keep it modest by default
+const defaultImportConcurrency = 4
+
 type importCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
+	overwrite      bool
+	concurrency    int
+	parallelism    int
+	decryptKey     string
+	secretsPath    string
+	values         stringSliceFlag
+	valuesPath     string
+
+	// overrides of the imported functions' fields, given with --set and --values
+	functionConfigOverrides common.FunctionConfigOverrides
+
+	// the values of scrubbed secrets, by function name, read from the secrets file
+	secretValues map[string]map[string]string
 }
 
 func newImportCommandeer(rootCommandeer *RootCommandeer) *importCommandeer {
@@ -28,18 +51,33 @@ func newImportCommandeer(rootCommandeer *RootCommandeer) *importCommandeer {
 
 	importFunctionCommand := newImportFunctionCommandeer(commandeer).cmd
 	importProjectCommand := newImportProjectCommandeer(commandeer).cmd
+	importBundleCommand := newImportBundleCommandeer(commandeer).cmd
 
 	cmd.AddCommand(
 		importFunctionCommand,
 		importProjectCommand,
+		importBundleCommand,
 	)
 
+	cmd.PersistentFlags().BoolVar(&commandeer.overwrite, "overwrite", false, "Overwrite functions that already exist, rather than failing")
+	cmd.PersistentFlags().IntVar(&commandeer.parallelism, "parallelism", 0, "Maximal number of resources to import concurrently (0 - unlimited)")
+	cmd.PersistentFlags().MarkDeprecated("parallelism", "use --concurrency instead (--parallelism 0 imports all the resources at once)") // nolint: errcheck
+	cmd.PersistentFlags().IntVar(&commandeer.concurrency, "concurrency", defaultImportConcurrency, "Maximal number of resources to import concurrently")
+	cmd.PersistentFlags().StringVar(&commandeer.decryptKey, "decrypt-key", os.Getenv("NUCTL_EXPORT_KEY"), "Passphrase an encrypted export was encrypted with (env: NUCTL_EXPORT_KEY)")
+	cmd.PersistentFlags().StringVar(&commandeer.secretsPath, "secrets-file", "", "File with the values of secrets scrubbed from the functions, by function name (as written by export functions --secrets-output)")
+	cmd.PersistentFlags().Var(&commandeer.values, "set", "Override a field of the imported functions (path=value, e.g. spec.build.registry=my-registry or spec.env.NAME=value), may be repeated")
+	cmd.PersistentFlags().StringVar(&commandeer.valuesPath, "values", "", "File with overrides of the imported functions' fields, as a partial function config (YAML), applied before --set")
+
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
 func (i *importCommandeer) resolveInputData(args []string) ([]byte, error) {
+	if err := i.validateConcurrency(); err != nil {
+		return nil, err
+	}
+
 	if len(args) >= 1 {
 		filename := args[0]
 		i.rootCommandeer.loggerInstance.DebugWith("Reading from a file", "filename", filename)
@@ -54,7 +92,63 @@ func (i *importCommandeer) resolveInputData(args []string) ([]byte, error) {
 	}
 
 	// read from file if given, fallback to stdin
-	return common.ReadFromInOrStdin(i.cmd.InOrStdin())
+	inputData, err := common.ReadFromInOrStdin(i.cmd.InOrStdin())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.readSecretValues(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read secrets file")
+	}
+
+	if err := i.readFunctionConfigOverrides(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read overrides")
+	}
+
+	// exports encrypted with export functions --encrypt-key
+	encryptedExport := common.ParseEncryptedExport(inputData)
+	if encryptedExport == nil {
+		return inputData, nil
+	}
+
+	if i.decryptKey == "" {
+		return nil, errors.New("The input is encrypted, its key must be provided with --decrypt-key")
+	}
+
+	return common.DecryptExport(encryptedExport, i.decryptKey)
+}
+
+func (i *importCommandeer) readSecretValues() error {
+	if i.secretsPath == "" {
+		return nil
+	}
+
+	encodedSecretValues, err := ioutil.ReadFile(i.secretsPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read file")
+	}
+
+	if err := yaml.Unmarshal(encodedSecretValues, &i.secretValues); err != nil {
+		return errors.Wrap(err, "Failed to parse file")
+	}
+
+	return nil
+}
+
+func (i *importCommandeer) readFunctionConfigOverrides() error {
+	var valuesFileBody []byte
+	var err error
+
+	if i.valuesPath != "" {
+		valuesFileBody, err = ioutil.ReadFile(i.valuesPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read values file")
+		}
+	}
+
+	i.functionConfigOverrides, err = common.NewFunctionConfigOverrides(valuesFileBody, i.values)
+
+	return err
 }
 
 func (i *importCommandeer) importFunction(functionConfig *functionconfig.Config, project string) error {
@@ -62,10 +156,24 @@ func (i *importCommandeer) importFunction(functionConfig *functionconfig.Config,
 	// populate namespace
 	functionConfig.Meta.Namespace = i.rootCommandeer.namespace
 
+	// exported with export functions --scrub-secrets
+	if functionConfig.HasScrubbedSecrets() {
+		if err := functionConfig.RestoreSecrets(i.secretValues[functionConfig.Meta.Name]); err != nil {
+			return errors.Errorf("%s, provide them with --secrets-file", err.Error())
+		}
+	}
+
 	if project != "" {
+		if functionConfig.Meta.Labels == nil {
+			functionConfig.Meta.Labels = map[string]string{}
+		}
 		functionConfig.Meta.Labels["nuclio.io/project-name"] = project
 	}
 
+	if err := i.functionConfigOverrides.Apply(functionConfig); err != nil {
+		return errors.Wrap(err, "Failed to apply overrides")
+	}
+
 	functions, err := i.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
 		Name:      functionConfig.Meta.Name,
 		Namespace: i.rootCommandeer.namespace,
@@ -75,7 +183,12 @@ func (i *importCommandeer) importFunction(functionConfig *functionconfig.Config,
 	}
 
 	if len(functions) > 0 {
-		return errors.Errorf("Function with the name: %s already exists", functionConfig.Meta.Name)
+		if !i.overwrite {
+			return errors.Errorf("Function with the name: %s already exists", functionConfig.Meta.Name)
+		}
+
+		i.rootCommandeer.loggerInstance.DebugWith("Overwriting existing function",
+			"name", functionConfig.Meta.Name)
 	}
 
 	_, err = i.rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
@@ -87,17 +200,114 @@ func (i *importCommandeer) importFunction(functionConfig *functionconfig.Config,
 }
 
 func (i *importCommandeer) importFunctions(functionConfigs map[string]*functionconfig.Config, project string) error {
-	var errGroup errgroup.Group
+
+	// import in a deterministic order, so that the summary is ordered regardless of completion order
+	var functionNames []string
+	for functionName := range functionConfigs {
+		functionNames = append(functionNames, functionName)
+	}
+	sort.Strings(functionNames)
 
 	i.rootCommandeer.loggerInstance.DebugWith("Importing functions", "functions", functionConfigs)
-	for _, functionConfig := range functionConfigs {
-		functionConfig := functionConfig // https://golang.org/doc/faq#closures_and_goroutines
-		errGroup.Go(func() error {
-			return i.importFunction(functionConfig, project)
-		})
+	var orderedFunctionConfigs []*functionconfig.Config
+	for _, functionName := range functionNames {
+		functionConfig := functionConfigs[functionName]
+
+		// dependencies are in the namespace the functions are imported to
+		functionConfig.Meta.Namespace = i.rootCommandeer.namespace
+		orderedFunctionConfigs = append(orderedFunctionConfigs, functionConfig)
+	}
+
+	// functions are imported only once the functions and API gateways they depend on are ready
+	importErrs, err := platform.NewDependencyScheduler(i.rootCommandeer.loggerInstance,
+		i.rootCommandeer.platform,
+		i.getConcurrency(len(orderedFunctionConfigs))).Run(orderedFunctionConfigs, func(functionConfig *functionconfig.Config) error {
+		return i.importFunction(functionConfig, project)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to order functions by their dependencies")
+	}
+
+	var failedFunctionNames []string
+	for functionIndex, functionName := range functionNames {
+		if importErrs[functionIndex] != nil {
+			fmt.Fprintf(i.cmd.OutOrStdout(), "Function %s failed to import: %s\n", // nolint: errcheck
+				functionName,
+				importErrs[functionIndex].Error())
+			failedFunctionNames = append(failedFunctionNames, functionName)
+		} else {
+			fmt.Fprintf(i.cmd.OutOrStdout(), "Function %s imported\n", functionName) // nolint: errcheck
+		}
+	}
+
+	if len(failedFunctionNames) > 0 {
+		return errors.Errorf("Failed to import %d of %d functions: %v",
+			len(failedFunctionNames),
+			len(functionNames),
+			failedFunctionNames)
+	}
+
+	return nil
+}
+
+// runImports runs all the given imports, no more than the configured concurrency at a time, and returns
+// the error of each import (nil on success) at its index. a failed import doesn't stop the others
+func (i *importCommandeer) runImports(importFuncs []func() error) []error {
+	var waitGroup sync.WaitGroup
+
+	importErrs := make([]error, len(importFuncs))
+	semaphore := make(chan struct{}, i.getConcurrency(len(importFuncs)))
+	for importIndex, importFunc := range importFuncs {
+		importIndex, importFunc := importIndex, importFunc // https://golang.org/doc/faq#closures_and_goroutines
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			importErrs[importIndex] = importFunc()
+		}()
+	}
+
+	waitGroup.Wait()
+
+	return importErrs
+}
+
+func (i *importCommandeer) validateConcurrency() error {
+	if i.concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+
+	if i.parallelism < 0 {
+		return errors.New("--parallelism must not be negative")
+	}
+
+	return nil
+}
+
+// getConcurrency returns the number of the given resources to import at a time. the deprecated --parallelism
+// keeps its meaning when given instead of --concurrency, where 0 imports all the resources at once
+func (i *importCommandeer) getConcurrency(resourceCount int) int {
+	if i.cmd.PersistentFlags().Changed("parallelism") && !i.cmd.PersistentFlags().Changed("concurrency") {
+		if i.parallelism > 0 {
+			return i.parallelism
+		}
+
+		// all of the resources at once
+		if resourceCount > 1 {
+			return resourceCount
+		}
+
+		return 1
+	}
+
+	if i.concurrency <= 0 {
+		return defaultImportConcurrency
 	}
 
-	return errGroup.Wait()
+	return i.concurrency
 }
 
 type importFunctionCommandeer struct {
@@ -136,12 +346,26 @@ Use --help for more information`)
 				return errors.Wrap(err, "Failed identifying input format")
 			}
 
-			functionConfigs, err := commandeer.resolveFunctionImportConfigs(functionBody, unmarshalFunc)
+			functionImportConfig, err := commandeer.resolveFunctionImportConfig(functionBody, unmarshalFunc)
 			if err != nil {
 				return errors.Wrap(err, "Failed to resolve function import configs")
 			}
 
-			return commandeer.importFunctions(functionConfigs, "")
+			err = commandeer.importFunctions(functionImportConfig.Functions, "")
+
+			// the events of the functions that were imported are imported even if others failed
+			if len(functionImportConfig.FunctionEvents) > 0 {
+				if functionEventImportErr := commandeer.importFunctionEvents(functionImportConfig.FunctionEvents); functionEventImportErr != nil {
+					commandeer.rootCommandeer.loggerInstance.WarnWith("Unable to import all function events",
+						"functionEventImportErr", functionEventImportErr)
+
+					if err == nil {
+						err = functionEventImportErr
+					}
+				}
+			}
+
+			return err
 		},
 	}
 
@@ -150,6 +374,47 @@ Use --help for more information`)
 	return commandeer
 }
 
+// FunctionImportConfig is the export of functions along with their events (export functions --include-events)
+type FunctionImportConfig struct {
+	Functions      map[string]*functionconfig.Config        `json:"functions"`
+	FunctionEvents map[string]*platform.FunctionEventConfig `json:"functionEvents"`
+}
+
+// resolveFunctionImportConfig resolves the functions, and their events if exported with them, from an export
+// of a single function, of multiple functions or of functions with their events
+func (i *importFunctionCommandeer) resolveFunctionImportConfig(functionBody []byte,
+	unmarshalFunc func(data []byte, v interface{}) error) (*FunctionImportConfig, error) {
+
+	// try functions with their events. a map of functions may not parse as one
+	functionImportConfig := FunctionImportConfig{}
+	if err := unmarshalFunc(functionBody, &functionImportConfig); err == nil && i.isFunctionImportConfig(&functionImportConfig) {
+		return &functionImportConfig, nil
+	}
+
+	functionConfigs, err := i.resolveFunctionImportConfigs(functionBody, unmarshalFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FunctionImportConfig{Functions: functionConfigs}, nil
+}
+
+// isFunctionImportConfig returns whether the parsed export is of functions with their events, rather than of a
+// function or a map of functions (which may have a function named "functions")
+func (i *importFunctionCommandeer) isFunctionImportConfig(functionImportConfig *FunctionImportConfig) bool {
+	if len(functionImportConfig.Functions) == 0 {
+		return false
+	}
+
+	for functionName, functionConfig := range functionImportConfig.Functions {
+		if functionConfig == nil || functionConfig.Meta.Name != functionName {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (i *importFunctionCommandeer) resolveFunctionImportConfigs(functionBody []byte,
 	unmarshalFunc func(data []byte, v interface{}) error) (map[string]*functionconfig.Config, error) {
 
@@ -181,6 +446,7 @@ type ProjectImportConfig struct {
 	Project        *platform.ProjectConfig
 	Functions      map[string]*functionconfig.Config
 	FunctionEvents map[string]*platform.FunctionEventConfig
+	APIGateways    map[string]*platform.APIGatewayConfig
 }
 
 type importProjectCommandeer struct {
@@ -195,7 +461,7 @@ func newImportProjectCommandeer(importCommandeer *importCommandeer) *importProje
 	cmd := &cobra.Command{
 		Use:     "projects [path-to-exported-project-file]",
 		Aliases: []string{"proj", "prj", "project"},
-		Short:   "(or project) Import project and all its functions and functionEvents",
+		Short:   "(or project) Import project and all its functions, functionEvents and API gateways",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// initialize root
@@ -214,6 +480,11 @@ Make sure to provide its content via STDIN / file path.
 Use --help for more information`)
 			}
 
+			// archives of export projects --output-archive (or export bundle)
+			if isBundle(projectBody) {
+				return commandeer.importProjectBundle(bytes.NewReader(projectBody))
+			}
+
 			unmarshalFunc, err := common.GetUnmarshalFunc(projectBody)
 			if err != nil {
 				return errors.Wrap(err, "Failed identifying input format")
@@ -233,7 +504,7 @@ Use --help for more information`)
 	return commandeer
 }
 
-func (i *importProjectCommandeer) importFunctionEvent(functionEvent *platform.FunctionEventConfig) error {
+func (i *importCommandeer) importFunctionEvent(functionEvent *platform.FunctionEventConfig) error {
 	functions, err := i.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
 		Name:      functionEvent.Meta.Labels["nuclio.io/function-name"],
 		Namespace: i.rootCommandeer.namespace,
@@ -260,19 +531,78 @@ func (i *importProjectCommandeer) importFunctionEvent(functionEvent *platform.Fu
 	})
 }
 
-func (i *importProjectCommandeer) importFunctionEvents(functionEvents map[string]*platform.FunctionEventConfig) error {
-	var errGroup errgroup.Group
+func (i *importCommandeer) importFunctionEvents(functionEvents map[string]*platform.FunctionEventConfig) error {
+	var importFuncs []func() error
 
 	i.rootCommandeer.loggerInstance.DebugWith("Importing function events",
 		"functionEvents", functionEvents)
 	for _, functionEventConfig := range functionEvents {
 		functionEventConfig := functionEventConfig // https://golang.org/doc/faq#closures_and_goroutines
-		errGroup.Go(func() error {
+		importFuncs = append(importFuncs, func() error {
 			return i.importFunctionEvent(functionEventConfig)
 		})
 	}
 
-	return errGroup.Wait()
+	for _, importErr := range i.runImports(importFuncs) {
+		if importErr != nil {
+			return importErr
+		}
+	}
+
+	return nil
+}
+
+func (i *importCommandeer) importAPIGateway(apiGatewayConfig *platform.APIGatewayConfig) error {
+
+	// populate namespace
+	apiGatewayConfig.Meta.Namespace = i.rootCommandeer.namespace
+
+	apiGateways, err := i.rootCommandeer.platform.GetAPIGateways(&platform.GetAPIGatewaysOptions{
+		Meta: platform.APIGatewayMeta{
+			Name:      apiGatewayConfig.Meta.Name,
+			Namespace: i.rootCommandeer.namespace,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to check existing API gateways")
+	}
+
+	if len(apiGateways) > 0 {
+		if !i.overwrite {
+			return errors.Errorf("API gateway with the name: %s already exists", apiGatewayConfig.Meta.Name)
+		}
+
+		return i.rootCommandeer.platform.UpdateAPIGateway(&platform.UpdateAPIGatewayOptions{
+			APIGatewayConfig: *apiGatewayConfig,
+		})
+	}
+
+	return i.rootCommandeer.platform.CreateAPIGateway(&platform.CreateAPIGatewayOptions{
+		APIGatewayConfig: *apiGatewayConfig,
+	})
+}
+
+// importAPIGateways imports the API gateways, once the functions they route to are imported
+func (i *importCommandeer) importAPIGateways(apiGateways map[string]*platform.APIGatewayConfig) error {
+	var importFuncs []func() error
+
+	i.rootCommandeer.loggerInstance.DebugWith("Importing API gateways", "apiGateways", apiGateways)
+	for _, apiGatewayConfig := range apiGateways {
+		apiGatewayConfig := apiGatewayConfig // https://golang.org/doc/faq#closures_and_goroutines
+		importFuncs = append(importFuncs, func() error {
+			return errors.Wrapf(i.importAPIGateway(apiGatewayConfig),
+				"Failed to import API gateway %s",
+				apiGatewayConfig.Meta.Name)
+		})
+	}
+
+	for _, importErr := range i.runImports(importFuncs) {
+		if importErr != nil {
+			return importErr
+		}
+	}
+
+	return nil
 }
 
 func (i *importProjectCommandeer) importProject(projectConfig *ProjectImportConfig) error {
@@ -318,6 +648,17 @@ func (i *importProjectCommandeer) importProject(projectConfig *ProjectImportConf
 		}
 	}
 
+	apiGatewayImportErr := i.importAPIGateways(projectConfig.APIGateways)
+	if apiGatewayImportErr != nil {
+		i.rootCommandeer.loggerInstance.WarnWith("Unable to import all API gateways",
+			"apiGatewayImportErr", apiGatewayImportErr)
+
+		// return this err only if not previously set
+		if err == nil {
+			err = apiGatewayImportErr
+		}
+	}
+
 	return err
 }
 
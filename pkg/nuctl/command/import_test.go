@@ -0,0 +1,215 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type importTestSuite struct {
+	suite.Suite
+	mockPlatform *mockplatform.Platform
+	commandeer   *importCommandeer
+}
+
+func (suite *importTestSuite) SetupTest() {
+	var err error
+
+	suite.mockPlatform = &mockplatform.Platform{}
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.platform = suite.mockPlatform
+	rootCommandeer.loggerInstance, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.commandeer = newImportCommandeer(rootCommandeer)
+}
+
+func (suite *importTestSuite) TestImportExistingFunction() {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "test"
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{&platform.AbstractFunction{Config: *functionConfig}}, nil)
+
+	// without overwrite, an existing function fails the import
+	err := suite.commandeer.importFunctions(map[string]*functionconfig.Config{"test": functionConfig}, "my-project")
+	suite.Require().Error(err)
+	suite.mockPlatform.AssertNotCalled(suite.T(), "CreateFunction", mock.Anything)
+
+	suite.mockPlatform.
+		On("CreateFunction", mock.MatchedBy(func(createFunctionOptions *platform.CreateFunctionOptions) bool {
+			return createFunctionOptions.FunctionConfig.Meta.Labels["nuclio.io/project-name"] == "my-project"
+		})).
+		Return(&platform.CreateFunctionResult{}, nil).
+		Once()
+
+	suite.commandeer.overwrite = true
+	suite.commandeer.concurrency = 1
+
+	err = suite.commandeer.importFunctions(map[string]*functionconfig.Config{"test": functionConfig}, "my-project")
+	suite.Require().NoError(err)
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *importTestSuite) TestGetConcurrency() {
+	for _, testCase := range []struct {
+		name                string
+		args                []string
+		expectedConcurrency int
+		expectedError       string
+	}{
+		{
+			name:                "default",
+			expectedConcurrency: defaultImportConcurrency,
+		},
+		{
+			name:                "concurrency",
+			args:                []string{"--concurrency", "2"},
+			expectedConcurrency: 2,
+		},
+		{
+			name:                "deprecatedParallelism",
+			args:                []string{"--parallelism", "3"},
+			expectedConcurrency: 3,
+		},
+		{
+			name:                "deprecatedUnlimitedParallelism",
+			args:                []string{"--parallelism", "0"},
+			expectedConcurrency: 10,
+		},
+		{
+			name:                "concurrencyOverridesParallelism",
+			args:                []string{"--parallelism", "0", "--concurrency", "2"},
+			expectedConcurrency: 2,
+		},
+		{
+			name:          "zeroConcurrency",
+			args:          []string{"--concurrency", "0"},
+			expectedError: "--concurrency must be at least 1",
+		},
+		{
+			name:          "negativeParallelism",
+			args:          []string{"--parallelism", "-1"},
+			expectedError: "--parallelism must not be negative",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			commandeer := newImportCommandeer(suite.commandeer.rootCommandeer)
+			err := commandeer.cmd.PersistentFlags().Parse(testCase.args)
+			suite.Require().NoError(err)
+
+			err = commandeer.validateConcurrency()
+			if testCase.expectedError != "" {
+				suite.Require().Error(err)
+				suite.Require().Equal(testCase.expectedError, err.Error())
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedConcurrency, commandeer.getConcurrency(10))
+		})
+	}
+}
+
+func (suite *importTestSuite) TestResolveFunctionImportConfig() {
+	importFunctionCommandeer := newImportFunctionCommandeer(suite.commandeer)
+
+	// functions exported with their events
+	functionImportConfig, err := importFunctionCommandeer.resolveFunctionImportConfig([]byte(`
+functions:
+  test:
+    metadata:
+      name: test
+functionEvents:
+  some-event:
+    metadata:
+      name: some-event
+      labels:
+        nuclio.io/function-name: test
+    spec:
+      body: hello
+`), yaml.Unmarshal)
+	suite.Require().NoError(err)
+	suite.Require().Contains(functionImportConfig.Functions, "test")
+	suite.Require().Equal("hello", functionImportConfig.FunctionEvents["some-event"].Spec.Body)
+
+	// a map of functions, one of which happens to be named "functions"
+	functionImportConfig, err = importFunctionCommandeer.resolveFunctionImportConfig([]byte(`
+functions:
+  metadata:
+    name: functions
+other:
+  metadata:
+    name: other
+`), yaml.Unmarshal)
+	suite.Require().NoError(err)
+	suite.Require().Len(functionImportConfig.Functions, 2)
+	suite.Require().Empty(functionImportConfig.FunctionEvents)
+
+	// a single function
+	functionImportConfig, err = importFunctionCommandeer.resolveFunctionImportConfig([]byte(`
+metadata:
+  name: single
+`), yaml.Unmarshal)
+	suite.Require().NoError(err)
+	suite.Require().Contains(functionImportConfig.Functions, "single")
+}
+
+func (suite *importTestSuite) TestImportFunctionEvents() {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "test"
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{&platform.AbstractFunction{Config: *functionConfig}}, nil)
+
+	// the event is renamed, to avoid colliding with the exported one
+	suite.mockPlatform.
+		On("CreateFunctionEvent", mock.MatchedBy(func(createFunctionEventOptions *platform.CreateFunctionEventOptions) bool {
+			return createFunctionEventOptions.FunctionEventConfig.Meta.Name != "some-event" &&
+				createFunctionEventOptions.FunctionEventConfig.Spec.Body == "hello"
+		})).
+		Return(nil).
+		Once()
+
+	err := suite.commandeer.importFunctionEvents(map[string]*platform.FunctionEventConfig{
+		"some-event": {
+			Meta: platform.FunctionEventMeta{
+				Name:   "some-event",
+				Labels: map[string]string{"nuclio.io/function-name": "test"},
+			},
+			Spec: platform.FunctionEventSpec{Body: "hello"},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func TestImportTestSuite(t *testing.T) {
+	suite.Run(t, new(importTestSuite))
+}
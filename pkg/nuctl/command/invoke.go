@@ -21,21 +21,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	nuctlcommon "github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
 
 	"github.com/mgutz/ansi"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
+	"github.com/satori/go.uuid"
 	"github.com/spf13/cobra"
 )
 
+// the time to wait for a connection to the function's address, before tunneling to it instead
+const reachabilityTimeout = 2 * time.Second
+
 type invokeCommandeer struct {
 	cmd                             *cobra.Command
 	rootCommandeer                  *RootCommandeer
@@ -45,6 +57,19 @@ type invokeCommandeer struct {
 	contentType                     string
 	headers                         string
 	body                            string
+	bodyFilePath                    string
+	formFields                      []string
+	grpc                            bool
+	grpcMethod                      string
+	grpcProtoPath                   string
+	captureLogsFilePath             string
+	repeat                          int
+	duration                        time.Duration
+	concurrency                     int
+	rps                             float64
+	cloudEventType                  string
+	cloudEventSource                string
+	cloudEventMode                  string
 }
 
 func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
@@ -71,10 +96,16 @@ func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
 			commandeer.createFunctionInvocationOptions.Name = args[0]
 			commandeer.createFunctionInvocationOptions.Namespace = rootCommandeer.namespace
 
-			// try parse body input from flag
-			commandeer.createFunctionInvocationOptions.Body, err = commandeer.resolveBody()
-			if err != nil {
-				return errors.Wrap(err, "Failed to resolve body")
+			// stream the body from a file or a multipart form if given, otherwise read it whole
+			if commandeer.bodyFilePath != "" || len(commandeer.formFields) > 0 {
+				if err := commandeer.resolveBodyStream(); err != nil {
+					return errors.Wrap(err, "Failed to resolve body stream")
+				}
+			} else {
+				commandeer.createFunctionInvocationOptions.Body, err = commandeer.resolveBody()
+				if err != nil {
+					return errors.Wrap(err, "Failed to resolve body")
+				}
 			}
 			commandeer.createFunctionInvocationOptions.Headers = http.Header{}
 
@@ -95,6 +126,13 @@ func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
 
 			commandeer.createFunctionInvocationOptions.Headers.Set("Content-Type", commandeer.contentType)
 
+			// wrap the body in a cloud event, if asked to
+			if commandeer.cloudEventType != "" {
+				if err := commandeer.wrapCloudEvent(); err != nil {
+					return errors.Wrap(err, "Failed to wrap body in cloud event")
+				}
+			}
+
 			// verify correctness of logger level
 			switch commandeer.createFunctionInvocationOptions.LogLevelName {
 			case "none", "debug", "info", "warn", "error":
@@ -114,8 +152,56 @@ func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
 				commandeer.createFunctionInvocationOptions.Via = platform.InvokeViaExternalIP
 			case "loadbalancer":
 				commandeer.createFunctionInvocationOptions.Via = platform.InvokeViaLoadBalancer
+			case "port-forward":
+				if commandeer.createFunctionInvocationOptions.URL != "" {
+					return errors.New("--via port-forward can't be used with --url")
+				}
+
+				// the tunnel reaches the function's HTTP port alone
+				if commandeer.grpc {
+					return errors.New("--via port-forward can't be used with --grpc")
+				}
+
+				commandeer.createFunctionInvocationOptions.Via = platform.InvokeViaAny
 			default:
-				return errors.New("Invalid via type - must be ingress / nodePort")
+				return errors.New("Invalid via type - must be any / external-ip / loadbalancer / port-forward")
+			}
+
+			if commandeer.createFunctionInvocationOptions.Stream && commandeer.grpc {
+				return errors.New("--stream can't be used with --grpc")
+			}
+
+			if commandeer.cloudEventType != "" && commandeer.grpc {
+				return errors.New("--cloudevent can't be used with --grpc")
+			}
+
+			if rootCommandeer.isJSONOutput() && (commandeer.grpc || commandeer.repeat != 0 || commandeer.duration != 0) {
+				return errors.New("--output json can't be used with --grpc, --repeat or --duration")
+			}
+
+			// tunnel to the function if asked to, or if it can't be reached otherwise (e.g. from outside a
+			// cluster in which it has a cluster IP alone). the tunnel is torn down once the function was invoked
+			tunnel, err := commandeer.createTunnel()
+			if err != nil {
+				return errors.Wrap(err, "Failed to create tunnel to function")
+			}
+
+			if tunnel != nil {
+				defer commandeer.closeTunnel(tunnel)
+
+				commandeer.createFunctionInvocationOptions.URL = tunnel.GetAddress()
+			}
+
+			if commandeer.repeat != 0 || commandeer.duration != 0 {
+				if commandeer.grpc || commandeer.captureLogsFilePath != "" || commandeer.createFunctionInvocationOptions.Stream {
+					return errors.New("--grpc, --capture-logs-to-file and --stream can't be used with --repeat or --duration")
+				}
+
+				return commandeer.invokeRepeatedly(cmd.OutOrStdout())
+			}
+
+			if commandeer.grpc {
+				return commandeer.invokeGRPC(cmd.OutOrStdout())
 			}
 
 			invokeResult, err := rootCommandeer.platform.CreateFunctionInvocation(&commandeer.createFunctionInvocationOptions)
@@ -123,6 +209,13 @@ func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
 				return errors.Wrap(err, "Failed to invoke function")
 			}
 
+			if rootCommandeer.isJSONOutput() {
+				return commandeer.renderInvokeResult(&commandeer.createFunctionInvocationOptions,
+					invokeResult,
+					cmd.OutOrStdout(),
+					cmd.ErrOrStderr())
+			}
+
 			// write the result to output
 			return commandeer.outputInvokeResult(&commandeer.createFunctionInvocationOptions, invokeResult, cmd.OutOrStdout())
 		},
@@ -132,10 +225,29 @@ func newInvokeCommandeer(rootCommandeer *RootCommandeer) *invokeCommandeer {
 	cmd.Flags().StringVarP(&commandeer.createFunctionInvocationOptions.Path, "path", "p", "", "Path to the function to invoke")
 	cmd.Flags().StringVarP(&commandeer.createFunctionInvocationOptions.Method, "method", "m", "", "HTTP method for invoking the function")
 	cmd.Flags().StringVarP(&commandeer.body, "body", "b", "", "HTTP message body")
+	cmd.Flags().StringVar(&commandeer.bodyFilePath, "body-file", "", "Path to a file to stream as the HTTP message body (the content type is guessed from the extension, unless given)")
+	cmd.Flags().StringArrayVar(&commandeer.formFields, "form", nil, "Multipart form field to send, as name=value or name=@path to upload a file (can be given more than once)")
 	cmd.Flags().StringVarP(&commandeer.headers, "headers", "d", "", "HTTP headers (name=val1[,name=val2,...])")
-	cmd.Flags().StringVarP(&commandeer.invokeVia, "via", "", "any", "Invoke the function via - \"any\": a load balancer or an external IP; \"loadbalancer\": a load balancer; \"external-ip\": an external IP")
+	cmd.Flags().StringVarP(&commandeer.invokeVia, "via", "", "any", "Invoke the function via - \"any\": a load balancer or an external IP; \"loadbalancer\": a load balancer; \"external-ip\": an external IP; \"port-forward\": a temporary tunnel (kubectl port-forward, or a docker network for the local platform). \"any\" falls back to a tunnel if the function isn't reachable")
 	cmd.Flags().StringVarP(&commandeer.createFunctionInvocationOptions.LogLevelName, "log-level", "l", "info", "Log level - \"none\", \"debug\", \"info\", \"warn\", or \"error\"")
+	cmd.Flags().StringVar(&commandeer.captureLogsFilePath, "capture-logs-to-file", "", "Write the function logs to the given file (one JSON-encoded log per line) rather than to the output")
+	cmd.Flags().StringVar(&commandeer.createFunctionInvocationOptions.URL, "url", "", "Address (host:port) at which to invoke the function, rather than the one resolved by the platform")
+	cmd.Flags().IntVar(&commandeer.createFunctionInvocationOptions.Port, "port", 0, "Container port to invoke, for functions that publish more than one port (local platform only). With --grpc, the port of the gRPC trigger to invoke, for functions that have more than one")
 	cmd.Flags().StringVarP(&commandeer.externalIPAddresses, "external-ips", "", os.Getenv("NUCTL_EXTERNAL_IP_ADDRESSES"), "External IP addresses (comma-delimited) with which to invoke the function")
+	cmd.Flags().BoolVar(&commandeer.grpc, "grpc", false, "Invoke the function over gRPC (requires grpcurl), with the body as the JSON-encoded request message")
+	cmd.Flags().StringVar(&commandeer.grpcMethod, "grpc-method", "", "Fully-qualified gRPC method to invoke (for example, \"package.Service/Method\")")
+	cmd.Flags().IntVar(&commandeer.repeat, "repeat", 0, "Invoke the function this many times, reporting aggregate stats rather than the responses")
+	cmd.Flags().IntVar(&commandeer.repeat, "requests", 0, "Same as --repeat")
+	cmd.Flags().DurationVar(&commandeer.duration, "duration", 0, "Keep invoking the function for this long (e.g. 30s), reporting aggregate stats rather than the responses. With --repeat, stops at whichever comes first")
+	cmd.Flags().IntVar(&commandeer.concurrency, "concurrency", 1, "Number of requests in flight at once (with --repeat or --duration)")
+	cmd.Flags().Float64Var(&commandeer.rps, "rps", 0, "Maximum number of requests per second, 0 for unlimited (with --repeat or --duration)")
+	cmd.Flags().StringVar(&commandeer.grpcProtoPath, "grpc-proto", "", "Path to the proto file describing the service (default - use server reflection)")
+	cmd.Flags().StringVar(&commandeer.cloudEventType, "cloudevent", "", "Wrap the body in a CloudEvent (1.0) of this type (for example, \"com.example.order.created\")")
+	cmd.Flags().StringVar(&commandeer.cloudEventSource, "cloudevent-source", "nuctl", "Source of the CloudEvent the body is wrapped in (with --cloudevent)")
+	cmd.Flags().StringVar(&commandeer.cloudEventMode, "cloudevent-mode", "binary", "How the CloudEvent is encoded (with --cloudevent) - \"binary\": as ce-* headers; \"structured\": as a JSON envelope around the body")
+	cmd.Flags().BoolVar(&commandeer.createFunctionInvocationOptions.Stream, "stream", false, "Write the response body as it arrives (e.g. chunked responses or server-sent events), rather than once it's complete")
+
+	completeFunctionName(cmd)
 
 	commandeer.cmd = cmd
 
@@ -146,8 +258,12 @@ func (i *invokeCommandeer) outputInvokeResult(createFunctionInvocationOptions *p
 	invokeResult *platform.CreateFunctionInvocationResult,
 	writer io.Writer) error {
 
-	// try to output the logs (ignore errors)
-	if createFunctionInvocationOptions.LogLevelName != "none" {
+	// capture the logs to a file, keeping the output for the response alone
+	if i.captureLogsFilePath != "" {
+		if err := i.captureFunctionLogs(invokeResult, i.captureLogsFilePath); err != nil {
+			return errors.Wrap(err, "Failed to capture logs")
+		}
+	} else if createFunctionInvocationOptions.LogLevelName != "none" {
 		if err := i.outputFunctionLogs(invokeResult, writer); err != nil {
 			return errors.Wrap(err, "Failed to output logs")
 		}
@@ -159,13 +275,243 @@ func (i *invokeCommandeer) outputInvokeResult(createFunctionInvocationOptions *p
 	}
 
 	// output the body
-	if err := i.outputResponseBody(invokeResult, writer); err != nil {
+	if invokeResult.BodyStream != nil {
+		if err := i.outputResponseBodyStream(invokeResult, writer); err != nil {
+			return errors.Wrap(err, "Failed to output body stream")
+		}
+	} else if err := i.outputResponseBody(invokeResult, writer); err != nil {
 		return errors.Wrap(err, "Failed to output body")
 	}
 
 	return nil
 }
 
+// renderInvokeResult writes the response as a JSON object to the writer, and the function logs (unless captured
+// to a file) to the log writer
+func (i *invokeCommandeer) renderInvokeResult(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions,
+	invokeResult *platform.CreateFunctionInvocationResult,
+	writer io.Writer,
+	logWriter io.Writer) error {
+
+	if i.captureLogsFilePath != "" {
+		if err := i.captureFunctionLogs(invokeResult, i.captureLogsFilePath); err != nil {
+			return errors.Wrap(err, "Failed to capture logs")
+		}
+	} else if createFunctionInvocationOptions.LogLevelName != "none" {
+		if err := i.outputFunctionLogs(invokeResult, logWriter); err != nil {
+			return errors.Wrap(err, "Failed to output logs")
+		}
+	}
+
+	body := invokeResult.Body
+
+	// the result is written once the response is complete
+	if invokeResult.BodyStream != nil {
+		defer invokeResult.BodyStream.Close() // nolint: errcheck
+
+		var err error
+		body, err = ioutil.ReadAll(invokeResult.BodyStream)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read response body")
+		}
+	}
+
+	headers := map[string]string{}
+	for headerName, headerValue := range invokeResult.Headers {
+		if strings.EqualFold(headerName, "X-Nuclio-Logs") || len(headerValue) == 0 {
+			continue
+		}
+
+		headers[headerName] = headerValue[0]
+	}
+
+	return i.rootCommandeer.renderResult(writer, map[string]interface{}{
+		"statusCode": invokeResult.StatusCode,
+		"headers":    headers,
+		"body":       string(body),
+	})
+}
+
+func (i *invokeCommandeer) invokeGRPC(writer io.Writer) error {
+	if i.grpcMethod == "" {
+		return errors.New("gRPC method must be provided (--grpc-method)")
+	}
+
+	invokeAddress, err := i.resolveGRPCInvokeAddress()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve gRPC invoke address")
+	}
+
+	cmdRunner, err := cmdrunner.NewShellRunner(i.rootCommandeer.loggerInstance)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create command runner")
+	}
+
+	// the request message is read from stdin, an empty body is an empty message
+	requestMessage := string(i.createFunctionInvocationOptions.Body)
+	if requestMessage == "" {
+		requestMessage = "{}"
+	}
+
+	i.rootCommandeer.loggerInstance.InfoWith("Executing function over gRPC",
+		"address", invokeAddress,
+		"method", i.grpcMethod)
+
+	var quotedArguments []string
+	for _, argument := range i.getGRPCurlArguments(invokeAddress) {
+		quotedArguments = append(quotedArguments, cmdrunner.Quote(argument))
+	}
+
+	runResult, err := cmdRunner.Run(&cmdrunner.RunOptions{
+		Stdin:             &requestMessage,
+		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+	},
+		"grpcurl %s",
+		strings.Join(quotedArguments, " "))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to invoke gRPC method %s", i.grpcMethod)
+	}
+
+	fmt.Fprintf(writer, "\n%s\n", ansi.Color("> Response body:", "blue+h")) // nolint: errcheck
+	fmt.Fprintln(writer, runResult.Output)                                  // nolint: errcheck
+
+	return nil
+}
+
+// resolveGRPCInvokeAddress returns the given address, or the one on which the function's gRPC trigger (rather
+// than its HTTP trigger) is reachable
+func (i *invokeCommandeer) resolveGRPCInvokeAddress() (string, error) {
+	if i.createFunctionInvocationOptions.URL != "" {
+		return i.createFunctionInvocationOptions.URL, nil
+	}
+
+	functions, err := i.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      i.createFunctionInvocationOptions.Name,
+		Namespace: i.createFunctionInvocationOptions.Namespace,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return "", errors.Errorf("Function not found: %s @ %s",
+			i.createFunctionInvocationOptions.Name,
+			i.createFunctionInvocationOptions.Namespace)
+	}
+
+	function := functions[0]
+	if err := function.Initialize(nil); err != nil {
+		return "", errors.Wrap(err, "Failed to initialize function")
+	}
+
+	return function.GetGRPCInvokeURL(i.createFunctionInvocationOptions.Via, i.createFunctionInvocationOptions.Port)
+}
+
+// getGRPCurlArguments returns the arguments with which grpcurl invokes the method at the given address, reading
+// the request message from stdin
+func (i *invokeCommandeer) getGRPCurlArguments(invokeAddress string) []string {
+	arguments := []string{"-plaintext"}
+
+	// use the given proto file if provided, otherwise grpcurl uses server reflection
+	if i.grpcProtoPath != "" {
+		arguments = append(arguments,
+			"-import-path", filepath.Dir(i.grpcProtoPath),
+			"-proto", filepath.Base(i.grpcProtoPath))
+	}
+
+	// pass headers as metadata
+	headers := common.StringToStringMap(i.headers, "=")
+
+	var headerNames []string
+	for headerName := range headers {
+		headerNames = append(headerNames, headerName)
+	}
+
+	sort.Strings(headerNames)
+
+	for _, headerName := range headerNames {
+		arguments = append(arguments, "-H", fmt.Sprintf("%s: %s", headerName, headers[headerName]))
+	}
+
+	return append(arguments, "-d", "@", invokeAddress, i.grpcMethod)
+}
+
+// createTunnel returns a tunnel to the function if invoking via port-forward, or if invoking via any and the
+// function's address isn't reachable. otherwise it returns nil
+func (i *invokeCommandeer) createTunnel() (platform.FunctionTunnel, error) {
+	switch i.invokeVia {
+	case "port-forward":
+	case "any":
+
+		// the tunnel reaches the function's HTTP port alone, so gRPC invocations use the trigger's address as is
+		if i.grpc || i.createFunctionInvocationOptions.URL != "" || i.externalIPAddresses != "" || i.isFunctionReachable() {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	i.rootCommandeer.loggerInstance.InfoWith("Creating tunnel to function",
+		"name", i.createFunctionInvocationOptions.Name,
+		"namespace", i.createFunctionInvocationOptions.Namespace)
+
+	tunnel, err := i.rootCommandeer.platform.CreateFunctionTunnel(&platform.CreateFunctionTunnelOptions{
+		Name:      i.createFunctionInvocationOptions.Name,
+		Namespace: i.createFunctionInvocationOptions.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.rootCommandeer.loggerInstance.DebugWith("Created tunnel to function", "address", tunnel.GetAddress())
+
+	return tunnel, nil
+}
+
+func (i *invokeCommandeer) closeTunnel(tunnel platform.FunctionTunnel) {
+	if err := tunnel.Close(); err != nil {
+		i.rootCommandeer.loggerInstance.WarnWith("Failed to close tunnel to function", "err", err)
+	}
+}
+
+// isFunctionReachable returns false if the function's address can't be resolved or connected to. a function
+// that can't be found is considered reachable, leaving it to the invocation to report
+func (i *invokeCommandeer) isFunctionReachable() bool {
+	functions, err := i.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      i.createFunctionInvocationOptions.Name,
+		Namespace: i.createFunctionInvocationOptions.Namespace,
+	})
+	if err != nil || len(functions) == 0 {
+		return true
+	}
+
+	function := functions[0]
+	if err := function.Initialize(nil); err != nil {
+		return true
+	}
+
+	invokeURL, err := function.GetInvokeURL(i.createFunctionInvocationOptions.Via)
+	if err != nil {
+		i.rootCommandeer.loggerInstance.DebugWith("Failed to resolve function address", "err", err)
+		return false
+	}
+
+	// the invoke URL is host:port, possibly followed by a path
+	invokeAddress := strings.SplitN(invokeURL, "/", 2)[0]
+
+	connection, err := net.DialTimeout("tcp", invokeAddress, reachabilityTimeout)
+	if err != nil {
+		i.rootCommandeer.loggerInstance.DebugWith("Function address isn't reachable",
+			"address", invokeAddress,
+			"err", err)
+		return false
+	}
+
+	connection.Close() // nolint: errcheck
+
+	return true
+}
+
 func (i *invokeCommandeer) resolveBody() ([]byte, error) {
 
 	// try resolve body from flag
@@ -177,13 +523,51 @@ func (i *invokeCommandeer) resolveBody() ([]byte, error) {
 	return nuctlcommon.ReadFromInOrStdin(i.cmd.InOrStdin())
 }
 
+// wrapCloudEvent wraps the body in a CloudEvent, either by setting its ce-* headers or by replacing it with a
+// structured CloudEvent holding it
+func (i *invokeCommandeer) wrapCloudEvent() error {
+	attributes := &cloudevent.Attributes{
+		ID:     uuid.NewV4().String(),
+		Source: i.cloudEventSource,
+		Type:   i.cloudEventType,
+		Time:   time.Now(),
+	}
+
+	switch i.cloudEventMode {
+	case functionconfig.CloudEventsModeBinary:
+		for headerName, headerValue := range cloudevent.GetBinaryHeaders(attributes) {
+			i.createFunctionInvocationOptions.Headers.Set(headerName, headerValue)
+		}
+
+	case functionconfig.CloudEventsModeStructured:
+		if i.createFunctionInvocationOptions.BodyStream != nil {
+			return errors.New("--cloudevent-mode structured can't be used with --body-file or --form")
+		}
+
+		encodedEvent, err := cloudevent.EncodeStructured(attributes, i.contentType, i.createFunctionInvocationOptions.Body)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode structured cloud event")
+		}
+
+		i.createFunctionInvocationOptions.Body = encodedEvent
+		i.createFunctionInvocationOptions.Headers.Set("Content-Type", cloudevent.StructuredContentType)
+
+	default:
+		return errors.Errorf("Invalid cloud event mode %s - must be binary / structured", i.cloudEventMode)
+	}
+
+	return nil
+}
+
 func (i *invokeCommandeer) resolveMethod() string {
 
 	// if user did not specified method
 	if i.createFunctionInvocationOptions.Method == "" {
 
-		// user provided request body, default to POST
-		if len(i.createFunctionInvocationOptions.Body) > 0 {
+		// user provided request body (or an event), default to POST
+		if len(i.createFunctionInvocationOptions.Body) > 0 ||
+			i.createFunctionInvocationOptions.BodyStream != nil ||
+			i.cloudEventType != "" {
 			return http.MethodPost
 		}
 
@@ -195,15 +579,7 @@ func (i *invokeCommandeer) resolveMethod() string {
 }
 
 func (i *invokeCommandeer) outputFunctionLogs(invokeResult *platform.CreateFunctionInvocationResult, writer io.Writer) error {
-
-	// the function logs should return as JSON
-	functionLogs := []map[string]interface{}{}
-
-	// wrap the contents in [] so that it appears as a JSON array
-	encodedFunctionLogs := invokeResult.Headers.Get("x-nuclio-logs")
-
-	// parse the JSON into function logs
-	err := json.Unmarshal([]byte(encodedFunctionLogs), &functionLogs)
+	functionLogs, err := i.parseFunctionLogs(invokeResult)
 	if err != nil {
 		return errors.Wrap(err, "Failed to parse logs")
 	}
@@ -250,6 +626,53 @@ func (i *invokeCommandeer) outputFunctionLogs(invokeResult *platform.CreateFunct
 	return nil
 }
 
+// captureFunctionLogs writes the function logs to a file, one JSON-encoded log per line. the file is written
+// even if there are no logs, so that whoever reads it can tell the invocation happened
+func (i *invokeCommandeer) captureFunctionLogs(invokeResult *platform.CreateFunctionInvocationResult, path string) error {
+	functionLogs, err := i.parseFunctionLogs(invokeResult)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse logs")
+	}
+
+	logsFile, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create logs file")
+	}
+
+	defer logsFile.Close() // nolint: errcheck
+
+	logsEncoder := json.NewEncoder(logsFile)
+	for _, functionLog := range functionLogs {
+		if err := logsEncoder.Encode(functionLog); err != nil {
+			return errors.Wrap(err, "Failed to write log")
+		}
+	}
+
+	i.rootCommandeer.loggerInstance.InfoWith("Captured function logs", "path", path, "logs", len(functionLogs))
+
+	return nil
+}
+
+func (i *invokeCommandeer) parseFunctionLogs(invokeResult *platform.CreateFunctionInvocationResult) (
+	[]map[string]interface{}, error) {
+
+	// the function logs should return as JSON
+	functionLogs := []map[string]interface{}{}
+
+	// no logs are returned when none were requested
+	encodedFunctionLogs := invokeResult.Headers.Get("x-nuclio-logs")
+	if encodedFunctionLogs == "" {
+		return functionLogs, nil
+	}
+
+	// parse the JSON into function logs
+	if err := json.Unmarshal([]byte(encodedFunctionLogs), &functionLogs); err != nil {
+		return nil, err
+	}
+
+	return functionLogs, nil
+}
+
 func (i *invokeCommandeer) stringInterfaceMapToInterfaceSlice(input map[string]interface{}) []interface{} {
 	output := []interface{}{}
 
@@ -291,6 +714,22 @@ func (i *invokeCommandeer) outputResponseHeaders(invokeResult *platform.CreateFu
 	return nil
 }
 
+// outputResponseBodyStream writes the response body as it arrives. it can't be indented like a complete body
+func (i *invokeCommandeer) outputResponseBodyStream(invokeResult *platform.CreateFunctionInvocationResult,
+	writer io.Writer) error {
+	defer invokeResult.BodyStream.Close() // nolint: errcheck
+
+	fmt.Fprintf(writer, "\n%s\n", ansi.Color("> Response body:", "blue+h")) // nolint: errcheck
+
+	if _, err := io.Copy(writer, invokeResult.BodyStream); err != nil {
+		return errors.Wrap(err, "Failed to read response body")
+	}
+
+	fmt.Fprintln(writer) // nolint: errcheck
+
+	return nil
+}
+
 func (i *invokeCommandeer) outputResponseBody(invokeResult *platform.CreateFunctionInvocationResult, writer io.Writer) error {
 	var responseBodyString string
 
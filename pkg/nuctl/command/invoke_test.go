@@ -0,0 +1,228 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type invokeTestSuite struct {
+	suite.Suite
+	commandeer *invokeCommandeer
+}
+
+func (suite *invokeTestSuite) SetupTest() {
+	var err error
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.loggerInstance, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.commandeer = newInvokeCommandeer(rootCommandeer)
+	suite.commandeer.createFunctionInvocationOptions.LogLevelName = "debug"
+}
+
+func (suite *invokeTestSuite) TestCaptureLogsToFile() {
+	tempDir, err := ioutil.TempDir("", "invoke-test-")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	suite.commandeer.captureLogsFilePath = filepath.Join(tempDir, "logs.jsonl")
+
+	invokeResult := &platform.CreateFunctionInvocationResult{
+		Headers: http.Header{
+			"Content-Type":  []string{"text/plain"},
+			"X-Nuclio-Logs": []string{`[{"level": "info", "message": "first message", "name": "my-function"}, {"level": "debug", "message": "second message", "name": "my-function", "some-arg": 3}]`},
+		},
+		Body:       []byte("the body"),
+		StatusCode: http.StatusOK,
+	}
+
+	var output bytes.Buffer
+	err = suite.commandeer.outputInvokeResult(&suite.commandeer.createFunctionInvocationOptions,
+		invokeResult,
+		&output)
+	suite.Require().NoError(err)
+
+	// the body goes to the output, the logs don't
+	suite.Require().Contains(output.String(), "the body")
+	suite.Require().NotContains(output.String(), "first message")
+
+	capturedLogs, err := ioutil.ReadFile(suite.commandeer.captureLogsFilePath)
+	suite.Require().NoError(err)
+
+	capturedLogLines := strings.Split(strings.TrimSpace(string(capturedLogs)), "\n")
+	suite.Require().Len(capturedLogLines, 2)
+	suite.Require().JSONEq(`{"level": "info", "message": "first message", "name": "my-function"}`, capturedLogLines[0])
+	suite.Require().JSONEq(`{"level": "debug", "message": "second message", "name": "my-function", "some-arg": 3}`,
+		capturedLogLines[1])
+}
+
+func (suite *invokeTestSuite) TestCaptureNoLogsToFile() {
+	tempDir, err := ioutil.TempDir("", "invoke-test-")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	suite.commandeer.captureLogsFilePath = filepath.Join(tempDir, "logs.jsonl")
+
+	err = suite.commandeer.outputInvokeResult(&suite.commandeer.createFunctionInvocationOptions,
+		&platform.CreateFunctionInvocationResult{Headers: http.Header{}},
+		ioutil.Discard)
+	suite.Require().NoError(err)
+
+	// the file is created even when there are no logs
+	capturedLogs, err := ioutil.ReadFile(suite.commandeer.captureLogsFilePath)
+	suite.Require().NoError(err)
+	suite.Require().Empty(capturedLogs)
+}
+
+func (suite *invokeTestSuite) TestGetLatencyPercentile() {
+	var latencies []time.Duration
+	for latencyIdx := 1; latencyIdx <= 200; latencyIdx++ {
+		latencies = append(latencies, time.Duration(latencyIdx)*time.Millisecond)
+	}
+
+	suite.Require().Equal(100*time.Millisecond, getLatencyPercentile(latencies, 50))
+	suite.Require().Equal(198*time.Millisecond, getLatencyPercentile(latencies, 99))
+	suite.Require().Equal(200*time.Millisecond, getLatencyPercentile(latencies, 100))
+	suite.Require().Equal(5*time.Millisecond, getLatencyPercentile(latencies[4:5], 50))
+}
+
+func (suite *invokeTestSuite) TestGetLatencyHistogram() {
+	var latencies []time.Duration
+	for latencyMs := 1; latencyMs <= 100; latencyMs++ {
+		latencies = append(latencies, time.Duration(latencyMs)*time.Millisecond)
+	}
+
+	histogram := getLatencyHistogram(latencies, 10)
+	suite.Require().Len(histogram, 10)
+
+	totalCount := 0
+	for _, bucket := range histogram {
+		totalCount += bucket.count
+	}
+
+	suite.Require().Equal(100, totalCount)
+	suite.Require().Equal(100*time.Millisecond, histogram[9].upperBound)
+	suite.Require().Equal(10, histogram[1].count)
+
+	// identical latencies fall in a single bucket
+	histogram = getLatencyHistogram(latencies[:1], 10)
+	suite.Require().Equal([]latencyHistogramBucket{{upperBound: time.Millisecond, count: 1}}, histogram)
+}
+
+func (suite *invokeTestSuite) TestGetGRPCurlArguments() {
+	suite.commandeer.grpcMethod = "nuclio.Function/Invoke"
+
+	// server reflection
+	suite.Require().Equal([]string{"-plaintext", "-d", "@", "10.0.0.1:30001", "nuclio.Function/Invoke"},
+		suite.commandeer.getGRPCurlArguments("10.0.0.1:30001"))
+
+	// each header is a single argument, whatever it contains
+	suite.commandeer.grpcProtoPath = "/protos/my service/invoke.proto"
+	suite.commandeer.headers = "x-b=it's,x-a=$(touch /tmp/injected)"
+
+	suite.Require().Equal([]string{
+		"-plaintext",
+		"-import-path", "/protos/my service",
+		"-proto", "invoke.proto",
+		"-H", "x-a: $(touch /tmp/injected)",
+		"-H", "x-b: it's",
+		"-d", "@", "10.0.0.1:30001", "nuclio.Function/Invoke",
+	}, suite.commandeer.getGRPCurlArguments("10.0.0.1:30001"))
+}
+
+func (suite *invokeTestSuite) TestResolveGRPCInvokeAddress() {
+	mockPlatform := &mockplatform.Platform{}
+	suite.commandeer.rootCommandeer.platform = mockPlatform
+
+	function := &grpcFunction{
+		AbstractFunction: platform.AbstractFunction{
+			Config: functionconfig.Config{
+				Spec: functionconfig.Spec{
+					Triggers: map[string]functionconfig.Trigger{
+						"http": {Kind: "http"},
+						"grpc": {Kind: "grpc", URL: ":9000"},
+					},
+				},
+			},
+		},
+	}
+
+	mockPlatform.
+		On("GetFunctions", mock.Anything).
+		Return([]platform.Function{function}, nil).
+		Once()
+
+	// the address of the gRPC trigger, rather than the function's invoke URL
+	suite.commandeer.createFunctionInvocationOptions.Via = platform.InvokeViaExternalIP
+	invokeAddress, err := suite.commandeer.resolveGRPCInvokeAddress()
+	suite.Require().NoError(err)
+	suite.Require().Equal("10.0.0.1:9000", invokeAddress)
+	suite.Require().Equal(platform.InvokeViaExternalIP, function.invokeVia)
+
+	// a given address is used as is
+	suite.commandeer.createFunctionInvocationOptions.URL = "localhost:50051"
+	invokeAddress, err = suite.commandeer.resolveGRPCInvokeAddress()
+	suite.Require().NoError(err)
+	suite.Require().Equal("localhost:50051", invokeAddress)
+
+	mockPlatform.AssertExpectations(suite.T())
+}
+
+func TestInvokeTestSuite(t *testing.T) {
+	suite.Run(t, new(invokeTestSuite))
+}
+
+// grpcFunction is a function whose gRPC trigger is at the external IP
+type grpcFunction struct {
+	platform.AbstractFunction
+	invokeVia platform.InvokeViaType
+}
+
+func (f *grpcFunction) GetInvokeURL(platform.InvokeViaType) (string, error) {
+	return "10.0.0.1:30000", nil
+}
+
+func (f *grpcFunction) GetGRPCInvokeURL(invokeVia platform.InvokeViaType, grpcPort int) (string, error) {
+	f.invokeVia = invokeVia
+
+	grpcPort, err := f.ResolveGRPCPort(grpcPort)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("10.0.0.1:%d", grpcPort), nil
+}
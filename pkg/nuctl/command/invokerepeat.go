@@ -0,0 +1,278 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+)
+
+// the number of buckets of the latency histogram, and the width of its largest bar
+const (
+	latencyHistogramBuckets  = 10
+	latencyHistogramBarWidth = 40
+)
+
+// invokeRepeatStats aggregates the results of repeated invocations
+type invokeRepeatStats struct {
+	requests    int
+	failed      int
+	errors      int
+	statusCodes map[int]int
+	latencies   []time.Duration
+	duration    time.Duration
+}
+
+// latencyHistogramBucket holds the number of latencies up to its upper bound (and above the previous bucket's)
+type latencyHistogramBucket struct {
+	upperBound time.Duration
+	count      int
+}
+
+// invokeRepeatedly invokes the function until the number of requests (--repeat) were sent or the duration
+// (--duration) passed, whichever comes first, with --concurrency requests in flight at a rate of up to --rps.
+// the responses aren't output, only the aggregate stats
+func (i *invokeCommandeer) invokeRepeatedly(writer io.Writer) error {
+	if i.repeat < 0 || i.duration < 0 {
+		return errors.New("Number of repeats and duration must not be negative")
+	}
+
+	if i.concurrency < 1 {
+		return errors.New("Concurrency must be at least 1")
+	}
+
+	if i.rps < 0 {
+		return errors.New("Requests per second must not be negative")
+	}
+
+	stats := invokeRepeatStats{
+		statusCodes: map[int]int{},
+	}
+	statsLock := sync.Mutex{}
+
+	// the logs of each invocation aren't output, so don't have the function return them
+	createFunctionInvocationOptions := i.createFunctionInvocationOptions
+	createFunctionInvocationOptions.LogLevelName = "none"
+
+	requests := make(chan struct{})
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(i.concurrency)
+
+	for workerIdx := 0; workerIdx < i.concurrency; workerIdx++ {
+		go func() {
+			defer waitGroup.Done()
+
+			for range requests {
+				requestOptions := createFunctionInvocationOptions
+				requestOptions.Headers = createFunctionInvocationOptions.Headers.Clone()
+
+				requestStartTime := time.Now()
+				invokeResult, err := i.rootCommandeer.platform.CreateFunctionInvocation(&requestOptions)
+				latency := time.Since(requestStartTime)
+
+				statsLock.Lock()
+				stats.requests++
+				stats.latencies = append(stats.latencies, latency)
+
+				if err != nil {
+					stats.failed++
+					stats.errors++
+					i.rootCommandeer.loggerInstance.DebugWith("Invocation failed", "err", err.Error())
+				} else {
+					stats.statusCodes[invokeResult.StatusCode]++
+					if invokeResult.StatusCode >= http.StatusBadRequest {
+						stats.failed++
+					}
+				}
+				statsLock.Unlock()
+			}
+		}()
+	}
+
+	var deadline <-chan time.Time
+	if i.duration > 0 {
+		deadlineTimer := time.NewTimer(i.duration)
+		defer deadlineTimer.Stop()
+
+		deadline = deadlineTimer.C
+	}
+
+	var ticks <-chan time.Time
+	if i.rps > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / i.rps))
+		defer ticker.Stop()
+
+		ticks = ticker.C
+	}
+
+	i.rootCommandeer.loggerInstance.InfoWith("Invoking function repeatedly",
+		"repeat", i.repeat,
+		"duration", i.duration,
+		"concurrency", i.concurrency,
+		"rps", i.rps)
+
+	startTime := time.Now()
+
+dispatch:
+	for sentRequests := 0; i.repeat == 0 || sentRequests < i.repeat; sentRequests++ {
+		if ticks != nil {
+			select {
+			case <-ticks:
+			case <-deadline:
+				break dispatch
+			}
+		}
+
+		select {
+		case requests <- struct{}{}:
+		case <-deadline:
+			break dispatch
+		}
+	}
+
+	// let the requests in flight complete
+	close(requests)
+	waitGroup.Wait()
+
+	stats.duration = time.Since(startTime)
+
+	i.renderInvokeRepeatStats(writer, &stats)
+
+	if stats.failed > 0 {
+		return errors.Errorf("%d of %d requests failed", stats.failed, stats.requests)
+	}
+
+	return nil
+}
+
+func (i *invokeCommandeer) renderInvokeRepeatStats(writer io.Writer, stats *invokeRepeatStats) {
+	records := [][]string{
+		{"requests", strconv.Itoa(stats.requests)},
+		{"failed", strconv.Itoa(stats.failed)},
+		{"errors", strconv.Itoa(stats.errors)},
+		{"duration", stats.duration.Round(time.Microsecond).String()},
+		{"rps", fmt.Sprintf("%.2f", float64(stats.requests)/stats.duration.Seconds())},
+	}
+
+	if len(stats.latencies) > 0 {
+		sort.Slice(stats.latencies, func(first, second int) bool {
+			return stats.latencies[first] < stats.latencies[second]
+		})
+
+		var totalLatency time.Duration
+		for _, latency := range stats.latencies {
+			totalLatency += latency
+		}
+
+		records = append(records,
+			[]string{"latency min", formatLatency(stats.latencies[0])},
+			[]string{"latency mean", formatLatency(totalLatency / time.Duration(len(stats.latencies)))},
+			[]string{"latency p50", formatLatency(getLatencyPercentile(stats.latencies, 50))},
+			[]string{"latency p90", formatLatency(getLatencyPercentile(stats.latencies, 90))},
+			[]string{"latency p99", formatLatency(getLatencyPercentile(stats.latencies, 99))},
+			[]string{"latency max", formatLatency(stats.latencies[len(stats.latencies)-1])})
+	}
+
+	var statusCodes []int
+	for statusCode := range stats.statusCodes {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Ints(statusCodes)
+
+	for _, statusCode := range statusCodes {
+		records = append(records, []string{fmt.Sprintf("status %d", statusCode), strconv.Itoa(stats.statusCodes[statusCode])})
+	}
+
+	renderer.NewRenderer(writer).RenderTable([]string{"Stat", "Value"}, records)
+
+	if len(stats.latencies) > 0 {
+		renderLatencyHistogram(writer, getLatencyHistogram(stats.latencies, latencyHistogramBuckets))
+	}
+}
+
+// getLatencyHistogram divides the range of the (sorted) latencies into buckets of equal width
+func getLatencyHistogram(sortedLatencies []time.Duration, buckets int) []latencyHistogramBucket {
+	minLatency := sortedLatencies[0]
+	maxLatency := sortedLatencies[len(sortedLatencies)-1]
+
+	// all the latencies are the same, a single bucket holds them
+	if minLatency == maxLatency {
+		return []latencyHistogramBucket{{upperBound: maxLatency, count: len(sortedLatencies)}}
+	}
+
+	bucketWidth := (maxLatency - minLatency) / time.Duration(buckets)
+	histogram := make([]latencyHistogramBucket, buckets)
+
+	for bucketIdx := range histogram {
+		histogram[bucketIdx].upperBound = minLatency + bucketWidth*time.Duration(bucketIdx+1)
+	}
+
+	// the last bucket holds the maximum, whatever the rounding of the width
+	histogram[buckets-1].upperBound = maxLatency
+
+	bucketIdx := 0
+	for _, latency := range sortedLatencies {
+		for latency > histogram[bucketIdx].upperBound {
+			bucketIdx++
+		}
+
+		histogram[bucketIdx].count++
+	}
+
+	return histogram
+}
+
+func renderLatencyHistogram(writer io.Writer, histogram []latencyHistogramBucket) {
+	maxCount := 0
+	for _, bucket := range histogram {
+		if bucket.count > maxCount {
+			maxCount = bucket.count
+		}
+	}
+
+	fmt.Fprintln(writer, "\nLatency histogram:") // nolint: errcheck
+
+	for _, bucket := range histogram {
+		bar := strings.Repeat("#", bucket.count*latencyHistogramBarWidth/maxCount)
+
+		fmt.Fprintf(writer, "  %12s [%6d] %s\n", formatLatency(bucket.upperBound), bucket.count, bar) // nolint: errcheck
+	}
+}
+
+// getLatencyPercentile returns the latency below which the given percentage of the (sorted) latencies are
+func getLatencyPercentile(sortedLatencies []time.Duration, percentile int) time.Duration {
+	latencyIdx := (len(sortedLatencies)*percentile+99)/100 - 1
+	if latencyIdx < 0 {
+		latencyIdx = 0
+	}
+
+	return sortedLatencies[latencyIdx]
+}
+
+func formatLatency(latency time.Duration) string {
+	return latency.Round(time.Microsecond).String()
+}
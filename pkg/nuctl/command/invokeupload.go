@@ -0,0 +1,171 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/errors"
+)
+
+// invokeFormField is a multipart form field given with --form, holding either a value or the path of a file
+// to upload
+type invokeFormField struct {
+	name     string
+	value    string
+	filePath string
+}
+
+var formFieldQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// resolveBodyStream has the request body streamed from the file given with --body-file, or encoded as a
+// multipart form from the fields given with --form, so that files aren't loaded into memory
+func (i *invokeCommandeer) resolveBodyStream() error {
+	if i.bodyFilePath != "" && len(i.formFields) > 0 {
+		return errors.New("--body-file and --form can't be used together")
+	}
+
+	if i.body != "" {
+		return errors.New("--body can't be used with --body-file or --form")
+	}
+
+	if i.grpc || i.repeat != 0 || i.duration != 0 {
+		return errors.New("--body-file and --form can't be used with --grpc, --repeat or --duration")
+	}
+
+	if i.bodyFilePath != "" {
+		bodyFile, err := os.Open(i.bodyFilePath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to open body file")
+		}
+
+		// the file is closed once the request is sent
+		i.createFunctionInvocationOptions.BodyStream = bodyFile
+
+		if !i.cmd.Flags().Changed("content-type") {
+			i.contentType = getContentTypeByExtension(i.bodyFilePath)
+		}
+
+		return nil
+	}
+
+	formFields, err := parseInvokeFormFields(i.formFields)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse form fields")
+	}
+
+	// the form is encoded as the request reads it
+	bodyReader, bodyWriter := io.Pipe()
+	multipartWriter := multipart.NewWriter(bodyWriter)
+
+	go func() {
+		bodyWriter.CloseWithError(writeInvokeForm(multipartWriter, formFields)) // nolint: errcheck
+	}()
+
+	i.createFunctionInvocationOptions.BodyStream = bodyReader
+	i.contentType = multipartWriter.FormDataContentType()
+
+	return nil
+}
+
+func parseInvokeFormFields(encodedFormFields []string) ([]invokeFormField, error) {
+	var formFields []invokeFormField
+
+	for _, encodedFormField := range encodedFormFields {
+		formFieldParts := strings.SplitN(encodedFormField, "=", 2)
+		if len(formFieldParts) != 2 || formFieldParts[0] == "" {
+			return nil, errors.Errorf("Form field must be name=value or name=@path, got %s", encodedFormField)
+		}
+
+		formField := invokeFormField{name: formFieldParts[0]}
+
+		if strings.HasPrefix(formFieldParts[1], "@") {
+			formField.filePath = strings.TrimPrefix(formFieldParts[1], "@")
+
+			// fail before sending anything if the file can't be uploaded
+			if _, err := os.Stat(formField.filePath); err != nil {
+				return nil, errors.Wrapf(err, "Failed to stat file of form field %s", formField.name)
+			}
+		} else {
+			formField.value = formFieldParts[1]
+		}
+
+		formFields = append(formFields, formField)
+	}
+
+	return formFields, nil
+}
+
+func writeInvokeForm(multipartWriter *multipart.Writer, formFields []invokeFormField) error {
+	for _, formField := range formFields {
+		if formField.filePath == "" {
+			if err := multipartWriter.WriteField(formField.name, formField.value); err != nil {
+				return errors.Wrapf(err, "Failed to write form field %s", formField.name)
+			}
+
+			continue
+		}
+
+		if err := writeInvokeFormFile(multipartWriter, formField); err != nil {
+			return errors.Wrapf(err, "Failed to write file of form field %s", formField.name)
+		}
+	}
+
+	return multipartWriter.Close()
+}
+
+func writeInvokeFormFile(multipartWriter *multipart.Writer, formField invokeFormField) error {
+	file, err := os.Open(formField.filePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open file")
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	// unlike CreateFormFile, set the part's content type by the file's extension
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		formFieldQuoteEscaper.Replace(formField.name),
+		formFieldQuoteEscaper.Replace(filepath.Base(formField.filePath))))
+	partHeader.Set("Content-Type", getContentTypeByExtension(formField.filePath))
+
+	part, err := multipartWriter.CreatePart(partHeader)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create part")
+	}
+
+	if _, err := io.Copy(part, file); err != nil {
+		return errors.Wrap(err, "Failed to copy file")
+	}
+
+	return nil
+}
+
+func getContentTypeByExtension(filePath string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
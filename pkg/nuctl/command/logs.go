@@ -0,0 +1,84 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type getFunctionLogsCommandeer struct {
+	*getCommandeer
+	getFunctionLogsOptions platform.GetFunctionLogsOptions
+}
+
+func newGetFunctionLogsCommandeer(getCommandeer *getCommandeer) *getFunctionLogsCommandeer {
+	commandeer := &getFunctionLogsCommandeer{
+		getCommandeer: getCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "logs name",
+		Short: "Display the logs of a function's replica",
+		Long: `Display the logs of a function's replica (a pod on kube, the function's container on local).
+A function with more than one replica requires choosing one with --replica`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.getFunctionLogsOptions.Since < 0 {
+				return errors.New("--since must not be negative")
+			}
+
+			rootCommandeer := getCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			commandeer.getFunctionLogsOptions.Name = args[0]
+			commandeer.getFunctionLogsOptions.Namespace = rootCommandeer.namespace
+
+			logStream, err := rootCommandeer.platform.GetFunctionLogs(&commandeer.getFunctionLogsOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get function logs")
+			}
+
+			defer logStream.Close() // nolint: errcheck
+
+			// when following, this returns only once the replica stops (or nuctl is interrupted)
+			if _, err := io.Copy(cmd.OutOrStdout(), logStream); err != nil {
+				return errors.Wrap(err, "Failed to read function logs")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&commandeer.getFunctionLogsOptions.Follow, "follow", "f", false, "Keep writing the logs as the replica writes them")
+	cmd.Flags().DurationVar(&commandeer.getFunctionLogsOptions.Since, "since", 0, "Only the logs written within this duration (e.g. 10m)")
+	cmd.Flags().StringVar(&commandeer.getFunctionLogsOptions.Replica, "replica", "", "The replica to display the logs of - a pod name on kube, the container name on local")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
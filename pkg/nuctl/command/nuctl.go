@@ -17,13 +17,18 @@ limitations under the License.
 package command
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	nuctl_common "github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/config"
 	"github.com/nuclio/nuclio/pkg/platform/factory"
 	"github.com/nuclio/nuclio/pkg/platform/kube"
+	"github.com/nuclio/nuclio/pkg/renderer"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -35,15 +40,82 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 )
 
+const (
+
+	// ExitCodeFunctionDiffers is nuctl's exit code when a function differs from its desired configuration
+	ExitCodeFunctionDiffers = 2
+
+	// ExitCodeUnsupportedOperation is nuctl's exit code when a command (or a value given to it) isn't supported on
+	// the platform
+	ExitCodeUnsupportedOperation = 3
+
+	// ExitCodeInvalidFunctionConfig is nuctl's exit code when a validated function configuration is invalid
+	ExitCodeInvalidFunctionConfig = 4
+
+	// ExitCodeResponsesDiffer is nuctl's exit code when the responses to replayed events differ from the recorded
+	// ones
+	ExitCodeResponsesDiffer = 5
+)
+
+// kubePlatforms are the platforms of operations that require the kube platform (e.g. updating functions in place).
+// tests add the fake platform, which emulates it
+var kubePlatforms = []string{"kube"}
+
+// UnsupportedOperationError is returned when a command is run on a platform it doesn't support
+type UnsupportedOperationError struct {
+	Operation    string
+	PlatformName string
+}
+
+func (e *UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("Operation %s is not supported on the %s platform", e.Operation, e.PlatformName)
+}
+
+// UnsupportedValueError is returned when a command is supported on the platform, but a value given to it isn't
+type UnsupportedValueError struct {
+	Flag         string
+	Value        string
+	PlatformName string
+	Reason       string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return fmt.Sprintf("Value %s of %s is not supported on the %s platform (%s)",
+		e.Value,
+		e.Flag,
+		e.PlatformName,
+		e.Reason)
+}
+
+// FunctionDiffersError is returned when a function differs from its desired configuration
+type FunctionDiffersError struct {
+	Name string
+}
+
+func (e *FunctionDiffersError) Error() string {
+	return fmt.Sprintf("Function %s differs from the desired configuration", e.Name)
+}
+
 type RootCommandeer struct {
 	loggerInstance        logger.Logger
 	cmd                   *cobra.Command
 	platformName          string
 	platform              platform.Platform
 	namespace             string
+	contextName           string
 	verbose               bool
+	logLevel              string
+	logOutput             string
+	output                string
+	resultRendered        bool
 	platformConfiguration interface{}
 
+	// if set, logs are written to it as JSON (one object per line), regardless of --log-output
+	jsonLogSink io.Writer
+
+	// the file logs are written to, if --log-output is a path. closed once the command is done
+	logFile *os.File
+
 	// platform-specific configurations
 	kubeConfiguration config.Configuration
 }
@@ -56,14 +128,33 @@ func NewRootCommandeer() *RootCommandeer {
 		Short:         "Nuclio command-line interface",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+
+			// commands with their own --output flag (e.g. get) shadow the global one, so take the one that was parsed
+			if outputFlag := cmd.Flags().Lookup("output"); outputFlag != nil {
+				commandeer.output = outputFlag.Value.String()
+			}
+
+			// contexts are managed by the config commands rather than applied to them
+			if cmd.HasParent() && cmd.Parent().Name() == "config" {
+				return nil
+			}
+
+			return commandeer.applyContext(cmd)
+		},
 	}
 
 	defaultPlatformType := common.GetEnvOrDefaultString("NUCTL_PLATFORM", "auto")
 	defaultNamespace := os.Getenv("NUCTL_NAMESPACE")
 
 	cmd.PersistentFlags().BoolVarP(&commandeer.verbose, "verbose", "v", false, "Verbose output")
-	cmd.PersistentFlags().StringVarP(&commandeer.platformName, "platform", "", defaultPlatformType, "Platform identifier - \"kube\", \"local\", or \"auto\"")
+	cmd.PersistentFlags().StringVar(&commandeer.logLevel, "log-level", "", "Log level of nuctl - \"debug\", \"info\", \"warn\" or \"error\" (default - \"info\", or \"debug\" if verbose). For invoke, sets the function's log level instead")
+	cmd.PersistentFlags().StringVar(&commandeer.logOutput, "log-output", "", "Where logs are written - \"stdout\" or \"stderr\" of the command, or a path to a file (default - the process's stdout, or the command's stderr with --output json)")
+	cmd.PersistentFlags().StringVarP(&commandeer.output, "output", "o", nuctl_common.OutputFormatText, "Output format of the command's result - \"text\" or \"json\" (some commands support more)")
+	cmd.PersistentFlags().StringVarP(&commandeer.platformName, "platform", "", defaultPlatformType, "Platform identifier - \"kube\", \"local\", \"mock\" (in-memory, for testing) or \"auto\"")
 	cmd.PersistentFlags().StringVarP(&commandeer.namespace, "namespace", "n", defaultNamespace, "Namespace")
+	cmd.PersistentFlags().StringVar(&commandeer.contextName, "context", os.Getenv("NUCTL_CONTEXT"), "Context whose defaults are used, instead of the current context (see nuctl config, env: NUCTL_CONTEXT)")
 
 	// platform specific
 	cmd.PersistentFlags().StringVarP(&commandeer.kubeConfiguration.KubeconfigPath,
@@ -76,6 +167,7 @@ func NewRootCommandeer() *RootCommandeer {
 	cmd.AddCommand(
 		newBuildCommandeer(commandeer).cmd,
 		newDeployCommandeer(commandeer).cmd,
+		newExecCommandeer(commandeer).cmd,
 		newInvokeCommandeer(commandeer).cmd,
 		newGetCommandeer(commandeer).cmd,
 		newDeleteCommandeer(commandeer).cmd,
@@ -84,6 +176,23 @@ func NewRootCommandeer() *RootCommandeer {
 		newCreateCommandeer(commandeer).cmd,
 		newExportCommandeer(commandeer).cmd,
 		newImportCommandeer(commandeer).cmd,
+		newApplyCommandeer(commandeer).cmd,
+		newScaleCommandeer(commandeer).cmd,
+		newPauseCommandeer(commandeer).cmd,
+		newResumeCommandeer(commandeer).cmd,
+		newTopCommandeer(commandeer).cmd,
+		newDeprecateCommandeer(commandeer).cmd,
+		newDiffCommandeer(commandeer).cmd,
+		newCompletionCommandeer(commandeer).cmd,
+		newCleanupCommandeer(commandeer).cmd,
+		newDoctorCommandeer(commandeer).cmd,
+		newPromoteCommandeer(commandeer).cmd,
+		newRollbackCommandeer(commandeer).cmd,
+		newConfigCommandeer(commandeer).cmd,
+		newPruneCommandeer(commandeer).cmd,
+		newValidateCommandeer(commandeer).cmd,
+		newReplayCommandeer(commandeer).cmd,
+		newTriggerCommandeer(commandeer).cmd,
 	)
 
 	commandeer.cmd = cmd
@@ -93,7 +202,23 @@ func NewRootCommandeer() *RootCommandeer {
 
 // Execute uses os.Args to execute the command
 func (rc *RootCommandeer) Execute() error {
-	return rc.cmd.Execute()
+	err := rc.cmd.Execute()
+
+	// post-run hooks aren't run when the command fails, so the log file is closed here
+	if closeErr := rc.closeLogFile(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	// the error is part of the result, so that scripts don't need to parse the logs for it
+	if err != nil && rc.isJSONOutput() && !rc.resultRendered {
+		if renderErr := rc.renderResult(rc.cmd.OutOrStdout(), map[string]string{
+			"error": errors.RootCause(err).Error(),
+		}); renderErr != nil {
+			return errors.Wrap(renderErr, "Failed to render error")
+		}
+	}
+
+	return err
 }
 
 // GetCmd returns the underlying cobra command
@@ -106,6 +231,62 @@ func (rc *RootCommandeer) CreateMarkdown(path string) error {
 	return doc.GenMarkdownTree(rc.cmd, path)
 }
 
+// applyContext sets the flags of the command which weren't given (explicitly, or through their environment
+// variables) to the values of the context, if there is one
+func (rc *RootCommandeer) applyContext(cmd *cobra.Command) error {
+	config, err := nuctl_common.ReadConfig(nuctl_common.GetConfigPath())
+	if err != nil {
+		return errors.Wrap(err, "Failed to read configuration")
+	}
+
+	contextName := rc.contextName
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+
+	if contextName == "" {
+		return nil
+	}
+
+	context, err := config.GetContext(contextName)
+	if err != nil {
+		return err
+	}
+
+	for _, contextFlag := range []struct {
+		name    string
+		envName string
+		value   string
+	}{
+		{"platform", "NUCTL_PLATFORM", context.Platform},
+		{"namespace", "NUCTL_NAMESPACE", context.Namespace},
+		{"kubeconfig", "", context.Kubeconfig},
+		{"registry", "NUCTL_REGISTRY", context.Registry},
+		{"run-registry", "NUCTL_RUN_REGISTRY", context.RunRegistry},
+		{"project-name", "", context.Project},
+		{"dashboard-url", "NUCTL_DASHBOARD_URL", context.DashboardURL},
+	} {
+		flag := cmd.Flags().Lookup(contextFlag.name)
+		if flag == nil || flag.Changed || contextFlag.value == "" {
+			continue
+		}
+
+		if contextFlag.envName != "" && os.Getenv(contextFlag.envName) != "" {
+			continue
+		}
+
+		if err := flag.Value.Set(contextFlag.value); err != nil {
+			return errors.Wrapf(err, "Failed to set --%s from context %s", contextFlag.name, contextName)
+		}
+	}
+
+	if rc.kubeConfiguration.KubeContext == "" {
+		rc.kubeConfiguration.KubeContext = context.KubeContext
+	}
+
+	return nil
+}
+
 func (rc *RootCommandeer) initialize() error {
 	var err error
 
@@ -128,16 +309,113 @@ func (rc *RootCommandeer) initialize() error {
 	return nil
 }
 
+// initializeForOperation initializes the root like initialize, but fails with an UnsupportedOperationError
+// if the platform isn't one of the operation's supported platforms
+func (rc *RootCommandeer) initializeForOperation(operation string, supportedPlatforms []string) error {
+
+	// an explicitly given platform can be validated before doing any work
+	if err := rc.validateGivenPlatform(operation, supportedPlatforms); err != nil {
+		return err
+	}
+
+	if err := rc.initialize(); err != nil {
+		return errors.Wrap(err, "Failed to initialize root")
+	}
+
+	// the platform was resolved automatically
+	return rc.validateOperationPlatform(operation, supportedPlatforms)
+}
+
+// validateGivenPlatform fails with an UnsupportedOperationError if the platform was given explicitly (rather
+// than resolved automatically), and isn't one of the operation's supported platforms
+func (rc *RootCommandeer) validateGivenPlatform(operation string, supportedPlatforms []string) error {
+	if rc.platformName != "auto" && !common.StringInSlice(rc.platformName, supportedPlatforms) {
+		return &UnsupportedOperationError{
+			Operation:    operation,
+			PlatformName: rc.platformName,
+		}
+	}
+
+	return nil
+}
+
+// validateOperationPlatform fails with an UnsupportedOperationError if the initialized platform isn't one of
+// the operation's supported platforms. used by operations that depend on the given flags
+func (rc *RootCommandeer) validateOperationPlatform(operation string, supportedPlatforms []string) error {
+	if !common.StringInSlice(rc.platform.GetName(), supportedPlatforms) {
+		return &UnsupportedOperationError{
+			Operation:    operation,
+			PlatformName: rc.platform.GetName(),
+		}
+	}
+
+	return nil
+}
+
+// resolveNamespacedName splits an identifier of the form [namespace/]name - the way get --all-namespaces lists
+// resources of other namespaces - into the namespace and the name. identifiers without a namespace are in the
+// namespace of the command. must be called once the root is initialized
+func (rc *RootCommandeer) resolveNamespacedName(cmd *cobra.Command, identifier string) (string, string, error) {
+	identifierParts := strings.Split(identifier, "/")
+	if len(identifierParts) == 1 {
+		return rc.namespace, identifier, nil
+	}
+
+	if len(identifierParts) != 2 || identifierParts[0] == "" || identifierParts[1] == "" {
+		return "", "", errors.Errorf("Identifier must be of the form [namespace/]name, got %s", identifier)
+	}
+
+	namespace, name := identifierParts[0], identifierParts[1]
+
+	// a namespace given with --namespace must agree
+	if namespaceFlag := cmd.Flags().Lookup("namespace"); namespaceFlag != nil && namespaceFlag.Changed &&
+		rc.namespace != namespace {
+		return "", "", errors.Errorf("Identifier %s is of namespace %s, but --namespace %s was given",
+			identifier,
+			namespace,
+			rc.namespace)
+	}
+
+	return namespace, name, nil
+}
+
 func (rc *RootCommandeer) createLogger() (logger.Logger, error) {
 	var loggerLevel nucliozap.Level
 
-	if rc.verbose {
-		loggerLevel = nucliozap.DebugLevel
-	} else {
-		loggerLevel = nucliozap.InfoLevel
+	switch rc.logLevel {
+	case "":
+		if rc.verbose {
+			loggerLevel = nucliozap.DebugLevel
+		} else {
+			loggerLevel = nucliozap.InfoLevel
+		}
+	case "debug", "info", "warn", "error":
+		loggerLevel = nucliozap.GetLevelByName(rc.logLevel)
+	default:
+		return nil, errors.Errorf("Invalid log level %s, must be one of: debug, info, warn, error", rc.logLevel)
 	}
 
-	loggerInstance, err := nucliozap.NewNuclioZapCmd("nuctl", loggerLevel)
+	if rc.jsonLogSink != nil {
+		loggerInstance, err := nucliozap.NewNuclioZap("nuctl", "json", &nucliozap.EncoderConfig{
+			JSON: nucliozap.EncoderConfigJSON{
+				LineEnding:        "\n",
+				TimeFieldName:     "time",
+				TimeFieldEncoding: "iso8601",
+			},
+		}, rc.jsonLogSink, rc.jsonLogSink, loggerLevel)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create logger")
+		}
+
+		return loggerInstance, nil
+	}
+
+	loggerOutput, err := rc.resolveLogOutput()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve log output")
+	}
+
+	loggerInstance, err := nucliozap.NewNuclioZap("nuctl", "console", nil, loggerOutput, loggerOutput, loggerLevel)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create logger")
 	}
@@ -145,6 +423,77 @@ func (rc *RootCommandeer) createLogger() (logger.Logger, error) {
 	return loggerInstance, nil
 }
 
+// resolveLogOutput returns the writer logs are written to. stdout and stderr are those of the command, which
+// are the process's unless redirected (e.g. to capture the output)
+func (rc *RootCommandeer) resolveLogOutput() (io.Writer, error) {
+	switch rc.logOutput {
+	case "":
+		if rc.isJSONOutput() {
+			return rc.cmd.ErrOrStderr(), nil
+		}
+
+		return os.Stdout, nil
+	case "stdout":
+		return rc.cmd.OutOrStdout(), nil
+	case "stderr":
+		return rc.cmd.ErrOrStderr(), nil
+	default:
+
+		// loggers may be created more than once per command, all writing to the same file
+		if rc.logFile != nil {
+			return rc.logFile, nil
+		}
+
+		logFile, err := os.OpenFile(rc.logOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to open log file %s", rc.logOutput)
+		}
+
+		rc.logFile = logFile
+
+		return logFile, nil
+	}
+}
+
+// closeLogFile closes the file logs are written to, if any
+func (rc *RootCommandeer) closeLogFile() error {
+	if rc.logFile == nil {
+		return nil
+	}
+
+	logFile := rc.logFile
+	rc.logFile = nil
+
+	if err := logFile.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to close log file %s", logFile.Name())
+	}
+
+	return nil
+}
+
+// isJSONOutput returns whether the command's result is written as JSON, in which case logs and progress
+// go to stderr, keeping stdout machine-parseable
+func (rc *RootCommandeer) isJSONOutput() bool {
+	return rc.output == nuctl_common.OutputFormatJSON
+}
+
+// getProgressWriter returns the writer to which a command writes human-readable progress (e.g. build output)
+func (rc *RootCommandeer) getProgressWriter(cmd *cobra.Command) io.Writer {
+	if rc.isJSONOutput() {
+		return cmd.ErrOrStderr()
+	}
+
+	return cmd.OutOrStdout()
+}
+
+// renderResult writes the result of a command as JSON. a command that fails after rendering its result (e.g. with
+// the failures in it) doesn't have its error rendered as well
+func (rc *RootCommandeer) renderResult(writer io.Writer, result interface{}) error {
+	rc.resultRendered = true
+
+	return renderer.NewRenderer(writer).RenderJSON(result)
+}
+
 func (rc *RootCommandeer) createPlatform(logger logger.Logger) (platform.Platform, error) {
 
 	// ask the factory to create the appropriate platform
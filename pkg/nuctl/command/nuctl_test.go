@@ -0,0 +1,3114 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/fake"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type fakePlatformTestSuite struct {
+	suite.Suite
+	outputBuffer bytes.Buffer
+	errorBuffer  bytes.Buffer
+}
+
+func (suite *fakePlatformTestSuite) SetupTest() {
+
+	// the fake platform emulates kube
+	kubePlatforms = []string{"kube", fake.Name}
+
+	fake.ResetSharedPlatform()
+	suite.outputBuffer.Reset()
+	suite.errorBuffer.Reset()
+}
+
+func (suite *fakePlatformTestSuite) TestDeployGetDelete() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler",
+		"--path", "/does/not/matter")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), `"image": "nuclio/processor-my-function:latest"`)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready |")
+
+	err = suite.executeNuctl("delete", "function", "my-function")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "No functions found")
+}
+
+func (suite *fakePlatformTestSuite) TestSimulatedDeployment() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(loggerInstance)
+	suite.Require().NoError(err)
+
+	// leave functions in the building state, as if they're still being deployed
+	fakePlatform.DeployedFunctionState = functionconfig.FunctionStateBuilding
+
+	err = suite.executeNuctl("deploy", "my-function", "--run-image", "my-image:latest")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| building |")
+
+	err = fakePlatform.SetFunctionState("", "my-function", functionconfig.FunctionStateReady)
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready |")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployFromImage() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--env", "SOME_ENV=some-value")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	// the rest of the configuration is still applied
+	functionConfig := functions[0].GetConfig()
+	suite.Require().Equal("my-registry/my-function:1.0.0", functionConfig.Spec.Image)
+	suite.Require().Equal(functionconfig.NeverBuild, functionConfig.Spec.Build.Mode)
+	suite.Require().Equal("some-value", functionConfig.Spec.Env[0].Value)
+
+	// a path means the function should be built, contradicting the prebuilt image
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--path", "/some/path")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "--from-image can't be used with --path")
+}
+
+func (suite *fakePlatformTestSuite) TestPruneOldImagesNotSupported() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--prune-old-images", "2")
+	suite.Require().Error(err)
+
+	unsupportedOperationError, ok := errors.RootCause(err).(*UnsupportedOperationError)
+	suite.Require().True(ok)
+	suite.Require().Equal(fake.Name, unsupportedOperationError.PlatformName)
+
+	// nothing was deployed
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions)
+}
+
+func (suite *fakePlatformTestSuite) TestCheckSecretRefsNotSupported() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "functions", "--check-secret-refs")
+	suite.Require().Error(err)
+
+	unsupportedOperationError, ok := errors.RootCause(err).(*UnsupportedOperationError)
+	suite.Require().True(ok)
+	suite.Require().Equal(fake.Name, unsupportedOperationError.PlatformName)
+}
+
+func (suite *fakePlatformTestSuite) TestCleanupNotSupported() {
+	err := suite.executeNuctl("cleanup", "--remove")
+	suite.Require().Error(err)
+
+	unsupportedOperationError, ok := errors.RootCause(err).(*UnsupportedOperationError)
+	suite.Require().True(ok)
+	suite.Require().Equal(fake.Name, unsupportedOperationError.PlatformName)
+}
+
+func (suite *fakePlatformTestSuite) TestScaleFunction() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--env", "SOME_ENV=some-value")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("scale", "function", "my-function", "--replicas", "3", "--wait")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| 3/3 ")
+
+	// nothing but the replicas changed
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), `"image": "my-registry/my-function:1.0.0"`)
+	suite.Require().Contains(suite.outputBuffer.String(), `"value": "some-value"`)
+
+	err = suite.executeNuctl("scale", "function", "my-function")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Number of replicas must be given")
+
+	err = suite.executeNuctl("scale", "function", "other-function", "--replicas", "2")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+}
+
+func (suite *fakePlatformTestSuite) TestPauseResumeFunction() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("pause", "function", "my-function")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| paused |")
+
+	// a paused function isn't invoked
+	err = suite.executeNuctl("invoke", "my-function", "--body", "ping")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function my-function is paused, resume it to invoke it")
+
+	// pausing again does nothing
+	err = suite.executeNuctl("pause", "function", "my-function")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("resume", "function", "my-function")
+	suite.Require().NoError(err)
+
+	// the configuration was left as is
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), `"image": "my-registry/my-function:1.0.0"`)
+	suite.Require().NotContains(suite.outputBuffer.String(), `"disable"`)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready |")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body", "ping")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "ping")
+
+	// only a paused function can be resumed
+	err = suite.executeNuctl("resume", "function", "my-function")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "isn't paused (state: ready)")
+
+	err = suite.executeNuctl("pause", "function", "other-function")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+}
+
+func (suite *fakePlatformTestSuite) TestPauseResumeFunctionTrigger() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--triggers", `{"my-kafka": {"kind": "kafka-cluster", "url": "kafka:9092"}}`)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("trigger", "pause", "my-function", "my-kafka")
+	suite.Require().NoError(err)
+
+	// the paused state is kept in the function's configuration
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), `"paused": true`)
+
+	// the function itself keeps running
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready |")
+
+	err = suite.executeNuctl("trigger", "resume", "my-function", "my-kafka")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), `"paused"`)
+
+	err = suite.executeNuctl("trigger", "pause", "my-function", "other-trigger")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Trigger not found: other-trigger")
+
+	err = suite.executeNuctl("trigger", "pause", "other-function", "my-kafka")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+}
+
+func (suite *fakePlatformTestSuite) TestDeprecateFunction() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--deprecate",
+		"--deprecation-message", "use my-function-v2",
+		"--deprecation-date", "2020-12-31")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| my-function (deprecated) |")
+	suite.Require().Empty(suite.errorBuffer.String())
+
+	err = suite.executeNuctl("get", "function", "my-function", "--warn-deprecated")
+	suite.Require().NoError(err)
+	suite.Require().Equal("WARNING: Function my-function is deprecated (removal date: 2020-12-31): use my-function-v2\n",
+		suite.errorBuffer.String())
+
+	// undeprecating leaves the rest of the function as is
+	err = suite.executeNuctl("deprecate", "function", "my-function", "--undo")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	suite.errorBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--warn-deprecated")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), "deprecated")
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready |")
+	suite.Require().Empty(suite.errorBuffer.String())
+
+	err = suite.executeNuctl("deprecate", "function", "my-function", "--message", "going away")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "--output", "json", "--warn-deprecated")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.errorBuffer.String(), "WARNING: Function my-function is deprecated: going away")
+
+	err = suite.executeNuctl("deprecate", "function", "my-function", "--date", "tomorrow")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Invalid deprecation date tomorrow")
+
+	err = suite.executeNuctl("deploy", "other-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--deprecation-message", "use my-function-v2")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "can only be used with --deprecate")
+}
+
+func (suite *fakePlatformTestSuite) TestGetFunctionsFailOnDeprecated() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("get", "function", "--fail-on-deprecated")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "old-function",
+		"--from-image", "my-registry/old-function:1.0.0",
+		"--deprecate",
+		"--deprecation-date", "2020-12-31")
+	suite.Require().NoError(err)
+
+	// the functions are still listed, along with the deprecation warning
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "--fail-on-deprecated")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "1 of 2 functions are deprecated")
+	suite.Require().Contains(suite.outputBuffer.String(), "| my-function ")
+	suite.Require().Contains(suite.errorBuffer.String(),
+		"WARNING: Function old-function is deprecated (removal date: 2020-12-31)")
+
+	// only the matched functions are checked
+	err = suite.executeNuctl("get", "function", "my-function", "--fail-on-deprecated")
+	suite.Require().NoError(err)
+}
+
+func (suite *fakePlatformTestSuite) TestGetFunctionYAMLIndent() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--env", "SOME_ENV=value")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--output", "yaml", "--indent", "4")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "\n    env:\n        - name: SOME_ENV\n")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("export", "function", "my-function", "--indent", "3")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "\n   env:\n      - name: SOME_ENV\n")
+
+	for _, args := range [][]string{
+		{"get", "function", "--output", "yaml", "--indent", "9"},
+		{"get", "function", "--output", "yaml", "--indent", "-2"},
+		{"get", "function", "--output", "json", "--indent", "4"},
+	} {
+		err = suite.executeNuctl(args...)
+		suite.Require().Error(err, args)
+	}
+}
+
+func (suite *fakePlatformTestSuite) TestDeployBlueGreen() {
+	triggers := `{"http": {"kind": "http", "attributes": {"ingresses": {"main": {"host": "my-function.example.com"}}}}}`
+
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--triggers", triggers)
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	err = fakePlatform.CreateAPIGateway(&platform.CreateAPIGatewayOptions{
+		APIGatewayConfig: platform.APIGatewayConfig{
+			Meta: platform.APIGatewayMeta{Name: "my-api-gateway"},
+			Spec: platform.APIGatewaySpec{
+				Host: "my-api.example.com",
+				Upstreams: []platform.APIGatewayUpstreamSpec{
+					{
+						Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+						NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "my-function"},
+					},
+				},
+			},
+		},
+	})
+	suite.Require().NoError(err)
+
+	// the function that wasn't deployed blue/green is updated to the blue version, keeping its name
+	fakePlatform.ResetCalls()
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:2.0.0",
+		"--triggers", triggers,
+		"--blue-green")
+	suite.Require().NoError(err)
+	suite.requireBlueGreenVersion("my-function", blueGreenSlotBlue, "my-registry/my-function:2.0.0")
+
+	// the standby served the ingresses and the API gateway while the function was updated, and then the API
+	// gateway was switched back to the function
+	var switchOverSteps []string
+	for _, call := range fakePlatform.GetCalls() {
+		switch callOptions := call.Options.(type) {
+		case *platform.UpdateAPIGatewayOptions:
+			switchOverSteps = append(switchOverSteps,
+				"api gateway to "+callOptions.APIGatewayConfig.Spec.Upstreams[0].NuclioFunction.Name)
+		case *platform.UpdateFunctionOptions:
+			switchOverSteps = append(switchOverSteps, fmt.Sprintf("%s (%s) with %d ingresses",
+				callOptions.FunctionMeta.Name,
+				callOptions.FunctionSpec.Image,
+				len(functionconfig.GetIngressesFromTriggers(callOptions.FunctionSpec.Triggers))))
+		}
+	}
+	suite.Require().Equal([]string{
+		"my-function-standby (my-registry/my-function:2.0.0) with 1 ingresses",
+		"my-function (my-registry/my-function:1.0.0) with 0 ingresses",
+		"api gateway to my-function-standby",
+		"my-function (my-registry/my-function:2.0.0) with 1 ingresses",
+		"api gateway to my-function",
+	}, switchOverSteps)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:3.0.0",
+		"--triggers", triggers,
+		"--blue-green")
+	suite.Require().NoError(err)
+	suite.requireBlueGreenVersion("my-function", blueGreenSlotGreen, "my-registry/my-function:3.0.0")
+
+	// a version that doesn't become ready is deleted, leaving the live one serving
+	fakePlatform.DeployedFunctionState = functionconfig.FunctionStateError
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:4.0.0",
+		"--triggers", triggers,
+		"--blue-green")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function isn't ready")
+	suite.requireBlueGreenVersion("my-function", blueGreenSlotGreen, "my-registry/my-function:3.0.0")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployBlueGreenUnsupported() {
+	kubePlatforms = []string{"kube"}
+
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--blue-green")
+	suite.Require().Error(err)
+
+	unsupportedOperationError, ok := errors.RootCause(err).(*UnsupportedOperationError)
+	suite.Require().True(ok)
+	suite.Require().Equal("deploy --blue-green", unsupportedOperationError.Operation)
+}
+
+func (suite *fakePlatformTestSuite) TestUnsupportedOperations() {
+
+	// the fake platform doesn't emulate kube here, so it supports neither kube nor local operations
+	kubePlatforms = []string{"kube"}
+
+	for _, testCase := range []struct {
+		args              []string
+		expectedOperation string
+	}{
+		{
+			args:              []string{"update", "function", "my-function"},
+			expectedOperation: "update function",
+		},
+		{
+			args:              []string{"deprecate", "function", "my-function"},
+			expectedOperation: "deprecate function",
+		},
+		{
+			args:              []string{"promote", "function", "my-function"},
+			expectedOperation: "promote function",
+		},
+		{
+			args:              []string{"deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0", "--canary", "10"},
+			expectedOperation: "deploy --canary",
+		},
+		{
+			args: []string{"deploy", "my-function",
+				"--from-image", "my-registry/my-function:1.0.0",
+				"--mount-secret-as-file", "tls-certs:/etc/tls"},
+			expectedOperation: "deploy --mount-secret-as-file",
+		},
+		{
+			args:              []string{"deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0", "--prune-old-images", "2"},
+			expectedOperation: "deploy --prune-old-images",
+		},
+		{
+			args:              []string{"get", "functions", "--all-namespaces"},
+			expectedOperation: "get functions --all-namespaces",
+		},
+		{
+			args:              []string{"get", "functions", "--context-all"},
+			expectedOperation: "get functions --context-all",
+		},
+		{
+			args:              []string{"get", "functions", "--check-secret-refs"},
+			expectedOperation: "get functions --check-secret-refs",
+		},
+		{
+			args:              []string{"prune", "build-cache"},
+			expectedOperation: "prune build-cache",
+		},
+		{
+			args:              []string{"cleanup"},
+			expectedOperation: "cleanup",
+		},
+	} {
+		suite.Run(testCase.expectedOperation, func() {
+			err := suite.executeNuctl(testCase.args...)
+			suite.Require().Error(err)
+
+			unsupportedOperationError, ok := errors.RootCause(err).(*UnsupportedOperationError)
+			suite.Require().True(ok, "Expected an unsupported operation error, got: %s", err.Error())
+			suite.Require().Equal(testCase.expectedOperation, unsupportedOperationError.Operation)
+			suite.Require().Equal(fake.Name, unsupportedOperationError.PlatformName)
+		})
+	}
+
+	// no function was changed
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	for _, call := range fakePlatform.GetCalls() {
+		suite.Require().NotContains([]string{"CreateFunction", "UpdateFunction", "DeleteFunction"}, call.Name)
+	}
+}
+
+func (suite *fakePlatformTestSuite) requireBlueGreenVersion(functionName string,
+	expectedSlot string,
+	expectedImage string) {
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	// the only version is the function itself, and it has the ingresses
+	functionConfig := functions[0].GetConfig()
+	suite.Require().Equal(functionName, functionConfig.Meta.Name)
+	suite.Require().Equal(expectedSlot, functionConfig.Meta.Annotations[blueGreenSlotAnnotation])
+	suite.Require().Equal(expectedImage, functionConfig.Spec.Image)
+	suite.Require().Equal("my-function.example.com",
+		functionconfig.GetIngressesFromTriggers(functionConfig.Spec.Triggers)["main"].Host)
+
+	// and the API gateways route to it
+	apiGateways, err := fakePlatform.GetAPIGateways(&platform.GetAPIGatewaysOptions{})
+	suite.Require().NoError(err)
+	for _, apiGateway := range apiGateways {
+		suite.Require().Equal(functionName, apiGateway.GetConfig().Spec.Upstreams[0].NuclioFunction.Name)
+	}
+}
+
+func (suite *fakePlatformTestSuite) TestDisableTrigger() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--triggers", `{
+			"periodic": {"kind": "cron", "attributes": {"interval": "10s"}},
+			"http": {"kind": "http", "maxWorkers": 2}
+		}`)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("update", "function", "my-function", "--disable-trigger", "periodic")
+	suite.Require().NoError(err)
+
+	functionConfig := suite.getFunctionConfig("my-function")
+	suite.Require().True(functionConfig.Spec.Triggers["periodic"].Disabled)
+	suite.Require().False(functionConfig.Spec.Triggers["http"].Disabled)
+	suite.Require().Equal("10s", functionConfig.Spec.Triggers["periodic"].Attributes["interval"])
+	suite.Require().Equal("my-registry/my-function:1.0.0", functionConfig.Spec.Image)
+
+	err = suite.executeNuctl("update", "function", "my-function", "--enable-trigger", "periodic")
+	suite.Require().NoError(err)
+
+	functionConfig = suite.getFunctionConfig("my-function")
+	suite.Require().False(functionConfig.Spec.Triggers["periodic"].Disabled)
+
+	err = suite.executeNuctl("update", "function", "my-function", "--disable-trigger", "queue")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(),
+		"Function my-function has no trigger named queue (available triggers: http, periodic)")
+}
+
+func (suite *fakePlatformTestSuite) getFunctionConfig(functionName string) *functionconfig.Config {
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("get", "function", functionName, "--output", "yaml")
+	suite.Require().NoError(err)
+
+	functionConfig := functionconfig.Config{}
+	err = yaml.Unmarshal(suite.outputBuffer.Bytes(), &functionConfig)
+	suite.Require().NoError(err)
+
+	return &functionConfig
+}
+
+func (suite *fakePlatformTestSuite) TestInvokeRepeatedly() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body", "ping", "--repeat", "20", "--concurrency", "4")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`requests +\| +20 `, suite.outputBuffer.String())
+	suite.Require().Regexp(`status 200 +\| +20 `, suite.outputBuffer.String())
+	suite.Require().NotContains(suite.outputBuffer.String(), "ping")
+
+	// requests are sent for the duration, at the given rate
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--duration", "300ms", "--rps", "20")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`requests +\| +[1-7] `, suite.outputBuffer.String())
+
+	// failed requests fail the command, after the stats are reported
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "other-function", "--repeat", "3")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "3 of 3 requests failed")
+	suite.Require().Regexp(`failed +\| +3 `, suite.outputBuffer.String())
+}
+
+func (suite *fakePlatformTestSuite) TestInvokeViaPortForward() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+	fakePlatform.ResetCalls()
+
+	// the function is invoked through the tunnel, which is closed afterwards
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body", "ping", "--via", "port-forward")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "ping")
+
+	var tunnelCallNames []string
+	for _, call := range fakePlatform.GetCalls() {
+		switch call.Name {
+		case "CreateFunctionTunnel", "CloseFunctionTunnel":
+			tunnelCallNames = append(tunnelCallNames, call.Name)
+		case "CreateFunctionInvocation":
+			tunnelCallNames = append(tunnelCallNames, call.Name)
+			suite.Require().Equal("127.0.0.1:8080", call.Options.(*platform.CreateFunctionInvocationOptions).URL)
+		}
+	}
+
+	suite.Require().Equal([]string{"CreateFunctionTunnel", "CreateFunctionInvocation", "CloseFunctionTunnel"},
+		tunnelCallNames)
+
+	err = suite.executeNuctl("invoke", "my-function", "--via", "port-forward", "--url", "127.0.0.1:9090")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "--via port-forward can't be used with --url")
+
+	err = suite.executeNuctl("invoke", "other-function", "--via", "port-forward")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+}
+
+func (suite *fakePlatformTestSuite) TestInvokeCloudEvent() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	// a binary event is sent as headers, leaving the body as is
+	fakePlatform.ResetCalls()
+	err = suite.executeNuctl("invoke", "my-function",
+		"--body", `{"order": 1}`,
+		"--cloudevent", "com.example.order.created",
+		"--cloudevent-source", "/orders")
+	suite.Require().NoError(err)
+
+	invocationOptions := suite.getCreateFunctionInvocationOptions(fakePlatform)
+	suite.Require().Equal(`{"order": 1}`, string(invocationOptions.Body))
+	suite.Require().Equal("1.0", invocationOptions.Headers.Get("ce-specversion"))
+	suite.Require().Equal("com.example.order.created", invocationOptions.Headers.Get("ce-type"))
+	suite.Require().Equal("/orders", invocationOptions.Headers.Get("ce-source"))
+	suite.Require().NotEmpty(invocationOptions.Headers.Get("ce-id"))
+
+	// a structured event wraps the body
+	fakePlatform.ResetCalls()
+	err = suite.executeNuctl("invoke", "my-function",
+		"--body", "ping",
+		"--content-type", "text/plain",
+		"--cloudevent", "com.example.ping",
+		"--cloudevent-mode", "structured")
+	suite.Require().NoError(err)
+
+	invocationOptions = suite.getCreateFunctionInvocationOptions(fakePlatform)
+	suite.Require().Equal("application/cloudevents+json", invocationOptions.Headers.Get("Content-Type"))
+	suite.Require().Equal(http.MethodPost, invocationOptions.Method)
+
+	structuredEvent := map[string]interface{}{}
+	err = json.Unmarshal(invocationOptions.Body, &structuredEvent)
+	suite.Require().NoError(err)
+	suite.Require().Equal("1.0", structuredEvent["specversion"])
+	suite.Require().Equal("com.example.ping", structuredEvent["type"])
+	suite.Require().Equal("nuctl", structuredEvent["source"])
+	suite.Require().Equal("text/plain", structuredEvent["datacontenttype"])
+	suite.Require().Equal("ping", structuredEvent["data"])
+
+	err = suite.executeNuctl("invoke", "my-function", "--cloudevent", "com.example.ping", "--cloudevent-mode", "json")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Invalid cloud event mode json")
+}
+
+func (suite *fakePlatformTestSuite) getCreateFunctionInvocationOptions(
+	fakePlatform *fake.Platform) *platform.CreateFunctionInvocationOptions {
+	for _, call := range fakePlatform.GetCalls() {
+		if call.Name == "CreateFunctionInvocation" {
+			return call.Options.(*platform.CreateFunctionInvocationOptions)
+		}
+	}
+
+	suite.FailNow("Function wasn't invoked")
+	return nil
+}
+
+func (suite *fakePlatformTestSuite) TestInvokeStream() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body", "data: first\n\ndata: second\n", "--stream")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "data: first\n\ndata: second\n")
+
+	err = suite.executeNuctl("invoke", "my-function", "--stream", "--repeat", "3")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestInvokeUpload() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	uploadDir, err := ioutil.TempDir("", "nuctl-invoke-upload")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(uploadDir) // nolint: errcheck
+
+	imagePath := path.Join(uploadDir, "image.jpg")
+	err = ioutil.WriteFile(imagePath, []byte("not-really-a-jpeg"), 0644)
+	suite.Require().NoError(err)
+
+	// the file is the body, its content type guessed by extension
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body-file", imagePath, "--output", "json")
+	suite.Require().NoError(err)
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &result)
+	suite.Require().NoError(err)
+	suite.Require().Equal("not-really-a-jpeg", result["body"])
+	suite.Require().Equal("image/jpeg", result["headers"].(map[string]interface{})["Content-Type"])
+
+	// the fields and file are encoded as a multipart form
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function",
+		"--form", "caption=a cat",
+		"--form", "image=@"+imagePath,
+		"--output", "json")
+	suite.Require().NoError(err)
+
+	result = map[string]interface{}{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &result)
+	suite.Require().NoError(err)
+
+	_, params, err := mime.ParseMediaType(result["headers"].(map[string]interface{})["Content-Type"].(string))
+	suite.Require().NoError(err)
+
+	form, err := multipart.NewReader(strings.NewReader(result["body"].(string)), params["boundary"]).ReadForm(1024)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"a cat"}, form.Value["caption"])
+	suite.Require().Len(form.File["image"], 1)
+	suite.Require().Equal("image.jpg", form.File["image"][0].Filename)
+	suite.Require().Equal("image/jpeg", form.File["image"][0].Header.Get("Content-Type"))
+
+	// missing files fail before invoking
+	err = suite.executeNuctl("invoke", "my-function", "--form", "image=@"+path.Join(uploadDir, "missing.jpg"))
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("invoke", "my-function", "--body-file", imagePath, "--body", "ping")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestPruneBuildCacheUnsupported() {
+	err := suite.executeNuctl("prune", "build-cache")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "prune build-cache")
+}
+
+func (suite *fakePlatformTestSuite) TestFunctionGroup() {
+	for _, functionName := range []string{"first-function", "second-function"} {
+		err := suite.executeNuctl("deploy", functionName,
+			"--from-image", "my-registry/my-function:1.0.0",
+			"--function-group", "my-group")
+		suite.Require().NoError(err)
+	}
+
+	err := suite.executeNuctl("deploy", "other-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "--group", "my-group")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "first-function")
+	suite.Require().Contains(suite.outputBuffer.String(), "second-function")
+	suite.Require().NotContains(suite.outputBuffer.String(), "other-function")
+
+	err = suite.executeNuctl("delete", "functions", "other-function", "--group", "my-group")
+	suite.Require().Error(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "functions", "--group", "my-group")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Function first-function deleted\nFunction second-function deleted\n",
+		suite.outputBuffer.String())
+
+	// only the function outside the group is left
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "other-function")
+	suite.Require().NotContains(suite.outputBuffer.String(), "first-function")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "--group", "my-group")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "No functions found")
+}
+
+func (suite *fakePlatformTestSuite) TestDeleteFunctionsBySelection() {
+	for functionName, labels := range map[string]string{
+		"etl-first":  "app=etl,env=review",
+		"etl-second": "app=etl",
+		"web":        "app=web",
+	} {
+		err := suite.executeNuctl("deploy", functionName,
+			"--from-image", "my-registry/my-function:1.0.0",
+			"--labels", labels)
+		suite.Require().NoError(err)
+	}
+
+	// a selection must be confirmed, and can't be combined with a name
+	err := suite.executeNuctl("delete", "functions", "--label-selector", "app=etl")
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("delete", "functions", "web", "--all", "--force")
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("delete", "functions", "--all", "--force", "--dry-run")
+	suite.Require().Error(err)
+
+	// a dry run only lists the selected functions
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "functions", "--label-selector", "app=etl", "--dry-run")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Function etl-first would be deleted\nFunction etl-second would be deleted\n",
+		suite.outputBuffer.String())
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "functions", "-l", "app=etl,env=review", "--force")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Function etl-first deleted\n", suite.outputBuffer.String())
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "functions", "--all", "--force", "--output", "json")
+	suite.Require().NoError(err)
+
+	result := deleteResult{}
+	suite.Require().NoError(json.Unmarshal(suite.outputBuffer.Bytes(), &result))
+	suite.Require().Equal([]string{"etl-second", "web"}, result.Deleted)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "No functions found")
+}
+
+func (suite *fakePlatformTestSuite) TestGetFunctionsSorted() {
+	for _, functionName := range []string{"charlie", "alpha", "bravo"} {
+		err := suite.executeNuctl("deploy", functionName, "--from-image", "my-registry/my-function:1.0.0")
+		suite.Require().NoError(err)
+	}
+
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("get", "functions", "--sort-by", "name")
+	suite.Require().NoError(err)
+	suite.Require().Regexp("(?s)alpha.*bravo.*charlie", suite.outputBuffer.String())
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "--sort-by", "name", "--reverse")
+	suite.Require().NoError(err)
+	suite.Require().Regexp("(?s)charlie.*bravo.*alpha", suite.outputBuffer.String())
+
+	// machine readable output is sorted too
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "--sort-by", "name", "--output", "json")
+	suite.Require().NoError(err)
+
+	var functionNames []string
+	decoder := json.NewDecoder(&suite.outputBuffer)
+	for decoder.More() {
+		functionConfig := functionconfig.Config{}
+		suite.Require().NoError(decoder.Decode(&functionConfig))
+		functionNames = append(functionNames, functionConfig.Meta.Name)
+	}
+	suite.Require().Equal([]string{"alpha", "bravo", "charlie"}, functionNames)
+
+	err = suite.executeNuctl("get", "functions", "--sort-by", "size")
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("get", "functions", "--reverse")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestAllNamespaces() {
+	for _, namespace := range []string{"team-a", "team-b"} {
+		err := suite.executeNuctl("deploy", "my-function",
+			"--from-image", "my-registry/my-function:1.0.0",
+			"--namespace", namespace)
+		suite.Require().NoError(err)
+
+		err = suite.executeNuctl("create", "project", "my-project", "--namespace", namespace)
+		suite.Require().NoError(err)
+	}
+
+	// only the functions of the namespace are listed without --all-namespaces
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("get", "functions", "--namespace", "team-a")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "team-a")
+	suite.Require().NotContains(suite.outputBuffer.String(), "team-b")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "my-function", "--all-namespaces")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`(?s)team-a +\| my-function.*team-b +\| my-function`, suite.outputBuffer.String())
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "projects", "-A")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`(?s)team-a +\| my-project.*team-b +\| my-project`, suite.outputBuffer.String())
+
+	// functions of other namespaces are identified by their namespace
+	err = suite.executeNuctl("delete", "function", "team-a/my-function", "--namespace", "team-b")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "but --namespace team-b was given")
+
+	err = suite.executeNuctl("delete", "function", "team-a/")
+	suite.Require().Error(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "function", "team-a/my-function", "--output", "json")
+	suite.Require().NoError(err)
+
+	result := deleteResult{}
+	suite.Require().NoError(json.Unmarshal(suite.outputBuffer.Bytes(), &result))
+	suite.Require().Equal("team-a", result.Namespace)
+	suite.Require().Equal([]string{"my-function"}, result.Deleted)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "functions", "-A")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), "team-a")
+	suite.Require().Contains(suite.outputBuffer.String(), "team-b")
+
+	// listing all namespaces doesn't apply to a single function's revisions
+	err = suite.executeNuctl("get", "function", "my-function", "-A", "--revisions")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployJSONEvents() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--json-events",
+		"--verbose")
+	suite.Require().NoError(err)
+
+	// every line is an event, logs included
+	var events []deployEvent
+	for _, line := range strings.Split(strings.TrimSuffix(suite.outputBuffer.String(), "\n"), "\n") {
+		event := deployEvent{}
+		suite.Require().NoError(json.Unmarshal([]byte(line), &event), "Line isn't JSON: %s", line)
+		suite.Require().NotEmpty(event.Time)
+		events = append(events, event)
+	}
+
+	// the deploy ends with the function's final state
+	lastEvent := events[len(events)-1]
+	suite.Require().Equal(deployEventTypeState, lastEvent.Type)
+	suite.Require().Empty(lastEvent.Error)
+
+	var states []functionconfig.FunctionState
+	logEvents := 0
+	for _, event := range events {
+		switch event.Type {
+		case deployEventTypeState:
+			states = append(states, event.State)
+		case deployEventTypeLog:
+			logRecord := map[string]interface{}{}
+			suite.Require().NoError(json.Unmarshal(event.Log, &logRecord))
+			suite.Require().Contains(logRecord, "message")
+			logEvents++
+		}
+	}
+	suite.Require().Equal([]functionconfig.FunctionState{
+		functionconfig.FunctionStateBuilding,
+		functionconfig.FunctionStateReady,
+	}, states)
+	suite.Require().NotZero(logEvents)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--json-events",
+		"--measure")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestCompletion() {
+	err := suite.executeNuctl("completion", "bash")
+	suite.Require().NoError(err)
+
+	for _, expectedContent := range []string{
+		"_nuctl_deploy()",
+		"_nuctl_get_functions()",
+		"_nuctl_invoke()",
+
+		// resource names are completed dynamically
+		"__nuctl_custom_func()",
+		"nuctl completion function-names",
+		"nuctl completion project-names",
+		"nuctl completion apigateway-names",
+	} {
+		suite.Require().Contains(suite.outputBuffer.String(), expectedContent)
+	}
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("completion", "fish")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "complete -c nuctl -n '__fish_use_subcommand' -a deploy")
+	suite.Require().Contains(suite.outputBuffer.String(), "-a '(__nuctl_function_names)'")
+	suite.Require().Contains(suite.outputBuffer.String(), "-a '(__nuctl_project_names)'")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("completion", "powershell")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "Register-ArgumentCompleter -Native -CommandName 'nuctl'")
+	suite.Require().Contains(suite.outputBuffer.String(), "'nuctl get apigateways' = @{ Subcommands = @{  }; ")
+	suite.Require().Contains(suite.outputBuffer.String(), "Names = 'apigateway-names' }")
+	suite.Require().Contains(suite.outputBuffer.String(), "'fn' = 'functions'")
+
+	err = suite.executeNuctl("completion", "tcsh")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestCompletionFunctionNames() {
+	for _, functionName := range []string{"second-function", "first-function"} {
+		err := suite.executeNuctl("deploy", functionName, "--from-image", "my-registry/my-function:1.0.0")
+		suite.Require().NoError(err)
+	}
+
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("completion", "function-names")
+	suite.Require().NoError(err)
+	suite.Require().Equal("first-function\nsecond-function\n", suite.outputBuffer.String())
+
+	// an unreachable platform completes nothing, without failing
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.cmd.SetOut(&suite.outputBuffer)
+	rootCommandeer.cmd.SetArgs([]string{"completion", "function-names",
+		"--platform", "kube",
+		"--kubeconfig", "/does/not/exist"})
+
+	suite.outputBuffer.Reset()
+	err = rootCommandeer.Execute()
+	suite.Require().NoError(err)
+	suite.Require().Empty(suite.outputBuffer.String())
+}
+
+func (suite *fakePlatformTestSuite) TestCompletionProjectNames() {
+	for _, projectName := range []string{"second-project", "first-project"} {
+		err := suite.executeNuctl("create", "project", projectName)
+		suite.Require().NoError(err)
+	}
+
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("completion", "project-names")
+	suite.Require().NoError(err)
+
+	// the platform always has the default project
+	suite.Require().Equal("default\nfirst-project\nsecond-project\n", suite.outputBuffer.String())
+}
+
+func (suite *fakePlatformTestSuite) TestDeployInteractive() {
+	err := suite.executeNuctlWithInput(strings.Join([]string{
+		"my-function",
+		"/does/not/matter",
+
+		// invalid answers are asked again
+		"cobol",
+		"golang",
+
+		// the default handler of the runtime
+		"",
+		"http, cron",
+		"http-port",
+		"8080",
+		"",
+	}, "\n")+"\n", "deploy", "--interactive")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "Must be one of: python:3.6,")
+	suite.Require().Contains(suite.outputBuffer.String(), "--path /does/not/matter --runtime golang --handler main:Handler")
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	functionSpec := functions[0].GetConfig().Spec
+	suite.Require().Equal("golang", functionSpec.Runtime)
+	suite.Require().Equal("main:Handler", functionSpec.Handler)
+	suite.Require().Equal("/does/not/matter", functionSpec.Build.Path)
+	suite.Require().Equal(8080, functionSpec.GetHTTPPort())
+	suite.Require().Equal("10s", functionSpec.Triggers["cron"].Attributes["interval"])
+
+	// what's given with flags isn't prompted for
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctlWithInput("none\n", "deploy", "other-function", "--interactive",
+		"--path", "/does/not/matter",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), "Runtime")
+
+	functions, err = fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "other-function"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions[0].GetConfig().Spec.Triggers)
+
+	// input that ends early fails the deploy
+	err = suite.executeNuctlWithInput("third-function\n", "deploy", "--interactive")
+	suite.Require().Error(err)
+
+	functions, err = fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "third-function"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployInvalidConfig() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--min-replicas", "3",
+		"--max-replicas", "2",
+		"--target-cpu", "200",
+		"--resource-request", "cpu=2",
+		"--resource-limit", "cpu=1",
+		"--triggers", `{"first": {"kind": "http"}, "second": {"kind": "http"}}`)
+	suite.Require().Error(err)
+
+	// all the problems are reported at once
+	validationError, ok := errors.RootCause(err).(*functionconfig.ValidationError)
+	suite.Require().True(ok)
+	suite.Require().Len(validationError.FieldErrors, 4)
+
+	for _, field := range []string{
+		"spec.minReplicas",
+		"spec.resources.requests.cpu",
+		"spec.triggers",
+		"spec.targetCPU",
+	} {
+		suite.Require().Contains(validationError.Error(), field+": ")
+	}
+
+	// nothing was deployed
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions)
+}
+
+func (suite *fakePlatformTestSuite) TestDeploySourceChecksum() {
+	sourceFile, err := ioutil.TempFile("", "nuctl-source-*.py")
+	suite.Require().NoError(err)
+	defer os.Remove(sourceFile.Name()) // nolint: errcheck
+
+	_, err = sourceFile.WriteString("def handler(context, event):\n    return 'hello'\n")
+	suite.Require().NoError(err)
+	sourceFile.Close() // nolint: errcheck
+
+	sourceChecksum, err := common.ComputeSourceChecksum(sourceFile.Name())
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--path", sourceFile.Name(),
+		"--source-checksum", "sha256:"+sourceChecksum)
+	suite.Require().NoError(err)
+
+	// a mismatch fails the deploy before anything is built
+	err = suite.executeNuctl("deploy", "other-function",
+		"--path", sourceFile.Name(),
+		"--source-checksum", common.ComputeChecksum([]byte("something else")))
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Source checksum mismatch")
+
+	err = suite.executeNuctl("deploy", "other-function",
+		"--path", "https://example.com/my-function.zip",
+		"--source-checksum", sourceChecksum)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "can't be used with a remote path")
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "other-function"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions)
+}
+
+func (suite *fakePlatformTestSuite) TestDiffFunction() {
+	functionConfigFile, err := ioutil.TempFile("", "nuctl-diff-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionConfigFile.Name()) // nolint: errcheck
+
+	writeFunctionConfig := func(handler string) {
+		err := ioutil.WriteFile(functionConfigFile.Name(), []byte(`metadata:
+  name: my-function
+spec:
+  runtime: python:3.6
+  handler: `+handler+`
+  env:
+  - name: SOME_ENV
+    value: some-value
+`), 0644)
+		suite.Require().NoError(err)
+	}
+
+	writeFunctionConfig("main:handler")
+
+	err = suite.executeNuctl("deploy", "my-function", "--file", functionConfigFile.Name())
+	suite.Require().NoError(err)
+
+	// the deployed function is the same as its configuration, regardless of what the platform populated
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("diff", "function", "my-function", "--file", functionConfigFile.Name())
+	suite.Require().NoError(err)
+	suite.Require().Empty(suite.outputBuffer.String())
+
+	writeFunctionConfig("main:other_handler")
+
+	err = suite.executeNuctl("diff", "function", "my-function", "--file", functionConfigFile.Name())
+	suite.Require().Error(err)
+
+	_, functionDiffers := errors.RootCause(err).(*FunctionDiffersError)
+	suite.Require().True(functionDiffers)
+
+	suite.Require().Contains(suite.outputBuffer.String(), "--- live/my-function\n")
+	suite.Require().Contains(suite.outputBuffer.String(), "-  handler: main:handler\n")
+	suite.Require().Contains(suite.outputBuffer.String(), "+  handler: main:other_handler\n")
+	suite.Require().NotContains(suite.outputBuffer.String(), "runtime")
+
+	// the differences can be only shown
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("diff", "function", "my-function",
+		"--file", functionConfigFile.Name(),
+		"--exit-code=false")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "+  handler: main:other_handler\n")
+
+	// and explicitly fail on
+	err = suite.executeNuctl("diff", "function", "my-function",
+		"--file", functionConfigFile.Name(),
+		"--exit-code")
+	suite.Require().Error(err)
+
+	// a function that doesn't exist is entirely added
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("diff", "function", "other-function", "--file", functionConfigFile.Name())
+	suite.Require().Error(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "+  name: other-function\n")
+}
+
+func (suite *fakePlatformTestSuite) TestValidateFunctionConfig() {
+	tempDir, err := ioutil.TempDir("", "nuctl-validate-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	writeFunctionConfig := func(fileName string, functionConfig string) string {
+		functionConfigPath := path.Join(tempDir, fileName)
+		suite.Require().NoError(ioutil.WriteFile(functionConfigPath, []byte(functionConfig), 0644))
+
+		return functionConfigPath
+	}
+
+	deployedFunctionConfigPath := writeFunctionConfig("deployed.yaml", `metadata:
+  name: deployed
+spec:
+  runtime: python:3.6
+  handler: main:handler
+  triggers:
+    http:
+      kind: http
+      attributes:
+        ingresses:
+          api:
+            host: example.com
+            paths:
+            - /api
+`)
+
+	err = suite.executeNuctl("deploy", "deployed", "--file", deployedFunctionConfigPath)
+	suite.Require().NoError(err)
+
+	validFunctionConfigPath := writeFunctionConfig("valid.yaml", `metadata:
+  name: valid
+spec:
+  runtime: python:3.6
+  handler: main:handler
+  triggers:
+    http:
+      kind: http
+      attributes:
+        port: 8080
+        ingresses:
+          api:
+            host: example.com
+            paths:
+            - /valid
+`)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("validate", "--file", validFunctionConfigPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "is valid")
+
+	invalidFunctionConfigPath := writeFunctionConfig("invalid.yaml", `metadata:
+  name: invalid
+spec:
+  runtime: python:3.6
+  handler: main:handler
+  triggers:
+    http:
+      kind: http
+      maxWorkers: 1000000
+      attributes:
+        port: "8080"
+        ingresses:
+          api:
+            host: example.com
+            paths:
+            - /api
+`)
+
+	// without the platform, only the configuration itself is checked
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("validate", "--file", invalidFunctionConfigPath, "--offline", "--output", "json")
+	suite.Require().Error(err)
+
+	_, functionConfigInvalid := errors.RootCause(err).(*InvalidFunctionConfigError)
+	suite.Require().True(functionConfigInvalid)
+
+	result := validateResult{}
+	suite.Require().NoError(json.Unmarshal(suite.outputBuffer.Bytes(), &result))
+	suite.Require().False(result.Valid)
+	suite.Require().Equal([]functionconfig.FieldError{
+		{Field: "spec.triggers.http.attributes.port", Message: "must be a number, got a string"},
+		{Field: "spec.triggers.http.maxWorkers", Message: "must not exceed 100000, got 1000000"},
+	}, result.Errors)
+
+	// against the platform, the ingress used by the deployed function is reported as well
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("validate", "--file", invalidFunctionConfigPath, "--output", "json")
+	suite.Require().Error(err)
+
+	result = validateResult{}
+	suite.Require().NoError(json.Unmarshal(suite.outputBuffer.Bytes(), &result))
+	suite.Require().Len(result.Errors, 3)
+	suite.Require().Equal(functionconfig.FieldError{
+		Field:   "spec.triggers.http.attributes.ingresses.api.paths",
+		Message: "example.com/api is already routed to function deployed",
+	}, result.Errors[2])
+
+	// validating a deployed function's own configuration doesn't conflict with itself
+	err = suite.executeNuctl("validate", "--file", deployedFunctionConfigPath)
+	suite.Require().NoError(err)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployTemplatedFunctionConfig() {
+	functionConfigFile, err := ioutil.TempFile("", "nuctl-template-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionConfigFile.Name()) // nolint: errcheck
+
+	err = ioutil.WriteFile(functionConfigFile.Name(), []byte(`metadata:
+  name: my-function
+spec:
+  runtime: python:3.6
+  handler: main:handler
+  env:
+  - name: ENVIRONMENT
+    value: {{ .environment }}
+  - name: GREETING
+    value: {{ .greeting }}
+`), 0644)
+	suite.Require().NoError(err)
+
+	greetingFile, err := ioutil.TempFile("", "nuctl-greeting-*.txt")
+	suite.Require().NoError(err)
+	defer os.Remove(greetingFile.Name()) // nolint: errcheck
+
+	err = ioutil.WriteFile(greetingFile.Name(), []byte("hello"), 0644)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--file", functionConfigFile.Name(),
+		"--set", "environment=staging",
+		"--set-file", "greeting="+greetingFile.Name())
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	env := functions[0].GetConfig().Spec.Env
+	suite.Require().Len(env, 2)
+	suite.Require().Equal("staging", env[0].Value)
+	suite.Require().Equal("hello", env[1].Value)
+
+	// variables must have values
+	err = suite.executeNuctl("deploy", "my-function",
+		"--file", functionConfigFile.Name(),
+		"--set", "environment=staging")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "greeting")
+
+	// unless they're allowed to be missing
+	err = suite.executeNuctl("deploy", "my-function",
+		"--file", functionConfigFile.Name(),
+		"--set", "environment=production",
+		"--allow-missing")
+	suite.Require().NoError(err)
+
+	functions, err = fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Equal("production", functions[0].GetConfig().Spec.Env[0].Value)
+	suite.Require().Empty(functions[0].GetConfig().Spec.Env[1].Value)
+}
+
+func (suite *fakePlatformTestSuite) TestImportFunctionsConcurrently() {
+	err := suite.executeNuctl("deploy", "function-c", "--from-image", "my-registry/function-c:1.0.0")
+	suite.Require().NoError(err)
+
+	functionsFile, err := ioutil.TempFile("", "nuctl-import-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionsFile.Name()) // nolint: errcheck
+
+	functionNames := []string{"function-a", "function-b", "function-c", "function-d", "function-e"}
+	for _, functionName := range functionNames {
+		_, err = functionsFile.WriteString(functionName + ":\n  metadata:\n    name: " + functionName + "\n")
+		suite.Require().NoError(err)
+	}
+	functionsFile.Close() // nolint: errcheck
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("import", "functions", functionsFile.Name(), "--concurrency", "3")
+
+	// function-c already exists, but the rest are still imported
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to import 1 of 5 functions")
+	suite.Require().Equal(`Function function-a imported
+Function function-b imported
+Function function-c failed to import: Function with the name: function-c already exists
+Function function-d imported
+Function function-e imported
+`, suite.outputBuffer.String())
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	for _, functionName := range functionNames {
+		functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: functionName})
+		suite.Require().NoError(err)
+		suite.Require().Len(functions, 1)
+	}
+}
+
+func (suite *fakePlatformTestSuite) TestImportFunctionsWithDependencies() {
+	functionsFile, err := ioutil.TempFile("", "nuctl-import-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionsFile.Name()) // nolint: errcheck
+
+	_, err = functionsFile.WriteString(`
+api:
+  metadata:
+    name: api
+  spec:
+    dependsOn: [orders, function/inventory]
+orders:
+  metadata:
+    name: orders
+  spec:
+    dependsOn: [inventory]
+inventory:
+  metadata:
+    name: inventory
+reports:
+  metadata:
+    name: reports
+  spec:
+    dependsOn: [apigateway/warehouse]
+dashboard:
+  metadata:
+    name: dashboard
+  spec:
+    dependsOn: [reports]
+`)
+	suite.Require().NoError(err)
+	functionsFile.Close() // nolint: errcheck
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("import", "functions", functionsFile.Name(), "--concurrency", "5")
+
+	// the API gateway reports depends on doesn't exist, so it and the function depending on it aren't imported
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to import 2 of 5 functions")
+	suite.Require().Equal(`Function api imported
+Function dashboard failed to import: Dependency function/reports failed to deploy
+Function inventory imported
+Function orders imported
+Function reports failed to import: Dependency apigateway/warehouse not found
+`, suite.outputBuffer.String())
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	// functions are created only after those they depend on
+	var createdFunctionNames []string
+	for _, call := range fakePlatform.GetCalls() {
+		if call.Name == "CreateFunction" {
+			createOptions := call.Options.(*platform.CreateFunctionOptions)
+			createdFunctionNames = append(createdFunctionNames, createOptions.FunctionConfig.Meta.Name)
+		}
+	}
+
+	suite.Require().Equal([]string{"inventory", "orders", "api"}, createdFunctionNames)
+}
+
+func (suite *fakePlatformTestSuite) TestImportFunctionsDependencyCycle() {
+	functionsFile, err := ioutil.TempFile("", "nuctl-import-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionsFile.Name()) // nolint: errcheck
+
+	_, err = functionsFile.WriteString(`
+function-a:
+  metadata:
+    name: function-a
+  spec:
+    dependsOn: [function-b]
+function-b:
+  metadata:
+    name: function-b
+  spec:
+    dependsOn: [function-a]
+`)
+	suite.Require().NoError(err)
+	functionsFile.Close() // nolint: errcheck
+
+	err = suite.executeNuctl("import", "functions", functionsFile.Name())
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(),
+		"Functions depend on one another in a cycle: function-a -> function-b -> function-a")
+
+	// none of them is imported
+	suite.Require().Empty(suite.getDeployedFunctionNames())
+}
+
+func (suite *fakePlatformTestSuite) TestExportImportScrubbedEncrypted() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--env", "LOG_LEVEL=debug",
+		"--env", "DB_PASSWORD=db-password")
+	suite.Require().NoError(err)
+
+	tempDir, err := ioutil.TempDir("", "nuctl-export-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	exportPath := path.Join(tempDir, "functions.yaml")
+	secretsPath := path.Join(tempDir, "secrets.yaml")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("export", "function", "my-function",
+		"--scrub-secrets",
+		"--secrets-output", secretsPath,
+		"--encrypt-key", "my-key")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "encrypted:")
+	suite.Require().NotContains(suite.outputBuffer.String(), "LOG_LEVEL")
+
+	err = ioutil.WriteFile(exportPath, suite.outputBuffer.Bytes(), 0600)
+	suite.Require().NoError(err)
+
+	encodedSecrets, err := ioutil.ReadFile(secretsPath)
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-function:\n  env.DB_PASSWORD: db-password\n", string(encodedSecrets))
+
+	err = suite.executeNuctl("delete", "function", "my-function")
+	suite.Require().NoError(err)
+
+	// the key and the scrubbed secrets are required
+	err = suite.executeNuctl("import", "function", exportPath)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "--decrypt-key")
+
+	err = suite.executeNuctl("import", "function", exportPath, "--decrypt-key", "other-key")
+	suite.Require().Error(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("import", "function", exportPath, "--decrypt-key", "my-key")
+	suite.Require().Error(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "Missing values of scrubbed secrets: env.DB_PASSWORD, provide them with --secrets-file")
+
+	err = suite.executeNuctl("import", "function", exportPath,
+		"--decrypt-key", "my-key",
+		"--secrets-file", secretsPath)
+	suite.Require().NoError(err)
+
+	functionConfig := suite.getFunctionConfig("my-function")
+	suite.Require().Len(functionConfig.Spec.Env, 2)
+	suite.Require().Equal("debug", functionConfig.Spec.Env[0].Value)
+	suite.Require().Equal("db-password", functionConfig.Spec.Env[1].Value)
+}
+
+func (suite *fakePlatformTestSuite) TestImportWithOverrides() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "staging-registry/my-function:1.0.0",
+		"--env", "ENVIRONMENT=staging")
+	suite.Require().NoError(err)
+
+	tempDir, err := ioutil.TempDir("", "nuctl-import-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	exportPath := path.Join(tempDir, "functions.yaml")
+	valuesPath := path.Join(tempDir, "values.yaml")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("export", "function", "my-function")
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(exportPath, suite.outputBuffer.Bytes(), 0600)
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(valuesPath, []byte(`
+spec:
+  image: prod-registry/{{ .Name }}:1.0.0
+  env:
+  - name: ENVIRONMENT
+    value: production
+`), 0600)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("delete", "function", "my-function")
+	suite.Require().NoError(err)
+
+	// a typo in a path fails the import
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("import", "function", exportPath, "--set", "spec.replica=2")
+	suite.Require().Error(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "Failed to apply overrides")
+
+	err = suite.executeNuctl("import", "function", exportPath,
+		"--values", valuesPath,
+		"--set", "spec.replicas=2",
+		"--set", "spec.env.LOG_LEVEL=info")
+	suite.Require().NoError(err)
+
+	functionConfig := suite.getFunctionConfig("my-function")
+	suite.Require().Equal("prod-registry/my-function:1.0.0", functionConfig.Spec.Image)
+	suite.Require().Equal(2, *functionConfig.Spec.Replicas)
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "ENVIRONMENT", Value: "production"},
+		{Name: "LOG_LEVEL", Value: "info"},
+	}, functionConfig.Spec.Env)
+}
+
+func (suite *fakePlatformTestSuite) TestContexts() {
+	configDir, err := ioutil.TempDir("", "nuctl-config-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(configDir) // nolint: errcheck
+
+	os.Setenv("NUCTL_CONFIG", path.Join(configDir, "config")) // nolint: errcheck
+	defer os.Unsetenv("NUCTL_CONFIG")                         // nolint: errcheck
+
+	// without contexts, the flags' defaults are used
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("config", "get-contexts")
+	suite.Require().NoError(err)
+	suite.Require().Equal("No contexts found\n", suite.outputBuffer.String())
+
+	err = suite.executeNuctl("config", "set-context", "dev",
+		"--namespace", "dev-namespace",
+		"--project", "my-project",
+		"--registry", "my-registry")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("config", "set-context", "prod", "--namespace", "prod-namespace")
+	suite.Require().NoError(err)
+
+	// setting a field leaves the rest as is
+	err = suite.executeNuctl("config", "set-context", "dev", "--dashboard-url", "http://dashboard:8070")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("config", "use-context", "staging")
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("config", "use-context", "dev")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("config", "current-context")
+	suite.Require().NoError(err)
+	suite.Require().Equal("dev\n", suite.outputBuffer.String())
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("config", "get-contexts", "--output", "json")
+	suite.Require().NoError(err)
+
+	config := common.Config{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &config)
+	suite.Require().NoError(err)
+	suite.Require().Equal("dev", config.CurrentContext)
+	suite.Require().Equal(&common.Context{
+		Platform:     fake.Name,
+		Namespace:    "dev-namespace",
+		Project:      "my-project",
+		Registry:     "my-registry",
+		DashboardURL: "http://dashboard:8070",
+	}, config.Contexts["dev"])
+
+	// commands are run with the current context's defaults
+	err = suite.executeNuctl("create", "project", "my-project")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	functionConfig := suite.getFunctionConfig("my-function")
+	suite.Require().Equal("dev-namespace", functionConfig.Meta.Namespace)
+	suite.Require().Equal("my-project", functionConfig.Meta.Labels["nuclio.io/project-name"])
+
+	// unless given explicitly, or with another context
+	for _, args := range [][]string{
+		{"--namespace", "prod-namespace"},
+		{"--context", "prod"},
+	} {
+		suite.outputBuffer.Reset()
+		err = suite.executeNuctl(append([]string{"get", "function", "--output", "json"}, args...)...)
+		suite.Require().NoError(err)
+		suite.Require().NotContains(suite.outputBuffer.String(), "my-function", args)
+	}
+
+	err = suite.executeNuctl("get", "function", "--context", "staging")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Context staging not found")
+
+	// deleting the current context unsets it
+	err = suite.executeNuctl("config", "delete-context", "dev")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("config", "current-context")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestLogOutput() {
+
+	// by default, logs aren't written to the command's output
+	err := suite.executeNuctl("get", "function", "--log-level", "debug")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), "Created platform")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "--log-level", "debug", "--log-output", "stdout")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "Created platform")
+
+	// debug logs are filtered out by a higher log level
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "--log-level", "info", "--log-output", "stdout")
+	suite.Require().NoError(err)
+	suite.Require().NotContains(suite.outputBuffer.String(), "Created platform")
+
+	// logs can be written to a file
+	logFile, err := ioutil.TempFile("", "nuctl-log-*.log")
+	suite.Require().NoError(err)
+	logFile.Close()                 // nolint: errcheck
+	defer os.Remove(logFile.Name()) // nolint: errcheck
+
+	err = suite.executeNuctl("get", "function", "--verbose", "--log-output", logFile.Name())
+	suite.Require().NoError(err)
+
+	logContents, err := ioutil.ReadFile(logFile.Name())
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(logContents), "Created platform")
+
+	// the log file is closed once the command is done, whether it succeeded or not
+	for _, args := range [][]string{
+		{"get", "function"},
+		{"get", "function", "does-not-exist"},
+	} {
+		rootCommandeer := NewRootCommandeer()
+		rootCommandeer.cmd.SetOut(&suite.outputBuffer)
+		rootCommandeer.cmd.SetErr(&suite.errorBuffer)
+		rootCommandeer.cmd.SetArgs(append(args, "--platform", fake.Name, "--verbose", "--log-output", logFile.Name()))
+
+		rootCommandeer.Execute() // nolint: errcheck
+		suite.Require().Nil(rootCommandeer.logFile)
+	}
+
+	closedLogContents, err := ioutil.ReadFile(logFile.Name())
+	suite.Require().NoError(err)
+	suite.Require().Greater(len(closedLogContents), len(logContents))
+
+	err = suite.executeNuctl("get", "function", "--log-level", "verbose")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Invalid log level verbose")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployMountSecretAsFile() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler",
+		"--path", "/does/not/matter",
+		"--mount-secret-as-file", "tls-certs:/etc/tls")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	volumes := functions[0].GetConfig().Spec.Volumes
+	suite.Require().Len(volumes, 1)
+	suite.Require().Equal("tls-certs", volumes[0].Volume.Secret.SecretName)
+	suite.Require().Equal("/etc/tls", volumes[0].VolumeMount.MountPath)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler",
+		"--path", "/does/not/matter",
+		"--mount-secret-as-file", "tls-certs:etc/tls")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "must be absolute")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployNetwork() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler",
+		"--path", "/does/not/matter",
+		"--platform-config", `{"attributes": {"restartPolicy": {"name": "always"}}}`,
+		"--network", "my-stack_default",
+		"--add-host", "db.local:10.0.0.5",
+		"--add-host", "host.docker.internal:host-gateway")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	// the network attributes are added to those of the platform configuration
+	platformAttributes := functions[0].GetConfig().Spec.Platform.Attributes
+	suite.Require().Equal("my-stack_default", platformAttributes["network"])
+	suite.Require().Equal([]string{"db.local:10.0.0.5", "host.docker.internal:host-gateway"},
+		platformAttributes["extraHosts"])
+	suite.Require().Contains(platformAttributes, "restartPolicy")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployEnvFiles() {
+	tempDir, err := ioutil.TempDir("", "nuctl-env-files")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	baseEnvFilePath := path.Join(tempDir, ".env")
+	err = ioutil.WriteFile(baseEnvFilePath, []byte("# shared\nDB_HOST=postgres\nDB_USER=nuclio\nLOG_LEVEL=info\n"), 0600)
+	suite.Require().NoError(err)
+
+	productionEnvFilePath := path.Join(tempDir, ".env.production")
+	err = ioutil.WriteFile(productionEnvFilePath,
+		[]byte("DB_HOST=postgres.prod\nDB_URL=postgres://${NUCTL_TEST_DB_PASSWORD}@postgres.prod\n"),
+		0600)
+	suite.Require().NoError(err)
+
+	os.Setenv("NUCTL_TEST_DB_PASSWORD", "secret") // nolint: errcheck
+	defer os.Unsetenv("NUCTL_TEST_DB_PASSWORD")   // nolint: errcheck
+
+	deployArgs := []string{"deploy", "my-function",
+		"--runtime", "python:3.6",
+		"--handler", "main:handler",
+		"--path", "/does/not/matter",
+		"--env-file", baseEnvFilePath,
+		"--env-file", productionEnvFilePath,
+		"--env", "LOG_LEVEL=debug",
+	}
+
+	err = suite.executeNuctl(append(deployArgs, "--env-expand")...)
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+
+	// later files override earlier ones, and the flags override them all
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "DB_HOST", Value: "postgres.prod"},
+		{Name: "DB_USER", Value: "nuclio"},
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "DB_URL", Value: "postgres://secret@postgres.prod"},
+	}, functions[0].GetConfig().Spec.Env)
+
+	// references are kept as is unless expanded
+	err = suite.executeNuctl(deployArgs...)
+	suite.Require().NoError(err)
+
+	functions, err = fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Contains(functions[0].GetConfig().Spec.Env,
+		v1.EnvVar{Name: "DB_URL", Value: "postgres://${NUCTL_TEST_DB_PASSWORD}@postgres.prod"})
+
+	// referencing a variable that isn't set fails the deployment
+	os.Unsetenv("NUCTL_TEST_DB_PASSWORD") // nolint: errcheck
+	err = suite.executeNuctl(append(deployArgs, "--env-expand")...)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "NUCTL_TEST_DB_PASSWORD")
+}
+
+func (suite *fakePlatformTestSuite) TestCreateFunctionScaffold() {
+	tempDir, err := ioutil.TempDir("", "nuctl-scaffold-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	outputDir := path.Join(tempDir, "my-function")
+
+	err = suite.executeNuctl("create", "function", "my-function",
+		"--scaffold",
+		"--runtime", "python:3.6",
+		"--output-dir", outputDir)
+	suite.Require().NoError(err)
+
+	encodedFunctionConfig, err := ioutil.ReadFile(path.Join(outputDir, "function.yaml"))
+	suite.Require().NoError(err)
+
+	functionConfig := functionconfig.Config{}
+	err = yaml.Unmarshal(encodedFunctionConfig, &functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-function", functionConfig.Meta.Name)
+	suite.Require().Equal("python:3.6", functionConfig.Spec.Runtime)
+	suite.Require().Equal("helloworld:handler", functionConfig.Spec.Handler)
+	suite.Require().Empty(functionConfig.Spec.Build.FunctionSourceCode)
+
+	handlerSource, err := ioutil.ReadFile(path.Join(outputDir, "helloworld.py"))
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(handlerSource), "def handler(context, event)")
+
+	// existing files aren't overwritten
+	err = suite.executeNuctl("create", "function", "my-function",
+		"--scaffold",
+		"--runtime", "python:3.6",
+		"--output-dir", outputDir)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "already exists")
+
+	// templates are per runtime
+	err = suite.executeNuctl("create", "function", "my-function",
+		"--scaffold",
+		"--runtime", "nodejs",
+		"--output-dir", path.Join(tempDir, "other-function"))
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "available templates: dates")
+
+	// only scaffolding is supported
+	err = suite.executeNuctl("create", "function", "my-function", "--runtime", "python")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestCreateFunctionFromTemplate() {
+	tempDir, err := ioutil.TempDir("", "nuctl-templates-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	archivePath := path.Join(tempDir, "templates.zip")
+	suite.writeFunctionTemplatesArchive(archivePath, map[string]string{
+		"templates/greeter/greeter.py": "def handler(context, event):\n    return 'hi'\n",
+		"templates/greeter/function.yaml.template": `spec:
+  runtime: python:3.9
+  handler: greeter:handler
+  env:
+  - name: GREETING
+    value: "{{ .greeting }}"
+  build:
+    functionSourceCode: {{ .SourceCode }}
+`,
+		"templates/greeter/function.yaml.values": `greeting:
+  displayName: Greeting
+  kind: string
+  attributes:
+    defaultValue: hello
+`,
+	})
+
+	archiveAddress := "file://" + archivePath
+
+	// registry templates are listed along with the built-in ones
+	err = suite.executeNuctl("get", "templates",
+		"--templates-archive-address", archiveAddress,
+		"--output", "wide")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "greeter")
+	suite.Require().Contains(suite.outputBuffer.String(), "greeting=hello")
+	suite.Require().Contains(suite.outputBuffer.String(), "helloworld")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "templates", "--runtime", "python", "--output", "json")
+	suite.Require().NoError(err)
+
+	var templateInfos []functionTemplateInfo
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &templateInfos)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(templateInfos)
+	for _, templateInfo := range templateInfos {
+		suite.Require().Contains(templateInfo.Runtime, "python")
+	}
+
+	// deploy the template, overriding its parameter
+	err = suite.executeNuctl("create", "function", "my-greeter",
+		"--template", "greeter",
+		"--set", "greeting=shalom",
+		"--templates-archive-address", archiveAddress)
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-greeter", "--output", "yaml")
+	suite.Require().NoError(err)
+
+	functionConfig := functionconfig.Config{}
+	err = yaml.Unmarshal(suite.outputBuffer.Bytes(), &functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal("python:3.9", functionConfig.Spec.Runtime)
+	suite.Require().Equal("greeter:handler", functionConfig.Spec.Handler)
+	suite.Require().Equal([]v1.EnvVar{{Name: "GREETING", Value: "shalom"}}, functionConfig.Spec.Env)
+	suite.Require().NotEmpty(functionConfig.Spec.Build.FunctionSourceCode)
+
+	// unknown parameters are rejected
+	err = suite.executeNuctl("create", "function", "other-greeter",
+		"--template", "greeter",
+		"--set", "farewell=bye",
+		"--templates-archive-address", archiveAddress)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "has no parameter farewell")
+
+	// built-in templates exist for several runtimes
+	err = suite.executeNuctl("create", "function", "my-function", "--template", "helloworld")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "specify one with --runtime")
+}
+
+func (suite *fakePlatformTestSuite) writeFunctionTemplatesArchive(archivePath string, files map[string]string) {
+	archiveFile, err := os.Create(archivePath)
+	suite.Require().NoError(err)
+	defer archiveFile.Close() // nolint: errcheck
+
+	zipWriter := zip.NewWriter(archiveFile)
+	for fileName, contents := range files {
+		fileWriter, err := zipWriter.Create(fileName)
+		suite.Require().NoError(err)
+
+		_, err = fileWriter.Write([]byte(contents))
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().NoError(zipWriter.Close())
+}
+
+func (suite *fakePlatformTestSuite) TestCreateGetDeleteAPIGateway() {
+	for _, functionName := range []string{"my-function", "my-function-v2"} {
+		err := suite.executeNuctl("deploy", functionName, "--run-image", "my-image:latest")
+		suite.Require().NoError(err)
+	}
+
+	// basic authentication requires credentials
+	err := suite.executeNuctl("create", "apigateway", "my-gateway",
+		"--function", "my-function",
+		"--authentication-mode", "basicAuth")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "requires a username and a password")
+
+	// API gateways only route to existing functions
+	err = suite.executeNuctl("create", "apigateway", "my-gateway", "--function", "does-not-exist")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Upstream function does-not-exist not found")
+
+	err = suite.executeNuctl("create", "apigateway", "my-gateway",
+		"--host", "my-host.com",
+		"--path", "/api",
+		"--function", "my-function",
+		"--canary-function", "my-function-v2",
+		"--canary-percentage", "20",
+		"--authentication-mode", "basicAuth",
+		"--basic-auth-username", "user",
+		"--basic-auth-password", "pass")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "apigateway", "my-gateway")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "| my-host.com | /api | basicAuth      |")
+	suite.Require().Contains(suite.outputBuffer.String(), "my-function, my-function-v2")
+	suite.Require().Contains(suite.outputBuffer.String(), "(20%)")
+	suite.Require().Contains(suite.outputBuffer.String(), "| ready")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "apigateway", "my-gateway", "--output", "json")
+	suite.Require().NoError(err)
+
+	apiGatewayConfig := platform.APIGatewayConfig{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &apiGatewayConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal("user", apiGatewayConfig.Spec.Authentication.BasicAuth.Username)
+	suite.Require().Equal("my-function-v2", apiGatewayConfig.Spec.Upstreams[1].NuclioFunction.Name)
+
+	err = suite.executeNuctl("delete", "apigateway", "my-gateway")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "apigateways")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "No API gateways found")
+}
+
+func (suite *fakePlatformTestSuite) executeNuctl(args ...string) error {
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.cmd.SetOut(&suite.outputBuffer)
+	rootCommandeer.cmd.SetErr(&suite.errorBuffer)
+	rootCommandeer.cmd.SetArgs(append(args, "--platform", fake.Name))
+
+	return rootCommandeer.Execute()
+}
+
+func (suite *fakePlatformTestSuite) executeNuctlWithInput(input string, args ...string) error {
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.cmd.SetOut(&suite.outputBuffer)
+	rootCommandeer.cmd.SetErr(&suite.errorBuffer)
+	rootCommandeer.cmd.SetIn(strings.NewReader(input))
+	rootCommandeer.cmd.SetArgs(append(args, "--platform", fake.Name))
+
+	return rootCommandeer.Execute()
+}
+
+// executeNuctlExec executes nuctl with the command to run given after --
+func (suite *fakePlatformTestSuite) executeNuctlExec(args []string, command ...string) error {
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.cmd.SetOut(&suite.outputBuffer)
+	rootCommandeer.cmd.SetErr(&suite.errorBuffer)
+	rootCommandeer.cmd.SetIn(&bytes.Buffer{})
+
+	args = append(args, "--platform", fake.Name)
+	if len(command) > 0 {
+		args = append(append(args, "--"), command...)
+	}
+
+	rootCommandeer.cmd.SetArgs(args)
+
+	return rootCommandeer.Execute()
+}
+
+func (suite *fakePlatformTestSuite) TearDownSuite() {
+	kubePlatforms = []string{"kube"}
+}
+
+func TestFakePlatformTestSuite(t *testing.T) {
+	suite.Run(t, new(fakePlatformTestSuite))
+}
+
+func (suite *fakePlatformTestSuite) TestExportImportProjectArchive() {
+	err := suite.executeNuctl("create", "project", "my-project")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--project-name", "my-project")
+	suite.Require().NoError(err)
+
+	archiveDir, err := ioutil.TempDir("", "nuctl-project-archive-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(archiveDir) // nolint: errcheck
+
+	archivePath := path.Join(archiveDir, "project.tar.gz")
+
+	// an archive holds a single project
+	err = suite.executeNuctl("export", "project", "--output-archive", archivePath)
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("export", "project", "my-project", "--output-archive", archivePath)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("delete", "function", "my-function")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("delete", "project", "my-project")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("import", "project", archivePath)
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	projects, err := fakePlatform.GetProjects(&platform.GetProjectsOptions{
+		Meta: platform.ProjectMeta{Name: "my-project"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: "my-function"})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+	suite.Require().Equal("my-registry/my-function:1.0.0", functions[0].GetConfig().Spec.Image)
+}
+
+func (suite *fakePlatformTestSuite) TestExportImportBundleAPIGateways() {
+	err := suite.executeNuctl("create", "project", "my-project")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--project-name", "my-project")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "other-function", "--from-image", "my-registry/other-function:1.0.0")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("create", "apigateway", "my-gateway",
+		"--host", "my-host.com",
+		"--path", "/api",
+		"--function", "my-function")
+	suite.Require().NoError(err)
+
+	// routes to a function of another project, so isn't exported with the project
+	err = suite.executeNuctl("create", "apigateway", "other-gateway", "--function", "other-function")
+	suite.Require().NoError(err)
+
+	bundleDir, err := ioutil.TempDir("", "nuctl-bundle-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(bundleDir) // nolint: errcheck
+
+	bundlePath := path.Join(bundleDir, "my-project.tar")
+
+	err = suite.executeNuctl("export", "bundle", "--project", "my-project", "--output", bundlePath)
+	suite.Require().NoError(err)
+
+	for _, args := range [][]string{
+		{"delete", "apigateway", "my-gateway"},
+		{"delete", "apigateway", "other-gateway"},
+		{"delete", "function", "my-function"},
+		{"delete", "project", "my-project"},
+	} {
+		err = suite.executeNuctl(args...)
+		suite.Require().NoError(err)
+	}
+
+	err = suite.executeNuctl("import", "bundle", bundlePath)
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	apiGateways, err := fakePlatform.GetAPIGateways(&platform.GetAPIGatewaysOptions{})
+	suite.Require().NoError(err)
+	suite.Require().Len(apiGateways, 1)
+
+	apiGatewayConfig := apiGateways[0].GetConfig()
+	suite.Require().Equal("my-gateway", apiGatewayConfig.Meta.Name)
+	suite.Require().Equal("my-host.com", apiGatewayConfig.Spec.Host)
+	suite.Require().Equal("/api", apiGatewayConfig.Spec.Path)
+	suite.Require().Equal([]string{"my-function"}, getAPIGatewayUpstreamFunctionNames(apiGatewayConfig))
+
+	// existing functions and API gateways are only replaced with --overwrite
+	fakePlatform.ResetCalls()
+	err = suite.executeNuctl("import", "bundle", bundlePath)
+	suite.Require().Error(err)
+
+	for _, call := range fakePlatform.GetCalls() {
+		suite.Require().NotContains([]string{"CreateAPIGateway", "UpdateAPIGateway"}, call.Name)
+	}
+
+	err = suite.executeNuctl("import", "bundle", bundlePath, "--overwrite")
+	suite.Require().NoError(err)
+
+	updateAPIGatewayCalls := 0
+	for _, call := range fakePlatform.GetCalls() {
+		if call.Name == "UpdateAPIGateway" {
+			updateAPIGatewayCalls++
+		}
+	}
+	suite.Require().Equal(1, updateAPIGatewayCalls)
+}
+
+func (suite *fakePlatformTestSuite) TestGetFunctionLogs() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	err = fakePlatform.SetFunctionLogs("", "my-function", "first line\nsecond line\n")
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "logs", "my-function", "--follow", "--since", "10m")
+	suite.Require().NoError(err)
+	suite.Require().Equal("first line\nsecond line\n", suite.outputBuffer.String())
+
+	// the function's only replica is named like it
+	err = suite.executeNuctl("get", "function", "logs", "my-function", "--replica", "other-replica")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Replica not found: other-replica")
+
+	err = suite.executeNuctl("get", "function", "logs", "other-function")
+	suite.Require().Error(err)
+
+	// listing functions is unaffected by the subcommand
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "my-function")
+}
+
+func (suite *fakePlatformTestSuite) TestExecFunction() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--project-name", "my-project")
+	suite.Require().NoError(err)
+
+	// the fake platform runs the command on the host
+	err = suite.executeNuctlExec([]string{"exec", "function", "my-function", "--project-name", "my-project"},
+		"sh", "-c", "echo hello")
+	suite.Require().NoError(err)
+	suite.Require().Equal("hello\n", suite.outputBuffer.String())
+
+	// the command's exit code is propagated
+	err = suite.executeNuctlExec([]string{"exec", "function", "my-function"}, "sh", "-c", "exit 3")
+	suite.Require().Error(err)
+
+	commandExitError, ok := errors.RootCause(err).(*CommandExitError)
+	suite.Require().True(ok, "Expected a command exit error, got: %s", err.Error())
+	suite.Require().Equal(3, commandExitError.ExitCode)
+
+	// the function belongs to another project
+	err = suite.executeNuctlExec([]string{"exec", "function", "my-function", "--project-name", "other-project"},
+		"true")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "belongs to project my-project, not other-project")
+
+	err = suite.executeNuctlExec([]string{"exec", "function", "my-function", "--replica", "other-replica"}, "true")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Replica not found: other-replica")
+
+	// the command must follow --
+	err = suite.executeNuctlExec([]string{"exec", "function", "my-function", "true"})
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestReplay() {
+	recordingDir, err := ioutil.TempDir("", "nuctl-replay")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(recordingDir) // nolint: errcheck
+
+	err = suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	// the fake function echoes the body with a 200
+	for recordName, record := range map[string]recording.Record{
+		"1.json": {
+			Method: "POST",
+			Path:   "/orders",
+			Body:   []byte(`{"id": 1, "items": ["a"]}`),
+			Response: recording.Response{
+				StatusCode: 200,
+				Body:       []byte(`{"items":["a"],"id":1}`),
+			},
+		},
+		"2.json": {
+			Method: "POST",
+			Path:   "/orders",
+			Body:   []byte("second"),
+			Response: recording.Response{
+				StatusCode: 201,
+				Body:       []byte("created"),
+			},
+		},
+		"3.json": {
+			Method:        "POST",
+			Body:          []byte("trunc"),
+			BodyTruncated: true,
+		},
+	} {
+		encodedRecord, err := json.Marshal(record)
+		suite.Require().NoError(err)
+		suite.Require().NoError(ioutil.WriteFile(path.Join(recordingDir, recordName), encodedRecord, 0644))
+	}
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("replay", "--function", "my-function", "--from", recordingDir)
+	suite.Require().Error(err)
+
+	responsesDifferError, ok := errors.RootCause(err).(*ResponsesDifferError)
+	suite.Require().True(ok, "Expected a responses differ error, got: %s", err.Error())
+	suite.Require().Equal(1, responsesDifferError.NumDifferences)
+	suite.Require().Contains(suite.outputBuffer.String(), "2.json (POST /orders):\n  expected: 201 created\n  actual:   200 second\n")
+	suite.Require().Contains(suite.outputBuffer.String(), "Replayed 2 events: 1 matched, 1 differed, 1 skipped\n")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("replay", "--function", "my-function", "--from", recordingDir, "--limit", "1", "--output", "json")
+	suite.Require().NoError(err)
+
+	result := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(suite.outputBuffer.Bytes(), &result))
+	suite.Require().Equal(float64(1), result["matched"])
+	suite.Require().Empty(result["differences"])
+
+	err = suite.executeNuctl("replay", "--function", "my-function", "--from", path.Join(recordingDir, "missing"))
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "does not exist")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployCanary() {
+	triggers := `{"http": {"kind": "http", "attributes": {"ingresses": {"main": {"host": "my-function.example.com"}}}}}`
+
+	// there's nothing to split the traffic of yet
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:2.0.0",
+		"--canary", "10")
+	suite.Require().Error(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--triggers", triggers)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:2.0.0",
+		"--canary", "100")
+	suite.Require().Error(err)
+
+	// the canary gets the live ingresses, with the weight of its traffic
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:2.0.0",
+		"--canary", "10")
+	suite.Require().NoError(err)
+
+	canaryConfig := suite.requireFunction("my-function-canary", "my-registry/my-function:2.0.0")
+	suite.Require().Equal("my-function", canaryConfig.Meta.Labels[canaryFunctionLabel])
+	suite.Require().Equal("my-function.example.com",
+		functionconfig.GetIngressesFromTriggers(canaryConfig.Spec.Triggers)["main"].Host)
+	suite.Require().Equal("true", canaryConfig.Spec.Triggers["http"].Annotations[nginxCanaryAnnotation])
+	suite.Require().Equal("10", canaryConfig.Spec.Triggers["http"].Annotations[nginxCanaryWeightAnnotation])
+
+	// rolling back leaves the live version as is
+	err = suite.executeNuctl("rollback", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.requireFunctionNotFound("my-function-canary")
+	suite.requireFunction("my-function", "my-registry/my-function:1.0.0")
+
+	err = suite.executeNuctl("rollback", "function", "my-function")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function my-function has no canary")
+
+	// promoting updates the live version to the canary
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:3.0.0",
+		"--canary", "50")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("promote", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.requireFunctionNotFound("my-function-canary")
+
+	liveConfig := suite.requireFunction("my-function", "my-registry/my-function:3.0.0")
+	suite.Require().Empty(liveConfig.Meta.Labels[canaryFunctionLabel])
+	suite.Require().NotContains(liveConfig.Spec.Triggers["http"].Annotations, nginxCanaryAnnotation)
+	suite.Require().Equal("my-function.example.com",
+		functionconfig.GetIngressesFromTriggers(liveConfig.Spec.Triggers)["main"].Host)
+}
+
+func (suite *fakePlatformTestSuite) TestRollbackFunctionRevisions() {
+	for _, image := range []string{"my-registry/my-function:1.0.0", "my-registry/my-function:2.0.0"} {
+		err := suite.executeNuctl("deploy", "my-function", "--from-image", image)
+		suite.Require().NoError(err)
+	}
+
+	suite.requireFunctionRevisionImages("my-function",
+		"my-registry/my-function:1.0.0",
+		"my-registry/my-function:2.0.0")
+
+	// by default, the function is rolled back to the revision before the latest, which is recorded as a new one
+	err := suite.executeNuctl("rollback", "function", "my-function")
+	suite.Require().NoError(err)
+	suite.requireFunction("my-function", "my-registry/my-function:1.0.0")
+
+	suite.requireFunctionRevisionImages("my-function",
+		"my-registry/my-function:1.0.0",
+		"my-registry/my-function:2.0.0",
+		"my-registry/my-function:1.0.0")
+
+	err = suite.executeNuctl("rollback", "function", "my-function", "--to-revision", "2")
+	suite.Require().NoError(err)
+	suite.requireFunction("my-function", "my-registry/my-function:2.0.0")
+
+	err = suite.executeNuctl("rollback", "function", "my-function", "--to-revision", "9")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Revision 9 not found (kept revisions: 1-4)")
+
+	// a function deployed once has nothing to roll back to
+	err = suite.executeNuctl("deploy", "other-function", "--from-image", "my-registry/other-function:1.0.0")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("rollback", "function", "other-function")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "nor a previous revision to roll back to")
+
+	err = suite.executeNuctl("get", "function", "--revisions")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "--revisions requires a function name")
+
+	// the revisions of a deleted function are deleted along with it
+	err = suite.executeNuctl("delete", "function", "my-function")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:3.0.0")
+	suite.Require().NoError(err)
+	suite.requireFunctionRevisionImages("my-function", "my-registry/my-function:3.0.0")
+}
+
+func (suite *fakePlatformTestSuite) requireFunctionRevisionImages(functionName string, expectedImages ...string) {
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("get", "function", functionName, "--revisions", "--output", "json")
+	suite.Require().NoError(err)
+
+	var revisions []platform.FunctionRevision
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &revisions)
+	suite.Require().NoError(err)
+
+	var images []string
+	for revisionIdx, revision := range revisions {
+		suite.Require().Equal(revisionIdx+1, revision.Revision)
+		images = append(images, revision.Config.Spec.Image)
+	}
+
+	suite.Require().Equal(expectedImages, images)
+}
+
+func (suite *fakePlatformTestSuite) requireFunction(functionName string, expectedImage string) *functionconfig.Config {
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: functionName})
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+	suite.Require().Equal(expectedImage, functions[0].GetConfig().Spec.Image)
+
+	return functions[0].GetConfig()
+}
+
+func (suite *fakePlatformTestSuite) requireFunctionNotFound(functionName string) {
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: functionName})
+	suite.Require().NoError(err)
+	suite.Require().Empty(functions)
+}
+
+func (suite *fakePlatformTestSuite) TestJSONOutput() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--output", "json",
+		"--log-level", "debug")
+	suite.Require().NoError(err)
+
+	// stdout holds the result alone, the logs go to stderr
+	report := deployReport{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &report)
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-function", report.Name)
+	suite.Require().Equal(string(functionconfig.FunctionStateReady), report.State)
+	suite.Require().Equal("my-registry/my-function:1.0.0", report.Image)
+	suite.Require().Contains(suite.errorBuffer.String(), "Created platform")
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "--body", "hello", "-o", "json")
+	suite.Require().NoError(err)
+
+	invokeResult := struct {
+		StatusCode int    `json:"statusCode"`
+		Body       string `json:"body"`
+	}{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &invokeResult)
+	suite.Require().NoError(err)
+	suite.Require().Equal(200, invokeResult.StatusCode)
+	suite.Require().Equal("hello", invokeResult.Body)
+
+	err = suite.executeNuctl("invoke", "my-function", "--repeat", "2", "-o", "json")
+	suite.Require().Error(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("delete", "function", "my-function", "-o", "json")
+	suite.Require().NoError(err)
+
+	deleted := deleteResult{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &deleted)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"my-function"}, deleted.Deleted)
+
+	// errors are rendered as the result too
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("invoke", "my-function", "-o", "json")
+	suite.Require().Error(err)
+
+	failure := map[string]string{}
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &failure)
+	suite.Require().NoError(err)
+	suite.Require().Contains(failure["error"], "Function not found")
+}
+
+func (suite *fakePlatformTestSuite) TestDeployWatch() {
+	err := suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--watch")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "platform apply (")
+	suite.Require().Contains(suite.outputBuffer.String(), "readiness wait (")
+
+	err = suite.executeNuctl("deploy", "my-function",
+		"--from-image", "my-registry/my-function:1.0.0",
+		"--watch",
+		"--json-events")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestTopFunction() {
+	for _, functionName := range []string{"second-function", "first-function"} {
+		err := suite.executeNuctl("deploy", functionName, "--from-image", "my-registry/my-function:1.0.0")
+		suite.Require().NoError(err)
+	}
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	cpuMillicores := int64(250)
+	memoryBytes := int64(64 * 1024 * 1024)
+	eventsPerSecond := 12.5
+	errorRate := 0.02
+
+	err = fakePlatform.SetFunctionUsage("", "first-function", platform.FunctionUsage{
+		Name:            "first-function",
+		Namespace:       "nuclio",
+		Replicas:        2,
+		CPUMillicores:   &cpuMillicores,
+		MemoryBytes:     &memoryBytes,
+		EventsPerSecond: &eventsPerSecond,
+		ErrorRate:       &errorRate,
+	})
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("top", "function")
+	suite.Require().NoError(err)
+
+	// sorted by name, and metrics that weren't reported are displayed as such
+	output := suite.outputBuffer.String()
+	suite.Require().Regexp(`first-function\s+\|\s+2\s+\|\s+250m\s+\|\s+64Mi\s+\|\s+12.50\s+\|\s+2.0%`, output)
+	suite.Require().Regexp(`second-function\s+\|\s+1\s+\|\s+-\s+\|\s+-\s+\|\s+-\s+\|\s+-`, output)
+	suite.Require().True(strings.Index(output, "first-function") < strings.Index(output, "second-function"))
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("top", "function", "first-function", "-o", "json")
+	suite.Require().NoError(err)
+
+	var functionUsages []platform.FunctionUsage
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &functionUsages)
+	suite.Require().NoError(err)
+	suite.Require().Len(functionUsages, 1)
+	suite.Require().Equal(int64(250), *functionUsages[0].CPUMillicores)
+
+	err = suite.executeNuctl("top", "function", "other-function")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestGetFunctionStreamStatus() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	err = fakePlatform.SetFunctionStreamStatus("", "my-function", []platform.FunctionReplicaStreamStatus{
+		{
+			Replica: "my-function-1",
+			Triggers: map[string][]platform.StreamShardStatus{
+				"stream": {
+					{ShardID: 0, Lag: 3, CommittedSequenceNumber: 10},
+					{ShardID: 1, Lag: 4, CommittedSequenceNumber: 20},
+				},
+			},
+		},
+		{
+			Replica: "my-function-2",
+			Triggers: map[string][]platform.StreamShardStatus{
+				"stream": {
+					{ShardID: 2, Lag: 0, CommittedSequenceNumber: 30},
+				},
+			},
+		},
+	})
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "function", "my-function", "--stream-status")
+	suite.Require().NoError(err)
+
+	output := suite.outputBuffer.String()
+	suite.Require().Regexp(`my-function-1\s+\|\s+stream\s+\|\s+0,1\s+\|\s+7`, output)
+	suite.Require().Regexp(`my-function-2\s+\|\s+stream\s+\|\s+2\s+\|\s+0`, output)
+
+	err = suite.executeNuctl("get", "function", "--stream-status")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "--stream-status requires a function name")
+
+	err = suite.executeNuctl("get", "function", "other-function", "--stream-status")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestGetDeleteFunctionInvocations() {
+	err := suite.executeNuctl("deploy", "my-function", "--from-image", "my-registry/my-function:1.0.0")
+	suite.Require().NoError(err)
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	now := time.Now().UTC()
+	err = fakePlatform.AddFunctionInvocations("", "my-function", []platform.FunctionInvocation{
+		{
+			ID:          "old",
+			Replica:     "my-function-1",
+			Time:        now.Add(-time.Hour),
+			TriggerKind: "http",
+			TriggerName: "default-http",
+			Method:      "GET",
+			Path:        "/old",
+			StatusCode:  200,
+		},
+		{
+			ID:          "failed",
+			Replica:     "my-function-1",
+			Time:        now.Add(-time.Minute),
+			TriggerKind: "http",
+			TriggerName: "default-http",
+			Method:      "POST",
+			Path:        "/failed",
+			StatusCode:  500,
+			Error:       "Something went wrong",
+		},
+	})
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "invocations", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`http:default-http\s+\|\s+GET /old\s+\|\s+200`, suite.outputBuffer.String())
+	suite.Require().Regexp(`http:default-http\s+\|\s+POST /failed\s+\|\s+500`, suite.outputBuffer.String())
+
+	// only the recent failed invocation
+	for _, args := range [][]string{{"--since", "10m"}, {"--failed"}} {
+		suite.outputBuffer.Reset()
+		err = suite.executeNuctl(append([]string{"get", "invocations", "my-function", "--output", "json"}, args...)...)
+		suite.Require().NoError(err)
+
+		var invocations []platform.FunctionInvocation
+		err = json.Unmarshal(suite.outputBuffer.Bytes(), &invocations)
+		suite.Require().NoError(err)
+		suite.Require().Len(invocations, 1)
+		suite.Require().Equal("failed", invocations[0].ID)
+	}
+
+	// purged invocations aren't retained again, even if the replicas still report them
+	err = suite.executeNuctl("delete", "invocations", "my-function")
+	suite.Require().NoError(err)
+
+	err = fakePlatform.AddFunctionInvocations("", "my-function", []platform.FunctionInvocation{
+		{ID: "failed", Time: now.Add(-time.Minute), TriggerKind: "http", TriggerName: "default-http"},
+	})
+	suite.Require().NoError(err)
+
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("get", "invocations", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), "No invocations found")
+
+	err = suite.executeNuctl("get", "invocations")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Getting invocations requires a function name")
+
+	err = suite.executeNuctl("get", "invocations", "other-function")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployStack() {
+	stackDir, err := ioutil.TempDir("", "nuctl-stack-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(stackDir) // nolint: errcheck
+
+	err = suite.executeNuctl("create", "project", "my-pipeline")
+	suite.Require().NoError(err)
+
+	// a function configured by a file, which has a registry of its own
+	err = os.MkdirAll(path.Join(stackDir, "enrich"), 0755)
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(path.Join(stackDir, "enrich", "function.yaml"), []byte(`
+metadata:
+  name: ignored
+spec:
+  image: my-registry/enrich:1.0.0
+  build:
+    registry: other-registry
+    mode: neverBuild
+`), 0644)
+	suite.Require().NoError(err)
+
+	stackPath := path.Join(stackDir, "stack.yaml")
+	err = ioutil.WriteFile(stackPath, []byte(`
+project: my-pipeline
+build:
+  registry: shared-registry
+  noCache: true
+functions:
+- name: ingest
+  path: ingest
+  spec:
+    runtime: python:3.6
+    handler: main:handler
+- name: enrich
+  file: enrich/function.yaml
+- name: notify
+  spec:
+    image: my-registry/notify:1.0.0
+    build:
+      mode: neverBuild
+`), 0644)
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--concurrency", "2")
+	suite.Require().NoError(err)
+
+	// the outcome of each function is rendered in the order of the manifest
+	suite.Require().Regexp(`(?s)ingest .*\| ready .*enrich .*\| ready .*notify .*\| ready `, suite.outputBuffer.String())
+
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	getFunctionConfig := func(name string) *functionconfig.Config {
+		functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{Name: name})
+		suite.Require().NoError(err)
+		suite.Require().Len(functions, 1)
+
+		return functions[0].GetConfig()
+	}
+
+	ingestConfig := getFunctionConfig("ingest")
+	suite.Require().Equal(path.Join(stackDir, "ingest"), ingestConfig.Spec.Build.Path)
+	suite.Require().Equal("shared-registry", ingestConfig.Spec.Build.Registry)
+	suite.Require().True(ingestConfig.Spec.Build.NoCache)
+	suite.Require().Equal("my-pipeline", ingestConfig.Meta.Labels["nuclio.io/project-name"])
+
+	// the function's own settings win, though its name is that of the manifest
+	enrichConfig := getFunctionConfig("enrich")
+	suite.Require().Equal("other-registry", enrichConfig.Spec.Build.Registry)
+	suite.Require().Equal("my-registry/enrich:1.0.0", enrichConfig.Spec.Image)
+	suite.Require().Equal("my-pipeline", enrichConfig.Meta.Labels["nuclio.io/project-name"])
+
+	// the registry given on the command line applies to all the functions
+	fake.ResetSharedPlatform()
+	err = suite.executeNuctl("create", "project", "my-pipeline")
+	suite.Require().NoError(err)
+
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--registry", "flag-registry")
+	suite.Require().NoError(err)
+
+	fakePlatform, err = fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("flag-registry", getFunctionConfig("ingest").Spec.Build.Registry)
+	suite.Require().Equal("flag-registry", getFunctionConfig("enrich").Spec.Build.Registry)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployStackOnlySkip() {
+	stackPath := suite.writeDeployStack(`
+functions:
+- name: function-a
+  spec:
+    image: my-registry/a:1.0.0
+- name: function-b
+  spec:
+    image: my-registry/b:1.0.0
+- name: function-c
+  spec:
+    image: my-registry/c:1.0.0
+`)
+	defer os.Remove(stackPath) // nolint: errcheck
+
+	err := suite.executeNuctl("deploy", "-f", stackPath, "--only", "function-a,function-b", "--skip", "function-b")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"function-a"}, suite.getDeployedFunctionNames())
+
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--skip", "function-a", "--skip", "function-c")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"function-a", "function-b"}, suite.getDeployedFunctionNames())
+
+	// filters must name functions of the stack
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--only", "function-d")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "function-d, which isn't in the stack")
+
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--skip", "function-a,function-b,function-c")
+	suite.Require().Error(err)
+
+	// filters only apply to stacks
+	err = suite.executeNuctl("deploy", "my-function", "--run-image", "my-image:latest", "--only", "my-function")
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployStackInvalidFunction() {
+	stackPath := suite.writeDeployStack(`
+functions:
+- name: function-a
+  spec:
+    image: my-registry/a:1.0.0
+- name: function-b
+  spec:
+    image: my-registry/b:1.0.0
+    asyncInvocation:
+      delivery: sometimes
+`)
+	defer os.Remove(stackPath) // nolint: errcheck
+
+	err := suite.executeNuctl("deploy", "-f", stackPath)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to configure function function-b")
+
+	// nothing is deployed unless all the functions are valid
+	suite.Require().Empty(suite.getDeployedFunctionNames())
+}
+
+func (suite *fakePlatformTestSuite) TestDeployStackJSONOutput() {
+	stackPath := suite.writeDeployStack(`{
+  "functions": [
+    {"name": "function-a", "spec": {"image": "my-registry/a:1.0.0"}},
+    {"name": "function-b", "spec": {"image": "my-registry/b:1.0.0"}}
+  ]
+}`)
+	defer os.Remove(stackPath) // nolint: errcheck
+
+	err := suite.executeNuctl("deploy", "-f", stackPath, "--output", "json")
+	suite.Require().NoError(err)
+
+	var reports []deployReport
+	err = json.Unmarshal(suite.outputBuffer.Bytes(), &reports)
+	suite.Require().NoError(err)
+	suite.Require().Len(reports, 2)
+	suite.Require().Equal("function-a", reports[0].Name)
+	suite.Require().Equal("my-registry/a:1.0.0", reports[0].Image)
+	suite.Require().Equal(string(functionconfig.FunctionStateReady), reports[1].State)
+
+	// a stack deploys no function by name
+	err = suite.executeNuctl("deploy", "function-a", "-f", stackPath)
+	suite.Require().Error(err)
+}
+
+func (suite *fakePlatformTestSuite) TestDeployStackDependencies() {
+	stackPath := suite.writeDeployStack(`
+functions:
+- name: function-c
+  spec:
+    image: my-registry/c:1.0.0
+    dependsOn: [function-b]
+- name: function-b
+  spec:
+    image: my-registry/b:1.0.0
+    dependsOn: [function-a]
+- name: function-a
+  spec:
+    image: my-registry/a:1.0.0
+`)
+	defer os.Remove(stackPath) // nolint: errcheck
+
+	getReportErrors := func() []string {
+		var reports []deployReport
+		err := json.Unmarshal(suite.outputBuffer.Bytes(), &reports)
+		suite.Require().NoError(err)
+
+		var reportErrors []string
+		for _, report := range reports {
+			reportErrors = append(reportErrors, report.Error)
+		}
+
+		return reportErrors
+	}
+
+	// functions skipped from the stack must already exist
+	suite.outputBuffer.Reset()
+	err := suite.executeNuctl("deploy", "-f", stackPath, "--skip", "function-a", "--output", "json")
+	suite.Require().Error(err)
+	suite.Require().Equal([]string{
+		"Dependency function/function-b failed to deploy",
+		"Dependency function/function-a not found",
+	}, getReportErrors())
+	suite.Require().Empty(suite.getDeployedFunctionNames())
+
+	// the manifest's order doesn't matter
+	suite.outputBuffer.Reset()
+	err = suite.executeNuctl("deploy", "-f", stackPath, "--output", "json")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"", "", ""}, getReportErrors())
+	suite.Require().Equal([]string{"function-a", "function-b", "function-c"}, suite.getDeployedFunctionNames())
+}
+
+func (suite *fakePlatformTestSuite) writeDeployStack(stack string) string {
+	stackFile, err := ioutil.TempFile("", "nuctl-stack-*.yaml")
+	suite.Require().NoError(err)
+
+	_, err = stackFile.WriteString(stack)
+	suite.Require().NoError(err)
+	suite.Require().NoError(stackFile.Close())
+
+	return stackFile.Name()
+}
+
+func (suite *fakePlatformTestSuite) getDeployedFunctionNames() []string {
+	fakePlatform, err := fake.GetSharedPlatform(nil)
+	suite.Require().NoError(err)
+
+	functions, err := fakePlatform.GetFunctions(&platform.GetFunctionsOptions{})
+	suite.Require().NoError(err)
+
+	var functionNames []string
+	for _, function := range functions {
+		functionNames = append(functionNames, function.GetConfig().Meta.Name)
+	}
+
+	return functionNames
+}
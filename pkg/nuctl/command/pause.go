@@ -0,0 +1,161 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type pauseCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newPauseCommandeer(rootCommandeer *RootCommandeer) *pauseCommandeer {
+	commandeer := &pauseCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause resources",
+	}
+
+	cmd.AddCommand(
+		newPauseFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type pauseFunctionCommandeer struct {
+	*pauseCommandeer
+}
+
+func newPauseFunctionCommandeer(pauseCommandeer *pauseCommandeer) *pauseFunctionCommandeer {
+	commandeer := &pauseFunctionCommandeer{
+		pauseCommandeer: pauseCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Stop a function's replicas without changing its configuration, until it's resumed",
+		Long: `Stop a function's replicas without changing its configuration, until it's resumed.
+
+On the kube platform, the function's deployment is scaled to zero and its cron jobs are suspended. On the
+local platform, the function's container is stopped. The function's state is "paused", and invoking it
+fails until it's resumed with "nuctl resume function"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCommandeer := pauseCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if err := rootCommandeer.platform.PauseFunction(&platform.PauseFunctionOptions{
+				Name:      args[0],
+				Namespace: rootCommandeer.namespace,
+			}); err != nil {
+				return errors.Wrap(err, "Failed to pause function")
+			}
+
+			rootCommandeer.loggerInstance.InfoWith("Function paused", "name", args[0])
+
+			return nil
+		},
+	}
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type resumeCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newResumeCommandeer(rootCommandeer *RootCommandeer) *resumeCommandeer {
+	commandeer := &resumeCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume paused resources",
+	}
+
+	cmd.AddCommand(
+		newResumeFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type resumeFunctionCommandeer struct {
+	*resumeCommandeer
+}
+
+func newResumeFunctionCommandeer(resumeCommandeer *resumeCommandeer) *resumeFunctionCommandeer {
+	commandeer := &resumeFunctionCommandeer{
+		resumeCommandeer: resumeCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Run the replicas of a paused function again, and wait for it to be ready",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCommandeer := resumeCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if err := rootCommandeer.platform.ResumeFunction(&platform.ResumeFunctionOptions{
+				Name:      args[0],
+				Namespace: rootCommandeer.namespace,
+			}); err != nil {
+				return errors.Wrap(err, "Failed to resume function")
+			}
+
+			rootCommandeer.loggerInstance.InfoWith("Function resumed", "name", args[0])
+
+			return nil
+		},
+	}
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
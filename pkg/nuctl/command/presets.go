@@ -0,0 +1,100 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nuclio/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// named resource requests and limits, selected with nuctl deploy --preset
+var resourcePresets = map[string]v1.ResourceRequirements{
+	"small": {
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("25m"),
+			v1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+	},
+	"medium": {
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("100m"),
+			v1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1"),
+			v1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	},
+	"large": {
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("2"),
+			v1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+	},
+}
+
+// applyResourcePreset sets the requests and limits of the given preset on the resources
+func applyResourcePreset(presetName string, resources *v1.ResourceRequirements) error {
+	preset, found := resourcePresets[presetName]
+	if !found {
+		return errors.Errorf("Unknown resource preset %s, must be one of: %s",
+			presetName,
+			strings.Join(getResourcePresetNames(), ", "))
+	}
+
+	if resources.Requests == nil {
+		resources.Requests = v1.ResourceList{}
+	}
+
+	if resources.Limits == nil {
+		resources.Limits = v1.ResourceList{}
+	}
+
+	for resourceName, quantity := range preset.Requests {
+		resources.Requests[resourceName] = quantity.DeepCopy()
+	}
+
+	for resourceName, quantity := range preset.Limits {
+		resources.Limits[resourceName] = quantity.DeepCopy()
+	}
+
+	return nil
+}
+
+func getResourcePresetNames() []string {
+	var presetNames []string
+
+	for presetName := range resourcePresets {
+		presetNames = append(presetNames, presetName)
+	}
+
+	sort.Strings(presetNames)
+
+	return presetNames
+}
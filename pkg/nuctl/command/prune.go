@@ -0,0 +1,101 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type pruneCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newPruneCommandeer(rootCommandeer *RootCommandeer) *pruneCommandeer {
+	commandeer := &pruneCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove data nuclio keeps across operations",
+	}
+
+	cmd.AddCommand(
+		newPruneBuildCacheCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// buildCachePruner is a platform whose builds persist package manager caches (build --build-cache)
+type buildCachePruner interface {
+	PruneBuildCache() error
+}
+
+type pruneBuildCacheCommandeer struct {
+	*pruneCommandeer
+
+	// the platforms whose builds persist build caches
+	supportedPlatforms []string
+}
+
+func newPruneBuildCacheCommandeer(pruneCommandeer *pruneCommandeer) *pruneBuildCacheCommandeer {
+	commandeer := &pruneBuildCacheCommandeer{
+		pruneCommandeer:    pruneCommandeer,
+		supportedPlatforms: []string{"local", "kube"},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "build-cache",
+		Short: "Remove the package manager caches persisted by builds (--build-cache)",
+		Long: `Remove the package manager caches (pip, npm, Maven, Go modules) persisted by builds with --build-cache.
+
+Docker builds persist them as BuildKit cache mounts, all of which are removed (including those of builds other
+than nuclio's). Kaniko builds persist them in the configured build cache PVC, which is emptied`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := commandeer.rootCommandeer.initializeForOperation("prune build-cache",
+				commandeer.supportedPlatforms); err != nil {
+				return err
+			}
+
+			pruner, isBuildCachePruner := commandeer.rootCommandeer.platform.(buildCachePruner)
+			if !isBuildCachePruner {
+				return &UnsupportedOperationError{
+					Operation:    "prune build-cache",
+					PlatformName: commandeer.rootCommandeer.platform.GetName(),
+				}
+			}
+
+			if err := pruner.PruneBuildCache(); err != nil {
+				return errors.Wrap(err, "Failed to prune build cache")
+			}
+
+			cmd.Println("Build cache pruned")
+
+			return nil
+		},
+	}
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
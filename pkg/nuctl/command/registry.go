@@ -0,0 +1,149 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher/registryauth"
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+	"github.com/nuclio/nuclio/pkg/platform/fake"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/spf13/cobra"
+)
+
+// registryCredentials are the credentials with which the built image is pushed to the registry, given
+// explicitly, read from a docker config.json or obtained from the cloud provider's identity
+type registryCredentials struct {
+	username         string
+	password         string
+	dockerConfigPath string
+	registryAuth     string
+}
+
+func addRegistryCredentialsFlags(cmd *cobra.Command, credentials *registryCredentials) {
+	cmd.Flags().StringVar(&credentials.username, "registry-user", "", "Username for pushing to the registry (overrides the docker config)")
+	cmd.Flags().StringVar(&credentials.password, "registry-password", "", "Password for pushing to the registry (overrides the docker config)")
+	cmd.Flags().StringVar(&credentials.dockerConfigPath, "docker-config", "", "Path to a docker config.json with the registry credentials (default - the docker CLI's config.json)")
+	cmd.Flags().StringVar(&credentials.registryAuth, "registry-auth", "", "Obtain short-lived registry credentials from the environment's cloud identity (one of ecr, gcr, acr or auto)")
+}
+
+// resolve returns the credentials of the given registry - the explicitly given ones, those of the registry
+// auth provider, or those in the docker config. returns nil if there are none
+func (rc *registryCredentials) resolve(loggerInstance logger.Logger,
+	cmdRunner cmdrunner.CmdRunner,
+	registry string) (*dockercreds.Credentials, error) {
+	if rc.registryAuth != "" {
+		if rc.username != "" || rc.password != "" {
+			return nil, errors.New("--registry-auth can't be given along with --registry-user and --registry-password")
+		}
+
+		return rc.resolveFromRegistryAuth(loggerInstance, registry)
+	}
+
+	if rc.username != "" || rc.password != "" {
+		if rc.username == "" || rc.password == "" {
+			return nil, errors.New("--registry-user and --registry-password must be given together")
+		}
+
+		return &dockercreds.Credentials{
+			URL:      dockercreds.GetRegistryHost(registry),
+			Username: rc.username,
+			Password: rc.password,
+		}, nil
+	}
+
+	dockerConfigPath := rc.dockerConfigPath
+	if dockerConfigPath == "" {
+		dockerConfigPath = dockercreds.GetDefaultDockerConfigPath()
+
+		// not having a docker config is fine, as long as one wasn't given explicitly
+		if dockerConfigPath == "" || !common.FileExists(dockerConfigPath) {
+			return nil, nil
+		}
+	}
+
+	dockerConfig, err := dockercreds.LoadDockerConfig(dockerConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load docker config")
+	}
+
+	return dockerConfig.GetRegistryCredentials(cmdRunner, registry)
+}
+
+func (rc *registryCredentials) resolveFromRegistryAuth(loggerInstance logger.Logger,
+	registry string) (*dockercreds.Credentials, error) {
+	registryAuthProvider, err := registryauth.NewProvider(loggerInstance, registryauth.Kind(rc.registryAuth))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry auth provider")
+	}
+
+	credentials, err := registryAuthProvider.GetCredentials(registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get registry credentials")
+	}
+
+	return &dockercreds.Credentials{
+		URL:      dockercreds.GetRegistryHost(registry),
+		Username: credentials.Username,
+		Password: credentials.Password,
+	}, nil
+}
+
+// logIn logs in to the registry the image is pushed to, if there are credentials for it
+func (rc *registryCredentials) logIn(rootCommandeer *RootCommandeer, registry string) error {
+	if registry == "" {
+		if rc.username != "" || rc.password != "" || rc.dockerConfigPath != "" || rc.registryAuth != "" {
+			return errors.New("Registry credentials were given without a registry (--registry)")
+		}
+
+		return nil
+	}
+
+	cmdRunner, err := cmdrunner.NewShellRunner(rootCommandeer.loggerInstance)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create command runner")
+	}
+
+	credentials, err := rc.resolve(rootCommandeer.loggerInstance, cmdRunner, registry)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve registry credentials")
+	}
+
+	// nothing is pushed anywhere on the fake platform
+	if credentials == nil || rootCommandeer.platform.GetName() == fake.Name {
+		return nil
+	}
+
+	dockerClient, err := dockerclient.NewShellClient(rootCommandeer.loggerInstance, cmdRunner)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create docker client")
+	}
+
+	rootCommandeer.loggerInstance.DebugWith("Logging in to registry",
+		"registry", registry,
+		"username", credentials.Username)
+
+	return dockerClient.LogIn(&dockerclient.LogInOptions{
+		URL:      credentials.URL,
+		Username: credentials.Username,
+		Password: credentials.Password,
+	})
+}
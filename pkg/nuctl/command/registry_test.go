@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type registryCredentialsTestSuite struct {
+	suite.Suite
+	logger           logger.Logger
+	cmdRunner        cmdrunner.CmdRunner
+	dockerConfigPath string
+}
+
+func (suite *registryCredentialsTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.cmdRunner, err = cmdrunner.NewShellRunner(suite.logger)
+	suite.Require().NoError(err)
+
+	dockerConfigFile, err := ioutil.TempFile("", "docker-config-*.json")
+	suite.Require().NoError(err)
+
+	_, err = dockerConfigFile.WriteString(`{"auths": {"registry.example.com": {"username": "file-user", "password": "file-password"}}}`)
+	suite.Require().NoError(err)
+	dockerConfigFile.Close() // nolint: errcheck
+
+	suite.dockerConfigPath = dockerConfigFile.Name()
+}
+
+func (suite *registryCredentialsTestSuite) TearDownTest() {
+	os.Remove(suite.dockerConfigPath) // nolint: errcheck
+}
+
+func (suite *registryCredentialsTestSuite) TestResolve() {
+	credentials := registryCredentials{dockerConfigPath: suite.dockerConfigPath}
+
+	resolvedCredentials, err := credentials.resolve(suite.logger, suite.cmdRunner, "registry.example.com/my-project")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&dockercreds.Credentials{
+		URL:      "registry.example.com",
+		Username: "file-user",
+		Password: "file-password",
+	}, resolvedCredentials)
+
+	// explicit credentials override the file
+	credentials.username = "flag-user"
+	credentials.password = "flag-password"
+
+	resolvedCredentials, err = credentials.resolve(suite.logger, suite.cmdRunner, "registry.example.com/my-project")
+	suite.Require().NoError(err)
+	suite.Require().Equal(&dockercreds.Credentials{
+		URL:      "registry.example.com",
+		Username: "flag-user",
+		Password: "flag-password",
+	}, resolvedCredentials)
+
+	credentials.password = ""
+	_, err = credentials.resolve(suite.logger, suite.cmdRunner, "registry.example.com")
+	suite.Require().Error(err)
+
+	// an explicitly given docker config must exist
+	credentials = registryCredentials{dockerConfigPath: suite.dockerConfigPath + ".missing"}
+	_, err = credentials.resolve(suite.logger, suite.cmdRunner, "registry.example.com")
+	suite.Require().Error(err)
+}
+
+func (suite *registryCredentialsTestSuite) TestResolveRegistryAuth() {
+	credentials := registryCredentials{registryAuth: "quay"}
+	_, err := credentials.resolve(suite.logger, suite.cmdRunner, "quay.io/my-project")
+	suite.Require().Error(err)
+
+	// a registry auth provider can't be given along with explicit credentials
+	credentials = registryCredentials{
+		registryAuth: "gcr",
+		username:     "flag-user",
+		password:     "flag-password",
+	}
+	_, err = credentials.resolve(suite.logger, suite.cmdRunner, "gcr.io/my-project")
+	suite.Require().Error(err)
+}
+
+func TestRegistryCredentialsTestSuite(t *testing.T) {
+	suite.Run(t, new(registryCredentialsTestSuite))
+}
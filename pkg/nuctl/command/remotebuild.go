@@ -0,0 +1,401 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/util"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	remoteBuildPollInterval = 2 * time.Second
+	remoteBuildTimeout      = 30 * time.Minute
+)
+
+// dashboardClient deploys functions through the dashboard, which builds them in-cluster (e.g. with kaniko) and
+// pushes them to its registry - so that functions can be deployed without a local docker daemon or registry access
+type dashboardClient struct {
+	logger       logger.Logger
+	dashboardURL string
+	httpClient   *http.Client
+	pollInterval time.Duration
+}
+
+func newDashboardClient(parentLogger logger.Logger, dashboardURL string) *dashboardClient {
+	return &dashboardClient{
+		logger:       parentLogger.GetChild("dashboard"),
+		dashboardURL: strings.TrimSuffix(dashboardURL, "/"),
+		httpClient:   &http.Client{Timeout: 5 * time.Minute},
+		pollInterval: remoteBuildPollInterval,
+	}
+}
+
+// deployRemotely has the dashboard build and deploy the function, and waits for it to be ready
+func (d *deployCommandeer) deployRemotely(cmd *cobra.Command) error {
+	client := newDashboardClient(d.rootCommandeer.loggerInstance, d.dashboardURL)
+	functionConfig := d.functionConfig
+
+	if err := client.uploadFunctionSource(&functionConfig); err != nil {
+		return errors.Wrap(err, "Failed to upload function source")
+	}
+
+	// the dashboard is given the configuration read from the file, rather than the file
+	functionConfig.Spec.Build.FunctionConfigPath = ""
+
+	if err := client.deployFunction(&functionConfig); err != nil {
+		return errors.Wrap(err, "Failed to deploy function")
+	}
+
+	function, err := client.waitForFunction(functionConfig.Meta.Name,
+		functionConfig.Meta.Namespace,
+		d.rootCommandeer.getProgressWriter(cmd))
+	if err != nil {
+		return errors.Wrap(err, "Failed to wait for function")
+	}
+
+	d.rootCommandeer.loggerInstance.InfoWith("Function deploy complete",
+		"functionName", functionConfig.Meta.Name,
+		"httpPort", function.Status.HTTPPort)
+
+	return nil
+}
+
+// uploadFunctionSource uploads the function's local source (a directory, which is archived, or an archive) and
+// points the function's build at the uploaded archive. a single source file is sent inline, and sources which
+// aren't local (e.g. URLs) are fetched by the dashboard itself
+func (dc *dashboardClient) uploadFunctionSource(functionConfig *functionconfig.Config) error {
+	functionPath := functionConfig.Spec.Build.Path
+	if functionPath == "" || common.IsURL(functionPath) || functionConfig.Spec.Build.CodeEntryType != "" {
+		return nil
+	}
+
+	if !common.FileExists(functionPath) {
+		return errors.Errorf("Function path doesn't exist: %s", functionPath)
+	}
+
+	var sourceFileName string
+	var sourceContents []byte
+	var err error
+
+	switch {
+	case common.IsDir(functionPath):
+		sourceFileName = filepath.Base(filepath.Clean(functionPath)) + ".tar.gz"
+
+		sourceContents, err = archiveFunctionDir(functionPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to archive function directory")
+		}
+
+	case util.IsCompressed(functionPath) || util.IsJar(functionPath):
+		sourceFileName = filepath.Base(functionPath)
+
+		sourceContents, err = ioutil.ReadFile(functionPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read function archive")
+		}
+
+	default:
+		sourceContents, err = ioutil.ReadFile(functionPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read function source")
+		}
+
+		functionConfig.Spec.Build.FunctionSourceCode = base64.StdEncoding.EncodeToString(sourceContents)
+		functionConfig.Spec.Build.Path = ""
+
+		return nil
+	}
+
+	dc.logger.DebugWith("Uploading function source", "name", sourceFileName, "size", len(sourceContents))
+
+	responseBody, err := dc.sendRequest(http.MethodPost,
+		"/api/function_sources",
+		map[string]string{"x-nuclio-source-file-name": sourceFileName},
+		sourceContents,
+		http.StatusCreated)
+	if err != nil {
+		return errors.Wrap(err, "Failed to upload function source")
+	}
+
+	uploadedSource := struct {
+		Path string `json:"path"`
+	}{}
+
+	if err := json.Unmarshal(responseBody, &uploadedSource); err != nil || uploadedSource.Path == "" {
+		return errors.New("Failed to get the path of the uploaded function source")
+	}
+
+	functionConfig.Spec.Build.Path = uploadedSource.Path
+
+	return nil
+}
+
+// deployFunction creates the function, or updates it if it exists
+func (dc *dashboardClient) deployFunction(functionConfig *functionconfig.Config) error {
+	encodedFunctionConfig, err := json.Marshal(functionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode function configuration")
+	}
+
+	headers := map[string]string{
+		"Content-Type":                "application/json",
+		"x-nuclio-function-namespace": functionConfig.Meta.Namespace,
+		"x-nuclio-project-name":       functionConfig.Meta.Labels["nuclio.io/project-name"],
+	}
+
+	existingFunction, err := dc.getFunction(functionConfig.Meta.Name, functionConfig.Meta.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	// the dashboard responds once the function is being built
+	if existingFunction == nil {
+		_, err = dc.sendRequest(http.MethodPost,
+			"/api/functions",
+			headers,
+			encodedFunctionConfig,
+			http.StatusAccepted)
+	} else {
+		_, err = dc.sendRequest(http.MethodPut,
+			"/api/functions/"+functionConfig.Meta.Name,
+			headers,
+			encodedFunctionConfig,
+			http.StatusAccepted)
+	}
+
+	return err
+}
+
+// waitForFunction waits for the function to be ready, writing the logs of its deploy as they're reported
+func (dc *dashboardClient) waitForFunction(name string,
+	namespace string,
+	progressWriter io.Writer) (*functionconfig.ConfigWithStatus, error) {
+	reportedLogs := 0
+	deadline := time.Now().Add(remoteBuildTimeout)
+
+	for {
+		function, err := dc.getFunction(name, namespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get function")
+		}
+
+		if function == nil {
+			return nil, errors.New("Function was deleted while it was being deployed")
+		}
+
+		for ; reportedLogs < len(function.Status.Logs); reportedLogs++ {
+			if message, ok := function.Status.Logs[reportedLogs]["message"].(string); ok {
+				fmt.Fprintln(progressWriter, message) // nolint: errcheck
+			}
+		}
+
+		switch function.Status.State {
+		case functionconfig.FunctionStateReady:
+			return function, nil
+		case functionconfig.FunctionStateError:
+			return nil, errors.Errorf("Function deploy failed (%s): %s", function.Status.State, function.Status.Message)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("Timed out waiting for function to be ready (state: %s)", function.Status.State)
+		}
+
+		time.Sleep(dc.pollInterval)
+	}
+}
+
+// getFunction returns the function, or nil if it doesn't exist
+func (dc *dashboardClient) getFunction(name string, namespace string) (*functionconfig.ConfigWithStatus, error) {
+	path := "/api/functions/" + name
+
+	statusCode, responseBody, err := dc.doRequest(http.MethodGet,
+		path,
+		map[string]string{"x-nuclio-function-namespace": namespace},
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("Dashboard responded to GET %s with %d: %s",
+			path,
+			statusCode,
+			strings.TrimSpace(string(responseBody)))
+	}
+
+	function := functionconfig.ConfigWithStatus{}
+	if err := json.Unmarshal(responseBody, &function); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode function")
+	}
+
+	return &function, nil
+}
+
+// sendRequest sends a request to the dashboard, failing unless it's responded to with the expected status code
+func (dc *dashboardClient) sendRequest(method string,
+	path string,
+	headers map[string]string,
+	body []byte,
+	expectedStatusCode int) ([]byte, error) {
+
+	statusCode, responseBody, err := dc.doRequest(method, path, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != expectedStatusCode {
+		return nil, errors.Errorf("Dashboard responded to %s %s with %d: %s",
+			method,
+			path,
+			statusCode,
+			strings.TrimSpace(string(responseBody)))
+	}
+
+	return responseBody, nil
+}
+
+func (dc *dashboardClient) doRequest(method string,
+	path string,
+	headers map[string]string,
+	body []byte) (int, []byte, error) {
+
+	request, err := http.NewRequest(method, dc.dashboardURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerName, headerValue := range headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	response, err := dc.httpClient.Do(request)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "Failed to send request to dashboard at %s", dc.dashboardURL)
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "Failed to read response")
+	}
+
+	return response.StatusCode, responseBody, nil
+}
+
+// archiveFunctionDir returns the contents of the directory as a tar.gz, without the paths its .nuclioignore lists
+func archiveFunctionDir(functionDir string) ([]byte, error) {
+	ignoreMatcher, err := util.ReadIgnoreFile(functionDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read ignore file")
+	}
+
+	archive := bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := filepath.Walk(functionDir, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(functionDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		if relativePath == "." {
+			return nil
+		}
+
+		if relativePath == util.IgnoreFileName || ignoreMatcher.Matches(relativePath, fileInfo.IsDir()) {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		// only directories and regular files are archived
+		if !fileInfo.IsDir() && !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, "")
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relativePath)
+		if fileInfo.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close() // nolint: errcheck
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to archive directory")
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to close archive")
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress archive")
+	}
+
+	return archive.Bytes(), nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type remoteBuildTestSuite struct {
+	suite.Suite
+	client         *dashboardClient
+	server         *httptest.Server
+	requests       []string
+	uploadedSource []byte
+	deployedConfig functionconfig.Config
+	functionExists bool
+	functionPolls  int
+	functionStatus []functionconfig.Status
+}
+
+func (suite *remoteBuildTestSuite) SetupTest() {
+	suite.requests = nil
+	suite.uploadedSource = nil
+	suite.functionExists = false
+	suite.functionPolls = 0
+	suite.functionStatus = []functionconfig.Status{
+		{State: functionconfig.FunctionStateBuilding, Logs: []map[string]interface{}{{"message": "Building"}}},
+		{State: functionconfig.FunctionStateReady, HTTPPort: 30080, Logs: []map[string]interface{}{
+			{"message": "Building"},
+			{"message": "Function deploy complete"},
+		}},
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.handleRequest))
+
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.client = newDashboardClient(logger, suite.server.URL+"/")
+	suite.client.pollInterval = 0
+}
+
+func (suite *remoteBuildTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *remoteBuildTestSuite) TestDeployFunctionDir() {
+	functionDir, err := ioutil.TempDir("", "nuclio-remote-build-test")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(functionDir) // nolint: errcheck
+
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(functionDir, "main.py"), []byte("code"), 0644))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(functionDir, "lib"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(functionDir, "lib", "util.py"), []byte("util"), 0644))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(functionDir, "venv"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(functionDir, "venv", "python"), []byte("bin"), 0644))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(functionDir, ".nuclioignore"), []byte("venv/\n"), 0644))
+
+	functionConfig := functionconfig.Config{}
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Meta.Namespace = "nuclio"
+	functionConfig.Spec.Build.Path = functionDir
+
+	suite.Require().NoError(suite.client.uploadFunctionSource(&functionConfig))
+	suite.Require().Equal("/uploads/1-"+filepath.Base(functionDir)+".tar.gz", functionConfig.Spec.Build.Path)
+
+	// the directory is archived, without the paths it ignores
+	suite.Require().Equal([]string{"lib/", "lib/util.py", "main.py"}, suite.getArchivedFileNames(suite.uploadedSource))
+
+	suite.Require().NoError(suite.client.deployFunction(&functionConfig))
+	suite.Require().Equal("/uploads/1-"+filepath.Base(functionDir)+".tar.gz", suite.deployedConfig.Spec.Build.Path)
+
+	progress := bytes.Buffer{}
+	function, err := suite.client.waitForFunction("my-function", "nuclio", &progress)
+	suite.Require().NoError(err)
+	suite.Require().Equal(30080, function.Status.HTTPPort)
+
+	// each log is written once
+	suite.Require().Equal("Building\nFunction deploy complete\n", progress.String())
+
+	suite.Require().Equal([]string{
+		"POST /api/function_sources",
+		"GET /api/functions/my-function",
+		"POST /api/functions",
+		"GET /api/functions/my-function",
+		"GET /api/functions/my-function",
+	}, suite.requests)
+}
+
+func (suite *remoteBuildTestSuite) TestDeployExistingFunctionFromFile() {
+	functionFile, err := ioutil.TempFile("", "nuclio-remote-build-test-*.py")
+	suite.Require().NoError(err)
+	defer os.Remove(functionFile.Name()) // nolint: errcheck
+
+	_, err = functionFile.WriteString("code")
+	suite.Require().NoError(err)
+	functionFile.Close() // nolint: errcheck
+
+	functionConfig := functionconfig.Config{}
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Spec.Build.Path = functionFile.Name()
+
+	// a single file is sent inline
+	suite.Require().NoError(suite.client.uploadFunctionSource(&functionConfig))
+	suite.Require().Empty(functionConfig.Spec.Build.Path)
+	suite.Require().Equal(base64.StdEncoding.EncodeToString([]byte("code")),
+		functionConfig.Spec.Build.FunctionSourceCode)
+
+	suite.functionExists = true
+	suite.Require().NoError(suite.client.deployFunction(&functionConfig))
+
+	suite.Require().Equal([]string{
+		"GET /api/functions/my-function",
+		"PUT /api/functions/my-function",
+	}, suite.requests)
+}
+
+func (suite *remoteBuildTestSuite) TestWaitForFailedFunction() {
+	suite.functionExists = true
+	suite.functionStatus = []functionconfig.Status{
+		{State: functionconfig.FunctionStateError, Message: "Failed to build"},
+	}
+
+	_, err := suite.client.waitForFunction("my-function", "nuclio", ioutil.Discard)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to build")
+}
+
+func (suite *remoteBuildTestSuite) handleRequest(responseWriter http.ResponseWriter, request *http.Request) {
+	suite.requests = append(suite.requests, request.Method+" "+request.URL.Path)
+
+	body, err := ioutil.ReadAll(request.Body)
+	suite.Require().NoError(err)
+
+	switch request.Method + " " + request.URL.Path {
+	case "POST /api/function_sources":
+		suite.uploadedSource = body
+
+		responseWriter.WriteHeader(http.StatusCreated)
+		responseWriter.Write([]byte(`{"path": "/uploads/1-` + // nolint: errcheck
+			request.Header.Get("x-nuclio-source-file-name") + `"}`))
+
+	case "POST /api/functions", "PUT /api/functions/my-function":
+		suite.Require().NoError(json.Unmarshal(body, &suite.deployedConfig))
+		suite.functionExists = true
+
+		responseWriter.WriteHeader(http.StatusAccepted)
+
+	case "GET /api/functions/my-function":
+		if !suite.functionExists {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		status := suite.functionStatus[suite.functionPolls]
+		if suite.functionPolls < len(suite.functionStatus)-1 {
+			suite.functionPolls++
+		}
+
+		encodedFunction, err := json.Marshal(&functionconfig.ConfigWithStatus{
+			Config: suite.deployedConfig,
+			Status: status,
+		})
+		suite.Require().NoError(err)
+
+		responseWriter.Write(encodedFunction) // nolint: errcheck
+
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (suite *remoteBuildTestSuite) getArchivedFileNames(archive []byte) []string {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	suite.Require().NoError(err)
+
+	tarReader := tar.NewReader(gzipReader)
+
+	var fileNames []string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		suite.Require().NoError(err)
+
+		fileNames = append(fileNames, header.Name)
+	}
+
+	sort.Strings(fileNames)
+
+	return fileNames
+}
+
+func TestRemoteBuildTestSuite(t *testing.T) {
+	suite.Run(t, new(remoteBuildTestSuite))
+}
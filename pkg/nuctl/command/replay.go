@@ -0,0 +1,393 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+// the length bodies are shortened to when showing differences
+const replayDifferenceBodyLength = 256
+
+// recorded headers that describe the original connection rather than the event, and so aren't replayed
+var unreplayedHeaders = []string{"Connection", "Content-Length", "Host", "Transfer-Encoding", "Accept-Encoding"}
+
+// ResponsesDifferError is returned when the responses to replayed events differ from the recorded ones
+type ResponsesDifferError struct {
+	FunctionName   string
+	NumDifferences int
+}
+
+func (e *ResponsesDifferError) Error() string {
+	return fmt.Sprintf("%d responses of function %s differ from the recorded ones", e.NumDifferences, e.FunctionName)
+}
+
+type replayDifference struct {
+	Record             string `json:"record"`
+	Method             string `json:"method"`
+	Path               string `json:"path"`
+	ExpectedStatusCode int    `json:"expectedStatusCode"`
+	ActualStatusCode   int    `json:"actualStatusCode,omitempty"`
+	ExpectedBody       string `json:"expectedBody"`
+	ActualBody         string `json:"actualBody,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+type replayResult struct {
+	Replayed    int                `json:"replayed"`
+	Matched     int                `json:"matched"`
+	Differed    int                `json:"differed"`
+	Skipped     int                `json:"skipped"`
+	Differences []replayDifference `json:"differences"`
+}
+
+type replayCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	functionName   string
+	from           string
+	speed          float64
+	invokeVia      string
+	limit          int
+	s3Region       string
+	s3Endpoint     string
+	v3ioURL        string
+	v3ioAccessKey  string
+}
+
+func newReplayCommandeer(rootCommandeer *RootCommandeer) *replayCommandeer {
+	commandeer := &replayCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "replay --function function-name --from recording",
+		Short: "Replay recorded events against a function and compare its responses",
+		Long: fmt.Sprintf(`Replay recorded events against a function and compare its responses.
+
+Events recorded by a function with spec.eventRecording are sent to the given function (e.g. a new version
+of it) in the order they were recorded, and its responses are compared with the recorded ones - the status
+codes, and the bodies (as JSON, if both are). Events of every trigger kind are replayed over HTTP. The
+recording is a local directory, s3://bucket/prefix or v3io://container/path. Exits with %d if any response
+differs`, ExitCodeResponsesDiffer),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.functionName == "" {
+				return errors.New("Function must be provided (--function)")
+			}
+
+			if commandeer.from == "" {
+				return errors.New("Recording must be provided (--from)")
+			}
+
+			if commandeer.speed < 0 {
+				return errors.New("Speed must not be negative")
+			}
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			store, err := commandeer.createStore()
+			if err != nil {
+				return errors.Wrap(err, "Failed to open recording")
+			}
+
+			result, err := commandeer.replay(store)
+			if err != nil {
+				return errors.Wrap(err, "Failed to replay events")
+			}
+
+			if err := commandeer.renderResult(cmd.OutOrStdout(), result); err != nil {
+				return errors.Wrap(err, "Failed to render result")
+			}
+
+			if result.Differed != 0 {
+				return &ResponsesDifferError{
+					FunctionName:   commandeer.functionName,
+					NumDifferences: result.Differed,
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.functionName, "function", "", "Name of the function to replay the events against")
+	cmd.Flags().StringVar(&commandeer.from, "from", "", "Recording to replay - a local directory, s3://bucket/prefix or v3io://container/path")
+	cmd.Flags().Float64Var(&commandeer.speed, "speed", 0, "Replay at this multiple of the recorded pace (e.g. 2 for twice as fast), 0 to replay as fast as possible")
+	cmd.Flags().StringVar(&commandeer.invokeVia, "via", "any", "Invoke the function via - \"any\": a load balancer or an external IP; \"loadbalancer\": a load balancer; \"external-ip\": an external IP")
+	cmd.Flags().IntVar(&commandeer.limit, "limit", 0, "Replay at most this many events, 0 for all of them")
+	cmd.Flags().StringVar(&commandeer.s3Region, "s3-region", "", "Region of the recording's S3 bucket")
+	cmd.Flags().StringVar(&commandeer.s3Endpoint, "s3-endpoint", "", "Endpoint of an S3-compatible store holding the recording")
+	cmd.Flags().StringVar(&commandeer.v3ioURL, "v3io-url", "", "URL of the v3io web API holding the recording")
+	cmd.Flags().StringVar(&commandeer.v3ioAccessKey, "v3io-access-key", os.Getenv("V3IO_ACCESS_KEY"), "Access key of the v3io web API holding the recording")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// createStore opens the recording store --from refers to
+func (r *replayCommandeer) createStore() (recording.Store, error) {
+	var eventRecording functionconfig.EventRecording
+
+	switch {
+	case strings.HasPrefix(r.from, "s3://"):
+		bucketAndPrefix := strings.SplitN(strings.TrimPrefix(r.from, "s3://"), "/", 2)
+		eventRecording = functionconfig.EventRecording{
+			Kind:     functionconfig.EventRecordingKindS3,
+			Bucket:   bucketAndPrefix[0],
+			Region:   r.s3Region,
+			Endpoint: r.s3Endpoint,
+		}
+
+		if len(bucketAndPrefix) == 2 {
+			eventRecording.Path = bucketAndPrefix[1]
+		}
+
+	case strings.HasPrefix(r.from, "v3io://"):
+		if r.v3ioURL == "" {
+			return nil, errors.New("A v3io recording requires the v3io web API URL (--v3io-url)")
+		}
+
+		containerAndPath := strings.SplitN(strings.TrimPrefix(r.from, "v3io://"), "/", 2)
+		eventRecording = functionconfig.EventRecording{
+			Kind:          functionconfig.EventRecordingKindV3io,
+			URL:           r.v3ioURL,
+			ContainerName: containerAndPath[0],
+			Secret:        r.v3ioAccessKey,
+		}
+
+		if len(containerAndPath) == 2 {
+			eventRecording.Path = containerAndPath[1]
+		}
+
+	default:
+		if fileInfo, err := os.Stat(r.from); err != nil || !fileInfo.IsDir() {
+			return nil, errors.Errorf("Recording directory %s does not exist", r.from)
+		}
+
+		eventRecording = functionconfig.EventRecording{
+			Kind: functionconfig.EventRecordingKindLocalDir,
+			Path: r.from,
+		}
+	}
+
+	return recording.NewStore(r.rootCommandeer.loggerInstance, &eventRecording)
+}
+
+// replay sends the recorded events to the function one by one, in the order they were recorded
+func (r *replayCommandeer) replay(store recording.Store) (*replayResult, error) {
+	var firstRecordTime time.Time
+
+	via, err := r.resolveVia()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve via")
+	}
+
+	recordNames, err := store.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list records")
+	}
+
+	if r.limit > 0 && len(recordNames) > r.limit {
+		recordNames = recordNames[:r.limit]
+	}
+
+	r.rootCommandeer.loggerInstance.InfoWith("Replaying events",
+		"function", r.functionName,
+		"numRecords", len(recordNames),
+		"speed", r.speed)
+
+	result := &replayResult{
+		Differences: []replayDifference{},
+	}
+
+	startTime := time.Now()
+	for _, recordName := range recordNames {
+		encodedRecord, err := store.Get(recordName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read record %s", recordName)
+		}
+
+		record := recording.Record{}
+		if err := json.Unmarshal(encodedRecord, &record); err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode record %s", recordName)
+		}
+
+		// an event whose body was truncated can't be replayed as it was
+		if record.BodyTruncated {
+			r.rootCommandeer.loggerInstance.DebugWith("Skipping record with truncated body", "record", recordName)
+			result.Skipped++
+			continue
+		}
+
+		// keep the recorded pace between events, sped up by --speed
+		if r.speed > 0 {
+			if firstRecordTime.IsZero() {
+				firstRecordTime = record.Time
+			}
+
+			replayTime := startTime.Add(time.Duration(float64(record.Time.Sub(firstRecordTime)) / r.speed))
+			time.Sleep(time.Until(replayTime))
+		}
+
+		result.Replayed++
+
+		if difference := r.replayRecord(recordName, &record, via); difference != nil {
+			result.Differed++
+			result.Differences = append(result.Differences, *difference)
+		} else {
+			result.Matched++
+		}
+	}
+
+	return result, nil
+}
+
+// replayRecord invokes the function with the recorded event, returning how its response differs from the
+// recorded one (nil if it doesn't)
+func (r *replayCommandeer) replayRecord(recordName string,
+	record *recording.Record,
+	via platform.InvokeViaType) *replayDifference {
+
+	createFunctionInvocationOptions := platform.CreateFunctionInvocationOptions{
+		Name:         r.functionName,
+		Namespace:    r.rootCommandeer.namespace,
+		Path:         record.Path,
+		Method:       record.Method,
+		Body:         record.Body,
+		Headers:      http.Header{},
+		LogLevelName: "none",
+		Via:          via,
+	}
+
+	for headerName, headerValue := range record.Headers {
+		createFunctionInvocationOptions.Headers.Set(headerName, headerValue)
+	}
+
+	for _, headerName := range unreplayedHeaders {
+		createFunctionInvocationOptions.Headers.Del(headerName)
+	}
+
+	if record.ContentType != "" {
+		createFunctionInvocationOptions.Headers.Set("Content-Type", record.ContentType)
+	}
+
+	difference := &replayDifference{
+		Record:             recordName,
+		Method:             record.Method,
+		Path:               record.Path,
+		ExpectedStatusCode: record.Response.StatusCode,
+		ExpectedBody:       shortenBody(record.Response.Body),
+	}
+
+	invokeResult, err := r.rootCommandeer.platform.CreateFunctionInvocation(&createFunctionInvocationOptions)
+	if err != nil {
+		difference.Error = err.Error()
+		return difference
+	}
+
+	if invokeResult.StatusCode == record.Response.StatusCode &&
+		responseBodiesEqual(record.Response.Body, record.Response.BodyTruncated, invokeResult.Body) {
+		return nil
+	}
+
+	difference.ActualStatusCode = invokeResult.StatusCode
+	difference.ActualBody = shortenBody(invokeResult.Body)
+
+	return difference
+}
+
+func (r *replayCommandeer) resolveVia() (platform.InvokeViaType, error) {
+	switch r.invokeVia {
+	case "any":
+		return platform.InvokeViaAny, nil
+	case "external-ip":
+		return platform.InvokeViaExternalIP, nil
+	case "loadbalancer":
+		return platform.InvokeViaLoadBalancer, nil
+	default:
+		return 0, errors.Errorf("Invalid via type %s - must be any / external-ip / loadbalancer", r.invokeVia)
+	}
+}
+
+func (r *replayCommandeer) renderResult(writer io.Writer, result *replayResult) error {
+	if r.rootCommandeer.isJSONOutput() {
+		return r.rootCommandeer.renderResult(writer, result)
+	}
+
+	for _, difference := range result.Differences {
+		fmt.Fprintf(writer, "%s (%s %s):\n", difference.Record, difference.Method, difference.Path) // nolint: errcheck
+
+		if difference.Error != "" {
+			fmt.Fprintf(writer, "  invocation failed: %s\n", difference.Error) // nolint: errcheck
+			continue
+		}
+
+		fmt.Fprintf(writer, "  expected: %d %s\n", difference.ExpectedStatusCode, difference.ExpectedBody) // nolint: errcheck
+		fmt.Fprintf(writer, "  actual:   %d %s\n", difference.ActualStatusCode, difference.ActualBody)     // nolint: errcheck
+	}
+
+	fmt.Fprintf(writer, "Replayed %d events: %d matched, %d differed, %d skipped\n", // nolint: errcheck
+		result.Replayed,
+		result.Matched,
+		result.Differed,
+		result.Skipped)
+
+	return nil
+}
+
+// responseBodiesEqual compares the bodies as JSON if both are, and byte by byte otherwise. a truncated
+// recorded body is compared with the same prefix of the actual one
+func responseBodiesEqual(expectedBody []byte, expectedBodyTruncated bool, actualBody []byte) bool {
+	if expectedBodyTruncated {
+		return bytes.HasPrefix(actualBody, expectedBody)
+	}
+
+	var expectedJSON, actualJSON interface{}
+	if json.Unmarshal(expectedBody, &expectedJSON) == nil && json.Unmarshal(actualBody, &actualJSON) == nil {
+		return reflect.DeepEqual(expectedJSON, actualJSON)
+	}
+
+	return bytes.Equal(expectedBody, actualBody)
+}
+
+func shortenBody(body []byte) string {
+	if len(body) > replayDifferenceBodyLength {
+		return string(body[:replayDifferenceBodyLength]) + "..."
+	}
+
+	return string(body)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+type rollbackCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newRollbackCommandeer(rootCommandeer *RootCommandeer) *rollbackCommandeer {
+	commandeer := &rollbackCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back functions and canaries",
+	}
+
+	cmd.AddCommand(
+		newRollbackFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type rollbackFunctionCommandeer struct {
+	*rollbackCommandeer
+	toRevision int
+
+	// functions only have canaries on the platforms canaries are deployed on (deploy --canary)
+	canarySupportedPlatforms []string
+}
+
+func newRollbackFunctionCommandeer(rollbackCommandeer *rollbackCommandeer) *rollbackFunctionCommandeer {
+	commandeer := &rollbackFunctionCommandeer{
+		rollbackCommandeer:       rollbackCommandeer,
+		canarySupportedPlatforms: kubePlatforms,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name [--to-revision N]",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Delete the canary of a function (deploy --canary), or if it has none, deploy it again with a previous revision",
+		Long: `Delete the canary of a function (deploy --canary), sending all the traffic back to the function.
+
+If the function has no canary, or a revision is given, it is deployed again with the configuration of a
+previous revision (by default, the one before the latest), without building it. The image of the revision
+must still exist. The rollback is recorded as a new revision, and revisions are listed by:
+
+    nuctl get function <name> --revisions`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.toRevision < 0 {
+				return errors.New("--to-revision must be positive")
+			}
+
+			rootCommandeer := rollbackCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if commandeer.toRevision == 0 &&
+				common.StringInSlice(rootCommandeer.platform.GetName(), commandeer.canarySupportedPlatforms) {
+				canaryFunction, err := findCanaryFunction(rootCommandeer, args[0])
+				if err != nil {
+					return err
+				}
+
+				if canaryFunction != nil {
+					return commandeer.rollbackCanary(args[0], canaryFunction)
+				}
+			}
+
+			return commandeer.rollbackToRevision(args[0])
+		},
+	}
+
+	cmd.Flags().IntVar(&commandeer.toRevision, "to-revision", 0, "The revision to roll back to (defaults to the one before the latest)")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (r *rollbackFunctionCommandeer) rollbackCanary(functionName string, canaryFunction platform.Function) error {
+	rootCommandeer := r.rootCommandeer
+
+	if err := rootCommandeer.platform.DeleteFunction(&platform.DeleteFunctionOptions{
+		FunctionConfig: *canaryFunction.GetConfig(),
+	}); err != nil {
+		return errors.Wrap(err, "Failed to delete canary")
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Canary rolled back", "name", functionName)
+
+	return nil
+}
+
+// rollbackToRevision deploys the function with the configuration of the requested revision, using the image
+// it was built into
+func (r *rollbackFunctionCommandeer) rollbackToRevision(functionName string) error {
+	rootCommandeer := r.rootCommandeer
+
+	revisions, err := rootCommandeer.platform.GetFunctionRevisions(&platform.GetFunctionRevisionsOptions{
+		Name:      functionName,
+		Namespace: rootCommandeer.namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function revisions")
+	}
+
+	var revision *platform.FunctionRevision
+	if r.toRevision == 0 {
+
+		// the latest revision is the one the function was last deployed with
+		if len(revisions) < 2 {
+			return nuclio.NewErrNotFound(fmt.Sprintf("Function %s has no canary, nor a previous revision to roll back to",
+				functionName))
+		}
+
+		revision = &revisions[len(revisions)-2]
+	} else if revision, err = platform.GetFunctionRevision(revisions, r.toRevision); err != nil {
+		return errors.Wrapf(err, "Failed to get revision of function %s", functionName)
+	}
+
+	functionConfig := revision.Config
+	functionConfig.Spec.Build.Mode = functionconfig.NeverBuild
+
+	rootCommandeer.loggerInstance.InfoWith("Rolling back function",
+		"name", functionName,
+		"revision", revision.Revision,
+		"image", functionConfig.Spec.Image)
+
+	if _, err := rootCommandeer.platform.CreateFunction(&platform.CreateFunctionOptions{
+		Logger:         rootCommandeer.loggerInstance,
+		FunctionConfig: functionConfig,
+	}); err != nil {
+		return errors.Wrapf(err, "Failed to roll back function to revision %d", revision.Revision)
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Function rolled back",
+		"name", functionName,
+		"revision", revision.Revision)
+
+	return nil
+}
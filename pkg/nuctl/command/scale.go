@@ -0,0 +1,205 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/spf13/cobra"
+)
+
+// local functions are a single container, and can't have any other number of replicas
+const localPlatformReplicas = 1
+
+type scaleCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newScaleCommandeer(rootCommandeer *RootCommandeer) *scaleCommandeer {
+	commandeer := &scaleCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "scale",
+		Short: "Scale resources",
+	}
+
+	cmd.AddCommand(
+		newScaleFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type scaleFunctionCommandeer struct {
+	*scaleCommandeer
+	replicas    int
+	wait        bool
+	waitTimeout time.Duration
+}
+
+func newScaleFunctionCommandeer(scaleCommandeer *scaleCommandeer) *scaleFunctionCommandeer {
+	commandeer := &scaleFunctionCommandeer{
+		scaleCommandeer: scaleCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function name --replicas N",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Set the number of replicas of a deployed function, leaving the rest of its configuration as is",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.replicas < 0 {
+				return errors.New("Number of replicas must be given (--replicas) and must not be negative")
+			}
+
+			rootCommandeer := scaleCommandeer.rootCommandeer
+
+			// an explicitly given platform can be validated before doing any work
+			if err := commandeer.validateReplicas(rootCommandeer.platformName); err != nil {
+				return err
+			}
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			// the platform was resolved automatically
+			if err := commandeer.validateReplicas(rootCommandeer.platform.GetName()); err != nil {
+				return err
+			}
+
+			return commandeer.scaleFunction(args[0])
+		},
+	}
+
+	cmd.Flags().IntVar(&commandeer.replicas, "replicas", -1, "Number of replicas the function should have")
+	cmd.Flags().BoolVar(&commandeer.wait, "wait", false, "Wait for the function to have the given number of available replicas")
+	cmd.Flags().DurationVar(&commandeer.waitTimeout, "wait-timeout", 5*time.Minute, "Maximum time to wait for the replicas (with --wait)")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (s *scaleFunctionCommandeer) validateReplicas(platformName string) error {
+	if platformName == "local" && s.replicas != localPlatformReplicas {
+		return &UnsupportedValueError{
+			Flag:         "--replicas",
+			Value:        strconv.Itoa(s.replicas),
+			PlatformName: platformName,
+			Reason:       "local functions run a single replica",
+		}
+	}
+
+	return nil
+}
+
+func (s *scaleFunctionCommandeer) scaleFunction(functionName string) error {
+	rootCommandeer := s.rootCommandeer
+
+	function, err := s.getFunction(functionName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	// update a copy of the live spec, so that nothing but the replicas changes
+	functionConfig := function.GetConfig()
+	functionSpec := functionConfig.Spec
+	functionSpec.Replicas = &s.replicas
+
+	if err := rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+		FunctionMeta: &functionConfig.Meta,
+		FunctionSpec: &functionSpec,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to update function")
+	}
+
+	if s.wait {
+		if err := s.waitForReplicas(functionName); err != nil {
+			return errors.Wrap(err, "Failed to wait for function replicas")
+		}
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Function scaled",
+		"name", functionName,
+		"replicas", s.replicas)
+
+	return nil
+}
+
+func (s *scaleFunctionCommandeer) getFunction(functionName string) (platform.Function, error) {
+	functions, err := s.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: s.rootCommandeer.namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	// some platforms only load the replicas on initialization
+	if err := functions[0].Initialize(nil); err != nil {
+		return nil, errors.Wrap(err, "Failed to initialize function")
+	}
+
+	return functions[0], nil
+}
+
+func (s *scaleFunctionCommandeer) waitForReplicas(functionName string) error {
+	deadline := time.Now().Add(s.waitTimeout)
+
+	for {
+		function, err := s.getFunction(functionName)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get function")
+		}
+
+		availableReplicas, _ := function.GetReplicas()
+		if availableReplicas == s.replicas {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.Errorf("Timed out after %s with %d of %d replicas available",
+				s.waitTimeout,
+				availableReplicas,
+				s.replicas)
+		}
+
+		s.rootCommandeer.loggerInstance.DebugWith("Waiting for function replicas",
+			"available", availableReplicas,
+			"desired", s.replicas)
+
+		time.Sleep(time.Second)
+	}
+}
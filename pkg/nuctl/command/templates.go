@@ -0,0 +1,287 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/dashboard/functiontemplates"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	nuctl_common "github.com/nuclio/nuclio/pkg/nuctl/command/common"
+
+	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/spf13/cobra"
+)
+
+// functionTemplatesOptions are the registries to fetch function templates from, on top of the built-in templates
+type functionTemplatesOptions struct {
+	gitRepository  string
+	gitRef         string
+	archiveAddress string
+}
+
+// functionTemplateInfo describes a function template, as listed by nuctl get templates
+type functionTemplateInfo struct {
+	Name        string            `json:"name"`
+	DisplayName string            `json:"displayName,omitempty"`
+	Runtime     string            `json:"runtime"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+}
+
+func addFunctionTemplatesFlags(cmd *cobra.Command, options *functionTemplatesOptions) {
+	cmd.Flags().StringVar(&options.gitRepository, "templates-git-repository", common.GetEnvOrDefaultString("NUCLIO_TEMPLATES_GIT_REPOSITORY", ""), "Git repository to fetch function templates from, in addition to the built-in ones (e.g. https://github.com/nuclio/nuclio-templates.git)")
+	cmd.Flags().StringVar(&options.gitRef, "templates-git-ref", common.GetEnvOrDefaultString("NUCLIO_TEMPLATES_GIT_REF", "refs/heads/master"), "Git reference of the function templates repository")
+	cmd.Flags().StringVar(&options.archiveAddress, "templates-archive-address", common.GetEnvOrDefaultString("NUCLIO_TEMPLATES_ARCHIVE_ADDRESS", ""), "Address (http(s):// or file://) of a zip archive to fetch function templates from, in addition to the built-in ones")
+}
+
+// fetchFunctionTemplates returns the built-in function templates, followed by those of the configured registries
+func fetchFunctionTemplates(loggerInstance logger.Logger,
+	options *functionTemplatesOptions) ([]*functiontemplates.FunctionTemplate, error) {
+	var fetchers []functiontemplates.FunctionTemplateFetcher
+
+	generatedFetcher, err := functiontemplates.NewGeneratedFunctionTemplateFetcher(loggerInstance)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create function template fetcher")
+	}
+
+	fetchers = append(fetchers, generatedFetcher)
+
+	if options.gitRepository != "" {
+		gitFetcher, err := functiontemplates.NewGitFunctionTemplateFetcher(loggerInstance,
+			options.gitRepository,
+			options.gitRef)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create git function template fetcher")
+		}
+
+		fetchers = append(fetchers, gitFetcher)
+	}
+
+	if options.archiveAddress != "" {
+		zipFetcher, err := functiontemplates.NewZipFunctionTemplateFetcher(loggerInstance, options.archiveAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create zip function template fetcher")
+		}
+
+		fetchers = append(fetchers, zipFetcher)
+	}
+
+	repository, err := functiontemplates.NewRepository(loggerInstance, fetchers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch function templates")
+	}
+
+	return repository.GetFunctionTemplates(nil), nil
+}
+
+// findFunctionTemplate returns the function template of the given name and runtime. the runtime may be omitted
+// if the template exists for a single runtime
+func findFunctionTemplate(functionTemplates []*functiontemplates.FunctionTemplate,
+	templateName string,
+	runtime string) (*functiontemplates.FunctionTemplate, error) {
+	requestedRuntimeName, _ := (&functionconfig.Spec{Runtime: runtime}).GetRuntimeNameAndVersion()
+
+	var matchingTemplates []*functiontemplates.FunctionTemplate
+	var matchingRuntimeNames []string
+	var runtimeTemplateNames []string
+
+	for _, functionTemplate := range functionTemplates {
+		runtimeName, _ := (&functionconfig.Spec{
+			Runtime: getFunctionTemplateRuntime(functionTemplate),
+		}).GetRuntimeNameAndVersion()
+
+		if runtime != "" && runtimeName != requestedRuntimeName {
+			continue
+		}
+
+		name := getFunctionTemplateName(functionTemplate)
+		if name == templateName {
+			matchingTemplates = append(matchingTemplates, functionTemplate)
+			matchingRuntimeNames = append(matchingRuntimeNames, runtimeName)
+		}
+
+		runtimeTemplateNames = append(runtimeTemplateNames, name)
+	}
+
+	switch len(matchingTemplates) {
+	case 1:
+		return matchingTemplates[0], nil
+	case 0:
+		if runtime == "" {
+			return nil, errors.Errorf("No function template %s", templateName)
+		}
+
+		if len(runtimeTemplateNames) == 0 {
+			return nil, errors.Errorf("No function templates for runtime %s", runtime)
+		}
+
+		sort.Strings(runtimeTemplateNames)
+
+		return nil, errors.Errorf("No function template %s for runtime %s, available templates: %s",
+			templateName,
+			runtime,
+			strings.Join(runtimeTemplateNames, ", "))
+	default:
+		sort.Strings(matchingRuntimeNames)
+
+		return nil, errors.Errorf("Function template %s exists for several runtimes (%s), specify one with --runtime",
+			templateName,
+			strings.Join(matchingRuntimeNames, ", "))
+	}
+}
+
+// renderFunctionTemplate returns the function config of the template, rendering the template's parameters with
+// their defaults, overridden by the given values (key=value)
+func renderFunctionTemplate(functionTemplate *functiontemplates.FunctionTemplate,
+	templateValues []string) (*functionconfig.Config, error) {
+	values, err := parseTemplateValues(templateValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse template values")
+	}
+
+	// built-in templates aren't parameterized
+	if functionTemplate.FunctionConfigTemplate == "" {
+		if len(values) != 0 {
+			return nil, errors.Errorf("Function template %s has no parameters",
+				getFunctionTemplateName(functionTemplate))
+		}
+
+		functionConfig := *functionTemplate.FunctionConfig
+		return &functionConfig, nil
+	}
+
+	renderValues := getFunctionTemplateParameterDefaults(functionTemplate)
+	for key, value := range values {
+		if _, found := renderValues[key]; !found {
+			return nil, errors.Errorf("Function template %s has no parameter %s",
+				getFunctionTemplateName(functionTemplate),
+				key)
+		}
+
+		renderValues[key] = value
+	}
+
+	renderedFunctionConfig, err := nuctl_common.RenderFunctionConfig([]byte(functionTemplate.FunctionConfigTemplate),
+		renderValues,
+		false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to render function template")
+	}
+
+	functionConfig := functionconfig.Config{}
+	if err := yaml.Unmarshal(renderedFunctionConfig, &functionConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode rendered function template")
+	}
+
+	// templates may leave the source code out of their configuration
+	if functionConfig.Spec.Build.FunctionSourceCode == "" && functionTemplate.SourceCode != "" {
+		functionConfig.Spec.Build.FunctionSourceCode = base64.StdEncoding.EncodeToString(
+			[]byte(functionTemplate.SourceCode))
+	}
+
+	return &functionConfig, nil
+}
+
+// parseTemplateValues parses values given as key=value
+func parseTemplateValues(templateValues []string) (map[string]string, error) {
+	values := map[string]string{}
+
+	for _, templateValue := range templateValues {
+		keyAndValue := strings.SplitN(templateValue, "=", 2)
+		if len(keyAndValue) != 2 || keyAndValue[0] == "" {
+			return nil, errors.Errorf("Value %s not in the format of key=value", templateValue)
+		}
+
+		values[keyAndValue[0]] = keyAndValue[1]
+	}
+
+	return values, nil
+}
+
+// getFunctionTemplateName returns the name of the template, without the unique suffix of built-in templates
+func getFunctionTemplateName(functionTemplate *functiontemplates.FunctionTemplate) string {
+	return strings.Split(functionTemplate.Name, ":")[0]
+}
+
+// getFunctionTemplateParameterDefaults returns the default value of every parameter of the template. a parameter
+// is either given with its value, or described for the dashboard with its default under attributes.defaultValue
+func getFunctionTemplateParameterDefaults(functionTemplate *functiontemplates.FunctionTemplate) map[string]string {
+	defaults := map[string]string{}
+
+	for parameterName, parameter := range functionTemplate.FunctionConfigValues {
+		var defaultValue interface{} = parameter
+
+		if parameterDescription, isDescription := parameter.(map[string]interface{}); isDescription {
+			defaultValue = nil
+
+			if attributes, ok := parameterDescription["attributes"].(map[string]interface{}); ok {
+				defaultValue = attributes["defaultValue"]
+			}
+		}
+
+		if defaultValue == nil {
+			defaults[parameterName] = ""
+			continue
+		}
+
+		defaults[parameterName] = fmt.Sprint(defaultValue)
+	}
+
+	return defaults
+}
+
+// getFunctionTemplateRuntime returns the runtime of the template. the runtime of a parameterized template is
+// known only once rendered, so it's rendered with the parameters' defaults
+func getFunctionTemplateRuntime(functionTemplate *functiontemplates.FunctionTemplate) string {
+	if functionTemplate.FunctionConfigTemplate == "" {
+		return functionTemplate.FunctionConfig.Spec.Runtime
+	}
+
+	renderedFunctionConfig, err := nuctl_common.RenderFunctionConfig([]byte(functionTemplate.FunctionConfigTemplate),
+		getFunctionTemplateParameterDefaults(functionTemplate),
+		true)
+	if err != nil {
+		return ""
+	}
+
+	functionConfig := functionconfig.Config{}
+	if err := yaml.Unmarshal(renderedFunctionConfig, &functionConfig); err != nil {
+		return ""
+	}
+
+	return functionConfig.Spec.Runtime
+}
+
+func getFunctionTemplateInfo(functionTemplate *functiontemplates.FunctionTemplate) functionTemplateInfo {
+	info := functionTemplateInfo{
+		Name:        getFunctionTemplateName(functionTemplate),
+		DisplayName: functionTemplate.DisplayName,
+		Runtime:     getFunctionTemplateRuntime(functionTemplate),
+	}
+
+	if functionTemplate.FunctionConfigTemplate != "" {
+		info.Parameters = getFunctionTemplateParameterDefaults(functionTemplate)
+	}
+
+	return info
+}
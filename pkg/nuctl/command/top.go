@@ -0,0 +1,176 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/renderer"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type topCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newTopCommandeer(rootCommandeer *RootCommandeer) *topCommandeer {
+	commandeer := &topCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Display resource usage",
+	}
+
+	cmd.AddCommand(
+		newTopFunctionCommandeer(commandeer).cmd,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type topFunctionCommandeer struct {
+	*topCommandeer
+	getFunctionUsageOptions platform.GetFunctionUsageOptions
+}
+
+func newTopFunctionCommandeer(topCommandeer *topCommandeer) *topFunctionCommandeer {
+	commandeer := &topFunctionCommandeer{
+		topCommandeer: topCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "function [name]",
+		Aliases: []string{"fu", "fn"},
+		Short:   "Display the resource usage and event rates of functions",
+		Long: `Display the CPU, memory, replicas, handled events per second and error rate of functions
+(all functions in the namespace, if a name isn't given).
+
+On kube, CPU and memory are read from metrics-server, and event rates from the prometheus server
+scraping the functions (--prometheus-url). On local, CPU and memory are read from docker stats, and
+event rates are calculated by sampling the functions' processors (--interval apart).
+Metrics which can't be read are displayed as "-"`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.getFunctionUsageOptions.SampleInterval <= 0 {
+				return errors.New("--interval must be positive")
+			}
+
+			rootCommandeer := topCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if len(args) > 0 {
+				commandeer.getFunctionUsageOptions.Name = args[0]
+			}
+
+			commandeer.getFunctionUsageOptions.Namespace = rootCommandeer.namespace
+
+			functionUsages, err := rootCommandeer.platform.GetFunctionUsage(&commandeer.getFunctionUsageOptions)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get function usage")
+			}
+
+			sort.Slice(functionUsages, func(i, j int) bool {
+				return functionUsages[i].Name < functionUsages[j].Name
+			})
+
+			if rootCommandeer.isJSONOutput() {
+				if functionUsages == nil {
+					functionUsages = []platform.FunctionUsage{}
+				}
+
+				return rootCommandeer.renderResult(cmd.OutOrStdout(), functionUsages)
+			}
+
+			if len(functionUsages) == 0 {
+				cmd.Println("No functions found")
+				return nil
+			}
+
+			commandeer.renderFunctionUsages(cmd, functionUsages)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&commandeer.getFunctionUsageOptions.SampleInterval, "interval", 2*time.Second, "Period over which event rates are sampled (local platform)")
+	cmd.Flags().StringVar(&commandeer.getFunctionUsageOptions.PrometheusURL, "prometheus-url", os.Getenv("NUCTL_PROMETHEUS_URL"), "URL of the prometheus server scraping the functions, for event rates (kube platform, env: NUCTL_PROMETHEUS_URL)")
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (t *topFunctionCommandeer) renderFunctionUsages(cmd *cobra.Command, functionUsages []platform.FunctionUsage) {
+	var records [][]string
+	for _, functionUsage := range functionUsages {
+		record := []string{
+			functionUsage.Namespace,
+			functionUsage.Name,
+			strconv.Itoa(functionUsage.Replicas),
+			"-",
+			"-",
+			"-",
+			"-",
+		}
+
+		if functionUsage.CPUMillicores != nil {
+			record[3] = fmt.Sprintf("%dm", *functionUsage.CPUMillicores)
+		}
+
+		if functionUsage.MemoryBytes != nil {
+			record[4] = fmt.Sprintf("%dMi", *functionUsage.MemoryBytes/(1024*1024))
+		}
+
+		if functionUsage.EventsPerSecond != nil {
+			record[5] = fmt.Sprintf("%.2f", *functionUsage.EventsPerSecond)
+		}
+
+		if functionUsage.ErrorRate != nil {
+			record[6] = fmt.Sprintf("%.1f%%", *functionUsage.ErrorRate*100)
+		}
+
+		records = append(records, record)
+	}
+
+	renderer.NewRenderer(cmd.OutOrStdout()).RenderTable([]string{
+		"Namespace",
+		"Name",
+		"Replicas",
+		"CPU",
+		"Memory",
+		"Events/s",
+		"Error rate",
+	}, records)
+}
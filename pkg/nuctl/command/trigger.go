@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type triggerCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+}
+
+func newTriggerCommandeer(rootCommandeer *RootCommandeer) *triggerCommandeer {
+	commandeer := &triggerCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "trigger",
+		Aliases: []string{"tr"},
+		Short:   "Control the triggers of running functions",
+	}
+
+	pauseCommand := newTriggerPauseCommandeer(commandeer).cmd
+	resumeCommand := newTriggerResumeCommandeer(commandeer).cmd
+
+	cmd.AddCommand(
+		pauseCommand,
+		resumeCommand,
+	)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type triggerPauseCommandeer struct {
+	*triggerCommandeer
+	cmd *cobra.Command
+}
+
+func newTriggerPauseCommandeer(triggerCommandeer *triggerCommandeer) *triggerPauseCommandeer {
+	commandeer := &triggerPauseCommandeer{
+		triggerCommandeer: triggerCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "pause function-name trigger-name",
+		Short: "Stop a function's trigger from consuming events, leaving the function running",
+		Long: `Stop a function's trigger from consuming events, leaving the function running.
+
+The trigger stops (e.g. a Kafka trigger leaves its consumer group, and stream triggers stop polling) and its
+workers are released, while the function's other triggers keep handling events. The trigger is marked paused
+in the function's configuration, so it stays paused when the function's replicas restart, until it's resumed
+with "nuctl trigger resume". Redeploying the function applies the triggers of the deployed configuration`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCommandeer := triggerCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if err := rootCommandeer.platform.PauseFunctionTrigger(&platform.PauseFunctionTriggerOptions{
+				Name:        args[0],
+				Namespace:   rootCommandeer.namespace,
+				TriggerName: args[1],
+			}); err != nil {
+				return errors.Wrap(err, "Failed to pause function trigger")
+			}
+
+			rootCommandeer.loggerInstance.InfoWith("Function trigger paused", "name", args[0], "trigger", args[1])
+
+			return nil
+		},
+	}
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+type triggerResumeCommandeer struct {
+	*triggerCommandeer
+	cmd *cobra.Command
+}
+
+func newTriggerResumeCommandeer(triggerCommandeer *triggerCommandeer) *triggerResumeCommandeer {
+	commandeer := &triggerResumeCommandeer{
+		triggerCommandeer: triggerCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "resume function-name trigger-name",
+		Short: "Have a paused trigger of a function consume events again",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCommandeer := triggerCommandeer.rootCommandeer
+
+			// initialize root
+			if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			if err := rootCommandeer.platform.ResumeFunctionTrigger(&platform.ResumeFunctionTriggerOptions{
+				Name:        args[0],
+				Namespace:   rootCommandeer.namespace,
+				TriggerName: args[1],
+			}); err != nil {
+				return errors.Wrap(err, "Failed to resume function trigger")
+			}
+
+			rootCommandeer.loggerInstance.InfoWith("Function trigger resumed", "name", args[0], "trigger", args[1])
+
+			return nil
+		},
+	}
+
+	completeFunctionName(cmd)
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
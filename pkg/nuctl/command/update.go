@@ -18,12 +18,15 @@ package command
 
 import (
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
 	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/spf13/cobra"
 	"k8s.io/api/core/v1"
 )
@@ -45,8 +48,12 @@ func newUpdateCommandeer(rootCommandeer *RootCommandeer) *updateCommandeer {
 		Short:   "Update resources",
 	}
 
+	updateFunctionCommand := newUpdateFunctionCommandeer(commandeer).cmd
+	updateProjectCommand := newUpdateProjectCommandeer(commandeer).cmd
+
 	cmd.AddCommand(
-		newUpdateFunctionCommandeer(commandeer).cmd,
+		updateFunctionCommand,
+		updateProjectCommand,
 	)
 
 	commandeer.cmd = cmd
@@ -54,6 +61,94 @@ func newUpdateCommandeer(rootCommandeer *RootCommandeer) *updateCommandeer {
 	return commandeer
 }
 
+type updateProjectCommandeer struct {
+	*updateCommandeer
+	description  string
+	specFilePath string
+}
+
+func newUpdateProjectCommandeer(updateCommandeer *updateCommandeer) *updateProjectCommandeer {
+	commandeer := &updateProjectCommandeer{
+		updateCommandeer: updateCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "project [namespace/]name",
+		Aliases: []string{"proj", "prj"},
+		Short:   "Update projects",
+		Long: `Update projects
+
+The fields the spec file sets (e.g. defaults, quotas) replace those of the project, and the rest are left as is.
+Functions are checked against the project's defaults and quotas when they're next deployed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// if we got positional arguments
+			if len(args) != 1 {
+				return errors.New("Project update requires an identifier")
+			}
+
+			if commandeer.specFilePath == "" && !cmd.Flags().Changed("description") {
+				return errors.New("Nothing to update - pass --spec-file and/or --description")
+			}
+
+			// initialize root
+			if err := updateCommandeer.rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			namespace, name, err := updateCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			projects, err := updateCommandeer.rootCommandeer.platform.GetProjects(&platform.GetProjectsOptions{
+				Meta: platform.ProjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+			if err != nil {
+				return errors.Wrap(err, "Failed to get projects")
+			}
+
+			if len(projects) == 0 {
+				return nuclio.NewErrNotFound("Project not found")
+			}
+
+			projectConfig := *projects[0].GetConfig()
+			projects[0].GetConfig().Spec.DeepCopyInto(&projectConfig.Spec)
+
+			if commandeer.specFilePath != "" {
+				specFileProjectSpec, err := readProjectSpecFile(commandeer.specFilePath)
+				if err != nil {
+					return errors.Wrap(err, "Failed to read project spec file")
+				}
+
+				applyProjectSpec(&projectConfig.Spec, specFileProjectSpec, cmd)
+			}
+
+			if cmd.Flags().Changed("description") {
+				projectConfig.Spec.Description = commandeer.description
+			}
+
+			if err := projectConfig.Spec.Validate(); err != nil {
+				return errors.Wrap(err, "Invalid project spec")
+			}
+
+			return updateCommandeer.rootCommandeer.platform.UpdateProject(&platform.UpdateProjectOptions{
+				ProjectConfig: projectConfig,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.description, "description", "", "Project description")
+	cmd.Flags().StringVar(&commandeer.specFilePath, "spec-file", "", "Path to a YAML/JSON project spec, with the defaults and quotas of the project's functions")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
 type updateFunctionCommandeer struct {
 	*updateCommandeer
 	functionConfig      functionconfig.Config
@@ -61,16 +156,22 @@ type updateFunctionCommandeer struct {
 	encodedTriggers     string
 	encodedLabels       string
 	encodedEnv          string
+	disableTriggers     stringSliceFlag
+	enableTriggers      stringSliceFlag
+
+	// functions can only be updated in place on platforms that support it
+	supportedPlatforms []string
 }
 
 func newUpdateFunctionCommandeer(updateCommandeer *updateCommandeer) *updateFunctionCommandeer {
 	commandeer := &updateFunctionCommandeer{
-		updateCommandeer: updateCommandeer,
-		functionConfig:   *functionconfig.NewConfig(),
+		updateCommandeer:   updateCommandeer,
+		functionConfig:     *functionconfig.NewConfig(),
+		supportedPlatforms: kubePlatforms,
 	}
 
 	cmd := &cobra.Command{
-		Use:     "function [name[:version]]",
+		Use:     "function [[namespace/]name[:version]]",
 		Aliases: []string{"fu", "fn"},
 		Short:   "Update functions",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -81,8 +182,18 @@ func newUpdateFunctionCommandeer(updateCommandeer *updateCommandeer) *updateFunc
 			}
 
 			// initialize root
-			if err := updateCommandeer.rootCommandeer.initialize(); err != nil {
-				return errors.Wrap(err, "Failed to initialize root")
+			if err := updateCommandeer.rootCommandeer.initializeForOperation("update function",
+				commandeer.supportedPlatforms); err != nil {
+				return err
+			}
+
+			namespace, name, err := updateCommandeer.rootCommandeer.resolveNamespacedName(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(commandeer.disableTriggers) > 0 || len(commandeer.enableTriggers) > 0 {
+				return commandeer.updateTriggersDisabled(namespace, name)
 			}
 
 			// decode the JSON data bindings
@@ -109,7 +220,8 @@ func newUpdateFunctionCommandeer(updateCommandeer *updateCommandeer) *updateFunc
 			}
 
 			// update stuff
-			commandeer.functionConfig.Meta.Namespace = updateCommandeer.rootCommandeer.namespace
+			commandeer.functionConfig.Meta.Name = name
+			commandeer.functionConfig.Meta.Namespace = namespace
 			commandeer.functionConfig.Spec.Build.Commands = commandeer.commands
 
 			return updateCommandeer.rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
@@ -126,7 +238,87 @@ func newUpdateFunctionCommandeer(updateCommandeer *updateCommandeer) *updateFunc
 	//	&commandeer.encodedDataBindings,
 	//	&commandeer.encodedTriggers)
 
+	cmd.Flags().Var(&commandeer.disableTriggers, "disable-trigger", "Name of a trigger of the live function to disable, leaving the rest of the function as is (can be given more than once)")
+	cmd.Flags().Var(&commandeer.enableTriggers, "enable-trigger", "Name of a disabled trigger of the live function to enable (can be given more than once)")
+
+	completeFunctionName(cmd)
+
 	commandeer.cmd = cmd
 
 	return commandeer
 }
+
+// updateTriggersDisabled disables and enables triggers of the live function, updating it in place
+func (u *updateFunctionCommandeer) updateTriggersDisabled(namespace string, functionName string) error {
+	rootCommandeer := u.rootCommandeer
+
+	functions, err := rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      functionName,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	// update a copy of the live spec, so that nothing but the triggers' disabled state changes
+	functionConfig := functions[0].GetConfig()
+	functionSpec := functionConfig.Spec
+	functionSpec.Triggers = map[string]functionconfig.Trigger{}
+	for triggerName, trigger := range functionConfig.Spec.Triggers {
+		functionSpec.Triggers[triggerName] = trigger
+	}
+
+	disabledByTriggerName := map[string]bool{}
+	for _, triggerName := range u.disableTriggers {
+		disabledByTriggerName[triggerName] = true
+	}
+
+	for _, triggerName := range u.enableTriggers {
+		if disabledByTriggerName[triggerName] {
+			return errors.Errorf("Trigger %s can't be both disabled and enabled", triggerName)
+		}
+
+		disabledByTriggerName[triggerName] = false
+	}
+
+	for triggerName, disabled := range disabledByTriggerName {
+		trigger, found := functionSpec.Triggers[triggerName]
+		if !found {
+			var availableTriggerNames []string
+			for availableTriggerName := range functionSpec.Triggers {
+				availableTriggerNames = append(availableTriggerNames, availableTriggerName)
+			}
+			sort.Strings(availableTriggerNames)
+
+			if len(availableTriggerNames) == 0 {
+				availableTriggerNames = []string{"none"}
+			}
+
+			return errors.Errorf("Function %s has no trigger named %s (available triggers: %s)",
+				functionName,
+				triggerName,
+				strings.Join(availableTriggerNames, ", "))
+		}
+
+		trigger.Disabled = disabled
+		functionSpec.Triggers[triggerName] = trigger
+	}
+
+	if err := rootCommandeer.platform.UpdateFunction(&platform.UpdateFunctionOptions{
+		FunctionMeta: &functionConfig.Meta,
+		FunctionSpec: &functionSpec,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to update function")
+	}
+
+	rootCommandeer.loggerInstance.InfoWith("Function triggers updated",
+		"name", functionName,
+		"disabled", []string(u.disableTriggers),
+		"enabled", []string(u.enableTriggers))
+
+	return nil
+}
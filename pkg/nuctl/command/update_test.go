@@ -0,0 +1,49 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/nuclio/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type updateTestSuite struct {
+	suite.Suite
+}
+
+func (suite *updateTestSuite) TestUpdateFunctionUnsupportedOnLocal() {
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.cmd.SetOutput(ioutil.Discard)
+	rootCommandeer.cmd.SetArgs([]string{"update", "function", "test", "--platform", "local"})
+
+	err := rootCommandeer.Execute()
+	suite.Require().Error(err)
+
+	unsupportedErr, ok := errors.RootCause(err).(*UnsupportedOperationError)
+	suite.Require().True(ok, "Expected an unsupported operation error, got: %s", err.Error())
+	suite.Require().Equal("Operation update function is not supported on the local platform", unsupportedErr.Error())
+
+	// no work should have been done
+	suite.Require().Nil(rootCommandeer.platform)
+}
+
+func TestUpdateTestSuite(t *testing.T) {
+	suite.Run(t, new(updateTestSuite))
+}
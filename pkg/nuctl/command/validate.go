@@ -0,0 +1,266 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+// InvalidFunctionConfigError is returned when a function configuration fails validation
+type InvalidFunctionConfigError struct {
+	Path string
+}
+
+func (e *InvalidFunctionConfigError) Error() string {
+	return fmt.Sprintf("Function configuration %s is invalid", e.Path)
+}
+
+type validateResult struct {
+	Valid  bool                        `json:"valid"`
+	Errors []functionconfig.FieldError `json:"errors"`
+}
+
+type validateCommandeer struct {
+	cmd                *cobra.Command
+	rootCommandeer     *RootCommandeer
+	functionConfigPath string
+	offline            bool
+}
+
+func newValidateCommandeer(rootCommandeer *RootCommandeer) *validateCommandeer {
+	commandeer := &validateCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate -f function-config [function-name]",
+		Short: "Validate a function configuration file without deploying it",
+		Long: fmt.Sprintf(`Validate a function configuration file without deploying it.
+
+The configuration is checked for the problems a deployment would fail on (e.g. trigger attributes of the
+wrong type, invalid resources, reserved names and conflicting ingresses), and then against the platform
+(e.g. ingresses used by other functions) unless --offline is given. All the problems are reported, and
+with -o json as a list of field errors. Exits with %d if the configuration is invalid`, ExitCodeInvalidFunctionConfig),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.functionConfigPath == "" {
+				return errors.New("Function configuration file must be provided (-f)")
+			}
+
+			if commandeer.offline {
+				var err error
+				if rootCommandeer.loggerInstance, err = rootCommandeer.createLogger(); err != nil {
+					return errors.Wrap(err, "Failed to create logger")
+				}
+			} else if err := rootCommandeer.initialize(); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			functionConfig, err := readFunctionConfigFile(commandeer.functionConfigPath, rootCommandeer.namespace)
+			if err != nil {
+				return errors.Wrap(err, "Failed to read function configuration")
+			}
+
+			// function name may be overridden by a positional argument
+			if len(args) == 1 {
+				functionConfig.Meta.Name = args[0]
+			}
+
+			fieldErrors, err := commandeer.validate(functionConfig)
+			if err != nil {
+				return errors.Wrap(err, "Failed to validate function configuration")
+			}
+
+			if err := commandeer.renderResult(cmd, fieldErrors); err != nil {
+				return errors.Wrap(err, "Failed to render result")
+			}
+
+			if len(fieldErrors) != 0 {
+				return &InvalidFunctionConfigError{
+					Path: commandeer.functionConfigPath,
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.functionConfigPath, "file", "f", "", "Path to the function configuration file")
+	cmd.Flags().BoolVar(&commandeer.offline, "offline", false, "Only validate the configuration itself, without checking it against the platform")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+// validate returns all the problems found in the function configuration. an error is returned only if the
+// validation itself failed (e.g. the platform couldn't be queried)
+func (v *validateCommandeer) validate(functionConfig *functionconfig.Config) ([]functionconfig.FieldError, error) {
+	var fieldErrors []functionconfig.FieldError
+
+	if err := functionConfig.Validate(); err != nil {
+		validationError, ok := err.(*functionconfig.ValidationError)
+		if !ok {
+			return nil, errors.Wrap(err, "Failed to validate function configuration")
+		}
+
+		fieldErrors = append(fieldErrors, validationError.FieldErrors...)
+	}
+
+	var triggerNames []string
+	for triggerName := range functionConfig.Spec.Triggers {
+		triggerNames = append(triggerNames, triggerName)
+	}
+	sort.Strings(triggerNames)
+
+	for _, triggerName := range triggerNames {
+		if maxWorkers := functionConfig.Spec.Triggers[triggerName].MaxWorkers; maxWorkers > trigger.MaxWorkersLimit {
+			fieldErrors = append(fieldErrors, functionconfig.FieldError{
+				Field:   fmt.Sprintf("spec.triggers.%s.maxWorkers", triggerName),
+				Message: fmt.Sprintf("must not exceed %d, got %d", trigger.MaxWorkersLimit, maxWorkers),
+			})
+		}
+	}
+
+	if v.offline {
+		return fieldErrors, nil
+	}
+
+	platformFieldErrors, err := v.validateAgainstPlatform(functionConfig, fieldErrors)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to validate function configuration against the platform")
+	}
+
+	return append(fieldErrors, platformFieldErrors...), nil
+}
+
+func (v *validateCommandeer) validateAgainstPlatform(functionConfig *functionconfig.Config,
+	configFieldErrors []functionconfig.FieldError) ([]functionconfig.FieldError, error) {
+	var fieldErrors []functionconfig.FieldError
+
+	if projectName := functionConfig.Meta.Labels["nuclio.io/project-name"]; projectName != "" {
+		projects, err := v.rootCommandeer.platform.GetProjects(&platform.GetProjectsOptions{
+			Meta: platform.ProjectMeta{
+				Name:      projectName,
+				Namespace: functionConfig.Meta.Namespace,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get projects")
+		}
+
+		if len(projects) == 0 {
+			fieldErrors = append(fieldErrors, functionconfig.FieldError{
+				Field:   "metadata.labels.nuclio.io/project-name",
+				Message: fmt.Sprintf("project %s does not exist", projectName),
+			})
+		}
+	}
+
+	// a malformed ingress was already reported, and can't be compared with those of other functions
+	for _, configFieldError := range configFieldErrors {
+		if strings.Contains(configFieldError.Field, ".attributes.ingresses") {
+			return fieldErrors, nil
+		}
+	}
+
+	functions, err := v.rootCommandeer.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Namespace: functionConfig.Meta.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	// the host and path of every ingress of the other functions
+	functionNamesByHostPath := map[string]string{}
+	for _, function := range functions {
+		otherFunctionConfig := function.GetConfig()
+		if otherFunctionConfig.Meta.Name == functionConfig.Meta.Name {
+			continue
+		}
+
+		for _, ingress := range functionconfig.GetIngressesFromTriggers(otherFunctionConfig.Spec.Triggers) {
+			for _, path := range ingress.Paths {
+				functionNamesByHostPath[ingress.Host+path] = otherFunctionConfig.Meta.Name
+			}
+		}
+	}
+
+	for triggerName, httpTrigger := range functionconfig.GetTriggersByKind(functionConfig.Spec.Triggers, "http") {
+		ingresses := functionconfig.GetIngressesFromTriggers(map[string]functionconfig.Trigger{
+			triggerName: httpTrigger,
+		})
+
+		var ingressNames []string
+		for ingressName := range ingresses {
+			ingressNames = append(ingressNames, ingressName)
+		}
+		sort.Strings(ingressNames)
+
+		for _, ingressName := range ingressNames {
+			ingress := ingresses[ingressName]
+			for _, path := range ingress.Paths {
+				if otherFunctionName, found := functionNamesByHostPath[ingress.Host+path]; found {
+					fieldErrors = append(fieldErrors, functionconfig.FieldError{
+						Field: fmt.Sprintf("spec.triggers.%s.attributes.ingresses.%s.paths", triggerName, ingressName),
+						Message: fmt.Sprintf("%s is already routed to function %s",
+							ingress.Host+path,
+							otherFunctionName),
+					})
+				}
+			}
+		}
+	}
+
+	return fieldErrors, nil
+}
+
+func (v *validateCommandeer) renderResult(cmd *cobra.Command, fieldErrors []functionconfig.FieldError) error {
+	if v.rootCommandeer.isJSONOutput() {
+		result := validateResult{
+			Valid:  len(fieldErrors) == 0,
+			Errors: fieldErrors,
+		}
+
+		// an empty list rather than null, so that consumers can iterate it as is
+		if result.Errors == nil {
+			result.Errors = []functionconfig.FieldError{}
+		}
+
+		return v.rootCommandeer.renderResult(cmd.OutOrStdout(), result)
+	}
+
+	if len(fieldErrors) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", v.functionConfigPath) // nolint: errcheck
+		return nil
+	}
+
+	for _, fieldError := range fieldErrors {
+		fmt.Fprintln(cmd.OutOrStdout(), fieldError.Error()) // nolint: errcheck
+	}
+
+	return nil
+}
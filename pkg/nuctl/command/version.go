@@ -19,14 +19,18 @@ package command
 import (
 	"fmt"
 
+	"github.com/nuclio/nuclio/pkg/nuctl/command/common"
+	"github.com/nuclio/nuclio/pkg/renderer"
 	"github.com/nuclio/nuclio/pkg/version"
 
+	"github.com/nuclio/errors"
 	"github.com/spf13/cobra"
 )
 
 type versionCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
+	output         string
 }
 
 func newVersionCommandeer(rootCommandeer *RootCommandeer) *versionCommandeer {
@@ -46,12 +50,25 @@ func newVersionCommandeer(rootCommandeer *RootCommandeer) *versionCommandeer {
 				return err
 			}
 
-			fmt.Printf("Client version:\n%#v", currentVersion)
+			rendererInstance := renderer.NewRenderer(cmd.OutOrStdout())
+
+			switch commandeer.output {
+			case common.OutputFormatText:
+				fmt.Fprintf(cmd.OutOrStdout(), "Client version:\n%#v", currentVersion) // nolint: errcheck
+			case common.OutputFormatJSON:
+				return rendererInstance.RenderJSON(currentVersion)
+			case common.OutputFormatYAML:
+				return rendererInstance.RenderYAML(currentVersion)
+			default:
+				return errors.Errorf("Invalid output format: %s", commandeer.output)
+			}
 
 			return nil
 		},
 	}
 
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", common.OutputFormatText, "Output format - \"text\", \"yaml\", or \"json\"")
+
 	commandeer.cmd = cmd
 
 	return commandeer
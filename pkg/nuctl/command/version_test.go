@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/version"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type versionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *versionTestSuite) TestOutputJSON() {
+	versionInfo := version.Info{
+		Label:     "1.2.3",
+		GitCommit: "abcdef",
+		OS:        "linux",
+		Arch:      "amd64",
+		GoVersion: "go1.14",
+		BuildTime: "2020-01-01T00:00:00Z",
+	}
+
+	version.Set(&versionInfo)
+
+	outputBuffer := bytes.Buffer{}
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.GetCmd().SetOut(&outputBuffer)
+	rootCommandeer.GetCmd().SetArgs([]string{"version", "--output", "json"})
+
+	err := rootCommandeer.Execute()
+	suite.Require().NoError(err)
+
+	outputVersionInfo := version.Info{}
+	err = json.Unmarshal(outputBuffer.Bytes(), &outputVersionInfo)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(versionInfo, outputVersionInfo)
+}
+
+func (suite *versionTestSuite) TestOutputInvalid() {
+	version.Set(&version.Info{Label: "1.2.3"})
+
+	rootCommandeer := NewRootCommandeer()
+	rootCommandeer.GetCmd().SetOut(&bytes.Buffer{})
+	rootCommandeer.GetCmd().SetArgs([]string{"version", "--output", "xml"})
+
+	err := rootCommandeer.Execute()
+	suite.Require().Error(err)
+}
+
+func TestVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(versionTestSuite))
+}
@@ -0,0 +1,631 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/kube"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+	// register the runtimes functions are built with
+	_ "github.com/nuclio/nuclio/pkg/processor/build"
+	"github.com/nuclio/nuclio/pkg/version"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	authorization_v1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// the oldest docker daemon that can build function images (multi-stage builds)
+const minimumDockerVersion = "17.05"
+
+// how long a registry is given to respond to a single request
+const registryRequestTimeout = 10 * time.Second
+
+// CheckStatus is the outcome of a check
+type CheckStatus string
+
+const (
+	CheckStatusOK      CheckStatus = "ok"
+	CheckStatusWarning CheckStatus = "warning"
+	CheckStatusError   CheckStatus = "error"
+	CheckStatusSkipped CheckStatus = "skipped"
+)
+
+// CheckResult is the outcome of a single check, and what to do about it if it didn't pass
+type CheckResult struct {
+	Name        string      `json:"name"`
+	Status      CheckStatus `json:"status"`
+	Message     string      `json:"message"`
+	Remediation string      `json:"remediation,omitempty"`
+}
+
+// Options are what the checks are run against
+type Options struct {
+	PlatformName        string
+	Namespace           string
+	KubeconfigPath      string
+	KubeContext         string
+	Registry            string
+	RegistryCredentials *dockercreds.Credentials
+
+	// the runtimes whose onbuild images are checked (default - all of them)
+	Runtimes []string
+}
+
+// Doctor checks the environment functions are built and deployed in for the problems first deployments
+// usually fail on - an unreachable docker daemon or registry, a missing kube context or permissions,
+// a bad platform configuration or unavailable onbuild images
+type Doctor struct {
+	logger                        logger.Logger
+	cmdRunner                     cmdrunner.CmdRunner
+	httpClient                    *http.Client
+	containerBuilderConfiguration *containerimagebuilderpusher.ContainerBuilderConfiguration
+
+	// overridden by tests
+	createKubeClientSet func(options *Options) (kubernetes.Interface, string, error)
+}
+
+// NewDoctor creates a doctor
+func NewDoctor(parentLogger logger.Logger,
+	cmdRunner cmdrunner.CmdRunner,
+	containerBuilderConfiguration *containerimagebuilderpusher.ContainerBuilderConfiguration) *Doctor {
+	newDoctor := &Doctor{
+		logger:                        parentLogger.GetChild("doctor"),
+		cmdRunner:                     cmdRunner,
+		httpClient:                    &http.Client{Timeout: registryRequestTimeout},
+		containerBuilderConfiguration: containerBuilderConfiguration,
+	}
+
+	newDoctor.createKubeClientSet = newDoctor.createKubeClientSetFromKubeconfig
+
+	return newDoctor
+}
+
+// Run runs the checks, returning their results in the order they ran
+func (d *Doctor) Run(options *Options) []CheckResult {
+	platformName, platformResult := d.checkPlatformConfiguration(options)
+
+	results := []CheckResult{
+		platformResult,
+		d.checkDocker(platformName),
+	}
+
+	if platformName == "kube" {
+		results = append(results, d.checkKube(options)...)
+	}
+
+	results = append(results, d.checkRegistry(platformName, options))
+	results = append(results, d.checkOnbuildImages(options)...)
+
+	return results
+}
+
+// GetFailedChecks returns the number of checks that failed
+func GetFailedChecks(results []CheckResult) int {
+	failedChecks := 0
+
+	for _, result := range results {
+		if result.Status == CheckStatusError {
+			failedChecks++
+		}
+	}
+
+	return failedChecks
+}
+
+// checkPlatformConfiguration validates the platform configuration, and returns the platform it resolves to
+func (d *Doctor) checkPlatformConfiguration(options *Options) (string, CheckResult) {
+	result := CheckResult{Name: "platform configuration"}
+	platformName := options.PlatformName
+
+	switch platformName {
+	case "local", "kube":
+	case "auto":
+		if options.KubeconfigPath != "" || kube.IsInCluster() {
+			platformName = "kube"
+		} else {
+			platformName = "local"
+		}
+	default:
+		result.Status = CheckStatusError
+		result.Message = fmt.Sprintf("Unsupported platform %s", platformName)
+		result.Remediation = "Pass --platform local, kube or auto (or set NUCTL_PLATFORM to one of them)"
+		return platformName, result
+	}
+
+	builderKind := d.containerBuilderConfiguration.Kind
+	switch {
+	case builderKind != "docker" && builderKind != "kaniko":
+		result.Status = CheckStatusError
+		result.Message = fmt.Sprintf("Unsupported container builder %s", builderKind)
+		result.Remediation = "Set NUCLIO_CONTAINER_BUILDER_KIND to docker or kaniko"
+		return platformName, result
+
+	case builderKind == "kaniko" && platformName != "kube":
+		result.Status = CheckStatusError
+		result.Message = "The kaniko container builder is only supported on the kube platform"
+		result.Remediation = "Unset NUCLIO_CONTAINER_BUILDER_KIND, or set it to docker"
+		return platformName, result
+	}
+
+	if platformName == "kube" && options.KubeconfigPath != "" {
+		if !common.FileExists(options.KubeconfigPath) {
+			result.Status = CheckStatusError
+			result.Message = fmt.Sprintf("Kubeconfig %s doesn't exist", options.KubeconfigPath)
+			result.Remediation = "Pass an existing kubeconfig with --kubeconfig, or set KUBECONFIG to one"
+			return platformName, result
+		}
+
+		kubeconfig, err := clientcmd.LoadFromFile(options.KubeconfigPath)
+		if err != nil {
+			result.Status = CheckStatusError
+			result.Message = fmt.Sprintf("Failed to load kubeconfig %s: %s",
+				options.KubeconfigPath,
+				errors.RootCause(err).Error())
+			result.Remediation = "Fix the kubeconfig, or pass another one with --kubeconfig"
+			return platformName, result
+		}
+
+		if options.KubeContext != "" {
+			if _, contextFound := kubeconfig.Contexts[options.KubeContext]; !contextFound {
+				result.Status = CheckStatusError
+				result.Message = fmt.Sprintf("Kubeconfig %s has no context %s",
+					options.KubeconfigPath,
+					options.KubeContext)
+				result.Remediation = "List the contexts with 'kubectl config get-contexts' and pass one of them"
+				return platformName, result
+			}
+		} else if kubeconfig.CurrentContext == "" {
+			result.Status = CheckStatusError
+			result.Message = fmt.Sprintf("Kubeconfig %s has no current context", options.KubeconfigPath)
+			result.Remediation = "Select a context with 'kubectl config use-context <context>'"
+			return platformName, result
+		}
+	}
+
+	result.Status = CheckStatusOK
+	result.Message = fmt.Sprintf("Using the %s platform, building images with %s", platformName, builderKind)
+
+	return platformName, result
+}
+
+func (d *Doctor) checkDocker(platformName string) CheckResult {
+	result := CheckResult{Name: "docker"}
+
+	// functions deployed on kube are built by kaniko, in the cluster
+	dockerRequired := platformName != "kube" || d.containerBuilderConfiguration.Kind == "docker"
+
+	runResult, err := d.cmdRunner.Run(nil, "docker version --format '{{.Server.Version}}'")
+	if err != nil {
+		output := runResult.Output + runResult.Stderr
+
+		switch {
+		case !dockerRequired:
+			result.Status = CheckStatusSkipped
+			result.Message = "Docker isn't available, and isn't required - images are built in the cluster"
+			return result
+
+		case runResult.ExitCode == 127 || strings.Contains(strings.ToLower(output), "not found"):
+			result.Message = "The docker CLI isn't installed"
+			result.Remediation = "Install docker (https://docs.docker.com/get-docker/)"
+
+		case strings.Contains(strings.ToLower(output), "permission denied"):
+			result.Message = "Not permitted to connect to the docker daemon"
+			result.Remediation = "Add your user to the docker group ('sudo usermod -aG docker $USER') and log in again"
+
+		default:
+			result.Message = fmt.Sprintf("Can't connect to the docker daemon: %s", getLastLine(output))
+			result.Remediation = "Start the docker daemon, or point DOCKER_HOST at a running one"
+		}
+
+		result.Status = CheckStatusError
+		return result
+	}
+
+	serverVersion := strings.TrimSpace(runResult.Output)
+	if compareVersions(serverVersion, minimumDockerVersion) < 0 {
+		result.Status = CheckStatusError
+		result.Message = fmt.Sprintf("Docker daemon version %s is older than %s", serverVersion, minimumDockerVersion)
+		result.Remediation = fmt.Sprintf("Upgrade docker to %s or newer", minimumDockerVersion)
+		return result
+	}
+
+	result.Status = CheckStatusOK
+	result.Message = fmt.Sprintf("Docker daemon %s is reachable", serverVersion)
+
+	return result
+}
+
+// checkKube checks the kube context is reachable, has nuclio installed and permits deploying functions
+func (d *Doctor) checkKube(options *Options) []CheckResult {
+	contextResult := CheckResult{Name: "kube context"}
+	permissionsResult := CheckResult{Name: "kube permissions"}
+
+	kubeClientSet, contextName, err := d.createKubeClientSet(options)
+	if err != nil {
+		contextResult.Status = CheckStatusError
+		contextResult.Message = fmt.Sprintf("Failed to load kube context: %s", errors.RootCause(err).Error())
+		contextResult.Remediation = "Pass a kubeconfig with --kubeconfig (or set KUBECONFIG), and verify 'kubectl get pods' works"
+		permissionsResult.Status = CheckStatusSkipped
+		permissionsResult.Message = "The kube context couldn't be loaded"
+		return []CheckResult{contextResult, permissionsResult}
+	}
+
+	serverVersion, err := kubeClientSet.Discovery().ServerVersion()
+	if err != nil {
+		contextResult.Status = CheckStatusError
+		contextResult.Message = fmt.Sprintf("Can't reach the cluster of context %s: %s",
+			contextName,
+			errors.RootCause(err).Error())
+		contextResult.Remediation = "Verify the cluster is up and 'kubectl get pods' works, or switch context with --kubeconfig"
+		permissionsResult.Status = CheckStatusSkipped
+		permissionsResult.Message = "The cluster isn't reachable"
+		return []CheckResult{contextResult, permissionsResult}
+	}
+
+	if _, err := kubeClientSet.Discovery().ServerResourcesForGroupVersion("nuclio.io/v1beta1"); err != nil {
+		contextResult.Status = CheckStatusError
+		contextResult.Message = fmt.Sprintf("Nuclio isn't installed in the cluster of context %s (no nuclio.io resources)",
+			contextName)
+		contextResult.Remediation = "Install nuclio in the cluster (see docs/setup/k8s/getting-started-k8s.md)"
+	} else {
+		contextResult.Status = CheckStatusOK
+		contextResult.Message = fmt.Sprintf("Reached cluster of context %s (Kubernetes %s)",
+			contextName,
+			serverVersion.GitVersion)
+	}
+
+	permissionsResult = d.checkKubePermissions(kubeClientSet, options.Namespace)
+
+	return []CheckResult{contextResult, permissionsResult}
+}
+
+func (d *Doctor) checkKubePermissions(kubeClientSet kubernetes.Interface, namespace string) CheckResult {
+	result := CheckResult{Name: "kube permissions"}
+
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	var deniedPermissions []string
+
+	for _, permission := range []struct {
+		group       string
+		resource    string
+		subresource string
+		verbs       []string
+	}{
+		{group: "nuclio.io", resource: "nucliofunctions", verbs: []string{"create", "get", "list", "update", "delete"}},
+		{group: "nuclio.io", resource: "nuclioprojects", verbs: []string{"create", "get", "list"}},
+		{group: "nuclio.io", resource: "nucliofunctionevents", verbs: []string{"get", "list"}},
+		{resource: "pods", verbs: []string{"list"}},
+		{resource: "pods", subresource: "log", verbs: []string{"get"}},
+	} {
+		for _, verb := range permission.verbs {
+			accessReview, err := kubeClientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(
+				&authorization_v1.SelfSubjectAccessReview{
+					Spec: authorization_v1.SelfSubjectAccessReviewSpec{
+						ResourceAttributes: &authorization_v1.ResourceAttributes{
+							Namespace:   namespace,
+							Verb:        verb,
+							Group:       permission.group,
+							Resource:    permission.resource,
+							Subresource: permission.subresource,
+						},
+					},
+				})
+			if err != nil {
+				result.Status = CheckStatusWarning
+				result.Message = fmt.Sprintf("Failed to review permissions: %s", errors.RootCause(err).Error())
+				return result
+			}
+
+			if !accessReview.Status.Allowed {
+				resource := permission.resource
+				if permission.subresource != "" {
+					resource = fmt.Sprintf("%s/%s", resource, permission.subresource)
+				}
+
+				deniedPermissions = append(deniedPermissions, fmt.Sprintf("%s %s", verb, resource))
+			}
+		}
+	}
+
+	if len(deniedPermissions) > 0 {
+		result.Status = CheckStatusError
+		result.Message = fmt.Sprintf("Not permitted to %s in namespace %s",
+			strings.Join(deniedPermissions, ", "),
+			namespace)
+		result.Remediation = "Ask a cluster admin to bind you to a role granting these permissions " +
+			"(see hack/k8s/resources/nuclio-rbac.yaml), or pass the namespace nuclio runs in with --namespace"
+		return result
+	}
+
+	result.Status = CheckStatusOK
+	result.Message = fmt.Sprintf("Permitted to deploy functions in namespace %s", namespace)
+
+	return result
+}
+
+func (d *Doctor) checkRegistry(platformName string, options *Options) CheckResult {
+	result := CheckResult{Name: "registry"}
+
+	if options.Registry == "" {
+		if platformName == "kube" {
+			result.Status = CheckStatusError
+			result.Message = "No registry was given - functions deployed on kube must be pushed to one"
+			result.Remediation = "Pass the registry the cluster pulls images from with --registry (or set NUCTL_REGISTRY)"
+			return result
+		}
+
+		result.Status = CheckStatusSkipped
+		result.Message = "No registry was given - images are only built locally"
+		return result
+	}
+
+	registryHost := getRegistryAPIHost(options.Registry)
+	client := &registryClient{
+		httpClient:  d.httpClient,
+		credentials: options.RegistryCredentials,
+	}
+
+	baseURL, err := client.ping(registryHost)
+	if err != nil {
+		result.Status = CheckStatusError
+		result.Message = fmt.Sprintf("%s: %s", registryHost, errors.RootCause(err).Error())
+
+		if baseURL == "" {
+			result.Remediation = "Verify the registry's address, and that it's reachable from this machine (e.g. through a proxy)"
+		} else {
+			result.Remediation = fmt.Sprintf("Log in with 'docker login %s', or pass --registry-user and --registry-password",
+				registryHost)
+		}
+
+		return result
+	}
+
+	credentialsDescription := "anonymously"
+	if options.RegistryCredentials != nil {
+		credentialsDescription = fmt.Sprintf("as %s", options.RegistryCredentials.Username)
+	}
+
+	if strings.HasPrefix(baseURL, "http://") {
+		result.Status = CheckStatusWarning
+		result.Message = fmt.Sprintf("%s is reachable %s, but only over plain HTTP", registryHost, credentialsDescription)
+		result.Remediation = fmt.Sprintf("Add %s to the docker daemon's insecure-registries", registryHost)
+		return result
+	}
+
+	result.Status = CheckStatusOK
+	result.Message = fmt.Sprintf("%s is reachable %s", registryHost, credentialsDescription)
+
+	if options.RegistryCredentials == nil && platformName == "kube" {
+		result.Status = CheckStatusWarning
+		result.Remediation = fmt.Sprintf("If pushing to the registry requires credentials, log in with 'docker login %s'",
+			registryHost)
+	}
+
+	return result
+}
+
+// checkOnbuildImages checks the onbuild images of the runtimes are present locally, or can be pulled
+func (d *Doctor) checkOnbuildImages(options *Options) []CheckResult {
+	var results []CheckResult
+
+	versionInfo, err := version.Get()
+	if err != nil {
+		return []CheckResult{{
+			Name:    "onbuild images",
+			Status:  CheckStatusWarning,
+			Message: fmt.Sprintf("Failed to get version: %s", errors.RootCause(err).Error()),
+		}}
+	}
+
+	runtimeNames := options.Runtimes
+	if len(runtimeNames) == 0 {
+		runtimeNames = runtime.RuntimeRegistrySingleton.GetKinds()
+	}
+
+	onbuildImageRegistry := d.containerBuilderConfiguration.DefaultOnbuildRegistryURL
+
+	for _, runtimeName := range runtimeNames {
+		result := CheckResult{Name: fmt.Sprintf("onbuild images (%s)", runtimeName)}
+
+		onbuildImages, err := d.getOnbuildImages(runtimeName, versionInfo, onbuildImageRegistry)
+		if err != nil {
+			result.Status = CheckStatusError
+			result.Message = errors.RootCause(err).Error()
+			results = append(results, result)
+			continue
+		}
+
+		var missingImages []string
+		for _, onbuildImage := range onbuildImages {
+			available, err := d.isImageAvailable(onbuildImage)
+			if err != nil {
+				result.Status = CheckStatusWarning
+				result.Message = fmt.Sprintf("Failed to check %s: %s", onbuildImage, errors.RootCause(err).Error())
+				break
+			}
+
+			if !available {
+				missingImages = append(missingImages, onbuildImage)
+			}
+		}
+
+		switch {
+		case result.Status != "":
+		case len(missingImages) > 0:
+			result.Status = CheckStatusError
+			result.Message = fmt.Sprintf("Not found locally or in the registry: %s", strings.Join(missingImages, ", "))
+			result.Remediation = "Pull or load the images, or point NUCLIO_DASHBOARD_DEFAULT_ONBUILD_REGISTRY_URL " +
+				"at a registry that has them"
+		default:
+			result.Status = CheckStatusOK
+			result.Message = fmt.Sprintf("%d onbuild images available", len(onbuildImages))
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func (d *Doctor) getOnbuildImages(runtimeName string,
+	versionInfo *version.Info,
+	onbuildImageRegistry string) ([]string, error) {
+
+	runtimeFactory, err := runtime.RuntimeRegistrySingleton.Get(strings.Split(runtimeName, ":")[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unknown runtime %s", runtimeName)
+	}
+
+	stagingDir, err := ioutil.TempDir("", "nuctl-doctor-")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create staging directory")
+	}
+
+	defer os.RemoveAll(stagingDir) // nolint: errcheck
+
+	runtimeInstance, err := runtimeFactory.(runtime.Factory).Create(d.logger,
+		stagingDir,
+		&functionconfig.Config{Spec: functionconfig.Spec{Runtime: runtimeName}})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create runtime")
+	}
+
+	processorDockerfileInfo, err := runtimeInstance.GetProcessorDockerfileInfo(versionInfo, onbuildImageRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get processor Dockerfile info")
+	}
+
+	var onbuildImages []string
+	for _, onbuildArtifact := range processorDockerfileInfo.OnbuildArtifacts {
+		onbuildImages = append(onbuildImages, onbuildArtifact.Image)
+	}
+
+	return onbuildImages, nil
+}
+
+// isImageAvailable returns whether the image is present locally, or can be pulled from its registry
+func (d *Doctor) isImageAvailable(image string) (bool, error) {
+	if _, err := d.cmdRunner.Run(nil, "docker image inspect %s", image); err == nil {
+		return true, nil
+	}
+
+	client := &registryClient{httpClient: d.httpClient}
+
+	baseURL, err := client.ping(getRegistryAPIHost(image))
+	if baseURL == "" {
+		return false, err
+	}
+
+	return client.imageExists(baseURL, image)
+}
+
+func (d *Doctor) createKubeClientSetFromKubeconfig(options *Options) (kubernetes.Interface, string, error) {
+	var restConfig *rest.Config
+	var contextName string
+	var err error
+
+	if options.KubeconfigPath == "" && kube.IsInCluster() {
+		contextName = "in-cluster"
+		restConfig, err = rest.InClusterConfig()
+	} else {
+		clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.KubeconfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: options.KubeContext})
+
+		rawConfig, rawConfigErr := clientConfig.RawConfig()
+		if rawConfigErr != nil {
+			return nil, "", errors.Wrap(rawConfigErr, "Failed to load kubeconfig")
+		}
+
+		contextName = rawConfig.CurrentContext
+		if options.KubeContext != "" {
+			contextName = options.KubeContext
+		}
+
+		restConfig, err = clientConfig.ClientConfig()
+	}
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to create REST config")
+	}
+
+	restConfig.Timeout = registryRequestTimeout
+
+	kubeClientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to create client set")
+	}
+
+	return kubeClientSet, contextName, nil
+}
+
+// compareVersions compares dotted versions numerically (e.g. 17.05 < 19.03.1), ignoring suffixes
+func compareVersions(version string, otherVersion string) int {
+	versionParts := strings.Split(version, ".")
+	otherVersionParts := strings.Split(otherVersion, ".")
+
+	for partIdx := 0; partIdx < len(versionParts) || partIdx < len(otherVersionParts); partIdx++ {
+		part := getVersionPart(versionParts, partIdx)
+		otherPart := getVersionPart(otherVersionParts, partIdx)
+
+		if part != otherPart {
+			if part < otherPart {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func getVersionPart(versionParts []string, partIdx int) int {
+	if partIdx >= len(versionParts) {
+		return 0
+	}
+
+	// e.g. "1-ce" of "18.09.1-ce"
+	digits := strings.TrimLeft(versionParts[partIdx], "0123456789")
+	part, _ := strconv.Atoi(strings.TrimSuffix(versionParts[partIdx], digits))
+
+	return part
+}
+
+func getLastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	authorization_v1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeCmdRunner returns the results it was given for commands, failing those it wasn't given
+type fakeCmdRunner struct {
+	results map[string]cmdrunner.RunResult
+}
+
+func (fcr *fakeCmdRunner) Run(runOptions *cmdrunner.RunOptions,
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+	command := fmt.Sprintf(format, vars...)
+
+	runResult, found := fcr.results[command]
+	if !found || runResult.ExitCode != 0 {
+		return runResult, errors.Errorf("Command failed: %s", command)
+	}
+
+	return runResult, nil
+}
+
+func (fcr *fakeCmdRunner) RunStream(runOptions *cmdrunner.RunOptions,
+	outputLineHandler func(line string),
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+	return fcr.Run(runOptions, format, vars...)
+}
+
+type doctorTestSuite struct {
+	suite.Suite
+	cmdRunner *fakeCmdRunner
+	doctor    *Doctor
+}
+
+func (suite *doctorTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.cmdRunner = &fakeCmdRunner{results: map[string]cmdrunner.RunResult{}}
+	suite.doctor = NewDoctor(loggerInstance,
+		suite.cmdRunner,
+		&containerimagebuilderpusher.ContainerBuilderConfiguration{Kind: "docker"})
+}
+
+func (suite *doctorTestSuite) TestCheckPlatformConfiguration() {
+	platformName, result := suite.doctor.checkPlatformConfiguration(&Options{PlatformName: "auto"})
+	suite.Require().Equal("local", platformName)
+	suite.Require().Equal(CheckStatusOK, result.Status)
+
+	_, result = suite.doctor.checkPlatformConfiguration(&Options{PlatformName: "other"})
+	suite.Require().Equal(CheckStatusError, result.Status)
+	suite.Require().NotEmpty(result.Remediation)
+
+	platformName, result = suite.doctor.checkPlatformConfiguration(&Options{
+		PlatformName:   "auto",
+		KubeconfigPath: "/no/such/kubeconfig",
+	})
+	suite.Require().Equal("kube", platformName)
+	suite.Require().Equal(CheckStatusError, result.Status)
+	suite.Require().Contains(result.Message, "/no/such/kubeconfig")
+
+	suite.doctor.containerBuilderConfiguration.Kind = "kaniko"
+	_, result = suite.doctor.checkPlatformConfiguration(&Options{PlatformName: "local"})
+	suite.Require().Equal(CheckStatusError, result.Status)
+}
+
+func (suite *doctorTestSuite) TestCheckDocker() {
+	dockerVersionCommand := "docker version --format '{{.Server.Version}}'"
+
+	for _, testCase := range []struct {
+		name           string
+		platformName   string
+		builderKind    string
+		runResult      *cmdrunner.RunResult
+		expectedStatus CheckStatus
+		expectedText   string
+	}{
+		{
+			name:           "reachable",
+			platformName:   "local",
+			runResult:      &cmdrunner.RunResult{Output: "19.03.12\n"},
+			expectedStatus: CheckStatusOK,
+			expectedText:   "19.03.12",
+		},
+		{
+			name:           "old",
+			platformName:   "local",
+			runResult:      &cmdrunner.RunResult{Output: "17.03.1-ce"},
+			expectedStatus: CheckStatusError,
+			expectedText:   "older",
+		},
+		{
+			name:           "missing",
+			platformName:   "local",
+			runResult:      &cmdrunner.RunResult{ExitCode: 127, Output: "/bin/sh: 1: docker: not found"},
+			expectedStatus: CheckStatusError,
+			expectedText:   "isn't installed",
+		},
+		{
+			name:         "unreachable",
+			platformName: "local",
+			runResult: &cmdrunner.RunResult{
+				ExitCode: 1,
+				Output:   "Cannot connect to the Docker daemon at unix:///var/run/docker.sock",
+			},
+			expectedStatus: CheckStatusError,
+			expectedText:   "Cannot connect",
+		},
+		{
+			name:           "notRequired",
+			platformName:   "kube",
+			builderKind:    "kaniko",
+			runResult:      &cmdrunner.RunResult{ExitCode: 127},
+			expectedStatus: CheckStatusSkipped,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.doctor.containerBuilderConfiguration.Kind = "docker"
+			if testCase.builderKind != "" {
+				suite.doctor.containerBuilderConfiguration.Kind = testCase.builderKind
+			}
+
+			suite.cmdRunner.results[dockerVersionCommand] = *testCase.runResult
+
+			result := suite.doctor.checkDocker(testCase.platformName)
+			suite.Require().Equal(testCase.expectedStatus, result.Status)
+			suite.Require().Contains(result.Message, testCase.expectedText)
+
+			if testCase.expectedStatus == CheckStatusError {
+				suite.Require().NotEmpty(result.Remediation)
+			}
+		})
+	}
+}
+
+func (suite *doctorTestSuite) TestCheckRegistry() {
+	server := suite.createRegistryServer()
+	defer server.Close()
+
+	suite.doctor.httpClient = server.Client()
+	registry := strings.TrimPrefix(server.URL, "https://") + "/my-project"
+
+	result := suite.doctor.checkRegistry("local", &Options{
+		Registry:            registry,
+		RegistryCredentials: &dockercreds.Credentials{Username: "user", Password: "pass"},
+	})
+	suite.Require().Equal(CheckStatusOK, result.Status, result.Message)
+	suite.Require().Contains(result.Message, "as user")
+
+	result = suite.doctor.checkRegistry("local", &Options{
+		Registry:            registry,
+		RegistryCredentials: &dockercreds.Credentials{Username: "user", Password: "wrong"},
+	})
+	suite.Require().Equal(CheckStatusError, result.Status)
+	suite.Require().Contains(result.Message, "rejected")
+	suite.Require().Contains(result.Remediation, "docker login")
+
+	result = suite.doctor.checkRegistry("local", &Options{Registry: registry})
+	suite.Require().Equal(CheckStatusError, result.Status)
+	suite.Require().Contains(result.Message, "requires authentication")
+
+	// kube functions must be pushed to a registry
+	result = suite.doctor.checkRegistry("kube", &Options{})
+	suite.Require().Equal(CheckStatusError, result.Status)
+
+	result = suite.doctor.checkRegistry("local", &Options{})
+	suite.Require().Equal(CheckStatusSkipped, result.Status)
+}
+
+func (suite *doctorTestSuite) TestIsImageAvailable() {
+	server := suite.createRegistryServer()
+	defer server.Close()
+
+	suite.doctor.httpClient = server.Client()
+	registryHost := strings.TrimPrefix(server.URL, "https://")
+
+	// present locally
+	suite.cmdRunner.results["docker image inspect local/image:1.0"] = cmdrunner.RunResult{}
+
+	available, err := suite.doctor.isImageAvailable("local/image:1.0")
+	suite.Require().NoError(err)
+	suite.Require().True(available)
+
+	// pulled anonymously from the registry
+	available, err = suite.doctor.isImageAvailable(registryHost + "/nuclio/onbuild:1.0")
+	suite.Require().NoError(err)
+	suite.Require().True(available)
+
+	available, err = suite.doctor.isImageAvailable(registryHost + "/nuclio/onbuild:2.0")
+	suite.Require().NoError(err)
+	suite.Require().False(available)
+}
+
+func (suite *doctorTestSuite) TestCheckKubePermissions() {
+	kubeClientSet := fake.NewSimpleClientset()
+	kubeClientSet.PrependReactor("create",
+		"selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			accessReview := action.(k8stesting.CreateAction).GetObject().(*authorization_v1.SelfSubjectAccessReview)
+			suite.Require().Equal("my-namespace", accessReview.Spec.ResourceAttributes.Namespace)
+
+			// may do anything but delete functions and read logs
+			resourceAttributes := accessReview.Spec.ResourceAttributes
+			accessReview.Status.Allowed = !(resourceAttributes.Verb == "delete" &&
+				resourceAttributes.Resource == "nucliofunctions") &&
+				resourceAttributes.Subresource != "log"
+
+			return true, accessReview, nil
+		})
+
+	result := suite.doctor.checkKubePermissions(kubeClientSet, "my-namespace")
+	suite.Require().Equal(CheckStatusError, result.Status)
+	suite.Require().Equal("Not permitted to delete nucliofunctions, get pods/log in namespace my-namespace",
+		result.Message)
+	suite.Require().NotEmpty(result.Remediation)
+}
+
+func (suite *doctorTestSuite) TestCompareVersions() {
+	suite.Require().Equal(0, compareVersions("17.05", "17.05.0"))
+	suite.Require().Equal(1, compareVersions("19.03.12", "17.05"))
+	suite.Require().Equal(1, compareVersions("17.10.0-ce", "17.05"))
+	suite.Require().Equal(-1, compareVersions("1.13.1", "17.05"))
+}
+
+func (suite *doctorTestSuite) TestSplitImageReference() {
+	for _, testCase := range []struct {
+		image              string
+		expectedRepository string
+		expectedReference  string
+	}{
+		{image: "quay.io/nuclio/processor:1.0-amd64", expectedRepository: "nuclio/processor", expectedReference: "1.0-amd64"},
+		{image: "localhost:5000/processor", expectedRepository: "processor", expectedReference: "latest"},
+		{image: "alpine:3.11", expectedRepository: "library/alpine", expectedReference: "3.11"},
+		{image: "nuclio/processor@sha256:abc", expectedRepository: "nuclio/processor", expectedReference: "sha256:abc"},
+	} {
+		repository, reference := splitImageReference(testCase.image)
+		suite.Require().Equal(testCase.expectedRepository, repository, testCase.image)
+		suite.Require().Equal(testCase.expectedReference, reference, testCase.image)
+	}
+}
+
+// createRegistryServer creates a registry which issues tokens to user:pass (and anonymously for pulls), and has
+// the image nuclio/onbuild:1.0
+func (suite *doctorTestSuite) createRegistryServer() *httptest.Server {
+	var server *httptest.Server
+
+	server = httptest.NewTLSServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch {
+		case request.URL.Path == "/token":
+			username, password, hasBasicAuth := request.BasicAuth()
+
+			switch {
+			case hasBasicAuth && username == "user" && password == "pass":
+				fmt.Fprint(responseWriter, `{"token": "user-token"}`) // nolint: errcheck
+			case !hasBasicAuth && strings.HasSuffix(request.URL.Query().Get("scope"), ":pull"):
+				fmt.Fprint(responseWriter, `{"access_token": "anonymous-token"}`) // nolint: errcheck
+			default:
+				responseWriter.WriteHeader(http.StatusUnauthorized)
+			}
+
+		case request.Header.Get("Authorization") == "":
+			responseWriter.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			responseWriter.WriteHeader(http.StatusUnauthorized)
+
+		case request.URL.Path == "/v2/":
+			if request.Header.Get("Authorization") != "Bearer user-token" {
+				responseWriter.WriteHeader(http.StatusUnauthorized)
+			}
+
+		case request.URL.Path == "/v2/nuclio/onbuild/manifests/1.0":
+		default:
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestDoctorTestSuite(t *testing.T) {
+	suite.Run(t, new(doctorTestSuite))
+}
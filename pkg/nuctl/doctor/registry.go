@@ -0,0 +1,288 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/dockercreds"
+
+	"github.com/nuclio/errors"
+)
+
+var authenticateParameterRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryClient talks to the HTTP API of a docker registry (v2), authenticating the way the docker CLI does -
+// with basic auth, or with a token the registry's auth server issues for the credentials
+type registryClient struct {
+	httpClient  *http.Client
+	credentials *dockercreds.Credentials
+}
+
+// getRegistryAPIHost returns the host a registry's API is served at, given a registry URL or an image
+func getRegistryAPIHost(registry string) string {
+	registryHost := dockercreds.GetRegistryHost(registry)
+
+	// a docker hub user (e.g. "myuser" rather than "docker.io/myuser")
+	if !strings.ContainsAny(registryHost, ".:") && registryHost != "localhost" {
+		registryHost = "index.docker.io"
+	}
+
+	if registryHost == "index.docker.io" {
+		return "registry-1.docker.io"
+	}
+
+	return registryHost
+}
+
+// ping verifies the registry is reachable and that the credentials (if any) are accepted, returning the base
+// URL of its API. registries are tried over HTTPS, and over plain HTTP if that fails
+func (rc *registryClient) ping(registryHost string) (string, error) {
+	var pingErr error
+
+	for _, scheme := range []string{"https", "http"} {
+		baseURL := fmt.Sprintf("%s://%s", scheme, registryHost)
+
+		statusCode, err := rc.get(baseURL+"/v2/", "", nil)
+		if err != nil {
+			if pingErr == nil {
+				pingErr = err
+			}
+
+			continue
+		}
+
+		switch statusCode {
+		case http.StatusOK:
+			return baseURL, nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			if rc.credentials == nil {
+				return baseURL, errors.New("Registry requires authentication, and no credentials were found")
+			}
+
+			return baseURL, errors.Errorf("Registry rejected the credentials of %s", rc.credentials.Username)
+		default:
+			return baseURL, errors.Errorf("Registry responded with unexpected status %d", statusCode)
+		}
+	}
+
+	return "", errors.Wrap(pingErr, "Failed to reach registry")
+}
+
+// imageExists returns whether the image's manifest can be pulled from the registry
+func (rc *registryClient) imageExists(baseURL string, image string) (bool, error) {
+	repository, reference := splitImageReference(image)
+
+	statusCode, err := rc.get(fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, repository, reference),
+		fmt.Sprintf("repository:%s:pull", repository),
+		map[string]string{
+			"Accept": strings.Join([]string{
+				"application/vnd.docker.distribution.manifest.list.v2+json",
+				"application/vnd.docker.distribution.manifest.v2+json",
+				"application/vnd.oci.image.index.v1+json",
+				"application/vnd.oci.image.manifest.v1+json",
+			}, ", "),
+		})
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get image manifest")
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden:
+
+		// registries respond with unauthorized to anonymous requests for repositories that don't exist
+		return false, nil
+	default:
+		return false, errors.Errorf("Registry responded with unexpected status %d", statusCode)
+	}
+}
+
+// get sends a request, answering the registry's authentication challenge if it responds with one
+func (rc *registryClient) get(requestURL string, scope string, headers map[string]string) (int, error) {
+	response, err := rc.send(requestURL, headers, "")
+	if err != nil {
+		return 0, err
+	}
+
+	if response.StatusCode != http.StatusUnauthorized {
+		return response.StatusCode, nil
+	}
+
+	authorization, err := rc.getAuthorization(response.Header.Get("WWW-Authenticate"), scope)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to authenticate with registry")
+	}
+
+	if authorization == "" {
+		return response.StatusCode, nil
+	}
+
+	if response, err = rc.send(requestURL, headers, authorization); err != nil {
+		return 0, err
+	}
+
+	return response.StatusCode, nil
+}
+
+func (rc *registryClient) send(requestURL string, headers map[string]string, authorization string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerName, headerValue := range headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	response, err := rc.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	io.Copy(ioutil.Discard, response.Body) // nolint: errcheck
+
+	return response, nil
+}
+
+// getAuthorization returns the authorization header answering the challenge, or an empty string if the
+// credentials (or lack thereof) can't answer it
+func (rc *registryClient) getAuthorization(challenge string, scope string) (string, error) {
+	challengeParameters := map[string]string{}
+	for _, match := range authenticateParameterRegex.FindAllStringSubmatch(challenge, -1) {
+		challengeParameters[strings.ToLower(match[1])] = match[2]
+	}
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(challenge), "basic"):
+		if rc.credentials == nil {
+			return "", nil
+		}
+
+		request := http.Request{Header: http.Header{}}
+		request.SetBasicAuth(rc.credentials.Username, rc.credentials.Password)
+
+		return request.Header.Get("Authorization"), nil
+
+	case strings.HasPrefix(strings.ToLower(challenge), "bearer"):
+		token, err := rc.getToken(challengeParameters, scope)
+		if err != nil {
+			return "", err
+		}
+
+		return "Bearer " + token, nil
+	}
+
+	return "", nil
+}
+
+// getToken gets a token from the registry's auth server, for the credentials if there are any
+func (rc *registryClient) getToken(challengeParameters map[string]string, scope string) (string, error) {
+	realm := challengeParameters["realm"]
+	if realm == "" {
+		return "", errors.New("Registry's authentication challenge has no realm")
+	}
+
+	query := url.Values{}
+	if challengeParameters["service"] != "" {
+		query.Set("service", challengeParameters["service"])
+	}
+
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create token request")
+	}
+
+	if rc.credentials != nil {
+		request.SetBasicAuth(rc.credentials.Username, rc.credentials.Password)
+	}
+
+	response, err := rc.httpClient.Do(request)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to request token")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+
+		// the credentials were rejected - the original request's unauthorized response stands
+		if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+			return "", nil
+		}
+
+		return "", errors.Errorf("Auth server responded with unexpected status %d", response.StatusCode)
+	}
+
+	tokenResponse := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return "", errors.Wrap(err, "Failed to decode token")
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+
+	return tokenResponse.AccessToken, nil
+}
+
+// splitImageReference splits an image into its repository (without the registry host) and tag or digest
+func splitImageReference(image string) (string, string) {
+	repository, reference := image, "latest"
+
+	if atIndex := strings.Index(repository, "@"); atIndex != -1 {
+		repository, reference = repository[:atIndex], repository[atIndex+1:]
+	} else if colonIndex := strings.LastIndex(repository, ":"); colonIndex > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:colonIndex], repository[colonIndex+1:]
+	}
+
+	if slashIndex := strings.Index(repository, "/"); slashIndex != -1 {
+		registryHost := repository[:slashIndex]
+		if strings.ContainsAny(registryHost, ".:") || registryHost == "localhost" {
+			return repository[slashIndex+1:], reference
+		}
+	}
+
+	// official docker hub images
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	return repository, reference
+}
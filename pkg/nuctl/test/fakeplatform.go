@@ -0,0 +1,86 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"os"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform/fake"
+	"github.com/nuclio/nuclio/pkg/version"
+
+	"github.com/nuclio/zap"
+)
+
+// FakePlatformTime is the time functions are deployed at on the fake platform, so that renderings are deterministic
+var FakePlatformTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// FakePlatformSuite executes nuctl against the in-memory platform, so its tests need neither docker nor a
+// kubernetes cluster. each test starts with an empty platform, whose calls the test may assert on
+type FakePlatformSuite struct {
+	Suite
+	Platform *fake.Platform
+}
+
+func (suite *FakePlatformSuite) SetupSuite() {
+	var err error
+
+	// update version so that linker doesn't need to inject it
+	version.SetFromEnv()
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	// the fake platform applies changes immediately, so there's little point in waiting long
+	suite.defaultWaitDuration = 5 * time.Second
+	suite.defaultWaitInterval = 100 * time.Millisecond
+
+	// nuctl creates a platform per command by name - select the fake one, which all commands share
+	suite.origPlatformType = os.Getenv(nuctlPlatformEnvVarName)
+	err = os.Setenv(nuctlPlatformEnvVarName, fake.Name)
+	suite.Require().NoError(err)
+}
+
+func (suite *FakePlatformSuite) SetupTest() {
+	var err error
+
+	suite.Suite.SetupTest()
+
+	fake.ResetSharedPlatform()
+	suite.Platform, err = fake.GetSharedPlatform(suite.logger)
+	suite.Require().NoError(err)
+
+	suite.Platform.Clock = func() time.Time {
+		return FakePlatformTime
+	}
+}
+
+// GetOutput returns what the commands executed so far in the test wrote
+func (suite *FakePlatformSuite) GetOutput() string {
+	return suite.outputBuffer.String()
+}
+
+// ResetOutput discards what the commands executed so far wrote, so that the next command's output can be
+// asserted on by itself
+func (suite *FakePlatformSuite) ResetOutput() {
+	suite.outputBuffer.Reset()
+}
+
+// AssertCallNames asserts on the names of the platform calls made since the last reset, in order
+func (suite *FakePlatformSuite) AssertCallNames(expectedCallNames ...string) {
+	suite.Require().Equal(expectedCallNames, suite.Platform.GetCallNames())
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakePlatformTestSuite struct {
+	FakePlatformSuite
+}
+
+func (suite *fakePlatformTestSuite) TestDeployGetDelete() {
+	err := suite.ExecuteNuctl([]string{"deploy", "my-function"}, map[string]string{
+		"run-image": "my-image:latest",
+		"env":       "SOME_ENV=some-value",
+	})
+	suite.Require().NoError(err)
+
+	// the default project is created on the way
+	suite.AssertCallNames("GetProjects", "CreateProject", "GetFunctions", "CreateFunction")
+
+	createFunctionOptions := suite.Platform.GetCalls()[3].Options.(*platform.CreateFunctionOptions)
+	suite.Require().Equal("my-image:latest", createFunctionOptions.FunctionConfig.Spec.Image)
+	suite.Require().Equal("some-value", createFunctionOptions.FunctionConfig.Spec.Env[0].Value)
+
+	suite.Platform.ResetCalls()
+	suite.ResetOutput()
+
+	err = suite.ExecuteNuctl([]string{"get", "function"}, nil)
+	suite.Require().NoError(err)
+	suite.AssertCallNames("GetProjects", "GetFunctions")
+	suite.Require().Equal(""+
+		"  NAMESPACE |    NAME     | PROJECT | STATE | NODE PORT | REPLICAS  \n"+
+		"  nuclio    | my-function |         | ready |         0 | 1/1       \n",
+		suite.GetOutput())
+
+	suite.Platform.ResetCalls()
+	suite.ResetOutput()
+
+	err = suite.ExecuteNuctl([]string{"delete", "function", "my-function"}, nil)
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.Platform.GetCallNames(), "DeleteFunction")
+
+	err = suite.ExecuteNuctl([]string{"get", "function"}, nil)
+	suite.Require().NoError(err)
+	suite.findPatternsInOutput([]string{"No functions found"}, []string{"my-function"})
+}
+
+func (suite *fakePlatformTestSuite) TestDeterministicRendering() {
+
+	// deploy out of order, so that the rendering can't depend on the order of deployment
+	for _, functionName := range []string{"function-c", "function-a", "function-b"} {
+		err := suite.ExecuteNuctl([]string{"deploy", functionName}, map[string]string{
+			"run-image": "my-image:latest",
+		})
+		suite.Require().NoError(err)
+	}
+
+	suite.ResetOutput()
+
+	err := suite.ExecuteNuctl([]string{"get", "function"}, map[string]string{
+		"output": "wide",
+	})
+	suite.Require().NoError(err)
+
+	firstOutput := suite.GetOutput()
+	suite.findRegexpPatternsInOutput([]string{
+		`function-a .* 2020-01-01T00:00:00Z`,
+	}, nil)
+
+	// functions are rendered by name, each time
+	suite.Require().Regexp(`(?s)function-a.*function-b.*function-c`, firstOutput)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		suite.ResetOutput()
+
+		err = suite.ExecuteNuctl([]string{"get", "function"}, map[string]string{
+			"output": "wide",
+		})
+		suite.Require().NoError(err)
+		suite.Require().Equal(firstOutput, suite.GetOutput())
+	}
+}
+
+func (suite *fakePlatformTestSuite) TestPlatformErrors() {
+	err := suite.ExecuteNuctl([]string{"invoke", "other-function"}, nil)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+
+	// the invocation reached the platform, which failed it
+	invocationCalls := 0
+	for _, call := range suite.Platform.GetCalls() {
+		if call.Name == "CreateFunctionInvocation" {
+			suite.Require().Equal("other-function", call.Options.(*platform.CreateFunctionInvocationOptions).Name)
+			invocationCalls++
+		}
+	}
+
+	suite.Require().Equal(1, invocationCalls)
+}
+
+func (suite *fakePlatformTestSuite) TestAuditLog() {
+	for _, envValue := range []string{"first", "second"} {
+		err := suite.ExecuteNuctl([]string{"deploy", "my-function"}, map[string]string{
+			"run-image": "my-image:latest",
+			"env":       "SOME_ENV=" + envValue,
+		})
+		suite.Require().NoError(err)
+	}
+
+	err := suite.ExecuteNuctl([]string{"deploy", "other-function"}, map[string]string{
+		"run-image": "my-image:latest",
+	})
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"delete", "function", "my-function"}, nil)
+	suite.Require().NoError(err)
+
+	suite.Platform.ResetCalls()
+	suite.ResetOutput()
+
+	err = suite.ExecuteNuctl([]string{"get", "audit"}, map[string]string{
+		"function": "my-function",
+		"output":   "wide",
+	})
+	suite.Require().NoError(err)
+	suite.AssertCallNames("GetProjects", "GetAuditRecords")
+
+	// the second deploy only changed the environment variable
+	suite.findRegexpPatternsInOutput([]string{
+		`2020-01-01T00:00:00Z \| deploy +\| my-function \| .* \| nuctl +\| +\d+ \| metadata\.name`,
+		`2020-01-01T00:00:00Z \| deploy +\| my-function \| .* \| nuctl +\| +1 \| spec\.env\[0\]\.value`,
+		`2020-01-01T00:00:00Z \| delete +\| my-function \| .* \| nuctl +\| +0 \|`,
+	}, []string{"other-function"})
+
+	// the records of the fake platform are all from its fixed time
+	suite.ResetOutput()
+
+	err = suite.ExecuteNuctl([]string{"get", "audit"}, map[string]string{
+		"since": "24h",
+	})
+	suite.Require().NoError(err)
+	suite.findPatternsInOutput([]string{"No audit records found"}, nil)
+
+	suite.ResetOutput()
+
+	err = suite.ExecuteNuctl([]string{"get", "audit"}, map[string]string{
+		"function": "my-function",
+		"output":   "json",
+	})
+	suite.Require().NoError(err)
+	suite.findPatternsInOutput([]string{`"previous": "first"`, `"current": "second"`}, nil)
+}
+
+func (suite *fakePlatformTestSuite) TestProjectDefaultsAndQuotas() {
+	tempDir, err := ioutil.TempDir("", "nuctl-project-spec-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	specFilePath := filepath.Join(tempDir, "project.yaml")
+	err = ioutil.WriteFile(specFilePath, []byte(`
+description: from the spec file
+defaults:
+  registry: registry.example.com
+  serviceAccount: analytics
+  nodeSelector:
+    pool: analytics
+quotas:
+  maxFunctions: 1
+`), 0644)
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"create", "project", "analytics"}, map[string]string{
+		"spec-file":   specFilePath,
+		"description": "from the flag",
+	})
+	suite.Require().NoError(err)
+
+	projectConfig := suite.Platform.GetCalls()[len(suite.Platform.GetCalls())-1].Options.(*platform.CreateProjectOptions).ProjectConfig
+	suite.Require().Equal("from the flag", projectConfig.Spec.Description)
+	suite.Require().Equal(1, projectConfig.Spec.Quotas.MaxFunctions)
+
+	// the project's defaults are applied to its functions
+	err = suite.ExecuteNuctl([]string{"deploy", "first-function"}, map[string]string{
+		"run-image":    "my-image:latest",
+		"project-name": "analytics",
+	})
+	suite.Require().NoError(err)
+
+	functions, err := suite.Platform.GetFunctions(&platform.GetFunctionsOptions{Name: "first-function"})
+	suite.Require().NoError(err)
+	suite.Require().Equal("registry.example.com", functions[0].GetConfig().Spec.Build.Registry)
+	suite.Require().Equal("analytics", functions[0].GetConfig().Spec.ServiceAccount)
+	suite.Require().Equal(map[string]string{"pool": "analytics"}, functions[0].GetConfig().Spec.NodeSelector)
+
+	// redeploying doesn't count against the quota, while another function does
+	err = suite.ExecuteNuctl([]string{"deploy", "first-function"}, map[string]string{
+		"run-image":    "my-image:latest",
+		"project-name": "analytics",
+	})
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"deploy", "second-function"}, map[string]string{
+		"run-image":    "my-image:latest",
+		"project-name": "analytics",
+	})
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Project quota of 1 functions would be exceeded")
+
+	// raising the quota leaves the rest of the project as is
+	err = ioutil.WriteFile(specFilePath, []byte(`{"quotas": {"maxFunctions": 2}}`), 0644)
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"update", "project", "analytics"}, map[string]string{
+		"spec-file": specFilePath,
+	})
+	suite.Require().NoError(err)
+
+	projects, err := suite.Platform.GetProjects(&platform.GetProjectsOptions{
+		Meta: platform.ProjectMeta{Name: "analytics"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("from the flag", projects[0].GetConfig().Spec.Description)
+	suite.Require().Equal("analytics", projects[0].GetConfig().Spec.Defaults.ServiceAccount)
+
+	err = suite.ExecuteNuctl([]string{"deploy", "second-function"}, map[string]string{
+		"run-image":    "my-image:latest",
+		"project-name": "analytics",
+	})
+	suite.Require().NoError(err)
+
+	// invalid quotas are rejected
+	err = ioutil.WriteFile(specFilePath, []byte(`{"quotas": {"maxCPU": "lots"}}`), 0644)
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"update", "project", "analytics"}, map[string]string{
+		"spec-file": specFilePath,
+	})
+	suite.Require().Error(err)
+}
+
+func TestFakePlatformTestSuite(t *testing.T) {
+	suite.Run(t, new(fakePlatformTestSuite))
+}
@@ -18,6 +18,7 @@ package test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -26,6 +27,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -126,6 +128,63 @@ func (suite *functionDeployTestSuite) TestDeploy() {
 	suite.Require().Contains(suite.outputBuffer.String(), "+gnirts siht esrever-")
 }
 
+func (suite *functionDeployTestSuite) TestInvokeResolvesPublishedPort() {
+
+	// relevant only for local platform
+	if suite.origPlatformType != "" && suite.origPlatformType != "local" {
+		suite.T().Skipf("Not on local platform")
+	}
+
+	uniqueSuffix := "-" + xid.New().String()
+	functionName := "invoke-port-reverser" + uniqueSuffix
+	imageName := "nuclio/processor-" + functionName
+
+	namedArgs := map[string]string{
+		"path":    path.Join(suite.GetFunctionsDir(), "common", "reverser", "golang"),
+		"runtime": "golang",
+		"handler": "main:Reverse",
+	}
+
+	err := suite.ExecuteNuctl([]string{"deploy", functionName, "--verbose", "--no-pull"}, namedArgs)
+
+	suite.Require().NoError(err)
+
+	// make sure to clean up after the test
+	defer suite.dockerClient.RemoveImage(imageName)
+
+	// use nutctl to delete the function when we're done
+	defer suite.ExecuteNuctl([]string{"delete", "fu", functionName}, nil)
+
+	// invoke without a URL, the port the function's container publishes is used
+	err = suite.ExecuteNuctlAndWait([]string{"invoke", functionName},
+		map[string]string{
+			"method": "POST",
+			"body":   "-reverse this string+",
+		},
+		false)
+	suite.Require().NoError(err)
+
+	// make sure reverser worked
+	suite.Require().Contains(suite.outputBuffer.String(), "+gnirts siht esrever-")
+
+	// the processor's HTTP port can be selected explicitly, other ports aren't published
+	err = suite.ExecuteNuctl([]string{"invoke", functionName}, map[string]string{"port": "8080"})
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"invoke", functionName}, map[string]string{"port": "9090"})
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "doesn't publish port 9090")
+
+	// a stopped function is reported as such, rather than failing to connect
+	containerName := fmt.Sprintf("nuclio-nuclio-%s", functionName)
+	err = suite.dockerClient.StopContainer(containerName)
+	suite.Require().NoError(err)
+
+	err = suite.ExecuteNuctl([]string{"invoke", functionName}, nil)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "isn't running")
+}
+
 func (suite *functionDeployTestSuite) TestInvokeWithBodyFromStdin() {
 	uniqueSuffix := "-" + xid.New().String()
 	functionName := "invoke-body-stdin-reverser" + uniqueSuffix
@@ -723,6 +782,134 @@ func (suite *functionDeployTestSuite) TestDeployFromLocalDirPath() {
 	suite.Require().Contains(suite.outputBuffer.String(), "+gnirts siht esrever-")
 }
 
+func (suite *functionDeployTestSuite) TestDeployFromGitRepository() {
+	uniqueSuffix := "-" + xid.New().String()
+	functionName := "git-reverser" + uniqueSuffix
+	imageName := "nuclio/processor-" + functionName
+
+	// create a local bare repository with the function under a subdirectory of a branch
+	tempDir, err := ioutil.TempDir("", "nuctl-git-test-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	repositoryDir := path.Join(tempDir, "repo.git")
+	workDir := path.Join(tempDir, "work")
+	suite.Require().NoError(os.MkdirAll(path.Join(workDir, "functions"), 0755))
+
+	for _, gitCommand := range []string{
+		fmt.Sprintf("git init --quiet --bare %s", repositoryDir),
+		fmt.Sprintf("git init --quiet %s", workDir),
+		fmt.Sprintf("git -C %s checkout --quiet -b release", workDir),
+		fmt.Sprintf("cp -r %s %s",
+			path.Join(suite.GetFunctionsDir(), "common", "reverser", "python"),
+			path.Join(workDir, "functions", "reverser")),
+		fmt.Sprintf("git -C %s add -A", workDir),
+		fmt.Sprintf("git -C %s -c user.name=test -c user.email=test@nuclio.io commit --quiet -m reverser", workDir),
+		fmt.Sprintf("git -C %s push --quiet --all %s", workDir, repositoryDir),
+	} {
+		_, err = suite.shellClient.Run(nil, gitCommand)
+		suite.Require().NoError(err)
+	}
+
+	err = suite.ExecuteNuctl([]string{"deploy", functionName, "--verbose", "--no-pull"},
+		map[string]string{
+			"path":    fmt.Sprintf("file://%s#release:functions/reverser", repositoryDir),
+			"runtime": "python:3.6",
+			"handler": "reverser:handler",
+		})
+	suite.Require().NoError(err)
+
+	// make sure to clean up after the test
+	defer suite.dockerClient.RemoveImage(imageName)
+
+	// use nuctl to delete the function when we're done
+	defer suite.ExecuteNuctl([]string{"delete", "fu", functionName}, nil)
+
+	// try a few times to invoke, until it succeeds
+	err = suite.ExecuteNuctlAndWait([]string{"invoke", functionName},
+		map[string]string{
+			"method": "POST",
+			"body":   "-reverse this string+",
+			"via":    "external-ip",
+		}, false)
+	suite.Require().NoError(err)
+
+	// check that invoke printed the value
+	suite.Require().Contains(suite.outputBuffer.String(), "+gnirts siht esrever-")
+}
+
+func (suite *functionDeployTestSuite) TestDeployFromMissingGitRepositoryFails() {
+	functionName := "git-missing-" + xid.New().String()
+
+	err := suite.ExecuteNuctl([]string{"deploy", functionName, "--verbose", "--no-pull"},
+		map[string]string{
+			"path":    "file:///no/such/repo.git#master",
+			"runtime": "python:3.6",
+			"handler": "reverser:handler",
+		})
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to fetch function source from git")
+}
+
+func (suite *functionDeployTestSuite) TestApply() {
+	uniqueSuffix := "-" + xid.New().String()
+	functionName := "apply-reverser" + uniqueSuffix
+	imageName := "nuclio/processor-" + functionName
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name: functionName,
+		},
+		Spec: functionconfig.Spec{
+			Runtime: "python:3.6",
+			Handler: "reverser:handler",
+			Build: functionconfig.Build{
+				Path:             path.Join(suite.GetFunctionsDir(), "common", "reverser", "python"),
+				NoBaseImagesPull: true,
+			},
+		},
+	}
+
+	functionConfigFile, err := ioutil.TempFile("", "apply-*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(functionConfigFile.Name()) // nolint: errcheck
+
+	writeFunctionConfig := func() {
+		functionConfigBody, err := yaml.Marshal(functionConfig)
+		suite.Require().NoError(err)
+
+		err = ioutil.WriteFile(functionConfigFile.Name(), functionConfigBody, 0644)
+		suite.Require().NoError(err)
+	}
+
+	// make sure to clean up after the test
+	defer suite.dockerClient.RemoveImage(imageName)
+
+	// use nuctl to delete the function when we're done
+	defer suite.ExecuteNuctl([]string{"delete", "fu", functionName}, nil)
+
+	// first apply creates the function
+	writeFunctionConfig()
+	err = suite.ExecuteNuctl([]string{"apply", "--verbose"}, map[string]string{"file": functionConfigFile.Name()})
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), fmt.Sprintf("Function %s created", functionName))
+
+	// applying the same configuration does nothing
+	suite.outputBuffer.Reset()
+	err = suite.ExecuteNuctl([]string{"apply"}, map[string]string{"file": functionConfigFile.Name()})
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), fmt.Sprintf("Function %s unchanged", functionName))
+
+	// a changed configuration updates the function in place
+	functionConfig.Spec.Description = "updated"
+	writeFunctionConfig()
+
+	suite.outputBuffer.Reset()
+	err = suite.ExecuteNuctl([]string{"apply", "--verbose"}, map[string]string{"file": functionConfigFile.Name()})
+	suite.Require().NoError(err)
+	suite.Require().Contains(suite.outputBuffer.String(), fmt.Sprintf("Function %s updated", functionName))
+}
+
 func (suite *functionDeployTestSuite) TestDeployCronTriggersK8s() {
 
 	// relevant only for kube platform
@@ -1001,6 +1188,31 @@ func (suite *functionExportImportTestSuite) TestFailToImportFunctionNoInput() {
 
 }
 
+func (suite *functionExportImportTestSuite) TestImportCancelled() {
+	tempDir, err := ioutil.TempDir("", "import-cancelled-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	// opening a fifo for reading blocks until it's opened for writing, so the import hangs
+	fifoPath := filepath.Join(tempDir, "functions.yaml")
+	err = syscall.Mkfifo(fifoPath, 0600)
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Second, cancel)
+
+	startTime := time.Now()
+	err = suite.ExecuteNuctlWithContext(ctx, []string{"import", "fu", fifoPath}, nil)
+	suite.Require().Error(err)
+	suite.Require().Equal(context.Canceled, errors.RootCause(err))
+	suite.Require().True(time.Since(startTime) < 10*time.Second)
+
+	// let the abandoned import complete, reading nothing
+	fifo, err := os.OpenFile(fifoPath, os.O_WRONLY, 0)
+	suite.Require().NoError(err)
+	fifo.Close() // nolint: errcheck
+}
+
 func (suite *functionExportImportTestSuite) TestImportMultiFunctions() {
 	functionsConfigPath := path.Join(suite.GetImportsDir(), "functions.yaml")
 
@@ -19,11 +19,15 @@ package test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/cmdrunner"
@@ -34,6 +38,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/version"
 
 	"github.com/ghodss/yaml"
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
@@ -104,10 +109,25 @@ func (suite *Suite) TearDownSuite() {
 func (suite *Suite) ExecuteNuctl(positionalArgs []string,
 	namedArgs map[string]string) error {
 
+	return suite.ExecuteNuctlWithContext(context.Background(), positionalArgs, namedArgs)
+}
+
+// ExecuteNuctlWithContext is like ExecuteNuctl, but returns the context's error as soon as it's done. commands
+// don't take a context, so a command abandoned this way runs on in the background until it returns by itself,
+// with its output discarded
+func (suite *Suite) ExecuteNuctlWithContext(ctx context.Context,
+	positionalArgs []string,
+	namedArgs map[string]string) error {
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "Context done before executing nuctl")
+	}
+
 	suite.rootCommandeer = command.NewRootCommandeer()
 
 	// set the output so we can capture it (but also output to stdout)
-	suite.rootCommandeer.GetCmd().SetOut(io.MultiWriter(os.Stdout, &suite.outputBuffer))
+	output := &detachableWriter{writer: io.MultiWriter(os.Stdout, &suite.outputBuffer)}
+	suite.rootCommandeer.GetCmd().SetOut(output)
 
 	// set the input so we can write to stdin
 	suite.rootCommandeer.GetCmd().SetIn(&suite.inputBuffer)
@@ -120,8 +140,16 @@ func (suite *Suite) ExecuteNuctl(positionalArgs []string,
 	// add positional arguments
 	argsStringSlice = append(argsStringSlice, positionalArgs...)
 
-	for argName, argValue := range namedArgs {
-		argsStringSlice = append(argsStringSlice, fmt.Sprintf("--%s", argName), argValue)
+	// add named arguments, ordered by name so that executions are reproducible
+	var argNames []string
+	for argName := range namedArgs {
+		argNames = append(argNames, argName)
+	}
+
+	sort.Strings(argNames)
+
+	for _, argName := range argNames {
+		argsStringSlice = append(argsStringSlice, fmt.Sprintf("--%s", argName), namedArgs[argName])
 	}
 
 	// override os.Args (this can't go wrong horribly, can it?)
@@ -130,20 +158,41 @@ func (suite *Suite) ExecuteNuctl(positionalArgs []string,
 	suite.logger.DebugWith("Executing nuctl", "args", argsStringSlice)
 
 	// execute
-	return suite.rootCommandeer.Execute()
+	executeErr := make(chan error, 1)
+	rootCommandeer := suite.rootCommandeer
+	go func() {
+		executeErr <- rootCommandeer.Execute()
+	}()
+
+	select {
+	case err := <-executeErr:
+		return err
+	case <-ctx.Done():
+
+		// the abandoned command mustn't write to the output of those that follow
+		output.detach()
+
+		suite.logger.WarnWith("Abandoned nuctl execution", "args", argsStringSlice, "err", ctx.Err().Error())
+		return errors.Wrap(ctx.Err(), "Context done while executing nuctl")
+	}
 }
 
-// ExecuteNuctl populates os.Args and executes nuctl as if it were executed from shell
+// ExecuteNuctlAndWait executes nuctl until it succeeds (or fails, if a failure is expected), giving up
+// after the default wait duration
 func (suite *Suite) ExecuteNuctlAndWait(positionalArgs []string,
 	namedArgs map[string]string,
 	expectFailure bool) error {
 
+	// an execution that hangs mustn't outlast the wait
+	ctx, cancel := context.WithTimeout(context.Background(), suite.defaultWaitDuration)
+	defer cancel()
+
 	return common.RetryUntilSuccessful(suite.defaultWaitDuration,
 		suite.defaultWaitInterval,
 		func() bool {
 
 			// execute
-			err := suite.ExecuteNuctl(positionalArgs, namedArgs)
+			err := suite.ExecuteNuctlWithContext(ctx, positionalArgs, namedArgs)
 			if expectFailure {
 				return err != nil
 			}
@@ -203,6 +252,57 @@ func (suite *Suite) findPatternsInOutput(patternsMustExist []string, patternsMus
 	}
 }
 
+// findRegexpPatternsInOutput is like findPatternsInOutput, but each pattern is a regular expression that
+// must (or must not) match at least one line of the output
+func (suite *Suite) findRegexpPatternsInOutput(patternsMustMatch []string, patternsMustNotMatch []string) {
+	compilePatterns := func(patterns []string) []*regexp.Regexp {
+		var compiledPatterns []*regexp.Regexp
+
+		for _, pattern := range patterns {
+			compiledPattern, err := regexp.Compile(pattern)
+			suite.Require().NoError(err, "Failed to compile pattern %s", pattern)
+
+			compiledPatterns = append(compiledPatterns, compiledPattern)
+		}
+
+		return compiledPatterns
+	}
+
+	compiledPatternsMustMatch := compilePatterns(patternsMustMatch)
+	compiledPatternsMustNotMatch := compilePatterns(patternsMustNotMatch)
+
+	matchedPatternsMustMatch := make([]bool, len(compiledPatternsMustMatch))
+	matchingLinesMustNotMatch := make([]string, len(compiledPatternsMustNotMatch))
+	matchedPatternsMustNotMatch := make([]bool, len(compiledPatternsMustNotMatch))
+
+	// iterate over all lines in result
+	scanner := bufio.NewScanner(&suite.outputBuffer)
+	for scanner.Scan() {
+		for patternIdx, compiledPattern := range compiledPatternsMustMatch {
+			if compiledPattern.MatchString(scanner.Text()) {
+				matchedPatternsMustMatch[patternIdx] = true
+			}
+		}
+
+		for patternIdx, compiledPattern := range compiledPatternsMustNotMatch {
+			if !matchedPatternsMustNotMatch[patternIdx] && compiledPattern.MatchString(scanner.Text()) {
+				matchedPatternsMustNotMatch[patternIdx] = true
+				matchingLinesMustNotMatch[patternIdx] = scanner.Text()
+			}
+		}
+	}
+
+	for patternIdx, matchedPattern := range matchedPatternsMustMatch {
+		suite.Require().True(matchedPattern, "No line of the output matches %s", patternsMustMatch[patternIdx])
+	}
+
+	for patternIdx, matchedPattern := range matchedPatternsMustNotMatch {
+		suite.Require().False(matchedPattern, "Output line \"%s\" matches %s",
+			matchingLinesMustNotMatch[patternIdx],
+			patternsMustNotMatch[patternIdx])
+	}
+}
+
 func (suite *Suite) assertFunctionImported(functionName string, imported bool) {
 
 	// reset output buffer for reading the nex output cleanly
@@ -228,3 +328,28 @@ func (suite *Suite) assertFunctionImported(functionName string, imported bool) {
 		suite.findPatternsInOutput([]string{"imported"}, nil)
 	}
 }
+
+// detachableWriter writes to a writer until detached, after which writes are discarded
+type detachableWriter struct {
+	lock     sync.Mutex
+	writer   io.Writer
+	detached bool
+}
+
+func (dw *detachableWriter) Write(buffer []byte) (int, error) {
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	if dw.detached {
+		return len(buffer), nil
+	}
+
+	return dw.writer.Write(buffer)
+}
+
+func (dw *detachableWriter) detach() {
+	dw.lock.Lock()
+	defer dw.lock.Unlock()
+
+	dw.detached = true
+}
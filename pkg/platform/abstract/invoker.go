@@ -18,14 +18,18 @@ package abstract
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type invoker struct {
@@ -73,13 +77,26 @@ func (i *invoker) invoke(createFunctionInvocationOptions *platform.CreateFunctio
 		return nil, errors.Wrap(err, "Failed to initialize function")
 	}
 
-	// get where the function resides
-	invokeURL, err = function.GetInvokeURL(createFunctionInvocationOptions.Via)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get invoke URL")
+	// a paused function has no replicas to handle the invocation
+	if function.GetStatus().State == functionconfig.FunctionStatePaused {
+		return nil, nuclio.NewErrConflict(fmt.Sprintf("Function %s is paused, resume it to invoke it",
+			createFunctionInvocationOptions.Name))
 	}
 
-	fullpath := "http://" + invokeURL
+	// get where the function resides, unless told explicitly
+	if createFunctionInvocationOptions.URL != "" {
+		invokeURL = createFunctionInvocationOptions.URL
+	} else {
+		invokeURL, err = function.GetInvokeURL(createFunctionInvocationOptions.Via)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get invoke URL")
+		}
+	}
+
+	fullpath := invokeURL
+	if !strings.Contains(fullpath, "://") {
+		fullpath = "http://" + fullpath
+	}
 
 	if createFunctionInvocationOptions.Path != "" {
 		fullpath += "/" + createFunctionInvocationOptions.Path
@@ -91,7 +108,11 @@ func (i *invoker) invoke(createFunctionInvocationOptions *platform.CreateFunctio
 
 	// set body for post
 	if createFunctionInvocationOptions.Method != "GET" {
-		body = bytes.NewBuffer(createFunctionInvocationOptions.Body)
+		if createFunctionInvocationOptions.BodyStream != nil {
+			body = createFunctionInvocationOptions.BodyStream
+		} else {
+			body = bytes.NewBuffer(createFunctionInvocationOptions.Body)
+		}
 	}
 
 	i.logger.InfoWith("Executing function",
@@ -120,10 +141,19 @@ func (i *invoker) invoke(createFunctionInvocationOptions *platform.CreateFunctio
 		return nil, errors.Wrap(err, "Failed to send HTTP request")
 	}
 
-	defer response.Body.Close() // nolint: errcheck
-
 	i.logger.InfoWith("Got response", "status", response.Status)
 
+	// the body is left for the caller to read as it arrives
+	if createFunctionInvocationOptions.Stream {
+		return &platform.CreateFunctionInvocationResult{
+			Headers:    response.Header,
+			StatusCode: response.StatusCode,
+			BodyStream: response.Body,
+		}, nil
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
 	// read the body
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abstract
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// invokerTestPlatform holds a single function, invoked at whatever URL the test gives
+type invokerTestPlatform struct {
+	platform.Platform
+	logger logger.Logger
+}
+
+func (itp *invokerTestPlatform) GetFunctions(getFunctionsOptions *platform.GetFunctionsOptions) ([]platform.Function, error) {
+	function, err := platform.NewAbstractFunction(itp.logger,
+		itp,
+		&functionconfig.Config{Meta: functionconfig.Meta{Name: getFunctionsOptions.Name}},
+		&functionconfig.Status{},
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []platform.Function{function}, nil
+}
+
+type invokerTestSuite struct {
+	suite.Suite
+	invoker *invoker
+}
+
+func (suite *invokerTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.invoker, err = newInvoker(loggerInstance, &invokerTestPlatform{logger: loggerInstance})
+	suite.Require().NoError(err)
+}
+
+func (suite *invokerTestSuite) TestInvokeStream() {
+	secondEventReleased := make(chan struct{})
+
+	// sends one event, and the next only once the first was read
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Content-Type", "text/event-stream")
+
+		fmt.Fprint(responseWriter, "data: first\n") // nolint: errcheck
+		responseWriter.(http.Flusher).Flush()
+
+		<-secondEventReleased
+
+		fmt.Fprint(responseWriter, "data: second\n") // nolint: errcheck
+	}))
+	defer server.Close()
+
+	invokeResult, err := suite.invoker.invoke(&platform.CreateFunctionInvocationOptions{
+		Name:         "my-function",
+		Method:       http.MethodGet,
+		Headers:      http.Header{},
+		LogLevelName: "none",
+		URL:          server.URL,
+		Stream:       true,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, invokeResult.StatusCode)
+	suite.Require().Equal("text/event-stream", invokeResult.Headers.Get("Content-Type"))
+	suite.Require().Nil(invokeResult.Body)
+
+	defer invokeResult.BodyStream.Close() // nolint: errcheck
+
+	// the first event arrives while the response is still being written
+	bodyReader := bufio.NewReader(invokeResult.BodyStream)
+
+	line, err := bodyReader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Require().Equal("data: first\n", line)
+
+	close(secondEventReleased)
+
+	line, err = bodyReader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Require().Equal("data: second\n", line)
+}
+
+func TestInvokerTestSuite(t *testing.T) {
+	suite.Run(t, new(invokerTestSuite))
+}
@@ -47,6 +47,34 @@ const (
 	DefaultTargetCPU               = 75
 )
 
+// errors of docker (and the registries it pulls from and pushes to), kubernetes and the resolver in build
+// containers that are likely to go away if the build is retried, rather than errors in the function itself
+var transientBuildErrorPatterns = []*regexp.Regexp{
+
+	// network errors of go's net and net/http packages (docker, kubectl, kaniko)
+	regexp.MustCompile(`(dial|read|write) tcp \S*: ((connect|read|write): )?` +
+		`(i/o timeout|connection reset by peer|connection refused)`),
+	regexp.MustCompile(`lookup \S+( on \S+)?: (Temporary failure in name resolution|server misbehaving|i/o timeout)`),
+	regexp.MustCompile(`net/http: (TLS handshake timeout|request canceled while waiting for connection)`),
+	regexp.MustCompile(`Client\.Timeout exceeded while awaiting headers`),
+	regexp.MustCompile(`http2: client connection lost`),
+
+	// the resolver of build containers, failing to resolve package indexes (e.g. pip's getaddrinfo)
+	regexp.MustCompile(`\[Errno -3\] Temporary failure in name resolution`),
+
+	// docker pulls and pushes cut short, and throttled or unavailable registries
+	regexp.MustCompile(`(?m)(error pulling image configuration|failed to copy|failed to do request|` +
+		`error parsing HTTP \d+ response body): .*unexpected EOF$`),
+	regexp.MustCompile(`received unexpected HTTP status: (429 Too Many Requests|502 Bad Gateway|` +
+		`503 Service Unavailable|504 Gateway Timeout)`),
+	regexp.MustCompile(`toomanyrequests: You have reached your pull rate limit`),
+
+	// kubernetes API servers that are overloaded or restarting (e.g. while creating kaniko jobs)
+	regexp.MustCompile(`the server is currently unable to handle the request`),
+	regexp.MustCompile(`the server has received too many requests and has asked us to try again later`),
+	regexp.MustCompile(`etcdserver: request timed out`),
+}
+
 type Platform struct {
 	Logger                         logger.Logger
 	platform                       platform.Platform
@@ -80,6 +108,51 @@ func NewPlatform(parentLogger logger.Logger, platform platform.Platform, platfor
 
 func (ap *Platform) CreateFunctionBuild(createFunctionBuildOptions *platform.CreateFunctionBuildOptions) (
 	*platform.CreateFunctionBuildResult, error) {
+	var buildResult *platform.CreateFunctionBuildResult
+	var err error
+
+	maxAttempts := 1
+	if createFunctionBuildOptions.BuildRetries > 0 {
+		maxAttempts += createFunctionBuildOptions.BuildRetries
+	}
+
+	attempt := 1
+	for ; attempt <= maxAttempts; attempt++ {
+
+		// the builder may modify the options it is given, so each attempt gets its own copy
+		attemptBuildOptions := *createFunctionBuildOptions
+
+		buildResult, err = ap.createFunctionBuild(&attemptBuildOptions)
+		if err == nil {
+			buildResult.BuildAttempts = attempt
+			return buildResult, nil
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		if createFunctionBuildOptions.BuildRetryOnTransientOnly && !isTransientBuildError(err) {
+			createFunctionBuildOptions.Logger.DebugWith("Build failed on a non transient error, not retrying",
+				"attempt", attempt)
+			break
+		}
+
+		createFunctionBuildOptions.Logger.WarnWith("Build failed, retrying",
+			"attempt", attempt,
+			"maxAttempts", maxAttempts,
+			"err", errors.RootCause(err).Error())
+	}
+
+	if attempt > 1 {
+		return nil, errors.Wrapf(err, "Build failed after %d attempts", attempt)
+	}
+
+	return nil, err
+}
+
+func (ap *Platform) createFunctionBuild(createFunctionBuildOptions *platform.CreateFunctionBuildOptions) (
+	*platform.CreateFunctionBuildResult, error) {
 
 	// execute a build
 	builder, err := build.NewBuilder(createFunctionBuildOptions.Logger, ap.platform, &common.AbstractS3Client{})
@@ -132,6 +205,7 @@ func (ap *Platform) HandleDeployFunction(existingFunctionConfig *functionconfig.
 	createFunctionOptions.FunctionConfig.Spec.Build.Mode = ""
 
 	// check if we need to build the image
+	buildStartTime := time.Now()
 	if functionBuildRequired && !functionconfig.ShouldSkipBuild(createFunctionOptions.FunctionConfig.Meta.Annotations) {
 		buildResult, buildErr = ap.platform.CreateFunctionBuild(&platform.CreateFunctionBuildOptions{
 			Logger:                     createFunctionOptions.Logger,
@@ -139,12 +213,18 @@ func (ap *Platform) HandleDeployFunction(existingFunctionConfig *functionconfig.
 			PlatformName:               ap.platform.GetName(),
 			OnAfterConfigUpdate:        onAfterConfigUpdatedWrapper,
 			DependantImagesRegistryURL: createFunctionOptions.DependantImagesRegistryURL,
+			BuildOutputLineHandler:     createFunctionOptions.BuildOutputLineHandler,
+			BuildRetries:               createFunctionOptions.BuildRetries,
+			BuildRetryOnTransientOnly:  createFunctionOptions.BuildRetryOnTransientOnly,
+			PhaseTimings:               createFunctionOptions.PhaseTimings,
+			FunctionConfigContents:     createFunctionOptions.FunctionConfigContents,
 		})
 
 		if buildErr == nil {
 
 			// use the function configuration augmented by the builder
 			createFunctionOptions.FunctionConfig.Spec.Image = buildResult.Image
+			createFunctionOptions.ImageStatus = buildResult.ImageStatus
 
 			// if run registry isn't set, set it to that of the build
 			if createFunctionOptions.FunctionConfig.Spec.RunRegistry == "" {
@@ -175,7 +255,10 @@ func (ap *Platform) HandleDeployFunction(existingFunctionConfig *functionconfig.
 		}
 	}
 
+	buildDuration := time.Since(buildStartTime)
+
 	// wrap the deployer's deploy with the base HandleDeployFunction
+	deployStartTime := time.Now()
 	deployResult, err := onAfterBuild(buildResult, buildErr)
 	if buildErr != nil || err != nil {
 		return nil, errors.Wrap(err, "Failed to deploy function")
@@ -191,6 +274,9 @@ func (ap *Platform) HandleDeployFunction(existingFunctionConfig *functionconfig.
 		deployResult.CreateFunctionBuildResult = *buildResult
 	}
 
+	deployResult.BuildDuration = buildDuration
+	deployResult.DeployDuration = time.Since(deployStartTime)
+
 	// indicate that we're done
 	createFunctionOptions.Logger.InfoWith("Function deploy complete", "httpPort", deployResult.Port)
 
@@ -209,6 +295,10 @@ func (ap *Platform) EnrichCreateFunctionOptions(createFunctionOptions *platform.
 		return errors.Wrap(err, "Failed enriching project name")
 	}
 
+	if err := ap.enrichProjectDefaults(createFunctionOptions); err != nil {
+		return errors.Wrap(err, "Failed enriching project defaults")
+	}
+
 	if err := ap.enrichImageName(createFunctionOptions); err != nil {
 		return errors.Wrap(err, "Failed enriching image name")
 	}
@@ -244,6 +334,18 @@ func (ap *Platform) ValidateCreateFunctionOptions(createFunctionOptions *platfor
 		return errors.Wrap(err, "Project existence validation failed")
 	}
 
+	if err := ap.validateFunctionReferences(createFunctionOptions); err != nil {
+		return errors.Wrap(err, "Function references validation failed")
+	}
+
+	if err := ap.validateProjectQuotas(createFunctionOptions); err != nil {
+		return errors.Wrap(err, "Project quotas validation failed")
+	}
+
+	if err := ap.validateExternalSecretEnv(createFunctionOptions); err != nil {
+		return errors.Wrap(err, "External secret environment variables validation failed")
+	}
+
 	return nil
 }
 
@@ -274,7 +376,46 @@ func (ap *Platform) ValidateDeleteProjectOptions(deleteProjectOptions *platform.
 
 // CreateFunctionInvocation will invoke a previously deployed function
 func (ap *Platform) CreateFunctionInvocation(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions) (*platform.CreateFunctionInvocationResult, error) {
-	return ap.invoker.invoke(createFunctionInvocationOptions)
+	createFunctionInvocationResult, err := ap.invoker.invoke(createFunctionInvocationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// only invocations on behalf of someone (e.g. from the dashboard) are audited, not those of nuctl or tests
+	if createFunctionInvocationOptions.Identity != nil {
+		ap.RecordAuditOperation(platform.AuditOperationInvoke,
+			createFunctionInvocationOptions.Identity,
+			createFunctionInvocationOptions.Namespace,
+			createFunctionInvocationOptions.Name,
+			nil,
+			nil)
+	}
+
+	return createFunctionInvocationResult, nil
+}
+
+// RecordAuditOperation records an operation performed on a function in the platform's audit log, along with
+// the changes between the function's previous and current configurations. the operation was already
+// performed, so failing to record it is only logged
+func (ap *Platform) RecordAuditOperation(operation platform.AuditOperation,
+	identity *platform.Identity,
+	namespace string,
+	name string,
+	previousConfig *functionconfig.Config,
+	currentConfig *functionconfig.Config) {
+
+	auditRecord, err := platform.NewAuditRecord(operation, identity, namespace, name, previousConfig, currentConfig)
+	if err == nil {
+		err = ap.platform.CreateAuditRecord(auditRecord)
+	}
+
+	if err != nil {
+		ap.Logger.WarnWith("Failed to record audit operation",
+			"operation", operation,
+			"namespace", namespace,
+			"name", name,
+			"err", err.Error())
+	}
 }
 
 // GetHealthCheckMode returns the healthcheck mode the platform requires
@@ -284,6 +425,16 @@ func (ap *Platform) GetHealthCheckMode() platform.HealthCheckMode {
 	return platform.HealthCheckModeExternal
 }
 
+// CreateAuditRecord adds a record of an operation performed on a function to the audit log
+func (ap *Platform) CreateAuditRecord(auditRecord *platform.AuditRecord) error {
+	return errors.New("Unsupported")
+}
+
+// GetAuditRecords returns the records of the operations performed on functions, oldest first
+func (ap *Platform) GetAuditRecords(getAuditRecordsOptions *platform.GetAuditRecordsOptions) ([]platform.AuditRecord, error) {
+	return nil, errors.New("Unsupported")
+}
+
 // CreateProject will probably create a new project
 func (ap *Platform) CreateProject(createProjectOptions *platform.CreateProjectOptions) error {
 	return errors.New("Unsupported")
@@ -325,6 +476,26 @@ func (ap *Platform) GetFunctionEvents(getFunctionEventsOptions *platform.GetFunc
 	return nil, errors.New("Unsupported")
 }
 
+// CreateAPIGateway will create a new API gateway, routing requests to functions
+func (ap *Platform) CreateAPIGateway(createAPIGatewayOptions *platform.CreateAPIGatewayOptions) error {
+	return errors.New("Unsupported")
+}
+
+// UpdateAPIGateway will update a previously existing API gateway
+func (ap *Platform) UpdateAPIGateway(updateAPIGatewayOptions *platform.UpdateAPIGatewayOptions) error {
+	return errors.New("Unsupported")
+}
+
+// DeleteAPIGateway will delete a previously existing API gateway
+func (ap *Platform) DeleteAPIGateway(deleteAPIGatewayOptions *platform.DeleteAPIGatewayOptions) error {
+	return errors.New("Unsupported")
+}
+
+// GetAPIGateways will list existing API gateways
+func (ap *Platform) GetAPIGateways(getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
+	return nil, errors.New("Unsupported")
+}
+
 // SetExternalIPAddresses configures the IP addresses invocations will use, if "via" is set to "external-ip".
 // If this is not invoked, each platform will try to discover these addresses automatically
 func (ap *Platform) SetExternalIPAddresses(externalIPAddresses []string) error {
@@ -391,6 +562,18 @@ func (ap *Platform) GetBaseImageRegistry(registry string) string {
 	return ap.ContainerBuilder.GetBaseImageRegistry(registry)
 }
 
+// GetRuntimeImages returns the images and package indexes the platform configuration has builds of the
+// runtime use, or nil if they use the runtime's defaults
+func (ap *Platform) GetRuntimeImages(runtimeName string) *platformconfig.RuntimeImages {
+
+	// nuctl creates platforms with its own configuration, so only the dashboard's builds are overridden
+	if platformConfig, ok := ap.Config.(*platformconfig.Config); ok {
+		return platformConfig.GetRuntimeImages(runtimeName)
+	}
+
+	return nil
+}
+
 // GetOnbuildImageRegistry returns onbuild image registry
 func (ap *Platform) GetOnbuildImageRegistry(registry string) string {
 	return ap.ContainerBuilder.GetOnbuildImageRegistry(registry)
@@ -401,6 +584,11 @@ func (ap *Platform) GetDefaultRegistryCredentialsSecretName() string {
 	return ap.ContainerBuilder.GetDefaultRegistryCredentialsSecretName()
 }
 
+// PruneBuildCache removes the package manager caches persisted across builds
+func (ap *Platform) PruneBuildCache() error {
+	return ap.ContainerBuilder.PruneBuildCache(ap.platform.ResolveDefaultNamespace("@nuclio.selfNamespace"))
+}
+
 func (ap *Platform) functionBuildRequired(createFunctionOptions *platform.CreateFunctionOptions) (bool, error) {
 
 	// if neverBuild was passed explicitly don't build
@@ -710,6 +898,21 @@ func (ap *Platform) enrichProjectName(createFunctionOptions *platform.CreateFunc
 	return nil
 }
 
+// enrichProjectDefaults sets the defaults of the function's project where the function doesn't set them
+func (ap *Platform) enrichProjectDefaults(createFunctionOptions *platform.CreateFunctionOptions) error {
+	project, err := ap.getFunctionProject(createFunctionOptions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function project")
+	}
+
+	// a missing project fails the validation
+	if project != nil {
+		project.GetConfig().Spec.ApplyDefaults(&createFunctionOptions.FunctionConfig)
+	}
+
+	return nil
+}
+
 // If a user specify the image name to be built - add "projectName-functionName-" prefix to it
 func (ap *Platform) enrichImageName(createFunctionOptions *platform.CreateFunctionOptions) error {
 	if ap.ImageNamePrefixTemplate == "" {
@@ -785,6 +988,118 @@ func (ap *Platform) validateProjectExists(createFunctionOptions *platform.Create
 	return nil
 }
 
+// validateFunctionReferences verifies that the functions the function references which exist belong to the
+// projects they're referenced in. functions which don't exist yet are resolved once they're deployed
+func (ap *Platform) validateFunctionReferences(createFunctionOptions *platform.CreateFunctionOptions) error {
+	functionReferences, err := createFunctionOptions.FunctionConfig.Spec.GetFunctionReferences(
+		createFunctionOptions.FunctionConfig.Meta.Labels["nuclio.io/project-name"])
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function references")
+	}
+
+	for _, functionReference := range functionReferences {
+		functions, err := ap.platform.GetFunctions(&platform.GetFunctionsOptions{
+			Name:      functionReference.FunctionName,
+			Namespace: createFunctionOptions.FunctionConfig.Meta.Namespace,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get referenced function %s", functionReference.FunctionName)
+		}
+
+		if len(functions) == 0 {
+			createFunctionOptions.Logger.WarnWith("Referenced function doesn't exist yet",
+				"alias", functionReference.Alias,
+				"function", functionReference.FunctionName)
+			continue
+		}
+
+		// function names are unique in a namespace, so the project is only there to catch mistakes
+		referencedProjectName := functions[0].GetConfig().Meta.Labels["nuclio.io/project-name"]
+		if referencedProjectName != functionReference.ProjectName {
+			return errors.Errorf("Referenced function %s belongs to project %s, not %s",
+				functionReference.FunctionName,
+				referencedProjectName,
+				functionReference.ProjectName)
+		}
+	}
+
+	return nil
+}
+
+// validateExternalSecretEnv verifies that the secret providers the function's external secret environment
+// variables reference are configured, so that a misconfigured function fails to deploy rather than to start
+func (ap *Platform) validateExternalSecretEnv(createFunctionOptions *platform.CreateFunctionOptions) error {
+
+	// nuctl creates platforms with its own configuration, so only the dashboard's deployments are validated
+	platformConfig, ok := ap.Config.(*platformconfig.Config)
+	if !ok {
+		return nil
+	}
+
+	for _, envVar := range createFunctionOptions.FunctionConfig.Spec.ExternalSecretEnv {
+		if envVar.ValueFrom.ExternalSecret == nil {
+			continue
+		}
+
+		providerName := envVar.ValueFrom.ExternalSecret.Provider
+		if _, found := platformConfig.SecretProviders[providerName]; !found {
+			return errors.Errorf("Environment variable %s references unknown secret provider %s",
+				envVar.Name,
+				providerName)
+		}
+	}
+
+	return nil
+}
+
+// validateProjectQuotas verifies that deploying the function doesn't exceed the quotas of its project
+func (ap *Platform) validateProjectQuotas(createFunctionOptions *platform.CreateFunctionOptions) error {
+	project, err := ap.getFunctionProject(createFunctionOptions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function project")
+	}
+
+	if project == nil || project.GetConfig().Spec.Quotas == nil {
+		return nil
+	}
+
+	functions, err := ap.platform.GetFunctions(&platform.GetFunctionsOptions{
+		Namespace: createFunctionOptions.FunctionConfig.Meta.Namespace,
+		Labels: fmt.Sprintf("nuclio.io/project-name=%s",
+			createFunctionOptions.FunctionConfig.Meta.Labels["nuclio.io/project-name"]),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get project functions")
+	}
+
+	var projectFunctionConfigs []*functionconfig.Config
+	for _, function := range functions {
+		projectFunctionConfigs = append(projectFunctionConfigs, function.GetConfig())
+	}
+
+	return project.GetConfig().Spec.ValidateQuotas(&createFunctionOptions.FunctionConfig, projectFunctionConfigs)
+}
+
+// getFunctionProject returns the project of the function, or nil if it doesn't exist
+func (ap *Platform) getFunctionProject(createFunctionOptions *platform.CreateFunctionOptions) (
+	platform.Project, error) {
+	projects, err := ap.platform.GetProjects(&platform.GetProjectsOptions{
+		Meta: platform.ProjectMeta{
+			Name:      createFunctionOptions.FunctionConfig.Meta.Labels["nuclio.io/project-name"],
+			Namespace: createFunctionOptions.FunctionConfig.Meta.Namespace,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed getting projects")
+	}
+
+	if len(projects) == 0 {
+		return nil, nil
+	}
+
+	return projects[0], nil
+}
+
 func (ap *Platform) validateTriggers(createFunctionOptions *platform.CreateFunctionOptions) error {
 
 	var httpTriggerExists bool
@@ -824,3 +1139,17 @@ func (ap *Platform) enrichMinMaxReplicas(createFunctionOptions *platform.CreateF
 		createFunctionOptions.FunctionConfig.Spec.MaxReplicas = createFunctionOptions.FunctionConfig.Spec.MinReplicas
 	}
 }
+
+// isTransientBuildError returns true if the build error looks like a network/registry hiccup rather than
+// an error in the function itself
+func isTransientBuildError(err error) bool {
+	errorStack := errors.GetErrorStackString(err, 10)
+
+	for _, transientBuildErrorPattern := range transientBuildErrorPatterns {
+		if transientBuildErrorPattern.MatchString(errorStack) {
+			return true
+		}
+	}
+
+	return false
+}
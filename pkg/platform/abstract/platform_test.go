@@ -12,6 +12,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/version"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/rs/xid"
@@ -118,7 +119,7 @@ func (suite *TestAbstractSuite) TestMinMaxReplicas() {
 	} {
 
 		// name it with index and shift with 65 to get A as first letter
-		functionName := string(idx + 65)
+		functionName := string(rune(idx + 65))
 		functionConfig := *functionconfig.NewConfig()
 
 		createFunctionOptions := &platform.CreateFunctionOptions{
@@ -210,3 +211,29 @@ func TestAbstractPlatformTestSuite(t *testing.T) {
 
 	suite.Run(t, new(TestAbstractSuite))
 }
+
+func TestIsTransientBuildError(t *testing.T) {
+	for _, testCase := range []struct {
+		err       error
+		transient bool
+	}{
+		{errors.Wrap(errors.New("dial tcp: lookup pypi.org: Temporary failure in name resolution"), "Failed to build"), true},
+		{errors.New("Get https://registry-1.docker.io/v2/: net/http: TLS handshake timeout"), true},
+		{errors.New("read tcp 10.0.0.1:1234->10.0.0.2:443: read: connection reset by peer"), true},
+		{errors.New("received unexpected HTTP status: 503 Service Unavailable"), true},
+		{errors.New("toomanyrequests: You have reached your pull rate limit. You may increase the limit by authenticating and upgrading"), true},
+		{errors.New("error pulling image configuration: Get https://registry/v2/blobs/sha256:abc: unexpected EOF"), true},
+		{errors.New("Failed to create job: the server is currently unable to handle the request (post jobs.batch)"), true},
+		{errors.New("NewConnectionError: Failed to establish a new connection: [Errno -3] Temporary failure in name resolution"), true},
+		{errors.New("ERROR: No matching distribution found for nonexistent-package"), false},
+		{errors.New("main.go:10: undefined: foo"), false},
+
+		// errors of the function's own code, which merely mention timeouts, EOFs or status codes
+		{errors.New("main.go:12:1: syntax error: unexpected EOF"), false},
+		{errors.New("main.go:503:2: undefined: handleTimeout"), false},
+		{errors.New("FAILED test_handler.py::test_request_timed_out - AssertionError"), false},
+		{errors.New("npm ERR! 429 quota exceeded in postinstall script"), false},
+	} {
+		assert.Equal(t, testCase.transient, isTransientBuildError(testCase.err), testCase.err.Error())
+	}
+}
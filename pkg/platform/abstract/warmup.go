@@ -0,0 +1,150 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abstract
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// how long a single warmup request may take - a cold runtime may take a while to compile the handler
+const warmupRequestTimeout = 60 * time.Second
+
+var warmupHTTPClient = &http.Client{Timeout: warmupRequestTimeout}
+
+// WarmUpFunction sends the function's warmup requests to one of its replicas, listening at the given address
+// (host:port). failing requests don't stop the warmup, and are counted in the returned status
+func WarmUpFunction(parentLogger logger.Logger,
+	replica string,
+	address string,
+	warmup *functionconfig.Warmup) *functionconfig.WarmupStatus {
+
+	count := warmup.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	concurrency := warmup.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	if concurrency > count {
+		concurrency = count
+	}
+
+	warmupURL := fmt.Sprintf("http://%s/%s", address, strings.TrimPrefix(warmup.Path, "/"))
+
+	parentLogger.DebugWith("Warming up function replica",
+		"replica", replica,
+		"url", warmupURL,
+		"count", count,
+		"concurrency", concurrency)
+
+	warmupStatus := &functionconfig.WarmupStatus{
+		Replica:     replica,
+		Invocations: count,
+	}
+
+	var statusLock sync.Mutex
+	var waitGroup sync.WaitGroup
+	requests := make(chan struct{}, count)
+
+	for requestIdx := 0; requestIdx < count; requestIdx++ {
+		requests <- struct{}{}
+	}
+
+	close(requests)
+
+	startTime := time.Now()
+
+	for workerIdx := 0; workerIdx < concurrency; workerIdx++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for range requests {
+				if err := sendWarmupRequest(warmupURL, warmup.Body); err != nil {
+					statusLock.Lock()
+					warmupStatus.Failures++
+					warmupStatus.LastError = errors.RootCause(err).Error()
+					statusLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+
+	now := time.Now()
+	warmupStatus.Time = &now
+	warmupStatus.DurationMilliseconds = now.Sub(startTime).Milliseconds()
+
+	if warmupStatus.Failures > 0 {
+		parentLogger.WarnWith("Some of the function replica's warmup requests failed",
+			"replica", replica,
+			"failures", warmupStatus.Failures,
+			"invocations", warmupStatus.Invocations,
+			"lastError", warmupStatus.LastError)
+	} else {
+		parentLogger.InfoWith("Function replica warmed up",
+			"replica", replica,
+			"invocations", warmupStatus.Invocations,
+			"durationMilliseconds", warmupStatus.DurationMilliseconds)
+	}
+
+	return warmupStatus
+}
+
+func sendWarmupRequest(warmupURL string, body string) error {
+	method := http.MethodGet
+	if body != "" {
+		method = http.MethodPost
+	}
+
+	request, err := http.NewRequest(method, warmupURL, strings.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create warmup request")
+	}
+
+	response, err := warmupHTTPClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send warmup request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	// drain the body so that the connection is reused by the next requests
+	io.Copy(ioutil.Discard, response.Body) // nolint: errcheck
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("Got unexpected response status %d", response.StatusCode)
+	}
+
+	return nil
+}
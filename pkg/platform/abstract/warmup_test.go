@@ -0,0 +1,117 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abstract
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type warmupTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *warmupTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *warmupTestSuite) TestWarmUpFunction() {
+	var requests int32
+	var bodiesLock sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		suite.Require().Equal(http.MethodPost, request.Method)
+		suite.Require().Equal("/warm", request.URL.Path)
+
+		body, _ := ioutil.ReadAll(request.Body)
+
+		bodiesLock.Lock()
+		bodies = append(bodies, string(body))
+		bodiesLock.Unlock()
+	}))
+	defer server.Close()
+
+	warmupStatus := WarmUpFunction(suite.logger,
+		"my-replica",
+		strings.TrimPrefix(server.URL, "http://"),
+		&functionconfig.Warmup{
+			Path:        "/warm",
+			Body:        "ping",
+			Count:       5,
+			Concurrency: 2,
+		})
+
+	suite.Require().EqualValues(5, atomic.LoadInt32(&requests))
+	suite.Require().Equal([]string{"ping", "ping", "ping", "ping", "ping"}, bodies)
+	suite.Require().Equal("my-replica", warmupStatus.Replica)
+	suite.Require().Equal(5, warmupStatus.Invocations)
+	suite.Require().Zero(warmupStatus.Failures)
+	suite.Require().Empty(warmupStatus.LastError)
+	suite.Require().NotNil(warmupStatus.Time)
+}
+
+func (suite *warmupTestSuite) TestWarmUpFunctionFailures() {
+	var requests int32
+
+	// every other request fails
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal(http.MethodGet, request.Method)
+
+		if atomic.AddInt32(&requests, 1)%2 == 0 {
+			responseWriter.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	// the count defaults to a single request
+	warmupStatus := WarmUpFunction(suite.logger,
+		"my-replica",
+		strings.TrimPrefix(server.URL, "http://"),
+		&functionconfig.Warmup{})
+	suite.Require().Equal(1, warmupStatus.Invocations)
+	suite.Require().Zero(warmupStatus.Failures)
+
+	warmupStatus = WarmUpFunction(suite.logger,
+		"my-replica",
+		strings.TrimPrefix(server.URL, "http://"),
+		&functionconfig.Warmup{Count: 4})
+	suite.Require().Equal(4, warmupStatus.Invocations)
+	suite.Require().Equal(2, warmupStatus.Failures)
+	suite.Require().Contains(warmupStatus.LastError, "500")
+}
+
+func TestWarmupTestSuite(t *testing.T) {
+	suite.Run(t, new(warmupTestSuite))
+}
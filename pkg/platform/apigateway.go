@@ -0,0 +1,100 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type APIGateway interface {
+
+	// GetConfig returns the API gateway config
+	GetConfig() *APIGatewayConfig
+}
+
+type AbstractAPIGateway struct {
+	Logger           logger.Logger
+	Platform         Platform
+	APIGatewayConfig APIGatewayConfig
+}
+
+func NewAbstractAPIGateway(parentLogger logger.Logger,
+	parentPlatform Platform,
+	apiGatewayConfig APIGatewayConfig) (*AbstractAPIGateway, error) {
+
+	return &AbstractAPIGateway{
+		Logger:           parentLogger.GetChild("api_gateway"),
+		Platform:         parentPlatform,
+		APIGatewayConfig: apiGatewayConfig,
+	}, nil
+}
+
+// GetConfig returns the API gateway config
+func (aag *AbstractAPIGateway) GetConfig() *APIGatewayConfig {
+	return &aag.APIGatewayConfig
+}
+
+// Validate fails on an API gateway the platforms can't route requests through, defaulting to no authentication
+func (agc *APIGatewayConfig) Validate() error {
+	if agc.Meta.Name == "" {
+		return errors.New("API gateway name must be provided")
+	}
+
+	switch agc.Spec.AuthenticationMode {
+	case "":
+		agc.Spec.AuthenticationMode = APIGatewayAuthenticationModeNone
+	case APIGatewayAuthenticationModeNone:
+	case APIGatewayAuthenticationModeBasicAuth:
+		if agc.Spec.Authentication == nil ||
+			agc.Spec.Authentication.BasicAuth == nil ||
+			agc.Spec.Authentication.BasicAuth.Username == "" ||
+			agc.Spec.Authentication.BasicAuth.Password == "" {
+			return errors.New("Basic authentication requires a username and a password")
+		}
+	default:
+		return errors.Errorf("Unsupported authentication mode: %s", agc.Spec.AuthenticationMode)
+	}
+
+	// a primary upstream, optionally with a canary getting some of the requests
+	if len(agc.Spec.Upstreams) == 0 || len(agc.Spec.Upstreams) > 2 {
+		return errors.New("An API gateway must have one or two upstreams")
+	}
+
+	for _, upstream := range agc.Spec.Upstreams {
+		if upstream.Kind != APIGatewayUpstreamKindNuclioFunction {
+			return errors.Errorf("Unsupported upstream kind: %s", upstream.Kind)
+		}
+
+		if upstream.NuclioFunction == nil || upstream.NuclioFunction.Name == "" {
+			return errors.New("Upstream must name a function")
+		}
+	}
+
+	if len(agc.Spec.Upstreams) == 2 {
+		if agc.Spec.Upstreams[0].NuclioFunction.Name == agc.Spec.Upstreams[1].NuclioFunction.Name {
+			return errors.New("The canary upstream must be a different function than the primary one")
+		}
+
+		canaryPercentage := agc.Spec.Upstreams[1].Percentage
+		if canaryPercentage < 1 || canaryPercentage > 99 {
+			return errors.Errorf("Canary upstream percentage must be between 1 and 99, got %d", canaryPercentage)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// MaxAuditRecords is the number of audit records kept for each namespace. older records are discarded
+const MaxAuditRecords = 1000
+
+// redactedValue replaces the values of sensitive fields in the changes of audit records
+const redactedValue = "<redacted>"
+
+type AuditOperation string
+
+const (
+	AuditOperationDeploy AuditOperation = "deploy"
+	AuditOperationImport AuditOperation = "import"
+	AuditOperationUpdate AuditOperation = "update"
+	AuditOperationDelete AuditOperation = "delete"
+	AuditOperationInvoke AuditOperation = "invoke"
+)
+
+// Identity identifies who performed an operation, and through what (e.g. nuctl, dashboard)
+type Identity struct {
+	User   string `json:"user,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// GetProcessIdentity returns the identity of the user running the current process, with the process's
+// executable as the source
+func GetProcessIdentity() *Identity {
+	identity := &Identity{
+		Source: filepath.Base(os.Args[0]),
+	}
+
+	if currentUser, err := user.Current(); err == nil {
+		identity.User = currentUser.Username
+	}
+
+	return identity
+}
+
+// AuditChange is a change to a field of a function's configuration, by the field's path
+// (e.g. spec.env[0].value)
+type AuditChange struct {
+	Path     string      `json:"path"`
+	Previous interface{} `json:"previous,omitempty"`
+	Current  interface{} `json:"current,omitempty"`
+}
+
+// AuditRecord records an operation performed on a function
+type AuditRecord struct {
+	Time      time.Time      `json:"time"`
+	Operation AuditOperation `json:"operation"`
+	Namespace string         `json:"namespace"`
+	Function  string         `json:"function"`
+	Identity
+	Changes []AuditChange `json:"changes,omitempty"`
+}
+
+// NewAuditRecord creates a record of an operation on a function, by the given identity (or the identity of
+// the current process, if nil). the changes are the differences between the previous and current
+// configurations of the function, either of which may be nil
+func NewAuditRecord(operation AuditOperation,
+	identity *Identity,
+	namespace string,
+	name string,
+	previousConfig *functionconfig.Config,
+	currentConfig *functionconfig.Config) (*AuditRecord, error) {
+
+	if identity == nil {
+		identity = GetProcessIdentity()
+	}
+
+	changes, err := DiffFunctionConfigs(previousConfig, currentConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to diff function configurations")
+	}
+
+	return &AuditRecord{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Namespace: namespace,
+		Function:  name,
+		Identity:  *identity,
+		Changes:   changes,
+	}, nil
+}
+
+// AddAuditRecord returns the records with the given record added after the latest, discarding the oldest
+// records beyond MaxAuditRecords
+func AddAuditRecord(records []AuditRecord, record *AuditRecord) []AuditRecord {
+	records = append(records, *record)
+
+	if len(records) > MaxAuditRecords {
+		records = records[len(records)-MaxAuditRecords:]
+	}
+
+	return records
+}
+
+// FilterAuditRecords returns the records of the function (or of all functions, if empty) that were recorded
+// at or after since (or all of them, if zero)
+func FilterAuditRecords(records []AuditRecord, name string, since time.Time) []AuditRecord {
+	var filteredRecords []AuditRecord
+
+	for _, record := range records {
+		if name != "" && record.Function != name {
+			continue
+		}
+
+		if !since.IsZero() && record.Time.Before(since) {
+			continue
+		}
+
+		filteredRecords = append(filteredRecords, record)
+	}
+
+	return filteredRecords
+}
+
+// DiffFunctionConfigs returns the changes between two configurations of a function, sorted by path. fields
+// that change on every deploy (e.g. the build timestamp) are ignored, the values of sensitive fields are
+// redacted and the function's source code is represented by its digest
+func DiffFunctionConfigs(previousConfig *functionconfig.Config,
+	currentConfig *functionconfig.Config) ([]AuditChange, error) {
+
+	previousValue, previousSecrets, err := encodeAuditedFunctionConfig(previousConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode previous function configuration")
+	}
+
+	currentValue, currentSecrets, err := encodeAuditedFunctionConfig(currentConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode current function configuration")
+	}
+
+	var changes []AuditChange
+	diffValues("", previousValue, currentValue, &changes)
+
+	// scrubbed values are replaced by references to their key, so a changed value with an unchanged key isn't
+	// a difference in the encoded configurations
+	for secretKey, previousSecret := range previousSecrets {
+		if currentSecret, found := currentSecrets[secretKey]; found && currentSecret != previousSecret {
+			changes = append(changes, AuditChange{
+				Path:     "spec." + secretKey,
+				Previous: redactedValue,
+				Current:  redactedValue,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// encodeAuditedFunctionConfig returns the configuration as generic JSON values, with its sensitive values
+// scrubbed, along with the scrubbed values by key
+func encodeAuditedFunctionConfig(functionConfig *functionconfig.Config) (interface{}, map[string]string, error) {
+	if functionConfig == nil {
+		return nil, nil, nil
+	}
+
+	// deep copy through json, so that the given configuration is left untouched
+	encodedConfig, err := json.Marshal(functionConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to encode function configuration")
+	}
+
+	auditedConfig := functionconfig.Config{}
+	if err := json.Unmarshal(encodedConfig, &auditedConfig); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to decode function configuration")
+	}
+
+	auditedConfig.Spec.ImageHash = ""
+	auditedConfig.Spec.Build.Timestamp = 0
+	auditedConfig.Spec.Build.Mode = ""
+	delete(auditedConfig.Meta.Annotations, functionconfig.FunctionAnnotationAppliedConfigHash)
+
+	if auditedConfig.Spec.Build.FunctionSourceCode != "" {
+		auditedConfig.Spec.Build.FunctionSourceCode = fmt.Sprintf("sha256:%x",
+			sha256.Sum256([]byte(auditedConfig.Spec.Build.FunctionSourceCode)))
+	}
+
+	scrubbedSecrets := auditedConfig.ScrubSecrets(functionconfig.DefaultSensitiveKeyPattern)
+
+	encodedConfig, err = json.Marshal(auditedConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to encode audited function configuration")
+	}
+
+	var auditedValue interface{}
+	if err := json.Unmarshal(encodedConfig, &auditedValue); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to decode audited function configuration")
+	}
+
+	return auditedValue, scrubbedSecrets, nil
+}
+
+// diffValues appends the changes between two generic JSON values, recursing into objects and lists
+func diffValues(path string, previousValue interface{}, currentValue interface{}, changes *[]AuditChange) {
+	previousMap, previousIsMap := previousValue.(map[string]interface{})
+	currentMap, currentIsMap := currentValue.(map[string]interface{})
+
+	// a missing object is diffed as an empty one, so that its fields are listed
+	if (previousIsMap || previousValue == nil) && (currentIsMap || currentValue == nil) &&
+		(previousIsMap || currentIsMap) {
+
+		for key, previousFieldValue := range previousMap {
+			diffValues(joinAuditPath(path, key), previousFieldValue, currentMap[key], changes)
+		}
+
+		for key, currentFieldValue := range currentMap {
+			if _, found := previousMap[key]; !found {
+				diffValues(joinAuditPath(path, key), nil, currentFieldValue, changes)
+			}
+		}
+
+		return
+	}
+
+	previousList, previousIsList := previousValue.([]interface{})
+	currentList, currentIsList := currentValue.([]interface{})
+
+	// likewise for a missing list
+	if (previousIsList || previousValue == nil) && (currentIsList || currentValue == nil) &&
+		(previousIsList || currentIsList) {
+
+		for itemIdx := 0; itemIdx < len(previousList) || itemIdx < len(currentList); itemIdx++ {
+			var previousItem, currentItem interface{}
+
+			if itemIdx < len(previousList) {
+				previousItem = previousList[itemIdx]
+			}
+
+			if itemIdx < len(currentList) {
+				currentItem = currentList[itemIdx]
+			}
+
+			diffValues(fmt.Sprintf("%s[%d]", path, itemIdx), previousItem, currentItem, changes)
+		}
+
+		return
+	}
+
+	// fields that aren't omitted when empty are missing from a configuration that doesn't exist
+	if isEmptyAuditValue(previousValue) && isEmptyAuditValue(currentValue) {
+		return
+	}
+
+	if !reflect.DeepEqual(previousValue, currentValue) {
+		*changes = append(*changes, AuditChange{
+			Path:     path,
+			Previous: previousValue,
+			Current:  currentValue,
+		})
+	}
+}
+
+func isEmptyAuditValue(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typedValue == ""
+	case float64:
+		return typedValue == 0
+	case bool:
+		return !typedValue
+	case map[string]interface{}:
+		return len(typedValue) == 0
+	case []interface{}:
+		return len(typedValue) == 0
+	}
+
+	return false
+}
+
+func joinAuditPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type auditTestSuite struct {
+	suite.Suite
+}
+
+func (suite *auditTestSuite) TestDiffFunctionConfigs() {
+	previousConfig := functionconfig.Config{}
+	previousConfig.Meta.Name = "my-function"
+	previousConfig.Spec.Runtime = "python:3.6"
+	previousConfig.Spec.Handler = "main:handler"
+	previousConfig.Spec.Build.Timestamp = 1
+	previousConfig.Spec.Build.FunctionSourceCode = "cHJpbnQoMSk="
+	previousConfig.Spec.Env = []v1.EnvVar{
+		{Name: "LEVEL", Value: "debug"},
+		{Name: "DB_PASSWORD", Value: "previous-password"},
+	}
+
+	currentConfig := previousConfig
+	currentConfig.Spec.Handler = "main:other_handler"
+	currentConfig.Spec.Build.Timestamp = 2
+	currentConfig.Spec.Build.FunctionSourceCode = "cHJpbnQoMik="
+	currentConfig.Spec.Env = []v1.EnvVar{
+		{Name: "LEVEL", Value: "info"},
+		{Name: "DB_PASSWORD", Value: "current-password"},
+		{Name: "REGION", Value: "eu"},
+	}
+
+	changes, err := DiffFunctionConfigs(&previousConfig, &currentConfig)
+	suite.Require().NoError(err)
+
+	var changedPaths []string
+	for _, change := range changes {
+		changedPaths = append(changedPaths, change.Path)
+	}
+
+	// the build timestamp is ignored, and changes are sorted by path
+	suite.Require().Equal([]string{
+		"spec.build.functionSourceCode",
+		"spec.env.DB_PASSWORD",
+		"spec.env[0].value",
+		"spec.env[2].name",
+		"spec.env[2].value",
+		"spec.handler",
+	}, changedPaths)
+
+	// the source code is represented by its digest
+	suite.Require().Contains(changes[0].Previous, "sha256:")
+	suite.Require().NotEqual(changes[0].Previous, changes[0].Current)
+
+	// sensitive values are redacted
+	suite.Require().Equal(AuditChange{
+		Path:     "spec.env.DB_PASSWORD",
+		Previous: redactedValue,
+		Current:  redactedValue,
+	}, changes[1])
+
+	suite.Require().Equal(AuditChange{Path: "spec.env[0].value", Previous: "debug", Current: "info"}, changes[2])
+	suite.Require().Equal(AuditChange{Path: "spec.env[2].name", Current: "REGION"}, changes[3])
+	suite.Require().Equal(AuditChange{
+		Path:     "spec.handler",
+		Previous: "main:handler",
+		Current:  "main:other_handler",
+	}, changes[5])
+
+	for _, change := range changes {
+		suite.Require().NotContains(fmt.Sprint(change.Previous, change.Current), "-password")
+	}
+
+	// the configurations are left untouched
+	suite.Require().Equal("current-password", currentConfig.Spec.Env[1].Value)
+
+	// identical configurations have no changes, and a function that didn't exist has all its fields changed
+	changes, err = DiffFunctionConfigs(&currentConfig, &currentConfig)
+	suite.Require().NoError(err)
+	suite.Require().Empty(changes)
+
+	changes, err = DiffFunctionConfigs(nil, &currentConfig)
+	suite.Require().NoError(err)
+	suite.Require().Contains(changes, AuditChange{Path: "metadata.name", Current: "my-function"})
+	suite.Require().Contains(changes, AuditChange{
+		Path:    "spec.env[1].value",
+		Current: functionconfig.ScrubbedSecretPrefix + "env.DB_PASSWORD",
+	})
+}
+
+func (suite *auditTestSuite) TestAddAndFilterAuditRecords() {
+	now := time.Now().UTC()
+
+	var records []AuditRecord
+	for recordIdx := 0; recordIdx < MaxAuditRecords+2; recordIdx++ {
+		function := "my-function"
+		if recordIdx%2 == 0 {
+			function = "other-function"
+		}
+
+		records = AddAuditRecord(records, &AuditRecord{
+			Time:      now.Add(time.Duration(recordIdx-MaxAuditRecords) * time.Minute),
+			Operation: AuditOperationDeploy,
+			Function:  function,
+		})
+	}
+
+	// the oldest records were discarded
+	suite.Require().Len(records, MaxAuditRecords)
+	suite.Require().Equal(now.Add(-(MaxAuditRecords-2)*time.Minute), records[0].Time)
+
+	filteredRecords := FilterAuditRecords(records, "my-function", now.Add(-2*time.Minute))
+	suite.Require().Len(filteredRecords, 2)
+	suite.Require().Equal(now.Add(-time.Minute), filteredRecords[0].Time)
+	suite.Require().Equal(now.Add(time.Minute), filteredRecords[1].Time)
+
+	suite.Require().Len(FilterAuditRecords(records, "", time.Time{}), MaxAuditRecords)
+}
+
+func (suite *auditTestSuite) TestNewAuditRecord() {
+	record, err := NewAuditRecord(AuditOperationDelete, nil, "nuclio", "my-function", nil, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(GetProcessIdentity().Source, record.Source)
+	suite.Require().Empty(record.Changes)
+
+	record, err = NewAuditRecord(AuditOperationInvoke,
+		&Identity{User: "alice", Source: "dashboard"},
+		"nuclio",
+		"my-function",
+		nil,
+		nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(Identity{User: "alice", Source: "dashboard"}, record.Identity)
+	suite.Require().Equal(AuditOperationInvoke, record.Operation)
+	suite.Require().False(record.Time.IsZero())
+}
+
+func TestAuditTestSuite(t *testing.T) {
+	suite.Run(t, new(auditTestSuite))
+}
@@ -4,5 +4,6 @@ import "github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 
 type Configuration struct {
 	KubeconfigPath                string
+	KubeContext                   string
 	ContainerBuilderConfiguration containerimagebuilderpusher.ContainerBuilderConfiguration
 }
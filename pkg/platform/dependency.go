@@ -0,0 +1,282 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// DefaultDependencyTimeout is the time a function waits for each of the resources it depends on to become ready.
+// dependencies deployed along with it are waited for while they're built, so it's generous
+const DefaultDependencyTimeout = 15 * time.Minute
+
+const defaultDependencyPollInterval = 2 * time.Second
+
+// DependencyScheduler deploys a set of functions, deploying each only once the resources it depends on
+// (spec.dependsOn) are ready - whether they're deployed along with it or already exist
+type DependencyScheduler struct {
+	Logger       logger.Logger
+	Platform     Platform
+	Concurrency  int
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+func NewDependencyScheduler(parentLogger logger.Logger, platform Platform, concurrency int) *DependencyScheduler {
+	return &DependencyScheduler{
+		Logger:       parentLogger.GetChild("dependencies"),
+		Platform:     platform,
+		Concurrency:  concurrency,
+		Timeout:      DefaultDependencyTimeout,
+		PollInterval: defaultDependencyPollInterval,
+	}
+}
+
+// Run deploys each of the functions with deployFunc, no more than the concurrency at a time, and returns the
+// error of each deploy (nil on success) at its index. a function isn't deployed if a function it depends on
+// fails to. if the functions depend on one another in a cycle, none of them is deployed
+func (ds *DependencyScheduler) Run(functionConfigs []*functionconfig.Config,
+	deployFunc func(functionConfig *functionconfig.Config) error) ([]error, error) {
+
+	functionIndexes := map[string]int{}
+	for functionIndex, functionConfig := range functionConfigs {
+		functionIndexes[functionConfig.Meta.Name] = functionIndex
+	}
+
+	functionDependencies := make([][]functionconfig.Dependency, len(functionConfigs))
+	for functionIndex, functionConfig := range functionConfigs {
+		dependencies, err := functionConfig.Spec.GetDependencies()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get the dependencies of function %s", functionConfig.Meta.Name)
+		}
+
+		functionDependencies[functionIndex] = dependencies
+	}
+
+	if err := ds.validateAcyclic(functionConfigs, functionDependencies, functionIndexes); err != nil {
+		return nil, err
+	}
+
+	concurrency := ds.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var waitGroup sync.WaitGroup
+
+	deployErrs := make([]error, len(functionConfigs))
+	deployDoneChans := make([]chan struct{}, len(functionConfigs))
+	for functionIndex := range functionConfigs {
+		deployDoneChans[functionIndex] = make(chan struct{})
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	for functionIndex, functionConfig := range functionConfigs {
+		functionIndex, functionConfig := functionIndex, functionConfig // https://golang.org/doc/faq#closures_and_goroutines
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer close(deployDoneChans[functionIndex])
+
+			// functions waiting for their dependencies don't take a slot, so that they don't starve them
+			for _, dependency := range functionDependencies[functionIndex] {
+				if err := ds.waitForDependency(functionConfig,
+					dependency,
+					functionIndexes,
+					deployDoneChans,
+					deployErrs); err != nil {
+					deployErrs[functionIndex] = err
+					return
+				}
+			}
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			deployErrs[functionIndex] = deployFunc(functionConfig)
+		}()
+	}
+
+	waitGroup.Wait()
+
+	return deployErrs, nil
+}
+
+func (ds *DependencyScheduler) waitForDependency(functionConfig *functionconfig.Config,
+	dependency functionconfig.Dependency,
+	functionIndexes map[string]int,
+	deployDoneChans []chan struct{},
+	deployErrs []error) error {
+
+	if dependency.Kind == functionconfig.DependencyKindFunction {
+		if dependencyIndex, found := functionIndexes[dependency.Name]; found {
+			<-deployDoneChans[dependencyIndex]
+
+			if deployErrs[dependencyIndex] != nil {
+				return errors.Errorf("Dependency %s failed to deploy", dependency)
+			}
+		}
+	}
+
+	// a function which isn't deployed (e.g. imported without deploying) doesn't need its dependencies ready
+	if functionconfig.ShouldSkipDeploy(functionConfig.Meta.Annotations) {
+		return nil
+	}
+
+	ds.Logger.DebugWith("Waiting for dependency to become ready",
+		"function", functionConfig.Meta.Name,
+		"dependency", dependency.String())
+
+	deadline := time.Now().Add(ds.Timeout)
+	for {
+		ready, err := ds.isDependencyReady(functionConfig.Meta.Namespace, dependency)
+		if err != nil {
+			return err
+		}
+
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.Errorf("Timed out waiting for dependency %s to become ready", dependency)
+		}
+
+		time.Sleep(ds.PollInterval)
+	}
+}
+
+// isDependencyReady returns whether the dependency is ready, or an error if it doesn't exist or failed
+func (ds *DependencyScheduler) isDependencyReady(namespace string, dependency functionconfig.Dependency) (bool, error) {
+	switch dependency.Kind {
+	case functionconfig.DependencyKindFunction:
+		functions, err := ds.Platform.GetFunctions(&GetFunctionsOptions{
+			Name:      dependency.Name,
+			Namespace: namespace,
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to get dependency %s", dependency)
+		}
+
+		if len(functions) == 0 {
+			return false, errors.Errorf("Dependency %s not found", dependency)
+		}
+
+		functionStatus := functions[0].GetStatus()
+		switch functionStatus.State {
+		case functionconfig.FunctionStateReady, functionconfig.FunctionStateScaledToZero:
+			return true, nil
+		case functionconfig.FunctionStateError, functionconfig.FunctionStateImported, functionconfig.FunctionStatePaused:
+			return false, errors.Errorf("Dependency %s is in %s state", dependency, functionStatus.State)
+		default:
+			return false, nil
+		}
+
+	case functionconfig.DependencyKindAPIGateway:
+		apiGateways, err := ds.Platform.GetAPIGateways(&GetAPIGatewaysOptions{
+			Meta: APIGatewayMeta{
+				Name:      dependency.Name,
+				Namespace: namespace,
+			},
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to get dependency %s", dependency)
+		}
+
+		if len(apiGateways) == 0 {
+			return false, errors.Errorf("Dependency %s not found", dependency)
+		}
+
+		apiGatewayStatus := apiGateways[0].GetConfig().Status
+		if apiGatewayStatus.State == APIGatewayStateError {
+			return false, errors.Errorf("Dependency %s is in error state: %s",
+				dependency,
+				apiGatewayStatus.LastError)
+		}
+
+		return apiGatewayStatus.State == APIGatewayStateReady, nil
+	}
+
+	return false, errors.Errorf("Unsupported dependency kind: %s", dependency.Kind)
+}
+
+// validateAcyclic fails if functions of the set depend on one another in a cycle, which would have them wait
+// for each other forever
+func (ds *DependencyScheduler) validateAcyclic(functionConfigs []*functionconfig.Config,
+	functionDependencies [][]functionconfig.Dependency,
+	functionIndexes map[string]int) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	visitStates := make([]int, len(functionConfigs))
+	var path []string
+
+	var visit func(functionIndex int) error
+	visit = func(functionIndex int) error {
+		path = append(path, functionConfigs[functionIndex].Meta.Name)
+		defer func() { path = path[:len(path)-1] }()
+
+		switch visitStates[functionIndex] {
+		case visiting:
+
+			// the cycle starts at the function's first visit
+			cycleStartIndex := 0
+			for path[cycleStartIndex] != functionConfigs[functionIndex].Meta.Name {
+				cycleStartIndex++
+			}
+
+			return errors.Errorf("Functions depend on one another in a cycle: %s",
+				strings.Join(path[cycleStartIndex:], " -> "))
+		case visited:
+			return nil
+		}
+
+		visitStates[functionIndex] = visiting
+		for _, dependency := range functionDependencies[functionIndex] {
+			if dependency.Kind != functionconfig.DependencyKindFunction {
+				continue
+			}
+
+			if dependencyIndex, found := functionIndexes[dependency.Name]; found {
+				if err := visit(dependencyIndex); err != nil {
+					return err
+				}
+			}
+		}
+		visitStates[functionIndex] = visited
+
+		return nil
+	}
+
+	for functionIndex := range functionConfigs {
+		if err := visit(functionIndex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
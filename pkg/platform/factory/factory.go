@@ -24,6 +24,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/config"
+	"github.com/nuclio/nuclio/pkg/platform/fake"
 	"github.com/nuclio/nuclio/pkg/platform/kube"
 	"github.com/nuclio/nuclio/pkg/platform/local"
 
@@ -42,7 +43,7 @@ func CreatePlatform(parentLogger logger.Logger,
 	var newPlatform platform.Platform
 	var err error
 
-	containerBuilderConfiguration := getContainerBuilderConfiguration(platformConfiguration)
+	containerBuilderConfiguration := GetContainerBuilderConfiguration(platformConfiguration)
 
 	switch platformType {
 	case "local":
@@ -52,14 +53,18 @@ func CreatePlatform(parentLogger logger.Logger,
 
 	case "kube":
 		newPlatform, err = kube.NewPlatform(parentLogger,
-			getKubeconfigPath(platformConfiguration),
+			GetKubeconfigPath(platformConfiguration),
+			getKubeContext(platformConfiguration),
 			containerBuilderConfiguration,
 			platformConfiguration)
 
+	case fake.Name:
+		newPlatform, err = fake.GetSharedPlatform(parentLogger)
+
 	case "auto":
 
 		// try to get kubeconfig path
-		kubeconfigPath := getKubeconfigPath(platformConfiguration)
+		kubeconfigPath := GetKubeconfigPath(platformConfiguration)
 
 		if kubeconfigPath != "" || kube.IsInCluster() {
 
@@ -128,7 +133,9 @@ func ensureDefaultProjectExistence(parentLogger logger.Logger, p platform.Platfo
 	return nil
 }
 
-func getContainerBuilderConfiguration(platformConfiguration interface{}) *containerimagebuilderpusher.ContainerBuilderConfiguration {
+// GetContainerBuilderConfiguration returns the configuration of the container image builder, either configured
+// or from the environment
+func GetContainerBuilderConfiguration(platformConfiguration interface{}) *containerimagebuilderpusher.ContainerBuilderConfiguration {
 	containerBuilderConfiguration := containerimagebuilderpusher.ContainerBuilderConfiguration{}
 
 	// it might not be a kube configuration
@@ -179,10 +186,21 @@ func getContainerBuilderConfiguration(platformConfiguration interface{}) *contai
 	containerBuilderConfiguration.CacheRepo =
 		common.GetEnvOrDefaultString("NUCLIO_DASHBOARD_KANIKO_CACHE_REPO", "")
 
+	if containerBuilderConfiguration.BuildCachePVC == "" {
+		containerBuilderConfiguration.BuildCachePVC =
+			common.GetEnvOrDefaultString("NUCLIO_KANIKO_BUILD_CACHE_PVC", "")
+	}
+
+	if containerBuilderConfiguration.RegistryAuthProvider == "" {
+		containerBuilderConfiguration.RegistryAuthProvider =
+			common.GetEnvOrDefaultString("NUCLIO_REGISTRY_AUTH_PROVIDER", "")
+	}
+
 	return &containerBuilderConfiguration
 }
 
-func getKubeconfigPath(platformConfiguration interface{}) string {
+// GetKubeconfigPath returns the path of the kubeconfig to use, either configured or the default one
+func GetKubeconfigPath(platformConfiguration interface{}) string {
 	var kubeconfigPath string
 
 	// it might not be a kube configuration
@@ -197,6 +215,14 @@ func getKubeconfigPath(platformConfiguration interface{}) string {
 	return kubeconfigPath
 }
 
+func getKubeContext(platformConfiguration interface{}) string {
+	if kubeConfiguration, ok := platformConfiguration.(*config.Configuration); ok {
+		return kubeConfiguration.KubeContext
+	}
+
+	return ""
+}
+
 func getKubeconfigFromHomeDir() string {
 	homeDir, err := homedir.Dir()
 	if err != nil {
@@ -0,0 +1,76 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+)
+
+type function struct {
+	platform.AbstractFunction
+
+	// what GetFunctionLogs returns, set through SetFunctionLogs
+	logs string
+
+	// what GetFunctionUsage returns, set through SetFunctionUsage
+	usage *platform.FunctionUsage
+
+	// what GetFunctionStreamStatus returns, set through SetFunctionStreamStatus
+	streamStatus []platform.FunctionReplicaStreamStatus
+
+	// what GetFunctionInvocations returns, added through AddFunctionInvocations
+	invocations platform.FunctionInvocations
+}
+
+// GetReplicas returns the current # of replicas and the configured # of replicas. a ready function is
+// considered to have all its configured replicas available immediately
+func (f *function) GetReplicas() (int, int) {
+	configuredReplicas := 1
+
+	switch {
+	case f.Config.Spec.Disable:
+		configuredReplicas = 0
+	case f.Config.Spec.Replicas != nil:
+		configuredReplicas = *f.Config.Spec.Replicas
+	case f.Config.Spec.MinReplicas != nil:
+		configuredReplicas = *f.Config.Spec.MinReplicas
+	}
+
+	if f.Status.State != functionconfig.FunctionStateReady {
+		return 0, configuredReplicas
+	}
+
+	return configuredReplicas, configuredReplicas
+}
+
+type functionTunnel struct {
+	platform *Platform
+	options  *platform.CreateFunctionTunnelOptions
+}
+
+// GetAddress returns a made up local address
+func (t *functionTunnel) GetAddress() string {
+	return "127.0.0.1:8080"
+}
+
+// Close records the tunnel was closed
+func (t *functionTunnel) Close() error {
+	t.platform.recordCall("CloseFunctionTunnel", t.options)
+
+	return nil
+}
@@ -0,0 +1,1318 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Name is the name the fake platform is selected by (e.g. NUCTL_PLATFORM=mock)
+const Name = "mock"
+
+const defaultNamespace = "nuclio"
+
+var sharedPlatform *Platform
+var sharedPlatformLock sync.Mutex
+
+// Platform is an in-memory platform, holding functions, projects, function events and API gateways in maps. Nothing
+// is built or run, which allows exercising commands without docker or kubernetes
+type Platform struct {
+	logger                         logger.Logger
+	lock                           sync.Mutex
+	functions                      map[string]*function
+	functionRevisions              map[string][]platform.FunctionRevision
+	auditRecords                   map[string][]platform.AuditRecord
+	projects                       map[string]*platform.AbstractProject
+	functionEvents                 map[string]*platform.AbstractFunctionEvent
+	apiGateways                    map[string]*platform.AbstractAPIGateway
+	externalIPAddresses            []string
+	defaultHTTPIngressHostTemplate string
+	imageNamePrefixTemplate        string
+
+	// the state deployed functions are left in. defaults to ready - set to building to simulate
+	// functions that take a while to deploy, and use SetFunctionState to complete the deployment
+	DeployedFunctionState functionconfig.FunctionState
+
+	// Clock returns the time functions are deployed at. set it to render deployment times deterministically
+	Clock func() time.Time
+
+	callsLock sync.Mutex
+	calls     []Call
+}
+
+// Call is a call made to the platform, recorded so that tests can assert on the calls commands make
+type Call struct {
+	Name    string
+	Options interface{}
+}
+
+// NewPlatform creates an empty in-memory platform
+func NewPlatform(parentLogger logger.Logger) (*Platform, error) {
+	return &Platform{
+		logger:                parentLogger.GetChild("platform"),
+		functions:             map[string]*function{},
+		functionRevisions:     map[string][]platform.FunctionRevision{},
+		auditRecords:          map[string][]platform.AuditRecord{},
+		projects:              map[string]*platform.AbstractProject{},
+		functionEvents:        map[string]*platform.AbstractFunctionEvent{},
+		apiGateways:           map[string]*platform.AbstractAPIGateway{},
+		DeployedFunctionState: functionconfig.FunctionStateReady,
+		Clock:                 time.Now,
+	}, nil
+}
+
+// GetSharedPlatform returns the process-wide in-memory platform, creating it on first use. nuctl creates
+// a platform per command, so commands executed in the same process (e.g. by tests) share state through it
+func GetSharedPlatform(parentLogger logger.Logger) (*Platform, error) {
+	sharedPlatformLock.Lock()
+	defer sharedPlatformLock.Unlock()
+
+	if sharedPlatform == nil {
+		var err error
+
+		sharedPlatform, err = NewPlatform(parentLogger)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create platform")
+		}
+	}
+
+	return sharedPlatform, nil
+}
+
+// ResetSharedPlatform discards the process-wide in-memory platform, along with all its state
+func ResetSharedPlatform() {
+	sharedPlatformLock.Lock()
+	defer sharedPlatformLock.Unlock()
+
+	sharedPlatform = nil
+}
+
+// GetCalls returns the calls made to the platform, oldest first
+func (p *Platform) GetCalls() []Call {
+	p.callsLock.Lock()
+	defer p.callsLock.Unlock()
+
+	return append([]Call{}, p.calls...)
+}
+
+// GetCallNames returns the names of the calls made to the platform, oldest first
+func (p *Platform) GetCallNames() []string {
+	var callNames []string
+
+	for _, call := range p.GetCalls() {
+		callNames = append(callNames, call.Name)
+	}
+
+	return callNames
+}
+
+// ResetCalls forgets the calls made to the platform so far
+func (p *Platform) ResetCalls() {
+	p.callsLock.Lock()
+	defer p.callsLock.Unlock()
+
+	p.calls = nil
+}
+
+//
+// Function
+//
+
+// CreateFunctionBuild pretends to build the function, returning the image it would have built
+func (p *Platform) CreateFunctionBuild(createFunctionBuildOptions *platform.CreateFunctionBuildOptions) (
+	*platform.CreateFunctionBuildResult, error) {
+
+	p.recordCall("CreateFunctionBuild", createFunctionBuildOptions)
+
+	return &platform.CreateFunctionBuildResult{
+		Image:                 p.getFunctionImage(&createFunctionBuildOptions.FunctionConfig),
+		BuildAttempts:         1,
+		UpdatedFunctionConfig: createFunctionBuildOptions.FunctionConfig,
+	}, nil
+}
+
+// CreateFunction stores the function, passing through the building state, and adds a revision of it
+func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunctionOptions) (
+	*platform.CreateFunctionResult, error) {
+
+	p.recordCall("CreateFunction", createFunctionOptions)
+
+	functionConfig := createFunctionOptions.FunctionConfig
+	if functionConfig.Meta.Name == "" {
+		return nil, nuclio.NewErrBadRequest("Function name must be provided")
+	}
+
+	if functionConfig.Meta.Namespace == "" {
+		functionConfig.Meta.Namespace = defaultNamespace
+	}
+
+	if err := p.applyProjectSettings(&functionConfig); err != nil {
+		return nil, err
+	}
+
+	functionConfig.Spec.Image = p.getFunctionImage(&functionConfig)
+
+	var previousFunctionConfig *functionconfig.Config
+	if existingFunction, found := p.getStoredFunction(functionConfig.Meta.Namespace, functionConfig.Meta.Name); found {
+		previousFunctionConfig = &existingFunction.Config
+	}
+
+	createFunctionOptions.PhaseTimings.Measure(common.PhasePlatformApply, func() error { // nolint: errcheck
+		p.setFunction(&functionConfig, functionconfig.FunctionStateBuilding)
+		return nil
+	})
+
+	// release requester
+	if createFunctionOptions.CreationStateUpdated != nil {
+		createFunctionOptions.CreationStateUpdated <- true
+	}
+
+	if err := createFunctionOptions.PhaseTimings.Measure(common.PhaseReadinessWait, func() error {
+		return p.SetFunctionState(functionConfig.Meta.Namespace,
+			functionConfig.Meta.Name,
+			p.DeployedFunctionState)
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to set deployed function state")
+	}
+
+	p.addFunctionRevision(&functionConfig)
+
+	p.lock.Lock()
+	p.addAuditRecord(platform.AuditOperationDeploy,
+		createFunctionOptions.Identity,
+		functionConfig.Meta.Namespace,
+		functionConfig.Meta.Name,
+		previousFunctionConfig,
+		&functionConfig)
+	p.lock.Unlock()
+
+	return &platform.CreateFunctionResult{
+		CreateFunctionBuildResult: platform.CreateFunctionBuildResult{
+			Image:                 functionConfig.Spec.Image,
+			BuildAttempts:         1,
+			UpdatedFunctionConfig: functionConfig,
+		},
+	}, nil
+}
+
+// UpdateFunction updates the annotations, spec and status of an existing function
+func (p *Platform) UpdateFunction(updateFunctionOptions *platform.UpdateFunctionOptions) error {
+	p.recordCall("UpdateFunction", updateFunctionOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(updateFunctionOptions.FunctionMeta.Namespace),
+		updateFunctionOptions.FunctionMeta.Name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	previousFunctionConfig := function.Config
+
+	if updateFunctionOptions.FunctionMeta.Annotations != nil {
+		function.Config.Meta.Annotations = updateFunctionOptions.FunctionMeta.Annotations
+	}
+
+	if updateFunctionOptions.FunctionSpec != nil {
+		function.Config.Spec = *updateFunctionOptions.FunctionSpec
+	}
+
+	if updateFunctionOptions.FunctionStatus != nil {
+		function.Status = *updateFunctionOptions.FunctionStatus
+	}
+
+	p.addAuditRecord(platform.AuditOperationUpdate,
+		updateFunctionOptions.Identity,
+		function.Config.Meta.Namespace,
+		function.Config.Meta.Name,
+		&previousFunctionConfig,
+		&function.Config)
+
+	return nil
+}
+
+// DeleteFunction deletes a function and its function events
+func (p *Platform) DeleteFunction(deleteFunctionOptions *platform.DeleteFunctionOptions) error {
+	p.recordCall("DeleteFunction", deleteFunctionOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	functionMeta := deleteFunctionOptions.FunctionConfig.Meta
+	functionKey := getKey(p.resolveNamespace(functionMeta.Namespace), functionMeta.Name)
+
+	if _, found := p.functions[functionKey]; !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	delete(p.functions, functionKey)
+	delete(p.functionRevisions, functionKey)
+
+	p.addAuditRecord(platform.AuditOperationDelete,
+		deleteFunctionOptions.Identity,
+		p.resolveNamespace(functionMeta.Namespace),
+		functionMeta.Name,
+		nil,
+		nil)
+
+	for functionEventKey, functionEvent := range p.functionEvents {
+		if functionEvent.FunctionEventConfig.Meta.Labels["nuclio.io/function-name"] == functionMeta.Name {
+			delete(p.functionEvents, functionEventKey)
+		}
+	}
+
+	return nil
+}
+
+// GetFunctionRevisions returns the configurations the function was deployed with, oldest first
+func (p *Platform) GetFunctionRevisions(getFunctionRevisionsOptions *platform.GetFunctionRevisionsOptions) (
+	[]platform.FunctionRevision, error) {
+	p.recordCall("GetFunctionRevisions", getFunctionRevisionsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	functionKey := getKey(p.resolveNamespace(getFunctionRevisionsOptions.Namespace), getFunctionRevisionsOptions.Name)
+	if _, found := p.functions[functionKey]; !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	return append([]platform.FunctionRevision{}, p.functionRevisions[functionKey]...), nil
+}
+
+// CreateAuditRecord adds the record to the audit log of its namespace
+func (p *Platform) CreateAuditRecord(auditRecord *platform.AuditRecord) error {
+	p.recordCall("CreateAuditRecord", auditRecord)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	namespace := p.resolveNamespace(auditRecord.Namespace)
+	p.auditRecords[namespace] = platform.AddAuditRecord(p.auditRecords[namespace], auditRecord)
+
+	return nil
+}
+
+// GetAuditRecords returns the audit records of the namespace, oldest first
+func (p *Platform) GetAuditRecords(getAuditRecordsOptions *platform.GetAuditRecordsOptions) (
+	[]platform.AuditRecord, error) {
+	p.recordCall("GetAuditRecords", getAuditRecordsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return platform.FilterAuditRecords(p.auditRecords[p.resolveNamespace(getAuditRecordsOptions.Namespace)],
+		getAuditRecordsOptions.Name,
+		getAuditRecordsOptions.Since), nil
+}
+
+// CreateFunctionInvocation echoes the request body of a ready function
+func (p *Platform) CreateFunctionInvocation(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions) (
+	*platform.CreateFunctionInvocationResult, error) {
+	p.recordCall("CreateFunctionInvocation", createFunctionInvocationOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(createFunctionInvocationOptions.Namespace),
+		createFunctionInvocationOptions.Name)]
+	if !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	if function.Status.State == functionconfig.FunctionStatePaused {
+		return nil, nuclio.NewErrConflict(fmt.Sprintf("Function %s is paused, resume it to invoke it",
+			createFunctionInvocationOptions.Name))
+	}
+
+	if function.Status.State != functionconfig.FunctionStateReady {
+		return nil, errors.Errorf("Function is not ready (state: %s)", function.Status.State)
+	}
+
+	if createFunctionInvocationOptions.Identity != nil {
+		p.addAuditRecord(platform.AuditOperationInvoke,
+			createFunctionInvocationOptions.Identity,
+			function.Config.Meta.Namespace,
+			function.Config.Meta.Name,
+			nil,
+			nil)
+	}
+
+	// the function echoes the body, read whole if streamed
+	body := createFunctionInvocationOptions.Body
+	if createFunctionInvocationOptions.BodyStream != nil {
+		var err error
+
+		body, err = ioutil.ReadAll(createFunctionInvocationOptions.BodyStream)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read body stream")
+		}
+
+		if bodyStreamCloser, ok := createFunctionInvocationOptions.BodyStream.(io.Closer); ok {
+			bodyStreamCloser.Close() // nolint: errcheck
+		}
+	}
+
+	// echo the content type, so that callers can tell how the body was encoded
+	headers := http.Header{}
+	if contentType := createFunctionInvocationOptions.Headers.Get("Content-Type"); contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+
+	if createFunctionInvocationOptions.Stream {
+		return &platform.CreateFunctionInvocationResult{
+			Headers:    headers,
+			StatusCode: http.StatusOK,
+			BodyStream: ioutil.NopCloser(bytes.NewReader(body)),
+		}, nil
+	}
+
+	return &platform.CreateFunctionInvocationResult{
+		Headers:    headers,
+		Body:       body,
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// GetFunctions returns copies of the stored functions matching the name, namespace and labels
+func (p *Platform) GetFunctions(getFunctionsOptions *platform.GetFunctionsOptions) ([]platform.Function, error) {
+	p.recordCall("GetFunctions", getFunctionsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var functions []platform.Function
+
+	namespace := p.resolveNamespace(getFunctionsOptions.Namespace)
+	labels := common.StringToStringMap(getFunctionsOptions.Labels, "=")
+
+	for _, function := range p.functions {
+		if !getFunctionsOptions.AllNamespaces && function.Config.Meta.Namespace != namespace {
+			continue
+		}
+
+		if getFunctionsOptions.Name != "" && function.Config.Meta.Name != getFunctionsOptions.Name {
+			continue
+		}
+
+		if !labelsMatch(function.Config.Meta.Labels, labels) {
+			continue
+		}
+
+		functionCopy := *function
+		functions = append(functions, &functionCopy)
+	}
+
+	// maps are iterated in random order, so sort for commands to render the same output each time
+	sort.Slice(functions, func(i, j int) bool {
+		return lessNamespacedName(functions[i].GetConfig().Meta.Namespace, functions[i].GetConfig().Meta.Name,
+			functions[j].GetConfig().Meta.Namespace, functions[j].GetConfig().Meta.Name)
+	})
+
+	return functions, nil
+}
+
+// SetFunctionState sets the state of a stored function, to simulate deployment progress
+func (p *Platform) SetFunctionState(namespace string, name string, state functionconfig.FunctionState) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	function.Status.State = state
+
+	// a function becoming ready is deployed
+	if state == functionconfig.FunctionStateReady {
+		lastDeployed := p.Clock().UTC()
+		function.Status.LastDeployed = &lastDeployed
+	}
+
+	return nil
+}
+
+// SetFunctionLogs sets the logs GetFunctionLogs returns for a function
+func (p *Platform) SetFunctionLogs(namespace string, name string, logs string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	function.logs = logs
+
+	return nil
+}
+
+// GetFunctionLogs returns the logs set through SetFunctionLogs. a function has a single replica, named
+// like the function, and following returns once the logs are read
+func (p *Platform) GetFunctionLogs(getFunctionLogsOptions *platform.GetFunctionLogsOptions) (io.ReadCloser, error) {
+	p.recordCall("GetFunctionLogs", getFunctionLogsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(getFunctionLogsOptions.Namespace),
+		getFunctionLogsOptions.Name)]
+	if !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	if getFunctionLogsOptions.Replica != "" && getFunctionLogsOptions.Replica != getFunctionLogsOptions.Name {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Replica not found: %s", getFunctionLogsOptions.Replica))
+	}
+
+	return ioutil.NopCloser(bytes.NewBufferString(function.logs)), nil
+}
+
+// GetFunctionExecCommand returns the command as is, so that it runs on the host. a function has a single replica,
+// named like the function
+func (p *Platform) GetFunctionExecCommand(getFunctionExecCommandOptions *platform.GetFunctionExecCommandOptions) (
+	[]string, error) {
+	p.recordCall("GetFunctionExecCommand", getFunctionExecCommandOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, found := p.functions[getKey(p.resolveNamespace(getFunctionExecCommandOptions.Namespace),
+		getFunctionExecCommandOptions.Name)]; !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	if getFunctionExecCommandOptions.Replica != "" &&
+		getFunctionExecCommandOptions.Replica != getFunctionExecCommandOptions.Name {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Replica not found: %s", getFunctionExecCommandOptions.Replica))
+	}
+
+	return getFunctionExecCommandOptions.Command, nil
+}
+
+// CreateFunctionTunnel returns a tunnel to the function's (made up) address. closing it is recorded as a
+// CloseFunctionTunnel call
+func (p *Platform) CreateFunctionTunnel(createFunctionTunnelOptions *platform.CreateFunctionTunnelOptions) (
+	platform.FunctionTunnel, error) {
+	p.recordCall("CreateFunctionTunnel", createFunctionTunnelOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, found := p.functions[getKey(p.resolveNamespace(createFunctionTunnelOptions.Namespace),
+		createFunctionTunnelOptions.Name)]; !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	return &functionTunnel{
+		platform: p,
+		options:  createFunctionTunnelOptions,
+	}, nil
+}
+
+// PauseFunction disables a function and marks it paused
+func (p *Platform) PauseFunction(pauseFunctionOptions *platform.PauseFunctionOptions) error {
+	p.recordCall("PauseFunction", pauseFunctionOptions)
+
+	return p.setFunctionPaused(pauseFunctionOptions.Namespace, pauseFunctionOptions.Name, true)
+}
+
+// ResumeFunction enables a paused function and marks it ready
+func (p *Platform) ResumeFunction(resumeFunctionOptions *platform.ResumeFunctionOptions) error {
+	p.recordCall("ResumeFunction", resumeFunctionOptions)
+
+	return p.setFunctionPaused(resumeFunctionOptions.Namespace, resumeFunctionOptions.Name, false)
+}
+
+func (p *Platform) setFunctionPaused(namespace string, name string, paused bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	switch {
+	case function.Status.State == functionconfig.FunctionStateImported:
+		return nuclio.NewErrBadRequest("Non-deployed functions cannot be paused or resumed")
+	case !paused && function.Status.State != functionconfig.FunctionStatePaused:
+		return nuclio.NewErrConflict(fmt.Sprintf("Function %s isn't paused (state: %s)", name, function.Status.State))
+	}
+
+	function.Config.Spec.Disable = paused
+	function.Status.State = functionconfig.FunctionStateReady
+	if paused {
+		function.Status.State = functionconfig.FunctionStatePaused
+	}
+
+	return nil
+}
+
+// PauseFunctionTrigger marks one of a function's triggers paused
+func (p *Platform) PauseFunctionTrigger(pauseFunctionTriggerOptions *platform.PauseFunctionTriggerOptions) error {
+	p.recordCall("PauseFunctionTrigger", pauseFunctionTriggerOptions)
+
+	return p.setFunctionTriggerPaused(pauseFunctionTriggerOptions.Namespace,
+		pauseFunctionTriggerOptions.Name,
+		pauseFunctionTriggerOptions.TriggerName,
+		true)
+}
+
+// ResumeFunctionTrigger marks a paused trigger of a function as no longer paused
+func (p *Platform) ResumeFunctionTrigger(resumeFunctionTriggerOptions *platform.ResumeFunctionTriggerOptions) error {
+	p.recordCall("ResumeFunctionTrigger", resumeFunctionTriggerOptions)
+
+	return p.setFunctionTriggerPaused(resumeFunctionTriggerOptions.Namespace,
+		resumeFunctionTriggerOptions.Name,
+		resumeFunctionTriggerOptions.TriggerName,
+		false)
+}
+
+func (p *Platform) setFunctionTriggerPaused(namespace string, name string, triggerName string, paused bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	triggerConfiguration, found := function.Config.Spec.Triggers[triggerName]
+	if !found {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Trigger not found: %s", triggerName))
+	}
+
+	triggerConfiguration.Paused = paused
+	function.Config.Spec.Triggers[triggerName] = triggerConfiguration
+
+	return nil
+}
+
+// SetFunctionUsage sets the usage GetFunctionUsage returns for a function
+func (p *Platform) SetFunctionUsage(namespace string, name string, usage platform.FunctionUsage) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	function.usage = &usage
+
+	return nil
+}
+
+// GetFunctionUsage returns the usage set through SetFunctionUsage. functions whose usage was not set report
+// their replicas, without any metrics
+func (p *Platform) GetFunctionUsage(getFunctionUsageOptions *platform.GetFunctionUsageOptions) (
+	[]platform.FunctionUsage, error) {
+	p.recordCall("GetFunctionUsage", getFunctionUsageOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var functionUsages []platform.FunctionUsage
+
+	namespace := p.resolveNamespace(getFunctionUsageOptions.Namespace)
+
+	for _, function := range p.functions {
+		if function.Config.Meta.Namespace != namespace {
+			continue
+		}
+
+		if getFunctionUsageOptions.Name != "" && function.Config.Meta.Name != getFunctionUsageOptions.Name {
+			continue
+		}
+
+		if function.usage != nil {
+			functionUsages = append(functionUsages, *function.usage)
+			continue
+		}
+
+		replicas, _ := function.GetReplicas()
+
+		functionUsages = append(functionUsages, platform.FunctionUsage{
+			Name:      function.Config.Meta.Name,
+			Namespace: function.Config.Meta.Namespace,
+			Replicas:  replicas,
+		})
+	}
+
+	if getFunctionUsageOptions.Name != "" && len(functionUsages) == 0 {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	sort.Slice(functionUsages, func(i, j int) bool {
+		return functionUsages[i].Name < functionUsages[j].Name
+	})
+
+	return functionUsages, nil
+}
+
+// SetFunctionStreamStatus sets the stream status GetFunctionStreamStatus returns for a function
+func (p *Platform) SetFunctionStreamStatus(namespace string,
+	name string,
+	streamStatus []platform.FunctionReplicaStreamStatus) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	function.streamStatus = streamStatus
+
+	return nil
+}
+
+// GetFunctionStreamStatus returns the stream status set through SetFunctionStreamStatus
+func (p *Platform) GetFunctionStreamStatus(getFunctionStreamStatusOptions *platform.GetFunctionStreamStatusOptions) (
+	[]platform.FunctionReplicaStreamStatus, error) {
+	p.recordCall("GetFunctionStreamStatus", getFunctionStreamStatusOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(getFunctionStreamStatusOptions.Namespace),
+		getFunctionStreamStatusOptions.Name)]
+	if !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	return append([]platform.FunctionReplicaStreamStatus{}, function.streamStatus...), nil
+}
+
+// AddFunctionInvocations adds invocations to those retained for a function, as if gathered from its replicas
+func (p *Platform) AddFunctionInvocations(namespace string,
+	name string,
+	invocations []platform.FunctionInvocation) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(namespace), name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	maxInvocations := functionconfig.DefaultInvocationInspectorMaxInvocations
+	if function.Config.Spec.InvocationInspector != nil {
+		maxInvocations = function.Config.Spec.InvocationInspector.GetMaxInvocations()
+	}
+
+	function.invocations.Add(invocations, maxInvocations)
+
+	return nil
+}
+
+// GetFunctionInvocations returns the invocations added through AddFunctionInvocations, since they were purged
+func (p *Platform) GetFunctionInvocations(getFunctionInvocationsOptions *platform.GetFunctionInvocationsOptions) (
+	[]platform.FunctionInvocation, error) {
+	p.recordCall("GetFunctionInvocations", getFunctionInvocationsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(getFunctionInvocationsOptions.Namespace),
+		getFunctionInvocationsOptions.Name)]
+	if !found {
+		return nil, nuclio.NewErrNotFound("Function not found")
+	}
+
+	return platform.FilterFunctionInvocations(function.invocations.Invocations,
+		getFunctionInvocationsOptions.Since,
+		getFunctionInvocationsOptions.FailedOnly), nil
+}
+
+// DeleteFunctionInvocations purges the invocations retained for a function
+func (p *Platform) DeleteFunctionInvocations(deleteFunctionInvocationsOptions *platform.DeleteFunctionInvocationsOptions) error {
+	p.recordCall("DeleteFunctionInvocations", deleteFunctionInvocationsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	function, found := p.functions[getKey(p.resolveNamespace(deleteFunctionInvocationsOptions.Namespace),
+		deleteFunctionInvocationsOptions.Name)]
+	if !found {
+		return nuclio.NewErrNotFound("Function not found")
+	}
+
+	function.invocations.Purge()
+
+	return nil
+}
+
+func (p *Platform) GetDefaultInvokeIPAddresses() ([]string, error) {
+	return []string{}, nil
+}
+
+//
+// Project
+//
+
+// CreateProject stores a project, replacing an existing one with the same name
+func (p *Platform) CreateProject(createProjectOptions *platform.CreateProjectOptions) error {
+	p.recordCall("CreateProject", createProjectOptions)
+
+	return p.setProject(&createProjectOptions.ProjectConfig)
+}
+
+// UpdateProject replaces an existing project
+func (p *Platform) UpdateProject(updateProjectOptions *platform.UpdateProjectOptions) error {
+	p.recordCall("UpdateProject", updateProjectOptions)
+
+	return p.setProject(&updateProjectOptions.ProjectConfig)
+}
+
+// DeleteProject deletes a project which has no functions
+func (p *Platform) DeleteProject(deleteProjectOptions *platform.DeleteProjectOptions) error {
+	p.recordCall("DeleteProject", deleteProjectOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if deleteProjectOptions.Meta.Name == platform.DefaultProjectName {
+		return errors.New("Cannot delete the default project")
+	}
+
+	namespace := p.resolveNamespace(deleteProjectOptions.Meta.Namespace)
+	projectKey := getKey(namespace, deleteProjectOptions.Meta.Name)
+
+	if _, found := p.projects[projectKey]; !found {
+		return nuclio.NewErrNotFound("Project not found")
+	}
+
+	for _, function := range p.functions {
+		if function.Config.Meta.Namespace == namespace &&
+			function.Config.Meta.Labels["nuclio.io/project-name"] == deleteProjectOptions.Meta.Name {
+			return nuclio.NewErrPreconditionFailed("Project has functions")
+		}
+	}
+
+	delete(p.projects, projectKey)
+
+	return nil
+}
+
+// GetProjects returns copies of the stored projects matching the name and namespace
+func (p *Platform) GetProjects(getProjectsOptions *platform.GetProjectsOptions) ([]platform.Project, error) {
+	p.recordCall("GetProjects", getProjectsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var projects []platform.Project
+
+	namespace := p.resolveNamespace(getProjectsOptions.Meta.Namespace)
+	for _, project := range p.projects {
+		if !getProjectsOptions.AllNamespaces && project.ProjectConfig.Meta.Namespace != namespace {
+			continue
+		}
+
+		if getProjectsOptions.Meta.Name != "" && project.ProjectConfig.Meta.Name != getProjectsOptions.Meta.Name {
+			continue
+		}
+
+		projectCopy := *project
+		projects = append(projects, &projectCopy)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return lessNamespacedName(projects[i].GetConfig().Meta.Namespace, projects[i].GetConfig().Meta.Name,
+			projects[j].GetConfig().Meta.Namespace, projects[j].GetConfig().Meta.Name)
+	})
+
+	return projects, nil
+}
+
+//
+// Function event
+//
+
+// CreateFunctionEvent stores a function event, replacing an existing one with the same name
+func (p *Platform) CreateFunctionEvent(createFunctionEventOptions *platform.CreateFunctionEventOptions) error {
+	p.recordCall("CreateFunctionEvent", createFunctionEventOptions)
+
+	return p.setFunctionEvent(&createFunctionEventOptions.FunctionEventConfig)
+}
+
+// UpdateFunctionEvent replaces an existing function event
+func (p *Platform) UpdateFunctionEvent(updateFunctionEventOptions *platform.UpdateFunctionEventOptions) error {
+	p.recordCall("UpdateFunctionEvent", updateFunctionEventOptions)
+
+	return p.setFunctionEvent(&updateFunctionEventOptions.FunctionEventConfig)
+}
+
+// DeleteFunctionEvent deletes a function event
+func (p *Platform) DeleteFunctionEvent(deleteFunctionEventOptions *platform.DeleteFunctionEventOptions) error {
+	p.recordCall("DeleteFunctionEvent", deleteFunctionEventOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	functionEventKey := getKey(p.resolveNamespace(deleteFunctionEventOptions.Meta.Namespace),
+		deleteFunctionEventOptions.Meta.Name)
+
+	if _, found := p.functionEvents[functionEventKey]; !found {
+		return nuclio.NewErrNotFound("Function event not found")
+	}
+
+	delete(p.functionEvents, functionEventKey)
+
+	return nil
+}
+
+// GetFunctionEvents returns copies of the stored function events matching the name, namespace and labels
+func (p *Platform) GetFunctionEvents(getFunctionEventsOptions *platform.GetFunctionEventsOptions) (
+	[]platform.FunctionEvent, error) {
+	p.recordCall("GetFunctionEvents", getFunctionEventsOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var functionEvents []platform.FunctionEvent
+
+	namespace := p.resolveNamespace(getFunctionEventsOptions.Meta.Namespace)
+	for _, functionEvent := range p.functionEvents {
+		functionEventMeta := functionEvent.FunctionEventConfig.Meta
+
+		if functionEventMeta.Namespace != namespace {
+			continue
+		}
+
+		if getFunctionEventsOptions.Meta.Name != "" && functionEventMeta.Name != getFunctionEventsOptions.Meta.Name {
+			continue
+		}
+
+		if !labelsMatch(functionEventMeta.Labels, getFunctionEventsOptions.Meta.Labels) {
+			continue
+		}
+
+		functionEventCopy := *functionEvent
+		functionEvents = append(functionEvents, &functionEventCopy)
+	}
+
+	sort.Slice(functionEvents, func(i, j int) bool {
+		return functionEvents[i].GetConfig().Meta.Name < functionEvents[j].GetConfig().Meta.Name
+	})
+
+	return functionEvents, nil
+}
+
+//
+// API gateway
+//
+
+// CreateAPIGateway stores an API gateway, replacing an existing one with the same name
+func (p *Platform) CreateAPIGateway(createAPIGatewayOptions *platform.CreateAPIGatewayOptions) error {
+	p.recordCall("CreateAPIGateway", createAPIGatewayOptions)
+
+	return p.setAPIGateway(&createAPIGatewayOptions.APIGatewayConfig)
+}
+
+// UpdateAPIGateway replaces an existing API gateway
+func (p *Platform) UpdateAPIGateway(updateAPIGatewayOptions *platform.UpdateAPIGatewayOptions) error {
+	p.recordCall("UpdateAPIGateway", updateAPIGatewayOptions)
+
+	return p.setAPIGateway(&updateAPIGatewayOptions.APIGatewayConfig)
+}
+
+// DeleteAPIGateway deletes an API gateway
+func (p *Platform) DeleteAPIGateway(deleteAPIGatewayOptions *platform.DeleteAPIGatewayOptions) error {
+	p.recordCall("DeleteAPIGateway", deleteAPIGatewayOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	apiGatewayKey := getKey(p.resolveNamespace(deleteAPIGatewayOptions.Meta.Namespace),
+		deleteAPIGatewayOptions.Meta.Name)
+
+	if _, found := p.apiGateways[apiGatewayKey]; !found {
+		return nuclio.NewErrNotFound("API gateway not found")
+	}
+
+	delete(p.apiGateways, apiGatewayKey)
+
+	return nil
+}
+
+// GetAPIGateways returns copies of the stored API gateways matching the name and namespace
+func (p *Platform) GetAPIGateways(getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
+	p.recordCall("GetAPIGateways", getAPIGatewaysOptions)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var apiGateways []platform.APIGateway
+
+	namespace := p.resolveNamespace(getAPIGatewaysOptions.Meta.Namespace)
+	for _, apiGateway := range p.apiGateways {
+		apiGatewayMeta := apiGateway.APIGatewayConfig.Meta
+
+		if !getAPIGatewaysOptions.AllNamespaces && apiGatewayMeta.Namespace != namespace {
+			continue
+		}
+
+		if getAPIGatewaysOptions.Meta.Name != "" && apiGatewayMeta.Name != getAPIGatewaysOptions.Meta.Name {
+			continue
+		}
+
+		apiGatewayCopy := *apiGateway
+		apiGateways = append(apiGateways, &apiGatewayCopy)
+	}
+
+	sort.Slice(apiGateways, func(i, j int) bool {
+		return lessNamespacedName(apiGateways[i].GetConfig().Meta.Namespace, apiGateways[i].GetConfig().Meta.Name,
+			apiGateways[j].GetConfig().Meta.Namespace, apiGateways[j].GetConfig().Meta.Name)
+	})
+
+	return apiGateways, nil
+}
+
+//
+// Misc
+//
+
+func (p *Platform) SetExternalIPAddresses(externalIPAddresses []string) error {
+	p.externalIPAddresses = externalIPAddresses
+	return nil
+}
+
+func (p *Platform) GetExternalIPAddresses() ([]string, error) {
+	return p.externalIPAddresses, nil
+}
+
+func (p *Platform) SetDefaultHTTPIngressHostTemplate(defaultHTTPIngressHostTemplate string) {
+	p.defaultHTTPIngressHostTemplate = defaultHTTPIngressHostTemplate
+}
+
+func (p *Platform) GetDefaultHTTPIngressHostTemplate() string {
+	return p.defaultHTTPIngressHostTemplate
+}
+
+func (p *Platform) SetImageNamePrefixTemplate(imageNamePrefixTemplate string) {
+	p.imageNamePrefixTemplate = imageNamePrefixTemplate
+}
+
+func (p *Platform) GetImageNamePrefixTemplate() string {
+	return p.imageNamePrefixTemplate
+}
+
+func (p *Platform) RenderImageNamePrefixTemplate(projectName string, functionName string) (string, error) {
+	return "", nil
+}
+
+func (p *Platform) GetScaleToZeroConfiguration() (*platformconfig.ScaleToZero, error) {
+	return nil, nil
+}
+
+func (p *Platform) GetNamespaces() ([]string, error) {
+	return []string{defaultNamespace}, nil
+}
+
+func (p *Platform) GetHealthCheckMode() platform.HealthCheckMode {
+	return platform.HealthCheckModeExternal
+}
+
+func (p *Platform) GetName() string {
+	return Name
+}
+
+func (p *Platform) GetNodes() ([]platform.Node, error) {
+	return []platform.Node{}, nil
+}
+
+func (p *Platform) ResolveDefaultNamespace(namespace string) string {
+	return p.resolveNamespace(namespace)
+}
+
+func (p *Platform) BuildAndPushContainerImage(buildOptions *containerimagebuilderpusher.BuildOptions) error {
+	return nil
+}
+
+func (p *Platform) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
+	return []string{}, nil
+}
+
+func (p *Platform) TransformOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (p *Platform) GetOnbuildImageRegistry(registry string) string {
+	return registry
+}
+
+func (p *Platform) GetBaseImageRegistry(registry string) string {
+	return registry
+}
+
+func (p *Platform) GetRuntimeImages(runtimeName string) *platformconfig.RuntimeImages {
+	return nil
+}
+
+func (p *Platform) GetDefaultRegistryCredentialsSecretName() string {
+	return ""
+}
+
+// GetSecretData returns not found, as the fake platform holds no secrets
+func (p *Platform) GetSecretData(namespace string, name string) (map[string][]byte, error) {
+	return nil, nuclio.NewErrNotFound(fmt.Sprintf("Secret not found: %s", name))
+}
+
+func (p *Platform) setFunction(functionConfig *functionconfig.Config, state functionconfig.FunctionState) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	functionStatus := functionconfig.Status{
+		State: state,
+	}
+
+	if state == functionconfig.FunctionStateReady {
+		lastDeployed := p.Clock().UTC()
+		functionStatus.LastDeployed = &lastDeployed
+	}
+
+	p.functions[getKey(functionConfig.Meta.Namespace, functionConfig.Meta.Name)] = &function{
+		AbstractFunction: platform.AbstractFunction{
+			Logger:   p.logger,
+			Config:   *functionConfig,
+			Platform: p,
+			Status:   functionStatus,
+		},
+	}
+}
+
+func (p *Platform) addFunctionRevision(functionConfig *functionconfig.Config) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	functionKey := getKey(functionConfig.Meta.Namespace, functionConfig.Meta.Name)
+
+	// images aren't pulled, so they have no digest
+	functionRevisions := platform.AddFunctionRevision(p.functionRevisions[functionKey], functionConfig, "")
+	functionRevisions[len(functionRevisions)-1].Deployed = p.Clock().UTC()
+
+	p.functionRevisions[functionKey] = functionRevisions
+}
+
+// addAuditRecord records an operation at the platform's clock time. the lock must be held
+func (p *Platform) addAuditRecord(operation platform.AuditOperation,
+	identity *platform.Identity,
+	namespace string,
+	name string,
+	previousConfig *functionconfig.Config,
+	currentConfig *functionconfig.Config) {
+
+	auditRecord, err := platform.NewAuditRecord(operation, identity, namespace, name, previousConfig, currentConfig)
+	if err != nil {
+		p.logger.WarnWith("Failed to create audit record", "err", err.Error())
+		return
+	}
+
+	auditRecord.Time = p.Clock().UTC()
+	p.auditRecords[namespace] = platform.AddAuditRecord(p.auditRecords[namespace], auditRecord)
+}
+
+func (p *Platform) getStoredFunction(namespace string, name string) (*function, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	storedFunction, found := p.functions[getKey(namespace, name)]
+	if !found {
+		return nil, false
+	}
+
+	// copy, as the stored function is replaced on deploy
+	functionCopy := *storedFunction
+
+	return &functionCopy, true
+}
+
+// applyProjectSettings sets the defaults of the function's project in it, and enforces the project's quotas
+func (p *Platform) applyProjectSettings(functionConfig *functionconfig.Config) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	projectName := functionConfig.Meta.Labels["nuclio.io/project-name"]
+	if projectName == "" {
+		return nil
+	}
+
+	project, found := p.projects[getKey(functionConfig.Meta.Namespace, projectName)]
+	if !found {
+		return nil
+	}
+
+	project.ProjectConfig.Spec.ApplyDefaults(functionConfig)
+
+	var projectFunctionConfigs []*functionconfig.Config
+	for _, function := range p.functions {
+		if function.Config.Meta.Namespace == functionConfig.Meta.Namespace &&
+			function.Config.Meta.Labels["nuclio.io/project-name"] == projectName {
+			projectFunctionConfigs = append(projectFunctionConfigs, &function.Config)
+		}
+	}
+
+	return project.ProjectConfig.Spec.ValidateQuotas(functionConfig, projectFunctionConfigs)
+}
+
+func (p *Platform) setProject(projectConfig *platform.ProjectConfig) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if projectConfig.Meta.Name == "" {
+		return nuclio.NewErrBadRequest("Project name must be provided")
+	}
+
+	if err := projectConfig.Spec.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	storedProjectConfig := *projectConfig
+	projectConfig.Spec.DeepCopyInto(&storedProjectConfig.Spec)
+	storedProjectConfig.Meta.Namespace = p.resolveNamespace(storedProjectConfig.Meta.Namespace)
+
+	p.projects[getKey(storedProjectConfig.Meta.Namespace, storedProjectConfig.Meta.Name)] = &platform.AbstractProject{
+		Logger:        p.logger,
+		Platform:      p,
+		ProjectConfig: storedProjectConfig,
+	}
+
+	return nil
+}
+
+func (p *Platform) setFunctionEvent(functionEventConfig *platform.FunctionEventConfig) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if functionEventConfig.Meta.Name == "" {
+		return nuclio.NewErrBadRequest("Function event name must be provided")
+	}
+
+	storedFunctionEventConfig := *functionEventConfig
+	storedFunctionEventConfig.Meta.Namespace = p.resolveNamespace(storedFunctionEventConfig.Meta.Namespace)
+
+	p.functionEvents[getKey(storedFunctionEventConfig.Meta.Namespace,
+		storedFunctionEventConfig.Meta.Name)] = &platform.AbstractFunctionEvent{
+		Logger:              p.logger,
+		Platform:            p,
+		FunctionEventConfig: storedFunctionEventConfig,
+	}
+
+	return nil
+}
+
+func (p *Platform) setAPIGateway(apiGatewayConfig *platform.APIGatewayConfig) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	storedAPIGatewayConfig := *apiGatewayConfig
+	if err := storedAPIGatewayConfig.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	storedAPIGatewayConfig.Meta.Namespace = p.resolveNamespace(storedAPIGatewayConfig.Meta.Namespace)
+
+	// like the platforms, only route to existing functions
+	for _, upstream := range storedAPIGatewayConfig.Spec.Upstreams {
+		if _, found := p.functions[getKey(storedAPIGatewayConfig.Meta.Namespace, upstream.NuclioFunction.Name)]; !found {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Upstream function %s not found", upstream.NuclioFunction.Name))
+		}
+	}
+
+	storedAPIGatewayConfig.Status = platform.APIGatewayStatus{State: platform.APIGatewayStateReady}
+
+	p.apiGateways[getKey(storedAPIGatewayConfig.Meta.Namespace,
+		storedAPIGatewayConfig.Meta.Name)] = &platform.AbstractAPIGateway{
+		Logger:           p.logger,
+		Platform:         p,
+		APIGatewayConfig: storedAPIGatewayConfig,
+	}
+
+	return nil
+}
+
+func (p *Platform) getFunctionImage(functionConfig *functionconfig.Config) string {
+	if functionConfig.Spec.Image != "" {
+		return functionConfig.Spec.Image
+	}
+
+	return fmt.Sprintf("nuclio/processor-%s:latest", functionConfig.Meta.Name)
+}
+
+func (p *Platform) resolveNamespace(namespace string) string {
+	if namespace == "" {
+		return defaultNamespace
+	}
+
+	return namespace
+}
+
+func (p *Platform) recordCall(name string, options interface{}) {
+	p.callsLock.Lock()
+	defer p.callsLock.Unlock()
+
+	p.calls = append(p.calls, Call{
+		Name:    name,
+		Options: options,
+	})
+}
+
+func getKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
+
+// lessNamespacedName orders resources by namespace, and by name within a namespace
+func lessNamespacedName(firstNamespace string, firstName string, secondNamespace string, secondName string) bool {
+	if firstNamespace != secondNamespace {
+		return firstNamespace < secondNamespace
+	}
+
+	return firstName < secondName
+}
+
+func labelsMatch(labels map[string]string, selector map[string]string) bool {
+	for labelName, labelValue := range selector {
+		if labels[labelName] != labelValue {
+			return false
+		}
+	}
+
+	return true
+}
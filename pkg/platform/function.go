@@ -42,6 +42,10 @@ type Function interface {
 	// GetInvokeURL returns the URL on which the function can be invoked
 	GetInvokeURL(InvokeViaType) (string, error)
 
+	// GetGRPCInvokeURL returns the address (host:port) on which the function's gRPC trigger listening on the
+	// given port can be invoked. if the port is 0, the function must have a single gRPC trigger
+	GetGRPCInvokeURL(InvokeViaType, int) (string, error)
+
 	// GetReplicas returns the current # of replicas and the configured # of replicas
 	GetReplicas() (int, int)
 
@@ -105,6 +109,41 @@ func (af *AbstractFunction) GetInvokeURL(InvokeViaType) (string, error) {
 	return "", errors.New("Unsupported")
 }
 
+// GetGRPCInvokeURL returns the address on which the function's gRPC trigger can be invoked
+func (af *AbstractFunction) GetGRPCInvokeURL(InvokeViaType, int) (string, error) {
+	return "", errors.New("Unsupported")
+}
+
+// ResolveGRPCPort returns the port of the function's gRPC trigger to invoke - the given port if it's that of
+// one of its gRPC triggers, or the port of its only gRPC trigger if none is given
+func (af *AbstractFunction) ResolveGRPCPort(grpcPort int) (int, error) {
+	grpcPorts := af.Config.Spec.GetGRPCPorts()
+
+	switch {
+	case len(grpcPorts) == 0:
+		return 0, errors.Errorf("Function %s has no gRPC trigger", af.Config.Meta.Name)
+
+	case grpcPort != 0:
+		for _, functionGRPCPort := range grpcPorts {
+			if functionGRPCPort == grpcPort {
+				return grpcPort, nil
+			}
+		}
+
+		return 0, errors.Errorf("Function %s has no gRPC trigger listening on port %d (ports: %v)",
+			af.Config.Meta.Name,
+			grpcPort,
+			grpcPorts)
+
+	case len(grpcPorts) > 1:
+		return 0, errors.Errorf("Function %s has more than one gRPC trigger (ports: %v), the port to invoke must be given",
+			af.Config.Meta.Name,
+			grpcPorts)
+	}
+
+	return grpcPorts[0], nil
+}
+
 // GetReplicas returns the current # of replicas and the configured # of replicas
 func (af *AbstractFunction) GetReplicas() (int, int) {
 	return 0, 0
@@ -0,0 +1,141 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+// ProcessorInvocationsPath is the path of the processor's web admin resource serving the invocations its
+// invocation inspector retains
+const ProcessorInvocationsPath = "invocations"
+
+// FunctionInvocation is the summary of an invocation of a function, as retained by the invocation inspector
+// of one of its replicas
+type FunctionInvocation struct {
+	ID                string        `json:"id"`
+	EventID           string        `json:"eventID,omitempty"`
+	Replica           string        `json:"replica,omitempty"`
+	Time              time.Time     `json:"time"`
+	TriggerKind       string        `json:"triggerKind"`
+	TriggerName       string        `json:"triggerName"`
+	Method            string        `json:"method,omitempty"`
+	Path              string        `json:"path,omitempty"`
+	ContentType       string        `json:"contentType,omitempty"`
+	RequestSize       int           `json:"requestSize"`
+	StatusCode        int           `json:"statusCode"`
+	Error             string        `json:"error,omitempty"`
+	Duration          time.Duration `json:"duration"`
+	Response          string        `json:"response,omitempty"`
+	ResponseTruncated bool          `json:"responseTruncated,omitempty"`
+}
+
+// Failed returns whether the invocation failed, i.e. the function returned an error or a server error
+func (fi *FunctionInvocation) Failed() bool {
+	return fi.Error != "" || fi.StatusCode >= http.StatusInternalServerError
+}
+
+// FunctionInvocations are the invocations of a function the platform retains, oldest first
+type FunctionInvocations struct {
+	Invocations []FunctionInvocation `json:"invocations,omitempty"`
+
+	// invocations before this time were purged, and aren't retained again as the replicas still report them
+	PurgedAt time.Time `json:"purgedAt,omitempty"`
+}
+
+// ParseFunctionReplicaInvocations returns the invocations the invocation inspector of a replica's processor
+// serves, oldest first
+func ParseFunctionReplicaInvocations(replica string, encodedInvocations []byte) ([]FunctionInvocation, error) {
+	invocationsByID := map[string]FunctionInvocation{}
+
+	if err := json.Unmarshal(encodedInvocations, &invocationsByID); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse processor invocations")
+	}
+
+	var invocations []FunctionInvocation
+	for _, invocation := range invocationsByID {
+		invocation.Replica = replica
+		invocations = append(invocations, invocation)
+	}
+
+	sortFunctionInvocations(invocations)
+
+	return invocations, nil
+}
+
+// Add adds the invocations gathered from the replicas to the retained ones, skipping those which are retained
+// already or were purged, and discards the oldest beyond maxInvocations
+func (fi *FunctionInvocations) Add(invocations []FunctionInvocation, maxInvocations int) {
+	retainedInvocationIDs := map[string]bool{}
+	for _, invocation := range fi.Invocations {
+		retainedInvocationIDs[invocation.ID] = true
+	}
+
+	for _, invocation := range invocations {
+		if retainedInvocationIDs[invocation.ID] || !invocation.Time.After(fi.PurgedAt) {
+			continue
+		}
+
+		fi.Invocations = append(fi.Invocations, invocation)
+		retainedInvocationIDs[invocation.ID] = true
+	}
+
+	sortFunctionInvocations(fi.Invocations)
+
+	if len(fi.Invocations) > maxInvocations {
+		fi.Invocations = fi.Invocations[len(fi.Invocations)-maxInvocations:]
+	}
+}
+
+// Purge discards the retained invocations, along with those the replicas still report
+func (fi *FunctionInvocations) Purge() {
+	fi.Invocations = nil
+	fi.PurgedAt = time.Now().UTC()
+}
+
+// FilterFunctionInvocations returns the invocations that started at or after since (or all of them, if zero),
+// only the failed ones if asked to
+func FilterFunctionInvocations(invocations []FunctionInvocation,
+	since time.Time,
+	failedOnly bool) []FunctionInvocation {
+	var filteredInvocations []FunctionInvocation
+
+	for _, invocation := range invocations {
+		if !since.IsZero() && invocation.Time.Before(since) {
+			continue
+		}
+
+		if failedOnly && !invocation.Failed() {
+			continue
+		}
+
+		filteredInvocations = append(filteredInvocations, invocation)
+	}
+
+	return filteredInvocations
+}
+
+func sortFunctionInvocations(invocations []FunctionInvocation) {
+	sort.SliceStable(invocations, func(i, j int) bool {
+		return invocations[i].Time.Before(invocations[j].Time)
+	})
+}
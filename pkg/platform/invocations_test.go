@@ -0,0 +1,102 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type invocationsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *invocationsTestSuite) TestParseFunctionReplicaInvocations() {
+	encodedInvocations := `{
+		"b": {"id": "b", "time": "2021-01-01T00:00:02Z", "triggerKind": "http", "statusCode": 500, "error": "Failed"},
+		"a": {"id": "a", "time": "2021-01-01T00:00:01Z", "triggerKind": "http", "statusCode": 200, "duration": 1000000}
+	}`
+
+	invocations, err := ParseFunctionReplicaInvocations("my-function-abcde", []byte(encodedInvocations))
+	suite.Require().NoError(err)
+
+	// sorted oldest first, and attributed to the replica
+	suite.Require().Len(invocations, 2)
+	suite.Require().Equal("a", invocations[0].ID)
+	suite.Require().Equal(time.Millisecond, invocations[0].Duration)
+	suite.Require().False(invocations[0].Failed())
+	suite.Require().Equal("b", invocations[1].ID)
+	suite.Require().True(invocations[1].Failed())
+
+	for _, invocation := range invocations {
+		suite.Require().Equal("my-function-abcde", invocation.Replica)
+	}
+
+	_, err = ParseFunctionReplicaInvocations("my-function-abcde", []byte("not json"))
+	suite.Require().Error(err)
+}
+
+func (suite *invocationsTestSuite) TestAddPurgeFilter() {
+	now := time.Now().UTC()
+	newInvocation := func(id string, age time.Duration, statusCode int) FunctionInvocation {
+		return FunctionInvocation{ID: id, Time: now.Add(-age), StatusCode: statusCode}
+	}
+
+	functionInvocations := FunctionInvocations{}
+
+	// invocations gathered again aren't duplicated, and only the last ones are retained
+	functionInvocations.Add([]FunctionInvocation{
+		newInvocation("c", time.Minute, 200),
+		newInvocation("a", 3*time.Minute, 200),
+		newInvocation("b", 2*time.Minute, 503),
+	}, 10)
+	functionInvocations.Add([]FunctionInvocation{
+		newInvocation("c", time.Minute, 200),
+		newInvocation("d", 30*time.Second, 200),
+	}, 3)
+
+	suite.requireInvocationIDs(functionInvocations.Invocations, "b", "c", "d")
+	suite.requireInvocationIDs(FilterFunctionInvocations(functionInvocations.Invocations, now.Add(-90*time.Second), false),
+		"c", "d")
+	suite.requireInvocationIDs(FilterFunctionInvocations(functionInvocations.Invocations, time.Time{}, true), "b")
+
+	// invocations before the purge aren't retained again
+	functionInvocations.Purge()
+	suite.Require().Empty(functionInvocations.Invocations)
+
+	functionInvocations.Add([]FunctionInvocation{
+		newInvocation("d", 30*time.Second, 200),
+		newInvocation("e", -time.Second, 200),
+	}, 3)
+
+	suite.requireInvocationIDs(functionInvocations.Invocations, "e")
+}
+
+func (suite *invocationsTestSuite) requireInvocationIDs(invocations []FunctionInvocation, expectedIDs ...string) {
+	var ids []string
+	for _, invocation := range invocations {
+		ids = append(ids, invocation.ID)
+	}
+
+	suite.Require().Equal(expectedIDs, ids)
+}
+
+func TestInvocationsTestSuite(t *testing.T) {
+	suite.Run(t, new(invocationsTestSuite))
+}
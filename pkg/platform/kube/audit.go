@@ -0,0 +1,146 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// the configmap keeping the audit records of a namespace, and the key of the records in it as a JSON list.
+// function names can't contain dots, so it can't be the configmap of a function
+const (
+	auditConfigMapName = "nuclio.audit"
+	auditConfigMapKey  = "records.json"
+)
+
+// CreateAuditRecord adds the record to the audit configmap of its namespace
+func (p *Platform) CreateAuditRecord(auditRecord *platform.AuditRecord) error {
+	namespace := p.ResolveDefaultNamespace(auditRecord.Namespace)
+
+	// functions may be operated on concurrently (e.g. by several dashboard users), so an update that lost the
+	// race is retried on the records that won it
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return addAuditRecord(p.consumer.kubeClientSet, namespace, auditRecord)
+	})
+}
+
+// GetAuditRecords returns the audit records of the namespace, oldest first, as kept in its audit configmap
+func (p *Platform) GetAuditRecords(getAuditRecordsOptions *platform.GetAuditRecordsOptions) (
+	[]platform.AuditRecord, error) {
+
+	records, _, err := getAuditRecords(p.consumer.kubeClientSet,
+		p.ResolveDefaultNamespace(getAuditRecordsOptions.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get audit records")
+	}
+
+	return platform.FilterAuditRecords(records, getAuditRecordsOptions.Name, getAuditRecordsOptions.Since), nil
+}
+
+// getAuditedFunctionConfig returns the configuration of the function as audited, or nil if the function
+// doesn't exist (or can't be read - the audit log is best effort)
+func (p *Platform) getAuditedFunctionConfig(namespace string, name string) *functionconfig.Config {
+	if namespace == "" || name == "" {
+		return nil
+	}
+
+	function, err := p.getFunction(namespace, name)
+	if err != nil || function == nil {
+		return nil
+	}
+
+	return &functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name:        function.Name,
+			Namespace:   function.Namespace,
+			Labels:      function.Labels,
+			Annotations: function.Annotations,
+		},
+		Spec: function.Spec,
+	}
+}
+
+func addAuditRecord(kubeClientSet kubernetes.Interface, namespace string, auditRecord *platform.AuditRecord) error {
+	records, configMap, err := getAuditRecords(kubeClientSet, namespace)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get audit records")
+	}
+
+	encodedRecords, err := json.Marshal(platform.AddAuditRecord(records, auditRecord))
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode audit records")
+	}
+
+	if configMap == nil {
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Create(&v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      auditConfigMapName,
+				Namespace: namespace,
+			},
+			Data: map[string]string{
+				auditConfigMapKey: string(encodedRecords),
+			},
+		})
+
+		// another record was added first, creating the configmap - retry updating it
+		if apierrors.IsAlreadyExists(err) {
+			return apierrors.NewConflict(v1.Resource("configmaps"), auditConfigMapName, err)
+		}
+	} else {
+		configMap.Data = map[string]string{
+			auditConfigMapKey: string(encodedRecords),
+		}
+
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Update(configMap)
+	}
+
+	return err
+}
+
+// getAuditRecords returns the records kept in the namespace's audit configmap, along with the configmap (nil
+// if nothing was recorded in the namespace yet)
+func getAuditRecords(kubeClientSet kubernetes.Interface,
+	namespace string) ([]platform.AuditRecord, *v1.ConfigMap, error) {
+
+	configMap, err := kubeClientSet.CoreV1().ConfigMaps(namespace).Get(auditConfigMapName, meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, errors.Wrap(err, "Failed to get audit configmap")
+	}
+
+	var records []platform.AuditRecord
+	if encodedRecords := configMap.Data[auditConfigMapKey]; encodedRecords != "" {
+		if err := json.Unmarshal([]byte(encodedRecords), &records); err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to decode audit records")
+		}
+	}
+
+	return records, configMap, nil
+}
@@ -18,6 +18,7 @@ package kube
 
 import (
 	"os"
+	"sort"
 
 	"github.com/nuclio/nuclio/pkg/platform"
 	nuclioio_client "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	// enable OIDC plugin
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -35,17 +37,19 @@ type consumer struct {
 	nuclioClientSet nuclioio_client.Interface
 	kubeHost        string
 	kubeconfigPath  string
+	kubeContext     string
 }
 
-func newConsumer(logger logger.Logger, kubeconfigPath string) (*consumer, error) {
-	logger.DebugWith("Using kubeconfig", "kubeconfigPath", kubeconfigPath)
+func newConsumer(logger logger.Logger, kubeconfigPath string, kubeContext string) (*consumer, error) {
+	logger.DebugWith("Using kubeconfig", "kubeconfigPath", kubeconfigPath, "kubeContext", kubeContext)
 
 	newConsumer := consumer{
 		kubeconfigPath: kubeconfigPath,
+		kubeContext:    kubeContext,
 	}
 
 	// create REST config
-	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	restConfig, err := newConsumer.getRestConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create REST config")
 	}
@@ -82,7 +86,7 @@ func (c *consumer) getNuclioClientSet(authConfig *platform.AuthConfig) (nuclioio
 	}
 
 	// create REST config
-	restConfig, err := clientcmd.BuildConfigFromFlags("", c.kubeconfigPath)
+	restConfig, err := c.getRestConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create REST config")
 	}
@@ -92,3 +96,31 @@ func (c *consumer) getNuclioClientSet(authConfig *platform.AuthConfig) (nuclioio
 
 	return nuclioio_client.NewForConfig(restConfig)
 }
+
+// getRestConfig creates a REST config from the kubeconfig, using its current context unless told otherwise
+func (c *consumer) getRestConfig() (*rest.Config, error) {
+	if c.kubeContext == "" {
+		return clientcmd.BuildConfigFromFlags("", c.kubeconfigPath)
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: c.kubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: c.kubeContext}).ClientConfig()
+}
+
+// GetKubeconfigContexts returns the names of the contexts configured in a kubeconfig, sorted
+func GetKubeconfigContexts(kubeconfigPath string) ([]string, error) {
+	kubeconfig, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load kubeconfig")
+	}
+
+	var contexts []string
+	for context := range kubeconfig.Contexts {
+		contexts = append(contexts, context)
+	}
+
+	sort.Strings(contexts)
+
+	return contexts, nil
+}
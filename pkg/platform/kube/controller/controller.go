@@ -30,19 +30,31 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+// how often the pods of functions which have a warmup are checked for ones which became ready
+const warmupMonitoringInterval = 5 * time.Second
+
+// how often the processors of functions with runtime liveness checks are asked for the restarts of their wrappers
+const runtimeLivenessMonitoringInterval = 30 * time.Second
+
+// how often the consumer groups of functions scaled to zero on their consumer lag are checked for lag
+const lagMonitoringInterval = 30 * time.Second
+
 type Controller struct {
-	logger                logger.Logger
-	namespace             string
-	restConfig            *rest.Config
-	kubeClientSet         kubernetes.Interface
-	nuclioClientSet       nuclioio_client.Interface
-	functionresClient     functionres.Client
-	imagePullSecrets      string
-	functionOperator      *functionOperator
-	projectOperator       *projectOperator
-	functionEventOperator *functionEventOperator
-	cronJobMonitoring     *CronJobMonitoring
-	platformConfiguration *platformconfig.Config
+	logger                    logger.Logger
+	namespace                 string
+	restConfig                *rest.Config
+	kubeClientSet             kubernetes.Interface
+	nuclioClientSet           nuclioio_client.Interface
+	functionresClient         functionres.Client
+	imagePullSecrets          string
+	functionOperator          *functionOperator
+	projectOperator           *projectOperator
+	functionEventOperator     *functionEventOperator
+	cronJobMonitoring         *CronJobMonitoring
+	warmupMonitoring          *WarmupMonitoring
+	runtimeLivenessMonitoring *RuntimeLivenessMonitoring
+	lagMonitoring             *LagMonitoring
+	platformConfiguration     *platformconfig.Config
 }
 
 func NewController(parentLogger logger.Logger,
@@ -120,6 +132,21 @@ func NewController(parentLogger logger.Logger,
 		newController,
 		&cronJobStalePodsDeletionInterval)
 
+	// create warmup monitoring
+	newController.warmupMonitoring = NewWarmupMonitoring(parentLogger,
+		newController,
+		warmupMonitoringInterval)
+
+	// create runtime liveness monitoring
+	newController.runtimeLivenessMonitoring = NewRuntimeLivenessMonitoring(parentLogger,
+		newController,
+		runtimeLivenessMonitoringInterval)
+
+	// create lag monitoring
+	newController.lagMonitoring = NewLagMonitoring(parentLogger,
+		newController,
+		lagMonitoringInterval)
+
 	return newController, nil
 }
 
@@ -144,6 +171,15 @@ func (c *Controller) Start() error {
 	// start cron job monitoring
 	c.cronJobMonitoring.start()
 
+	// start warmup monitoring
+	c.warmupMonitoring.start()
+
+	// start runtime liveness monitoring
+	c.runtimeLivenessMonitoring.start()
+
+	// start lag monitoring
+	c.lagMonitoring.start()
+
 	return nil
 }
 
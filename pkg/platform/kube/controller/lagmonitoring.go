@@ -0,0 +1,242 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+
+	"github.com/Shopify/sarama"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/v3io/scaler-types"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the attributes of a kafka trigger the lag of its consumer group is read with
+type kafkaTriggerAttributes struct {
+	Brokers       []string
+	Topics        []string
+	ConsumerGroup string
+}
+
+// LagMonitoring wakes the functions scaled on their consumer lag in nuclio mode which were scaled to zero, once
+// the consumer groups of their kafka triggers have lag. with no replicas, no processor reports the lag for the
+// HPA to scale the function on (in keda mode, KEDA does this by itself)
+type LagMonitoring struct {
+	logger     logger.Logger
+	controller *Controller
+	interval   time.Duration
+
+	// returns the number of messages the consumer group has yet to consume from the topics
+	getConsumerGroupLag func(brokers []string, consumerGroup string, topics []string) (int64, error)
+}
+
+func NewLagMonitoring(parentLogger logger.Logger,
+	controller *Controller,
+	interval time.Duration) *LagMonitoring {
+
+	newLagMonitoring := &LagMonitoring{
+		logger:              parentLogger.GetChild("lag_monitoring"),
+		controller:          controller,
+		interval:            interval,
+		getConsumerGroupLag: getKafkaConsumerGroupLag,
+	}
+
+	parentLogger.DebugWith("Successfully created lag monitoring instance", "interval", interval)
+
+	return newLagMonitoring
+}
+
+func (lm *LagMonitoring) start() {
+	go lm.startLagLoop()
+}
+
+func (lm *LagMonitoring) startLagLoop() {
+	lm.logger.InfoWith("Starting lag loop", "interval", lm.interval)
+
+	for {
+		time.Sleep(lm.interval)
+
+		if err := lm.wakeLaggingFunctions(); err != nil {
+			lm.logger.WarnWith("Failed to wake lagging functions", "err", err.Error())
+		}
+	}
+}
+
+// wakeLaggingFunctions scales the functions scaled to zero whose consumer groups have lag from zero
+func (lm *LagMonitoring) wakeLaggingFunctions() error {
+	functions, err := lm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(lm.controller.namespace).
+		List(meta_v1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Failed to list functions")
+	}
+
+	for functionIdx := range functions.Items {
+		function := &functions.Items[functionIdx]
+
+		if function.Spec.AutoScale == nil ||
+			function.Spec.AutoScale.GetMode() != functionconfig.AutoScaleModeNuclio ||
+			function.Status.State != functionconfig.FunctionStateScaledToZero {
+			continue
+		}
+
+		lag, err := lm.getFunctionLag(function)
+		if err != nil {
+			lm.logger.WarnWith("Failed to get function lag",
+				"namespace", function.Namespace,
+				"function", function.Name,
+				"err", err.Error())
+			continue
+		}
+
+		if lag == 0 {
+			continue
+		}
+
+		lm.logger.InfoWith("Waking function with lag",
+			"namespace", function.Namespace,
+			"function", function.Name,
+			"lag", lag)
+
+		if err := lm.scaleFunctionFromZero(function); err != nil {
+			lm.logger.WarnWith("Failed to scale function from zero",
+				"namespace", function.Namespace,
+				"function", function.Name,
+				"err", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// getFunctionLag returns the total lag of the consumer groups of the function's kafka triggers
+func (lm *LagMonitoring) getFunctionLag(function *nuclioio.NuclioFunction) (int64, error) {
+	var functionLag int64
+
+	for triggerName, trigger := range function.Spec.Triggers {
+		// the kinds of triggers KEDA reads the lag of are the kafka ones
+		if !common.StringInSlice(trigger.Kind, functionconfig.AutoScaleTriggerKinds[functionconfig.AutoScaleModeKEDA]) {
+			continue
+		}
+
+		attributes := kafkaTriggerAttributes{}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return 0, errors.Wrapf(err, "Failed to decode the attributes of trigger %s", triggerName)
+		}
+
+		brokers := attributes.Brokers
+		if len(brokers) == 0 && trigger.URL != "" {
+			brokers = []string{trigger.URL}
+		}
+
+		if len(brokers) == 0 || attributes.ConsumerGroup == "" {
+			continue
+		}
+
+		triggerLag, err := lm.getConsumerGroupLag(brokers, attributes.ConsumerGroup, attributes.Topics)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Failed to get the lag of trigger %s", triggerName)
+		}
+
+		functionLag += triggerLag
+	}
+
+	return functionLag, nil
+}
+
+// scaleFunctionFromZero has the function operator scale the function from zero, the way the resource scaler does
+func (lm *LagMonitoring) scaleFunctionFromZero(function *nuclioio.NuclioFunction) error {
+	now := time.Now()
+
+	function.Status.State = functionconfig.FunctionStateWaitingForScaleResourcesFromZero
+	function.Status.ScaleToZero = &functionconfig.ScaleToZeroStatus{
+		LastScaleEvent:     scaler_types.ScaleFromZeroStartedScaleEvent,
+		LastScaleEventTime: &now,
+	}
+
+	if _, err := lm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(function.Namespace).
+		Update(function); err != nil {
+		return errors.Wrap(err, "Failed to update function status")
+	}
+
+	return nil
+}
+
+// getKafkaConsumerGroupLag returns the number of messages the consumer group has yet to consume from the
+// partitions of the topics. partitions the group hasn't committed an offset in are skipped
+func getKafkaConsumerGroupLag(brokers []string, consumerGroup string, topics []string) (int64, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_10_2_0
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to create kafka client")
+	}
+
+	defer client.Close() // nolint: errcheck
+
+	topicPartitions := map[string][]int32{}
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Failed to get the partitions of topic %s", topic)
+		}
+
+		topicPartitions[topic] = partitions
+	}
+
+	clusterAdmin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to create kafka cluster admin")
+	}
+
+	committedOffsets, err := clusterAdmin.ListConsumerGroupOffsets(consumerGroup, topicPartitions)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to list consumer group offsets")
+	}
+
+	var lag int64
+
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			committedOffset := committedOffsets.GetBlock(topic, partition)
+			if committedOffset == nil || committedOffset.Offset < 0 {
+				continue
+			}
+
+			newestOffset, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return 0, errors.Wrapf(err, "Failed to get the newest offset of %s/%d", topic, partition)
+			}
+
+			if newestOffset > committedOffset.Offset {
+				lag += newestOffset - committedOffset.Offset
+			}
+		}
+	}
+
+	return lag, nil
+}
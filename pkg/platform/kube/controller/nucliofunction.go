@@ -104,6 +104,7 @@ func (fo *functionOperator) CreateOrUpdate(ctx context.Context, object runtime.O
 		functionconfig.FunctionStateWaitingForScaleResourcesToZero,
 		functionconfig.FunctionStateReady,
 		functionconfig.FunctionStateScaledToZero,
+		functionconfig.FunctionStatePaused,
 	}
 	if !functionconfig.FunctionStateInSlice(function.Status.State, statesToRespond) {
 		fo.logger.DebugWith("NuclioFunction is not waiting for resource creation or ready, skipping create/update",
@@ -181,6 +182,11 @@ func (fo *functionOperator) CreateOrUpdate(ctx context.Context, object runtime.O
 		case functionconfig.FunctionStateWaitingForResourceConfiguration:
 			scaleEvent = scaler_types.ResourceUpdatedScaleEvent
 			finalState = functionconfig.FunctionStateReady
+
+			// a disabled function has no replicas until it's resumed
+			if function.Spec.Disable {
+				finalState = functionconfig.FunctionStatePaused
+			}
 		}
 
 		functionStatus := &functionconfig.Status{
@@ -188,6 +194,11 @@ func (fo *functionOperator) CreateOrUpdate(ctx context.Context, object runtime.O
 			HTTPPort: httpPort,
 		}
 
+		if function.Status.State == functionconfig.FunctionStateWaitingForResourceConfiguration {
+			lastDeployed := time.Now().UTC()
+			functionStatus.LastDeployed = &lastDeployed
+		}
+
 		if err := fo.setFunctionScaleToZeroStatus(ctx, functionStatus, scaleEvent); err != nil {
 			return errors.Wrap(err, "Failed setting function scale to zero status")
 		}
@@ -246,6 +257,16 @@ func (fo *functionOperator) setFunctionStatus(function *nuclioio.NuclioFunction,
 
 	fo.logger.DebugWith("Setting function state", "name", function.Name, "status", status)
 
+	// the image is only known to whoever built it, and is kept across the controller's updates
+	if status.Image == nil {
+		status.Image = function.Status.Image
+	}
+
+	// as is the time it was last deployed, when only scaled
+	if status.LastDeployed == nil {
+		status.LastDeployed = function.Status.LastDeployed
+	}
+
 	// indicate error state
 	function.Status = *status
 
@@ -0,0 +1,209 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the port function containers serve their healthchecks (and the restarts of their wrappers) on
+const functionContainerHealthCheckPort = 8082
+
+// RuntimeLivenessMonitoring records the restarts of the wrappers of functions with runtime liveness checks
+// in the functions' status, as reported by the processors of their pods
+type RuntimeLivenessMonitoring struct {
+	logger     logger.Logger
+	controller *Controller
+	interval   time.Duration
+	httpClient *http.Client
+}
+
+func NewRuntimeLivenessMonitoring(parentLogger logger.Logger,
+	controller *Controller,
+	interval time.Duration) *RuntimeLivenessMonitoring {
+
+	newRuntimeLivenessMonitoring := &RuntimeLivenessMonitoring{
+		logger:     parentLogger.GetChild("runtime_liveness_monitoring"),
+		controller: controller,
+		interval:   interval,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	parentLogger.DebugWith("Successfully created runtime liveness monitoring instance", "interval", interval)
+
+	return newRuntimeLivenessMonitoring
+}
+
+func (rlm *RuntimeLivenessMonitoring) start() {
+	go rlm.startRuntimeLivenessLoop()
+}
+
+func (rlm *RuntimeLivenessMonitoring) startRuntimeLivenessLoop() {
+	rlm.logger.InfoWith("Starting runtime liveness loop", "interval", rlm.interval)
+
+	for {
+		time.Sleep(rlm.interval)
+
+		if err := rlm.updateRuntimeLivenessStatuses(); err != nil {
+			rlm.logger.WarnWith("Failed to update runtime liveness statuses", "err", err.Error())
+		}
+	}
+}
+
+// updateRuntimeLivenessStatuses records the restarts of the wrappers of the functions' pods in their status
+func (rlm *RuntimeLivenessMonitoring) updateRuntimeLivenessStatuses() error {
+	functions, err := rlm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(rlm.controller.namespace).
+		List(meta_v1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "Failed to list functions")
+	}
+
+	for functionIdx := range functions.Items {
+		function := &functions.Items[functionIdx]
+
+		if function.Spec.RuntimeLiveness == nil || function.Status.State != functionconfig.FunctionStateReady {
+			continue
+		}
+
+		runtimeLivenessStatus, err := rlm.getFunctionRuntimeLivenessStatus(function)
+		if err != nil {
+			rlm.logger.WarnWith("Failed to get function runtime liveness status",
+				"namespace", function.Namespace,
+				"function", function.Name,
+				"err", err.Error())
+			continue
+		}
+
+		if runtimeLivenessStatusesEqual(runtimeLivenessStatus, function.Status.RuntimeLiveness) {
+			continue
+		}
+
+		function.Status.RuntimeLiveness = runtimeLivenessStatus
+
+		if _, err := rlm.controller.nuclioClientSet.
+			NuclioV1beta1().
+			NuclioFunctions(function.Namespace).
+			Update(function); err != nil {
+			rlm.logger.WarnWith("Failed to update function runtime liveness status",
+				"namespace", function.Namespace,
+				"function", function.Name,
+				"err", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// getFunctionRuntimeLivenessStatus returns the restarts of the wrappers across the function's running pods
+func (rlm *RuntimeLivenessMonitoring) getFunctionRuntimeLivenessStatus(
+	function *nuclioio.NuclioFunction) (*functionconfig.RuntimeLivenessStatus, error) {
+	pods, err := rlm.controller.kubeClientSet.
+		CoreV1().
+		Pods(function.Namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: fmt.Sprintf("nuclio.io/class=function,nuclio.io/function-name=%s", function.Name),
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list function pods")
+	}
+
+	var runtimeLivenessStatus *functionconfig.RuntimeLivenessStatus
+
+	for podIdx := range pods.Items {
+		pod := &pods.Items[podIdx]
+		if pod.Status.PodIP == "" {
+			continue
+		}
+
+		podRuntimeLivenessStatus, err := rlm.getPodRuntimeLivenessStatus(pod.Status.PodIP)
+		if err != nil {
+			rlm.logger.DebugWith("Failed to get pod runtime liveness status",
+				"pod", pod.Name,
+				"err", err.Error())
+			continue
+		}
+
+		if podRuntimeLivenessStatus == nil {
+			continue
+		}
+
+		if runtimeLivenessStatus == nil {
+			runtimeLivenessStatus = &functionconfig.RuntimeLivenessStatus{}
+		}
+
+		runtimeLivenessStatus.Add(podRuntimeLivenessStatus)
+	}
+
+	return runtimeLivenessStatus, nil
+}
+
+// getPodRuntimeLivenessStatus returns the restarts of the pod's wrappers, or nil if the processor doesn't
+// check their liveness
+func (rlm *RuntimeLivenessMonitoring) getPodRuntimeLivenessStatus(
+	podIP string) (*functionconfig.RuntimeLivenessStatus, error) {
+	response, err := rlm.httpClient.Get(fmt.Sprintf("http://%s:%d/runtime-liveness",
+		podIP,
+		functionContainerHealthCheckPort))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get runtime liveness status")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	switch response.StatusCode {
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, errors.Errorf("Unexpected runtime liveness status code %d", response.StatusCode)
+	}
+
+	runtimeLivenessStatus := &functionconfig.RuntimeLivenessStatus{}
+	if err := json.NewDecoder(response.Body).Decode(runtimeLivenessStatus); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode runtime liveness status")
+	}
+
+	return runtimeLivenessStatus, nil
+}
+
+func runtimeLivenessStatusesEqual(first *functionconfig.RuntimeLivenessStatus,
+	second *functionconfig.RuntimeLivenessStatus) bool {
+	if first == nil || second == nil {
+		return first == second
+	}
+
+	if (first.LastRestartTime == nil) != (second.LastRestartTime == nil) ||
+		(first.LastRestartTime != nil && !first.LastRestartTime.Equal(*second.LastRestartTime)) {
+		return false
+	}
+
+	return first.Restarts == second.Restarts &&
+		first.LastError == second.LastError &&
+		first.Failed == second.Failed
+}
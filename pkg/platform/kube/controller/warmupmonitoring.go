@@ -0,0 +1,212 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// the port function containers serve their HTTP trigger on
+const functionContainerHTTPPort = 8080
+
+// WarmupMonitoring sends the warmup requests of functions which have one to their pods once they're ready -
+// whether they were created by a deployment or by scaling the function up
+type WarmupMonitoring struct {
+	logger       logger.Logger
+	controller   *Controller
+	interval     time.Duration
+	startTime    time.Time
+	warmedUpPods map[types.UID]bool
+}
+
+func NewWarmupMonitoring(parentLogger logger.Logger,
+	controller *Controller,
+	interval time.Duration) *WarmupMonitoring {
+
+	newWarmupMonitoring := &WarmupMonitoring{
+		logger:       parentLogger.GetChild("warmup_monitoring"),
+		controller:   controller,
+		interval:     interval,
+		warmedUpPods: map[types.UID]bool{},
+	}
+
+	parentLogger.DebugWith("Successfully created warmup monitoring instance", "interval", interval)
+
+	return newWarmupMonitoring
+}
+
+func (wm *WarmupMonitoring) start() {
+	wm.startTime = time.Now()
+
+	go wm.startWarmupLoop()
+}
+
+func (wm *WarmupMonitoring) startWarmupLoop() {
+	wm.logger.InfoWith("Starting warmup loop", "interval", wm.interval)
+
+	for {
+		time.Sleep(wm.interval)
+
+		if err := wm.warmUpReadyPods(); err != nil {
+			wm.logger.WarnWith("Failed to warm up ready function pods", "err", err.Error())
+		}
+	}
+}
+
+// warmUpReadyPods starts warming up the function pods which became ready since they were last checked
+func (wm *WarmupMonitoring) warmUpReadyPods() error {
+	pods, err := wm.controller.kubeClientSet.
+		CoreV1().
+		Pods(wm.controller.namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: "nuclio.io/class=function,nuclio.io/function-name,!nuclio.io/function-cron-job-pod",
+		})
+	if err != nil {
+		return errors.Wrap(err, "Failed to list function pods")
+	}
+
+	currentPods := map[types.UID]bool{}
+	functions := map[string]*nuclioio.NuclioFunction{}
+
+	for podIdx := range pods.Items {
+		pod := &pods.Items[podIdx]
+		currentPods[pod.UID] = true
+
+		if wm.warmedUpPods[pod.UID] {
+			continue
+		}
+
+		podReadyTime := getPodReadyTime(pod)
+		if podReadyTime == nil || pod.Status.PodIP == "" {
+			continue
+		}
+
+		functionName := pod.Labels["nuclio.io/function-name"]
+		functionKey := fmt.Sprintf("%s/%s", pod.Namespace, functionName)
+
+		function, functionFound := functions[functionKey]
+		if !functionFound {
+			function, err = wm.controller.nuclioClientSet.
+				NuclioV1beta1().
+				NuclioFunctions(pod.Namespace).
+				Get(functionName, meta_v1.GetOptions{})
+			if err != nil {
+				wm.logger.WarnWith("Failed to get function of pod",
+					"pod", pod.Name,
+					"function", functionKey,
+					"err", err.Error())
+				continue
+			}
+
+			functions[functionKey] = function
+		}
+
+		wm.warmedUpPods[pod.UID] = true
+
+		if !wm.podRequiresWarmup(function, podReadyTime) {
+			continue
+		}
+
+		go wm.warmUpPod(pod.Namespace, functionName, pod.Name, pod.Status.PodIP)
+	}
+
+	// forget the pods which were removed
+	for podUID := range wm.warmedUpPods {
+		if !currentPods[podUID] {
+			delete(wm.warmedUpPods, podUID)
+		}
+	}
+
+	return nil
+}
+
+func (wm *WarmupMonitoring) podRequiresWarmup(function *nuclioio.NuclioFunction, podReadyTime *time.Time) bool {
+	if function.Spec.Warmup == nil {
+		return false
+	}
+
+	// a pod which was ready before the controller started may have been warmed up by its previous instance
+	lastWarmup := function.Status.Warmup
+	if podReadyTime.Before(wm.startTime) &&
+		lastWarmup != nil &&
+		lastWarmup.Time != nil &&
+		podReadyTime.Before(*lastWarmup.Time) {
+		return false
+	}
+
+	return true
+}
+
+// warmUpPod sends the function's warmup requests to the pod, and reports their results in the function's status
+func (wm *WarmupMonitoring) warmUpPod(namespace string, functionName string, podName string, podIP string) {
+	function, err := wm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(namespace).
+		Get(functionName, meta_v1.GetOptions{})
+	if err != nil || function.Spec.Warmup == nil {
+		return
+	}
+
+	warmupStatus := abstract.WarmUpFunction(wm.logger,
+		podName,
+		fmt.Sprintf("%s:%d", podIP, functionContainerHTTPPort),
+		function.Spec.Warmup)
+
+	// the function may have been updated during the warmup
+	function, err = wm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(namespace).
+		Get(functionName, meta_v1.GetOptions{})
+	if err != nil {
+		wm.logger.WarnWith("Failed to get function to update its warmup status",
+			"function", functionName,
+			"err", err.Error())
+		return
+	}
+
+	function.Status.Warmup = warmupStatus
+
+	if _, err := wm.controller.nuclioClientSet.
+		NuclioV1beta1().
+		NuclioFunctions(namespace).
+		Update(function); err != nil {
+		wm.logger.WarnWith("Failed to update function warmup status",
+			"function", functionName,
+			"err", err.Error())
+	}
+}
+
+// getPodReadyTime returns when the pod became ready, or nil if it isn't
+func getPodReadyTime(pod *v1.Pod) *time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+			return &condition.LastTransitionTime.Time
+		}
+	}
+
+	return nil
+}
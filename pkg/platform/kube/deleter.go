@@ -63,6 +63,23 @@ func (d *deleter) delete(consumer *consumer, deleteFunctionOptions *platform.Del
 		return errors.Wrap(err, "Failed to delete function CR")
 	}
 
+	// the revisions of a function deployed again with the same name start over
+	err = consumer.kubeClientSet.
+		CoreV1().
+		ConfigMaps(deleteFunctionOptions.FunctionConfig.Meta.Namespace).
+		Delete(RevisionsConfigMapNameFromFunctionName(resourceName), &meta_v1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		d.logger.WarnWith("Failed to delete function revisions configmap", "err", err.Error())
+	}
+
+	err = consumer.kubeClientSet.
+		CoreV1().
+		ConfigMaps(deleteFunctionOptions.FunctionConfig.Meta.Namespace).
+		Delete(InvocationsConfigMapNameFromFunctionName(resourceName), &meta_v1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		d.logger.WarnWith("Failed to delete function invocations configmap", "err", err.Error())
+	}
+
 	d.logger.InfoWith("Function deleted", "name", resourceName)
 
 	return nil
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
@@ -155,21 +156,48 @@ func (d *deployer) deploy(functionInstance *nuclioio.NuclioFunction,
 		deployLogger = d.logger
 	}
 
+	// pods created by this deploy are those created after the apply. creation timestamps are in seconds
+	applyTime := time.Now().Truncate(time.Second)
+
 	// do the create / update
-	_, err := d.createOrUpdateFunction(functionInstance,
-		createFunctionOptions,
-		&functionconfig.Status{
-			State: functionconfig.FunctionStateWaitingForResourceConfiguration,
-		})
+	err := createFunctionOptions.PhaseTimings.Measure(common.PhasePlatformApply, func() error {
+		_, err := d.createOrUpdateFunction(functionInstance,
+			createFunctionOptions,
+			&functionconfig.Status{
+				State: functionconfig.FunctionStateWaitingForResourceConfiguration,
+				Image: createFunctionOptions.ImageStatus,
+			})
+		return err
+	})
 	if err != nil {
 		return nil, nil, err.Error(), errors.Wrap(err, "Failed to create function")
 	}
 
+	// scheduling is only waited for separately when the phases are of interest, as it polls the pods
+	if createFunctionOptions.PhaseTimings != nil {
+		err = createFunctionOptions.PhaseTimings.Measure(common.PhasePodScheduling, func() error {
+			return waitForFunctionPodScheduling(d.consumer,
+				functionInstance.Namespace,
+				functionInstance.Name,
+				applyTime)
+		})
+		if err != nil {
+			podLogs, briefErrorsMessage := d.getFunctionPodLogsAndEvents(functionInstance.Namespace, functionInstance.Name)
+			return nil, nil, briefErrorsMessage, errors.Wrapf(err, "Failed to wait for function pod scheduling.\n%s", podLogs)
+		}
+	}
+
 	// wait for the function to be ready
-	updatedFunctionInstance, err := waitForFunctionReadiness(deployLogger,
-		d.consumer,
-		functionInstance.Namespace,
-		functionInstance.Name)
+	var updatedFunctionInstance *nuclioio.NuclioFunction
+	err = createFunctionOptions.PhaseTimings.Measure(common.PhaseReadinessWait, func() error {
+		var err error
+
+		updatedFunctionInstance, err = waitForFunctionReadiness(deployLogger,
+			d.consumer,
+			functionInstance.Namespace,
+			functionInstance.Name)
+		return err
+	})
 	if err != nil {
 		podLogs, briefErrorsMessage := d.getFunctionPodLogsAndEvents(functionInstance.Namespace, functionInstance.Name)
 		return nil, updatedFunctionInstance, briefErrorsMessage, errors.Wrapf(err, "Failed to wait for function readiness.\n%s", podLogs)
@@ -199,7 +227,9 @@ func waitForFunctionReadiness(loggerInstance logger.Logger,
 		}
 
 		switch function.Status.State {
-		case functionconfig.FunctionStateReady:
+
+		// a disabled function is done once its resources are configured, without replicas
+		case functionconfig.FunctionStateReady, functionconfig.FunctionStatePaused:
 			return true, nil
 		case functionconfig.FunctionStateError:
 			return false, errors.Errorf("NuclioFunction in error state:\n%s", function.Status.Message)
@@ -212,6 +242,53 @@ func waitForFunctionReadiness(loggerInstance logger.Logger,
 	return function, err
 }
 
+// waitForFunctionPodScheduling waits until a pod of the function created since the given time is scheduled on a
+// node, or until the function is no longer being deployed (e.g. it has no replicas, or it failed)
+func waitForFunctionPodScheduling(consumer *consumer,
+	namespace string,
+	name string,
+	since time.Time) error {
+
+	conditionFunc := func() (bool, error) {
+		function, err := consumer.nuclioClientSet.NuclioV1beta1().
+			NuclioFunctions(namespace).
+			Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return true, err
+		}
+
+		switch function.Status.State {
+		case functionconfig.FunctionStateReady, functionconfig.FunctionStatePaused, functionconfig.FunctionStateError:
+			return true, nil
+		}
+
+		functionPods, err := consumer.kubeClientSet.CoreV1().
+			Pods(namespace).
+			List(meta_v1.ListOptions{
+				LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", name),
+			})
+		if err != nil {
+			return true, err
+		}
+
+		for _, pod := range functionPods.Items {
+			if pod.CreationTimestamp.Time.Before(since) {
+				continue
+			}
+
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionTrue {
+					return true, nil
+				}
+			}
+		}
+
+		return false, nil
+	}
+
+	return wait.PollInfinite(250*time.Millisecond, conditionFunc)
+}
+
 func (d *deployer) getFunctionPodLogsAndEvents(namespace string, name string) (string, string) {
 	var briefErrorsMessage string
 	podLogsMessage := "\nPod logs:\n"
@@ -209,6 +209,33 @@ func (f *function) GetInvokeURL(invokeViaType platform.InvokeViaType) (string, e
 	return fmt.Sprintf("%s:%d%s", host, port, path), nil
 }
 
+// GetGRPCInvokeURL returns the address on which the function's gRPC trigger can be invoked - the node port its
+// service exposes the trigger's port on, or the trigger's port on the service's domain name
+func (f *function) GetGRPCInvokeURL(invokeViaType platform.InvokeViaType, grpcPort int) (string, error) {
+	grpcPort, err := f.ResolveGRPCPort(grpcPort)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve gRPC port")
+	}
+
+	switch invokeViaType {
+	case platform.InvokeViaExternalIP:
+		return f.getExternalIPGRPCInvokeURL(grpcPort)
+	case platform.InvokeViaDomainName:
+		return f.getDomainNameGRPCInvokeURL(grpcPort)
+	case platform.InvokeViaLoadBalancer:
+		return "", errors.New("gRPC triggers can't be invoked via a load balancer")
+	}
+
+	invokeURL, err := f.getExternalIPGRPCInvokeURL(grpcPort)
+	if err == nil {
+		return invokeURL, nil
+	}
+
+	f.Logger.DebugWith("Could not get external IP gRPC invoke URL", "err", err)
+
+	return f.getDomainNameGRPCInvokeURL(grpcPort)
+}
+
 // GetReplicas returns the current # of replicas and the configured # of replicas
 func (f *function) GetReplicas() (int, int) {
 	return f.availableReplicas, f.configuredReplicas
@@ -310,10 +337,55 @@ func (f *function) getExternalIPInvokeURL() (string, int, string, error) {
 }
 
 func (f *function) getDomainNameInvokeURL() (string, int, string, error) {
+
+	// the service may not have been found when the function was initialized
+	if f.service == nil {
+		return "", 0, "", nil
+	}
+
 	host, port := GetDomainNameInvokeURL(f.service.Name, f.function.Namespace)
 	return host, port, "", nil
 }
 
+func (f *function) getExternalIPGRPCInvokeURL(grpcPort int) (string, error) {
+	nodePort := f.getServiceNodePort(grpcPort)
+	if nodePort == 0 {
+		return "", errors.Errorf("The function's service doesn't expose port %d on a node port", grpcPort)
+	}
+
+	host, _, err := f.GetExternalIPInvocationURL()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get external IP invocation URL")
+	}
+
+	return fmt.Sprintf("%s:%d", host, nodePort), nil
+}
+
+func (f *function) getDomainNameGRPCInvokeURL(grpcPort int) (string, error) {
+	if f.service == nil {
+		return "", errors.New("The function's service wasn't found")
+	}
+
+	host, _ := GetDomainNameInvokeURL(f.service.Name, f.function.Namespace)
+
+	return fmt.Sprintf("%s:%d", host, grpcPort), nil
+}
+
+// getServiceNodePort returns the node port on which the function's service exposes the given port, if any
+func (f *function) getServiceNodePort(port int) int {
+	if f.service == nil {
+		return 0
+	}
+
+	for _, servicePort := range f.service.Spec.Ports {
+		if int(servicePort.Port) == port {
+			return int(servicePort.NodePort)
+		}
+	}
+
+	return 0
+}
+
 func GetDomainNameInvokeURL(serviceName, namespace string) (string, int) {
 	var domainName string
 
@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+type functionTestSuite struct {
+	suite.Suite
+	mockPlatform *mockplatform.Platform
+}
+
+func (suite *functionTestSuite) SetupTest() {
+	suite.mockPlatform = &mockplatform.Platform{}
+	suite.mockPlatform.On("GetExternalIPAddresses").Return([]string{"10.0.0.1"}, nil)
+}
+
+func (suite *functionTestSuite) TestGetGRPCInvokeURL() {
+	function := suite.newFunction(map[string]functionconfig.Trigger{
+		"grpc": {Kind: "grpc", URL: ":9000"},
+	}, []v1.ServicePort{
+		{Name: "http", Port: 8080, NodePort: 30000},
+		{Name: "grpc-9000", Port: 9000, NodePort: 30001},
+	})
+
+	// the node port of the trigger's port, rather than that of the HTTP port
+	invokeURL, err := function.GetGRPCInvokeURL(platform.InvokeViaExternalIP, 0)
+	suite.Require().NoError(err)
+	suite.Require().Equal("10.0.0.1:30001", invokeURL)
+
+	invokeURL, err = function.GetGRPCInvokeURL(platform.InvokeViaAny, 9000)
+	suite.Require().NoError(err)
+	suite.Require().Equal("10.0.0.1:30001", invokeURL)
+
+	invokeURL, err = function.GetGRPCInvokeURL(platform.InvokeViaDomainName, 0)
+	suite.Require().NoError(err)
+	suite.Require().Equal("nuclio-my-function.default.svc.cluster.local:9000", invokeURL)
+
+	_, err = function.GetGRPCInvokeURL(platform.InvokeViaLoadBalancer, 0)
+	suite.Require().Error(err)
+
+	// not the port of a gRPC trigger
+	_, err = function.GetGRPCInvokeURL(platform.InvokeViaAny, 8080)
+	suite.Require().Error(err)
+}
+
+func (suite *functionTestSuite) TestGetGRPCInvokeURLWithoutNodePort() {
+	function := suite.newFunction(map[string]functionconfig.Trigger{
+		"grpc": {Kind: "grpc"},
+	}, []v1.ServicePort{
+		{Name: "http", Port: 8080},
+		{Name: "grpc-50051", Port: 50051},
+	})
+
+	_, err := function.GetGRPCInvokeURL(platform.InvokeViaExternalIP, 0)
+	suite.Require().Error(err)
+
+	// falls back to the service's domain name
+	invokeURL, err := function.GetGRPCInvokeURL(platform.InvokeViaAny, 0)
+	suite.Require().NoError(err)
+	suite.Require().Equal("nuclio-my-function.default.svc.cluster.local:50051", invokeURL)
+}
+
+func (suite *functionTestSuite) TestGetGRPCInvokeURLWithoutGRPCTrigger() {
+	function := suite.newFunction(nil, []v1.ServicePort{
+		{Name: "http", Port: 8080, NodePort: 30000},
+	})
+
+	_, err := function.GetGRPCInvokeURL(platform.InvokeViaAny, 0)
+	suite.Require().Error(err)
+}
+
+func (suite *functionTestSuite) newFunction(triggers map[string]functionconfig.Trigger,
+	servicePorts []v1.ServicePort) *function {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	nuclioioFunction := &nuclioio.NuclioFunction{}
+	nuclioioFunction.Name = "my-function"
+	nuclioioFunction.Namespace = "default"
+	nuclioioFunction.Spec.Triggers = triggers
+
+	function, err := newFunction(loggerInstance, suite.mockPlatform, nuclioioFunction, nil)
+	suite.Require().NoError(err)
+
+	function.service = &v1.Service{}
+	function.service.Name = "nuclio-my-function"
+	function.service.Spec.Ports = servicePorts
+
+	return function
+}
+
+func TestFunctionTestSuite(t *testing.T) {
+	suite.Run(t, new(functionTestSuite))
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functionres
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/kube"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	autos_v2 "k8s.io/api/autoscaling/v2beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// the metric the processors report the consumer lag of their triggers in
+const consumerLagMetricName = "nuclio_processor_consumer_lag"
+
+// KEDA's scaled objects, which replace the HPA of functions scaled in keda mode
+const (
+	scaledObjectAPIVersion = "keda.sh/v1alpha1"
+	scaledObjectKind       = "ScaledObject"
+	scaledObjectsPath      = "/apis/keda.sh/v1alpha1/namespaces/%s/scaledobjects"
+)
+
+type scaledObject struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               scaledObjectSpec `json:"spec"`
+}
+
+type scaledObjectSpec struct {
+	ScaleTargetRef  scaledObjectTargetRef `json:"scaleTargetRef"`
+	MinReplicaCount int32                 `json:"minReplicaCount"`
+	MaxReplicaCount int32                 `json:"maxReplicaCount"`
+	PollingInterval int32                 `json:"pollingInterval"`
+	CooldownPeriod  int32                 `json:"cooldownPeriod"`
+	Triggers        []scaledObjectTrigger `json:"triggers"`
+}
+
+type scaledObjectTargetRef struct {
+	Name string `json:"name"`
+}
+
+type scaledObjectTrigger struct {
+	Type     string            `json:"type"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// the attributes of a kafka trigger KEDA reads the lag of its consumer group with
+type kafkaTriggerAttributes struct {
+	Brokers       []string
+	Topics        []string
+	ConsumerGroup string
+}
+
+// isScaledByKEDA returns whether the function is scaled by a KEDA scaled object rather than by its HPA
+func isScaledByKEDA(function *nuclioio.NuclioFunction) bool {
+	return function.Spec.AutoScale != nil && function.Spec.AutoScale.GetMode() == functionconfig.AutoScaleModeKEDA
+}
+
+// getConsumerLagMetricSpecs returns the metrics the HPA of a function scaled on its consumer lag targets - the
+// lag reported by its replicas, averaged across them
+func (lc *lazyClient) getConsumerLagMetricSpecs(function *nuclioio.NuclioFunction) []autos_v2.MetricSpec {
+	return []autos_v2.MetricSpec{
+		{
+			Type: "Pods",
+			Pods: &autos_v2.PodsMetricSource{
+				MetricName:         consumerLagMetricName,
+				TargetAverageValue: *apiresource.NewQuantity(function.Spec.AutoScale.LagThreshold, apiresource.DecimalSI),
+			},
+		},
+	}
+}
+
+// createOrUpdateScaledObject creates or updates the KEDA scaled object of a function scaled in keda mode, and
+// deletes that of a function which no longer is
+func (lc *lazyClient) createOrUpdateScaledObject(functionLabels labels.Set, function *nuclioio.NuclioFunction) error {
+	if !isScaledByKEDA(function) {
+
+		// KEDA may not even be installed, so failing to delete isn't fatal
+		if err := lc.deleteScaledObject(function.Namespace, function.Name); err != nil {
+			lc.logger.DebugWith("Failed to delete scaled object",
+				"name", function.Name,
+				"err", err.Error())
+		}
+
+		return nil
+	}
+
+	newScaledObject, err := lc.generateScaledObject(functionLabels, function)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate scaled object")
+	}
+
+	scaledObjectsRequestPath := fmt.Sprintf(scaledObjectsPath, function.Namespace)
+	restClient := lc.kubeClientSet.CoreV1().RESTClient()
+
+	existingScaledObjectBody, err := restClient.Get().
+		AbsPath(scaledObjectsRequestPath, newScaledObject.Name).
+		DoRaw()
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "Failed to get scaled object")
+		}
+
+		encodedScaledObject, err := json.Marshal(newScaledObject)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode scaled object")
+		}
+
+		lc.logger.DebugWith("Creating scaled object", "name", newScaledObject.Name)
+
+		if _, err := restClient.Post().
+			AbsPath(scaledObjectsRequestPath).
+			SetHeader("Content-Type", "application/json").
+			Body(encodedScaledObject).
+			DoRaw(); err != nil {
+			return errors.Wrap(err, "Failed to create scaled object")
+		}
+
+		return nil
+	}
+
+	existingScaledObject := scaledObject{}
+	if err := json.Unmarshal(existingScaledObjectBody, &existingScaledObject); err != nil {
+		return errors.Wrap(err, "Failed to decode scaled object")
+	}
+
+	existingScaledObject.Labels = newScaledObject.Labels
+	existingScaledObject.Spec = newScaledObject.Spec
+
+	encodedScaledObject, err := json.Marshal(&existingScaledObject)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode scaled object")
+	}
+
+	lc.logger.DebugWith("Updating scaled object", "name", newScaledObject.Name)
+
+	if _, err := restClient.Put().
+		AbsPath(scaledObjectsRequestPath, newScaledObject.Name).
+		SetHeader("Content-Type", "application/json").
+		Body(encodedScaledObject).
+		DoRaw(); err != nil {
+		return errors.Wrap(err, "Failed to update scaled object")
+	}
+
+	return nil
+}
+
+// deleteScaledObject deletes the KEDA scaled object of a function, if it has one
+func (lc *lazyClient) deleteScaledObject(namespace string, functionName string) error {
+	_, err := lc.kubeClientSet.CoreV1().RESTClient().
+		Delete().
+		AbsPath(fmt.Sprintf(scaledObjectsPath, namespace), kube.ScaledObjectNameFromFunctionName(functionName)).
+		DoRaw()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// generateScaledObject returns the KEDA scaled object of a function, which scales its deployment on the lag of
+// the consumer group of each topic its kafka triggers consume
+func (lc *lazyClient) generateScaledObject(functionLabels labels.Set,
+	function *nuclioio.NuclioFunction) (*scaledObject, error) {
+	autoScale := function.Spec.AutoScale
+
+	// unlike the HPA, KEDA scales functions to zero replicas (and from zero, once there's lag)
+	minReplicas := function.GetComputedMinReplicas()
+	maxReplicas := function.GetComputedMaxReplicas()
+	if maxReplicas < 1 {
+		maxReplicas = 1
+	}
+
+	newScaledObject := &scaledObject{
+		TypeMeta: meta_v1.TypeMeta{
+			APIVersion: scaledObjectAPIVersion,
+			Kind:       scaledObjectKind,
+		},
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      kube.ScaledObjectNameFromFunctionName(function.Name),
+			Namespace: function.Namespace,
+			Labels:    functionLabels,
+		},
+		Spec: scaledObjectSpec{
+			ScaleTargetRef: scaledObjectTargetRef{
+				Name: kube.DeploymentNameFromFunctionName(function.Name),
+			},
+			MinReplicaCount: minReplicas,
+			MaxReplicaCount: maxReplicas,
+			PollingInterval: int32(autoScale.GetPollingInterval().Seconds()),
+			CooldownPeriod:  int32(autoScale.GetCooldownPeriod().Seconds()),
+		},
+	}
+
+	// generate the triggers in a consistent order, so that updates don't needlessly change the object
+	var triggerNames []string
+	for triggerName, trigger := range function.Spec.Triggers {
+		if common.StringInSlice(trigger.Kind, functionconfig.AutoScaleTriggerKinds[functionconfig.AutoScaleModeKEDA]) {
+			triggerNames = append(triggerNames, triggerName)
+		}
+	}
+
+	sort.Strings(triggerNames)
+
+	for _, triggerName := range triggerNames {
+		trigger := function.Spec.Triggers[triggerName]
+
+		attributes := kafkaTriggerAttributes{}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode the attributes of trigger %s", triggerName)
+		}
+
+		brokers := attributes.Brokers
+		if len(brokers) == 0 && trigger.URL != "" {
+			brokers = []string{trigger.URL}
+		}
+
+		if len(brokers) == 0 || attributes.ConsumerGroup == "" {
+			return nil, errors.Errorf("Trigger %s must have brokers and a consumer group to be scaled by KEDA",
+				triggerName)
+		}
+
+		for _, topic := range attributes.Topics {
+			newScaledObject.Spec.Triggers = append(newScaledObject.Spec.Triggers, scaledObjectTrigger{
+				Type: "kafka",
+				Metadata: map[string]string{
+					"bootstrapServers": strings.Join(brokers, ","),
+					"consumerGroup":    attributes.ConsumerGroup,
+					"topic":            topic,
+					"lagThreshold":     strconv.FormatInt(autoScale.LagThreshold, 10),
+				},
+			})
+		}
+	}
+
+	if len(newScaledObject.Spec.Triggers) == 0 {
+		return nil, errors.New("Function has no kafka topics to be scaled by KEDA on")
+	}
+
+	return newScaledObject, nil
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -65,8 +66,18 @@ const (
 	containerHTTPPortName         = "http"
 	containerMetricPort           = 8090
 	containerMetricPortName       = "metrics"
+	containerGRPCPortNamePrefix   = "grpc-"
 	nvidiaGpuResourceName         = "nvidia.com/gpu"
+	nvidiaGPUSharingStrategyLabel = "nvidia.com/gpu.sharing-strategy"
 	nginxIngressUpdateGracePeriod = 5 * time.Second
+
+	nginxRewriteTargetAnnotation       = "nginx.ingress.kubernetes.io/rewrite-target"
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+	seccompContainerAnnotationPrefix   = "container.seccomp.security.alpha.kubernetes.io/"
+
+	tempVolumeName      = "tmp"
+	tempVolumeMountPath = "/tmp"
 )
 
 type deploymentResourceMethod string
@@ -225,6 +236,11 @@ func (lc *lazyClient) CreateOrUpdate(ctx context.Context, function *nuclioio.Nuc
 		return nil, errors.Wrap(err, "Failed to create/update HPA")
 	}
 
+	// create, update or delete the KEDA scaled object, which replaces the HPA of functions scaled by KEDA
+	if err := lc.createOrUpdateScaledObject(functionLabels, function); err != nil {
+		return nil, errors.Wrap(err, "Failed to create/update scaled object")
+	}
+
 	// create or update ingress
 	resources.ingress, err = lc.createOrUpdateIngress(functionLabels, function)
 	if err != nil {
@@ -329,6 +345,11 @@ func (lc *lazyClient) Delete(ctx context.Context, namespace string, name string)
 		lc.logger.DebugWith("Deleted HPA", "namespace", namespace, "hpaName", hpaName)
 	}
 
+	// Delete scaled object if exists (KEDA may not be installed, so failing to is not fatal)
+	if err := lc.deleteScaledObject(namespace, name); err != nil {
+		lc.logger.DebugWith("Failed to delete scaled object", "namespace", namespace, "name", name, "err", err.Error())
+	}
+
 	// Delete Service if exists
 	serviceName := kube.ServiceNameFromFunctionName(name)
 	err = lc.kubeClientSet.CoreV1().Services(namespace).Delete(serviceName, deleteOptions)
@@ -434,7 +455,7 @@ func (lc *lazyClient) createOrUpdateCronTriggerCronJobs(functionLabels labels.Se
 			function,
 			triggerName,
 			cronJobSpec,
-			suspendCronJobs)
+			suspendCronJobs || cronTrigger.Disabled)
 		if err != nil {
 
 			go func() {
@@ -719,6 +740,11 @@ func (lc *lazyClient) createOrUpdateDeployment(functionLabels labels.Set,
 	// get volumes and volumeMounts from configuration
 	volumes, volumeMounts := lc.getFunctionVolumeAndMounts(function)
 
+	nodeSelector, tolerations, err := lc.getPodScheduling(function)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get pod scheduling")
+	}
+
 	getDeployment := func() (interface{}, error) {
 		return lc.kubeClientSet.AppsV1().
 			Deployments(function.Namespace).
@@ -735,9 +761,6 @@ func (lc *lazyClient) createOrUpdateDeployment(functionLabels labels.Set,
 
 	createDeployment := func() (interface{}, error) {
 		method := createDeploymentResourceMethod
-		container := v1.Container{Name: "nuclio"}
-		lc.populateDeploymentContainer(functionLabels, function, &container)
-		container.VolumeMounts = volumeMounts
 
 		deploymentSpec := apps_v1.DeploymentSpec{
 			Selector: &meta_v1.LabelSelector{
@@ -755,11 +778,14 @@ func (lc *lazyClient) createOrUpdateDeployment(functionLabels labels.Set,
 					ImagePullSecrets: []v1.LocalObjectReference{
 						{Name: imagePullSecrets},
 					},
-					Containers: []v1.Container{
-						container,
-					},
+					Containers:         lc.populateDeploymentContainers(functionLabels, function, nil, volumeMounts),
 					Volumes:            volumes,
 					ServiceAccountName: function.Spec.ServiceAccount,
+					NodeSelector:       nodeSelector,
+					Tolerations:        tolerations,
+
+					// the time the processor has to drain, from when its preStop hook is called
+					TerminationGracePeriodSeconds: function.Spec.TerminationGracePeriodSeconds,
 				},
 			},
 		}
@@ -811,9 +837,14 @@ func (lc *lazyClient) createOrUpdateDeployment(functionLabels labels.Set,
 		deployment.Annotations = deploymentAnnotations
 		deployment.Spec.Replicas = replicas
 		deployment.Spec.Template.Annotations = podAnnotations
-		lc.populateDeploymentContainer(functionLabels, function, &deployment.Spec.Template.Spec.Containers[0])
+		deployment.Spec.Template.Spec.Containers = lc.populateDeploymentContainers(functionLabels,
+			function,
+			deployment.Spec.Template.Spec.Containers,
+			volumeMounts)
 		deployment.Spec.Template.Spec.Volumes = volumes
-		deployment.Spec.Template.Spec.Containers[0].VolumeMounts = volumeMounts
+		deployment.Spec.Template.Spec.TerminationGracePeriodSeconds = function.Spec.TerminationGracePeriodSeconds
+		deployment.Spec.Template.Spec.NodeSelector = nodeSelector
+		deployment.Spec.Template.Spec.Tolerations = tolerations
 
 		if function.Spec.ServiceAccount != "" {
 			deployment.Spec.Template.Spec.ServiceAccountName = function.Spec.ServiceAccount
@@ -847,6 +878,10 @@ func (lc *lazyClient) resolveDeploymentStrategy(function *nuclioio.NuclioFunctio
 	// redeploying a Nuclio function will get stuck if no GPU is available
 	// to overcome it, we simply change the update strategy to recreate
 	// so k8s will kill the existing pod\function and create the new one
+	if function.Spec.GPU != nil {
+		return apps_v1.RecreateDeploymentStrategyType
+	}
+
 	if gpuResource, ok := function.Spec.Resources.Limits[nvidiaGpuResourceName]; ok {
 
 		// requested a gpu resource, change to recreate
@@ -859,6 +894,84 @@ func (lc *lazyClient) resolveDeploymentStrategy(function *nuclioio.NuclioFunctio
 	return apps_v1.RollingUpdateDeploymentStrategyType
 }
 
+// getGPUResources returns the resources a replica requests for the GPUs of spec.gpu - whole GPUs, or its share
+// of a GPU. a replica gets as many of a GPU's shares as its fraction of the GPU, rounded up
+func (lc *lazyClient) getGPUResources(function *nuclioio.NuclioFunction) v1.ResourceList {
+	gpu := function.Spec.GPU
+	if gpu == nil {
+		return nil
+	}
+
+	if !gpu.IsShared() {
+		return v1.ResourceList{
+			nvidiaGpuResourceName: *apiresource.NewQuantity(int64(gpu.Count), apiresource.DecimalSI),
+		}
+	}
+
+	gpuSharing := lc.platformConfigurationProvider.GetPlatformConfiguration().GPUSharing
+
+	// tolerate floating point errors, so that e.g. 0.3 of a GPU shared by 10 is 3 shares rather than 4
+	shares := int64(math.Ceil(gpu.Count*float64(gpuSharing.GetReplicas()) - 1e-9))
+
+	return v1.ResourceList{
+		v1.ResourceName(gpuSharing.GetResourceName()): *apiresource.NewQuantity(shares, apiresource.DecimalSI),
+	}
+}
+
+// getPodScheduling returns the node selector and tolerations of the function's pods - those of its scheduling
+// preset and its own node selector, and those its GPUs require
+func (lc *lazyClient) getPodScheduling(function *nuclioio.NuclioFunction) (map[string]string, []v1.Toleration, error) {
+	nodeSelector := map[string]string{}
+	var tolerations []v1.Toleration
+
+	if function.Spec.SchedulingPreset != "" {
+		schedulingPresets := lc.platformConfigurationProvider.GetPlatformConfiguration().SchedulingPresets
+
+		schedulingPreset, found := schedulingPresets[function.Spec.SchedulingPreset]
+		if !found {
+			return nil, nil, errors.Errorf("Scheduling preset %s isn't in the platform configuration",
+				function.Spec.SchedulingPreset)
+		}
+
+		for labelName, labelValue := range schedulingPreset.NodeSelector {
+			nodeSelector[labelName] = labelValue
+		}
+
+		tolerations = append(tolerations, schedulingPreset.Tolerations...)
+	}
+
+	for labelName, labelValue := range function.Spec.NodeSelector {
+		nodeSelector[labelName] = labelValue
+	}
+
+	if function.Spec.GPU != nil {
+
+		// GPU nodes are commonly tainted, so that only pods using their GPUs are scheduled to them
+		tolerations = append(tolerations, v1.Toleration{
+			Key:      nvidiaGpuResourceName,
+			Operator: v1.TolerationOpExists,
+			Effect:   v1.TaintEffectNoSchedule,
+		})
+
+		// shared GPUs are on the nodes whose device plugin shares them the requested way, as labeled by
+		// GPU feature discovery
+		if function.Spec.GPU.IsShared() {
+			sharingStrategy := "time-slicing"
+			if function.Spec.GPU.GetSharing() == functionconfig.GPUSharingMPS {
+				sharingStrategy = "mps"
+			}
+
+			nodeSelector[nvidiaGPUSharingStrategyLabel] = sharingStrategy
+		}
+	}
+
+	if len(nodeSelector) == 0 {
+		nodeSelector = nil
+	}
+
+	return nodeSelector, tolerations, nil
+}
+
 func (lc *lazyClient) enrichDeploymentFromPlatformConfiguration(function *nuclioio.NuclioFunction,
 	deployment *apps_v1.Deployment, method deploymentResourceMethod) error {
 	var allowSetDeploymentStrategy = true
@@ -965,12 +1078,23 @@ func (lc *lazyClient) createOrUpdateHorizontalPodAutoscaler(functionLabels label
 		return (resource).(*autos_v2.HorizontalPodAutoscaler).ObjectMeta.DeletionTimestamp != nil
 	}
 
+	// functions scaled by KEDA are scaled by the HPA of their scaled object instead
+	hpaRequired := minReplicas != maxReplicas && !isScaledByKEDA(function)
+
+	getMetricSpecs := func() ([]autos_v2.MetricSpec, error) {
+		if function.Spec.AutoScale != nil {
+			return lc.getConsumerLagMetricSpecs(function), nil
+		}
+
+		return lc.GetFunctionMetricSpecs(function.Name, targetCPU)
+	}
+
 	createHorizontalPodAutoscaler := func() (interface{}, error) {
-		if minReplicas == maxReplicas {
+		if !hpaRequired {
 			return nil, nil
 		}
 
-		metricSpecs, err := lc.GetFunctionMetricSpecs(function.Name, targetCPU)
+		metricSpecs, err := getMetricSpecs()
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to get function metric specs")
 		}
@@ -999,7 +1123,7 @@ func (lc *lazyClient) createOrUpdateHorizontalPodAutoscaler(functionLabels label
 	updateHorizontalPodAutoscaler := func(resourceToUpdate interface{}) (interface{}, error) {
 		hpa := resourceToUpdate.(*autos_v2.HorizontalPodAutoscaler)
 
-		metricSpecs, err := lc.GetFunctionMetricSpecs(function.Name, targetCPU)
+		metricSpecs, err := getMetricSpecs()
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to get function metric specs")
 		}
@@ -1009,14 +1133,15 @@ func (lc *lazyClient) createOrUpdateHorizontalPodAutoscaler(functionLabels label
 		hpa.Spec.MinReplicas = &minReplicas
 		hpa.Spec.MaxReplicas = maxReplicas
 
-		// when the min replicas equal the max replicas, there's no need for hpa resourceToUpdate
-		if minReplicas == maxReplicas {
+		// when the min replicas equal the max replicas (or KEDA scales the function), there's no need for hpa
+		// resourceToUpdate
+		if !hpaRequired {
 			propogationPolicy := meta_v1.DeletePropagationForeground
 			deleteOptions := &meta_v1.DeleteOptions{
 				PropagationPolicy: &propogationPolicy,
 			}
 
-			lc.logger.DebugWith("Deleting hpa - min replicas and max replicas are equal",
+			lc.logger.DebugWith("Deleting hpa - min replicas and max replicas are equal or scaled by KEDA",
 				"name", hpa.Name)
 
 			err := lc.kubeClientSet.AutoscalingV2beta1().
@@ -1266,10 +1391,24 @@ func (lc *lazyClient) getPodAnnotations(function *nuclioio.NuclioFunction) (map[
 		"nuclio.io/image-hash": function.Spec.ImageHash,
 	}
 
-	// add annotations for prometheus pull
+	// add annotations for prometheus pull, both nuclio's and the ones prometheus scrape configurations commonly use
 	if lc.functionsHaveMetricSink(lc.platformConfigurationProvider.GetPlatformConfiguration(), "prometheusPull") {
 		annotations["nuclio.io/prometheus_pull"] = "true"
 		annotations["nuclio.io/prometheus_pull_port"] = strconv.Itoa(containerMetricPort)
+		annotations["prometheus.io/scrape"] = "true"
+		annotations["prometheus.io/port"] = strconv.Itoa(containerMetricPort)
+		annotations["prometheus.io/path"] = "/metrics"
+	}
+
+	// seccomp profiles are set through annotations in the kubernetes versions nuclio supports
+	if function.Spec.SecurityContext != nil && function.Spec.SecurityContext.SeccompProfile != "" {
+		annotations[seccompContainerAnnotationPrefix+"nuclio"] = function.Spec.SecurityContext.SeccompProfile
+	}
+
+	// describe how the replicas share their GPU
+	if function.Spec.GPU != nil && function.Spec.GPU.IsShared() {
+		annotations["nuclio.io/gpu-sharing"] = string(function.Spec.GPU.GetSharing())
+		annotations["nuclio.io/gpu-fraction"] = strconv.FormatFloat(function.Spec.GPU.Count, 'f', -1, 64)
 	}
 
 	// add function annotations
@@ -1327,6 +1466,32 @@ func (lc *lazyClient) getFunctionEnvironment(functionLabels labels.Set,
 		},
 	})
 
+	return append(env, lc.getFunctionReferencesEnvironment(function)...)
+}
+
+// getFunctionReferencesEnvironment returns the URLs of the functions the function references. they're the
+// addresses of the functions' services, which stay the same as the functions are redeployed and scaled
+// (including to zero, when the services route to the DLX)
+func (lc *lazyClient) getFunctionReferencesEnvironment(function *nuclioio.NuclioFunction) []v1.EnvVar {
+	functionReferences, err := function.Spec.GetFunctionReferences(function.Labels["nuclio.io/project-name"])
+	if err != nil {
+		lc.logger.WarnWith("Ignoring invalid function references",
+			"function", function.Name,
+			"err", errors.Cause(err).Error())
+		return nil
+	}
+
+	var env []v1.EnvVar
+	for _, functionReference := range functionReferences {
+		env = append(env, v1.EnvVar{
+			Name: functionReference.GetEnvName(),
+			Value: fmt.Sprintf("http://%s.%s.svc:%d",
+				kube.ServiceNameFromFunctionName(functionReference.FunctionName),
+				function.Namespace,
+				containerHTTPPort),
+		})
+	}
+
 	return env
 }
 
@@ -1360,6 +1525,7 @@ func (lc *lazyClient) populateServiceSpec(functionLabels labels.Set,
 
 	spec.Type = function.Spec.ServiceType
 	serviceTypeIsNodePort := spec.Type == v1.ServiceTypeNodePort
+	previousPorts := spec.Ports
 
 	// update the service's node port on the following conditions:
 	// 1. this is a new service (spec.Ports is an empty list)
@@ -1385,6 +1551,50 @@ func (lc *lazyClient) populateServiceSpec(functionLabels labels.Set,
 
 	// make sure the ports exist (add if not)
 	spec.Ports = lc.ensureServicePortsExist(spec.Ports, platformServicePorts)
+
+	// expose the ports of the gRPC triggers, like the HTTP trigger's port
+	spec.Ports = lc.populateGRPCServicePorts(function, spec.Ports, previousPorts, serviceTypeIsNodePort)
+}
+
+// populateGRPCServicePorts replaces the gRPC ports of the service with those of the function's gRPC triggers,
+// keeping the node ports previously assigned to them so that they don't change on redeploy
+func (lc *lazyClient) populateGRPCServicePorts(function *nuclioio.NuclioFunction,
+	servicePorts []v1.ServicePort,
+	previousServicePorts []v1.ServicePort,
+	serviceTypeIsNodePort bool) []v1.ServicePort {
+	previousNodePorts := map[string]int32{}
+	for _, previousServicePort := range previousServicePorts {
+		if strings.HasPrefix(previousServicePort.Name, containerGRPCPortNamePrefix) {
+			previousNodePorts[previousServicePort.Name] = previousServicePort.NodePort
+		}
+	}
+
+	var populatedServicePorts []v1.ServicePort
+	for _, servicePort := range servicePorts {
+		if !strings.HasPrefix(servicePort.Name, containerGRPCPortNamePrefix) {
+			populatedServicePorts = append(populatedServicePorts, servicePort)
+		}
+	}
+
+	for _, grpcPort := range function.Spec.GetGRPCPorts() {
+		grpcServicePort := v1.ServicePort{
+			Name: getGRPCPortName(grpcPort),
+			Port: int32(grpcPort),
+		}
+
+		if serviceTypeIsNodePort {
+			grpcServicePort.NodePort = previousNodePorts[grpcServicePort.Name]
+		}
+
+		populatedServicePorts = append(populatedServicePorts, grpcServicePort)
+	}
+
+	return populatedServicePorts
+}
+
+// getGRPCPortName returns the name of the container and service port of a gRPC trigger's port
+func getGRPCPortName(grpcPort int) string {
+	return fmt.Sprintf("%s%d", containerGRPCPortNamePrefix, grpcPort)
 }
 
 func (lc *lazyClient) getServicePortsFromPlatform(platformConfiguration *platformconfig.Config) []v1.ServicePort {
@@ -1457,6 +1667,7 @@ func (lc *lazyClient) generateCronTriggerCronJobSpec(functionLabels labels.Set,
 		Schedule          string
 		Interval          string
 		ConcurrencyPolicy string
+		CatchUp           bool
 		Event             cron.Event
 	}
 
@@ -1497,6 +1708,12 @@ func (lc *lazyClient) generateCronTriggerCronJobSpec(functionLabels labels.Set,
 	// generate the curl command to be run by the CronJob to invoke the function
 	curlCommand := fmt.Sprintf("curl --silent %s %s", headersAsCurlArg, functionAddress)
 
+	// kubernetes keeps when the cron job last ran and starts a missed run. to catch up on ticks the
+	// function didn't handle (e.g. while it restarted), failed invocations fail the job, which is retried
+	if attributes.CatchUp {
+		curlCommand = fmt.Sprintf("curl --silent --fail %s %s", headersAsCurlArg, functionAddress)
+	}
+
 	if attributes.Event.Body != "" {
 		eventBody := attributes.Event.Body
 
@@ -1589,15 +1806,90 @@ func (lc *lazyClient) populateIngressConfig(functionLabels labels.Set,
 	spec.Rules = []ext_v1beta1.IngressRule{}
 	spec.TLS = []ext_v1beta1.IngressTLS{}
 
-	for _, ingress := range functionconfig.GetIngressesFromTriggers(function.Spec.Triggers) {
+	ingresses := functionconfig.GetIngressesFromTriggers(function.Spec.Triggers)
+
+	for _, ingress := range ingresses {
 		if err := lc.addIngressToSpec(&ingress, functionLabels, function, spec); err != nil {
 			return errors.Wrap(err, "Failed to add ingress to spec")
 		}
 	}
 
+	// the function's ingresses are rules of the same ingress resource, so they share its annotations
+	ingressAnnotations, err := lc.getIngressAnnotations(ingresses)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get ingress annotations")
+	}
+
+	for annotationName, annotationValue := range ingressAnnotations {
+		meta.Annotations[annotationName] = annotationValue
+	}
+
 	return nil
 }
 
+// getIngressAnnotations returns the annotations requesting the ingresses' certificates from cert-manager and
+// rewriting their paths
+func (lc *lazyClient) getIngressAnnotations(ingresses map[string]functionconfig.Ingress) (map[string]string, error) {
+	annotations := map[string]string{}
+
+	setAnnotation := func(annotationName string, annotationValue string) error {
+		if existingValue, found := annotations[annotationName]; found && existingValue != annotationValue {
+			return errors.Errorf("The function's ingresses must have the same %s (got %s and %s)",
+				annotationName,
+				existingValue,
+				annotationValue)
+		}
+
+		annotations[annotationName] = annotationValue
+
+		return nil
+	}
+
+	for _, ingress := range ingresses {
+		if ingress.TLSIssuer != "" {
+			var issuerAnnotationName string
+
+			switch ingress.TLSIssuerKind {
+			case "", functionconfig.IngressTLSIssuerKindClusterIssuer:
+				issuerAnnotationName = certManagerClusterIssuerAnnotation
+			case functionconfig.IngressTLSIssuerKindIssuer:
+				issuerAnnotationName = certManagerIssuerAnnotation
+			default:
+				return nil, errors.Errorf("Unknown TLS issuer kind %s (expected %s or %s)",
+					ingress.TLSIssuerKind,
+					functionconfig.IngressTLSIssuerKindIssuer,
+					functionconfig.IngressTLSIssuerKindClusterIssuer)
+			}
+
+			if err := setAnnotation(issuerAnnotationName, ingress.TLSIssuer); err != nil {
+				return nil, err
+			}
+		}
+
+		if ingress.RewriteTarget != "" {
+			if err := setAnnotation(nginxRewriteTargetAnnotation, ingress.RewriteTarget); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if annotations[certManagerIssuerAnnotation] != "" && annotations[certManagerClusterIssuerAnnotation] != "" {
+		return nil, errors.New("The function's ingresses must have the same TLS issuer kind")
+	}
+
+	return annotations, nil
+}
+
+// renderIngressTemplate replaces the {function}, {project} and {namespace} placeholders of ingress hosts and
+// TLS secret names
+func (lc *lazyClient) renderIngressTemplate(ingressTemplate string, function *nuclioio.NuclioFunction) string {
+	return strings.NewReplacer(
+		"{function}", function.Name,
+		"{project}", function.Labels["nuclio.io/project-name"],
+		"{namespace}", function.Namespace,
+	).Replace(ingressTemplate)
+}
+
 func (lc *lazyClient) formatIngressPattern(ingressPattern string,
 	functionLabels labels.Set,
 	function *nuclioio.NuclioFunction) (string, error) {
@@ -1643,8 +1935,17 @@ func (lc *lazyClient) addIngressToSpec(ingress *functionconfig.Ingress,
 		"paths", ingress.Paths,
 		"TLS", ingress.TLS)
 
+	ingressesConfiguration := lc.platformConfigurationProvider.GetPlatformConfiguration().Ingresses
+
+	host := ingress.Host
+	if host == "" {
+		host = ingressesConfiguration.HostTemplate
+	}
+
+	host = lc.renderIngressTemplate(host, function)
+
 	ingressRule := ext_v1beta1.IngressRule{
-		Host: ingress.Host,
+		Host: host,
 	}
 
 	ingressRule.IngressRuleValue.HTTP = &ext_v1beta1.HTTPIngressRuleValue{}
@@ -1669,15 +1970,32 @@ func (lc *lazyClient) addIngressToSpec(ingress *functionconfig.Ingress,
 
 		// add path
 		ingressRule.IngressRuleValue.HTTP.Paths = append(ingressRule.IngressRuleValue.HTTP.Paths, httpIngressPath)
+	}
 
-		// add TLS if such exists
-		if ingress.TLS.SecretName != "" {
-			ingressTLS := ext_v1beta1.IngressTLS{}
-			ingressTLS.SecretName = ingress.TLS.SecretName
-			ingressTLS.Hosts = ingress.TLS.Hosts
+	// add TLS if such exists. cert-manager issues certificates into the secret, so ingresses requesting them
+	// get one by default
+	secretName := ingress.TLS.SecretName
+	if secretName == "" && ingress.TLSIssuer != "" {
+		secretName = ingressesConfiguration.GetTLSSecretNameTemplate()
+	}
+
+	if secretName != "" && len(ingress.Paths) > 0 {
+		var tlsHosts []string
+		for _, tlsHost := range ingress.TLS.Hosts {
+
+			// the ingress's host may have been resolved from the platform's template
+			if tlsHost == ingress.Host {
+				tlsHosts = append(tlsHosts, host)
+			} else {
+				tlsHosts = append(tlsHosts, lc.renderIngressTemplate(tlsHost, function))
+			}
+		}
 
-			spec.TLS = append(spec.TLS, ingressTLS)
+		if len(tlsHosts) == 0 {
+			tlsHosts = []string{host}
 		}
+
+		lc.addIngressTLSToSpec(lc.renderIngressTemplate(secretName, function), tlsHosts, spec)
 	}
 
 	spec.Rules = append(spec.Rules, ingressRule)
@@ -1685,13 +2003,67 @@ func (lc *lazyClient) addIngressToSpec(ingress *functionconfig.Ingress,
 	return nil
 }
 
+// addIngressTLSToSpec adds the hosts to the TLS of the secret, so that cert-manager issues a single certificate
+// for each secret
+func (lc *lazyClient) addIngressTLSToSpec(secretName string, hosts []string, spec *ext_v1beta1.IngressSpec) {
+	for ingressTLSIndex := range spec.TLS {
+		ingressTLS := &spec.TLS[ingressTLSIndex]
+		if ingressTLS.SecretName != secretName {
+			continue
+		}
+
+		for _, host := range hosts {
+			if !common.StringSliceContainsString(ingressTLS.Hosts, host) {
+				ingressTLS.Hosts = append(ingressTLS.Hosts, host)
+			}
+		}
+
+		return
+	}
+
+	spec.TLS = append(spec.TLS, ext_v1beta1.IngressTLS{
+		SecretName: secretName,
+		Hosts:      hosts,
+	})
+}
+
+// populateDeploymentContainers populates the processor's container (the first of the given, created if there
+// are none) and replaces the rest with the function's sidecars
+func (lc *lazyClient) populateDeploymentContainers(functionLabels labels.Set,
+	function *nuclioio.NuclioFunction,
+	containers []v1.Container,
+	volumeMounts []v1.VolumeMount) []v1.Container {
+
+	if len(containers) == 0 {
+		containers = []v1.Container{{Name: "nuclio"}}
+	}
+
+	lc.populateDeploymentContainer(functionLabels, function, &containers[0])
+	containers[0].VolumeMounts = volumeMounts
+
+	return append(containers[:1], function.Spec.Sidecars...)
+}
+
 func (lc *lazyClient) populateDeploymentContainer(functionLabels labels.Set,
 	function *nuclioio.NuclioFunction,
 	container *v1.Container) {
 	healthCheckHTTPPort := 8082
 
 	container.Image = function.Spec.Image
-	container.Resources = function.Spec.Resources
+
+	// a copy, so that the GPU resources aren't added to the function's
+	container.Resources = *function.Spec.Resources.DeepCopy()
+	if gpuResources := lc.getGPUResources(function); gpuResources != nil {
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = v1.ResourceList{}
+		}
+
+		// extended resources are requested as much as they're limited
+		for resourceName, quantity := range gpuResources {
+			container.Resources.Limits[resourceName] = quantity
+		}
+	}
+
 	if container.Resources.Requests == nil {
 		container.Resources.Requests = make(v1.ResourceList)
 
@@ -1702,6 +2074,7 @@ func (lc *lazyClient) populateDeploymentContainer(functionLabels labels.Set,
 		}
 	}
 	container.Env = lc.getFunctionEnvironment(functionLabels, function)
+	container.EnvFrom = lc.getFunctionEnvironmentFromSecrets(function)
 	container.Ports = []v1.ContainerPort{
 		{
 			Name:          containerHTTPPortName,
@@ -1719,6 +2092,14 @@ func (lc *lazyClient) populateDeploymentContainer(functionLabels labels.Set,
 		})
 	}
 
+	for _, grpcPort := range function.Spec.GetGRPCPorts() {
+		container.Ports = append(container.Ports, v1.ContainerPort{
+			Name:          getGRPCPortName(grpcPort),
+			ContainerPort: int32(grpcPort),
+			Protocol:      "TCP",
+		})
+	}
+
 	container.ReadinessProbe = &v1.Probe{
 		Handler: v1.Handler{
 			HTTPGet: &v1.HTTPGetAction{
@@ -1743,6 +2124,19 @@ func (lc *lazyClient) populateDeploymentContainer(functionLabels labels.Set,
 		PeriodSeconds:       5,
 	}
 
+	// drain the processor before it's sent SIGTERM - it stops receiving events and handles those in flight, for
+	// up to the pod's termination grace period
+	container.Lifecycle = &v1.Lifecycle{
+		PreStop: &v1.Handler{
+			HTTPGet: &v1.HTTPGetAction{
+				Port: intstr.FromInt(healthCheckHTTPPort),
+				Path: "/drain",
+			},
+		},
+	}
+
+	container.SecurityContext = lc.getContainerSecurityContext(function)
+
 	// always pull is the default since each create / update will trigger a rollingupdate including
 	// pulling the image. this is because the tag of the image doesn't change between revisions of the function
 	if function.Spec.ImagePullPolicy == "" {
@@ -1836,9 +2230,25 @@ func (lc *lazyClient) getFunctionVolumeAndMounts(function *nuclioio.NuclioFuncti
 
 	// merge from functionconfig and injected configuration
 	configVolumes = append(configVolumes, function.Spec.Volumes...)
+	configVolumes = append(configVolumes, lc.getFunctionVolumesFromSecrets(function)...)
 	configVolumes = append(configVolumes, processorConfigVolume)
 	configVolumes = append(configVolumes, platformConfigVolume)
 
+	// the processor and runtimes write their temporary files under /tmp, which must remain writable when the
+	// root filesystem is read-only (unless the function mounts a volume of its own there)
+	if function.Spec.SecurityContext != nil &&
+		function.Spec.SecurityContext.ReadOnlyRootFilesystem &&
+		!lc.functionHasVolumeMountedAt(function, tempVolumeMountPath) {
+
+		tempVolume := functionconfig.Volume{}
+		tempVolume.Volume.Name = tempVolumeName
+		tempVolume.Volume.EmptyDir = &v1.EmptyDirVolumeSource{}
+		tempVolume.VolumeMount.Name = tempVolumeName
+		tempVolume.VolumeMount.MountPath = tempVolumeMountPath
+
+		configVolumes = append(configVolumes, tempVolume)
+	}
+
 	var volumes []v1.Volume
 	var volumeMounts []v1.VolumeMount
 
@@ -1872,6 +2282,82 @@ func (lc *lazyClient) getFunctionVolumeAndMounts(function *nuclioio.NuclioFuncti
 	return volumes, volumeMounts
 }
 
+func (lc *lazyClient) functionHasVolumeMountedAt(function *nuclioio.NuclioFunction, mountPath string) bool {
+	for _, volume := range function.Spec.Volumes {
+		if filepath.Clean(volume.VolumeMount.MountPath) == mountPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getContainerSecurityContext returns the security context of the processor's container, or nil if the function
+// doesn't have one (in which case the container runs with the defaults of the image and cluster)
+func (lc *lazyClient) getContainerSecurityContext(function *nuclioio.NuclioFunction) *v1.SecurityContext {
+	functionSecurityContext := function.Spec.SecurityContext
+	if functionSecurityContext == nil {
+		return nil
+	}
+
+	securityContext := &v1.SecurityContext{
+		RunAsUser:  functionSecurityContext.RunAsUser,
+		RunAsGroup: functionSecurityContext.RunAsGroup,
+	}
+
+	if functionSecurityContext.ReadOnlyRootFilesystem {
+		readOnlyRootFilesystem := true
+		securityContext.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+	}
+
+	if len(functionSecurityContext.DropCapabilities) > 0 {
+		securityContext.Capabilities = &v1.Capabilities{}
+
+		for _, capability := range functionSecurityContext.DropCapabilities {
+			securityContext.Capabilities.Drop = append(securityContext.Capabilities.Drop, v1.Capability(capability))
+		}
+	}
+
+	return securityContext
+}
+
+func (lc *lazyClient) getFunctionEnvironmentFromSecrets(function *nuclioio.NuclioFunction) []v1.EnvFromSource {
+	var envFromSources []v1.EnvFromSource
+
+	for _, envFromSecret := range function.Spec.EnvFromSecrets {
+		envFromSources = append(envFromSources, v1.EnvFromSource{
+			SecretRef: &v1.SecretEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: envFromSecret.Name,
+				},
+			},
+		})
+	}
+
+	return envFromSources
+}
+
+// getFunctionVolumesFromSecrets returns read-only volumes of the secrets the function mounts, named after the
+// secrets so that they don't collide with the function's own volumes
+func (lc *lazyClient) getFunctionVolumesFromSecrets(function *nuclioio.NuclioFunction) []functionconfig.Volume {
+	var secretVolumes []functionconfig.Volume
+
+	for _, volumeFromSecret := range function.Spec.VolumesFromSecrets {
+		secretVolume := functionconfig.Volume{}
+		secretVolume.Volume.Name = "secret-" + volumeFromSecret.Name
+		secretVolume.Volume.Secret = &v1.SecretVolumeSource{
+			SecretName: volumeFromSecret.Name,
+		}
+		secretVolume.VolumeMount.Name = secretVolume.Volume.Name
+		secretVolume.VolumeMount.MountPath = volumeFromSecret.MountPath
+		secretVolume.VolumeMount.ReadOnly = true
+
+		secretVolumes = append(secretVolumes, secretVolume)
+	}
+
+	return secretVolumes
+}
+
 func (lc *lazyClient) deleteFunctionEvents(ctx context.Context, functionName string, namespace string) error {
 
 	// create error group
@@ -29,7 +29,9 @@ import (
 	apps_v1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type mockedPlatformConfigurationProvider struct {
@@ -49,6 +51,9 @@ type lazyTestSuite struct {
 func (suite *lazyTestSuite) SetupTest() {
 	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
 	suite.client.logger = suite.logger
+	suite.client.platformConfigurationProvider = &mockedPlatformConfigurationProvider{
+		platformConfiguration: &platformconfig.Config{},
+	}
 }
 
 func (suite *lazyTestSuite) TestNoTriggers() {
@@ -187,12 +192,109 @@ func (suite *lazyTestSuite) TestTriggerDefinedMultipleIngresses() {
 	suite.Require().Equal("/constant-value-4", rule.HTTP.Paths[1].Path)
 }
 
+func (suite *lazyTestSuite) TestIngressTLSAndRewrite() {
+	suite.client.platformConfigurationProvider = &mockedPlatformConfigurationProvider{
+		platformConfiguration: &platformconfig.Config{
+			Ingresses: platformconfig.Ingresses{
+				HostTemplate: "{function}.{project}.example.com",
+			},
+		},
+	}
+
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+	functionInstance.Namespace = "func-namespace"
+	functionInstance.Labels = map[string]string{"nuclio.io/project-name": "my-project"}
+	functionInstance.Spec.Triggers = map[string]functionconfig.Trigger{
+		"mh": {
+			Kind: "http",
+			Attributes: map[string]interface{}{
+				"ingresses": map[string]interface{}{
+					"public": map[string]interface{}{
+						"paths":         []string{"/api(/|$)(.*)"},
+						"tlsIssuer":     "letsencrypt",
+						"rewriteTarget": "/$2",
+					},
+					"alias": map[string]interface{}{
+						"host":      "{function}.example.org",
+						"paths":     []string{"/"},
+						"tlsIssuer": "letsencrypt",
+					},
+					"internal": map[string]interface{}{
+						"host":       "internal.example.com",
+						"paths":      []string{"/{{.Name}}"},
+						"secretName": "internal-tls",
+					},
+				},
+			},
+		},
+	}
+
+	ingressMeta := meta_v1.ObjectMeta{}
+	ingressSpec := ext_v1beta1.IngressSpec{}
+
+	err := suite.client.populateIngressConfig(map[string]string{}, &functionInstance, &ingressMeta, &ingressSpec)
+	suite.Require().NoError(err)
+
+	// hosts are templated, by default from the platform configuration
+	suite.Require().Len(ingressSpec.Rules, 3)
+	suite.Require().NotNil(suite.getIngressRuleByHost(ingressSpec.Rules, "func-name.my-project.example.com"))
+	suite.Require().NotNil(suite.getIngressRuleByHost(ingressSpec.Rules, "func-name.example.org"))
+	suite.Require().Equal("/func-name",
+		suite.getIngressRuleByHost(ingressSpec.Rules, "internal.example.com").HTTP.Paths[0].Path)
+
+	// cert-manager issues a certificate for the hosts requesting one, into the default secret
+	suite.Require().Equal("letsencrypt", ingressMeta.Annotations["cert-manager.io/cluster-issuer"])
+	suite.Require().Equal("/$2", ingressMeta.Annotations["nginx.ingress.kubernetes.io/rewrite-target"])
+	suite.Require().Len(ingressSpec.TLS, 2)
+
+	for _, ingressTLS := range ingressSpec.TLS {
+		switch ingressTLS.SecretName {
+		case "func-name-tls":
+			suite.Require().ElementsMatch([]string{
+				"func-name.my-project.example.com",
+				"func-name.example.org",
+			}, ingressTLS.Hosts)
+		case "internal-tls":
+			suite.Require().Equal([]string{"internal.example.com"}, ingressTLS.Hosts)
+		default:
+			suite.Failf("Unexpected TLS secret", "Secret %s", ingressTLS.SecretName)
+		}
+	}
+
+	// the ingresses share the annotations, so they can't conflict
+	functionInstance.Spec.Triggers["mh"].Attributes["ingresses"].(map[string]interface{})["alias"] =
+		map[string]interface{}{
+			"paths":         []string{"/"},
+			"rewriteTarget": "/other",
+		}
+
+	err = suite.client.populateIngressConfig(map[string]string{},
+		&functionInstance,
+		&meta_v1.ObjectMeta{},
+		&ext_v1beta1.IngressSpec{})
+	suite.Require().Error(err)
+}
+
 func (suite *lazyTestSuite) TestPlatformServicePorts() {
 
-	// configuration with no ports
-	servicePorts := suite.client.getServicePortsFromPlatform(&platformconfig.Config{})
+	falseValue := false
+
+	// configuration with scraping disabled - no ports
+	servicePorts := suite.client.getServicePortsFromPlatform(&platformconfig.Config{
+		Metrics: platformconfig.Metrics{
+			Scrape: platformconfig.MetricsScrape{
+				Enabled: &falseValue,
+			},
+		},
+	})
 	suite.Require().Len(servicePorts, 0)
 
+	// empty configuration - functions are scraped by default
+	servicePorts = suite.client.getServicePortsFromPlatform(&platformconfig.Config{})
+	suite.Require().Len(servicePorts, 1)
+	suite.Require().Equal(containerMetricPortName, servicePorts[0].Name)
+
 	// configuration with prometheus pull
 	servicePorts = suite.client.getServicePortsFromPlatform(&platformconfig.Config{
 		Metrics: platformconfig.Metrics{
@@ -242,6 +344,43 @@ func (suite *lazyTestSuite) TestPlatformServicePorts() {
 	suite.Require().Len(toServicePorts, 2)
 }
 
+func (suite *lazyTestSuite) TestGRPCTriggerPorts() {
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+	functionInstance.Spec.ServiceType = v1.ServiceTypeNodePort
+	functionInstance.Spec.Triggers = map[string]functionconfig.Trigger{
+		"grpc":        {Kind: "grpc"},
+		"grpc-custom": {Kind: "grpc", URL: ":9000"},
+	}
+
+	container := v1.Container{}
+	suite.client.populateDeploymentContainer(nil, &functionInstance, &container)
+
+	var containerPortNames []string
+	for _, containerPort := range container.Ports {
+		containerPortNames = append(containerPortNames, containerPort.Name)
+	}
+	suite.Require().Equal([]string{containerHTTPPortName, containerMetricPortName, "grpc-9000", "grpc-50051"}, containerPortNames)
+
+	// a service whose gRPC port was assigned a node port, and which exposes a port of a removed trigger
+	serviceSpec := v1.ServiceSpec{
+		Ports: []v1.ServicePort{
+			{Name: containerHTTPPortName, Port: int32(containerHTTPPort), NodePort: 30000},
+			{Name: "grpc-50051", Port: 50051, NodePort: 30001},
+			{Name: "grpc-7000", Port: 7000, NodePort: 30002},
+		},
+	}
+
+	suite.client.populateServiceSpec(nil, &functionInstance, &serviceSpec)
+
+	suite.Require().Equal([]v1.ServicePort{
+		{Name: containerHTTPPortName, Port: int32(containerHTTPPort), NodePort: 30000},
+		{Name: containerMetricPortName, Port: int32(containerMetricPort)},
+		{Name: "grpc-9000", Port: 9000},
+		{Name: "grpc-50051", Port: 50051, NodePort: 30001},
+	}, serviceSpec.Ports)
+}
+
 func (suite *lazyTestSuite) TestEnrichDeploymentFromPlatformConfiguration() {
 	suite.client.SetPlatformConfigurationProvider(&mockedPlatformConfigurationProvider{
 		platformConfiguration: &platformconfig.Config{
@@ -324,6 +463,336 @@ func (suite *lazyTestSuite) TestEnrichDeploymentFromPlatformConfiguration() {
 	suite.Require().NoError(err)
 }
 
+func (suite *lazyTestSuite) TestSecretsFromFunctionSpec() {
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+	functionInstance.Spec.EnvFromSecrets = []functionconfig.EnvFromSecret{{Name: "credentials"}}
+	functionInstance.Spec.VolumesFromSecrets = []functionconfig.VolumeFromSecret{
+		{Name: "certificates", MountPath: "/etc/certs"},
+	}
+
+	envFromSources := suite.client.getFunctionEnvironmentFromSecrets(&functionInstance)
+	suite.Require().Len(envFromSources, 1)
+	suite.Require().Equal("credentials", envFromSources[0].SecretRef.Name)
+
+	volumes, volumeMounts := suite.client.getFunctionVolumeAndMounts(&functionInstance)
+
+	// the secret volume precedes the processor and platform configuration volumes
+	suite.Require().Len(volumes, 3)
+	suite.Require().Equal("secret-certificates", volumes[0].Name)
+	suite.Require().Equal("certificates", volumes[0].Secret.SecretName)
+	suite.Require().Equal(v1.VolumeMount{
+		Name:      "secret-certificates",
+		MountPath: "/etc/certs",
+		ReadOnly:  true,
+	}, volumeMounts[0])
+}
+
+func (suite *lazyTestSuite) TestFunctionReferencesEnvironment() {
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "caller"
+	functionInstance.Namespace = "nuclio"
+	functionInstance.Labels = map[string]string{"nuclio.io/project-name": "shop"}
+	functionInstance.Spec.FunctionReferences = map[string]string{
+		"mailer":  "mailer",
+		"billing": "payments/billing",
+	}
+
+	env := suite.client.getFunctionEnvironment(functionInstance.Labels, &functionInstance)
+
+	// the references follow the platform's env, ordered by alias
+	suite.Require().Equal([]v1.EnvVar{
+		{Name: "NUCLIO_FUNCTION_URL_BILLING", Value: "http://nuclio-billing.nuclio.svc:8080"},
+		{Name: "NUCLIO_FUNCTION_URL_MAILER", Value: "http://nuclio-mailer.nuclio.svc:8080"},
+	}, env[len(env)-2:])
+}
+
+func (suite *lazyTestSuite) TestDeploymentContainersSidecars() {
+	suite.client.platformConfigurationProvider = &mockedPlatformConfigurationProvider{
+		platformConfiguration: &platformconfig.Config{},
+	}
+
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+	functionInstance.Spec.Image = "my-registry/func-name:latest"
+	functionInstance.Spec.Sidecars = []v1.Container{
+		{Name: "envoy", Image: "envoyproxy/envoy:v1.16.0"},
+	}
+
+	volumeMounts := []v1.VolumeMount{{Name: "processor-config-volume", MountPath: "/etc/nuclio/config/processor"}}
+
+	// on creation, the processor's container precedes the sidecars
+	containers := suite.client.populateDeploymentContainers(nil, &functionInstance, nil, volumeMounts)
+	suite.Require().Len(containers, 2)
+	suite.Require().Equal("nuclio", containers[0].Name)
+	suite.Require().Equal("my-registry/func-name:latest", containers[0].Image)
+	suite.Require().Equal(volumeMounts, containers[0].VolumeMounts)
+	suite.Require().Equal(functionInstance.Spec.Sidecars[0], containers[1])
+
+	// the processor is drained before it's terminated
+	suite.Require().Equal("/drain", containers[0].Lifecycle.PreStop.HTTPGet.Path)
+	suite.Require().Equal(intstr.FromInt(8082), containers[0].Lifecycle.PreStop.HTTPGet.Port)
+
+	// on update, the sidecars are replaced with the function's
+	functionInstance.Spec.Sidecars = []v1.Container{
+		{Name: "agent", Image: "my-registry/agent:1.0.0"},
+		{Name: "envoy", Image: "envoyproxy/envoy:v1.17.0"},
+	}
+
+	containers = suite.client.populateDeploymentContainers(nil, &functionInstance, containers, volumeMounts)
+	suite.Require().Len(containers, 3)
+	suite.Require().Equal("nuclio", containers[0].Name)
+	suite.Require().Equal(functionInstance.Spec.Sidecars, containers[1:])
+
+	// and removed once the function has none
+	functionInstance.Spec.Sidecars = nil
+
+	containers = suite.client.populateDeploymentContainers(nil, &functionInstance, containers, volumeMounts)
+	suite.Require().Len(containers, 1)
+	suite.Require().Equal("nuclio", containers[0].Name)
+}
+
+func (suite *lazyTestSuite) TestSecurityContext() {
+	suite.client.platformConfigurationProvider = &mockedPlatformConfigurationProvider{
+		platformConfiguration: &platformconfig.Config{},
+	}
+
+	runAsUser := int64(1000)
+
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+	functionInstance.Spec.SecurityContext = &functionconfig.SecurityContext{
+		RunAsUser:              &runAsUser,
+		ReadOnlyRootFilesystem: true,
+		DropCapabilities:       []string{"ALL"},
+		SeccompProfile:         functionconfig.SeccompProfileRuntimeDefault,
+	}
+
+	containers := suite.client.populateDeploymentContainers(nil, &functionInstance, nil, nil)
+	securityContext := containers[0].SecurityContext
+	suite.Require().NotNil(securityContext)
+	suite.Require().Equal(runAsUser, *securityContext.RunAsUser)
+	suite.Require().Nil(securityContext.RunAsGroup)
+	suite.Require().True(*securityContext.ReadOnlyRootFilesystem)
+	suite.Require().Equal([]v1.Capability{"ALL"}, securityContext.Capabilities.Drop)
+
+	// the seccomp profile is set by annotation
+	podAnnotations, err := suite.client.getPodAnnotations(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal(functionconfig.SeccompProfileRuntimeDefault,
+		podAnnotations["container.seccomp.security.alpha.kubernetes.io/nuclio"])
+
+	// /tmp remains writable
+	volumes, volumeMounts := suite.client.getFunctionVolumeAndMounts(&functionInstance)
+	suite.Require().Equal("tmp", volumes[len(volumes)-1].Name)
+	suite.Require().NotNil(volumes[len(volumes)-1].EmptyDir)
+	suite.Require().Equal("/tmp", volumeMounts[len(volumeMounts)-1].MountPath)
+
+	// and the context is removed once the function has none
+	functionInstance.Spec.SecurityContext = nil
+
+	containers = suite.client.populateDeploymentContainers(nil, &functionInstance, containers, nil)
+	suite.Require().Nil(containers[0].SecurityContext)
+
+	volumes, _ = suite.client.getFunctionVolumeAndMounts(&functionInstance)
+	for _, volume := range volumes {
+		suite.Require().NotEqual("tmp", volume.Name)
+	}
+}
+
+func (suite *lazyTestSuite) TestGPUScheduling() {
+	gpuTaintToleration := v1.Toleration{
+		Key:      "nvidia.com/gpu",
+		Operator: v1.TolerationOpExists,
+		Effect:   v1.TaintEffectNoSchedule,
+	}
+
+	suite.client.platformConfigurationProvider = &mockedPlatformConfigurationProvider{
+		platformConfiguration: &platformconfig.Config{
+			SchedulingPresets: map[string]platformconfig.SchedulingPreset{
+				"ml": {
+					NodeSelector: map[string]string{"pool": "ml"},
+					Tolerations:  []v1.Toleration{{Key: "dedicated", Value: "ml", Effect: v1.TaintEffectNoSchedule}},
+				},
+			},
+			GPUSharing: platformconfig.GPUSharing{Replicas: 10},
+		},
+	}
+
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "inference"
+	functionInstance.Spec.Resources.Limits = v1.ResourceList{"memory": resource.MustParse("1Gi")}
+	functionInstance.Spec.GPU = &functionconfig.GPU{Count: 2}
+	functionInstance.Spec.SchedulingPreset = "ml"
+
+	// whole GPUs are requested as such, on the preset's nodes
+	containers := suite.client.populateDeploymentContainers(nil, &functionInstance, nil, nil)
+	gpuLimit := containers[0].Resources.Limits["nvidia.com/gpu"]
+	memoryLimit := containers[0].Resources.Limits["memory"]
+	suite.Require().Equal("2", gpuLimit.String())
+	suite.Require().Equal("1Gi", memoryLimit.String())
+
+	// without changing the function's resources
+	suite.Require().Len(functionInstance.Spec.Resources.Limits, 1)
+
+	nodeSelector, tolerations, err := suite.client.getPodScheduling(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{"pool": "ml"}, nodeSelector)
+	suite.Require().Equal([]v1.Toleration{
+		{Key: "dedicated", Value: "ml", Effect: v1.TaintEffectNoSchedule},
+		gpuTaintToleration,
+	}, tolerations)
+	suite.Require().Equal(apps_v1.RecreateDeploymentStrategyType, suite.client.resolveDeploymentStrategy(&functionInstance))
+
+	// the function's own node selector is on top of the preset's
+	functionInstance.Spec.NodeSelector = map[string]string{"pool": "ml-large", "zone": "a"}
+
+	nodeSelector, _, err = suite.client.getPodScheduling(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{"pool": "ml-large", "zone": "a"}, nodeSelector)
+
+	functionInstance.Spec.NodeSelector = nil
+
+	// a fraction of a GPU is requested in shares, on nodes sharing their GPUs the requested way
+	functionInstance.Spec.GPU = &functionconfig.GPU{Count: 0.3, Sharing: functionconfig.GPUSharingMPS}
+	functionInstance.Spec.SchedulingPreset = ""
+
+	containers = suite.client.populateDeploymentContainers(nil, &functionInstance, nil, nil)
+	sharedGPULimit := containers[0].Resources.Limits["nvidia.com/gpu.shared"]
+	suite.Require().Equal("3", sharedGPULimit.String())
+	suite.Require().NotContains(containers[0].Resources.Limits, v1.ResourceName("nvidia.com/gpu"))
+
+	nodeSelector, tolerations, err = suite.client.getPodScheduling(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{"nvidia.com/gpu.sharing-strategy": "mps"}, nodeSelector)
+	suite.Require().Equal([]v1.Toleration{gpuTaintToleration}, tolerations)
+
+	podAnnotations, err := suite.client.getPodAnnotations(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal("mps", podAnnotations["nuclio.io/gpu-sharing"])
+	suite.Require().Equal("0.3", podAnnotations["nuclio.io/gpu-fraction"])
+
+	// presets must be in the platform configuration
+	functionInstance.Spec.SchedulingPreset = "unknown"
+	_, _, err = suite.client.getPodScheduling(&functionInstance)
+	suite.Require().Error(err)
+
+	// functions without GPUs or a preset aren't constrained
+	functionInstance.Spec.GPU = nil
+	functionInstance.Spec.SchedulingPreset = ""
+
+	nodeSelector, tolerations, err = suite.client.getPodScheduling(&functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Nil(nodeSelector)
+	suite.Require().Nil(tolerations)
+}
+
+func (suite *lazyTestSuite) TestConsumerLagAutoScale() {
+	minReplicas := 0
+	maxReplicas := 8
+
+	functionInstance := nuclioio.NuclioFunction{}
+	functionInstance.Name = "orders"
+	functionInstance.Namespace = "nuclio"
+	functionInstance.Spec.MinReplicas = &minReplicas
+	functionInstance.Spec.MaxReplicas = &maxReplicas
+	functionInstance.Spec.AutoScale = &functionconfig.AutoScale{LagThreshold: 100}
+	functionInstance.Spec.Triggers = map[string]functionconfig.Trigger{
+		"http": {Kind: "http"},
+		"orders": {
+			Kind: "kafka-cluster",
+			URL:  "kafka:9092",
+			Attributes: map[string]interface{}{
+				"topics":        []string{"orders", "returns"},
+				"consumerGroup": "fulfillment",
+			},
+		},
+		"audit": {
+			Kind: "kafka-cluster",
+			Attributes: map[string]interface{}{
+				"brokers":       []string{"audit-0:9092", "audit-1:9092"},
+				"topics":        []string{"audit"},
+				"consumerGroup": "auditors",
+			},
+		},
+	}
+
+	// in nuclio mode, the HPA targets the lag each replica reports
+	metricSpecs := suite.client.getConsumerLagMetricSpecs(&functionInstance)
+	suite.Require().Len(metricSpecs, 1)
+	suite.Require().Equal("nuclio_processor_consumer_lag", metricSpecs[0].Pods.MetricName)
+	suite.Require().Equal("100", metricSpecs[0].Pods.TargetAverageValue.String())
+	suite.Require().False(isScaledByKEDA(&functionInstance))
+
+	// in keda mode, a scaled object scales the function on the lag of each topic's consumer group
+	functionInstance.Spec.AutoScale = &functionconfig.AutoScale{
+		LagThreshold:   100,
+		Mode:           functionconfig.AutoScaleModeKEDA,
+		CooldownPeriod: "1m",
+	}
+	suite.Require().True(isScaledByKEDA(&functionInstance))
+
+	functionLabels := map[string]string{"nuclio.io/function-name": "orders"}
+
+	scaledObject, err := suite.client.generateScaledObject(functionLabels, &functionInstance)
+	suite.Require().NoError(err)
+	suite.Require().Equal("keda.sh/v1alpha1", scaledObject.APIVersion)
+	suite.Require().Equal("ScaledObject", scaledObject.Kind)
+	suite.Require().Equal("nuclio-orders", scaledObject.Name)
+	suite.Require().Equal("nuclio", scaledObject.Namespace)
+	suite.Require().Equal(functionLabels, scaledObject.Labels)
+	suite.Require().Equal(scaledObjectSpec{
+		ScaleTargetRef:  scaledObjectTargetRef{Name: "nuclio-orders"},
+		MinReplicaCount: 0,
+		MaxReplicaCount: 8,
+		PollingInterval: 30,
+		CooldownPeriod:  60,
+		Triggers: []scaledObjectTrigger{
+			{
+				Type: "kafka",
+				Metadata: map[string]string{
+					"bootstrapServers": "audit-0:9092,audit-1:9092",
+					"consumerGroup":    "auditors",
+					"topic":            "audit",
+					"lagThreshold":     "100",
+				},
+			},
+			{
+				Type: "kafka",
+				Metadata: map[string]string{
+					"bootstrapServers": "kafka:9092",
+					"consumerGroup":    "fulfillment",
+					"topic":            "orders",
+					"lagThreshold":     "100",
+				},
+			},
+			{
+				Type: "kafka",
+				Metadata: map[string]string{
+					"bootstrapServers": "kafka:9092",
+					"consumerGroup":    "fulfillment",
+					"topic":            "returns",
+					"lagThreshold":     "100",
+				},
+			},
+		},
+	}, scaledObject.Spec)
+
+	// KEDA needs the consumer group to read its lag
+	functionInstance.Spec.Triggers = map[string]functionconfig.Trigger{
+		"orders": {
+			Kind: "kafka-cluster",
+			URL:  "kafka:9092",
+			Attributes: map[string]interface{}{
+				"topics": []string{"orders"},
+			},
+		},
+	}
+
+	_, err = suite.client.generateScaledObject(functionLabels, &functionInstance)
+	suite.Require().Error(err)
+}
+
 func (suite *lazyTestSuite) getIngressRuleByHost(rules []ext_v1beta1.IngressRule, host string) *ext_v1beta1.IngressRule {
 	for _, rule := range rules {
 		if rule.Host == host {
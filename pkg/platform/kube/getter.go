@@ -44,8 +44,14 @@ func (g *getter) get(consumer *consumer, getFunctionsOptions *platform.GetFuncti
 	var platformFunctions []platform.Function
 	var functions []nuclioio.NuclioFunction
 
-	// if identifier specified, we need to get a single function
-	if getFunctionsOptions.Name != "" {
+	namespace := getFunctionsOptions.Namespace
+	if getFunctionsOptions.AllNamespaces {
+		namespace = meta_v1.NamespaceAll
+	}
+
+	// if identifier specified, we need to get a single function (functions of the same name in all
+	// namespaces are listed by name)
+	if getFunctionsOptions.Name != "" && !getFunctionsOptions.AllNamespaces {
 
 		// get specific function CR
 		function, err := consumer.nuclioClientSet.NuclioV1beta1().NuclioFunctions(getFunctionsOptions.Namespace).Get(getFunctionsOptions.Name, meta_v1.GetOptions{})
@@ -63,7 +69,12 @@ func (g *getter) get(consumer *consumer, getFunctionsOptions *platform.GetFuncti
 
 	} else {
 
-		functionInstanceList, err := consumer.nuclioClientSet.NuclioV1beta1().NuclioFunctions(getFunctionsOptions.Namespace).List(meta_v1.ListOptions{LabelSelector: getFunctionsOptions.Labels})
+		listOptions := meta_v1.ListOptions{LabelSelector: getFunctionsOptions.Labels}
+		if getFunctionsOptions.Name != "" {
+			listOptions.FieldSelector = "metadata.name=" + getFunctionsOptions.Name
+		}
+
+		functionInstanceList, err := consumer.nuclioClientSet.NuclioV1beta1().NuclioFunctions(namespace).List(listOptions)
 
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to list functions")
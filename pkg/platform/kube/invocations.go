@@ -0,0 +1,230 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// the key of the invocations in the function's invocations configmap
+const functionInvocationsConfigMapKey = "invocations.json"
+
+// GetFunctionInvocations gathers the invocations the invocation inspectors of the function's running pods
+// retain, through the API server's pod proxy, adds them to those kept in its invocations configmap and returns
+// them. the invocations a pod retained are lost if it restarts before they're gathered
+func (p *Platform) GetFunctionInvocations(getFunctionInvocationsOptions *platform.GetFunctionInvocationsOptions) (
+	[]platform.FunctionInvocation, error) {
+	namespace := getFunctionInvocationsOptions.Namespace
+	name := getFunctionInvocationsOptions.Name
+
+	function, err := p.getFunction(namespace, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	if function == nil {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s", name, namespace))
+	}
+
+	maxInvocations := functionconfig.DefaultInvocationInspectorMaxInvocations
+	if function.Spec.InvocationInspector != nil {
+		maxInvocations = function.Spec.InvocationInspector.GetMaxInvocations()
+	}
+
+	replicaInvocations, err := p.getFunctionReplicaInvocations(namespace, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function replica invocations")
+	}
+
+	var invocations []platform.FunctionInvocation
+
+	// invocations may be gathered concurrently (e.g. by several dashboard users), so an update that lost the
+	// race is retried on the invocations that won it
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return updateFunctionInvocations(p.consumer.kubeClientSet,
+			namespace,
+			name,
+			func(functionInvocations *platform.FunctionInvocations) {
+				functionInvocations.Add(replicaInvocations, maxInvocations)
+				invocations = functionInvocations.Invocations
+			})
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to update function invocations")
+	}
+
+	return platform.FilterFunctionInvocations(invocations,
+		getFunctionInvocationsOptions.Since,
+		getFunctionInvocationsOptions.FailedOnly), nil
+}
+
+// DeleteFunctionInvocations purges the invocations kept in the function's invocations configmap. those the
+// pods still retain aren't gathered again
+func (p *Platform) DeleteFunctionInvocations(deleteFunctionInvocationsOptions *platform.DeleteFunctionInvocationsOptions) error {
+	namespace := deleteFunctionInvocationsOptions.Namespace
+	name := deleteFunctionInvocationsOptions.Name
+
+	function, err := p.getFunction(namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	if function == nil {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s", name, namespace))
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return updateFunctionInvocations(p.consumer.kubeClientSet,
+			namespace,
+			name,
+			func(functionInvocations *platform.FunctionInvocations) {
+				functionInvocations.Purge()
+			})
+	}); err != nil {
+		return errors.Wrap(err, "Failed to purge function invocations")
+	}
+
+	return nil
+}
+
+// getFunctionReplicaInvocations returns the invocations the invocation inspectors of the function's running
+// pods retain. pods whose processors can't be reached are skipped
+func (p *Platform) getFunctionReplicaInvocations(namespace string,
+	name string) ([]platform.FunctionInvocation, error) {
+
+	pods, err := p.consumer.kubeClientSet.CoreV1().
+		Pods(namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", name),
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list function pods")
+	}
+
+	var invocations []platform.FunctionInvocation
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+
+		encodedInvocations, err := p.getProcessorWebAdminResource(&pod, platform.ProcessorInvocationsPath)
+		if err != nil {
+			p.Logger.DebugWith("Failed to get processor invocations",
+				"pod", pod.Name,
+				"err", errors.RootCause(err).Error())
+			continue
+		}
+
+		podInvocations, err := platform.ParseFunctionReplicaInvocations(pod.Name, encodedInvocations)
+		if err != nil {
+			p.Logger.DebugWith("Failed to parse processor invocations", "pod", pod.Name, "err", err.Error())
+			continue
+		}
+
+		invocations = append(invocations, podInvocations...)
+	}
+
+	return invocations, nil
+}
+
+// updateFunctionInvocations has updater change the invocations kept in the function's invocations configmap,
+// creating it if needed
+func updateFunctionInvocations(kubeClientSet kubernetes.Interface,
+	namespace string,
+	name string,
+	updater func(*platform.FunctionInvocations)) error {
+	functionInvocations, configMap, err := getFunctionInvocations(kubeClientSet, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function invocations")
+	}
+
+	updater(functionInvocations)
+
+	encodedInvocations, err := json.Marshal(functionInvocations)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode function invocations")
+	}
+
+	if configMap == nil {
+		configMapName := InvocationsConfigMapNameFromFunctionName(name)
+
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Create(&v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"nuclio.io/function-name": name,
+				},
+			},
+			Data: map[string]string{
+				functionInvocationsConfigMapKey: string(encodedInvocations),
+			},
+		})
+
+		// the invocations were gathered concurrently, creating the configmap - retry updating it
+		if apierrors.IsAlreadyExists(err) {
+			return apierrors.NewConflict(v1.Resource("configmaps"), configMapName, err)
+		}
+	} else {
+		configMap.Data = map[string]string{
+			functionInvocationsConfigMapKey: string(encodedInvocations),
+		}
+
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Update(configMap)
+	}
+
+	return err
+}
+
+// getFunctionInvocations returns the invocations kept in the function's invocations configmap, along with the
+// configmap (nil if the function's invocations were never gathered)
+func getFunctionInvocations(kubeClientSet kubernetes.Interface,
+	namespace string,
+	name string) (*platform.FunctionInvocations, *v1.ConfigMap, error) {
+
+	functionInvocations := &platform.FunctionInvocations{}
+
+	configMap, err := kubeClientSet.CoreV1().
+		ConfigMaps(namespace).
+		Get(InvocationsConfigMapNameFromFunctionName(name), meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return functionInvocations, nil, nil
+		}
+
+		return nil, nil, errors.Wrap(err, "Failed to get function invocations configmap")
+	}
+
+	if encodedInvocations := configMap.Data[functionInvocationsConfigMapKey]; encodedInvocations != "" {
+		if err := json.Unmarshal([]byte(encodedInvocations), functionInvocations); err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to decode function invocations")
+		}
+	}
+
+	return functionInvocations, configMap, nil
+}
@@ -19,11 +19,14 @@ package kube
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
@@ -36,6 +39,7 @@ import (
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
+	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -55,6 +59,7 @@ const Mib = 1048576
 // NewPlatform instantiates a new kubernetes platform
 func NewPlatform(parentLogger logger.Logger,
 	kubeconfigPath string,
+	kubeContext string,
 	containerBuilderConfiguration *containerimagebuilderpusher.ContainerBuilderConfiguration,
 	platformConfiguration interface{}) (*Platform, error) {
 	newPlatform := &Platform{}
@@ -70,7 +75,7 @@ func NewPlatform(parentLogger logger.Logger,
 	newPlatform.kubeconfigPath = kubeconfigPath
 
 	// create consumer
-	newPlatform.consumer, err = newConsumer(newPlatform.Logger, kubeconfigPath)
+	newPlatform.consumer, err = newConsumer(newPlatform.Logger, kubeconfigPath, kubeContext)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create consumer")
 	}
@@ -125,6 +130,10 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 	var existingFunctionInstance *nuclioio.NuclioFunction
 	var existingFunctionConfig *functionconfig.ConfigWithStatus
 
+	// the configuration the function had before, to audit the changes of this deploy
+	previousFunctionConfig := p.getAuditedFunctionConfig(createFunctionOptions.FunctionConfig.Meta.Namespace,
+		createFunctionOptions.FunctionConfig.Meta.Name)
+
 	// wrap logger
 	logStream, err := abstract.NewLogStream("deployer", nucliozap.InfoLevel, createFunctionOptions.Logger)
 	if err != nil {
@@ -277,8 +286,18 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 				createFunctionOptions,
 				&functionconfig.Status{
 					State: functionconfig.FunctionStateImported,
+					Image: createFunctionOptions.ImageStatus,
 				})
 
+			if err == nil {
+				p.RecordAuditOperation(platform.AuditOperationImport,
+					createFunctionOptions.Identity,
+					createFunctionOptions.FunctionConfig.Meta.Namespace,
+					createFunctionOptions.FunctionConfig.Meta.Name,
+					previousFunctionConfig,
+					&createFunctionOptions.FunctionConfig)
+			}
+
 			return &platform.CreateFunctionResult{
 				CreateFunctionBuildResult: platform.CreateFunctionBuildResult{
 					Image:                 createFunctionOptions.FunctionConfig.Spec.Image,
@@ -309,6 +328,18 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 			return nil, deployErr
 		}
 
+		// the function is deployed at this point, so failing to record it isn't fatal
+		if err := recordFunctionRevision(p.consumer.kubeClientSet, &createFunctionOptions.FunctionConfig); err != nil {
+			createFunctionOptions.Logger.WarnWith("Failed to record function revision", "err", err.Error())
+		}
+
+		p.RecordAuditOperation(platform.AuditOperationDeploy,
+			createFunctionOptions.Identity,
+			createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name,
+			previousFunctionConfig,
+			&createFunctionOptions.FunctionConfig)
+
 		return createFunctionResult, nil
 	}
 
@@ -330,12 +361,365 @@ func (p *Platform) GetFunctions(getFunctionsOptions *platform.GetFunctionsOption
 
 // UpdateFunction will update a previously deployed function
 func (p *Platform) UpdateFunction(updateFunctionOptions *platform.UpdateFunctionOptions) error {
-	return p.updater.update(updateFunctionOptions)
+	namespace := updateFunctionOptions.FunctionMeta.Namespace
+	name := updateFunctionOptions.FunctionMeta.Name
+	previousFunctionConfig := p.getAuditedFunctionConfig(namespace, name)
+
+	if err := p.updater.update(updateFunctionOptions); err != nil {
+		return err
+	}
+
+	p.RecordAuditOperation(platform.AuditOperationUpdate,
+		updateFunctionOptions.Identity,
+		namespace,
+		name,
+		previousFunctionConfig,
+		p.getAuditedFunctionConfig(namespace, name))
+
+	return nil
 }
 
 // DeleteFunction will delete a previously deployed function
 func (p *Platform) DeleteFunction(deleteFunctionOptions *platform.DeleteFunctionOptions) error {
-	return p.deleter.delete(p.consumer, deleteFunctionOptions)
+	if err := p.deleter.delete(p.consumer, deleteFunctionOptions); err != nil {
+		return err
+	}
+
+	p.RecordAuditOperation(platform.AuditOperationDelete,
+		deleteFunctionOptions.Identity,
+		deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+		deleteFunctionOptions.FunctionConfig.Meta.Name,
+		nil,
+		nil)
+
+	return nil
+}
+
+// GetFunctionLogs streams the logs of one of the function's pods
+func (p *Platform) GetFunctionLogs(getFunctionLogsOptions *platform.GetFunctionLogsOptions) (io.ReadCloser, error) {
+	podName, err := p.getFunctionPodName(getFunctionLogsOptions.Namespace,
+		getFunctionLogsOptions.Name,
+		getFunctionLogsOptions.Replica)
+	if err != nil {
+		return nil, err
+	}
+
+	podLogOptions := &v1.PodLogOptions{
+		Container: "nuclio",
+		Follow:    getFunctionLogsOptions.Follow,
+	}
+
+	if getFunctionLogsOptions.Since > 0 {
+		sinceSeconds := int64(getFunctionLogsOptions.Since.Seconds())
+		podLogOptions.SinceSeconds = &sinceSeconds
+	}
+
+	logStream, err := p.consumer.kubeClientSet.CoreV1().
+		Pods(getFunctionLogsOptions.Namespace).
+		GetLogs(podName, podLogOptions).
+		Stream()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to stream pod logs")
+	}
+
+	return logStream, nil
+}
+
+// GetFunctionExecCommand returns a kubectl exec of the command in the function container of one of the
+// function's pods. kubectl must be installed where the command runs
+func (p *Platform) GetFunctionExecCommand(getFunctionExecCommandOptions *platform.GetFunctionExecCommandOptions) (
+	[]string, error) {
+	podName, err := p.getFunctionPodName(getFunctionExecCommandOptions.Namespace,
+		getFunctionExecCommandOptions.Name,
+		getFunctionExecCommandOptions.Replica)
+	if err != nil {
+		return nil, err
+	}
+
+	execCommand := []string{"kubectl"}
+
+	if p.kubeconfigPath != "" {
+		execCommand = append(execCommand, "--kubeconfig", p.kubeconfigPath)
+	}
+
+	if p.consumer.kubeContext != "" {
+		execCommand = append(execCommand, "--context", p.consumer.kubeContext)
+	}
+
+	execCommand = append(execCommand, "exec", "--stdin")
+
+	if getFunctionExecCommandOptions.TTY {
+		execCommand = append(execCommand, "--tty")
+	}
+
+	execCommand = append(execCommand,
+		"--namespace", getFunctionExecCommandOptions.Namespace,
+		podName,
+		"--container", "nuclio",
+		"--")
+
+	return append(execCommand, getFunctionExecCommandOptions.Command...), nil
+}
+
+// CreateFunctionTunnel runs a kubectl port forward from a free local port to the function's service, for
+// functions whose service isn't reachable from outside the cluster. kubectl must be installed where this runs
+func (p *Platform) CreateFunctionTunnel(createFunctionTunnelOptions *platform.CreateFunctionTunnelOptions) (
+	platform.FunctionTunnel, error) {
+	if _, err := p.consumer.nuclioClientSet.NuclioV1beta1().
+		NuclioFunctions(createFunctionTunnelOptions.Namespace).
+		Get(createFunctionTunnelOptions.Name, meta_v1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+				createFunctionTunnelOptions.Name,
+				createFunctionTunnelOptions.Namespace))
+		}
+
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	portForwardCommand := []string{"kubectl"}
+
+	if p.kubeconfigPath != "" {
+		portForwardCommand = append(portForwardCommand, "--kubeconfig", p.kubeconfigPath)
+	}
+
+	if p.consumer.kubeContext != "" {
+		portForwardCommand = append(portForwardCommand, "--context", p.consumer.kubeContext)
+	}
+
+	// an empty local port has kubectl pick a free one, which it then reports
+	portForwardCommand = append(portForwardCommand,
+		"port-forward",
+		"--namespace", createFunctionTunnelOptions.Namespace,
+		"--address", "127.0.0.1",
+		fmt.Sprintf("service/%s", ServiceNameFromFunctionName(createFunctionTunnelOptions.Name)),
+		":8080")
+
+	tunnel, err := newPortForwardTunnel(p.Logger, portForwardCommand, portForwardReadyTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to port forward to function")
+	}
+
+	return tunnel, nil
+}
+
+// PauseFunction disables the function, which scales its deployment to zero and suspends its cron jobs
+func (p *Platform) PauseFunction(pauseFunctionOptions *platform.PauseFunctionOptions) error {
+	return p.setFunctionDisabled(pauseFunctionOptions.Namespace,
+		pauseFunctionOptions.Name,
+		pauseFunctionOptions.AuthConfig,
+		true)
+}
+
+// ResumeFunction enables a paused function, which scales its deployment back up
+func (p *Platform) ResumeFunction(resumeFunctionOptions *platform.ResumeFunctionOptions) error {
+	return p.setFunctionDisabled(resumeFunctionOptions.Namespace,
+		resumeFunctionOptions.Name,
+		resumeFunctionOptions.AuthConfig,
+		false)
+}
+
+// setFunctionDisabled has the controller reconfigure the function's resources with it disabled (or enabled),
+// and waits for it to be paused (or ready)
+func (p *Platform) setFunctionDisabled(namespace string, name string, authConfig *platform.AuthConfig, disable bool) error {
+	function, err := p.consumer.nuclioClientSet.NuclioV1beta1().
+		NuclioFunctions(namespace).
+		Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s", name, namespace))
+		}
+
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	functionIsPaused := function.Status.State == functionconfig.FunctionStatePaused || function.Spec.Disable
+
+	switch {
+	case function.Status.State == functionconfig.FunctionStateImported:
+		return nuclio.NewErrBadRequest("Non-deployed functions cannot be paused or resumed")
+	case disable && function.Status.State == functionconfig.FunctionStatePaused:
+		return nil
+	case !disable && !functionIsPaused:
+		return nuclio.NewErrConflict(fmt.Sprintf("Function %s isn't paused (state: %s)", name, function.Status.State))
+	}
+
+	function.Spec.Disable = disable
+	function.Status.State = functionconfig.FunctionStateWaitingForResourceConfiguration
+
+	nuclioClientSet, err := p.consumer.getNuclioClientSet(authConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get nuclio clientset")
+	}
+
+	if _, err := nuclioClientSet.NuclioV1beta1().NuclioFunctions(namespace).Update(function); err != nil {
+		return errors.Wrap(err, "Failed to update function CR")
+	}
+
+	if _, err := waitForFunctionReadiness(p.Logger, p.consumer, namespace, name); err != nil {
+		return errors.Wrap(err, "Failed to wait for function readiness")
+	}
+
+	return nil
+}
+
+// PauseFunctionTrigger marks the trigger paused in the function's configuration, which its replicas reload without
+// restarting (and read when they restart). the running replicas are also told to pause it right away
+func (p *Platform) PauseFunctionTrigger(pauseFunctionTriggerOptions *platform.PauseFunctionTriggerOptions) error {
+	return p.setFunctionTriggerPaused(pauseFunctionTriggerOptions.Namespace,
+		pauseFunctionTriggerOptions.Name,
+		pauseFunctionTriggerOptions.TriggerName,
+		pauseFunctionTriggerOptions.AuthConfig,
+		true)
+}
+
+// ResumeFunctionTrigger marks a paused trigger of the function as no longer paused, the same way it was paused
+func (p *Platform) ResumeFunctionTrigger(resumeFunctionTriggerOptions *platform.ResumeFunctionTriggerOptions) error {
+	return p.setFunctionTriggerPaused(resumeFunctionTriggerOptions.Namespace,
+		resumeFunctionTriggerOptions.Name,
+		resumeFunctionTriggerOptions.TriggerName,
+		resumeFunctionTriggerOptions.AuthConfig,
+		false)
+}
+
+func (p *Platform) setFunctionTriggerPaused(namespace string,
+	name string,
+	triggerName string,
+	authConfig *platform.AuthConfig,
+	paused bool) error {
+	function, err := p.consumer.nuclioClientSet.NuclioV1beta1().
+		NuclioFunctions(namespace).
+		Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s", name, namespace))
+		}
+
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	if function.Status.State == functionconfig.FunctionStateImported {
+		return nuclio.NewErrBadRequest("Triggers of non-deployed functions cannot be paused or resumed")
+	}
+
+	triggerConfiguration, found := function.Spec.Triggers[triggerName]
+	if !found {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Trigger not found: %s", triggerName))
+	}
+
+	// cron triggers are run by cron jobs rather than by the processors
+	if triggerConfiguration.Kind == "cron" {
+		return nuclio.NewErrBadRequest("Cron triggers cannot be paused on the kube platform, pause the function instead")
+	}
+
+	if triggerConfiguration.Paused == paused {
+		return nil
+	}
+
+	// the controller updates the function's config map, without restarting its replicas
+	triggerConfiguration.Paused = paused
+	function.Spec.Triggers[triggerName] = triggerConfiguration
+	function.Status.State = functionconfig.FunctionStateWaitingForResourceConfiguration
+
+	nuclioClientSet, err := p.consumer.getNuclioClientSet(authConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get nuclio clientset")
+	}
+
+	if _, err := nuclioClientSet.NuclioV1beta1().NuclioFunctions(namespace).Update(function); err != nil {
+		return errors.Wrap(err, "Failed to update function CR")
+	}
+
+	if _, err := waitForFunctionReadiness(p.Logger, p.consumer, namespace, name); err != nil {
+		return errors.Wrap(err, "Failed to wait for function readiness")
+	}
+
+	// the replicas reload the config map once the kubelet syncs it, which may take a minute or so
+	p.setReplicasTriggerPaused(namespace, name, triggerName, paused)
+
+	return nil
+}
+
+// setReplicasTriggerPaused has the processors of the function's running pods pause (or resume) the trigger,
+// through the API server's pod proxy. replicas which fail to are left to reload their configuration
+func (p *Platform) setReplicasTriggerPaused(namespace string, name string, triggerName string, paused bool) {
+	action := "resume"
+	if paused {
+		action = "pause"
+	}
+
+	pods, err := p.consumer.kubeClientSet.CoreV1().
+		Pods(namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", name),
+		})
+	if err != nil {
+		p.Logger.WarnWith("Failed to list function pods, leaving them to reload the trigger",
+			"function", name,
+			"err", err.Error())
+		return
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+
+		if _, err := p.consumer.kubeClientSet.CoreV1().
+			RESTClient().
+			Post().
+			Namespace(namespace).
+			Resource("pods").
+			Name(fmt.Sprintf("%s:%s", pod.Name, processorWebAdminPort)).
+			SubResource("proxy").
+			Suffix("triggers", triggerName, action).
+			DoRaw(); err != nil {
+			p.Logger.WarnWith("Failed to set trigger paused in replica, leaving it to reload the trigger",
+				"pod", pod.Name,
+				"trigger", triggerName,
+				"paused", paused,
+				"err", errors.RootCause(err).Error())
+		}
+	}
+}
+
+// getFunctionPodName returns the name of the given replica of a function, verifying it exists, or of its only
+// pod if no replica is given
+func (p *Platform) getFunctionPodName(namespace string, name string, replica string) (string, error) {
+	functionPods, err := p.consumer.kubeClientSet.CoreV1().
+		Pods(namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", name),
+		})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to list function pods")
+	}
+
+	var podNames []string
+	for _, pod := range functionPods.Items {
+		podNames = append(podNames, pod.Name)
+	}
+
+	sort.Strings(podNames)
+
+	switch {
+	case replica != "":
+		if !common.StringSliceContainsString(podNames, replica) {
+			return "", nuclio.NewErrNotFound(fmt.Sprintf("Replica not found: %s (the function's replicas are: %s)",
+				replica,
+				strings.Join(podNames, ", ")))
+		}
+
+		return replica, nil
+	case len(podNames) == 0:
+		return "", errors.Errorf("Function %s has no pods, is replicas set to 0?", name)
+	case len(podNames) > 1:
+		return "", errors.Errorf("Function %s has more than one replica, choose one of: %s",
+			name,
+			strings.Join(podNames, ", "))
+	default:
+		return podNames[0], nil
+	}
 }
 
 func IsInCluster() bool {
@@ -368,6 +752,10 @@ func (p *Platform) GetNodes() ([]platform.Node, error) {
 
 // CreateProject will probably create a new project
 func (p *Platform) CreateProject(createProjectOptions *platform.CreateProjectOptions) error {
+	if err := createProjectOptions.ProjectConfig.Spec.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
 	newProject := nuclioio.NuclioProject{}
 	p.platformProjectToProject(&createProjectOptions.ProjectConfig, &newProject)
 
@@ -384,6 +772,10 @@ func (p *Platform) CreateProject(createProjectOptions *platform.CreateProjectOpt
 
 // UpdateProject will update a previously existing project
 func (p *Platform) UpdateProject(updateProjectOptions *platform.UpdateProjectOptions) error {
+	if err := updateProjectOptions.ProjectConfig.Spec.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
 	project, err := p.consumer.nuclioClientSet.NuclioV1beta1().
 		NuclioProjects(updateProjectOptions.ProjectConfig.Meta.Namespace).
 		Get(updateProjectOptions.ProjectConfig.Meta.Name, meta_v1.GetOptions{})
@@ -429,8 +821,14 @@ func (p *Platform) GetProjects(getProjectsOptions *platform.GetProjectsOptions)
 	var platformProjects []platform.Project
 	var projects []nuclioio.NuclioProject
 
-	// if identifier specified, we need to get a single NuclioProject
-	if getProjectsOptions.Meta.Name != "" {
+	namespace := getProjectsOptions.Meta.Namespace
+	if getProjectsOptions.AllNamespaces {
+		namespace = meta_v1.NamespaceAll
+	}
+
+	// if identifier specified, we need to get a single NuclioProject (projects of the same name in all
+	// namespaces are listed by name)
+	if getProjectsOptions.Meta.Name != "" && !getProjectsOptions.AllNamespaces {
 
 		// get specific NuclioProject CR
 		Project, err := p.consumer.nuclioClientSet.NuclioV1beta1().
@@ -451,9 +849,14 @@ func (p *Platform) GetProjects(getProjectsOptions *platform.GetProjectsOptions)
 
 	} else {
 
+		listOptions := meta_v1.ListOptions{LabelSelector: ""}
+		if getProjectsOptions.Meta.Name != "" {
+			listOptions.FieldSelector = "metadata.name=" + getProjectsOptions.Meta.Name
+		}
+
 		projectInstanceList, err := p.consumer.nuclioClientSet.NuclioV1beta1().
-			NuclioProjects(getProjectsOptions.Meta.Namespace).
-			List(meta_v1.ListOptions{LabelSelector: ""})
+			NuclioProjects(namespace).
+			List(listOptions)
 
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to list projects")
@@ -713,6 +1116,20 @@ func (p *Platform) GetNamespaces() ([]string, error) {
 	return namespaceNames, nil
 }
 
+// GetSecretData returns the data of a kubernetes secret in the namespace, by key
+func (p *Platform) GetSecretData(namespace string, name string) (map[string][]byte, error) {
+	secret, err := p.consumer.kubeClientSet.CoreV1().Secrets(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nuclio.NewErrNotFound(fmt.Sprintf("Secret not found: %s @ %s", name, namespace))
+		}
+
+		return nil, errors.Wrapf(err, "Failed to get secret %s", name)
+	}
+
+	return secret.Data, nil
+}
+
 func (p *Platform) GetDefaultInvokeIPAddresses() ([]string, error) {
 	return []string{}, nil
 }
@@ -0,0 +1,226 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the kinds of resources a function may reference
+const (
+	ReferenceKindSecret    = "secret"
+	ReferenceKindConfigMap = "configmap"
+)
+
+// Reference is a reference of a function to a secret or configmap, or to a key in one
+type Reference struct {
+	Kind string
+	Name string
+
+	// Key is empty when the function references the resource as a whole
+	Key string
+
+	// Source is where the function references it (e.g. "env PASSWORD")
+	Source string
+}
+
+// GetFunctionDanglingReferences returns the references of the function to secrets, configmaps and keys
+// in them which don't exist in the function's namespace. optional references are ignored
+func (p *Platform) GetFunctionDanglingReferences(functionConfig *functionconfig.Config) ([]Reference, error) {
+	return getDanglingReferences(p.consumer.kubeClientSet, functionConfig)
+}
+
+func getDanglingReferences(kubeClientSet kubernetes.Interface, functionConfig *functionconfig.Config) ([]Reference, error) {
+	var danglingReferences []Reference
+
+	// each resource is only read once, nil if it doesn't exist
+	resourceKeys := map[string]map[string]bool{}
+
+	for _, reference := range getFunctionReferences(functionConfig) {
+		resourceID := reference.Kind + "/" + reference.Name
+
+		keys, resourceRead := resourceKeys[resourceID]
+		if !resourceRead {
+			var err error
+
+			keys, err = getResourceKeys(kubeClientSet, functionConfig.Meta.Namespace, reference.Kind, reference.Name)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to get %s %s", reference.Kind, reference.Name)
+			}
+
+			resourceKeys[resourceID] = keys
+		}
+
+		if keys == nil || (reference.Key != "" && !keys[reference.Key]) {
+			danglingReferences = append(danglingReferences, reference)
+		}
+	}
+
+	return danglingReferences, nil
+}
+
+// getResourceKeys returns the keys of a secret or configmap (only their names - values are never read
+// beyond the client), or nil if it doesn't exist
+func getResourceKeys(kubeClientSet kubernetes.Interface, namespace string, kind string, name string) (map[string]bool, error) {
+	keys := map[string]bool{}
+
+	switch kind {
+	case ReferenceKindSecret:
+		secret, err := kubeClientSet.CoreV1().Secrets(namespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		for key := range secret.Data {
+			keys[key] = true
+		}
+
+		for key := range secret.StringData {
+			keys[key] = true
+		}
+	case ReferenceKindConfigMap:
+		configMap, err := kubeClientSet.CoreV1().ConfigMaps(namespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		for key := range configMap.Data {
+			keys[key] = true
+		}
+
+		for key := range configMap.BinaryData {
+			keys[key] = true
+		}
+	}
+
+	return keys, nil
+}
+
+// getFunctionReferences returns the required references of the function's env, volumes, secrets and image
+// pull secret
+func getFunctionReferences(functionConfig *functionconfig.Config) []Reference {
+	var references []Reference
+
+	isOptional := func(optional *bool) bool {
+		return optional != nil && *optional
+	}
+
+	for _, envVar := range functionConfig.Spec.Env {
+		if envVar.ValueFrom == nil {
+			continue
+		}
+
+		source := fmt.Sprintf("env %s", envVar.Name)
+
+		if secretKeyRef := envVar.ValueFrom.SecretKeyRef; secretKeyRef != nil && !isOptional(secretKeyRef.Optional) {
+			references = append(references, Reference{
+				Kind:   ReferenceKindSecret,
+				Name:   secretKeyRef.Name,
+				Key:    secretKeyRef.Key,
+				Source: source,
+			})
+		}
+
+		if configMapKeyRef := envVar.ValueFrom.ConfigMapKeyRef; configMapKeyRef != nil && !isOptional(configMapKeyRef.Optional) {
+			references = append(references, Reference{
+				Kind:   ReferenceKindConfigMap,
+				Name:   configMapKeyRef.Name,
+				Key:    configMapKeyRef.Key,
+				Source: source,
+			})
+		}
+	}
+
+	for _, volume := range functionConfig.Spec.Volumes {
+		source := fmt.Sprintf("volume %s", volume.Volume.Name)
+
+		if secret := volume.Volume.Secret; secret != nil && !isOptional(secret.Optional) {
+			references = append(references, Reference{
+				Kind:   ReferenceKindSecret,
+				Name:   secret.SecretName,
+				Source: source,
+			})
+
+			for _, item := range secret.Items {
+				references = append(references, Reference{
+					Kind:   ReferenceKindSecret,
+					Name:   secret.SecretName,
+					Key:    item.Key,
+					Source: source,
+				})
+			}
+		}
+
+		if configMap := volume.Volume.ConfigMap; configMap != nil && !isOptional(configMap.Optional) {
+			references = append(references, Reference{
+				Kind:   ReferenceKindConfigMap,
+				Name:   configMap.Name,
+				Source: source,
+			})
+
+			for _, item := range configMap.Items {
+				references = append(references, Reference{
+					Kind:   ReferenceKindConfigMap,
+					Name:   configMap.Name,
+					Key:    item.Key,
+					Source: source,
+				})
+			}
+		}
+	}
+
+	for _, envFromSecret := range functionConfig.Spec.EnvFromSecrets {
+		references = append(references, Reference{
+			Kind:   ReferenceKindSecret,
+			Name:   envFromSecret.Name,
+			Source: "envFromSecrets",
+		})
+	}
+
+	for _, volumeFromSecret := range functionConfig.Spec.VolumesFromSecrets {
+		references = append(references, Reference{
+			Kind:   ReferenceKindSecret,
+			Name:   volumeFromSecret.Name,
+			Source: fmt.Sprintf("volumeFromSecret %s", volumeFromSecret.MountPath),
+		})
+	}
+
+	if functionConfig.Spec.ImagePullSecrets != "" {
+		references = append(references, Reference{
+			Kind:   ReferenceKindSecret,
+			Name:   functionConfig.Spec.ImagePullSecrets,
+			Source: "image pull secrets",
+		})
+	}
+
+	return references
+}
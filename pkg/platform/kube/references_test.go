@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type referencesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *referencesTestSuite) TestGetDanglingReferences() {
+	kubeClientSet := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "my-secret", Namespace: "my-namespace"},
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "my-configmap", Namespace: "my-namespace"},
+			Data:       map[string]string{"host": "localhost"},
+		},
+
+		// exists, but in another namespace
+		&v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "other-secret", Namespace: "other-namespace"},
+		})
+
+	optional := true
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name:      "my-function",
+			Namespace: "my-namespace",
+		},
+		Spec: functionconfig.Spec{
+			Env: []v1.EnvVar{
+				{Name: "PLAIN", Value: "value"},
+				{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "my-secret"},
+						Key:                  "password",
+					},
+				}},
+				{Name: "USERNAME", ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "my-secret"},
+						Key:                  "username",
+					},
+				}},
+				{Name: "HOST", ValueFrom: &v1.EnvVarSource{
+					ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+						Key:                  "host",
+					},
+				}},
+				{Name: "OPTIONAL", ValueFrom: &v1.EnvVarSource{
+					ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "missing-configmap"},
+						Key:                  "anything",
+						Optional:             &optional,
+					},
+				}},
+			},
+			Volumes: []functionconfig.Volume{
+				{
+					Volume: v1.Volume{
+						Name: "certs",
+						VolumeSource: v1.VolumeSource{
+							Secret: &v1.SecretVolumeSource{SecretName: "other-secret"},
+						},
+					},
+				},
+			},
+			EnvFromSecrets: []functionconfig.EnvFromSecret{
+				{Name: "my-secret"},
+				{Name: "missing-secret"},
+			},
+			VolumesFromSecrets: []functionconfig.VolumeFromSecret{
+				{Name: "missing-secret", MountPath: "/etc/missing"},
+			},
+		},
+	}
+
+	danglingReferences, err := getDanglingReferences(kubeClientSet, &functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]Reference{
+		{Kind: ReferenceKindSecret, Name: "my-secret", Key: "username", Source: "env USERNAME"},
+		{Kind: ReferenceKindSecret, Name: "other-secret", Source: "volume certs"},
+		{Kind: ReferenceKindSecret, Name: "missing-secret", Source: "envFromSecrets"},
+		{Kind: ReferenceKindSecret, Name: "missing-secret", Source: "volumeFromSecret /etc/missing"},
+	}, danglingReferences)
+}
+
+func TestReferencesTestSuite(t *testing.T) {
+	suite.Run(t, new(referencesTestSuite))
+}
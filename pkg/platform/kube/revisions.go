@@ -0,0 +1,167 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the key of the revisions in the function's revisions configmap, as a JSON list
+const functionRevisionsConfigMapKey = "revisions.json"
+
+// GetFunctionRevisions returns the configurations the function was deployed with, oldest first, as kept in
+// its revisions configmap
+func (p *Platform) GetFunctionRevisions(getFunctionRevisionsOptions *platform.GetFunctionRevisionsOptions) (
+	[]platform.FunctionRevision, error) {
+
+	function, err := p.getFunction(getFunctionRevisionsOptions.Namespace, getFunctionRevisionsOptions.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	if function == nil {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionRevisionsOptions.Name,
+			getFunctionRevisionsOptions.Namespace))
+	}
+
+	revisions, _, err := getFunctionRevisions(p.consumer.kubeClientSet,
+		getFunctionRevisionsOptions.Namespace,
+		getFunctionRevisionsOptions.Name)
+
+	return revisions, err
+}
+
+// recordFunctionRevision adds the configuration the function was just deployed with to its revisions, along
+// with the digest of the image its pods run
+func recordFunctionRevision(kubeClientSet kubernetes.Interface, functionConfig *functionconfig.Config) error {
+	namespace := functionConfig.Meta.Namespace
+	name := functionConfig.Meta.Name
+
+	revisions, configMap, err := getFunctionRevisions(kubeClientSet, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function revisions")
+	}
+
+	revisions = platform.AddFunctionRevision(revisions,
+		functionConfig,
+		getFunctionImageDigest(kubeClientSet, namespace, name, functionConfig.Spec.Image))
+
+	encodedRevisions, err := json.Marshal(revisions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode function revisions")
+	}
+
+	if configMap == nil {
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Create(&v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      RevisionsConfigMapNameFromFunctionName(name),
+				Namespace: namespace,
+				Labels: map[string]string{
+					"nuclio.io/function-name": name,
+				},
+			},
+			Data: map[string]string{
+				functionRevisionsConfigMapKey: string(encodedRevisions),
+			},
+		})
+	} else {
+		configMap.Data = map[string]string{
+			functionRevisionsConfigMapKey: string(encodedRevisions),
+		}
+
+		_, err = kubeClientSet.CoreV1().ConfigMaps(namespace).Update(configMap)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to write function revisions configmap")
+	}
+
+	return nil
+}
+
+// getFunctionRevisions returns the revisions kept in the function's revisions configmap, along with the
+// configmap (nil if the function has no revisions yet)
+func getFunctionRevisions(kubeClientSet kubernetes.Interface,
+	namespace string,
+	name string) ([]platform.FunctionRevision, *v1.ConfigMap, error) {
+
+	configMap, err := kubeClientSet.CoreV1().
+		ConfigMaps(namespace).
+		Get(RevisionsConfigMapNameFromFunctionName(name), meta_v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, errors.Wrap(err, "Failed to get function revisions configmap")
+	}
+
+	var revisions []platform.FunctionRevision
+	if encodedRevisions := configMap.Data[functionRevisionsConfigMapKey]; encodedRevisions != "" {
+		if err := json.Unmarshal([]byte(encodedRevisions), &revisions); err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to decode function revisions")
+		}
+	}
+
+	return revisions, configMap, nil
+}
+
+// getFunctionImageDigest returns the digest of the image the function's processor containers pulled for the
+// given image, as the kubelet reports it. returns an empty string if no pod runs the image
+func getFunctionImageDigest(kubeClientSet kubernetes.Interface, namespace string, name string, image string) string {
+	pods, err := kubeClientSet.CoreV1().Pods(namespace).List(meta_v1.ListOptions{
+		LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", name),
+	})
+	if err != nil {
+		return ""
+	}
+
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+
+			// the kubelet may report the image fully qualified (e.g. docker.io/library/...)
+			if containerStatus.Name != "nuclio" ||
+				containerStatus.ImageID == "" ||
+				(containerStatus.Image != image && !strings.HasSuffix(containerStatus.Image, "/"+image)) {
+				continue
+			}
+
+			// e.g. docker-pullable://registry/repository@sha256:...
+			imageID := containerStatus.ImageID
+			if digestIdx := strings.LastIndex(imageID, "@"); digestIdx != -1 {
+				return imageID[digestIdx+1:]
+			}
+
+			return strings.TrimPrefix(imageID, "docker://")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type revisionsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *revisionsTestSuite) TestRecordFunctionRevision() {
+	kubeClientSet := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "nuclio-my-function-abcde",
+			Namespace: "my-namespace",
+			Labels:    map[string]string{"nuclio.io/function-name": "my-function"},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:    "nuclio",
+					Image:   "docker.io/my-registry/my-function:1.0.0",
+					ImageID: "docker-pullable://my-registry/my-function@sha256:0123456789",
+				},
+			},
+		},
+	})
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name:      "my-function",
+			Namespace: "my-namespace",
+		},
+	}
+
+	// the first revision creates the configmap
+	functionConfig.Spec.Image = "my-registry/my-function:1.0.0"
+	err := recordFunctionRevision(kubeClientSet, &functionConfig)
+	suite.Require().NoError(err)
+
+	// no pod runs the image of the second, so its digest is unknown
+	functionConfig.Spec.Image = "my-registry/my-function:2.0.0"
+	err = recordFunctionRevision(kubeClientSet, &functionConfig)
+	suite.Require().NoError(err)
+
+	revisions, configMap, err := getFunctionRevisions(kubeClientSet, "my-namespace", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().Equal("nuclio-my-function.revisions", configMap.Name)
+	suite.Require().Equal("my-function", configMap.Labels["nuclio.io/function-name"])
+
+	suite.Require().Len(revisions, 2)
+	suite.Require().Equal(1, revisions[0].Revision)
+	suite.Require().Equal("my-registry/my-function:1.0.0", revisions[0].Config.Spec.Image)
+	suite.Require().Equal("sha256:0123456789", revisions[0].ImageDigest)
+	suite.Require().Equal(2, revisions[1].Revision)
+	suite.Require().Equal("my-registry/my-function:2.0.0", revisions[1].Config.Spec.Image)
+	suite.Require().Empty(revisions[1].ImageDigest)
+
+	// functions which were never deployed have no revisions
+	revisions, configMap, err = getFunctionRevisions(kubeClientSet, "my-namespace", "other-function")
+	suite.Require().NoError(err)
+	suite.Require().Nil(configMap)
+	suite.Require().Empty(revisions)
+}
+
+func TestRevisionsTestSuite(t *testing.T) {
+	suite.Run(t, new(revisionsTestSuite))
+}
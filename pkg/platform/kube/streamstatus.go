@@ -0,0 +1,99 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the port the processor's web admin listens on
+const processorWebAdminPort = "8081"
+
+// GetFunctionStreamStatus returns the shards the stream triggers of each of the function's running pods consume,
+// as their processors report them through the API server's pod proxy
+func (p *Platform) GetFunctionStreamStatus(getFunctionStreamStatusOptions *platform.GetFunctionStreamStatusOptions) (
+	[]platform.FunctionReplicaStreamStatus, error) {
+
+	function, err := p.getFunction(getFunctionStreamStatusOptions.Namespace, getFunctionStreamStatusOptions.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	if function == nil {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionStreamStatusOptions.Name,
+			getFunctionStreamStatusOptions.Namespace))
+	}
+
+	pods, err := p.consumer.kubeClientSet.CoreV1().
+		Pods(getFunctionStreamStatusOptions.Namespace).
+		List(meta_v1.ListOptions{
+			LabelSelector: fmt.Sprintf("nuclio.io/function-name=%s", getFunctionStreamStatusOptions.Name),
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list function pods")
+	}
+
+	var functionStreamStatus []platform.FunctionReplicaStreamStatus
+	for _, pod := range pods.Items {
+		replicaStreamStatus := &platform.FunctionReplicaStreamStatus{Replica: pod.Name}
+
+		if pod.Status.Phase == v1.PodRunning {
+			encodedStatistics, err := p.getProcessorWebAdminResource(&pod, platform.ProcessorStatisticsPath)
+			if err != nil {
+				p.Logger.DebugWith("Failed to get processor statistics",
+					"pod", pod.Name,
+					"err", errors.RootCause(err).Error())
+			} else if parsedStreamStatus, err := platform.ParseFunctionReplicaStreamStatus(pod.Name,
+				encodedStatistics); err != nil {
+				p.Logger.DebugWith("Failed to parse processor statistics", "pod", pod.Name, "err", err.Error())
+			} else {
+				replicaStreamStatus = parsedStreamStatus
+			}
+		}
+
+		functionStreamStatus = append(functionStreamStatus, *replicaStreamStatus)
+	}
+
+	sort.Slice(functionStreamStatus, func(i, j int) bool {
+		return functionStreamStatus[i].Replica < functionStreamStatus[j].Replica
+	})
+
+	return functionStreamStatus, nil
+}
+
+// getProcessorWebAdminResource returns a resource the web admin of the pod's processor serves, through the API
+// server's pod proxy
+func (p *Platform) getProcessorWebAdminResource(pod *v1.Pod, resourcePath string) ([]byte, error) {
+	return p.consumer.kubeClientSet.CoreV1().
+		RESTClient().
+		Get().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(fmt.Sprintf("%s:%s", pod.Name, processorWebAdminPort)).
+		SubResource("proxy").
+		Suffix(resourcePath).
+		DoRaw()
+}
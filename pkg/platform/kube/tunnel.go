@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const portForwardReadyTimeout = 30 * time.Second
+
+// kubectl reports each address it listens on as "Forwarding from 127.0.0.1:<port> -> <port>"
+var portForwardAddressRegex = regexp.MustCompile(`^Forwarding from (127\.0\.0\.1:\d+) -> `)
+
+// portForwardTunnel is a port forward run by kubectl, forwarding a local port to a function's service
+type portForwardTunnel struct {
+	logger      logger.Logger
+	cmd         *exec.Cmd
+	address     string
+	stderr      bytes.Buffer
+	stderrLock  sync.Mutex
+	stopOnce    sync.Once
+	processDone chan error
+}
+
+// newPortForwardTunnel runs the port forward command and waits for it to report the local address it listens on
+func newPortForwardTunnel(parentLogger logger.Logger,
+	command []string,
+	readyTimeout time.Duration) (*portForwardTunnel, error) {
+	tunnel := &portForwardTunnel{
+		logger:      parentLogger.GetChild("tunnel"),
+		cmd:         exec.Command(command[0], command[1:]...),
+		processDone: make(chan error, 1),
+	}
+
+	tunnel.cmd.Stderr = &lockedWriter{writer: &tunnel.stderr, lock: &tunnel.stderrLock}
+
+	stdout, err := tunnel.cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get port forward stdout")
+	}
+
+	tunnel.logger.DebugWith("Starting port forward", "command", command)
+
+	if err := tunnel.cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "Failed to start port forward (is kubectl installed?)")
+	}
+
+	addressChan := make(chan string, 1)
+
+	// read the output until the process exits, so that it never blocks on writing it
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if address := parsePortForwardAddress(scanner.Text()); address != "" {
+				select {
+				case addressChan <- address:
+				default:
+				}
+			}
+		}
+
+		tunnel.processDone <- tunnel.cmd.Wait()
+	}()
+
+	select {
+	case tunnel.address = <-addressChan:
+		tunnel.logger.DebugWith("Port forward is ready", "address", tunnel.address)
+
+		return tunnel, nil
+
+	case err := <-tunnel.processDone:
+		return nil, errors.Wrapf(err, "Port forward exited: %s", tunnel.getStderr())
+
+	case <-time.After(readyTimeout):
+		tunnel.kill()
+		<-tunnel.processDone
+
+		return nil, errors.Errorf("Timed out waiting for port forward to be ready: %s", tunnel.getStderr())
+	}
+}
+
+// GetAddress returns the local address forwarded to the function
+func (t *portForwardTunnel) GetAddress() string {
+	return t.address
+}
+
+// Close stops the port forward
+func (t *portForwardTunnel) Close() error {
+	t.stopOnce.Do(func() {
+		t.logger.DebugWith("Stopping port forward", "address", t.address)
+
+		t.kill()
+		<-t.processDone
+	})
+
+	return nil
+}
+
+func (t *portForwardTunnel) kill() {
+	if err := t.cmd.Process.Kill(); err != nil {
+		t.logger.WarnWith("Failed to kill port forward", "err", err)
+	}
+}
+
+func (t *portForwardTunnel) getStderr() string {
+	t.stderrLock.Lock()
+	defer t.stderrLock.Unlock()
+
+	return t.stderr.String()
+}
+
+// parsePortForwardAddress returns the local address in a line of kubectl port-forward output, if it has one
+func parsePortForwardAddress(line string) string {
+	match := portForwardAddressRegex.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// lockedWriter serializes writes, so that what was written can be read while the process runs
+type lockedWriter struct {
+	writer io.Writer
+	lock   *sync.Mutex
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.writer.Write(p)
+}
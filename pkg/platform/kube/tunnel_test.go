@@ -0,0 +1,72 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type tunnelTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *tunnelTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *tunnelTestSuite) TestParsePortForwardAddress() {
+	suite.Require().Equal("127.0.0.1:43817", parsePortForwardAddress("Forwarding from 127.0.0.1:43817 -> 8080"))
+	suite.Require().Empty(parsePortForwardAddress("Forwarding from [::1]:43817 -> 8080"))
+	suite.Require().Empty(parsePortForwardAddress("Handling connection for 43817"))
+}
+
+func (suite *tunnelTestSuite) TestPortForward() {
+	tunnel, err := newPortForwardTunnel(suite.logger,
+		[]string{"sh", "-c", "echo 'Forwarding from 127.0.0.1:43817 -> 8080'; sleep 60"},
+		10*time.Second)
+	suite.Require().NoError(err)
+	suite.Require().Equal("127.0.0.1:43817", tunnel.GetAddress())
+
+	// closing kills the port forward, and may be repeated
+	suite.Require().NoError(tunnel.Close())
+	suite.Require().NotNil(tunnel.cmd.ProcessState)
+	suite.Require().NoError(tunnel.Close())
+}
+
+func (suite *tunnelTestSuite) TestPortForwardExits() {
+	_, err := newPortForwardTunnel(suite.logger,
+		[]string{"sh", "-c", "echo 'error: services \"nuclio-missing\" not found' >&2; exit 1"},
+		10*time.Second)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "not found")
+}
+
+func (suite *tunnelTestSuite) TestPortForwardTimesOut() {
+	_, err := newPortForwardTunnel(suite.logger, []string{"sleep", "60"}, 100*time.Millisecond)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Timed out")
+}
+
+func TestTunnelTestSuite(t *testing.T) {
+	suite.Run(t, new(tunnelTestSuite))
+}
@@ -33,10 +33,26 @@ func ConfigMapNameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
 
+// RevisionsConfigMapNameFromFunctionName returns the name of the configmap keeping the function's revisions.
+// function names are also service names, which can't contain dots, so it can't be the configmap of another function
+func RevisionsConfigMapNameFromFunctionName(functionName string) string {
+	return fmt.Sprintf("nuclio-%s.revisions", functionName)
+}
+
+// InvocationsConfigMapNameFromFunctionName returns the name of the configmap keeping the function's invocations,
+// as retained by the invocation inspector
+func InvocationsConfigMapNameFromFunctionName(functionName string) string {
+	return fmt.Sprintf("nuclio-%s.invocations", functionName)
+}
+
 func HPANameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
 
+func ScaledObjectNameFromFunctionName(functionName string) string {
+	return fmt.Sprintf("nuclio-%s", functionName)
+}
+
 func IngressNameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
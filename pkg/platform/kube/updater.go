@@ -55,13 +55,27 @@ func (u *updater) update(updateFunctionOptions *platform.UpdateFunctionOptions)
 		return errors.Wrap(err, "Failed to get function")
 	}
 
+	// update its annotations if passed
+	if updateFunctionOptions.FunctionMeta.Annotations != nil {
+		function.Annotations = updateFunctionOptions.FunctionMeta.Annotations
+	}
+
 	// update it with spec if passed
 	if updateFunctionOptions.FunctionSpec != nil {
+		previousSpec := function.Spec
 		function.Spec = *updateFunctionOptions.FunctionSpec
-
-		// update the spec with a new image hash to trigger pod restart. in the future this can be removed,
-		// assuming the processor can reload configuration
-		function.Spec.ImageHash = strconv.Itoa(int(time.Now().UnixNano()))
+		function.Spec.ImageHash = previousSpec.ImageHash
+
+		// the processors reload the configuration (from the config map) when only hot-reloadable fields changed
+		// (e.g. triggers), so the pods are left running. otherwise update the spec with a new image hash to
+		// trigger pod restart
+		changedFields, err := previousSpec.GetChangesRequiringRestart(&function.Spec)
+		if err != nil || len(changedFields) > 0 {
+			function.Spec.ImageHash = strconv.Itoa(int(time.Now().UnixNano()))
+		} else {
+			u.logger.InfoWith("Function configuration will be reloaded by its replicas, not restarting them",
+				"name", function.Name)
+		}
 	}
 
 	// update it with status if passed
@@ -0,0 +1,228 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// the window over which prometheus calculates event rates. must span at least two scrapes
+const usagePrometheusRateWindow = "1m"
+
+// the subset of metrics.k8s.io/v1beta1 PodMetricsList we use
+type podMetricsList struct {
+	Items []struct {
+		Containers []struct {
+			Usage map[string]string `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// the subset of a prometheus instant query response we use
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// GetFunctionUsage returns the CPU and memory used by the functions' pods, as metrics-server reports them, and
+// the rate of events they handled, as prometheus calculates them. event rates are only returned if a prometheus
+// URL is given
+func (p *Platform) GetFunctionUsage(getFunctionUsageOptions *platform.GetFunctionUsageOptions) (
+	[]platform.FunctionUsage, error) {
+
+	functions, err := p.GetFunctions(&platform.GetFunctionsOptions{
+		Name:      getFunctionUsageOptions.Name,
+		Namespace: getFunctionUsageOptions.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	if getFunctionUsageOptions.Name != "" && len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionUsageOptions.Name,
+			getFunctionUsageOptions.Namespace))
+	}
+
+	var functionUsages []platform.FunctionUsage
+	for _, function := range functions {
+		functionConfig := function.GetConfig()
+
+		functionUsage := platform.FunctionUsage{
+			Name:      functionConfig.Meta.Name,
+			Namespace: functionConfig.Meta.Namespace,
+		}
+
+		if err := function.Initialize(nil); err != nil {
+			p.Logger.WarnWith("Failed to initialize function", "name", functionUsage.Name, "err", err.Error())
+		} else {
+			functionUsage.Replicas, _ = function.GetReplicas()
+		}
+
+		functionUsage.CPUMillicores, functionUsage.MemoryBytes, err = p.getFunctionResourceUsage(functionUsage.Namespace,
+			functionUsage.Name)
+		if err != nil {
+			p.Logger.WarnWith("Failed to get function resource usage, is metrics-server installed?",
+				"name", functionUsage.Name,
+				"err", errors.RootCause(err).Error())
+		}
+
+		if getFunctionUsageOptions.PrometheusURL != "" {
+			functionUsage.EventsPerSecond, functionUsage.ErrorRate, err = p.getFunctionEventRates(
+				getFunctionUsageOptions.PrometheusURL,
+				functionUsage.Namespace,
+				functionUsage.Name)
+			if err != nil {
+				p.Logger.WarnWith("Failed to get function event rates from prometheus",
+					"name", functionUsage.Name,
+					"err", errors.RootCause(err).Error())
+			}
+		}
+
+		functionUsages = append(functionUsages, functionUsage)
+	}
+
+	return functionUsages, nil
+}
+
+func (p *Platform) getFunctionResourceUsage(namespace string, name string) (*int64, *int64, error) {
+	encodedPodMetrics, err := p.consumer.kubeClientSet.CoreV1().
+		RESTClient().
+		Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", namespace, "pods").
+		Param("labelSelector", fmt.Sprintf("nuclio.io/function-name=%s", name)).
+		DoRaw()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to get pod metrics")
+	}
+
+	return parsePodMetrics(encodedPodMetrics)
+}
+
+func (p *Platform) getFunctionEventRates(prometheusURL string, namespace string, name string) (*float64, *float64, error) {
+	query := fmt.Sprintf(`sum by (result) (rate(nuclio_processor_handled_events_total{namespace="%s",function="%s"}[%s]))`,
+		namespace,
+		name,
+		usagePrometheusRateWindow)
+
+	httpClient := http.Client{Timeout: 10 * time.Second}
+
+	response, err := httpClient.Get(fmt.Sprintf("%s/api/v1/query?query=%s",
+		strings.TrimSuffix(prometheusURL, "/"),
+		url.QueryEscape(query)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to query prometheus")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	encodedResponse, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to read prometheus response")
+	}
+
+	return parsePrometheusEventRates(encodedResponse)
+}
+
+// sums the CPU (in millicores) and memory (in bytes) used by all containers of all pods
+func parsePodMetrics(encodedPodMetrics []byte) (*int64, *int64, error) {
+	podMetrics := podMetricsList{}
+	if err := json.Unmarshal(encodedPodMetrics, &podMetrics); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse pod metrics")
+	}
+
+	var cpuMillicores, memoryBytes int64
+	for _, podMetric := range podMetrics.Items {
+		for _, container := range podMetric.Containers {
+			cpuQuantity, err := resource.ParseQuantity(container.Usage["cpu"])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "Failed to parse CPU usage: %s", container.Usage["cpu"])
+			}
+
+			memoryQuantity, err := resource.ParseQuantity(container.Usage["memory"])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "Failed to parse memory usage: %s", container.Usage["memory"])
+			}
+
+			cpuMillicores += cpuQuantity.MilliValue()
+			memoryBytes += memoryQuantity.Value()
+		}
+	}
+
+	return &cpuMillicores, &memoryBytes, nil
+}
+
+// returns the events handled per second and the fraction of them which failed, from the per-result rates
+func parsePrometheusEventRates(encodedResponse []byte) (*float64, *float64, error) {
+	queryResponse := prometheusQueryResponse{}
+	if err := json.Unmarshal(encodedResponse, &queryResponse); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse prometheus response")
+	}
+
+	if queryResponse.Status != "success" {
+		return nil, nil, errors.Errorf("Prometheus query failed: %s", queryResponse.Error)
+	}
+
+	ratesByResult := map[string]float64{}
+	for _, result := range queryResponse.Data.Result {
+
+		// encoded as [<timestamp>, "<value>"]
+		if len(result.Value) != 2 {
+			return nil, nil, errors.Errorf("Unexpected prometheus value: %v", result.Value)
+		}
+
+		encodedRate, isString := result.Value[1].(string)
+		if !isString {
+			return nil, nil, errors.Errorf("Unexpected prometheus value: %v", result.Value)
+		}
+
+		rate, err := strconv.ParseFloat(encodedRate, 64)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed to parse prometheus value: %s", encodedRate)
+		}
+
+		ratesByResult[result.Metric["result"]] = rate
+	}
+
+	eventsPerSecond := ratesByResult["success"] + ratesByResult["failure"]
+
+	var errorRate float64
+	if eventsPerSecond > 0 {
+		errorRate = ratesByResult["failure"] / eventsPerSecond
+	}
+
+	return &eventsPerSecond, &errorRate, nil
+}
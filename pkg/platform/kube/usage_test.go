@@ -0,0 +1,72 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type usageTestSuite struct {
+	suite.Suite
+}
+
+func (suite *usageTestSuite) TestParsePodMetrics() {
+	cpuMillicores, memoryBytes, err := parsePodMetrics([]byte(`{
+  "kind": "PodMetricsList",
+  "items": [
+    {"containers": [{"name": "nuclio", "usage": {"cpu": "250m", "memory": "64Mi"}}]},
+    {"containers": [{"name": "nuclio", "usage": {"cpu": "1500000n", "memory": "1024Ki"}}]}
+  ]
+}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(252), *cpuMillicores)
+	suite.Require().Equal(int64(65*1024*1024), *memoryBytes)
+
+	_, _, err = parsePodMetrics([]byte(`{"items": [{"containers": [{"usage": {"cpu": "lots"}}]}]}`))
+	suite.Require().Error(err)
+}
+
+func (suite *usageTestSuite) TestParsePrometheusEventRates() {
+	eventsPerSecond, errorRate, err := parsePrometheusEventRates([]byte(`{
+  "status": "success",
+  "data": {
+    "resultType": "vector",
+    "result": [
+      {"metric": {"result": "success"}, "value": [1602720000.123, "9"]},
+      {"metric": {"result": "failure"}, "value": [1602720000.123, "1"]}
+    ]
+  }
+}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(10.0, *eventsPerSecond)
+	suite.Require().Equal(0.1, *errorRate)
+
+	// the function handled no events within the window
+	eventsPerSecond, errorRate, err = parsePrometheusEventRates([]byte(`{"status": "success", "data": {"result": []}}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(0.0, *eventsPerSecond)
+	suite.Require().Equal(0.0, *errorRate)
+
+	_, _, err = parsePrometheusEventRates([]byte(`{"status": "error", "error": "parse error"}`))
+	suite.Require().Error(err)
+}
+
+func TestUsageTestSuite(t *testing.T) {
+	suite.Run(t, new(usageTestSuite))
+}
@@ -0,0 +1,484 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+
+	// the reverse proxy serving the API gateways of all namespaces, configured from the local store
+	apiGatewayProxyContainerName = "nuclio-local-api-gateway-proxy"
+	apiGatewayProxyDir           = baseDir + "/api-gateway-proxy"
+	apiGatewayProxyConfigPath    = apiGatewayProxyDir + "/nginx.conf"
+	apiGatewayProxyHtpasswdDir   = apiGatewayProxyDir + "/htpasswd"
+
+	defaultAPIGatewayProxyImage = "nginx:1.19-alpine"
+	defaultAPIGatewayProxyPort  = "8090"
+)
+
+// an API gateway along with the addresses (host:port) of its upstream functions, in the order of its
+// upstreams. an upstream function without a running container has an empty address
+type apiGatewayRoute struct {
+	config            *platform.APIGatewayConfig
+	upstreamAddresses []string
+}
+
+// CreateAPIGateway will create a new API gateway, routing requests to functions through the API gateway proxy
+func (p *Platform) CreateAPIGateway(createAPIGatewayOptions *platform.CreateAPIGatewayOptions) error {
+	return p.createOrUpdateAPIGateway(&createAPIGatewayOptions.APIGatewayConfig)
+}
+
+// UpdateAPIGateway will update a previously existing API gateway
+func (p *Platform) UpdateAPIGateway(updateAPIGatewayOptions *platform.UpdateAPIGatewayOptions) error {
+	existingAPIGateways, err := p.localStore.getAPIGateways(&updateAPIGatewayOptions.APIGatewayConfig.Meta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	if len(existingAPIGateways) == 0 {
+		return nuclio.NewErrNotFound(fmt.Sprintf("API gateway %s not found",
+			updateAPIGatewayOptions.APIGatewayConfig.Meta.Name))
+	}
+
+	return p.createOrUpdateAPIGateway(&updateAPIGatewayOptions.APIGatewayConfig)
+}
+
+// DeleteAPIGateway will delete a previously existing API gateway
+func (p *Platform) DeleteAPIGateway(deleteAPIGatewayOptions *platform.DeleteAPIGatewayOptions) error {
+	if err := p.localStore.deleteAPIGateway(&deleteAPIGatewayOptions.Meta); err != nil {
+		return err
+	}
+
+	return p.syncAPIGatewayProxy()
+}
+
+// GetAPIGateways will list existing API gateways
+func (p *Platform) GetAPIGateways(getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
+	return p.localStore.getAPIGateways(&getAPIGatewaysOptions.Meta)
+}
+
+func (p *Platform) createOrUpdateAPIGateway(apiGatewayConfig *platform.APIGatewayConfig) error {
+	if err := apiGatewayConfig.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	// only route to existing functions
+	for _, upstream := range apiGatewayConfig.Spec.Upstreams {
+		functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+			Name:      upstream.NuclioFunction.Name,
+			Namespace: apiGatewayConfig.Meta.Namespace,
+		})
+		if err != nil {
+			return errors.Wrap(err, "Failed to get upstream function")
+		}
+
+		if len(functions) == 0 {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Upstream function %s not found",
+				upstream.NuclioFunction.Name))
+		}
+	}
+
+	// the proxy serves the API gateways of all namespaces, so their host and path must be unique across them
+	apiGateways, err := p.localStore.getAPIGateways(&platform.APIGatewayMeta{Namespace: "*"})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		existingAPIGatewayConfig := apiGateway.GetConfig()
+		if existingAPIGatewayConfig.Meta.Namespace == apiGatewayConfig.Meta.Namespace &&
+			existingAPIGatewayConfig.Meta.Name == apiGatewayConfig.Meta.Name {
+			continue
+		}
+
+		if existingAPIGatewayConfig.Spec.Host == apiGatewayConfig.Spec.Host &&
+			normalizeAPIGatewayPath(existingAPIGatewayConfig.Spec.Path) == normalizeAPIGatewayPath(apiGatewayConfig.Spec.Path) {
+			return nuclio.NewErrConflict(fmt.Sprintf("API gateway %s already serves host %q and path %q",
+				existingAPIGatewayConfig.Meta.Name,
+				apiGatewayConfig.Spec.Host,
+				normalizeAPIGatewayPath(apiGatewayConfig.Spec.Path)))
+		}
+	}
+
+	apiGatewayConfig.Status = platform.APIGatewayStatus{State: platform.APIGatewayStateReady}
+	if err := p.localStore.createOrUpdateAPIGateway(apiGatewayConfig); err != nil {
+		return errors.Wrap(err, "Failed to store API gateway")
+	}
+
+	if err := p.syncAPIGatewayProxy(); err != nil {
+
+		// keep the API gateway, so it can be fixed or deleted, but report why it doesn't serve requests
+		apiGatewayConfig.Status = platform.APIGatewayStatus{
+			State:     platform.APIGatewayStateError,
+			LastError: errors.RootCause(err).Error(),
+		}
+
+		if storeErr := p.localStore.createOrUpdateAPIGateway(apiGatewayConfig); storeErr != nil {
+			p.Logger.WarnWith("Failed to store API gateway state", "err", storeErr.Error())
+		}
+
+		return errors.Wrap(err, "Failed to configure API gateway proxy")
+	}
+
+	return nil
+}
+
+// updateFunctionAPIGateways reconfigures the API gateway proxy if any API gateway routes to the function,
+// whose container (and thus address) has changed
+func (p *Platform) updateFunctionAPIGateways(namespace string, functionName string) error {
+	apiGateways, err := p.localStore.getAPIGateways(&platform.APIGatewayMeta{Namespace: namespace})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		for _, upstream := range apiGateway.GetConfig().Spec.Upstreams {
+			if upstream.NuclioFunction != nil && upstream.NuclioFunction.Name == functionName {
+				return p.syncAPIGatewayProxy()
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncAPIGatewayProxy renders the configuration of the proxy from all of the API gateways and applies it,
+// running the proxy if it isn't running yet and removing it if there are no more API gateways
+func (p *Platform) syncAPIGatewayProxy() error {
+	apiGateways, err := p.localStore.getAPIGateways(&platform.APIGatewayMeta{Namespace: "*"})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	if len(apiGateways) == 0 {
+		return p.removeAPIGatewayProxy()
+	}
+
+	var routes []apiGatewayRoute
+	for _, apiGateway := range apiGateways {
+		route, err := p.resolveAPIGatewayRoute(apiGateway.GetConfig())
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve API gateway route")
+		}
+
+		routes = append(routes, route)
+	}
+
+	// the credentials of basic authentication are kept in a file per API gateway
+	if _, _, err := p.localStore.runCommand(nil, "/bin/rm -rf %s", apiGatewayProxyHtpasswdDir); err != nil {
+		return errors.Wrap(err, "Failed to remove API gateway credentials")
+	}
+
+	for _, route := range routes {
+		if route.config.Spec.AuthenticationMode != platform.APIGatewayAuthenticationModeBasicAuth {
+			continue
+		}
+
+		basicAuth := route.config.Spec.Authentication.BasicAuth
+		if err := p.writeAPIGatewayProxyFile(getAPIGatewayHtpasswdPath(route.config),
+			[]byte(fmt.Sprintf("%s:{PLAIN}%s\n", basicAuth.Username, basicAuth.Password))); err != nil {
+			return errors.Wrap(err, "Failed to write API gateway credentials")
+		}
+	}
+
+	if err := p.writeAPIGatewayProxyFile(apiGatewayProxyConfigPath,
+		[]byte(renderAPIGatewayProxyConfig(routes))); err != nil {
+		return errors.Wrap(err, "Failed to write API gateway proxy configuration")
+	}
+
+	return p.applyAPIGatewayProxyConfig()
+}
+
+// resolveAPIGatewayRoute resolves the addresses of the containers of the API gateway's upstream functions
+func (p *Platform) resolveAPIGatewayRoute(apiGatewayConfig *platform.APIGatewayConfig) (apiGatewayRoute, error) {
+	route := apiGatewayRoute{
+		config: apiGatewayConfig,
+	}
+
+	for _, upstream := range apiGatewayConfig.Spec.Upstreams {
+		containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+			Name: p.getFunctionContainerName(apiGatewayConfig.Meta.Namespace, upstream.NuclioFunction.Name),
+		})
+		if err != nil {
+			return route, errors.Wrap(err, "Failed to get function containers")
+		}
+
+		address := ""
+		if len(containers) > 0 {
+			address = getContainerHTTPAddress(&containers[0])
+		}
+
+		if address == "" {
+			p.Logger.WarnWith("API gateway upstream function has no running container",
+				"apiGateway", apiGatewayConfig.Meta.Name,
+				"function", upstream.NuclioFunction.Name)
+		}
+
+		route.upstreamAddresses = append(route.upstreamAddresses, address)
+	}
+
+	return route, nil
+}
+
+// applyAPIGatewayProxyConfig has a running proxy reload its configuration (verifying it first), or runs it
+func (p *Platform) applyAPIGatewayProxyConfig() error {
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name: apiGatewayProxyContainerName,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateway proxy container")
+	}
+
+	if len(containers) > 0 {
+		return p.dockerClient.ExecInContainer(apiGatewayProxyContainerName, &dockerclient.ExecOptions{
+			Command: fmt.Sprintf(`/bin/sh -c "nginx -t -c %s && nginx -s reload -c %s"`,
+				apiGatewayProxyConfigPath,
+				apiGatewayProxyConfigPath),
+		})
+	}
+
+	port := common.GetEnvOrDefaultString("NUCLIO_LOCAL_API_GATEWAY_PORT", defaultAPIGatewayProxyPort)
+	hostPort, err := strconv.Atoi(port)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid API gateway port: %s", port)
+	}
+
+	// remove a stopped proxy, so it can be run again
+	if err := p.removeAPIGatewayProxy(); err != nil {
+		return errors.Wrap(err, "Failed to remove stopped API gateway proxy")
+	}
+
+	p.Logger.InfoWith("Running API gateway proxy", "port", hostPort)
+
+	_, err = p.dockerClient.RunContainer(
+		common.GetEnvOrDefaultString("NUCLIO_LOCAL_API_GATEWAY_PROXY_IMAGE", defaultAPIGatewayProxyImage),
+		&dockerclient.RunOptions{
+			ContainerName:    apiGatewayProxyContainerName,
+			Ports:            map[int]int{hostPort: 8080},
+			ReadOnlyVolumes:  map[string]string{volumeName: baseDir},
+			Command:          fmt.Sprintf(`nginx -c %s -g "daemon off;"`, apiGatewayProxyConfigPath),
+			ImageMayNotExist: true,
+			Labels:           map[string]string{"nuclio.io/platform": "local"},
+			RestartPolicy: &dockerclient.RestartPolicy{
+				Name: dockerclient.RestartPolicyNameUnlessStopped,
+			},
+		})
+	if err != nil {
+		return errors.Wrap(err, "Failed to run API gateway proxy")
+	}
+
+	return nil
+}
+
+func (p *Platform) removeAPIGatewayProxy() error {
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name:    apiGatewayProxyContainerName,
+		Stopped: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get API gateway proxy container")
+	}
+
+	for _, container := range containers {
+		if err := p.dockerClient.RemoveContainer(container.ID); err != nil {
+			return errors.Wrap(err, "Failed to remove API gateway proxy container")
+		}
+	}
+
+	return nil
+}
+
+// writeAPIGatewayProxyFile writes a file the proxy reads to the store's volume, as is (unlike resources,
+// which are written encoded)
+func (p *Platform) writeAPIGatewayProxyFile(filePath string, contents []byte) error {
+	env := map[string]string{"NUCLIO_CONTENTS": base64.StdEncoding.EncodeToString(contents)}
+
+	_, _, err := p.localStore.runCommand(env,
+		`/bin/sh -c "mkdir -p %s && /bin/printenv NUCLIO_CONTENTS | /usr/bin/base64 -d > %s"`,
+		path.Dir(filePath),
+		filePath)
+
+	return err
+}
+
+// renderAPIGatewayProxyConfig renders the nginx configuration serving the routes, with a server per host.
+// the path of an API gateway is stripped from the requests passed to its functions
+func renderAPIGatewayProxyConfig(routes []apiGatewayRoute) string {
+	routesByHost := map[string][]apiGatewayRoute{}
+	for _, route := range routes {
+		routesByHost[route.config.Spec.Host] = append(routesByHost[route.config.Spec.Host], route)
+	}
+
+	var hosts []string
+	for host := range routesByHost {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	config := strings.Builder{}
+	config.WriteString(`worker_processes 1;
+error_log /dev/stderr warn;
+
+events {
+    worker_connections 1024;
+}
+
+http {
+    access_log /dev/stdout;
+    client_max_body_size 0;
+`)
+
+	// requests without a matching host are served by the API gateways without one, if any
+	if _, found := routesByHost[""]; !found {
+		config.WriteString(`
+    server {
+        listen 8080 default_server;
+        return 404;
+    }
+`)
+	}
+
+	canaryIndex := 0
+	for _, host := range hosts {
+		hostRoutes := routesByHost[host]
+
+		// regular expression locations are matched in order, so longer paths are matched first
+		sort.SliceStable(hostRoutes, func(i, j int) bool {
+			return len(normalizeAPIGatewayPath(hostRoutes[i].config.Spec.Path)) >
+				len(normalizeAPIGatewayPath(hostRoutes[j].config.Spec.Path))
+		})
+
+		// a canary's share of the requests is decided by a variable per API gateway, which must be
+		// defined outside the server
+		var locations strings.Builder
+		for _, route := range hostRoutes {
+			proxyPass := ""
+
+			switch {
+			case common.StringInSlice("", route.upstreamAddresses):
+				proxyPass = "return 503;"
+			case len(route.upstreamAddresses) == 1:
+				proxyPass = fmt.Sprintf("proxy_pass http://%s;", route.upstreamAddresses[0])
+			default:
+				canaryIndex++
+				canaryVariable := fmt.Sprintf("$nuclio_api_gateway_upstream_%d", canaryIndex)
+
+				fmt.Fprintf(&config, `
+    split_clients "${request_id}" %s {
+        %d%% "%s";
+        * "%s";
+    }
+`, canaryVariable, route.config.Spec.Upstreams[1].Percentage, route.upstreamAddresses[1], route.upstreamAddresses[0])
+
+				proxyPass = fmt.Sprintf("proxy_pass http://%s;", canaryVariable)
+			}
+
+			locations.WriteString(renderAPIGatewayProxyLocation(route.config, proxyPass))
+		}
+
+		serverName := host
+		listen := "listen 8080;"
+		if host == "" {
+			serverName = "_"
+			listen = "listen 8080 default_server;"
+		}
+
+		fmt.Fprintf(&config, `
+    server {
+        %s
+        server_name %s;
+%s    }
+`, listen, serverName, locations.String())
+	}
+
+	config.WriteString("}\n")
+
+	return config.String()
+}
+
+func renderAPIGatewayProxyLocation(apiGatewayConfig *platform.APIGatewayConfig, proxyPass string) string {
+	location := strings.Builder{}
+	apiGatewayPath := normalizeAPIGatewayPath(apiGatewayConfig.Spec.Path)
+
+	fmt.Fprintf(&location, "\n        # %s/%s\n", apiGatewayConfig.Meta.Namespace, apiGatewayConfig.Meta.Name)
+
+	if apiGatewayPath == "/" {
+		location.WriteString("        location / {\n")
+	} else {
+		quotedPath := regexp.QuoteMeta(apiGatewayPath)
+
+		fmt.Fprintf(&location, "        location ~ \"^%s(/.*)?$\" {\n", quotedPath)
+		fmt.Fprintf(&location, "            rewrite \"^%s/?(.*)$\" /$1 break;\n", quotedPath)
+	}
+
+	if apiGatewayConfig.Spec.AuthenticationMode == platform.APIGatewayAuthenticationModeBasicAuth {
+		fmt.Fprintf(&location, "            auth_basic \"%s\";\n", apiGatewayConfig.Meta.Name)
+		fmt.Fprintf(&location, "            auth_basic_user_file %s;\n", getAPIGatewayHtpasswdPath(apiGatewayConfig))
+
+		// the function doesn't need the credentials
+		location.WriteString("            proxy_set_header Authorization \"\";\n")
+	}
+
+	location.WriteString("            proxy_set_header Host $host;\n")
+	location.WriteString("            proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
+	fmt.Fprintf(&location, "            %s\n", proxyPass)
+	location.WriteString("        }\n")
+
+	return location.String()
+}
+
+func getAPIGatewayHtpasswdPath(apiGatewayConfig *platform.APIGatewayConfig) string {
+	return path.Join(apiGatewayProxyHtpasswdDir,
+		fmt.Sprintf("%s.%s", apiGatewayConfig.Meta.Namespace, apiGatewayConfig.Meta.Name))
+}
+
+// normalizeAPIGatewayPath returns the path with a single leading slash and no trailing one ("/" if empty)
+func normalizeAPIGatewayPath(apiGatewayPath string) string {
+	return "/" + strings.Trim(apiGatewayPath, "/")
+}
+
+// getContainerHTTPAddress returns the address at which other containers reach the function's HTTP port,
+// or an empty string if the container isn't running
+func getContainerHTTPAddress(container *dockerclient.Container) string {
+	if container.State != nil && !container.State.Running {
+		return ""
+	}
+
+	_, ipAddress := getContainerNetworkEndpoint(container)
+	if ipAddress == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:8080", ipAddress)
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type apiGatewayTestSuite struct {
+	suite.Suite
+	mockDockerClient *dockerclient.MockDockerClient
+	platform         *Platform
+}
+
+func (suite *apiGatewayTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient = dockerclient.NewMockDockerClient()
+	suite.platform = &Platform{
+		Platform:     &abstract.Platform{Logger: loggerInstance},
+		dockerClient: suite.mockDockerClient,
+	}
+}
+
+func (suite *apiGatewayTestSuite) TestResolveAPIGatewayRoute() {
+	apiGatewayConfig := suite.newAPIGatewayConfig("my-gateway", "", "/api", "primary", "canary")
+
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Name: "nuclio-nuclio-primary",
+	}).Return([]dockerclient.Container{
+		{
+			ID:    "primary-id",
+			State: &dockerclient.ContainerState{Running: true},
+			NetworkSettings: &dockerclient.NetworkSettings{
+				DefaultNetworkSettings: dockerclient.DefaultNetworkSettings{IPAddress: "172.17.0.2"},
+			},
+		},
+	}, nil).Once()
+
+	// the canary is on a user defined network
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Name: "nuclio-nuclio-canary",
+	}).Return([]dockerclient.Container{
+		{
+			ID:    "canary-id",
+			State: &dockerclient.ContainerState{Running: true},
+			NetworkSettings: &dockerclient.NetworkSettings{
+				Networks: map[string]*dockerclient.EndpointSettings{
+					"my-network": {IPAddress: "172.18.0.3"},
+				},
+			},
+		},
+	}, nil).Once()
+
+	route, err := suite.platform.resolveAPIGatewayRoute(apiGatewayConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"172.17.0.2:8080", "172.18.0.3:8080"}, route.upstreamAddresses)
+
+	// a function whose container isn't running has no address
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Name: "nuclio-nuclio-primary",
+	}).Return([]dockerclient.Container{
+		{
+			ID:    "primary-id",
+			State: &dockerclient.ContainerState{Running: false},
+		},
+	}, nil).Once()
+
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Name: "nuclio-nuclio-canary",
+	}).Return([]dockerclient.Container{}, nil).Once()
+
+	route, err = suite.platform.resolveAPIGatewayRoute(apiGatewayConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"", ""}, route.upstreamAddresses)
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *apiGatewayTestSuite) TestRenderAPIGatewayProxyConfig() {
+	basicAuthAPIGatewayConfig := suite.newAPIGatewayConfig("basic", "my-host.com", "", "primary")
+	basicAuthAPIGatewayConfig.Spec.AuthenticationMode = platform.APIGatewayAuthenticationModeBasicAuth
+	basicAuthAPIGatewayConfig.Spec.Authentication = &platform.APIGatewayAuthenticationSpec{
+		BasicAuth: &platform.APIGatewayBasicAuth{Username: "user", Password: "pass"},
+	}
+
+	config := renderAPIGatewayProxyConfig([]apiGatewayRoute{
+		{
+			config:            suite.newAPIGatewayConfig("short", "", "/api/", "primary"),
+			upstreamAddresses: []string{"172.17.0.2:8080"},
+		},
+		{
+			config:            suite.newAPIGatewayConfig("long", "", "/api/v2", "primary", "canary"),
+			upstreamAddresses: []string{"172.17.0.2:8080", "172.17.0.3:8080"},
+		},
+		{
+			config:            basicAuthAPIGatewayConfig,
+			upstreamAddresses: []string{"172.17.0.2:8080"},
+		},
+		{
+			config:            suite.newAPIGatewayConfig("unavailable", "", "/unavailable", "stopped"),
+			upstreamAddresses: []string{""},
+		},
+	})
+
+	// API gateways without a host are served by the default server, where longer paths are matched first
+	suite.Require().Contains(config, "listen 8080 default_server;\n        server_name _;")
+	suite.Require().NotContains(config, "return 404;")
+	suite.Require().Contains(config, `location ~ "^/api(/.*)?$" {`)
+	suite.Require().Contains(config, `rewrite "^/api/?(.*)$" /$1 break;`)
+	suite.Require().Less(suite.indexOf(config, "# nuclio/long"), suite.indexOf(config, "# nuclio/short"))
+	suite.Require().Contains(config, "proxy_pass http://172.17.0.2:8080;")
+
+	// the canary gets its percentage of the requests
+	suite.Require().Contains(config, `split_clients "${request_id}" $nuclio_api_gateway_upstream_1 {
+        10% "172.17.0.3:8080";
+        * "172.17.0.2:8080";
+    }`)
+	suite.Require().Contains(config, "proxy_pass http://$nuclio_api_gateway_upstream_1;")
+
+	// an API gateway with a host has a server of its own, requiring authentication
+	suite.Require().Contains(config, "listen 8080;\n        server_name my-host.com;")
+	suite.Require().Contains(config, "location / {")
+	suite.Require().Contains(config, `auth_basic "basic";`)
+	suite.Require().Contains(config, "auth_basic_user_file /etc/nuclio/store/api-gateway-proxy/htpasswd/nuclio.basic;")
+
+	// a function that isn't running is unavailable
+	suite.Require().Contains(config, "return 503;")
+
+	// without an API gateway for all hosts, requests for other hosts aren't found
+	config = renderAPIGatewayProxyConfig([]apiGatewayRoute{
+		{
+			config:            basicAuthAPIGatewayConfig,
+			upstreamAddresses: []string{"172.17.0.2:8080"},
+		},
+	})
+
+	suite.Require().Contains(config, "listen 8080 default_server;\n        return 404;")
+}
+
+func (suite *apiGatewayTestSuite) newAPIGatewayConfig(name string,
+	host string,
+	path string,
+	functionNames ...string) *platform.APIGatewayConfig {
+
+	apiGatewayConfig := &platform.APIGatewayConfig{
+		Meta: platform.APIGatewayMeta{
+			Name:      name,
+			Namespace: "nuclio",
+		},
+		Spec: platform.APIGatewaySpec{
+			Host:               host,
+			Path:               path,
+			AuthenticationMode: platform.APIGatewayAuthenticationModeNone,
+		},
+	}
+
+	for functionIndex, functionName := range functionNames {
+		upstream := platform.APIGatewayUpstreamSpec{
+			Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+			NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: functionName},
+		}
+
+		if functionIndex > 0 {
+			upstream.Percentage = 10
+		}
+
+		apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams, upstream)
+	}
+
+	return apiGatewayConfig
+}
+
+func (suite *apiGatewayTestSuite) indexOf(config string, substring string) int {
+	index := strings.Index(config, substring)
+	suite.Require().NotEqual(-1, index)
+
+	return index
+}
+
+func TestAPIGatewayTestSuite(t *testing.T) {
+	suite.Run(t, new(apiGatewayTestSuite))
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+)
+
+// CreateAuditRecord adds the record to the audit records of its namespace, as kept in the local store
+func (p *Platform) CreateAuditRecord(auditRecord *platform.AuditRecord) error {
+	storedAuditRecord := *auditRecord
+	storedAuditRecord.Namespace = p.ResolveDefaultNamespace(auditRecord.Namespace)
+
+	return p.localStore.addAuditRecord(&storedAuditRecord)
+}
+
+// GetAuditRecords returns the audit records of the namespace, oldest first, as kept in the local store
+func (p *Platform) GetAuditRecords(getAuditRecordsOptions *platform.GetAuditRecordsOptions) (
+	[]platform.AuditRecord, error) {
+
+	records, err := p.localStore.getAuditRecords(p.ResolveDefaultNamespace(getAuditRecordsOptions.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get audit records")
+	}
+
+	return platform.FilterAuditRecords(records, getAuditRecordsOptions.Name, getAuditRecordsOptions.Since), nil
+}
@@ -0,0 +1,460 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// the CPU percentage per replica the autoscaler aims for, unless the function specifies a target
+const defaultAutoscalerTargetCPU = 75
+
+// the label of the containers the autoscaler adds to a function, holding their index
+const functionReplicaLabel = "nuclio.io/function-replica"
+
+// how long a replica the autoscaler adds has to become ready before it's removed
+const replicaReadinessTimeout = 60 * time.Second
+
+type functionReplica struct {
+	containerID string
+	port        int
+}
+
+// scalableFunction is a function the autoscaler scales. its first replica is the function's own container,
+// the rest are added and removed by the autoscaler
+type scalableFunction struct {
+	namespace   string
+	name        string
+	image       string
+	runOptions  dockerclient.RunOptions
+	minReplicas int
+	maxReplicas int
+	targetCPU   int
+	warmup      *functionconfig.Warmup
+	replicas    []functionReplica
+	proxy       *functionProxy
+
+	// a paused function's container is stopped, and isn't scaled until it's resumed
+	paused bool
+}
+
+// autoscaler scales functions between their minimum and maximum replicas by the CPU their replicas consume,
+// scaling down to the minimum when they got no events. each function's HTTP port is served by a proxy, which
+// distributes the events between the replicas
+type autoscaler struct {
+	logger       logger.Logger
+	dockerClient dockerclient.Client
+	interval     time.Duration
+	lock         sync.Mutex
+	functions    map[string]*scalableFunction
+
+	// overridden by tests
+	getFreeLocalPort func() (int, error)
+	awaitReadiness   func(containerID string) error
+}
+
+func newAutoscaler(parentLogger logger.Logger,
+	dockerClient dockerclient.Client,
+	interval time.Duration,
+	getFreeLocalPort func() (int, error)) *autoscaler {
+	newAutoscaler := &autoscaler{
+		logger:           parentLogger.GetChild("autoscaler"),
+		dockerClient:     dockerClient,
+		interval:         interval,
+		functions:        map[string]*scalableFunction{},
+		getFreeLocalPort: getFreeLocalPort,
+	}
+
+	newAutoscaler.awaitReadiness = newAutoscaler.awaitContainerHealth
+
+	return newAutoscaler
+}
+
+// getFunctionReplicaBounds returns the replicas a function is scaled between. a function with a fixed number
+// of replicas isn't scaled, but is still served by a proxy
+func getFunctionReplicaBounds(spec *functionconfig.Spec) (int, int) {
+	if spec.Replicas != nil {
+		return *spec.Replicas, *spec.Replicas
+	}
+
+	minReplicas := 1
+	if spec.MinReplicas != nil && *spec.MinReplicas > 1 {
+		minReplicas = *spec.MinReplicas
+	}
+
+	maxReplicas := minReplicas
+	if spec.MaxReplicas != nil && *spec.MaxReplicas > maxReplicas {
+		maxReplicas = *spec.MaxReplicas
+	}
+
+	return minReplicas, maxReplicas
+}
+
+// start autoscales the registered functions every interval, forever
+func (a *autoscaler) start() {
+	a.logger.DebugWith("Starting autoscaler", "interval", a.interval)
+
+	go func() {
+		for range time.NewTicker(a.interval).C {
+			a.autoscale()
+		}
+	}()
+}
+
+// registerFunction starts serving the function's HTTP port with a proxy to its replicas - the function's
+// container, published on the given port, and the replicas added to reach the function's minimum
+func (a *autoscaler) registerFunction(functionConfig *functionconfig.Config,
+	httpPort int,
+	containerID string,
+	containerPort int,
+	runOptions *dockerclient.RunOptions) error {
+	proxy, err := newFunctionProxy(a.logger, httpPort)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create function proxy")
+	}
+
+	function := &scalableFunction{
+		namespace: functionConfig.Meta.Namespace,
+		name:      functionConfig.Meta.Name,
+		replicas:  []functionReplica{{containerID: containerID, port: containerPort}},
+		proxy:     proxy,
+	}
+
+	a.setFunctionSpec(function, functionConfig, runOptions)
+
+	function.proxy.setReplicaAddresses(a.getReplicaAddresses(function))
+	function.proxy.start()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.functions[a.getFunctionKey(function.namespace, function.name)] = function
+
+	a.logger.InfoWith("Function registered for autoscaling",
+		"namespace", function.namespace,
+		"name", function.name,
+		"port", httpPort,
+		"minReplicas", function.minReplicas,
+		"maxReplicas", function.maxReplicas,
+		"targetCPU", function.targetCPU)
+
+	return a.scaleTo(function, function.minReplicas)
+}
+
+// replaceFunction switches the proxy of a registered function to a new deployment of it - the given container,
+// published on the given port, and the replicas added to reach the function's minimum. the previous deployment's
+// added replicas are removed, but not its own container. once the proxy is switched, failing to scale is left
+// for the next scaling decision to correct
+func (a *autoscaler) replaceFunction(functionConfig *functionconfig.Config,
+	containerID string,
+	containerPort int,
+	runOptions *dockerclient.RunOptions) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	function, found := a.functions[a.getFunctionKey(functionConfig.Meta.Namespace, functionConfig.Meta.Name)]
+	if !found {
+		return errors.Errorf("Function %s isn't registered", functionConfig.Meta.Name)
+	}
+
+	previousReplicas := function.replicas
+
+	// send the events to the new container before removing the previous replicas, whose names the new
+	// deployment's replicas take
+	function.replicas = []functionReplica{{containerID: containerID, port: containerPort}}
+	function.proxy.setReplicaAddresses(a.getReplicaAddresses(function))
+
+	for _, previousReplica := range previousReplicas[1:] {
+		if err := a.dockerClient.RemoveContainer(previousReplica.containerID); err != nil {
+			a.logger.WarnWith("Failed to remove previous replica container",
+				"containerID", previousReplica.containerID,
+				"err", err.Error())
+		}
+	}
+
+	a.setFunctionSpec(function, functionConfig, runOptions)
+
+	a.logger.InfoWith("Function replaced",
+		"namespace", function.namespace,
+		"name", function.name,
+		"containerID", containerID,
+		"minReplicas", function.minReplicas,
+		"maxReplicas", function.maxReplicas)
+
+	if err := a.scaleTo(function, function.minReplicas); err != nil {
+		a.logger.WarnWith("Failed to scale replaced function", "name", function.name, "err", err.Error())
+	}
+
+	return nil
+}
+
+// unregisterFunction stops the function's proxy and removes the replicas the autoscaler added. it returns
+// the port the proxy served, or 0 if the function wasn't registered
+func (a *autoscaler) unregisterFunction(namespace string, name string) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	functionKey := a.getFunctionKey(namespace, name)
+
+	function, found := a.functions[functionKey]
+	if !found {
+		return 0, nil
+	}
+
+	delete(a.functions, functionKey)
+
+	httpPort := function.proxy.getPort()
+	if err := function.proxy.stop(); err != nil {
+		a.logger.WarnWith("Failed to stop function proxy", "name", name, "err", err.Error())
+	}
+
+	// the function's own container is removed along with the function
+	if err := a.scaleTo(function, 1); err != nil {
+		return httpPort, errors.Wrap(err, "Failed to remove function replicas")
+	}
+
+	return httpPort, nil
+}
+
+// setFunctionPaused stops (or resumes) scaling the function. a paused function is scaled down to its own
+// container, which is stopped by the caller
+func (a *autoscaler) setFunctionPaused(namespace string, name string, paused bool) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	function, found := a.functions[a.getFunctionKey(namespace, name)]
+	if !found {
+		return nil
+	}
+
+	function.paused = paused
+
+	if paused {
+		if err := a.scaleTo(function, 1); err != nil {
+			return errors.Wrap(err, "Failed to remove function replicas")
+		}
+	}
+
+	return nil
+}
+
+// getProxyPort returns the port the function's proxy serves, or 0 if the function isn't registered
+func (a *autoscaler) getProxyPort(namespace string, name string) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	function, found := a.functions[a.getFunctionKey(namespace, name)]
+	if !found {
+		return 0
+	}
+
+	return function.proxy.getPort()
+}
+
+func (a *autoscaler) autoscale() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, function := range a.functions {
+		if function.paused {
+			continue
+		}
+
+		desiredReplicas, err := a.getDesiredReplicas(function)
+		if err != nil {
+			a.logger.WarnWith("Failed to get function's desired replicas", "name", function.name, "err", err.Error())
+			continue
+		}
+
+		if desiredReplicas == len(function.replicas) {
+			continue
+		}
+
+		a.logger.InfoWith("Scaling function",
+			"namespace", function.namespace,
+			"name", function.name,
+			"from", len(function.replicas),
+			"to", desiredReplicas)
+
+		if err := a.scaleTo(function, desiredReplicas); err != nil {
+			a.logger.WarnWith("Failed to scale function", "name", function.name, "err", err.Error())
+		}
+	}
+}
+
+// getDesiredReplicas returns the replicas that would bring the function's average CPU to its target. a function
+// which got no events since the last time is scaled down to its minimum
+func (a *autoscaler) getDesiredReplicas(function *scalableFunction) (int, error) {
+	var totalEvents int
+	for _, eventCount := range function.proxy.takeEventCounts() {
+		totalEvents += eventCount
+	}
+
+	if totalEvents == 0 {
+		return function.minReplicas, nil
+	}
+
+	var containerIDs []string
+	for _, replica := range function.replicas {
+		containerIDs = append(containerIDs, replica.containerID)
+	}
+
+	containerStats, err := a.dockerClient.GetContainerStats(containerIDs)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to get container stats")
+	}
+
+	var totalCPUPercent float64
+	for _, containerID := range containerIDs {
+		totalCPUPercent += containerStats[containerID].CPUPercent
+	}
+
+	averageCPUPercent := totalCPUPercent / float64(len(containerIDs))
+	desiredReplicas := int(math.Ceil(float64(len(containerIDs)) * averageCPUPercent / float64(function.targetCPU)))
+
+	if desiredReplicas < function.minReplicas {
+		desiredReplicas = function.minReplicas
+	}
+
+	if desiredReplicas > function.maxReplicas {
+		desiredReplicas = function.maxReplicas
+	}
+
+	return desiredReplicas, nil
+}
+
+// scaleTo adds or removes replicas until the function has the given number of replicas, never removing the
+// function's own container
+func (a *autoscaler) scaleTo(function *scalableFunction, replicas int) error {
+	defer func() {
+		function.proxy.setReplicaAddresses(a.getReplicaAddresses(function))
+	}()
+
+	for len(function.replicas) > replicas && len(function.replicas) > 1 {
+		lastReplica := function.replicas[len(function.replicas)-1]
+
+		// stop sending events to the replica before removing it
+		function.replicas = function.replicas[:len(function.replicas)-1]
+		function.proxy.setReplicaAddresses(a.getReplicaAddresses(function))
+
+		if err := a.dockerClient.RemoveContainer(lastReplica.containerID); err != nil {
+			return errors.Wrapf(err, "Failed to remove replica container %s", lastReplica.containerID)
+		}
+	}
+
+	for len(function.replicas) < replicas {
+		replica, err := a.runReplica(function, len(function.replicas))
+		if err != nil {
+			return errors.Wrap(err, "Failed to run replica")
+		}
+
+		function.replicas = append(function.replicas, *replica)
+	}
+
+	return nil
+}
+
+func (a *autoscaler) runReplica(function *scalableFunction, index int) (*functionReplica, error) {
+	port, err := a.getFreeLocalPort()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get free local port")
+	}
+
+	labels := map[string]string{}
+	for labelName, labelValue := range function.runOptions.Labels {
+		labels[labelName] = labelValue
+	}
+
+	labels[functionReplicaLabel] = strconv.Itoa(index)
+
+	runOptions := function.runOptions
+	runOptions.ContainerName = fmt.Sprintf("%s-replica-%d", function.runOptions.ContainerName, index)
+	runOptions.Ports = map[int]int{port: 8080}
+	runOptions.Labels = labels
+
+	containerID, err := a.dockerClient.RunContainer(function.image, &runOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to run replica container")
+	}
+
+	if err := a.awaitReadiness(containerID); err != nil {
+
+		// a replica that isn't ready would fail the events sent to it
+		a.dockerClient.RemoveContainer(containerID) // nolint: errcheck
+		return nil, errors.Wrap(err, "Replica wasn't ready in time")
+	}
+
+	// warm the replica up before the proxy sends it events
+	if function.warmup != nil {
+		abstract.WarmUpFunction(a.logger, runOptions.ContainerName, a.getReplicaAddress(port), function.warmup)
+	}
+
+	a.logger.DebugWith("Replica is ready",
+		"name", function.name,
+		"containerName", runOptions.ContainerName,
+		"port", port)
+
+	return &functionReplica{containerID: containerID, port: port}, nil
+}
+
+func (a *autoscaler) awaitContainerHealth(containerID string) error {
+	readinessTimeout := replicaReadinessTimeout
+	return a.dockerClient.AwaitContainerHealth(containerID, &readinessTimeout)
+}
+
+func (a *autoscaler) getReplicaAddresses(function *scalableFunction) []string {
+	var replicaAddresses []string
+	for _, replica := range function.replicas {
+		replicaAddresses = append(replicaAddresses, a.getReplicaAddress(replica.port))
+	}
+
+	return replicaAddresses
+}
+
+func (a *autoscaler) getReplicaAddress(port int) string {
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+func (a *autoscaler) setFunctionSpec(function *scalableFunction,
+	functionConfig *functionconfig.Config,
+	runOptions *dockerclient.RunOptions) {
+	function.image = functionConfig.Spec.Image
+	function.runOptions = *runOptions
+	function.minReplicas, function.maxReplicas = getFunctionReplicaBounds(&functionConfig.Spec)
+
+	function.warmup = functionConfig.Spec.Warmup
+
+	function.targetCPU = functionConfig.Spec.TargetCPU
+	if function.targetCPU <= 0 {
+		function.targetCPU = defaultAutoscalerTargetCPU
+	}
+}
+
+func (a *autoscaler) getFunctionKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
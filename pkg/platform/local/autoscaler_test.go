@@ -0,0 +1,355 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type autoscalerTestSuite struct {
+	suite.Suite
+	mockDockerClient *dockerclient.MockDockerClient
+	autoscaler       *autoscaler
+
+	// the replicas are served by test servers, whose ports are handed out as the replicas' free ports
+	replicaServers  []*httptest.Server
+	replicaRequests map[int]int
+	freePorts       []int
+}
+
+func (suite *autoscalerTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.replicaRequests = map[int]int{}
+	suite.replicaServers = nil
+	suite.freePorts = nil
+
+	for replicaIndex := 0; replicaIndex < 4; replicaIndex++ {
+		replicaIndex := replicaIndex
+
+		replicaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			suite.replicaRequests[replicaIndex]++
+		}))
+
+		suite.replicaServers = append(suite.replicaServers, replicaServer)
+		suite.freePorts = append(suite.freePorts, suite.getServerPort(replicaServer))
+	}
+
+	suite.mockDockerClient = dockerclient.NewMockDockerClient()
+	suite.autoscaler = newAutoscaler(loggerInstance, suite.mockDockerClient, time.Hour, suite.getFreeLocalPort)
+	suite.autoscaler.awaitReadiness = func(containerID string) error {
+		return nil
+	}
+}
+
+func (suite *autoscalerTestSuite) TearDownTest() {
+	for _, replicaServer := range suite.replicaServers {
+		replicaServer.Close()
+	}
+}
+
+func (suite *autoscalerTestSuite) TestGetFunctionReplicaBounds() {
+	one, two, four := 1, 2, 4
+
+	for _, testCase := range []struct {
+		name                string
+		spec                functionconfig.Spec
+		expectedMinReplicas int
+		expectedMaxReplicas int
+	}{
+		{name: "none", spec: functionconfig.Spec{}, expectedMinReplicas: 1, expectedMaxReplicas: 1},
+		{name: "max", spec: functionconfig.Spec{MaxReplicas: &four}, expectedMinReplicas: 1, expectedMaxReplicas: 4},
+		{name: "minAndMax", spec: functionconfig.Spec{MinReplicas: &two, MaxReplicas: &four}, expectedMinReplicas: 2, expectedMaxReplicas: 4},
+		{name: "maxBelowMin", spec: functionconfig.Spec{MinReplicas: &two, MaxReplicas: &one}, expectedMinReplicas: 2, expectedMaxReplicas: 2},
+		{name: "fixed", spec: functionconfig.Spec{Replicas: &two, MaxReplicas: &four}, expectedMinReplicas: 2, expectedMaxReplicas: 2},
+	} {
+		suite.Run(testCase.name, func() {
+			minReplicas, maxReplicas := getFunctionReplicaBounds(&testCase.spec)
+			suite.Require().Equal(testCase.expectedMinReplicas, minReplicas)
+			suite.Require().Equal(testCase.expectedMaxReplicas, maxReplicas)
+		})
+	}
+}
+
+func (suite *autoscalerTestSuite) TestAutoscale() {
+	two, three := 2, 3
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{Name: "scaled", Namespace: "nuclio"},
+		Spec: functionconfig.Spec{
+			Image:       "scaled:latest",
+			MinReplicas: &two,
+			MaxReplicas: &three,
+			TargetCPU:   50,
+		},
+	}
+
+	runOptions := &dockerclient.RunOptions{
+		ContainerName: "nuclio-nuclio-scaled",
+		Labels:        map[string]string{"nuclio.io/function-name": "scaled"},
+	}
+
+	// the function's own container is served by the first test server, registering adds a replica up to the minimum
+	suite.expectReplicaRun(1)
+
+	proxyPort := suite.getFreeProxyPort()
+	err := suite.autoscaler.registerFunction(&functionConfig,
+		proxyPort,
+		"function-id",
+		suite.takeFreePort(),
+		runOptions)
+	suite.Require().NoError(err)
+	suite.Require().Equal(proxyPort, suite.autoscaler.getProxyPort("nuclio", "scaled"))
+
+	// the proxy distributes the requests between the replicas
+	suite.sendRequests(proxyPort, 4)
+	suite.Require().Equal(map[int]int{0: 2, 1: 2}, suite.replicaRequests)
+
+	// the replicas are busy, so the function is scaled up to its maximum
+	suite.mockDockerClient.On("GetContainerStats", []string{"function-id", "replica-1-id"}).
+		Return(map[string]dockerclient.ContainerStats{
+			"function-id":  {CPUPercent: 80},
+			"replica-1-id": {CPUPercent: 90},
+		}, nil).
+		Once()
+
+	suite.expectReplicaRun(2)
+	suite.autoscaler.autoscale()
+
+	// the added replica gets its share of the requests
+	suite.sendRequests(proxyPort, 3)
+	suite.Require().Equal(map[int]int{0: 3, 1: 3, 2: 1}, suite.replicaRequests)
+
+	// the replicas are idle, so the function is scaled down to its minimum
+	suite.mockDockerClient.On("GetContainerStats", []string{"function-id", "replica-1-id", "replica-2-id"}).
+		Return(map[string]dockerclient.ContainerStats{
+			"function-id":  {CPUPercent: 10},
+			"replica-1-id": {CPUPercent: 5},
+			"replica-2-id": {CPUPercent: 5},
+		}, nil).
+		Once()
+
+	suite.mockDockerClient.On("RemoveContainer", "replica-2-id").Return(nil).Once()
+	suite.autoscaler.autoscale()
+
+	// no events since the last time, so the function stays at its minimum without consulting its stats
+	suite.autoscaler.autoscale()
+
+	// unregistering removes the replicas the autoscaler added, but not the function's container
+	suite.mockDockerClient.On("RemoveContainer", "replica-1-id").Return(nil).Once()
+
+	unregisteredProxyPort, err := suite.autoscaler.unregisterFunction("nuclio", "scaled")
+	suite.Require().NoError(err)
+	suite.Require().Equal(proxyPort, unregisteredProxyPort)
+	suite.Require().Zero(suite.autoscaler.getProxyPort("nuclio", "scaled"))
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *autoscalerTestSuite) TestReplaceFunction() {
+	two, three := 2, 3
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{Name: "scaled", Namespace: "nuclio"},
+		Spec: functionconfig.Spec{
+			Image:       "scaled:latest",
+			MinReplicas: &two,
+			MaxReplicas: &three,
+		},
+	}
+
+	runOptions := &dockerclient.RunOptions{
+		ContainerName: "nuclio-nuclio-scaled",
+		Labels:        map[string]string{"nuclio.io/function-name": "scaled"},
+	}
+
+	suite.expectReplicaRun(1)
+
+	proxyPort := suite.getFreeProxyPort()
+	err := suite.autoscaler.registerFunction(&functionConfig,
+		proxyPort,
+		"function-id",
+		suite.takeFreePort(),
+		runOptions)
+	suite.Require().NoError(err)
+
+	// the new deployment's container is served by the third test server. the previous deployment's replica
+	// is removed and a new one is added in its place
+	suite.mockDockerClient.On("RemoveContainer", "replica-1-id").Return(nil).Once()
+	suite.expectReplicaRun(1)
+
+	err = suite.autoscaler.replaceFunction(&functionConfig, "next-function-id", suite.takeFreePort(), runOptions)
+	suite.Require().NoError(err)
+
+	// the proxy keeps serving the same port, only sending the requests to the new deployment
+	suite.Require().Equal(proxyPort, suite.autoscaler.getProxyPort("nuclio", "scaled"))
+
+	suite.sendRequests(proxyPort, 4)
+	suite.Require().Equal(map[int]int{2: 2, 3: 2}, suite.replicaRequests)
+
+	// replacing an unregistered function fails
+	functionConfig.Meta.Name = "unregistered"
+	err = suite.autoscaler.replaceFunction(&functionConfig, "other-id", 0, runOptions)
+	suite.Require().Error(err)
+
+	suite.mockDockerClient.On("RemoveContainer", "replica-1-id").Return(nil).Once()
+	_, err = suite.autoscaler.unregisterFunction("nuclio", "scaled")
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *autoscalerTestSuite) TestPauseFunction() {
+	two := 2
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{Name: "scaled", Namespace: "nuclio"},
+		Spec: functionconfig.Spec{
+			Image:       "scaled:latest",
+			MinReplicas: &two,
+		},
+	}
+
+	runOptions := &dockerclient.RunOptions{
+		ContainerName: "nuclio-nuclio-scaled",
+		Labels:        map[string]string{"nuclio.io/function-name": "scaled"},
+	}
+
+	suite.expectReplicaRun(1)
+
+	err := suite.autoscaler.registerFunction(&functionConfig,
+		suite.getFreeProxyPort(),
+		"function-id",
+		suite.takeFreePort(),
+		runOptions)
+	suite.Require().NoError(err)
+
+	// pausing removes the added replica, leaving the function's own container to be stopped
+	suite.mockDockerClient.On("RemoveContainer", "replica-1-id").Return(nil).Once()
+
+	err = suite.autoscaler.setFunctionPaused("nuclio", "scaled", true)
+	suite.Require().NoError(err)
+
+	// a paused function isn't scaled back up to its minimum
+	suite.autoscaler.autoscale()
+	suite.mockDockerClient.AssertExpectations(suite.T())
+
+	// once resumed, it is
+	err = suite.autoscaler.setFunctionPaused("nuclio", "scaled", false)
+	suite.Require().NoError(err)
+
+	suite.expectReplicaRun(1)
+	suite.autoscaler.autoscale()
+
+	// pausing an unregistered function does nothing
+	err = suite.autoscaler.setFunctionPaused("nuclio", "unregistered", true)
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient.On("RemoveContainer", "replica-1-id").Return(nil).Once()
+	_, err = suite.autoscaler.unregisterFunction("nuclio", "scaled")
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *autoscalerTestSuite) TestGetDesiredReplicasWithinBounds() {
+	function := &scalableFunction{
+		minReplicas: 1,
+		maxReplicas: 5,
+		targetCPU:   75,
+		replicas:    []functionReplica{{containerID: "a"}, {containerID: "b"}},
+		proxy:       &functionProxy{eventCounts: map[string]int{"127.0.0.1:1": 10}},
+	}
+
+	suite.mockDockerClient.On("GetContainerStats", []string{"a", "b"}).
+		Return(map[string]dockerclient.ContainerStats{
+			"a": {CPUPercent: 30},
+			"b": {CPUPercent: 40},
+		}, nil).
+		Once()
+
+	// two replicas at an average of 35% need one replica to reach 75%
+	desiredReplicas, err := suite.autoscaler.getDesiredReplicas(function)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, desiredReplicas)
+}
+
+func (suite *autoscalerTestSuite) sendRequests(port int, count int) {
+	for requestIndex := 0; requestIndex < count; requestIndex++ {
+		response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+		suite.Require().NoError(err)
+		response.Body.Close() // nolint: errcheck
+	}
+}
+
+func (suite *autoscalerTestSuite) expectReplicaRun(index int) {
+	suite.mockDockerClient.On("RunContainer", "scaled:latest", mock.MatchedBy(func(runOptions *dockerclient.RunOptions) bool {
+		return runOptions.ContainerName == fmt.Sprintf("nuclio-nuclio-scaled-replica-%d", index) &&
+			runOptions.Labels[functionReplicaLabel] == strconv.Itoa(index) &&
+			runOptions.Labels["nuclio.io/function-name"] == "scaled"
+	})).Return(fmt.Sprintf("replica-%d-id", index), nil).Once()
+}
+
+func (suite *autoscalerTestSuite) getFreeLocalPort() (int, error) {
+	return suite.takeFreePort(), nil
+}
+
+func (suite *autoscalerTestSuite) takeFreePort() int {
+	suite.Require().NotEmpty(suite.freePorts)
+
+	freePort := suite.freePorts[0]
+	suite.freePorts = suite.freePorts[1:]
+
+	return freePort
+}
+
+func (suite *autoscalerTestSuite) getFreeProxyPort() int {
+	platform := &Platform{}
+
+	proxyPort, err := platform.getFreeLocalPort()
+	suite.Require().NoError(err)
+
+	return proxyPort
+}
+
+func (suite *autoscalerTestSuite) getServerPort(server *httptest.Server) int {
+	serverURL, err := url.Parse(server.URL)
+	suite.Require().NoError(err)
+
+	port, err := strconv.Atoi(serverURL.Port())
+	suite.Require().NoError(err)
+
+	return port
+}
+
+func TestAutoscalerTestSuite(t *testing.T) {
+	suite.Run(t, new(autoscalerTestSuite))
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+)
+
+// getContainerOS returns the operating system of the containers docker runs (linux or windows) - a daemon
+// running windows containers runs functions built for windows, whose paths and tools differ. it's read from
+// the daemon once
+func (p *Platform) getContainerOS() string {
+	p.containerOSOnce.Do(func() {
+		containerOS, err := p.dockerClient.GetServerOS()
+		if err != nil || containerOS == "" {
+			p.Logger.WarnWith("Failed to get the operating system of docker's containers, assuming linux",
+				"err", err)
+
+			containerOS = functionconfig.BuildOSLinux
+		}
+
+		p.containerOS = containerOS
+	})
+
+	return p.containerOS
+}
+
+// getContainerHTTPGetCommand returns the command requesting the URL from within a function's container, which
+// fails unless the response is successful. linux images have uhttpc, and windows images curl
+func (p *Platform) getContainerHTTPGetCommand(url string) string {
+	if p.getContainerOS() == functionconfig.BuildOSWindows {
+		return fmt.Sprintf("curl.exe --silent --fail --url '%s'", url)
+	}
+
+	return fmt.Sprintf("/usr/local/bin/uhttpc --url '%s'", url)
+}
+
+// getContainerPath returns the path in a function's container of the given absolute path. windows containers
+// are mounted at paths of their drive (e.g. /etc/nuclio is C:\etc\nuclio)
+func (p *Platform) getContainerPath(containerPath string) string {
+	if p.getContainerOS() != functionconfig.BuildOSWindows || !strings.HasPrefix(containerPath, "/") {
+		return containerPath
+	}
+
+	return `C:` + strings.Replace(path.Clean(containerPath), "/", `\`, -1)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type containerOSTestSuite struct {
+	suite.Suite
+	platform *Platform
+}
+
+func (suite *containerOSTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.platform = &Platform{
+		Platform:     &abstract.Platform{Logger: loggerInstance},
+		dockerClient: dockerclient.NewMockDockerClient(),
+	}
+}
+
+func (suite *containerOSTestSuite) TestLinuxContainers() {
+	suite.Require().Equal("/etc/nuclio/cron", suite.platform.getContainerPath("/etc/nuclio/cron"))
+	suite.Require().Equal("/usr/local/bin/uhttpc --url 'http://127.0.0.1:8081/statistics'",
+		suite.platform.getContainerHTTPGetCommand("http://127.0.0.1:8081/statistics"))
+}
+
+func (suite *containerOSTestSuite) TestWindowsContainers() {
+	suite.platform.containerOSOnce.Do(func() {
+		suite.platform.containerOS = functionconfig.BuildOSWindows
+	})
+
+	suite.Require().Equal(`C:\etc\nuclio\cron`, suite.platform.getContainerPath("/etc/nuclio/cron/"))
+	suite.Require().Equal(`D:\data`, suite.platform.getContainerPath(`D:\data`))
+	suite.Require().Equal("curl.exe --silent --fail --url 'http://127.0.0.1:8081/statistics'",
+		suite.platform.getContainerHTTPGetCommand("http://127.0.0.1:8081/statistics"))
+
+	// the security context is ignored, as windows containers have no equivalent
+	runAsUser := int64(1000)
+	runOptions := &dockerclient.RunOptions{}
+	suite.platform.populateSecurityRunOptions(runOptions, &functionconfig.SecurityContext{
+		RunAsUser:              &runAsUser,
+		ReadOnlyRootFilesystem: true,
+	})
+
+	suite.Require().Equal(&dockerclient.RunOptions{}, runOptions)
+}
+
+func TestContainerOSTestSuite(t *testing.T) {
+	suite.Run(t, new(containerOSTestSuite))
+}
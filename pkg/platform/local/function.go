@@ -63,6 +63,26 @@ func (f *function) GetInvokeURL(invokeViaType platform.InvokeViaType) (string, e
 	return fmt.Sprintf("%s:%d", host, port), nil
 }
 
+// GetGRPCInvokeURL returns the address of the host port on which the port of the function's gRPC trigger is published
+func (f *function) GetGRPCInvokeURL(invokeViaType platform.InvokeViaType, grpcPort int) (string, error) {
+	grpcPort, err := f.ResolveGRPCPort(grpcPort)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve gRPC port")
+	}
+
+	localPlatform, isLocalPlatform := f.Platform.(*Platform)
+	if !isLocalPlatform {
+		return "", errors.New("Unsupported")
+	}
+
+	return localPlatform.resolveFunctionInvokeURL(&platform.CreateFunctionInvocationOptions{
+		Name:      f.Config.Meta.Name,
+		Namespace: f.Config.Meta.Namespace,
+		Port:      grpcPort,
+		Via:       invokeViaType,
+	})
+}
+
 // GetIngresses returns all ingresses for this function
 func (f *function) GetIngresses() map[string]functionconfig.Ingress {
 
@@ -0,0 +1,151 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// GetFunctionInvocations gathers the invocations the invocation inspectors of the function's containers retain,
+// adds them to those kept in the local store and returns them. the invocations a container retained are lost
+// if it restarts before they're gathered
+func (p *Platform) GetFunctionInvocations(getFunctionInvocationsOptions *platform.GetFunctionInvocationsOptions) (
+	[]platform.FunctionInvocation, error) {
+
+	functionMeta := &functionconfig.Meta{
+		Name:      getFunctionInvocationsOptions.Name,
+		Namespace: getFunctionInvocationsOptions.Namespace,
+	}
+
+	function, err := p.getLocalStoreFunction(functionMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	maxInvocations := functionconfig.DefaultInvocationInspectorMaxInvocations
+	if invocationInspector := function.GetConfig().Spec.InvocationInspector; invocationInspector != nil {
+		maxInvocations = invocationInspector.GetMaxInvocations()
+	}
+
+	containersInvocations, err := p.getContainersInvocations(functionMeta.Namespace, functionMeta.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function container invocations")
+	}
+
+	var invocations []platform.FunctionInvocation
+	if err := p.localStore.updateFunctionInvocations(functionMeta,
+		func(functionInvocations *platform.FunctionInvocations) {
+			functionInvocations.Add(containersInvocations, maxInvocations)
+			invocations = functionInvocations.Invocations
+		}); err != nil {
+		return nil, errors.Wrap(err, "Failed to update function invocations")
+	}
+
+	return platform.FilterFunctionInvocations(invocations,
+		getFunctionInvocationsOptions.Since,
+		getFunctionInvocationsOptions.FailedOnly), nil
+}
+
+// DeleteFunctionInvocations purges the invocations kept in the local store. those the containers still retain
+// aren't gathered again
+func (p *Platform) DeleteFunctionInvocations(deleteFunctionInvocationsOptions *platform.DeleteFunctionInvocationsOptions) error {
+	functionMeta := &functionconfig.Meta{
+		Name:      deleteFunctionInvocationsOptions.Name,
+		Namespace: deleteFunctionInvocationsOptions.Namespace,
+	}
+
+	if _, err := p.getLocalStoreFunction(functionMeta); err != nil {
+		return err
+	}
+
+	if err := p.localStore.updateFunctionInvocations(functionMeta,
+		func(functionInvocations *platform.FunctionInvocations) {
+			functionInvocations.Purge()
+		}); err != nil {
+		return errors.Wrap(err, "Failed to purge function invocations")
+	}
+
+	return nil
+}
+
+func (p *Platform) getLocalStoreFunction(functionMeta *functionconfig.Meta) (platform.Function, error) {
+	functions, err := p.localStore.getFunctions(functionMeta)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read functions from local store")
+	}
+
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			functionMeta.Name,
+			functionMeta.Namespace))
+	}
+
+	return functions[0], nil
+}
+
+// returns the invocations the invocation inspectors of the function's containers retain. containers whose
+// processors can't be reached are skipped
+func (p *Platform) getContainersInvocations(namespace string, name string) ([]platform.FunctionInvocation, error) {
+
+	// the function's own container along with the replicas the autoscaler added
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":      "local",
+			"nuclio.io/namespace":     namespace,
+			"nuclio.io/function-name": name,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function containers")
+	}
+
+	var invocations []platform.FunctionInvocation
+	for _, container := range containers {
+		var stdout string
+
+		// the web admin isn't published, so read it from within the container
+		if err := p.dockerClient.ExecInContainer(container.ID, &dockerclient.ExecOptions{
+			Command: p.getContainerHTTPGetCommand(fmt.Sprintf("http://127.0.0.1:8081/%s",
+				platform.ProcessorInvocationsPath)),
+			Stdout: &stdout,
+		}); err != nil {
+			p.Logger.DebugWith("Failed to get processor invocations",
+				"containerID", container.ID,
+				"err", errors.RootCause(err).Error())
+			continue
+		}
+
+		containerInvocations, err := platform.ParseFunctionReplicaInvocations(strings.TrimPrefix(container.Name, "/"),
+			[]byte(stdout))
+		if err != nil {
+			p.Logger.DebugWith("Failed to parse processor invocations", "containerID", container.ID, "err", err.Error())
+			continue
+		}
+
+		invocations = append(invocations, containerInvocations...)
+	}
+
+	return invocations, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// OrphanContainer is a function container whose function isn't registered, e.g. one left behind by
+// a deploy that failed or was interrupted
+type OrphanContainer struct {
+	ID           string
+	Name         string
+	FunctionName string
+	Status       string
+}
+
+// GetOrphanContainers returns the function containers in the namespace whose functions aren't registered.
+// only containers labeled as created by nuclio for a function are considered
+func (p *Platform) GetOrphanContainers(namespace string) ([]OrphanContainer, error) {
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read functions from local store")
+	}
+
+	registeredFunctionNames := map[string]bool{}
+	for _, function := range functions {
+		registeredFunctionNames[function.GetConfig().Meta.Name] = true
+	}
+
+	return getOrphanContainers(p.dockerClient, namespace, registeredFunctionNames)
+}
+
+// RemoveOrphanContainers removes orphan containers, as returned by GetOrphanContainers
+func (p *Platform) RemoveOrphanContainers(orphanContainers []OrphanContainer) error {
+	for _, orphanContainer := range orphanContainers {
+		p.Logger.InfoWith("Removing orphan container",
+			"id", orphanContainer.ID,
+			"functionName", orphanContainer.FunctionName)
+
+		if err := p.dockerClient.RemoveContainer(orphanContainer.ID); err != nil {
+			return errors.Wrapf(err, "Failed to remove container %s", orphanContainer.ID)
+		}
+	}
+
+	return nil
+}
+
+func getOrphanContainers(dockerClient dockerclient.Client,
+	namespace string,
+	registeredFunctionNames map[string]bool) ([]OrphanContainer, error) {
+
+	// stopped containers too, as that's how failed deploys leave them
+	containers, err := dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":  "local",
+			"nuclio.io/namespace": namespace,
+		},
+		Stopped: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get containers")
+	}
+
+	var orphanContainers []OrphanContainer
+	for _, container := range containers {
+		if container.Config == nil {
+			continue
+		}
+
+		// not a function's container
+		functionName := container.Config.Labels["nuclio.io/function-name"]
+		if functionName == "" || registeredFunctionNames[functionName] {
+			continue
+		}
+
+		orphanContainer := OrphanContainer{
+			ID:           container.ID,
+			Name:         strings.TrimPrefix(container.Name, "/"),
+			FunctionName: functionName,
+		}
+
+		if container.State != nil {
+			orphanContainer.Status = container.State.Status
+		}
+
+		orphanContainers = append(orphanContainers, orphanContainer)
+	}
+
+	return orphanContainers, nil
+}
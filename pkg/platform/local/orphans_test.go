@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type orphansTestSuite struct {
+	suite.Suite
+	mockDockerClient *dockerclient.MockDockerClient
+	platform         *Platform
+}
+
+func (suite *orphansTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient = dockerclient.NewMockDockerClient()
+	suite.platform = &Platform{
+		Platform:     &abstract.Platform{Logger: loggerInstance},
+		dockerClient: suite.mockDockerClient,
+	}
+}
+
+func (suite *orphansTestSuite) TestGetAndRemoveOrphanContainers() {
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":  "local",
+			"nuclio.io/namespace": "nuclio",
+		},
+		Stopped: true,
+	}).Return([]dockerclient.Container{
+		{
+			ID:     "registered-id",
+			Name:   "/nuclio-nuclio-registered",
+			Config: &dockerclient.Config{Labels: map[string]string{"nuclio.io/function-name": "registered"}},
+		},
+		{
+			ID:     "orphan-id",
+			Name:   "/nuclio-nuclio-orphan",
+			State:  &dockerclient.ContainerState{Status: "exited"},
+			Config: &dockerclient.Config{Labels: map[string]string{"nuclio.io/function-name": "orphan"}},
+		},
+
+		// labeled by nuclio, but not a function's container
+		{
+			ID:     "other-id",
+			Name:   "/nuclio-other",
+			Config: &dockerclient.Config{Labels: map[string]string{"nuclio.io/platform": "local"}},
+		},
+	}, nil).Once()
+
+	orphanContainers, err := getOrphanContainers(suite.mockDockerClient, "nuclio", map[string]bool{"registered": true})
+	suite.Require().NoError(err)
+	suite.Require().Equal([]OrphanContainer{
+		{ID: "orphan-id", Name: "nuclio-nuclio-orphan", FunctionName: "orphan", Status: "exited"},
+	}, orphanContainers)
+
+	suite.mockDockerClient.On("RemoveContainer", "orphan-id").Return(nil).Once()
+
+	err = suite.platform.RemoveOrphanContainers(orphanContainers)
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func TestOrphansTestSuite(t *testing.T) {
+	suite.Run(t, new(orphansTestSuite))
+}
@@ -21,12 +21,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/cmdrunner"
@@ -36,14 +40,18 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/abstract"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/config"
+	"github.com/nuclio/nuclio/pkg/secretprovider"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
 	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type Platform struct {
@@ -54,11 +62,27 @@ type Platform struct {
 	checkFunctionContainersHealthiness    bool
 	functionContainersHealthinessTimeout  time.Duration
 	functionContainersHealthinessInterval time.Duration
+	autoscaler                            *autoscaler
+	containerOS                           string
+	containerOSOnce                       sync.Once
 }
 
 const Mib = 1048576
 const UnhealthyContainerErrorMessage = "Container is not healthy (detected by nuclio platform)"
 
+// the time between scaling decisions of the autoscaler
+const autoscalerInterval = 30 * time.Second
+
+// where the processor's cron triggers persist when they last fired (the cron trigger's StateDir, which
+// can't be imported here without an import cycle)
+const cronTriggerStateDir = "/etc/nuclio/cron"
+
+// where the secrets functions consume are kept on the host, unless NUCLIO_LOCAL_SECRETS_DIR says otherwise
+const defaultLocalSecretsDir = "/etc/nuclio/secrets"
+
+// where the processor reads its configuration from
+const processorConfigPath = "/etc/nuclio/config/processor/processor.yaml"
+
 // NewPlatform instantiates a new local platform
 func NewPlatform(parentLogger logger.Logger,
 	containerBuilderConfiguration *containerimagebuilderpusher.ContainerBuilderConfiguration,
@@ -109,12 +133,22 @@ func NewPlatform(parentLogger logger.Logger,
 			}
 		}(newPlatform)
 	}
+
+	// the autoscaler is disabled by default, as it must run in a long-lived process (e.g. the dashboard)
+	if common.GetEnvOrDefaultBool("NUCLIO_LOCAL_AUTOSCALER_ENABLED", false) {
+		newPlatform.autoscaler = newAutoscaler(newPlatform.Logger,
+			newPlatform.dockerClient,
+			autoscalerInterval,
+			newPlatform.getFreeLocalPort)
+
+		newPlatform.autoscaler.start()
+	}
+
 	return newPlatform, nil
 }
 
 // CreateFunction will simply run a docker image
 func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunctionOptions) (*platform.CreateFunctionResult, error) {
-	var previousHTTPPort int
 	var err error
 	var existingFunctionConfig *functionconfig.ConfigWithStatus
 
@@ -142,6 +176,12 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 	createFunctionOptions.FunctionConfig.Spec.RunRegistry = ""
 	createFunctionOptions.FunctionConfig.Spec.Build.Registry = ""
 
+	// nor does it run containers alongside the processor's
+	if len(createFunctionOptions.FunctionConfig.Spec.Sidecars) > 0 {
+		createFunctionOptions.Logger.WarnWith("Sidecars aren't supported on the local platform, ignoring them",
+			"sidecars", len(createFunctionOptions.FunctionConfig.Spec.Sidecars))
+	}
+
 	// it's possible to pass a function without specifying any meta in the request, in that case skip getting existing function
 	if createFunctionOptions.FunctionConfig.Meta.Namespace != "" && createFunctionOptions.FunctionConfig.Meta.Name != "" {
 		existingFunctions, err := p.localStore.getFunctions(&createFunctionOptions.FunctionConfig.Meta)
@@ -200,11 +240,6 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 			return errors.Wrap(err, "Failed to create function")
 		}
 
-		previousHTTPPort, err = p.deletePreviousContainers(createFunctionOptions)
-		if err != nil {
-			return errors.Wrap(err, "Failed to delete previous containers")
-		}
-
 		// indicate that the creation state has been updated. local platform has no "building" state yet
 		if createFunctionOptions.CreationStateUpdated != nil {
 			createFunctionOptions.CreationStateUpdated <- true
@@ -230,21 +265,44 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 		var deployErr error
 		functionStatus := functionconfig.Status{
 			State: functionconfig.FunctionStateImported,
+			Image: createFunctionOptions.ImageStatus,
 		}
 
 		if !skipFunctionDeploy {
-			createFunctionResult, deployErr = p.deployFunction(createFunctionOptions, previousHTTPPort)
+
+			// the previous deployment keeps serving the function while it's built, and is only replaced by
+			// the deployment
+			createFunctionResult, deployErr = p.deployFunction(createFunctionOptions)
 			if deployErr != nil {
 				reportCreationError(deployErr) // nolint: errcheck
 				return nil, deployErr
 			}
 
+			lastDeployed := time.Now().UTC()
 			functionStatus = functionconfig.Status{
-				HTTPPort: createFunctionResult.Port,
-				State:    functionconfig.FunctionStateReady,
+				HTTPPort:     createFunctionResult.Port,
+				State:        functionconfig.FunctionStateReady,
+				Warmup:       createFunctionResult.Warmup,
+				Image:        createFunctionOptions.ImageStatus,
+				LastDeployed: &lastDeployed,
+			}
+
+			// a disabled function is deployed paused
+			if createFunctionOptions.FunctionConfig.Spec.Disable {
+				if err := p.stopFunctionContainers(&createFunctionOptions.FunctionConfig); err != nil {
+					reportCreationError(err) // nolint: errcheck
+					return nil, errors.Wrap(err, "Failed to stop function containers")
+				}
+
+				functionStatus.State = functionconfig.FunctionStatePaused
 			}
 		} else {
 			p.Logger.Info("Skipping function deployment")
+
+			if _, err = p.deletePreviousContainers(createFunctionOptions); err != nil {
+				return nil, errors.Wrap(err, "Failed to delete previous containers")
+			}
+
 			createFunctionResult = &platform.CreateFunctionResult{
 				CreateFunctionBuildResult: platform.CreateFunctionBuildResult{
 					Image:                 createFunctionOptions.FunctionConfig.Spec.Image,
@@ -261,6 +319,42 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 			return nil, errors.Wrap(err, "Failed to update function with state")
 		}
 
+		// the function is deployed at this point, so failing to record it or clean up after it isn't fatal
+		if !skipFunctionDeploy {
+			if err := p.recordFunctionRevision(&createFunctionOptions.FunctionConfig); err != nil {
+				createFunctionOptions.Logger.WarnWith("Failed to record function revision", "err", err.Error())
+			}
+		}
+
+		auditOperation := platform.AuditOperationDeploy
+		if skipFunctionDeploy {
+			auditOperation = platform.AuditOperationImport
+		}
+
+		var previousFunctionConfig *functionconfig.Config
+		if existingFunctionConfig != nil {
+			previousFunctionConfig = &existingFunctionConfig.Config
+		}
+
+		p.RecordAuditOperation(auditOperation,
+			createFunctionOptions.Identity,
+			createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name,
+			previousFunctionConfig,
+			&createFunctionOptions.FunctionConfig)
+
+		if !skipFunctionDeploy && createFunctionOptions.PruneOldImages > 0 {
+			if err := p.pruneOldFunctionImages(createFunctionOptions, createFunctionResult); err != nil {
+				createFunctionOptions.Logger.WarnWith("Failed to prune old function images", "err", err.Error())
+			}
+		}
+
+		// the function has a new container, which the API gateways routing to it should route to
+		if err := p.updateFunctionAPIGateways(createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name); err != nil {
+			createFunctionOptions.Logger.WarnWith("Failed to update function API gateways", "err", err.Error())
+		}
+
 		return createFunctionResult, nil
 	}
 
@@ -282,8 +376,11 @@ func (p *Platform) CreateFunction(createFunctionOptions *platform.CreateFunction
 func (p *Platform) GetFunctions(getFunctionsOptions *platform.GetFunctionsOptions) ([]platform.Function, error) {
 	var functions []platform.Function
 
-	// get project filter
-	projectName := common.StringToStringMap(getFunctionsOptions.Labels, "=")["nuclio.io/project-name"]
+	// the functions are filtered by a label selector (e.g. nuclio.io/project-name=my-project,tier!=dev)
+	labelSelector, err := labels.Parse(getFunctionsOptions.Labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse label selector")
+	}
 
 	// get all the functions in the store. these functions represent both functions that are deployed
 	// and functions that failed to build
@@ -296,9 +393,8 @@ func (p *Platform) GetFunctions(getFunctionsOptions *platform.GetFunctionsOption
 		return nil, errors.Wrap(err, "Failed to read functions from local store")
 	}
 
-	// filter by project name
 	for _, localStoreFunction := range localStoreFunctions {
-		if projectName != "" && localStoreFunction.GetConfig().Meta.Labels["nuclio.io/project-name"] != projectName {
+		if !labelSelector.Matches(labels.Set(localStoreFunction.GetConfig().Meta.Labels)) {
 			continue
 		}
 		functions = append(functions, localStoreFunction)
@@ -315,270 +411,904 @@ func (p *Platform) UpdateFunction(updateFunctionOptions *platform.UpdateFunction
 	return nil
 }
 
-// DeleteFunction will delete a previously deployed function
-func (p *Platform) DeleteFunction(deleteFunctionOptions *platform.DeleteFunctionOptions) error {
+// GetFunctionLogs streams the logs of the function's container, its only replica
+func (p *Platform) GetFunctionLogs(getFunctionLogsOptions *platform.GetFunctionLogsOptions) (io.ReadCloser, error) {
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      getFunctionLogsOptions.Name,
+		Namespace: getFunctionLogsOptions.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
 
-	// delete the function from the local store
-	err := p.localStore.deleteFunction(&deleteFunctionOptions.FunctionConfig.Meta)
-	if err != nil && err != nuclio.ErrNotFound {
-		p.Logger.WarnWith("Failed to delete function from local store", "err", err.Error())
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionLogsOptions.Name,
+			getFunctionLogsOptions.Namespace))
 	}
 
-	getFunctionEventsOptions := &platform.FunctionEventMeta{
-		Labels: map[string]string{
-			"nuclio.io/function-name": deleteFunctionOptions.FunctionConfig.Meta.Name,
-		},
-		Namespace: deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+	containerName := p.getFunctionContainerName(getFunctionLogsOptions.Namespace, getFunctionLogsOptions.Name)
+	if getFunctionLogsOptions.Replica != "" && getFunctionLogsOptions.Replica != containerName {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Replica not found: %s (the function's only replica is %s)",
+			getFunctionLogsOptions.Replica,
+			containerName))
 	}
-	functionEvents, err := p.localStore.getFunctionEvents(getFunctionEventsOptions)
+
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name:    containerName,
+		Stopped: true,
+	})
 	if err != nil {
-		return errors.Wrap(err, "Failed to get function events")
+		return nil, errors.Wrap(err, "Failed to get function containers")
 	}
 
-	p.Logger.InfoWith("Got function events", "num", len(functionEvents))
+	container := getContainerByName(containers, containerName)
+	if container == nil {
+		return nil, errors.Errorf("Function %s has no container", getFunctionLogsOptions.Name)
+	}
 
-	errGroup, _ := errgroup.WithContext(context.TODO())
-	for _, functionEvent := range functionEvents {
+	return p.dockerClient.GetContainerLogStream(container.ID, &dockerclient.ContainerLogsOptions{
+		Follow: getFunctionLogsOptions.Follow,
+		Since:  getFunctionLogsOptions.Since,
+	})
+}
 
-		errGroup.Go(func() error {
-			err = p.localStore.deleteFunctionEvent(&functionEvent.GetConfig().Meta)
-			if err != nil {
-				return errors.Wrap(err, "Failed to delete function event")
-			}
-			return nil
-		})
+// GetFunctionExecCommand returns a docker exec of the command in the function's container, its only replica
+func (p *Platform) GetFunctionExecCommand(getFunctionExecCommandOptions *platform.GetFunctionExecCommandOptions) (
+	[]string, error) {
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      getFunctionExecCommandOptions.Name,
+		Namespace: getFunctionExecCommandOptions.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
 	}
 
-	// wait for all errgroup goroutines
-	if err := errGroup.Wait(); err != nil {
-		return errors.Wrap(err, "Failed to delete function events")
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionExecCommandOptions.Name,
+			getFunctionExecCommandOptions.Namespace))
 	}
 
-	getContainerOptions := &dockerclient.GetContainerOptions{
-		Labels: map[string]string{
-			"nuclio.io/platform":      "local",
-			"nuclio.io/namespace":     deleteFunctionOptions.FunctionConfig.Meta.Namespace,
-			"nuclio.io/function-name": deleteFunctionOptions.FunctionConfig.Meta.Name,
-		},
+	containerName := p.getFunctionContainerName(getFunctionExecCommandOptions.Namespace,
+		getFunctionExecCommandOptions.Name)
+	if getFunctionExecCommandOptions.Replica != "" && getFunctionExecCommandOptions.Replica != containerName {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Replica not found: %s (the function's only replica is %s)",
+			getFunctionExecCommandOptions.Replica,
+			containerName))
 	}
 
-	containersInfo, err := p.dockerClient.GetContainers(getContainerOptions)
+	// only running containers
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name: containerName,
+	})
 	if err != nil {
-		return errors.Wrap(err, "Failed to get containers")
+		return nil, errors.Wrap(err, "Failed to get function containers")
 	}
 
-	// iterate over contains and delete them. It's possible that under some weird circumstances
-	// there are a few instances of this function in the namespace
-	for _, containerInfo := range containersInfo {
-		if err := p.dockerClient.RemoveContainer(containerInfo.ID); err != nil {
-			return err
-		}
+	container := getContainerByName(containers, containerName)
+	if container == nil {
+		return nil, errors.Errorf("Function %s has no running container", getFunctionExecCommandOptions.Name)
 	}
 
-	p.Logger.InfoWith("Function deleted", "name", deleteFunctionOptions.FunctionConfig.Meta.Name)
+	execCommand := []string{"docker", "exec", "--interactive"}
 
-	return nil
-}
+	if getFunctionExecCommandOptions.TTY {
+		execCommand = append(execCommand, "--tty")
+	}
 
-// GetHealthCheckMode returns the healthcheck mode the platform requires
-func (p *Platform) GetHealthCheckMode() platform.HealthCheckMode {
+	execCommand = append(execCommand, container.ID)
 
-	// The internal client needs to perform the health check
-	return platform.HealthCheckModeInternalClient
+	return append(execCommand, getFunctionExecCommandOptions.Command...), nil
 }
 
-// GetName returns the platform name
-func (p *Platform) GetName() string {
-	return "local"
-}
+// CreateFunctionTunnel reaches the function's container over its docker network, for when its published port
+// isn't reachable (e.g. nuctl runs in a container). a caller running in a container is attached to the network
+func (p *Platform) CreateFunctionTunnel(createFunctionTunnelOptions *platform.CreateFunctionTunnelOptions) (
+	platform.FunctionTunnel, error) {
 
-func (p *Platform) GetNodes() ([]platform.Node, error) {
+	containerName := p.getFunctionContainerName(createFunctionTunnelOptions.Namespace, createFunctionTunnelOptions.Name)
 
-	// just create a single node
-	return []platform.Node{&node{}}, nil
-}
+	// only running containers
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name: containerName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function containers")
+	}
 
-// CreateProject will create a new project
-func (p *Platform) CreateProject(createProjectOptions *platform.CreateProjectOptions) error {
-	return p.localStore.createOrUpdateProject(&createProjectOptions.ProjectConfig)
-}
+	container := getContainerByName(containers, containerName)
+	if container == nil {
+		return nil, errors.Errorf("Function %s has no running container", createFunctionTunnelOptions.Name)
+	}
 
-// UpdateProject will update an existing project
-func (p *Platform) UpdateProject(updateProjectOptions *platform.UpdateProjectOptions) error {
-	return p.localStore.createOrUpdateProject(&updateProjectOptions.ProjectConfig)
-}
+	// docker names a container's host after the container's (short) ID
+	callerContainerID := ""
+	if common.RunningInContainer() {
+		if callerContainerID, err = os.Hostname(); err != nil {
+			return nil, errors.Wrap(err, "Failed to get container ID")
+		}
+	}
 
-// DeleteProject will delete an existing project
-func (p *Platform) DeleteProject(deleteProjectOptions *platform.DeleteProjectOptions) error {
-	if err := p.Platform.ValidateDeleteProjectOptions(deleteProjectOptions); err != nil {
-		return err
+	tunnel, err := newNetworkTunnel(p.Logger, p.dockerClient, container, callerContainerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create tunnel to function")
 	}
-	return p.localStore.deleteProject(&deleteProjectOptions.Meta)
-}
 
-// GetProjects will list existing projects
-func (p *Platform) GetProjects(getProjectsOptions *platform.GetProjectsOptions) ([]platform.Project, error) {
-	return p.localStore.getProjects(&getProjectsOptions.Meta)
+	return tunnel, nil
 }
 
-// CreateFunctionEvent will create a new function event that can later be used as a template from
-// which to invoke functions
-func (p *Platform) CreateFunctionEvent(createFunctionEventOptions *platform.CreateFunctionEventOptions) error {
-	return p.localStore.createOrUpdateFunctionEvent(&createFunctionEventOptions.FunctionEventConfig)
-}
+// PauseFunction stops the function's container, leaving it (and the function's configuration) in place
+func (p *Platform) PauseFunction(pauseFunctionOptions *platform.PauseFunctionOptions) error {
+	function, err := p.getPausableFunction(pauseFunctionOptions.Namespace, pauseFunctionOptions.Name)
+	if err != nil {
+		return err
+	}
 
-// UpdateFunctionEvent will update a previously existing function event
-func (p *Platform) UpdateFunctionEvent(updateFunctionEventOptions *platform.UpdateFunctionEventOptions) error {
-	return p.localStore.createOrUpdateFunctionEvent(&updateFunctionEventOptions.FunctionEventConfig)
-}
+	functionStatus := function.GetStatus()
+	if functionStatus.State == functionconfig.FunctionStatePaused {
+		return nil
+	}
 
-// DeleteFunctionEvent will delete a previously existing function event
-func (p *Platform) DeleteFunctionEvent(deleteFunctionEventOptions *platform.DeleteFunctionEventOptions) error {
-	return p.localStore.deleteFunctionEvent(&deleteFunctionEventOptions.Meta)
-}
+	if err := p.stopFunctionContainers(function.GetConfig()); err != nil {
+		return errors.Wrap(err, "Failed to stop function containers")
+	}
 
-// GetFunctionEvents will list existing function events
-func (p *Platform) GetFunctionEvents(getFunctionEventsOptions *platform.GetFunctionEventsOptions) ([]platform.FunctionEvent, error) {
-	return p.localStore.getFunctionEvents(&getFunctionEventsOptions.Meta)
-}
+	functionStatus.State = functionconfig.FunctionStatePaused
+	functionStatus.Message = ""
 
-// GetExternalIPAddresses returns the external IP addresses invocations will use, if "via" is set to "external-ip".
-// These addresses are either set through SetExternalIPAddresses or automatically discovered
-func (p *Platform) GetExternalIPAddresses() ([]string, error) {
+	return p.storeFunctionDisabled(function, functionStatus, true)
+}
 
-	// check if parent has addresses
-	externalIPAddress, err := p.Platform.GetExternalIPAddresses()
+// ResumeFunction starts the container of a paused function, and waits for it to be ready
+func (p *Platform) ResumeFunction(resumeFunctionOptions *platform.ResumeFunctionOptions) error {
+	function, err := p.getPausableFunction(resumeFunctionOptions.Namespace, resumeFunctionOptions.Name)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get external IP addresses from parent")
+		return err
 	}
 
-	// if the parent has something, use that
-	if len(externalIPAddress) != 0 {
-		return externalIPAddress, nil
+	functionConfig := function.GetConfig()
+	functionStatus := function.GetStatus()
+	if functionStatus.State != functionconfig.FunctionStatePaused {
+		return nuclio.NewErrConflict(fmt.Sprintf("Function %s isn't paused (state: %s)",
+			resumeFunctionOptions.Name,
+			functionStatus.State))
 	}
 
-	// If the testing environment variable is set - use that
-	if os.Getenv("NUCLIO_TEST_HOST") != "" {
-		return []string{os.Getenv("NUCLIO_TEST_HOST")}, nil
+	containers, err := p.getFunctionContainers(&platform.CreateFunctionOptions{
+		FunctionConfig: *functionConfig,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function containers")
 	}
 
-	if common.RunningInContainer() {
-		return []string{"172.17.0.1"}, nil
+	if len(containers) == 0 {
+		return errors.Errorf("Function %s has no container", resumeFunctionOptions.Name)
 	}
 
-	// return an empty string to maintain backwards compatibility
-	return []string{""}, nil
-}
-
-// ResolveDefaultNamespace returns the proper default resource namespace, given the current default namespace
-func (p *Platform) ResolveDefaultNamespace(defaultNamespace string) string {
-
-	// if no default namespace is chosen, use "nuclio"
-	if defaultNamespace == "@nuclio.selfNamespace" || defaultNamespace == "" {
-		return "nuclio"
+	readinessTimeout := abstract.DefaultReadinessTimeoutSeconds * time.Second
+	if functionConfig.Spec.ReadinessTimeoutSeconds != 0 {
+		readinessTimeout = time.Duration(functionConfig.Spec.ReadinessTimeoutSeconds) * time.Second
 	}
 
-	return defaultNamespace
-}
+	for _, container := range containers {
+		if err := p.dockerClient.StartContainer(container.ID); err != nil {
+			return errors.Wrapf(err, "Failed to start container %s", container.Name)
+		}
 
-// GetNamespaces returns all the namespaces in the platform
-func (p *Platform) GetNamespaces() ([]string, error) {
-	return []string{"nuclio"}, nil
-}
+		if err := p.dockerClient.AwaitContainerHealth(container.ID, &readinessTimeout); err != nil {
+			functionStatus.State = functionconfig.FunctionStateError
+			functionStatus.Message = UnhealthyContainerErrorMessage
 
-func (p *Platform) GetDefaultInvokeIPAddresses() ([]string, error) {
-	return []string{"172.17.0.1"}, nil
-}
+			if storeErr := p.storeFunctionDisabled(function, functionStatus, false); storeErr != nil {
+				p.Logger.WarnWith("Failed to store function status", "err", storeErr.Error())
+			}
 
-func (p *Platform) getFreeLocalPort() (int, error) {
-	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
-	if err != nil {
-		return 0, err
+			return errors.Wrap(err, "Function wasn't ready in time")
+		}
 	}
 
-	l, err := net.ListenTCP("tcp", addr)
-	if err != nil {
-		return 0, err
+	if p.autoscaler != nil {
+		if err := p.autoscaler.setFunctionPaused(resumeFunctionOptions.Namespace, resumeFunctionOptions.Name, false); err != nil {
+			return errors.Wrap(err, "Failed to resume function autoscaling")
+		}
 	}
 
-	defer l.Close() // nolint: errcheck
-	return l.Addr().(*net.TCPAddr).Port, nil
+	functionStatus.State = functionconfig.FunctionStateReady
+	functionStatus.Message = ""
+
+	return p.storeFunctionDisabled(function, functionStatus, false)
 }
 
-func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunctionOptions,
-	previousHTTPPort int) (*platform.CreateFunctionResult, error) {
+// stopFunctionContainers stops the containers of a function being paused
+func (p *Platform) stopFunctionContainers(functionConfig *functionconfig.Config) error {
 
-	// get function platform specific configuration
-	functionPlatformConfiguration, err := newFunctionPlatformConfiguration(&createFunctionOptions.FunctionConfig)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create function platform configuration")
+	// the autoscaler's replicas are removed first, so that only the function's own container is left
+	if p.autoscaler != nil {
+		if err := p.autoscaler.setFunctionPaused(functionConfig.Meta.Namespace, functionConfig.Meta.Name, true); err != nil {
+			return errors.Wrap(err, "Failed to pause function autoscaling")
+		}
 	}
 
-	// get function port - either from configuration, from the previous deployment or from a free port
-	functionHTTPPort, err := p.getFunctionHTTPPort(createFunctionOptions, previousHTTPPort)
+	containers, err := p.getFunctionContainers(&platform.CreateFunctionOptions{
+		FunctionConfig: *functionConfig,
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get function HTTP port")
+		return errors.Wrap(err, "Failed to get function containers")
 	}
 
-	createFunctionOptions.Logger.DebugWith("Function port allocated",
-		"port", functionHTTPPort,
-		"previousHTTPPort", previousHTTPPort)
-
-	labels := map[string]string{
-		"nuclio.io/platform":      "local",
-		"nuclio.io/namespace":     createFunctionOptions.FunctionConfig.Meta.Namespace,
-		"nuclio.io/function-name": createFunctionOptions.FunctionConfig.Meta.Name,
-		"nuclio.io/function-spec": p.encodeFunctionSpec(&createFunctionOptions.FunctionConfig.Spec),
+	for _, container := range containers {
+		if err := p.dockerClient.StopContainer(container.ID); err != nil {
+			return errors.Wrapf(err, "Failed to stop container %s", container.Name)
+		}
 	}
 
-	for labelName, labelValue := range createFunctionOptions.FunctionConfig.Meta.Labels {
-		labels[labelName] = labelValue
-	}
+	return nil
+}
 
-	marshalledAnnotations := p.marshallAnnotations(createFunctionOptions.FunctionConfig.Meta.Annotations)
-	if marshalledAnnotations != nil {
-		labels["nuclio.io/annotations"] = string(marshalledAnnotations)
+// getPausableFunction returns the function from the local store, if it was deployed
+func (p *Platform) getPausableFunction(namespace string, name string) (platform.Function, error) {
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      name,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
 	}
 
-	// create processor configuration at a temporary location unless user specified a configuration
-	localProcessorConfigPath, err := p.createProcessorConfig(createFunctionOptions)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create processor configuration")
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s", name, namespace))
 	}
 
-	// create volumes string[string] map for volumes
-	volumesMap := map[string]string{
-		localProcessorConfigPath: path.Join("/", "etc", "nuclio", "config", "processor", "processor.yaml"),
+	switch functions[0].GetStatus().State {
+	case functionconfig.FunctionStateImported,
+		functionconfig.FunctionStateWaitingForBuild,
+		functionconfig.FunctionStateBuilding:
+		return nil, nuclio.NewErrBadRequest("Non-deployed functions cannot be paused or resumed")
 	}
 
-	for _, volume := range createFunctionOptions.FunctionConfig.Spec.Volumes {
+	return functions[0], nil
+}
 
-		// only add hostpath volumes
-		if volume.Volume.HostPath != nil {
-			volumesMap[volume.Volume.HostPath.Path] = volume.VolumeMount.MountPath
-		}
-	}
+// storeFunctionDisabled stores the function's status, and whether it's disabled in its configuration
+func (p *Platform) storeFunctionDisabled(function platform.Function,
+	functionStatus *functionconfig.Status,
+	disable bool) error {
+	if err := p.localStore.updateFunction(&function.GetConfig().Meta,
+		func(configWithStatus *functionconfig.ConfigWithStatus) error {
+			configWithStatus.Spec.Disable = disable
+			configWithStatus.Status = *functionStatus
 
-	envMap := map[string]string{}
-	for _, env := range createFunctionOptions.FunctionConfig.Spec.Env {
-		envMap[env.Name] = env.Value
+			return nil
+		}); err != nil {
+		return errors.Wrap(err, "Failed to store function")
 	}
 
-	// run the docker image
-	containerID, err := p.dockerClient.RunContainer(createFunctionOptions.FunctionConfig.Spec.Image, &dockerclient.RunOptions{
-		ContainerName: p.GetContainerNameByCreateFunctionOptions(createFunctionOptions),
-		Ports:         map[int]int{functionHTTPPort: 8080},
-		Env:           envMap,
-		Labels:        labels,
-		Volumes:       volumesMap,
-		Network:       functionPlatformConfiguration.Network,
-		RestartPolicy: functionPlatformConfiguration.RestartPolicy,
+	return nil
+}
+
+// PauseFunctionTrigger marks the trigger paused in the stored function's configuration, and in the processor
+// configuration its containers mount - which their processors reload without restarting
+func (p *Platform) PauseFunctionTrigger(pauseFunctionTriggerOptions *platform.PauseFunctionTriggerOptions) error {
+	return p.setFunctionTriggerPaused(pauseFunctionTriggerOptions.Namespace,
+		pauseFunctionTriggerOptions.Name,
+		pauseFunctionTriggerOptions.TriggerName,
+		true)
+}
+
+// ResumeFunctionTrigger marks a paused trigger of the function as no longer paused, the same way it was paused
+func (p *Platform) ResumeFunctionTrigger(resumeFunctionTriggerOptions *platform.ResumeFunctionTriggerOptions) error {
+	return p.setFunctionTriggerPaused(resumeFunctionTriggerOptions.Namespace,
+		resumeFunctionTriggerOptions.Name,
+		resumeFunctionTriggerOptions.TriggerName,
+		false)
+}
+
+func (p *Platform) setFunctionTriggerPaused(namespace string, name string, triggerName string, paused bool) error {
+	function, err := p.getPausableFunction(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	functionConfig := *function.GetConfig()
+	changed := false
+
+	// the trigger is looked up in the stored configuration, which may have changed since the function was read
+	if err := p.localStore.updateFunction(&functionConfig.Meta,
+		func(configWithStatus *functionconfig.ConfigWithStatus) error {
+			triggerConfiguration, found := configWithStatus.Spec.Triggers[triggerName]
+			if !found {
+				return nuclio.NewErrNotFound(fmt.Sprintf("Trigger not found: %s", triggerName))
+			}
+
+			if triggerConfiguration.Paused != paused {
+				triggerConfiguration.Paused = paused
+				configWithStatus.Spec.Triggers[triggerName] = triggerConfiguration
+				changed = true
+			}
+
+			functionConfig = configWithStatus.Config
+
+			return nil
+		}); err != nil {
+		return errors.Wrap(err, "Failed to store function")
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := p.rewriteProcessorConfigs(&functionConfig); err != nil {
+		return errors.Wrap(err, "Failed to rewrite processor configuration")
+	}
+
+	p.Logger.InfoWith("Set function trigger paused",
+		"name", name,
+		"namespace", namespace,
+		"trigger", triggerName,
+		"paused", paused)
+
+	return nil
+}
+
+// rewriteProcessorConfigs writes the function's configuration to the processor configuration files its
+// containers mount. the processors poll the files, so each is written to a temporary file in its directory and
+// renamed over it - a processor never reads a partially written file
+func (p *Platform) rewriteProcessorConfigs(functionConfig *functionconfig.Config) error {
+	containers, err := p.getFunctionContainers(&platform.CreateFunctionOptions{
+		FunctionConfig: *functionConfig,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function containers")
+	}
+
+	processorConfigMountDestination := p.getContainerPath(path.Dir(processorConfigPath))
+
+	for _, container := range containers {
+		for _, mount := range container.Mounts {
+			var err error
+
+			switch {
+			case strings.EqualFold(mount.Destination, processorConfigMountDestination):
+				err = p.replaceProcessorConfig(filepath.Join(mount.Source, path.Base(processorConfigPath)),
+					functionConfig)
+
+			// containers created before the configuration's directory was mounted mount the file itself, which
+			// only its contents can be replaced in (a renamed file isn't seen by the container)
+			case mount.Destination == processorConfigPath:
+				err = p.writeProcessorConfig(mount.Source, functionConfig)
+
+			default:
+				continue
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "Failed to write processor configuration of container %s", container.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// replaceProcessorConfig writes the processor configuration to a temporary file in the directory of the given
+// one, and renames it over the given one
+func (p *Platform) replaceProcessorConfig(processorConfigFilePath string, functionConfig *functionconfig.Config) error {
+	processorConfigFileInfo, err := os.Stat(processorConfigFilePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat processor configuration")
+	}
+
+	temporaryProcessorConfigFile, err := ioutil.TempFile(filepath.Dir(processorConfigFilePath),
+		"."+filepath.Base(processorConfigFilePath)+".")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create temporary processor configuration")
+	}
+
+	temporaryProcessorConfigFilePath := temporaryProcessorConfigFile.Name()
+	temporaryProcessorConfigFile.Close() // nolint: errcheck
+
+	// the temporary file is left only if writing it failed
+	defer os.Remove(temporaryProcessorConfigFilePath) // nolint: errcheck
+
+	// keep the permissions of the replaced file, which a container not running as the image's user relies on
+	if err := os.Chmod(temporaryProcessorConfigFilePath, processorConfigFileInfo.Mode().Perm()); err != nil {
+		return errors.Wrap(err, "Failed to set temporary processor configuration permissions")
+	}
+
+	if err := p.writeProcessorConfig(temporaryProcessorConfigFilePath, functionConfig); err != nil {
+		return errors.Wrap(err, "Failed to write temporary processor configuration")
+	}
+
+	if err := os.Rename(temporaryProcessorConfigFilePath, processorConfigFilePath); err != nil {
+		return errors.Wrap(err, "Failed to replace processor configuration")
+	}
+
+	return nil
+}
+
+// writeProcessorConfig replaces the contents of an existing processor configuration file
+func (p *Platform) writeProcessorConfig(processorConfigFilePath string, functionConfig *functionconfig.Config) error {
+	configWriter, err := processorconfig.NewWriter()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create processor configuration writer")
+	}
+
+	processorConfigFile, err := os.OpenFile(processorConfigFilePath, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open processor configuration")
+	}
+
+	if err := configWriter.Write(processorConfigFile, &processor.Configuration{
+		Config: *functionConfig,
+	}); err != nil {
+		processorConfigFile.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to write processor configuration")
+	}
+
+	return processorConfigFile.Close()
+}
+
+// DeleteFunction will delete a previously deployed function
+func (p *Platform) DeleteFunction(deleteFunctionOptions *platform.DeleteFunctionOptions) error {
+
+	// stop the function's proxy and remove the replicas the autoscaler added
+	if p.autoscaler != nil {
+		if _, err := p.autoscaler.unregisterFunction(deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+			deleteFunctionOptions.FunctionConfig.Meta.Name); err != nil {
+			p.Logger.WarnWith("Failed to unregister function from autoscaler", "err", err.Error())
+		}
+	}
+
+	// delete the function from the local store
+	err := p.localStore.deleteFunction(&deleteFunctionOptions.FunctionConfig.Meta)
+	if err != nil && err != nuclio.ErrNotFound {
+		p.Logger.WarnWith("Failed to delete function from local store", "err", err.Error())
+	}
+
+	err = p.localStore.deleteFunctionRevisions(&deleteFunctionOptions.FunctionConfig.Meta)
+	if err != nil && err != nuclio.ErrNotFound {
+		p.Logger.WarnWith("Failed to delete function revisions from local store", "err", err.Error())
+	}
+
+	err = p.localStore.deleteFunctionInvocations(&deleteFunctionOptions.FunctionConfig.Meta)
+	if err != nil && err != nuclio.ErrNotFound {
+		p.Logger.WarnWith("Failed to delete function invocations from local store", "err", err.Error())
+	}
+
+	getFunctionEventsOptions := &platform.FunctionEventMeta{
+		Labels: map[string]string{
+			"nuclio.io/function-name": deleteFunctionOptions.FunctionConfig.Meta.Name,
+		},
+		Namespace: deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+	}
+	functionEvents, err := p.localStore.getFunctionEvents(getFunctionEventsOptions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function events")
+	}
+
+	p.Logger.InfoWith("Got function events", "num", len(functionEvents))
+
+	errGroup, _ := errgroup.WithContext(context.TODO())
+	for _, functionEvent := range functionEvents {
+
+		errGroup.Go(func() error {
+			err = p.localStore.deleteFunctionEvent(&functionEvent.GetConfig().Meta)
+			if err != nil {
+				return errors.Wrap(err, "Failed to delete function event")
+			}
+			return nil
+		})
+	}
+
+	// wait for all errgroup goroutines
+	if err := errGroup.Wait(); err != nil {
+		return errors.Wrap(err, "Failed to delete function events")
+	}
+
+	getContainerOptions := &dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":      "local",
+			"nuclio.io/namespace":     deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+			"nuclio.io/function-name": deleteFunctionOptions.FunctionConfig.Meta.Name,
+		},
+	}
+
+	containersInfo, err := p.dockerClient.GetContainers(getContainerOptions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get containers")
+	}
+
+	// iterate over contains and delete them. It's possible that under some weird circumstances
+	// there are a few instances of this function in the namespace
+	for _, containerInfo := range containersInfo {
+		if err := p.dockerClient.RemoveContainer(containerInfo.ID); err != nil {
+			return err
+		}
+	}
+
+	// a function deployed again with the same name shouldn't catch up on the deleted function's ticks
+	if err := os.RemoveAll(p.getFunctionCronStateDir(deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+		deleteFunctionOptions.FunctionConfig.Meta.Name)); err != nil {
+		p.Logger.WarnWith("Failed to remove cron triggers state directory", "err", err.Error())
+	}
+
+	// API gateways routing to the function respond that it's unavailable until it's deployed again
+	if err := p.updateFunctionAPIGateways(deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+		deleteFunctionOptions.FunctionConfig.Meta.Name); err != nil {
+		p.Logger.WarnWith("Failed to update function API gateways", "err", err.Error())
+	}
+
+	p.RecordAuditOperation(platform.AuditOperationDelete,
+		deleteFunctionOptions.Identity,
+		deleteFunctionOptions.FunctionConfig.Meta.Namespace,
+		deleteFunctionOptions.FunctionConfig.Meta.Name,
+		nil,
+		nil)
+
+	p.Logger.InfoWith("Function deleted", "name", deleteFunctionOptions.FunctionConfig.Meta.Name)
+
+	return nil
+}
+
+// GetHealthCheckMode returns the healthcheck mode the platform requires
+func (p *Platform) GetHealthCheckMode() platform.HealthCheckMode {
+
+	// The internal client needs to perform the health check
+	return platform.HealthCheckModeInternalClient
+}
+
+// GetName returns the platform name
+func (p *Platform) GetName() string {
+	return "local"
+}
+
+func (p *Platform) GetNodes() ([]platform.Node, error) {
+
+	// just create a single node
+	return []platform.Node{&node{}}, nil
+}
+
+// CreateProject will create a new project
+func (p *Platform) CreateProject(createProjectOptions *platform.CreateProjectOptions) error {
+	if err := createProjectOptions.ProjectConfig.Spec.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	return p.localStore.createOrUpdateProject(&createProjectOptions.ProjectConfig)
+}
+
+// UpdateProject will update an existing project
+func (p *Platform) UpdateProject(updateProjectOptions *platform.UpdateProjectOptions) error {
+	if err := updateProjectOptions.ProjectConfig.Spec.Validate(); err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	return p.localStore.createOrUpdateProject(&updateProjectOptions.ProjectConfig)
+}
+
+// DeleteProject will delete an existing project
+func (p *Platform) DeleteProject(deleteProjectOptions *platform.DeleteProjectOptions) error {
+	if err := p.Platform.ValidateDeleteProjectOptions(deleteProjectOptions); err != nil {
+		return err
+	}
+	return p.localStore.deleteProject(&deleteProjectOptions.Meta)
+}
+
+// GetProjects will list existing projects
+func (p *Platform) GetProjects(getProjectsOptions *platform.GetProjectsOptions) ([]platform.Project, error) {
+	return p.localStore.getProjects(&getProjectsOptions.Meta)
+}
+
+// CreateFunctionEvent will create a new function event that can later be used as a template from
+// which to invoke functions
+func (p *Platform) CreateFunctionEvent(createFunctionEventOptions *platform.CreateFunctionEventOptions) error {
+	return p.localStore.createOrUpdateFunctionEvent(&createFunctionEventOptions.FunctionEventConfig)
+}
+
+// UpdateFunctionEvent will update a previously existing function event
+func (p *Platform) UpdateFunctionEvent(updateFunctionEventOptions *platform.UpdateFunctionEventOptions) error {
+	return p.localStore.createOrUpdateFunctionEvent(&updateFunctionEventOptions.FunctionEventConfig)
+}
+
+// DeleteFunctionEvent will delete a previously existing function event
+func (p *Platform) DeleteFunctionEvent(deleteFunctionEventOptions *platform.DeleteFunctionEventOptions) error {
+	return p.localStore.deleteFunctionEvent(&deleteFunctionEventOptions.Meta)
+}
+
+// GetFunctionEvents will list existing function events
+func (p *Platform) GetFunctionEvents(getFunctionEventsOptions *platform.GetFunctionEventsOptions) ([]platform.FunctionEvent, error) {
+	return p.localStore.getFunctionEvents(&getFunctionEventsOptions.Meta)
+}
+
+// CreateFunctionInvocation will invoke a previously deployed function, at the port its container publishes
+func (p *Platform) CreateFunctionInvocation(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions) (*platform.CreateFunctionInvocationResult, error) {
+	if createFunctionInvocationOptions.URL == "" {
+		invokeURL, err := p.resolveFunctionInvokeURL(createFunctionInvocationOptions)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve function invoke URL")
+		}
+
+		createFunctionInvocationOptions.URL = invokeURL
+	}
+
+	return p.Platform.CreateFunctionInvocation(createFunctionInvocationOptions)
+}
+
+// GetExternalIPAddresses returns the external IP addresses invocations will use, if "via" is set to "external-ip".
+// These addresses are either set through SetExternalIPAddresses or automatically discovered
+func (p *Platform) GetExternalIPAddresses() ([]string, error) {
+
+	// check if parent has addresses
+	externalIPAddress, err := p.Platform.GetExternalIPAddresses()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get external IP addresses from parent")
+	}
+
+	// if the parent has something, use that
+	if len(externalIPAddress) != 0 {
+		return externalIPAddress, nil
+	}
+
+	// If the testing environment variable is set - use that
+	if os.Getenv("NUCLIO_TEST_HOST") != "" {
+		return []string{os.Getenv("NUCLIO_TEST_HOST")}, nil
+	}
+
+	if common.RunningInContainer() {
+		return []string{"172.17.0.1"}, nil
+	}
+
+	// return an empty string to maintain backwards compatibility
+	return []string{""}, nil
+}
+
+// ResolveDefaultNamespace returns the proper default resource namespace, given the current default namespace
+func (p *Platform) ResolveDefaultNamespace(defaultNamespace string) string {
+
+	// if no default namespace is chosen, use "nuclio"
+	if defaultNamespace == "@nuclio.selfNamespace" || defaultNamespace == "" {
+		return "nuclio"
+	}
+
+	return defaultNamespace
+}
+
+// GetNamespaces returns all the namespaces in the platform
+func (p *Platform) GetNamespaces() ([]string, error) {
+	return []string{"nuclio"}, nil
+}
+
+func (p *Platform) GetDefaultInvokeIPAddresses() ([]string, error) {
+	return []string{"172.17.0.1"}, nil
+}
+
+func (p *Platform) getFreeLocalPort() (int, error) {
+	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+
+	l, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+
+	defer l.Close() // nolint: errcheck
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunctionOptions) (*platform.CreateFunctionResult, error) {
+	var previousHTTPPort int
+	var previousContainers []dockerclient.Container
+
+	// get function platform specific configuration
+	functionPlatformConfiguration, err := newFunctionPlatformConfiguration(&createFunctionOptions.FunctionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create function platform configuration")
+	}
+
+	// fail early and clearly if the network wasn't created yet (e.g. the compose stack isn't up)
+	if functionPlatformConfiguration.Network != "" {
+		networkExists, err := p.dockerClient.NetworkExists(functionPlatformConfiguration.Network)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to check whether the function's network exists")
+		}
+
+		if !networkExists {
+			return nil, errors.Errorf("Network %s doesn't exist, create it (or start the docker-compose stack that defines it) first",
+				functionPlatformConfiguration.Network)
+		}
+	}
+
+	// resolved before the previous containers are touched, so that a missing reference doesn't take them down
+	functionReferencesEnv, err := p.getFunctionReferencesEnv(&createFunctionOptions.FunctionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve function references")
+	}
+
+	// a function whose port is served by the autoscaler's proxy is redeployed blue/green - its new container is
+	// run alongside the previous one, and the proxy is switched to it once it's ready. a function which isn't
+	// proxied is redeployed side by side - its new container is run alongside the previous one on a temporary
+	// port, and once it's ready the function's port is moved over to it. otherwise, the previous container must
+	// release the function's port before the new one can publish it
+	blueGreen := p.shouldDeployBlueGreen(&createFunctionOptions.FunctionConfig)
+	sideBySide := false
+
+	switch {
+	case blueGreen:
+		previousHTTPPort = p.autoscaler.getProxyPort(createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name)
+
+		if previousContainers, err = p.getFunctionContainers(createFunctionOptions); err != nil {
+			return nil, errors.Wrap(err, "Failed to get previous containers")
+		}
+
+	case p.autoscaler == nil:
+		if previousContainers, err = p.getFunctionContainers(createFunctionOptions); err != nil {
+			return nil, errors.Wrap(err, "Failed to get previous containers")
+		}
+
+		sideBySide = len(previousContainers) > 0
+		if sideBySide {
+			previousHTTPPort = p.getContainerHTTPTriggerPort(&previousContainers[0])
+		}
+	}
+
+	if !blueGreen && !sideBySide {
+		if previousHTTPPort, err = p.deletePreviousContainers(createFunctionOptions); err != nil {
+			return nil, errors.Wrap(err, "Failed to delete previous containers")
+		}
+	}
+
+	// get function port - either from configuration, from the previous deployment or from a free port
+	functionHTTPPort, err := p.getFunctionHTTPPort(createFunctionOptions, previousHTTPPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function HTTP port")
+	}
+
+	createFunctionOptions.Logger.DebugWith("Function port allocated",
+		"port", functionHTTPPort,
+		"previousHTTPPort", previousHTTPPort)
+
+	// a proxied function's port is served by the autoscaler's proxy, so its container publishes another port. so
+	// does the new container of a function deployed side by side, until it's moved to the function's port
+	containerHTTPPort := functionHTTPPort
+	proxied := p.isFunctionProxied(&createFunctionOptions.FunctionConfig)
+	if proxied || sideBySide {
+		if containerHTTPPort, err = p.getFreeLocalPort(); err != nil {
+			return nil, errors.Wrap(err, "Failed to get free local port")
+		}
+	}
+
+	labels := map[string]string{
+		"nuclio.io/platform":      "local",
+		"nuclio.io/namespace":     createFunctionOptions.FunctionConfig.Meta.Namespace,
+		"nuclio.io/function-name": createFunctionOptions.FunctionConfig.Meta.Name,
+		"nuclio.io/function-spec": p.encodeFunctionSpec(&createFunctionOptions.FunctionConfig.Spec),
+	}
+
+	for labelName, labelValue := range createFunctionOptions.FunctionConfig.Meta.Labels {
+		labels[labelName] = labelValue
+	}
+
+	marshalledAnnotations := p.marshallAnnotations(createFunctionOptions.FunctionConfig.Meta.Annotations)
+	if marshalledAnnotations != nil {
+		labels["nuclio.io/annotations"] = string(marshalledAnnotations)
+	}
+
+	// create processor configuration at a temporary location unless user specified a configuration
+	localProcessorConfigPath, err := p.createProcessorConfig(createFunctionOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create processor configuration")
+	}
+
+	// create volumes string[string] map for volumes. the configuration's directory is mounted rather than the
+	// file, so that the file can be replaced while the container runs (and windows containers can only mount
+	// directories)
+	volumesMap := map[string]string{
+		filepath.Dir(localProcessorConfigPath): p.getContainerPath(path.Dir(processorConfigPath)),
+	}
+
+	// cron triggers which persist when they last fired keep it on the host, so that it survives redeployments
+	if p.functionHasPersistentCronTriggers(&createFunctionOptions.FunctionConfig) {
+		cronStateDir := p.getFunctionCronStateDir(createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name)
+
+		if err := os.MkdirAll(cronStateDir, 0755); err != nil {
+			return nil, errors.Wrap(err, "Failed to create cron triggers state directory")
+		}
+
+		volumesMap[cronStateDir] = p.getContainerPath(cronTriggerStateDir)
+	}
+
+	for _, volume := range createFunctionOptions.FunctionConfig.Spec.Volumes {
+
+		// only add hostpath volumes
+		if volume.Volume.HostPath != nil {
+			volumesMap[volume.Volume.HostPath.Path] = p.getContainerPath(volume.VolumeMount.MountPath)
+		}
+	}
+
+	envMap := map[string]string{}
+	for _, env := range createFunctionOptions.FunctionConfig.Spec.Env {
+		envMap[env.Name] = env.Value
+	}
+
+	for envName, envValue := range functionReferencesEnv {
+		envMap[envName] = envValue
+	}
+
+	externalSecretEnv, err := p.resolveExternalSecretEnv(&createFunctionOptions.FunctionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve external secret environment variables")
+	}
+
+	for envName, envValue := range externalSecretEnv {
+		envMap[envName] = envValue
+	}
+
+	envFiles, secretVolumesMap, err := p.getFunctionSecretFiles(&createFunctionOptions.FunctionConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function secrets")
+	}
+
+	containerName := p.GetContainerNameByCreateFunctionOptions(createFunctionOptions)
+	if blueGreen || sideBySide {
+
+		// the new container is given the function's container name once the previous one is removed
+		containerName = p.getNextFunctionContainerName(createFunctionOptions)
+
+		// in case a previous blue/green or side by side deployment was interrupted
+		p.dockerClient.RemoveContainer(containerName) // nolint: errcheck
+	}
+
+	runOptions := &dockerclient.RunOptions{
+		ContainerName:   containerName,
+		Ports:           map[int]int{containerHTTPPort: 8080},
+		Env:             envMap,
+		EnvFiles:        envFiles,
+		Labels:          labels,
+		Volumes:         volumesMap,
+		ReadOnlyVolumes: secretVolumesMap,
+		Network:         functionPlatformConfiguration.Network,
+		ExtraHosts:      functionPlatformConfiguration.ExtraHosts,
+		RestartPolicy:   functionPlatformConfiguration.RestartPolicy,
+	}
+
+	if err := p.populateGRPCPorts(runOptions, &createFunctionOptions.FunctionConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate gRPC ports")
+	}
+
+	p.populateSecurityRunOptions(runOptions, createFunctionOptions.FunctionConfig.Spec.SecurityContext)
+
+	// run the docker image
+	var containerID string
+	err = createFunctionOptions.PhaseTimings.Measure(common.PhasePlatformApply, func() error {
+		var err error
+
+		containerID, err = p.dockerClient.RunContainer(createFunctionOptions.FunctionConfig.Spec.Image, runOptions)
+		return err
 	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to run docker container")
 	}
 
+	// unless the proxy was switched to it, the new container is removed and the previous one keeps serving
+	// the function (rather than being left for inspection). a side by side deployment's new container is always
+	// removed, once it's been replaced by one publishing the function's port
+	switchedToNewContainer := false
+	if blueGreen || sideBySide {
+		newContainerID := containerID
+
+		defer func() {
+			if !switchedToNewContainer {
+				p.dockerClient.RemoveContainer(newContainerID) // nolint: errcheck
+			}
+		}()
+	}
+
 	p.Logger.InfoWith("Waiting for function to be ready", "timeout", createFunctionOptions.FunctionConfig.Spec.ReadinessTimeoutSeconds)
 
 	var readinessTimeout time.Duration
@@ -588,7 +1318,9 @@ func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunction
 		readinessTimeout = abstract.DefaultReadinessTimeoutSeconds * time.Second
 	}
 
-	if err = p.dockerClient.AwaitContainerHealth(containerID, &readinessTimeout); err != nil {
+	if err = createFunctionOptions.PhaseTimings.Measure(common.PhaseReadinessWait, func() error {
+		return p.dockerClient.AwaitContainerHealth(containerID, &readinessTimeout)
+	}); err != nil {
 		var errMessage string
 
 		// try to get error logs
@@ -602,6 +1334,44 @@ func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunction
 		return nil, errors.Wrap(err, errMessage)
 	}
 
+	// warm the function up before it's served, so that a blue/green deployment's previous container keeps
+	// serving the function in the meantime
+	var warmupStatus *functionconfig.WarmupStatus
+	if warmup := createFunctionOptions.FunctionConfig.Spec.Warmup; warmup != nil {
+		warmupStatus, err = p.warmUpFunctionContainer(createFunctionOptions, containerID, containerHTTPPort)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to warm up function")
+		}
+	}
+
+	if blueGreen {
+		switchedToNewContainer = true
+
+		if err = p.switchFunctionContainer(createFunctionOptions,
+			containerID,
+			containerHTTPPort,
+			runOptions,
+			previousContainers); err != nil {
+			return nil, errors.Wrap(err, "Failed to switch to the new function container")
+		}
+	} else if sideBySide {
+		if containerID, err = p.moveFunctionPort(createFunctionOptions,
+			functionHTTPPort,
+			runOptions,
+			previousContainers,
+			readinessTimeout); err != nil {
+			return nil, errors.Wrap(err, "Failed to move the function's port to the new function container")
+		}
+	} else if proxied {
+		if err = p.autoscaler.registerFunction(&createFunctionOptions.FunctionConfig,
+			functionHTTPPort,
+			containerID,
+			containerHTTPPort,
+			runOptions); err != nil {
+			return nil, errors.Wrap(err, "Failed to register function for autoscaling")
+		}
+	}
+
 	return &platform.CreateFunctionResult{
 		CreateFunctionBuildResult: platform.CreateFunctionBuildResult{
 			Image:                 createFunctionOptions.FunctionConfig.Spec.Image,
@@ -609,9 +1379,27 @@ func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunction
 		},
 		Port:        functionHTTPPort,
 		ContainerID: containerID,
+		Warmup:      warmupStatus,
 	}, nil
 }
 
+// warmUpFunctionContainer sends the function's warmup requests to its container, through the port it publishes.
+// failing requests are reported in the returned status, rather than failing the deployment
+func (p *Platform) warmUpFunctionContainer(createFunctionOptions *platform.CreateFunctionOptions,
+	containerID string,
+	containerHTTPPort int) (*functionconfig.WarmupStatus, error) {
+
+	externalIPAddresses, err := p.GetExternalIPAddresses()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get external IP addresses")
+	}
+
+	return abstract.WarmUpFunction(createFunctionOptions.Logger,
+		containerID,
+		fmt.Sprintf("%s:%d", externalIPAddresses[0], containerHTTPPort),
+		createFunctionOptions.FunctionConfig.Spec.Warmup), nil
+}
+
 func (p *Platform) createProcessorConfig(createFunctionOptions *platform.CreateFunctionOptions) (string, error) {
 
 	configWriter, err := processorconfig.NewWriter()
@@ -619,14 +1407,38 @@ func (p *Platform) createProcessorConfig(createFunctionOptions *platform.CreateF
 		return "", errors.Wrap(err, "Failed to create processor configuration writer")
 	}
 
-	// must specify "/tmp" here so that it's available on docker for mac
-	processorConfigFile, err := ioutil.TempFile("/tmp", "processor-config-")
+	// containers mount the configuration's directory, so each configuration has one of its own. on linux, must
+	// specify "/tmp" here so that it's available on docker for mac
+	processorConfigParentDir := ""
+	if p.getContainerOS() != functionconfig.BuildOSWindows {
+		processorConfigParentDir = "/tmp"
+	}
+
+	processorConfigDir, err := ioutil.TempDir(processorConfigParentDir, "processor-config-")
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create temporary processor config directory")
+	}
+
+	// the directory is mounted, so the container must be able to list it regardless of its user
+	if err := os.Chmod(processorConfigDir, 0755); err != nil {
+		return "", errors.Wrap(err, "Failed to set processor config directory permissions")
+	}
+
+	processorConfigFile, err := os.Create(filepath.Join(processorConfigDir, path.Base(processorConfigPath)))
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to create temporary processor config")
 	}
 
 	defer processorConfigFile.Close() // nolint: errcheck
 
+	// a container which doesn't run as the image's user must still be able to read its configuration
+	if securityContext := createFunctionOptions.FunctionConfig.Spec.SecurityContext; securityContext != nil &&
+		securityContext.RunAsUser != nil {
+		if err := processorConfigFile.Chmod(0644); err != nil {
+			return "", errors.Wrap(err, "Failed to set processor config permissions")
+		}
+	}
+
 	if err = configWriter.Write(processorConfigFile, &processor.Configuration{
 		Config: createFunctionOptions.FunctionConfig,
 	}); err != nil {
@@ -681,12 +1493,495 @@ func (p *Platform) getFunctionHTTPPort(createFunctionOptions *platform.CreateFun
 	return freeLocalPort, nil
 }
 
+// isFunctionProxied returns whether the function's port is served by the autoscaler's proxy. when the autoscaler
+// is enabled all functions are, so that they can be scaled and redeployed without downtime
+func (p *Platform) isFunctionProxied(functionConfig *functionconfig.Config) bool {
+	if p.autoscaler != nil {
+		return true
+	}
+
+	if _, maxReplicas := getFunctionReplicaBounds(&functionConfig.Spec); maxReplicas > 1 {
+		p.Logger.WarnWith("Function has more than one replica, but the autoscaler is disabled - running one replica",
+			"name", functionConfig.Meta.Name,
+			"maxReplicas", maxReplicas)
+	}
+
+	return false
+}
+
+// shouldDeployBlueGreen returns whether the function is redeployed blue/green, which requires its port to be
+// served by the autoscaler's proxy already
+func (p *Platform) shouldDeployBlueGreen(functionConfig *functionconfig.Config) bool {
+	if p.autoscaler == nil {
+		return false
+	}
+
+	proxyPort := p.autoscaler.getProxyPort(functionConfig.Meta.Namespace, functionConfig.Meta.Name)
+	if proxyPort == 0 {
+		return false
+	}
+
+	// the proxy can't move to another port
+	configuredHTTPPort := functionConfig.Spec.GetHTTPPort()
+
+	return configuredHTTPPort == 0 || configuredHTTPPort == proxyPort
+}
+
+// switchFunctionContainer switches the function's proxy to its new container, then removes the previous
+// containers and gives the new one the function's container name
+func (p *Platform) switchFunctionContainer(createFunctionOptions *platform.CreateFunctionOptions,
+	containerID string,
+	containerPort int,
+	runOptions *dockerclient.RunOptions,
+	previousContainers []dockerclient.Container) error {
+	containerName := p.GetContainerNameByCreateFunctionOptions(createFunctionOptions)
+
+	// the replicas the autoscaler adds are named after the function's container
+	replicaRunOptions := *runOptions
+	replicaRunOptions.ContainerName = containerName
+
+	if err := p.autoscaler.replaceFunction(&createFunctionOptions.FunctionConfig,
+		containerID,
+		containerPort,
+		&replicaRunOptions); err != nil {
+		return errors.Wrap(err, "Failed to switch function proxy")
+	}
+
+	createFunctionOptions.Logger.InfoWith("Switched to the new function container, removing the previous one",
+		"containerID", containerID)
+
+	for _, previousContainer := range previousContainers {
+		if err := p.dockerClient.RemoveContainer(previousContainer.ID); err != nil {
+			return errors.Wrapf(err, "Failed to remove previous container %s", previousContainer.Name)
+		}
+	}
+
+	if err := p.dockerClient.RenameContainer(containerID, containerName); err != nil {
+		return errors.Wrap(err, "Failed to rename function container")
+	}
+
+	return nil
+}
+
+// moveFunctionPort moves the function's port from its previous containers to a container like the new one,
+// which was verified to be ready on a temporary port. the previous containers are stopped to release the
+// function's ports, and are started again if the container that takes them over doesn't become ready
+func (p *Platform) moveFunctionPort(createFunctionOptions *platform.CreateFunctionOptions,
+	functionHTTPPort int,
+	runOptions *dockerclient.RunOptions,
+	previousContainers []dockerclient.Container,
+	readinessTimeout time.Duration) (string, error) {
+	containerName := p.GetContainerNameByCreateFunctionOptions(createFunctionOptions)
+
+	functionRunOptions := *runOptions
+	functionRunOptions.ContainerName = containerName
+	functionRunOptions.Ports = map[int]int{functionHTTPPort: 8080}
+
+	// the new container keeps the ports of its gRPC triggers until it's removed
+	if err := p.populateGRPCPorts(&functionRunOptions, &createFunctionOptions.FunctionConfig); err != nil {
+		return "", errors.Wrap(err, "Failed to populate gRPC ports")
+	}
+
+	createFunctionOptions.Logger.InfoWith("New function container is ready, moving the function's port to it",
+		"port", functionHTTPPort)
+
+	// the previous containers are kept (under another name) until the function's port is moved
+	for previousContainerIdx, previousContainer := range previousContainers {
+		if err := p.dockerClient.StopContainer(previousContainer.ID); err != nil {
+			p.restorePreviousContainers(previousContainers[:previousContainerIdx], containerName)
+			return "", errors.Wrapf(err, "Failed to stop previous container %s", previousContainer.Name)
+		}
+
+		if err := p.dockerClient.RenameContainer(previousContainer.ID,
+			p.getPreviousFunctionContainerName(containerName, previousContainerIdx)); err != nil {
+			p.restorePreviousContainers(previousContainers[:previousContainerIdx+1], containerName)
+			return "", errors.Wrapf(err, "Failed to rename previous container %s", previousContainer.Name)
+		}
+	}
+
+	containerID, err := p.dockerClient.RunContainer(createFunctionOptions.FunctionConfig.Spec.Image, &functionRunOptions)
+	if err == nil {
+		err = p.dockerClient.AwaitContainerHealth(containerID, &readinessTimeout)
+	}
+
+	if err != nil {
+		if containerID != "" {
+			p.dockerClient.RemoveContainer(containerID) // nolint: errcheck
+		}
+
+		p.restorePreviousContainers(previousContainers, containerName)
+
+		return "", errors.Wrap(err, "Function container wasn't ready on the function's port")
+	}
+
+	for _, previousContainer := range previousContainers {
+		if err := p.dockerClient.RemoveContainer(previousContainer.ID); err != nil {
+			return "", errors.Wrapf(err, "Failed to remove previous container %s", previousContainer.Name)
+		}
+	}
+
+	return containerID, nil
+}
+
+// restorePreviousContainers gives the previous containers their names back and starts them, so that they keep
+// serving the function after a failed deployment
+func (p *Platform) restorePreviousContainers(previousContainers []dockerclient.Container, containerName string) {
+	for _, previousContainer := range previousContainers {
+		if err := p.dockerClient.RenameContainer(previousContainer.ID,
+			strings.TrimPrefix(previousContainer.Name, "/")); err != nil {
+			p.Logger.WarnWith("Failed to restore previous container name",
+				"containerID", previousContainer.ID,
+				"err", err)
+		}
+
+		// containers that weren't running are left as is
+		if previousContainer.State != nil && !previousContainer.State.Running {
+			continue
+		}
+
+		if err := p.dockerClient.StartContainer(previousContainer.ID); err != nil {
+			p.Logger.WarnWith("Failed to start previous container",
+				"containerID", previousContainer.ID,
+				"err", err)
+		}
+	}
+}
+
+func (p *Platform) getNextFunctionContainerName(createFunctionOptions *platform.CreateFunctionOptions) string {
+	return fmt.Sprintf("%s-next", p.GetContainerNameByCreateFunctionOptions(createFunctionOptions))
+}
+
+func (p *Platform) getPreviousFunctionContainerName(containerName string, previousContainerIdx int) string {
+	return fmt.Sprintf("%s-previous-%d", containerName, previousContainerIdx)
+}
+
+// populateGRPCPorts publishes the ports of the function's gRPC triggers on free ports, like the HTTP
+// trigger's port
+func (p *Platform) populateGRPCPorts(runOptions *dockerclient.RunOptions, functionConfig *functionconfig.Config) error {
+	for _, grpcPort := range functionConfig.Spec.GetGRPCPorts() {
+		hostGRPCPort, err := p.getFreeLocalPort()
+		if err != nil {
+			return errors.Wrap(err, "Failed to get free local port")
+		}
+
+		runOptions.Ports[hostGRPCPort] = grpcPort
+	}
+
+	return nil
+}
+
+// populateSecurityRunOptions maps the function's security context to the equivalent docker run options
+func (p *Platform) populateSecurityRunOptions(runOptions *dockerclient.RunOptions,
+	securityContext *functionconfig.SecurityContext) {
+	if securityContext == nil {
+		return
+	}
+
+	// windows containers have neither linux users, capabilities nor seccomp
+	if p.getContainerOS() == functionconfig.BuildOSWindows {
+		p.Logger.WarnWith("Ignoring function security context, which windows containers don't support",
+			"containerName", runOptions.ContainerName)
+		return
+	}
+
+	if securityContext.RunAsUser != nil {
+		runOptions.User = strconv.FormatInt(*securityContext.RunAsUser, 10)
+
+		if securityContext.RunAsGroup != nil {
+			runOptions.User += ":" + strconv.FormatInt(*securityContext.RunAsGroup, 10)
+		}
+	}
+
+	// the processor and runtimes write their temporary files under /tmp, which must remain writable (unless the
+	// function mounts a volume of its own there)
+	if securityContext.ReadOnlyRootFilesystem {
+		runOptions.ReadOnly = true
+
+		tmpMounted := false
+		for _, containerPath := range runOptions.Volumes {
+			if path.Clean(containerPath) == "/tmp" {
+				tmpMounted = true
+			}
+		}
+
+		if !tmpMounted {
+			runOptions.TmpfsPaths = []string{"/tmp"}
+		}
+	}
+
+	runOptions.CapDrop = securityContext.DropCapabilities
+
+	// docker applies its default profile unless told otherwise
+	switch {
+	case securityContext.SeccompProfile == functionconfig.SeccompProfileUnconfined:
+		runOptions.SecurityOpts = []string{"seccomp=unconfined"}
+	case strings.HasPrefix(securityContext.SeccompProfile, functionconfig.SeccompProfileLocalhostPrefix):
+		runOptions.SecurityOpts = []string{"seccomp=" +
+			strings.TrimPrefix(securityContext.SeccompProfile, functionconfig.SeccompProfileLocalhostPrefix)}
+	}
+}
+
+func (p *Platform) functionHasPersistentCronTriggers(functionConfig *functionconfig.Config) bool {
+	for _, cronTrigger := range functionconfig.GetTriggersByKind(functionConfig.Spec.Triggers, "cron") {
+		var cronAttributes struct {
+			Persist bool
+			CatchUp bool
+		}
+
+		if err := mapstructure.Decode(cronTrigger.Attributes, &cronAttributes); err != nil {
+			continue
+		}
+
+		if cronAttributes.Persist || cronAttributes.CatchUp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getFunctionCronStateDir returns the host directory holding when the function's cron triggers last fired
+// (under /tmp, so that it's available on docker for mac)
+// getFunctionSecretFiles returns the env files of the secrets the function takes its env from, and the directories
+// of the secrets it mounts (host path to mount path). locally, secret "name" is the env file name.env or the
+// directory name under the secrets directory
+func (p *Platform) getFunctionSecretFiles(functionConfig *functionconfig.Config) ([]string, map[string]string, error) {
+	secretsDir := common.GetEnvOrDefaultString("NUCLIO_LOCAL_SECRETS_DIR", defaultLocalSecretsDir)
+
+	var envFiles []string
+	for _, envFromSecret := range functionConfig.Spec.EnvFromSecrets {
+		envFile := path.Join(secretsDir, envFromSecret.Name+".env")
+		if !common.FileExists(envFile) {
+			return nil, nil, errors.Errorf("Env file of secret %s doesn't exist: %s", envFromSecret.Name, envFile)
+		}
+
+		envFiles = append(envFiles, envFile)
+	}
+
+	secretVolumesMap := map[string]string{}
+	for _, volumeFromSecret := range functionConfig.Spec.VolumesFromSecrets {
+		secretDir := path.Join(secretsDir, volumeFromSecret.Name)
+		if !common.IsDir(secretDir) {
+			return nil, nil, errors.Errorf("Directory of secret %s doesn't exist: %s", volumeFromSecret.Name, secretDir)
+		}
+
+		secretVolumesMap[secretDir] = p.getContainerPath(volumeFromSecret.MountPath)
+	}
+
+	return envFiles, secretVolumesMap, nil
+}
+
+// resolveExternalSecretEnv reads the values of the function's external secret environment variables through the
+// secret providers of the platform configuration. the values are only passed to the container, which has no
+// platform configuration of its own to read them with
+func (p *Platform) resolveExternalSecretEnv(functionConfig *functionconfig.Config) (map[string]string, error) {
+	if len(functionConfig.Spec.ExternalSecretEnv) == 0 {
+		return nil, nil
+	}
+
+	// nuctl creates platforms with its own configuration, which has no secret providers
+	platformConfig, ok := p.Config.(*platformconfig.Config)
+	if !ok {
+		return nil, errors.New("External secrets are read through the secret providers of the platform " +
+			"configuration, so functions using them must be deployed through the dashboard")
+	}
+
+	secretResolver, err := secretprovider.NewResolver(p.Logger, platformConfig.SecretProviders)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create secret resolver")
+	}
+
+	return secretResolver.Resolve(functionConfig.Spec.ExternalSecretEnv)
+}
+
+// GetSecretData returns the data of a secret, by key. locally, secret "name" is the directory name under the
+// secrets directory, holding a file per key (as mounted by volumesFromSecrets). secrets aren't namespaced
+func (p *Platform) GetSecretData(namespace string, name string) (map[string][]byte, error) {
+	secretDir := path.Join(common.GetEnvOrDefaultString("NUCLIO_LOCAL_SECRETS_DIR", defaultLocalSecretsDir), name)
+	if !common.IsDir(secretDir) {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Directory of secret %s doesn't exist: %s", name, secretDir))
+	}
+
+	secretFiles, err := ioutil.ReadDir(secretDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list directory of secret %s", name)
+	}
+
+	secretData := map[string][]byte{}
+	for _, secretFile := range secretFiles {
+		if secretFile.IsDir() {
+			continue
+		}
+
+		if secretData[secretFile.Name()], err = ioutil.ReadFile(path.Join(secretDir, secretFile.Name())); err != nil {
+			return nil, errors.Wrapf(err, "Failed to read key %s of secret %s", secretFile.Name(), name)
+		}
+	}
+
+	return secretData, nil
+}
+
+func (p *Platform) getFunctionCronStateDir(namespace string, name string) string {
+	return path.Join("/tmp", "nuclio-cron-state", fmt.Sprintf("%s-%s", namespace, name))
+}
+
 func (p *Platform) GetContainerNameByCreateFunctionOptions(createFunctionOptions *platform.CreateFunctionOptions) string {
-	return fmt.Sprintf("nuclio-%s-%s",
-		createFunctionOptions.FunctionConfig.Meta.Namespace,
+	return p.getFunctionContainerName(createFunctionOptions.FunctionConfig.Meta.Namespace,
 		createFunctionOptions.FunctionConfig.Meta.Name)
 }
 
+func (p *Platform) getFunctionContainerName(namespace string, name string) string {
+	return fmt.Sprintf("nuclio-%s-%s", namespace, name)
+}
+
+// resolveFunctionInvokeURL returns the address of the port published by the function's container, verifying
+// the function is actually running. via the domain name, it's the address of the container's port on its network
+func (p *Platform) resolveFunctionInvokeURL(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions) (string, error) {
+	functionName := createFunctionInvocationOptions.Name
+
+	if createFunctionInvocationOptions.Via == platform.InvokeViaLoadBalancer {
+		return "", errors.New("Functions can't be invoked via a load balancer on the local platform")
+	}
+
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      functionName,
+		Namespace: createFunctionInvocationOptions.Namespace,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get functions")
+	}
+
+	if len(functions) == 0 {
+		return "", errors.Errorf("Function not found: %s @ %s", functionName, createFunctionInvocationOptions.Namespace)
+	}
+
+	containerName := p.getFunctionContainerName(createFunctionInvocationOptions.Namespace, functionName)
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name:    containerName,
+		Stopped: true,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get function containers")
+	}
+
+	// only the function's container itself, and not those of its previous deployments (e.g. -previous-0) or
+	// of functions whose names it's a prefix of
+	container := getContainerByName(containers, containerName)
+	if container == nil {
+		return "", errors.Errorf("Function %s isn't running (it has no container)", functionName)
+	}
+
+	if container.State != nil && !container.State.Running {
+		return "", errors.Errorf("Function %s isn't running (its container is %s)", functionName, container.State.Status)
+	}
+
+	// other containers on the function's network reach it by the container's name, at the container's port
+	if createFunctionInvocationOptions.Via == platform.InvokeViaDomainName {
+		containerPort := createFunctionInvocationOptions.Port
+		if containerPort == 0 {
+			containerPort = functions[0].GetConfig().Spec.GetHTTPListenPort()
+		}
+
+		return fmt.Sprintf("%s:%d", containerName, containerPort), nil
+	}
+
+	// container ports which are published on the host, by the host port they're published on
+	publishedPorts := map[int]int{}
+	if container.NetworkSettings != nil {
+		for containerPort, portBindings := range container.NetworkSettings.Ports {
+			if len(portBindings) == 0 {
+				continue
+			}
+
+			containerPortNumber, err := strconv.Atoi(strings.TrimSuffix(string(containerPort), "/tcp"))
+			if err != nil {
+				continue
+			}
+
+			hostPortNumber, err := strconv.Atoi(portBindings[0].HostPort)
+			if err != nil {
+				continue
+			}
+
+			publishedPorts[containerPortNumber] = hostPortNumber
+		}
+	}
+
+	var hostPort int
+
+	switch {
+	case createFunctionInvocationOptions.Port != 0:
+		publishedPort, found := publishedPorts[createFunctionInvocationOptions.Port]
+		if !found {
+			return "", errors.Errorf("Function %s doesn't publish port %d (published ports: %s)",
+				functionName,
+				createFunctionInvocationOptions.Port,
+				p.formatPublishedPorts(publishedPorts))
+		}
+
+		hostPort = publishedPort
+
+	case len(publishedPorts) == 0:
+		return "", errors.Errorf("Function %s doesn't publish any port", functionName)
+
+	case len(publishedPorts) > 1 && publishedPorts[8080] != 0:
+
+		// the HTTP trigger's port, unless another one was asked for
+		hostPort = publishedPorts[8080]
+
+	case len(publishedPorts) > 1:
+		return "", errors.Errorf("Function %s publishes more than one port (%s), the port to invoke must be given",
+			functionName,
+			p.formatPublishedPorts(publishedPorts))
+
+	default:
+		for _, publishedPort := range publishedPorts {
+			hostPort = publishedPort
+		}
+	}
+
+	// the HTTP trigger of a scalable function is served by the autoscaler's proxy
+	if p.autoscaler != nil && hostPort == publishedPorts[8080] {
+		if proxyPort := p.autoscaler.getProxyPort(createFunctionInvocationOptions.Namespace, functionName); proxyPort != 0 {
+			hostPort = proxyPort
+		}
+	}
+
+	externalIPAddresses, err := p.GetExternalIPAddresses()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get external IP addresses")
+	}
+
+	return fmt.Sprintf("%s:%d", externalIPAddresses[0], hostPort), nil
+}
+
+// getContainerByName returns the container named exactly so, or nil if there's none
+func getContainerByName(containers []dockerclient.Container, containerName string) *dockerclient.Container {
+	for containerIdx := range containers {
+		if strings.TrimPrefix(containers[containerIdx].Name, "/") == containerName {
+			return &containers[containerIdx]
+		}
+	}
+
+	return nil
+}
+
+func (p *Platform) formatPublishedPorts(publishedPorts map[int]int) string {
+	var containerPorts []int
+	for containerPort := range publishedPorts {
+		containerPorts = append(containerPorts, containerPort)
+	}
+
+	sort.Ints(containerPorts)
+
+	var formattedPorts []string
+	for _, containerPort := range containerPorts {
+		formattedPorts = append(formattedPorts, fmt.Sprintf("%d->%d", containerPort, publishedPorts[containerPort]))
+	}
+
+	return strings.Join(formattedPorts, ", ")
+}
+
 func (p *Platform) getContainerHTTPTriggerPort(container *dockerclient.Container) int {
 	ports := container.HostConfig.PortBindings["8080/tcp"]
 	if len(ports) == 0 {
@@ -712,11 +2007,45 @@ func (p *Platform) marshallAnnotations(annotations map[string]string) []byte {
 	return marshalledAnnotations
 }
 
+// getFunctionContainers returns the function's own containers, without the replicas the autoscaler added
+func (p *Platform) getFunctionContainers(createFunctionOptions *platform.CreateFunctionOptions) ([]dockerclient.Container, error) {
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name:    p.GetContainerNameByCreateFunctionOptions(createFunctionOptions),
+		Stopped: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function containers")
+	}
+
+	var functionContainers []dockerclient.Container
+	for _, container := range containers {
+		if container.Config != nil {
+			if _, isReplica := container.Config.Labels[functionReplicaLabel]; isReplica {
+				continue
+			}
+		}
+
+		functionContainers = append(functionContainers, container)
+	}
+
+	return functionContainers, nil
+}
+
 func (p *Platform) deletePreviousContainers(createFunctionOptions *platform.CreateFunctionOptions) (int, error) {
 	var previousHTTPPort int
 
 	createFunctionOptions.Logger.InfoWith("Cleaning up before deployment")
 
+	// a registered function's port is served by its proxy, which must release it for the new deployment
+	var proxyPort int
+	if p.autoscaler != nil {
+		var err error
+		if proxyPort, err = p.autoscaler.unregisterFunction(createFunctionOptions.FunctionConfig.Meta.Namespace,
+			createFunctionOptions.FunctionConfig.Meta.Name); err != nil {
+			return 0, errors.Wrap(err, "Failed to unregister function from autoscaler")
+		}
+	}
+
 	getContainerOptions := &dockerclient.GetContainerOptions{
 		Name:    p.GetContainerNameByCreateFunctionOptions(createFunctionOptions),
 		Stopped: true,
@@ -743,9 +2072,59 @@ func (p *Platform) deletePreviousContainers(createFunctionOptions *platform.Crea
 		}
 	}
 
+	// the function's container published another port
+	if proxyPort != 0 {
+		previousHTTPPort = proxyPort
+	}
+
 	return previousHTTPPort, nil
 }
 
+// pruneOldFunctionImages removes all but the most recent images built for the function, never removing
+// the image the function now runs
+func (p *Platform) pruneOldFunctionImages(createFunctionOptions *platform.CreateFunctionOptions,
+	createFunctionResult *platform.CreateFunctionResult) error {
+
+	images, err := p.dockerClient.GetImages(&dockerclient.GetImageOptions{
+		Labels: map[string]string{
+			"nuclio.io/namespace":     createFunctionOptions.FunctionConfig.Meta.Namespace,
+			"nuclio.io/function-name": createFunctionOptions.FunctionConfig.Meta.Name,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function images")
+	}
+
+	// most recent first
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Created.After(images[j].Created)
+	})
+
+	for imageIdx, image := range images {
+		if imageIdx < createFunctionOptions.PruneOldImages ||
+			common.StringSliceContainsString(image.RepoTags, createFunctionResult.Image) {
+			continue
+		}
+
+		// an image may still be used by some other container, in which case it's simply kept
+		if err := p.dockerClient.RemoveImage(image.ID); err != nil {
+			createFunctionOptions.Logger.DebugWith("Failed to remove old function image",
+				"imageID", image.ID,
+				"err", err.Error())
+			continue
+		}
+
+		createFunctionResult.PrunedImages++
+		createFunctionResult.ReclaimedImageBytes += image.Size
+	}
+
+	createFunctionOptions.Logger.DebugWith("Pruned old function images",
+		"images", createFunctionResult.PrunedImages,
+		"reclaimed", common.FormatBytes(createFunctionResult.ReclaimedImageBytes))
+
+	return nil
+}
+
 func (p *Platform) ValidateFunctionContainersHealthiness() {
 	namespaces, err := p.GetNamespaces()
 	if err != nil {
@@ -819,6 +2198,7 @@ func (p *Platform) checkAndSetFunctionUnhealthy(containerID string, function pla
 	if err := p.dockerClient.AwaitContainerHealth(containerID,
 		&p.functionContainersHealthinessTimeout); err != nil {
 		functionStatus := function.GetStatus()
+		checkedState := functionStatus.State
 
 		// set function state to error
 		functionStatus.State = functionconfig.FunctionStateError
@@ -831,10 +2211,7 @@ func (p *Platform) checkAndSetFunctionUnhealthy(containerID string, function pla
 			"functionStatus", functionStatus)
 
 		// function container is not healthy or missing, set function state as error
-		return p.localStore.createOrUpdateFunction(&functionconfig.ConfigWithStatus{
-			Config: *function.GetConfig(),
-			Status: *functionStatus,
-		})
+		return p.storeFunctionStatus(function, checkedState, functionStatus)
 	}
 	return nil
 }
@@ -845,6 +2222,7 @@ func (p *Platform) checkAndSetFunctionHealthy(containerID string, function platf
 		return errors.Wrapf(err, "Failed to ensure healthiness for container id %s", containerID)
 	}
 	functionStatus := function.GetStatus()
+	checkedState := functionStatus.State
 
 	// set function as ready
 	functionStatus.State = functionconfig.FunctionStateReady
@@ -857,8 +2235,26 @@ func (p *Platform) checkAndSetFunctionHealthy(containerID string, function platf
 		"functionStatus", functionStatus)
 
 	// function container is not healthy or missing, set function state as error
-	return p.localStore.createOrUpdateFunction(&functionconfig.ConfigWithStatus{
-		Config: *function.GetConfig(),
-		Status: *functionStatus,
-	})
+	return p.storeFunctionStatus(function, checkedState, functionStatus)
+}
+
+// storeFunctionStatus stores the function's status, keeping its stored configuration - unless the function left
+// the checked state since it was read (e.g. it was paused or redeployed while its health was checked)
+func (p *Platform) storeFunctionStatus(function platform.Function,
+	checkedState functionconfig.FunctionState,
+	functionStatus *functionconfig.Status) error {
+	return p.localStore.updateFunction(&function.GetConfig().Meta,
+		func(configWithStatus *functionconfig.ConfigWithStatus) error {
+			if configWithStatus.Status.State != checkedState {
+				p.Logger.DebugWith("Function state changed while its health was checked, not storing it",
+					"functionName", function.GetConfig().Meta.Name,
+					"state", configWithStatus.Status.State)
+
+				return nil
+			}
+
+			configWithStatus.Status = *functionStatus
+
+			return nil
+		})
 }
@@ -0,0 +1,406 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/config"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// deployRecordingDockerClient records the calls that manage the function's containers, in order
+type deployRecordingDockerClient struct {
+	*dockerclient.MockDockerClient
+	containers []dockerclient.Container
+	calls      []string
+	runErrors  map[string]error
+}
+
+func (c *deployRecordingDockerClient) GetContainers(options *dockerclient.GetContainerOptions) ([]dockerclient.Container, error) {
+	return c.containers, nil
+}
+
+func (c *deployRecordingDockerClient) RunContainer(imageName string, runOptions *dockerclient.RunOptions) (string, error) {
+	c.calls = append(c.calls, fmt.Sprintf("run %s %v", runOptions.ContainerName, runOptions.Ports))
+
+	if err := c.runErrors[runOptions.ContainerName]; err != nil {
+		return "", err
+	}
+
+	return runOptions.ContainerName + "-id", nil
+}
+
+func (c *deployRecordingDockerClient) AwaitContainerHealth(containerID string, timeout *time.Duration) error {
+	c.calls = append(c.calls, "await "+containerID)
+	return nil
+}
+
+func (c *deployRecordingDockerClient) StopContainer(containerID string) error {
+	c.calls = append(c.calls, "stop "+containerID)
+	return nil
+}
+
+func (c *deployRecordingDockerClient) StartContainer(containerID string) error {
+	c.calls = append(c.calls, "start "+containerID)
+	return nil
+}
+
+func (c *deployRecordingDockerClient) RenameContainer(containerID string, name string) error {
+	c.calls = append(c.calls, fmt.Sprintf("rename %s %s", containerID, name))
+	return nil
+}
+
+func (c *deployRecordingDockerClient) RemoveContainer(containerID string) error {
+	c.calls = append(c.calls, "remove "+containerID)
+	return nil
+}
+
+// storeDockerClient keeps the files the store writes in its container, and returns them when they're read
+type storeDockerClient struct {
+	*dockerclient.MockDockerClient
+	containers []dockerclient.Container
+	files      map[string]string
+}
+
+var (
+	storeWriteCommandRegex = regexp.MustCompile(`^/bin/sh -c "mkdir -p \S+ && /bin/printenv NUCLIO_CONTENTS > \S+ && mv -f \S+ (\S+)"$`)
+	storeReadCommandRegex  = regexp.MustCompile(`^/bin/sh -c "/bin/cat (\S+)"$`)
+)
+
+func (c *storeDockerClient) GetContainers(options *dockerclient.GetContainerOptions) ([]dockerclient.Container, error) {
+	return c.containers, nil
+}
+
+func (c *storeDockerClient) ExecInContainer(containerID string, execOptions *dockerclient.ExecOptions) error {
+	if match := storeWriteCommandRegex.FindStringSubmatch(execOptions.Command); match != nil {
+		c.files[match[1]] = execOptions.Env["NUCLIO_CONTENTS"]
+	}
+
+	if match := storeReadCommandRegex.FindStringSubmatch(execOptions.Command); match != nil {
+		contents, found := c.files[match[1]]
+		if !found {
+			return errors.New("No such file or directory")
+		}
+
+		*execOptions.Stdout = contents + "\n"
+	}
+
+	return nil
+}
+
+type platformTestSuite struct {
+	suite.Suite
+	logger       logger.Logger
+	dockerClient *deployRecordingDockerClient
+	platform     *Platform
+}
+
+func (suite *platformTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.dockerClient = &deployRecordingDockerClient{
+		MockDockerClient: dockerclient.NewMockDockerClient(),
+		runErrors:        map[string]error{},
+	}
+
+	// the autoscaler is disabled, as it is for nuctl
+	suite.platform = &Platform{
+		Platform:     &abstract.Platform{Logger: suite.logger},
+		dockerClient: suite.dockerClient,
+	}
+}
+
+func (suite *platformTestSuite) TestDeployExistingFunctionSideBySide() {
+	suite.dockerClient.containers = []dockerclient.Container{suite.newPreviousContainer()}
+
+	createFunctionResult, err := suite.platform.deployFunction(suite.newCreateFunctionOptions())
+	suite.Require().NoError(err)
+	suite.Require().Equal(32100, createFunctionResult.Port)
+	suite.Require().Equal("nuclio-default-my-function-id", createFunctionResult.ContainerID)
+
+	// the previous container keeps serving the function's port until the new container is ready on another
+	// port, and is removed once the port was moved to the new container
+	calls := suite.dockerClient.calls
+	suite.Require().Len(calls, 9)
+	suite.Require().Equal("remove nuclio-default-my-function-next", calls[0])
+	suite.Require().Regexp(`^run nuclio-default-my-function-next map\[\d+:8080\]$`, calls[1])
+	suite.Require().NotContains(calls[1], "32100")
+	suite.Require().Equal([]string{
+		"await nuclio-default-my-function-next-id",
+		"stop previous-id",
+		"rename previous-id nuclio-default-my-function-previous-0",
+		"run nuclio-default-my-function map[32100:8080]",
+		"await nuclio-default-my-function-id",
+		"remove previous-id",
+		"remove nuclio-default-my-function-next-id",
+	}, calls[2:])
+}
+
+func (suite *platformTestSuite) TestDeployExistingFunctionSideBySideRestoresPrevious() {
+	suite.dockerClient.containers = []dockerclient.Container{suite.newPreviousContainer()}
+	suite.dockerClient.runErrors["nuclio-default-my-function"] = errors.New("port is already allocated")
+
+	_, err := suite.platform.deployFunction(suite.newCreateFunctionOptions())
+	suite.Require().Error(err)
+
+	// the previous container is given its name back and serves the function again
+	calls := suite.dockerClient.calls
+	suite.Require().Equal([]string{
+		"stop previous-id",
+		"rename previous-id nuclio-default-my-function-previous-0",
+		"run nuclio-default-my-function map[32100:8080]",
+		"rename previous-id nuclio-default-my-function",
+		"start previous-id",
+		"remove nuclio-default-my-function-next-id",
+	}, calls[3:])
+}
+
+func (suite *platformTestSuite) TestDeployNewFunction() {
+	createFunctionResult, err := suite.platform.deployFunction(suite.newCreateFunctionOptions())
+	suite.Require().NoError(err)
+
+	// there's nothing to replace, so the container publishes the function's port right away
+	suite.Require().Equal([]string{
+		fmt.Sprintf("run nuclio-default-my-function map[%d:8080]", createFunctionResult.Port),
+		"await nuclio-default-my-function-id",
+	}, suite.dockerClient.calls)
+}
+
+func (suite *platformTestSuite) TestPauseAndResumeFunctionTrigger() {
+	createFunctionOptions := suite.newCreateFunctionOptions()
+	createFunctionOptions.FunctionConfig.Spec.Triggers = map[string]functionconfig.Trigger{
+		"my-http": {Kind: "http", MaxWorkers: 1},
+	}
+
+	dockerClient := suite.newStoreDockerClient(&createFunctionOptions.FunctionConfig)
+
+	// the function's container mounts the directory of its processor configuration
+	processorConfigFilePath, err := suite.platform.createProcessorConfig(createFunctionOptions)
+	suite.Require().NoError(err)
+
+	processorConfigDir := filepath.Dir(processorConfigFilePath)
+	defer os.RemoveAll(processorConfigDir) // nolint: errcheck
+
+	dockerClient.containers = []dockerclient.Container{
+		{
+			ID:   "my-function-id",
+			Name: "/nuclio-default-my-function",
+			Mounts: []dockerclient.MountPoint{
+				{Source: processorConfigDir, Destination: path.Dir(processorConfigPath)},
+			},
+		},
+	}
+
+	initialProcessorConfigFileInfo, err := os.Stat(processorConfigFilePath)
+	suite.Require().NoError(err)
+
+	err = suite.platform.PauseFunctionTrigger(&platform.PauseFunctionTriggerOptions{
+		Name:        "my-function",
+		Namespace:   "default",
+		TriggerName: "my-http",
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(suite.readProcessorConfig(processorConfigFilePath).Spec.Triggers["my-http"].Paused)
+
+	// the configuration was replaced rather than written over, and no temporary file was left behind
+	processorConfigFileInfo, err := os.Stat(processorConfigFilePath)
+	suite.Require().NoError(err)
+	suite.Require().False(os.SameFile(initialProcessorConfigFileInfo, processorConfigFileInfo))
+	suite.Require().Equal(initialProcessorConfigFileInfo.Mode(), processorConfigFileInfo.Mode())
+
+	processorConfigFiles, err := ioutil.ReadDir(processorConfigDir)
+	suite.Require().NoError(err)
+	suite.Require().Len(processorConfigFiles, 1)
+	suite.Require().Equal(path.Base(processorConfigPath), processorConfigFiles[0].Name())
+
+	err = suite.platform.ResumeFunctionTrigger(&platform.ResumeFunctionTriggerOptions{
+		Name:        "my-function",
+		Namespace:   "default",
+		TriggerName: "my-http",
+	})
+	suite.Require().NoError(err)
+	suite.Require().False(suite.readProcessorConfig(processorConfigFilePath).Spec.Triggers["my-http"].Paused)
+
+	// the stored function was resumed too
+	functions, err := suite.platform.localStore.getFunctions(&createFunctionOptions.FunctionConfig.Meta)
+	suite.Require().NoError(err)
+	suite.Require().Len(functions, 1)
+	suite.Require().False(functions[0].GetConfig().Spec.Triggers["my-http"].Paused)
+}
+
+func (suite *platformTestSuite) TestResolveFunctionInvokeURL() {
+	createFunctionOptions := suite.newCreateFunctionOptions()
+	createFunctionOptions.FunctionConfig.Spec.Triggers = map[string]functionconfig.Trigger{
+		"my-http": {Kind: "http", URL: ":9090"},
+	}
+
+	dockerClient := suite.newStoreDockerClient(&createFunctionOptions.FunctionConfig)
+	err := suite.platform.SetExternalIPAddresses([]string{"10.0.0.1"})
+	suite.Require().NoError(err)
+
+	newContainer := func(name string, hostPort string) dockerclient.Container {
+		container := dockerclient.Container{
+			Name:            name,
+			State:           &dockerclient.ContainerState{Running: true},
+			NetworkSettings: &dockerclient.NetworkSettings{},
+		}
+		container.NetworkSettings.Ports = dockerclient.PortMap{"8080/tcp": {{HostPort: hostPort}}}
+
+		return container
+	}
+
+	// containers whose names contain the function's container name aren't its container
+	dockerClient.containers = []dockerclient.Container{
+		newContainer("/nuclio-default-my-function-previous-0", "32100"),
+		newContainer("/nuclio-default-my-function2", "32101"),
+		newContainer("/nuclio-default-my-function", "32102"),
+	}
+
+	for _, testCase := range []struct {
+		name              string
+		via               platform.InvokeViaType
+		expectedInvokeURL string
+		expectedError     bool
+	}{
+		{name: "any", via: platform.InvokeViaAny, expectedInvokeURL: "10.0.0.1:32102"},
+		{name: "externalIP", via: platform.InvokeViaExternalIP, expectedInvokeURL: "10.0.0.1:32102"},
+
+		// at the port the HTTP trigger listens on within the container
+		{name: "domainName", via: platform.InvokeViaDomainName, expectedInvokeURL: "nuclio-default-my-function:9090"},
+		{name: "loadBalancer", via: platform.InvokeViaLoadBalancer, expectedError: true},
+	} {
+		suite.Run(testCase.name, func() {
+			invokeURL, err := suite.platform.resolveFunctionInvokeURL(&platform.CreateFunctionInvocationOptions{
+				Name:      "my-function",
+				Namespace: "default",
+				Via:       testCase.via,
+			})
+			if testCase.expectedError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedInvokeURL, invokeURL)
+		})
+	}
+
+	// without the function's own container, it isn't running
+	dockerClient.containers = dockerClient.containers[:2]
+	_, err = suite.platform.resolveFunctionInvokeURL(&platform.CreateFunctionInvocationOptions{
+		Name:      "my-function",
+		Namespace: "default",
+	})
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "it has no container")
+}
+
+// newStoreDockerClient makes the platform's store keep its files in memory, with the function deployed
+func (suite *platformTestSuite) newStoreDockerClient(functionConfig *functionconfig.Config) *storeDockerClient {
+	dockerClient := &storeDockerClient{
+		MockDockerClient: dockerclient.NewMockDockerClient(),
+		files:            map[string]string{},
+	}
+
+	suite.platform.dockerClient = dockerClient
+	suite.platform.localStore = &store{
+		logger:       suite.logger,
+		dockerClient: dockerClient,
+		platform:     suite.platform,
+	}
+
+	encodedFunction, err := json.Marshal(&functionconfig.ConfigWithStatus{
+		Config: *functionConfig,
+		Status: functionconfig.Status{State: functionconfig.FunctionStateReady},
+	})
+	suite.Require().NoError(err)
+
+	dockerClient.files[fmt.Sprintf("/etc/nuclio/store/functions/%s/%s.json",
+		functionConfig.Meta.Namespace,
+		functionConfig.Meta.Name)] = base64.StdEncoding.EncodeToString(encodedFunction)
+
+	return dockerClient
+}
+
+func (suite *platformTestSuite) readProcessorConfig(processorConfigFilePath string) *processor.Configuration {
+	processorConfigFile, err := os.Open(processorConfigFilePath)
+	suite.Require().NoError(err)
+
+	defer processorConfigFile.Close() // nolint: errcheck
+
+	configReader, err := processorconfig.NewReader()
+	suite.Require().NoError(err)
+
+	processorConfiguration := &processor.Configuration{}
+	suite.Require().NoError(configReader.Read(processorConfigFile, processorConfiguration))
+
+	return processorConfiguration
+}
+
+func (suite *platformTestSuite) newPreviousContainer() dockerclient.Container {
+	return dockerclient.Container{
+		ID:   "previous-id",
+		Name: "/nuclio-default-my-function",
+		HostConfig: &dockerclient.HostConfig{
+			PortBindings: dockerclient.PortMap{
+				"8080/tcp": {{HostPort: "32100"}},
+			},
+		},
+		State: &dockerclient.ContainerState{Running: true},
+	}
+}
+
+func (suite *platformTestSuite) newCreateFunctionOptions() *platform.CreateFunctionOptions {
+	return &platform.CreateFunctionOptions{
+		Logger: suite.logger,
+		FunctionConfig: functionconfig.Config{
+			Meta: functionconfig.Meta{
+				Name:      "my-function",
+				Namespace: "default",
+			},
+			Spec: functionconfig.Spec{
+				Image: "my-function:latest",
+			},
+		},
+	}
+}
+
+func TestPlatformTestSuite(t *testing.T) {
+	suite.Run(t, new(platformTestSuite))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// functionProxy serves a function's HTTP port, distributing the requests between its replicas round-robin.
+// it counts the requests each replica got, which is the event throughput the autoscaler considers
+type functionProxy struct {
+	logger   logger.Logger
+	listener net.Listener
+	server   *http.Server
+
+	lock sync.Mutex
+
+	// the addresses (host:port) of the replicas, and the requests sent to each since they were last taken
+	replicaAddresses []string
+	nextReplicaIndex int
+	eventCounts      map[string]int
+}
+
+func newFunctionProxy(parentLogger logger.Logger, port int) (*functionProxy, error) {
+	newFunctionProxy := &functionProxy{
+		logger:      parentLogger.GetChild("proxy"),
+		eventCounts: map[string]int{},
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to listen on port %d", port)
+	}
+
+	newFunctionProxy.listener = listener
+	newFunctionProxy.server = &http.Server{
+		Handler: &httputil.ReverseProxy{
+			Director: newFunctionProxy.direct,
+		},
+	}
+
+	return newFunctionProxy, nil
+}
+
+// start serves requests until the proxy is stopped
+func (fp *functionProxy) start() {
+	go func() {
+		if err := fp.server.Serve(fp.listener); err != nil && err != http.ErrServerClosed {
+			fp.logger.WarnWith("Proxy stopped serving", "err", err.Error())
+		}
+	}()
+}
+
+func (fp *functionProxy) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return fp.server.Shutdown(ctx)
+}
+
+func (fp *functionProxy) getPort() int {
+	return fp.listener.Addr().(*net.TCPAddr).Port
+}
+
+// setReplicaAddresses replaces the replicas requests are distributed between
+func (fp *functionProxy) setReplicaAddresses(replicaAddresses []string) {
+	fp.lock.Lock()
+	defer fp.lock.Unlock()
+
+	fp.replicaAddresses = append([]string{}, replicaAddresses...)
+}
+
+// takeEventCounts returns the number of requests each replica got since the counts were last taken
+func (fp *functionProxy) takeEventCounts() map[string]int {
+	fp.lock.Lock()
+	defer fp.lock.Unlock()
+
+	eventCounts := fp.eventCounts
+	fp.eventCounts = map[string]int{}
+
+	return eventCounts
+}
+
+func (fp *functionProxy) direct(request *http.Request) {
+	replicaAddress := fp.getNextReplicaAddress()
+
+	request.URL.Scheme = "http"
+	request.URL.Host = replicaAddress
+}
+
+func (fp *functionProxy) getNextReplicaAddress() string {
+	fp.lock.Lock()
+	defer fp.lock.Unlock()
+
+	// the request will fail with a bad gateway
+	if len(fp.replicaAddresses) == 0 {
+		return ""
+	}
+
+	replicaAddress := fp.replicaAddresses[fp.nextReplicaIndex%len(fp.replicaAddresses)]
+	fp.nextReplicaIndex = (fp.nextReplicaIndex + 1) % len(fp.replicaAddresses)
+	fp.eventCounts[replicaAddress]++
+
+	return replicaAddress
+}
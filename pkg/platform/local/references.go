@@ -0,0 +1,67 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// getFunctionReferencesEnv returns the URLs of the functions the function references, by env var. locally, a
+// function is reached through the port it publishes on the host - which it keeps as it's redeployed, and which
+// the autoscaler's proxy serves as it's scaled. referenced functions must therefore be deployed first
+func (p *Platform) getFunctionReferencesEnv(functionConfig *functionconfig.Config) (map[string]string, error) {
+	functionReferences, err := functionConfig.Spec.GetFunctionReferences(functionConfig.Meta.Labels["nuclio.io/project-name"])
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function references")
+	}
+
+	if len(functionReferences) == 0 {
+		return nil, nil
+	}
+
+	invokeIPAddresses, err := p.GetDefaultInvokeIPAddresses()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get invoke IP addresses")
+	}
+
+	env := map[string]string{}
+	for _, functionReference := range functionReferences {
+		functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+			Name:      functionReference.FunctionName,
+			Namespace: functionConfig.Meta.Namespace,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get referenced function %s", functionReference.FunctionName)
+		}
+
+		if len(functions) == 0 || functions[0].GetStatus().HTTPPort == 0 {
+			return nil, errors.Errorf("Referenced function %s (%s) must be deployed first",
+				functionReference.FunctionName,
+				functionReference.Alias)
+		}
+
+		env[functionReference.GetEnvName()] = fmt.Sprintf("http://%s:%d",
+			invokeIPAddresses[0],
+			functions[0].GetStatus().HTTPPort)
+	}
+
+	return env, nil
+}
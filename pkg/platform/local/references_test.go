@@ -0,0 +1,106 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type referencesTestSuite struct {
+	suite.Suite
+	dockerClient *execRecordingDockerClient
+	platform     *Platform
+}
+
+func (suite *referencesTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.dockerClient = &execRecordingDockerClient{
+		MockDockerClient: dockerclient.NewMockDockerClient(),
+		outputs:          map[string]string{},
+	}
+
+	suite.platform = &Platform{}
+	suite.platform.localStore, err = newStore(loggerInstance, nil, suite.dockerClient)
+	suite.Require().NoError(err)
+}
+
+func (suite *referencesTestSuite) TestGetFunctionReferencesEnv() {
+	suite.storeFunction("mailer", "shop", 32001)
+	suite.storeFunction("billing", "payments", 32002)
+
+	functionConfig := functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name:      "caller",
+			Namespace: "nuclio",
+			Labels:    map[string]string{"nuclio.io/project-name": "shop"},
+		},
+		Spec: functionconfig.Spec{
+			FunctionReferences: map[string]string{
+				"mailer":  "mailer",
+				"billing": "payments/billing",
+			},
+		},
+	}
+
+	env, err := suite.platform.getFunctionReferencesEnv(&functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{
+		"NUCLIO_FUNCTION_URL_BILLING": "http://172.17.0.1:32002",
+		"NUCLIO_FUNCTION_URL_MAILER":  "http://172.17.0.1:32001",
+	}, env)
+
+	// functions must be deployed before they're referenced, so that their port is known
+	functionConfig.Spec.FunctionReferences["reports"] = "reports"
+
+	_, err = suite.platform.getFunctionReferencesEnv(&functionConfig)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Referenced function reports (reports) must be deployed first")
+}
+
+func (suite *referencesTestSuite) storeFunction(name string, projectName string, httpPort int) {
+	encodedFunction, err := json.Marshal(&functionconfig.ConfigWithStatus{
+		Config: functionconfig.Config{
+			Meta: functionconfig.Meta{
+				Name:      name,
+				Namespace: "nuclio",
+				Labels:    map[string]string{"nuclio.io/project-name": projectName},
+			},
+		},
+		Status: functionconfig.Status{
+			State:    functionconfig.FunctionStateReady,
+			HTTPPort: httpPort,
+		},
+	})
+	suite.Require().NoError(err)
+
+	command := `/bin/sh -c "/bin/cat /etc/nuclio/store/functions/nuclio/` + name + `.json"`
+	suite.dockerClient.outputs[command] = base64.StdEncoding.EncodeToString(encodedFunction) + "\n"
+}
+
+func TestReferencesTestSuite(t *testing.T) {
+	suite.Run(t, new(referencesTestSuite))
+}
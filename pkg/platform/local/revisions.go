@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// GetFunctionRevisions returns the configurations the function was deployed with, oldest first, as kept in
+// the local store
+func (p *Platform) GetFunctionRevisions(getFunctionRevisionsOptions *platform.GetFunctionRevisionsOptions) (
+	[]platform.FunctionRevision, error) {
+
+	functionMeta := &functionconfig.Meta{
+		Name:      getFunctionRevisionsOptions.Name,
+		Namespace: getFunctionRevisionsOptions.Namespace,
+	}
+
+	functions, err := p.localStore.getFunctions(functionMeta)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			functionMeta.Name,
+			functionMeta.Namespace))
+	}
+
+	return p.localStore.getFunctionRevisions(functionMeta)
+}
+
+// recordFunctionRevision adds the configuration the function was just deployed with to its revisions, along
+// with the ID of the image its container runs
+func (p *Platform) recordFunctionRevision(functionConfig *functionconfig.Config) error {
+
+	// the image ID is only informative, so failing to get it doesn't fail recording the revision
+	var imageID string
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name: p.getFunctionContainerName(functionConfig.Meta.Namespace, functionConfig.Meta.Name),
+	})
+	if err != nil {
+		p.Logger.DebugWith("Failed to get function container", "err", err.Error())
+	} else if len(containers) > 0 {
+		imageID = containers[0].Image
+	}
+
+	return p.localStore.updateFunctionRevisions(&functionConfig.Meta,
+		func(revisions []platform.FunctionRevision) []platform.FunctionRevision {
+			return platform.AddFunctionRevision(revisions, functionConfig, imageID)
+		})
+}
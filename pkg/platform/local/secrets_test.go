@@ -0,0 +1,102 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type secretsTestSuite struct {
+	suite.Suite
+	secretsDir string
+	platform   *Platform
+}
+
+func (suite *secretsTestSuite) SetupTest() {
+	var err error
+
+	suite.secretsDir, err = ioutil.TempDir("", "nuclio-secrets-test")
+	suite.Require().NoError(err)
+
+	err = os.Setenv("NUCLIO_LOCAL_SECRETS_DIR", suite.secretsDir)
+	suite.Require().NoError(err)
+
+	suite.platform = &Platform{dockerClient: dockerclient.NewMockDockerClient()}
+}
+
+func (suite *secretsTestSuite) TearDownTest() {
+	os.Unsetenv("NUCLIO_LOCAL_SECRETS_DIR") // nolint: errcheck
+	os.RemoveAll(suite.secretsDir)          // nolint: errcheck
+}
+
+func (suite *secretsTestSuite) TestGetFunctionSecretFiles() {
+	err := ioutil.WriteFile(path.Join(suite.secretsDir, "credentials.env"), []byte("PASSWORD=hunter2\n"), 0600)
+	suite.Require().NoError(err)
+
+	err = os.Mkdir(path.Join(suite.secretsDir, "certificates"), 0700)
+	suite.Require().NoError(err)
+
+	functionConfig := functionconfig.Config{
+		Spec: functionconfig.Spec{
+			EnvFromSecrets:     []functionconfig.EnvFromSecret{{Name: "credentials"}},
+			VolumesFromSecrets: []functionconfig.VolumeFromSecret{{Name: "certificates", MountPath: "/etc/certs"}},
+		},
+	}
+
+	envFiles, secretVolumesMap, err := suite.platform.getFunctionSecretFiles(&functionConfig)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{path.Join(suite.secretsDir, "credentials.env")}, envFiles)
+	suite.Require().Equal(map[string]string{path.Join(suite.secretsDir, "certificates"): "/etc/certs"}, secretVolumesMap)
+}
+
+func (suite *secretsTestSuite) TestGetFunctionSecretFilesMissingSecret() {
+	functionConfig := functionconfig.Config{
+		Spec: functionconfig.Spec{
+			EnvFromSecrets: []functionconfig.EnvFromSecret{{Name: "missing"}},
+		},
+	}
+
+	_, _, err := suite.platform.getFunctionSecretFiles(&functionConfig)
+	suite.Require().Error(err)
+}
+
+func (suite *secretsTestSuite) TestGetSecretData() {
+	err := os.Mkdir(path.Join(suite.secretsDir, "git-credentials"), 0700)
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(path.Join(suite.secretsDir, "git-credentials", "ssh-privatekey"), []byte("key"), 0600)
+	suite.Require().NoError(err)
+
+	secretData, err := suite.platform.GetSecretData("nuclio", "git-credentials")
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string][]byte{"ssh-privatekey": []byte("key")}, secretData)
+
+	_, err = suite.platform.GetSecretData("nuclio", "missing")
+	suite.Require().Error(err)
+}
+
+func TestSecretsTestSuite(t *testing.T) {
+	suite.Run(t, new(secretsTestSuite))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type securityTestSuite struct {
+	suite.Suite
+	platform *Platform
+}
+
+func (suite *securityTestSuite) SetupTest() {
+	suite.platform = &Platform{dockerClient: dockerclient.NewMockDockerClient()}
+}
+
+func (suite *securityTestSuite) TestPopulateSecurityRunOptions() {
+	runAsUser := int64(1000)
+	runAsGroup := int64(3000)
+
+	runOptions := &dockerclient.RunOptions{}
+	suite.platform.populateSecurityRunOptions(runOptions, &functionconfig.SecurityContext{
+		RunAsUser:              &runAsUser,
+		RunAsGroup:             &runAsGroup,
+		ReadOnlyRootFilesystem: true,
+		DropCapabilities:       []string{"ALL"},
+		SeccompProfile:         "localhost/profiles/nuclio.json",
+	})
+
+	suite.Require().Equal("1000:3000", runOptions.User)
+	suite.Require().True(runOptions.ReadOnly)
+	suite.Require().Equal([]string{"/tmp"}, runOptions.TmpfsPaths)
+	suite.Require().Equal([]string{"ALL"}, runOptions.CapDrop)
+	suite.Require().Equal([]string{"seccomp=profiles/nuclio.json"}, runOptions.SecurityOpts)
+
+	// a volume mounted at /tmp keeps it writable, and docker's default seccomp profile needn't be asked for
+	runOptions = &dockerclient.RunOptions{
+		Volumes: map[string]string{"/var/nuclio/tmp": "/tmp/"},
+	}
+	suite.platform.populateSecurityRunOptions(runOptions, &functionconfig.SecurityContext{
+		ReadOnlyRootFilesystem: true,
+		SeccompProfile:         functionconfig.SeccompProfileRuntimeDefault,
+	})
+
+	suite.Require().Empty(runOptions.User)
+	suite.Require().True(runOptions.ReadOnly)
+	suite.Require().Empty(runOptions.TmpfsPaths)
+	suite.Require().Empty(runOptions.SecurityOpts)
+
+	// nothing changes without a security context
+	runOptions = &dockerclient.RunOptions{}
+	suite.platform.populateSecurityRunOptions(runOptions, nil)
+	suite.Require().Equal(&dockerclient.RunOptions{}, runOptions)
+}
+
+func TestSecurityTestSuite(t *testing.T) {
+	suite.Run(t, new(securityTestSuite))
+}
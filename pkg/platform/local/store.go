@@ -23,7 +23,10 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
@@ -31,21 +34,40 @@ import (
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/rs/xid"
 )
 
 const (
-	volumeName        = "nuclio-local-storage"
-	containerName     = "nuclio-local-storage-reader"
-	baseDir           = "/etc/nuclio/store"
-	functionsDir      = baseDir + "/functions"
-	projectsDir       = baseDir + "/projects"
-	functionEventsDir = baseDir + "/function-events"
+	volumeName             = "nuclio-local-storage"
+	containerName          = "nuclio-local-storage-reader"
+	baseDir                = "/etc/nuclio/store"
+	functionsDir           = baseDir + "/functions"
+	functionRevisionsDir   = baseDir + "/function-revisions"
+	functionInvocationsDir = baseDir + "/function-invocations"
+	auditDir               = baseDir + "/audit"
+	projectsDir            = baseDir + "/projects"
+	functionEventsDir      = baseDir + "/function-events"
+	apiGatewaysDir         = baseDir + "/api-gateways"
+	locksDir               = baseDir + "/locks"
+)
+
+// a resource is locked by the nuctl invocation that creates its lock directory (mkdir being atomic). a lock that's
+// held for longer than lockStaleMinutes was left by an invocation that died holding it, and is broken
+const (
+	lockRetryInterval = 100 * time.Millisecond
+	lockTimeout       = 30 * time.Second
+	lockStaleMinutes  = 1
 )
 
 type store struct {
 	logger       logger.Logger
 	dockerClient dockerclient.Client
 	platform     platform.Platform
+
+	// a docker daemon running windows containers can't run the (linux) storage container, so the store is
+	// kept by the host's shell instead
+	hostCmdRunner     cmdrunner.CmdRunner
+	hostCmdRunnerOnce sync.Once
 }
 
 func newStore(parentLogger logger.Logger,
@@ -63,10 +85,7 @@ func newStore(parentLogger logger.Logger,
 //
 
 func (s *store) createOrUpdateProject(projectConfig *platform.ProjectConfig) error {
-	resourcePath := s.getResourcePath(projectsDir, projectConfig.Meta.Namespace, projectConfig.Meta.Name)
-
-	// write the contents to that file name at the appropriate path
-	return s.serializeAndWriteFileContents(resourcePath, projectConfig)
+	return s.writeResource(projectsDir, projectConfig.Meta.Namespace, projectConfig.Meta.Name, projectConfig)
 }
 
 func (s *store) getProjects(projectMeta *platform.ProjectMeta) ([]platform.Project, error) {
@@ -102,10 +121,7 @@ func (s *store) deleteProject(projectMeta *platform.ProjectMeta) error {
 //
 
 func (s *store) createOrUpdateFunctionEvent(functionEventConfig *platform.FunctionEventConfig) error {
-	resourcePath := s.getResourcePath(functionEventsDir, functionEventConfig.Meta.Namespace, functionEventConfig.Meta.Name)
-
-	// write the contents to that file name at the appropriate path
-	return s.serializeAndWriteFileContents(resourcePath, functionEventConfig)
+	return s.writeResource(functionEventsDir, functionEventConfig.Meta.Namespace, functionEventConfig.Meta.Name, functionEventConfig)
 }
 
 func (s *store) getFunctionEvents(functionEventMeta *platform.FunctionEventMeta) ([]platform.FunctionEvent, error) {
@@ -147,15 +163,49 @@ func (s *store) deleteFunctionEvent(functionEventMeta *platform.FunctionEventMet
 	return s.deleteResource(functionEventsDir, functionEventMeta.Namespace, functionEventMeta.Name)
 }
 
+//
+// API gateways
+//
+
+func (s *store) createOrUpdateAPIGateway(apiGatewayConfig *platform.APIGatewayConfig) error {
+	return s.writeResource(apiGatewaysDir, apiGatewayConfig.Meta.Namespace, apiGatewayConfig.Meta.Name, apiGatewayConfig)
+}
+
+// getAPIGateways returns the API gateways of a namespace, or those of all namespaces if it's "*"
+func (s *store) getAPIGateways(apiGatewayMeta *platform.APIGatewayMeta) ([]platform.APIGateway, error) {
+	var apiGateways []platform.APIGateway
+
+	rowHandler := func(row []byte) error {
+		newAPIGateway := platform.AbstractAPIGateway{}
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, &newAPIGateway.APIGatewayConfig); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal API gateway")
+		}
+
+		apiGateways = append(apiGateways, &newAPIGateway)
+
+		return nil
+	}
+
+	err := s.getResources(apiGatewaysDir, apiGatewayMeta.Namespace, apiGatewayMeta.Name, rowHandler)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get API gateways")
+	}
+
+	return apiGateways, nil
+}
+
+func (s *store) deleteAPIGateway(apiGatewayMeta *platform.APIGatewayMeta) error {
+	return s.deleteResource(apiGatewaysDir, apiGatewayMeta.Namespace, apiGatewayMeta.Name)
+}
+
 //
 // Function (used only for the period before there's a docker container to represent the function)
 //
 
 func (s *store) createOrUpdateFunction(functionConfig *functionconfig.ConfigWithStatus) error {
-	resourcePath := s.getResourcePath(functionsDir, functionConfig.Meta.Namespace, functionConfig.Meta.Name)
-
-	// write the contents to that file name at the appropriate path
-	return s.serializeAndWriteFileContents(resourcePath, functionConfig)
+	return s.writeResource(functionsDir, functionConfig.Meta.Namespace, functionConfig.Meta.Name, functionConfig)
 }
 
 func (s *store) getFunctions(functionMeta *functionconfig.Meta) ([]platform.Function, error) {
@@ -187,14 +237,226 @@ func (s *store) getFunctions(functionMeta *functionconfig.Meta) ([]platform.Func
 	return functions, nil
 }
 
+// updateFunction has updater change the stored function, while holding it locked - so that concurrent changes
+// (e.g. pausing the function while its health is checked) aren't lost
+func (s *store) updateFunction(functionMeta *functionconfig.Meta,
+	updater func(*functionconfig.ConfigWithStatus) error) error {
+	unlock, err := s.lockResource(functionsDir, functionMeta.Namespace, functionMeta.Name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock function")
+	}
+
+	defer unlock()
+
+	var configWithStatus *functionconfig.ConfigWithStatus
+
+	rowHandler := func(row []byte) error {
+		configWithStatus = &functionconfig.ConfigWithStatus{}
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, configWithStatus); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal function")
+		}
+
+		return nil
+	}
+
+	if err := s.getResources(functionsDir, functionMeta.Namespace, functionMeta.Name, rowHandler); err != nil {
+		return errors.Wrap(err, "Failed to get function")
+	}
+
+	if configWithStatus == nil {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			functionMeta.Name,
+			functionMeta.Namespace))
+	}
+
+	if err := updater(configWithStatus); err != nil {
+		return err
+	}
+
+	resourcePath := s.getResourcePath(functionsDir, functionMeta.Namespace, functionMeta.Name)
+
+	return s.serializeAndWriteFileContents(resourcePath, configWithStatus)
+}
+
 func (s *store) deleteFunction(functionMeta *functionconfig.Meta) error {
 	return s.deleteResource(functionsDir, functionMeta.Namespace, functionMeta.Name)
 }
 
+//
+// Function revisions (all the revisions of a function are kept in a single resource)
+//
+
+func (s *store) createOrUpdateFunctionRevisions(functionMeta *functionconfig.Meta,
+	revisions []platform.FunctionRevision) error {
+	return s.writeResource(functionRevisionsDir, functionMeta.Namespace, functionMeta.Name, revisions)
+}
+
+func (s *store) getFunctionRevisions(functionMeta *functionconfig.Meta) ([]platform.FunctionRevision, error) {
+	var revisions []platform.FunctionRevision
+
+	rowHandler := func(row []byte) error {
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, &revisions); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal function revisions")
+		}
+
+		return nil
+	}
+
+	err := s.getResources(functionRevisionsDir, functionMeta.Namespace, functionMeta.Name, rowHandler)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function revisions")
+	}
+
+	return revisions, nil
+}
+
+// updateFunctionRevisions replaces the revisions of a function with those returned by updater, while holding
+// the function's revisions locked - so that concurrent deployments don't lose each other's revisions
+func (s *store) updateFunctionRevisions(functionMeta *functionconfig.Meta,
+	updater func([]platform.FunctionRevision) []platform.FunctionRevision) error {
+	unlock, err := s.lockResource(functionRevisionsDir, functionMeta.Namespace, functionMeta.Name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock function revisions")
+	}
+
+	defer unlock()
+
+	revisions, err := s.getFunctionRevisions(functionMeta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function revisions")
+	}
+
+	resourcePath := s.getResourcePath(functionRevisionsDir, functionMeta.Namespace, functionMeta.Name)
+
+	return s.serializeAndWriteFileContents(resourcePath, updater(revisions))
+}
+
+func (s *store) deleteFunctionRevisions(functionMeta *functionconfig.Meta) error {
+	return s.deleteResource(functionRevisionsDir, functionMeta.Namespace, functionMeta.Name)
+}
+
+func (s *store) getFunctionInvocations(functionMeta *functionconfig.Meta) (*platform.FunctionInvocations, error) {
+	functionInvocations := &platform.FunctionInvocations{}
+
+	rowHandler := func(row []byte) error {
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, functionInvocations); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal function invocations")
+		}
+
+		return nil
+	}
+
+	err := s.getResources(functionInvocationsDir, functionMeta.Namespace, functionMeta.Name, rowHandler)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function invocations")
+	}
+
+	return functionInvocations, nil
+}
+
+// updateFunctionInvocations has updater change the invocations of a function, while holding them locked - so
+// that invocations gathered concurrently aren't lost
+func (s *store) updateFunctionInvocations(functionMeta *functionconfig.Meta,
+	updater func(*platform.FunctionInvocations)) error {
+	unlock, err := s.lockResource(functionInvocationsDir, functionMeta.Namespace, functionMeta.Name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock function invocations")
+	}
+
+	defer unlock()
+
+	functionInvocations, err := s.getFunctionInvocations(functionMeta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get function invocations")
+	}
+
+	updater(functionInvocations)
+
+	resourcePath := s.getResourcePath(functionInvocationsDir, functionMeta.Namespace, functionMeta.Name)
+
+	return s.serializeAndWriteFileContents(resourcePath, functionInvocations)
+}
+
+func (s *store) deleteFunctionInvocations(functionMeta *functionconfig.Meta) error {
+	return s.deleteResource(functionInvocationsDir, functionMeta.Namespace, functionMeta.Name)
+}
+
+//
+// Audit (all the records of a namespace are kept in a single resource)
+//
+
+// the name of the resource keeping the audit records of a namespace
+const auditRecordsResourceName = "records"
+
+func (s *store) getAuditRecords(namespace string) ([]platform.AuditRecord, error) {
+	var records []platform.AuditRecord
+
+	rowHandler := func(row []byte) error {
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, &records); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal audit records")
+		}
+
+		return nil
+	}
+
+	if err := s.getResources(auditDir, namespace, auditRecordsResourceName, rowHandler); err != nil {
+		return nil, errors.Wrap(err, "Failed to get audit records")
+	}
+
+	return records, nil
+}
+
+// addAuditRecord adds the record to the audit records of its namespace, while holding them locked - so that
+// concurrent operations don't lose each other's records
+func (s *store) addAuditRecord(auditRecord *platform.AuditRecord) error {
+	unlock, err := s.lockResource(auditDir, auditRecord.Namespace, auditRecordsResourceName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock audit records")
+	}
+
+	defer unlock()
+
+	records, err := s.getAuditRecords(auditRecord.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get audit records")
+	}
+
+	resourcePath := s.getResourcePath(auditDir, auditRecord.Namespace, auditRecordsResourceName)
+
+	return s.serializeAndWriteFileContents(resourcePath, platform.AddAuditRecord(records, auditRecord))
+}
+
 //
 // Implementation
 //
 
+// writeResource writes the resource while holding it locked, so that it doesn't overwrite a concurrent
+// read-modify-write update of it
+func (s *store) writeResource(resourceDir string,
+	resourceNamespace string,
+	resourceName string,
+	resourceConfig interface{}) error {
+	unlock, err := s.lockResource(resourceDir, resourceNamespace, resourceName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock resource")
+	}
+
+	defer unlock()
+
+	resourcePath := s.getResourcePath(resourceDir, resourceNamespace, resourceName)
+
+	// write the contents to that file name at the appropriate path
+	return s.serializeAndWriteFileContents(resourcePath, resourceConfig)
+}
+
 func (s *store) serializeAndWriteFileContents(resourcePath string, resourceConfig interface{}) error {
 
 	// serialize the resource to json
@@ -225,7 +487,7 @@ func (s *store) getResources(resourceDir string,
 	if resourceName != "" {
 		resourcePath = s.getResourcePath(resourceDir, resourceNamespace, resourceName)
 	} else {
-		resourcePath = path.Join(s.getResourceNamespaceDir(resourceDir, resourceNamespace), "*")
+		resourcePath = path.Join(s.getResourceNamespaceDir(resourceDir, resourceNamespace), "*.json")
 	}
 
 	commandStdout, _, err := s.runCommand(nil, `/bin/sh -c "/bin/cat %s"`, resourcePath)
@@ -274,21 +536,72 @@ func (s *store) writeFileContents(filePath string, contents []byte) error {
 	// set NUCLIO_CONTENTS as base64 encoded value
 	env := map[string]string{"NUCLIO_CONTENTS": base64.StdEncoding.EncodeToString(contents)}
 
+	// write a hidden temporary file and rename it, which is atomic - so that a concurrent reader never reads a
+	// partially written file, and concurrent writers don't interleave
+	temporaryFilePath := path.Join(fileDir, fmt.Sprintf(".%s.%s", path.Base(filePath), xid.New().String()))
+
 	// generate a command
 	_, _, err := s.runCommand(env,
-		`/bin/sh -c "mkdir -p %s && /bin/printenv NUCLIO_CONTENTS > %s"`,
+		`/bin/sh -c "mkdir -p %s && /bin/printenv NUCLIO_CONTENTS > %s && mv -f %s %s"`,
 		fileDir,
+		temporaryFilePath,
+		temporaryFilePath,
 		filePath)
 
 	return err
 }
 
+// lockResource locks a resource for all the nuctl invocations sharing the store, waiting for up to lockTimeout
+// for it to be unlocked. the returned function unlocks it
+func (s *store) lockResource(resourceDir string, resourceNamespace string, resourceName string) (func(), error) {
+	lockPath := path.Join(locksDir, fmt.Sprintf("%s.%s.%s", path.Base(resourceDir), resourceNamespace, resourceName))
+
+	for deadline := time.Now().Add(lockTimeout); ; {
+		if _, _, err := s.runCommand(nil, `/bin/sh -c "mkdir -p %s && mkdir %s"`, locksDir, lockPath); err == nil {
+			break
+		}
+
+		// break a stale lock, left by an invocation that died holding it
+		staleLockPath, _, err := s.runCommand(nil, "/usr/bin/find %s -maxdepth 0 -mmin +%d", lockPath, lockStaleMinutes)
+		if err == nil && strings.TrimSpace(staleLockPath) != "" {
+			s.logger.WarnWith("Breaking stale lock", "path", lockPath)
+
+			s.runCommand(nil, "/bin/rmdir %s", lockPath) // nolint: errcheck
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("Timed out waiting for lock %s", lockPath)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() {
+		if _, _, err := s.runCommand(nil, "/bin/rmdir %s", lockPath); err != nil {
+			s.logger.WarnWith("Failed to unlock", "path", lockPath, "err", err.Error())
+		}
+	}, nil
+}
+
 func (s *store) runCommand(env map[string]string, format string, args ...interface{}) (string, string, error) {
 	var commandStdout, commandStderr string
 
 	// format the command to a string
 	command := fmt.Sprintf(format, args...)
 
+	if hostCmdRunner := s.getHostCmdRunner(); hostCmdRunner != nil {
+		runResult, err := hostCmdRunner.Run(&cmdrunner.RunOptions{
+			Env:               env,
+			CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+		}, "%s", command)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "Failed to execute command: %s", command)
+		}
+
+		return runResult.Output, runResult.Stderr, nil
+	}
+
 	// execute a command within a container called `containerName`. if it fails because the container doesn't exist,
 	// try to run the container. if it fails because it's already created, run exec again (could be that multiple
 	// calls to getResources occurred at the same time). Repeat this 3 times
@@ -333,11 +646,37 @@ func (s *store) runCommand(env map[string]string, format string, args ...interfa
 	return commandStdout, commandStderr, nil
 }
 
+// getHostCmdRunner returns the runner of the host's shell if the store is kept by it, or nil if it's kept by the
+// storage container
+func (s *store) getHostCmdRunner() cmdrunner.CmdRunner {
+	s.hostCmdRunnerOnce.Do(func() {
+		containerOS, err := s.dockerClient.GetServerOS()
+		if err != nil || containerOS != functionconfig.BuildOSWindows {
+			return
+		}
+
+		s.logger.DebugWith("Docker runs windows containers, keeping the store on the host", "baseDir", baseDir)
+
+		if s.hostCmdRunner, err = cmdrunner.NewShellRunner(s.logger); err != nil {
+			s.logger.WarnWith("Failed to create host command runner", "err", err.Error())
+		}
+	})
+
+	return s.hostCmdRunner
+}
+
 func (s *store) deleteResource(resourceDir string, resourceNamespace string, resourceName string) error {
+	unlock, err := s.lockResource(resourceDir, resourceNamespace, resourceName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock resource")
+	}
+
+	defer unlock()
+
 	resourcePath := s.getResourcePath(resourceDir, resourceNamespace, resourceName)
 
 	// stat the file
-	_, _, err := s.runCommand(nil, "/bin/stat %s", resourcePath)
+	_, _, err = s.runCommand(nil, "/bin/stat %s", resourcePath)
 	if err != nil {
 		return nuclio.ErrNotFound
 	}
@@ -0,0 +1,178 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// execRecordingDockerClient records the commands executed in the store's container, failing those that begin
+// with one of the given prefixes (once per occurrence) and returning the given output for the others
+type execRecordingDockerClient struct {
+	*dockerclient.MockDockerClient
+	commands        []string
+	failingCommands []string
+	outputs         map[string]string
+}
+
+func (c *execRecordingDockerClient) ExecInContainer(containerID string, execOptions *dockerclient.ExecOptions) error {
+	c.commands = append(c.commands, execOptions.Command)
+
+	for failingCommandIndex, failingCommand := range c.failingCommands {
+		if strings.HasPrefix(execOptions.Command, failingCommand) {
+			c.failingCommands = append(c.failingCommands[:failingCommandIndex], c.failingCommands[failingCommandIndex+1:]...)
+			return errors.New("File exists")
+		}
+	}
+
+	if execOptions.Stdout != nil {
+		*execOptions.Stdout = c.outputs[execOptions.Command]
+	}
+
+	return nil
+}
+
+type storeTestSuite struct {
+	suite.Suite
+	dockerClient *execRecordingDockerClient
+	store        *store
+}
+
+func (suite *storeTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.dockerClient = &execRecordingDockerClient{
+		MockDockerClient: dockerclient.NewMockDockerClient(),
+		outputs:          map[string]string{},
+	}
+
+	suite.store, err = newStore(loggerInstance, nil, suite.dockerClient)
+	suite.Require().NoError(err)
+}
+
+func (suite *storeTestSuite) TestWriteAtomically() {
+	err := suite.store.createOrUpdateFunction(&functionconfig.ConfigWithStatus{
+		Config: functionconfig.Config{
+			Meta: functionconfig.Meta{Name: "my-function", Namespace: "nuclio"},
+		},
+	})
+	suite.Require().NoError(err)
+
+	// the function is written to a hidden temporary file, which is then renamed - while holding it locked
+	suite.Require().Len(suite.dockerClient.commands, 3)
+	suite.Require().Equal(`/bin/sh -c "mkdir -p /etc/nuclio/store/locks && mkdir /etc/nuclio/store/locks/functions.nuclio.my-function"`,
+		suite.dockerClient.commands[0])
+	suite.Require().Regexp(regexp.MustCompile(`^/bin/sh -c "mkdir -p /etc/nuclio/store/functions/nuclio && `+
+		`/bin/printenv NUCLIO_CONTENTS > (/etc/nuclio/store/functions/nuclio/\.my-function\.json\.\w+) && `+
+		`mv -f (/etc/nuclio/store/functions/nuclio/\.my-function\.json\.\w+) /etc/nuclio/store/functions/nuclio/my-function\.json"$`),
+		suite.dockerClient.commands[1])
+	suite.Require().Equal("/bin/rmdir /etc/nuclio/store/locks/functions.nuclio.my-function", suite.dockerClient.commands[2])
+
+	// temporary files are never read
+	_, err = suite.store.getFunctions(&functionconfig.Meta{Namespace: "nuclio"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(`/bin/sh -c "/bin/cat /etc/nuclio/store/functions/nuclio/*.json"`, suite.dockerClient.commands[3])
+}
+
+func (suite *storeTestSuite) TestUpdateFunction() {
+	lockCommand := `/bin/sh -c "mkdir -p /etc/nuclio/store/locks && mkdir /etc/nuclio/store/locks/functions.nuclio.my-function"`
+	readCommand := `/bin/sh -c "/bin/cat /etc/nuclio/store/functions/nuclio/my-function.json"`
+
+	suite.dockerClient.outputs[readCommand] = base64.StdEncoding.EncodeToString(
+		[]byte(`{"metadata":{"name":"my-function","namespace":"nuclio"},"spec":{"disable":true},"status":{"state":"paused"}}`)) + "\n"
+
+	err := suite.store.updateFunction(&functionconfig.Meta{Name: "my-function", Namespace: "nuclio"},
+		func(configWithStatus *functionconfig.ConfigWithStatus) error {
+
+			// the stored function is updated, rather than the caller's copy of it
+			suite.Require().True(configWithStatus.Spec.Disable)
+
+			configWithStatus.Status.State = functionconfig.FunctionStateReady
+
+			return nil
+		})
+	suite.Require().NoError(err)
+
+	// the function is read and written while holding it locked
+	suite.Require().Len(suite.dockerClient.commands, 4)
+	suite.Require().Equal(lockCommand, suite.dockerClient.commands[0])
+	suite.Require().Equal(readCommand, suite.dockerClient.commands[1])
+	suite.Require().Contains(suite.dockerClient.commands[2], "mv -f")
+	suite.Require().Equal("/bin/rmdir /etc/nuclio/store/locks/functions.nuclio.my-function", suite.dockerClient.commands[3])
+
+	// functions that weren't stored can't be updated
+	err = suite.store.updateFunction(&functionconfig.Meta{Name: "other-function", Namespace: "nuclio"},
+		func(configWithStatus *functionconfig.ConfigWithStatus) error {
+			suite.Require().FailNow("Updater called for a missing function")
+			return nil
+		})
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function not found")
+}
+
+func (suite *storeTestSuite) TestLockResource() {
+	lockCommand := `/bin/sh -c "mkdir -p /etc/nuclio/store/locks && mkdir /etc/nuclio/store/locks/function-revisions.nuclio.my-function"`
+
+	// the lock is held by another invocation once
+	suite.dockerClient.failingCommands = []string{lockCommand}
+
+	unlock, err := suite.store.lockResource(functionRevisionsDir, "nuclio", "my-function")
+	suite.Require().NoError(err)
+
+	unlock()
+
+	suite.Require().Equal([]string{
+		lockCommand,
+		"/usr/bin/find /etc/nuclio/store/locks/function-revisions.nuclio.my-function -maxdepth 0 -mmin +1",
+		lockCommand,
+		"/bin/rmdir /etc/nuclio/store/locks/function-revisions.nuclio.my-function",
+	}, suite.dockerClient.commands)
+}
+
+func (suite *storeTestSuite) TestBreakStaleLock() {
+	lockCommand := `/bin/sh -c "mkdir -p /etc/nuclio/store/locks && mkdir /etc/nuclio/store/locks/function-revisions.nuclio.my-function"`
+	staleLockCommand := "/usr/bin/find /etc/nuclio/store/locks/function-revisions.nuclio.my-function -maxdepth 0 -mmin +1"
+
+	suite.dockerClient.failingCommands = []string{lockCommand}
+	suite.dockerClient.outputs[staleLockCommand] = "/etc/nuclio/store/locks/function-revisions.nuclio.my-function\n"
+
+	unlock, err := suite.store.lockResource(functionRevisionsDir, "nuclio", "my-function")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(unlock)
+
+	suite.Require().Equal([]string{
+		lockCommand,
+		staleLockCommand,
+		"/bin/rmdir /etc/nuclio/store/locks/function-revisions.nuclio.my-function",
+		lockCommand,
+	}, suite.dockerClient.commands)
+}
+
+func TestStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(storeTestSuite))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// GetFunctionStreamStatus returns the shards the stream triggers of each of the function's containers consume,
+// as their processors report them
+func (p *Platform) GetFunctionStreamStatus(getFunctionStreamStatusOptions *platform.GetFunctionStreamStatusOptions) (
+	[]platform.FunctionReplicaStreamStatus, error) {
+
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      getFunctionStreamStatusOptions.Name,
+		Namespace: getFunctionStreamStatusOptions.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read functions from local store")
+	}
+
+	if len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionStreamStatusOptions.Name,
+			getFunctionStreamStatusOptions.Namespace))
+	}
+
+	return p.getContainersStreamStatus(getFunctionStreamStatusOptions.Namespace, getFunctionStreamStatusOptions.Name)
+}
+
+func (p *Platform) getContainersStreamStatus(namespace string, name string) ([]platform.FunctionReplicaStreamStatus, error) {
+
+	// the function's own container along with the replicas the autoscaler added
+	containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":      "local",
+			"nuclio.io/namespace":     namespace,
+			"nuclio.io/function-name": name,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function containers")
+	}
+
+	var functionStreamStatus []platform.FunctionReplicaStreamStatus
+	for _, container := range containers {
+		containerName := strings.TrimPrefix(container.Name, "/")
+		replicaStreamStatus := &platform.FunctionReplicaStreamStatus{Replica: containerName}
+
+		encodedStatistics, err := p.getProcessorStatistics(container.ID)
+		if err != nil {
+			p.Logger.DebugWith("Failed to get processor statistics",
+				"containerID", container.ID,
+				"err", errors.RootCause(err).Error())
+		} else if parsedStreamStatus, err := platform.ParseFunctionReplicaStreamStatus(containerName,
+			encodedStatistics); err != nil {
+			p.Logger.DebugWith("Failed to parse processor statistics", "containerID", container.ID, "err", err.Error())
+		} else {
+			replicaStreamStatus = parsedStreamStatus
+		}
+
+		functionStreamStatus = append(functionStreamStatus, *replicaStreamStatus)
+	}
+
+	sort.Slice(functionStreamStatus, func(i, j int) bool {
+		return functionStreamStatus[i].Replica < functionStreamStatus[j].Replica
+	})
+
+	return functionStreamStatus, nil
+}
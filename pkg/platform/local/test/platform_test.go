@@ -16,7 +16,9 @@ limitations under the License.
 package test
 
 import (
+	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +30,7 @@ import (
 	processorsuite "github.com/nuclio/nuclio/pkg/processor/test/suite"
 
 	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
 )
 
 type TestSuite struct {
@@ -166,6 +169,77 @@ func (suite *TestSuite) TestImportFunctionFlow() {
 		})
 }
 
+// Test that a function is ready only once it passes its readiness check
+func (suite *TestSuite) TestDeployFunctionWithReadinessCheck() {
+	createFunctionOptions := suite.getDelayedReadinessDeployOptions("delayed-readiness", 5)
+	createFunctionOptions.FunctionConfig.Spec.ReadinessTimeoutSeconds = 60
+
+	suite.DeployFunction(createFunctionOptions,
+		func(deployResult *platform.CreateFunctionResult) bool {
+			function := suite.getFunction(deployResult.UpdatedFunctionConfig.Meta.Name)
+			suite.Require().Equal(functionconfig.FunctionStateReady, function.GetStatus().State)
+
+			// the function was ready to serve once deployed
+			invocationResult, err := suite.Platform.CreateFunctionInvocation(&platform.CreateFunctionInvocationOptions{
+				Name:      deployResult.UpdatedFunctionConfig.Meta.Name,
+				Namespace: suite.namespace,
+				Path:      "/",
+				Method:    http.MethodGet,
+				Headers:   http.Header{},
+				Via:       platform.InvokeViaAny,
+			})
+			suite.Require().NoError(err)
+			suite.Require().Equal(http.StatusOK, invocationResult.StatusCode)
+
+			return true
+		})
+}
+
+// Test that a function which doesn't pass its readiness check in time fails to deploy, leaving its container
+func (suite *TestSuite) TestDeployFunctionReadinessCheckTimeout() {
+	createFunctionOptions := suite.getDelayedReadinessDeployOptions("delayed-readiness-timeout", 600)
+	createFunctionOptions.FunctionConfig.Spec.ReadinessTimeoutSeconds = 10
+
+	suite.PopulateDeployOptions(createFunctionOptions)
+	defer suite.Platform.DeleteFunction(&platform.DeleteFunctionOptions{ // nolint: errcheck
+		FunctionConfig: createFunctionOptions.FunctionConfig,
+	})
+
+	deployStartTime := time.Now()
+	_, err := suite.Platform.CreateFunction(createFunctionOptions)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Function wasn't ready in time")
+
+	// gave up once the readiness timeout passed
+	suite.Require().True(time.Since(deployStartTime) < 2*time.Minute)
+
+	// the container is left for inspection
+	containers, err := suite.DockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		Name: suite.Platform.(*local.Platform).GetContainerNameByCreateFunctionOptions(createFunctionOptions),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(containers, 1)
+}
+
+func (suite *TestSuite) getDelayedReadinessDeployOptions(functionName string,
+	readyAfterSeconds int) *platform.CreateFunctionOptions {
+	functionPath := []string{suite.GetTestFunctionsDir(), "common", "delayed-readiness", "python", "delayedreadiness.py"}
+	createFunctionOptions := suite.TestSuite.GetDeployOptions(functionName, filepath.Join(functionPath...))
+	createFunctionOptions.FunctionConfig.Spec.Build.NoBaseImagesPull = true
+	createFunctionOptions.FunctionConfig.Meta.Namespace = suite.namespace
+	createFunctionOptions.FunctionConfig.Spec.Env = []v1.EnvVar{
+		{Name: "READY_AFTER_SECONDS", Value: strconv.Itoa(readyAfterSeconds)},
+	}
+	createFunctionOptions.FunctionConfig.Spec.Readiness = &functionconfig.Readiness{
+		Dependencies: []functionconfig.ReadinessDependency{
+			{Name: "handler", Kind: functionconfig.ReadinessDependencyKindHandler, Path: "/"},
+		},
+		PeriodSeconds: 1,
+	}
+
+	return createFunctionOptions
+}
+
 func (suite *TestSuite) getDeployOptions(functionName string) *platform.CreateFunctionOptions {
 	functionPath := []string{suite.GetTestFunctionsDir(), "common", "reverser", "python", "reverser.py"}
 	createFunctionOptions := suite.TestSuite.GetDeployOptions(functionName, filepath.Join(functionPath...))
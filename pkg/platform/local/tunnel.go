@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// networkTunnel reaches a function's container over its docker network. if the caller runs in a container
+// itself, that container is attached to the function's network for as long as the tunnel is open
+type networkTunnel struct {
+	logger       logger.Logger
+	dockerClient dockerclient.Client
+	address      string
+	networkName  string
+	containerID  string
+	closeOnce    sync.Once
+	closeErr     error
+}
+
+// newNetworkTunnel returns a tunnel to the function's container. callerContainerID is the container the caller
+// runs in, or empty if it runs on the docker host
+func newNetworkTunnel(parentLogger logger.Logger,
+	dockerClient dockerclient.Client,
+	functionContainer *dockerclient.Container,
+	callerContainerID string) (*networkTunnel, error) {
+	networkName, ipAddress := getContainerNetworkEndpoint(functionContainer)
+	if ipAddress == "" {
+		return nil, errors.New("Function container has no network address")
+	}
+
+	tunnel := &networkTunnel{
+		logger:       parentLogger.GetChild("tunnel"),
+		dockerClient: dockerClient,
+		address:      fmt.Sprintf("%s:8080", ipAddress),
+	}
+
+	// already on the network, e.g. the caller is the dashboard
+	if callerContainerID == "" || isContainerOnNetwork(dockerClient, callerContainerID, networkName) {
+		return tunnel, nil
+	}
+
+	tunnel.logger.DebugWith("Attaching to function network",
+		"network", networkName,
+		"containerID", callerContainerID)
+
+	if err := dockerClient.ConnectNetwork(networkName, callerContainerID); err != nil {
+		return nil, errors.Wrapf(err, "Failed to attach container %s to network %s", callerContainerID, networkName)
+	}
+
+	tunnel.networkName = networkName
+	tunnel.containerID = callerContainerID
+
+	return tunnel, nil
+}
+
+// GetAddress returns the function container's address on its network
+func (t *networkTunnel) GetAddress() string {
+	return t.address
+}
+
+// Close detaches the caller's container from the function's network, if it was attached to it
+func (t *networkTunnel) Close() error {
+	t.closeOnce.Do(func() {
+		if t.containerID == "" {
+			return
+		}
+
+		t.logger.DebugWith("Detaching from function network",
+			"network", t.networkName,
+			"containerID", t.containerID)
+
+		if err := t.dockerClient.DisconnectNetwork(t.networkName, t.containerID); err != nil {
+			t.closeErr = errors.Wrapf(err, "Failed to detach container %s from network %s", t.containerID, t.networkName)
+		}
+	})
+
+	return t.closeErr
+}
+
+// getContainerNetworkEndpoint returns the network through which other containers reach the container, and its
+// address on that network. both are empty if the container has no address
+func getContainerNetworkEndpoint(container *dockerclient.Container) (string, string) {
+	if container.NetworkSettings == nil {
+		return "", ""
+	}
+
+	if container.NetworkSettings.IPAddress != "" {
+		return "bridge", container.NetworkSettings.IPAddress
+	}
+
+	// a container on a user defined network has its address there
+	var networkNames []string
+	for networkName := range container.NetworkSettings.Networks {
+		networkNames = append(networkNames, networkName)
+	}
+
+	sort.Strings(networkNames)
+
+	for _, networkName := range networkNames {
+		if endpoint := container.NetworkSettings.Networks[networkName]; endpoint != nil && endpoint.IPAddress != "" {
+			return networkName, endpoint.IPAddress
+		}
+	}
+
+	return "", ""
+}
+
+func isContainerOnNetwork(dockerClient dockerclient.Client, containerID string, networkName string) bool {
+	containers, err := dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+		ID: containerID,
+	})
+	if err != nil || len(containers) == 0 || containers[0].NetworkSettings == nil {
+		return false
+	}
+
+	if networkName == "bridge" && containers[0].NetworkSettings.IPAddress != "" {
+		return true
+	}
+
+	_, found := containers[0].NetworkSettings.Networks[networkName]
+
+	return found
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type tunnelTestSuite struct {
+	suite.Suite
+	logger            logger.Logger
+	mockDockerClient  *dockerclient.MockDockerClient
+	functionContainer *dockerclient.Container
+}
+
+func (suite *tunnelTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+	suite.mockDockerClient = dockerclient.NewMockDockerClient()
+
+	suite.functionContainer = &dockerclient.Container{
+		NetworkSettings: &dockerclient.NetworkSettings{
+			Networks: map[string]*dockerclient.EndpointSettings{
+				"nuclio-network": {IPAddress: "172.20.0.5"},
+			},
+		},
+	}
+}
+
+func (suite *tunnelTestSuite) TestFromHost() {
+	tunnel, err := newNetworkTunnel(suite.logger, suite.mockDockerClient, suite.functionContainer, "")
+	suite.Require().NoError(err)
+	suite.Require().Equal("172.20.0.5:8080", tunnel.GetAddress())
+	suite.Require().NoError(tunnel.Close())
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *tunnelTestSuite) TestFromContainer() {
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{ID: "caller-id"}).
+		Return([]dockerclient.Container{
+			{ID: "caller-id", NetworkSettings: &dockerclient.NetworkSettings{
+				Networks: map[string]*dockerclient.EndpointSettings{"bridge": {IPAddress: "172.17.0.2"}},
+			}},
+		}, nil).Once()
+
+	suite.mockDockerClient.On("ConnectNetwork", "nuclio-network", "caller-id").Return(nil).Once()
+
+	tunnel, err := newNetworkTunnel(suite.logger, suite.mockDockerClient, suite.functionContainer, "caller-id")
+	suite.Require().NoError(err)
+	suite.Require().Equal("172.20.0.5:8080", tunnel.GetAddress())
+
+	// the caller is detached once, however many times the tunnel is closed
+	suite.mockDockerClient.On("DisconnectNetwork", "nuclio-network", "caller-id").Return(nil).Once()
+
+	suite.Require().NoError(tunnel.Close())
+	suite.Require().NoError(tunnel.Close())
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *tunnelTestSuite) TestFromContainerOnNetwork() {
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{ID: "caller-id"}).
+		Return([]dockerclient.Container{*suite.functionContainer}, nil).Once()
+
+	tunnel, err := newNetworkTunnel(suite.logger, suite.mockDockerClient, suite.functionContainer, "caller-id")
+	suite.Require().NoError(err)
+	suite.Require().NoError(tunnel.Close())
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *tunnelTestSuite) TestNoAddress() {
+	_, err := newNetworkTunnel(suite.logger, suite.mockDockerClient, &dockerclient.Container{}, "")
+	suite.Require().Error(err)
+}
+
+func TestTunnelTestSuite(t *testing.T) {
+	suite.Run(t, new(tunnelTestSuite))
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package local
 
 import (
+	"net"
+	"strings"
+
 	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 
@@ -25,8 +28,14 @@ import (
 )
 
 type functionPlatformConfiguration struct {
+
+	// Network is an existing docker network the function's container joins (e.g. that of a docker-compose
+	// stack), so that it can resolve the names of the services on it
 	Network       string
 	RestartPolicy *dockerclient.RestartPolicy
+
+	// ExtraHosts are added to the container's /etc/hosts (host:ip, where ip may be host-gateway)
+	ExtraHosts []string
 }
 
 func newFunctionPlatformConfiguration(functionConfig *functionconfig.Config) (*functionPlatformConfiguration, error) {
@@ -37,5 +46,24 @@ func newFunctionPlatformConfiguration(functionConfig *functionconfig.Config) (*f
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	for _, extraHost := range newConfiguration.ExtraHosts {
+		if err := validateExtraHost(extraHost); err != nil {
+			return nil, errors.Wrapf(err, "Invalid extra host %q", extraHost)
+		}
+	}
+
 	return &newConfiguration, nil
 }
+
+func validateExtraHost(extraHost string) error {
+	hostAndIP := strings.SplitN(extraHost, ":", 2)
+	if len(hostAndIP) != 2 || hostAndIP[0] == "" {
+		return errors.New("Expected host:ip")
+	}
+
+	if hostAndIP[1] != "host-gateway" && net.ParseIP(hostAndIP[1]) == nil {
+		return errors.Errorf("Expected an IP address or host-gateway, got %s", hostAndIP[1])
+	}
+
+	return nil
+}
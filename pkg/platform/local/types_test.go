@@ -0,0 +1,64 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type functionPlatformConfigurationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *functionPlatformConfigurationTestSuite) TestNetwork() {
+	functionPlatformConfiguration, err := newFunctionPlatformConfiguration(suite.createFunctionConfig(map[string]interface{}{
+		"network":    "my-stack_default",
+		"extraHosts": []interface{}{"db.local:10.0.0.5", "host.docker.internal:host-gateway", "ipv6.local:::1"},
+	}))
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-stack_default", functionPlatformConfiguration.Network)
+	suite.Require().Equal([]string{"db.local:10.0.0.5", "host.docker.internal:host-gateway", "ipv6.local:::1"},
+		functionPlatformConfiguration.ExtraHosts)
+}
+
+func (suite *functionPlatformConfigurationTestSuite) TestInvalidExtraHosts() {
+	for _, extraHost := range []string{
+		"db.local",
+		":10.0.0.5",
+		"db.local:db",
+	} {
+		_, err := newFunctionPlatformConfiguration(suite.createFunctionConfig(map[string]interface{}{
+			"extraHosts": []interface{}{extraHost},
+		}))
+		suite.Require().Error(err, "Expected %s to be invalid", extraHost)
+	}
+}
+
+func (suite *functionPlatformConfigurationTestSuite) createFunctionConfig(attributes map[string]interface{}) *functionconfig.Config {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Spec.Platform.Attributes = attributes
+
+	return functionConfig
+}
+
+func TestFunctionPlatformConfigurationTestSuite(t *testing.T) {
+	suite.Run(t, new(functionPlatformConfigurationTestSuite))
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const defaultUsageSampleInterval = 2 * time.Second
+
+// the event counters of a trigger, as the processor's web admin serves them
+type triggerStatistics struct {
+	EventsHandledSuccessTotal uint64 `json:"eventsHandledSuccessTotal"`
+	EventsHandledFailureTotal uint64 `json:"eventsHandledFailureTotal"`
+}
+
+// GetFunctionUsage returns the CPU and memory used by the functions' containers, as docker stats reports them, and
+// the rate of events their triggers handled, by sampling the processors' statistics twice
+func (p *Platform) GetFunctionUsage(getFunctionUsageOptions *platform.GetFunctionUsageOptions) (
+	[]platform.FunctionUsage, error) {
+
+	functions, err := p.localStore.getFunctions(&functionconfig.Meta{
+		Name:      getFunctionUsageOptions.Name,
+		Namespace: getFunctionUsageOptions.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read functions from local store")
+	}
+
+	if getFunctionUsageOptions.Name != "" && len(functions) == 0 {
+		return nil, nuclio.NewErrNotFound(fmt.Sprintf("Function not found: %s @ %s",
+			getFunctionUsageOptions.Name,
+			getFunctionUsageOptions.Namespace))
+	}
+
+	var functionMetas []functionconfig.Meta
+	for _, function := range functions {
+		functionMetas = append(functionMetas, function.GetConfig().Meta)
+	}
+
+	sampleInterval := getFunctionUsageOptions.SampleInterval
+	if sampleInterval == 0 {
+		sampleInterval = defaultUsageSampleInterval
+	}
+
+	return p.getFunctionUsages(functionMetas, sampleInterval)
+}
+
+func (p *Platform) getFunctionUsages(functionMetas []functionconfig.Meta,
+	sampleInterval time.Duration) ([]platform.FunctionUsage, error) {
+
+	var functionUsages []platform.FunctionUsage
+	var functionContainerIDs [][]string
+	var firstSamples []map[string]map[string]triggerStatistics
+
+	for _, functionMeta := range functionMetas {
+
+		// the function's own container along with the replicas the autoscaler added
+		containers, err := p.dockerClient.GetContainers(&dockerclient.GetContainerOptions{
+			Labels: map[string]string{
+				"nuclio.io/platform":      "local",
+				"nuclio.io/namespace":     functionMeta.Namespace,
+				"nuclio.io/function-name": functionMeta.Name,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get function containers")
+		}
+
+		var containerIDs []string
+		for _, container := range containers {
+			containerIDs = append(containerIDs, container.ID)
+		}
+
+		functionUsages = append(functionUsages, platform.FunctionUsage{
+			Name:      functionMeta.Name,
+			Namespace: functionMeta.Namespace,
+			Replicas:  len(containerIDs),
+		})
+
+		functionContainerIDs = append(functionContainerIDs, containerIDs)
+		firstSamples = append(firstSamples, p.getContainersTriggerStatistics(containerIDs))
+	}
+
+	if len(functionUsages) == 0 {
+		return functionUsages, nil
+	}
+
+	time.Sleep(sampleInterval)
+
+	for functionIndex, containerIDs := range functionContainerIDs {
+		functionUsage := &functionUsages[functionIndex]
+		if len(containerIDs) == 0 {
+			continue
+		}
+
+		containerStats, err := p.dockerClient.GetContainerStats(containerIDs)
+		if err != nil {
+			p.Logger.WarnWith("Failed to get function container stats",
+				"name", functionUsage.Name,
+				"err", errors.RootCause(err).Error())
+		} else {
+			var cpuMillicores, memoryBytes int64
+			for _, containerID := range containerIDs {
+
+				// docker reports the percentage of a single CPU
+				cpuMillicores += int64(containerStats[containerID].CPUPercent * 10)
+				memoryBytes += containerStats[containerID].MemoryBytes
+			}
+
+			functionUsage.CPUMillicores = &cpuMillicores
+			functionUsage.MemoryBytes = &memoryBytes
+		}
+
+		functionUsage.EventsPerSecond, functionUsage.ErrorRate = getEventRates(firstSamples[functionIndex],
+			p.getContainersTriggerStatistics(containerIDs),
+			sampleInterval)
+	}
+
+	return functionUsages, nil
+}
+
+// returns the statistics of each trigger, by container ID. containers whose statistics could not be read
+// (e.g. not ready yet) are omitted
+func (p *Platform) getContainersTriggerStatistics(containerIDs []string) map[string]map[string]triggerStatistics {
+	containersTriggerStatistics := map[string]map[string]triggerStatistics{}
+
+	for _, containerID := range containerIDs {
+		encodedStatistics, err := p.getProcessorStatistics(containerID)
+		if err != nil {
+			p.Logger.DebugWith("Failed to get processor statistics",
+				"containerID", containerID,
+				"err", errors.RootCause(err).Error())
+			continue
+		}
+
+		triggersStatistics := map[string]triggerStatistics{}
+		if err := json.Unmarshal(encodedStatistics, &triggersStatistics); err != nil {
+			p.Logger.DebugWith("Failed to parse processor statistics",
+				"containerID", containerID,
+				"err", err.Error())
+			continue
+		}
+
+		containersTriggerStatistics[containerID] = triggersStatistics
+	}
+
+	return containersTriggerStatistics
+}
+
+// returns the statistics of the triggers of the processor running in the container, as its web admin serves them
+func (p *Platform) getProcessorStatistics(containerID string) ([]byte, error) {
+	var stdout string
+
+	// the web admin isn't published, so read it from within the container
+	if err := p.dockerClient.ExecInContainer(containerID, &dockerclient.ExecOptions{
+		Command: p.getContainerHTTPGetCommand(fmt.Sprintf("http://127.0.0.1:8081/%s", platform.ProcessorStatisticsPath)),
+		Stdout:  &stdout,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to read statistics from processor")
+	}
+
+	return []byte(stdout), nil
+}
+
+// returns the events handled per second between the two samples, and the fraction of them which failed. only
+// containers sampled both times are counted. the rates are nil if no container was
+func getEventRates(firstSample map[string]map[string]triggerStatistics,
+	secondSample map[string]map[string]triggerStatistics,
+	sampleInterval time.Duration) (*float64, *float64) {
+	var sampled bool
+	var succeeded, failed uint64
+
+	for containerID, secondTriggersStatistics := range secondSample {
+		firstTriggersStatistics, found := firstSample[containerID]
+		if !found {
+			continue
+		}
+
+		sampled = true
+
+		for triggerID, secondTriggerStatistics := range secondTriggersStatistics {
+			firstTriggerStatistics := firstTriggersStatistics[triggerID]
+
+			// the counters only go back if the processor restarted in between
+			if secondTriggerStatistics.EventsHandledSuccessTotal < firstTriggerStatistics.EventsHandledSuccessTotal ||
+				secondTriggerStatistics.EventsHandledFailureTotal < firstTriggerStatistics.EventsHandledFailureTotal {
+				continue
+			}
+
+			succeeded += secondTriggerStatistics.EventsHandledSuccessTotal - firstTriggerStatistics.EventsHandledSuccessTotal
+			failed += secondTriggerStatistics.EventsHandledFailureTotal - firstTriggerStatistics.EventsHandledFailureTotal
+		}
+	}
+
+	if !sampled {
+		return nil, nil
+	}
+
+	eventsPerSecond := float64(succeeded+failed) / sampleInterval.Seconds()
+
+	var errorRate float64
+	if succeeded+failed > 0 {
+		errorRate = float64(failed) / float64(succeeded+failed)
+	}
+
+	return &eventsPerSecond, &errorRate
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/dockerclient"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/abstract"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type usageTestSuite struct {
+	suite.Suite
+	mockDockerClient *dockerclient.MockDockerClient
+	platform         *Platform
+}
+
+func (suite *usageTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.mockDockerClient = dockerclient.NewMockDockerClient()
+	suite.platform = &Platform{
+		Platform:     &abstract.Platform{Logger: loggerInstance},
+		dockerClient: suite.mockDockerClient,
+	}
+}
+
+func (suite *usageTestSuite) TestGetFunctionUsages() {
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":      "local",
+			"nuclio.io/namespace":     "nuclio",
+			"nuclio.io/function-name": "scaled",
+		},
+	}).Return([]dockerclient.Container{
+		{ID: "function-id"},
+		{ID: "replica-id"},
+	}, nil)
+
+	suite.mockDockerClient.On("GetContainers", &dockerclient.GetContainerOptions{
+		Labels: map[string]string{
+			"nuclio.io/platform":      "local",
+			"nuclio.io/namespace":     "nuclio",
+			"nuclio.io/function-name": "stopped",
+		},
+	}).Return([]dockerclient.Container{}, nil)
+
+	suite.mockDockerClient.On("GetContainerStats", []string{"function-id", "replica-id"}).
+		Return(map[string]dockerclient.ContainerStats{
+			"function-id": {CPUPercent: 12.5, MemoryBytes: 1000},
+			"replica-id":  {CPUPercent: 2.5, MemoryBytes: 500},
+		}, nil)
+
+	functionUsages, err := suite.platform.getFunctionUsages([]functionconfig.Meta{
+		{Name: "scaled", Namespace: "nuclio"},
+		{Name: "stopped", Namespace: "nuclio"},
+	}, time.Millisecond)
+	suite.Require().NoError(err)
+	suite.Require().Len(functionUsages, 2)
+
+	suite.Require().Equal("scaled", functionUsages[0].Name)
+	suite.Require().Equal(2, functionUsages[0].Replicas)
+	suite.Require().Equal(int64(150), *functionUsages[0].CPUMillicores)
+	suite.Require().Equal(int64(1500), *functionUsages[0].MemoryBytes)
+
+	// the processors' statistics couldn't be read
+	suite.Require().Nil(functionUsages[0].EventsPerSecond)
+	suite.Require().Nil(functionUsages[0].ErrorRate)
+
+	suite.Require().Equal("stopped", functionUsages[1].Name)
+	suite.Require().Equal(0, functionUsages[1].Replicas)
+	suite.Require().Nil(functionUsages[1].CPUMillicores)
+
+	suite.mockDockerClient.AssertExpectations(suite.T())
+}
+
+func (suite *usageTestSuite) TestGetEventRates() {
+	firstSample := map[string]map[string]triggerStatistics{
+		"function-id": {
+			"http":  {EventsHandledSuccessTotal: 100, EventsHandledFailureTotal: 10},
+			"kafka": {EventsHandledSuccessTotal: 50},
+		},
+		"replica-id": {
+			"http": {EventsHandledSuccessTotal: 30},
+		},
+	}
+
+	secondSample := map[string]map[string]triggerStatistics{
+		"function-id": {
+			"http":  {EventsHandledSuccessTotal: 116, EventsHandledFailureTotal: 12},
+			"kafka": {EventsHandledSuccessTotal: 56},
+		},
+
+		// restarted in between
+		"replica-id": {
+			"http": {EventsHandledSuccessTotal: 5},
+		},
+
+		// started in between
+		"new-replica-id": {
+			"http": {EventsHandledSuccessTotal: 1000},
+		},
+	}
+
+	eventsPerSecond, errorRate := getEventRates(firstSample, secondSample, 2*time.Second)
+	suite.Require().Equal(12.0, *eventsPerSecond)
+	suite.Require().Equal(2.0/24, *errorRate)
+
+	// no container was sampled twice
+	eventsPerSecond, errorRate = getEventRates(firstSample, map[string]map[string]triggerStatistics{}, time.Second)
+	suite.Require().Nil(eventsPerSecond)
+	suite.Require().Nil(errorRate)
+}
+
+func TestUsageTestSuite(t *testing.T) {
+	suite.Run(t, new(usageTestSuite))
+}
@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"io"
+
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
@@ -53,6 +55,90 @@ func (mp *Platform) DeleteFunction(deleteFunctionOptions *platform.DeleteFunctio
 	return args.Error(0)
 }
 
+// GetFunctionLogs returns the logs of a function's replica as a stream
+func (mp *Platform) GetFunctionLogs(getFunctionLogsOptions *platform.GetFunctionLogsOptions) (io.ReadCloser, error) {
+	args := mp.Called(getFunctionLogsOptions)
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+// GetFunctionExecCommand returns the command line that runs a command inside a function's container
+func (mp *Platform) GetFunctionExecCommand(getFunctionExecCommandOptions *platform.GetFunctionExecCommandOptions) ([]string, error) {
+	args := mp.Called(getFunctionExecCommandOptions)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// CreateFunctionTunnel makes a function reachable from where the caller runs
+func (mp *Platform) CreateFunctionTunnel(createFunctionTunnelOptions *platform.CreateFunctionTunnelOptions) (platform.FunctionTunnel, error) {
+	args := mp.Called(createFunctionTunnelOptions)
+	return args.Get(0).(platform.FunctionTunnel), args.Error(1)
+}
+
+// PauseFunction stops a function's replicas without changing its configuration
+func (mp *Platform) PauseFunction(pauseFunctionOptions *platform.PauseFunctionOptions) error {
+	args := mp.Called(pauseFunctionOptions)
+	return args.Error(0)
+}
+
+// ResumeFunction runs the replicas of a paused function again
+func (mp *Platform) ResumeFunction(resumeFunctionOptions *platform.ResumeFunctionOptions) error {
+	args := mp.Called(resumeFunctionOptions)
+	return args.Error(0)
+}
+
+// PauseFunctionTrigger stops one of a function's triggers from consuming events
+func (mp *Platform) PauseFunctionTrigger(pauseFunctionTriggerOptions *platform.PauseFunctionTriggerOptions) error {
+	args := mp.Called(pauseFunctionTriggerOptions)
+	return args.Error(0)
+}
+
+// ResumeFunctionTrigger has a paused trigger of a function consume events again
+func (mp *Platform) ResumeFunctionTrigger(resumeFunctionTriggerOptions *platform.ResumeFunctionTriggerOptions) error {
+	args := mp.Called(resumeFunctionTriggerOptions)
+	return args.Error(0)
+}
+
+// GetFunctionUsage returns the resource usage and event rates of functions
+func (mp *Platform) GetFunctionUsage(getFunctionUsageOptions *platform.GetFunctionUsageOptions) ([]platform.FunctionUsage, error) {
+	args := mp.Called(getFunctionUsageOptions)
+	return args.Get(0).([]platform.FunctionUsage), args.Error(1)
+}
+
+// GetFunctionStreamStatus returns the shards each of the function's replicas consumes
+func (mp *Platform) GetFunctionStreamStatus(getFunctionStreamStatusOptions *platform.GetFunctionStreamStatusOptions) ([]platform.FunctionReplicaStreamStatus, error) {
+	args := mp.Called(getFunctionStreamStatusOptions)
+	return args.Get(0).([]platform.FunctionReplicaStreamStatus), args.Error(1)
+}
+
+// GetFunctionRevisions returns the configurations a function was deployed with
+func (mp *Platform) GetFunctionRevisions(getFunctionRevisionsOptions *platform.GetFunctionRevisionsOptions) ([]platform.FunctionRevision, error) {
+	args := mp.Called(getFunctionRevisionsOptions)
+	return args.Get(0).([]platform.FunctionRevision), args.Error(1)
+}
+
+// GetFunctionInvocations returns the invocations of a function the invocation inspector retains
+func (mp *Platform) GetFunctionInvocations(getFunctionInvocationsOptions *platform.GetFunctionInvocationsOptions) ([]platform.FunctionInvocation, error) {
+	args := mp.Called(getFunctionInvocationsOptions)
+	return args.Get(0).([]platform.FunctionInvocation), args.Error(1)
+}
+
+// DeleteFunctionInvocations purges the invocations of a function the invocation inspector retains
+func (mp *Platform) DeleteFunctionInvocations(deleteFunctionInvocationsOptions *platform.DeleteFunctionInvocationsOptions) error {
+	args := mp.Called(deleteFunctionInvocationsOptions)
+	return args.Error(0)
+}
+
+// CreateAuditRecord adds a record of an operation performed on a function to the audit log
+func (mp *Platform) CreateAuditRecord(auditRecord *platform.AuditRecord) error {
+	args := mp.Called(auditRecord)
+	return args.Error(0)
+}
+
+// GetAuditRecords returns the records of the operations performed on functions
+func (mp *Platform) GetAuditRecords(getAuditRecordsOptions *platform.GetAuditRecordsOptions) ([]platform.AuditRecord, error) {
+	args := mp.Called(getAuditRecordsOptions)
+	return args.Get(0).([]platform.AuditRecord), args.Error(1)
+}
+
 // CreateFunctionInvocation will invoke a previously deployed function
 func (mp *Platform) CreateFunctionInvocation(createFunctionInvocationOptions *platform.CreateFunctionInvocationOptions) (*platform.CreateFunctionInvocationResult, error) {
 	args := mp.Called(createFunctionInvocationOptions)
@@ -122,6 +208,34 @@ func (mp *Platform) GetFunctionEvents(getFunctionEventsOptions *platform.GetFunc
 	return args.Get(0).([]platform.FunctionEvent), args.Error(1)
 }
 
+//
+// API gateway
+//
+
+// CreateAPIGateway will create a new API gateway, routing requests to functions
+func (mp *Platform) CreateAPIGateway(createAPIGatewayOptions *platform.CreateAPIGatewayOptions) error {
+	args := mp.Called(createAPIGatewayOptions)
+	return args.Error(0)
+}
+
+// UpdateAPIGateway will update a previously existing API gateway
+func (mp *Platform) UpdateAPIGateway(updateAPIGatewayOptions *platform.UpdateAPIGatewayOptions) error {
+	args := mp.Called(updateAPIGatewayOptions)
+	return args.Error(0)
+}
+
+// DeleteAPIGateway will delete a previously existing API gateway
+func (mp *Platform) DeleteAPIGateway(deleteAPIGatewayOptions *platform.DeleteAPIGatewayOptions) error {
+	args := mp.Called(deleteAPIGatewayOptions)
+	return args.Error(0)
+}
+
+// GetAPIGateways will list existing API gateways
+func (mp *Platform) GetAPIGateways(getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
+	args := mp.Called(getAPIGatewaysOptions)
+	return args.Get(0).([]platform.APIGateway), args.Error(1)
+}
+
 //
 // Misc
 //
@@ -219,6 +333,12 @@ func (mp *Platform) GetBaseImageRegistry(registry string) string {
 	return "quay.io"
 }
 
+// GetRuntimeImages returns the images and package indexes builds of the runtime use
+func (mp *Platform) GetRuntimeImages(runtimeName string) *platformconfig.RuntimeImages {
+	args := mp.Called(runtimeName)
+	return args.Get(0).(*platformconfig.RuntimeImages)
+}
+
 func (mp *Platform) GetOnbuildImageRegistry(registry string) string {
 	return ""
 }
@@ -226,3 +346,9 @@ func (mp *Platform) GetOnbuildImageRegistry(registry string) string {
 func (mp *Platform) GetDefaultRegistryCredentialsSecretName() string {
 	return "nuclio-registry-credentials"
 }
+
+// GetSecretData returns the data of a secret in the namespace, by key
+func (mp *Platform) GetSecretData(namespace string, name string) (map[string][]byte, error) {
+	args := mp.Called(namespace, name)
+	return args.Get(0).(map[string][]byte), args.Error(1)
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package platform
 
 import (
+	"io"
+
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
@@ -57,6 +59,53 @@ type Platform interface {
 	// GetFunctions will list existing functions
 	GetFunctions(getFunctionsOptions *GetFunctionsOptions) ([]Function, error)
 
+	// GetFunctionLogs returns the logs of a function's replica as a stream. the caller must close it
+	GetFunctionLogs(getFunctionLogsOptions *GetFunctionLogsOptions) (io.ReadCloser, error)
+
+	// GetFunctionExecCommand returns the command line (binary first) that runs a command inside the container of
+	// one of the function's replicas, with the caller's standard streams attached
+	GetFunctionExecCommand(getFunctionExecCommandOptions *GetFunctionExecCommandOptions) ([]string, error)
+
+	// CreateFunctionTunnel makes the function reachable from where the caller runs, even if it isn't exposed
+	// outside the platform. the caller must close the tunnel once done with it
+	CreateFunctionTunnel(createFunctionTunnelOptions *CreateFunctionTunnelOptions) (FunctionTunnel, error)
+
+	// PauseFunction stops a function's replicas without changing its configuration, until it's resumed
+	PauseFunction(pauseFunctionOptions *PauseFunctionOptions) error
+
+	// ResumeFunction runs the replicas of a paused function again
+	ResumeFunction(resumeFunctionOptions *ResumeFunctionOptions) error
+
+	// PauseFunctionTrigger stops one of a function's triggers from consuming events, leaving the function running,
+	// until it's resumed. the paused state is kept in the function's configuration, across restarts
+	PauseFunctionTrigger(pauseFunctionTriggerOptions *PauseFunctionTriggerOptions) error
+
+	// ResumeFunctionTrigger has a paused trigger of a function consume events again
+	ResumeFunctionTrigger(resumeFunctionTriggerOptions *ResumeFunctionTriggerOptions) error
+
+	// GetFunctionUsage returns the resource usage and event rates of functions
+	GetFunctionUsage(getFunctionUsageOptions *GetFunctionUsageOptions) ([]FunctionUsage, error)
+
+	// GetFunctionStreamStatus returns the shards each of the function's replicas consumes through its stream
+	// triggers, with their lag and committed sequence numbers
+	GetFunctionStreamStatus(getFunctionStreamStatusOptions *GetFunctionStreamStatusOptions) ([]FunctionReplicaStreamStatus, error)
+
+	// GetFunctionRevisions returns the configurations a function was deployed with, oldest first
+	GetFunctionRevisions(getFunctionRevisionsOptions *GetFunctionRevisionsOptions) ([]FunctionRevision, error)
+
+	// GetFunctionInvocations returns the invocations of a function the invocation inspector retains, oldest
+	// first, gathering the latest ones from its replicas
+	GetFunctionInvocations(getFunctionInvocationsOptions *GetFunctionInvocationsOptions) ([]FunctionInvocation, error)
+
+	// DeleteFunctionInvocations purges the invocations of a function the invocation inspector retains
+	DeleteFunctionInvocations(deleteFunctionInvocationsOptions *DeleteFunctionInvocationsOptions) error
+
+	// CreateAuditRecord adds a record of an operation performed on a function to the audit log
+	CreateAuditRecord(auditRecord *AuditRecord) error
+
+	// GetAuditRecords returns the records of the operations performed on functions, oldest first
+	GetAuditRecords(getAuditRecordsOptions *GetAuditRecordsOptions) ([]AuditRecord, error)
+
 	// GetDefaultInvokeIPAddresses will return a list of ip addresses to be used by the platform to invoke a function
 	GetDefaultInvokeIPAddresses() ([]string, error)
 
@@ -93,6 +142,22 @@ type Platform interface {
 	// GetFunctionEvents will list existing function events
 	GetFunctionEvents(getFunctionEventsOptions *GetFunctionEventsOptions) ([]FunctionEvent, error)
 
+	//
+	// API gateway
+	//
+
+	// CreateAPIGateway will create a new API gateway, routing requests to functions
+	CreateAPIGateway(createAPIGatewayOptions *CreateAPIGatewayOptions) error
+
+	// UpdateAPIGateway will update a previously existing API gateway
+	UpdateAPIGateway(updateAPIGatewayOptions *UpdateAPIGatewayOptions) error
+
+	// DeleteAPIGateway will delete a previously existing API gateway
+	DeleteAPIGateway(deleteAPIGatewayOptions *DeleteAPIGatewayOptions) error
+
+	// GetAPIGateways will list existing API gateways
+	GetAPIGateways(getAPIGatewaysOptions *GetAPIGatewaysOptions) ([]APIGateway, error)
+
 	//
 	// Misc
 	//
@@ -148,6 +213,14 @@ type Platform interface {
 	// GetBaseImageRegistry returns base image registry
 	GetBaseImageRegistry(registry string) string
 
+	// GetRuntimeImages returns the images and package indexes the platform configuration has builds of the
+	// runtime use, or nil if they use the runtime's defaults
+	GetRuntimeImages(runtimeName string) *platformconfig.RuntimeImages
+
 	// GetDefaultRegistryCredentialsSecretName returns secret with credentials to push/pull from docker registry
 	GetDefaultRegistryCredentialsSecretName() string
+
+	// GetSecretData returns the data of a secret in the namespace, by key. e.g. the builder reads the SSH keys
+	// git repositories are cloned with from secrets
+	GetSecretData(namespace string, name string) (map[string][]byte, error)
 }
@@ -17,13 +17,17 @@ limitations under the License.
 package platform
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
@@ -85,3 +89,176 @@ func (ap *AbstractProject) CreateAndWait() error {
 
 	return nil
 }
+
+// Validate verifies the project's defaults and quotas
+func (ps *ProjectSpec) Validate() error {
+	if ps.Quotas == nil {
+		return nil
+	}
+
+	if ps.Quotas.MaxFunctions < 0 || ps.Quotas.MaxReplicas < 0 {
+		return errors.New("Project quotas must not be negative")
+	}
+
+	if ps.Quotas.MaxCPU != "" {
+		maxCPU, err := apiresource.ParseQuantity(ps.Quotas.MaxCPU)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse CPU quota %s", ps.Quotas.MaxCPU)
+		}
+
+		if maxCPU.Sign() < 0 {
+			return errors.New("Project quotas must not be negative")
+		}
+	}
+
+	return nil
+}
+
+// ApplyDefaults sets the project's defaults in the configuration of a function deployed to it, where the
+// function doesn't set them. node selectors are merged, and resources the function neither requests nor
+// limits get the project's default request and limit
+func (ps *ProjectSpec) ApplyDefaults(functionConfig *functionconfig.Config) {
+	if ps.Defaults == nil {
+		return
+	}
+
+	if functionConfig.Spec.Build.Registry == "" {
+		functionConfig.Spec.Build.Registry = ps.Defaults.Registry
+	}
+
+	if functionConfig.Spec.ServiceAccount == "" {
+		functionConfig.Spec.ServiceAccount = ps.Defaults.ServiceAccount
+	}
+
+	for labelName, labelValue := range ps.Defaults.NodeSelector {
+		if functionConfig.Spec.NodeSelector == nil {
+			functionConfig.Spec.NodeSelector = map[string]string{}
+		}
+
+		if _, found := functionConfig.Spec.NodeSelector[labelName]; !found {
+			functionConfig.Spec.NodeSelector[labelName] = labelValue
+		}
+	}
+
+	// a default request above a limit the function sets (or vice versa) would make its pods invalid, so
+	// resources are defaulted as a whole
+	resources := &functionConfig.Spec.Resources
+	isResourceSet := func(resourceName v1.ResourceName) bool {
+		_, requested := resources.Requests[resourceName]
+		_, limited := resources.Limits[resourceName]
+		return requested || limited
+	}
+
+	defaultRequests := v1.ResourceList{}
+	for resourceName, quantity := range ps.Defaults.Resources.Requests {
+		if !isResourceSet(resourceName) {
+			defaultRequests[resourceName] = quantity.DeepCopy()
+		}
+	}
+
+	defaultLimits := v1.ResourceList{}
+	for resourceName, quantity := range ps.Defaults.Resources.Limits {
+		if !isResourceSet(resourceName) {
+			defaultLimits[resourceName] = quantity.DeepCopy()
+		}
+	}
+
+	resources.Requests = mergeResourceLists(resources.Requests, defaultRequests)
+	resources.Limits = mergeResourceLists(resources.Limits, defaultLimits)
+}
+
+// ValidateQuotas verifies that the project's quotas aren't exceeded by deploying the function along with the
+// project's other functions. a function which is redeployed is counted once, with its new configuration
+func (ps *ProjectSpec) ValidateQuotas(functionConfig *functionconfig.Config,
+	projectFunctionConfigs []*functionconfig.Config) error {
+	if ps.Quotas == nil {
+		return nil
+	}
+
+	functionConfigs := []*functionconfig.Config{functionConfig}
+	for _, projectFunctionConfig := range projectFunctionConfigs {
+		if projectFunctionConfig.Meta.Name != functionConfig.Meta.Name {
+			functionConfigs = append(functionConfigs, projectFunctionConfig)
+		}
+	}
+
+	if ps.Quotas.MaxFunctions > 0 && len(functionConfigs) > ps.Quotas.MaxFunctions {
+		return nuclio.NewErrForbidden(fmt.Sprintf("Project quota of %d functions would be exceeded",
+			ps.Quotas.MaxFunctions))
+	}
+
+	totalReplicas := 0
+	totalMilliCPU := int64(0)
+	for _, projectFunctionConfig := range functionConfigs {
+		maxReplicas := getFunctionMaxReplicas(projectFunctionConfig)
+		totalReplicas += maxReplicas
+		replicaCPU := getFunctionReplicaCPU(projectFunctionConfig)
+		totalMilliCPU += replicaCPU.MilliValue() * int64(maxReplicas)
+	}
+
+	if ps.Quotas.MaxReplicas > 0 && totalReplicas > ps.Quotas.MaxReplicas {
+		return nuclio.NewErrForbidden(fmt.Sprintf("Project quota of %d replicas would be exceeded (%d)",
+			ps.Quotas.MaxReplicas,
+			totalReplicas))
+	}
+
+	if ps.Quotas.MaxCPU != "" {
+		maxCPU, err := apiresource.ParseQuantity(ps.Quotas.MaxCPU)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse CPU quota %s", ps.Quotas.MaxCPU)
+		}
+
+		if totalMilliCPU > maxCPU.MilliValue() {
+			return nuclio.NewErrForbidden(fmt.Sprintf("Project quota of %s CPU would be exceeded (%s)",
+				ps.Quotas.MaxCPU,
+				apiresource.NewMilliQuantity(totalMilliCPU, apiresource.DecimalSI).String()))
+		}
+	}
+
+	return nil
+}
+
+// getFunctionMaxReplicas returns the replicas the function may scale to, the way the platforms resolve them
+func getFunctionMaxReplicas(functionConfig *functionconfig.Config) int {
+	for _, replicas := range []*int{
+		functionConfig.Spec.Replicas,
+		functionConfig.Spec.MaxReplicas,
+		functionConfig.Spec.MinReplicas,
+	} {
+		if replicas != nil {
+			if *replicas < 0 {
+				return 0
+			}
+
+			return *replicas
+		}
+	}
+
+	return 1
+}
+
+// getFunctionReplicaCPU returns the CPU a replica of the function may use - its limit, or its request if it
+// isn't limited
+func getFunctionReplicaCPU(functionConfig *functionconfig.Config) apiresource.Quantity {
+	if cpu, found := functionConfig.Spec.Resources.Limits[v1.ResourceCPU]; found {
+		return cpu
+	}
+
+	return functionConfig.Spec.Resources.Requests[v1.ResourceCPU]
+}
+
+func mergeResourceLists(resourceList v1.ResourceList, defaultResourceList v1.ResourceList) v1.ResourceList {
+	if len(defaultResourceList) == 0 {
+		return resourceList
+	}
+
+	if resourceList == nil {
+		resourceList = v1.ResourceList{}
+	}
+
+	for resourceName, quantity := range defaultResourceList {
+		resourceList[resourceName] = quantity
+	}
+
+	return resourceList
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type projectTestSuite struct {
+	suite.Suite
+}
+
+func (suite *projectTestSuite) TestApplyDefaults() {
+	projectSpec := ProjectSpec{
+		Defaults: &ProjectDefaults{
+			Registry:       "registry.example.com",
+			ServiceAccount: "analytics",
+			NodeSelector:   map[string]string{"pool": "analytics", "zone": "a"},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("100m"),
+					v1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				Limits: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("512Mi"),
+				},
+			},
+		},
+	}
+
+	functionConfig := functionconfig.Config{}
+	functionConfig.Spec.ServiceAccount = "custom"
+	functionConfig.Spec.NodeSelector = map[string]string{"zone": "b"}
+	functionConfig.Spec.Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")}
+
+	projectSpec.ApplyDefaults(&functionConfig)
+
+	// what the function sets is kept
+	suite.Require().Equal("registry.example.com", functionConfig.Spec.Build.Registry)
+	suite.Require().Equal("custom", functionConfig.Spec.ServiceAccount)
+	suite.Require().Equal(map[string]string{"pool": "analytics", "zone": "b"}, functionConfig.Spec.NodeSelector)
+
+	// resources the function limits aren't requested by default, so that the request doesn't exceed the limit
+	suite.Require().Len(functionConfig.Spec.Resources.Requests, 1)
+	suite.Require().Equal("100m", functionConfig.Spec.Resources.Requests.Cpu().String())
+	suite.Require().Equal("1", functionConfig.Spec.Resources.Limits.Cpu().String())
+	suite.Require().Equal("64Mi", functionConfig.Spec.Resources.Limits.Memory().String())
+
+	// projects without defaults leave functions as is
+	functionConfig = functionconfig.Config{}
+	(&ProjectSpec{}).ApplyDefaults(&functionConfig)
+	suite.Require().Empty(functionConfig.Spec.Build.Registry)
+	suite.Require().Nil(functionConfig.Spec.Resources.Limits)
+}
+
+func (suite *projectTestSuite) TestValidateQuotas() {
+	newFunctionConfig := func(name string, maxReplicas int, cpu string) *functionconfig.Config {
+		functionConfig := functionconfig.Config{}
+		functionConfig.Meta.Name = name
+		functionConfig.Spec.MaxReplicas = &maxReplicas
+		if cpu != "" {
+			functionConfig.Spec.Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}
+		}
+
+		return &functionConfig
+	}
+
+	projectFunctionConfigs := []*functionconfig.Config{
+		newFunctionConfig("first", 2, "500m"),
+		newFunctionConfig("second", 1, ""),
+	}
+
+	for _, testCase := range []struct {
+		name           string
+		quotas         ProjectQuotas
+		functionConfig *functionconfig.Config
+		expectedError  string
+	}{
+		{
+			name:           "unlimited",
+			functionConfig: newFunctionConfig("third", 100, "10"),
+		},
+		{
+			name:           "functionsExceeded",
+			quotas:         ProjectQuotas{MaxFunctions: 2},
+			functionConfig: newFunctionConfig("third", 1, ""),
+			expectedError:  "Project quota of 2 functions would be exceeded",
+		},
+		{
+			name:           "redeployNotCounted",
+			quotas:         ProjectQuotas{MaxFunctions: 2, MaxReplicas: 5},
+			functionConfig: newFunctionConfig("second", 3, ""),
+		},
+		{
+			name:           "replicasExceeded",
+			quotas:         ProjectQuotas{MaxReplicas: 5},
+			functionConfig: newFunctionConfig("second", 4, ""),
+			expectedError:  "Project quota of 5 replicas would be exceeded (6)",
+		},
+		{
+			name:           "cpuWithinQuota",
+			quotas:         ProjectQuotas{MaxCPU: "2"},
+			functionConfig: newFunctionConfig("third", 2, "500m"),
+		},
+		{
+			name:           "cpuExceeded",
+			quotas:         ProjectQuotas{MaxCPU: "2"},
+			functionConfig: newFunctionConfig("third", 3, "500m"),
+			expectedError:  "Project quota of 2 CPU would be exceeded (2500m)",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			projectSpec := ProjectSpec{Quotas: &testCase.quotas}
+
+			err := projectSpec.ValidateQuotas(testCase.functionConfig, projectFunctionConfigs)
+			if testCase.expectedError == "" {
+				suite.Require().NoError(err)
+				return
+			}
+
+			suite.Require().Error(err)
+			suite.Require().Equal(testCase.expectedError, err.Error())
+			suite.Require().Equal(http.StatusForbidden, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+		})
+	}
+}
+
+func (suite *projectTestSuite) TestValidate() {
+	suite.Require().NoError((&ProjectSpec{}).Validate())
+	suite.Require().NoError((&ProjectSpec{Quotas: &ProjectQuotas{MaxFunctions: 3, MaxCPU: "1500m"}}).Validate())
+	suite.Require().Error((&ProjectSpec{Quotas: &ProjectQuotas{MaxReplicas: -1}}).Validate())
+	suite.Require().Error((&ProjectSpec{Quotas: &ProjectQuotas{MaxCPU: "lots"}}).Validate())
+	suite.Require().Error((&ProjectSpec{Quotas: &ProjectQuotas{MaxCPU: "-1"}}).Validate())
+}
+
+func TestProjectTestSuite(t *testing.T) {
+	suite.Run(t, new(projectTestSuite))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// MaxFunctionRevisions is the number of revisions kept for each function. older revisions are discarded
+const MaxFunctionRevisions = 10
+
+// AddFunctionRevision returns the revisions with a revision of the given configuration added after the latest,
+// discarding the oldest revisions beyond MaxFunctionRevisions
+func AddFunctionRevision(revisions []FunctionRevision,
+	functionConfig *functionconfig.Config,
+	imageDigest string) []FunctionRevision {
+
+	revisionNumber := 1
+	if len(revisions) > 0 {
+		revisionNumber = revisions[len(revisions)-1].Revision + 1
+	}
+
+	revisions = append(revisions, FunctionRevision{
+		Revision:    revisionNumber,
+		Config:      *functionConfig,
+		ImageDigest: imageDigest,
+		Deployed:    time.Now().UTC(),
+	})
+
+	if len(revisions) > MaxFunctionRevisions {
+		revisions = revisions[len(revisions)-MaxFunctionRevisions:]
+	}
+
+	return revisions
+}
+
+// GetFunctionRevision returns the revision with the given number
+func GetFunctionRevision(revisions []FunctionRevision, revisionNumber int) (*FunctionRevision, error) {
+	for revisionIdx := range revisions {
+		if revisions[revisionIdx].Revision == revisionNumber {
+			return &revisions[revisionIdx], nil
+		}
+	}
+
+	return nil, nuclio.NewErrNotFound(fmt.Sprintf("Revision %d not found (kept revisions: %s)",
+		revisionNumber,
+		formatRevisionNumbers(revisions)))
+}
+
+func formatRevisionNumbers(revisions []FunctionRevision) string {
+	if len(revisions) == 0 {
+		return "none"
+	}
+
+	return fmt.Sprintf("%d-%d", revisions[0].Revision, revisions[len(revisions)-1].Revision)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type revisionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *revisionTestSuite) TestAddFunctionRevision() {
+	var revisions []FunctionRevision
+
+	functionConfig := functionconfig.Config{}
+	functionConfig.Meta.Name = "my-function"
+
+	for deployment := 1; deployment <= MaxFunctionRevisions+2; deployment++ {
+		functionConfig.Spec.Image = fmt.Sprintf("my-function:%d", deployment)
+		revisions = AddFunctionRevision(revisions, &functionConfig, "sha256:digest")
+	}
+
+	// the oldest revisions were discarded, and the rest keep their numbers
+	suite.Require().Len(revisions, MaxFunctionRevisions)
+	suite.Require().Equal(3, revisions[0].Revision)
+	suite.Require().Equal(MaxFunctionRevisions+2, revisions[len(revisions)-1].Revision)
+	suite.Require().Equal(functionConfig.Spec.Image, revisions[len(revisions)-1].Config.Spec.Image)
+	suite.Require().Equal("sha256:digest", revisions[len(revisions)-1].ImageDigest)
+	suite.Require().False(revisions[len(revisions)-1].Deployed.IsZero())
+
+	// revisions hold a copy of the configuration
+	functionConfig.Spec.Image = "changed"
+	suite.Require().NotEqual("changed", revisions[len(revisions)-1].Config.Spec.Image)
+}
+
+func (suite *revisionTestSuite) TestGetFunctionRevision() {
+	revisions := []FunctionRevision{{Revision: 4}, {Revision: 5}, {Revision: 6}}
+
+	revision, err := GetFunctionRevision(revisions, 5)
+	suite.Require().NoError(err)
+	suite.Require().Equal(5, revision.Revision)
+
+	_, err = GetFunctionRevision(revisions, 3)
+	suite.Require().EqualError(err, "Revision 3 not found (kept revisions: 4-6)")
+
+	_, err = GetFunctionRevision(nil, 1)
+	suite.Require().EqualError(err, "Revision 1 not found (kept revisions: none)")
+}
+
+func TestRevisionTestSuite(t *testing.T) {
+	suite.Run(t, new(revisionTestSuite))
+}
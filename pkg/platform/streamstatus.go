@@ -0,0 +1,51 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"encoding/json"
+
+	"github.com/nuclio/errors"
+)
+
+// ProcessorStatisticsPath is the path of the processor's web admin resource serving the statistics of its triggers
+const ProcessorStatisticsPath = "statistics"
+
+// ParseFunctionReplicaStreamStatus returns the stream status of a replica from the trigger statistics its
+// processor serves. triggers which don't report the shards they consume (i.e. aren't stream triggers) are omitted
+func ParseFunctionReplicaStreamStatus(replica string, encodedStatistics []byte) (*FunctionReplicaStreamStatus, error) {
+	triggersStatistics := map[string]struct {
+		Shards *[]StreamShardStatus `json:"shards"`
+	}{}
+
+	if err := json.Unmarshal(encodedStatistics, &triggersStatistics); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse processor statistics")
+	}
+
+	replicaStreamStatus := FunctionReplicaStreamStatus{
+		Replica:  replica,
+		Triggers: map[string][]StreamShardStatus{},
+	}
+
+	for triggerName, triggerStatistics := range triggersStatistics {
+		if triggerStatistics.Shards != nil {
+			replicaStreamStatus.Triggers[triggerName] = *triggerStatistics.Shards
+		}
+	}
+
+	return &replicaStreamStatus, nil
+}
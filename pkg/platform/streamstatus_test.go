@@ -0,0 +1,63 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type streamStatusTestSuite struct {
+	suite.Suite
+}
+
+func (suite *streamStatusTestSuite) TestParseFunctionReplicaStreamStatus() {
+	encodedStatistics := `{
+		"http": {"eventsHandledSuccessTotal": 10},
+		"stream": {
+			"eventsHandledSuccessTotal": 20,
+			"shards": [
+				{"shardID": 0, "lag": 5, "committedSequenceNumber": 100},
+				{"shardID": 2, "lag": 0, "committedSequenceNumber": 80}
+			]
+		},
+		"idle-stream": {"shards": []}
+	}`
+
+	replicaStreamStatus, err := ParseFunctionReplicaStreamStatus("my-function-abcde", []byte(encodedStatistics))
+	suite.Require().NoError(err)
+
+	// only stream triggers are reported, even those consuming no shards
+	suite.Require().Equal(&FunctionReplicaStreamStatus{
+		Replica: "my-function-abcde",
+		Triggers: map[string][]StreamShardStatus{
+			"stream": {
+				{ShardID: 0, Lag: 5, CommittedSequenceNumber: 100},
+				{ShardID: 2, Lag: 0, CommittedSequenceNumber: 80},
+			},
+			"idle-stream": {},
+		},
+	}, replicaStreamStatus)
+
+	_, err = ParseFunctionReplicaStreamStatus("my-function-abcde", []byte("not json"))
+	suite.Require().Error(err)
+}
+
+func TestStreamStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(streamStatusTestSuite))
+}
@@ -18,11 +18,15 @@ package platform
 
 // use k8s structure definitions for now. In the future, duplicate them for cleanliness
 import (
+	"io"
 	"net/http"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 
 	"github.com/nuclio/logger"
+	"k8s.io/api/core/v1"
 )
 
 //
@@ -44,6 +48,26 @@ type CreateFunctionBuildOptions struct {
 	OnAfterConfigUpdate        func(*functionconfig.Config) error
 	OutputImageFile            string
 	DependantImagesRegistryURL string
+
+	// if either is set, no image is built. instead, the processor Dockerfile is written to OutputDockerfile
+	// and the build context (with the onbuild artifacts staged) is copied to OutputContextDir, so that the
+	// image can be built externally
+	OutputDockerfile string
+	OutputContextDir string
+
+	// if set, called with each line of the image build's output as it is produced
+	BuildOutputLineHandler func(line string)
+
+	// number of times to retry a failed build, optionally only if the failure seems transient
+	BuildRetries              int
+	BuildRetryOnTransientOnly bool
+
+	// if set, the durations of the build's phases are recorded in it
+	PhaseTimings *common.PhaseTimings
+
+	// if set, the function configuration is read from these contents rather than from the file at
+	// Spec.Build.FunctionConfigPath, which is only recorded (e.g. when the file is a rendered template)
+	FunctionConfigContents []byte
 }
 
 type CreateFunctionOptions struct {
@@ -53,6 +77,30 @@ type CreateFunctionOptions struct {
 	InputImageFile             string
 	AuthConfig                 *AuthConfig
 	DependantImagesRegistryURL string
+
+	// if set, called with each line of the image build's output as it is produced
+	BuildOutputLineHandler func(line string)
+
+	// number of times to retry a failed build, optionally only if the failure seems transient
+	BuildRetries              int
+	BuildRetryOnTransientOnly bool
+
+	// if set, the durations of the build's phases are recorded in it
+	PhaseTimings *common.PhaseTimings
+
+	// if positive, after a successful deploy all but this many of the most recent images of the
+	// function are removed (local platform only)
+	PruneOldImages int
+
+	// set once the function is built, to be recorded in the function's status
+	ImageStatus *functionconfig.ImageStatus
+
+	// who deploys the function, to be recorded in the audit log. if nil, the user running the process
+	Identity *Identity
+
+	// if set, the function configuration is read from these contents rather than from the file at
+	// Spec.Build.FunctionConfigPath, which is only recorded (e.g. when the file is a rendered template)
+	FunctionConfigContents []byte
 }
 
 type UpdateFunctionOptions struct {
@@ -60,19 +108,34 @@ type UpdateFunctionOptions struct {
 	FunctionSpec   *functionconfig.Spec
 	FunctionStatus *functionconfig.Status
 	AuthConfig     *AuthConfig
+
+	// who updates the function, to be recorded in the audit log. if nil, the user running the process
+	Identity *Identity
 }
 
 type DeleteFunctionOptions struct {
 	FunctionConfig functionconfig.Config
 	AuthConfig     *AuthConfig
+
+	// who deletes the function, to be recorded in the audit log. if nil, the user running the process
+	Identity *Identity
 }
 
 // CreateFunctionBuildResult holds information detected/generated as a result of a build process
 type CreateFunctionBuildResult struct {
 	Image string
 
+	// number of build attempts it took to build the image
+	BuildAttempts int
+
+	// the build args the processor Dockerfile expects, when the build context was output rather than built
+	BuildArgs map[string]string
+
 	// the function configuration read by the builder either from function.yaml or inline configuration
 	UpdatedFunctionConfig functionconfig.Config
+
+	// the digest and signature of the built image, if it was signed or had its SBOM generated
+	ImageStatus *functionconfig.ImageStatus
 }
 
 // CreateFunctionResult holds the results of a deploy
@@ -80,6 +143,17 @@ type CreateFunctionResult struct {
 	CreateFunctionBuildResult
 	Port        int
 	ContainerID string
+
+	// results of the warmup of the deployed function, if it has one
+	Warmup *functionconfig.WarmupStatus
+
+	// time spent building the function image and deploying it until ready
+	BuildDuration  time.Duration
+	DeployDuration time.Duration
+
+	// old images of the function removed after the deploy and the disk space they took
+	PrunedImages        int
+	ReclaimedImageBytes int64
 }
 
 // GetFunctionsOptions is the base for all platform get options
@@ -88,6 +162,184 @@ type GetFunctionsOptions struct {
 	Namespace  string
 	Labels     string
 	AuthConfig *AuthConfig
+
+	// get the functions of all namespaces rather than of Namespace, on platforms that support it
+	AllNamespaces bool
+}
+
+// GetFunctionLogsOptions are options for reading the logs of a function
+type GetFunctionLogsOptions struct {
+	Name      string
+	Namespace string
+
+	// keep reading logs as they are written
+	Follow bool
+
+	// if set, only logs written within this duration
+	Since time.Duration
+
+	// the replica (pod, or container) to read the logs of. may be omitted if the function has a single replica
+	Replica string
+}
+
+// GetFunctionExecCommandOptions are options for running a command inside a function's container
+type GetFunctionExecCommandOptions struct {
+	Name      string
+	Namespace string
+
+	// the replica (pod, or container) to run the command in. may be omitted if the function has a single replica
+	Replica string
+
+	// the command to run and its arguments
+	Command []string
+
+	// allocate a terminal for the command
+	TTY bool
+}
+
+// CreateFunctionTunnelOptions are options for reaching a function that isn't exposed outside the platform
+type CreateFunctionTunnelOptions struct {
+	Name      string
+	Namespace string
+}
+
+// FunctionTunnel makes a function reachable from where it was created, until it's closed
+type FunctionTunnel interface {
+
+	// GetAddress returns the address (host:port) at which the function's HTTP port is reachable
+	GetAddress() string
+
+	// Close tears the tunnel down
+	Close() error
+}
+
+// PauseFunctionOptions are options for pausing a function, leaving its configuration as is
+type PauseFunctionOptions struct {
+	Name       string
+	Namespace  string
+	AuthConfig *AuthConfig
+}
+
+// ResumeFunctionOptions are options for resuming a paused function
+type ResumeFunctionOptions struct {
+	Name       string
+	Namespace  string
+	AuthConfig *AuthConfig
+}
+
+// PauseFunctionTriggerOptions are options for pausing one of a function's triggers
+type PauseFunctionTriggerOptions struct {
+	Name        string
+	Namespace   string
+	TriggerName string
+	AuthConfig  *AuthConfig
+}
+
+// ResumeFunctionTriggerOptions are options for resuming a paused trigger of a function
+type ResumeFunctionTriggerOptions struct {
+	Name        string
+	Namespace   string
+	TriggerName string
+	AuthConfig  *AuthConfig
+}
+
+// GetFunctionUsageOptions are options for getting the resource usage of functions
+type GetFunctionUsageOptions struct {
+
+	// if empty, all functions in the namespace
+	Name      string
+	Namespace string
+
+	// the period over which event rates are calculated, for platforms that sample the function's counters
+	SampleInterval time.Duration
+
+	// the prometheus server scraping the functions, for platforms that get event rates from it
+	PrometheusURL string
+}
+
+// FunctionUsage is the resource usage of a function's replicas, and the rate of the events they handle.
+// metrics the platform could not get are left nil
+type FunctionUsage struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Replicas  int    `json:"replicas"`
+
+	// summed over all replicas
+	CPUMillicores *int64 `json:"cpuMillicores,omitempty"`
+	MemoryBytes   *int64 `json:"memoryBytes,omitempty"`
+
+	EventsPerSecond *float64 `json:"eventsPerSecond,omitempty"`
+
+	// the fraction of handled events which failed, between 0 and 1
+	ErrorRate *float64 `json:"errorRate,omitempty"`
+}
+
+// GetFunctionStreamStatusOptions are options for getting the state of a function's stream triggers
+type GetFunctionStreamStatusOptions struct {
+	Name      string
+	Namespace string
+}
+
+// FunctionReplicaStreamStatus is the state of the stream triggers of one of a function's replicas
+type FunctionReplicaStreamStatus struct {
+	Replica string `json:"replica"`
+
+	// the shards each stream trigger consumes, by trigger name. replicas whose triggers don't report their
+	// shards (e.g. not ready yet) have none
+	Triggers map[string][]StreamShardStatus `json:"triggers,omitempty"`
+}
+
+// StreamShardStatus is the state of a shard consumed by a replica's stream trigger
+type StreamShardStatus struct {
+	ShardID                 int    `json:"shardID"`
+	Lag                     uint64 `json:"lag"`
+	CommittedSequenceNumber uint64 `json:"committedSequenceNumber"`
+}
+
+// GetFunctionRevisionsOptions are options for getting the revisions of a function
+type GetFunctionRevisionsOptions struct {
+	Name      string
+	Namespace string
+}
+
+// FunctionRevision is a configuration a function was deployed with. revisions are numbered from 1, in the
+// order the function was deployed
+type FunctionRevision struct {
+	Revision int                   `json:"revision"`
+	Config   functionconfig.Config `json:"config"`
+
+	// the digest (or ID) of the image the function ran, if the platform could tell
+	ImageDigest string    `json:"imageDigest,omitempty"`
+	Deployed    time.Time `json:"deployed"`
+}
+
+// GetFunctionInvocationsOptions are options for getting the invocations the invocation inspector retains
+type GetFunctionInvocationsOptions struct {
+	Name      string
+	Namespace string
+
+	// if set, only the invocations that started at or after this time are returned
+	Since time.Time
+
+	// if set, only the invocations that failed are returned
+	FailedOnly bool
+}
+
+// DeleteFunctionInvocationsOptions are options for purging the invocations the invocation inspector retains
+type DeleteFunctionInvocationsOptions struct {
+	Name      string
+	Namespace string
+}
+
+// GetAuditRecordsOptions are options for getting the audit records of a namespace
+type GetAuditRecordsOptions struct {
+	Namespace string
+
+	// if set, only the records of this function are returned
+	Name string
+
+	// if set, only the records recorded at or after this time are returned
+	Since time.Time
 }
 
 // InvokeViaType defines via which mechanism the function will be invoked
@@ -110,6 +362,22 @@ type CreateFunctionInvocationOptions struct {
 	Headers      http.Header
 	LogLevelName string
 	Via          InvokeViaType
+
+	// if set, the function is invoked at this address rather than the one the platform resolves
+	URL string
+
+	// if the function publishes more than one port, the (container) port to invoke
+	Port int
+
+	// if set, the request body is streamed from it rather than taken from Body (e.g. an uploaded file)
+	BodyStream io.Reader
+
+	// if set, the response body is returned as a stream to read as it arrives (e.g. chunked responses or
+	// server-sent events) rather than read in whole
+	Stream bool
+
+	// if set, the invocation is recorded in the audit log as performed by this identity
+	Identity *Identity
 }
 
 // CreateFunctionInvocationResult holds the result of a single invocation
@@ -117,6 +385,9 @@ type CreateFunctionInvocationResult struct {
 	Headers    http.Header
 	Body       []byte
 	StatusCode int
+
+	// when streaming, the response body instead of Body. the caller must close it
+	BodyStream io.ReadCloser
 }
 
 // AddressType
@@ -149,6 +420,27 @@ type ProjectMeta struct {
 type ProjectSpec struct {
 	DisplayName string `json:"displayName,omitempty"` // Deprecated. Will be removed in the next major version release
 	Description string `json:"description,omitempty"`
+
+	// Defaults are applied to the functions deployed to the project which don't set them
+	Defaults *ProjectDefaults `json:"defaults,omitempty"`
+
+	// Quotas limit the functions of the project, and are enforced by the platform when functions are deployed
+	Quotas *ProjectQuotas `json:"quotas,omitempty"`
+}
+
+type ProjectDefaults struct {
+	Registry       string                  `json:"registry,omitempty"`
+	NodeSelector   map[string]string       `json:"nodeSelector,omitempty"`
+	Resources      v1.ResourceRequirements `json:"resources,omitempty"`
+	ServiceAccount string                  `json:"serviceAccount,omitempty"`
+}
+
+// ProjectQuotas are the limits of the project's functions, where zero is unlimited. replicas and CPU are
+// counted at the functions' max replicas
+type ProjectQuotas struct {
+	MaxFunctions int    `json:"maxFunctions,omitempty"`
+	MaxReplicas  int    `json:"maxReplicas,omitempty"`
+	MaxCPU       string `json:"maxCPU,omitempty"`
 }
 
 type ProjectConfig struct {
@@ -170,13 +462,31 @@ type DeleteProjectOptions struct {
 
 type GetProjectsOptions struct {
 	Meta ProjectMeta
+
+	// get the projects of all namespaces rather than of Meta.Namespace, on platforms that support it
+	AllNamespaces bool
 }
 
 // to appease k8s
 func (s *ProjectSpec) DeepCopyInto(out *ProjectSpec) {
-
-	// TODO: proper deep copy
 	*out = *s
+
+	if s.Defaults != nil {
+		out.Defaults = &ProjectDefaults{}
+		*out.Defaults = *s.Defaults
+		if s.Defaults.NodeSelector != nil {
+			out.Defaults.NodeSelector = map[string]string{}
+			for labelName, labelValue := range s.Defaults.NodeSelector {
+				out.Defaults.NodeSelector[labelName] = labelValue
+			}
+		}
+		s.Defaults.Resources.DeepCopyInto(&out.Defaults.Resources)
+	}
+
+	if s.Quotas != nil {
+		out.Quotas = &ProjectQuotas{}
+		*out.Quotas = *s.Quotas
+	}
 }
 
 //
@@ -225,3 +535,94 @@ func (s *FunctionEventSpec) DeepCopyInto(out *FunctionEventSpec) {
 	// TODO: proper deep copy
 	*out = *s
 }
+
+//
+// APIGateway
+//
+
+type APIGatewayAuthenticationMode string
+
+const (
+	APIGatewayAuthenticationModeNone      APIGatewayAuthenticationMode = "none"
+	APIGatewayAuthenticationModeBasicAuth APIGatewayAuthenticationMode = "basicAuth"
+)
+
+type APIGatewayUpstreamKind string
+
+const (
+	APIGatewayUpstreamKindNuclioFunction APIGatewayUpstreamKind = "nucliofunction"
+)
+
+type APIGatewayState string
+
+const (
+	APIGatewayStateReady APIGatewayState = "ready"
+	APIGatewayStateError APIGatewayState = "error"
+)
+
+type APIGatewayMeta struct {
+	Name        string            `json:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type APIGatewayBasicAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type APIGatewayAuthenticationSpec struct {
+	BasicAuth *APIGatewayBasicAuth `json:"basicAuth,omitempty"`
+}
+
+type NuclioFunctionAPIGatewaySpec struct {
+	Name string `json:"name,omitempty"`
+}
+
+type APIGatewayUpstreamSpec struct {
+	Kind           APIGatewayUpstreamKind        `json:"kind,omitempty"`
+	NuclioFunction *NuclioFunctionAPIGatewaySpec `json:"nucliofunction,omitempty"`
+
+	// the percentage of the requests the upstream gets, when it's the canary (second) upstream
+	Percentage int `json:"percentage,omitempty"`
+}
+
+type APIGatewaySpec struct {
+	Host               string                        `json:"host,omitempty"`
+	Path               string                        `json:"path,omitempty"`
+	Description        string                        `json:"description,omitempty"`
+	AuthenticationMode APIGatewayAuthenticationMode  `json:"authenticationMode,omitempty"`
+	Authentication     *APIGatewayAuthenticationSpec `json:"authentication,omitempty"`
+	Upstreams          []APIGatewayUpstreamSpec      `json:"upstreams,omitempty"`
+}
+
+type APIGatewayStatus struct {
+	State     APIGatewayState `json:"state,omitempty"`
+	LastError string          `json:"lastError,omitempty"`
+}
+
+type APIGatewayConfig struct {
+	Meta   APIGatewayMeta
+	Spec   APIGatewaySpec
+	Status APIGatewayStatus
+}
+
+type CreateAPIGatewayOptions struct {
+	APIGatewayConfig APIGatewayConfig
+}
+
+type UpdateAPIGatewayOptions struct {
+	APIGatewayConfig APIGatewayConfig
+}
+
+type DeleteAPIGatewayOptions struct {
+	Meta APIGatewayMeta
+}
+
+type GetAPIGatewaysOptions struct {
+	Meta APIGatewayMeta
+
+	// get the API gateways of all namespaces rather than of Meta.Namespace, on platforms that support it
+	AllNamespaces bool
+}
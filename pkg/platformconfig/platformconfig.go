@@ -18,21 +18,41 @@ package platformconfig
 
 import (
 	"os"
+	"strings"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 
 	"github.com/nuclio/errors"
 )
 
+// DefaultFunctionScrapeMetricSinkName is the name of the prometheus pull metric sink functions get by default
+const DefaultFunctionScrapeMetricSinkName = "defaultPrometheusPull"
+
 type Config struct {
 	Kind                     string                   `json:"kind,omitempty"`
 	WebAdmin                 WebServer                `json:"webAdmin,omitempty"`
 	HealthCheck              WebServer                `json:"healthCheck,omitempty"`
 	Logger                   Logger                   `json:"logger,omitempty"`
 	Metrics                  Metrics                  `json:"metrics,omitempty"`
+	Tracing                  Tracing                  `json:"tracing,omitempty"`
 	ScaleToZero              ScaleToZero              `json:"scaleToZero,omitempty"`
 	AutoScale                AutoScale                `json:"autoScale,omitempty"`
 	FunctionAugmentedConfigs []LabelSelectorAndConfig `json:"functionAugmentedConfigs,omitempty"`
+
+	// node selectors and tolerations functions may be scheduled with, and how the nodes' GPUs are shared.
+	// honoured by the kube platform
+	SchedulingPresets map[string]SchedulingPreset `json:"schedulingPresets,omitempty"`
+	GPUSharing        GPUSharing                  `json:"gpuSharing,omitempty"`
+
+	// the images and package indexes function builds use, by runtime name (e.g. python:3.7) or by runtime kind
+	// (e.g. python) for all its versions
+	RuntimeImages map[string]RuntimeImages `json:"runtimeImages,omitempty"`
+
+	// defaults of the ingresses of functions' HTTP triggers. honoured by the kube platform
+	Ingresses Ingresses `json:"ingresses,omitempty"`
+
+	// the external secret stores functions read environment variables from (spec.externalSecretEnv), by name
+	SecretProviders map[string]SecretProvider `json:"secretProviders,omitempty"`
 }
 
 func NewPlatformConfig(configurationPath string) (*Config, error) {
@@ -58,6 +78,21 @@ func NewPlatformConfig(configurationPath string) (*Config, error) {
 	return config, nil
 }
 
+// GetRuntimeImages returns the images and package indexes builds of the runtime use, by its name or else by its
+// kind. returns nil if the runtime's defaults are used
+func (config *Config) GetRuntimeImages(runtimeName string) *RuntimeImages {
+	if runtimeImages, found := config.RuntimeImages[runtimeName]; found {
+		return &runtimeImages
+	}
+
+	runtimeKind := strings.Split(runtimeName, ":")[0]
+	if runtimeImages, found := config.RuntimeImages[runtimeKind]; found {
+		return &runtimeImages
+	}
+
+	return nil
+}
+
 func (config *Config) GetSystemLoggerSinks() (map[string]LoggerSinkWithLevel, error) {
 	return config.getLoggerSinksWithLevel(config.Logger.System)
 }
@@ -95,7 +130,27 @@ func (config *Config) GetSystemMetricSinks() (map[string]MetricSink, error) {
 }
 
 func (config *Config) GetFunctionMetricSinks() (map[string]MetricSink, error) {
-	return config.getMetricSinks(config.Metrics.Functions)
+	metricSinks, err := config.getMetricSinks(config.Metrics.Functions)
+	if err != nil {
+		return nil, err
+	}
+
+	// functions expose their metrics for prometheus to scrape, unless disabled or explicitly configured
+	if config.Metrics.Scrape.Enabled != nil && !*config.Metrics.Scrape.Enabled {
+		return metricSinks, nil
+	}
+
+	for _, metricSink := range metricSinks {
+		if metricSink.Kind == "prometheusPull" {
+			return metricSinks, nil
+		}
+	}
+
+	metricSinks[DefaultFunctionScrapeMetricSinkName] = MetricSink{
+		Kind: "prometheusPull",
+	}
+
+	return metricSinks, nil
 }
 
 func (config *Config) getMetricSinks(metricSinkNames []string) (map[string]MetricSink, error) {
@@ -19,6 +19,7 @@ package platformconfig
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
@@ -444,6 +445,49 @@ metrics:
 	suite.Require().True(compare.CompareNoOrder(expectedFunctionMetricSinks, functionMetricSinks))
 }
 
+func (suite *PlatformConfigTestSuite) TestGetFunctionMetricSinksDefaultScrape() {
+	configurationContents := `
+metrics:
+  sinks:
+    pushSink:
+      kind: prometheusPush
+      url: 10.0.0.1:30
+  functions:
+  - pushSink
+`
+
+	var readConfiguration Config
+
+	// read configuration
+	err := suite.reader.Read(bytes.NewBufferString(configurationContents), "yaml", &readConfiguration)
+	suite.Require().NoError(err)
+
+	// functions get a prometheus pull sink by default
+	functionMetricSinks, err := readConfiguration.GetFunctionMetricSinks()
+	suite.Require().NoError(err)
+
+	expectedFunctionMetricSinks := map[string]MetricSink{
+		"pushSink": {
+			Kind: "prometheusPush",
+			URL:  "10.0.0.1:30",
+		},
+		DefaultFunctionScrapeMetricSinkName: {
+			Kind: "prometheusPull",
+		},
+	}
+
+	suite.Require().True(compare.CompareNoOrder(expectedFunctionMetricSinks, functionMetricSinks))
+
+	// unless scraping is disabled
+	falseValue := false
+	readConfiguration.Metrics.Scrape.Enabled = &falseValue
+
+	functionMetricSinks, err = readConfiguration.GetFunctionMetricSinks()
+	suite.Require().NoError(err)
+	suite.Require().Len(functionMetricSinks, 1)
+	suite.Require().Contains(functionMetricSinks, "pushSink")
+}
+
 func (suite *PlatformConfigTestSuite) TestFunctionAugmentedConfigs() {
 	var readConfiguration Config
 	zero := 0
@@ -501,6 +545,54 @@ functionAugmentedConfigs:
 		readConfiguration.FunctionAugmentedConfigs))
 }
 
+func (suite *PlatformConfigTestSuite) TestRuntimeImages() {
+	var readConfiguration Config
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	configurationContents := fmt.Sprintf(`
+runtimeImages:
+  python:
+    baseImage: registry.corp/hardened/python:3.7
+    baseImageDigest: %s
+    pipIndexURL: https://pypi.corp/simple
+    enforce: true
+  python:3.6:
+    baseImage: registry.corp/hardened/python:3.6@%s
+    baseImageDigest: %s
+  nodejs:
+    onbuildImage: registry.corp/nuclio/handler-builder-nodejs-onbuild:{{ .Label }}-{{ .Arch }}
+    onbuildImageDigest: sha256:invalid
+`, digest, digest, digest)
+
+	// read configuration
+	err := suite.reader.Read(bytes.NewBufferString(configurationContents), "yaml", &readConfiguration)
+	suite.Require().NoError(err)
+
+	// runtimes are matched by name, then by kind
+	runtimeImages := readConfiguration.GetRuntimeImages("python:3.7")
+	suite.Require().NotNil(runtimeImages)
+	suite.Require().True(runtimeImages.Enforce)
+	suite.Require().Equal("https://pypi.corp/simple", runtimeImages.PipIndexURL)
+
+	baseImage, err := runtimeImages.GetBaseImage()
+	suite.Require().NoError(err)
+	suite.Require().Equal("registry.corp/hardened/python:3.7@"+digest, baseImage)
+
+	// an image already referenced by its pinned digest is left as is
+	baseImage, err = readConfiguration.GetRuntimeImages("python:3.6").GetBaseImage()
+	suite.Require().NoError(err)
+	suite.Require().Equal("registry.corp/hardened/python:3.6@"+digest, baseImage)
+
+	// no image is pinned to an invalid or different digest
+	_, err = readConfiguration.GetRuntimeImages("nodejs").GetOnbuildImage()
+	suite.Require().Error(err)
+
+	runtimeImages.BaseImage = "registry.corp/hardened/python:3.7@sha256:" + strings.Repeat("cd", 32)
+	_, err = runtimeImages.GetBaseImage()
+	suite.Require().Error(err)
+
+	suite.Require().Nil(readConfiguration.GetRuntimeImages("golang"))
+}
+
 func TestRegistryTestSuite(t *testing.T) {
 	suite.Run(t, new(PlatformConfigTestSuite))
 }
@@ -17,9 +17,14 @@ limitations under the License.
 package platformconfig
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 
+	"github.com/nuclio/errors"
 	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -86,6 +91,152 @@ type Metrics struct {
 	Sinks     map[string]MetricSink `json:"sinks,omitempty"`
 	System    []string              `json:"system,omitempty"`
 	Functions []string              `json:"functions,omitempty"`
+	Scrape    MetricsScrape         `json:"scrape,omitempty"`
+}
+
+// MetricsScrape configures the prometheus pull metric sink functions get by default, which serves /metrics
+// for prometheus to scrape. it is enabled unless explicitly disabled
+type MetricsScrape struct {
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// Tracing configures exporting the spans of event handling over OTLP/HTTP. tracing is enabled when a URL
+// is set, unless explicitly disabled
+type Tracing struct {
+	Enabled       *bool             `json:"enabled,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	SampleRatio   *float64          `json:"sampleRatio,omitempty"`
+	FlushInterval string            `json:"flushInterval,omitempty"`
+}
+
+// SecretProvider is an external secret store (vault, awsSecretsManager or azureKeyVault). secrets are read with
+// the identity of the function's environment (e.g. its service account), and re-read every refresh interval
+type SecretProvider struct {
+	Kind            string                 `json:"kind,omitempty"`
+	URL             string                 `json:"url,omitempty"`
+	Attributes      map[string]interface{} `json:"attributes,omitempty"`
+	RefreshInterval string                 `json:"refreshInterval,omitempty"`
+}
+
+// SchedulingPreset is a set of node selectors and tolerations, which functions name to be scheduled with
+// (spec.schedulingPreset) rather than repeating them - e.g. to run on a pool of GPU nodes
+type SchedulingPreset struct {
+	NodeSelector map[string]string    `json:"nodeSelector,omitempty"`
+	Tolerations  []core_v1.Toleration `json:"tolerations,omitempty"`
+}
+
+// GPUSharing describes how the nvidia device plugin shares the nodes' GPUs, for functions requesting a
+// fraction of a GPU
+type GPUSharing struct {
+
+	// ResourceName is the resource shared GPUs are advertised as (default nvidia.com/gpu.shared)
+	ResourceName string `json:"resourceName,omitempty"`
+
+	// Replicas is the number of replicas each GPU is shared by (default 4)
+	Replicas int `json:"replicas,omitempty"`
+}
+
+// Ingresses are the defaults of the ingresses of functions' HTTP triggers. templates may hold {function},
+// {project} and {namespace} placeholders, which are replaced by those of the function
+type Ingresses struct {
+
+	// HostTemplate is the host of ingresses which don't set one (e.g. {function}.{project}.example.com)
+	HostTemplate string `json:"hostTemplate,omitempty"`
+
+	// TLSSecretNameTemplate is the secret cert-manager stores the certificates of ingresses with a TLS issuer
+	// in, for those which don't name one (default {function}-tls)
+	TLSSecretNameTemplate string `json:"tlsSecretNameTemplate,omitempty"`
+}
+
+// DefaultIngressTLSSecretNameTemplate is the secret of ingresses' certificates, unless configured otherwise
+const DefaultIngressTLSSecretNameTemplate = "{function}-tls"
+
+// GetTLSSecretNameTemplate returns the template of the secrets of ingresses' certificates
+func (i *Ingresses) GetTLSSecretNameTemplate() string {
+	if i.TLSSecretNameTemplate == "" {
+		return DefaultIngressTLSSecretNameTemplate
+	}
+
+	return i.TLSSecretNameTemplate
+}
+
+// GPU sharing defaults
+const (
+	DefaultGPUSharingResourceName = "nvidia.com/gpu.shared"
+	DefaultGPUSharingReplicas     = 4
+)
+
+// GetResourceName returns the resource shared GPUs are advertised as
+func (gs *GPUSharing) GetResourceName() string {
+	if gs.ResourceName == "" {
+		return DefaultGPUSharingResourceName
+	}
+
+	return gs.ResourceName
+}
+
+// GetReplicas returns the number of replicas each GPU is shared by
+func (gs *GPUSharing) GetReplicas() int {
+	if gs.Replicas == 0 {
+		return DefaultGPUSharingReplicas
+	}
+
+	return gs.Replicas
+}
+
+// RuntimeImages overrides the base and onbuild images builds of a runtime use, and the package indexes their
+// build commands install from - e.g. to build functions only from hardened images and through a mirror
+type RuntimeImages struct {
+	BaseImage    string `json:"baseImage,omitempty"`
+	OnbuildImage string `json:"onbuildImage,omitempty"`
+
+	// if set, the images are pulled by these digests (e.g. sha256:...), so that a build fails rather than use
+	// an image that was pushed again under the same tag
+	BaseImageDigest    string `json:"baseImageDigest,omitempty"`
+	OnbuildImageDigest string `json:"onbuildImageDigest,omitempty"`
+
+	PipIndexURL    string `json:"pipIndexURL,omitempty"`
+	NPMRegistryURL string `json:"npmRegistryURL,omitempty"`
+
+	// if set, functions can't build from other images (spec.build.baseImage and spec.build.onbuildImage)
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+var imageDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// GetBaseImage returns the base image, pinned to its digest if one is set
+func (ri *RuntimeImages) GetBaseImage() (string, error) {
+	return pinImage(ri.BaseImage, ri.BaseImageDigest)
+}
+
+// GetOnbuildImage returns the onbuild image, pinned to its digest if one is set. the image may be a template,
+// like spec.build.onbuildImage
+func (ri *RuntimeImages) GetOnbuildImage() (string, error) {
+	return pinImage(ri.OnbuildImage, ri.OnbuildImageDigest)
+}
+
+// pinImage returns the image referenced by the digest (e.g. repository:tag@sha256:...), which the container
+// runtime verifies the pulled image against
+func pinImage(image string, digest string) (string, error) {
+	if image == "" || digest == "" {
+		return image, nil
+	}
+
+	if !imageDigestPattern.MatchString(digest) {
+		return "", errors.Errorf("Invalid digest of image %s: %s (expected sha256:<64 hex characters>)", image, digest)
+	}
+
+	// an image that's already referenced by digest must be referenced by the pinned one
+	if digestIdx := strings.LastIndex(image, "@"); digestIdx != -1 {
+		if image[digestIdx+1:] != digest {
+			return "", errors.Errorf("Image %s isn't referenced by its pinned digest %s", image, digest)
+		}
+
+		return image, nil
+	}
+
+	return image + "@" + digest, nil
 }
 
 type LabelSelectorAndConfig struct {
@@ -27,14 +27,17 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/inlineparser"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	// load runtimes so that they register to runtime registry
@@ -46,6 +49,7 @@ import (
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/python"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/ruby"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/shell"
+	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/wasm"
 	"github.com/nuclio/nuclio/pkg/processor/build/util"
 	"github.com/nuclio/nuclio/pkg/version"
 
@@ -60,10 +64,17 @@ const (
 	FunctionConfigFileName = "function.yaml"
 	uhttpcImage            = "quay.io/nuclio/uhttpc:0.0.1-amd64"
 	GithubEntryType        = "github"
+	GitEntryType           = "git"
 	ArchiveEntryType       = "archive"
 	S3EntryType            = "s3"
 	ImageEntryType         = "image"
 	SourceCodeEntryType    = "sourceCode"
+
+	// where the build cache of offline builds is copied to - in the staging dir, in the processor image and
+	// in the handler dir (for runtimes which resolve packages in the onbuild stage)
+	buildCacheDirInStaging = "build-cache"
+	buildCacheDirInImage   = "/nuclio-build-cache"
+	buildCacheDirInHandler = ".nuclio-build-cache"
 )
 
 // holds parameters for things that are required before a runtime can be initialized
@@ -117,6 +128,12 @@ type Builder struct {
 	originalFunctionConfig functionconfig.Config
 
 	s3Client common.S3Client
+
+	// runs git and the tools that generate SBOMs and sign images, created when first needed
+	cmdRunner cmdrunner.CmdRunner
+
+	// the commit the function's code was checked out at, when cloned from a git repository
+	sourceCommit string
 }
 
 // NewBuilder returns a new builder
@@ -140,10 +157,14 @@ func (b *Builder) Build(options *platform.CreateFunctionBuildOptions) (*platform
 
 	b.logger.InfoWith("Building", "name", b.options.FunctionConfig.Meta.Name)
 
+	if err = b.validateBuildContextOutputs(); err != nil {
+		return nil, errors.Wrap(err, "Invalid build context outputs")
+	}
+
 	configurationRead := false
 	configFilePath := b.providedFunctionConfigFilePath()
 	b.logger.DebugWith("Function configuration found in directory", "configFilePath", configFilePath)
-	if common.IsFile(configFilePath) {
+	if b.options.FunctionConfigContents != nil || common.IsFile(configFilePath) {
 		if _, err = b.readConfiguration(); err != nil {
 			return nil, errors.Wrap(err, "Failed to read configuration")
 		}
@@ -218,6 +239,11 @@ func (b *Builder) Build(options *platform.CreateFunctionBuildOptions) (*platform
 		return nil, errors.Wrap(err, "Failed to enrich configuration")
 	}
 
+	// fail before building, rather than after
+	if err = b.validateSupplyChainConfiguration(); err != nil {
+		return nil, errors.Wrap(err, "Invalid SBOM or signing configuration")
+	}
+
 	// copy the configuration we enriched, restoring any fields that should not be leaked externally
 	enrichedConfiguration := b.options.FunctionConfig
 
@@ -255,6 +281,25 @@ func (b *Builder) Build(options *platform.CreateFunctionBuildOptions) (*platform
 		UpdatedFunctionConfig: enrichedConfiguration,
 	}
 
+	// whoever builds the output context needs the build args the Dockerfile expects
+	if b.isBuildContextOutput() {
+		if buildResult.BuildArgs, err = b.getBuildArgs(); err != nil {
+			return nil, errors.Wrap(err, "Failed to get build args")
+		}
+	} else if enrichedConfiguration.Spec.Build.CodeEntryType != ImageEntryType {
+		if buildResult.ImageStatus, err = b.secureImage(processorImage); err != nil {
+			return nil, errors.Wrap(err, "Failed to secure image")
+		}
+	}
+
+	if b.sourceCommit != "" {
+		if buildResult.ImageStatus == nil {
+			buildResult.ImageStatus = &functionconfig.ImageStatus{}
+		}
+
+		buildResult.ImageStatus.SourceCommit = b.sourceCommit
+	}
+
 	b.logger.InfoWith("Build complete", "result", buildResult)
 
 	return buildResult, nil
@@ -312,6 +357,11 @@ func (b *Builder) GenerateDockerfileContents(baseImage string,
 	// now that all artifacts are in the artifacts directory, we can craft a Dockerfile
 	dockerfileTemplateContents := `# Multistage builds
 
+{{ if .MultiPlatform }}
+# Set by buildx to the architecture of the platform each stage is built for
+ARG TARGETARCH
+{{ end }}
+
 {{ range $onbuildStage := .OnbuildStages }}
 {{ $onbuildStage }}
 {{ end }}
@@ -342,27 +392,72 @@ COPY {{ $localArtifactPath }} {{ $imageArtifactPath }}
 
 {{ if .HealthcheckRequired }}
 # Readiness probe
+{{ if .Windows }}
+HEALTHCHECK --interval=1s --timeout=3s CMD curl.exe --silent --fail http://127.0.0.1:8082/ready || exit 1
+{{ else }}
 HEALTHCHECK --interval=1s --timeout=3s CMD /usr/local/bin/uhttpc --url http://127.0.0.1:8082/ready || exit 1
 {{ end }}
+{{ end }}
 
 # Run the post-copy directives
 {{ range $directive := .PostCopyDirectives }}
 {{ $directive.Kind }} {{ $directive.Value }}
 {{ end }}
 
+{{ if .PostInstallDirectives }}
+# Run the post-install hook
+{{ range $directive := .PostInstallDirectives }}
+{{ $directive.Kind }} {{ $directive.Value }}
+{{ end }}
+{{ end }}
+
+{{ if not .Windows }}
+# The processor and runtimes write temporary files only under /tmp, so that the root filesystem may be read-only
+ENV TMPDIR=/tmp PYTHONDONTWRITEBYTECODE=1
+{{ end }}
+
+{{ if .PreFinalDirectives }}
+# Run the pre-final hook
+{{ range $directive := .PreFinalDirectives }}
+{{ $directive.Kind }} {{ $directive.Value }}
+{{ end }}
+{{ end }}
+
 # Run processor with configuration and platform configuration
+{{ if .Windows }}
+CMD [ "C:\\usr\\local\\bin\\processor.exe" ]
+{{ else }}
 CMD [ "processor" ]
+{{ end }}
 `
 
-	onbuildStages, err := b.platform.GetOnbuildStages(onbuildArtifacts)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to transform retrieve onbuild stages")
-	}
+	var onbuildStages []string
+	var onbuildArtifactPaths map[string]string
+	var err error
 
-	// Transform `onbuildArtifactPaths` depending on the builder being used
-	onbuildArtifactPaths, err := b.platform.TransformOnbuildArtifactPaths(onbuildArtifacts)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to transform onbuildArtifactPaths")
+	multiPlatform := len(b.options.FunctionConfig.Spec.Build.Platforms) > 0
+
+	// each platform needs the artifacts of its own onbuild images, so they're copied from stages of the build
+	// regardless of the builder being used
+	if multiPlatform {
+		onbuildArtifacts, err = b.getMultiPlatformOnbuildArtifacts(onbuildArtifacts)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get multi-platform onbuild artifacts")
+		}
+
+		onbuildStages = containerimagebuilderpusher.GetMultiStageOnbuildStages(onbuildArtifacts)
+		onbuildArtifactPaths = containerimagebuilderpusher.TransformMultiStageOnbuildArtifactPaths(onbuildArtifacts)
+	} else {
+		onbuildStages, err = b.platform.GetOnbuildStages(onbuildArtifacts)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to transform retrieve onbuild stages")
+		}
+
+		// Transform `onbuildArtifactPaths` depending on the builder being used
+		onbuildArtifactPaths, err = b.platform.TransformOnbuildArtifactPaths(onbuildArtifacts)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to transform onbuildArtifactPaths")
+		}
 	}
 
 	dockerfileTemplate, err := template.New("singleStageDockerfile").
@@ -373,13 +468,17 @@ CMD [ "processor" ]
 
 	var dockerfileTemplateBuffer bytes.Buffer
 	err = dockerfileTemplate.Execute(&dockerfileTemplateBuffer, &map[string]interface{}{
-		"BaseImage":            baseImage,
-		"OnbuildStages":        onbuildStages,
-		"OnbuildArtifactPaths": onbuildArtifactPaths,
-		"ImageArtifactPaths":   imageArtifactPaths,
-		"PreCopyDirectives":    directives["preCopy"],
-		"PostCopyDirectives":   directives["postCopy"],
-		"HealthcheckRequired":  healthCheckRequired,
+		"MultiPlatform":         multiPlatform,
+		"BaseImage":             baseImage,
+		"OnbuildStages":         onbuildStages,
+		"OnbuildArtifactPaths":  onbuildArtifactPaths,
+		"ImageArtifactPaths":    imageArtifactPaths,
+		"PreCopyDirectives":     directives["preCopy"],
+		"PostCopyDirectives":    directives["postCopy"],
+		"PostInstallDirectives": directives["postInstall"],
+		"PreFinalDirectives":    directives["preFinal"],
+		"HealthcheckRequired":   healthCheckRequired,
+		"Windows":               b.options.FunctionConfig.Spec.Build.GetOS() == functionconfig.BuildOSWindows,
 	})
 
 	if err != nil {
@@ -391,6 +490,38 @@ CMD [ "processor" ]
 	return dockerfileContents, nil
 }
 
+// getMultiPlatformOnbuildArtifacts returns the onbuild artifacts with images of the architecture nuclio was
+// built for (e.g. handler-builder-python-onbuild:1.4.0-amd64) replaced by those of the architecture each
+// platform is built for
+func (b *Builder) getMultiPlatformOnbuildArtifacts(onbuildArtifacts []runtime.Artifact) ([]runtime.Artifact, error) {
+	versionInfo, err := version.Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get version info")
+	}
+
+	archSuffix := "-" + versionInfo.Arch
+
+	var multiPlatformOnbuildArtifacts []runtime.Artifact
+	for artifactIndex, onbuildArtifact := range onbuildArtifacts {
+		if !strings.HasSuffix(onbuildArtifact.Image, archSuffix) {
+			multiPlatformOnbuildArtifacts = append(multiPlatformOnbuildArtifacts, onbuildArtifact)
+			continue
+		}
+
+		onbuildArtifact.Image = strings.TrimSuffix(onbuildArtifact.Image, archSuffix) + "-${TARGETARCH}"
+
+		// COPY --from doesn't expand build args, so an external image is copied from a stage of its own
+		if onbuildArtifact.ExternalImage {
+			onbuildArtifact.ExternalImage = false
+			onbuildArtifact.Name = fmt.Sprintf("external-%d", artifactIndex)
+		}
+
+		multiPlatformOnbuildArtifacts = append(multiPlatformOnbuildArtifacts, onbuildArtifact)
+	}
+
+	return multiPlatformOnbuildArtifacts, nil
+}
+
 func (b *Builder) initializeSupportedRuntimes() {
 	b.runtimeInfo = map[string]runtimeInfo{}
 
@@ -408,10 +539,22 @@ func (b *Builder) initializeSupportedRuntimes() {
 	b.runtimeInfo["java"] = runtimeInfo{"java", slashSlashParser, 0}
 	b.runtimeInfo["ruby"] = runtimeInfo{"rb", poundParser, 0}
 	b.runtimeInfo["dotnetcore"] = runtimeInfo{"cs", slashSlashParser, 0}
+
+	// modules are binary, and have no inline configuration
+	b.runtimeInfo["wasm"] = runtimeInfo{"wasm", nil, 0}
 }
 
 func (b *Builder) readConfiguration() (string, error) {
 
+	// the contents given in place of the configuration file (e.g. a rendered template) take precedence
+	if b.options.FunctionConfigContents != nil {
+		if err := b.readFunctionConfig(b.options.FunctionConfigContents); err != nil {
+			return "", errors.Wrap(err, "Failed to read function configuration")
+		}
+
+		return b.options.FunctionConfig.Spec.Build.FunctionConfigPath, nil
+	}
+
 	if functionConfigPath := b.providedFunctionConfigFilePath(); functionConfigPath != "" {
 		if err := b.readFunctionConfigFile(functionConfigPath); err != nil {
 			return "", errors.Wrap(err, "Failed to read function configuration")
@@ -469,11 +612,23 @@ func (b *Builder) validateAndEnrichConfiguration() error {
 		b.options.FunctionConfig.Spec.Runtime = b.runtime.GetName()
 	}
 
-	// if the function handler isn't set, ask runtime
+	// if the function handler isn't set, look for it in the function directory and fall back to asking the runtime
 	if b.options.FunctionConfig.Spec.Handler == "" {
-		functionHandlers, err := b.runtime.DetectFunctionHandlers(b.GetFunctionPath())
-		if err != nil {
-			return errors.Wrap(err, "Failed to detect function handler")
+		var functionHandlers []string
+		var err error
+
+		if common.IsDir(b.GetFunctionPath()) {
+			functionHandlers, err = b.detectFunctionHandlersInDir(b.GetFunctionPath(), b.runtime.GetName())
+			if err != nil {
+				return errors.Wrap(err, "Failed to detect function handler in directory")
+			}
+		}
+
+		if len(functionHandlers) == 0 {
+			functionHandlers, err = b.runtime.DetectFunctionHandlers(b.GetFunctionPath())
+			if err != nil {
+				return errors.Wrap(err, "Failed to detect function handler")
+			}
 		}
 
 		if len(functionHandlers) == 0 {
@@ -637,6 +792,22 @@ func (b *Builder) resolveFunctionPath(functionPath string) (string, string, erro
 		return "", "", errors.New("Must provide valid URL when code entry type is github or archive")
 	}
 
+	// offline builds can only use function code which is already here
+	if b.options.FunctionConfig.Spec.Build.Offline &&
+		(isURL || codeEntryType == S3EntryType || codeEntryType == GitEntryType) {
+		return "", "", errors.Errorf("Function code can't be downloaded in an offline build: %s", functionPath)
+	}
+
+	// the function path is the repository's URL, which isn't necessarily a URL (e.g. git@github.com:org/repo)
+	if codeEntryType == GitEntryType {
+		repositoryFunctionPath, err := b.cloneFunctionGitRepository(functionPath)
+		if err != nil {
+			return "", "", errors.Wrap(err, "Failed to clone function repository")
+		}
+
+		return repositoryFunctionPath, "", nil
+	}
+
 	// if the function path is a URL, type is Github or S3 - first download the file
 	// for backwards compatibility, don't check for entry type url specifically
 	if functionPath, err = b.resolveFunctionPathFromURL(functionPath, codeEntryType); err != nil {
@@ -678,7 +849,7 @@ func (b *Builder) validateAndParseS3Attributes(attributes map[string]interface{}
 	parsedAttributes := map[string]string{}
 
 	mandatoryFields := []string{"s3Bucket", "s3ItemKey"}
-	optionalFields := []string{"s3Region", "s3AccessKeyId", "s3SecretAccessKey", "s3SessionToken"}
+	optionalFields := []string{"s3Region", "s3Endpoint", "s3AccessKeyId", "s3SecretAccessKey", "s3SessionToken"}
 
 	for _, key := range append(mandatoryFields, optionalFields...) {
 		value, found := attributes[key]
@@ -774,22 +945,18 @@ func (b *Builder) resolveUserSpecifiedArchiveWorkdir(decompressDir string) (stri
 }
 
 func (b *Builder) readFunctionConfigFile(functionConfigPath string) error {
-
-	// read the file once for logging
 	functionConfigContents, err := ioutil.ReadFile(functionConfigPath)
 	if err != nil {
-		return errors.Wrap(err, "Failed to read function configuration file")
+		return errors.Wrapf(err, "Failed to read function configuration file: %s", functionConfigPath)
 	}
 
-	// log
-	b.logger.DebugWith("Read function configuration file", "contents", string(functionConfigContents))
+	return b.readFunctionConfig(functionConfigContents)
+}
 
-	functionConfigFile, err := os.Open(functionConfigPath)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to open function configuration file: %s", functionConfigPath)
-	}
+func (b *Builder) readFunctionConfig(functionConfigContents []byte) error {
 
-	defer functionConfigFile.Close() // nolint: errcheck
+	// log
+	b.logger.DebugWith("Read function configuration", "contents", string(functionConfigContents))
 
 	functionconfigReader, err := functionconfig.NewReader(b.logger)
 	if err != nil {
@@ -797,7 +964,7 @@ func (b *Builder) readFunctionConfigFile(functionConfigPath string) error {
 	}
 
 	// read the configuration
-	if err := functionconfigReader.Read(functionConfigFile,
+	if err := functionconfigReader.Read(bytes.NewReader(functionConfigContents),
 		"yaml",
 		&b.options.FunctionConfig); err != nil {
 
@@ -829,6 +996,10 @@ func (b *Builder) createRuntime() (runtime.Runtime, error) {
 		return nil, errors.Wrap(err, "Failed to create runtime")
 	}
 
+	runtimeInstance.SetSecretDataGetter(func(name string) (map[string][]byte, error) {
+		return b.platform.GetSecretData(b.options.FunctionConfig.Meta.Namespace, name)
+	})
+
 	return runtimeInstance, nil
 }
 
@@ -839,18 +1010,21 @@ func (b *Builder) getRuntimeName() (string, error) {
 	// if runtime isn't set, try to look at extension
 	if runtimeName == "" {
 
-		// if the function path is a directory, runtime must be specified in the command-line arguments or configuration
+		// if the function path is a directory, inspect its contents
 		if common.IsDir(b.options.FunctionConfig.Spec.Build.Path) {
-			if common.FileExists(path.Join(b.options.FunctionConfig.Spec.Build.Path, FunctionConfigFileName)) {
-				return "", errors.New("Build path is directory - function.yaml must specify runtime")
-			}
-
-			return "", errors.New("Build path is directory - runtime must be specified")
-		}
+			runtimeName, err = b.detectRuntimeInDir(b.options.FunctionConfig.Spec.Build.Path)
+			if err != nil {
+				if common.FileExists(path.Join(b.options.FunctionConfig.Spec.Build.Path, FunctionConfigFileName)) {
+					return "", errors.Wrap(err, "Build path is directory - function.yaml must specify runtime")
+				}
 
-		runtimeName, err = b.getRuntimeNameByFileExtension(b.options.FunctionConfig.Spec.Build.Path)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to get runtime name")
+				return "", errors.Wrap(err, "Build path is directory - runtime must be specified")
+			}
+		} else {
+			runtimeName, err = b.getRuntimeNameByFileExtension(b.options.FunctionConfig.Spec.Build.Path)
+			if err != nil {
+				return "", errors.Wrap(err, "Failed to get runtime name")
+			}
 		}
 
 		b.logger.DebugWith("Runtime auto-detected", "runtime", runtimeName)
@@ -915,6 +1089,10 @@ func (b *Builder) prepareStagingDir() error {
 		return errors.Wrap(err, "Failed to prepare staging dir")
 	}
 
+	if err := b.copyBuildCacheToStagingDir(); err != nil {
+		return errors.Wrap(err, "Failed to copy build cache to staging dir")
+	}
+
 	// first, tell the specific runtime to do its thing
 	if err := b.runtime.OnAfterStagingDirCreated(b.stagingDir); err != nil {
 		return errors.Wrap(err, "Failed to prepare staging dir")
@@ -923,6 +1101,81 @@ func (b *Builder) prepareStagingDir() error {
 	return nil
 }
 
+// copyBuildCacheToStagingDir copies the packages offline builds resolve from into staging
+func (b *Builder) copyBuildCacheToStagingDir() error {
+	if !b.isBuildCacheUsed() {
+		return nil
+	}
+
+	buildCacheDir := b.options.FunctionConfig.Spec.Build.CacheDir
+	if !common.IsDir(buildCacheDir) {
+		return errors.Errorf("Build cache dir doesn't exist: %s", buildCacheDir)
+	}
+
+	if _, err := util.CopyDir(buildCacheDir, path.Join(b.stagingDir, buildCacheDirInStaging)); err != nil {
+		return errors.Wrap(err, "Failed to copy build cache")
+	}
+
+	// go modules are resolved as the handler is compiled in the onbuild stage, which only gets the handler dir
+	goBuildCacheDir := path.Join(buildCacheDir, "go")
+	if b.runtime.GetName() == "golang" && common.IsDir(goBuildCacheDir) {
+		if _, err := util.CopyDir(goBuildCacheDir,
+			path.Join(b.getHandlerDir(b.stagingDir), buildCacheDirInHandler, "go")); err != nil {
+			return errors.Wrap(err, "Failed to copy go modules build cache")
+		}
+	}
+
+	b.logger.DebugWith("Copied build cache to staging dir", "buildCacheDir", buildCacheDir)
+
+	return nil
+}
+
+func (b *Builder) isBuildCacheUsed() bool {
+	return b.options.FunctionConfig.Spec.Build.Offline && b.options.FunctionConfig.Spec.Build.CacheDir != ""
+}
+
+// getBuildCacheDirectives returns the directives which point the package managers of the build commands at
+// the build cache
+func (b *Builder) getBuildCacheDirectives() map[string][]functionconfig.Directive {
+	if !b.isBuildCacheUsed() {
+		return nil
+	}
+
+	// build args rather than environment variables, so that they don't remain in the processor image
+	return map[string][]functionconfig.Directive{
+		"preCopy": {
+			{Kind: "ARG", Value: "PIP_NO_INDEX=1"},
+			{Kind: "ARG", Value: fmt.Sprintf("PIP_FIND_LINKS=%s/pip", buildCacheDirInImage)},
+			{Kind: "ARG", Value: "NPM_CONFIG_OFFLINE=true"},
+			{Kind: "ARG", Value: fmt.Sprintf("NPM_CONFIG_CACHE=%s/npm", buildCacheDirInImage)},
+			{Kind: "ARG", Value: fmt.Sprintf("GOPROXY=file://%s/go", buildCacheDirInImage)},
+			{Kind: "ARG", Value: "GOSUMDB=off"},
+			{Kind: "ARG", Value: "GOFLAGS=-mod=mod"},
+		},
+	}
+}
+
+// mountBuildCache has the RUN directives bind-mount the build cache from the build context (BuildKit bind
+// mounts), so that it's never part of an image layer. the mount is writable, as npm writes to its cache, but
+// the writes are discarded once the command is done
+func (b *Builder) mountBuildCache(directives map[string][]functionconfig.Directive) {
+	if !b.isBuildCacheUsed() {
+		return
+	}
+
+	buildCacheMount := fmt.Sprintf("--mount=type=bind,source=%s,target=%s,rw ",
+		buildCacheDirInStaging,
+		buildCacheDirInImage)
+
+	for _, stageDirectives := range directives {
+		for directiveIdx := range stageDirectives {
+			if stageDirectives[directiveIdx].Kind == "RUN" {
+				stageDirectives[directiveIdx].Value = buildCacheMount + stageDirectives[directiveIdx].Value
+			}
+		}
+	}
+}
+
 func (b *Builder) getHandlerSubPath() string {
 
 	// when it is a java function, and it is not structured as a java project - apply java project structure
@@ -947,7 +1200,16 @@ func (b *Builder) copyHandlerToStagingDir(handlerSubPath string) error {
 	// we just want to copy the file from wherever it is to the staging dir root
 	for _, handlerDirObjectPath := range handlerDirObjectPaths {
 
-		// copy the object (TODO: most likely will need to better support dirs)
+		// directories are copied without the paths listed in their .nuclioignore
+		if common.IsDir(handlerDirObjectPath) {
+			if err := util.CopyDirWithIgnore(handlerDirObjectPath, handlerDirIncludingSubpath); err != nil {
+				return errors.Wrap(err, "Failed to copy handler directory")
+			}
+
+			continue
+		}
+
+		// copy the object
 		if err := util.CopyTo(handlerDirObjectPath, handlerDirIncludingSubpath); err != nil {
 			return errors.Wrap(err, "Failed to copy handler object")
 		}
@@ -956,6 +1218,19 @@ func (b *Builder) copyHandlerToStagingDir(handlerSubPath string) error {
 	return nil
 }
 
+func (b *Builder) getCmdRunner() (cmdrunner.CmdRunner, error) {
+	if b.cmdRunner == nil {
+		cmdRunner, err := cmdrunner.NewShellRunner(b.logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create command runner")
+		}
+
+		b.cmdRunner = cmdRunner
+	}
+
+	return b.cmdRunner, nil
+}
+
 func (b *Builder) mkDirUnderTemp(name string) (string, error) {
 
 	dir := path.Join(b.tempDir, name)
@@ -1017,21 +1292,127 @@ func (b *Builder) buildProcessorImage() (string, error) {
 
 	b.logger.InfoWith("Building processor image", "imageName", imageName)
 
+	// allows finding the images of a function later on, e.g. to prune old ones
+	imageLabels := map[string]string{
+		"nuclio.io/namespace":     b.options.FunctionConfig.Meta.Namespace,
+		"nuclio.io/function-name": b.GetFunctionName(),
+	}
+
 	err = b.platform.BuildAndPushContainerImage(&containerimagebuilderpusher.BuildOptions{
 		ContextDir:          b.stagingDir,
 		Image:               imageName,
 		TempDir:             b.tempDir,
 		DockerfileInfo:      processorDockerfileInfo,
 		NoCache:             b.options.FunctionConfig.Spec.Build.NoCache,
+		CacheFrom:           b.options.FunctionConfig.Spec.Build.CacheFrom,
+		Network:             b.options.FunctionConfig.Spec.Build.Network,
+		Platforms:           b.options.FunctionConfig.Spec.Build.Platforms,
+		OS:                  b.options.FunctionConfig.Spec.Build.GetOS(),
 		NoBaseImagePull:     b.GetNoBaseImagePull(),
 		BuildArgs:           buildArgs,
+		Labels:              imageLabels,
 		RegistryURL:         b.options.FunctionConfig.Spec.Build.Registry,
 		SecretName:          b.options.FunctionConfig.Spec.ImagePullSecrets,
 		OutputImageFile:     b.options.OutputImageFile,
 		BuildTimeoutSeconds: b.resolveBuildTimeoutSeconds(),
+		OutputLineHandler:   b.options.BuildOutputLineHandler,
+		PhaseTimings:        b.options.PhaseTimings,
+		BuildCacheMounts:    b.getPersistentBuildCacheMounts(),
+		BuildSecrets:        b.getBuildSecrets(),
+		BuildContextMounted: b.isBuildCacheUsed(),
+		ContextOnly:         b.isBuildContextOutput(),
 	})
+	if err != nil {
+		return "", err
+	}
+
+	if b.isBuildContextOutput() {
+		if err := b.outputBuildContext(processorDockerfileInfo); err != nil {
+			return "", errors.Wrap(err, "Failed to output build context")
+		}
+	}
+
+	return imageName, nil
+}
+
+// getBuildSecrets returns the secrets the commands of the build hooks mount
+func (b *Builder) getBuildSecrets() []functionconfig.BuildSecret {
+	if b.options.FunctionConfig.Spec.Build.Hooks == nil {
+		return nil
+	}
 
-	return imageName, err
+	return b.options.FunctionConfig.Spec.Build.Hooks.GetSecrets()
+}
+
+// getPersistentBuildCacheMounts returns the package manager caches of the runtime to persist across builds,
+// if asked to
+func (b *Builder) getPersistentBuildCacheMounts() []containerimagebuilderpusher.BuildCacheMount {
+	if !b.options.FunctionConfig.Spec.Build.PersistentCache {
+		return nil
+	}
+
+	return containerimagebuilderpusher.GetBuildCacheMounts(b.runtime.GetName())
+}
+
+func (b *Builder) isBuildContextOutput() bool {
+	return b.options.OutputDockerfile != "" || b.options.OutputContextDir != ""
+}
+
+// validateBuildContextOutputs fails before anything is staged if the build context can't be output
+func (b *Builder) validateBuildContextOutputs() error {
+	if !b.isBuildContextOutput() {
+		return nil
+	}
+
+	if b.options.OutputImageFile != "" {
+		return errors.New("An output image file can't be used when only outputting the build context")
+	}
+
+	if b.options.OutputContextDir == "" {
+		return nil
+	}
+
+	if common.FileExists(b.options.OutputContextDir) {
+		if !common.IsDir(b.options.OutputContextDir) {
+			return errors.Errorf("Output context dir is not a directory: %s", b.options.OutputContextDir)
+		}
+
+		entries, err := ioutil.ReadDir(b.options.OutputContextDir)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read output context dir")
+		}
+
+		// don't mix the context with whatever is already there
+		if len(entries) > 0 {
+			return errors.Errorf("Output context dir is not empty: %s", b.options.OutputContextDir)
+		}
+	}
+
+	return nil
+}
+
+// outputBuildContext writes the processor Dockerfile and copies the staging dir (the build context) to
+// where they were requested, so that the image can be built externally
+func (b *Builder) outputBuildContext(processorDockerfileInfo *runtime.ProcessorDockerfileInfo) error {
+	if b.options.OutputDockerfile != "" {
+		if err := ioutil.WriteFile(b.options.OutputDockerfile,
+			[]byte(processorDockerfileInfo.DockerfileContents),
+			0644); err != nil {
+			return errors.Wrap(err, "Failed to write Dockerfile")
+		}
+
+		b.logger.InfoWith("Wrote processor Dockerfile", "path", b.options.OutputDockerfile)
+	}
+
+	if b.options.OutputContextDir != "" {
+		if _, err := util.CopyDir(b.stagingDir, b.options.OutputContextDir); err != nil {
+			return errors.Wrap(err, "Failed to copy build context")
+		}
+
+		b.logger.InfoWith("Copied build context", "dir", b.options.OutputContextDir)
+	}
+
+	return nil
 }
 
 func (b *Builder) createProcessorDockerfile(baseImageRegistry string, onbuildImageRegistry string) (
@@ -1075,6 +1456,10 @@ func (b *Builder) parseInlineBlocks() error {
 		return errors.Wrap(err, "Failed to get runtime comment parser")
 	}
 
+	if commentParser == nil {
+		return nil
+	}
+
 	blocks, err := commentParser.Parse(b.options.FunctionConfig.Spec.Build.Path)
 	if err != nil {
 		return errors.Wrap(err, "Failed to parse inline blocks")
@@ -1169,8 +1554,16 @@ func (b *Builder) getHandlerDir(stagingDir string) string {
 func (b *Builder) getRuntimeProcessorDockerfileInfo(baseImageRegistry string, onbuildImageRegistry string) (
 	*runtime.ProcessorDockerfileInfo, error) {
 
+	// the platform configuration may have the runtime's builds use other images and package indexes
+	runtimeImages := b.platform.GetRuntimeImages(b.options.FunctionConfig.Spec.Runtime)
+	if err := b.validateRuntimeImagesEnforced(runtimeImages); err != nil {
+		return nil, errors.Wrap(err, "Function overrides the images of its runtime")
+	}
+
 	// gather the processor dockerfile info
-	processorDockerfileInfo, err := b.resolveProcessorDockerfileInfo(baseImageRegistry, onbuildImageRegistry)
+	processorDockerfileInfo, err := b.resolveProcessorDockerfileInfo(baseImageRegistry,
+		onbuildImageRegistry,
+		runtimeImages)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get processor Dockerfile info")
 	}
@@ -1190,6 +1583,14 @@ func (b *Builder) getRuntimeProcessorDockerfileInfo(baseImageRegistry string, on
 	// merge directives passed by user with directives passed by runtime
 	directives = b.mergeDirectives(directives, processorDockerfileInfo.Directives)
 
+	// the hooks run after all other directives of their stage
+	directives = b.mergeDirectives(directives, b.getBuildHooksDirectives())
+
+	// offline builds resolve packages from the build cache
+	directives = b.mergeDirectives(b.getBuildCacheDirectives(), directives)
+	b.mountBuildCache(directives)
+	directives = b.mergeDirectives(b.getPackageIndexDirectives(runtimeImages), directives)
+
 	// path where generated dockerfile should reside (staging)
 	processorDockerfileInfo.DockerfilePath = filepath.Join(b.stagingDir, "Dockerfile.processor")
 
@@ -1208,7 +1609,8 @@ func (b *Builder) getRuntimeProcessorDockerfileInfo(baseImageRegistry string, on
 }
 
 func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
-	onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+	onbuildImageRegistry string,
+	runtimeImages *platformconfig.RuntimeImages) (*runtime.ProcessorDockerfileInfo, error) {
 	versionInfo, err := version.Get()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get version info")
@@ -1228,8 +1630,13 @@ func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
 	}
 
 	// set the base image
-	processorDockerfileInfo.BaseImage = b.getProcessorDockerfileBaseImage(runtimeProcessorDockerfileInfo.BaseImage,
-		baseImageRegistry)
+	processorDockerfileInfo.BaseImage, err = b.getProcessorDockerfileBaseImage(runtimeProcessorDockerfileInfo.BaseImage,
+		baseImageRegistry,
+		runtimeImages)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get base image")
+	}
+
 	processorDockerfileInfo.BaseImage, err = b.renderDependantImageURL(processorDockerfileInfo.BaseImage,
 		b.options.DependantImagesRegistryURL)
 	if err != nil {
@@ -1239,7 +1646,8 @@ func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
 	// set the onbuild images
 	for idx, onbuildArtifact := range processorDockerfileInfo.OnbuildArtifacts {
 		onbuildArtifact.Image, err = b.getProcessorDockerfileOnbuildImage(versionInfo,
-			runtimeProcessorDockerfileInfo.OnbuildArtifacts[idx].Image)
+			runtimeProcessorDockerfileInfo.OnbuildArtifacts[idx].Image,
+			runtimeImages)
 
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to get onbuild image")
@@ -1252,8 +1660,10 @@ func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
 		}
 	}
 
-	// if the platform requires an internal healthcheck client - add health check artifact
-	if b.platform.GetHealthCheckMode() == platform.HealthCheckModeInternalClient {
+	// if the platform requires an internal healthcheck client - add health check artifact. windows images
+	// have curl
+	if b.platform.GetHealthCheckMode() == platform.HealthCheckModeInternalClient &&
+		b.options.FunctionConfig.Spec.Build.GetOS() != functionconfig.BuildOSWindows {
 		artifact := runtime.Artifact{
 			Name:          "uhttpc",
 			Image:         uhttpcImage,
@@ -1269,15 +1679,22 @@ func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
 	return &processorDockerfileInfo, nil
 }
 
-func (b *Builder) getProcessorDockerfileBaseImage(runtimeDefaultBaseImage string, baseImageRegistry string) string {
+func (b *Builder) getProcessorDockerfileBaseImage(runtimeDefaultBaseImage string,
+	baseImageRegistry string,
+	runtimeImages *platformconfig.RuntimeImages) (string, error) {
 
 	// override base image, if required
 	switch b.options.FunctionConfig.Spec.Build.BaseImage {
 
-	// if user didn't pass anything, use default as specified in Dockerfile
+	// if user didn't pass anything, use the platform's image for the runtime, or the default as specified in
+	// the Dockerfile
 	case "":
+		if runtimeImages != nil && runtimeImages.BaseImage != "" {
+			return runtimeImages.GetBaseImage()
+		}
+
 		if baseImageRegistry == "" {
-			return runtimeDefaultBaseImage
+			return runtimeDefaultBaseImage, nil
 		}
 
 		// if a non empty baseImageRegistry was passed, use it as a registry prefix for the default base image
@@ -1285,21 +1702,33 @@ func (b *Builder) getProcessorDockerfileBaseImage(runtimeDefaultBaseImage string
 		if sepIndex != -1 {
 			runtimeDefaultBaseImage = runtimeDefaultBaseImage[sepIndex+1:]
 		}
-		return strings.Join([]string{baseImageRegistry, runtimeDefaultBaseImage}, "/")
+		return strings.Join([]string{baseImageRegistry, runtimeDefaultBaseImage}, "/"), nil
 
 	// if user specified something - use that, as is
 	// see description on https://github.com/nuclio/nuclio/pull/1544 - we don't implicitly mutate the given baseimage
 	default:
-		return b.options.FunctionConfig.Spec.Build.BaseImage
+		return b.options.FunctionConfig.Spec.Build.BaseImage, nil
 	}
 }
 
 func (b *Builder) getProcessorDockerfileOnbuildImage(versionInfo *version.Info,
-	runtimeDefaultOnbuildImage string) (string, error) {
+	runtimeDefaultOnbuildImage string,
+	runtimeImages *platformconfig.RuntimeImages) (string, error) {
+	onbuildImage := b.options.FunctionConfig.Spec.Build.OnbuildImage
+
+	// otherwise, the platform's image for the runtime (pinned to its digest, which the template leaves as is)
+	if onbuildImage == "" && runtimeImages != nil && runtimeImages.OnbuildImage != "" {
+		var err error
+
+		onbuildImage, err = runtimeImages.GetOnbuildImage()
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get the platform's onbuild image")
+		}
+	}
 
 	// if the user supplied an onbuild image, format it with the appropriate tag,
-	if b.options.FunctionConfig.Spec.Build.OnbuildImage != "" {
-		onbuildImageTemplate, err := template.New("onbuildImage").Parse(b.options.FunctionConfig.Spec.Build.OnbuildImage)
+	if onbuildImage != "" {
+		onbuildImageTemplate, err := template.New("onbuildImage").Parse(onbuildImage)
 		if err != nil {
 			return "", errors.Wrap(err, "Failed to create onbuildImage template")
 		}
@@ -1314,18 +1743,70 @@ func (b *Builder) getProcessorDockerfileOnbuildImage(versionInfo *version.Info,
 			return "", errors.Wrap(err, "Failed to run template")
 		}
 
-		onbuildImage := onbuildImageTemplateBuffer.String()
+		b.options.Logger.DebugWith("Using provided onbuild image",
+			"onbuildImageTemplate", onbuildImage,
+			"onbuildImage", onbuildImageTemplateBuffer.String())
 
-		b.options.Logger.DebugWith("Using user provided onbuild image",
-			"onbuildImageTemplate", b.options.FunctionConfig.Spec.Build.OnbuildImage,
-			"onbuildImage", onbuildImage)
-
-		return onbuildImage, nil
+		return onbuildImageTemplateBuffer.String(), nil
 	}
 
 	return runtimeDefaultOnbuildImage, nil
 }
 
+// validateRuntimeImagesEnforced fails functions that build from other images than those the platform enforces
+// for their runtime
+func (b *Builder) validateRuntimeImagesEnforced(runtimeImages *platformconfig.RuntimeImages) error {
+	if runtimeImages == nil || !runtimeImages.Enforce {
+		return nil
+	}
+
+	if b.options.FunctionConfig.Spec.Build.BaseImage != "" &&
+		b.options.FunctionConfig.Spec.Build.BaseImage != runtimeImages.BaseImage {
+		return nuclio.NewErrBadRequest(fmt.Sprintf("The platform builds %s functions from its own base image, "+
+			"spec.build.baseImage can't be set", b.options.FunctionConfig.Spec.Runtime))
+	}
+
+	if b.options.FunctionConfig.Spec.Build.OnbuildImage != "" &&
+		b.options.FunctionConfig.Spec.Build.OnbuildImage != runtimeImages.OnbuildImage {
+		return nuclio.NewErrBadRequest(fmt.Sprintf("The platform builds %s functions from its own onbuild image, "+
+			"spec.build.onbuildImage can't be set", b.options.FunctionConfig.Spec.Runtime))
+	}
+
+	return nil
+}
+
+// getPackageIndexDirectives returns the directives having the build's package managers install from the
+// indexes the platform configures for the runtime. build args aren't kept in the processor image
+func (b *Builder) getPackageIndexDirectives(runtimeImages *platformconfig.RuntimeImages) map[string][]functionconfig.Directive {
+	if runtimeImages == nil {
+		return nil
+	}
+
+	var directives []functionconfig.Directive
+
+	if runtimeImages.PipIndexURL != "" {
+		directives = append(directives, functionconfig.Directive{
+			Kind:  "ARG",
+			Value: "PIP_INDEX_URL=" + runtimeImages.PipIndexURL,
+		})
+	}
+
+	if runtimeImages.NPMRegistryURL != "" {
+		directives = append(directives, functionconfig.Directive{
+			Kind:  "ARG",
+			Value: "NPM_CONFIG_REGISTRY=" + runtimeImages.NPMRegistryURL,
+		})
+	}
+
+	if len(directives) == 0 {
+		return nil
+	}
+
+	return map[string][]functionconfig.Directive{
+		"preCopy": directives,
+	}
+}
+
 func (b *Builder) getBuildArgs() (map[string]string, error) {
 	versionInfo, err := version.Get()
 	if err != nil {
@@ -1345,9 +1826,59 @@ func (b *Builder) getBuildArgs() (map[string]string, error) {
 	// set handler dir
 	buildArgs["NUCLIO_BUILD_LOCAL_HANDLER_DIR"] = "handler"
 
+	// the args the build hooks declare
+	if b.options.FunctionConfig.Spec.Build.Hooks != nil {
+		for _, stage := range b.options.FunctionConfig.Spec.Build.Hooks.GetStages() {
+			for argName, argValue := range stage.Args {
+				buildArgs[argName] = argValue
+			}
+		}
+	}
+
 	return buildArgs, nil
 }
 
+// getBuildHooksDirectives returns the directives of the build hooks, keyed by the stage they run at. each stage
+// declares its args, and its commands mount its secrets
+func (b *Builder) getBuildHooksDirectives() map[string][]functionconfig.Directive {
+	directives := map[string][]functionconfig.Directive{}
+
+	if b.options.FunctionConfig.Spec.Build.Hooks == nil {
+		return directives
+	}
+
+	for stageName, stage := range b.options.FunctionConfig.Spec.Build.Hooks.GetStages() {
+		var argNames []string
+		for argName := range stage.Args {
+			argNames = append(argNames, argName)
+		}
+
+		sort.Strings(argNames)
+
+		for _, argName := range argNames {
+			directives[stageName] = append(directives[stageName], functionconfig.Directive{
+				Kind:  "ARG",
+				Value: argName,
+			})
+		}
+
+		var secretMounts string
+		for _, secret := range stage.Secrets {
+			secretMounts += fmt.Sprintf("--mount=type=secret,id=%s ", secret.ID)
+		}
+
+		// validation rejects "@nuclio.postCopy" in hooks, so all commands end up as pre-copy directives
+		commandDirectives, _ := b.commandsToDirectives(stage.Commands)
+
+		for _, commandDirective := range commandDirectives["preCopy"] {
+			commandDirective.Value = secretMounts + commandDirective.Value
+			directives[stageName] = append(directives[stageName], commandDirective)
+		}
+	}
+
+	return directives
+}
+
 func (b *Builder) commandsToDirectives(commands []string) (map[string][]functionconfig.Directive, error) {
 
 	// create directives
@@ -1415,7 +1946,7 @@ func (b *Builder) commandsToDirectives(commands []string) (map[string][]function
 func (b *Builder) mergeDirectives(first map[string][]functionconfig.Directive,
 	second map[string][]functionconfig.Directive) map[string][]functionconfig.Directive {
 
-	keys := []string{"preCopy", "postCopy"}
+	keys := []string{"preCopy", "postCopy", "postInstall", "preFinal"}
 	merged := map[string][]functionconfig.Directive{}
 
 	for _, key := range keys {
@@ -1426,8 +1957,10 @@ func (b *Builder) mergeDirectives(first map[string][]functionconfig.Directive,
 			second,
 		} {
 
-			// add all directives from input into merged
-			merged[key] = append(merged[key], input[key]...)
+			// add all directives from input into merged. the hook stages are only set if they have directives
+			if inputDirectives, found := input[key]; found {
+				merged[key] = append(merged[key], inputDirectives...)
+			}
 		}
 	}
 
@@ -1483,14 +2016,26 @@ func (b *Builder) resolveFunctionPathFromURL(functionPath string, codeEntryType
 	var err error
 
 	if common.IsURL(functionPath) || codeEntryType == S3EntryType {
-		if codeEntryType == GithubEntryType {
+		var s3Attributes map[string]string
+
+		switch codeEntryType {
+		case GithubEntryType:
 			functionPath, err = b.getFunctionPathFromGithubURL(functionPath)
 			if err != nil {
 				return "", errors.Wrapf(err, "Failed to infer function path of github entry type")
 			}
+		case S3EntryType:
+			s3Attributes, err = b.validateAndParseS3Attributes(b.options.FunctionConfig.Spec.Build.CodeEntryAttributes)
+			if err != nil {
+				return "", errors.Wrap(err, "Failed to parse and validate s3 code entry attributes")
+			}
+
+			// the item is named like the function's file, so that a source file keeps its name
+			functionPath = s3Attributes["s3ItemKey"]
 		}
 
-		isArchive := codeEntryType == S3EntryType ||
+		// an item of a bucket is an archive, unless it's named like a source file
+		isArchive := (codeEntryType == S3EntryType && !b.isSourceFileS3ItemKey(functionPath)) ||
 			codeEntryType == GithubEntryType ||
 			codeEntryType == ArchiveEntryType
 
@@ -1514,7 +2059,7 @@ func (b *Builder) resolveFunctionPathFromURL(functionPath string, codeEntryType
 
 		switch codeEntryType {
 		case S3EntryType:
-			err = b.downloadFunctionFromS3(tempFile)
+			err = b.downloadFunctionFromS3(tempFile, s3Attributes)
 		default:
 			err = b.downloadFunctionFromURL(tempFile, functionPath, codeEntryType)
 		}
@@ -1533,22 +2078,28 @@ func (b *Builder) resolveFunctionPathFromURL(functionPath string, codeEntryType
 	return functionPath, nil
 }
 
-func (b *Builder) downloadFunctionFromS3(tempFile *os.File) error {
-	s3Attributes, err := b.validateAndParseS3Attributes(b.options.FunctionConfig.Spec.Build.CodeEntryAttributes)
-	if err != nil {
-		return errors.Wrap(err, "Failed to parse and validate s3 code entry attributes")
-	}
+func (b *Builder) isSourceFileS3ItemKey(itemKey string) bool {
+	return path.Ext(itemKey) != "" && !util.IsCompressed(itemKey)
+}
+
+func (b *Builder) downloadFunctionFromS3(tempFile *os.File, s3Attributes map[string]string) error {
+	b.logger.DebugWith("Downloading function from s3",
+		"bucket", s3Attributes["s3Bucket"],
+		"itemKey", s3Attributes["s3ItemKey"],
+		"endpoint", s3Attributes["s3Endpoint"],
+		"target", tempFile.Name())
 
-	err = b.s3Client.Download(tempFile,
+	err := b.s3Client.Download(tempFile,
 		s3Attributes["s3Bucket"],
 		s3Attributes["s3ItemKey"],
 		s3Attributes["s3Region"],
+		s3Attributes["s3Endpoint"],
 		s3Attributes["s3AccessKeyId"],
 		s3Attributes["s3SecretAccessKey"],
 		s3Attributes["s3SessionToken"])
 
 	if err != nil {
-		return errors.Wrap(err, "Failed to download the function archive from s3")
+		return errors.Wrap(err, "Failed to download the function from s3")
 	}
 
 	return nil
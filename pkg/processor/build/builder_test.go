@@ -22,13 +22,20 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/build/util"
+	"github.com/nuclio/nuclio/pkg/version"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/nuclio/errors"
@@ -60,15 +67,46 @@ type mockS3Client struct {
 }
 
 // mock function
-func (msc mockS3Client) Download(file *os.File, bucket, itemKey, region, accessKeyID, secretAccessKey, sessionToken string) error {
-	functionArchiveFileBytes, _ := ioutil.ReadFile(FunctionsArchiveFilePath)
+func (msc mockS3Client) Download(file *os.File, bucket, itemKey, region, endpoint, accessKeyID, secretAccessKey, sessionToken string) error {
+	functionFileBytes := []byte("def handler(context, event):\n    pass\n")
+	if util.IsCompressed(itemKey) {
+		functionFileBytes, _ = ioutil.ReadFile(FunctionsArchiveFilePath)
+	}
 
-	_ = ioutil.WriteFile(file.Name(), functionArchiveFileBytes, os.FileMode(os.O_RDWR))
+	_ = ioutil.WriteFile(file.Name(), functionFileBytes, os.FileMode(os.O_RDWR))
 
-	args := msc.Called(file, bucket, itemKey, region, accessKeyID, secretAccessKey, sessionToken)
+	args := msc.Called(file, bucket, itemKey, region, endpoint, accessKeyID, secretAccessKey, sessionToken)
 	return args.Error(0)
 }
 
+// records the commands it's asked to run, returning the output registered for their prefix
+type recordingCmdRunner struct {
+	commands []string
+	outputs  map[string]string
+}
+
+func (rcr *recordingCmdRunner) Run(runOptions *cmdrunner.RunOptions,
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+	command := fmt.Sprintf(format, vars...)
+	rcr.commands = append(rcr.commands, command)
+
+	for prefix, output := range rcr.outputs {
+		if strings.HasPrefix(command, prefix) {
+			return cmdrunner.RunResult{Output: output}, nil
+		}
+	}
+
+	return cmdrunner.RunResult{}, nil
+}
+
+func (rcr *recordingCmdRunner) RunStream(runOptions *cmdrunner.RunOptions,
+	outputLineHandler func(line string),
+	format string,
+	vars ...interface{}) (cmdrunner.RunResult, error) {
+	return rcr.Run(runOptions, format, vars...)
+}
+
 // SetupSuite is called for suite setup
 func (suite *testSuite) SetupSuite() {
 	var err error
@@ -156,6 +194,151 @@ func (suite *testSuite) TestGetRuntimeNameFromBuildDirNoRuntime() {
 	}
 }
 
+func (suite *testSuite) TestGetRuntimeNameFromBuildDir() {
+	for _, testCase := range []struct {
+		name                string
+		files               map[string]string
+		expectedRuntimeName string
+		expectError         bool
+	}{
+		{
+			name: "RequirementsFile",
+			files: map[string]string{
+				"requirements.txt": "requests\n",
+				"main.py":          "def handler(context, event):\n    pass\n",
+			},
+			expectedRuntimeName: "python",
+		},
+		{
+			name: "PackageJSON",
+			files: map[string]string{
+				"package.json": "{}",
+				"build.sh":     "npm install",
+			},
+			expectedRuntimeName: "nodejs",
+		},
+		{
+			name: "GoModule",
+			files: map[string]string{
+				"go.mod":     "module handler",
+				"handler.go": "package main",
+			},
+			expectedRuntimeName: "golang",
+		},
+		{
+			name: "HandlerFileExtension",
+			files: map[string]string{
+				"handler.rb": "def main(context, event)\nend\n",
+				"README.md":  "ruby function",
+				"setup.sh":   "gem install",
+			},
+			expectedRuntimeName: "ruby",
+		},
+		{
+			name: "WasmModule",
+			files: map[string]string{
+				"transform.wasm": "\x00asm",
+				"build.sh":       "cargo build --target wasm32-wasi",
+			},
+			expectedRuntimeName: "wasm",
+		},
+		{
+			name: "IgnoredFiles",
+			files: map[string]string{
+				".nuclioignore": "*.js\n",
+				"main.py":       "def handler(context, event):\n    pass\n",
+				"bundle.js":     "",
+			},
+			expectedRuntimeName: "python",
+		},
+		{
+			name: "SeveralRuntimes",
+			files: map[string]string{
+				"main.py":   "",
+				"bundle.js": "",
+			},
+			expectError: true,
+		},
+		{
+			name: "NoSourceFiles",
+			files: map[string]string{
+				"README.md": "",
+			},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			functionDir := suite.createFunctionDir(testCase.files)
+			defer os.RemoveAll(functionDir) // nolint: errcheck
+
+			suite.builder.options.FunctionConfig.Spec.Runtime = ""
+			suite.builder.options.FunctionConfig.Spec.Build.Path = functionDir
+			runtimeName, err := suite.builder.getRuntimeName()
+
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedRuntimeName, runtimeName)
+		})
+	}
+}
+
+func (suite *testSuite) TestDetectFunctionHandlersInDir() {
+	for _, testCase := range []struct {
+		name             string
+		runtimeName      string
+		files            map[string]string
+		expectedHandlers []string
+	}{
+		{
+			name:        "Python",
+			runtimeName: "python",
+			files: map[string]string{
+				"main.py":  "import os\n\ndef helper(x):\n    pass\n\ndef handle(context, event):\n    pass\n",
+				"utils.py": "def parse(data):\n    pass\n",
+			},
+			expectedHandlers: []string{"main:handle"},
+		},
+		{
+			name:        "NodeJS",
+			runtimeName: "nodejs",
+			files: map[string]string{
+				"index.js": "exports.handler = function(context, event) {};\n",
+			},
+			expectedHandlers: []string{"index:handler"},
+		},
+		{
+			name:        "IgnoredFiles",
+			runtimeName: "python",
+			files: map[string]string{
+				".nuclioignore": "test_*.py\n",
+				"test_main.py":  "def handler(context, event):\n    pass\n",
+				"main.py":       "def handler(context, event):\n    pass\n",
+			},
+			expectedHandlers: []string{"main:handler"},
+		},
+		{
+			name:        "UnsupportedRuntime",
+			runtimeName: "java",
+			files: map[string]string{
+				"Handler.java": "",
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			functionDir := suite.createFunctionDir(testCase.files)
+			defer os.RemoveAll(functionDir) // nolint: errcheck
+
+			handlers, err := suite.builder.detectFunctionHandlersInDir(functionDir, testCase.runtimeName)
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedHandlers, handlers)
+		})
+	}
+}
+
 func (suite *testSuite) TestWriteFunctionSourceCodeToTempFileWritesReturnsFilePath() {
 	tests := []struct {
 		inputSourceCode    string
@@ -240,6 +423,23 @@ func (suite *testSuite) TestGetImage() {
 	suite.Require().Equal(fmt.Sprintf("nuclio/%sprocessor", imageNamePrefix), imageName)
 }
 
+func (suite *testSuite) TestReadConfigurationFromContents() {
+	suite.builder.options.FunctionConfig.Spec.Build.FunctionConfigPath = "/path/to/function.yaml.tmpl"
+	suite.builder.options.FunctionConfigContents = []byte(`spec:
+  runtime: python:3.6
+  handler: main:handler
+`)
+
+	// the recorded path doesn't exist, so the configuration can only come from the contents
+	functionConfigPath, err := suite.builder.readConfiguration()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/path/to/function.yaml.tmpl", functionConfigPath)
+	suite.Require().Equal("python:3.6", suite.builder.options.FunctionConfig.Spec.Runtime)
+	suite.Require().Equal("main:handler", suite.builder.options.FunctionConfig.Spec.Handler)
+	suite.Require().Equal("/path/to/function.yaml.tmpl",
+		suite.builder.options.FunctionConfig.Spec.Build.FunctionConfigPath)
+}
+
 func (suite *testSuite) TestMergeDirectives() {
 
 	mergeDirectivesCases := []struct {
@@ -589,6 +789,7 @@ func (suite *testSuite) TestResolveFunctionPathS3CodeEntry() {
 			mock.MatchedBy(common.GenerateStringMatchVerifier("my-s3-bucket")),
 			mock.MatchedBy(common.GenerateStringMatchVerifier("funcs.zip")),
 			mock.MatchedBy(common.GenerateStringMatchVerifier("my-s3-region")),
+			"",
 			mock.MatchedBy(common.GenerateStringMatchVerifier("my-s3-access-key-id")),
 			mock.MatchedBy(common.GenerateStringMatchVerifier("my-s3-secret-access-key")),
 			mock.MatchedBy(common.GenerateStringMatchVerifier("my-s3-session-token"))).
@@ -611,6 +812,549 @@ func (suite *testSuite) TestResolveFunctionPathS3CodeEntry() {
 	suite.testResolveFunctionPathArchive(buildConfiguration, "")
 }
 
+func (suite *testSuite) TestResolveFunctionPathS3CompatibleSourceFile() {
+	suite.mockS3Client.
+		On("Download",
+			mock.Anything,
+			"my-minio-bucket",
+			"funcs/handler.py",
+			"",
+			"http://minio.example.com:9000",
+			"my-minio-access-key",
+			"my-minio-secret-key",
+			"").
+		Return(nil).
+		Once()
+
+	suite.builder.options.FunctionConfig.Spec.Build = functionconfig.Build{
+		CodeEntryType: S3EntryType,
+		CodeEntryAttributes: map[string]interface{}{
+			"s3Bucket":          "my-minio-bucket",
+			"s3ItemKey":         "funcs/handler.py",
+			"s3Endpoint":        "http://minio.example.com:9000",
+			"s3AccessKeyId":     "my-minio-access-key",
+			"s3SecretAccessKey": "my-minio-secret-key",
+		},
+	}
+
+	err := suite.builder.createTempDir()
+	suite.Require().NoError(err)
+
+	defer suite.builder.cleanupTempDir() // nolint: errcheck
+
+	// a source file is downloaded as is, keeping its name
+	path, _, err := suite.builder.resolveFunctionPath("")
+	suite.Require().NoError(err)
+	suite.Require().Equal(filepath.Join(suite.builder.tempDir, "download", "handler.py"), path)
+	suite.Require().True(common.IsFile(path))
+
+	suite.mockS3Client.AssertExpectations(suite.T())
+}
+
+func (suite *testSuite) TestResolveFunctionPathOffline() {
+	suite.builder.options.FunctionConfig.Spec.Build.Offline = true
+
+	_, _, err := suite.builder.resolveFunctionPath("http://some-address.com/my-func.py")
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Function code can't be downloaded in an offline build")
+}
+
+func (suite *testSuite) TestGetBuildCacheDirectives() {
+
+	// the build cache is only used by offline builds
+	suite.builder.options.FunctionConfig.Spec.Build.CacheDir = "/some/cache"
+
+	suite.Require().Nil(suite.builder.getBuildCacheDirectives())
+
+	suite.builder.options.FunctionConfig.Spec.Build.Offline = true
+
+	userDirectives := map[string][]functionconfig.Directive{
+		"preCopy": {
+			{Kind: "RUN", Value: "pip install some-package"},
+		},
+		"postCopy": {
+			{Kind: "ENV", Value: "SOME_VAR=some-value"},
+			{Kind: "RUN", Value: "echo done"},
+		},
+	}
+
+	directives := suite.builder.mergeDirectives(suite.builder.getBuildCacheDirectives(), userDirectives)
+	suite.builder.mountBuildCache(directives)
+
+	// the package managers are pointed at the cache before the user's commands
+	preCopyDirectives := directives["preCopy"]
+	suite.Require().Contains(preCopyDirectives,
+		functionconfig.Directive{Kind: "ARG", Value: "PIP_FIND_LINKS=/nuclio-build-cache/pip"})
+
+	// the cache is only mounted into the commands, rather than copied into a layer of the image
+	for _, stageDirectives := range directives {
+		for _, directive := range stageDirectives {
+			suite.Require().NotEqual("COPY", directive.Kind)
+		}
+	}
+
+	buildCacheMount := "--mount=type=bind,source=build-cache,target=/nuclio-build-cache,rw "
+	suite.Require().Equal(functionconfig.Directive{Kind: "RUN", Value: buildCacheMount + "pip install some-package"},
+		preCopyDirectives[len(preCopyDirectives)-1])
+	suite.Require().Equal([]functionconfig.Directive{
+		{Kind: "ENV", Value: "SOME_VAR=some-value"},
+		{Kind: "RUN", Value: buildCacheMount + "echo done"},
+	}, directives["postCopy"])
+
+	// the user's directives are left as they are
+	suite.Require().Equal("echo done", userDirectives["postCopy"][1].Value)
+}
+
+func (suite *testSuite) TestBuildHooks() {
+	version.Set(&version.Info{Label: "1.4.0", Arch: "amd64"})
+	defer version.Set(&version.Info{})
+
+	suite.builder.options.FunctionConfig.Spec.Build.Hooks = &functionconfig.BuildHooks{
+		PreCopy: &functionconfig.BuildHook{
+			Commands: []string{"apt-get update && \\", "apt-get install -y libpq-dev"},
+		},
+		PostInstall: &functionconfig.BuildHook{
+			Commands: []string{"pip download --index-url https://$(cat /run/secrets/index-token)@${INDEX_HOST} pkg"},
+			Args:     map[string]string{"INDEX_HOST": "pypi.example.com"},
+			Secrets:  []functionconfig.BuildSecret{{ID: "index-token", Env: "INDEX_TOKEN"}},
+		},
+		PreFinal: &functionconfig.BuildHook{
+			Commands: []string{"rm -rf /wheels"},
+		},
+	}
+
+	directives := suite.builder.mergeDirectives(map[string][]functionconfig.Directive{
+		"postCopy": {
+			{Kind: "RUN", Value: "pip install -r requirements.txt"},
+		},
+	}, suite.builder.getBuildHooksDirectives())
+
+	suite.Require().Equal([]functionconfig.Directive{
+		{Kind: "RUN", Value: "apt-get update && apt-get install -y libpq-dev"},
+	}, directives["preCopy"])
+
+	// the args are declared, and the secrets mounted, by the commands of their own stage
+	suite.Require().Equal([]functionconfig.Directive{
+		{Kind: "ARG", Value: "INDEX_HOST"},
+		{
+			Kind: "RUN",
+			Value: "--mount=type=secret,id=index-token " +
+				"pip download --index-url https://$(cat /run/secrets/index-token)@${INDEX_HOST} pkg",
+		},
+	}, directives["postInstall"])
+
+	dockerfileContents, err := suite.builder.GenerateDockerfileContents("python:3.7",
+		[]runtime.Artifact{},
+		map[string]string{},
+		directives,
+		false)
+	suite.Require().NoError(err)
+
+	// the post-install hook runs after the post-copy directives, and the pre-final hook right before the command
+	postCopyIndex := strings.Index(dockerfileContents, "RUN pip install -r requirements.txt")
+	postInstallIndex := strings.Index(dockerfileContents, "RUN --mount=type=secret,id=index-token pip download")
+	preFinalIndex := strings.Index(dockerfileContents, "RUN rm -rf /wheels")
+	commandIndex := strings.Index(dockerfileContents, `CMD [ "processor" ]`)
+
+	suite.Require().True(postCopyIndex > 0)
+	suite.Require().True(postInstallIndex > postCopyIndex)
+	suite.Require().True(preFinalIndex > postInstallIndex)
+	suite.Require().True(commandIndex > preFinalIndex)
+
+	buildArgs, err := suite.builder.getBuildArgs()
+	suite.Require().NoError(err)
+	suite.Require().Equal("pypi.example.com", buildArgs["INDEX_HOST"])
+
+	suite.Require().Equal([]functionconfig.BuildSecret{{ID: "index-token", Env: "INDEX_TOKEN"}},
+		suite.builder.getBuildSecrets())
+}
+
+func (suite *testSuite) TestGenerateMultiPlatformDockerfileContents() {
+	version.Set(&version.Info{Label: "1.4.0", Arch: "amd64"})
+	defer version.Set(&version.Info{})
+
+	suite.builder.options.FunctionConfig.Spec.Build.Platforms = []string{"linux/amd64", "linux/arm64"}
+
+	dockerfileContents, err := suite.builder.GenerateDockerfileContents("python:3.7",
+		[]runtime.Artifact{
+			{
+				Name:  "python-onbuild",
+				Image: "quay.io/nuclio/handler-builder-python-onbuild:1.4.0-amd64",
+				Paths: map[string]string{"/home/nuclio/bin/processor": "/usr/local/bin/processor"},
+			},
+			{
+				Name:          "uhttpc",
+				Image:         "quay.io/nuclio/uhttpc:0.0.1-amd64",
+				Paths:         map[string]string{"/home/nuclio/bin/uhttpc": "/usr/local/bin/uhttpc"},
+				ExternalImage: true,
+			},
+		},
+		map[string]string{},
+		map[string][]functionconfig.Directive{},
+		true)
+	suite.Require().NoError(err)
+
+	// the onbuild image is that of each platform's architecture, and its artifacts are copied from its stage
+	suite.Require().Contains(dockerfileContents, "ARG TARGETARCH")
+	suite.Require().Contains(dockerfileContents,
+		"FROM quay.io/nuclio/handler-builder-python-onbuild:1.4.0-${TARGETARCH} AS python-onbuild")
+	suite.Require().Contains(dockerfileContents,
+		"COPY --from=python-onbuild /home/nuclio/bin/processor /usr/local/bin/processor")
+
+	// so is that of an external image, which is copied from a stage of its own
+	suite.Require().Contains(dockerfileContents, "FROM quay.io/nuclio/uhttpc:0.0.1-${TARGETARCH} AS external-1")
+	suite.Require().Contains(dockerfileContents,
+		"COPY --from=external-1 /home/nuclio/bin/uhttpc /usr/local/bin/uhttpc")
+}
+
+func (suite *testSuite) TestGenerateWindowsDockerfileContents() {
+	suite.builder.options.FunctionConfig.Spec.Build.OS = functionconfig.BuildOSWindows
+
+	dockerfileContents, err := suite.builder.GenerateDockerfileContents("mcr.microsoft.com/windows/nanoserver:1809",
+		[]runtime.Artifact{},
+		map[string]string{},
+		map[string][]functionconfig.Directive{},
+		true)
+	suite.Require().NoError(err)
+
+	// windows images are probed with curl, and run the processor's executable
+	suite.Require().Contains(dockerfileContents,
+		"HEALTHCHECK --interval=1s --timeout=3s CMD curl.exe --silent --fail http://127.0.0.1:8082/ready || exit 1")
+	suite.Require().Contains(dockerfileContents, `CMD [ "C:\\usr\\local\\bin\\processor.exe" ]`)
+	suite.Require().NotContains(dockerfileContents, "uhttpc")
+	suite.Require().NotContains(dockerfileContents, "TMPDIR")
+}
+
+func (suite *testSuite) TestValidateBuildContextOutputs() {
+	outputDir, err := ioutil.TempDir("", "build-context-output-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(outputDir) // nolint: errcheck
+
+	// nothing to validate when building
+	suite.Require().NoError(suite.builder.validateBuildContextOutputs())
+
+	// an empty dir and one that doesn't exist yet are fine
+	suite.builder.options.OutputContextDir = outputDir
+	suite.Require().NoError(suite.builder.validateBuildContextOutputs())
+
+	suite.builder.options.OutputContextDir = filepath.Join(outputDir, "context")
+	suite.Require().NoError(suite.builder.validateBuildContextOutputs())
+
+	// nothing is built, so there's no image to output
+	suite.builder.options.OutputImageFile = filepath.Join(outputDir, "image.tar")
+	suite.Require().Error(suite.builder.validateBuildContextOutputs())
+	suite.builder.options.OutputImageFile = ""
+
+	// a dir with something in it or a file aren't
+	existingFilePath := filepath.Join(outputDir, "existing")
+	suite.Require().NoError(ioutil.WriteFile(existingFilePath, []byte("data"), 0644))
+
+	suite.builder.options.OutputContextDir = outputDir
+	suite.Require().Error(suite.builder.validateBuildContextOutputs())
+
+	suite.builder.options.OutputContextDir = existingFilePath
+	suite.Require().Error(suite.builder.validateBuildContextOutputs())
+}
+
+func (suite *testSuite) TestOutputBuildContext() {
+	var err error
+
+	suite.builder.stagingDir, err = ioutil.TempDir("", "build-staging-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(suite.builder.stagingDir) // nolint: errcheck
+
+	outputDir, err := ioutil.TempDir("", "build-context-output-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(outputDir) // nolint: errcheck
+
+	// a staged handler and an onbuild artifact
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.builder.stagingDir, "handler"), 0755))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.builder.stagingDir, "artifacts"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(suite.builder.stagingDir, "handler", "main.py"),
+		[]byte("def handler(context, event):\n    pass\n"),
+		0644))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(suite.builder.stagingDir, "artifacts", "processor"),
+		[]byte("processor"),
+		0755))
+
+	suite.builder.options.OutputDockerfile = filepath.Join(outputDir, "Dockerfile")
+	suite.builder.options.OutputContextDir = filepath.Join(outputDir, "context")
+	suite.Require().True(suite.builder.isBuildContextOutput())
+
+	err = suite.builder.outputBuildContext(&runtime.ProcessorDockerfileInfo{
+		DockerfileContents: "FROM python:3.7\nCOPY handler /opt/nuclio\n",
+	})
+	suite.Require().NoError(err)
+
+	dockerfileContents, err := ioutil.ReadFile(suite.builder.options.OutputDockerfile)
+	suite.Require().NoError(err)
+	suite.Require().Equal("FROM python:3.7\nCOPY handler /opt/nuclio\n", string(dockerfileContents))
+
+	suite.Require().True(common.IsFile(filepath.Join(suite.builder.options.OutputContextDir, "handler", "main.py")))
+	suite.Require().True(common.IsFile(filepath.Join(suite.builder.options.OutputContextDir, "artifacts", "processor")))
+}
+
+func (suite *testSuite) TestSecureImage() {
+	imageHash := strings.Repeat("ab", 32)
+
+	cmdRunner := &recordingCmdRunner{
+		outputs: map[string]string{
+			"cosign triangulate": fmt.Sprintf("registry.example.com/my-function:sha256-%s.sig\n", imageHash),
+		},
+	}
+	suite.builder.cmdRunner = cmdRunner
+	suite.builder.options.FunctionConfig.Meta.Name = "my-function"
+
+	// nothing to do unless asked to
+	imageStatus, err := suite.builder.secureImage("my-function:latest")
+	suite.Require().NoError(err)
+	suite.Require().Nil(imageStatus)
+	suite.Require().Empty(cmdRunner.commands)
+
+	// signing requires a registry, and a key requires signing
+	suite.builder.options.FunctionConfig.Spec.Build.Sign = true
+	suite.Require().Error(suite.builder.validateSupplyChainConfiguration())
+
+	suite.builder.options.FunctionConfig.Spec.Build.Sign = false
+	suite.builder.options.FunctionConfig.Spec.Build.SigningKey = "cosign.key"
+	suite.builder.options.FunctionConfig.Spec.Build.Registry = "registry.example.com"
+	suite.Require().Error(suite.builder.validateSupplyChainConfiguration())
+
+	suite.builder.options.FunctionConfig.Spec.Build.SBOMFormat = "xml"
+	suite.builder.options.FunctionConfig.Spec.Build.Sign = true
+	suite.Require().Error(suite.builder.validateSupplyChainConfiguration())
+
+	// the SBOM is generated of, and the signature made for, the pushed image's digest
+	suite.builder.options.FunctionConfig.Spec.Build.SBOMFormat = functionconfig.SBOMFormatCycloneDX
+	suite.Require().NoError(suite.builder.validateSupplyChainConfiguration())
+
+	imageStatus, err = suite.builder.secureImage("my-function:latest")
+	suite.Require().NoError(err)
+
+	digest := fmt.Sprintf("registry.example.com/my-function@sha256:%s", imageHash)
+	suite.Require().Equal(&functionconfig.ImageStatus{
+		Digest:    digest,
+		Signature: fmt.Sprintf("registry.example.com/my-function:sha256-%s.sig", imageHash),
+	}, imageStatus)
+
+	suite.Require().Equal([]string{
+		"cosign triangulate registry.example.com/my-function:latest",
+		fmt.Sprintf("syft %s -o cyclonedx-json=my-function.cyclonedx.json", digest),
+		fmt.Sprintf("cosign sign --yes --key cosign.key %s", digest),
+		fmt.Sprintf("cosign attest --yes --key cosign.key --type cyclonedx --predicate my-function.cyclonedx.json %s",
+			digest),
+	}, cmdRunner.commands)
+
+	// without a registry, the SBOM is generated of the local image
+	cmdRunner.commands = nil
+	suite.builder.options.FunctionConfig.Spec.Build.Registry = ""
+	suite.builder.options.FunctionConfig.Spec.Build.Sign = false
+	suite.builder.options.FunctionConfig.Spec.Build.SigningKey = ""
+	suite.builder.options.FunctionConfig.Spec.Build.SBOMFormat = functionconfig.SBOMFormatSPDX
+	suite.builder.options.FunctionConfig.Spec.Build.SBOMPath = "/tmp/sbom.json"
+
+	imageStatus, err = suite.builder.secureImage("my-function:latest")
+	suite.Require().NoError(err)
+	suite.Require().Nil(imageStatus)
+	suite.Require().Equal([]string{
+		"syft docker:my-function:latest -o spdx-json=/tmp/sbom.json",
+	}, cmdRunner.commands)
+}
+
+func (suite *testSuite) TestResolveFunctionPathGitCodeEntry() {
+	sourceDir, err := ioutil.TempDir("", "nuclio-git-test")
+	suite.Require().NoError(err)
+
+	defer os.RemoveAll(sourceDir) // nolint: errcheck
+
+	// git doesn't clone submodules from local paths unless allowed to
+	for envName, envValue := range map[string]string{
+		"GIT_CONFIG_COUNT":   "1",
+		"GIT_CONFIG_KEY_0":   "protocol.file.allow",
+		"GIT_CONFIG_VALUE_0": "always",
+	} {
+		suite.Require().NoError(os.Setenv(envName, envValue))
+		defer os.Unsetenv(envName) // nolint: errcheck
+	}
+
+	// a library, which the function's repository has as a submodule from its second commit on
+	libraryDir := filepath.Join(sourceDir, "library")
+	suite.Require().NoError(os.MkdirAll(libraryDir, 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(libraryDir, "lib.py"), []byte("pass\n"), 0644))
+	suite.runGit(libraryDir, "init", "-q")
+	suite.runGit(libraryDir, "add", ".")
+	suite.runGit(libraryDir, "commit", "-q", "-m", "Add library")
+
+	functionDir := filepath.Join(sourceDir, "function")
+	suite.Require().NoError(os.MkdirAll(filepath.Join(functionDir, "my-python-func"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(functionDir, "my-python-func", "main.py"),
+		[]byte("def handler(context, event):\n    pass\n"),
+		0644))
+	suite.runGit(functionDir, "init", "-q")
+	suite.runGit(functionDir, "checkout", "-q", "-b", "master")
+	suite.runGit(functionDir, "add", ".")
+	suite.runGit(functionDir, "commit", "-q", "-m", "Add function")
+	suite.runGit(functionDir, "tag", "v1")
+	firstCommit := suite.runGit(functionDir, "rev-parse", "HEAD")
+
+	suite.runGit(functionDir, "submodule", "add", "-q", "file://"+libraryDir, "my-python-func/lib")
+	suite.runGit(functionDir, "commit", "-q", "-m", "Add library")
+	secondCommit := suite.runGit(functionDir, "rev-parse", "HEAD")
+
+	for _, testCase := range []struct {
+		name            string
+		attributes      map[string]interface{}
+		expectedCommit  string
+		expectedFiles   []string
+		unexpectedFiles []string
+		expectError     bool
+	}{
+		{
+			name: "BranchWithSubmodules",
+			attributes: map[string]interface{}{
+				"branch":     "master",
+				"submodules": true,
+				"workDir":    "/my-python-func",
+			},
+			expectedCommit:  secondCommit,
+			expectedFiles:   []string{"main.py", "lib/lib.py"},
+			unexpectedFiles: []string{".git", "lib/.git"},
+		},
+		{
+			name:            "BranchWithoutSubmodules",
+			attributes:      map[string]interface{}{"workDir": "/my-python-func"},
+			expectedCommit:  secondCommit,
+			expectedFiles:   []string{"main.py"},
+			unexpectedFiles: []string{"lib/lib.py"},
+		},
+		{
+			name:           "Tag",
+			attributes:     map[string]interface{}{"tag": "v1", "workDir": "/my-python-func"},
+			expectedCommit: firstCommit,
+			expectedFiles:  []string{"main.py"},
+		},
+		{
+			name: "Commit",
+			attributes: map[string]interface{}{
+				"commit":  firstCommit,
+				"depth":   float64(0),
+				"workDir": "/my-python-func",
+			},
+			expectedCommit: firstCommit,
+			expectedFiles:  []string{"main.py"},
+		},
+		{
+			name:        "BranchAndTag",
+			attributes:  map[string]interface{}{"branch": "master", "tag": "v1"},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.Require().NoError(suite.builder.createTempDir())
+			defer suite.builder.cleanupTempDir() // nolint: errcheck
+
+			suite.builder.sourceCommit = ""
+			suite.builder.options.FunctionConfig.Spec.Build = functionconfig.Build{
+				CodeEntryType:       GitEntryType,
+				Path:                "file://" + functionDir,
+				CodeEntryAttributes: testCase.attributes,
+			}
+
+			path, _, err := suite.builder.resolveFunctionPath(suite.builder.options.FunctionConfig.Spec.Build.Path)
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(filepath.Join(suite.builder.tempDir, "git", "my-python-func"), path)
+			suite.Require().Equal(testCase.expectedCommit, suite.builder.sourceCommit)
+
+			for _, expectedFile := range testCase.expectedFiles {
+				suite.Require().True(common.FileExists(filepath.Join(path, expectedFile)), expectedFile)
+			}
+
+			for _, unexpectedFile := range testCase.unexpectedFiles {
+				suite.Require().False(common.FileExists(filepath.Join(path, unexpectedFile)), unexpectedFile)
+			}
+		})
+	}
+}
+
+func (suite *testSuite) TestGetGitSSHCommand() {
+	suite.Require().NoError(suite.builder.createTempDir())
+	defer suite.builder.cleanupTempDir() // nolint: errcheck
+
+	suite.builder.options.FunctionConfig.Meta.Namespace = "my-namespace"
+
+	// ssh's defaults
+	sshCommand, err := suite.builder.getGitSSHCommand(&gitCodeEntryAttributes{})
+	suite.Require().NoError(err)
+	suite.Require().Empty(sshCommand)
+
+	// the key and known hosts are read from the secret
+	suite.mockPlatform.
+		On("GetSecretData", "my-namespace", "git-credentials").
+		Return(map[string][]byte{
+			"ssh-privatekey": []byte("my-key"),
+			"known_hosts":    []byte("github.com ssh-ed25519 AAAA\n"),
+		}, nil).
+		Once()
+
+	suite.builder.options.FunctionConfig.Spec.Build.CodeEntryAttributes = map[string]interface{}{
+		"sshKeyFrom": map[string]interface{}{"name": "git-credentials"},
+	}
+
+	attributes, err := suite.builder.parseGitCodeEntryAttributes()
+	suite.Require().NoError(err)
+
+	sshCommand, err = suite.builder.getGitSSHCommand(attributes)
+	suite.Require().NoError(err)
+
+	sshDir := filepath.Join(suite.builder.tempDir, "git-ssh")
+	suite.Require().Equal(fmt.Sprintf("ssh -o BatchMode=yes -i '%s/id' -o IdentitiesOnly=yes "+
+		"-o StrictHostKeyChecking=yes -o UserKnownHostsFile='%s/known_hosts'", sshDir, sshDir), sshCommand)
+
+	sshKey, err := ioutil.ReadFile(filepath.Join(sshDir, "id"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-key\n", string(sshKey))
+
+	knownHosts, err := ioutil.ReadFile(filepath.Join(sshDir, "known_hosts"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("github.com ssh-ed25519 AAAA\n", string(knownHosts))
+
+	// a key on the host, without verifying the server's key
+	sshCommand, err = suite.builder.getGitSSHCommand(&gitCodeEntryAttributes{
+		SSHKeyPath:            "/etc/nuclio/git/id_ed25519",
+		InsecureIgnoreHostKey: true,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("ssh -o BatchMode=yes -i '/etc/nuclio/git/id_ed25519' -o IdentitiesOnly=yes "+
+		"-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null", sshCommand)
+
+	// a key is either read from a secret or from the host
+	suite.builder.options.FunctionConfig.Spec.Build.CodeEntryAttributes = map[string]interface{}{
+		"sshKeyFrom": map[string]interface{}{"name": "git-credentials"},
+		"sshKeyPath": "/etc/nuclio/git/id_ed25519",
+	}
+
+	_, err = suite.builder.parseGitCodeEntryAttributes()
+	suite.Require().Error(err)
+}
+
+// runGit runs git in the directory, returning its trimmed output
+func (suite *testSuite) runGit(dir string, args ...string) string {
+	gitArgs := append([]string{"-c", "user.name=nuclio", "-c", "user.email=nuclio@example.com"}, args...)
+
+	command := exec.Command("git", gitArgs...)
+	command.Dir = dir
+
+	output, err := command.CombinedOutput()
+	suite.Require().NoError(err, string(output))
+
+	return strings.TrimSpace(string(output))
+}
+
 func (suite *testSuite) mergeDirectivesAndVerify(first map[string][]functionconfig.Directive,
 	second map[string][]functionconfig.Directive,
 	merged map[string][]functionconfig.Directive) {
@@ -722,6 +1466,67 @@ func (suite *testSuite) testResolveFunctionPathArchiveBadWorkDir(buildConfigurat
 	httpmock.DeactivateAndReset()
 }
 
+func (suite *testSuite) TestRuntimeImages() {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	runtimeImages := &platformconfig.RuntimeImages{
+		BaseImage:       "registry.local/python:3.7",
+		OnbuildImage:    "registry.local/handler-builder-python-onbuild:{{ .Label }}-{{ .Arch }}",
+		BaseImageDigest: digest,
+		PipIndexURL:     "https://pypi.local/simple",
+		NPMRegistryURL:  "https://npm.local",
+	}
+	versionInfo := &version.Info{Label: "1.4.0", Arch: "amd64"}
+
+	// the platform's images are used (and pinned) when the function doesn't set its own
+	baseImage, err := suite.builder.getProcessorDockerfileBaseImage("python:3.6", "quay.io", runtimeImages)
+	suite.Require().NoError(err)
+	suite.Require().Equal("registry.local/python:3.7@"+digest, baseImage)
+
+	onbuildImage, err := suite.builder.getProcessorDockerfileOnbuildImage(versionInfo, "default", runtimeImages)
+	suite.Require().NoError(err)
+	suite.Require().Equal("registry.local/handler-builder-python-onbuild:1.4.0-amd64", onbuildImage)
+
+	// the function's images take precedence when not enforced
+	suite.builder.options.FunctionConfig.Spec.Build.BaseImage = "python:3.8"
+	suite.Require().NoError(suite.builder.validateRuntimeImagesEnforced(runtimeImages))
+
+	baseImage, err = suite.builder.getProcessorDockerfileBaseImage("python:3.6", "quay.io", runtimeImages)
+	suite.Require().NoError(err)
+	suite.Require().Equal("python:3.8", baseImage)
+
+	// but fail the build when enforced
+	runtimeImages.Enforce = true
+	err = suite.builder.validateRuntimeImagesEnforced(runtimeImages)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "spec.build.baseImage")
+
+	// a digest that isn't sha256 is rejected
+	suite.builder.options.FunctionConfig.Spec.Build.BaseImage = ""
+	runtimeImages.BaseImageDigest = "md5:1234"
+	_, err = suite.builder.getProcessorDockerfileBaseImage("python:3.6", "", runtimeImages)
+	suite.Require().Error(err)
+
+	// package indexes are passed as build args
+	directives := suite.builder.getPackageIndexDirectives(runtimeImages)
+	suite.Require().Equal([]functionconfig.Directive{
+		{Kind: "ARG", Value: "PIP_INDEX_URL=https://pypi.local/simple"},
+		{Kind: "ARG", Value: "NPM_CONFIG_REGISTRY=https://npm.local"},
+	}, directives["preCopy"])
+	suite.Require().Nil(suite.builder.getPackageIndexDirectives(nil))
+}
+
+func (suite *testSuite) createFunctionDir(files map[string]string) string {
+	functionDir, err := ioutil.TempDir("", "nuclio-build-test-")
+	suite.Require().NoError(err)
+
+	for fileName, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(functionDir, fileName), []byte(contents), 0644)
+		suite.Require().NoError(err)
+	}
+
+	return functionDir
+}
+
 func TestBuilderSuite(t *testing.T) {
 	if testing.Short() {
 		return
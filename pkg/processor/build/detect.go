@@ -0,0 +1,202 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/build/util"
+
+	"github.com/nuclio/errors"
+)
+
+// files whose presence in a function directory identifies its runtime
+var runtimeMarkerFiles = []struct {
+	fileName    string
+	runtimeName string
+}{
+	{"requirements.txt", "python"},
+	{"Pipfile", "python"},
+	{"setup.py", "python"},
+	{"pyproject.toml", "python"},
+	{"package.json", "nodejs"},
+	{"go.mod", "golang"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"Gemfile", "ruby"},
+}
+
+var (
+
+	// python and ruby handlers are top level functions receiving (context, event)
+	contextEventHandlerRegex = regexp.MustCompile(`(?m)^def\s+(\w+)\s*\(\s*context\s*,\s*event\s*\)`)
+	nodejsHandlerRegex       = regexp.MustCompile(`(?m)(?:module\.)?exports\.(\w+)\s*=`)
+)
+
+// detectRuntimeInDir infers the runtime of a function directory, first by well known dependency files
+// and then by the extensions of the top level source files
+func (b *Builder) detectRuntimeInDir(functionDir string) (string, error) {
+	ignoreMatcher, err := util.ReadIgnoreFile(functionDir)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read ignore file")
+	}
+
+	fileNames, err := b.getTopLevelFileNames(functionDir, ignoreMatcher)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to list function directory")
+	}
+
+	for _, marker := range runtimeMarkerFiles {
+		if common.StringSliceContainsString(fileNames, marker.fileName) {
+			return marker.runtimeName, nil
+		}
+	}
+
+	candidateRuntimeNames := map[string]bool{}
+	for _, fileName := range fileNames {
+		if filepath.Ext(fileName) == ".csproj" {
+			candidateRuntimeNames["dotnetcore"] = true
+			continue
+		}
+
+		runtimeName, err := b.getRuntimeNameByFileExtension(fileName)
+		if err != nil {
+
+			// not a source file (e.g. README.md)
+			continue
+		}
+
+		// shell scripts often accompany functions of other runtimes, so they only count when alone
+		if runtimeName == "shell" {
+			continue
+		}
+
+		candidateRuntimeNames[runtimeName] = true
+	}
+
+	switch len(candidateRuntimeNames) {
+	case 0:
+		if b.dirHasExtension(fileNames, ".sh") {
+			return "shell", nil
+		}
+
+		return "", errors.Errorf("Could not detect the runtime of %s - runtime must be specified", functionDir)
+	case 1:
+		for runtimeName := range candidateRuntimeNames {
+			return runtimeName, nil
+		}
+	}
+
+	var runtimeNames []string
+	for runtimeName := range candidateRuntimeNames {
+		runtimeNames = append(runtimeNames, runtimeName)
+	}
+
+	sort.Strings(runtimeNames)
+
+	return "", errors.Errorf("Found source files of several runtimes (%s) in %s - runtime must be specified",
+		strings.Join(runtimeNames, ", "),
+		functionDir)
+}
+
+// detectFunctionHandlersInDir looks for handler functions in the top level source files of a function directory.
+// Returns no handlers for runtimes whose handlers aren't detected this way
+func (b *Builder) detectFunctionHandlersInDir(functionDir string, runtimeName string) ([]string, error) {
+	var handlerRegex *regexp.Regexp
+	var extension string
+
+	switch runtimeName {
+	case "python", "pypy":
+		handlerRegex, extension = contextEventHandlerRegex, ".py"
+	case "nodejs":
+		handlerRegex, extension = nodejsHandlerRegex, ".js"
+	case "ruby":
+		handlerRegex, extension = contextEventHandlerRegex, ".rb"
+	default:
+		return nil, nil
+	}
+
+	ignoreMatcher, err := util.ReadIgnoreFile(functionDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read ignore file")
+	}
+
+	fileNames, err := b.getTopLevelFileNames(functionDir, ignoreMatcher)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list function directory")
+	}
+
+	var handlers []string
+	for _, fileName := range fileNames {
+		if filepath.Ext(fileName) != extension {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(path.Join(functionDir, fileName))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %s", fileName)
+		}
+
+		moduleName := strings.TrimSuffix(fileName, extension)
+		for _, match := range handlerRegex.FindAllStringSubmatch(string(contents), -1) {
+			handlers = append(handlers, fmt.Sprintf("%s:%s", moduleName, match[1]))
+		}
+	}
+
+	b.logger.DebugWith("Detected function handlers in directory",
+		"functionDir", functionDir,
+		"runtime", runtimeName,
+		"handlers", handlers)
+
+	return handlers, nil
+}
+
+func (b *Builder) getTopLevelFileNames(dir string, ignoreMatcher *util.IgnoreMatcher) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() || ignoreMatcher.Matches(entry.Name(), false) {
+			continue
+		}
+
+		fileNames = append(fileNames, entry.Name())
+	}
+
+	// ReadDir returns entries sorted by name, so detection is deterministic
+	return fileNames, nil
+}
+
+func (b *Builder) dirHasExtension(fileNames []string, extension string) bool {
+	for _, fileName := range fileNames {
+		if filepath.Ext(fileName) == extension {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,412 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	defaultGitDepth            = 1
+	defaultGitSSHKeySecretKey  = "ssh-privatekey"
+	gitKnownHostsSecretKey     = "known_hosts"
+	gitSSHKeyFileName          = "id"
+	gitSSHKnownHostsFileName   = "known_hosts"
+	gitSSHDirInTemp            = "git-ssh"
+	gitRepositoryDirInTemp     = "git"
+	gitRepositoryMetadataEntry = ".git"
+)
+
+// gitCodeEntryAttributes are the code entry attributes of the git code entry type
+type gitCodeEntryAttributes struct {
+
+	// what to check out - a branch, a tag or a commit. a commit may be given with the branch it's on, which is
+	// fetched if the repository doesn't allow fetching the commit directly. defaults to the remote's HEAD
+	Branch string `mapstructure:"branch"`
+	Tag    string `mapstructure:"tag"`
+	Commit string `mapstructure:"commit"`
+
+	// the depth of the (shallow) clone, and of the submodules' clones. 0 clones the entire history
+	Depth *int `mapstructure:"depth"`
+
+	// clone the repository's submodules, recursively
+	Submodules bool `mapstructure:"submodules"`
+
+	// the SSH private key to clone with - read from a secret, or from a file on the builder's host
+	SSHKeyFrom *gitSSHKeySecretRef `mapstructure:"sshKeyFrom"`
+	SSHKeyPath string              `mapstructure:"sshKeyPath"`
+
+	// the known hosts the SSH server's key is verified against - contents, or a file on the builder's host. if
+	// neither is given, the known_hosts key of the SSH key's secret (if any), then ssh's default files
+	KnownHosts     string `mapstructure:"knownHosts"`
+	KnownHostsPath string `mapstructure:"knownHostsPath"`
+
+	// don't verify the SSH server's key at all
+	InsecureIgnoreHostKey bool `mapstructure:"insecureIgnoreHostKey"`
+}
+
+// gitSSHKeySecretRef references the key of a secret holding an SSH private key - a kubernetes secret in the
+// function's namespace on kube, a directory under the secrets directory on local
+type gitSSHKeySecretRef struct {
+	Name string `mapstructure:"name"`
+	Key  string `mapstructure:"key"`
+}
+
+// cloneFunctionGitRepository clones the repository the function's code is in, checking out the configured branch,
+// tag or commit, and returns the directory holding the function (the repository, or its workDir). the commit
+// checked out is recorded, to be reported in the function's status
+func (b *Builder) cloneFunctionGitRepository(repositoryURL string) (string, error) {
+	attributes, err := b.parseGitCodeEntryAttributes()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse git code entry attributes")
+	}
+
+	if repositoryURL == "" {
+		return "", errors.New("Must provide the repository URL as the function path when code entry type is git")
+	}
+
+	sshCommand, err := b.getGitSSHCommand(attributes)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to prepare SSH configuration")
+	}
+
+	repositoryDir, err := b.mkDirUnderTemp(gitRepositoryDirInTemp)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create temporary dir for the repository")
+	}
+
+	git := b.getGitCommand(repositoryDir, sshCommand)
+	runOptions := &cmdrunner.RunOptions{
+		LogRedactions:     b.getGitURLRedactions(repositoryURL),
+		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+	}
+
+	redactedRepositoryURL := common.Redact(runOptions.LogRedactions, repositoryURL)
+
+	b.logger.InfoWith("Cloning function repository",
+		"url", redactedRepositoryURL,
+		"branch", attributes.Branch,
+		"tag", attributes.Tag,
+		"commit", attributes.Commit)
+
+	if _, err := b.runGitCommand(runOptions, "git init -q %s", quoteShellArgument(repositoryDir)); err != nil {
+		return "", errors.Wrap(err, "Failed to initialize repository")
+	}
+
+	if _, err := b.runGitCommand(runOptions, "%s remote add origin %s", git, quoteShellArgument(repositoryURL)); err != nil {
+		return "", errors.Wrap(err, "Failed to add repository remote")
+	}
+
+	depthArgument := ""
+	if *attributes.Depth > 0 {
+		depthArgument = fmt.Sprintf("--depth %d ", *attributes.Depth)
+	}
+
+	if _, err := b.runGitCommand(runOptions, "%s fetch -q %sorigin %s",
+		git,
+		depthArgument,
+		quoteShellArgument(attributes.getFetchedReference())); err != nil {
+
+		// servers may not allow fetching commits which aren't the tip of a ref. fetch the branch the commit
+		// is on (or all of them) and look for it in their history
+		if attributes.Commit == "" {
+			return "", errors.Wrap(err, "Failed to fetch repository")
+		}
+
+		b.logger.DebugWith("Failed to fetch commit, fetching its history", "commit", attributes.Commit)
+
+		fetchedBranches := ""
+		if attributes.Branch != "" {
+			fetchedBranches = " " + quoteShellArgument("refs/heads/"+attributes.Branch)
+		}
+
+		if _, err := b.runGitCommand(runOptions, "%s fetch -q origin%s", git, fetchedBranches); err != nil {
+			return "", errors.Wrap(err, "Failed to fetch repository")
+		}
+	}
+
+	checkedOutReference := "FETCH_HEAD"
+	if attributes.Commit != "" {
+		checkedOutReference = attributes.Commit
+	}
+
+	if _, err := b.runGitCommand(runOptions, "%s checkout -q --detach %s",
+		git,
+		quoteShellArgument(checkedOutReference)); err != nil {
+		return "", errors.Wrapf(err, "Failed to check out %s", checkedOutReference)
+	}
+
+	if attributes.Submodules {
+		if _, err := b.runGitCommand(runOptions, "%s submodule update -q --init --recursive %s",
+			git,
+			depthArgument); err != nil {
+
+			// a submodule's commit may not be the tip of its branch, which a shallow clone can't check out
+			if depthArgument == "" {
+				return "", errors.Wrap(err, "Failed to update submodules")
+			}
+
+			b.logger.DebugWith("Failed to update submodules shallowly, updating them with their history")
+
+			if _, err := b.runGitCommand(runOptions, "%s submodule update -q --init --recursive", git); err != nil {
+				return "", errors.Wrap(err, "Failed to update submodules")
+			}
+		}
+	}
+
+	resolvedCommit, err := b.runGitCommand(runOptions, "%s rev-parse HEAD", git)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve checked out commit")
+	}
+
+	b.sourceCommit = strings.TrimSpace(resolvedCommit)
+
+	b.logger.InfoWith("Cloned function repository", "url", redactedRepositoryURL, "commit", b.sourceCommit)
+
+	// the function is built from the files - the repository's (and its submodules') metadata would only
+	// bloat the image
+	if err := removeGitMetadata(repositoryDir); err != nil {
+		return "", errors.Wrap(err, "Failed to remove repository metadata")
+	}
+
+	return b.resolveUserSpecifiedArchiveWorkdir(repositoryDir)
+}
+
+func (b *Builder) parseGitCodeEntryAttributes() (*gitCodeEntryAttributes, error) {
+	attributes := gitCodeEntryAttributes{}
+
+	if err := mapstructure.Decode(b.options.FunctionConfig.Spec.Build.CodeEntryAttributes, &attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if attributes.Branch != "" && attributes.Tag != "" {
+		return nil, errors.New("Only one of branch and tag may be given")
+	}
+
+	if attributes.Depth == nil {
+		depth := defaultGitDepth
+		attributes.Depth = &depth
+	}
+
+	if *attributes.Depth < 0 {
+		return nil, errors.New("Depth must not be negative")
+	}
+
+	if attributes.SSHKeyFrom != nil && attributes.SSHKeyPath != "" {
+		return nil, errors.New("Only one of sshKeyFrom and sshKeyPath may be given")
+	}
+
+	if attributes.SSHKeyFrom != nil {
+		if attributes.SSHKeyFrom.Name == "" {
+			return nil, errors.New("The name of the secret of sshKeyFrom must be given")
+		}
+
+		if attributes.SSHKeyFrom.Key == "" {
+			attributes.SSHKeyFrom.Key = defaultGitSSHKeySecretKey
+		}
+	}
+
+	if attributes.KnownHosts != "" && attributes.KnownHostsPath != "" {
+		return nil, errors.New("Only one of knownHosts and knownHostsPath may be given")
+	}
+
+	return &attributes, nil
+}
+
+// getFetchedReference returns the reference to fetch - the commit, if one's pinned
+func (gcea *gitCodeEntryAttributes) getFetchedReference() string {
+	switch {
+	case gcea.Commit != "":
+		return gcea.Commit
+	case gcea.Tag != "":
+		return "refs/tags/" + gcea.Tag
+	case gcea.Branch != "":
+		return "refs/heads/" + gcea.Branch
+	default:
+		return "HEAD"
+	}
+}
+
+// getGitSSHCommand returns the ssh command git connects to the repository with, or empty if ssh's defaults
+// should be used. the key and known hosts read from secrets are written under the temp dir
+func (b *Builder) getGitSSHCommand(attributes *gitCodeEntryAttributes) (string, error) {
+	sshKeyPath := attributes.SSHKeyPath
+	knownHostsPath := attributes.KnownHostsPath
+	knownHosts := attributes.KnownHosts
+
+	var sshKey []byte
+	if attributes.SSHKeyFrom != nil {
+		secretData, err := b.platform.GetSecretData(b.options.FunctionConfig.Meta.Namespace,
+			attributes.SSHKeyFrom.Name)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to get secret %s", attributes.SSHKeyFrom.Name)
+		}
+
+		var found bool
+		if sshKey, found = secretData[attributes.SSHKeyFrom.Key]; !found {
+			return "", errors.Errorf("Secret %s has no key %s", attributes.SSHKeyFrom.Name, attributes.SSHKeyFrom.Key)
+		}
+
+		if knownHosts == "" && knownHostsPath == "" {
+			knownHosts = string(secretData[gitKnownHostsSecretKey])
+		}
+	}
+
+	if sshKey == nil && sshKeyPath == "" && knownHosts == "" && knownHostsPath == "" &&
+		!attributes.InsecureIgnoreHostKey {
+		return "", nil
+	}
+
+	var sshDir string
+	if sshKey != nil || knownHosts != "" {
+		var err error
+
+		if sshDir, err = b.mkDirUnderTemp(gitSSHDirInTemp); err != nil {
+			return "", errors.Wrap(err, "Failed to create temporary dir for SSH files")
+		}
+	}
+
+	if sshKey != nil {
+		sshKeyPath = filepath.Join(sshDir, gitSSHKeyFileName)
+
+		// ssh refuses keys other users can read, and keys not ending with a newline
+		if !strings.HasSuffix(string(sshKey), "\n") {
+			sshKey = append(sshKey, '\n')
+		}
+
+		if err := ioutil.WriteFile(sshKeyPath, sshKey, 0600); err != nil {
+			return "", errors.Wrap(err, "Failed to write SSH key")
+		}
+	}
+
+	if knownHosts != "" {
+		knownHostsPath = filepath.Join(sshDir, gitSSHKnownHostsFileName)
+
+		if err := ioutil.WriteFile(knownHostsPath, []byte(knownHosts), 0600); err != nil {
+			return "", errors.Wrap(err, "Failed to write known hosts")
+		}
+	}
+
+	// never prompt - there's no one to answer
+	sshCommand := "ssh -o BatchMode=yes"
+
+	if sshKeyPath != "" {
+		sshCommand += fmt.Sprintf(" -i %s -o IdentitiesOnly=yes", quoteShellArgument(sshKeyPath))
+	}
+
+	switch {
+	case attributes.InsecureIgnoreHostKey:
+		sshCommand += " -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+	case knownHostsPath != "":
+		sshCommand += fmt.Sprintf(" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s",
+			quoteShellArgument(knownHostsPath))
+	default:
+		sshCommand += " -o StrictHostKeyChecking=yes"
+	}
+
+	return sshCommand, nil
+}
+
+// getGitCommand returns the git command (as a shell command prefix) operating on the repository. the ssh command
+// is given as configuration, so that it's used for the submodules too
+func (b *Builder) getGitCommand(repositoryDir string, sshCommand string) string {
+	git := fmt.Sprintf("git -C %s", quoteShellArgument(repositoryDir))
+
+	if sshCommand != "" {
+		git += fmt.Sprintf(" -c core.sshCommand=%s", quoteShellArgument(sshCommand))
+	}
+
+	return git
+}
+
+// getGitURLRedactions returns the password (e.g. an access token) of the repository URL, if it has one
+func (b *Builder) getGitURLRedactions(repositoryURL string) []string {
+	parsedURL, err := url.Parse(repositoryURL)
+	if err != nil || parsedURL.User == nil {
+		return nil
+	}
+
+	if password, hasPassword := parsedURL.User.Password(); hasPassword && password != "" {
+		return []string{password}
+	}
+
+	return nil
+}
+
+func (b *Builder) runGitCommand(runOptions *cmdrunner.RunOptions, format string, vars ...interface{}) (string, error) {
+	cmdRunner, err := b.getCmdRunner()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get command runner")
+	}
+
+	runResult, err := cmdRunner.Run(runOptions, format, vars...)
+	if err != nil {
+		return "", errors.Wrapf(err, "Git command failed: %s", runResult.Stderr)
+	}
+
+	return runResult.Output, nil
+}
+
+// removeGitMetadata removes the metadata of the repository and of its submodules (directories or files
+// named .git)
+func removeGitMetadata(repositoryDir string) error {
+	var metadataPaths []string
+
+	if err := filepath.Walk(repositoryDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Name() != gitRepositoryMetadataEntry {
+			return nil
+		}
+
+		metadataPaths = append(metadataPaths, path)
+
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "Failed to walk repository")
+	}
+
+	for _, metadataPath := range metadataPaths {
+		if err := os.RemoveAll(metadataPath); err != nil {
+			return errors.Wrapf(err, "Failed to remove %s", metadataPath)
+		}
+	}
+
+	return nil
+}
+
+// quoteShellArgument quotes the argument for /bin/sh, which the command runner runs commands with
+func quoteShellArgument(argument string) string {
+	return "'" + strings.Replace(argument, "'", `'"'"'`, -1) + "'"
+}
@@ -19,6 +19,7 @@ package dotnetcore
 import (
 	"fmt"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/version"
 )
@@ -38,6 +39,10 @@ func (d *dotnetcore) GetProcessorDockerfileInfo(versionInfo *version.Info,
 
 	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{}
 
+	if d.FunctionConfig.Spec.Build.GetOS() == functionconfig.BuildOSWindows {
+		return d.getWindowsProcessorDockerfileInfo(versionInfo, onbuildImageRegistry)
+	}
+
 	// fill onbuild artifact
 	artifact := runtime.Artifact{
 		Name: "dotnetcore-onbuild",
@@ -59,3 +64,30 @@ func (d *dotnetcore) GetProcessorDockerfileInfo(versionInfo *version.Info,
 
 	return &processorDockerfileInfo, nil
 }
+
+// getWindowsProcessorDockerfileInfo returns the information of windows images, whose onbuild image is tagged
+// with the windows prefix (e.g. 1.4.0-windows-amd64)
+func (d *dotnetcore) getWindowsProcessorDockerfileInfo(versionInfo *version.Info,
+	onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+
+	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{}
+
+	artifact := runtime.Artifact{
+		Name: "dotnetcore-onbuild",
+		Image: fmt.Sprintf("%s/nuclio/handler-builder-dotnetcore-onbuild:%s-windows-%s",
+			onbuildImageRegistry,
+			versionInfo.Label,
+			versionInfo.Arch),
+		Paths: map[string]string{
+			"/home/nuclio/bin/processor.exe":         "/usr/local/bin/processor.exe",
+			"/home/nuclio/bin/wrapper":               "/opt/nuclio/wrapper",
+			"/home/nuclio/bin/handler":               "/opt/nuclio/handler",
+			"/home/nuclio/src/nuclio-sdk-dotnetcore": "/opt/nuclio/nuclio-sdk-dotnetcore",
+		},
+	}
+	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+	processorDockerfileInfo.BaseImage = "mcr.microsoft.com/dotnet/core/runtime:3.1-nanoserver-1809"
+
+	return &processorDockerfileInfo, nil
+}
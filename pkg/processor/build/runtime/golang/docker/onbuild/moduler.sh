@@ -30,8 +30,22 @@ if [ ! -f "go.mod" ]; then
 	mv /processor_go.sum go.sum
 fi
 
+# offline builds resolve modules from the build cache (nuctl --build-cache-dir), if one is given
+if [ "${NUCLIO_BUILD_OFFLINE}" = "true" ]; then
+	export GOFLAGS=-mod=mod
+	export GOSUMDB=off
+	export GOPROXY=off
+
+	if [ -d .nuclio-build-cache/go ]; then
+		export GOPROXY="file://$(pwd)/.nuclio-build-cache/go"
+	fi
+fi
+
 # download missing modules & remove unused modules
 go mod tidy
 
+# the modules are in the module cache now
+rm -rf .nuclio-build-cache
+
 # Removing breadcrums
 rm -rf /processor_go.mod /processor_go.sum
@@ -16,6 +16,13 @@
 
 set -e
 
+# if the user passed a gradle / maven project, build it with the scripts the builder created for it
+if [ -f /home/gradle/src/userHandler/nuclio-build-project.sh ]; then
+    cd /home/gradle/src/userHandler
+    sh ./nuclio-build-project.sh
+    exit 0
+fi
+
 # count how many jars there are in in /home/gradle/src/userHandler/src/main/java (this is where the onbuild docker
 # image puts the user provided files). if the user passed source, this should be 0. if the user
 # passed a jar, this should be one. if it's neither, give up
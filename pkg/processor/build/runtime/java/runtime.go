@@ -20,8 +20,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"text/template"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -43,8 +45,18 @@ func (j *java) GetName() string {
 // OnAfterStagingDirCreated will build jar if the source is a Java file
 // It will set generatedJarPath field
 func (j *java) OnAfterStagingDirCreated(stagingDir string) error {
+	buildAttributes, err := newBuildAttributes(j.FunctionConfig.Spec.Build.RuntimeAttributes)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get build attributes")
+	}
+
+	// if the user's code is a gradle / maven project, create the scripts building it into the handler jar
+	if buildAttributes.isProjectBuild() {
+		return j.createProjectBuildScripts(stagingDir, buildAttributes)
+	}
+
 	// create a build script alongside the user's code. if user provided a script, it'll use that
-	return j.createGradleBuildScript(stagingDir)
+	return j.createGradleBuildScript(stagingDir, buildAttributes)
 }
 
 // GetProcessorDockerfileInfo returns information required to build the processor Dockerfile
@@ -71,7 +83,7 @@ func (j *java) GetProcessorDockerfileInfo(versionInfo *version.Info,
 	return &processorDockerfileInfo, nil
 }
 
-func (j *java) createGradleBuildScript(stagingBuildDir string) error {
+func (j *java) createGradleBuildScript(stagingBuildDir string, buildAttributes *buildAttributes) error {
 	handlerPath := path.Join(stagingBuildDir, "handler")
 
 	// if user supplied gradle build script - use it
@@ -95,7 +107,7 @@ func (j *java) createGradleBuildScript(stagingBuildDir string) error {
 		return nil
 	}
 
-	gradleBuildScriptTemplate, err := template.New("gradleBuildScript").Parse(j.getGradleBuildScriptTemplateContents())
+	gradleBuildScriptTemplate, err := j.parseTemplate("gradleBuildScript", j.getGradleBuildScriptTemplateContents())
 	if err != nil {
 		return errors.Wrap(err, "Failed to create gradle build script template")
 	}
@@ -112,14 +124,11 @@ func (j *java) createGradleBuildScript(stagingBuildDir string) error {
 		return errors.Wrap(err, "Failed to parse dependencies")
 	}
 
-	buildAttributes, err := newBuildAttributes(j.FunctionConfig.Spec.Build.RuntimeAttributes)
-	if err != nil {
-		return errors.Wrap(err, "Failed to get build attributes repositories")
-	}
-
 	data := map[string]interface{}{
-		"Dependencies": dependencies,
-		"Repositories": buildAttributes.Repositories,
+		"Dependencies":      dependencies,
+		"Repositories":      buildAttributes.Repositories,
+		"MavenRepositories": buildAttributes.MavenRepositories,
+		"Relocations":       buildAttributes.Relocations,
 	}
 
 	var gradleBuildScriptTemplateBuffer bytes.Buffer
@@ -143,6 +152,7 @@ repositories {
 	{{ range .Repositories }}
 	{{ . }}
 	{{ end }}
+	{{ template "mavenRepositories" .MavenRepositories }}
 }
 
 dependencies {
@@ -156,12 +166,116 @@ dependencies {
 shadowJar {
    baseName = 'user-handler'
    classifier = null  // Don't append "all" to jar name
+   {{ template "relocations" .Relocations }}
 }
 
 task userHandler(dependsOn: shadowJar)
 `
 }
 
+// createProjectBuildScripts creates the scripts building the user's gradle / maven project (or one of its
+// modules) and shading the module's jar with its runtime dependencies into the handler jar
+func (j *java) createProjectBuildScripts(stagingBuildDir string, buildAttributes *buildAttributes) error {
+	handlerPath := path.Join(stagingBuildDir, "handler")
+
+	buildTool, err := j.getProjectBuildTool(handlerPath, buildAttributes)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get project build tool")
+	}
+
+	if !common.IsDir(path.Join(handlerPath, buildAttributes.Module)) {
+		return errors.Errorf("Module %s not found in function's directory", buildAttributes.Module)
+	}
+
+	data := map[string]interface{}{
+		"BuildTool":         buildTool,
+		"Module":            buildAttributes.Module,
+		"ModuleDir":         path.Join(".", buildAttributes.Module),
+		"GradleProjectPath": buildAttributes.getGradleProjectPath(),
+		"GradleTask":        strings.TrimSuffix(buildAttributes.getGradleProjectPath(), ":") + ":nuclioCollectHandler",
+		"Repositories":      buildAttributes.Repositories,
+		"MavenRepositories": buildAttributes.MavenRepositories,
+		"Relocations":       buildAttributes.Relocations,
+	}
+
+	scripts := map[string]string{
+		"nuclio-build-project.sh":      projectBuildScriptTemplate,
+		"nuclio-shade/build.gradle":    shadeBuildScriptTemplate,
+		"nuclio-shade/settings.gradle": shadeSettingsScriptTemplate,
+	}
+
+	if buildTool == buildToolMaven {
+		scripts["nuclio-settings.xml"] = mavenSettingsTemplate
+	} else {
+		scripts["nuclio-init.gradle"] = gradleInitScriptTemplate
+	}
+
+	for scriptPath, scriptTemplateContents := range scripts {
+		scriptTemplate, err := j.parseTemplate(scriptPath, scriptTemplateContents)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create %s template", scriptPath)
+		}
+
+		var scriptBuffer bytes.Buffer
+		if err := scriptTemplate.Execute(&scriptBuffer, data); err != nil {
+			return errors.Wrapf(err, "Failed to render %s", scriptPath)
+		}
+
+		scriptPath = path.Join(handlerPath, scriptPath)
+		if err := os.MkdirAll(path.Dir(scriptPath), 0755); err != nil {
+			return errors.Wrapf(err, "Failed to create directory of %s", scriptPath)
+		}
+
+		scriptMode := os.FileMode(0644)
+		if strings.HasSuffix(scriptPath, ".sh") {
+			scriptMode = 0755
+		}
+
+		if err := ioutil.WriteFile(scriptPath, scriptBuffer.Bytes(), scriptMode); err != nil {
+			return errors.Wrapf(err, "Failed to write %s", scriptPath)
+		}
+	}
+
+	j.Logger.DebugWith("Created project build scripts",
+		"buildTool", buildTool,
+		"module", buildAttributes.Module,
+		"relocations", buildAttributes.Relocations)
+
+	return nil
+}
+
+func (j *java) getProjectBuildTool(handlerPath string, buildAttributes *buildAttributes) (string, error) {
+	if buildAttributes.BuildTool != "" {
+		return buildAttributes.BuildTool, nil
+	}
+
+	if common.IsFile(path.Join(handlerPath, "pom.xml")) {
+		return buildToolMaven, nil
+	}
+
+	for _, gradleScriptName := range []string{
+		"settings.gradle",
+		"settings.gradle.kts",
+		"build.gradle",
+		"build.gradle.kts",
+	} {
+		if common.IsFile(path.Join(handlerPath, gradleScriptName)) {
+			return buildToolGradle, nil
+		}
+	}
+
+	return "", errors.New("Function's directory is neither a gradle nor a maven project")
+}
+
+func (j *java) parseTemplate(name string, contents string) (*template.Template, error) {
+	return template.New(name).
+		Funcs(template.FuncMap{
+			"groovy": quoteGroovyString,
+			"xml":    template.HTMLEscapeString,
+		}).
+		Parse(contents + sharedTemplates)
+}
+
 func (j *java) parseDependencies(rawDependencies []string) ([]dependency, error) {
 	var dependencies []dependency
 
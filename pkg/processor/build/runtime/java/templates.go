@@ -0,0 +1,154 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package java
+
+import "strings"
+
+// templates shared by the gradle scripts
+const sharedTemplates = `
+{{ define "mavenRepositories" }}{{ range . }}
+	maven {
+		url {{ groovy .URL }}
+		{{ if .Username }}credentials {
+			username {{ groovy .Username }}
+			password {{ groovy .Password }}
+		}{{ end }}
+	}
+{{ end }}{{ end }}
+{{ define "relocations" }}{{ range . }}
+   relocate {{ groovy .Pattern }}, {{ groovy .ShadedPattern }}
+{{ end }}{{ end }}
+`
+
+// builds the user's project and collects the module's jar along with its runtime dependencies to nuclio-shade/libs,
+// then shades them into the handler jar where build-user-handler.sh expects it
+const projectBuildScriptTemplate = `#!/usr/bin/env sh
+
+set -e
+
+mkdir -p nuclio-shade/libs
+{{ if eq .BuildTool "maven" }}
+mvn_command=mvn
+if [ -x ./mvnw ]; then
+    mvn_command=./mvnw
+fi
+
+$mvn_command --batch-mode --settings nuclio-settings.xml \
+    {{ if .Module }}--projects {{ .Module }} --also-make {{ end }}-DskipTests \
+    -DincludeScope=runtime -DoutputDirectory=$(pwd)/nuclio-shade/libs \
+    package dependency:copy-dependencies
+
+find {{ .ModuleDir }}/target -maxdepth 1 -name '*.jar' \
+    ! -name '*-sources.jar' ! -name '*-javadoc.jar' ! -name '*-tests.jar' \
+    -exec cp {} nuclio-shade/libs \;
+{{ else }}
+gradle_command=gradle
+if [ -x ./gradlew ]; then
+    gradle_command=./gradlew
+fi
+
+$gradle_command --init-script nuclio-init.gradle {{ .GradleTask }}
+{{ end }}
+cd nuclio-shade
+gradle userHandler
+
+mkdir -p ../build/libs
+cp build/libs/user-handler.jar ../build/libs/user-handler.jar
+`
+
+// adds the function's repositories to the user's gradle project, and a task collecting the module's jars
+const gradleInitScriptTemplate = `allprojects {
+    repositories {
+	{{ range .Repositories }}
+	{{ . }}
+	{{ end }}
+	{{ template "mavenRepositories" .MavenRepositories }}
+    }
+
+    afterEvaluate { project ->
+        if (project.path == {{ groovy .GradleProjectPath }}) {
+            project.task('nuclioCollectHandler', type: Copy, dependsOn: 'jar') {
+                from project.jar
+                from project.configurations.runtimeClasspath
+                into "${project.rootDir}/nuclio-shade/libs"
+            }
+        }
+    }
+}
+`
+
+// adds the function's repositories (and their credentials) to the user's maven project
+const mavenSettingsTemplate = `<settings>
+  <servers>
+  {{ range $index, $repository := .MavenRepositories }}{{ if $repository.Username }}
+    <server>
+      <id>nuclio-{{ $index }}</id>
+      <username>{{ xml $repository.Username }}</username>
+      <password>{{ xml $repository.Password }}</password>
+    </server>
+  {{ end }}{{ end }}
+  </servers>
+  <profiles>
+    <profile>
+      <id>nuclio</id>
+      <repositories>
+      {{ range $index, $repository := .MavenRepositories }}
+        <repository>
+          <id>nuclio-{{ $index }}</id>
+          <url>{{ xml $repository.URL }}</url>
+        </repository>
+      {{ end }}
+      </repositories>
+    </profile>
+  </profiles>
+  <activeProfiles>
+    <activeProfile>nuclio</activeProfile>
+  </activeProfiles>
+</settings>
+`
+
+// shades the collected jars into the handler jar. the SDK is taken from the onbuild image rather than
+// the project's dependencies
+const shadeBuildScriptTemplate = `plugins {
+  id 'com.github.johnrengelman.shadow' version '2.0.2'
+  id 'java'
+}
+
+dependencies {
+    compile fileTree(dir: 'libs', include: '*.jar', exclude: 'nuclio-sdk*.jar')
+    compile files('../nuclio-sdk-1.0-SNAPSHOT.jar')
+}
+
+shadowJar {
+   baseName = 'user-handler'
+   classifier = null  // Don't append "all" to jar name
+   {{ template "relocations" .Relocations }}
+}
+
+task userHandler(dependsOn: shadowJar)
+`
+
+// keeps gradle from treating the shading build as part of the user's project
+const shadeSettingsScriptTemplate = `rootProject.name = 'user-handler'
+`
+
+func quoteGroovyString(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `'`, `\'`, -1)
+
+	return "'" + value + "'"
+}
@@ -18,6 +18,8 @@ package java
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/mitchellh/mapstructure"
@@ -43,8 +45,32 @@ func newDependency(raw string) (*dependency, error) {
 	return &newDependency, nil
 }
 
+const (
+	buildToolGradle = "gradle"
+	buildToolMaven  = "maven"
+)
+
+type mavenRepository struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// relocation moves the packages of dependencies conflicting with those of the nuclio SDK
+type relocation struct {
+	Pattern       string
+	ShadedPattern string
+}
+
 type buildAttributes struct {
-	Repositories []string
+	Repositories      []string
+	MavenRepositories []mavenRepository
+
+	// build an existing Gradle / Maven project (or one of its modules) rather than a single handler
+	BuildTool string
+	Module    string
+
+	Relocations []relocation
 }
 
 func newBuildAttributes(encodedBuildAttributes map[string]interface{}) (*buildAttributes, error) {
@@ -61,5 +87,59 @@ func newBuildAttributes(encodedBuildAttributes map[string]interface{}) (*buildAt
 		}
 	}
 
+	if err := newBuildAttributes.validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid build attributes")
+	}
+
 	return &newBuildAttributes, nil
 }
+
+// isProjectBuild returns whether the handler is an existing Gradle / Maven project
+func (ba *buildAttributes) isProjectBuild() bool {
+	return ba.BuildTool != "" || ba.Module != ""
+}
+
+// getGradleProjectPath returns the gradle path of the module (e.g. "services/orders" is ":services:orders")
+func (ba *buildAttributes) getGradleProjectPath() string {
+	return ":" + strings.Replace(ba.Module, "/", ":", -1)
+}
+
+func (ba *buildAttributes) validate() error {
+	switch ba.BuildTool {
+	case "", buildToolGradle, buildToolMaven:
+	default:
+		return errors.Errorf("Unsupported build tool: %s", ba.BuildTool)
+	}
+
+	if ba.Module != "" {
+		ba.Module = strings.Trim(path.Clean(ba.Module), "/")
+		if path.IsAbs(ba.Module) || ba.Module == ".." || strings.HasPrefix(ba.Module, "../") {
+			return errors.Errorf("Module must be a path within the function's directory: %s", ba.Module)
+		}
+
+		// the root project
+		if ba.Module == "." {
+			ba.Module = ""
+		}
+	}
+
+	for _, repository := range ba.MavenRepositories {
+		if repository.URL == "" {
+			return errors.New("Maven repositories require a URL")
+		}
+	}
+
+	for _, relocation := range ba.Relocations {
+		if relocation.Pattern == "" || relocation.ShadedPattern == "" {
+			return errors.New("Relocations require a pattern and a shaded pattern")
+		}
+
+		// the processor's wrapper loads the handler through the SDK's classes
+		if strings.HasPrefix("io.nuclio", relocation.Pattern) ||
+			strings.HasPrefix(relocation.Pattern, "io.nuclio") {
+			return errors.Errorf("The nuclio SDK can't be relocated: %s", relocation.Pattern)
+		}
+	}
+
+	return nil
+}
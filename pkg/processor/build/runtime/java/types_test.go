@@ -17,8 +17,15 @@ limitations under the Licensg.
 package java
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -36,6 +43,107 @@ func (suite *testSuite) TestSuccessfulParseDependencies() {
 	suite.Require().Equal("0.2.1", dep.Version)
 }
 
+func (suite *testSuite) TestBuildAttributes() {
+	buildAttributes, err := newBuildAttributes(map[string]interface{}{
+		"buildTool": "maven",
+		"module":    "services/orders/",
+		"mavenRepositories": []interface{}{
+			map[string]interface{}{"url": "https://repo.local/maven", "username": "user", "password": "pass"},
+		},
+		"relocations": []interface{}{
+			map[string]interface{}{"pattern": "com.google.gson", "shadedPattern": "shaded.com.google.gson"},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(buildAttributes.isProjectBuild())
+	suite.Require().Equal("services/orders", buildAttributes.Module)
+	suite.Require().Equal(":services:orders", buildAttributes.getGradleProjectPath())
+	suite.Require().Equal("pass", buildAttributes.MavenRepositories[0].Password)
+	suite.Require().Equal("shaded.com.google.gson", buildAttributes.Relocations[0].ShadedPattern)
+
+	for _, invalidAttributes := range []map[string]interface{}{
+		{"buildTool": "ant"},
+		{"module": "../other"},
+		{"mavenRepositories": []interface{}{map[string]interface{}{"username": "user"}}},
+		{"relocations": []interface{}{map[string]interface{}{"pattern": "io.nuclio", "shadedPattern": "x.io.nuclio"}}},
+		{"relocations": []interface{}{map[string]interface{}{"pattern": "io", "shadedPattern": "x.io"}}},
+	} {
+		_, err = newBuildAttributes(invalidAttributes)
+		suite.Require().Error(err, "Attributes: %v", invalidAttributes)
+	}
+
+	// not a project build
+	buildAttributes, err = newBuildAttributes(nil)
+	suite.Require().NoError(err)
+	suite.Require().False(buildAttributes.isProjectBuild())
+}
+
+func (suite *testSuite) TestCreateProjectBuildScripts() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	stagingDir, err := ioutil.TempDir("", "nuclio-java-test-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(stagingDir) // nolint: errcheck
+
+	handlerPath := path.Join(stagingDir, "handler")
+	suite.Require().NoError(os.MkdirAll(path.Join(handlerPath, "services", "orders"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(path.Join(handlerPath, "settings.gradle"), []byte(""), 0644))
+
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Spec.Build.RuntimeAttributes = map[string]interface{}{
+		"module": "services/orders",
+		"mavenRepositories": []interface{}{
+			map[string]interface{}{"url": "https://repo.local/maven", "username": "user", "password": "it's"},
+		},
+		"relocations": []interface{}{
+			map[string]interface{}{"pattern": "com.google.gson", "shadedPattern": "shaded.com.google.gson"},
+		},
+	}
+
+	javaRuntime := &java{
+		AbstractRuntime: &runtime.AbstractRuntime{
+			Logger:         loggerInstance,
+			StagingDir:     stagingDir,
+			FunctionConfig: functionConfig,
+		},
+	}
+
+	suite.Require().NoError(javaRuntime.OnAfterStagingDirCreated(stagingDir))
+
+	// the project is detected as a gradle project, so no maven settings are created
+	suite.Require().False(suite.fileExists(handlerPath, "nuclio-settings.xml"))
+	suite.Require().False(suite.fileExists(handlerPath, "build.gradle"))
+
+	buildScript := suite.readFile(handlerPath, "nuclio-build-project.sh")
+	suite.Require().Contains(buildScript, "--init-script nuclio-init.gradle :services:orders:nuclioCollectHandler")
+
+	initScript := suite.readFile(handlerPath, "nuclio-init.gradle")
+	suite.Require().Contains(initScript, "if (project.path == ':services:orders')")
+	suite.Require().Contains(initScript, "url 'https://repo.local/maven'")
+	suite.Require().Contains(initScript, `password 'it\'s'`)
+
+	shadeScript := suite.readFile(handlerPath, "nuclio-shade/build.gradle")
+	suite.Require().Contains(shadeScript, "relocate 'com.google.gson', 'shaded.com.google.gson'")
+	suite.Require().True(suite.fileExists(handlerPath, "nuclio-shade/settings.gradle"))
+
+	// a missing module fails the build
+	functionConfig.Spec.Build.RuntimeAttributes["module"] = "services/missing"
+	suite.Require().Error(javaRuntime.OnAfterStagingDirCreated(stagingDir))
+}
+
+func (suite *testSuite) readFile(dir string, name string) string {
+	contents, err := ioutil.ReadFile(path.Join(dir, name))
+	suite.Require().NoError(err)
+
+	return string(contents)
+}
+
+func (suite *testSuite) fileExists(dir string, name string) bool {
+	_, err := os.Stat(path.Join(dir, name))
+	return err == nil
+}
+
 func TestBuilderSuite(t *testing.T) {
 	if testing.Short() {
 		return
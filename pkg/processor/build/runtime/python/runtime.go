@@ -17,16 +17,46 @@ limitations under the License.
 package python
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
 	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/version"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+
+	// the requirements are staged outside the handler dir, so that they're copied into the image (and installed)
+	// before the handler is - changing the function's code doesn't invalidate the layer they're installed in
+	requirementsDirInStaging = "python-requirements"
+	requirementsDirInImage   = "/opt/nuclio/python-requirements"
+	requirementsFileName     = "requirements.txt"
+	constraintsFileName      = "constraints.txt"
+
+	// virtualenvs are keyed by the hash of what's installed in them
+	virtualenvsDirInImage = "/opt/nuclio/venvs"
+
+	// the length of the hash of the requirements in the virtualenv's path
+	requirementsHashLength = 16
 )
 
 type python struct {
 	*runtime.AbstractRuntime
+
+	// set once the staging dir is created, if the function has requirements to install
+	requirementsHash string
+	buildAttributes  *buildAttributes
+	extraIndexURLs   []string
 }
 
 // GetName returns the name of the runtime, including version if applicable
@@ -69,6 +99,7 @@ func (p *python) GetProcessorDockerfileInfo(versionInfo *version.Info,
 	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
 
 	processorDockerfileInfo.Directives = map[string][]functionconfig.Directive{
+		"preCopy": p.getRequirementsDirectives(),
 		"postCopy": {
 			{
 				Kind: "RUN",
@@ -81,3 +112,163 @@ func (p *python) GetProcessorDockerfileInfo(versionInfo *version.Info,
 
 	return &processorDockerfileInfo, nil
 }
+
+// OnAfterStagingDirCreated stages the function's requirements (and constraints), to be installed in a layer of
+// their own, and resolves the credentials of the extra indexes they're installed from
+func (p *python) OnAfterStagingDirCreated(stagingDir string) error {
+	buildAttributes, err := newBuildAttributes(p.FunctionConfig.Spec.Build.RuntimeAttributes)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get build attributes")
+	}
+
+	p.requirementsHash = ""
+	p.extraIndexURLs = nil
+
+	handlerDir := path.Join(stagingDir, "handler")
+	requirementsPath := path.Join(handlerDir, buildAttributes.RequirementsPath)
+	if buildAttributes.SkipRequirements || !common.IsFile(requirementsPath) {
+		p.Logger.DebugWith("No requirements to install", "requirementsPath", buildAttributes.RequirementsPath)
+		return nil
+	}
+
+	requirementsDir := path.Join(stagingDir, requirementsDirInStaging)
+	if err := os.MkdirAll(requirementsDir, 0755); err != nil {
+		return errors.Wrap(err, "Failed to create requirements dir in staging")
+	}
+
+	stagedFiles := map[string]string{
+		requirementsFileName: requirementsPath,
+	}
+
+	if buildAttributes.ConstraintsPath != "" {
+		stagedFiles[constraintsFileName] = path.Join(handlerDir, buildAttributes.ConstraintsPath)
+	}
+
+	requirementsHash := sha256.New()
+	for _, stagedFileName := range []string{requirementsFileName, constraintsFileName} {
+		sourcePath, found := stagedFiles[stagedFileName]
+		if !found {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(sourcePath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read %s", sourcePath)
+		}
+
+		if err := ioutil.WriteFile(path.Join(requirementsDir, stagedFileName), contents, 0644); err != nil {
+			return errors.Wrapf(err, "Failed to stage %s", stagedFileName)
+		}
+
+		requirementsHash.Write([]byte(stagedFileName + "\n")) // nolint: errcheck
+		requirementsHash.Write(contents)                      // nolint: errcheck
+	}
+
+	// the indexes change what may be installed, unlike their credentials
+	for _, extraIndex := range buildAttributes.ExtraIndexes {
+		requirementsHash.Write([]byte(extraIndex.URL + "\n")) // nolint: errcheck
+
+		extraIndexURL, err := p.getExtraIndexURL(&extraIndex)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to resolve extra index %s", extraIndex.URL)
+		}
+
+		p.extraIndexURLs = append(p.extraIndexURLs, extraIndexURL)
+	}
+
+	p.buildAttributes = buildAttributes
+	p.requirementsHash = hex.EncodeToString(requirementsHash.Sum(nil))[:requirementsHashLength]
+
+	p.Logger.DebugWith("Staged requirements",
+		"requirementsPath", buildAttributes.RequirementsPath,
+		"constraintsPath", buildAttributes.ConstraintsPath,
+		"requirementsHash", p.requirementsHash)
+
+	return nil
+}
+
+// getExtraIndexURL returns the URL of the extra index, with the credentials of its secret (if any)
+func (p *python) getExtraIndexURL(extraIndex *extraIndex) (string, error) {
+	if extraIndex.CredentialsFrom == nil {
+		return extraIndex.URL, nil
+	}
+
+	if p.GetSecretData == nil {
+		return "", errors.New("Secrets can't be read by this build")
+	}
+
+	secretData, err := p.GetSecretData(extraIndex.CredentialsFrom.Name)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get secret %s", extraIndex.CredentialsFrom.Name)
+	}
+
+	credentials := map[string]string{}
+	for _, key := range []string{extraIndex.CredentialsFrom.UsernameKey, extraIndex.CredentialsFrom.PasswordKey} {
+		value, found := secretData[key]
+		if !found {
+			return "", errors.Errorf("Secret %s has no key %s", extraIndex.CredentialsFrom.Name, key)
+		}
+
+		credentials[key] = strings.TrimSpace(string(value))
+	}
+
+	parsedURL, err := url.Parse(extraIndex.URL)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse URL")
+	}
+
+	parsedURL.User = url.UserPassword(credentials[extraIndex.CredentialsFrom.UsernameKey],
+		credentials[extraIndex.CredentialsFrom.PasswordKey])
+
+	return parsedURL.String(), nil
+}
+
+// getRequirementsDirectives returns the directives installing the staged requirements - into a virtualenv keyed
+// by their hash, which sees the packages of the base image (e.g. the nuclio SDK). the layer is reused by
+// rebuilds as long as the requirements, the constraints and the indexes don't change
+func (p *python) getRequirementsDirectives() []functionconfig.Directive {
+	if p.requirementsHash == "" {
+		return nil
+	}
+
+	directives := []functionconfig.Directive{
+		{Kind: "COPY", Value: fmt.Sprintf("%s %s", requirementsDirInStaging, requirementsDirInImage)},
+	}
+
+	// a build arg rather than a flag, so that the credentials aren't in the command (or its logs). build args
+	// aren't kept in the processor image's environment
+	if len(p.extraIndexURLs) > 0 {
+		directives = append(directives, functionconfig.Directive{
+			Kind:  "ARG",
+			Value: fmt.Sprintf(`PIP_EXTRA_INDEX_URL="%s"`, strings.Join(p.extraIndexURLs, " ")),
+		})
+	}
+
+	pipInstallArgs := fmt.Sprintf("install --no-cache-dir -r %s/%s", requirementsDirInImage, requirementsFileName)
+	if p.buildAttributes.ConstraintsPath != "" {
+		pipInstallArgs += fmt.Sprintf(" -c %s/%s", requirementsDirInImage, constraintsFileName)
+	}
+
+	// python 2.7 images have no venv module
+	if p.buildAttributes.NoVirtualenv || p.FunctionConfig.Spec.Runtime == "python:2.7" {
+		return append(directives, functionconfig.Directive{
+			Kind:  "RUN",
+			Value: "pip " + pipInstallArgs,
+		})
+	}
+
+	virtualenvDir := path.Join(virtualenvsDirInImage, p.requirementsHash)
+
+	return append(directives,
+		functionconfig.Directive{
+			Kind: "RUN",
+			Value: fmt.Sprintf("python -m venv --system-site-packages %s && %s/bin/pip %s",
+				virtualenvDir,
+				virtualenvDir,
+				pipInstallArgs),
+		},
+		functionconfig.Directive{
+			Kind:  "ENV",
+			Value: fmt.Sprintf("NUCLIO_PYTHON_EXE_PATH=%s/bin/python", virtualenvDir),
+		})
+}
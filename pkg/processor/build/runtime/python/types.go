@@ -0,0 +1,122 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package python
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	defaultRequirementsPath = "requirements.txt"
+	defaultUsernameKey      = "username"
+	defaultPasswordKey      = "password"
+)
+
+// credentialsSecretRef references the keys of a secret holding the credentials of a package index - a
+// kubernetes secret in the function's namespace on kube, a directory under the secrets directory on local
+type credentialsSecretRef struct {
+	Name        string `mapstructure:"name"`
+	UsernameKey string `mapstructure:"usernameKey"`
+	PasswordKey string `mapstructure:"passwordKey"`
+}
+
+// extraIndex is a package index pip looks for the requirements in, on top of the main one
+type extraIndex struct {
+	URL             string                `mapstructure:"url"`
+	CredentialsFrom *credentialsSecretRef `mapstructure:"credentialsFrom"`
+}
+
+type buildAttributes struct {
+
+	// the requirements file and the constraints file, relative to the function's directory
+	RequirementsPath string `mapstructure:"requirementsPath"`
+	ConstraintsPath  string `mapstructure:"constraintsPath"`
+
+	// don't install the requirements file, e.g. when they're installed by the build commands
+	SkipRequirements bool `mapstructure:"skipRequirements"`
+
+	// install the requirements into the interpreter of the base image rather than into a virtualenv, for base
+	// images which can't create one
+	NoVirtualenv bool `mapstructure:"noVirtualenv"`
+
+	ExtraIndexes []extraIndex `mapstructure:"extraIndexes"`
+}
+
+func newBuildAttributes(encodedBuildAttributes map[string]interface{}) (*buildAttributes, error) {
+	newBuildAttributes := buildAttributes{}
+
+	// parse attributes
+	if err := mapstructure.Decode(encodedBuildAttributes, &newBuildAttributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode build attributes")
+	}
+
+	if newBuildAttributes.RequirementsPath == "" {
+		newBuildAttributes.RequirementsPath = defaultRequirementsPath
+	}
+
+	if err := newBuildAttributes.validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid build attributes")
+	}
+
+	return &newBuildAttributes, nil
+}
+
+func (ba *buildAttributes) validate() error {
+	for _, filePath := range []*string{&ba.RequirementsPath, &ba.ConstraintsPath} {
+		if *filePath == "" {
+			continue
+		}
+
+		*filePath = strings.TrimPrefix(path.Clean(*filePath), "/")
+		if *filePath == ".." || strings.HasPrefix(*filePath, "../") {
+			return errors.Errorf("Requirements and constraints must be files within the function's directory: %s",
+				*filePath)
+		}
+	}
+
+	for extraIndexIdx := range ba.ExtraIndexes {
+		extraIndex := &ba.ExtraIndexes[extraIndexIdx]
+
+		parsedURL, err := url.Parse(extraIndex.URL)
+		if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			return errors.Errorf("Extra indexes require a URL: %s", extraIndex.URL)
+		}
+
+		if extraIndex.CredentialsFrom == nil {
+			continue
+		}
+
+		if extraIndex.CredentialsFrom.Name == "" {
+			return errors.Errorf("The name of the secret of the credentials of %s must be given", extraIndex.URL)
+		}
+
+		if extraIndex.CredentialsFrom.UsernameKey == "" {
+			extraIndex.CredentialsFrom.UsernameKey = defaultUsernameKey
+		}
+
+		if extraIndex.CredentialsFrom.PasswordKey == "" {
+			extraIndex.CredentialsFrom.PasswordKey = defaultPasswordKey
+		}
+	}
+
+	return nil
+}
@@ -66,8 +66,15 @@ type Runtime interface {
 	// GetProcessorImageObjectPaths returns the paths of all objects that should reside in the handler
 	// directory
 	GetHandlerDirObjectPaths() []string
+
+	// SetSecretDataGetter provides the runtime with a getter of the secrets its build may read (e.g. the
+	// credentials of package indexes)
+	SetSecretDataGetter(secretDataGetter SecretDataGetter)
 }
 
+// SecretDataGetter returns the data of a secret in the function's namespace, by key
+type SecretDataGetter func(name string) (map[string][]byte, error)
+
 type Factory interface {
 	Create(logger.Logger, string, *functionconfig.Config) (Runtime, error)
 }
@@ -78,6 +85,9 @@ type AbstractRuntime struct {
 	FunctionConfig *functionconfig.Config
 	DockerClient   dockerclient.Client
 	CmdRunner      cmdrunner.CmdRunner
+
+	// GetSecretData is set by the builder once the runtime is created
+	GetSecretData SecretDataGetter
 }
 
 func NewAbstractRuntime(logger logger.Logger,
@@ -109,6 +119,10 @@ func (ar *AbstractRuntime) OnAfterStagingDirCreated(stagingDir string) error {
 	return nil
 }
 
+func (ar *AbstractRuntime) SetSecretDataGetter(secretDataGetter SecretDataGetter) {
+	ar.GetSecretData = secretDataGetter
+}
+
 // return a map of objects the runtime needs to copy into the processor image
 // the key can be a dir, a file or a url of a file
 // the value is an absolute path into the docker image
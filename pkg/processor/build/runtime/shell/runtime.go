@@ -19,6 +19,7 @@ package shell
 import (
 	"fmt"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/version"
 )
@@ -38,6 +39,10 @@ func (s *shell) GetProcessorDockerfileInfo(versionInfo *version.Info,
 
 	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{}
 
+	if s.FunctionConfig.Spec.Build.GetOS() == functionconfig.BuildOSWindows {
+		return s.getWindowsProcessorDockerfileInfo(versionInfo, onbuildImageRegistry)
+	}
+
 	// set the default base image
 	processorDockerfileInfo.BaseImage = "alpine:3.11"
 
@@ -61,6 +66,34 @@ func (s *shell) GetProcessorDockerfileInfo(versionInfo *version.Info,
 	return &processorDockerfileInfo, nil
 }
 
+// getWindowsProcessorDockerfileInfo returns the information of windows images. there's no windows processor
+// image, so the processor is supplied by a windows onbuild image of the runtime
+func (s *shell) getWindowsProcessorDockerfileInfo(versionInfo *version.Info,
+	onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+
+	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{}
+
+	processorDockerfileInfo.BaseImage = "mcr.microsoft.com/windows/nanoserver:1809"
+
+	artifact := runtime.Artifact{
+		Name: "shell-onbuild",
+		Image: fmt.Sprintf("%s/nuclio/handler-builder-shell-onbuild:%s-windows-%s",
+			onbuildImageRegistry,
+			versionInfo.Label,
+			versionInfo.Arch),
+		Paths: map[string]string{
+			"/home/nuclio/bin/processor.exe": "/usr/local/bin/processor.exe",
+		},
+	}
+	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+	processorDockerfileInfo.ImageArtifactPaths = map[string]string{
+		"handler": "/opt/nuclio",
+	}
+
+	return &processorDockerfileInfo, nil
+}
+
 // GetProcessorImageObjectPaths returns the paths of all objects that should reside in the handler
 // directory
 func (s *shell) GetHandlerDirObjectPaths() []string {
@@ -0,0 +1,96 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+	"github.com/nuclio/nuclio/pkg/version"
+
+	"github.com/nuclio/errors"
+)
+
+type wasm struct {
+	*runtime.AbstractRuntime
+}
+
+// GetName returns the name of the runtime, including version if applicable
+func (w *wasm) GetName() string {
+	return "wasm"
+}
+
+// DetectFunctionHandlers returns a list of all the handlers
+// in that directory given a path holding a function (or functions)
+func (w *wasm) DetectFunctionHandlers(functionPath string) ([]string, error) {
+	if !common.IsDir(functionPath) {
+		return w.AbstractRuntime.DetectFunctionHandlers(functionPath)
+	}
+
+	fileInfos, err := ioutil.ReadDir(functionPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list function directory")
+	}
+
+	var moduleNames []string
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() && filepath.Ext(fileInfo.Name()) == ".wasm" {
+			moduleNames = append(moduleNames, strings.TrimSuffix(fileInfo.Name(), ".wasm"))
+		}
+	}
+
+	if len(moduleNames) != 1 {
+		return nil, errors.Errorf("Expected a single module in %s, found %d - handler must be specified",
+			functionPath,
+			len(moduleNames))
+	}
+
+	return []string{fmt.Sprintf("%s:%s", moduleNames[0], "handler")}, nil
+}
+
+// GetProcessorDockerfileInfo returns information required to build the processor Dockerfile
+func (w *wasm) GetProcessorDockerfileInfo(versionInfo *version.Info,
+	onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+
+	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{}
+
+	// the processor embeds wasmtime and only needs glibc, so the image holds little more than it and the module
+	processorDockerfileInfo.BaseImage = "debian:buster-slim"
+
+	processorDockerfileInfo.ImageArtifactPaths = map[string]string{
+		"handler": "/opt/nuclio",
+	}
+
+	// fill onbuild artifact
+	artifact := runtime.Artifact{
+		Name: "wasm-onbuild",
+		Image: fmt.Sprintf("%s/nuclio/handler-builder-wasm-onbuild:%s-%s",
+			onbuildImageRegistry,
+			versionInfo.Label,
+			versionInfo.Arch),
+		Paths: map[string]string{
+			"/home/nuclio/bin/processor": "/usr/local/bin/processor",
+		},
+	}
+	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+	return &processorDockerfileInfo, nil
+}
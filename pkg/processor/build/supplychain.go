@@ -0,0 +1,194 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// the signature reference cosign triangulates for an image - the tag its signature is pushed to
+var cosignSignatureRegex = regexp.MustCompile(`^(.+):sha256-([a-f0-9]{64})\.sig$`)
+
+// the output formats and attestation predicate types of each SBOM format
+var sbomSyftOutputs = map[string]string{
+	functionconfig.SBOMFormatCycloneDX: "cyclonedx-json",
+	functionconfig.SBOMFormatSPDX:      "spdx-json",
+}
+
+var sbomCosignPredicateTypes = map[string]string{
+	functionconfig.SBOMFormatCycloneDX: "cyclonedx",
+	functionconfig.SBOMFormatSPDX:      "spdxjson",
+}
+
+// secureImage generates an SBOM of the built image and signs it, as configured. the digest and signature of
+// a pushed image are returned, or nil if the image was neither pushed nor signed
+func (b *Builder) secureImage(imageName string) (*functionconfig.ImageStatus, error) {
+	functionBuild := b.options.FunctionConfig.Spec.Build
+
+	if functionBuild.SBOMFormat == "" && !functionBuild.Sign {
+		return nil, nil
+	}
+
+	// an image that isn't pushed has no digest, and isn't signed
+	if functionBuild.Registry == "" {
+		if err := b.generateSBOM(fmt.Sprintf("docker:%s", imageName)); err != nil {
+			return nil, errors.Wrap(err, "Failed to generate SBOM")
+		}
+
+		return nil, nil
+	}
+
+	imageStatus, err := b.resolvePushedImage(fmt.Sprintf("%s/%s", functionBuild.Registry, imageName))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve pushed image")
+	}
+
+	// sign and generate the SBOM of the image by its digest, so that it's exactly the image that was built
+	// (and not whatever the tag refers to at the time)
+	if functionBuild.SBOMFormat != "" {
+		if err := b.generateSBOM(imageStatus.Digest); err != nil {
+			return nil, errors.Wrap(err, "Failed to generate SBOM")
+		}
+	}
+
+	if !functionBuild.Sign {
+		imageStatus.Signature = ""
+		return imageStatus, nil
+	}
+
+	if _, err := b.runSupplyChainCommand("cosign sign --yes %s%s", b.getSigningKeyArgument(), imageStatus.Digest); err != nil {
+		return nil, errors.Wrap(err, "Failed to sign image")
+	}
+
+	// the SBOM is attached to the image as a signed attestation, so that admission policies can verify it
+	if functionBuild.SBOMFormat != "" {
+		if _, err := b.runSupplyChainCommand("cosign attest --yes %s--type %s --predicate %s %s",
+			b.getSigningKeyArgument(),
+			sbomCosignPredicateTypes[functionBuild.SBOMFormat],
+			b.getSBOMPath(),
+			imageStatus.Digest); err != nil {
+			return nil, errors.Wrap(err, "Failed to attest SBOM")
+		}
+	}
+
+	b.logger.InfoWith("Signed image",
+		"digest", imageStatus.Digest,
+		"signature", imageStatus.Signature)
+
+	return imageStatus, nil
+}
+
+func (b *Builder) validateSupplyChainConfiguration() error {
+	functionBuild := b.options.FunctionConfig.Spec.Build
+
+	if _, found := sbomSyftOutputs[functionBuild.SBOMFormat]; functionBuild.SBOMFormat != "" && !found {
+		return errors.Errorf("Unsupported SBOM format %s, must be one of: %s",
+			functionBuild.SBOMFormat,
+			strings.Join(functionconfig.SBOMFormats, ", "))
+	}
+
+	// images are only pushed when there's a registry, and only pushed images can be signed
+	if functionBuild.Sign && functionBuild.Registry == "" {
+		return errors.New("Signing an image requires a registry to push it to")
+	}
+
+	if functionBuild.SigningKey != "" && !functionBuild.Sign {
+		return errors.New("A signing key can only be given when signing the image")
+	}
+
+	return nil
+}
+
+// resolvePushedImage returns the digest reference of a pushed image, and where its signature is (or would be)
+func (b *Builder) resolvePushedImage(image string) (*functionconfig.ImageStatus, error) {
+	output, err := b.runSupplyChainCommand("cosign triangulate %s", image)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to triangulate image")
+	}
+
+	signature := strings.TrimSpace(output)
+
+	match := cosignSignatureRegex.FindStringSubmatch(signature)
+	if match == nil {
+		return nil, errors.Errorf("Unexpected signature reference: %s", signature)
+	}
+
+	return &functionconfig.ImageStatus{
+		Digest:    fmt.Sprintf("%s@sha256:%s", match[1], match[2]),
+		Signature: signature,
+	}, nil
+}
+
+func (b *Builder) generateSBOM(source string) error {
+	functionBuild := b.options.FunctionConfig.Spec.Build
+
+	if _, err := b.runSupplyChainCommand("syft %s -o %s=%s",
+		source,
+		sbomSyftOutputs[functionBuild.SBOMFormat],
+		b.getSBOMPath()); err != nil {
+		return errors.Wrap(err, "Failed to run syft")
+	}
+
+	b.logger.InfoWith("Generated SBOM",
+		"format", functionBuild.SBOMFormat,
+		"path", b.getSBOMPath())
+
+	return nil
+}
+
+// getSBOMPath returns where the SBOM is written - <function name>.<format>.json in the working directory, unless
+// specified
+func (b *Builder) getSBOMPath() string {
+	if b.options.FunctionConfig.Spec.Build.SBOMPath != "" {
+		return b.options.FunctionConfig.Spec.Build.SBOMPath
+	}
+
+	return fmt.Sprintf("%s.%s.json", b.GetFunctionName(), b.options.FunctionConfig.Spec.Build.SBOMFormat)
+}
+
+// getSigningKeyArgument returns cosign's key argument, followed by a space. without a key, cosign signs keyless
+// (with a short lived certificate issued for the identity it authenticates with)
+func (b *Builder) getSigningKeyArgument() string {
+	if b.options.FunctionConfig.Spec.Build.SigningKey == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("--key %s ", b.options.FunctionConfig.Spec.Build.SigningKey)
+}
+
+func (b *Builder) runSupplyChainCommand(format string, vars ...interface{}) (string, error) {
+	cmdRunner, err := b.getCmdRunner()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get command runner")
+	}
+
+	runResult, err := cmdRunner.Run(&cmdrunner.RunOptions{
+		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+	}, format, vars...)
+	if err != nil {
+		return "", errors.Wrapf(err, "Command failed: %s", runResult.Stderr)
+	}
+
+	return runResult.Output, nil
+}
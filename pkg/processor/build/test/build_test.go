@@ -659,6 +659,8 @@ HEALTHCHECK --interval=1s --timeout=3s CMD /usr/local/bin/uhttpc --url http://12
 # Run the post-copy directives
 postCopyKind1 postCopyValue1
 postCopyKind2 postCopyValue2
+# The processor and runtimes write temporary files only under /tmp, so that the root filesystem may be read-only
+ENV TMPDIR=/tmp PYTHONDONTWRITEBYTECODE=1
 # Run processor with configuration and platform configuration
 CMD [ "processor" ]`)
 
@@ -707,6 +709,8 @@ COPY imageLocal2 imageImage2
 # Run the post-copy directives
 postCopyKind1 postCopyValue1
 postCopyKind2 postCopyValue2
+# The processor and runtimes write temporary files only under /tmp, so that the root filesystem may be read-only
+ENV TMPDIR=/tmp PYTHONDONTWRITEBYTECODE=1
 # Run processor with configuration and platform configuration
 CMD [ "processor" ]`)
 }
@@ -792,6 +796,8 @@ HEALTHCHECK --interval=1s --timeout=3s CMD /usr/local/bin/uhttpc --url http://12
 # Run the post-copy directives
 postCopyKind1 postCopyValue1
 postCopyKind2 postCopyValue2
+# The processor and runtimes write temporary files only under /tmp, so that the root filesystem may be read-only
+ENV TMPDIR=/tmp PYTHONDONTWRITEBYTECODE=1
 # Run processor with configuration and platform configuration
 CMD [ "processor" ]`)
 
@@ -843,6 +849,8 @@ COPY imageLocal2 imageImage2
 # Run the post-copy directives
 postCopyKind1 postCopyValue1
 postCopyKind2 postCopyValue2
+# The processor and runtimes write temporary files only under /tmp, so that the root filesystem may be read-only
+ENV TMPDIR=/tmp PYTHONDONTWRITEBYTECODE=1
 # Run processor with configuration and platform configuration
 CMD [ "processor" ]`)
 }
@@ -146,3 +146,53 @@ func CopyDir(source string, dest string) (bool, error) {
 	}
 	return true, nil
 }
+
+// CopyDirWithIgnore recursively copies a directory tree like CopyDir, skipping the files and directories
+// matched by the source directory's .nuclioignore file. The ignore file itself is not copied
+func CopyDirWithIgnore(source string, dest string) error {
+	ignoreMatcher, err := ReadIgnoreFile(source)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read ignore file")
+	}
+
+	return copyDirWithIgnore(source, dest, "", ignoreMatcher)
+}
+
+func copyDirWithIgnore(source string, dest string, relativeDir string, ignoreMatcher *IgnoreMatcher) error {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dest, fi.Mode()); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		relativePath := path.Join(relativeDir, entry.Name())
+
+		if relativePath == IgnoreFileName || ignoreMatcher.Matches(relativePath, entry.IsDir()) {
+			continue
+		}
+
+		sfp := filepath.Join(source, entry.Name())
+		dfp := filepath.Join(dest, entry.Name())
+
+		if entry.IsDir() {
+			err = copyDirWithIgnore(sfp, dfp, relativePath, ignoreMatcher)
+		} else {
+			err = CopyFile(sfp, dfp)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
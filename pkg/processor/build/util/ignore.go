@@ -0,0 +1,141 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/errors"
+)
+
+// IgnoreFileName is the name of the file listing paths to exclude from a function's build context
+const IgnoreFileName = ".nuclioignore"
+
+type ignorePattern struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreMatcher matches paths against .nuclioignore patterns. The syntax follows .gitignore - blank lines
+// and lines starting with # are skipped, a leading ! re-includes a path, a trailing / matches only
+// directories and a pattern containing a / is matched against the full path relative to the root
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// NewIgnoreMatcher creates a matcher from a list of patterns
+func NewIgnoreMatcher(lines []string) *IgnoreMatcher {
+	matcher := &IgnoreMatcher{}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := ignorePattern{}
+
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			pattern.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		pattern.pattern = line
+		matcher.patterns = append(matcher.patterns, pattern)
+	}
+
+	return matcher
+}
+
+// ReadIgnoreFile reads the .nuclioignore file in the given directory. If the directory holds no such file,
+// an empty matcher is returned
+func ReadIgnoreFile(dir string) (*IgnoreMatcher, error) {
+	ignoreFilePath := filepath.Join(dir, IgnoreFileName)
+	if !common.FileExists(ignoreFilePath) {
+		return NewIgnoreMatcher(nil), nil
+	}
+
+	ignoreFile, err := os.Open(ignoreFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open %s", ignoreFilePath)
+	}
+
+	defer ignoreFile.Close() // nolint: errcheck
+
+	var lines []string
+	scanner := bufio.NewScanner(ignoreFile)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", ignoreFilePath)
+	}
+
+	return NewIgnoreMatcher(lines), nil
+}
+
+// Matches returns whether a path (relative to the directory holding the ignore file) should be ignored
+func (im *IgnoreMatcher) Matches(relativePath string, isDir bool) bool {
+	relativePath = filepath.ToSlash(filepath.Clean(relativePath))
+	ignored := false
+
+	// the last matching pattern wins, so that a later negation can re-include a path
+	for _, pattern := range im.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		if pattern.matches(relativePath) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+func (ip *ignorePattern) matches(relativePath string) bool {
+	if ip.anchored {
+		matched, _ := path.Match(ip.pattern, relativePath)
+		return matched
+	}
+
+	matched, _ := path.Match(ip.pattern, path.Base(relativePath))
+	return matched
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ignoreTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ignoreTestSuite) TestMatches() {
+	matcher := NewIgnoreMatcher([]string{
+		"# comment",
+		"",
+		"*.pyc",
+		"tests/",
+		"/build/output",
+		"*.log",
+		"!keep.log",
+	})
+
+	for _, testCase := range []struct {
+		path            string
+		isDir           bool
+		expectedIgnored bool
+	}{
+		{path: "main.py"},
+		{path: "main.pyc", expectedIgnored: true},
+		{path: "lib/util.pyc", expectedIgnored: true},
+		{path: "tests", isDir: true, expectedIgnored: true},
+		{path: "lib/tests", isDir: true, expectedIgnored: true},
+		{path: "tests"},
+		{path: "build/output", expectedIgnored: true},
+		{path: "lib/build/output"},
+		{path: "debug.log", expectedIgnored: true},
+		{path: "keep.log"},
+	} {
+		suite.Require().Equal(testCase.expectedIgnored,
+			matcher.Matches(testCase.path, testCase.isDir),
+			"Unexpected result for %s", testCase.path)
+	}
+}
+
+func (suite *ignoreTestSuite) TestCopyDirWithIgnore() {
+	sourceDir, err := ioutil.TempDir("", "nuclio-ignore-test-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(sourceDir) // nolint: errcheck
+
+	destDir, err := ioutil.TempDir("", "nuclio-ignore-test-")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(destDir) // nolint: errcheck
+
+	for _, filePath := range []string{
+		"main.py",
+		"lib/util.py",
+		"venv/bin/python",
+		"data.csv",
+	} {
+		suite.Require().NoError(os.MkdirAll(filepath.Dir(filepath.Join(sourceDir, filePath)), 0755))
+		suite.Require().NoError(ioutil.WriteFile(filepath.Join(sourceDir, filePath), []byte{}, 0644))
+	}
+
+	err = ioutil.WriteFile(filepath.Join(sourceDir, IgnoreFileName), []byte("venv/\n*.csv\n"), 0644)
+	suite.Require().NoError(err)
+
+	err = CopyDirWithIgnore(sourceDir, destDir)
+	suite.Require().NoError(err)
+
+	suite.Require().True(common.FileExists(filepath.Join(destDir, "main.py")))
+	suite.Require().True(common.FileExists(filepath.Join(destDir, "lib", "util.py")))
+	suite.Require().False(common.FileExists(filepath.Join(destDir, "venv")))
+	suite.Require().False(common.FileExists(filepath.Join(destDir, "data.csv")))
+	suite.Require().False(common.FileExists(filepath.Join(destDir, IgnoreFileName)))
+}
+
+func TestIgnoreTestSuite(t *testing.T) {
+	suite.Run(t, new(ignoreTestSuite))
+}
@@ -19,12 +19,58 @@ package cloudevent
 import (
 	"time"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// the prefix of the headers holding the attributes of a binary 1.0 event. kafka headers are prefixed with
+// ce_ rather than ce-
+const headerPrefix = "ce-"
+const kafkaHeaderPrefix = "ce_"
+
+// the names of the headers holding the attributes of a binary event
+type binaryHeaderNames struct {
+	id          string
+	source      string
+	name        string
+	time        string
+	eventType   string
+	typeVersion string
+	version     string
+}
+
+var binaryHeaderNamesV01 = binaryHeaderNames{
+	id:          "CE-EventID",
+	source:      "CE-Source",
+	name:        "CE-Name",
+	time:        "CE-EventTime",
+	eventType:   "CE-EventType",
+	typeVersion: "CE-EventTypeVersion",
+	version:     "CE-CloudEventsVersion",
+}
+
+func newBinaryHeaderNamesV1(prefix string) binaryHeaderNames {
+	return binaryHeaderNames{
+		id:        prefix + "id",
+		source:    prefix + "source",
+		name:      prefix + "source",
+		time:      prefix + "time",
+		eventType: prefix + "type",
+		version:   prefix + "specversion",
+	}
+}
+
 // Binary wraps a nuclio.Event with a cloudevent whose data is encoded in the nuclio.Event body.
 type Binary struct {
 	wrappedEvent
+	headerNames binaryHeaderNames
+}
+
+// IsBinary returns true if the event has the headers of a binary CloudEvent
+func IsBinary(event nuclio.Event) bool {
+	_, found := resolveBinaryHeaderNames(event)
+
+	return found
 }
 
 // SetEvent wraps a Nuclio event
@@ -34,12 +80,19 @@ func (s *Binary) SetEvent(event nuclio.Event) error {
 	// set trigger info provider to ourselves
 	s.event.SetTriggerInfoProvider(s)
 
+	headerNames, found := resolveBinaryHeaderNames(event)
+	if !found {
+		return errors.New("Event has no CloudEvents version header")
+	}
+
+	s.headerNames = headerNames
+
 	return nil
 }
 
 // GetID returns the ID of the event
 func (s *Binary) GetID() nuclio.ID {
-	return nuclio.ID(s.event.GetHeaderString("CE-EventID"))
+	return nuclio.ID(s.getHeaderString(s.headerNames.id))
 }
 
 // get the class of source (sync, async, etc)
@@ -49,17 +102,17 @@ func (s *Binary) GetClass() string {
 
 // get specific kind of source (http, rabbit mq, etc)
 func (s *Binary) GetKind() string {
-	return s.event.GetHeaderString("CE-Source")
+	return s.getHeaderString(s.headerNames.source)
 }
 
 // get specific kind of source (http, rabbit mq, etc)
 func (s *Binary) GetName() string {
-	return s.event.GetHeaderString("CE-Name")
+	return s.getHeaderString(s.headerNames.name)
 }
 
 // GetTimestamp returns when the event originated
 func (s *Binary) GetTimestamp() time.Time {
-	parsedTime, err := time.Parse(time.RFC3339, s.event.GetHeaderString("CE-EventTime"))
+	parsedTime, err := time.Parse(time.RFC3339, s.getHeaderString(s.headerNames.time))
 	if err != nil {
 		return time.Time{}
 	}
@@ -69,17 +122,17 @@ func (s *Binary) GetTimestamp() time.Time {
 
 // GetType returns the type of event
 func (s *Binary) GetType() string {
-	return s.event.GetHeaderString("CE-EventType")
+	return s.getHeaderString(s.headerNames.eventType)
 }
 
 // GetTypeVersion returns the version of the type
 func (s *Binary) GetTypeVersion() string {
-	return s.event.GetHeaderString("CE-EventTypeVersion")
+	return s.getHeaderString(s.headerNames.typeVersion)
 }
 
 // GetVersion returns the version of the event
 func (s *Binary) GetVersion() string {
-	return s.event.GetHeaderString("CE-CloudEventsVersion")
+	return s.getHeaderString(s.headerNames.version)
 }
 
 // GetLastInBatch returns whether the event is the last event in a trigger specific batch
@@ -91,3 +144,45 @@ func (s *Binary) GetLastInBatch() bool {
 func (s *Binary) GetOffset() int {
 	return s.event.GetOffset()
 }
+
+func (s *Binary) getHeaderString(headerName string) string {
+	if headerName == "" {
+		return ""
+	}
+
+	return getHeaderString(s.event, headerName)
+}
+
+// resolveBinaryHeaderNames returns the names of the headers holding the event's attributes, by the spec version
+// header it has (if any)
+func resolveBinaryHeaderNames(event nuclio.Event) (binaryHeaderNames, bool) {
+	if event.GetHeaderString(binaryHeaderNamesV01.version) != "" {
+		return binaryHeaderNamesV01, true
+	}
+
+	for _, prefix := range []string{headerPrefix, kafkaHeaderPrefix} {
+		headerNames := newBinaryHeaderNamesV1(prefix)
+		if getHeaderString(event, headerNames.version) != "" {
+			return headerNames, true
+		}
+	}
+
+	return binaryHeaderNames{}, false
+}
+
+// getHeaderString returns the header as a string. not every event implements GetHeaderString (e.g. kafka events
+// only implement GetHeader), so the header is looked up both ways
+func getHeaderString(event nuclio.Event, headerName string) string {
+	if headerValue := event.GetHeaderString(headerName); headerValue != "" {
+		return headerValue
+	}
+
+	switch typedHeaderValue := event.GetHeader(headerName).(type) {
+	case string:
+		return typedHeaderValue
+	case []byte:
+		return string(typedHeaderValue)
+	}
+
+	return ""
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// StructuredContentType is the content type of a structured CloudEvent
+const StructuredContentType = "application/cloudevents+json"
+
+// Attributes are the attributes of an emitted (1.0) CloudEvent
+type Attributes struct {
+	ID     string
+	Source string
+	Type   string
+	Time   time.Time
+}
+
+type structuredEncoding struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// GetBinaryHeaders returns the headers with which data is emitted as a binary CloudEvent. the data's content type
+// is the content type of the message carrying it
+func GetBinaryHeaders(attributes *Attributes) map[string]string {
+	headers := map[string]string{
+		headerPrefix + "specversion": SpecVersion,
+		headerPrefix + "id":          attributes.ID,
+		headerPrefix + "source":      attributes.Source,
+		headerPrefix + "type":        attributes.Type,
+	}
+
+	if !attributes.Time.IsZero() {
+		headers[headerPrefix+"time"] = attributes.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	return headers
+}
+
+// EncodeStructured returns data of the given content type encoded as a structured CloudEvent, whose content type
+// is StructuredContentType. JSON data is embedded as is, text as a string and anything else base64-encoded
+func EncodeStructured(attributes *Attributes, contentType string, data []byte) ([]byte, error) {
+	encoding := structuredEncoding{
+		SpecVersion:     SpecVersion,
+		ID:              attributes.ID,
+		Source:          attributes.Source,
+		Type:            attributes.Type,
+		DataContentType: contentType,
+	}
+
+	if !attributes.Time.IsZero() {
+		encoding.Time = attributes.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	switch {
+	case len(data) == 0:
+	case strings.Contains(contentType, "json") && json.Valid(data):
+		encoding.Data = data
+	case isTextContentType(contentType) && utf8.Valid(data):
+		encodedData, err := json.Marshal(string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		encoding.Data = encodedData
+	default:
+		encoding.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+
+	return json.Marshal(&encoding)
+}
+
+func isTextContentType(contentType string) bool {
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "json")
+}
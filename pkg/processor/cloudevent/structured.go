@@ -17,9 +17,12 @@ limitations under the License.
 package cloudevent
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"time"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
@@ -27,19 +30,42 @@ import (
 type Structured struct {
 	wrappedEvent
 	cloudEvent cloudEvent
+
+	// the data of a 1.0 event, and its attributes (by ce-<attribute> header name)
+	data             []byte
+	attributeHeaders map[string]interface{}
+}
+
+// IsStructured returns true if the event's content type is that of a (single) structured CloudEvent
+func IsStructured(event nuclio.Event) bool {
+	contentType := event.GetContentType()
+
+	return strings.HasPrefix(contentType, "application/cloudevents") &&
+		!strings.HasPrefix(contentType, "application/cloudevents-batch")
 }
 
 // SetEvent wraps a Nuclio event
 func (s *Structured) SetEvent(event nuclio.Event) error {
 	s.event = event
+	s.cloudEvent = cloudEvent{}
+	s.data = nil
+	s.attributeHeaders = nil
 
 	// set trigger info provider to ourselves
 	s.event.SetTriggerInfoProvider(s)
 
-	body := string(event.GetBody())
+	body := event.GetBody()
 
 	// parse the event body into the cloud event
-	return json.Unmarshal([]byte(body), &s.cloudEvent)
+	if err := json.Unmarshal(body, &s.cloudEvent); err != nil {
+		return err
+	}
+
+	if s.cloudEvent.SpecVersion == "" {
+		return nil
+	}
+
+	return s.normalize(body)
 }
 
 // GetID returns the ID of the event
@@ -74,6 +100,10 @@ func (s *Structured) GetContentType() string {
 
 // GetBody returns the body of the event
 func (s *Structured) GetBody() []byte {
+	if s.cloudEvent.SpecVersion != "" {
+		return s.data
+	}
+
 	switch typedBody := s.cloudEvent.Data.(type) {
 	case string:
 		return []byte(typedBody)
@@ -89,9 +119,54 @@ func (s *Structured) GetBodyObject() interface{} {
 	return s.cloudEvent.Data
 }
 
-// GetHeaders loads all headers into a map of string / interface{}
+// GetHeaders loads all headers into a map of string / interface{}. the headers of a 1.0 event are those of the
+// wrapped event along with its attributes, as if it were a binary event
 func (s *Structured) GetHeaders() map[string]interface{} {
-	return s.cloudEvent.Extensions
+	if s.cloudEvent.SpecVersion == "" {
+		return s.cloudEvent.Extensions
+	}
+
+	headers := map[string]interface{}{}
+	for headerName, headerValue := range s.event.GetHeaders() {
+		headers[headerName] = headerValue
+	}
+
+	for headerName, headerValue := range s.attributeHeaders {
+		headers[headerName] = headerValue
+	}
+
+	return headers
+}
+
+// GetHeader returns the header by name as an interface{}
+func (s *Structured) GetHeader(key string) interface{} {
+	if attributeValue, found := s.attributeHeaders[strings.ToLower(key)]; found {
+		return attributeValue
+	}
+
+	return s.event.GetHeader(key)
+}
+
+// GetHeaderByteSlice returns the header by name as a byte slice
+func (s *Structured) GetHeaderByteSlice(key string) []byte {
+	if _, found := s.attributeHeaders[strings.ToLower(key)]; found {
+		return []byte(s.GetHeaderString(key))
+	}
+
+	return s.event.GetHeaderByteSlice(key)
+}
+
+// GetHeaderString returns the header by name as a string
+func (s *Structured) GetHeaderString(key string) string {
+	switch typedAttributeValue := s.attributeHeaders[strings.ToLower(key)].(type) {
+	case nil:
+		return s.event.GetHeaderString(key)
+	case string:
+		return typedAttributeValue
+	default:
+		encodedAttributeValue, _ := json.Marshal(typedAttributeValue)
+		return string(encodedAttributeValue)
+	}
 }
 
 // GetType returns the type of event
@@ -118,3 +193,64 @@ func (s *Structured) GetLastInBatch() bool {
 func (s *Structured) GetOffset() int {
 	return s.cloudEvent.Offset
 }
+
+// normalize sets the 0.1 attributes of a 1.0 event, so that they're read the same way, and resolves its data
+func (s *Structured) normalize(body []byte) error {
+	s.cloudEvent.CloudEventsVersion = s.cloudEvent.SpecVersion
+	s.cloudEvent.EventID = s.cloudEvent.ID
+	s.cloudEvent.EventType = s.cloudEvent.Type
+	s.cloudEvent.EventTime = s.cloudEvent.Time
+	s.cloudEvent.ContentType = s.cloudEvent.DataContentType
+
+	// the attributes which aren't part of the spec are extensions
+	attributes := map[string]interface{}{}
+	if err := json.Unmarshal(body, &attributes); err != nil {
+		return err
+	}
+
+	s.cloudEvent.Extensions = map[string]interface{}{}
+	s.attributeHeaders = map[string]interface{}{}
+
+	for attributeName, attributeValue := range attributes {
+		if attributeName == "data" || attributeName == "data_base64" {
+			continue
+		}
+
+		if !structuredAttributeNames[attributeName] {
+			s.cloudEvent.Extensions[attributeName] = attributeValue
+		}
+
+		s.attributeHeaders[headerPrefix+attributeName] = attributeValue
+	}
+
+	switch typedData := s.cloudEvent.Data.(type) {
+	case nil:
+		if s.cloudEvent.DataBase64 != "" {
+			data, err := base64.StdEncoding.DecodeString(s.cloudEvent.DataBase64)
+			if err != nil {
+				return errors.Wrap(err, "Failed to decode data_base64")
+			}
+
+			s.data = data
+			s.cloudEvent.Data = data
+		}
+
+	case string:
+		s.data = []byte(typedData)
+
+	// JSON data is passed as is
+	default:
+		data, err := json.Marshal(typedData)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode data")
+		}
+
+		s.data = data
+
+		if s.cloudEvent.ContentType == "" {
+			s.cloudEvent.ContentType = "application/json"
+		}
+	}
+
+	return nil
+}
@@ -20,7 +20,15 @@ import (
 	"time"
 )
 
+// SpecVersion is the version of the CloudEvents spec events are emitted in
+const SpecVersion = "1.0"
+
+// cloudEvent holds the attributes of a structured CloudEvent, in either spec 0.1 or 1.0. the attributes of a 1.0
+// event are normalized into their 0.1 counterparts once parsed
 type cloudEvent struct {
+
+	// spec 0.1
+
 	EventType          string                 `json:"eventType,omitempty"`
 	EventTypeVersion   string                 `json:"eventTypeVersion,omitempty"`
 	CloudEventsVersion string                 `json:"cloudEventsVersion,omitempty"`
@@ -32,4 +40,28 @@ type cloudEvent struct {
 	Data               interface{}            `json:"data,omitempty"`
 	LastInBatch        bool                   `json:"last_in_batch,omitempty"`
 	Offset             int                    `json:"offset,omitempty"`
+
+	// spec 1.0
+	SpecVersion     string    `json:"specversion,omitempty"`
+	ID              string    `json:"id,omitempty"`
+	Type            string    `json:"type,omitempty"`
+	Time            time.Time `json:"time,omitempty"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	Subject         string    `json:"subject,omitempty"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	DataBase64      string    `json:"data_base64,omitempty"`
+}
+
+// the attributes of a 1.0 structured event which aren't extensions
+var structuredAttributeNames = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"time":            true,
+	"datacontenttype": true,
+	"subject":         true,
+	"dataschema":      true,
+	"data":            true,
+	"data_base64":     true,
 }
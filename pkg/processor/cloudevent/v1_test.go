@@ -0,0 +1,227 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+)
+
+// testEvent only implements GetHeader (like kafka events), its headers are looked up by their exact name
+type testEvent struct {
+	nuclio.AbstractEvent
+	contentType string
+	body        []byte
+	headers     map[string]interface{}
+}
+
+func (te *testEvent) GetContentType() string {
+	return te.contentType
+}
+
+func (te *testEvent) GetBody() []byte {
+	return te.body
+}
+
+func (te *testEvent) GetHeader(key string) interface{} {
+	return te.headers[key]
+}
+
+func (te *testEvent) GetHeaders() map[string]interface{} {
+	return te.headers
+}
+
+type v1TestSuite struct {
+	suite.Suite
+}
+
+func (suite *v1TestSuite) TestStructured() {
+	event := &testEvent{
+		contentType: "application/cloudevents+json; charset=utf-8",
+		body: []byte(`{
+	"specversion": "1.0",
+	"id": "testID",
+	"source": "/test/source",
+	"type": "com.example.test",
+	"subject": "testSubject",
+	"time": "2020-06-01T10:00:00Z",
+	"datacontenttype": "text/plain",
+	"testextension": "testExtensionValue",
+	"data": "testData"
+}`),
+		headers: map[string]interface{}{"X-Request-Id": "testRequestID"},
+	}
+
+	suite.Require().True(IsStructured(event))
+	suite.Require().False(IsBinary(event))
+
+	var structuredEvent Structured
+	err := structuredEvent.SetEvent(event)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(nuclio.ID("testID"), structuredEvent.GetID())
+	suite.Require().Equal("com.example.test", structuredEvent.GetType())
+	suite.Require().Equal("1.0", structuredEvent.GetVersion())
+	suite.Require().Equal("/test/source", structuredEvent.GetTriggerInfo().GetKind())
+	suite.Require().Equal(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC), structuredEvent.GetTimestamp().UTC())
+	suite.Require().Equal("text/plain", structuredEvent.GetContentType())
+	suite.Require().Equal([]byte("testData"), structuredEvent.GetBody())
+
+	// the attributes are read as headers, as if the event were binary
+	suite.Require().Equal("testSubject", structuredEvent.GetHeaderString("ce-subject"))
+	suite.Require().Equal("testExtensionValue", structuredEvent.GetHeaderString("CE-TestExtension"))
+	suite.Require().Equal("testRequestID", structuredEvent.GetHeaders()["X-Request-Id"])
+	suite.Require().Equal("/test/source", structuredEvent.GetHeaders()["ce-source"])
+}
+
+func (suite *v1TestSuite) TestStructuredData() {
+	var structuredEvent Structured
+
+	// JSON data is passed as is
+	err := structuredEvent.SetEvent(&testEvent{
+		body: []byte(`{"specversion": "1.0", "id": "1", "source": "s", "type": "t", "data": {"a": "b"}}`),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("application/json", structuredEvent.GetContentType())
+	suite.Require().JSONEq(`{"a": "b"}`, string(structuredEvent.GetBody()))
+
+	// binary data is base64-encoded
+	err = structuredEvent.SetEvent(&testEvent{
+		body: []byte(`{"specversion": "1.0", "id": "2", "source": "s", "type": "t", "datacontenttype": "image/png", "data_base64": "AAEC"}`),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(nuclio.ID("2"), structuredEvent.GetID())
+	suite.Require().Equal("image/png", structuredEvent.GetContentType())
+	suite.Require().Equal([]byte{0, 1, 2}, structuredEvent.GetBody())
+
+	// batches aren't parsed as a single event
+	suite.Require().False(IsStructured(&testEvent{contentType: "application/cloudevents-batch+json"}))
+}
+
+func (suite *v1TestSuite) TestBinary() {
+	event := &testEvent{
+		contentType: "application/json",
+		body:        []byte(`{"a": "b"}`),
+		headers: map[string]interface{}{
+			"ce_specversion": []byte("1.0"),
+			"ce_id":          []byte("testID"),
+			"ce_source":      []byte("/test/source"),
+			"ce_type":        []byte("com.example.test"),
+			"ce_time":        []byte("2020-06-01T10:00:00Z"),
+		},
+	}
+
+	suite.Require().False(IsStructured(event))
+	suite.Require().True(IsBinary(event))
+
+	var binaryEvent Binary
+	err := binaryEvent.SetEvent(event)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(nuclio.ID("testID"), binaryEvent.GetID())
+	suite.Require().Equal("com.example.test", binaryEvent.GetType())
+	suite.Require().Equal("1.0", binaryEvent.GetVersion())
+	suite.Require().Equal("", binaryEvent.GetTypeVersion())
+	suite.Require().Equal("/test/source", binaryEvent.GetTriggerInfo().GetKind())
+	suite.Require().Equal(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC), binaryEvent.GetTimestamp())
+	suite.Require().Equal("application/json", binaryEvent.GetContentType())
+	suite.Require().Equal(event.body, binaryEvent.GetBody())
+
+	// an event without a version header isn't a binary event
+	err = binaryEvent.SetEvent(&testEvent{headers: map[string]interface{}{"ce_id": "testID"}})
+	suite.Require().Error(err)
+}
+
+func (suite *v1TestSuite) TestEncode() {
+	attributes := &Attributes{
+		ID:     "testID",
+		Source: "/test/source",
+		Type:   "com.example.test",
+		Time:   time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	suite.Require().Equal(map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          "testID",
+		"ce-source":      "/test/source",
+		"ce-type":        "com.example.test",
+		"ce-time":        "2020-06-01T10:00:00Z",
+	}, GetBinaryHeaders(attributes))
+
+	for _, testCase := range []struct {
+		contentType  string
+		data         []byte
+		expectedJSON string
+	}{
+		{
+			contentType:  "application/json",
+			data:         []byte(`{"a":"b"}`),
+			expectedJSON: `{"datacontenttype": "application/json", "data": {"a": "b"}}`,
+		},
+		{
+			contentType:  "text/plain; charset=utf-8",
+			data:         []byte("testData"),
+			expectedJSON: `{"datacontenttype": "text/plain; charset=utf-8", "data": "testData"}`,
+		},
+		{
+			contentType:  "image/png",
+			data:         []byte{0, 1, 2},
+			expectedJSON: `{"datacontenttype": "image/png", "data_base64": "AAEC"}`,
+		},
+		{
+			contentType:  "application/json",
+			expectedJSON: `{"datacontenttype": "application/json"}`,
+		},
+	} {
+		encodedEvent, err := EncodeStructured(attributes, testCase.contentType, testCase.data)
+		suite.Require().NoError(err)
+
+		expectedEvent := map[string]interface{}{
+			"specversion": "1.0",
+			"id":          "testID",
+			"source":      "/test/source",
+			"type":        "com.example.test",
+			"time":        "2020-06-01T10:00:00Z",
+		}
+
+		err = json.Unmarshal([]byte(testCase.expectedJSON), &expectedEvent)
+		suite.Require().NoError(err)
+
+		var decodedEvent map[string]interface{}
+		err = json.Unmarshal(encodedEvent, &decodedEvent)
+		suite.Require().NoError(err)
+		suite.Require().Equal(expectedEvent, decodedEvent)
+
+		// what's encoded is parsed back as is
+		var structuredEvent Structured
+		err = structuredEvent.SetEvent(&testEvent{contentType: StructuredContentType, body: encodedEvent})
+		suite.Require().NoError(err)
+		suite.Require().Equal(nuclio.ID("testID"), structuredEvent.GetID())
+
+		if len(testCase.data) != 0 {
+			suite.Require().Equal(string(testCase.data), string(structuredEvent.GetBody()))
+		}
+	}
+}
+
+func TestV1TestSuite(t *testing.T) {
+	suite.Run(t, new(v1TestSuite))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+
+	// TimeoutHeader is how long the caller is willing to wait for the event to be handled (a duration, e.g. 5s)
+	TimeoutHeader = "X-Nuclio-Event-Timeout"
+
+	// Header is the time by which the event must be handled, in milliseconds since the epoch. handlers get it
+	// with the event, and may pass it on to the functions they call so that those give up along with them
+	Header = "X-Nuclio-Deadline"
+)
+
+// ErrExceeded is returned for events which weren't handled by their deadline
+var ErrExceeded = errors.New("Event deadline exceeded")
+
+// deadlineEvent exposes the deadline of the event as one of its headers, so that the handler (and the runtime
+// wrapper passing it the event) know how long they have. go handlers get it as a context, through GetContext()
+type deadlineEvent struct {
+	nuclio.Event
+	encodedDeadline string
+	context         context.Context
+}
+
+// WithDeadline returns the event with the given deadline, and a function releasing the deadline's resources
+// once the event was handled. if the deadline is zero, the event is returned as is
+func WithDeadline(event nuclio.Event, deadline time.Time) (nuclio.Event, context.CancelFunc) {
+	if deadline.IsZero() {
+		return event, func() {}
+	}
+
+	deadlineContext, cancel := context.WithDeadline(context.Background(), deadline)
+
+	return &deadlineEvent{
+		Event:           event,
+		encodedDeadline: strconv.FormatInt(deadline.UnixNano()/int64(time.Millisecond), 10),
+		context:         deadlineContext,
+	}, cancel
+}
+
+// Get returns the deadline of the event, if it has one
+func Get(event nuclio.Event) (time.Time, bool) {
+	encodedDeadline := strings.TrimSpace(event.GetHeaderString(Header))
+	if encodedDeadline == "" {
+		return time.Time{}, false
+	}
+
+	deadlineMilliseconds, err := strconv.ParseInt(encodedDeadline, 10, 64)
+	if err != nil || deadlineMilliseconds <= 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, deadlineMilliseconds*int64(time.Millisecond)), true
+}
+
+// GetRequested returns the deadline the caller asked for, if any - either the deadline itself (e.g. passed on
+// by a calling function) or how long it's willing to wait, from now. malformed values are ignored
+func GetRequested(event nuclio.Event) (time.Time, bool) {
+	requestedDeadline, found := Get(event)
+
+	if encodedTimeout := strings.TrimSpace(event.GetHeaderString(TimeoutHeader)); encodedTimeout != "" {
+		if timeout, err := time.ParseDuration(encodedTimeout); err == nil && timeout > 0 {
+			timeoutDeadline := time.Now().Add(timeout)
+			if !found || timeoutDeadline.Before(requestedDeadline) {
+				requestedDeadline, found = timeoutDeadline, true
+			}
+		}
+	}
+
+	return requestedDeadline, found
+}
+
+// GetContext returns a context that's done once the event's deadline passed
+func (de *deadlineEvent) GetContext() context.Context {
+	return de.context
+}
+
+func (de *deadlineEvent) GetHeader(key string) interface{} {
+	if strings.EqualFold(key, Header) {
+		return de.encodedDeadline
+	}
+
+	return de.Event.GetHeader(key)
+}
+
+func (de *deadlineEvent) GetHeaderByteSlice(key string) []byte {
+	if strings.EqualFold(key, Header) {
+		return []byte(de.encodedDeadline)
+	}
+
+	return de.Event.GetHeaderByteSlice(key)
+}
+
+func (de *deadlineEvent) GetHeaderString(key string) string {
+	if strings.EqualFold(key, Header) {
+		return de.encodedDeadline
+	}
+
+	return de.Event.GetHeaderString(key)
+}
+
+func (de *deadlineEvent) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{}
+
+	// replace the incoming deadline, whichever its case
+	for headerName, headerValue := range de.Event.GetHeaders() {
+		if !strings.EqualFold(headerName, Header) {
+			headers[headerName] = headerValue
+		}
+	}
+
+	headers[Header] = de.encodedDeadline
+
+	return headers
+}
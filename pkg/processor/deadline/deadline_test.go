@@ -0,0 +1,158 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+)
+
+type testEvent struct {
+	nuclio.AbstractEvent
+	headers map[string]interface{}
+}
+
+func (te *testEvent) GetHeader(key string) interface{} {
+	return te.headers[key]
+}
+
+func (te *testEvent) GetHeaderString(key string) string {
+	if value, ok := te.headers[key].(string); ok {
+		return value
+	}
+
+	return ""
+}
+
+func (te *testEvent) GetHeaders() map[string]interface{} {
+	return te.headers
+}
+
+type deadlineTestSuite struct {
+	suite.Suite
+}
+
+func (suite *deadlineTestSuite) TestWithDeadline() {
+	event := &testEvent{headers: map[string]interface{}{"X-Request-Id": "1234"}}
+
+	// no deadline, no change
+	sameEvent, release := WithDeadline(event, time.Time{})
+	release()
+	suite.Require().Equal(event, sameEvent)
+
+	_, found := Get(sameEvent)
+	suite.Require().False(found)
+
+	eventDeadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	deadlineEvent, release := WithDeadline(event, eventDeadline)
+	defer release()
+
+	// the deadline is a header of the event, along with the ones it had
+	encodedDeadline := strconv.FormatInt(eventDeadline.UnixNano()/int64(time.Millisecond), 10)
+	suite.Require().Equal(encodedDeadline, deadlineEvent.GetHeaderString(Header))
+	suite.Require().Equal(map[string]interface{}{
+		"X-Request-Id": "1234",
+		Header:         encodedDeadline,
+	}, deadlineEvent.GetHeaders())
+
+	receivedDeadline, found := Get(deadlineEvent)
+	suite.Require().True(found)
+	suite.Require().True(eventDeadline.Equal(receivedDeadline))
+
+	// go handlers get it as a context
+	eventContext := deadlineEvent.(interface{ GetContext() context.Context }).GetContext()
+	contextDeadline, found := eventContext.Deadline()
+	suite.Require().True(found)
+	suite.Require().True(eventDeadline.Equal(contextDeadline))
+
+	// an expired deadline's context is done
+	expiredEvent, release := WithDeadline(event, time.Now().Add(-time.Second))
+	defer release()
+
+	select {
+	case <-expiredEvent.(interface{ GetContext() context.Context }).GetContext().Done():
+	case <-time.After(time.Second):
+		suite.Fail("Expired deadline's context isn't done")
+	}
+}
+
+func (suite *deadlineTestSuite) TestGetRequested() {
+	laterDeadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	encodedLaterDeadline := strconv.FormatInt(laterDeadline.UnixNano()/int64(time.Millisecond), 10)
+
+	for _, testCase := range []struct {
+		name             string
+		headers          map[string]interface{}
+		expectedFound    bool
+		expectedDeadline time.Time
+		expectedTimeout  time.Duration
+	}{
+		{
+			name:    "none",
+			headers: map[string]interface{}{},
+		},
+		{
+			name:    "malformed",
+			headers: map[string]interface{}{Header: "tomorrow", TimeoutHeader: "-5s"},
+		},
+		{
+			name:             "deadline",
+			headers:          map[string]interface{}{Header: encodedLaterDeadline},
+			expectedFound:    true,
+			expectedDeadline: laterDeadline,
+		},
+		{
+			name:            "timeout",
+			headers:         map[string]interface{}{TimeoutHeader: "5s"},
+			expectedFound:   true,
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:            "earlier timeout",
+			headers:         map[string]interface{}{Header: encodedLaterDeadline, TimeoutHeader: "5s"},
+			expectedFound:   true,
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:             "earlier deadline",
+			headers:          map[string]interface{}{Header: encodedLaterDeadline, TimeoutHeader: "2h"},
+			expectedFound:    true,
+			expectedDeadline: laterDeadline,
+		},
+	} {
+		requestedDeadline, found := GetRequested(&testEvent{headers: testCase.headers})
+		suite.Require().Equal(testCase.expectedFound, found, testCase.name)
+
+		if testCase.expectedTimeout != 0 {
+			suite.Require().WithinDuration(time.Now().Add(testCase.expectedTimeout),
+				requestedDeadline,
+				time.Second,
+				testCase.name)
+		} else if !testCase.expectedDeadline.IsZero() {
+			suite.Require().True(testCase.expectedDeadline.Equal(requestedDeadline), testCase.name)
+		}
+	}
+}
+
+func TestDeadlineTestSuite(t *testing.T) {
+	suite.Run(t, new(deadlineTestSuite))
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/heptiolabs/healthcheck"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// ReadinessCheckHeaderName is set on the requests of handler dependency checks, so that handlers can tell them
+// apart from events
+const ReadinessCheckHeaderName = "X-Nuclio-Readiness-Check"
+
+// newDependencyCheck returns a check which passes once the dependency is reachable. handler dependencies are
+// requested from the HTTP trigger listening on handlerAddress
+func newDependencyCheck(dependency *functionconfig.ReadinessDependency,
+	handlerAddress string,
+	timeout time.Duration) (healthcheck.Check, error) {
+	switch dependency.Kind {
+	case functionconfig.ReadinessDependencyKindTCP:
+		return healthcheck.TCPDialCheck(dependency.Address, timeout), nil
+
+	case functionconfig.ReadinessDependencyKindHTTP:
+		return newHTTPGetCheck(dependency.URL, nil, timeout), nil
+
+	case functionconfig.ReadinessDependencyKindHandler:
+		return newHTTPGetCheck(fmt.Sprintf("http://%s/%s", handlerAddress, strings.TrimPrefix(dependency.Path, "/")),
+			map[string]string{ReadinessCheckHeaderName: "true"},
+			timeout), nil
+	}
+
+	return nil, errors.Errorf("Unsupported readiness dependency kind: %s", dependency.Kind)
+}
+
+// newHTTPGetCheck returns a check which passes when the URL responds with a 2xx status
+func newHTTPGetCheck(url string, headers map[string]string, timeout time.Duration) healthcheck.Check {
+	client := http.Client{
+		Timeout: timeout,
+
+		// a redirect isn't a successful response
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return func() error {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create request")
+		}
+
+		for headerName, headerValue := range headers {
+			request.Header.Set(headerName, headerValue)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+
+		defer response.Body.Close() // nolint: errcheck
+
+		// drain the body, so that the connection is reused
+		io.Copy(ioutil.Discard, response.Body) // nolint: errcheck
+
+		if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+			return errors.Errorf("Responded with status %d", response.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+// dependencyChecker runs the check of a dependency periodically in the background, logging when the dependency
+// becomes reachable or unreachable
+type dependencyChecker struct {
+	logger    logger.Logger
+	name      string
+	check     healthcheck.Check
+	lock      sync.Mutex
+	lastError error
+	checked   bool
+}
+
+func newDependencyChecker(parentLogger logger.Logger,
+	dependency *functionconfig.ReadinessDependency,
+	handlerAddress string,
+	timeout time.Duration) (*dependencyChecker, error) {
+
+	check, err := newDependencyCheck(dependency, handlerAddress, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dependencyChecker{
+		logger: parentLogger,
+		name:   dependency.Name,
+		check:  healthcheck.Timeout(check, timeout),
+	}, nil
+}
+
+// start returns a check reporting the result of the latest run of the dependency's check
+func (dc *dependencyChecker) start(ctx context.Context, period time.Duration) healthcheck.Check {
+	return healthcheck.AsyncWithContext(ctx, func() error {
+		err := dc.check()
+		dc.recordResult(err)
+
+		if err != nil {
+			return errors.Wrapf(err, "Dependency %s is unreachable", dc.name)
+		}
+
+		return nil
+	}, period)
+}
+
+func (dc *dependencyChecker) recordResult(err error) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	// only log transitions, rather than every check
+	switch {
+	case err != nil && (!dc.checked || dc.lastError == nil):
+		dc.logger.WarnWith("Readiness dependency is unreachable", "dependency", dc.name, "err", err.Error())
+	case err == nil && (!dc.checked || dc.lastError != nil):
+		dc.logger.InfoWith("Readiness dependency is reachable", "dependency", dc.name)
+	}
+
+	dc.lastError = err
+	dc.checked = true
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type DependenciesTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *DependenciesTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *DependenciesTestSuite) TestTCPDependency() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	check, err := newDependencyCheck(&functionconfig.ReadinessDependency{
+		Name:    "db",
+		Kind:    functionconfig.ReadinessDependencyKindTCP,
+		Address: listener.Addr().String(),
+	}, "", time.Second)
+	suite.Require().NoError(err)
+	suite.Require().NoError(check())
+
+	// once nothing listens, the dependency is unreachable
+	listener.Close() // nolint: errcheck
+	suite.Require().Error(check())
+}
+
+func (suite *DependenciesTestSuite) TestHTTPDependency() {
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	check, err := newDependencyCheck(&functionconfig.ReadinessDependency{
+		Name: "api",
+		Kind: functionconfig.ReadinessDependencyKindHTTP,
+		URL:  server.URL,
+	}, "", time.Second)
+	suite.Require().NoError(err)
+	suite.Require().NoError(check())
+
+	for _, statusCode = range []int{http.StatusFound, http.StatusNotFound, http.StatusServiceUnavailable} {
+		suite.Require().Error(check(), "status %d", statusCode)
+	}
+}
+
+func (suite *DependenciesTestSuite) TestHandlerDependency() {
+	var requestPath, readinessCheckHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		requestPath = request.URL.Path
+		readinessCheckHeader = request.Header.Get(ReadinessCheckHeaderName)
+	}))
+	defer server.Close()
+
+	// the handler is requested on the address the function's HTTP trigger listens on
+	check, err := newDependencyCheck(&functionconfig.ReadinessDependency{
+		Name: "handler",
+		Kind: functionconfig.ReadinessDependencyKindHandler,
+		Path: "/ready",
+	}, server.Listener.Addr().String(), time.Second)
+	suite.Require().NoError(err)
+	suite.Require().NoError(check())
+	suite.Require().Equal("/ready", requestPath)
+	suite.Require().Equal("true", readinessCheckHeader)
+}
+
+func (suite *DependenciesTestSuite) TestHTTPGetCheckHeaders() {
+	var readinessCheckHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		readinessCheckHeader = request.Header.Get(ReadinessCheckHeaderName)
+	}))
+	defer server.Close()
+
+	check := newHTTPGetCheck(server.URL, map[string]string{ReadinessCheckHeaderName: "true"}, time.Second)
+	suite.Require().NoError(check())
+	suite.Require().Equal("true", readinessCheckHeader)
+}
+
+func (suite *DependenciesTestSuite) TestUnsupportedKind() {
+	_, err := newDependencyCheck(&functionconfig.ReadinessDependency{
+		Name: "queue",
+		Kind: "amqp",
+	}, "", time.Second)
+	suite.Require().Error(err)
+}
+
+func (suite *DependenciesTestSuite) TestChecker() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	address := listener.Addr().String()
+	listener.Close() // nolint: errcheck
+
+	checker, err := newDependencyChecker(suite.logger, &functionconfig.ReadinessDependency{
+		Name:    "db",
+		Kind:    functionconfig.ReadinessDependencyKindTCP,
+		Address: address,
+	}, "", 100*time.Millisecond)
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	check := checker.start(ctx, 50*time.Millisecond)
+	suite.Require().Eventually(func() bool {
+		return check() != nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// bring the dependency up and wait for the check to notice
+	listener, err = net.Listen("tcp", address)
+	suite.Require().NoError(err)
+	defer listener.Close() // nolint: errcheck
+
+	suite.Require().Eventually(func() bool {
+		return check() == nil
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestDependenciesTestSuite(t *testing.T) {
+	suite.Run(t, new(DependenciesTestSuite))
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package healthcheck
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 
@@ -27,24 +31,47 @@ import (
 	"github.com/nuclio/logger"
 )
 
+// drainer is implemented by processors that can be drained before they're terminated
+type drainer interface {
+
+	// Drain stops receiving events and waits for those in flight to be handled
+	Drain()
+}
+
+// runtimeLivenessReporter is implemented by processors which report the restarts of their runtime's wrappers
+type runtimeLivenessReporter interface {
+
+	// GetRuntimeLivenessStatus returns the restarts of the wrappers due to failed liveness checks, if checked
+	GetRuntimeLivenessStatus() *functionconfig.RuntimeLivenessStatus
+}
+
 type Server struct {
 	Enabled       bool
 	ListenAddress string
 	logger        logger.Logger
 	processor     status.Provider
 	handler       healthcheck.Handler
+	readiness     *functionconfig.Readiness
+
+	// the address the function's HTTP trigger listens on, which handler dependencies are requested from
+	handlerAddress string
 }
 
-func NewServer(logger logger.Logger, processor status.Provider, configuration *platformconfig.WebServer) (*Server, error) {
+func NewServer(logger logger.Logger,
+	processor status.Provider,
+	configuration *platformconfig.WebServer,
+	functionSpec *functionconfig.Spec) (*Server, error) {
 	if configuration.Enabled == nil {
 		return nil, errors.New("Enabled must carry a value")
 	}
 
 	newServer := &Server{
-		Enabled:       *configuration.Enabled,
-		ListenAddress: configuration.ListenAddress,
-		logger:        logger.GetChild("healthcheck.server"),
-		processor:     processor,
+		Enabled:        *configuration.Enabled,
+		ListenAddress:  configuration.ListenAddress,
+		logger:         logger.GetChild("healthcheck.server"),
+		processor:      processor,
+		readiness:      functionSpec.Readiness,
+		handlerAddress: fmt.Sprintf("127.0.0.1:%d", functionSpec.GetHTTPListenPort()),
 	}
 
 	// create the healthcheck handler
@@ -72,15 +99,77 @@ func (s *Server) Start() error {
 		return nil
 	})
 
-	// register an always-healthy liveness check until we have a better design for detecting handler deaths
+	// the processor is ready only once the function's dependencies are reachable too
+	if err := s.addDependencyChecks(); err != nil {
+		return errors.Wrap(err, "Failed to add readiness dependency checks")
+	}
+
+	// register the processor's status check as its liveness check too. the processor is in error once one of
+	// its runtimes failed for good (e.g. a wrapper that exceeded the runtime liveness restarts)
 	s.handler.AddLivenessCheck("processor_liveness", func() error {
+		if s.processor.GetStatus() == status.Error {
+			return errors.New("Processor is in error state")
+		}
+
 		return nil
 	})
 
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/", s.handler)
+
+	// let the platform drain the processor before terminating it (e.g. from kubernetes' preStop hook). the
+	// response is returned once the processor drained
+	if processorDrainer, isDrainer := s.processor.(drainer); isDrainer {
+		serveMux.HandleFunc("/drain", func(responseWriter http.ResponseWriter, request *http.Request) {
+			s.logger.Info("Draining processor")
+
+			processorDrainer.Drain()
+			responseWriter.WriteHeader(http.StatusOK)
+		})
+	}
+
+	// let the platform record the restarts of the runtime's wrappers in the function's status
+	if processorLivenessReporter, isLivenessReporter := s.processor.(runtimeLivenessReporter); isLivenessReporter {
+		serveMux.HandleFunc("/runtime-liveness", func(responseWriter http.ResponseWriter, request *http.Request) {
+			runtimeLivenessStatus := processorLivenessReporter.GetRuntimeLivenessStatus()
+			if runtimeLivenessStatus == nil {
+				responseWriter.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			responseWriter.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(responseWriter).Encode(runtimeLivenessStatus) // nolint: errcheck
+		})
+	}
+
 	// start listening
-	go http.ListenAndServe(s.ListenAddress, s.handler) // nolint: errcheck
+	go http.ListenAndServe(s.ListenAddress, serveMux) // nolint: errcheck
 
 	s.logger.InfoWith("Listening", "listenAddress", s.ListenAddress)
 
 	return nil
 }
+
+func (s *Server) addDependencyChecks() error {
+	if s.readiness == nil {
+		return nil
+	}
+
+	for dependencyIndex := range s.readiness.Dependencies {
+		dependency := &s.readiness.Dependencies[dependencyIndex]
+
+		checker, err := newDependencyChecker(s.logger, dependency, s.handlerAddress, s.readiness.GetTimeout())
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create check of dependency %s", dependency.Name)
+		}
+
+		s.handler.AddReadinessCheck("dependency_"+dependency.Name,
+			checker.start(context.Background(), s.readiness.GetPeriod()))
+	}
+
+	s.logger.InfoWith("Checking readiness dependencies",
+		"dependencies", len(s.readiness.Dependencies),
+		"period", s.readiness.GetPeriod())
+
+	return nil
+}
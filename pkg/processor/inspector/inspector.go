@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspector
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/rs/xid"
+)
+
+// Invocation is the summary of an invocation of the function, as retained by the inspector
+type Invocation struct {
+	ID                string        `json:"id"`
+	EventID           string        `json:"eventID,omitempty"`
+	Time              time.Time     `json:"time"`
+	TriggerKind       string        `json:"triggerKind"`
+	TriggerName       string        `json:"triggerName"`
+	Method            string        `json:"method,omitempty"`
+	Path              string        `json:"path,omitempty"`
+	ContentType       string        `json:"contentType,omitempty"`
+	RequestSize       int           `json:"requestSize"`
+	StatusCode        int           `json:"statusCode"`
+	Error             string        `json:"error,omitempty"`
+	Duration          time.Duration `json:"duration"`
+	Response          string        `json:"response,omitempty"`
+	ResponseTruncated bool          `json:"responseTruncated,omitempty"`
+}
+
+// Inspector retains a summary of the last invocations of the function (a sample of them), in memory, so that
+// they can be inspected after the fact. a nil inspector (returned when inspection is disabled) retains nothing
+type Inspector struct {
+	logger         logger.Logger
+	sampleRatio    float64
+	maxBodySize    int
+	maxInvocations int
+	invocations    []Invocation
+	nextIndex      int
+	lock           sync.Mutex
+}
+
+// NewInspector creates an inspector, or returns nil if inspection isn't enabled
+func NewInspector(parentLogger logger.Logger, configuration *functionconfig.InvocationInspector) *Inspector {
+	if configuration == nil {
+		return nil
+	}
+
+	newInspector := &Inspector{
+		logger:         parentLogger.GetChild("inspector"),
+		sampleRatio:    configuration.GetSampleRatio(),
+		maxBodySize:    configuration.GetMaxBodySize(),
+		maxInvocations: configuration.GetMaxInvocations(),
+	}
+
+	newInspector.logger.InfoWith("Inspecting invocations",
+		"maxInvocations", newInspector.maxInvocations,
+		"sampleRatio", newInspector.sampleRatio)
+
+	return newInspector
+}
+
+// Inspect retains the summary of the invocation, if it's sampled, in place of the oldest one once the inspector
+// retains as many as it may
+func (i *Inspector) Inspect(event nuclio.Event,
+	triggerKind string,
+	triggerName string,
+	response interface{},
+	processError error,
+	duration time.Duration) {
+	if i == nil || (i.sampleRatio < 1 && rand.Float64() >= i.sampleRatio) {
+		return
+	}
+
+	// the record holds the status code and the (truncated) response the HTTP trigger would have written
+	record := recording.NewRecord(event, "", triggerKind, triggerName, response, processError, i.maxBodySize)
+
+	invocation := Invocation{
+		ID:                xid.New().String(),
+		EventID:           record.ID,
+		Time:              time.Now().Add(-duration),
+		TriggerKind:       triggerKind,
+		TriggerName:       triggerName,
+		Method:            record.Method,
+		Path:              record.Path,
+		ContentType:       record.ContentType,
+		RequestSize:       len(event.GetBody()),
+		StatusCode:        record.Response.StatusCode,
+		Duration:          duration,
+		ResponseTruncated: record.Response.BodyTruncated,
+	}
+
+	// the response to a failed invocation is the error
+	if processError != nil {
+		invocation.Error = string(record.Response.Body)
+	} else {
+		invocation.Response = string(record.Response.Body)
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if len(i.invocations) < i.maxInvocations {
+		i.invocations = append(i.invocations, invocation)
+		return
+	}
+
+	i.invocations[i.nextIndex] = invocation
+	i.nextIndex = (i.nextIndex + 1) % i.maxInvocations
+}
+
+// GetInvocations returns the retained invocations, oldest first
+func (i *Inspector) GetInvocations() []Invocation {
+	if i == nil {
+		return nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	invocations := make([]Invocation, 0, len(i.invocations))
+	invocations = append(invocations, i.invocations[i.nextIndex:]...)
+	invocations = append(invocations, i.invocations[:i.nextIndex]...)
+
+	return invocations
+}
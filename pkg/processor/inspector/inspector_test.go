@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspector
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type inspectorTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *inspectorTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *inspectorTestSuite) TestDisabled() {
+	inspector := NewInspector(suite.logger, nil)
+	suite.Require().Nil(inspector)
+
+	// a nil inspector retains nothing
+	inspector.Inspect(&nuclio.MemoryEvent{}, "http", "my-http", "ok", nil, time.Millisecond)
+	suite.Require().Empty(inspector.GetInvocations())
+}
+
+func (suite *inspectorTestSuite) TestInspect() {
+	inspector := NewInspector(suite.logger, &functionconfig.InvocationInspector{
+		MaxInvocations: 3,
+		MaxBodySize:    4,
+	})
+
+	event := &nuclio.MemoryEvent{
+		Method: "POST",
+		Path:   "/orders",
+		Body:   []byte(`{"id":"1234567890"}`),
+	}
+
+	inspector.Inspect(event, "http", "my-http", "created", nil, 20*time.Millisecond)
+	inspector.Inspect(event,
+		"http",
+		"my-http",
+		nil,
+		nuclio.NewErrBadRequest("Invalid order"),
+		5*time.Millisecond)
+
+	invocations := inspector.GetInvocations()
+	suite.Require().Len(invocations, 2)
+
+	suite.Require().Equal("POST", invocations[0].Method)
+	suite.Require().Equal("/orders", invocations[0].Path)
+	suite.Require().Equal(len(event.Body), invocations[0].RequestSize)
+	suite.Require().Equal(http.StatusOK, invocations[0].StatusCode)
+	suite.Require().Equal(20*time.Millisecond, invocations[0].Duration)
+	suite.Require().Equal("crea", invocations[0].Response)
+	suite.Require().True(invocations[0].ResponseTruncated)
+
+	// a failed invocation has the error's status code and message
+	suite.Require().Equal(http.StatusBadRequest, invocations[1].StatusCode)
+	suite.Require().Equal("Inva", invocations[1].Error)
+	suite.Require().Empty(invocations[1].Response)
+	suite.Require().NotEqual(invocations[0].ID, invocations[1].ID)
+
+	// once as many as may be retained are, the oldest are replaced
+	for invocationIndex := 0; invocationIndex < 3; invocationIndex++ {
+		inspector.Inspect(event,
+			"http",
+			"my-http",
+			nil,
+			errors.New(strings.Repeat("x", invocationIndex+1)),
+			time.Millisecond)
+	}
+
+	invocations = inspector.GetInvocations()
+	suite.Require().Len(invocations, 3)
+
+	for invocationIndex, invocation := range invocations {
+		suite.Require().Equal(http.StatusInternalServerError, invocation.StatusCode)
+		suite.Require().Equal(strings.Repeat("x", invocationIndex+1), invocation.Error)
+	}
+}
+
+func TestInspectorTestSuite(t *testing.T) {
+	suite.Run(t, new(inspectorTestSuite))
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"sync"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the invokers of the contexts handed to in-process (e.g. Go) handlers, by their platform
+var contextInvokers sync.Map
+
+// RegisterContext makes the invoker available to handlers through the context
+func RegisterContext(context *nuclio.Context, invoker *Invoker) {
+	if invoker == nil {
+		return
+	}
+
+	contextInvokers.Store(context.Platform, invoker)
+}
+
+// CallFunctionAsync enqueues an invocation of a function with the event, returning without waiting for the function
+// to handle it. it's the asynchronous counterpart of context.Platform.CallFunction for Go handlers
+func CallFunctionAsync(context *nuclio.Context, functionName string, event nuclio.Event) error {
+	return CallFunctionAsyncBatch(context, functionName, []nuclio.Event{event})
+}
+
+// CallFunctionAsyncBatch enqueues an invocation of a function with each of the events. either all of them are
+// enqueued, or none are
+func CallFunctionAsyncBatch(context *nuclio.Context, functionName string, events []nuclio.Event) error {
+	invoker, found := contextInvokers.Load(context.Platform)
+	if !found {
+		return errors.New("Async invocations aren't available in this context")
+	}
+
+	invocations := make([]*Invocation, len(events))
+	for eventIndex, event := range events {
+		invocations[eventIndex] = NewInvocation(functionName, event)
+	}
+
+	return invoker.(*Invoker).Enqueue(invocations...)
+}
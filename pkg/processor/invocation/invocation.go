@@ -0,0 +1,61 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"fmt"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Invocation is a request to a function, delivered without waiting for its response
+type Invocation struct {
+	FunctionName string            `json:"functionName"`
+	Method       string            `json:"method,omitempty"`
+	Path         string            `json:"path,omitempty"`
+	ContentType  string            `json:"contentType,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         []byte            `json:"body,omitempty"`
+
+	// the number of failed deliveries of the invocation, if persisted
+	Retries int `json:"retries,omitempty"`
+}
+
+// NewInvocation copies the event (which the caller may reuse once the invocation is enqueued) into an invocation
+func NewInvocation(functionName string, event nuclio.Event) *Invocation {
+	invocation := &Invocation{
+		FunctionName: functionName,
+		Method:       event.GetMethod(),
+		Path:         event.GetPath(),
+		ContentType:  event.GetContentType(),
+		Headers:      map[string]string{},
+		Body:         append([]byte{}, event.GetBody()...),
+	}
+
+	for headerName, headerValue := range event.GetHeaders() {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			invocation.Headers[headerName] = typedHeaderValue
+		case []byte:
+			invocation.Headers[headerName] = string(typedHeaderValue)
+		default:
+			invocation.Headers[headerName] = fmt.Sprint(typedHeaderValue)
+		}
+	}
+
+	return invocation
+}
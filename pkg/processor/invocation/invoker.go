@@ -0,0 +1,366 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/rs/xid"
+)
+
+// ErrQueueFull is returned when there's no room in the queue for the invocations
+var ErrQueueFull = errors.New("Async invocation queue is full")
+
+const (
+	invocationFileExtension = ".json"
+	failedDirName           = "failed"
+	initialRetryInterval    = time.Second
+	maxRetryInterval        = time.Minute
+)
+
+type queuedInvocation struct {
+	invocation *Invocation
+
+	// the file holding the invocation, if persisted
+	filePath string
+}
+
+// Invoker delivers the invocations a function enqueues to their functions in the background, according to the
+// configured delivery guarantee
+type Invoker struct {
+	logger      logger.Logger
+	sender      Sender
+	persisted   bool
+	path        string
+	maxRetries  int
+	queue       chan *queuedInvocation
+	queued      int64
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	workersDone sync.WaitGroup
+}
+
+// NewInvoker creates an invoker and starts delivering invocations - including, with persisted delivery, those
+// left over by a previous processor. a nil configuration means best-effort delivery with the defaults
+func NewInvoker(parentLogger logger.Logger,
+	configuration *functionconfig.AsyncInvocation,
+	sender Sender) (*Invoker, error) {
+
+	newInvoker := &Invoker{
+		logger:     parentLogger.GetChild("invoker"),
+		sender:     sender,
+		persisted:  configuration.GetDelivery() == functionconfig.AsyncInvocationDeliveryPersisted,
+		maxRetries: configuration.GetMaxRetries(),
+		stopChan:   make(chan struct{}),
+	}
+
+	var pendingInvocations []*queuedInvocation
+
+	if newInvoker.persisted {
+		var err error
+
+		newInvoker.path = configuration.Path
+
+		pendingInvocations, err = newInvoker.loadPendingInvocations()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to load pending invocations")
+		}
+	}
+
+	// the queue holds all the pending invocations even if they exceed its size, so that they're all delivered
+	queueSize := configuration.GetQueueSize()
+	if len(pendingInvocations) > queueSize {
+		queueSize = len(pendingInvocations)
+	}
+
+	newInvoker.queue = make(chan *queuedInvocation, queueSize)
+	for _, pendingInvocation := range pendingInvocations {
+		newInvoker.queue <- pendingInvocation
+	}
+
+	newInvoker.queued = int64(len(pendingInvocations))
+
+	newInvoker.logger.InfoWith("Delivering async invocations",
+		"delivery", configuration.GetDelivery(),
+		"path", newInvoker.path,
+		"queueSize", queueSize,
+		"workers", configuration.GetWorkers(),
+		"pending", len(pendingInvocations))
+
+	for workerIndex := 0; workerIndex < configuration.GetWorkers(); workerIndex++ {
+		newInvoker.workersDone.Add(1)
+		go newInvoker.deliverInvocations()
+	}
+
+	return newInvoker, nil
+}
+
+// Enqueue queues the invocations for delivery, returning without waiting for them to be delivered. either all
+// invocations are queued, or none are (e.g. when there's no room for all of them). with persisted delivery,
+// the invocations are written to files before Enqueue returns
+func (i *Invoker) Enqueue(invocations ...*Invocation) error {
+	if i == nil {
+		return errors.New("Async invocations aren't available")
+	}
+
+	// reserve room for the invocations, so that queuing them never blocks
+	if atomic.AddInt64(&i.queued, int64(len(invocations))) > int64(cap(i.queue)) {
+		atomic.AddInt64(&i.queued, -int64(len(invocations)))
+		return ErrQueueFull
+	}
+
+	queuedInvocations := make([]*queuedInvocation, len(invocations))
+	for invocationIndex, invocation := range invocations {
+		queuedInvocations[invocationIndex] = &queuedInvocation{invocation: invocation}
+
+		if i.persisted {
+			queuedInvocations[invocationIndex].filePath = filepath.Join(i.path, i.getInvocationFileName())
+
+			if err := i.persistInvocation(queuedInvocations[invocationIndex]); err != nil {
+
+				// don't deliver some of the invocations
+				for _, persistedInvocation := range queuedInvocations[:invocationIndex] {
+					os.Remove(persistedInvocation.filePath) // nolint: errcheck
+				}
+
+				atomic.AddInt64(&i.queued, -int64(len(invocations)))
+				return errors.Wrap(err, "Failed to persist invocation")
+			}
+		}
+	}
+
+	for _, queuedInvocation := range queuedInvocations {
+		i.queue <- queuedInvocation
+	}
+
+	return nil
+}
+
+// Stop stops delivering invocations once those being delivered are. with best-effort delivery the queued
+// invocations are dropped, and with persisted delivery they're delivered by the next processor
+func (i *Invoker) Stop() {
+	if i == nil {
+		return
+	}
+
+	i.stopOnce.Do(func() {
+		close(i.stopChan)
+		i.workersDone.Wait()
+
+		if queued := atomic.LoadInt64(&i.queued); queued > 0 && !i.persisted {
+			i.logger.WarnWith("Dropping undelivered async invocations", "invocations", queued)
+		}
+	})
+}
+
+func (i *Invoker) deliverInvocations() {
+	defer i.workersDone.Done()
+
+	for {
+
+		// don't start delivering another invocation once stopped, even if some are queued
+		select {
+		case <-i.stopChan:
+			return
+		default:
+		}
+
+		select {
+		case <-i.stopChan:
+			return
+		case queuedInvocation := <-i.queue:
+			i.deliverInvocation(queuedInvocation)
+		}
+	}
+}
+
+func (i *Invoker) deliverInvocation(queuedInvocation *queuedInvocation) {
+	invocation := queuedInvocation.invocation
+
+	err := i.sender.Send(invocation)
+	if err == nil {
+		if i.persisted {
+			if err := os.Remove(queuedInvocation.filePath); err != nil {
+				i.logger.WarnWith("Failed to remove delivered invocation", "path", queuedInvocation.filePath, "err", err)
+			}
+		}
+
+		atomic.AddInt64(&i.queued, -1)
+		return
+	}
+
+	if !i.persisted {
+		i.logger.WarnWith("Failed to deliver async invocation, dropping it",
+			"function", invocation.FunctionName,
+			"err", errors.RootCause(err).Error())
+
+		atomic.AddInt64(&i.queued, -1)
+		return
+	}
+
+	invocation.Retries++
+
+	if invocation.Retries > i.maxRetries {
+		i.logger.ErrorWith("Failed to deliver async invocation, giving up",
+			"function", invocation.FunctionName,
+			"retries", i.maxRetries,
+			"path", queuedInvocation.filePath,
+			"err", errors.RootCause(err).Error())
+
+		failedFilePath := filepath.Join(i.path, failedDirName, filepath.Base(queuedInvocation.filePath))
+		if err := os.Rename(queuedInvocation.filePath, failedFilePath); err != nil {
+			i.logger.WarnWith("Failed to move aside undeliverable invocation", "path", queuedInvocation.filePath, "err", err)
+		}
+
+		atomic.AddInt64(&i.queued, -1)
+		return
+	}
+
+	// record the retry, so that it's counted across processors
+	if err := i.persistInvocation(queuedInvocation); err != nil {
+		i.logger.WarnWith("Failed to update invocation", "path", queuedInvocation.filePath, "err", err)
+	}
+
+	retryInterval := i.getRetryInterval(invocation.Retries)
+
+	i.logger.DebugWith("Failed to deliver async invocation, retrying",
+		"function", invocation.FunctionName,
+		"retries", invocation.Retries,
+		"retryInterval", retryInterval,
+		"err", errors.RootCause(err).Error())
+
+	// the invocation is still counted as queued, so there's room for it in the queue
+	time.AfterFunc(retryInterval, func() {
+		select {
+		case <-i.stopChan:
+		default:
+			i.queue <- queuedInvocation
+		}
+	})
+}
+
+func (i *Invoker) getQueued() int64 {
+	return atomic.LoadInt64(&i.queued)
+}
+
+func (i *Invoker) getRetryInterval(retries int) time.Duration {
+	retryInterval := initialRetryInterval
+
+	for retry := 1; retry < retries && retryInterval < maxRetryInterval; retry++ {
+		retryInterval *= 2
+	}
+
+	if retryInterval > maxRetryInterval {
+		return maxRetryInterval
+	}
+
+	return retryInterval
+}
+
+// getInvocationFileName returns a unique file name, ordered by the time the invocation was enqueued
+func (i *Invoker) getInvocationFileName() string {
+	return fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), xid.New().String(), invocationFileExtension)
+}
+
+// persistInvocation writes the invocation to a temporary file and renames it, so that a crash never leaves
+// a partially written invocation behind
+func (i *Invoker) persistInvocation(queuedInvocation *queuedInvocation) error {
+	encodedInvocation, err := json.Marshal(queuedInvocation.invocation)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode invocation")
+	}
+
+	temporaryFilePath := queuedInvocation.filePath + ".tmp"
+
+	temporaryFile, err := os.OpenFile(temporaryFilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create invocation file")
+	}
+
+	if _, err := temporaryFile.Write(encodedInvocation); err != nil {
+		temporaryFile.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to write invocation file")
+	}
+
+	if err := temporaryFile.Sync(); err != nil {
+		temporaryFile.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to sync invocation file")
+	}
+
+	if err := temporaryFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close invocation file")
+	}
+
+	return os.Rename(temporaryFilePath, queuedInvocation.filePath)
+}
+
+func (i *Invoker) loadPendingInvocations() ([]*queuedInvocation, error) {
+	if err := os.MkdirAll(filepath.Join(i.path, failedDirName), 0755); err != nil {
+		return nil, errors.Wrapf(err, "Failed to create invocations directory %s", i.path)
+	}
+
+	fileInfos, err := ioutil.ReadDir(i.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read invocations directory %s", i.path)
+	}
+
+	var fileNames []string
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() && strings.HasSuffix(fileInfo.Name(), invocationFileExtension) {
+			fileNames = append(fileNames, fileInfo.Name())
+		}
+	}
+
+	// deliver in the order the invocations were enqueued
+	sort.Strings(fileNames)
+
+	var pendingInvocations []*queuedInvocation
+	for _, fileName := range fileNames {
+		filePath := filepath.Join(i.path, fileName)
+
+		encodedInvocation, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read invocation file %s", filePath)
+		}
+
+		invocation := &Invocation{}
+		if err := json.Unmarshal(encodedInvocation, invocation); err != nil {
+			i.logger.WarnWith("Skipping malformed invocation file", "path", filePath, "err", err)
+			continue
+		}
+
+		pendingInvocations = append(pendingInvocations, &queuedInvocation{
+			invocation: invocation,
+			filePath:   filePath,
+		})
+	}
+
+	return pendingInvocations, nil
+}
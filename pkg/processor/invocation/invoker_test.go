@@ -0,0 +1,286 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingSender fails the first failures invocations it's asked to send, and records the others
+type recordingSender struct {
+	lock        sync.Mutex
+	failures    int
+	invocations []*Invocation
+	blockChan   chan struct{}
+}
+
+func (rs *recordingSender) Send(invocation *Invocation) error {
+	if rs.blockChan != nil {
+		<-rs.blockChan
+	}
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.failures > 0 {
+		rs.failures--
+		return errors.New("Function unavailable")
+	}
+
+	rs.invocations = append(rs.invocations, invocation)
+	return nil
+}
+
+func (rs *recordingSender) getInvocations() []*Invocation {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	return append([]*Invocation{}, rs.invocations...)
+}
+
+type invokerTestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	tempDir string
+}
+
+func (suite *invokerTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *invokerTestSuite) SetupTest() {
+	var err error
+
+	suite.tempDir, err = ioutil.TempDir("", "invoker-test")
+	suite.Require().NoError(err)
+}
+
+func (suite *invokerTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *invokerTestSuite) TestBestEffort() {
+	sender := &recordingSender{failures: 1}
+
+	invoker, err := NewInvoker(suite.logger, nil, sender)
+	suite.Require().NoError(err)
+	defer invoker.Stop()
+
+	// the first invocation fails and is dropped
+	suite.Require().NoError(invoker.Enqueue(&Invocation{FunctionName: "dropped"}))
+	suite.Require().Eventually(func() bool { return invoker.getQueued() == 0 }, time.Second, 10*time.Millisecond)
+
+	suite.Require().NoError(invoker.Enqueue(&Invocation{FunctionName: "a"}, &Invocation{FunctionName: "b"}))
+	suite.Require().Eventually(func() bool { return len(sender.getInvocations()) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *invokerTestSuite) TestQueueFull() {
+	sender := &recordingSender{blockChan: make(chan struct{})}
+
+	invoker, err := NewInvoker(suite.logger, &functionconfig.AsyncInvocation{
+		QueueSize: 2,
+		Workers:   1,
+	}, sender)
+	suite.Require().NoError(err)
+
+	// there's no room for a batch larger than the queue, and nothing of it is queued
+	suite.Require().Equal(ErrQueueFull, invoker.Enqueue(&Invocation{}, &Invocation{}, &Invocation{}))
+	suite.Require().NoError(invoker.Enqueue(&Invocation{}, &Invocation{}))
+	suite.Require().Equal(ErrQueueFull, invoker.Enqueue(&Invocation{}))
+
+	close(sender.blockChan)
+	suite.Require().Eventually(func() bool { return len(sender.getInvocations()) == 2 }, time.Second, 10*time.Millisecond)
+	invoker.Stop()
+}
+
+func (suite *invokerTestSuite) TestPersisted() {
+	sender := &recordingSender{blockChan: make(chan struct{})}
+	configuration := &functionconfig.AsyncInvocation{
+		Delivery: functionconfig.AsyncInvocationDeliveryPersisted,
+		Path:     suite.tempDir,
+		Workers:  1,
+	}
+
+	invoker, err := NewInvoker(suite.logger, configuration, sender)
+	suite.Require().NoError(err)
+
+	// the invocations are written before being delivered
+	suite.Require().NoError(invoker.Enqueue(&Invocation{FunctionName: "a"}, &Invocation{FunctionName: "b"}))
+	suite.Require().Len(suite.getInvocationFiles(), 2)
+
+	// stop the invoker while the first invocation is being delivered, leaving the second one behind
+	suite.Require().Eventually(func() bool { return len(invoker.queue) == 1 }, time.Second, 10*time.Millisecond)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		sender.blockChan <- struct{}{}
+	}()
+
+	invoker.Stop()
+
+	suite.Require().Len(sender.getInvocations(), 1)
+	suite.Require().Equal("a", sender.getInvocations()[0].FunctionName)
+	suite.Require().Len(suite.getInvocationFiles(), 1)
+
+	// a new invoker delivers the pending invocation
+	sender = &recordingSender{}
+
+	invoker, err = NewInvoker(suite.logger, configuration, sender)
+	suite.Require().NoError(err)
+	defer invoker.Stop()
+
+	suite.Require().Eventually(func() bool { return len(sender.getInvocations()) == 1 }, time.Second, 10*time.Millisecond)
+	suite.Require().Equal("b", sender.getInvocations()[0].FunctionName)
+	suite.Require().Eventually(func() bool { return len(suite.getInvocationFiles()) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *invokerTestSuite) TestPersistedRetries() {
+	maxRetries := 1
+	sender := &recordingSender{failures: 3}
+
+	invoker, err := NewInvoker(suite.logger, &functionconfig.AsyncInvocation{
+		Delivery:   functionconfig.AsyncInvocationDeliveryPersisted,
+		Path:       suite.tempDir,
+		MaxRetries: &maxRetries,
+	}, sender)
+	suite.Require().NoError(err)
+	defer invoker.Stop()
+
+	// fails, is retried after a second and fails again, so it's moved aside
+	suite.Require().NoError(invoker.Enqueue(&Invocation{FunctionName: "a"}))
+	suite.Require().Eventually(func() bool { return invoker.getQueued() == 0 }, 3*time.Second, 10*time.Millisecond)
+
+	suite.Require().Empty(suite.getInvocationFiles())
+
+	failedFiles, err := ioutil.ReadDir(filepath.Join(suite.tempDir, failedDirName))
+	suite.Require().NoError(err)
+	suite.Require().Len(failedFiles, 1)
+}
+
+func (suite *invokerTestSuite) TestRetryInterval() {
+	invoker := &Invoker{}
+
+	suite.Require().Equal(time.Second, invoker.getRetryInterval(1))
+	suite.Require().Equal(4*time.Second, invoker.getRetryInterval(3))
+	suite.Require().Equal(time.Minute, invoker.getRetryInterval(20))
+}
+
+func (suite *invokerTestSuite) TestCallFunctionAsync() {
+	sender := &recordingSender{}
+	context := &nuclio.Context{Platform: &nuclio.Platform{}}
+
+	suite.Require().Error(CallFunctionAsync(context, "target", &nuclio.MemoryEvent{}))
+
+	invoker, err := NewInvoker(suite.logger, nil, sender)
+	suite.Require().NoError(err)
+	defer invoker.Stop()
+
+	RegisterContext(context, invoker)
+
+	suite.Require().NoError(CallFunctionAsyncBatch(context, "target", []nuclio.Event{
+		&nuclio.MemoryEvent{Body: []byte("first"), Headers: map[string]interface{}{"X-Count": 1}},
+		&nuclio.MemoryEvent{Body: []byte("second"), Path: "/path"},
+	}))
+
+	suite.Require().Eventually(func() bool { return len(sender.getInvocations()) == 2 }, time.Second, 10*time.Millisecond)
+
+	bodies := map[string]*Invocation{}
+	for _, invocation := range sender.getInvocations() {
+		suite.Require().Equal("target", invocation.FunctionName)
+		bodies[string(invocation.Body)] = invocation
+	}
+
+	suite.Require().Equal("1", bodies["first"].Headers["X-Count"])
+	suite.Require().Equal("/path", bodies["second"].Path)
+}
+
+func (suite *invokerTestSuite) TestHTTPSender() {
+	var receivedRequest *http.Request
+	var receivedBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		receivedRequest = request
+		receivedBody, _ = ioutil.ReadAll(request.Body)
+
+		if request.URL.Path == "/fail" {
+			responseWriter.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSender("kube", "default")
+	suite.Require().Equal("http://nuclio-target:8080/path", sender.getFunctionURL(&Invocation{
+		FunctionName: "target",
+		Path:         "/path",
+	}))
+	suite.Require().Equal("http://nuclio-default-target:8080/", NewHTTPSender("local", "default").getFunctionURL(&Invocation{
+		FunctionName: "target",
+	}))
+
+	// send to the test server by way of a proxy-less client that rewrites the host
+	sender.client.Transport = &rewriteHostTransport{host: server.Listener.Addr().String()}
+
+	err := sender.Send(&Invocation{
+		FunctionName: "target",
+		Path:         "/path",
+		ContentType:  "application/json",
+		Headers:      map[string]string{"X-Header": "value"},
+		Body:         []byte(`{"a": 1}`),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.MethodPost, receivedRequest.Method)
+	suite.Require().Equal("application/json", receivedRequest.Header.Get("Content-Type"))
+	suite.Require().Equal("value", receivedRequest.Header.Get("X-Header"))
+	suite.Require().Equal(`{"a": 1}`, string(receivedBody))
+
+	suite.Require().Error(sender.Send(&Invocation{FunctionName: "target", Path: "/fail"}))
+}
+
+func (suite *invokerTestSuite) getInvocationFiles() []string {
+	fileNames, err := filepath.Glob(filepath.Join(suite.tempDir, "*"+invocationFileExtension))
+	suite.Require().NoError(err)
+
+	return fileNames
+}
+
+type rewriteHostTransport struct {
+	host string
+}
+
+func (rht *rewriteHostTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request.URL.Host = rht.host
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestInvokerTestSuite(t *testing.T) {
+	suite.Run(t, new(invokerTestSuite))
+}
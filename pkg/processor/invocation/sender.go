@@ -0,0 +1,104 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+const sendTimeout = time.Minute
+
+// Sender delivers an invocation to its function
+type Sender interface {
+
+	// Send returns once the function handled the invocation. an error means the invocation should be retried
+	Send(invocation *Invocation) error
+}
+
+// HTTPSender delivers invocations to the HTTP triggers of functions in the same namespace, addressing them the
+// way the platform's CallFunction does
+type HTTPSender struct {
+	client       *http.Client
+	platformKind string
+	namespace    string
+}
+
+// NewHTTPSender creates a sender for functions of the given platform kind and namespace
+func NewHTTPSender(platformKind string, namespace string) *HTTPSender {
+	return &HTTPSender{
+		client:       &http.Client{Timeout: sendTimeout},
+		platformKind: platformKind,
+		namespace:    namespace,
+	}
+}
+
+func (hs *HTTPSender) Send(invocation *Invocation) error {
+	method := invocation.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	request, err := http.NewRequest(method, hs.getFunctionURL(invocation), bytes.NewReader(invocation.Body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerName, headerValue := range invocation.Headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	if invocation.ContentType != "" {
+		request.Header.Set("Content-Type", invocation.ContentType)
+	}
+
+	response, err := hs.client.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to invoke function %s", invocation.FunctionName)
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	// drain the body, so that the connection is reused
+	io.Copy(ioutil.Discard, response.Body) // nolint: errcheck
+
+	// the function handled the invocation, even if it rejected it - retrying won't change that
+	if response.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("Function %s responded with status %d", invocation.FunctionName, response.StatusCode)
+	}
+
+	return nil
+}
+
+func (hs *HTTPSender) getFunctionURL(invocation *Invocation) string {
+	var functionHost string
+
+	if hs.platformKind == "local" {
+		functionHost = fmt.Sprintf("nuclio-%s-%s", hs.namespace, invocation.FunctionName)
+	} else {
+		functionHost = fmt.Sprintf("nuclio-%s", invocation.FunctionName)
+	}
+
+	return fmt.Sprintf("http://%s:8080/%s", functionHost, strings.TrimPrefix(invocation.Path, "/"))
+}
@@ -50,6 +50,8 @@ func (esg *TriggerGatherer) Gather() error {
 
 	esg.track("EventsHandledSuccessTotal", float64(diffStatistics.EventsHandledSuccessTotal))
 	esg.track("EventsHandledFailureTotal", float64(diffStatistics.EventsHandledFailureTotal))
+	esg.track("EventsDeadLetteredTotal", float64(diffStatistics.EventsDeadLetteredTotal))
+	esg.track("EventsDeadLetterFailureTotal", float64(diffStatistics.EventsDeadLetterFailureTotal))
 
 	return nil
 }
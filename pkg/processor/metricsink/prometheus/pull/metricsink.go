@@ -92,8 +92,12 @@ func (ms *MetricSink) Start() error {
 		return nil
 	}
 
+	// serve /metrics from a mux of our own, so as not to collide with other users of the default one
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/metrics", ms)
+
 	// create server so that we can stop it
-	ms.httpServer = &http.Server{Addr: ms.configuration.URL, Handler: nil}
+	ms.httpServer = &http.Server{Addr: ms.configuration.URL, Handler: serveMux}
 
 	// listen in the background
 	go ms.listen() // nolint: errcheck
@@ -131,9 +135,6 @@ func (ms *MetricSink) listen() error {
 	// save the handler that the registry provides
 	ms.metricRegistryHandler = promhttp.HandlerFor(ms.metricRegistry, promhttp.HandlerOpts{})
 
-	// register ourselves as the handler. we wrap ms.metricRegistryHandler
-	http.Handle("/metrics", ms)
-
 	// start listening
 	if err := ms.httpServer.ListenAndServe(); err != nil {
 		return errors.Wrapf(err, "Failed to listen on %s", ms.configuration.URL)
@@ -17,6 +17,8 @@ limitations under the License.
 package prometheus
 
 import (
+	"strconv"
+
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/nuclio/errors"
@@ -28,10 +30,16 @@ type TriggerGatherer struct {
 	trigger                                     trigger.Trigger
 	logger                                      logger.Logger
 	handledEventsTotal                          *prometheus.CounterVec
+	deadLetteredEventsTotal                     *prometheus.CounterVec
 	workerAllocationCount                       prometheus.Counter
 	workerAllocationTotal                       *prometheus.CounterVec
 	workerAllocationWaitDurationMilliSecondsSum prometheus.Counter
 	workerAllocationWorkersAvailablePercentage  prometheus.Counter
+	consumerLag                                 prometheus.Gauge
+	rateLimitedEventsTotal                      prometheus.Counter
+	rateLimitWaitDurationMilliSecondsSum        prometheus.Counter
+	shardLag                                    *prometheus.GaugeVec
+	shardCommittedSequenceNumber                *prometheus.GaugeVec
 	prevStatistics                              trigger.Statistics
 }
 
@@ -60,6 +68,12 @@ func NewTriggerGatherer(instanceName string,
 		ConstLabels: labels,
 	}, []string{"result"})
 
+	newTriggerGatherer.deadLetteredEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "nuclio_processor_dead_lettered_events_total",
+		Help:        "Total number of events published to the dead-letter target, by result",
+		ConstLabels: labels,
+	}, []string{"result"})
+
 	newTriggerGatherer.workerAllocationTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name:        "nuclio_processor_worker_allocation_total",
 		Help:        "Total number of worker allocations, by result",
@@ -84,13 +98,56 @@ func NewTriggerGatherer(instanceName string,
 		ConstLabels: labels,
 	})
 
-	for _, collector := range []prometheus.Collector{
+	newTriggerGatherer.consumerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "nuclio_processor_consumer_lag",
+		Help:        "Number of messages the trigger has yet to receive (reported by stream triggers, e.g. kafka and jetstream)",
+		ConstLabels: labels,
+	})
+
+	newTriggerGatherer.rateLimitedEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "nuclio_processor_rate_limited_events_total",
+		Help:        "Total number of events held back by the trigger's rate limit (rejected, or which waited for it)",
+		ConstLabels: labels,
+	})
+
+	newTriggerGatherer.rateLimitWaitDurationMilliSecondsSum = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "nuclio_processor_rate_limit_wait_duration_milliseconds_sum",
+		Help:        "Total number of milliseconds events waited for the trigger's rate limit",
+		ConstLabels: labels,
+	})
+
+	collectors := []prometheus.Collector{
 		newTriggerGatherer.handledEventsTotal,
+		newTriggerGatherer.deadLetteredEventsTotal,
 		newTriggerGatherer.workerAllocationTotal,
 		newTriggerGatherer.workerAllocationCount,
 		newTriggerGatherer.workerAllocationWaitDurationMilliSecondsSum,
 		newTriggerGatherer.workerAllocationWorkersAvailablePercentage,
-	} {
+		newTriggerGatherer.consumerLag,
+		newTriggerGatherer.rateLimitedEventsTotal,
+		newTriggerGatherer.rateLimitWaitDurationMilliSecondsSum,
+	}
+
+	// stream triggers which report the state of each shard they consume (e.g. v3io stream)
+	if _, isShardStatisticsProvider := newTriggerGatherer.getShardStatisticsProvider(); isShardStatisticsProvider {
+		newTriggerGatherer.shardLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "nuclio_processor_shard_lag",
+			Help:        "Number of records in the shard the trigger has yet to handle",
+			ConstLabels: labels,
+		}, []string{"shard"})
+
+		newTriggerGatherer.shardCommittedSequenceNumber = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "nuclio_processor_shard_committed_sequence_number",
+			Help:        "Sequence number of the last record in the shard marked as handled",
+			ConstLabels: labels,
+		}, []string{"shard"})
+
+		collectors = append(collectors,
+			newTriggerGatherer.shardLag,
+			newTriggerGatherer.shardCommittedSequenceNumber)
+	}
+
+	for _, collector := range collectors {
 		if err := metricRegistry.Register(collector); err != nil {
 			return nil, errors.Wrap(err, "Failed to register collector")
 		}
@@ -120,6 +177,14 @@ func (tg *TriggerGatherer) Gather() error {
 		"result": "failure",
 	}).Add(float64(diffStatistics.EventsHandledFailureTotal))
 
+	tg.deadLetteredEventsTotal.With(prometheus.Labels{
+		"result": "success",
+	}).Add(float64(diffStatistics.EventsDeadLetteredTotal))
+
+	tg.deadLetteredEventsTotal.With(prometheus.Labels{
+		"result": "failure",
+	}).Add(float64(diffStatistics.EventsDeadLetterFailureTotal))
+
 	tg.workerAllocationCount.Add(
 		float64(diffStatistics.WorkerAllocatorStatistics.WorkerAllocationCount))
 	tg.workerAllocationWaitDurationMilliSecondsSum.Add(
@@ -139,7 +204,36 @@ func (tg *TriggerGatherer) Gather() error {
 		"result": "error_timeout",
 	}).Add(float64(diffStatistics.WorkerAllocatorStatistics.WorkerAllocationTimeoutTotal))
 
+	tg.consumerLag.Set(float64(diffStatistics.ConsumerLag))
+
+	tg.rateLimitedEventsTotal.Add(float64(diffStatistics.RateLimiterStatistics.LimitedTotal))
+	tg.rateLimitWaitDurationMilliSecondsSum.Add(
+		float64(diffStatistics.RateLimiterStatistics.WaitDurationMilliSecondsSum))
+
+	if shardStatisticsProvider, isShardStatisticsProvider := tg.getShardStatisticsProvider(); isShardStatisticsProvider {
+		tg.gatherShardStatistics(shardStatisticsProvider.GetShardStatistics())
+	}
+
 	tg.prevStatistics = currentStatistics
 
 	return nil
 }
+
+func (tg *TriggerGatherer) getShardStatisticsProvider() (trigger.ShardStatisticsProvider, bool) {
+	shardStatisticsProvider, isShardStatisticsProvider := tg.trigger.(trigger.ShardStatisticsProvider)
+	return shardStatisticsProvider, isShardStatisticsProvider
+}
+
+// gatherShardStatistics sets the gauges of the shards the trigger consumes. shards claimed by other replicas
+// since the last gather are no longer reported by this one
+func (tg *TriggerGatherer) gatherShardStatistics(shardsStatistics []trigger.ShardStatistics) {
+	tg.shardLag.Reset()
+	tg.shardCommittedSequenceNumber.Reset()
+
+	for _, shardStatistics := range shardsStatistics {
+		shardLabels := prometheus.Labels{"shard": strconv.Itoa(shardStatistics.ShardID)}
+
+		tg.shardLag.With(shardLabels).Set(float64(shardStatistics.Lag))
+		tg.shardCommittedSequenceNumber.With(shardLabels).Set(float64(shardStatistics.CommittedSequenceNumber))
+	}
+}
@@ -18,6 +18,7 @@ package prometheus
 
 import (
 	"strconv"
+	"sync"
 	"sync/atomic"
 
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
@@ -34,9 +35,58 @@ type WorkerGatherer struct {
 	prevRuntimeStatistics                  runtime.Statistics
 	handledEventsDurationMillisecondsSum   prometheus.Counter
 	handledEventsDurationMillisecondsCount prometheus.Counter
+	handlerDurationMilliseconds            *handlerDurationHistogram
+	runtimeRestartsTotal                   prometheus.Counter
 	logger                                 logger.Logger
 }
 
+// handlerDurationHistogram exposes the runtime's handler duration buckets, as read in the last Gather(),
+// as a prometheus histogram
+type handlerDurationHistogram struct {
+	desc       *prometheus.Desc
+	lock       sync.Mutex
+	statistics runtime.Statistics
+}
+
+func newHandlerDurationHistogram(labels prometheus.Labels) *handlerDurationHistogram {
+	return &handlerDurationHistogram{
+		desc: prometheus.NewDesc("nuclio_processor_handler_duration_milliseconds",
+			"Histogram of the milliseconds it took the handler to handle events",
+			nil,
+			labels),
+	}
+}
+
+func (h *handlerDurationHistogram) Describe(descs chan<- *prometheus.Desc) {
+	descs <- h.desc
+}
+
+func (h *handlerDurationHistogram) Collect(metrics chan<- prometheus.Metric) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// prometheus buckets are cumulative
+	buckets := map[float64]uint64{}
+	cumulativeCount := uint64(0)
+
+	for bucketIndex, bucketUpperBound := range runtime.DurationMilliSecondsBuckets {
+		cumulativeCount += h.statistics.DurationMilliSecondsBucketCounts[bucketIndex]
+		buckets[bucketUpperBound] = cumulativeCount
+	}
+
+	metrics <- prometheus.MustNewConstHistogram(h.desc,
+		h.statistics.DurationMilliSecondsCount,
+		float64(h.statistics.DurationMilliSecondsSum),
+		buckets)
+}
+
+func (h *handlerDurationHistogram) set(statistics *runtime.Statistics) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.statistics = *statistics
+}
+
 func NewWorkerGatherer(instanceName string,
 	trigger trigger.Trigger,
 	logger logger.Logger,
@@ -78,6 +128,22 @@ func NewWorkerGatherer(instanceName string,
 		return nil, errors.Wrap(err, "Failed to register handledEventsDurationCount")
 	}
 
+	newWorkerGatherer.handlerDurationMilliseconds = newHandlerDurationHistogram(labels)
+
+	if err := metricRegistry.Register(newWorkerGatherer.handlerDurationMilliseconds); err != nil {
+		return nil, errors.Wrap(err, "Failed to register handlerDurationMilliseconds")
+	}
+
+	newWorkerGatherer.runtimeRestartsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "nuclio_processor_runtime_restarts_total",
+		Help:        "Total number of times the runtime wrapper was restarted",
+		ConstLabels: labels,
+	})
+
+	if err := metricRegistry.Register(newWorkerGatherer.runtimeRestartsTotal); err != nil {
+		return nil, errors.Wrap(err, "Failed to register runtimeRestartsTotal")
+	}
+
 	newWorkerGatherer.logger.DebugWith("Worker gatherer created",
 		"triggerID", trigger.GetID(),
 		"triggerKind", trigger.GetKind(),
@@ -100,6 +166,10 @@ func (wg *WorkerGatherer) Gather() error {
 
 	wg.handledEventsDurationMillisecondsSum.Add(float64(durationMilliSecondsSum))
 	wg.handledEventsDurationMillisecondsCount.Add(float64(durationMilliSecondsCount))
+	wg.runtimeRestartsTotal.Add(float64(atomic.LoadUint64(&diffRuntimeStatistics.WrapperRestartsTotal)))
+
+	// the histogram is exposed as is, since the runtime statistics are cumulative
+	wg.handlerDurationMilliseconds.set(&currentRuntimeStatistics)
 
 	// save previous
 	wg.prevRuntimeStatistics = currentRuntimeStatistics
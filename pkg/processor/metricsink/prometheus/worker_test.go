@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type workerGathererTestSuite struct {
+	suite.Suite
+}
+
+func (suite *workerGathererTestSuite) TestHandlerDurationHistogram() {
+	histogram := newHandlerDurationHistogram(prometheus.Labels{"function": "f"})
+
+	statistics := runtime.Statistics{}
+	for _, duration := range []time.Duration{
+		3 * time.Millisecond,
+		5 * time.Millisecond,
+		40 * time.Millisecond,
+		2 * time.Second,
+		time.Minute,
+	} {
+		statistics.ObserveDuration(duration)
+	}
+
+	histogram.set(&statistics)
+
+	// buckets are cumulative, the minute long event only appears in +Inf
+	expected := `
+# HELP nuclio_processor_handler_duration_milliseconds Histogram of the milliseconds it took the handler to handle events
+# TYPE nuclio_processor_handler_duration_milliseconds histogram
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="5"} 2
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="10"} 2
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="25"} 2
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="50"} 3
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="100"} 3
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="250"} 3
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="500"} 3
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="1000"} 3
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="2500"} 4
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="5000"} 4
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="10000"} 4
+nuclio_processor_handler_duration_milliseconds_bucket{function="f",le="+Inf"} 5
+nuclio_processor_handler_duration_milliseconds_sum{function="f"} 62048
+nuclio_processor_handler_duration_milliseconds_count{function="f"} 5
+`
+
+	suite.Require().NoError(testutil.CollectAndCompare(histogram, strings.NewReader(expected)))
+}
+
+func (suite *workerGathererTestSuite) TestDiffBucketCounts() {
+	previous := runtime.Statistics{}
+	previous.ObserveDuration(time.Millisecond)
+
+	current := previous
+	current.ObserveDuration(time.Millisecond)
+	current.ObserveDuration(time.Second)
+
+	diff := current.DiffFrom(&previous)
+	suite.Require().Equal(uint64(2), diff.DurationMilliSecondsCount)
+	suite.Require().Equal(uint64(1), diff.DurationMilliSecondsBucketCounts[0])
+	suite.Require().Equal(uint64(1), diff.DurationMilliSecondsBucketCounts[7])
+}
+
+func TestWorkerGathererTestSuite(t *testing.T) {
+	suite.Run(t, new(workerGathererTestSuite))
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Record is an event the function handled, and the function's response to it
+type Record struct {
+	ID            string            `json:"id,omitempty"`
+	Time          time.Time         `json:"time"`
+	FunctionName  string            `json:"functionName"`
+	TriggerKind   string            `json:"triggerKind"`
+	TriggerName   string            `json:"triggerName"`
+	Method        string            `json:"method,omitempty"`
+	Path          string            `json:"path,omitempty"`
+	ContentType   string            `json:"contentType,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          []byte            `json:"body,omitempty"`
+	BodyTruncated bool              `json:"bodyTruncated,omitempty"`
+	Response      Response          `json:"response"`
+}
+
+// Response is the function's response to a recorded event, as the HTTP trigger would have written it
+type Response struct {
+	StatusCode    int               `json:"statusCode"`
+	ContentType   string            `json:"contentType,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          []byte            `json:"body,omitempty"`
+	BodyTruncated bool              `json:"bodyTruncated,omitempty"`
+}
+
+// NewRecord copies the event (which the trigger may reuse once it's handled) and the function's response to
+// it into a record, truncating bodies beyond maxBodySize
+func NewRecord(event nuclio.Event,
+	functionName string,
+	triggerKind string,
+	triggerName string,
+	response interface{},
+	processError error,
+	maxBodySize int) *Record {
+
+	record := &Record{
+		ID:           string(event.GetID()),
+		Time:         event.GetTimestamp(),
+		FunctionName: functionName,
+		TriggerKind:  triggerKind,
+		TriggerName:  triggerName,
+		Method:       event.GetMethod(),
+		Path:         event.GetPath(),
+		ContentType:  event.GetContentType(),
+		Headers:      stringifyHeaders(event.GetHeaders()),
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	record.Body, record.BodyTruncated = truncateBody(event.GetBody(), maxBodySize)
+	record.Response = newResponse(response, processError, maxBodySize)
+
+	return record
+}
+
+func newResponse(response interface{}, processError error, maxBodySize int) Response {
+	var body []byte
+
+	recordedResponse := Response{
+		StatusCode: http.StatusOK,
+	}
+
+	// an error's status code (internal error, unless it has one) and message
+	if processError != nil {
+		switch typedError := processError.(type) {
+		case nuclio.ErrorWithStatusCode:
+			recordedResponse.StatusCode = typedError.StatusCode()
+		case *nuclio.ErrorWithStatusCode:
+			recordedResponse.StatusCode = typedError.StatusCode()
+		default:
+			recordedResponse.StatusCode = http.StatusInternalServerError
+		}
+
+		recordedResponse.Body, recordedResponse.BodyTruncated = truncateBody([]byte(processError.Error()), maxBodySize)
+		return recordedResponse
+	}
+
+	switch typedResponse := response.(type) {
+	case nuclio.Response:
+		body = typedResponse.Body
+		recordedResponse.ContentType = typedResponse.ContentType
+		recordedResponse.Headers = stringifyHeaders(typedResponse.Headers)
+
+		if typedResponse.StatusCode != 0 {
+			recordedResponse.StatusCode = typedResponse.StatusCode
+		}
+	case []byte:
+		body = typedResponse
+	case string:
+		body = []byte(typedResponse)
+	}
+
+	recordedResponse.Body, recordedResponse.BodyTruncated = truncateBody(body, maxBodySize)
+
+	return recordedResponse
+}
+
+// truncateBody copies the body, up to maxBodySize bytes
+func truncateBody(body []byte, maxBodySize int) ([]byte, bool) {
+	if len(body) > maxBodySize {
+		return append([]byte{}, body[:maxBodySize]...), true
+	}
+
+	return append([]byte{}, body...), false
+}
+
+func stringifyHeaders(headers map[string]interface{}) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	stringHeaders := map[string]string{}
+	for headerName, headerValue := range headers {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			stringHeaders[headerName] = typedHeaderValue
+		case []byte:
+			stringHeaders[headerName] = string(typedHeaderValue)
+		case int:
+			stringHeaders[headerName] = strconv.Itoa(typedHeaderValue)
+		default:
+			stringHeaders[headerName] = fmt.Sprint(typedHeaderValue)
+		}
+	}
+
+	return stringHeaders
+}
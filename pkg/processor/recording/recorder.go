@@ -0,0 +1,165 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/rs/xid"
+)
+
+const maxQueuedRecords = 1024
+
+// Recorder writes a sample of the events the processor handles (with the function's responses) to a store,
+// in the background. a nil recorder (returned when recording is disabled) records nothing
+type Recorder struct {
+	logger       logger.Logger
+	store        Store
+	functionName string
+	sampleRatio  float64
+	triggers     []string
+	maxBodySize  int
+	records      chan *Record
+	stopChan     chan struct{}
+	stoppedChan  chan struct{}
+}
+
+// NewRecorder creates a recorder writing to the configured store, or returns nil if recording isn't enabled
+func NewRecorder(parentLogger logger.Logger,
+	configuration *functionconfig.EventRecording,
+	functionName string) (*Recorder, error) {
+
+	if configuration == nil {
+		return nil, nil
+	}
+
+	recorderLogger := parentLogger.GetChild("recorder")
+
+	store, err := NewStore(recorderLogger, configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create recording store")
+	}
+
+	newRecorder := &Recorder{
+		logger:       recorderLogger,
+		store:        store,
+		functionName: functionName,
+		sampleRatio:  configuration.GetSampleRatio(),
+		triggers:     configuration.Triggers,
+		maxBodySize:  configuration.GetMaxBodySize(),
+		records:      make(chan *Record, maxQueuedRecords),
+		stopChan:     make(chan struct{}),
+		stoppedChan:  make(chan struct{}),
+	}
+
+	newRecorder.logger.InfoWith("Recording events",
+		"kind", configuration.Kind,
+		"path", configuration.Path,
+		"sampleRatio", newRecorder.sampleRatio,
+		"triggers", newRecorder.triggers)
+
+	go newRecorder.writeRecords()
+
+	return newRecorder, nil
+}
+
+// Record records the event and the function's response to it, if the event is sampled
+func (r *Recorder) Record(event nuclio.Event,
+	triggerKind string,
+	triggerName string,
+	response interface{},
+	processError error) {
+	if r == nil || !r.shouldRecord(triggerName) {
+		return
+	}
+
+	// the record is created synchronously, as the trigger may reuse the event once it's handled
+	record := NewRecord(event, r.functionName, triggerKind, triggerName, response, processError, r.maxBodySize)
+
+	select {
+	case r.records <- record:
+	default:
+
+		// never block the handling of events on recording
+		r.logger.Debug("Record queue is full, dropping record")
+	}
+}
+
+// Stop writes the queued records and stops recording
+func (r *Recorder) Stop() {
+	if r == nil {
+		return
+	}
+
+	close(r.stopChan)
+	<-r.stoppedChan
+}
+
+func (r *Recorder) shouldRecord(triggerName string) bool {
+	if len(r.triggers) > 0 && !common.StringSliceContainsString(r.triggers, triggerName) {
+		return false
+	}
+
+	return r.sampleRatio >= 1 || rand.Float64() < r.sampleRatio
+}
+
+func (r *Recorder) writeRecords() {
+	for {
+		select {
+		case record := <-r.records:
+			r.writeRecord(record)
+
+		case <-r.stopChan:
+
+			// write whatever was recorded before stopping
+			for {
+				select {
+				case record := <-r.records:
+					r.writeRecord(record)
+				default:
+					close(r.stoppedChan)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Recorder) writeRecord(record *Record) {
+	encodedRecord, err := json.Marshal(record)
+	if err != nil {
+		r.logger.WarnWith("Failed to encode record", "err", err.Error())
+		return
+	}
+
+	if err := r.store.Put(NewRecordName(record), encodedRecord); err != nil {
+		r.logger.WarnWith("Failed to write record", "err", err.Error())
+	}
+}
+
+// NewRecordName returns a unique name for the record, which sorts by the time the event was recorded
+func NewRecordName(record *Record) string {
+	return fmt.Sprintf("%020d-%s%s", record.Time.UnixNano(), xid.New().String(), recordNameSuffix)
+}
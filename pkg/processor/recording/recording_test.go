@@ -0,0 +1,213 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type recordingTestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	tempDir string
+}
+
+func (suite *recordingTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.tempDir, err = ioutil.TempDir("", "recording-test")
+	suite.Require().NoError(err)
+}
+
+func (suite *recordingTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *recordingTestSuite) TestNewRecord() {
+	event := &nuclio.MemoryEvent{
+		Method:      "POST",
+		Path:        "/orders",
+		ContentType: "application/json",
+		Body:        []byte(`{"id":"1234567890"}`),
+		Headers: map[string]interface{}{
+			"X-Request-Id": "abc",
+			"X-Retries":    3,
+		},
+	}
+
+	for _, testCase := range []struct {
+		name             string
+		response         interface{}
+		processError     error
+		expectedResponse Response
+	}{
+		{
+			name: "nuclioResponse",
+			response: nuclio.Response{
+				StatusCode:  http.StatusCreated,
+				ContentType: "text/plain",
+				Headers:     map[string]interface{}{"X-Order": "1234"},
+				Body:        []byte("created"),
+			},
+			expectedResponse: Response{
+				StatusCode:  http.StatusCreated,
+				ContentType: "text/plain",
+				Headers:     map[string]string{"X-Order": "1234"},
+				Body:        []byte("created"),
+			},
+		},
+		{
+			name:     "nuclioResponseWithoutStatusCode",
+			response: nuclio.Response{Body: []byte("ok")},
+			expectedResponse: Response{
+				StatusCode: http.StatusOK,
+				Body:       []byte("ok"),
+			},
+		},
+		{
+			name:     "string",
+			response: "a fairly long response",
+			expectedResponse: Response{
+				StatusCode:    http.StatusOK,
+				Body:          []byte("a fairly l"),
+				BodyTruncated: true,
+			},
+		},
+		{
+			name:         "errorWithStatusCode",
+			processError: nuclio.NewErrBadRequest("bad"),
+			expectedResponse: Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       []byte("bad"),
+			},
+		},
+		{
+			name:         "error",
+			processError: errors.New("failed"),
+			expectedResponse: Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       []byte("failed"),
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			record := NewRecord(event, "orders", "http", "http", testCase.response, testCase.processError, 10)
+
+			suite.Require().Equal("orders", record.FunctionName)
+			suite.Require().Equal("POST", record.Method)
+			suite.Require().Equal("/orders", record.Path)
+			suite.Require().Equal("application/json", record.ContentType)
+			suite.Require().Equal(map[string]string{"X-Request-Id": "abc", "X-Retries": "3"}, record.Headers)
+			suite.Require().Equal([]byte(`{"id":"123`), record.Body)
+			suite.Require().True(record.BodyTruncated)
+			suite.Require().False(record.Time.IsZero())
+			suite.Require().Equal(testCase.expectedResponse, record.Response)
+		})
+	}
+}
+
+func (suite *recordingTestSuite) TestLocalDirStore() {
+	store, err := NewStore(suite.logger, &functionconfig.EventRecording{
+		Kind: functionconfig.EventRecordingKindLocalDir,
+		Path: filepath.Join(suite.tempDir, "records"),
+	})
+	suite.Require().NoError(err)
+
+	for _, name := range []string{"2.json", "1.json"} {
+		suite.Require().NoError(store.Put(name, []byte(name)))
+	}
+
+	// only records are listed
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(suite.tempDir, "records", "README"), nil, 0644))
+
+	names, err := store.List()
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"1.json", "2.json"}, names)
+
+	contents, err := store.Get("2.json")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]byte("2.json"), contents)
+}
+
+func (suite *recordingTestSuite) TestRecorder() {
+	recordsDir := filepath.Join(suite.tempDir, "records")
+
+	recorder, err := NewRecorder(suite.logger, &functionconfig.EventRecording{
+		Kind:     functionconfig.EventRecordingKindLocalDir,
+		Path:     recordsDir,
+		Triggers: []string{"http"},
+	}, "orders")
+	suite.Require().NoError(err)
+
+	recorder.Record(&nuclio.MemoryEvent{Body: []byte("first")}, "http", "http", "1", nil)
+	recorder.Record(&nuclio.MemoryEvent{Body: []byte("ignored")}, "cron", "everyMinute", "2", nil)
+	recorder.Record(&nuclio.MemoryEvent{Body: []byte("second")}, "http", "http", nil, errors.New("failed"))
+	recorder.Stop()
+
+	store, err := NewStore(suite.logger, &functionconfig.EventRecording{
+		Kind: functionconfig.EventRecordingKindLocalDir,
+		Path: recordsDir,
+	})
+	suite.Require().NoError(err)
+
+	names, err := store.List()
+	suite.Require().NoError(err)
+	suite.Require().Len(names, 2)
+
+	var bodies []string
+	for _, name := range names {
+		encodedRecord, err := store.Get(name)
+		suite.Require().NoError(err)
+
+		record := Record{}
+		suite.Require().NoError(json.Unmarshal(encodedRecord, &record))
+		suite.Require().Equal("orders", record.FunctionName)
+		bodies = append(bodies, string(record.Body))
+	}
+
+	suite.Require().ElementsMatch([]string{"first", "second"}, bodies)
+}
+
+func (suite *recordingTestSuite) TestDisabledRecorder() {
+	recorder, err := NewRecorder(suite.logger, nil, "orders")
+	suite.Require().NoError(err)
+	suite.Require().Nil(recorder)
+
+	// a nil recorder records nothing
+	recorder.Record(&nuclio.MemoryEvent{}, "http", "http", nil, nil)
+	recorder.Stop()
+}
+
+func TestRecordingSuite(t *testing.T) {
+	suite.Run(t, new(recordingTestSuite))
+}
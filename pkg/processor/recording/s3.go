@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/nuclio/errors"
+)
+
+// s3Store holds each record in an object of a bucket, under a key prefix
+type s3Store struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+func newS3Store(configuration *functionconfig.EventRecording) (*s3Store, error) {
+	session, err := common.NewAWSSession(&common.AWSSessionConfiguration{
+		Region:   configuration.Region,
+		Endpoint: configuration.Endpoint,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	// S3-compatible stores are addressed by path
+	if configuration.Endpoint != "" {
+		session.Config.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	return &s3Store{
+		client: s3.New(session),
+		bucket: configuration.Bucket,
+		prefix: strings.Trim(configuration.Path, "/"),
+	}, nil
+}
+
+func (ss *s3Store) Put(name string, contents []byte) error {
+	_, err := ss.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(ss.getKey(name)),
+		Body:   bytes.NewReader(contents),
+	})
+
+	return err
+}
+
+func (ss *s3Store) List() ([]string, error) {
+	var names []string
+
+	listPrefix := ""
+	if ss.prefix != "" {
+		listPrefix = ss.prefix + "/"
+	}
+
+	if err := ss.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(ss.bucket),
+		Prefix: aws.String(listPrefix),
+	}, func(output *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range output.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), listPrefix)
+			if !strings.Contains(name, "/") && strings.HasSuffix(name, recordNameSuffix) {
+				names = append(names, name)
+			}
+		}
+
+		return true
+	}); err != nil {
+		return nil, errors.Wrapf(err, "Failed to list objects of bucket %s", ss.bucket)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (ss *s3Store) Get(name string) ([]byte, error) {
+	output, err := ss.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(ss.getKey(name)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get object %s", ss.getKey(name))
+	}
+
+	defer output.Body.Close() // nolint: errcheck
+
+	return ioutil.ReadAll(output.Body)
+}
+
+func (ss *s3Store) getKey(name string) string {
+	return path.Join(ss.prefix, name)
+}
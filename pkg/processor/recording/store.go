@@ -0,0 +1,102 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// the suffix of the names of records in a store
+const recordNameSuffix = ".json"
+
+// Store is where records are written to by the processor, and read from when they're replayed. records are
+// named so that sorting them by name orders them by the time they were recorded
+type Store interface {
+
+	// Put writes an encoded record
+	Put(name string, contents []byte) error
+
+	// List returns the names of the records, sorted
+	List() ([]string, error)
+
+	// Get reads an encoded record
+	Get(name string) ([]byte, error)
+}
+
+// NewStore creates a store of the configured kind
+func NewStore(parentLogger logger.Logger, configuration *functionconfig.EventRecording) (Store, error) {
+	switch configuration.Kind {
+	case functionconfig.EventRecordingKindLocalDir:
+		return newLocalDirStore(configuration.Path)
+	case functionconfig.EventRecordingKindS3:
+		return newS3Store(configuration)
+	case functionconfig.EventRecordingKindV3io:
+		return newV3ioStore(parentLogger, configuration)
+	default:
+		return nil, errors.Errorf("Unsupported event recording kind: %s", configuration.Kind)
+	}
+}
+
+// localDirStore holds each record in a file in a directory
+type localDirStore struct {
+	dir string
+}
+
+func newLocalDirStore(dir string) (*localDirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "Failed to create recording directory %s", dir)
+	}
+
+	return &localDirStore{
+		dir: dir,
+	}, nil
+}
+
+func (lds *localDirStore) Put(name string, contents []byte) error {
+	return ioutil.WriteFile(filepath.Join(lds.dir, name), contents, 0644)
+}
+
+func (lds *localDirStore) List() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(lds.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list recording directory %s", lds.dir)
+	}
+
+	var names []string
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() && strings.HasSuffix(fileInfo.Name(), recordNameSuffix) {
+			names = append(names, fileInfo.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (lds *localDirStore) Get(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(lds.dir, name))
+}
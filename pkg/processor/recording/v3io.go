@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	v3io "github.com/v3io/v3io-go/pkg/dataplane"
+	v3iohttp "github.com/v3io/v3io-go/pkg/dataplane/http"
+)
+
+// v3ioStore holds each record in an object under a path of a v3io container
+type v3ioStore struct {
+	container v3io.Container
+	dir       string
+}
+
+func newV3ioStore(parentLogger logger.Logger, configuration *functionconfig.EventRecording) (*v3ioStore, error) {
+	v3ioContext, err := v3iohttp.NewContext(parentLogger, &v3iohttp.NewContextInput{
+		NumWorkers: 1,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io context")
+	}
+
+	v3ioSession, err := v3ioContext.NewSession(&v3io.NewSessionInput{
+		URL:       configuration.URL,
+		AccessKey: configuration.Secret,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io session")
+	}
+
+	v3ioContainer, err := v3ioSession.NewContainer(&v3io.NewContainerInput{
+		ContainerName: configuration.ContainerName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io container")
+	}
+
+	return &v3ioStore{
+		container: v3ioContainer,
+		dir:       "/" + strings.Trim(configuration.Path, "/"),
+	}, nil
+}
+
+func (vs *v3ioStore) Put(name string, contents []byte) error {
+	return vs.container.PutObjectSync(&v3io.PutObjectInput{
+		Path: path.Join(vs.dir, name),
+		Body: contents,
+	})
+}
+
+func (vs *v3ioStore) List() ([]string, error) {
+	var names []string
+
+	marker := ""
+	for {
+		response, err := vs.container.GetContainerContentsSync(&v3io.GetContainerContentsInput{
+			Path:   vs.dir + "/",
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list %s", vs.dir)
+		}
+
+		output := response.Output.(*v3io.GetContainerContentsOutput)
+		for _, content := range output.Contents {
+			if name := path.Base(content.Key); strings.HasSuffix(name, recordNameSuffix) {
+				names = append(names, name)
+			}
+		}
+
+		response.Release()
+
+		if !output.IsTruncated || output.NextMarker == "" {
+			break
+		}
+
+		marker = output.NextMarker
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (vs *v3ioStore) Get(name string) ([]byte, error) {
+	response, err := vs.container.GetObjectSync(&v3io.GetObjectInput{
+		Path: path.Join(vs.dir, name),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get %s", name)
+	}
+
+	defer response.Release()
+
+	return append([]byte{}, response.Body()...), nil
+}
@@ -16,12 +16,14 @@ using System;
 using System.Net;
 using System.Net.Sockets;
 using System.Text;
+using System.Threading.Tasks;
 
 namespace processor
 {
     public interface ISocketHandler
     {
         void SendMessage(string message);        
+        Task SendMessageAsync(string message);
         event EventHandler MessageReceived;
     }
 }
\ No newline at end of file
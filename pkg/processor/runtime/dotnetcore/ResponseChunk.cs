@@ -0,0 +1,59 @@
+//  Copyright 2017 The Nuclio Authors.
+// 
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+// 
+//      http://www.apache.org/licenses/LICENSE-2.0
+// 
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+using System;
+using System.Text.Json.Serialization;
+
+namespace processor
+{
+    // A chunk of a streamed response body
+    public class ResponseChunk
+    {
+        [JsonPropertyName("body")]
+        public string Body { get; set; }
+
+        [JsonPropertyName("body_encoding")]
+        public string BodyEncoding { get; set; }
+
+        public static ResponseChunk FromBytes(byte[] buffer, int count)
+        {
+            return new ResponseChunk()
+            {
+                Body = Convert.ToBase64String(buffer, 0, count),
+                BodyEncoding = "base64"
+            };
+        }
+
+        public static ResponseChunk FromData(object data)
+        {
+            if (data is byte[] bytes)
+            {
+                return FromBytes(bytes, bytes.Length);
+            }
+
+            return new ResponseChunk()
+            {
+                Body = data == null ? string.Empty : data.ToString(),
+                BodyEncoding = "text"
+            };
+        }
+    }
+
+    // Ends a streamed response body, with the error if the handler failed while streaming it
+    public class ResponseStreamEnd
+    {
+        [JsonPropertyName("error")]
+        public string Error { get; set; }
+    }
+}
@@ -40,6 +40,11 @@ namespace processor
         }
 
         public async void SendMessage(string message)
+        {
+            await SendMessageAsync(message);
+        }
+
+        public async Task SendMessageAsync(string message)
         {
             var data = System.Text.Encoding.UTF8.GetBytes(message);
             if (_socket != null)
@@ -14,7 +14,11 @@
 
 using System;
 using System.Collections.Generic;
+using System.IO;
+using System.Runtime.CompilerServices;
 using System.Text;
+using System.Text.Json;
+using System.Threading.Tasks;
 using Nuclio.Sdk;
 
 namespace processor
@@ -22,6 +26,8 @@ namespace processor
 
     public class Wrapper
     {
+        private const int StreamChunkSize = 64 * 1024;
+
         private delegate object MethodDelegate(Context context, Event eve);
         private static MethodDelegate methodDelegate;
 
@@ -80,6 +86,7 @@ namespace processor
             {
                 var st = new System.Diagnostics.Stopwatch();
                 Response response = null;
+                var streamed = false;
                 var context = new Context();
                 try
                 {
@@ -87,7 +94,19 @@ namespace processor
                     var eve = NuclioSerializationHelpers<Event>.Deserialize(msgArgs.Message);
                     context.Logger.LogEvent += LogEvent;
                     var result = InvokeFunction(context, eve);
-                    response = CreateResponse(result);
+
+                    // the handler returned a body to stream (optionally along with the response to stream it in)
+                    var streamedBody = GetStreamedBody(result);
+                    if (streamedBody != null)
+                    {
+                        var streamedResponse = CreateResponse(GetStreamedResponse(result));
+                        streamed = true;
+                        StreamResponseAsync(streamedResponse, streamedBody).GetAwaiter().GetResult();
+                    }
+                    else
+                    {
+                        response = CreateResponse(result);
+                    }
                 }
                 catch (Exception ex)
                 {
@@ -99,11 +118,87 @@ namespace processor
                     context.Logger.LogEvent -= LogEvent;
                     var metric = new Metric() { Duration = st.Elapsed.TotalSeconds };
                     socketHandler.SendMessage(string.Join(String.Empty, "m", NuclioSerializationHelpers<Metric>.Serialize(metric), Environment.NewLine));
-                    socketHandler.SendMessage(string.Join(String.Empty, "r", NuclioSerializationHelpers<Response>.Serialize(response), Environment.NewLine));
+                    if (!streamed)
+                    {
+                        socketHandler.SendMessage(string.Join(String.Empty, "r", NuclioSerializationHelpers<Response>.Serialize(response), Environment.NewLine));
+                    }
                 }
             }
         }
 
+        // Streams are read in chunks, async enumerables (e.g. of strings or byte arrays) yield the chunks.
+        // Either may be returned alone, or as the body of a (Response, body) tuple
+        private static bool IsStreamable(object value)
+        {
+            return value is Stream || value is IAsyncEnumerable<object>;
+        }
+
+        private static object GetStreamedBody(object result)
+        {
+            if (result is ITuple tuple && tuple.Length == 2 && tuple[0] is Response && IsStreamable(tuple[1]))
+            {
+                return tuple[1];
+            }
+
+            return IsStreamable(result) ? result : null;
+        }
+
+        private static object GetStreamedResponse(object result)
+        {
+            if (result is ITuple tuple && tuple.Length == 2 && tuple[0] is Response)
+            {
+                return tuple[0];
+            }
+
+            return null;
+        }
+
+        // Sends the response followed by the chunks of its body. Chunks are sent one at a time, so the handler
+        // doesn't produce them faster than the processor writes them
+        private async Task StreamResponseAsync(Response response, object body)
+        {
+            // tell the processor the body follows the response
+            var serializedResponse = NuclioSerializationHelpers<Response>.Serialize(response);
+            serializedResponse = serializedResponse.Substring(0, serializedResponse.LastIndexOf('}')) + ",\"stream\":true}";
+            await socketHandler.SendMessageAsync(string.Join(String.Empty, "r", serializedResponse, Environment.NewLine));
+
+            var streamEnd = new ResponseStreamEnd();
+            try
+            {
+                if (body is Stream stream)
+                {
+                    using (stream)
+                    {
+                        var buffer = new byte[StreamChunkSize];
+                        int bytesRead;
+                        while ((bytesRead = await stream.ReadAsync(buffer, 0, buffer.Length)) > 0)
+                        {
+                            await SendResponseChunkAsync(ResponseChunk.FromBytes(buffer, bytesRead));
+                        }
+                    }
+                }
+                else
+                {
+                    await foreach (var data in (IAsyncEnumerable<object>)body)
+                    {
+                        await SendResponseChunkAsync(ResponseChunk.FromData(data));
+                    }
+                }
+            }
+            catch (Exception ex)
+            {
+                Console.WriteLine($"Error streaming response: {ex.Message}");
+                streamEnd.Error = ex.Message;
+            }
+
+            await socketHandler.SendMessageAsync(string.Join(String.Empty, "e", JsonSerializer.Serialize(streamEnd), Environment.NewLine));
+        }
+
+        private Task SendResponseChunkAsync(ResponseChunk chunk)
+        {
+            return socketHandler.SendMessageAsync(string.Join(String.Empty, "c", JsonSerializer.Serialize(chunk), Environment.NewLine));
+        }
+
         private Response CreateResponse(object value)
         {
             // Create use case for every response type. Currently supported is Response, Exception and primitive types.
@@ -123,9 +123,8 @@ func (g *golang) callEntrypoint(event nuclio.Event, functionLogger logger.Logger
 	// calculate how long it took to invoke the function
 	callDuration := time.Since(startTime)
 
-	// add duration to sum
-	g.Statistics.DurationMilliSecondsSum += uint64(callDuration.Nanoseconds() / 1000000)
-	g.Statistics.DurationMilliSecondsCount++
+	// add duration to sum and histogram
+	g.Statistics.ObserveDuration(callDuration)
 
 	return
 }
@@ -41,6 +41,12 @@ public class JsonEvent implements io.nuclio.Event {
     private String type;
     private String type_version;
     private String version;
+    private String control;
+
+    // isPing returns true if this is a liveness ping sent by the processor rather than an event
+    public boolean isPing() {
+        return "ping".equals(this.control);
+    }
 
     @Override
     public byte[] getBody() {
@@ -56,6 +56,12 @@ public class ResponseEncoder {
         this.out.flush();
     }
 
+    public void encodePong() throws Throwable {
+        this.out.write('p');
+        this.out.write('\n');
+        this.out.flush();
+    }
+
     public void encodeMetrics(long duration) throws Throwable {
         this.out.write('m');
 
@@ -165,10 +165,17 @@ public class Wrapper {
 
         while (true) {
             try {
-                Event event = eventReader.next();
+                JsonEvent event = eventReader.next();
                 if (event == null) {
                     break;
                 }
+
+                // answer liveness pings without calling the handler
+                if (event.isPing()) {
+                    responseEncoder.encodePong();
+                    continue;
+                }
+
                 start = System.currentTimeMillis();
                 response = handler.handleEvent(context, event);
             } catch (Exception err) {
@@ -133,3 +133,8 @@ func (j *java) getJVMOptions() ([]string, error) {
 func (j *java) GetEventEncoder(writer io.Writer) rpc.EventEncoder {
 	return rpc.NewEventJSONEncoder(j.Logger, writer)
 }
+
+// SupportsLivenessCheck returns true if the wrapper answers liveness pings
+func (j *java) SupportsLivenessCheck() bool {
+	return true
+}
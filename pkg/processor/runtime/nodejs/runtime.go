@@ -155,3 +155,8 @@ func (n *nodejs) getNodeExePath() (string, error) {
 func (n *nodejs) GetEventEncoder(writer io.Writer) rpc.EventEncoder {
 	return rpc.NewEventJSONEncoder(n.Logger, writer)
 }
+
+// SupportsLivenessCheck returns true if the wrapper answers liveness pings
+func (n *nodejs) SupportsLivenessCheck() bool {
+	return true
+}
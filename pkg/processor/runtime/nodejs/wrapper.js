@@ -8,7 +8,7 @@ function Response(body=null, headers=null, content_type='text/plain', status_cod
     this.content_type = content_type;
     this.status_code = status_code;
 
-    if (!is_string(this.body)) {
+    if (!is_string(this.body) && !is_streamable(this.body)) {
 	this.body = JSON.stringify(this.body);
 	this.content_type = json_ctype;
     }
@@ -32,6 +32,19 @@ function is_string(obj) {
     return typeof(obj) == 'string' || (obj instanceof String)
 }
 
+function is_readable_stream(obj) {
+    return obj !== null && obj !== undefined && typeof(obj.pipe) == 'function' && typeof(obj.on) == 'function';
+}
+
+function is_async_iterable(obj) {
+    return obj !== null && obj !== undefined && typeof(obj[Symbol.asyncIterator]) == 'function';
+}
+
+// Streamable bodies are streamed to the processor in chunks as they're read
+function is_streamable(obj) {
+    return is_readable_stream(obj) || is_async_iterable(obj);
+}
+
 // Status reply is a list of [status, content]
 function is_status_reply(handler_output) {
     if (!handler_output instanceof Array) {
@@ -88,13 +101,136 @@ function response_from_output(handler_output) {
     return response;
 }
 
-function send_reply(handler_output) {
+// Returns the body of the handler output if it should be streamed, null otherwise
+function streamed_body_from_output(handler_output) {
+    if (is_streamable(handler_output)) {
+        return handler_output;
+    }
+
+    if (is_status_reply(handler_output) && is_streamable(handler_output[1])) {
+        return handler_output[1];
+    }
+
+    if ((handler_output instanceof Response) && is_streamable(handler_output.body)) {
+        return handler_output.body;
+    }
+
+    return null;
+}
+
+function streamed_response_from_output(handler_output) {
+    var response = {
+        body: '',
+        content_type: 'text/plain',
+        headers: {},
+        status_code: 200,
+        body_encoding: 'text',
+        stream: true
+    };
+
+    if (is_status_reply(handler_output)) {
+        response.status_code = handler_output[0];
+    } else if (handler_output instanceof Response) {
+        response.content_type = handler_output.content_type;
+        response.headers = handler_output.headers;
+        response.status_code = handler_output.status_code;
+    }
+
+    return response;
+}
+
+function chunk_from_data(data) {
+    var chunk = {
+        body: data,
+        body_encoding: 'text'
+    };
+
+    if ((data instanceof Buffer) || (data instanceof Uint8Array)) {
+        chunk.body = Buffer.from(data).toString('base64');
+        chunk.body_encoding = 'base64';
+    } else if (!is_string(data)) {
+        chunk.body = JSON.stringify(data);
+    }
+
+    return chunk;
+}
+
+// Writes a message to the socket, resolving once the socket can take more
+function write_message(message) {
+    return new Promise(function(resolve) {
+        if (socket.write(message)) {
+            resolve();
+        } else {
+            socket.once('drain', resolve);
+        }
+    });
+}
+
+function write_chunk(data) {
+    return write_message('c' + JSON.stringify(chunk_from_data(data)) + '\n');
+}
+
+function stream_readable(readable) {
+    return new Promise(function(resolve, reject) {
+        readable.on('data', function(data) {
+
+            // the processor reads chunks as fast as the response is written - don't read faster than that
+            if (!socket.write('c' + JSON.stringify(chunk_from_data(data)) + '\n')) {
+                readable.pause();
+                socket.once('drain', function() { readable.resume(); });
+            }
+        });
+        readable.on('end', resolve);
+        readable.on('error', reject);
+    });
+}
+
+async function stream_body(body) {
+    if (is_readable_stream(body)) {
+        await stream_readable(body);
+        return;
+    }
+
+    for await (const data of body) {
+        await write_chunk(data);
+    }
+}
+
+function send_duration() {
     end = new Date();
     var duration = {
         duration: Math.max(0.00000000001, (end.getTime() - start.getTime()) / 1000)
     }
     socket.write('m' + JSON.stringify(duration) + '\n');
-    
+}
+
+// Sends the response followed by the chunks of its body, and ends the stream (with the error, if the body
+// failed while streaming)
+async function send_streamed_reply(handler_output, body) {
+    socket.write('r' + JSON.stringify(streamed_response_from_output(handler_output)) + '\n');
+
+    var stream_end = {};
+
+    try {
+        await stream_body(body);
+    } catch (err) {
+        console.log('ERROR: ' + err);
+        stream_end.error = err.toString();
+    }
+
+    send_duration();
+    socket.write('e' + JSON.stringify(stream_end) + '\n');
+}
+
+function send_reply(handler_output) {
+    var streamed_body = streamed_body_from_output(handler_output);
+    if (streamed_body !== null) {
+        send_streamed_reply(handler_output, streamed_body);
+        return;
+    }
+
+    send_duration();
+
     var response = response_from_output(handler_output);
     socket.write('r' + JSON.stringify(response) + '\n');
 }
@@ -131,6 +267,13 @@ function connectSocket() {
     socket.on('data', function(data) {
         try {
             var evt = JSON.parse(data);
+
+            // answer liveness pings without calling the handler
+            if (evt.control === 'ping') {
+                socket.write('p\n');
+                return;
+            }
+
             evt.body = new Buffer(evt.body, 'base64');
             evt.timestamp = new Date(evt['timestamp'] * 1000);
 
@@ -13,6 +13,7 @@
 # limitations under the License.
 
 import argparse
+import base64
 import json
 import logging
 import re
@@ -31,6 +32,9 @@ class Wrapper(object):
 
     _max_message_size = 4 * 1024 * 1024
 
+    # the time by which the event must be handled, in milliseconds since the epoch
+    _deadline_header = 'x-nuclio-deadline'
+
     def __init__(self,
                  logger,
                  handler,
@@ -47,6 +51,10 @@ class Wrapper(object):
         self._processor_sock = None
         self._platform = nuclio_sdk.Platform(platform_kind, namespace=namespace)
 
+        # allow handlers to invoke functions without waiting for their responses
+        self._platform.call_function_async = self._call_function_async
+        self._platform.call_function_async_batch = self._call_function_async_batch
+
         # holds the function that will be called
         self._entrypoint = self._load_entrypoint_from_handler(handler)
 
@@ -66,6 +74,12 @@ class Wrapper(object):
                                            worker_id,
                                            nuclio_sdk.TriggerInfo(trigger_kind, trigger_name))
 
+        # let handlers know how long they have left to handle the event. once its deadline passes the event is
+        # cancelled, and the processor restarts the wrapper
+        self._event_deadline = None
+        self._context.remaining_time = self._remaining_time
+        self._context.is_cancelled = self._is_cancelled
+
         # call init context
         if hasattr(entrypoint_module, 'init_context'):
             try:
@@ -128,8 +142,14 @@ class Wrapper(object):
 
                 msg = next(self._unpacker)
 
+                # answer liveness pings without calling the handler
+                if msg.get('control') == 'ping':
+                    self._write_packet_to_processor('p')
+                    continue
+
                 # decode the event
                 event = nuclio_sdk.Event.from_msgpack(msg)
+                self._event_deadline = self._get_event_deadline(event)
 
                 try:
 
@@ -177,6 +197,29 @@ class Wrapper(object):
             if num_requests == 0:
                 break
 
+    def _remaining_time(self):
+        """Returns the seconds left to handle the current event, or None if it has no deadline"""
+        if self._event_deadline is None:
+            return None
+
+        return max(0.0, self._event_deadline - time.time())
+
+    def _is_cancelled(self):
+        """Returns whether the deadline of the current event passed"""
+        return self._event_deadline is not None and time.time() >= self._event_deadline
+
+    def _get_event_deadline(self, event):
+        headers = getattr(event, 'headers', None) or {}
+
+        for header_name, header_value in headers.items():
+            if str(header_name).lower() == self._deadline_header:
+                try:
+                    return int(header_value) / 1000.0
+                except (TypeError, ValueError):
+                    return None
+
+        return None
+
     def _load_entrypoint_from_handler(self, handler):
         """
         Load handler function from handler.
@@ -217,6 +260,46 @@ class Wrapper(object):
 
         raise RuntimeError('Failed to connect to {0} in given timeframe'.format(self._socket_path))
 
+    def _call_function_async(self, function_name, event):
+        """Enqueue an invocation of a function with the event, without waiting for its response"""
+        self._call_function_async_batch(function_name, [event])
+
+    def _call_function_async_batch(self, function_name, events):
+        """
+        Enqueue an invocation of a function with each of the events. The processor delivers them according to
+        the function's asyncInvocation configuration
+        """
+        self._write_packet_to_processor('i' + json.dumps({
+            'function_name': function_name,
+            'events': [self._encode_async_invocation(event) for event in events],
+        }))
+
+    @staticmethod
+    def _encode_async_invocation(event):
+        body = getattr(event, 'body', None)
+        content_type = getattr(event, 'content_type', None)
+
+        # encode bodies the same way call_function does
+        if isinstance(body, (dict, list)):
+            body = json.dumps(body)
+            content_type = content_type or 'application/json'
+
+        if body is None:
+            body = b''
+
+        if not isinstance(body, bytes):
+            body = body.encode('utf-8')
+
+        headers = getattr(event, 'headers', None) or {}
+
+        return {
+            'method': getattr(event, 'method', None) or 'POST',
+            'path': getattr(event, 'path', None) or '/',
+            'content_type': content_type or '',
+            'headers': dict((str(key), str(value)) for key, value in headers.items()),
+            'body': base64.b64encode(body).decode('ascii'),
+        }
+
     def _write_packet_to_processor(self, body):
         self._processor_sock_wfile.write(body + '\n')
         self._processor_sock_wfile.flush()
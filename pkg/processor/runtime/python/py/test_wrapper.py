@@ -12,6 +12,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
+import base64
 import io
 import json
 import logging
@@ -241,3 +242,79 @@ class TestCallFunction(unittest.TestCase):
         self._mockConnection.url = url
 
         return self._mockConnection
+
+
+class TestEventDeadline(unittest.TestCase):
+
+    def setUp(self):
+
+        # only the deadline of the event is needed
+        self._wrapper = wrapper.Wrapper.__new__(wrapper.Wrapper)
+        self._wrapper._event_deadline = None
+
+    def test_no_deadline(self):
+        self._wrapper._event_deadline = self._wrapper._get_event_deadline(nuclio_sdk.Event(body='body'))
+
+        self.assertIsNone(self._wrapper._remaining_time())
+        self.assertFalse(self._wrapper._is_cancelled())
+
+    def test_deadline(self):
+        event = nuclio_sdk.Event(body='body')
+        event.headers = {'X-Nuclio-Deadline': str(int((time.time() + 60) * 1000))}
+        self._wrapper._event_deadline = self._wrapper._get_event_deadline(event)
+
+        self.assertTrue(50 < self._wrapper._remaining_time() <= 60)
+        self.assertFalse(self._wrapper._is_cancelled())
+
+        # the deadline passed
+        event.headers = {'x-nuclio-deadline': str(int((time.time() - 1) * 1000))}
+        self._wrapper._event_deadline = self._wrapper._get_event_deadline(event)
+
+        self.assertEqual(0, self._wrapper._remaining_time())
+        self.assertTrue(self._wrapper._is_cancelled())
+
+
+class TestCallFunctionAsync(unittest.TestCase):
+
+    def setUp(self):
+
+        # only the packet writing of the wrapper is needed
+        self._wrapper = wrapper.Wrapper.__new__(wrapper.Wrapper)
+        self._wrapper._write_packet_to_processor = mock.MagicMock()
+
+    def test_call_function_async(self):
+        event = nuclio_sdk.Event(method='PUT', path='/items', body={'a': 'some_body'})
+        event.headers = {'X-Id': 1}
+
+        self._wrapper._call_function_async('function-name', event)
+
+        packet = self._wrapper._write_packet_to_processor.call_args[0][0]
+        self.assertEqual('i', packet[0])
+
+        invocations = json.loads(packet[1:])
+        self.assertEqual('function-name', invocations['function_name'])
+        self.assertEqual(1, len(invocations['events']))
+
+        encoded_event = invocations['events'][0]
+        self.assertEqual('PUT', encoded_event['method'])
+        self.assertEqual('/items', encoded_event['path'])
+        self.assertEqual('application/json', encoded_event['content_type'])
+        self.assertEqual({'X-Id': '1'}, encoded_event['headers'])
+        self.assertEqual({'a': 'some_body'}, json.loads(base64.b64decode(encoded_event['body']).decode('utf-8')))
+
+    def test_call_function_async_batch(self):
+        events = [
+            nuclio_sdk.Event(body='text'),
+            nuclio_sdk.Event(body=b'\x00\x01'),
+        ]
+
+        self._wrapper._call_function_async_batch('function-name', events)
+
+        # all the events are sent in a single packet
+        self.assertEqual(1, self._wrapper._write_packet_to_processor.call_count)
+
+        packet = self._wrapper._write_packet_to_processor.call_args[0][0]
+        encoded_events = json.loads(packet[1:])['events']
+
+        self.assertEqual([b'text', b'\x00\x01'], [base64.b64decode(encoded_event['body'])
+                                                  for encoded_event in encoded_events])
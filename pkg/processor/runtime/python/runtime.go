@@ -180,3 +180,8 @@ func (py *python) getPythonExePath() (string, error) {
 func (py *python) GetEventEncoder(writer io.Writer) rpc.EventEncoder {
 	return rpc.NewEventMsgPackEncoder(py.Logger, writer)
 }
+
+// SupportsLivenessCheck returns true if the wrapper answers liveness pings
+func (py *python) SupportsLivenessCheck() bool {
+	return true
+}
@@ -22,12 +22,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 	"github.com/nuclio/nuclio/pkg/processwaiter"
@@ -51,10 +58,53 @@ type result struct {
 	BodyEncoding string                 `json:"body_encoding"`
 	Headers      map[string]interface{} `json:"headers"`
 
+	// the handler streams the body in chunks following the result
+	Stream bool `json:"stream"`
+
 	DecodedBody []byte
+	stream      *resultStream
 	err         error
 }
 
+// resultChunk is a chunk of a streamed body. the last one (ending the stream) has no body, and an error if the
+// handler failed while streaming
+type resultChunk struct {
+	Body         string `json:"body"`
+	BodyEncoding string `json:"body_encoding"`
+	Error        string `json:"error"`
+}
+
+// resultStream pipes the chunks of a streamed body from the wrapper to whoever reads the response
+type resultStream struct {
+	reader   *io.PipeReader
+	writer   *io.PipeWriter
+	doneChan chan struct{}
+	endOnce  sync.Once
+}
+
+func newResultStream() *resultStream {
+	reader, writer := io.Pipe()
+
+	return &resultStream{
+		reader:   reader,
+		writer:   writer,
+		doneChan: make(chan struct{}),
+	}
+}
+
+func (rs *resultStream) write(chunk []byte) {
+
+	// the reader closed the body (e.g. the client disconnected) - discard the rest of it
+	rs.writer.Write(chunk) // nolint: errcheck
+}
+
+func (rs *resultStream) end(err error) {
+	rs.endOnce.Do(func() {
+		rs.writer.CloseWithError(err) // nolint: errcheck
+		close(rs.doneChan)
+	})
+}
+
 // Runtime is a runtime that communicates via unix domain socket
 type AbstractRuntime struct {
 	runtime.AbstractRuntime
@@ -67,6 +117,40 @@ type AbstractRuntime struct {
 	startChan      chan struct{}
 	socketType     SocketType
 	processWaiter  *processwaiter.ProcessWaiter
+
+	// the liveness watcher restarts the wrapper from its own goroutine. lock guards the status and the current
+	// wrapper (its process, waiter and result channel), and wrapperLock serializes starting and stopping it
+	lock        sync.Mutex
+	wrapperLock sync.Mutex
+
+	// events and liveness pings are written to the wrapper from different goroutines
+	encoderLock      sync.Mutex
+	pongChan         chan struct{}
+	livenessStopChan chan struct{}
+
+	// guarded by lock
+	livenessStatus functionconfig.RuntimeLivenessStatus
+
+	// the body the handler streams for the current event, if any
+	streamLock    sync.Mutex
+	currentStream *resultStream
+}
+
+// rpcAsyncInvocations are invocations the handler enqueues for another function (e.g. with
+// context.platform.call_function_async)
+type rpcAsyncInvocations struct {
+	FunctionName string               `json:"function_name"`
+	Events       []rpcAsyncInvocation `json:"events"`
+}
+
+type rpcAsyncInvocation struct {
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers"`
+
+	// base64 encoded
+	Body []byte `json:"body"`
 }
 
 type rpcLogRecord struct {
@@ -92,17 +176,23 @@ func NewAbstractRuntime(logger logger.Logger,
 		configuration:   configuration,
 		runtime:         runtimeInstance,
 		startChan:       make(chan struct{}, 1),
+		pongChan:        make(chan struct{}, 1),
 	}
 
 	return newRuntime, nil
 }
 
 func (r *AbstractRuntime) Start() error {
+	r.wrapperLock.Lock()
+	defer r.wrapperLock.Unlock()
+
 	if err := r.startWrapper(); err != nil {
 		r.SetStatus(status.Error)
 		return errors.Wrap(err, "Failed to run wrapper")
 	}
 
+	r.startLivenessWatcher()
+
 	r.SetStatus(status.Ready)
 	return nil
 }
@@ -113,15 +203,21 @@ func (r *AbstractRuntime) ProcessEvent(event nuclio.Event, functionLogger logger
 		return nil, errors.Errorf("Processor not ready (current status: %s)", currentStatus)
 	}
 
+	// the wrapper handles the next event once it's done streaming the body of the previous one
+	r.waitForStreamEnd()
+
 	r.functionLogger = functionLogger
 
 	// We don't use defer to reset r.functionLogger since it decreases performance
-	if err := r.eventEncoder.Encode(event); err != nil {
+	r.encoderLock.Lock()
+	err := r.eventEncoder.Encode(event)
+	r.encoderLock.Unlock()
+	if err != nil {
 		r.functionLogger = nil
 		return nil, errors.Wrapf(err, "Can't encode event: %+v", event)
 	}
 
-	result, ok := <-r.resultChan
+	result, ok := <-r.getResultChan()
 	r.functionLogger = nil
 	if !ok {
 		msg := "Client disconnected"
@@ -131,43 +227,61 @@ func (r *AbstractRuntime) ProcessEvent(event nuclio.Event, functionLogger logger
 		return nil, errors.New(msg)
 	}
 
-	return nuclio.Response{
+	response := nuclio.Response{
 		Body:        result.DecodedBody,
 		ContentType: result.ContentType,
 		Headers:     result.Headers,
 		StatusCode:  result.StatusCode,
+	}
+
+	if result.stream == nil {
+		return response, nil
+	}
+
+	// only the http trigger writes the body as it's streamed, other triggers get it whole
+	if r.configuration.TriggerKind != "http" {
+		body, err := ioutil.ReadAll(result.stream.reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read streamed response")
+		}
+
+		response.Body = body
+		return response, nil
+	}
+
+	return &runtime.StreamedResponse{
+		Response:   response,
+		BodyStream: result.stream.reader,
 	}, nil
 }
 
 // Stop stops the runtime
 func (r *AbstractRuntime) Stop() error {
-	if r.wrapperProcess != nil {
-
-		// stop waiting for process
-		if err := r.processWaiter.Cancel(); err != nil {
-			r.Logger.WarnWith("Failed to cancel process waiting")
-		}
-
-		err := r.wrapperProcess.Kill()
-		if err != nil {
-			r.SetStatus(status.Error)
-			return errors.Wrap(err, "Can't kill wrapper process")
-		}
+	if r.livenessStopChan != nil {
+		close(r.livenessStopChan)
+		r.livenessStopChan = nil
 	}
 
-	r.SetStatus(status.Stopped)
-	return nil
+	r.wrapperLock.Lock()
+	defer r.wrapperLock.Unlock()
+
+	return r.stopWrapper()
 }
 
 // Restart restarts the runtime
 func (r *AbstractRuntime) Restart() error {
-	if err := r.Stop(); err != nil {
+	r.wrapperLock.Lock()
+	defer r.wrapperLock.Unlock()
+
+	if err := r.stopWrapper(); err != nil {
 		return err
 	}
 
+	resultChan := r.getResultChan()
+
 	// Send error for current event (non-blocking)
 	select {
-	case r.resultChan <- &result{
+	case resultChan <- &result{
 		StatusCode: http.StatusRequestTimeout,
 		err:        errors.New("Runtime restarted"),
 	}:
@@ -176,7 +290,7 @@ func (r *AbstractRuntime) Restart() error {
 		r.Logger.Warn("Nothing waiting on result channel during restart. Continuing")
 	}
 
-	close(r.resultChan)
+	close(resultChan)
 	if err := r.startWrapper(); err != nil {
 		r.SetStatus(status.Error)
 		return errors.Wrap(err, "Can't start wrapper process")
@@ -201,6 +315,84 @@ func (r *AbstractRuntime) SupportsRestart() bool {
 	return true
 }
 
+// SupportsLivenessCheck returns true if the wrapper answers liveness pings
+func (r *AbstractRuntime) SupportsLivenessCheck() bool {
+	return false
+}
+
+// SetStatus sets the runtime's reported status
+func (r *AbstractRuntime) SetStatus(newStatus status.Status) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.AbstractRuntime.SetStatus(newStatus)
+}
+
+// GetStatus returns the runtime's reported status
+func (r *AbstractRuntime) GetStatus() status.Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.AbstractRuntime.GetStatus()
+}
+
+// GetLivenessStatus returns the restarts of the wrapper due to failed liveness checks
+func (r *AbstractRuntime) GetLivenessStatus() functionconfig.RuntimeLivenessStatus {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.livenessStatus
+}
+
+// recordLivenessFailure records a ping the wrapper didn't respond to, which either restarted the wrapper or
+// (once it can't be restarted anymore) failed the runtime
+func (r *AbstractRuntime) recordLivenessFailure(pingErr error, restarted bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.livenessStatus.LastError = pingErr.Error()
+
+	if !restarted {
+		r.livenessStatus.Failed = true
+		return
+	}
+
+	now := time.Now()
+	r.livenessStatus.Restarts++
+	r.livenessStatus.LastRestartTime = &now
+}
+
+// stopWrapper stops the current wrapper, if any. must be called while holding the wrapper lock
+func (r *AbstractRuntime) stopWrapper() error {
+
+	// a body being streamed won't be anymore
+	r.endStream(errors.New("Wrapper stopped"))
+
+	r.lock.Lock()
+	wrapperProcess := r.wrapperProcess
+	processWaiter := r.processWaiter
+	r.wrapperProcess = nil
+	r.lock.Unlock()
+
+	if wrapperProcess != nil {
+
+		// stop waiting for process
+		if err := processWaiter.Cancel(); err != nil {
+			r.Logger.WarnWith("Failed to cancel process waiting")
+		}
+
+		err := wrapperProcess.Kill()
+		if err != nil {
+			r.SetStatus(status.Error)
+			return errors.Wrap(err, "Can't kill wrapper process")
+		}
+	}
+
+	r.SetStatus(status.Stopped)
+	return nil
+}
+
+// startWrapper runs a new wrapper and connects to it. must be called while holding the wrapper lock
 func (r *AbstractRuntime) startWrapper() error {
 	var err error
 
@@ -217,7 +409,7 @@ func (r *AbstractRuntime) startWrapper() error {
 		return errors.Wrap(err, "Can't create listener")
 	}
 
-	r.processWaiter, err = processwaiter.NewProcessWaiter()
+	processWaiter, err := processwaiter.NewProcessWaiter()
 	if err != nil {
 		return errors.Wrap(err, "Failed to create process waiter")
 	}
@@ -227,9 +419,12 @@ func (r *AbstractRuntime) startWrapper() error {
 		return errors.Wrap(err, "Can't run wrapper")
 	}
 
+	r.lock.Lock()
 	r.wrapperProcess = wrapperProcess
+	r.processWaiter = processWaiter
+	r.lock.Unlock()
 
-	go r.watchWrapperProcess()
+	go r.watchWrapperProcess(processWaiter, wrapperProcess)
 
 	conn, err := listener.Accept()
 	if err != nil {
@@ -238,9 +433,17 @@ func (r *AbstractRuntime) startWrapper() error {
 
 	r.Logger.InfoWith("Wrapper connected", "wid", r.Context.WorkerID)
 
+	r.encoderLock.Lock()
 	r.eventEncoder = r.runtime.GetEventEncoder(conn)
-	r.resultChan = make(chan *result)
-	go r.wrapperOutputHandler(conn, r.resultChan)
+	r.encoderLock.Unlock()
+
+	resultChan := make(chan *result)
+
+	r.lock.Lock()
+	r.resultChan = resultChan
+	r.lock.Unlock()
+
+	go r.wrapperOutputHandler(conn, resultChan)
 
 	// wait for start if required to
 	if r.runtime.WaitForStart() {
@@ -258,6 +461,11 @@ func (r *AbstractRuntime) startWrapper() error {
 func (r *AbstractRuntime) createUnixListener() (net.Listener, string, error) {
 	socketPath := fmt.Sprintf(socketPathTemplate, xid.New().String())
 
+	// windows images have no /tmp, but their user's temporary directory
+	if goruntime.GOOS == "windows" {
+		socketPath = filepath.Join(os.TempDir(), filepath.Base(socketPath))
+	}
+
 	if common.FileExists(socketPath) {
 		if err := os.Remove(socketPath); err != nil {
 			return nil, "", errors.Wrapf(err, "Can't remove socket at %q", socketPath)
@@ -323,6 +531,7 @@ func (r *AbstractRuntime) wrapperOutputHandler(conn io.Reader, resultChan chan *
 
 		if unmarshalledResult.err != nil {
 			r.Logger.WarnWith(string(common.FailedReadFromConnection), "err", unmarshalledResult.err)
+			r.endStream(unmarshalledResult.err)
 			resultChan <- unmarshalledResult
 			continue
 		}
@@ -332,31 +541,130 @@ func (r *AbstractRuntime) wrapperOutputHandler(conn io.Reader, resultChan chan *
 
 			// try to unmarshall the result
 			if unmarshalledResult.err = json.Unmarshal(data[1:], unmarshalledResult); unmarshalledResult.err != nil {
-				r.resultChan <- unmarshalledResult
+				resultChan <- unmarshalledResult
 				continue
 			}
 
-			switch unmarshalledResult.BodyEncoding {
-			case "text":
-				unmarshalledResult.DecodedBody = []byte(unmarshalledResult.Body)
-			case "base64":
-				unmarshalledResult.DecodedBody, unmarshalledResult.err = base64.StdEncoding.DecodeString(unmarshalledResult.Body)
-			default:
-				unmarshalledResult.err = fmt.Errorf("Unknown body encoding - %q", unmarshalledResult.BodyEncoding)
+			unmarshalledResult.DecodedBody, unmarshalledResult.err = decodeBody(unmarshalledResult.Body,
+				unmarshalledResult.BodyEncoding)
+
+			// the body follows in chunks. the stream must be current before the result is handed over, so the
+			// next event waits for it to end
+			if unmarshalledResult.Stream && unmarshalledResult.err == nil {
+				unmarshalledResult.stream = r.startStream()
 			}
 
 			// write back to result channel
 			resultChan <- unmarshalledResult
+		case 'c':
+			r.handleResultChunk(data[1:])
+		case 'e':
+			r.handleResultStreamEnd(data[1:])
 		case 'm':
 			r.handleResponseMetric(data[1:])
 		case 'l':
 			r.handleResponseLog(data[1:])
+		case 'i':
+			r.handleAsyncInvocations(data[1:])
 		case 's':
 			r.handleStart()
+		case 'p':
+			r.handlePong()
 		}
 	}
 }
 
+func decodeBody(body string, bodyEncoding string) ([]byte, error) {
+	switch bodyEncoding {
+	case "text":
+		return []byte(body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("Unknown body encoding - %q", bodyEncoding)
+	}
+}
+
+func (r *AbstractRuntime) startStream() *resultStream {
+	r.streamLock.Lock()
+	defer r.streamLock.Unlock()
+
+	r.currentStream = newResultStream()
+	return r.currentStream
+}
+
+func (r *AbstractRuntime) endStream(err error) {
+	r.streamLock.Lock()
+	currentStream := r.currentStream
+	r.currentStream = nil
+	r.streamLock.Unlock()
+
+	if currentStream != nil {
+		currentStream.end(err)
+	}
+}
+
+func (r *AbstractRuntime) isStreaming() bool {
+	r.streamLock.Lock()
+	defer r.streamLock.Unlock()
+
+	return r.currentStream != nil
+}
+
+func (r *AbstractRuntime) waitForStreamEnd() {
+	r.streamLock.Lock()
+	currentStream := r.currentStream
+	r.streamLock.Unlock()
+
+	if currentStream != nil {
+		<-currentStream.doneChan
+	}
+}
+
+func (r *AbstractRuntime) handleResultChunk(response []byte) {
+	r.streamLock.Lock()
+	currentStream := r.currentStream
+	r.streamLock.Unlock()
+
+	if currentStream == nil {
+		r.Logger.Warn("Got a response chunk while no response is streamed, ignoring it")
+		return
+	}
+
+	var chunk resultChunk
+	if err := json.Unmarshal(response, &chunk); err != nil {
+		r.endStream(errors.Wrap(err, "Can't decode response chunk"))
+		return
+	}
+
+	decodedBody, err := decodeBody(chunk.Body, chunk.BodyEncoding)
+	if err != nil {
+		r.endStream(errors.Wrap(err, "Can't decode response chunk body"))
+		return
+	}
+
+	// blocks until read, so the wrapper doesn't stream faster than the response is written
+	currentStream.write(decodedBody)
+}
+
+func (r *AbstractRuntime) handleResultStreamEnd(response []byte) {
+	var chunk resultChunk
+
+	if err := json.Unmarshal(response, &chunk); err != nil {
+		r.endStream(errors.Wrap(err, "Can't decode response stream end"))
+		return
+	}
+
+	if chunk.Error != "" {
+		r.resolveFunctionLogger(r.functionLogger).WarnWith("Handler failed while streaming response",
+			"err", chunk.Error)
+		r.endStream(errors.New(chunk.Error))
+		return
+	}
+
+	r.endStream(nil)
+}
+
 func (r *AbstractRuntime) handleResponseLog(response []byte) {
 	var logRecord rpcLogRecord
 
@@ -381,6 +689,36 @@ func (r *AbstractRuntime) handleResponseLog(response []byte) {
 	logFunc(logRecord.Message, vars...)
 }
 
+// handleAsyncInvocations enqueues the invocations. the wrapper doesn't wait for them to be enqueued, so failures
+// are only logged
+func (r *AbstractRuntime) handleAsyncInvocations(response []byte) {
+	var asyncInvocations rpcAsyncInvocations
+
+	if err := json.Unmarshal(response, &asyncInvocations); err != nil {
+		r.Logger.ErrorWith("Can't decode async invocations", "error", err)
+		return
+	}
+
+	invocations := make([]*invocation.Invocation, len(asyncInvocations.Events))
+	for eventIndex, event := range asyncInvocations.Events {
+		invocations[eventIndex] = &invocation.Invocation{
+			FunctionName: asyncInvocations.FunctionName,
+			Method:       event.Method,
+			Path:         event.Path,
+			ContentType:  event.ContentType,
+			Headers:      event.Headers,
+			Body:         event.Body,
+		}
+	}
+
+	if err := r.configuration.AsyncInvoker.Enqueue(invocations...); err != nil {
+		r.resolveFunctionLogger(r.functionLogger).ErrorWith("Failed to enqueue async invocations",
+			"function", asyncInvocations.FunctionName,
+			"invocations", len(invocations),
+			"err", errors.RootCause(err).Error())
+	}
+}
+
 func (r *AbstractRuntime) handleResponseMetric(response []byte) {
 	var metrics struct {
 		DurationSec float64 `json:"duration"`
@@ -397,14 +735,133 @@ func (r *AbstractRuntime) handleResponseMetric(response []byte) {
 		return
 	}
 
-	r.Statistics.DurationMilliSecondsCount++
-	r.Statistics.DurationMilliSecondsSum += uint64(metrics.DurationSec * 1000)
+	r.Statistics.ObserveDuration(time.Duration(metrics.DurationSec * float64(time.Second)))
 }
 
 func (r *AbstractRuntime) handleStart() {
 	r.startChan <- struct{}{}
 }
 
+func (r *AbstractRuntime) handlePong() {
+
+	// non blocking, a pong nobody waits for (its ping timed out) is dropped by the next ping
+	select {
+	case r.pongChan <- struct{}{}:
+	default:
+	}
+}
+
+func (r *AbstractRuntime) startLivenessWatcher() {
+	liveness := r.configuration.Spec.RuntimeLiveness
+	if liveness == nil {
+		return
+	}
+
+	if !r.runtime.SupportsLivenessCheck() {
+		r.Logger.WarnWith("Runtime doesn't support liveness checks, ignoring them",
+			"runtime", r.configuration.Spec.Runtime)
+		return
+	}
+
+	r.livenessStopChan = make(chan struct{})
+
+	go r.watchWrapperLiveness(liveness.GetInterval(),
+		liveness.GetTimeout(),
+		liveness.GetMaxRestarts(),
+		r.livenessStopChan)
+}
+
+// watchWrapperLiveness pings the wrapper every interval and restarts it if it doesn't respond within the
+// timeout, up to maxRestarts times. after that the runtime is set to error, failing the processor's liveness
+func (r *AbstractRuntime) watchWrapperLiveness(interval time.Duration,
+	timeout time.Duration,
+	maxRestarts int,
+	stopChan chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	restarts := 0
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		if r.GetStatus() != status.Ready {
+			continue
+		}
+
+		// pongs are read after the chunks of a streamed body, which are read as fast as the client reads them
+		if r.isStreaming() {
+			continue
+		}
+
+		pingErr := r.pingWrapper(timeout, stopChan)
+		if pingErr == nil {
+			continue
+		}
+
+		// we may have been stopped while waiting for the pong
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+
+		if restarts >= maxRestarts {
+			r.recordLivenessFailure(pingErr, false)
+			r.Logger.ErrorWith("Wrapper isn't responding and can't be restarted anymore",
+				"wid", r.Context.WorkerID,
+				"err", pingErr.Error(),
+				"restarts", restarts)
+			r.SetStatus(status.Error)
+			return
+		}
+
+		restarts++
+		atomic.AddUint64(&r.Statistics.WrapperRestartsTotal, 1)
+		r.recordLivenessFailure(pingErr, true)
+
+		r.Logger.WarnWith("Wrapper isn't responding, restarting it",
+			"wid", r.Context.WorkerID,
+			"err", pingErr.Error(),
+			"restart", restarts,
+			"maxRestarts", maxRestarts)
+
+		if err := r.Restart(); err != nil {
+			r.Logger.ErrorWith("Failed to restart wrapper", "wid", r.Context.WorkerID, "err", err.Error())
+			return
+		}
+	}
+}
+
+func (r *AbstractRuntime) pingWrapper(timeout time.Duration, stopChan chan struct{}) error {
+
+	// drop the pong of a previous ping that timed out
+	select {
+	case <-r.pongChan:
+	default:
+	}
+
+	r.encoderLock.Lock()
+	err := r.eventEncoder.EncodePing()
+	r.encoderLock.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "Failed to send ping")
+	}
+
+	select {
+	case <-r.pongChan:
+		return nil
+	case <-stopChan:
+		return errors.New("Stopped while waiting for pong")
+	case <-time.After(timeout):
+		return errors.Errorf("No pong within %s", timeout)
+	}
+}
+
 // resolveFunctionLogger return either functionLogger if provided or root logger if not
 func (r *AbstractRuntime) resolveFunctionLogger(functionLogger logger.Logger) logger.Logger {
 	if functionLogger == nil {
@@ -419,13 +876,34 @@ func (r *AbstractRuntime) newResultChan() {
 	r.resultChan = make(chan *result, 1)
 }
 
-func (r *AbstractRuntime) watchWrapperProcess() {
+func (r *AbstractRuntime) getResultChan() chan *result {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.resultChan
+}
+
+func (r *AbstractRuntime) getWrapperProcess() *os.Process {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-	// whatever happens, clear wrapper process
-	defer func() { r.wrapperProcess = nil }()
+	return r.wrapperProcess
+}
+
+// watchWrapperProcess waits for the given wrapper process, which a restart may have replaced by the time it exits
+func (r *AbstractRuntime) watchWrapperProcess(processWaiter *processwaiter.ProcessWaiter, wrapperProcess *os.Process) {
+
+	// whatever happens, clear wrapper process (unless it was already replaced)
+	defer func() {
+		r.lock.Lock()
+		if r.wrapperProcess == wrapperProcess {
+			r.wrapperProcess = nil
+		}
+		r.lock.Unlock()
+	}()
 
 	// wait for the process
-	processWaitResult := <-r.processWaiter.Wait(r.wrapperProcess, nil)
+	processWaitResult := <-processWaiter.Wait(wrapperProcess, nil)
 
 	// if we were simply canceled, do nothing
 	if processWaitResult.Err == processwaiter.ErrCancelled {
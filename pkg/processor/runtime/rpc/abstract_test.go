@@ -14,28 +14,33 @@ limitations under the License.
 package rpc
 
 import (
+	"bufio"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
 )
 
 type testRuntime struct {
 	*AbstractRuntime
-	wrapperProcess *os.Process
-	wrapperConn    net.Conn
+	wrapperConn net.Conn
 }
 
 // NewRuntime returns a new Python runtime
@@ -61,8 +66,6 @@ func (r *testRuntime) RunWrapper(socketPath string) (*os.Process, error) {
 	if err = cmd.Start(); err != nil {
 		return nil, err
 	}
-	r.wrapperProcess = cmd.Process
-
 	// Connect to runtime
 	r.wrapperConn, err = net.Dial("unix", socketPath)
 	if err != nil {
@@ -76,6 +79,19 @@ func (r *testRuntime) GetEventEncoder(writer io.Writer) EventEncoder {
 	return NewEventJSONEncoder(r.Logger, writer)
 }
 
+func (r *testRuntime) SupportsLivenessCheck() bool {
+	return true
+}
+
+type channelSender struct {
+	invocations chan *invocation.Invocation
+}
+
+func (cs *channelSender) Send(invocation *invocation.Invocation) error {
+	cs.invocations <- invocation
+	return nil
+}
+
 type RuntimeSuite struct {
 	suite.Suite
 	testRuntimeInstance *testRuntime
@@ -95,14 +111,243 @@ func (suite *RuntimeSuite) TestRestart() {
 
 	time.Sleep(1 * time.Second)
 
-	oldPid := suite.testRuntimeInstance.wrapperProcess.Pid
+	oldPid := suite.testRuntimeInstance.getWrapperProcess().Pid
 	err = suite.testRuntimeInstance.Restart()
 	suite.Require().NoError(err, "Can't restart runtime")
-	suite.Require().NotEqual(oldPid, suite.testRuntimeInstance.wrapperProcess.Pid, "Wrapper process didn't change")
+	suite.Require().NotEqual(oldPid, suite.testRuntimeInstance.getWrapperProcess().Pid, "Wrapper process didn't change")
+}
+
+func (suite *RuntimeSuite) TestLivenessPongs() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.Spec.RuntimeLiveness = &functionconfig.RuntimeLiveness{
+		IntervalSeconds: 1,
+		TimeoutSeconds:  1,
+		MaxRestarts:     1,
+	}
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	// act as the wrapper, answering every ping
+	go func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if line == "{\"control\":\"ping\"}\n" {
+				conn.Write([]byte("p\n")) // nolint: errcheck
+			}
+		}
+	}(suite.testRuntimeInstance.wrapperConn)
+
+	oldPid := suite.testRuntimeInstance.getWrapperProcess().Pid
+
+	time.Sleep(3500 * time.Millisecond)
+
+	suite.Require().Equal(oldPid, suite.testRuntimeInstance.getWrapperProcess().Pid, "Wrapper process was restarted")
+	suite.Require().Equal(status.Ready, suite.testRuntimeInstance.GetStatus())
+	suite.Require().Zero(atomic.LoadUint64(&suite.testRuntimeInstance.GetStatistics().WrapperRestartsTotal))
+}
+
+func (suite *RuntimeSuite) TestLivenessRestartsHungWrapper() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	oldPid := suite.testRuntimeInstance.getWrapperProcess().Pid
+
+	// the wrapper never answers pings, so it's restarted once and the watcher then fails the runtime and returns
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+
+	watcherDoneChan := make(chan struct{})
+
+	go func() {
+		suite.testRuntimeInstance.watchWrapperLiveness(10*time.Millisecond, 10*time.Millisecond, 1, stopChan)
+		close(watcherDoneChan)
+	}()
+
+	select {
+	case <-watcherDoneChan:
+	case <-time.After(10 * time.Second):
+		suite.Require().FailNow("Liveness watcher didn't give up on the wrapper")
+	}
+
+	suite.Require().Equal(status.Error, suite.testRuntimeInstance.GetStatus())
+	suite.Require().Equal(uint64(1), atomic.LoadUint64(&suite.testRuntimeInstance.GetStatistics().WrapperRestartsTotal))
+
+	livenessStatus := suite.testRuntimeInstance.GetLivenessStatus()
+	suite.Require().Equal(1, livenessStatus.Restarts)
+	suite.Require().NotNil(livenessStatus.LastRestartTime)
+	suite.Require().Contains(livenessStatus.LastError, "No pong")
+	suite.Require().True(livenessStatus.Failed)
+
+	suite.Require().NotEqual(oldPid, suite.testRuntimeInstance.getWrapperProcess().Pid, "Wrapper process didn't change")
+}
+
+func (suite *RuntimeSuite) TestAsyncInvocations() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+
+	sender := &channelSender{invocations: make(chan *invocation.Invocation, 2)}
+	configInstance.AsyncInvoker, err = invocation.NewInvoker(loggerInstance, nil, sender)
+	suite.Require().NoError(err)
+	defer configInstance.AsyncInvoker.Stop()
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	// the wrapper enqueues two invocations of another function
+	_, err = suite.testRuntimeInstance.wrapperConn.Write([]byte(`i{"function_name": "target", "events": [` +
+		`{"method": "PUT", "path": "/items", "content_type": "text/plain", "headers": {"X-Id": "1"}, "body": "Zmlyc3Q="}, ` +
+		`{"body": "c2Vjb25k"}]}` + "\n"))
+	suite.Require().NoError(err)
+
+	bodies := map[string]*invocation.Invocation{}
+	for invocationIndex := 0; invocationIndex < 2; invocationIndex++ {
+		select {
+		case receivedInvocation := <-sender.invocations:
+			suite.Require().Equal("target", receivedInvocation.FunctionName)
+			bodies[string(receivedInvocation.Body)] = receivedInvocation
+		case <-time.After(5 * time.Second):
+			suite.Fail("Invocation wasn't delivered")
+			return
+		}
+	}
+
+	suite.Require().Equal("PUT", bodies["first"].Method)
+	suite.Require().Equal("/items", bodies["first"].Path)
+	suite.Require().Equal("text/plain", bodies["first"].ContentType)
+	suite.Require().Equal("1", bodies["first"].Headers["X-Id"])
+	suite.Require().Contains(bodies, "second")
+}
+
+func (suite *RuntimeSuite) TestStreamedResponse() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.TriggerKind = "http"
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	go suite.streamResponses(suite.testRuntimeInstance.wrapperConn, "")
+
+	response, err := suite.testRuntimeInstance.ProcessEvent(&TestEvent{}, loggerInstance)
+	suite.Require().NoError(err)
+
+	streamedResponse, ok := response.(*runtime.StreamedResponse)
+	suite.Require().True(ok, "Response isn't streamed: %T", response)
+	suite.Require().Equal(201, streamedResponse.StatusCode)
+	suite.Require().Equal("text/csv", streamedResponse.ContentType)
+
+	body, err := ioutil.ReadAll(streamedResponse.BodyStream)
+	suite.Require().NoError(err)
+	suite.Require().Equal("a,b\n1,2\n", string(body))
+
+	// the next event is handled once the stream ended. closing the body discards the rest of it
+	response, err = suite.testRuntimeInstance.ProcessEvent(&TestEvent{}, loggerInstance)
+	suite.Require().NoError(err)
+	suite.Require().NoError(response.(*runtime.StreamedResponse).BodyStream.Close())
+
+	response, err = suite.testRuntimeInstance.ProcessEvent(&TestEvent{}, loggerInstance)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&runtime.StreamedResponse{}, response)
+}
+
+func (suite *RuntimeSuite) TestStreamedResponseFailure() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.TriggerKind = "http"
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	go suite.streamResponses(suite.testRuntimeInstance.wrapperConn, "out of rows")
+
+	response, err := suite.testRuntimeInstance.ProcessEvent(&TestEvent{}, loggerInstance)
+	suite.Require().NoError(err)
+
+	body, err := ioutil.ReadAll(response.(*runtime.StreamedResponse).BodyStream)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "out of rows")
+	suite.Require().Equal("a,b\n1,2\n", string(body))
+}
+
+func (suite *RuntimeSuite) TestStreamedResponseCollected() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.TriggerKind = "kafka-cluster"
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	go suite.streamResponses(suite.testRuntimeInstance.wrapperConn, "")
+
+	// triggers other than http get the whole body
+	response, err := suite.testRuntimeInstance.ProcessEvent(&TestEvent{}, loggerInstance)
+	suite.Require().NoError(err)
+
+	typedResponse, ok := response.(nuclio.Response)
+	suite.Require().True(ok, "Unexpected response: %T", response)
+	suite.Require().Equal(201, typedResponse.StatusCode)
+	suite.Require().Equal("a,b\n1,2\n", string(typedResponse.Body))
+}
+
+// streamResponses acts as the wrapper, streaming the response to every event in two chunks. if streamError
+// is set, the stream ends with it
+func (suite *RuntimeSuite) streamResponses(conn net.Conn, streamError string) {
+	reader := bufio.NewReader(conn)
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return
+		}
+
+		conn.Write([]byte(`r{"status_code": 201, "content_type": "text/csv", "body": "", ` + // nolint: errcheck
+			`"body_encoding": "text", "stream": true}` + "\n"))
+		conn.Write([]byte(`c{"body": "a,b\n", "body_encoding": "text"}` + "\n"))      // nolint: errcheck
+		conn.Write([]byte(`c{"body": "MSwyCg==", "body_encoding": "base64"}` + "\n")) // nolint: errcheck
+		conn.Write([]byte(`e{"error": "` + streamError + `"}` + "\n"))                // nolint: errcheck
+	}
 }
 
 func (suite *RuntimeSuite) TearDownTest() {
-	if suite.testRuntimeInstance != nil && suite.testRuntimeInstance.wrapperProcess != nil {
+	if suite.testRuntimeInstance != nil && suite.testRuntimeInstance.getWrapperProcess() != nil {
 		suite.testRuntimeInstance.Stop() // nolint: errcheck
 	}
 }
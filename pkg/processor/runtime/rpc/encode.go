@@ -22,6 +22,14 @@ import (
 
 type EventEncoder interface {
 	Encode(event nuclio.Event) error
+
+	// EncodePing writes a ping control message, which wrappers that support liveness checks answer with a pong
+	EncodePing() error
+}
+
+// pingMessage is sent in place of an event. wrappers tell it apart from events by the control key
+var pingMessage = map[string]interface{}{
+	"control": "ping",
 }
 
 func eventAsMap(event nuclio.Event) map[string]interface{} {
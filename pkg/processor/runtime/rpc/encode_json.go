@@ -50,3 +50,8 @@ func (e *EventJSONEncoder) Encode(event nuclio.Event) error {
 
 	return json.NewEncoder(e.writer).Encode(eventToEncode)
 }
+
+// EncodePing writes the JSON encoding of a ping control message to the stream, followed by a newline character
+func (e *EventJSONEncoder) EncodePing() error {
+	return json.NewEncoder(e.writer).Encode(pingMessage)
+}
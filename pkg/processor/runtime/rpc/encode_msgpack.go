@@ -37,8 +37,17 @@ func (e *EventMsgPackEncoder) Encode(event nuclio.Event) error {
 		eventToEncode["body"] = event.GetBody()
 	}
 
+	return e.encodeMessage(eventToEncode)
+}
+
+// EncodePing writes the MsgPack encoding of a ping control message to the stream, prefixed by its size
+func (e *EventMsgPackEncoder) EncodePing() error {
+	return e.encodeMessage(pingMessage)
+}
+
+func (e *EventMsgPackEncoder) encodeMessage(message map[string]interface{}) error {
 	e.buf.Reset()
-	if err := e.encoder.Encode(eventToEncode); err != nil {
+	if err := e.encoder.Encode(message); err != nil {
 		return errors.Wrap(err, "Failed to encode message")
 	}
 
@@ -42,4 +42,7 @@ type Runtime interface {
 
 	// WaitForStart returns whether the runtime supports sending an indication that it started
 	WaitForStart() bool
+
+	// SupportsLivenessCheck returns true if the wrapper answers liveness pings
+	SupportsLivenessCheck() bool
 }
@@ -19,7 +19,9 @@ package runtime
 import (
 	"os"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/databinding"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 
 	"github.com/nuclio/errors"
@@ -61,6 +63,13 @@ type Runtime interface {
 	SupportsRestart() bool
 }
 
+// LivenessReporter is implemented by runtimes which check the liveness of their wrapper process
+type LivenessReporter interface {
+
+	// GetLivenessStatus returns the restarts of the wrapper due to failed liveness checks
+	GetLivenessStatus() functionconfig.RuntimeLivenessStatus
+}
+
 // AbstractRuntime is the base for all runtimes
 type AbstractRuntime struct {
 	Logger         logger.Logger
@@ -207,6 +216,9 @@ func (ar *AbstractRuntime) createContext(parentLogger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to initialize Platform")
 	}
 
+	// allow in-process handlers to enqueue async invocations through the context
+	invocation.RegisterContext(newContext, configuration.AsyncInvoker)
+
 	// iterate through data bindings and get the context object - the thing users will actuall
 	// work with in the handlers
 	for databindingName, databindingInstance := range databindings {
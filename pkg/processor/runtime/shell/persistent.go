@@ -0,0 +1,187 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os/exec"
+	goruntime "runtime"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// responses larger than this are rejected, as they're more likely a command writing something other than
+// frames to its stdout than an actual response
+const maxResponseFrameSize = 64 * 1024 * 1024
+
+// persistentProcess is the function's command in persistent mode - started once, and sent events over its stdin
+// rather than executed per event. each event body is written as a frame, a 4 byte big endian length followed by
+// the body, and the command writes its response to its stdout as a frame the same way. the command's stderr is
+// logged
+type persistentProcess struct {
+	logger  logger.Logger
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	exited  chan struct{}
+	exitErr error
+}
+
+func startPersistentProcess(parentLogger logger.Logger,
+	functionLogger logger.Logger,
+	command string,
+	env []string) (*persistentProcess, error) {
+
+	// exec, so that stopping the shell stops the command. cmd can't, so in windows images it's the one stopped
+	if goruntime.GOOS != "windows" {
+		command = "exec " + command
+	}
+
+	shellName, shellArgs := getShellCommandArgs(command)
+	cmd := exec.Command(shellName, shellArgs...)
+	cmd.Env = env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get stdin pipe")
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get stdout pipe")
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get stderr pipe")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "Failed to start command")
+	}
+
+	newPersistentProcess := &persistentProcess{
+		logger: parentLogger,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		exited: make(chan struct{}),
+	}
+
+	go newPersistentProcess.logStderr(stderr, functionLogger)
+
+	go func() {
+		newPersistentProcess.exitErr = cmd.Wait()
+		close(newPersistentProcess.exited)
+	}()
+
+	parentLogger.DebugWith("Persistent command started", "command", command, "pid", cmd.Process.Pid)
+
+	return newPersistentProcess, nil
+}
+
+// processEvent sends the event body to the command and returns its response
+func (pp *persistentProcess) processEvent(body []byte, timeout time.Duration) ([]byte, error) {
+	type result struct {
+		body []byte
+		err  error
+	}
+
+	// the result channel is buffered, so that the exchange doesn't leak if it times out - it fails once the
+	// timed out command is stopped
+	resultChan := make(chan result, 1)
+
+	go func() {
+		responseBody, err := pp.exchangeFrames(body)
+		resultChan <- result{responseBody, err}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.body, result.err
+	case <-time.After(timeout):
+		return nil, errors.Errorf("Timed out after %s waiting for the command's response", timeout)
+	}
+}
+
+// stop closes the command's stdin, and kills it if it doesn't exit within the grace period
+func (pp *persistentProcess) stop(gracePeriod time.Duration) error {
+	pp.stdin.Close() // nolint: errcheck
+
+	select {
+	case <-pp.exited:
+		return nil
+	case <-time.After(gracePeriod):
+	}
+
+	if err := pp.cmd.Process.Kill(); err != nil {
+		return errors.Wrap(err, "Failed to kill command")
+	}
+
+	<-pp.exited
+
+	return nil
+}
+
+// hasExited returns true if the command exited
+func (pp *persistentProcess) hasExited() bool {
+	select {
+	case <-pp.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (pp *persistentProcess) exchangeFrames(body []byte) ([]byte, error) {
+	frame := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	frame = append(frame, body...)
+
+	if _, err := pp.stdin.Write(frame); err != nil {
+		return nil, errors.Wrap(err, "Failed to write event to command")
+	}
+
+	responseFrameHeader := make([]byte, 4)
+	if _, err := io.ReadFull(pp.stdout, responseFrameHeader); err != nil {
+		return nil, errors.Wrap(err, "Failed to read response frame header from command")
+	}
+
+	responseSize := binary.BigEndian.Uint32(responseFrameHeader)
+	if responseSize > maxResponseFrameSize {
+		return nil, errors.Errorf("Response frame of %d bytes exceeds the limit of %d (is the command writing "+
+			"anything but frames to its stdout?)", responseSize, maxResponseFrameSize)
+	}
+
+	responseBody := make([]byte, responseSize)
+	if _, err := io.ReadFull(pp.stdout, responseBody); err != nil {
+		return nil, errors.Wrap(err, "Failed to read response from command")
+	}
+
+	return responseBody, nil
+}
+
+func (pp *persistentProcess) logStderr(stderr io.Reader, functionLogger logger.Logger) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		functionLogger.InfoWith("Command stderr", "line", scanner.Text())
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	goruntime "runtime"
 	"strings"
 	"time"
 
@@ -36,12 +37,19 @@ import (
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// the time a command has to handle an event (TODO: from configuration)
+const eventTimeout = 60 * time.Second
+
+// the time a persistent command has to exit once its stdin is closed, before it's killed
+const persistentProcessStopGracePeriod = 5 * time.Second
+
 type shell struct {
 	*runtime.AbstractRuntime
-	configuration *Configuration
-	command       string
-	env           []string
-	ctx           context.Context
+	configuration     *Configuration
+	command           string
+	env               []string
+	ctx               context.Context
+	persistentProcess *persistentProcess
 }
 
 // NewRuntime returns a new shell runtime
@@ -69,12 +77,22 @@ func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runti
 
 	newShellRuntime.env = newShellRuntime.getEnvFromConfiguration()
 
+	if configuration.Mode == ModePersistent {
+		if err := newShellRuntime.startPersistentProcess(); err != nil {
+			return nil, errors.Wrap(err, "Failed to start persistent command")
+		}
+	}
+
 	newShellRuntime.SetStatus(status.Ready)
 
 	return newShellRuntime, nil
 }
 
 func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	if s.configuration.Mode == ModePersistent {
+		return s.processEventPersistent(event)
+	}
+
 	command := s.command
 
 	command += " " + s.getCommandArguments(event)
@@ -86,12 +104,13 @@ func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (
 		"bodyLen", len(event.GetBody()),
 		"command", command)
 
-	// create a timeout context (TODO: from configuration)
-	ctx, cancel := context.WithTimeout(s.ctx, 60*time.Second)
+	// create a timeout context
+	ctx, cancel := context.WithTimeout(s.ctx, eventTimeout)
 	defer cancel()
 
 	// create a command
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	shellName, shellArgs := getShellCommandArgs(command)
+	cmd := exec.CommandContext(ctx, shellName, shellArgs...)
 	cmd.Stdin = strings.NewReader(string(event.GetBody()))
 
 	// set the command env
@@ -112,9 +131,8 @@ func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (
 	// calculate call duration
 	callDuration := time.Since(startTime)
 
-	// add duration to sum
-	s.Statistics.DurationMilliSecondsSum += uint64(callDuration.Nanoseconds() / 1000000)
-	s.Statistics.DurationMilliSecondsCount++
+	// add duration to sum and histogram
+	s.Statistics.ObserveDuration(callDuration)
 
 	s.Logger.DebugWith("Shell executed",
 		"eventID", event.GetID())
@@ -126,6 +144,101 @@ func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (
 	}, nil
 }
 
+// Stop stops the persistent command, if there is one
+func (s *shell) Stop() error {
+	if s.persistentProcess != nil {
+		if err := s.persistentProcess.stop(persistentProcessStopGracePeriod); err != nil {
+			return errors.Wrap(err, "Failed to stop persistent command")
+		}
+
+		s.persistentProcess = nil
+	}
+
+	return s.AbstractRuntime.Stop()
+}
+
+// Restart restarts the persistent command
+func (s *shell) Restart() error {
+	if s.configuration.Mode != ModePersistent {
+		return s.AbstractRuntime.Restart()
+	}
+
+	if err := s.Stop(); err != nil {
+		return errors.Wrap(err, "Failed to stop runtime")
+	}
+
+	if err := s.startPersistentProcess(); err != nil {
+		return errors.Wrap(err, "Failed to start persistent command")
+	}
+
+	s.SetStatus(status.Ready)
+
+	return nil
+}
+
+// SupportsRestart returns true in persistent mode
+func (s *shell) SupportsRestart() bool {
+	return s.configuration.Mode == ModePersistent
+}
+
+func (s *shell) processEventPersistent(event nuclio.Event) (interface{}, error) {
+
+	// the command is restarted for the event if it exited, or was stopped after failing the previous one
+	if s.persistentProcess != nil && s.persistentProcess.hasExited() {
+		s.Logger.WarnWith("Persistent command exited, restarting it", "err", s.persistentProcess.exitErr)
+		s.persistentProcess = nil
+	}
+
+	if s.persistentProcess == nil {
+		if err := s.startPersistentProcess(); err != nil {
+			return nil, errors.Wrap(err, "Failed to restart persistent command")
+		}
+	}
+
+	s.Logger.DebugWith("Sending event to persistent command",
+		"eventID", event.GetID(),
+		"bodyLen", len(event.GetBody()))
+
+	startTime := time.Now()
+
+	responseBody, err := s.persistentProcess.processEvent(event.GetBody(), eventTimeout)
+	if err != nil {
+
+		// the command's stdin/stdout can't be trusted to be at a frame boundary anymore
+		if stopErr := s.persistentProcess.stop(0); stopErr != nil {
+			s.Logger.WarnWith("Failed to stop persistent command", "err", stopErr)
+		}
+
+		s.persistentProcess = nil
+
+		return nil, errors.Wrap(err, "Failed to process event in persistent command")
+	}
+
+	s.Statistics.ObserveDuration(time.Since(startTime))
+
+	return nuclio.Response{
+		StatusCode: http.StatusOK,
+		Headers:    s.configuration.ResponseHeaders,
+		Body:       responseBody,
+	}, nil
+}
+
+func (s *shell) startPersistentProcess() error {
+	command := s.command
+	if s.configuration.Arguments != "" {
+		command += " " + s.configuration.Arguments
+	}
+
+	persistentProcess, err := startPersistentProcess(s.Logger, s.FunctionLogger, command, s.env)
+	if err != nil {
+		return errors.Wrap(err, "Failed to start persistent process")
+	}
+
+	s.persistentProcess = persistentProcess
+
+	return nil
+}
+
 func (s *shell) getCommand() (string, error) {
 	var command string
 
@@ -206,3 +319,12 @@ func (s *shell) getEnvFromEvent(event nuclio.Event) []string {
 		fmt.Sprintf("NUCLIO_EVENT_VERSION=%s", event.GetVersion()),
 	}
 }
+
+// getShellCommandArgs returns the shell running the command and its arguments - sh, or cmd in windows images
+func getShellCommandArgs(command string) (string, []string) {
+	if goruntime.GOOS == "windows" {
+		return "cmd", []string{"/S", "/C", command}
+	}
+
+	return "sh", []string{"-c", command}
+}
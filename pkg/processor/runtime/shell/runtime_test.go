@@ -0,0 +1,174 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/api/core/v1"
+)
+
+// the persistent command of the tests is the test binary itself, running TestPersistentCommand
+const persistentCommandEnv = "NUCLIO_SHELL_TEST_PERSISTENT_COMMAND"
+
+type shellTestSuite struct {
+	suite.Suite
+}
+
+func (suite *shellTestSuite) TestPersistentMode() {
+	shellRuntime := suite.createRuntime(map[string]interface{}{
+		"mode":            ModePersistent,
+		"arguments":       "-test.run=TestPersistentCommand",
+		"responseHeaders": map[string]interface{}{"header1": "value1"},
+	})
+	defer shellRuntime.Stop() // nolint: errcheck
+
+	suite.Require().True(shellRuntime.SupportsRestart())
+
+	// events are handled by the same process
+	firstResponse := suite.processEvent(shellRuntime, "first")
+	suite.Require().Regexp(`^\d+:tsrif$`, string(firstResponse.Body))
+	suite.Require().Equal("value1", firstResponse.Headers["header1"])
+
+	secondResponse := suite.processEvent(shellRuntime, "second")
+	pid := string(firstResponse.Body[:len(firstResponse.Body)-len("tsrif")])
+	suite.Require().Equal(pid+"dnoces", string(secondResponse.Body))
+
+	// empty bodies are framed too
+	suite.Require().Equal(pid, string(suite.processEvent(shellRuntime, "").Body))
+
+	// a command that exits fails the event, and is restarted for the next one
+	_, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("exit")}, nil)
+	suite.Require().Error(err)
+
+	thirdResponse := suite.processEvent(shellRuntime, "third")
+	suite.Require().NotEqual(pid+"driht", string(thirdResponse.Body))
+	suite.Require().Regexp(`^\d+:driht$`, string(thirdResponse.Body))
+
+	// as is one whose response isn't a frame
+	_, err = shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("garbage")}, nil)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "exceeds the limit")
+
+	suite.Require().Regexp(`^\d+:htruof$`, string(suite.processEvent(shellRuntime, "fourth").Body))
+
+	suite.Require().NoError(shellRuntime.Stop())
+}
+
+func (suite *shellTestSuite) TestUnknownMode() {
+	configuration := suite.createRuntimeConfiguration(map[string]interface{}{"mode": "daemon"})
+
+	_, err := NewConfiguration(configuration)
+	suite.Require().Error(err)
+}
+
+func (suite *shellTestSuite) processEvent(shellRuntime runtime.Runtime, body string) nuclio.Response {
+	response, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte(body)}, nil)
+	suite.Require().NoError(err)
+
+	return response.(nuclio.Response)
+}
+
+func (suite *shellTestSuite) createRuntime(runtimeAttributes map[string]interface{}) runtime.Runtime {
+	configuration, err := NewConfiguration(suite.createRuntimeConfiguration(runtimeAttributes))
+	suite.Require().NoError(err)
+
+	shellRuntime, err := NewRuntime(configuration.FunctionLogger, configuration)
+	suite.Require().NoError(err)
+
+	return shellRuntime
+}
+
+func (suite *shellTestSuite) createRuntimeConfiguration(runtimeAttributes map[string]interface{}) *runtime.Configuration {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("shell-test")
+	suite.Require().NoError(err)
+
+	return &runtime.Configuration{
+		FunctionLogger: loggerInstance,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:      "shell-test",
+					Namespace: "test",
+				},
+				Spec: functionconfig.Spec{
+					Handler:           os.Args[0],
+					RuntimeAttributes: runtimeAttributes,
+					Env:               []v1.EnvVar{{Name: persistentCommandEnv, Value: "true"}},
+				},
+			},
+			PlatformConfig: &platformconfig.Config{
+				Kind: "docker",
+			},
+		},
+	}
+}
+
+func TestShellTestSuite(t *testing.T) {
+	suite.Run(t, new(shellTestSuite))
+}
+
+// TestPersistentCommand is the persistent command of the tests. it responds to each event with its pid and the
+// event's body reversed
+func TestPersistentCommand(t *testing.T) {
+	if os.Getenv(persistentCommandEnv) == "" {
+		t.Skip("Not running as a persistent command")
+	}
+
+	frameHeader := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(os.Stdin, frameHeader); err != nil {
+			os.Exit(0)
+		}
+
+		body := make([]byte, binary.BigEndian.Uint32(frameHeader))
+		if _, err := io.ReadFull(os.Stdin, body); err != nil {
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "Handling %s\n", body) // nolint: errcheck
+
+		switch string(body) {
+		case "exit":
+			os.Exit(1)
+		case "garbage":
+			os.Stdout.WriteString("this isn't a frame") // nolint: errcheck
+			continue
+		}
+
+		response := []byte(fmt.Sprintf("%d:", os.Getpid()))
+		for index := len(body) - 1; index >= 0; index-- {
+			response = append(response, body[index])
+		}
+
+		binary.BigEndian.PutUint32(frameHeader, uint32(len(response)))
+		os.Stdout.Write(append(frameHeader, response...)) // nolint: errcheck
+	}
+}
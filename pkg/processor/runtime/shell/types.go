@@ -7,10 +7,20 @@ import (
 	"github.com/nuclio/errors"
 )
 
+const (
+
+	// the command is executed for each event, with the event's body as its stdin
+	ModeExec = "exec"
+
+	// the command is started once, and events are streamed to it over its stdin (see persistentProcess)
+	ModePersistent = "persistent"
+)
+
 type Configuration struct {
 	*runtime.Configuration
 	Arguments       string
 	ResponseHeaders map[string]interface{}
+	Mode            string
 }
 
 func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
@@ -23,5 +33,16 @@ func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configurati
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	switch newConfiguration.Mode {
+	case "":
+		newConfiguration.Mode = ModeExec
+	case ModeExec, ModePersistent:
+	default:
+		return nil, errors.Errorf("Unknown shell mode %s (expected %s or %s)",
+			newConfiguration.Mode,
+			ModeExec,
+			ModePersistent)
+	}
+
 	return &newConfiguration, nil
 }
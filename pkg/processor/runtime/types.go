@@ -17,16 +17,47 @@ limitations under the License.
 package runtime
 
 import (
+	"io"
 	"sync/atomic"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/inspector"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
+	"github.com/nuclio/nuclio/pkg/processor/tracing"
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
 
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// DurationMilliSecondsBuckets are the upper bounds of the handler duration histogram buckets
+var DurationMilliSecondsBuckets = [...]float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
 type Statistics struct {
 	DurationMilliSecondsSum   uint64
 	DurationMilliSecondsCount uint64
+
+	// number of durations that fell in each of DurationMilliSecondsBuckets (not cumulative). durations longer
+	// than the last bucket are only accounted for in DurationMilliSecondsCount
+	DurationMilliSecondsBucketCounts [len(DurationMilliSecondsBuckets)]uint64
+	WrapperRestartsTotal             uint64
+}
+
+// ObserveDuration accounts for an event that took the given duration to handle
+func (s *Statistics) ObserveDuration(duration time.Duration) {
+	durationMilliSeconds := uint64(duration / time.Millisecond)
+
+	atomic.AddUint64(&s.DurationMilliSecondsSum, durationMilliSeconds)
+	atomic.AddUint64(&s.DurationMilliSecondsCount, 1)
+
+	for bucketIndex, bucketUpperBound := range DurationMilliSecondsBuckets {
+		if float64(durationMilliSeconds) <= bucketUpperBound {
+			atomic.AddUint64(&s.DurationMilliSecondsBucketCounts[bucketIndex], 1)
+			break
+		}
+	}
 }
 
 func (s *Statistics) DiffFrom(prev *Statistics) Statistics {
@@ -34,14 +65,33 @@ func (s *Statistics) DiffFrom(prev *Statistics) Statistics {
 	// atomically load the counters
 	currDurationMilliSecondsSum := atomic.LoadUint64(&s.DurationMilliSecondsSum)
 	currDurationMilliSecondsCount := atomic.LoadUint64(&s.DurationMilliSecondsCount)
+	currWrapperRestartsTotal := atomic.LoadUint64(&s.WrapperRestartsTotal)
 
 	prevDurationMilliSecondsSum := atomic.LoadUint64(&prev.DurationMilliSecondsSum)
 	prevDurationMilliSecondsCount := atomic.LoadUint64(&prev.DurationMilliSecondsCount)
+	prevWrapperRestartsTotal := atomic.LoadUint64(&prev.WrapperRestartsTotal)
 
-	return Statistics{
+	diff := Statistics{
 		DurationMilliSecondsSum:   currDurationMilliSecondsSum - prevDurationMilliSecondsSum,
 		DurationMilliSecondsCount: currDurationMilliSecondsCount - prevDurationMilliSecondsCount,
+		WrapperRestartsTotal:      currWrapperRestartsTotal - prevWrapperRestartsTotal,
+	}
+
+	for bucketIndex := range s.DurationMilliSecondsBucketCounts {
+		diff.DurationMilliSecondsBucketCounts[bucketIndex] =
+			atomic.LoadUint64(&s.DurationMilliSecondsBucketCounts[bucketIndex]) -
+				atomic.LoadUint64(&prev.DurationMilliSecondsBucketCounts[bucketIndex])
 	}
+
+	return diff
+}
+
+// StreamedResponse is a response whose body the handler streams (e.g. a large file or report) rather than returns
+// at once. the body is streamed until read to its end, and must be read or closed before the runtime handles
+// another event
+type StreamedResponse struct {
+	nuclio.Response
+	BodyStream io.ReadCloser
 }
 
 type Configuration struct {
@@ -50,4 +100,19 @@ type Configuration struct {
 	WorkerID       int
 	TriggerName    string
 	TriggerKind    string
+
+	// AdmissionController limits the events handled by all the triggers of the function, if set
+	AdmissionController *admission.Controller
+
+	// Tracer traces the handling of events, if set
+	Tracer *tracing.Tracer
+
+	// EventRecorder records a sample of the handled events, if set
+	EventRecorder *recording.Recorder
+
+	// InvocationInspector retains a summary of the last invocations, if set
+	InvocationInspector *inspector.Inspector
+
+	// AsyncInvoker delivers the invocations the function enqueues for other functions, if set
+	AsyncInvoker *invocation.Invoker
 }
@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+)
+
+const moduleExtension = ".wasm"
+
+// getModulePathAndExportName returns the path of the module a handler (module:export) refers to, and the name
+// of the exported handler function
+func getModulePathAndExportName(handlerDir string, handler string) (string, string, error) {
+	moduleName, exportName, err := functionconfig.ParseHandler(handler)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed to parse handler")
+	}
+
+	if moduleName == "" || exportName == "" {
+		return "", "", errors.Errorf("Handler must be in the form of module:export, got %s", handler)
+	}
+
+	if !strings.HasSuffix(moduleName, moduleExtension) {
+		moduleName += moduleExtension
+	}
+
+	return path.Join(handlerDir, moduleName), exportName, nil
+}
+
+// getHandlerDir returns the directory modules are looked for in
+func getHandlerDir() string {
+	if handlerDir := os.Getenv("NUCLIO_WASM_HANDLER_DIR"); handlerDir != "" {
+		return handlerDir
+	}
+
+	return "/opt/nuclio"
+}
+
+// unpackResult splits the result of a handler into the offset and length of the response body in the module's
+// memory (the upper and lower 32 bits, respectively)
+func unpackResult(result int64) (uint32, uint32) {
+	return uint32(uint64(result) >> 32), uint32(uint64(result))
+}
+
+// validateMemoryRange verifies a range a module returned lies within its memory
+func validateMemoryRange(offset uint32, length uint32, memorySize uint64) error {
+	if uint64(offset)+uint64(length) > memorySize {
+		return errors.Errorf("Range [%d, %d) is outside of the module's memory (%d bytes)",
+			offset,
+			uint64(offset)+uint64(length),
+			memorySize)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type abiTestSuite struct {
+	suite.Suite
+}
+
+func (suite *abiTestSuite) TestGetModulePathAndExportName() {
+	modulePath, exportName, err := getModulePathAndExportName("/opt/nuclio", "transform:handler")
+	suite.Require().NoError(err)
+	suite.Require().Equal("/opt/nuclio/transform.wasm", modulePath)
+	suite.Require().Equal("handler", exportName)
+
+	// the extension may be given
+	modulePath, exportName, err = getModulePathAndExportName("/opt/nuclio", "transform.wasm:handle_event")
+	suite.Require().NoError(err)
+	suite.Require().Equal("/opt/nuclio/transform.wasm", modulePath)
+	suite.Require().Equal("handle_event", exportName)
+
+	for _, invalidHandler := range []string{"handler", "transform:", "a:b:c"} {
+		_, _, err = getModulePathAndExportName("/opt/nuclio", invalidHandler)
+		suite.Require().Error(err, invalidHandler)
+	}
+}
+
+func (suite *abiTestSuite) TestGetHandlerDir() {
+	suite.Require().Equal("/opt/nuclio", getHandlerDir())
+
+	os.Setenv("NUCLIO_WASM_HANDLER_DIR", "/tmp/modules") // nolint: errcheck
+	defer os.Unsetenv("NUCLIO_WASM_HANDLER_DIR")         // nolint: errcheck
+
+	suite.Require().Equal("/tmp/modules", getHandlerDir())
+}
+
+func (suite *abiTestSuite) TestUnpackResult() {
+	offset, length := unpackResult(int64(1024)<<32 | 17)
+	suite.Require().Equal(uint32(1024), offset)
+	suite.Require().Equal(uint32(17), length)
+
+	// offsets in the upper half of a 4GiB memory make the result negative
+	offset, length = unpackResult(-1 << 32)
+	suite.Require().Equal(uint32(0xffffffff), offset)
+	suite.Require().Equal(uint32(0), length)
+}
+
+func (suite *abiTestSuite) TestValidateMemoryRange() {
+	suite.Require().NoError(validateMemoryRange(0, 65536, 65536))
+	suite.Require().NoError(validateMemoryRange(100, 0, 65536))
+	suite.Require().Error(validateMemoryRange(65530, 10, 65536))
+	suite.Require().Error(validateMemoryRange(0xffffffff, 0xffffffff, 65536))
+}
+
+func TestABITestSuite(t *testing.T) {
+	suite.Run(t, new(abiTestSuite))
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+#ifndef NUCLIO_WASM_INTERFACE_H
+#define NUCLIO_WASM_INTERFACE_H
+
+#include <stdbool.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include <wasi.h>
+#include <wasm.h>
+#include <wasmtime.h>
+
+// instance_t is an instance of a module, in a store of its own, along with the exports the runtime calls
+typedef struct {
+    wasmtime_store_t *store;
+    wasmtime_context_t *context;
+    wasmtime_instance_t instance;
+    wasmtime_memory_t memory;
+    wasmtime_func_t alloc;
+    wasmtime_func_t free;
+    bool has_free;
+    wasmtime_func_t handler;
+} instance_t;
+
+// copy_message returns a NUL terminated copy of a message, to be freed by the caller
+static char *copy_message(wasm_byte_vec_t *message) {
+    size_t size = message->size;
+
+    // some messages already include the terminating NUL
+    if (size > 0 && message->data[size - 1] == '\0') {
+        size--;
+    }
+
+    char *copy = malloc(size + 1);
+    memcpy(copy, message->data, size);
+    copy[size] = '\0';
+    wasm_byte_vec_delete(message);
+
+    return copy;
+}
+
+// error_message returns the message of an error or a trap (whichever is set) and deletes it
+static char *error_message(wasmtime_error_t *error, wasm_trap_t *trap) {
+    wasm_byte_vec_t message;
+
+    if (error != NULL) {
+        wasmtime_error_message(error, &message);
+        wasmtime_error_delete(error);
+    } else {
+        wasm_trap_message(trap, &message);
+        wasm_trap_delete(trap);
+    }
+
+    return copy_message(&message);
+}
+
+static char *compile_module(wasm_engine_t *engine, const uint8_t *wasm, size_t size, wasmtime_module_t **module) {
+    wasmtime_error_t *error = wasmtime_module_new(engine, wasm, size, module);
+    if (error != NULL) {
+        return error_message(error, NULL);
+    }
+
+    return NULL;
+}
+
+static char *get_func_export(instance_t *instance, const char *name, wasmtime_func_t *func, bool *found) {
+    wasmtime_extern_t item;
+
+    *found = wasmtime_instance_export_get(instance->context, &instance->instance, name, strlen(name), &item);
+    if (!*found) {
+        return NULL;
+    }
+
+    if (item.kind != WASMTIME_EXTERN_FUNC) {
+        return strdup("Export isn't a function");
+    }
+
+    *func = item.of.func;
+    return NULL;
+}
+
+// instantiate creates an instance of a module, with WASI (inheriting the environment, stdout and stderr)
+static char *instantiate(wasm_engine_t *engine,
+                         wasmtime_module_t *module,
+                         const char *handler_name,
+                         instance_t *instance) {
+    wasmtime_error_t *error = NULL;
+    wasm_trap_t *trap = NULL;
+    wasmtime_extern_t item;
+    bool found;
+    char *message;
+
+    memset(instance, 0, sizeof(*instance));
+    instance->store = wasmtime_store_new(engine, NULL, NULL);
+    instance->context = wasmtime_store_context(instance->store);
+
+    wasi_config_t *wasi_config = wasi_config_new();
+    wasi_config_inherit_env(wasi_config);
+    wasi_config_inherit_stdout(wasi_config);
+    wasi_config_inherit_stderr(wasi_config);
+
+    error = wasmtime_context_set_wasi(instance->context, wasi_config);
+    if (error != NULL) {
+        return error_message(error, NULL);
+    }
+
+    wasmtime_linker_t *linker = wasmtime_linker_new(engine);
+    error = wasmtime_linker_define_wasi(linker);
+    if (error == NULL) {
+        error = wasmtime_linker_instantiate(linker, instance->context, module, &instance->instance, &trap);
+    }
+    wasmtime_linker_delete(linker);
+
+    if (error != NULL || trap != NULL) {
+        return error_message(error, trap);
+    }
+
+    if (!wasmtime_instance_export_get(instance->context, &instance->instance, "memory", strlen("memory"), &item) ||
+        item.kind != WASMTIME_EXTERN_MEMORY) {
+        return strdup("Module doesn't export a memory named memory");
+    }
+    instance->memory = item.of.memory;
+
+    if ((message = get_func_export(instance, "nuclio_alloc", &instance->alloc, &found)) != NULL || !found) {
+        return message != NULL ? message : strdup("Module doesn't export nuclio_alloc");
+    }
+
+    if ((message = get_func_export(instance, "nuclio_free", &instance->free, &instance->has_free)) != NULL) {
+        return message;
+    }
+
+    if ((message = get_func_export(instance, handler_name, &instance->handler, &found)) != NULL || !found) {
+        return message != NULL ? message : strdup("Module doesn't export the handler");
+    }
+
+    return NULL;
+}
+
+static char *call(instance_t *instance,
+                  wasmtime_func_t *func,
+                  const wasmtime_val_t *args,
+                  size_t nargs,
+                  wasmtime_val_t *results,
+                  size_t nresults) {
+    wasm_trap_t *trap = NULL;
+
+    wasmtime_error_t *error = wasmtime_func_call(instance->context, func, args, nargs, results, nresults, &trap);
+    if (error != NULL || trap != NULL) {
+        return error_message(error, trap);
+    }
+
+    return NULL;
+}
+
+// call_alloc calls nuclio_alloc(size: i32) -> i32
+static char *call_alloc(instance_t *instance, int32_t size, int32_t *offset) {
+    wasmtime_val_t arg = {.kind = WASMTIME_I32, .of.i32 = size};
+    wasmtime_val_t result;
+
+    char *message = call(instance, &instance->alloc, &arg, 1, &result, 1);
+    if (message != NULL) {
+        return message;
+    }
+
+    if (result.kind != WASMTIME_I32) {
+        return strdup("nuclio_alloc must return an i32");
+    }
+
+    *offset = result.of.i32;
+    return NULL;
+}
+
+// call_free calls nuclio_free(offset: i32, size: i32), if the module exports it
+static char *call_free(instance_t *instance, int32_t offset, int32_t size) {
+    if (!instance->has_free) {
+        return NULL;
+    }
+
+    wasmtime_val_t args[2] = {
+        {.kind = WASMTIME_I32, .of.i32 = offset},
+        {.kind = WASMTIME_I32, .of.i32 = size},
+    };
+
+    return call(instance, &instance->free, args, 2, NULL, 0);
+}
+
+// call_handler calls handler(offset: i32, size: i32) -> i64
+static char *call_handler(instance_t *instance, int32_t offset, int32_t size, int64_t *response) {
+    wasmtime_val_t args[2] = {
+        {.kind = WASMTIME_I32, .of.i32 = offset},
+        {.kind = WASMTIME_I32, .of.i32 = size},
+    };
+    wasmtime_val_t result;
+
+    char *message = call(instance, &instance->handler, args, 2, &result, 1);
+    if (message != NULL) {
+        return message;
+    }
+
+    if (result.kind != WASMTIME_I64) {
+        return strdup("The handler must return an i64");
+    }
+
+    *response = result.of.i64;
+    return NULL;
+}
+
+static uint8_t *memory_data(instance_t *instance, size_t *size) {
+    *size = wasmtime_memory_data_size(instance->context, &instance->memory);
+    return wasmtime_memory_data(instance->context, &instance->memory);
+}
+
+static void delete_instance(instance_t *instance) {
+    if (instance->store != NULL) {
+        wasmtime_store_delete(instance->store);
+        instance->store = NULL;
+    }
+}
+
+#endif
@@ -0,0 +1,239 @@
+// +build wasmtime
+
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+/*
+#cgo pkg-config: wasmtime
+
+#include "interface.h"
+*/
+import "C"
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the engine and the compiled module are shared by the runtimes of all workers, each of which instantiates the
+// module in a store of its own
+var (
+	initLock sync.Mutex
+	engine   *C.wasm_engine_t
+	modules  = map[string]*C.wasmtime_module_t{}
+)
+
+type wasm struct {
+	*runtime.AbstractRuntime
+	configuration *Configuration
+	module        *C.wasmtime_module_t
+	exportName    string
+	instance      C.instance_t
+}
+
+// NewRuntime returns a new WebAssembly runtime
+func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runtime.Runtime, error) {
+	runtimeLogger := parentLogger.GetChild("wasm")
+
+	abstractRuntime, err := runtime.NewAbstractRuntime(runtimeLogger, configuration.Configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create abstract runtime")
+	}
+
+	newWasmRuntime := &wasm{
+		AbstractRuntime: abstractRuntime,
+		configuration:   configuration,
+	}
+
+	modulePath, exportName, err := getModulePathAndExportName(getHandlerDir(), configuration.Spec.Handler)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get module path")
+	}
+
+	newWasmRuntime.exportName = exportName
+
+	newWasmRuntime.module, err = getModule(modulePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to load module %s", modulePath)
+	}
+
+	if err := newWasmRuntime.instantiate(); err != nil {
+		return nil, errors.Wrapf(err, "Failed to instantiate module %s", modulePath)
+	}
+
+	newWasmRuntime.SetStatus(status.Ready)
+
+	return newWasmRuntime, nil
+}
+
+func (w *wasm) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	w.Logger.DebugWith("Processing event",
+		"name", w.configuration.Meta.Name,
+		"version", w.configuration.Spec.Version,
+		"eventID", event.GetID())
+
+	startTime := time.Now()
+
+	responseBody, err := w.callHandler(event.GetBody())
+	if err != nil {
+
+		// a trap may leave the module's memory in any state, so the next event gets a fresh instance
+		if instantiateErr := w.instantiate(); instantiateErr != nil {
+			w.SetStatus(status.Error)
+			return nil, errors.Wrap(instantiateErr, "Failed to instantiate module after a failed invocation")
+		}
+
+		return nil, errors.Wrap(err, "Failed to invoke handler")
+	}
+
+	w.Statistics.ObserveDuration(time.Since(startTime))
+
+	return nuclio.Response{
+		StatusCode: http.StatusOK,
+		Headers:    w.configuration.ResponseHeaders,
+		Body:       responseBody,
+	}, nil
+}
+
+// Stop deletes the module's instance
+func (w *wasm) Stop() error {
+	C.delete_instance(&w.instance)
+
+	return w.AbstractRuntime.Stop()
+}
+
+func (w *wasm) instantiate() error {
+	C.delete_instance(&w.instance)
+
+	cExportName := C.CString(w.exportName)
+	defer C.free(unsafe.Pointer(cExportName))
+
+	if message := C.instantiate(engine, w.module, cExportName, &w.instance); message != nil {
+		C.delete_instance(&w.instance)
+		return errors.New(toGoString(message))
+	}
+
+	return nil
+}
+
+// callHandler copies the body into the module's memory, calls the handler and copies the response out of it
+func (w *wasm) callHandler(body []byte) ([]byte, error) {
+	var bodyOffset C.int32_t
+
+	if message := C.call_alloc(&w.instance, C.int32_t(len(body)), &bodyOffset); message != nil {
+		return nil, errors.Errorf("nuclio_alloc failed: %s", toGoString(message))
+	}
+
+	memory := w.getMemory()
+	if err := validateMemoryRange(uint32(bodyOffset), uint32(len(body)), uint64(len(memory))); err != nil {
+		return nil, errors.Wrap(err, "nuclio_alloc returned an invalid offset")
+	}
+
+	copy(memory[uint32(bodyOffset):], body)
+
+	var result C.int64_t
+	if message := C.call_handler(&w.instance, bodyOffset, C.int32_t(len(body)), &result); message != nil {
+		return nil, errors.New(toGoString(message))
+	}
+
+	responseOffset, responseLength := unpackResult(int64(result))
+
+	// the handler may have grown the memory
+	memory = w.getMemory()
+	if err := validateMemoryRange(responseOffset, responseLength, uint64(len(memory))); err != nil {
+		return nil, errors.Wrap(err, "The handler returned an invalid response")
+	}
+
+	responseBody := make([]byte, responseLength)
+	copy(responseBody, memory[responseOffset:responseOffset+responseLength])
+
+	for _, allocation := range [][2]uint32{
+		{uint32(bodyOffset), uint32(len(body))},
+		{responseOffset, responseLength},
+	} {
+		if message := C.call_free(&w.instance, C.int32_t(allocation[0]), C.int32_t(allocation[1])); message != nil {
+			return nil, errors.Errorf("nuclio_free failed: %s", toGoString(message))
+		}
+	}
+
+	return responseBody, nil
+}
+
+// getMemory returns the module's memory. it's only valid until the module is called again
+func (w *wasm) getMemory() []byte {
+	var size C.size_t
+
+	data := C.memory_data(&w.instance, &size)
+	if size == 0 {
+		return nil
+	}
+
+	return (*[1 << 32]byte)(unsafe.Pointer(data))[:size:size]
+}
+
+// getModule returns the compiled module at a path, compiling it the first time
+func getModule(modulePath string) (*C.wasmtime_module_t, error) {
+	initLock.Lock()
+	defer initLock.Unlock()
+
+	if engine == nil {
+		engine = C.wasm_engine_new()
+	}
+
+	if module, found := modules[modulePath]; found {
+		return module, nil
+	}
+
+	moduleContents, err := ioutil.ReadFile(modulePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read module")
+	}
+
+	if len(moduleContents) == 0 {
+		return nil, errors.New("Module is empty")
+	}
+
+	var module *C.wasmtime_module_t
+	if message := C.compile_module(engine,
+		(*C.uint8_t)(unsafe.Pointer(&moduleContents[0])),
+		C.size_t(len(moduleContents)),
+		&module); message != nil {
+		return nil, errors.Errorf("Failed to compile module: %s", toGoString(message))
+	}
+
+	modules[modulePath] = module
+
+	return module, nil
+}
+
+// toGoString converts a message returned by the C layer, and frees it
+func toGoString(message *C.char) string {
+	defer C.free(unsafe.Pointer(message))
+
+	return C.GoString(message)
+}
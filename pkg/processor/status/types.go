@@ -36,6 +36,7 @@ const (
 	Ready
 	Error
 	Stopped
+	Draining
 )
 
 func (s Status) String() string {
@@ -48,6 +49,8 @@ func (s Status) String() string {
 		return "error"
 	case Stopped:
 		return "stopped"
+	case Draining:
+		return "draining"
 	}
 
 	return fmt.Sprintf("Unknown status - %d", s)
@@ -0,0 +1,94 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"strings"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// tracedEvent exposes the traceparent of the span handling the event as one of its headers, so that the
+// handler (and the runtime wrapper passing it the event) can continue the trace
+type tracedEvent struct {
+	nuclio.Event
+	traceparent string
+}
+
+// WithSpan returns the event with a traceparent header identifying the given span. if the span is nil, the
+// event is returned as is
+func WithSpan(event nuclio.Event, span *Span) nuclio.Event {
+	if span == nil {
+		return event
+	}
+
+	return &tracedEvent{
+		Event:       event,
+		traceparent: span.Context().Traceparent(),
+	}
+}
+
+// GetTraceparent returns the traceparent header of the event, if any
+func GetTraceparent(event nuclio.Event) string {
+	switch typedTraceparent := event.GetHeader(TraceparentHeader).(type) {
+	case string:
+		return typedTraceparent
+	case []byte:
+		return string(typedTraceparent)
+	}
+
+	return ""
+}
+
+func (te *tracedEvent) GetHeader(key string) interface{} {
+	if strings.EqualFold(key, TraceparentHeader) {
+		return te.traceparent
+	}
+
+	return te.Event.GetHeader(key)
+}
+
+func (te *tracedEvent) GetHeaderByteSlice(key string) []byte {
+	if strings.EqualFold(key, TraceparentHeader) {
+		return []byte(te.traceparent)
+	}
+
+	return te.Event.GetHeaderByteSlice(key)
+}
+
+func (te *tracedEvent) GetHeaderString(key string) string {
+	if strings.EqualFold(key, TraceparentHeader) {
+		return te.traceparent
+	}
+
+	return te.Event.GetHeaderString(key)
+}
+
+func (te *tracedEvent) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{}
+
+	// replace the incoming traceparent, whichever its case
+	for headerName, headerValue := range te.Event.GetHeaders() {
+		if !strings.EqualFold(headerName, TraceparentHeader) {
+			headers[headerName] = headerValue
+		}
+	}
+
+	headers[TraceparentHeader] = te.traceparent
+
+	return headers
+}
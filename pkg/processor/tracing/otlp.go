@@ -0,0 +1,192 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	otlpTracesPath     = "/v1/traces"
+	otlpExportTimeout  = 10 * time.Second
+	otlpStatusCodeOK   = 1
+	otlpStatusCodeFail = 2
+)
+
+// otlpExporter exports spans to an OTLP/HTTP collector, JSON encoded
+type otlpExporter struct {
+	url                string
+	headers            map[string]string
+	resourceAttributes []otlpAttribute
+	httpClient         *http.Client
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func newOTLPExporter(url string, headers map[string]string, resourceAttributes map[string]interface{}) *otlpExporter {
+
+	// the url may be that of the collector, or of its traces endpoint
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+
+	return &otlpExporter{
+		url:                url,
+		headers:            headers,
+		resourceAttributes: encodeOTLPAttributes(resourceAttributes),
+		httpClient:         &http.Client{Timeout: otlpExportTimeout},
+	}
+}
+
+func (oe *otlpExporter) export(spans []*Span) error {
+	encodedSpans := make([]otlpSpan, 0, len(spans))
+
+	for _, span := range spans {
+		encodedSpans = append(encodedSpans, oe.encodeSpan(span))
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": oe.resourceAttributes,
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "nuclio"},
+						"spans": encodedSpans,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode spans")
+	}
+
+	request, err := http.NewRequest(http.MethodPost, oe.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create export request")
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for headerName, headerValue := range oe.headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	response, err := oe.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send spans")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return errors.Errorf("Collector responded with %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+func (oe *otlpExporter) encodeSpan(span *Span) otlpSpan {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	encodedSpan := otlpSpan{
+		TraceID:           span.context.TraceID.String(),
+		SpanID:            span.context.SpanID.String(),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.startTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.endTime.UnixNano(), 10),
+		Attributes:        encodeOTLPAttributes(span.attributes),
+		Status:            otlpStatus{Code: otlpStatusCodeOK},
+	}
+
+	if span.parentSpanID != (SpanID{}) {
+		encodedSpan.ParentSpanID = span.parentSpanID.String()
+	}
+
+	if span.errorMessage != "" {
+		encodedSpan.Status = otlpStatus{Code: otlpStatusCodeFail, Message: span.errorMessage}
+	}
+
+	return encodedSpan
+}
+
+func encodeOTLPAttributes(attributes map[string]interface{}) []otlpAttribute {
+	encodedAttributes := make([]otlpAttribute, 0, len(attributes))
+
+	for key, value := range attributes {
+		var encodedValue map[string]interface{}
+
+		switch typedValue := value.(type) {
+		case string:
+			encodedValue = map[string]interface{}{"stringValue": typedValue}
+		case bool:
+			encodedValue = map[string]interface{}{"boolValue": typedValue}
+		case int:
+			encodedValue = map[string]interface{}{"intValue": strconv.Itoa(typedValue)}
+		case int64:
+			encodedValue = map[string]interface{}{"intValue": strconv.FormatInt(typedValue, 10)}
+		case float64:
+			encodedValue = map[string]interface{}{"doubleValue": typedValue}
+		default:
+			continue
+		}
+
+		encodedAttributes = append(encodedAttributes, otlpAttribute{Key: key, Value: encodedValue})
+	}
+
+	// for a stable encoding
+	sort.Slice(encodedAttributes, func(i, j int) bool {
+		return encodedAttributes[i].Key < encodedAttributes[j].Key
+	})
+
+	return encodedAttributes
+}
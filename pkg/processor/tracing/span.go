@@ -0,0 +1,104 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind follows the OTLP span kinds
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindConsumer SpanKind = 5
+)
+
+// Span is an operation within a trace. all methods are safe to call on a nil span, which is what a nil
+// tracer starts, so that callers needn't check whether tracing is enabled
+type Span struct {
+	tracer       *Tracer
+	name         string
+	kind         SpanKind
+	context      SpanContext
+	parentSpanID SpanID
+	startTime    time.Time
+	endTime      time.Time
+	ended        int32
+
+	lock         sync.Mutex
+	attributes   map[string]interface{}
+	errorMessage string
+}
+
+// StartChild starts a span whose parent is this span
+func (s *Span) StartChild(name string, kind SpanKind) *Span {
+	if s == nil {
+		return nil
+	}
+
+	return s.tracer.startSpan(name, kind, s.context)
+}
+
+// Context returns the span's context, to propagate to the operations it calls
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.context
+}
+
+// SetAttribute sets an attribute of the span - a string, bool, int, int64 or float64
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.errorMessage = err.Error()
+}
+
+// End ends the span, queuing it for export if it's sampled. ending a span more than once has no effect
+func (s *Span) End() {
+	if s == nil || !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+
+	s.endTime = time.Now()
+
+	if s.context.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceparentHeader is the W3C trace context header that propagates the trace of an event
+const TraceparentHeader = "traceparent"
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (tid TraceID) String() string {
+	return hex.EncodeToString(tid[:])
+}
+
+func (sid SpanID) String() string {
+	return hex.EncodeToString(sid[:])
+}
+
+// SpanContext identifies a span within its trace
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns whether the span context identifies a span (all zero IDs are invalid)
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value, returning whether it's valid
+func ParseTraceparent(traceparent string) (SpanContext, bool) {
+	var spanContext SpanContext
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+
+	// future versions may append fields, which are ignored
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return spanContext, false
+	}
+
+	if !decodeHex(parts[1], spanContext.TraceID[:]) ||
+		!decodeHex(parts[2], spanContext.SpanID[:]) {
+		return spanContext, false
+	}
+
+	var flags [1]byte
+	if !decodeHex(parts[3], flags[:]) {
+		return spanContext, false
+	}
+
+	spanContext.Sampled = flags[0]&0x01 == 0x01
+
+	return spanContext, spanContext.IsValid()
+}
+
+func decodeHex(encoded string, decoded []byte) bool {
+	if len(encoded) != hex.EncodedLen(len(decoded)) || strings.ToLower(encoded) != encoded {
+		return false
+	}
+
+	_, err := hex.Decode(decoded, []byte(encoded))
+	return err == nil
+}
+
+func newTraceID() TraceID {
+	var traceID TraceID
+	rand.Read(traceID[:]) // nolint: errcheck
+
+	return traceID
+}
+
+func newSpanID() SpanID {
+	var spanID SpanID
+	rand.Read(spanID[:]) // nolint: errcheck
+
+	return spanID
+}
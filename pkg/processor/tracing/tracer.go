@@ -0,0 +1,208 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultFlushInterval = 5 * time.Second
+	maxQueuedSpans       = 2048
+	maxExportBatchSize   = 512
+)
+
+type exporter interface {
+	export(spans []*Span) error
+}
+
+// Tracer starts spans and exports the sampled ones in batches, in the background. a nil tracer (returned
+// when tracing is disabled) starts nil spans
+type Tracer struct {
+	logger        logger.Logger
+	exporter      exporter
+	sampleRatio   float64
+	flushInterval time.Duration
+	spans         chan *Span
+	stopChan      chan struct{}
+	stoppedChan   chan struct{}
+}
+
+// NewTracer creates a tracer exporting to the configured OTLP/HTTP endpoint, with the given resource
+// attributes (e.g. service.name), or returns nil if tracing isn't enabled
+func NewTracer(parentLogger logger.Logger,
+	configuration *platformconfig.Tracing,
+	resourceAttributes map[string]interface{}) (*Tracer, error) {
+	var err error
+
+	if configuration.URL == "" || (configuration.Enabled != nil && !*configuration.Enabled) {
+		return nil, nil
+	}
+
+	newTracer := &Tracer{
+		logger:        parentLogger.GetChild("tracer"),
+		sampleRatio:   1,
+		flushInterval: defaultFlushInterval,
+		spans:         make(chan *Span, maxQueuedSpans),
+		stopChan:      make(chan struct{}),
+		stoppedChan:   make(chan struct{}),
+	}
+
+	if configuration.SampleRatio != nil {
+		if *configuration.SampleRatio < 0 || *configuration.SampleRatio > 1 {
+			return nil, errors.Errorf("Sample ratio must be between 0 and 1, got %f", *configuration.SampleRatio)
+		}
+
+		newTracer.sampleRatio = *configuration.SampleRatio
+	}
+
+	if configuration.FlushInterval != "" {
+		newTracer.flushInterval, err = time.ParseDuration(configuration.FlushInterval)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse flush interval")
+		}
+	}
+
+	newTracer.exporter = newOTLPExporter(configuration.URL, configuration.Headers, resourceAttributes)
+
+	newTracer.logger.InfoWith("Exporting traces",
+		"url", configuration.URL,
+		"sampleRatio", newTracer.sampleRatio,
+		"flushInterval", newTracer.flushInterval)
+
+	go newTracer.exportSpans()
+
+	return newTracer, nil
+}
+
+// StartSpan starts a span continuing the trace of the given W3C traceparent, or a new trace if it's empty
+// or invalid
+func (t *Tracer) StartSpan(name string, kind SpanKind, traceparent string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	parentContext, _ := ParseTraceparent(traceparent)
+
+	return t.startSpan(name, kind, parentContext)
+}
+
+// Stop exports the spans that have ended and stops exporting
+func (t *Tracer) Stop() {
+	if t == nil {
+		return
+	}
+
+	close(t.stopChan)
+	<-t.stoppedChan
+}
+
+func (t *Tracer) startSpan(name string, kind SpanKind, parentContext SpanContext) *Span {
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		startTime:  time.Now(),
+		attributes: map[string]interface{}{},
+	}
+
+	span.context.SpanID = newSpanID()
+
+	// a span is sampled along with its parent. a new trace is sampled by its ID, so that the decision is
+	// consistent across the services the trace spans
+	if parentContext.IsValid() {
+		span.context.TraceID = parentContext.TraceID
+		span.context.Sampled = parentContext.Sampled
+		span.parentSpanID = parentContext.SpanID
+	} else {
+		span.context.TraceID = newTraceID()
+		span.context.Sampled = t.shouldSample(span.context.TraceID)
+	}
+
+	return span
+}
+
+func (t *Tracer) shouldSample(traceID TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+
+	return float64(binary.BigEndian.Uint64(traceID[8:])) < t.sampleRatio*math.MaxUint64
+}
+
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case t.spans <- span:
+	default:
+
+		// never block the handling of events on exporting
+		t.logger.Debug("Span queue is full, dropping span")
+	}
+}
+
+func (t *Tracer) exportSpans() {
+	var batch []*Span
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := t.exporter.export(batch); err != nil {
+			t.logger.WarnWith("Failed to export spans", "numSpans", len(batch), "err", err.Error())
+		}
+
+		batch = nil
+	}
+
+	for {
+		select {
+		case span := <-t.spans:
+			batch = append(batch, span)
+			if len(batch) >= maxExportBatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-t.stopChan:
+
+			// drain whatever ended before stopping
+			for {
+				select {
+				case span := <-t.spans:
+					batch = append(batch, span)
+				default:
+					flush()
+					close(t.stoppedChan)
+					return
+				}
+			}
+		}
+	}
+}
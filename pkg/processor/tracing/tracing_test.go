@@ -0,0 +1,254 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type testEvent struct {
+	nuclio.AbstractEvent
+	headers map[string]interface{}
+}
+
+func (te *testEvent) GetHeader(key string) interface{} {
+	return te.headers[key]
+}
+
+func (te *testEvent) GetHeaders() map[string]interface{} {
+	return te.headers
+}
+
+type tracingTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	collector     *httptest.Server
+	collectorLock sync.Mutex
+	exportedSpans []map[string]interface{}
+	resources     []interface{}
+}
+
+func (suite *tracingTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.exportedSpans = nil
+	suite.collector = httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("/v1/traces", request.URL.Path)
+		suite.Require().Equal("application/json", request.Header.Get("Content-Type"))
+		suite.Require().Equal("secret", request.Header.Get("X-Api-Key"))
+
+		body, err := ioutil.ReadAll(request.Body)
+		suite.Require().NoError(err)
+
+		var exportRequest struct {
+			ResourceSpans []struct {
+				Resource   map[string]interface{}
+				ScopeSpans []struct {
+					Spans []map[string]interface{}
+				}
+			}
+		}
+
+		suite.Require().NoError(json.Unmarshal(body, &exportRequest))
+
+		suite.collectorLock.Lock()
+		defer suite.collectorLock.Unlock()
+
+		for _, resourceSpans := range exportRequest.ResourceSpans {
+			suite.resources = append(suite.resources, resourceSpans.Resource["attributes"])
+
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				suite.exportedSpans = append(suite.exportedSpans, scopeSpans.Spans...)
+			}
+		}
+	}))
+}
+
+func (suite *tracingTestSuite) TearDownTest() {
+	suite.collector.Close()
+}
+
+func (suite *tracingTestSuite) TestParseTraceparent() {
+	for _, testCase := range []struct {
+		name          string
+		traceparent   string
+		expectedValid bool
+		expectSampled bool
+	}{
+		{name: "sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectedValid: true, expectSampled: true},
+		{name: "notSampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", expectedValid: true},
+		{name: "futureVersion", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expectedValid: true, expectSampled: true},
+		{name: "empty", traceparent: ""},
+		{name: "invalidVersion", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zeroTraceID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "upperCase", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "shortSpanID", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01"},
+	} {
+		suite.Run(testCase.name, func() {
+			spanContext, valid := ParseTraceparent(testCase.traceparent)
+			suite.Require().Equal(testCase.expectedValid, valid)
+
+			if valid {
+				suite.Require().Equal("4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID.String())
+				suite.Require().Equal("00f067aa0ba902b7", spanContext.SpanID.String())
+				suite.Require().Equal(testCase.expectSampled, spanContext.Sampled)
+			}
+		})
+	}
+
+	spanContext, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	suite.Require().Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", spanContext.Traceparent())
+}
+
+func (suite *tracingTestSuite) TestExportSpans() {
+	tracer := suite.createTracer(1)
+
+	parentTraceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	span := tracer.StartSpan("http my-trigger", SpanKindServer, parentTraceparent)
+	span.SetAttribute("http.status_code", 500)
+
+	childSpan := span.StartChild("handle event", SpanKindInternal)
+	childSpan.SetError(errors.New("Handler failed"))
+	childSpan.End()
+
+	span.End()
+
+	// ending again has no effect
+	span.End()
+
+	tracer.Stop()
+
+	suite.Require().Len(suite.exportedSpans, 2)
+	suite.Require().Len(suite.resources, 1)
+
+	exportedChildSpan, exportedSpan := suite.exportedSpans[0], suite.exportedSpans[1]
+
+	// the span continues the incoming trace
+	suite.Require().Equal("4bf92f3577b34da6a3ce929d0e0e4736", exportedSpan["traceId"])
+	suite.Require().Equal("00f067aa0ba902b7", exportedSpan["parentSpanId"])
+	suite.Require().Equal(span.Context().SpanID.String(), exportedSpan["spanId"])
+	suite.Require().Equal("http my-trigger", exportedSpan["name"])
+	suite.Require().EqualValues(SpanKindServer, exportedSpan["kind"])
+	suite.Require().Equal([]interface{}{
+		map[string]interface{}{"key": "http.status_code", "value": map[string]interface{}{"intValue": "500"}},
+	}, exportedSpan["attributes"])
+	suite.Require().Equal(map[string]interface{}{"code": float64(otlpStatusCodeOK)}, exportedSpan["status"])
+
+	// the child span is a child of the span and carries the error
+	suite.Require().Equal("4bf92f3577b34da6a3ce929d0e0e4736", exportedChildSpan["traceId"])
+	suite.Require().Equal(span.Context().SpanID.String(), exportedChildSpan["parentSpanId"])
+	suite.Require().Equal(map[string]interface{}{
+		"code":    float64(otlpStatusCodeFail),
+		"message": "Handler failed",
+	}, exportedChildSpan["status"])
+}
+
+func (suite *tracingTestSuite) TestSampling() {
+	tracer := suite.createTracer(0)
+
+	// a new trace isn't sampled, but its context is still propagated
+	span := tracer.StartSpan("new", SpanKindServer, "")
+	suite.Require().True(span.Context().IsValid())
+	suite.Require().False(span.Context().Sampled)
+	span.End()
+
+	// a sampled parent's decision is followed regardless of the ratio
+	sampledSpan := tracer.StartSpan("sampled", SpanKindServer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	suite.Require().True(sampledSpan.Context().Sampled)
+	sampledSpan.End()
+
+	tracer.Stop()
+
+	suite.Require().Len(suite.exportedSpans, 1)
+	suite.Require().Equal("sampled", suite.exportedSpans[0]["name"])
+}
+
+func (suite *tracingTestSuite) TestDisabled() {
+	tracer, err := NewTracer(suite.logger, &platformconfig.Tracing{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().Nil(tracer)
+
+	// nil tracers and spans are no-ops
+	span := tracer.StartSpan("span", SpanKindServer, "")
+	span.SetAttribute("key", "value")
+	span.StartChild("child", SpanKindInternal).End()
+	span.End()
+	tracer.Stop()
+
+	suite.Require().False(span.Context().IsValid())
+}
+
+func (suite *tracingTestSuite) TestWithSpan() {
+	event := &testEvent{
+		headers: map[string]interface{}{
+			"Traceparent": []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+			"X-Other":     "value",
+		},
+	}
+
+	// untraced events are returned as is
+	suite.Require().Equal(event, WithSpan(event, nil))
+
+	tracer := suite.createTracer(1)
+	defer tracer.Stop()
+
+	span := tracer.StartSpan("span", SpanKindServer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracedEvent := WithSpan(event, span)
+
+	suite.Require().Equal(span.Context().Traceparent(), tracedEvent.GetHeaderString("traceparent"))
+	suite.Require().Equal(span.Context().Traceparent(), GetTraceparent(tracedEvent))
+	suite.Require().Equal(map[string]interface{}{
+		"traceparent": span.Context().Traceparent(),
+		"X-Other":     "value",
+	}, tracedEvent.GetHeaders())
+}
+
+func (suite *tracingTestSuite) createTracer(sampleRatio float64) *Tracer {
+	tracer, err := NewTracer(suite.logger, &platformconfig.Tracing{
+		URL:           suite.collector.URL,
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		SampleRatio:   &sampleRatio,
+		FlushInterval: "1h",
+	}, map[string]interface{}{
+		"service.name": "my-function",
+	})
+	suite.Require().NoError(err)
+	suite.Require().NotNil(tracer)
+
+	return tracer
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(tracingTestSuite))
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the time a batch waits to fill up, if the trigger doesn't specify one
+const defaultBatchMaxWait = time.Second
+
+// BatchRecord is a single record of a batch event
+type BatchRecord struct {
+	Body    string            `json:"body"`
+	ShardID int               `json:"shardID"`
+	Offset  int               `json:"offset"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// BatchEvent is the single event a batch of stream records is delivered to the function as. Its body
+// is a JSON array of the records
+type BatchEvent struct {
+	nuclio.AbstractEvent
+	path    string
+	shardID int
+	records []BatchRecord
+	body    []byte
+}
+
+// NewBatchEvent creates a batch event of the records read from the given path (e.g. topic) and shard
+func NewBatchEvent(path string, shardID int) *BatchEvent {
+	return &BatchEvent{
+		path:    path,
+		shardID: shardID,
+	}
+}
+
+// Add adds an event read from the stream to the batch, copying what it needs of it
+func (be *BatchEvent) Add(event nuclio.Event) {
+	record := BatchRecord{
+		Body:    string(event.GetBody()),
+		ShardID: event.GetShardID(),
+		Offset:  event.GetOffset(),
+	}
+
+	for headerName, headerValue := range event.GetHeaders() {
+		if record.Headers == nil {
+			record.Headers = map[string]string{}
+		}
+
+		switch typedHeaderValue := headerValue.(type) {
+		case []byte:
+			record.Headers[headerName] = string(typedHeaderValue)
+		case string:
+			record.Headers[headerName] = typedHeaderValue
+		default:
+			record.Headers[headerName] = fmt.Sprint(typedHeaderValue)
+		}
+	}
+
+	be.records = append(be.records, record)
+	be.body = nil
+}
+
+// Len returns the number of records in the batch
+func (be *BatchEvent) Len() int {
+	return len(be.records)
+}
+
+// Reset empties the batch, so that it can be reused for the next one
+func (be *BatchEvent) Reset() {
+	be.records = be.records[:0]
+	be.body = nil
+}
+
+func (be *BatchEvent) GetBody() []byte {
+	if be.body == nil {
+
+		// records hold only strings and ints, so encoding them can't fail
+		be.body, _ = json.Marshal(be.records)
+	}
+
+	return be.body
+}
+
+func (be *BatchEvent) GetSize() int {
+	return len(be.GetBody())
+}
+
+func (be *BatchEvent) GetContentType() string {
+	return "application/json"
+}
+
+func (be *BatchEvent) GetPath() string {
+	return be.path
+}
+
+func (be *BatchEvent) GetShardID() int {
+	return be.shardID
+}
+
+// GetOffset returns the offset of the last record in the batch
+func (be *BatchEvent) GetOffset() int {
+	if len(be.records) == 0 {
+		return 0
+	}
+
+	return be.records[len(be.records)-1].Offset
+}
+
+func (be *BatchEvent) GetHeaders() map[string]interface{} {
+	return map[string]interface{}{
+		"X-Nuclio-Batch-Size": len(be.records),
+	}
+}
+
+func (be *BatchEvent) GetHeader(key string) interface{} {
+	return be.GetHeaders()[key]
+}
+
+// GetBatchMaxSize returns the number of records a batch holds at most, or 0 if the trigger isn't batched
+func (c *Configuration) GetBatchMaxSize() int {
+	if c.Batch == nil {
+		return 0
+	}
+
+	return c.Batch.MaxSize
+}
+
+// GetBatchMaxWait returns how long a batch waits to fill up from when its first record was read
+func (c *Configuration) GetBatchMaxWait() time.Duration {
+	if c.Batch == nil || c.Batch.MaxWaitMs == 0 {
+		return defaultBatchMaxWait
+	}
+
+	return time.Duration(c.Batch.MaxWaitMs) * time.Millisecond
+}
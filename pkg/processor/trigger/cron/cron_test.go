@@ -18,19 +18,43 @@ package cron
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/test/suite"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
 
 	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	cronlib "github.com/robfig/cron"
 	"github.com/stretchr/testify/suite"
 )
 
+// recordingRuntime records when the missed ticks it got were due
+type recordingRuntime struct {
+	runtime.Runtime
+	missedTickTimes []string
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	if missedTickTime := event.GetHeaderString("X-Nuclio-Cron-Missed-Tick-Time"); missedTickTime != "" {
+		rr.missedTickTimes = append(rr.missedTickTimes, missedTickTime)
+	}
+
+	return nil, nil
+}
+
 type TestSuite struct {
 	processorsuite.TestSuite
-	trigger cron
+	trigger   cron
+	stateDirs []string
 }
 
 func (suite *TestSuite) SetupSuite() {
@@ -46,6 +70,16 @@ func (suite *TestSuite) SetupTest() {
 	suite.trigger.Logger = suite.Logger.GetChild("cron")
 }
 
+func (suite *TestSuite) TearDownTest() {
+	for _, stateDir := range suite.stateDirs {
+		os.RemoveAll(stateDir) // nolint: errcheck
+	}
+
+	suite.stateDirs = nil
+
+	suite.TestSuite.TearDownTest()
+}
+
 func (suite *TestSuite) TestGetInterval() {
 	var err error
 
@@ -171,6 +205,102 @@ func (suite *TestSuite) TestNextScheduleDayDifference() {
 	suite.Assert().Equal(nextEventSubmitTime.Day(), lastRuntime.Day()+1, "Event should be fired the next day")
 }
 
+func (suite *TestSuite) TestGetMissedTickTimes() {
+	suite.trigger.tickMethod = tickMethodInterval
+	suite.trigger.schedule = cronlib.ConstantDelaySchedule{Delay: time.Minute}
+	suite.trigger.configuration = &Configuration{MaxCatchUpTicks: 3}
+
+	now := time.Now()
+	lastFiredTime := now.Add(-5*time.Minute - 30*time.Second)
+
+	// the latest ticks are kept
+	missedTickTimes, droppedTicks := suite.trigger.getMissedTickTimes(lastFiredTime, now)
+	suite.Require().Equal([]time.Time{
+		lastFiredTime.Add(3 * time.Minute),
+		lastFiredTime.Add(4 * time.Minute),
+		lastFiredTime.Add(5 * time.Minute),
+	}, missedTickTimes)
+	suite.Require().Equal(2, droppedTicks)
+
+	// the next tick is still due
+	missedTickTimes, droppedTicks = suite.trigger.getMissedTickTimes(now.Add(-30*time.Second), now)
+	suite.Require().Empty(missedTickTimes)
+	suite.Require().Zero(droppedTicks)
+}
+
+func (suite *TestSuite) TestCatchUp() {
+	recordingRuntime := suite.createTriggerWithRecordingRuntime(map[string]interface{}{
+		"interval": "1m",
+		"catchUp":  true,
+	})
+
+	// never fired, nothing to catch up on
+	suite.Require().WithinDuration(time.Now(), suite.trigger.catchUp(), time.Second)
+	suite.Require().Empty(recordingRuntime.missedTickTimes)
+
+	// fired recently, the schedule is kept
+	lastFiredTime := time.Now().Add(-30 * time.Second)
+	suite.trigger.writeLastFiredTime(lastFiredTime)
+	suite.Require().True(lastFiredTime.Equal(suite.trigger.catchUp()))
+	suite.Require().Empty(recordingRuntime.missedTickTimes)
+
+	// the missed ticks are fired, each with when it was due
+	lastFiredTime = time.Now().Add(-3*time.Minute - 30*time.Second)
+	suite.trigger.writeLastFiredTime(lastFiredTime)
+	suite.Require().WithinDuration(time.Now(), suite.trigger.catchUp(), time.Second)
+
+	expectedMissedTickTimes := []string{
+		lastFiredTime.Add(time.Minute).Format(time.RFC3339),
+		lastFiredTime.Add(2 * time.Minute).Format(time.RFC3339),
+		lastFiredTime.Add(3 * time.Minute).Format(time.RFC3339),
+	}
+	suite.Require().Equal(expectedMissedTickTimes, recordingRuntime.missedTickTimes)
+
+	// the last missed tick is persisted as the last fired
+	persistedLastFiredTime, err := suite.trigger.readLastFiredTime()
+	suite.Require().NoError(err)
+	suite.Require().True(lastFiredTime.Add(3 * time.Minute).Equal(persistedLastFiredTime))
+}
+
+func (suite *TestSuite) TestPersistWithoutCatchUpSkipsMissedTicks() {
+	recordingRuntime := suite.createTriggerWithRecordingRuntime(map[string]interface{}{
+		"interval": "1m",
+		"persist":  true,
+	})
+
+	suite.trigger.writeLastFiredTime(time.Now().Add(-10 * time.Minute))
+	suite.Require().WithinDuration(time.Now(), suite.trigger.catchUp(), time.Second)
+	suite.Require().Empty(recordingRuntime.missedTickTimes)
+}
+
+func (suite *TestSuite) createTriggerWithRecordingRuntime(attributes map[string]interface{}) *recordingRuntime {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "cron",
+		Attributes: attributes,
+	}, &runtime.Configuration{Configuration: &processor.Configuration{}})
+	suite.Require().NoError(err)
+
+	recordingRuntime := &recordingRuntime{}
+	workerInstance, err := worker.NewWorker(suite.Logger, 0, recordingRuntime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewSingletonWorkerAllocator(suite.Logger, workerInstance)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.Logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	stateDir, err := ioutil.TempDir("", "cron-state-")
+	suite.Require().NoError(err)
+
+	suite.stateDirs = append(suite.stateDirs, stateDir)
+
+	suite.trigger = *triggerInstance.(*cron)
+	suite.trigger.stateFilePath = filepath.Join(stateDir, "test")
+
+	return recordingRuntime
+}
+
 func (suite *TestSuite) getInterval(delay string) (cronlib.Schedule, error) {
 	delayDuration, err := time.ParseDuration(delay)
 	if err != nil {
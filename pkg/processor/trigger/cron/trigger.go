@@ -17,6 +17,9 @@ limitations under the License.
 package cron
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -41,6 +44,9 @@ type cron struct {
 	tickMethod    int
 	schedule      cronlib.Schedule
 	stop          chan int
+
+	// where the time the trigger last fired is persisted
+	stateFilePath string
 }
 
 func newTrigger(logger logger.Logger,
@@ -61,6 +67,7 @@ func newTrigger(logger logger.Logger,
 		AbstractTrigger: abstractTrigger,
 		configuration:   configuration,
 		stop:            make(chan int),
+		stateFilePath:   filepath.Join(StateDir, configuration.ID),
 	}
 
 	if configuration.Interval != "" {
@@ -120,7 +127,7 @@ func (c *cron) GetConfig() map[string]interface{} {
 }
 
 func (c *cron) handleEvents() {
-	lastRunTime := time.Now()
+	lastRunTime := c.catchUp()
 	stop := false
 
 	for {
@@ -204,4 +211,131 @@ func (c *cron) handleTick() {
 		&c.configuration.Event,
 		c.Logger,
 		10*time.Second)
+
+	c.writeLastFiredTime(time.Now())
+}
+
+// catchUp fires the ticks missed since the trigger last fired (if configured to), returning the time the
+// schedule should continue from
+func (c *cron) catchUp() time.Time {
+	now := time.Now()
+
+	if !c.configuration.Persist {
+		return now
+	}
+
+	lastFiredTime, err := c.readLastFiredTime()
+	if err != nil {
+		c.Logger.WarnWith("Failed to read when the trigger last fired", "path", c.stateFilePath, "err", err.Error())
+		return now
+	}
+
+	// never fired before
+	if lastFiredTime.IsZero() {
+		return now
+	}
+
+	missedTickTimes, droppedTicks := c.getMissedTickTimes(lastFiredTime, now)
+
+	// the next tick is still due, keep the schedule
+	if len(missedTickTimes) == 0 {
+		return lastFiredTime
+	}
+
+	if !c.configuration.CatchUp {
+		c.Logger.InfoWith("Skipping ticks missed since the trigger last fired",
+			"lastFiredTime", lastFiredTime,
+			"missedTicks", len(missedTickTimes)+droppedTicks)
+
+		return now
+	}
+
+	c.Logger.InfoWith("Catching up on ticks missed since the trigger last fired",
+		"lastFiredTime", lastFiredTime,
+		"missedTicks", len(missedTickTimes)+droppedTicks,
+		"droppedTicks", droppedTicks)
+
+	for _, missedTickTime := range missedTickTimes {
+		c.AllocateWorkerAndSubmitEvent( // nolint: errcheck
+			c.getCatchUpEvent(missedTickTime),
+			c.Logger,
+			10*time.Second)
+
+		c.writeLastFiredTime(missedTickTime)
+	}
+
+	return now
+}
+
+// getMissedTickTimes returns the times of the ticks after lastFiredTime and up to now - the latest ones,
+// up to the max catch up ticks - and the number of earlier ticks which were dropped
+func (c *cron) getMissedTickTimes(lastFiredTime time.Time, now time.Time) ([]time.Time, int) {
+	var missedTickTimes []time.Time
+	var droppedTicks int
+
+	for tickTime := c.calculateNextEventSubmittingTime(lastFiredTime); !tickTime.After(now); tickTime = c.calculateNextEventSubmittingTime(tickTime) {
+		missedTickTimes = append(missedTickTimes, tickTime)
+
+		if len(missedTickTimes) > c.configuration.MaxCatchUpTicks {
+			missedTickTimes = missedTickTimes[1:]
+			droppedTicks++
+		}
+	}
+
+	return missedTickTimes, droppedTicks
+}
+
+// getCatchUpEvent returns the configured event, with a header holding when the missed tick was due
+func (c *cron) getCatchUpEvent(missedTickTime time.Time) *Event {
+	catchUpEvent := c.configuration.Event
+	catchUpEvent.Headers = map[string]interface{}{}
+
+	for headerKey, headerValue := range c.configuration.Event.Headers {
+		catchUpEvent.Headers[headerKey] = headerValue
+	}
+
+	catchUpEvent.Headers["X-Nuclio-Cron-Missed-Tick-Time"] = missedTickTime.Format(time.RFC3339)
+
+	return &catchUpEvent
+}
+
+// readLastFiredTime returns the persisted time the trigger last fired, or a zero time if it never fired
+func (c *cron) readLastFiredTime() (time.Time, error) {
+	encodedLastFiredTime, err := ioutil.ReadFile(c.stateFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, errors.Wrap(err, "Failed to read state file")
+	}
+
+	lastFiredTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(encodedLastFiredTime)))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Failed to parse state file")
+	}
+
+	return lastFiredTime, nil
+}
+
+func (c *cron) writeLastFiredTime(lastFiredTime time.Time) {
+	if !c.configuration.Persist {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.stateFilePath), 0755); err != nil {
+		c.Logger.WarnWith("Failed to create state directory", "path", c.stateFilePath, "err", err.Error())
+		return
+	}
+
+	// write and rename, so that a restart never finds a partially written file
+	temporaryStateFilePath := c.stateFilePath + ".tmp"
+	if err := ioutil.WriteFile(temporaryStateFilePath, []byte(lastFiredTime.Format(time.RFC3339Nano)), 0644); err != nil {
+		c.Logger.WarnWith("Failed to write state file", "path", c.stateFilePath, "err", err.Error())
+		return
+	}
+
+	if err := os.Rename(temporaryStateFilePath, c.stateFilePath); err != nil {
+		c.Logger.WarnWith("Failed to replace state file", "path", c.stateFilePath, "err", err.Error())
+	}
 }
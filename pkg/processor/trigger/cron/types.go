@@ -30,8 +30,22 @@ type Configuration struct {
 	Schedule string
 	Interval string
 	Event    Event
+
+	// persist when the trigger last fired (in StateDir), so that its schedule survives restarts
+	Persist bool
+
+	// when the trigger starts, fire the ticks missed since it last fired. implies Persist
+	CatchUp bool
+
+	// the most missed ticks fired when catching up (the latest ones)
+	MaxCatchUpTicks int
 }
 
+// StateDir is where the time each trigger last fired is persisted, in a file named by the trigger ID
+const StateDir = "/etc/nuclio/cron"
+
+const DefaultMaxCatchUpTicks = 100
+
 func NewConfiguration(ID string,
 	triggerConfiguration *functionconfig.Trigger,
 	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
@@ -45,5 +59,13 @@ func NewConfiguration(ID string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	if newConfiguration.CatchUp {
+		newConfiguration.Persist = true
+	}
+
+	if newConfiguration.MaxCatchUpTicks == 0 {
+		newConfiguration.MaxCatchUpTicks = DefaultMaxCatchUpTicks
+	}
+
 	return &newConfiguration, nil
 }
@@ -0,0 +1,72 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// how long an HTTP dead-letter target has to acknowledge a record
+const httpPublishTimeout = 10 * time.Second
+
+// httpPublisher posts each record to an HTTP endpoint, with its headers
+type httpPublisher struct {
+	logger logger.Logger
+	url    string
+	client *http.Client
+}
+
+func newHTTPPublisher(parentLogger logger.Logger, configuration *functionconfig.DeadLetter) (*httpPublisher, error) {
+	return &httpPublisher{
+		logger: parentLogger,
+		url:    configuration.URL,
+		client: &http.Client{Timeout: httpPublishTimeout},
+	}, nil
+}
+
+func (hp *httpPublisher) Publish(record *Record) error {
+	request, err := http.NewRequest(http.MethodPost, hp.url, bytes.NewReader(record.Body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create dead-letter request")
+	}
+
+	for headerName, headerValue := range record.GetHeaders() {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	response, err := hp.client.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to post dead-letter request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode >= http.StatusBadRequest {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return errors.Errorf("Dead-letter endpoint responded with %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/Shopify/sarama"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// kafkaPublisher produces each record to a kafka topic, with its headers as the message's headers
+type kafkaPublisher struct {
+	logger   logger.Logger
+	topic    string
+	producer sarama.SyncProducer
+}
+
+func newKafkaPublisher(parentLogger logger.Logger, configuration *functionconfig.DeadLetter) (*kafkaPublisher, error) {
+	config := sarama.NewConfig()
+
+	// message headers were added in 0.11
+	config.Version = sarama.V0_11_0_0
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(strings.Split(configuration.URL, ","), config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create kafka producer")
+	}
+
+	return &kafkaPublisher{
+		logger:   parentLogger,
+		topic:    configuration.Topic,
+		producer: producer,
+	}, nil
+}
+
+func (kp *kafkaPublisher) Publish(record *Record) error {
+	var headers []sarama.RecordHeader
+	for headerName, headerValue := range record.GetHeaders() {
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(headerName),
+			Value: []byte(headerValue),
+		})
+	}
+
+	if _, _, err := kp.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   kp.topic,
+		Value:   sarama.ByteEncoder(record.Body),
+		Headers: headers,
+	}); err != nil {
+		return errors.Wrapf(err, "Failed to produce to topic %s", kp.topic)
+	}
+
+	return nil
+}
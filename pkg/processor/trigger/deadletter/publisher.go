@@ -0,0 +1,116 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the headers describing why an event was dead-lettered, published along with the event's own headers
+const (
+	ErrorHeaderName       = "X-Nuclio-Dead-Letter-Error"
+	AttemptsHeaderName    = "X-Nuclio-Dead-Letter-Attempts"
+	TriggerHeaderName     = "X-Nuclio-Dead-Letter-Trigger"
+	FunctionHeaderName    = "X-Nuclio-Dead-Letter-Function"
+	ContentTypeHeaderName = "Content-Type"
+)
+
+// Publisher publishes events the function failed to process to a dead-letter target
+type Publisher interface {
+
+	// Publish publishes the record, returning once the target acknowledged it
+	Publish(record *Record) error
+}
+
+// Record is an event the function failed to process
+type Record struct {
+	Body         []byte
+	ContentType  string
+	Headers      map[string]string
+	TriggerName  string
+	FunctionName string
+	Attempts     int
+	Error        string
+}
+
+// NewRecord copies the event (which the trigger may reuse once the record is published) into a record
+func NewRecord(event nuclio.Event, triggerName string, functionName string, attempts int, processError error) *Record {
+	record := &Record{
+		Body:         append([]byte{}, event.GetBody()...),
+		ContentType:  event.GetContentType(),
+		Headers:      map[string]string{},
+		TriggerName:  triggerName,
+		FunctionName: functionName,
+		Attempts:     attempts,
+		Error:        processError.Error(),
+	}
+
+	for headerName, headerValue := range event.GetHeaders() {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			record.Headers[headerName] = typedHeaderValue
+		case []byte:
+			record.Headers[headerName] = string(typedHeaderValue)
+		default:
+			record.Headers[headerName] = fmt.Sprint(typedHeaderValue)
+		}
+	}
+
+	return record
+}
+
+// GetHeaders returns the event's headers along with those describing why it was dead-lettered
+func (r *Record) GetHeaders() map[string]string {
+	headers := map[string]string{}
+	for headerName, headerValue := range r.Headers {
+		headers[headerName] = headerValue
+	}
+
+	if r.ContentType != "" {
+		headers[ContentTypeHeaderName] = r.ContentType
+	}
+
+	headers[ErrorHeaderName] = r.Error
+	headers[AttemptsHeaderName] = strconv.Itoa(r.Attempts)
+	headers[TriggerHeaderName] = r.TriggerName
+	headers[FunctionHeaderName] = r.FunctionName
+
+	return headers
+}
+
+// NewPublisher creates a publisher to the configured dead-letter target
+func NewPublisher(parentLogger logger.Logger, configuration *functionconfig.DeadLetter) (Publisher, error) {
+	loggerInstance := parentLogger.GetChild("deadletter")
+
+	switch configuration.Kind {
+	case functionconfig.DeadLetterKindKafka:
+		return newKafkaPublisher(loggerInstance, configuration)
+	case functionconfig.DeadLetterKindV3ioStream:
+		return newV3ioStreamPublisher(loggerInstance, configuration)
+	case functionconfig.DeadLetterKindHTTP:
+		return newHTTPPublisher(loggerInstance, configuration)
+	default:
+		return nil, errors.Errorf("Unsupported dead-letter kind: %s", configuration.Kind)
+	}
+}
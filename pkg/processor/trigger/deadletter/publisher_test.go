@@ -0,0 +1,136 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type testEvent struct {
+	nuclio.AbstractEvent
+	body    []byte
+	headers map[string]interface{}
+}
+
+func (te *testEvent) GetBody() []byte {
+	return te.body
+}
+
+func (te *testEvent) GetContentType() string {
+	return "application/json"
+}
+
+func (te *testEvent) GetHeaders() map[string]interface{} {
+	return te.headers
+}
+
+type publisherTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *publisherTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *publisherTestSuite) TestNewRecord() {
+	event := &testEvent{
+		body: []byte(`{"a": 1}`),
+		headers: map[string]interface{}{
+			"X-String": "value",
+			"X-Bytes":  []byte("bytes"),
+			"X-Int":    3,
+		},
+	}
+
+	record := NewRecord(event, "my-trigger", "my-function", 3, errors.New("Something failed"))
+
+	// the body is copied, since triggers reuse their events
+	event.body[0] = '['
+	suite.Require().Equal(`{"a": 1}`, string(record.Body))
+
+	suite.Require().Equal(map[string]string{
+		"X-String":            "value",
+		"X-Bytes":             "bytes",
+		"X-Int":               "3",
+		ContentTypeHeaderName: "application/json",
+		ErrorHeaderName:       "Something failed",
+		AttemptsHeaderName:    "3",
+		TriggerHeaderName:     "my-trigger",
+		FunctionHeaderName:    "my-function",
+	}, record.GetHeaders())
+}
+
+func (suite *publisherTestSuite) TestHTTPPublisher() {
+	var receivedBody []byte
+	var receivedHeaders http.Header
+
+	responseStatusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = ioutil.ReadAll(r.Body)
+		receivedHeaders = r.Header
+		w.WriteHeader(responseStatusCode)
+	}))
+	defer server.Close()
+
+	publisher, err := NewPublisher(suite.logger, &functionconfig.DeadLetter{
+		Kind: functionconfig.DeadLetterKindHTTP,
+		URL:  server.URL,
+	})
+	suite.Require().NoError(err)
+
+	record := &Record{
+		Body:         []byte("body"),
+		Headers:      map[string]string{"X-Custom": "custom"},
+		TriggerName:  "my-trigger",
+		FunctionName: "my-function",
+		Attempts:     1,
+		Error:        "Something failed",
+	}
+
+	err = publisher.Publish(record)
+	suite.Require().NoError(err)
+	suite.Require().Equal("body", string(receivedBody))
+	suite.Require().Equal("custom", receivedHeaders.Get("X-Custom"))
+	suite.Require().Equal("Something failed", receivedHeaders.Get(ErrorHeaderName))
+	suite.Require().Equal("1", receivedHeaders.Get(AttemptsHeaderName))
+
+	// a record the endpoint rejects isn't published
+	responseStatusCode = http.StatusServiceUnavailable
+	err = publisher.Publish(record)
+	suite.Require().Error(err)
+}
+
+func (suite *publisherTestSuite) TestUnsupportedKind() {
+	_, err := NewPublisher(suite.logger, &functionconfig.DeadLetter{Kind: "carrier-pigeon"})
+	suite.Require().Error(err)
+}
+
+func TestPublisherTestSuite(t *testing.T) {
+	suite.Run(t, new(publisherTestSuite))
+}
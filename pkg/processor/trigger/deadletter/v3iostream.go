@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"encoding/json"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	v3io "github.com/v3io/v3io-go/pkg/dataplane"
+	v3iohttp "github.com/v3io/v3io-go/pkg/dataplane/http"
+)
+
+// v3ioStreamPublisher puts each record in a v3io stream. the record's headers are its client info, encoded
+// as JSON
+type v3ioStreamPublisher struct {
+	logger     logger.Logger
+	streamPath string
+	container  v3io.Container
+}
+
+func newV3ioStreamPublisher(parentLogger logger.Logger,
+	configuration *functionconfig.DeadLetter) (*v3ioStreamPublisher, error) {
+	v3ioContext, err := v3iohttp.NewContext(parentLogger, &v3iohttp.NewContextInput{
+		NumWorkers: 1,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io context")
+	}
+
+	v3ioSession, err := v3ioContext.NewSession(&v3io.NewSessionInput{
+		URL:       configuration.URL,
+		AccessKey: configuration.Secret,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io session")
+	}
+
+	v3ioContainer, err := v3ioSession.NewContainer(&v3io.NewContainerInput{
+		ContainerName: configuration.ContainerName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create v3io container")
+	}
+
+	return &v3ioStreamPublisher{
+		logger:     parentLogger,
+		streamPath: configuration.StreamPath,
+		container:  v3ioContainer,
+	}, nil
+}
+
+func (vsp *v3ioStreamPublisher) Publish(record *Record) error {
+	clientInfo, err := json.Marshal(record.GetHeaders())
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode record headers")
+	}
+
+	response, err := vsp.container.PutRecordsSync(&v3io.PutRecordsInput{
+		Path: vsp.streamPath,
+		Records: []*v3io.StreamRecord{
+			{Data: record.Body, ClientInfo: clientInfo},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to put record in stream %s", vsp.streamPath)
+	}
+
+	defer response.Release()
+
+	putRecordsOutput := response.Output.(*v3io.PutRecordsOutput)
+	if putRecordsOutput.FailedRecordCount > 0 {
+		return errors.Errorf("Failed to put record in stream %s: %s",
+			vsp.streamPath,
+			putRecordsOutput.Records[0].ErrorMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event is a single request message of a call
+type Event struct {
+	nuclio.AbstractEvent
+	body        []byte
+	contentType string
+	path        string
+	timestamp   time.Time
+
+	// the metadata of the call, along with the headers of the request. keys are lower cased
+	headers map[string]string
+}
+
+// GetContentType returns the content type of the request
+func (e *Event) GetContentType() string {
+	return e.contentType
+}
+
+// GetBody returns the payload of the request
+func (e *Event) GetBody() []byte {
+	return e.body
+}
+
+// GetHeader returns the header by name as an interface{}
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaderString(key)
+}
+
+// GetHeaderByteSlice returns the header by name as a byte slice
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeaderString returns the header by name as a string
+func (e *Event) GetHeaderString(key string) string {
+	return e.headers[strings.ToLower(key)]
+}
+
+// GetHeaders returns the metadata of the call and the headers of the request
+func (e *Event) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{}
+	for headerKey, headerValue := range e.headers {
+		headers[headerKey] = headerValue
+	}
+
+	return headers
+}
+
+// GetMethod returns POST, as all calls are
+func (e *Event) GetMethod() string {
+	return http.MethodPost
+}
+
+// GetPath returns the path of the request if it has one, or else the full name of the called method
+func (e *Event) GetPath() string {
+	return e.path
+}
+
+// GetTimestamp returns when the request was received
+func (e *Event) GetTimestamp() time.Time {
+	return e.timestamp
+}
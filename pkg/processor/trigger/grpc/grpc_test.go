@@ -0,0 +1,410 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	google_grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpc_reflection "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	grpc_status "google.golang.org/grpc/status"
+)
+
+// echoRuntime responds with the upper cased body along with the path and name header of the event, fails
+// on "fail" and responds with a 404 on "missing"
+type echoRuntime struct {
+	runtime.Runtime
+}
+
+func (er *echoRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	switch string(event.GetBody()) {
+	case "fail":
+		return nil, errors.New("Failed")
+	case "missing":
+		return nuclio.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       []byte("Not here"),
+		}, nil
+	}
+
+	return nuclio.Response{
+		Body:        []byte(strings.ToUpper(string(event.GetBody()))),
+		ContentType: event.GetContentType(),
+		Headers: map[string]interface{}{
+			"X-Path": event.GetPath(),
+			"X-Name": event.GetHeaderString("X-Name"),
+		},
+	}, nil
+}
+
+func (er *echoRuntime) GetStatus() status.Status {
+	return status.Ready
+}
+
+type grpcTestSuite struct {
+	suite.Suite
+	logger           logger.Logger
+	trigger          *grpc
+	clientConnection *google_grpc.ClientConn
+}
+
+func (suite *grpcTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *grpcTestSuite) TearDownTest() {
+	if suite.clientConnection != nil {
+		suite.clientConnection.Close() // nolint: errcheck
+		suite.clientConnection = nil
+	}
+
+	if suite.trigger != nil {
+		_, err := suite.trigger.Stop(false)
+		suite.Require().NoError(err)
+
+		suite.trigger = nil
+	}
+}
+
+func (suite *grpcTestSuite) TestConfigurationDefaults() {
+	configuration := suite.createConfiguration(nil)
+
+	suite.Require().Equal(DefaultURL, configuration.URL)
+	suite.Require().Equal(DefaultMaxMessageSize, configuration.MaxMessageSize)
+
+	for _, attributes := range []map[string]interface{}{
+		{"maxMessageSize": -1},
+		{"methods": []string{"Echo"}},
+		{"methods": []string{"/Service/Echo"}},
+		{"descriptorSetPath": "/etc/nuclio/echo.pb"},
+	} {
+		_, err := NewConfiguration("test", &functionconfig.Trigger{
+			Kind:       "grpc",
+			Attributes: attributes,
+		}, suite.createRuntimeConfiguration())
+		suite.Require().Error(err)
+	}
+}
+
+func (suite *grpcTestSuite) TestInvoke() {
+	suite.startTrigger(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// metadata of the call is exposed as headers, and overridden by the headers of the request
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-name", "from-metadata")
+
+	response := &InvokeResponse{}
+	err := suite.clientConnection.Invoke(ctx, invokeMethodName, &InvokeRequest{
+		Body:        []byte("hello"),
+		ContentType: "text/plain",
+	}, response)
+	suite.Require().NoError(err)
+	suite.Require().Equal(&InvokeResponse{
+		Body:        []byte("HELLO"),
+		ContentType: "text/plain",
+		StatusCode:  http.StatusOK,
+		Headers: map[string]string{
+			"x-path": invokeMethodName,
+			"x-name": "from-metadata",
+		},
+	}, response)
+
+	response = &InvokeResponse{}
+	err = suite.clientConnection.Invoke(ctx, invokeMethodName, &InvokeRequest{
+		Body:    []byte("hello"),
+		Path:    "/greet",
+		Headers: map[string]string{"X-Name": "from-request"},
+	}, response)
+	suite.Require().NoError(err)
+	suite.Require().Equal("/greet", response.Headers["x-path"])
+	suite.Require().Equal("from-request", response.Headers["x-name"])
+
+	// errors and error status codes are returned as gRPC errors
+	err = suite.clientConnection.Invoke(ctx, invokeMethodName, &InvokeRequest{Body: []byte("fail")}, &InvokeResponse{})
+	suite.Require().Equal(codes.Unknown, grpc_status.Code(err))
+
+	err = suite.clientConnection.Invoke(ctx, invokeMethodName, &InvokeRequest{Body: []byte("missing")}, &InvokeResponse{})
+	suite.Require().Equal(codes.NotFound, grpc_status.Code(err))
+	suite.Require().Equal("Not here", grpc_status.Convert(err).Message())
+}
+
+func (suite *grpcTestSuite) TestInvokeStream() {
+	suite.startTrigger(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := suite.clientConnection.NewStream(ctx, &invokeServiceDesc.Streams[0], invokeStreamMethodName)
+	suite.Require().NoError(err)
+
+	// each request is an event, responded to in order
+	for _, body := range []string{"one", "two", "three"} {
+		err = stream.SendMsg(&InvokeRequest{Body: []byte(body)})
+		suite.Require().NoError(err)
+
+		response := &InvokeResponse{}
+		err = stream.RecvMsg(response)
+		suite.Require().NoError(err)
+		suite.Require().Equal(strings.ToUpper(body), string(response.Body))
+	}
+
+	suite.Require().NoError(stream.CloseSend())
+}
+
+func (suite *grpcTestSuite) TestUserProvidedMethods() {
+	suite.startTrigger(map[string]interface{}{
+		"methods": []string{"/echo.Echo/Echo"},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the messages of user provided methods are passed as is
+	var responseHeader metadata.MD
+	response := &rawMessage{}
+	err := suite.clientConnection.Invoke(ctx,
+		"/echo.Echo/Echo",
+		&rawMessage{data: []byte("raw")},
+		response,
+		google_grpc.Header(&responseHeader),
+		google_grpc.CallCustomCodec(codec{})) // nolint: staticcheck
+	suite.Require().NoError(err)
+	suite.Require().Equal("RAW", string(response.data))
+	suite.Require().Equal([]string{"/echo.Echo/Echo"}, responseHeader.Get("x-path"))
+
+	// methods which aren't configured aren't served
+	err = suite.clientConnection.Invoke(ctx,
+		"/echo.Echo/Other",
+		&rawMessage{data: []byte("raw")},
+		&rawMessage{},
+		google_grpc.CallCustomCodec(codec{})) // nolint: staticcheck
+	suite.Require().Equal(codes.Unimplemented, grpc_status.Code(err))
+}
+
+func (suite *grpcTestSuite) TestReflection() {
+	descriptorSetPath := suite.writeEchoDescriptorSet()
+	defer os.Remove(descriptorSetPath) // nolint: errcheck
+
+	suite.startTrigger(map[string]interface{}{
+		"reflection":        true,
+		"descriptorSetPath": descriptorSetPath,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reflectionClient, err := grpc_reflection.NewServerReflectionClient(suite.clientConnection).ServerReflectionInfo(ctx)
+	suite.Require().NoError(err)
+
+	// the generic service and those of the descriptor set are reflected
+	err = reflectionClient.Send(&grpc_reflection.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection.ServerReflectionRequest_ListServices{},
+	})
+	suite.Require().NoError(err)
+
+	reflectionResponse, err := reflectionClient.Recv()
+	suite.Require().NoError(err)
+
+	var serviceNames []string
+	for _, serviceResponse := range reflectionResponse.GetListServicesResponse().GetService() {
+		serviceNames = append(serviceNames, serviceResponse.GetName())
+	}
+
+	suite.Require().Equal([]string{
+		"echo.Echo",
+		"grpc.reflection.v1alpha.ServerReflection",
+		"nuclio.Function",
+	}, serviceNames)
+
+	for symbol, expectedFileName := range map[string]string{
+		"nuclio.Function":        "invoke.proto",
+		"nuclio.Function.Invoke": "invoke.proto",
+		"echo.Echo":              "echo.proto",
+	} {
+		err = reflectionClient.Send(&grpc_reflection.ServerReflectionRequest{
+			MessageRequest: &grpc_reflection.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: symbol,
+			},
+		})
+		suite.Require().NoError(err)
+
+		reflectionResponse, err = reflectionClient.Recv()
+		suite.Require().NoError(err)
+		suite.Require().Nil(reflectionResponse.GetErrorResponse(), symbol)
+
+		fileDescriptorProto := &descriptor.FileDescriptorProto{}
+		err = proto.Unmarshal(reflectionResponse.GetFileDescriptorResponse().GetFileDescriptorProto()[0],
+			fileDescriptorProto)
+		suite.Require().NoError(err)
+		suite.Require().Equal(expectedFileName, fileDescriptorProto.GetName())
+	}
+
+	suite.Require().NoError(reflectionClient.CloseSend())
+
+	// the services of the descriptor set are served like other user provided services
+	response := &rawMessage{}
+	err = suite.clientConnection.Invoke(ctx,
+		"/echo.Echo/Echo",
+		&rawMessage{data: []byte("raw")},
+		response,
+		google_grpc.CallCustomCodec(codec{})) // nolint: staticcheck
+	suite.Require().NoError(err)
+	suite.Require().Equal("RAW", string(response.data))
+}
+
+func (suite *grpcTestSuite) TestReflectionMethodsNotPassedToFunction() {
+	suite.startTrigger(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// without reflection, its calls aren't served - rather than being submitted as events
+	err := suite.clientConnection.Invoke(ctx,
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+		&rawMessage{data: []byte("raw")},
+		&rawMessage{},
+		google_grpc.CallCustomCodec(codec{})) // nolint: staticcheck
+	suite.Require().Equal(codes.Unimplemented, grpc_status.Code(err))
+}
+
+// writeEchoDescriptorSet writes the descriptor set of a service with an Echo method, returning its path
+func (suite *grpcTestSuite) writeEchoDescriptorSet() string {
+	descriptorSet := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("echo.proto"),
+				Package: proto.String("echo"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Message"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{
+								Name:   proto.String("text"),
+								Number: proto.Int32(1),
+								Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+						},
+					},
+				},
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("Echo"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{
+								Name:       proto.String("Echo"),
+								InputType:  proto.String(".echo.Message"),
+								OutputType: proto.String(".echo.Message"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	encodedDescriptorSet, err := proto.Marshal(descriptorSet)
+	suite.Require().NoError(err)
+
+	descriptorSetFile, err := ioutil.TempFile("", "echo-*.pb")
+	suite.Require().NoError(err)
+
+	defer descriptorSetFile.Close() // nolint: errcheck
+
+	_, err = descriptorSetFile.Write(encodedDescriptorSet)
+	suite.Require().NoError(err)
+
+	return descriptorSetFile.Name()
+}
+
+func (suite *grpcTestSuite) startTrigger(attributes map[string]interface{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	address := listener.Addr().String()
+	listener.Close() // nolint: errcheck
+
+	configuration := suite.createConfiguration(attributes)
+	configuration.URL = address
+
+	var workers []*worker.Worker
+	for workerIndex := 0; workerIndex < 2; workerIndex++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIndex, &echoRuntime{})
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*grpc)
+
+	err = suite.trigger.Start(nil)
+	suite.Require().NoError(err)
+
+	suite.clientConnection, err = google_grpc.Dial(address, google_grpc.WithInsecure())
+	suite.Require().NoError(err)
+}
+
+func (suite *grpcTestSuite) createConfiguration(attributes map[string]interface{}) *Configuration {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "grpc",
+		Attributes: attributes,
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	return configuration
+}
+
+func (suite *grpcTestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+}
+
+func TestGRPCSuite(t *testing.T) {
+	suite.Run(t, new(grpcTestSuite))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	google_grpc "google.golang.org/grpc"
+)
+
+// the messages and service of invoke.proto
+
+const (
+	invokeServiceName      = "nuclio.Function"
+	invokeMethodName       = "/nuclio.Function/Invoke"
+	invokeStreamMethodName = "/nuclio.Function/InvokeStream"
+)
+
+// InvokeRequest is a request of the generic service
+type InvokeRequest struct {
+	Body        []byte            `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	ContentType string            `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Headers     map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Path        string            `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+}
+
+func (m *InvokeRequest) Reset()         { *m = InvokeRequest{} }
+func (m *InvokeRequest) String() string { return proto.CompactTextString(m) }
+func (*InvokeRequest) ProtoMessage()    {}
+
+// InvokeResponse is a response of the generic service
+type InvokeResponse struct {
+	Body        []byte            `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	ContentType string            `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Headers     map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	StatusCode  int32             `protobuf:"varint,4,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+}
+
+func (m *InvokeResponse) Reset()         { *m = InvokeResponse{} }
+func (m *InvokeResponse) String() string { return proto.CompactTextString(m) }
+func (*InvokeResponse) ProtoMessage()    {}
+
+// invokeServer is implemented by the trigger
+type invokeServer interface {
+	invoke(ctx context.Context, request *InvokeRequest) (*InvokeResponse, error)
+	invokeStream(stream google_grpc.ServerStream) error
+}
+
+var invokeServiceDesc = google_grpc.ServiceDesc{
+	ServiceName: invokeServiceName,
+	HandlerType: (*invokeServer)(nil),
+	Methods: []google_grpc.MethodDesc{
+		{
+			MethodName: "Invoke",
+			Handler: func(server interface{},
+				ctx context.Context,
+				decode func(interface{}) error,
+				interceptor google_grpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &InvokeRequest{}
+				if err := decode(request); err != nil {
+					return nil, err
+				}
+
+				return server.(invokeServer).invoke(ctx, request)
+			},
+		},
+	},
+	Streams: []google_grpc.StreamDesc{
+		{
+			StreamName: "InvokeStream",
+			Handler: func(server interface{}, stream google_grpc.ServerStream) error {
+				return server.(invokeServer).invokeStream(stream)
+			},
+			ClientStreams: true,
+			ServerStreams: true,
+		},
+	},
+	Metadata: invokeFileDescriptor,
+}
+
+// rawMessage is a message of a method which isn't of the generic service, passed to and from the function as is
+type rawMessage struct {
+	data []byte
+}
+
+// codec encodes raw messages as is, and the messages of the generic service as protobuf
+type codec struct{}
+
+func (c codec) Marshal(v interface{}) ([]byte, error) {
+	if message, isRaw := v.(*rawMessage); isRaw {
+		return message.data, nil
+	}
+
+	return proto.Marshal(v.(proto.Message))
+}
+
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	if message, isRaw := v.(*rawMessage); isRaw {
+		message.data = append([]byte{}, data...)
+		return nil
+	}
+
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+// String returns the name of the codec, which is the content subtype of the calls
+func (c codec) String() string {
+	return "proto"
+}
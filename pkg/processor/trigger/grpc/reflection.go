@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/nuclio/errors"
+	google_grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// the methods of the reflection service, which are never passed to the function
+const reflectionMethodPrefix = "/grpc.reflection."
+
+// the gzipped file descriptor of invoke.proto, which is how the reflection service expects a service's metadata
+var invokeFileDescriptor = mustEncodeFileDescriptor(newInvokeFileDescriptorProto())
+
+// registerReflection serves the reflection service, reflecting the generic service along with the services of
+// the configured descriptor set (if any), which are served like any other user provided service
+func (g *grpc) registerReflection() error {
+	if g.configuration.DescriptorSetPath != "" {
+		if err := g.registerDescriptorSetServices(g.configuration.DescriptorSetPath); err != nil {
+			return errors.Wrap(err, "Failed to register the services of the descriptor set")
+		}
+	}
+
+	reflection.Register(g.server)
+
+	return nil
+}
+
+// registerDescriptorSetServices registers the services of a descriptor set (as generated by protoc with
+// --descriptor_set_out and --include_imports), so that they're reflected
+func (g *grpc) registerDescriptorSetServices(descriptorSetPath string) error {
+	encodedDescriptorSet, err := ioutil.ReadFile(descriptorSetPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read descriptor set %s", descriptorSetPath)
+	}
+
+	descriptorSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(encodedDescriptorSet, descriptorSet); err != nil {
+		return errors.Wrapf(err, "Failed to decode descriptor set %s", descriptorSetPath)
+	}
+
+	for _, fileDescriptorProto := range descriptorSet.File {
+		fileDescriptor, err := encodeFileDescriptor(fileDescriptorProto)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to encode file descriptor %s", fileDescriptorProto.GetName())
+		}
+
+		// the reflection service resolves the files a file imports by name
+		if proto.FileDescriptor(fileDescriptorProto.GetName()) == nil {
+			proto.RegisterFile(fileDescriptorProto.GetName(), fileDescriptor)
+		}
+
+		for _, serviceDescriptorProto := range fileDescriptorProto.Service {
+			serviceName := serviceDescriptorProto.GetName()
+			if packageName := fileDescriptorProto.GetPackage(); packageName != "" {
+				serviceName = packageName + "." + serviceName
+			}
+
+			if serviceName == invokeServiceName {
+				continue
+			}
+
+			g.server.RegisterService(g.newUserServiceDesc(serviceName, serviceDescriptorProto, fileDescriptor), g)
+		}
+	}
+
+	return nil
+}
+
+// newUserServiceDesc returns the description of a user provided service, all of whose methods are handled
+// like those of services which aren't registered
+func (g *grpc) newUserServiceDesc(serviceName string,
+	serviceDescriptorProto *descriptor.ServiceDescriptorProto,
+	fileDescriptor []byte) *google_grpc.ServiceDesc {
+	serviceDesc := &google_grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    fileDescriptor,
+	}
+
+	for _, methodDescriptorProto := range serviceDescriptorProto.Method {
+		serviceDesc.Streams = append(serviceDesc.Streams, google_grpc.StreamDesc{
+			StreamName:    methodDescriptorProto.GetName(),
+			Handler:       g.invokeMethod,
+			ClientStreams: true,
+			ServerStreams: true,
+		})
+	}
+
+	return serviceDesc
+}
+
+func isReflectionMethod(method string) bool {
+	return strings.HasPrefix(method, reflectionMethodPrefix)
+}
+
+func encodeFileDescriptor(fileDescriptorProto *descriptor.FileDescriptorProto) ([]byte, error) {
+	encodedFileDescriptor, err := proto.Marshal(fileDescriptorProto)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal file descriptor")
+	}
+
+	var gzippedFileDescriptor bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&gzippedFileDescriptor)
+	if _, err := gzipWriter.Write(encodedFileDescriptor); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress file descriptor")
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress file descriptor")
+	}
+
+	return gzippedFileDescriptor.Bytes(), nil
+}
+
+func mustEncodeFileDescriptor(fileDescriptorProto *descriptor.FileDescriptorProto) []byte {
+	fileDescriptor, err := encodeFileDescriptor(fileDescriptorProto)
+	if err != nil {
+		panic(err)
+	}
+
+	return fileDescriptor
+}
+
+// newInvokeFileDescriptorProto returns the descriptor of invoke.proto
+func newInvokeFileDescriptorProto() *descriptor.FileDescriptorProto {
+	newField := func(name string, jsonName string, number int32, fieldType descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto {
+		return &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(jsonName),
+			Number:   proto.Int32(number),
+			Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     fieldType.Enum(),
+		}
+	}
+
+	// maps are repeated fields of a nested entry message
+	newHeadersField := func(messageName string) *descriptor.FieldDescriptorProto {
+		headersField := newField("headers", "headers", 3, descriptor.FieldDescriptorProto_TYPE_MESSAGE)
+		headersField.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		headersField.TypeName = proto.String(".nuclio." + messageName + ".HeadersEntry")
+
+		return headersField
+	}
+
+	headersEntry := &descriptor.DescriptorProto{
+		Name: proto.String("HeadersEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			newField("key", "key", 1, descriptor.FieldDescriptorProto_TYPE_STRING),
+			newField("value", "value", 2, descriptor.FieldDescriptorProto_TYPE_STRING),
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String("invoke.proto"),
+		Package: proto.String("nuclio"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("InvokeRequest"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("body", "body", 1, descriptor.FieldDescriptorProto_TYPE_BYTES),
+					newField("content_type", "contentType", 2, descriptor.FieldDescriptorProto_TYPE_STRING),
+					newHeadersField("InvokeRequest"),
+					newField("path", "path", 4, descriptor.FieldDescriptorProto_TYPE_STRING),
+				},
+				NestedType: []*descriptor.DescriptorProto{headersEntry},
+			},
+			{
+				Name: proto.String("InvokeResponse"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("body", "body", 1, descriptor.FieldDescriptorProto_TYPE_BYTES),
+					newField("content_type", "contentType", 2, descriptor.FieldDescriptorProto_TYPE_STRING),
+					newHeadersField("InvokeResponse"),
+					newField("status_code", "statusCode", 4, descriptor.FieldDescriptorProto_TYPE_INT32),
+				},
+				NestedType: []*descriptor.DescriptorProto{headersEntry},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{
+				Name: proto.String("Function"),
+				Method: []*descriptor.MethodDescriptorProto{
+					{
+						Name:       proto.String("Invoke"),
+						InputType:  proto.String(".nuclio.InvokeRequest"),
+						OutputType: proto.String(".nuclio.InvokeResponse"),
+					},
+					{
+						Name:            proto.String("InvokeStream"),
+						InputType:       proto.String(".nuclio.InvokeRequest"),
+						OutputType:      proto.String(".nuclio.InvokeResponse"),
+						ClientStreaming: proto.Bool(true),
+						ServerStreaming: proto.Bool(true),
+					},
+				},
+			},
+		},
+	}
+}
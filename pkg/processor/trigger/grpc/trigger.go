@@ -0,0 +1,349 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	google_grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var fullMethodNameRegex = regexp.MustCompile(`^/[^/]+\.[^/]+/[^/]+$`)
+
+type grpc struct {
+	trigger.AbstractTrigger
+	configuration *Configuration
+	server        *google_grpc.Server
+}
+
+func newTrigger(logger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration) (trigger.Trigger, error) {
+
+	// calls are handled concurrently, so they must share the workers
+	if !workerAllocator.Shareable() {
+		return nil, errors.New("gRPC trigger requires a shareable worker allocator")
+	}
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(logger,
+		workerAllocator,
+		&configuration.Configuration,
+		"sync",
+		"grpc",
+		configuration.Name)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	return &grpc{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+	}, nil
+}
+
+func (g *grpc) Start(checkpoint functionconfig.Checkpoint) error {
+	g.Logger.InfoWith("Starting",
+		"listenAddress", g.configuration.URL,
+		"methods", g.configuration.Methods,
+		"maxMessageSize", g.configuration.MaxMessageSize,
+		"reflection", g.configuration.Reflection)
+
+	listener, err := net.Listen("tcp", g.configuration.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on %s", g.configuration.URL)
+	}
+
+	g.server = google_grpc.NewServer(
+		google_grpc.CustomCodec(codec{}), // nolint: staticcheck
+		google_grpc.MaxRecvMsgSize(g.configuration.MaxMessageSize),
+		google_grpc.UnknownServiceHandler(g.invokeMethod))
+
+	g.server.RegisterService(&invokeServiceDesc, g)
+
+	if g.configuration.Reflection {
+		if err := g.registerReflection(); err != nil {
+			listener.Close() // nolint: errcheck
+			return errors.Wrap(err, "Failed to register reflection")
+		}
+	}
+
+	go g.server.Serve(listener) // nolint: errcheck
+
+	return nil
+}
+
+func (g *grpc) Stop(force bool) (functionconfig.Checkpoint, error) {
+	g.Logger.Debug("Shutting down")
+
+	if g.server != nil {
+		g.server.Stop()
+	}
+
+	return nil, nil
+}
+
+func (g *grpc) GetConfig() map[string]interface{} {
+	return common.StructureToMap(g.configuration)
+}
+
+// invoke handles a call of the generic Invoke method
+func (g *grpc) invoke(ctx context.Context, request *InvokeRequest) (*InvokeResponse, error) {
+	return g.handleInvokeRequest(ctx, invokeMethodName, request)
+}
+
+// invokeStream handles a call of the generic InvokeStream method, submitting each request as an event
+func (g *grpc) invokeStream(stream google_grpc.ServerStream) error {
+	for {
+		request := &InvokeRequest{}
+		if err := stream.RecvMsg(request); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		response, err := g.handleInvokeRequest(stream.Context(), invokeStreamMethodName, request)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.SendMsg(response); err != nil {
+			return err
+		}
+	}
+}
+
+// invokeMethod handles a call of a method of a user provided service, submitting each of the request
+// messages as an event whose body is the encoded message, and responding with the body of the response.
+// streaming methods are handled as bidirectional, with a response to each request
+func (g *grpc) invokeMethod(server interface{}, stream google_grpc.ServerStream) error {
+	method, _ := google_grpc.MethodFromServerStream(stream)
+
+	if !g.isMethodServed(method) {
+		return status.Errorf(codes.Unimplemented, "Method %s isn't served", method)
+	}
+
+	for {
+		request := &rawMessage{}
+		if err := stream.RecvMsg(request); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		headers := getMetadataHeaders(stream.Context())
+
+		event := &Event{
+			body:        request.data,
+			contentType: headers["content-type"],
+			path:        method,
+			timestamp:   time.Now(),
+			headers:     headers,
+		}
+
+		response, err := g.submitEvent(event)
+		if err != nil {
+			return err
+		}
+
+		// the headers are sent along with the first response, later ones are ignored
+		stream.SetHeader(metadata.New(response.Headers)) // nolint: errcheck
+
+		if err := stream.SendMsg(&rawMessage{data: response.Body}); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grpc) handleInvokeRequest(ctx context.Context,
+	method string,
+	request *InvokeRequest) (*InvokeResponse, error) {
+	headers := getMetadataHeaders(ctx)
+	for headerKey, headerValue := range request.Headers {
+		headers[strings.ToLower(headerKey)] = headerValue
+	}
+
+	path := request.Path
+	if path == "" {
+		path = method
+	}
+
+	return g.submitEvent(&Event{
+		body:        request.Body,
+		contentType: request.ContentType,
+		path:        path,
+		timestamp:   time.Now(),
+		headers:     headers,
+	})
+}
+
+// submitEvent submits the event, returning the response of the function. submission and processing errors, as
+// well as responses with an error status code, are returned as errors of the corresponding gRPC code
+func (g *grpc) submitEvent(event *Event) (*InvokeResponse, error) {
+	response, submitError, processError := g.AllocateWorkerAndSubmitEvent(event,
+		nil,
+		time.Duration(*g.configuration.WorkerAvailabilityTimeoutMilliseconds)*time.Millisecond)
+
+	if submitError != nil {
+		if errors.Cause(submitError) == admission.ErrQueueFull {
+			return nil, status.Error(codes.ResourceExhausted, submitError.Error())
+		}
+
+		g.Logger.WarnWith("Failed to submit event", "err", submitError.Error())
+		return nil, status.Error(codes.Unavailable, submitError.Error())
+	}
+
+	if processError != nil {
+		return nil, status.Error(codes.Unknown, processError.Error())
+	}
+
+	invokeResponse := &InvokeResponse{
+		StatusCode: http.StatusOK,
+	}
+
+	switch typedResponse := response.(type) {
+	case nil:
+	case nuclio.Response:
+		g.populateInvokeResponse(invokeResponse, &typedResponse)
+	case *nuclio.Response:
+		g.populateInvokeResponse(invokeResponse, typedResponse)
+	case []byte:
+		invokeResponse.Body = typedResponse
+	case string:
+		invokeResponse.Body = []byte(typedResponse)
+	default:
+		g.Logger.WarnWith("Unsupported response type", "type", typedResponse)
+		return nil, status.Error(codes.Internal, "Unsupported response type")
+	}
+
+	if invokeResponse.StatusCode >= http.StatusBadRequest {
+		return nil, status.Error(getStatusCode(int(invokeResponse.StatusCode)), string(invokeResponse.Body))
+	}
+
+	return invokeResponse, nil
+}
+
+func (g *grpc) populateInvokeResponse(invokeResponse *InvokeResponse, response *nuclio.Response) {
+	invokeResponse.Body = response.Body
+	invokeResponse.ContentType = response.ContentType
+
+	if response.StatusCode != 0 {
+		invokeResponse.StatusCode = int32(response.StatusCode)
+	}
+
+	if len(response.Headers) > 0 {
+		invokeResponse.Headers = map[string]string{}
+		for headerKey, headerValue := range response.Headers {
+			switch typedHeaderValue := headerValue.(type) {
+			case string:
+				invokeResponse.Headers[strings.ToLower(headerKey)] = typedHeaderValue
+			case int:
+				invokeResponse.Headers[strings.ToLower(headerKey)] = strconv.Itoa(typedHeaderValue)
+			}
+		}
+	}
+}
+
+func (g *grpc) isMethodServed(method string) bool {
+
+	// reflection calls are served by the reflection service, if at all
+	if isReflectionMethod(method) {
+		return false
+	}
+
+	if len(g.configuration.Methods) == 0 {
+		return true
+	}
+
+	for _, servedMethod := range g.configuration.Methods {
+		if servedMethod == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getMetadataHeaders returns the first value of each of the metadata keys of the call
+func getMetadataHeaders(ctx context.Context) map[string]string {
+	headers := map[string]string{}
+
+	incomingMetadata, _ := metadata.FromIncomingContext(ctx)
+	for metadataKey, metadataValues := range incomingMetadata {
+		if len(metadataValues) > 0 {
+			headers[metadataKey] = metadataValues[0]
+		}
+	}
+
+	return headers
+}
+
+// getStatusCode returns the gRPC code corresponding to an HTTP error status code
+func getStatusCode(httpStatusCode int) codes.Code {
+	switch httpStatusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	if httpStatusCode >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+
+	return codes.Unknown
+}
+
+func isFullMethodName(method string) bool {
+	return fullMethodNameRegex.MatchString(method)
+}
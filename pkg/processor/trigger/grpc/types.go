@@ -0,0 +1,85 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type Configuration struct {
+	trigger.Configuration
+
+	// the full names (/package.Service/Method) of the methods of user provided services which are served,
+	// their messages passed to and from the function as is. when empty, any method is served
+	Methods []string
+
+	// messages larger than this are rejected
+	MaxMessageSize int
+
+	// whether to serve the reflection service, reflecting the generic service along with the services of the
+	// descriptor set at DescriptorSetPath (if any)
+	Reflection        bool
+	DescriptorSetPath string
+}
+
+const (
+	DefaultURL            = ":50051"
+	DefaultMaxMessageSize = 4 * 1024 * 1024
+)
+
+func NewConfiguration(ID string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *trigger.NewConfiguration(ID, triggerConfiguration, runtimeConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.MaxMessageSize < 0 {
+		return nil, errors.Errorf("Max message size must not be negative, got %d", newConfiguration.MaxMessageSize)
+	}
+
+	for _, method := range newConfiguration.Methods {
+		if !isFullMethodName(method) {
+			return nil, errors.Errorf("Method must be of the form /package.Service/Method, got %s", method)
+		}
+	}
+
+	if newConfiguration.DescriptorSetPath != "" && !newConfiguration.Reflection {
+		return nil, errors.New("A descriptor set can only be given along with reflection")
+	}
+
+	if newConfiguration.URL == "" {
+		newConfiguration.URL = DefaultURL
+	}
+
+	if newConfiguration.MaxMessageSize == 0 {
+		newConfiguration.MaxMessageSize = DefaultMaxMessageSize
+	}
+
+	return &newConfiguration, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/errors"
+	"github.com/valyala/fasthttp"
+)
+
+// the keys of the request's user values the worker and event of the request are kept in, for the access log
+const (
+	accessLogWorkerIDKey = "nuclio.accessLog.workerID"
+	accessLogEventIDKey  = "nuclio.accessLog.eventID"
+)
+
+// AccessLog configures the trigger's access log, a JSON line per request
+type AccessLog struct {
+	Enabled bool
+
+	// where the lines are written - "stdout" (the default) or the path of a file, which is appended to
+	Path string
+
+	// the fraction of the requests that are logged, (0, 1]. defaults to 1 (every request)
+	SampleRate float64
+}
+
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	RemoteAddr string  `json:"remoteAddr"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	LatencyMs  float64 `json:"latencyMs"`
+	WorkerID   *int    `json:"workerID,omitempty"`
+	EventID    string  `json:"eventID,omitempty"`
+}
+
+type accessLogger struct {
+	lock       sync.Mutex
+	writer     io.Writer
+	file       *os.File
+	sampleRate float64
+}
+
+func newAccessLogger(configuration *AccessLog) (*accessLogger, error) {
+	newAccessLogger := &accessLogger{
+		writer:     os.Stdout,
+		sampleRate: configuration.SampleRate,
+	}
+
+	if configuration.Path != "" && configuration.Path != "stdout" {
+		file, err := os.OpenFile(configuration.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to open access log %s", configuration.Path)
+		}
+
+		newAccessLogger.writer = file
+		newAccessLogger.file = file
+	}
+
+	return newAccessLogger, nil
+}
+
+// log writes the entry of a request, which started at the given time, unless it isn't sampled
+func (al *accessLogger) log(ctx *fasthttp.RequestCtx, startTime time.Time) {
+	if al.sampleRate < 1 && rand.Float64() >= al.sampleRate {
+		return
+	}
+
+	entry := accessLogEntry{
+		Time:       startTime.UTC().Format(time.RFC3339Nano),
+		RemoteAddr: ctx.RemoteAddr().String(),
+		Method:     common.ByteSliceToString(ctx.Method()),
+		Path:       common.ByteSliceToString(ctx.Path()),
+		Status:     ctx.Response.StatusCode(),
+		LatencyMs:  float64(time.Since(startTime).Microseconds()) / 1000,
+	}
+
+	// requests which weren't submitted to a worker (e.g. CORS preflight requests) have neither
+	if workerID, ok := ctx.UserValue(accessLogWorkerIDKey).(int); ok {
+		entry.WorkerID = &workerID
+	}
+
+	if eventID, ok := ctx.UserValue(accessLogEventIDKey).(string); ok {
+		entry.EventID = eventID
+	}
+
+	encodedEntry, err := json.Marshal(&entry)
+	if err != nil {
+		return
+	}
+
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	al.writer.Write(append(encodedEntry, '\n')) // nolint: errcheck
+}
+
+func (al *accessLogger) close() error {
+	if al.file == nil {
+		return nil
+	}
+
+	return al.file.Close()
+}
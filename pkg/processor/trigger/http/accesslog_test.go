@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/http/cors"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+)
+
+type accessLogTestSuite struct {
+	suite.Suite
+	logger          logger.Logger
+	accessLogBuffer bytes.Buffer
+	trigger         *http
+}
+
+func (suite *accessLogTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.accessLogBuffer.Reset()
+	suite.trigger = &http{
+		AbstractTrigger: trigger.AbstractTrigger{
+			Logger: suite.logger,
+		},
+		configuration: &Configuration{
+			CORS: cors.NewCORS(),
+		},
+		accessLogger: &accessLogger{
+			writer:     &suite.accessLogBuffer,
+			sampleRate: 1,
+		},
+	}
+}
+
+func (suite *accessLogTestSuite) TestLogRequest() {
+	requestCtx := suite.createPreflightRequestCtx("/some/path")
+	suite.trigger.onRequestFromFastHTTP()(requestCtx)
+
+	entry := accessLogEntry{}
+	err := json.Unmarshal(suite.accessLogBuffer.Bytes(), &entry)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(fasthttp.MethodOptions, entry.Method)
+	suite.Require().Equal("/some/path", entry.Path)
+	suite.Require().Equal(fasthttp.StatusOK, entry.Status)
+	suite.Require().NotEmpty(entry.Time)
+
+	// preflight requests aren't submitted to a worker
+	suite.Require().Nil(entry.WorkerID)
+	suite.Require().Empty(entry.EventID)
+}
+
+func (suite *accessLogTestSuite) TestLogSubmittedRequest() {
+	requestCtx := suite.createPreflightRequestCtx("/")
+	requestCtx.SetUserValue(accessLogWorkerIDKey, 3)
+	requestCtx.SetUserValue(accessLogEventIDKey, "some-event-id")
+
+	suite.trigger.accessLogger.log(requestCtx, requestCtx.Time())
+
+	entry := accessLogEntry{}
+	err := json.Unmarshal(suite.accessLogBuffer.Bytes(), &entry)
+	suite.Require().NoError(err)
+
+	suite.Require().NotNil(entry.WorkerID)
+	suite.Require().Equal(3, *entry.WorkerID)
+	suite.Require().Equal("some-event-id", entry.EventID)
+}
+
+func (suite *accessLogTestSuite) TestSampling() {
+	suite.trigger.accessLogger.sampleRate = 0.000001
+
+	for requestIndex := 0; requestIndex < 10; requestIndex++ {
+		suite.trigger.onRequestFromFastHTTP()(suite.createPreflightRequestCtx("/"))
+	}
+
+	// with such a rate, it's practically impossible for any request to be sampled
+	suite.Require().Zero(suite.accessLogBuffer.Len())
+}
+
+func (suite *accessLogTestSuite) createPreflightRequestCtx(path string) *fasthttp.RequestCtx {
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Request.Header.SetMethod(fasthttp.MethodOptions)
+	requestCtx.Request.SetRequestURI(path)
+	requestCtx.Request.Header.Set("Origin", "foo.bar")
+	requestCtx.Request.Header.Set("Access-Control-Request-Method", "GET")
+
+	return requestCtx
+}
+
+func TestAccessLogTestSuite(t *testing.T) {
+	suite.Run(t, new(accessLogTestSuite))
+}
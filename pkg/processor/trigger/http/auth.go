@@ -0,0 +1,408 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	net_http "net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/nuclio/errors"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	AuthenticationModeNone   = "none"
+	AuthenticationModeAPIKey = "apiKey"
+	AuthenticationModeJWT    = "jwt"
+
+	DefaultAPIKeyHeader = "X-Api-Key"
+
+	// JWTClaimHeaderPrefix prefixes the headers a validated token's claims are passed to the function in
+	// (e.g. X-Nuclio-Jwt-Claim-Sub). such headers are removed from incoming requests, so they can't be spoofed
+	JWTClaimHeaderPrefix = "X-Nuclio-Jwt-Claim-"
+)
+
+// a JWKS is fetched again on an unknown key ID (e.g. after the issuer rotated its keys), but not more often than this
+const jwksMinRefreshInterval = 30 * time.Second
+
+// Authentication configures how the trigger authenticates requests, before they're submitted to a worker
+type Authentication struct {
+
+	// none (the default), apiKey or jwt
+	Mode string
+
+	APIKey *APIKeyAuthentication
+	JWT    *JWTAuthentication
+}
+
+// APIKeyAuthentication accepts requests holding one of the keys in a header
+type APIKeyAuthentication struct {
+
+	// the header holding the key, X-Api-Key by default
+	Header string
+
+	Keys []string
+
+	// name of an environment variable holding comma separated keys (e.g. populated from a secret), in addition
+	// to Keys
+	KeysEnv string
+}
+
+// JWTAuthentication accepts requests holding a bearer token signed by one of the keys of a JWKS
+type JWTAuthentication struct {
+	JWKSURL string
+
+	// if set, the token's iss and aud claims must match them
+	Issuer   string
+	Audience string
+}
+
+func (a *Authentication) validate() error {
+	switch a.Mode {
+	case "", AuthenticationModeNone:
+		return nil
+	case AuthenticationModeAPIKey:
+		if a.APIKey == nil || (len(a.APIKey.Keys) == 0 && a.APIKey.KeysEnv == "") {
+			return errors.New("API key authentication requires keys or keysEnv")
+		}
+	case AuthenticationModeJWT:
+		if a.JWT == nil || a.JWT.JWKSURL == "" {
+			return errors.New("JWT authentication requires a JWKS URL")
+		}
+	default:
+		return errors.Errorf("Authentication mode must be one of %s, %s, %s - got %s",
+			AuthenticationModeNone,
+			AuthenticationModeAPIKey,
+			AuthenticationModeJWT,
+			a.Mode)
+	}
+
+	return nil
+}
+
+// authenticator authenticates a request, returning the reason it was rejected (or an empty string if it wasn't)
+type authenticator interface {
+	authenticate(ctx *fasthttp.RequestCtx) string
+}
+
+func newAuthenticator(authentication *Authentication) (authenticator, error) {
+	switch authentication.Mode {
+	case AuthenticationModeAPIKey:
+		return newAPIKeyAuthenticator(authentication.APIKey)
+	case AuthenticationModeJWT:
+		return newJWTAuthenticator(authentication.JWT), nil
+	default:
+		return nil, nil
+	}
+}
+
+type apiKeyAuthenticator struct {
+	header string
+	keys   [][]byte
+}
+
+func newAPIKeyAuthenticator(configuration *APIKeyAuthentication) (*apiKeyAuthenticator, error) {
+	newAPIKeyAuthenticator := apiKeyAuthenticator{
+		header: configuration.Header,
+	}
+
+	if newAPIKeyAuthenticator.header == "" {
+		newAPIKeyAuthenticator.header = DefaultAPIKeyHeader
+	}
+
+	keys := configuration.Keys
+	if configuration.KeysEnv != "" {
+		for _, key := range strings.Split(os.Getenv(configuration.KeysEnv), ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.Errorf("No API keys configured (is %s set?)", configuration.KeysEnv)
+	}
+
+	for _, key := range keys {
+		newAPIKeyAuthenticator.keys = append(newAPIKeyAuthenticator.keys, []byte(key))
+	}
+
+	return &newAPIKeyAuthenticator, nil
+}
+
+func (a *apiKeyAuthenticator) authenticate(ctx *fasthttp.RequestCtx) string {
+	requestKey := ctx.Request.Header.Peek(a.header)
+	if len(requestKey) == 0 {
+		return fmt.Sprintf("Missing %s header", a.header)
+	}
+
+	// compare against every key in constant time, so that the time taken doesn't leak how much of a key matched
+	matched := 0
+	for _, key := range a.keys {
+		matched |= subtle.ConstantTimeCompare(requestKey, key)
+	}
+
+	if matched != 1 {
+		return "Invalid API key"
+	}
+
+	return ""
+}
+
+type jwtAuthenticator struct {
+	configuration *JWTAuthentication
+	parser        *jwt.Parser
+	httpClient    *net_http.Client
+
+	keysLock    sync.Mutex
+	keys        map[string]interface{}
+	lastFetched time.Time
+}
+
+func newJWTAuthenticator(configuration *JWTAuthentication) *jwtAuthenticator {
+	return &jwtAuthenticator{
+		configuration: configuration,
+		parser: &jwt.Parser{
+			ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		},
+		httpClient: &net_http.Client{
+			Timeout: 10 * time.Second,
+		},
+		keys: map[string]interface{}{},
+	}
+}
+
+func (a *jwtAuthenticator) authenticate(ctx *fasthttp.RequestCtx) string {
+
+	// claims are only ever passed to the function by the trigger
+	var spoofedHeaderNames []string
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		if strings.HasPrefix(strings.ToLower(string(key)), strings.ToLower(JWTClaimHeaderPrefix)) {
+			spoofedHeaderNames = append(spoofedHeaderNames, string(key))
+		}
+	})
+
+	for _, spoofedHeaderName := range spoofedHeaderNames {
+		ctx.Request.Header.Del(spoofedHeaderName)
+	}
+
+	authorization := common.ByteSliceToString(ctx.Request.Header.Peek("Authorization"))
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return "Missing bearer token"
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimPrefix(authorization, "Bearer "), claims, a.getKey); err != nil {
+		return fmt.Sprintf("Invalid token: %s", errors.RootCause(err).Error())
+	}
+
+	if a.configuration.Issuer != "" && !claims.VerifyIssuer(a.configuration.Issuer, true) {
+		return "Invalid token issuer"
+	}
+
+	if a.configuration.Audience != "" && !verifyAudience(claims["aud"], a.configuration.Audience) {
+		return "Invalid token audience"
+	}
+
+	for claimName, claimValue := range claims {
+		headerValue, ok := claimValue.(string)
+		if !ok {
+			encodedClaimValue, err := json.Marshal(claimValue)
+			if err != nil {
+				continue
+			}
+
+			headerValue = string(encodedClaimValue)
+		}
+
+		ctx.Request.Header.Set(JWTClaimHeaderPrefix+claimName, headerValue)
+	}
+
+	return ""
+}
+
+// getKey returns the key that signed the token, by its key ID
+func (a *jwtAuthenticator) getKey(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+
+	a.keysLock.Lock()
+	defer a.keysLock.Unlock()
+
+	key := a.lookupKey(keyID)
+	if key == nil && time.Since(a.lastFetched) >= jwksMinRefreshInterval {
+		if err := a.fetchKeys(); err != nil {
+			return nil, errors.Wrap(err, "Failed to fetch JWKS")
+		}
+
+		key = a.lookupKey(keyID)
+	}
+
+	if key == nil {
+		return nil, errors.Errorf("Unknown key ID %s", keyID)
+	}
+
+	return key, nil
+}
+
+// lookupKey returns the key by its ID. a token without a key ID can only be verified by the only key of a JWKS
+func (a *jwtAuthenticator) lookupKey(keyID string) interface{} {
+	if keyID == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key
+		}
+	}
+
+	return a.keys[keyID]
+}
+
+func (a *jwtAuthenticator) fetchKeys() error {
+	a.lastFetched = time.Now()
+
+	response, err := a.httpClient.Get(a.configuration.JWKSURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get JWKS")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != net_http.StatusOK {
+		return errors.Errorf("Got unexpected status code from JWKS URL: %d", response.StatusCode)
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+
+	if err := json.NewDecoder(response.Body).Decode(&jwks); err != nil {
+		return errors.Wrap(err, "Failed to decode JWKS")
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+
+		// keys that aren't for verifying signatures, or of an unsupported type, are skipped
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		if publicKey, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = publicKey
+		}
+	}
+
+	a.keys = keys
+
+	return nil
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+func (jwk *jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBase64URLInt(jwk.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode modulus")
+		}
+
+		e, err := decodeBase64URLInt(jwk.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("Unsupported curve %s", jwk.Curve)
+		}
+
+		x, err := decodeBase64URLInt(jwk.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode x")
+		}
+
+		y, err := decodeBase64URLInt(jwk.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode y")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, errors.Errorf("Unsupported key type %s", jwk.KeyType)
+	}
+}
+
+func decodeBase64URLInt(encoded string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// verifyAudience returns whether the aud claim, a string or a list of strings, holds the audience
+func verifyAudience(audienceClaim interface{}, audience string) bool {
+	switch typedAudienceClaim := audienceClaim.(type) {
+	case string:
+		return typedAudienceClaim == audience
+	case []interface{}:
+		for _, claimedAudience := range typedAudienceClaim {
+			if claimedAudience == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	net_http "net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+)
+
+type authTestSuite struct {
+	suite.Suite
+	logger     logger.Logger
+	privateKey *rsa.PrivateKey
+	jwksServer *httptest.Server
+}
+
+func (suite *authTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	suite.jwksServer = httptest.NewServer(net_http.HandlerFunc(func(writer net_http.ResponseWriter,
+		request *net_http.Request) {
+		json.NewEncoder(writer).Encode(map[string]interface{}{ // nolint: errcheck
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "key-1",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(suite.privateKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(suite.privateKey.E)).Bytes()),
+				},
+			},
+		})
+	}))
+}
+
+func (suite *authTestSuite) TearDownSuite() {
+	suite.jwksServer.Close()
+}
+
+func (suite *authTestSuite) TestAPIKey() {
+	err := os.Setenv("TEST_API_KEYS", "env-key-1, env-key-2")
+	suite.Require().NoError(err)
+	defer os.Unsetenv("TEST_API_KEYS") // nolint: errcheck
+
+	httpTrigger := suite.createTrigger(&Authentication{
+		Mode: AuthenticationModeAPIKey,
+		APIKey: &APIKeyAuthentication{
+			Keys:    []string{"some-key"},
+			KeysEnv: "TEST_API_KEYS",
+		},
+	})
+
+	for _, testCase := range []struct {
+		name           string
+		key            string
+		expectedReason string
+	}{
+		{name: "configured key", key: "some-key"},
+		{name: "key from environment", key: "env-key-2"},
+		{name: "missing key", expectedReason: "Missing X-Api-Key header"},
+		{name: "wrong key", key: "some-other-key", expectedReason: "Invalid API key"},
+	} {
+		suite.Run(testCase.name, func() {
+			requestCtx := &fasthttp.RequestCtx{}
+			if testCase.key != "" {
+				requestCtx.Request.Header.Set("X-Api-Key", testCase.key)
+			}
+
+			suite.Require().Equal(testCase.expectedReason, httpTrigger.authenticator.authenticate(requestCtx))
+		})
+	}
+}
+
+func (suite *authTestSuite) TestJWT() {
+	httpTrigger := suite.createTrigger(&Authentication{
+		Mode: AuthenticationModeJWT,
+		JWT: &JWTAuthentication{
+			JWKSURL:  suite.jwksServer.URL,
+			Issuer:   "https://issuer.example.com",
+			Audience: "my-function",
+		},
+	})
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    "https://issuer.example.com",
+			"aud":    []string{"other-function", "my-function"},
+			"sub":    "some-user",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": []string{"admins"},
+		}
+	}
+
+	for _, testCase := range []struct {
+		name           string
+		claims         jwt.MapClaims
+		keyID          string
+		unsigned       bool
+		expectedReason string
+	}{
+		{name: "valid token", claims: validClaims(), keyID: "key-1"},
+		{name: "unknown key", claims: validClaims(), keyID: "key-2", expectedReason: "Invalid token: Unknown key ID key-2"},
+		{name: "unsigned token", claims: validClaims(), unsigned: true, expectedReason: "Invalid token: signing method none is invalid"},
+		{
+			name:           "expired token",
+			claims:         jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "my-function", "exp": time.Now().Add(-time.Hour).Unix()},
+			keyID:          "key-1",
+			expectedReason: "Invalid token: Token is expired",
+		},
+		{
+			name:           "wrong issuer",
+			claims:         jwt.MapClaims{"iss": "https://other.example.com", "aud": "my-function"},
+			keyID:          "key-1",
+			expectedReason: "Invalid token issuer",
+		},
+		{
+			name:           "wrong audience",
+			claims:         jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "other-function"},
+			keyID:          "key-1",
+			expectedReason: "Invalid token audience",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			var signedToken string
+			var err error
+
+			if testCase.unsigned {
+				signedToken, err = jwt.NewWithClaims(jwt.SigningMethodNone, testCase.claims).
+					SignedString(jwt.UnsafeAllowNoneSignatureType)
+			} else {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, testCase.claims)
+				token.Header["kid"] = testCase.keyID
+				signedToken, err = token.SignedString(suite.privateKey)
+			}
+			suite.Require().NoError(err)
+
+			requestCtx := &fasthttp.RequestCtx{}
+			requestCtx.Request.Header.Set("Authorization", "Bearer "+signedToken)
+			requestCtx.Request.Header.Set(JWTClaimHeaderPrefix+"Role", "spoofed")
+
+			suite.Require().Equal(testCase.expectedReason, httpTrigger.authenticator.authenticate(requestCtx))
+
+			// claims are passed in headers, and can't be spoofed
+			suite.Require().Empty(requestCtx.Request.Header.Peek(JWTClaimHeaderPrefix + "Role"))
+			if testCase.expectedReason == "" {
+				suite.Require().Equal("some-user", string(requestCtx.Request.Header.Peek(JWTClaimHeaderPrefix+"sub")))
+				suite.Require().Equal(`["admins"]`,
+					string(requestCtx.Request.Header.Peek(JWTClaimHeaderPrefix+"groups")))
+			}
+		})
+	}
+}
+
+func (suite *authTestSuite) TestRejectUnauthenticatedRequest() {
+	httpTrigger := suite.createTrigger(&Authentication{
+		Mode: AuthenticationModeJWT,
+		JWT: &JWTAuthentication{
+			JWKSURL: suite.jwksServer.URL,
+		},
+	})
+
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Request.SetRequestURI("/")
+	httpTrigger.onRequestFromFastHTTP()(requestCtx)
+
+	suite.Require().Equal(fasthttp.StatusUnauthorized, requestCtx.Response.StatusCode())
+	suite.Require().Equal(`Bearer error="invalid_token"`,
+		string(requestCtx.Response.Header.Peek("WWW-Authenticate")))
+	suite.Require().JSONEq(`{"error": "Missing bearer token"}`, string(requestCtx.Response.Body()))
+}
+
+func (suite *authTestSuite) TestInvalidConfiguration() {
+	for _, authentication := range []*Authentication{
+		{Mode: "oauth"},
+		{Mode: AuthenticationModeAPIKey},
+		{Mode: AuthenticationModeJWT, JWT: &JWTAuthentication{Issuer: "https://issuer.example.com"}},
+	} {
+		suite.Require().Error(authentication.validate())
+	}
+
+	suite.Require().NoError((&Authentication{}).validate())
+}
+
+func (suite *authTestSuite) createTrigger(authentication *Authentication) *http {
+	suite.Require().NoError(authentication.validate())
+
+	authenticator, err := newAuthenticator(authentication)
+	suite.Require().NoError(err)
+
+	return &http{
+		AbstractTrigger: trigger.AbstractTrigger{
+			Logger: suite.logger,
+		},
+		configuration: &Configuration{
+			Authentication: authentication,
+		},
+		authenticator: authenticator,
+	}
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(authTestSuite))
+}
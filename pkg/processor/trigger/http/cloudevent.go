@@ -0,0 +1,76 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+
+	"github.com/nuclio/errors"
+	"github.com/satori/go.uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// the type of responses emitted as CloudEvents, unless configured otherwise
+const defaultCloudEventResponseType = "io.nuclio.response"
+
+// getCloudEventResponseAttributes returns the attributes of a response emitted as a CloudEvent
+func (h *http) getCloudEventResponseAttributes() *cloudevent.Attributes {
+	attributes := &cloudevent.Attributes{
+		ID:     uuid.NewV4().String(),
+		Source: h.configuration.CloudEvents.ResponseSource,
+		Type:   h.configuration.CloudEvents.ResponseType,
+		Time:   time.Now(),
+	}
+
+	if attributes.Source == "" {
+		attributes.Source = fmt.Sprintf("/nuclio/%s/%s", h.Namespace, h.FunctionName)
+	}
+
+	if attributes.Type == "" {
+		attributes.Type = defaultCloudEventResponseType
+	}
+
+	return attributes
+}
+
+// encodeCloudEventResponse emits the response as a CloudEvent of the given mode. a streamed response is emitted as
+// a binary CloudEvent, as its body can't be wrapped
+func encodeCloudEventResponse(response *fasthttp.Response, attributes *cloudevent.Attributes, mode string) error {
+	if mode == functionconfig.CloudEventsModeBinary || response.IsBodyStream() {
+		for headerName, headerValue := range cloudevent.GetBinaryHeaders(attributes) {
+			response.Header.Set(headerName, headerValue)
+		}
+
+		return nil
+	}
+
+	encodedResponse, err := cloudevent.EncodeStructured(attributes,
+		string(response.Header.ContentType()),
+		response.Body())
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode structured cloud event")
+	}
+
+	response.SetBody(encodedResponse)
+	response.Header.SetContentType(cloudevent.StructuredContentType)
+
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+)
+
+type cloudEventTestSuite struct {
+	suite.Suite
+	attributes *cloudevent.Attributes
+}
+
+func (suite *cloudEventTestSuite) SetupTest() {
+	suite.attributes = &cloudevent.Attributes{
+		ID:     "testID",
+		Source: "/nuclio/nuclio/my-function",
+		Type:   defaultCloudEventResponseType,
+	}
+}
+
+func (suite *cloudEventTestSuite) TestBinary() {
+	var response fasthttp.Response
+	response.SetBodyString(`{"a": "b"}`)
+	response.Header.SetContentType("application/json")
+
+	err := encodeCloudEventResponse(&response, suite.attributes, functionconfig.CloudEventsModeBinary)
+	suite.Require().NoError(err)
+
+	// the body is left as is
+	suite.Require().Equal(`{"a": "b"}`, string(response.Body()))
+	suite.Require().Equal("application/json", string(response.Header.ContentType()))
+	suite.Require().Equal("1.0", string(response.Header.Peek("ce-specversion")))
+	suite.Require().Equal("testID", string(response.Header.Peek("ce-id")))
+	suite.Require().Equal("io.nuclio.response", string(response.Header.Peek("ce-type")))
+}
+
+func (suite *cloudEventTestSuite) TestStructured() {
+	var response fasthttp.Response
+	response.SetBodyString(`{"a": "b"}`)
+	response.Header.SetContentType("application/json")
+
+	err := encodeCloudEventResponse(&response, suite.attributes, functionconfig.CloudEventsModeStructured)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(cloudevent.StructuredContentType, string(response.Header.ContentType()))
+
+	structuredEvent := map[string]interface{}{}
+	err = json.Unmarshal(response.Body(), &structuredEvent)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "testID",
+		"source":          "/nuclio/nuclio/my-function",
+		"type":            "io.nuclio.response",
+		"datacontenttype": "application/json",
+		"data":            map[string]interface{}{"a": "b"},
+	}, structuredEvent)
+}
+
+func (suite *cloudEventTestSuite) TestStructuredStream() {
+	var response fasthttp.Response
+	response.SetBodyStream(bytes.NewBufferString("data: first\n\n"), -1)
+	response.Header.SetContentType("text/event-stream")
+
+	// a streamed body can't be wrapped, so the response is a binary event
+	err := encodeCloudEventResponse(&response, suite.attributes, functionconfig.CloudEventsModeStructured)
+	suite.Require().NoError(err)
+	suite.Require().Equal("text/event-stream", string(response.Header.ContentType()))
+	suite.Require().Equal("1.0", string(response.Header.Peek("ce-specversion")))
+}
+
+func TestCloudEventTestSuite(t *testing.T) {
+	suite.Run(t, new(cloudEventTestSuite))
+}
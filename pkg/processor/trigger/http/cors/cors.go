@@ -30,10 +30,14 @@ type CORS struct {
 
 	// allow configuration
 	AllowOrigin      string
+	AllowOrigins     []string
 	AllowMethods     []string
 	AllowHeaders     []string
 	AllowCredentials bool
 
+	// response headers the browser exposes to the requesting code
+	ExposeHeaders []string
+
 	// preflight
 	PreflightRequestMethod string
 	PreflightMaxAgeSeconds int64
@@ -41,6 +45,7 @@ type CORS struct {
 	// computed
 	allowMethodsStr           string
 	allowHeadersStr           string
+	exposeHeadersStr          string
 	preflightMaxAgeSecondsStr string
 	allowCredentialsStr       string
 	simpleMethods             []string
@@ -76,7 +81,16 @@ func (c *CORS) OriginAllowed(origin string) bool {
 	if origin == "" {
 		return false
 	}
-	return c.AllowOrigin == "*" || origin == c.AllowOrigin
+	if c.AllowOrigin == "*" || origin == c.AllowOrigin {
+		return true
+	}
+
+	for _, allowOrigin := range c.AllowOrigins {
+		if allowOrigin == "*" || origin == allowOrigin {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *CORS) MethodAllowed(method string) bool {
@@ -111,7 +125,14 @@ func (c *CORS) EncodeAllowCredentialsHeader() string {
 	if c.allowCredentialsStr == "" {
 		c.allowCredentialsStr = strconv.FormatBool(c.AllowCredentials)
 	}
-	return c.allowHeadersStr
+	return c.allowCredentialsStr
+}
+
+func (c *CORS) EncodeExposeHeaders() string {
+	if c.exposeHeadersStr == "" {
+		c.exposeHeadersStr = strings.Join(c.ExposeHeaders, ", ")
+	}
+	return c.exposeHeadersStr
 }
 
 func (c *CORS) EncodePreflightMaxAgeSeconds() string {
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/http/cors"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+)
+
+type corsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *corsTestSuite) TestCORSResponseHeaders() {
+	for _, testCase := range []struct {
+		name                    string
+		configuration           *cors.CORS
+		requestOrigin           string
+		expectedResponseHeaders map[string]string
+	}{
+		{
+			name: "AllowedOrigin",
+			configuration: &cors.CORS{
+				Enabled:          true,
+				AllowOrigins:     []string{"https://a.example.com", "https://b.example.com"},
+				AllowCredentials: true,
+				ExposeHeaders:    []string{"X-Request-Id", "X-Total-Count"},
+			},
+			requestOrigin: "https://b.example.com",
+			expectedResponseHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://b.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id, X-Total-Count",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name: "NotAllowedOrigin",
+			configuration: &cors.CORS{
+				Enabled:      true,
+				AllowOrigins: []string{"https://a.example.com"},
+			},
+			requestOrigin: "https://c.example.com",
+			expectedResponseHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:          "NoOrigin",
+			configuration: cors.NewCORS(),
+			expectedResponseHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			httpTrigger := http{
+				configuration: &Configuration{
+					CORS: testCase.configuration,
+				},
+			}
+
+			ctx := &fasthttp.RequestCtx{}
+			if testCase.requestOrigin != "" {
+				ctx.Request.Header.Set("Origin", testCase.requestOrigin)
+			}
+
+			httpTrigger.setCORSResponseHeaders(ctx)
+
+			for headerName, headerValue := range testCase.expectedResponseHeaders {
+				suite.Require().Equal(headerValue, string(ctx.Response.Header.Peek(headerName)), headerName)
+			}
+		})
+	}
+}
+
+func (suite *corsTestSuite) TestCORSConfiguration() {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind: "http",
+		Attributes: map[string]interface{}{
+			"cors": map[string]interface{}{
+				"enabled":                true,
+				"allowOrigins":           []string{"https://a.example.com"},
+				"exposeHeaders":          []string{"X-Request-Id"},
+				"allowCredentials":       true,
+				"preflightMaxAgeSeconds": 600,
+			},
+		},
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	})
+	suite.Require().NoError(err)
+
+	// a list of origins replaces the default "*"
+	suite.Require().True(configuration.CORS.OriginAllowed("https://a.example.com"))
+	suite.Require().False(configuration.CORS.OriginAllowed("https://b.example.com"))
+	suite.Require().Equal([]string{"X-Request-Id"}, configuration.CORS.ExposeHeaders)
+	suite.Require().Equal("true", configuration.CORS.EncodeAllowCredentialsHeader())
+	suite.Require().Equal("600", configuration.CORS.EncodePreflightMaxAgeSeconds())
+
+	// defaults are kept for what wasn't configured
+	suite.Require().Equal(cors.NewCORS().AllowMethods, configuration.CORS.AllowMethods)
+}
+
+func TestCORSTestSuite(t *testing.T) {
+	suite.Run(t, new(corsTestSuite))
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	net_http "net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/errors"
+	"github.com/valyala/fasthttp"
+)
+
+// Limits are the limits of the requests the trigger accepts, and how it encodes the bodies of requests and
+// responses. they're set for the trigger, and may be overridden per route
+type Limits struct {
+
+	// the size in bytes of the largest request body accepted, after decompression (default 4MiB). larger
+	// requests are responded to with 413
+	MaxRequestBodySize int
+
+	// whether request bodies encoded with gzip or deflate (per Content-Encoding) are decompressed before
+	// they're handled
+	DecompressRequests *bool
+
+	// whether response bodies are compressed with gzip or deflate, when the request accepts them
+	// (per Accept-Encoding)
+	CompressResponses *bool
+
+	// the time a request (headers and body) has to be read, and the response has to be written. requests
+	// which aren't read in time are responded to with 408
+	ReadTimeout  string
+	WriteTimeout string
+
+	// the time the handler has to respond, after which the request is responded to with 408 (the event is
+	// still handled)
+	HandlerTimeout string
+}
+
+// Route holds the limits of requests whose path starts with a prefix, overriding those of the trigger. the
+// route with the longest matching prefix applies
+type Route struct {
+	PathPrefix string
+	Limits     `mapstructure:",squash"`
+}
+
+// resolvedLimits are the limits of a route, combined with those of the trigger
+type resolvedLimits struct {
+	pathPrefix         string
+	maxRequestBodySize int
+	decompressRequests bool
+	compressResponses  bool
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	handlerTimeout     time.Duration
+}
+
+// resolveLimits returns the limits of the routes, longest prefix first, followed by those of the trigger (whose
+// prefix is empty, so it matches all paths)
+func (c *Configuration) resolveLimits() ([]*resolvedLimits, error) {
+	triggerLimits, err := c.resolveRouteLimits(&resolvedLimits{
+		maxRequestBodySize: fasthttp.DefaultMaxRequestBodySize,
+	}, "", &c.Limits)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid limits")
+	}
+
+	var routesLimits []*resolvedLimits
+	pathPrefixes := map[string]bool{}
+
+	for routeIndex := range c.Routes {
+		route := &c.Routes[routeIndex]
+
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			return nil, errors.Errorf("Path prefix of route must start with /, got \"%s\"", route.PathPrefix)
+		}
+
+		if pathPrefixes[route.PathPrefix] {
+			return nil, errors.Errorf("Path prefix %s has more than one route", route.PathPrefix)
+		}
+
+		pathPrefixes[route.PathPrefix] = true
+
+		routeLimits, err := c.resolveRouteLimits(triggerLimits, route.PathPrefix, &route.Limits)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid limits of route %s", route.PathPrefix)
+		}
+
+		routesLimits = append(routesLimits, routeLimits)
+	}
+
+	sort.Slice(routesLimits, func(i, j int) bool {
+		return len(routesLimits[i].pathPrefix) > len(routesLimits[j].pathPrefix)
+	})
+
+	return append(routesLimits, triggerLimits), nil
+}
+
+// resolveRouteLimits overrides the given base limits with those that are set
+func (c *Configuration) resolveRouteLimits(baseLimits *resolvedLimits,
+	pathPrefix string,
+	limits *Limits) (*resolvedLimits, error) {
+
+	routeLimits := *baseLimits
+	routeLimits.pathPrefix = pathPrefix
+
+	if limits.MaxRequestBodySize < 0 {
+		return nil, errors.New("Max request body size must not be negative")
+	}
+
+	if limits.MaxRequestBodySize > 0 {
+		routeLimits.maxRequestBodySize = limits.MaxRequestBodySize
+	}
+
+	if limits.DecompressRequests != nil {
+		routeLimits.decompressRequests = *limits.DecompressRequests
+	}
+
+	if limits.CompressResponses != nil {
+		routeLimits.compressResponses = *limits.CompressResponses
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "read timeout",
+			Value:   limits.ReadTimeout,
+			Field:   &routeLimits.readTimeout,
+			Default: baseLimits.readTimeout,
+		},
+		{
+			Name:    "write timeout",
+			Value:   limits.WriteTimeout,
+			Field:   &routeLimits.writeTimeout,
+			Default: baseLimits.writeTimeout,
+		},
+		{
+			Name:    "handler timeout",
+			Value:   limits.HandlerTimeout,
+			Field:   &routeLimits.handlerTimeout,
+			Default: baseLimits.handlerTimeout,
+		},
+	} {
+		if err := c.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, err
+		}
+
+		if *durationConfigField.Field < 0 {
+			return nil, errors.Errorf("The %s must not be negative", durationConfigField.Name)
+		}
+	}
+
+	return &routeLimits, nil
+}
+
+// route is where the trigger handles the requests whose path starts with its prefix, within its limits
+type route struct {
+	limits  *resolvedLimits
+	handler fasthttp.RequestHandler
+}
+
+// createRoutes creates a route per path prefix, which handles requests with the given handler within its limits
+func (h *http) createRoutes(requestHandler fasthttp.RequestHandler) error {
+	routesLimits, err := h.configuration.resolveLimits()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve limits")
+	}
+
+	h.routes = nil
+	for _, routeLimits := range routesLimits {
+		routeLimits := routeLimits // https://golang.org/doc/faq#closures_and_goroutines
+
+		handler := func(ctx *fasthttp.RequestCtx) {
+			if routeLimits.decompressRequests && !h.decompressRequestBody(ctx, routeLimits.maxRequestBodySize) {
+				return
+			}
+
+			requestHandler(ctx)
+		}
+
+		// compress after the handler, so that only its response is compressed
+		if routeLimits.compressResponses {
+			handler = fasthttp.CompressHandler(handler)
+		}
+
+		// respond on time, leaving the handler to complete the event in the background
+		if routeLimits.handlerTimeout > 0 {
+			handler = fasthttp.TimeoutWithCodeHandler(handler,
+				routeLimits.handlerTimeout,
+				"Handler timed out",
+				net_http.StatusRequestTimeout)
+		}
+
+		h.routes = append(h.routes, &route{
+			limits:  routeLimits,
+			handler: handler,
+		})
+	}
+
+	return nil
+}
+
+// getRoute returns the route of a path, or nil if the trigger has no routes
+func (h *http) getRoute(path []byte) *route {
+	for _, route := range h.routes {
+		if bytes.HasPrefix(path, []byte(route.limits.pathPrefix)) {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// onHeaderReceived applies the limits of the request's route, before its body is read
+func (h *http) onHeaderReceived(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	requestURI := header.RequestURI()
+	if queryIndex := bytes.IndexByte(requestURI, '?'); queryIndex != -1 {
+		requestURI = requestURI[:queryIndex]
+	}
+
+	route := h.getRoute(requestURI)
+	if route == nil {
+		return fasthttp.RequestConfig{}
+	}
+
+	return fasthttp.RequestConfig{
+		ReadTimeout:        route.limits.readTimeout,
+		WriteTimeout:       route.limits.writeTimeout,
+		MaxRequestBodySize: route.limits.maxRequestBodySize,
+	}
+}
+
+// onRequestError responds to requests which couldn't be read
+func (h *http) onRequestError(ctx *fasthttp.RequestCtx, err error) {
+	h.UpdateStatistics(false)
+
+	if err == fasthttp.ErrBodyTooLarge {
+		ctx.Error("Request body too large", net_http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if netError, ok := err.(interface{ Timeout() bool }); ok && netError.Timeout() {
+		ctx.Error("Request timeout", net_http.StatusRequestTimeout)
+		return
+	}
+
+	if _, ok := err.(*fasthttp.ErrSmallBuffer); ok {
+		ctx.Error("Too big request header", net_http.StatusRequestHeaderFieldsTooLarge)
+		return
+	}
+
+	ctx.Error("Error when parsing request", net_http.StatusBadRequest)
+}
+
+// decompressRequestBody replaces a request body encoded with gzip or deflate with the decompressed body, as long
+// as it's within the max size. responds and returns false if it isn't, or can't be decompressed
+func (h *http) decompressRequestBody(ctx *fasthttp.RequestCtx, maxRequestBodySize int) bool {
+	var bodyReader io.ReadCloser
+	var err error
+
+	contentEncoding := strings.ToLower(strings.TrimSpace(common.ByteSliceToString(
+		ctx.Request.Header.Peek(fasthttp.HeaderContentEncoding))))
+
+	switch contentEncoding {
+	case "gzip", "x-gzip":
+		bodyReader, err = gzip.NewReader(bytes.NewReader(ctx.Request.Body()))
+	case "deflate":
+		bodyReader, err = zlib.NewReader(bytes.NewReader(ctx.Request.Body()))
+	default:
+		return true
+	}
+
+	if err == nil {
+		defer bodyReader.Close() // nolint: errcheck
+
+		// read one byte more than allowed, to tell whether the body is too large without decompressing all of it
+		var body []byte
+		body, err = ioutil.ReadAll(io.LimitReader(bodyReader, int64(maxRequestBodySize)+1))
+		if err == nil {
+			if len(body) > maxRequestBodySize {
+				h.UpdateStatistics(false)
+				ctx.Error("Request body too large", net_http.StatusRequestEntityTooLarge)
+				return false
+			}
+
+			ctx.Request.SetBody(body)
+			ctx.Request.Header.Del(fasthttp.HeaderContentEncoding)
+			return true
+		}
+	}
+
+	h.UpdateStatistics(false)
+	ctx.Error("Failed to decompress request body: "+err.Error(), net_http.StatusBadRequest)
+	return false
+}
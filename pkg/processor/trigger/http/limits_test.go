@@ -0,0 +1,332 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	net_http "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+type limitsTestSuite struct {
+	suite.Suite
+	trigger  *http
+	listener *fasthttputil.InmemoryListener
+}
+
+func (suite *limitsTestSuite) TearDownTest() {
+	if suite.listener != nil {
+		suite.listener.Close() // nolint: errcheck
+		suite.listener = nil
+	}
+}
+
+func (suite *limitsTestSuite) TestResolveLimits() {
+	configuration := &Configuration{
+		Limits: Limits{
+			MaxRequestBodySize: 1024,
+			CompressResponses:  suite.boolPointer(true),
+			ReadTimeout:        "10s",
+			HandlerTimeout:     "30s",
+		},
+		Routes: []Route{
+			{
+				PathPrefix: "/uploads",
+				Limits: Limits{
+					MaxRequestBodySize: 1024 * 1024,
+					DecompressRequests: suite.boolPointer(true),
+					HandlerTimeout:     "5m",
+				},
+			},
+			{
+				PathPrefix: "/uploads/images",
+				Limits: Limits{
+					CompressResponses: suite.boolPointer(false),
+				},
+			},
+		},
+	}
+
+	routeLimits, err := configuration.resolveLimits()
+	suite.Require().NoError(err)
+	suite.Require().Len(routeLimits, 3)
+
+	// longest prefix first, inheriting the limits of the trigger rather than of shorter prefixes
+	suite.Require().Equal(&resolvedLimits{
+		pathPrefix:         "/uploads/images",
+		maxRequestBodySize: 1024,
+		readTimeout:        10 * time.Second,
+		handlerTimeout:     30 * time.Second,
+	}, routeLimits[0])
+
+	suite.Require().Equal(&resolvedLimits{
+		pathPrefix:         "/uploads",
+		maxRequestBodySize: 1024 * 1024,
+		decompressRequests: true,
+		compressResponses:  true,
+		readTimeout:        10 * time.Second,
+		handlerTimeout:     5 * time.Minute,
+	}, routeLimits[1])
+
+	suite.Require().Equal("", routeLimits[2].pathPrefix)
+	suite.Require().Equal(1024, routeLimits[2].maxRequestBodySize)
+
+	// the default max body size is fasthttp's
+	routeLimits, err = (&Configuration{}).resolveLimits()
+	suite.Require().NoError(err)
+	suite.Require().Equal(fasthttp.DefaultMaxRequestBodySize, routeLimits[0].maxRequestBodySize)
+
+	for _, invalidConfiguration := range []*Configuration{
+		{Limits: Limits{MaxRequestBodySize: -1}},
+		{Limits: Limits{ReadTimeout: "soon"}},
+		{Limits: Limits{HandlerTimeout: "-1s"}},
+		{Routes: []Route{{PathPrefix: "uploads"}}},
+		{Routes: []Route{{PathPrefix: "/uploads"}, {PathPrefix: "/uploads"}}},
+	} {
+		_, err = invalidConfiguration.resolveLimits()
+		suite.Require().Error(err)
+	}
+}
+
+func (suite *limitsTestSuite) TestMaxRequestBodySize() {
+	suite.startServer(&Configuration{
+		Limits: Limits{
+			MaxRequestBodySize: 10,
+		},
+		Routes: []Route{
+			{
+				PathPrefix: "/large",
+				Limits: Limits{
+					MaxRequestBodySize: 100,
+				},
+			},
+		},
+	}, suite.echo)
+
+	for _, testCase := range []struct {
+		path               string
+		bodySize           int
+		expectedStatusCode int
+	}{
+		{path: "/small", bodySize: 10, expectedStatusCode: net_http.StatusOK},
+		{path: "/small", bodySize: 11, expectedStatusCode: net_http.StatusRequestEntityTooLarge},
+		{path: "/large?query=value", bodySize: 100, expectedStatusCode: net_http.StatusOK},
+		{path: "/large", bodySize: 101, expectedStatusCode: net_http.StatusRequestEntityTooLarge},
+	} {
+		response := suite.post(testCase.path, strings.Repeat("a", testCase.bodySize), nil)
+		suite.Require().Equal(testCase.expectedStatusCode, response.StatusCode, "path %s", testCase.path)
+	}
+}
+
+func (suite *limitsTestSuite) TestDecompressRequests() {
+	suite.startServer(&Configuration{
+		Limits: Limits{
+			MaxRequestBodySize: 100,
+		},
+		Routes: []Route{
+			{
+				PathPrefix: "/decompressed",
+				Limits: Limits{
+					DecompressRequests: suite.boolPointer(true),
+				},
+			},
+		},
+	}, suite.echo)
+
+	gzipHeaders := map[string]string{"Content-Encoding": "gzip"}
+
+	response := suite.post("/decompressed", suite.gzip("hello"), gzipHeaders)
+	suite.Require().Equal(net_http.StatusOK, response.StatusCode)
+	suite.Require().Equal("hello", suite.readBody(response))
+
+	// other routes get the body as is
+	response = suite.post("/other", suite.gzip("hello"), gzipHeaders)
+	suite.Require().Equal(net_http.StatusOK, response.StatusCode)
+	suite.Require().Equal(suite.gzip("hello"), suite.readBody(response))
+
+	// the limit applies to the decompressed body, which compresses well
+	compressedBody := suite.gzip(strings.Repeat("a", 101))
+	suite.Require().True(len(compressedBody) < 100)
+
+	response = suite.post("/decompressed", compressedBody, gzipHeaders)
+	suite.Require().Equal(net_http.StatusRequestEntityTooLarge, response.StatusCode)
+
+	response = suite.post("/decompressed", "not gzip", gzipHeaders)
+	suite.Require().Equal(net_http.StatusBadRequest, response.StatusCode)
+}
+
+func (suite *limitsTestSuite) TestCompressResponses() {
+	suite.startServer(&Configuration{
+		Limits: Limits{
+			CompressResponses: suite.boolPointer(true),
+		},
+		Routes: []Route{
+			{
+				PathPrefix: "/uncompressed",
+				Limits: Limits{
+					CompressResponses: suite.boolPointer(false),
+				},
+			},
+		},
+	}, suite.echo)
+
+	acceptGzipHeaders := map[string]string{"Accept-Encoding": "gzip"}
+
+	// fasthttp doesn't bother compressing small bodies
+	body := strings.Repeat("hello", 100)
+
+	response := suite.post("/compressed", body, acceptGzipHeaders)
+	suite.Require().Equal("gzip", response.Header.Get("Content-Encoding"))
+
+	defer response.Body.Close() // nolint: errcheck
+
+	gzipReader, err := gzip.NewReader(response.Body)
+	suite.Require().NoError(err)
+
+	decompressedBody, err := ioutil.ReadAll(gzipReader)
+	suite.Require().NoError(err)
+	suite.Require().Equal(body, string(decompressedBody))
+
+	response = suite.post("/uncompressed", body, acceptGzipHeaders)
+	suite.Require().Empty(response.Header.Get("Content-Encoding"))
+	suite.Require().Equal(body, suite.readBody(response))
+}
+
+func (suite *limitsTestSuite) TestHandlerTimeout() {
+	suite.startServer(&Configuration{
+		Routes: []Route{
+			{
+				PathPrefix: "/slow",
+				Limits: Limits{
+					HandlerTimeout: "50ms",
+				},
+			},
+		},
+	}, func(ctx *fasthttp.RequestCtx) {
+		if strings.HasPrefix(string(ctx.Path()), "/slow") {
+			time.Sleep(time.Second)
+		}
+
+		ctx.WriteString("done") // nolint: errcheck
+	})
+
+	response := suite.post("/slow", "", nil)
+	suite.Require().Equal(net_http.StatusRequestTimeout, response.StatusCode)
+
+	// without a handler timeout, the handler takes as long as it takes
+	response = suite.post("/fast", "", nil)
+	suite.Require().Equal(net_http.StatusOK, response.StatusCode)
+}
+
+func (suite *limitsTestSuite) startServer(configuration *Configuration, requestHandler fasthttp.RequestHandler) {
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.trigger = &http{
+		AbstractTrigger: trigger.AbstractTrigger{
+			Logger: logger,
+		},
+		configuration: configuration,
+	}
+
+	err = suite.trigger.createRoutes(requestHandler)
+	suite.Require().NoError(err)
+
+	triggerLimits := suite.trigger.routes[len(suite.trigger.routes)-1].limits
+
+	server := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			suite.trigger.getRoute(ctx.Path()).handler(ctx)
+		},
+		MaxRequestBodySize: triggerLimits.maxRequestBodySize,
+		HeaderReceived:     suite.trigger.onHeaderReceived,
+		ErrorHandler:       suite.trigger.onRequestError,
+	}
+
+	suite.listener = fasthttputil.NewInmemoryListener()
+	go server.Serve(suite.listener) // nolint: errcheck
+}
+
+func (suite *limitsTestSuite) echo(ctx *fasthttp.RequestCtx) {
+	ctx.Write(ctx.Request.Body()) // nolint: errcheck
+}
+
+func (suite *limitsTestSuite) post(path string, body string, headers map[string]string) *net_http.Response {
+	request, err := net_http.NewRequest(net_http.MethodPost, "http://trigger"+path, strings.NewReader(body))
+	suite.Require().NoError(err)
+
+	for headerName, headerValue := range headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	client := &net_http.Client{
+		Transport: &net_http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return suite.listener.Dial()
+			},
+
+			// let the tests see the encoding of responses
+			DisableCompression: true,
+		},
+	}
+
+	response, err := client.Do(request)
+	suite.Require().NoError(err)
+
+	return response
+}
+
+func (suite *limitsTestSuite) readBody(response *net_http.Response) string {
+	defer response.Body.Close() // nolint: errcheck
+
+	body, err := ioutil.ReadAll(response.Body)
+	suite.Require().NoError(err)
+
+	return string(body)
+}
+
+func (suite *limitsTestSuite) gzip(body string) string {
+	var compressedBody bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&compressedBody)
+	_, err := gzipWriter.Write([]byte(body))
+	suite.Require().NoError(err)
+	suite.Require().NoError(gzipWriter.Close())
+
+	return compressedBody.String()
+}
+
+func (suite *limitsTestSuite) boolPointer(value bool) *bool {
+	return &value
+}
+
+func TestLimitsTestSuite(t *testing.T) {
+	suite.Run(t, new(limitsTestSuite))
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/util/ratelimit"
+
+	"github.com/valyala/fasthttp"
+)
+
+// allowRequest takes a token of the request's caller from the trigger's rate limit, if it has one. requests for
+// which there's no token are rejected with ratelimit.ErrRateLimited, and told when to retry (in whole seconds,
+// per Retry-After)
+func (h *http) allowRequest(ctx *fasthttp.RequestCtx) error {
+	if h.RateLimiter == nil {
+		return nil
+	}
+
+	allowed, retryAfter := h.RateLimiter.Allow(h.getRateLimitKey(ctx))
+	if allowed {
+		return nil
+	}
+
+	ctx.Response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	return ratelimit.ErrRateLimited
+}
+
+// getRateLimitKey returns the key of the bucket the request's caller takes tokens from - its remote IP address or
+// the value of the configured header, or the empty key (shared by all callers) if the limit isn't per caller
+func (h *http) getRateLimitKey(ctx *fasthttp.RequestCtx) string {
+	rateLimit := h.configuration.RateLimit
+
+	if rateLimit.Key == functionconfig.RateLimitKeyIP {
+		return ctx.RemoteIP().String()
+	}
+
+	if headerName := rateLimit.GetKeyHeaderName(); headerName != "" {
+		return string(ctx.Request.Header.Peek(headerName))
+	}
+
+	return ""
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"net"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/util/ratelimit"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+)
+
+type rateLimitTestSuite struct {
+	suite.Suite
+}
+
+func (suite *rateLimitTestSuite) TestSharedLimit() {
+	triggerInstance := suite.createTrigger(&functionconfig.RateLimit{RPS: 0.5, Burst: 2})
+
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "")))
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.2", "")))
+
+	// all callers share the bucket, and are told to retry once it has a token
+	requestCtx := suite.createRequestCtx("10.0.0.3", "")
+	suite.Require().Equal(ratelimit.ErrRateLimited, triggerInstance.allowRequest(requestCtx))
+	suite.Require().Equal("2", string(requestCtx.Response.Header.Peek("Retry-After")))
+}
+
+func (suite *rateLimitTestSuite) TestLimitPerIP() {
+	triggerInstance := suite.createTrigger(&functionconfig.RateLimit{RPS: 1, Key: functionconfig.RateLimitKeyIP})
+
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "")))
+	suite.Require().Equal(ratelimit.ErrRateLimited,
+		triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "")))
+
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.2", "")))
+}
+
+func (suite *rateLimitTestSuite) TestLimitPerHeader() {
+	triggerInstance := suite.createTrigger(&functionconfig.RateLimit{RPS: 1, Key: "header:X-Api-Key"})
+
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "tenant-a")))
+
+	// the same caller, regardless of where it calls from
+	suite.Require().Equal(ratelimit.ErrRateLimited,
+		triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.2", "tenant-a")))
+
+	suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "tenant-b")))
+}
+
+func (suite *rateLimitTestSuite) TestNoLimit() {
+	triggerInstance := suite.createTrigger(nil)
+
+	for requestIndex := 0; requestIndex < 10; requestIndex++ {
+		suite.Require().NoError(triggerInstance.allowRequest(suite.createRequestCtx("10.0.0.1", "")))
+	}
+}
+
+func (suite *rateLimitTestSuite) createTrigger(rateLimit *functionconfig.RateLimit) *http {
+	triggerInstance := &http{
+		configuration: &Configuration{
+			Configuration: trigger.Configuration{
+				Trigger: functionconfig.Trigger{
+					RateLimit: rateLimit,
+				},
+			},
+		},
+	}
+
+	if rateLimit != nil {
+		triggerInstance.RateLimiter = ratelimit.NewLimiter(rateLimit.RPS, rateLimit.GetBurst())
+	}
+
+	return triggerInstance
+}
+
+func (suite *rateLimitTestSuite) createRequestCtx(remoteIP string, apiKey string) *fasthttp.RequestCtx {
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(remoteIP), Port: 40000}, nil)
+
+	if apiKey != "" {
+		requestCtx.Request.Header.Set("X-Api-Key", apiKey)
+	}
+
+	return requestCtx
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(rateLimitTestSuite))
+}
@@ -26,8 +26,13 @@ import (
 
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/deadline"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/status"
+	"github.com/nuclio/nuclio/pkg/processor/tracing"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
+	"github.com/nuclio/nuclio/pkg/processor/util/ratelimit"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
 	"github.com/nuclio/errors"
@@ -51,6 +56,9 @@ type http struct {
 	timeouts         []uint64 // flag of worker is in timeout
 	answering        []uint64 // flag the worker is answering
 	server           *fasthttp.Server
+	accessLogger     *accessLogger
+	authenticator    authenticator
+	routes           []*route
 }
 
 func newTrigger(logger logger.Logger,
@@ -93,6 +101,24 @@ func newTrigger(logger logger.Logger,
 		answering:        make([]uint64, numWorkers),
 	}
 
+	if configuration.AccessLog != nil && configuration.AccessLog.Enabled {
+		newTrigger.accessLogger, err = newAccessLogger(configuration.AccessLog)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create access logger")
+		}
+	}
+
+	if configuration.Authentication != nil {
+		newTrigger.authenticator, err = newAuthenticator(configuration.Authentication)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create authenticator")
+		}
+	}
+
+	if err := newTrigger.createRoutes(newTrigger.handleRequest); err != nil {
+		return nil, errors.Wrap(err, "Failed to create routes")
+	}
+
 	newTrigger.allocateEvents(numWorkers)
 	return &newTrigger, nil
 }
@@ -101,13 +127,24 @@ func (h *http) Start(checkpoint functionconfig.Checkpoint) error {
 	h.Logger.InfoWith("Starting",
 		"listenAddress", h.configuration.URL,
 		"readBufferSize", h.configuration.ReadBufferSize,
-		"cors", h.configuration.CORS)
+		"cors", h.configuration.CORS,
+		"accessLog", h.configuration.AccessLog,
+		"authenticationMode", h.getAuthenticationMode(),
+		"routes", len(h.configuration.Routes))
+
+	// the limits of the trigger are the last route's, and apply until the route of a request is known
+	triggerLimits := h.routes[len(h.routes)-1].limits
 
 	h.server = &fasthttp.Server{
-		Handler:        h.onRequestFromFastHTTP(),
-		Name:           "nuclio",
-		ReadBufferSize: h.configuration.ReadBufferSize,
-		Logger:         NewFastHTTPLogger(h.Logger),
+		Handler:            h.onRequestFromFastHTTP(),
+		Name:               "nuclio",
+		ReadBufferSize:     h.configuration.ReadBufferSize,
+		MaxRequestBodySize: triggerLimits.maxRequestBodySize,
+		ReadTimeout:        triggerLimits.readTimeout,
+		WriteTimeout:       triggerLimits.writeTimeout,
+		HeaderReceived:     h.onHeaderReceived,
+		ErrorHandler:       h.onRequestError,
+		Logger:             NewFastHTTPLogger(h.Logger),
 	}
 
 	// start listening
@@ -130,6 +167,12 @@ func (h *http) Stop(force bool) (functionconfig.Checkpoint, error) {
 		}
 	}
 
+	if h.accessLogger != nil {
+		if err := h.accessLogger.close(); err != nil {
+			return nil, errors.Wrap(err, "Failed to close access log")
+		}
+	}
+
 	return nil, nil
 }
 
@@ -169,6 +212,7 @@ func (h *http) TimeoutWorker(worker *worker.Worker) error {
 }
 
 func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
+	span *tracing.Span,
 	functionLogger logger.Logger,
 	timeout time.Duration) (response interface{}, timedOut bool, submitError error, processError error) {
 
@@ -176,8 +220,17 @@ func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
 
 	defer h.HandleSubmitPanic(workerInstance, &submitError)
 
+	if err := h.allowRequest(ctx); err != nil {
+		h.UpdateStatistics(false)
+		return nil, false, err, nil
+	}
+
 	// allocate a worker
+	allocationSpan := span.StartChild("allocate worker", tracing.SpanKindInternal)
 	workerInstance, err := h.WorkerAllocator.Allocate(timeout)
+	allocationSpan.SetError(err)
+	allocationSpan.End()
+
 	if err != nil {
 		h.UpdateStatistics(false)
 		return nil, false, errors.Wrap(err, "Failed to allocate worker"), nil
@@ -198,7 +251,13 @@ func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
 	event.ctx = ctx
 
 	// submit to worker
-	response, processError = h.SubmitEventToWorker(functionLogger, workerInstance, event)
+	response, processError = h.SubmitEventToWorkerInSpan(span, functionLogger, workerInstance, event)
+
+	// the event's ID is only given when it's submitted
+	if h.accessLogger != nil {
+		ctx.SetUserValue(accessLogWorkerIDKey, workerIndex)
+		ctx.SetUserValue(accessLogEventIDKey, string(event.GetID()))
+	}
 
 	// release worker when we're done
 	h.WorkerAllocator.Release(workerInstance)
@@ -215,6 +274,10 @@ func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
 
 func (h *http) onRequestFromFastHTTP() fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
+		if h.accessLogger != nil {
+			defer h.accessLogger.log(ctx, time.Now())
+		}
+
 		if h.configuration.CORS != nil &&
 			h.configuration.CORS.Enabled &&
 			common.ByteSliceToString(ctx.Method()) == h.configuration.CORS.PreflightRequestMethod {
@@ -223,11 +286,52 @@ func (h *http) onRequestFromFastHTTP() fasthttp.RequestHandler {
 			// That means => function will not be able to answer on the method configured by PreflightRequestMethod
 			h.handlePreflightRequest(ctx)
 		} else {
-			h.handleRequest(ctx)
+			if h.configuration.CORS != nil && h.configuration.CORS.Enabled {
+				h.setCORSResponseHeaders(ctx)
+			}
+
+			// unauthenticated requests never reach a worker
+			if h.authenticator != nil {
+				if reason := h.authenticator.authenticate(ctx); reason != "" {
+					h.rejectUnauthenticatedRequest(ctx, reason)
+					return
+				}
+			}
+
+			if route := h.getRoute(ctx.Path()); route != nil {
+				route.handler(ctx)
+			} else {
+				h.handleRequest(ctx)
+			}
 		}
 	}
 }
 
+func (h *http) rejectUnauthenticatedRequest(ctx *fasthttp.RequestCtx, reason string) {
+	h.Logger.DebugWith("Rejecting unauthenticated request",
+		"path", string(ctx.Path()),
+		"reason", reason)
+
+	if h.getAuthenticationMode() == AuthenticationModeJWT {
+		ctx.Response.Header.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.Response.SetStatusCode(net_http.StatusUnauthorized)
+
+	if err := json.NewEncoder(ctx).Encode(map[string]string{"error": reason}); err != nil {
+		h.Logger.WarnWith("Can't encode error message", "error", err)
+	}
+}
+
+func (h *http) getAuthenticationMode() string {
+	if h.configuration.Authentication == nil || h.configuration.Authentication.Mode == "" {
+		return AuthenticationModeNone
+	}
+
+	return h.configuration.Authentication.Mode
+}
+
 func (h *http) handlePreflightRequest(ctx *fasthttp.RequestCtx) {
 
 	// default to bad preflight request unless all specifications are valid
@@ -241,6 +345,7 @@ func (h *http) handlePreflightRequest(ctx *fasthttp.RequestCtx) {
 
 	// indicate whether resource can be shared
 	ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+	ctx.Response.Header.Add("Vary", "Origin")
 
 	// indicate resource support credentials
 	if h.configuration.CORS.AllowCredentials {
@@ -265,6 +370,30 @@ func (h *http) handlePreflightRequest(ctx *fasthttp.RequestCtx) {
 	h.UpdateStatistics(true)
 }
 
+// setCORSResponseHeaders lets the browser share the response of an actual (non preflight) cross-origin request.
+// the function may still override these headers in its response
+func (h *http) setCORSResponseHeaders(ctx *fasthttp.RequestCtx) {
+	origin := common.ByteSliceToString(ctx.Request.Header.Peek("Origin"))
+
+	// not a cross-origin request, or one from an origin that isn't allowed - the browser will block it
+	if !h.configuration.CORS.OriginAllowed(origin) {
+		return
+	}
+
+	ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+	ctx.Response.Header.Add("Vary", "Origin")
+
+	if h.configuration.CORS.AllowCredentials {
+		ctx.Response.Header.Set("Access-Control-Allow-Credentials",
+			h.configuration.CORS.EncodeAllowCredentialsHeader())
+	}
+
+	if len(h.configuration.CORS.ExposeHeaders) > 0 {
+		ctx.Response.Header.Set("Access-Control-Expose-Headers",
+			h.configuration.CORS.EncodeExposeHeaders())
+	}
+}
+
 func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 	if h.status != status.Ready {
 		h.UpdateStatistics(false)
@@ -302,7 +431,17 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 		functionLogger, _ = nucliozap.NewMuxLogger(bufferLogger.Logger, h.Logger)
 	}
 
+	span := h.StartEventSpan(string(ctx.Request.Header.Peek(tracing.TraceparentHeader)))
+	span.SetAttribute("http.method", string(ctx.Method()))
+	span.SetAttribute("http.target", string(ctx.Path()))
+
+	defer func() {
+		span.SetAttribute("http.status_code", ctx.Response.StatusCode())
+		span.End()
+	}()
+
 	response, timedOut, submitError, processError := h.AllocateWorkerAndSubmitEvent(ctx,
+		span,
 		functionLogger,
 		time.Duration(*h.configuration.WorkerAvailabilityTimeoutMilliseconds)*time.Millisecond)
 
@@ -310,6 +449,11 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	responseWriteSpan := span.StartChild("write response", tracing.SpanKindInternal)
+	defer responseWriteSpan.End()
+
+	span.SetError(submitError)
+
 	// Clear active context in case of error
 	if submitError != nil || processError != nil {
 		for i, activeCtx := range h.activeContexts {
@@ -349,10 +493,15 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 	if submitError != nil {
 		switch errors.Cause(submitError) {
 
-		// no available workers
-		case worker.ErrNoAvailableWorkers:
+		// no available workers, or the event waited for admission for too long
+		case worker.ErrNoAvailableWorkers, admission.ErrQueueTimeout:
 			ctx.Response.SetStatusCode(net_http.StatusServiceUnavailable)
 
+		// the function handles as many events as it may, and has as many queued, or the caller exceeded
+		// the trigger's rate limit
+		case admission.ErrQueueFull, ratelimit.ErrRateLimited:
+			ctx.Response.SetStatusCode(net_http.StatusTooManyRequests)
+
 			// something else - most likely a bug
 		default:
 			h.Logger.WarnWith("Failed to submit event", "err", submitError)
@@ -377,6 +526,13 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 			statusCode = net_http.StatusInternalServerError
 		}
 
+		// the handler was cancelled, same as when it's timed out by the function's event timeout
+		if errors.Cause(processError) == deadline.ErrExceeded {
+			ctx.Response.SetStatusCode(net_http.StatusRequestTimeout)
+			ctx.Response.SetBody(timeoutResponse)
+			return
+		}
+
 		ctx.Response.SetStatusCode(statusCode)
 		ctx.Response.SetBodyString(processError.Error())
 		return
@@ -388,26 +544,13 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 
 		// set body
 		ctx.Response.SetBody(typedResponse.Body)
+		h.setResponseMeta(ctx, &typedResponse)
 
-		// set headers
-		for headerKey, headerValue := range typedResponse.Headers {
-			switch typedHeaderValue := headerValue.(type) {
-			case string:
-				ctx.Response.Header.Set(headerKey, typedHeaderValue)
-			case int:
-				ctx.Response.Header.Set(headerKey, strconv.Itoa(typedHeaderValue))
-			}
-		}
-
-		// set content type if set
-		if typedResponse.ContentType != "" {
-			ctx.SetContentType(typedResponse.ContentType)
-		}
+	case *runtime.StreamedResponse:
 
-		// set status code if set
-		if typedResponse.StatusCode != 0 {
-			ctx.Response.SetStatusCode(typedResponse.StatusCode)
-		}
+		// the body is written as it's streamed (with chunked transfer encoding), and closed once written
+		ctx.Response.SetBodyStream(typedResponse.BodyStream, -1)
+		h.setResponseMeta(ctx, &typedResponse.Response)
 
 	case []byte:
 		ctx.Write(typedResponse) // nolint: errcheck
@@ -415,6 +558,39 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 	case string:
 		ctx.WriteString(typedResponse) // nolint: errcheck
 	}
+
+	// emit the response as a cloud event, if configured to
+	if h.configuration.CloudEvents != nil && h.configuration.CloudEvents.ResponseMode != "" {
+		if err := encodeCloudEventResponse(&ctx.Response,
+			h.getCloudEventResponseAttributes(),
+			h.configuration.CloudEvents.ResponseMode); err != nil {
+			h.Logger.WarnWith("Failed to emit response as cloud event", "err", err)
+		}
+	}
+}
+
+// setResponseMeta sets the headers, content type and status code of the response
+func (h *http) setResponseMeta(ctx *fasthttp.RequestCtx, response *nuclio.Response) {
+
+	// set headers
+	for headerKey, headerValue := range response.Headers {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			ctx.Response.Header.Set(headerKey, typedHeaderValue)
+		case int:
+			ctx.Response.Header.Set(headerKey, strconv.Itoa(typedHeaderValue))
+		}
+	}
+
+	// set content type if set
+	if response.ContentType != "" {
+		ctx.SetContentType(response.ContentType)
+	}
+
+	// set status code if set
+	if response.StatusCode != 0 {
+		ctx.Response.SetStatusCode(response.StatusCode)
+	}
 }
 
 func (h *http) preflightRequestValidation(ctx *fasthttp.RequestCtx, origin string) bool {
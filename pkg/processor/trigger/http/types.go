@@ -30,6 +30,10 @@ type Configuration struct {
 	trigger.Configuration
 	ReadBufferSize int
 	CORS           *cors.CORS
+	AccessLog      *AccessLog
+	Authentication *Authentication
+	Limits         `mapstructure:",squash"`
+	Routes         []Route
 }
 
 const DefaultReadBufferSize = 16 * 1024
@@ -58,6 +62,28 @@ func NewConfiguration(ID string,
 	if newConfiguration.CORS != nil && newConfiguration.CORS.Enabled {
 		newConfiguration.CORS = createCORSConfiguration(newConfiguration.CORS)
 	}
+
+	if newConfiguration.AccessLog != nil && newConfiguration.AccessLog.Enabled {
+		if newConfiguration.AccessLog.SampleRate < 0 || newConfiguration.AccessLog.SampleRate > 1 {
+			return nil, errors.Errorf("Access log sample rate must be between 0 and 1, got %v",
+				newConfiguration.AccessLog.SampleRate)
+		}
+
+		if newConfiguration.AccessLog.SampleRate == 0 {
+			newConfiguration.AccessLog.SampleRate = 1
+		}
+	}
+
+	if newConfiguration.Authentication != nil {
+		if err := newConfiguration.Authentication.validate(); err != nil {
+			return nil, errors.Wrap(err, "Invalid authentication configuration")
+		}
+	}
+
+	if _, err := newConfiguration.resolveLimits(); err != nil {
+		return nil, errors.Wrap(err, "Invalid limits configuration")
+	}
+
 	return &newConfiguration, nil
 }
 
@@ -79,6 +105,18 @@ func createCORSConfiguration(CORSConfiguration *cors.CORS) *cors.CORS {
 		corsInstance.AllowOrigin = CORSConfiguration.AllowOrigin
 	}
 
+	// a list of origins replaces the default of allowing any origin
+	if len(CORSConfiguration.AllowOrigins) > 0 {
+		corsInstance.AllowOrigins = CORSConfiguration.AllowOrigins
+		if CORSConfiguration.AllowOrigin == "" {
+			corsInstance.AllowOrigin = ""
+		}
+	}
+
+	if len(CORSConfiguration.ExposeHeaders) > 0 {
+		corsInstance.ExposeHeaders = CORSConfiguration.ExposeHeaders
+	}
+
 	if CORSConfiguration.AllowCredentials {
 		corsInstance.AllowCredentials = CORSConfiguration.AllowCredentials
 	}
@@ -0,0 +1,186 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	natsio "github.com/nats-io/go-nats"
+	"github.com/nuclio/errors"
+)
+
+// the go-nats client nuclio uses predates JetStream, so the trigger speaks the JetStream API - JSON requests
+// over core NATS subjects - itself. it only needs to create consumers, request messages and acknowledge them
+const (
+	apiRequestTimeout = 10 * time.Second
+
+	consumerCreateSubjectTemplate = "$JS.API.CONSUMER.DURABLE.CREATE.%s.%s"
+	consumerNextSubjectTemplate   = "$JS.API.CONSUMER.MSG.NEXT.%s.%s"
+
+	// push consumers deliver to this subject, which all the replicas subscribe to in a queue group
+	deliverSubjectTemplate = "_nuclio.jetstream.%s.%s"
+
+	ackPayload = "+ACK"
+	nakPayload = "-NAK"
+)
+
+type consumerConfig struct {
+	DurableName    string `json:"durable_name"`
+	DeliverSubject string `json:"deliver_subject,omitempty"`
+	DeliverGroup   string `json:"deliver_group,omitempty"`
+	DeliverPolicy  string `json:"deliver_policy"`
+	AckPolicy      string `json:"ack_policy"`
+	AckWait        int64  `json:"ack_wait"`
+	MaxDeliver     int    `json:"max_deliver,omitempty"`
+	MaxAckPending  int    `json:"max_ack_pending,omitempty"`
+	FilterSubject  string `json:"filter_subject,omitempty"`
+}
+
+type createConsumerRequest struct {
+	StreamName string         `json:"stream_name"`
+	Config     consumerConfig `json:"config"`
+}
+
+type nextRequest struct {
+	Batch int `json:"batch"`
+}
+
+type apiResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+	NumPending uint64 `json:"num_pending"`
+}
+
+// messageMetadata is encoded in the subject a JetStream message is acknowledged on (its reply subject)
+type messageMetadata struct {
+	Stream         string
+	Consumer       string
+	Delivered      uint64
+	StreamSequence uint64
+	Timestamp      time.Time
+	Pending        uint64
+}
+
+func newConsumerConfig(configuration *Configuration) *consumerConfig {
+	newConsumerConfig := &consumerConfig{
+		DurableName:   configuration.Durable,
+		DeliverPolicy: configuration.DeliverPolicy,
+		AckPolicy:     "explicit",
+		AckWait:       int64(configuration.ackWait),
+		MaxDeliver:    configuration.MaxDeliver,
+		FilterSubject: configuration.Subject,
+	}
+
+	if configuration.Mode == ModePush {
+		newConsumerConfig.DeliverSubject = getDeliverSubject(configuration)
+		newConsumerConfig.DeliverGroup = configuration.Durable
+		newConsumerConfig.MaxAckPending = configuration.MaxAckPending
+	}
+
+	return newConsumerConfig
+}
+
+func getDeliverSubject(configuration *Configuration) string {
+	return fmt.Sprintf(deliverSubjectTemplate, configuration.Stream, configuration.Durable)
+}
+
+// createConsumer creates the durable consumer, or does nothing if it exists with the same configuration.
+// returns the number of messages pending for it
+func createConsumer(natsConnection *natsio.Conn, configuration *Configuration) (uint64, error) {
+	encodedRequest, err := json.Marshal(&createConsumerRequest{
+		StreamName: configuration.Stream,
+		Config:     *newConsumerConfig(configuration),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to encode consumer creation request")
+	}
+
+	responseMessage, err := natsConnection.Request(fmt.Sprintf(consumerCreateSubjectTemplate,
+		configuration.Stream,
+		configuration.Durable), encodedRequest, apiRequestTimeout)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to request consumer creation (is JetStream enabled?)")
+	}
+
+	response := apiResponse{}
+	if err := json.Unmarshal(responseMessage.Data, &response); err != nil {
+		return 0, errors.Wrap(err, "Failed to decode consumer creation response")
+	}
+
+	if response.Error != nil {
+		return 0, errors.Errorf("Failed to create consumer: %s (%d)", response.Error.Description, response.Error.Code)
+	}
+
+	return response.NumPending, nil
+}
+
+// requestNextMessage asks the server to deliver the next message of a pull consumer to the inbox. the request
+// waits on the server until there's a message
+func requestNextMessage(natsConnection *natsio.Conn, configuration *Configuration, inbox string) error {
+	encodedRequest, err := json.Marshal(&nextRequest{Batch: 1})
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode next message request")
+	}
+
+	return natsConnection.PublishRequest(fmt.Sprintf(consumerNextSubjectTemplate,
+		configuration.Stream,
+		configuration.Durable), inbox, encodedRequest)
+}
+
+// parseMessageMetadata parses the reply subject of a message, which is either
+// $JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<timestamp>.<pending> or, on newer servers,
+// $JS.ACK.<domain>.<account hash>.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<timestamp>.<pending>[.<token>]
+func parseMessageMetadata(replySubject string) (*messageMetadata, error) {
+	tokens := strings.Split(replySubject, ".")
+	if len(tokens) < 9 || tokens[0] != "$JS" || tokens[1] != "ACK" {
+		return nil, errors.Errorf("Not a JetStream message reply subject: %s", replySubject)
+	}
+
+	tokens = tokens[2:]
+	if len(tokens) > 7 {
+		tokens = tokens[2:]
+	}
+
+	if len(tokens) < 7 {
+		return nil, errors.Errorf("Not a JetStream message reply subject: %s", replySubject)
+	}
+
+	var numbers [5]uint64
+	for numberIndex := range numbers {
+		number, err := strconv.ParseUint(tokens[2+numberIndex], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse reply subject %s", replySubject)
+		}
+
+		numbers[numberIndex] = number
+	}
+
+	return &messageMetadata{
+		Stream:         tokens[0],
+		Consumer:       tokens[1],
+		Delivered:      numbers[0],
+		StreamSequence: numbers[1],
+		Timestamp:      time.Unix(0, int64(numbers[3])),
+		Pending:        numbers[4],
+	}, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"strconv"
+	"time"
+
+	natsio "github.com/nats-io/go-nats"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event holds a JetStream message
+type Event struct {
+	nuclio.AbstractEvent
+	natsMessage *natsio.Msg
+	metadata    *messageMetadata
+}
+
+func (e *Event) GetBody() []byte {
+	return e.natsMessage.Data
+}
+
+func (e *Event) GetSize() int {
+	return len(e.natsMessage.Data)
+}
+
+// GetPath returns the subject the message was published to
+func (e *Event) GetPath() string {
+	return e.natsMessage.Subject
+}
+
+func (e *Event) GetTimestamp() time.Time {
+	if e.metadata == nil {
+		return time.Now()
+	}
+
+	return e.metadata.Timestamp
+}
+
+// GetOffset returns the message's sequence in the stream
+func (e *Event) GetOffset() int {
+	if e.metadata == nil {
+		return 0
+	}
+
+	return int(e.metadata.StreamSequence)
+}
+
+// GetHeaders returns the message's stream, stream sequence and the number of times it was delivered
+func (e *Event) GetHeaders() map[string]interface{} {
+	if e.metadata == nil {
+		return map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"X-Nuclio-Jetstream-Stream":    e.metadata.Stream,
+		"X-Nuclio-Jetstream-Sequence":  strconv.FormatUint(e.metadata.StreamSequence, 10),
+		"X-Nuclio-Jetstream-Delivered": strconv.FormatUint(e.metadata.Delivered, 10),
+	}
+}
+
+// GetHeader returns the header by name as an interface{}
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaders()[key]
+}
+
+func (e *Event) NATSMessage() *natsio.Msg {
+	return e.natsMessage
+}
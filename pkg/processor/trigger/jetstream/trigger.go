@@ -0,0 +1,186 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	natsio "github.com/nats-io/go-nats"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type jetStream struct {
+	trigger.AbstractTrigger
+	configuration  *Configuration
+	stop           chan struct{}
+	natsConnection *natsio.Conn
+	subscription   *natsio.Subscription
+}
+
+func newTrigger(parentLogger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration) (trigger.Trigger, error) {
+	abstractTrigger, err := trigger.NewAbstractTrigger(parentLogger.GetChild(configuration.ID),
+		workerAllocator,
+		&configuration.Configuration,
+		"async",
+		"jetstream",
+		configuration.Name)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	return &jetStream{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+		stop:            make(chan struct{}),
+	}, nil
+}
+
+func (js *jetStream) Start(checkpoint functionconfig.Checkpoint) error {
+	var err error
+
+	js.Logger.InfoWith("Starting",
+		"serverURL", js.configuration.URL,
+		"stream", js.configuration.Stream,
+		"durable", js.configuration.Durable,
+		"subject", js.configuration.Subject,
+		"mode", js.configuration.Mode)
+
+	js.natsConnection, err = natsio.Connect(js.configuration.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Can't connect to NATS server %s", js.configuration.URL)
+	}
+
+	pending, err := createConsumer(js.natsConnection, js.configuration)
+	if err != nil {
+		js.natsConnection.Close()
+		return errors.Wrapf(err, "Can't create consumer %s of stream %s", js.configuration.Durable, js.configuration.Stream)
+	}
+
+	atomic.StoreUint64(&js.Statistics.ConsumerLag, pending)
+
+	messageChan := make(chan *natsio.Msg, js.configuration.MaxWorkers)
+
+	// a pull consumer delivers a message per request to an inbox. a request is kept pending for every worker, so
+	// that each is handed a message as soon as it's free. a push consumer delivers to the replicas' queue group,
+	// up to max ack pending messages at a time
+	var inbox string
+	if js.configuration.Mode == ModePull {
+		inbox = natsio.NewInbox()
+		js.subscription, err = js.natsConnection.ChanSubscribe(inbox, messageChan)
+	} else {
+		js.subscription, err = js.natsConnection.ChanQueueSubscribe(getDeliverSubject(js.configuration),
+			js.configuration.Durable,
+			messageChan)
+	}
+
+	if err != nil {
+		js.natsConnection.Close()
+		return errors.Wrapf(err, "Can't subscribe to consumer %s", js.configuration.Durable)
+	}
+
+	if inbox != "" {
+		for workerIndex := 0; workerIndex < js.configuration.MaxWorkers; workerIndex++ {
+			if err := requestNextMessage(js.natsConnection, js.configuration, inbox); err != nil {
+				js.natsConnection.Close()
+				return errors.Wrap(err, "Failed to request messages")
+			}
+		}
+	}
+
+	go js.listenForMessages(messageChan, inbox)
+
+	return nil
+}
+
+func (js *jetStream) Stop(force bool) (functionconfig.Checkpoint, error) {
+	close(js.stop)
+
+	// unacknowledged messages are redelivered once their ack wait passes
+	err := js.subscription.Unsubscribe()
+	js.natsConnection.Close()
+
+	return nil, err
+}
+
+func (js *jetStream) GetConfig() map[string]interface{} {
+	return common.StructureToMap(js.configuration)
+}
+
+func (js *jetStream) listenForMessages(messageChan chan *natsio.Msg, inbox string) {
+	for {
+		select {
+		case natsMessage := <-messageChan:
+			go js.handleMessage(natsMessage, inbox)
+		case <-js.stop:
+			return
+		}
+	}
+}
+
+// handleMessage submits the message to a worker and acknowledges it, or negatively acknowledges it for immediate
+// redelivery if it wasn't handled successfully. in pull mode, another message is then requested
+func (js *jetStream) handleMessage(natsMessage *natsio.Msg, inbox string) {
+	if inbox != "" {
+		defer func() {
+			if err := requestNextMessage(js.natsConnection, js.configuration, inbox); err != nil {
+				js.Logger.WarnWith("Failed to request next message", "err", err.Error())
+			}
+		}()
+	}
+
+	event := &Event{natsMessage: natsMessage}
+
+	metadata, err := parseMessageMetadata(natsMessage.Reply)
+	if err != nil {
+		js.Logger.WarnWith("Received a message which isn't from JetStream, ignoring it",
+			"subject", natsMessage.Subject,
+			"err", err.Error())
+		return
+	}
+
+	event.metadata = metadata
+	atomic.StoreUint64(&js.Statistics.ConsumerLag, metadata.Pending)
+
+	workerAvailabilityTimeout := time.Duration(*js.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond
+
+	_, submitError, processError := js.AllocateWorkerAndSubmitEvent(event, js.Logger, workerAvailabilityTimeout)
+
+	ackPayloadToSend := ackPayload
+	if submitError != nil {
+		js.Logger.WarnWith("Can't submit event", "error", submitError)
+		ackPayloadToSend = nakPayload
+	} else if processError != nil {
+		js.Logger.WarnWith("Can't process event", "error", processError)
+		ackPayloadToSend = nakPayload
+	}
+
+	if err := js.natsConnection.Publish(natsMessage.Reply, []byte(ackPayloadToSend)); err != nil {
+		js.Logger.WarnWith("Failed to acknowledge message",
+			"streamSequence", metadata.StreamSequence,
+			"ack", ackPayloadToSend,
+			"err", err.Error())
+	}
+}
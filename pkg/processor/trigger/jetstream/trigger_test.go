@@ -0,0 +1,386 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nats-io/gnatsd/server"
+	natsio "github.com/nats-io/go-nats"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingRuntime records the bodies of the events it got, failing on "fail"
+type recordingRuntime struct {
+	runtime.Runtime
+	lock   sync.Mutex
+	bodies []string
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	rr.bodies = append(rr.bodies, string(event.GetBody()))
+
+	if string(event.GetBody()) == "fail" {
+		return nil, errors.New("Failed")
+	}
+
+	return nil, nil
+}
+
+// fakeStream serves the parts of the JetStream API the trigger uses on top of a core NATS server, delivering
+// queued messages to pull requests and recording the acknowledgements
+type fakeStream struct {
+	lock                  sync.Mutex
+	natsConnection        *natsio.Conn
+	queuedMessages        []string
+	pendingInboxes        []string
+	lastSequence          uint64
+	createConsumerRequest createConsumerRequest
+	acks                  []string
+}
+
+func (fs *fakeStream) serve(natsConnection *natsio.Conn, stream string) error {
+	fs.natsConnection = natsConnection
+
+	if _, err := natsConnection.Subscribe(fmt.Sprintf(consumerCreateSubjectTemplate, stream, "*"),
+		func(message *natsio.Msg) {
+			fs.lock.Lock()
+			defer fs.lock.Unlock()
+
+			json.Unmarshal(message.Data, &fs.createConsumerRequest) // nolint: errcheck
+
+			response := fmt.Sprintf(`{"num_pending": %d}`, len(fs.queuedMessages))
+			natsConnection.Publish(message.Reply, []byte(response)) // nolint: errcheck
+		}); err != nil {
+		return err
+	}
+
+	if _, err := natsConnection.Subscribe(fmt.Sprintf(consumerNextSubjectTemplate, stream, "*"),
+		func(message *natsio.Msg) {
+			fs.lock.Lock()
+			defer fs.lock.Unlock()
+
+			fs.pendingInboxes = append(fs.pendingInboxes, message.Reply)
+			fs.deliver()
+		}); err != nil {
+		return err
+	}
+
+	_, err := natsConnection.Subscribe("$JS.ACK.>", func(message *natsio.Msg) {
+		fs.lock.Lock()
+		defer fs.lock.Unlock()
+
+		metadata, _ := parseMessageMetadata(message.Subject)
+		fs.acks = append(fs.acks, fmt.Sprintf("%d:%s", metadata.StreamSequence, string(message.Data)))
+	})
+
+	return err
+}
+
+func (fs *fakeStream) publish(body string) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.queuedMessages = append(fs.queuedMessages, body)
+	fs.deliver()
+}
+
+func (fs *fakeStream) getAcks() []string {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	return append([]string{}, fs.acks...)
+}
+
+// deliver hands queued messages to pending pull requests. must be called with the lock held
+func (fs *fakeStream) deliver() {
+	for len(fs.queuedMessages) > 0 && len(fs.pendingInboxes) > 0 {
+		fs.lastSequence++
+
+		replySubject := fmt.Sprintf("$JS.ACK.%s.%s.1.%d.%d.%d.%d",
+			fs.createConsumerRequest.StreamName,
+			fs.createConsumerRequest.Config.DurableName,
+			fs.lastSequence,
+			fs.lastSequence,
+			time.Now().UnixNano(),
+			len(fs.queuedMessages)-1)
+
+		fs.natsConnection.PublishMsg(&natsio.Msg{ // nolint: errcheck
+			Subject: fs.pendingInboxes[0],
+			Reply:   replySubject,
+			Data:    []byte(fs.queuedMessages[0]),
+		})
+
+		fs.queuedMessages = fs.queuedMessages[1:]
+		fs.pendingInboxes = fs.pendingInboxes[1:]
+	}
+}
+
+type jetStreamTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *jetStreamTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *jetStreamTestSuite) TestConfigurationDefaults() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{"stream": "orders"})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("default-my-function-my-trigger", configuration.Durable)
+	suite.Require().Equal(ModePull, configuration.Mode)
+	suite.Require().Equal(DeliverPolicyAll, configuration.DeliverPolicy)
+	suite.Require().Equal(defaultAckWait, configuration.ackWait)
+	suite.Require().Equal(configuration.MaxWorkers, configuration.MaxAckPending)
+}
+
+func (suite *jetStreamTestSuite) TestConfigurationValidation() {
+	for _, testCase := range []struct {
+		name          string
+		attributes    map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			attributes: map[string]interface{}{
+				"stream":        "orders",
+				"durable":       "order-processor",
+				"subject":       "orders.created",
+				"mode":          "push",
+				"ackWait":       "1m",
+				"deliverPolicy": "new",
+				"maxDeliver":    5,
+			},
+		},
+		{
+			name:          "missingStream",
+			attributes:    map[string]interface{}{},
+			expectedError: true,
+		},
+		{
+			name:          "invalidStream",
+			attributes:    map[string]interface{}{"stream": "orders.created"},
+			expectedError: true,
+		},
+		{
+			name:          "invalidDurable",
+			attributes:    map[string]interface{}{"stream": "orders", "durable": "order processor"},
+			expectedError: true,
+		},
+		{
+			name:          "invalidMode",
+			attributes:    map[string]interface{}{"stream": "orders", "mode": "poll"},
+			expectedError: true,
+		},
+		{
+			name:          "invalidDeliverPolicy",
+			attributes:    map[string]interface{}{"stream": "orders", "deliverPolicy": "first"},
+			expectedError: true,
+		},
+		{
+			name:          "invalidAckWait",
+			attributes:    map[string]interface{}{"stream": "orders", "ackWait": "-1s"},
+			expectedError: true,
+		},
+		{
+			name:          "negativeMaxDeliver",
+			attributes:    map[string]interface{}{"stream": "orders", "maxDeliver": -1},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := suite.newConfiguration(testCase.attributes)
+
+			if testCase.expectedError {
+				suite.Require().Error(err)
+			} else {
+				suite.Require().NoError(err)
+			}
+		})
+	}
+}
+
+func (suite *jetStreamTestSuite) TestConsumerConfig() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"stream":        "orders",
+		"durable":       "order-processor",
+		"subject":       "orders.created",
+		"ackWait":       "1m",
+		"maxAckPending": 64,
+	})
+	suite.Require().NoError(err)
+
+	// pull consumers have no deliver subject
+	suite.Require().Equal(&consumerConfig{
+		DurableName:   "order-processor",
+		DeliverPolicy: DeliverPolicyAll,
+		AckPolicy:     "explicit",
+		AckWait:       int64(time.Minute),
+		FilterSubject: "orders.created",
+	}, newConsumerConfig(configuration))
+
+	// push consumers deliver to a queue group shared by the replicas
+	configuration.Mode = ModePush
+
+	suite.Require().Equal(&consumerConfig{
+		DurableName:    "order-processor",
+		DeliverSubject: "_nuclio.jetstream.orders.order-processor",
+		DeliverGroup:   "order-processor",
+		DeliverPolicy:  DeliverPolicyAll,
+		AckPolicy:      "explicit",
+		AckWait:        int64(time.Minute),
+		MaxAckPending:  64,
+		FilterSubject:  "orders.created",
+	}, newConsumerConfig(configuration))
+}
+
+func (suite *jetStreamTestSuite) TestParseMessageMetadata() {
+	expectedMetadata := &messageMetadata{
+		Stream:         "orders",
+		Consumer:       "order-processor",
+		Delivered:      2,
+		StreamSequence: 42,
+		Timestamp:      time.Unix(0, 1600000000000000000),
+		Pending:        7,
+	}
+
+	for _, replySubject := range []string{
+		"$JS.ACK.orders.order-processor.2.42.40.1600000000000000000.7",
+		"$JS.ACK._.ACCOUNTHASH.orders.order-processor.2.42.40.1600000000000000000.7",
+		"$JS.ACK._.ACCOUNTHASH.orders.order-processor.2.42.40.1600000000000000000.7.random",
+	} {
+		metadata, err := parseMessageMetadata(replySubject)
+		suite.Require().NoError(err)
+		suite.Require().Equal(expectedMetadata, metadata)
+	}
+
+	for _, replySubject := range []string{
+		"",
+		"_INBOX.abcdef",
+		"$JS.ACK.orders.order-processor.2.42",
+		"$JS.ACK.orders.order-processor.2.forty-two.40.1600000000000000000.7",
+	} {
+		_, err := parseMessageMetadata(replySubject)
+		suite.Require().Error(err, replySubject)
+	}
+}
+
+func (suite *jetStreamTestSuite) TestPullConsumer() {
+	natsServer := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go natsServer.Start()
+	defer natsServer.Shutdown()
+	suite.Require().True(natsServer.ReadyForConnections(10 * time.Second))
+
+	serverURL := "nats://" + natsServer.Addr().String()
+
+	natsConnection, err := natsio.Connect(serverURL)
+	suite.Require().NoError(err)
+	defer natsConnection.Close()
+
+	stream := &fakeStream{}
+	err = stream.serve(natsConnection, "orders")
+	suite.Require().NoError(err)
+
+	err = natsConnection.Flush()
+	suite.Require().NoError(err)
+
+	stream.publish("first")
+
+	configuration, err := suite.newConfiguration(map[string]interface{}{"stream": "orders"})
+	suite.Require().NoError(err)
+	configuration.URL = serverURL
+
+	recordingRuntime := &recordingRuntime{}
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, recordingRuntime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewSingletonWorkerAllocator(suite.logger, workerInstance)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	err = triggerInstance.Start(nil)
+	suite.Require().NoError(err)
+	defer triggerInstance.Stop(true) // nolint: errcheck
+
+	// the consumer was created as durable with explicit acks, and its backlog reported as lag
+	suite.Require().Equal("orders", stream.createConsumerRequest.StreamName)
+	suite.Require().Equal("default-my-function-my-trigger", stream.createConsumerRequest.Config.DurableName)
+	suite.Require().Equal("explicit", stream.createConsumerRequest.Config.AckPolicy)
+	suite.Require().Empty(stream.createConsumerRequest.Config.DeliverSubject)
+
+	stream.publish("fail")
+	stream.publish("third")
+
+	// failed messages are negatively acknowledged for redelivery
+	suite.Require().Eventually(func() bool {
+		return len(stream.getAcks()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	suite.Require().Equal([]string{"1:+ACK", "2:-NAK", "3:+ACK"}, stream.getAcks())
+	suite.Require().Equal([]string{"first", "fail", "third"}, recordingRuntime.bodies)
+	suite.Require().Equal(uint64(0), atomic.LoadUint64(&triggerInstance.(*jetStream).Statistics.ConsumerLag))
+	suite.Require().True(strings.HasPrefix(triggerInstance.(*jetStream).subscription.Subject, "_INBOX."))
+}
+
+func (suite *jetStreamTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	runtimeConfiguration := &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+
+	runtimeConfiguration.Meta.Name = "my-function"
+	runtimeConfiguration.Meta.Namespace = "default"
+
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			Name:       "my-trigger",
+			URL:        "nats://nats:4222",
+			Attributes: attributes,
+		},
+		runtimeConfiguration)
+}
+
+func TestJetStreamSuite(t *testing.T) {
+	suite.Run(t, new(jetStreamTestSuite))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+// Consumer modes
+const (
+	ModePull = "pull"
+	ModePush = "push"
+)
+
+// Deliver policies - where a new consumer starts reading the stream
+const (
+	DeliverPolicyAll  = "all"
+	DeliverPolicyNew  = "new"
+	DeliverPolicyLast = "last"
+)
+
+const defaultAckWait = 30 * time.Second
+
+// stream and consumer names can't hold subject tokens or wildcards
+var invalidNameCharacters = regexp.MustCompile(`[.*>\s]`)
+
+type Configuration struct {
+	trigger.Configuration
+
+	// Stream is the name of the (existing) stream to consume
+	Stream string
+
+	// Durable is the name of the durable consumer, shared by all the function's replicas. defaults to
+	// <namespace>-<function>-<trigger>
+	Durable string
+
+	// Subject filters the stream's messages, if set
+	Subject string
+
+	// Mode is either pull (default) or push
+	Mode string
+
+	// AckWait is the time the function has to handle a message before it's redelivered (default 30s)
+	AckWait string
+
+	// MaxDeliver is the number of times a message is delivered (default unlimited)
+	MaxDeliver int
+
+	// MaxAckPending is the number of messages delivered to all replicas and not yet acknowledged (push mode,
+	// default maxWorkers)
+	MaxAckPending int
+
+	// DeliverPolicy is one of all (default), new or last
+	DeliverPolicy string
+
+	ackWait time.Duration
+}
+
+func NewConfiguration(ID string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *trigger.NewConfiguration(ID, triggerConfiguration, runtimeConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	// set defaults
+	if newConfiguration.Durable == "" {
+		newConfiguration.Durable = invalidNameCharacters.ReplaceAllString(fmt.Sprintf("%s-%s-%s",
+			runtimeConfiguration.Meta.Namespace,
+			runtimeConfiguration.Meta.Name,
+			newConfiguration.Name), "-")
+	}
+
+	if newConfiguration.Mode == "" {
+		newConfiguration.Mode = ModePull
+	}
+
+	if newConfiguration.DeliverPolicy == "" {
+		newConfiguration.DeliverPolicy = DeliverPolicyAll
+	}
+
+	if newConfiguration.MaxAckPending == 0 {
+		newConfiguration.MaxAckPending = newConfiguration.MaxWorkers
+	}
+
+	if err := newConfiguration.ParseDurationOrDefault(&trigger.DurationConfigField{
+		Name:    "ack wait",
+		Value:   newConfiguration.AckWait,
+		Field:   &newConfiguration.ackWait,
+		Default: defaultAckWait,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse ack wait")
+	}
+
+	if err := newConfiguration.validate(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate JetStream trigger configuration")
+	}
+
+	return &newConfiguration, nil
+}
+
+func (c *Configuration) validate() error {
+	natsURL, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse NATS URL")
+	}
+
+	if natsURL.Scheme != "nats" {
+		return errors.New("Invalid URL. Must begin with 'nats://'")
+	}
+
+	if c.Stream == "" {
+		return errors.New("Stream must be set")
+	}
+
+	if invalidNameCharacters.MatchString(c.Stream) {
+		return errors.Errorf("Stream %q must not contain '.', '*', '>' or whitespace", c.Stream)
+	}
+
+	if invalidNameCharacters.MatchString(c.Durable) {
+		return errors.Errorf("Durable %q must not contain '.', '*', '>' or whitespace", c.Durable)
+	}
+
+	if !common.StringInSlice(c.Mode, []string{ModePull, ModePush}) {
+		return errors.Errorf("Mode must be one of %s, %s - got %s", ModePull, ModePush, c.Mode)
+	}
+
+	if !common.StringInSlice(c.DeliverPolicy, []string{DeliverPolicyAll, DeliverPolicyNew, DeliverPolicyLast}) {
+		return errors.Errorf("Deliver policy must be one of %s, %s, %s - got %s",
+			DeliverPolicyAll,
+			DeliverPolicyNew,
+			DeliverPolicyLast,
+			c.DeliverPolicy)
+	}
+
+	if c.ackWait <= 0 {
+		return errors.Errorf("Ack wait must be positive, got %s", c.ackWait)
+	}
+
+	if c.MaxDeliver < 0 {
+		return errors.New("Max deliver must not be negative")
+	}
+
+	if c.MaxAckPending < 0 {
+		return errors.New("Max ack pending must not be negative")
+	}
+
+	return nil
+}
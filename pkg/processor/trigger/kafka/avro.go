@@ -0,0 +1,446 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/nuclio/errors"
+)
+
+// avroSchema is a parsed avro schema, holding just enough to decode the binary encoding into
+// generic values (logical types are decoded as their underlying type)
+type avroSchema struct {
+	typeName string
+
+	// record
+	fields []avroField
+
+	// enum
+	symbols []string
+
+	// array / map
+	items *avroSchema
+
+	// fixed
+	size int
+
+	// union
+	branches []*avroSchema
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+type avroSchemaParser struct {
+	namedSchemas map[string]*avroSchema
+}
+
+func parseAvroSchema(schema string) (*avroSchema, error) {
+	var schemaNode interface{}
+
+	if err := json.Unmarshal([]byte(schema), &schemaNode); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal schema")
+	}
+
+	parser := avroSchemaParser{
+		namedSchemas: map[string]*avroSchema{},
+	}
+
+	return parser.parse(schemaNode, "")
+}
+
+// Decode decodes a binary encoded avro value
+func (as *avroSchema) Decode(payload []byte) (interface{}, error) {
+	reader := avroReader{buffer: payload}
+
+	return reader.read(as)
+}
+
+func (asp *avroSchemaParser) parse(schemaNode interface{}, namespace string) (*avroSchema, error) {
+	switch typedSchemaNode := schemaNode.(type) {
+	case string:
+		if isAvroPrimitive(typedSchemaNode) {
+			return &avroSchema{typeName: typedSchemaNode}, nil
+		}
+
+		// reference to a previously defined named type
+		if namedSchema, found := asp.namedSchemas[asp.getFullName(typedSchemaNode, namespace)]; found {
+			return namedSchema, nil
+		}
+
+		if namedSchema, found := asp.namedSchemas[typedSchemaNode]; found {
+			return namedSchema, nil
+		}
+
+		return nil, errors.Errorf("Unknown type: %s", typedSchemaNode)
+
+	case []interface{}:
+		union := avroSchema{typeName: "union"}
+
+		for _, branchNode := range typedSchemaNode {
+			branch, err := asp.parse(branchNode, namespace)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to parse union branch")
+			}
+
+			union.branches = append(union.branches, branch)
+		}
+
+		return &union, nil
+
+	case map[string]interface{}:
+		return asp.parseComplex(typedSchemaNode, namespace)
+
+	default:
+		return nil, errors.Errorf("Invalid schema node: %v", schemaNode)
+	}
+}
+
+func (asp *avroSchemaParser) parseComplex(schemaNode map[string]interface{}, namespace string) (*avroSchema, error) {
+	typeName, isString := schemaNode["type"].(string)
+	if !isString {
+
+		// e.g. {"type": {"type": "array", ...}}
+		return asp.parse(schemaNode["type"], namespace)
+	}
+
+	switch typeName {
+	case "record", "error":
+		schema := avroSchema{typeName: "record"}
+
+		fullName, recordNamespace, err := asp.registerNamedSchema(schemaNode, namespace, &schema)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldNodes, isList := schemaNode["fields"].([]interface{})
+		if !isList {
+			return nil, errors.Errorf("Record %s must have a list of fields", fullName)
+		}
+
+		for _, fieldNode := range fieldNodes {
+			fieldAttributes, isMap := fieldNode.(map[string]interface{})
+			if !isMap {
+				return nil, errors.Errorf("Invalid field in record %s", fullName)
+			}
+
+			fieldName, _ := fieldAttributes["name"].(string)
+
+			fieldSchema, err := asp.parse(fieldAttributes["type"], recordNamespace)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse field %s of record %s", fieldName, fullName)
+			}
+
+			schema.fields = append(schema.fields, avroField{name: fieldName, schema: fieldSchema})
+		}
+
+		return &schema, nil
+
+	case "enum":
+		schema := avroSchema{typeName: typeName}
+
+		if _, _, err := asp.registerNamedSchema(schemaNode, namespace, &schema); err != nil {
+			return nil, err
+		}
+
+		symbolNodes, _ := schemaNode["symbols"].([]interface{})
+		for _, symbolNode := range symbolNodes {
+			symbol, _ := symbolNode.(string)
+			schema.symbols = append(schema.symbols, symbol)
+		}
+
+		return &schema, nil
+
+	case "fixed":
+		schema := avroSchema{typeName: typeName}
+
+		if _, _, err := asp.registerNamedSchema(schemaNode, namespace, &schema); err != nil {
+			return nil, err
+		}
+
+		size, isNumber := schemaNode["size"].(float64)
+		if !isNumber || size < 0 {
+			return nil, errors.New("Fixed type must have a non-negative size")
+		}
+
+		schema.size = int(size)
+
+		return &schema, nil
+
+	case "array", "map":
+		itemsKey := "items"
+		if typeName == "map" {
+			itemsKey = "values"
+		}
+
+		items, err := asp.parse(schemaNode[itemsKey], namespace)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s %s", typeName, itemsKey)
+		}
+
+		return &avroSchema{typeName: typeName, items: items}, nil
+
+	default:
+
+		// primitive, possibly annotated with a logical type
+		return asp.parse(typeName, namespace)
+	}
+}
+
+func (asp *avroSchemaParser) registerNamedSchema(schemaNode map[string]interface{},
+	namespace string,
+	schema *avroSchema) (string, string, error) {
+	name, _ := schemaNode["name"].(string)
+	if name == "" {
+		return "", "", errors.Errorf("Type %s must have a name", schema.typeName)
+	}
+
+	if schemaNamespace, found := schemaNode["namespace"].(string); found {
+		namespace = schemaNamespace
+	}
+
+	fullName := asp.getFullName(name, namespace)
+
+	// the namespace of nested types is that of the enclosing named type
+	if lastDotIndex := strings.LastIndex(fullName, "."); lastDotIndex != -1 {
+		namespace = fullName[:lastDotIndex]
+	}
+
+	// register before parsing the fields so that recursive types can reference themselves
+	asp.namedSchemas[fullName] = schema
+
+	return fullName, namespace, nil
+}
+
+func (asp *avroSchemaParser) getFullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func isAvroPrimitive(typeName string) bool {
+	switch typeName {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return true
+	}
+
+	return false
+}
+
+type avroReader struct {
+	buffer []byte
+	offset int
+}
+
+func (ar *avroReader) read(schema *avroSchema) (interface{}, error) {
+	switch schema.typeName {
+	case "null":
+		return nil, nil
+
+	case "boolean":
+		value, err := ar.readBytes(1)
+		if err != nil {
+			return nil, err
+		}
+
+		return value[0] != 0, nil
+
+	case "int", "long":
+		return ar.readLong()
+
+	case "float":
+		value, err := ar.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float32frombits(binary.LittleEndian.Uint32(value)), nil
+
+	case "double":
+		value, err := ar.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(binary.LittleEndian.Uint64(value)), nil
+
+	case "bytes":
+		return ar.readLengthPrefixed()
+
+	case "string":
+		value, err := ar.readLengthPrefixed()
+		if err != nil {
+			return nil, err
+		}
+
+		return string(value), nil
+
+	case "fixed":
+		return ar.readBytes(schema.size)
+
+	case "enum":
+		index, err := ar.readLong()
+		if err != nil {
+			return nil, err
+		}
+
+		if index < 0 || index >= int64(len(schema.symbols)) {
+			return nil, errors.Errorf("Enum index out of range: %d", index)
+		}
+
+		return schema.symbols[index], nil
+
+	case "union":
+		index, err := ar.readLong()
+		if err != nil {
+			return nil, err
+		}
+
+		if index < 0 || index >= int64(len(schema.branches)) {
+			return nil, errors.Errorf("Union index out of range: %d", index)
+		}
+
+		return ar.read(schema.branches[index])
+
+	case "record":
+		record := map[string]interface{}{}
+
+		for _, field := range schema.fields {
+			value, err := ar.read(field.schema)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to read field %s", field.name)
+			}
+
+			record[field.name] = value
+		}
+
+		return record, nil
+
+	case "array":
+		items := []interface{}{}
+
+		err := ar.readBlocks(func() error {
+			item, err := ar.read(schema.items)
+			if err != nil {
+				return err
+			}
+
+			items = append(items, item)
+			return nil
+		})
+
+		return items, err
+
+	case "map":
+		values := map[string]interface{}{}
+
+		err := ar.readBlocks(func() error {
+			key, err := ar.readLengthPrefixed()
+			if err != nil {
+				return err
+			}
+
+			value, err := ar.read(schema.items)
+			if err != nil {
+				return err
+			}
+
+			values[string(key)] = value
+			return nil
+		})
+
+		return values, err
+
+	default:
+		return nil, errors.Errorf("Unsupported type: %s", schema.typeName)
+	}
+}
+
+// readBlocks reads the blocks of an array or map, each prefixed with its item count. a negative count
+// is followed by the block size in bytes and a zero count terminates the sequence
+func (ar *avroReader) readBlocks(readItem func() error) error {
+	for {
+		count, err := ar.readLong()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return nil
+		}
+
+		if count < 0 {
+			count = -count
+
+			if _, err := ar.readLong(); err != nil {
+				return err
+			}
+		}
+
+		for itemIdx := int64(0); itemIdx < count; itemIdx++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ar *avroReader) readLong() (int64, error) {
+	value, numBytes := binary.Uvarint(ar.buffer[ar.offset:])
+	if numBytes <= 0 {
+		return 0, errors.New("Failed to read variable length integer")
+	}
+
+	ar.offset += numBytes
+
+	// zigzag decode
+	return int64(value>>1) ^ -int64(value&1), nil
+}
+
+func (ar *avroReader) readLengthPrefixed() ([]byte, error) {
+	length, err := ar.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if length < 0 {
+		return nil, errors.Errorf("Invalid length: %d", length)
+	}
+
+	return ar.readBytes(int(length))
+}
+
+func (ar *avroReader) readBytes(length int) ([]byte, error) {
+	if length > len(ar.buffer)-ar.offset {
+		return nil, errors.Errorf("Unexpected end of payload (needed %d bytes, %d remaining)",
+			length,
+			len(ar.buffer)-ar.offset)
+	}
+
+	value := ar.buffer[ar.offset : ar.offset+length]
+	ar.offset += length
+
+	return value, nil
+}
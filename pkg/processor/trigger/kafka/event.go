@@ -26,14 +26,26 @@ import (
 type Event struct {
 	nuclio.AbstractEvent
 	kafkaMessage *sarama.ConsumerMessage
+
+	// set when the message value was decoded (e.g. against a schema registry)
+	body        []byte
+	contentType string
 }
 
 func (e *Event) GetBody() []byte {
+	if e.body != nil {
+		return e.body
+	}
+
 	return e.kafkaMessage.Value
 }
 
 func (e *Event) GetSize() int {
-	return len(e.kafkaMessage.Value)
+	return len(e.GetBody())
+}
+
+func (e *Event) GetContentType() string {
+	return e.contentType
 }
 
 func (e *Event) GetShardID() int {
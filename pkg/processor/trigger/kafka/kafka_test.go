@@ -0,0 +1,521 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/util/partitionworker"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/Shopify/sarama"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type configurationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *configurationTestSuite) TestSASLMechanism() {
+	for _, testCase := range []struct {
+		name              string
+		sasl              map[string]interface{}
+		expectedMechanism string
+		expectedError     bool
+	}{
+		{
+			name:              "default",
+			sasl:              map[string]interface{}{"enable": true, "user": "u", "password": "p"},
+			expectedMechanism: sarama.SASLTypePlaintext,
+		},
+		{
+			name: "oauth",
+			sasl: map[string]interface{}{
+				"enable":    true,
+				"mechanism": sarama.SASLTypeOAuth,
+				"oauth": map[string]interface{}{
+					"tokenURL": "https://auth.example.com/token",
+					"clientID": "id",
+				},
+			},
+			expectedMechanism: sarama.SASLTypeOAuth,
+		},
+		{
+			name: "oauthMissingTokenURL",
+			sasl: map[string]interface{}{
+				"enable":    true,
+				"mechanism": sarama.SASLTypeOAuth,
+				"oauth":     map[string]interface{}{"clientID": "id"},
+			},
+			expectedError: true,
+		},
+		{
+			name:          "unsupported",
+			sasl:          map[string]interface{}{"enable": true, "mechanism": "GSSAPI"},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("test",
+				&functionconfig.Trigger{
+					URL: "broker:9092",
+					Attributes: map[string]interface{}{
+						"topics":        []string{"topic"},
+						"consumerGroup": "group",
+						"sasl":          testCase.sasl,
+					},
+				},
+				&runtime.Configuration{
+					Configuration: &processor.Configuration{},
+				})
+
+			if testCase.expectedError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedMechanism, configuration.SASL.Mechanism)
+		})
+	}
+}
+
+type oauthTestSuite struct {
+	suite.Suite
+}
+
+func (suite *oauthTestSuite) TestTokenIsCached() {
+	numTokenRequests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		numTokenRequests++
+
+		suite.Require().NoError(request.ParseForm())
+		suite.Require().Equal("client_credentials", request.Form.Get("grant_type"))
+
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.Write([]byte(`{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	configuration := Configuration{}
+	configuration.SASL.OAuth.TokenURL = server.URL
+	configuration.SASL.OAuth.ClientID = "id"
+	configuration.SASL.OAuth.ClientSecret = "secret"
+	configuration.SASL.OAuth.Extensions = map[string]string{"logicalCluster": "lkc-1"}
+
+	tokenProvider := newOAuthTokenProvider(&configuration)
+
+	for attemptIdx := 0; attemptIdx < 2; attemptIdx++ {
+		accessToken, err := tokenProvider.Token()
+		suite.Require().NoError(err)
+		suite.Require().Equal("token", accessToken.Token)
+		suite.Require().Equal("lkc-1", accessToken.Extensions["logicalCluster"])
+	}
+
+	suite.Require().Equal(1, numTokenRequests)
+}
+
+type schemaRegistryTestSuite struct {
+	suite.Suite
+	server            *httptest.Server
+	numSchemaRequests int
+	decoder           *schemaRegistryDecoder
+	registeredSchemas map[int]map[string]string
+}
+
+func (suite *schemaRegistryTestSuite) SetupTest() {
+	suite.numSchemaRequests = 0
+	suite.registeredSchemas = map[int]map[string]string{
+		1: {
+			"schema": `{
+				"type": "record",
+				"name": "User",
+				"namespace": "com.example",
+				"fields": [
+					{"name": "name", "type": "string"},
+					{"name": "age", "type": "int"},
+					{"name": "email", "type": ["null", "string"]},
+					{"name": "tags", "type": {"type": "array", "items": "string"}},
+					{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+					{"name": "next", "type": ["null", "com.example.User"]}
+				]
+			}`,
+		},
+		2: {"schema": `syntax = "proto3"; message Foo { string bar = 1; }`, "schemaType": "PROTOBUF"},
+		3: {"schema": `{"type": "object"}`, "schemaType": "JSON"},
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.numSchemaRequests++
+
+		user, password, _ := request.BasicAuth()
+		suite.Require().Equal("user", user)
+		suite.Require().Equal("password", password)
+
+		var schemaID int
+		if _, err := fmt.Sscanf(request.URL.Path, "/schemas/ids/%d", &schemaID); err != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		schema, found := suite.registeredSchemas[schemaID]
+		if !found {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(responseWriter).Encode(schema) // nolint: errcheck
+	}))
+
+	suite.decoder = newSchemaRegistryDecoder(suite.server.URL+"/", "user", "password")
+}
+
+func (suite *schemaRegistryTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *schemaRegistryTestSuite) TestDecodeAvro() {
+	var payload []byte
+	payload = appendAvroString(payload, "bob")
+	payload = appendAvroLong(payload, 30)
+	payload = appendAvroLong(payload, 1)
+	payload = appendAvroString(payload, "bob@example.com")
+	payload = appendAvroLong(payload, 2)
+	payload = appendAvroString(payload, "a")
+	payload = appendAvroString(payload, "b")
+	payload = appendAvroLong(payload, 0)
+	payload = appendAvroLong(payload, 1)
+
+	// a nested user with no next
+	payload = appendAvroLong(payload, 1)
+	payload = appendAvroString(payload, "alice")
+	payload = appendAvroLong(payload, -5)
+	payload = appendAvroLong(payload, 0)
+	payload = appendAvroLong(payload, 0)
+	payload = appendAvroLong(payload, 0)
+	payload = appendAvroLong(payload, 0)
+
+	// decode twice to verify the schema is only fetched once
+	for attemptIdx := 0; attemptIdx < 2; attemptIdx++ {
+		body, contentType, err := suite.decoder.Decode(suite.frame(1, payload))
+		suite.Require().NoError(err)
+		suite.Require().Equal("application/json", contentType)
+		suite.Require().JSONEq(`{
+			"name": "bob",
+			"age": 30,
+			"email": "bob@example.com",
+			"tags": ["a", "b"],
+			"kind": "B",
+			"next": {"name": "alice", "age": -5, "email": null, "tags": [], "kind": "A", "next": null}
+		}`, string(body))
+	}
+
+	suite.Require().Equal(1, suite.numSchemaRequests)
+
+	// truncated payload
+	_, _, err := suite.decoder.Decode(suite.frame(1, payload[:5]))
+	suite.Require().Error(err)
+}
+
+func (suite *schemaRegistryTestSuite) TestDecodeProtobuf() {
+
+	// message indexes [1, 0], followed by the serialized message
+	payload := appendAvroLong(nil, 2)
+	payload = appendAvroLong(payload, 1)
+	payload = appendAvroLong(payload, 0)
+	payload = append(payload, 0x0a, 0x01, 'x')
+
+	body, contentType, err := suite.decoder.Decode(suite.frame(2, payload))
+	suite.Require().NoError(err)
+	suite.Require().Equal("application/x-protobuf", contentType)
+	suite.Require().Equal([]byte{0x0a, 0x01, 'x'}, body)
+}
+
+func (suite *schemaRegistryTestSuite) TestDecodeJSON() {
+	body, contentType, err := suite.decoder.Decode(suite.frame(3, []byte(`{"a": 1}`)))
+	suite.Require().NoError(err)
+	suite.Require().Equal("application/json", contentType)
+	suite.Require().Equal(`{"a": 1}`, string(body))
+}
+
+func (suite *schemaRegistryTestSuite) TestDecodeInvalid() {
+
+	// not framed
+	_, _, err := suite.decoder.Decode([]byte(`{"a": 1}`))
+	suite.Require().Error(err)
+
+	// unknown schema
+	_, _, err = suite.decoder.Decode(suite.frame(100, []byte{}))
+	suite.Require().Error(err)
+}
+
+func (suite *schemaRegistryTestSuite) frame(schemaID uint32, payload []byte) []byte {
+	framedPayload := make([]byte, schemaRegistryHeaderLength)
+	binary.BigEndian.PutUint32(framedPayload[1:], schemaID)
+
+	return append(framedPayload, payload...)
+}
+
+func appendAvroLong(buffer []byte, value int64) []byte {
+	encodedValue := make([]byte, binary.MaxVarintLen64)
+	numBytes := binary.PutUvarint(encodedValue, uint64((value<<1)^(value>>63)))
+
+	return append(buffer, encodedValue[:numBytes]...)
+}
+
+func appendAvroString(buffer []byte, value string) []byte {
+	return append(appendAvroLong(buffer, int64(len(value))), value...)
+}
+
+// recordingRuntime records the bodies of the events it processes, failing those in failedEvents
+type recordingRuntime struct {
+	runtime.Runtime
+	bodies       []string
+	failedEvents map[int]bool
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	rr.bodies = append(rr.bodies, string(event.GetBody()))
+
+	if rr.failedEvents[len(rr.bodies)-1] {
+		return nil, errors.New("Failed to process event")
+	}
+
+	return nil, nil
+}
+
+func (rr *recordingRuntime) GetStatus() status.Status {
+	return status.Ready
+}
+
+type fakeConsumerGroupSession struct {
+	sarama.ConsumerGroupSession
+	lock          sync.Mutex
+	markedOffsets []int64
+}
+
+func (fcgs *fakeConsumerGroupSession) MarkMessage(message *sarama.ConsumerMessage, metadata string) {
+	fcgs.lock.Lock()
+	defer fcgs.lock.Unlock()
+
+	fcgs.markedOffsets = append(fcgs.markedOffsets, message.Offset)
+}
+
+func (fcgs *fakeConsumerGroupSession) getMarkedOffsets() []int64 {
+	fcgs.lock.Lock()
+	defer fcgs.lock.Unlock()
+
+	return append([]int64{}, fcgs.markedOffsets...)
+}
+
+type fakeConsumerGroupClaim struct {
+	sarama.ConsumerGroupClaim
+	messages            chan *sarama.ConsumerMessage
+	highWaterMarkOffset int64
+}
+
+func (fcgc *fakeConsumerGroupClaim) Topic() string {
+	return "topic"
+}
+
+func (fcgc *fakeConsumerGroupClaim) Partition() int32 {
+	return 3
+}
+
+func (fcgc *fakeConsumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return fcgc.messages
+}
+
+func (fcgc *fakeConsumerGroupClaim) HighWaterMarkOffset() int64 {
+	return fcgc.highWaterMarkOffset
+}
+
+func (fcgc *fakeConsumerGroupClaim) StopConsuming() <-chan struct{} {
+	return nil
+}
+
+type batchTestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	runtime *recordingRuntime
+	session *fakeConsumerGroupSession
+	claim   *fakeConsumerGroupClaim
+	trigger *kafka
+}
+
+func (suite *batchTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+
+	suite.runtime = &recordingRuntime{}
+	workerInstance, err := worker.NewWorker(suite.logger, 0, suite.runtime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewSingletonWorkerAllocator(suite.logger, workerInstance)
+	suite.Require().NoError(err)
+
+	configuration, err := NewConfiguration("test",
+		&functionconfig.Trigger{
+			URL:   "broker:9092",
+			Batch: &functionconfig.Batch{MaxSize: 2, MaxWaitMs: 50},
+			Attributes: map[string]interface{}{
+				"topics":        []string{"topic"},
+				"consumerGroup": "group",
+			},
+		},
+		&runtime.Configuration{
+			Configuration: &processor.Configuration{},
+		})
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*kafka)
+	suite.trigger.partitionWorkerAllocator, err = partitionworker.NewPooledWorkerAllocator(suite.logger,
+		workerAllocator)
+	suite.Require().NoError(err)
+
+	suite.session = &fakeConsumerGroupSession{}
+	suite.claim = &fakeConsumerGroupClaim{
+		messages: make(chan *sarama.ConsumerMessage, 10),
+	}
+}
+
+func (suite *batchTestSuite) TestBatchesDeliveredWhenFull() {
+	for offset := int64(0); offset < 4; offset++ {
+		suite.claim.messages <- suite.newMessage(offset)
+	}
+
+	close(suite.claim.messages)
+
+	err := suite.trigger.ConsumeClaim(suite.session, suite.claim)
+	suite.Require().NoError(err)
+
+	suite.Require().Len(suite.runtime.bodies, 2)
+	suite.Require().Equal([]trigger.BatchRecord{
+		{Body: "message-0", ShardID: 3, Offset: 0, Headers: map[string]string{"key": "value-0"}},
+		{Body: "message-1", ShardID: 3, Offset: 1, Headers: map[string]string{"key": "value-1"}},
+	}, suite.decodeBatch(suite.runtime.bodies[0]))
+
+	// each batch is acknowledged by marking its last message
+	suite.Require().Equal([]int64{1, 3}, suite.session.getMarkedOffsets())
+}
+
+func (suite *batchTestSuite) TestPartialBatchDeliveredAfterMaxWait() {
+	suite.claim.messages <- suite.newMessage(0)
+
+	consumeDone := make(chan error)
+	go func() {
+		consumeDone <- suite.trigger.ConsumeClaim(suite.session, suite.claim)
+	}()
+
+	// the batch is delivered without waiting for the claim to end
+	suite.Require().Eventually(func() bool {
+		return len(suite.session.getMarkedOffsets()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(suite.claim.messages)
+	suite.Require().NoError(<-consumeDone)
+
+	suite.Require().Len(suite.runtime.bodies, 1)
+	suite.Require().Len(suite.decodeBatch(suite.runtime.bodies[0]), 1)
+}
+
+func (suite *batchTestSuite) TestFailedBatchNotAcknowledged() {
+	suite.runtime.failedEvents = map[int]bool{1: true}
+
+	for offset := int64(0); offset < 5; offset++ {
+		suite.claim.messages <- suite.newMessage(offset)
+	}
+
+	close(suite.claim.messages)
+
+	err := suite.trigger.ConsumeClaim(suite.session, suite.claim)
+	suite.Require().NoError(err)
+
+	// the last batch is delivered once the claim ends, even if not full
+	suite.Require().Len(suite.runtime.bodies, 3)
+	suite.Require().Equal([]int64{1, 4}, suite.session.getMarkedOffsets())
+}
+
+func (suite *batchTestSuite) TestConsumerLagReported() {
+	suite.claim.highWaterMarkOffset = 10
+	suite.claim.messages <- suite.newMessage(0)
+
+	consumeDone := make(chan error)
+	go func() {
+		consumeDone <- suite.trigger.ConsumeClaim(suite.session, suite.claim)
+	}()
+
+	suite.Require().Eventually(func() bool {
+		return len(suite.session.getMarkedOffsets()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// offsets 1 to 9 are yet to be consumed
+	suite.Require().Equal(uint64(9), suite.trigger.GetStatistics().ConsumerLag)
+
+	close(suite.claim.messages)
+	suite.Require().NoError(<-consumeDone)
+
+	// the lag of a claim which is no longer consumed isn't reported
+	suite.Require().Equal(uint64(0), suite.trigger.GetStatistics().ConsumerLag)
+}
+
+func (suite *batchTestSuite) newMessage(offset int64) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic:     "topic",
+		Partition: 3,
+		Offset:    offset,
+		Value:     []byte(fmt.Sprintf("message-%d", offset)),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("key"), Value: []byte(fmt.Sprintf("value-%d", offset))},
+		},
+	}
+}
+
+func (suite *batchTestSuite) decodeBatch(body string) []trigger.BatchRecord {
+	var records []trigger.BatchRecord
+
+	err := json.Unmarshal([]byte(body), &records)
+	suite.Require().NoError(err)
+
+	return records
+}
+
+func TestKafkaSuite(t *testing.T) {
+	suite.Run(t, new(configurationTestSuite))
+	suite.Run(t, new(oauthTestSuite))
+	suite.Run(t, new(schemaRegistryTestSuite))
+	suite.Run(t, new(batchTestSuite))
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/nuclio/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const oauthTokenRequestTimeout = 10 * time.Second
+
+// oauthTokenProvider provides SASL/OAUTHBEARER tokens using the OAuth client credentials flow. tokens
+// are cached and only refreshed when they are about to expire
+type oauthTokenProvider struct {
+	tokenSource oauth2.TokenSource
+	extensions  map[string]string
+}
+
+func newOAuthTokenProvider(configuration *Configuration) sarama.AccessTokenProvider {
+	clientCredentialsConfig := clientcredentials.Config{
+		ClientID:     configuration.SASL.OAuth.ClientID,
+		ClientSecret: configuration.SASL.OAuth.ClientSecret,
+		TokenURL:     configuration.SASL.OAuth.TokenURL,
+		Scopes:       configuration.SASL.OAuth.Scopes,
+	}
+
+	// sarama requires that token retrieval never blocks indefinitely
+	tokenContext := context.WithValue(context.Background(),
+		oauth2.HTTPClient,
+		&http.Client{Timeout: oauthTokenRequestTimeout})
+
+	return &oauthTokenProvider{
+		tokenSource: clientCredentialsConfig.TokenSource(tokenContext),
+		extensions:  configuration.SASL.OAuth.Extensions,
+	}
+}
+
+func (otp *oauthTokenProvider) Token() (*sarama.AccessToken, error) {
+	token, err := otp.tokenSource.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get OAuth token")
+	}
+
+	return &sarama.AccessToken{
+		Token:      token.AccessToken,
+		Extensions: otp.extensions,
+	}, nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	schemaRegistryMagicByte      = 0
+	schemaRegistryHeaderLength   = 5
+	schemaRegistryRequestTimeout = 10 * time.Second
+
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+)
+
+type registeredSchema struct {
+	schemaType string
+	avroSchema *avroSchema
+}
+
+// schemaRegistryDecoder decodes messages serialized in the confluent schema registry wire format
+// (a zero magic byte, followed by a big endian 4 byte schema ID and the serialized payload)
+type schemaRegistryDecoder struct {
+	url        string
+	user       string
+	password   string
+	httpClient *http.Client
+
+	schemasLock sync.Mutex
+	schemas     map[uint32]*registeredSchema
+}
+
+func newSchemaRegistryDecoder(url string, user string, password string) *schemaRegistryDecoder {
+	return &schemaRegistryDecoder{
+		url:        strings.TrimSuffix(url, "/"),
+		user:       user,
+		password:   password,
+		httpClient: &http.Client{Timeout: schemaRegistryRequestTimeout},
+		schemas:    map[uint32]*registeredSchema{},
+	}
+}
+
+// Decode returns the decoded payload and its content type. avro payloads are decoded to JSON, JSON
+// payloads are returned as is and protobuf payloads are stripped of their framing (message indexes)
+func (srd *schemaRegistryDecoder) Decode(payload []byte) ([]byte, string, error) {
+	if len(payload) < schemaRegistryHeaderLength || payload[0] != schemaRegistryMagicByte {
+		return nil, "", errors.New("Payload is not in schema registry wire format")
+	}
+
+	schemaID := binary.BigEndian.Uint32(payload[1:schemaRegistryHeaderLength])
+	payload = payload[schemaRegistryHeaderLength:]
+
+	schema, err := srd.getSchema(schemaID)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Failed to get schema %d", schemaID)
+	}
+
+	switch schema.schemaType {
+	case schemaTypeAvro:
+		value, err := schema.avroSchema.Decode(payload)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "Failed to decode avro payload")
+		}
+
+		body, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "Failed to encode decoded avro payload")
+		}
+
+		return body, "application/json", nil
+
+	case schemaTypeJSON:
+		return payload, "application/json", nil
+
+	case schemaTypeProtobuf:
+		body, err := skipProtobufMessageIndexes(payload)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "Failed to read protobuf message indexes")
+		}
+
+		return body, "application/x-protobuf", nil
+
+	default:
+		return nil, "", errors.Errorf("Unsupported schema type: %s", schema.schemaType)
+	}
+}
+
+func (srd *schemaRegistryDecoder) getSchema(schemaID uint32) (*registeredSchema, error) {
+	srd.schemasLock.Lock()
+	defer srd.schemasLock.Unlock()
+
+	// schemas are immutable once registered, so they can be cached indefinitely
+	if schema, found := srd.schemas[schemaID]; found {
+		return schema, nil
+	}
+
+	schema, err := srd.fetchSchema(schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	srd.schemas[schemaID] = schema
+
+	return schema, nil
+}
+
+func (srd *schemaRegistryDecoder) fetchSchema(schemaID uint32) (*registeredSchema, error) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", srd.url, schemaID), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create schema request")
+	}
+
+	request.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+
+	if srd.user != "" {
+		request.SetBasicAuth(srd.user, srd.password)
+	}
+
+	response, err := srd.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to request schema")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read schema response")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Schema registry responded with %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	schemaResponse := struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType,omitempty"`
+	}{}
+
+	if err := json.Unmarshal(responseBody, &schemaResponse); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal schema response")
+	}
+
+	// the registry omits the schema type for avro schemas
+	schema := registeredSchema{
+		schemaType: schemaResponse.SchemaType,
+	}
+
+	if schema.schemaType == "" {
+		schema.schemaType = schemaTypeAvro
+	}
+
+	if schema.schemaType == schemaTypeAvro {
+		schema.avroSchema, err = parseAvroSchema(schemaResponse.Schema)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse avro schema")
+		}
+	}
+
+	return &schema, nil
+}
+
+// skipProtobufMessageIndexes skips the message indexes that identify the message type within the
+// schema. the indexes are a zigzag encoded count, followed by that many zigzag encoded indexes
+func skipProtobufMessageIndexes(payload []byte) ([]byte, error) {
+	reader := avroReader{buffer: payload}
+
+	numIndexes, err := reader.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if numIndexes < 0 {
+		return nil, errors.Errorf("Invalid number of message indexes: %d", numIndexes)
+	}
+
+	for indexIdx := int64(0); indexIdx < numIndexes; indexIdx++ {
+		if _, err := reader.readLong(); err != nil {
+			return nil, err
+		}
+	}
+
+	return reader.buffer[reader.offset:], nil
+}
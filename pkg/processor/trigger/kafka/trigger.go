@@ -18,6 +18,9 @@ package kafka
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -29,11 +32,12 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/rcrowley/go-metrics"
 )
 
 type submittedEvent struct {
-	event  Event
+	event  nuclio.Event
 	worker *worker.Worker
 	done   chan error
 }
@@ -43,9 +47,16 @@ type kafka struct {
 	configuration            *Configuration
 	kafkaConfig              *sarama.Config
 	consumerGroup            sarama.ConsumerGroup
-	shutdownSignal           chan struct{}
+	cancelConsumption        context.CancelFunc
+	consumptionStopped       chan struct{}
+	stopping                 int32
 	stopConsumptionChan      chan struct{}
 	partitionWorkerAllocator partitionworker.Allocator
+	schemaRegistryDecoder    *schemaRegistryDecoder
+
+	// the lag of each claim (topic/partition) the trigger consumes, reported as the trigger's consumer lag
+	claimLags     map[string]int64
+	claimLagsLock sync.Mutex
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -63,6 +74,7 @@ func newTrigger(parentLogger logger.Logger,
 	newTrigger := &kafka{
 		configuration:       configuration,
 		stopConsumptionChan: make(chan struct{}, 1),
+		claimLags:           map[string]int64{},
 	}
 
 	newTrigger.AbstractTrigger, err = trigger.NewAbstractTrigger(loggerInstance,
@@ -89,7 +101,16 @@ func newTrigger(parentLogger logger.Logger,
 		"fetchDefault", configuration.FetchDefault,
 		"fetchMax", configuration.FetchMax,
 		"channelBufferSize", configuration.ChannelBufferSize,
-		"maxWaitHandlerDuringRebalance", configuration.maxWaitHandlerDuringRebalance)
+		"saslMechanism", configuration.SASL.Mechanism,
+		"schemaRegistryURL", configuration.SchemaRegistry.URL,
+		"maxWaitHandlerDuringRebalance", configuration.maxWaitHandlerDuringRebalance,
+		"batchMaxSize", configuration.GetBatchMaxSize())
+
+	if configuration.SchemaRegistry.URL != "" {
+		newTrigger.schemaRegistryDecoder = newSchemaRegistryDecoder(configuration.SchemaRegistry.URL,
+			configuration.SchemaRegistry.User,
+			configuration.SchemaRegistry.Password)
+	}
 
 	newTrigger.kafkaConfig, err = newTrigger.newKafkaConfig()
 	if err != nil {
@@ -113,15 +134,26 @@ func (k *kafka) Start(checkpoint functionconfig.Checkpoint) error {
 		return errors.Wrap(err, "Failed to create consumer")
 	}
 
-	k.shutdownSignal = make(chan struct{}, 1)
+	consumptionContext, cancelConsumption := context.WithCancel(context.Background())
+	k.cancelConsumption = cancelConsumption
+	k.consumptionStopped = make(chan struct{})
 
 	// start consumption in the background
 	go func() {
+		defer close(k.consumptionStopped)
+
 		for {
 			k.Logger.DebugWith("Starting to consume from broker", "topics", k.configuration.Topics)
 
 			// start consuming. this will exit without error if a rebalancing occurs
-			err = k.consumerGroup.Consume(context.Background(), k.configuration.Topics, k)
+			err := k.consumerGroup.Consume(consumptionContext, k.configuration.Topics, k)
+
+			// the trigger was stopped. the session ended, committing the offsets of the handled messages
+			if consumptionContext.Err() != nil {
+				k.Logger.DebugWith("Consumption stopped", "topics", k.configuration.Topics)
+
+				return
+			}
 
 			if err != nil {
 				k.Logger.WarnWith("Failed to consume from group, waiting before retrying", "err", errors.GetErrorStackString(err, 10))
@@ -137,8 +169,13 @@ func (k *kafka) Start(checkpoint functionconfig.Checkpoint) error {
 }
 
 func (k *kafka) Stop(force bool) (functionconfig.Checkpoint, error) {
-	k.shutdownSignal <- struct{}{}
-	close(k.shutdownSignal)
+
+	// end the session, waiting for the handlers of the claims to return
+	atomic.StoreInt32(&k.stopping, 1)
+	if k.cancelConsumption != nil {
+		k.cancelConsumption()
+		<-k.consumptionStopped
+	}
 
 	err := k.consumerGroup.Close()
 	if err != nil {
@@ -184,9 +221,6 @@ func (k *kafka) Cleanup(session sarama.ConsumerGroupSession) error {
 func (k *kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	var submitError error
 
-	// cleared when the consumption should stop
-	consumeMessages := true
-
 	submittedEventInstance := submittedEvent{
 		done: make(chan error),
 	}
@@ -196,6 +230,33 @@ func (k *kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.C
 	// submit the events in a goroutine so that we can unblock immediately
 	go k.eventSubmitter(claim, submittedEventChan)
 
+	if k.configuration.Batch != nil {
+		submitError = k.consumeClaimInBatches(session, claim, submittedEventChan, &submittedEventInstance)
+	} else {
+		submitError = k.consumeClaimMessages(session, claim, submittedEventChan, &submittedEventInstance)
+	}
+
+	k.Logger.DebugWith("Claim consumption stopped", "partition", claim.Partition())
+
+	// the claim's lag is now another member's to report
+	k.setClaimLag(claim, -1)
+
+	// shut down the event submitter
+	close(submittedEventChan)
+
+	return submitError
+}
+
+func (k *kafka) consumeClaimMessages(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent) error {
+	var err error
+	var event Event
+
+	// cleared when the consumption should stop
+	consumeMessages := true
+
 	// the exit condition is that (a) the Messages() channel was closed and (b) we got a signal telling us
 	// to stop consumption
 	for message := range claim.Messages() {
@@ -205,72 +266,184 @@ func (k *kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.C
 			break
 		}
 
-		// allocate a worker for this topic/partition
-		workerInstance, cookie, err := k.partitionWorkerAllocator.AllocateWorker(claim.Topic(),
-			int(claim.Partition()),
-			nil)
+		event.kafkaMessage = message
+		k.decodeMessage(&event)
+
+		consumeMessages, err = k.submitEvent(session, claim, submittedEventChan, submittedEventInstance, &event, message)
 		if err != nil {
-			return errors.Wrap(err, "Failed to allocate worker")
+			return err
 		}
+	}
 
-		submittedEventInstance.event.kafkaMessage = message
-		submittedEventInstance.worker = workerInstance
+	return nil
+}
+
+// consumeClaimInBatches aggregates messages into a batch event, submitted once it holds the maximum number of
+// messages or the maximum wait passed since its first message was read
+func (k *kafka) consumeClaimInBatches(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent) error {
+	var event Event
+	var lastMessage *sarama.ConsumerMessage
+	var batchTimeout <-chan time.Time
 
-		// handle in the goroutine so we don't block
-		submittedEventChan <- &submittedEventInstance
+	batchEvent := trigger.NewBatchEvent(claim.Topic(), int(claim.Partition()))
+	messages := claim.Messages()
 
-		// wait for handling done or indication to stop
+	for {
 		select {
-		case err := <-submittedEventInstance.done:
+		case message, ok := <-messages:
+			if ok {
+				event.kafkaMessage = message
+				k.decodeMessage(&event)
+
+				batchEvent.Add(&event)
+				lastMessage = message
+
+				// start waiting for the batch to fill up
+				if batchEvent.Len() == 1 {
+					batchTimeout = time.After(k.configuration.GetBatchMaxWait())
+				}
 
-			// we successfully submitted the message to the handler. mark it
-			if err == nil {
-				session.MarkMessage(message, "")
+				if batchEvent.Len() < k.configuration.Batch.MaxSize {
+					continue
+				}
+			} else if batchEvent.Len() == 0 {
+				return nil
 			}
 
-		case <-claim.StopConsuming():
-			k.Logger.DebugWith("Got signal to stop consumption",
-				"wait", k.configuration.maxWaitHandlerDuringRebalance,
-				"partition", claim.Partition())
+		case <-batchTimeout:
+		}
 
-			// don't consume any more messages
-			consumeMessages = false
+		consumeMessages, err := k.submitEvent(session,
+			claim,
+			submittedEventChan,
+			submittedEventInstance,
+			batchEvent,
+			lastMessage)
+		if err != nil {
+			return err
+		}
 
-			// wait a bit more for event to process
-			select {
-			case <-submittedEventInstance.done:
-				k.Logger.DebugWith("Handler done, rebalancing will commence")
+		batchEvent.Reset()
+		batchTimeout = nil
 
-			case <-time.After(k.configuration.maxWaitHandlerDuringRebalance):
-				k.Logger.DebugWith("Timed out waiting for handler to complete", "partition", claim.Partition())
+		if !consumeMessages {
+			k.Logger.DebugWith("Stopping message consumption", "partition", claim.Partition())
 
-				// mark this as a failure, metric-wise
-				k.UpdateStatistics(false)
+			return nil
+		}
+	}
+}
 
-				// restart the worker, and having failed that shut down
-				if err := k.cancelEventHandling(workerInstance, claim); err != nil {
-					k.Logger.DebugWith("Failed to cancel event handling",
-						"err", err.Error(),
-						"partition", claim.Partition())
+// submitEvent submits an event (a message, or a batch of them) to a worker and waits for it to be handled,
+// marking the last message as consumed if it was. returns false if the consumption should stop
+func (k *kafka) submitEvent(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent,
+	event nuclio.Event,
+	lastMessage *sarama.ConsumerMessage) (bool, error) {
+	consumeMessages := true
 
-					panic("Failed to cancel event handling")
-				}
-			}
+	// allocate a worker for this topic/partition
+	workerInstance, cookie, err := k.partitionWorkerAllocator.AllocateWorker(claim.Topic(),
+		int(claim.Partition()),
+		nil)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to allocate worker")
+	}
+
+	submittedEventInstance.event = event
+	submittedEventInstance.worker = workerInstance
+
+	// the messages after the last one submitted are yet to be consumed
+	k.setClaimLag(claim, claim.HighWaterMarkOffset()-lastMessage.Offset-1)
+
+	// handle in the goroutine so we don't block
+	submittedEventChan <- submittedEventInstance
+
+	// wait for handling done or indication to stop
+	select {
+	case err := <-submittedEventInstance.done:
+
+		// we successfully submitted the message to the handler. mark it (and with it, the messages before it)
+		if err == nil {
+			session.MarkMessage(lastMessage, "")
 		}
 
-		// release the worker from whence it came
-		err = k.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance)
-		if err != nil {
-			return errors.Wrap(err, "Failed to release worker")
+	case <-claim.StopConsuming():
+		maxWaitHandler := k.configuration.maxWaitHandlerDuringRebalance
+
+		// when the trigger is stopped rather than rebalanced (e.g. the replica is draining), the handler may
+		// take as long as the function's termination grace period
+		if atomic.LoadInt32(&k.stopping) == 1 {
+			maxWaitHandler = k.configuration.RuntimeConfiguration.Spec.GetTerminationGracePeriod()
+		}
+
+		k.Logger.DebugWith("Got signal to stop consumption",
+			"wait", maxWaitHandler,
+			"partition", claim.Partition())
+
+		// don't consume any more messages
+		consumeMessages = false
+
+		// wait a bit more for event to process
+		select {
+		case <-submittedEventInstance.done:
+			k.Logger.DebugWith("Handler done, rebalancing will commence")
+
+		case <-time.After(maxWaitHandler):
+			k.Logger.DebugWith("Timed out waiting for handler to complete", "partition", claim.Partition())
+
+			// mark this as a failure, metric-wise
+			k.UpdateStatistics(false)
+
+			// restart the worker, and having failed that shut down
+			if err := k.cancelEventHandling(workerInstance, claim); err != nil {
+				k.Logger.DebugWith("Failed to cancel event handling",
+					"err", err.Error(),
+					"partition", claim.Partition())
+
+				panic("Failed to cancel event handling")
+			}
 		}
 	}
 
-	k.Logger.DebugWith("Claim consumption stopped", "partition", claim.Partition())
+	// release the worker from whence it came
+	if err := k.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance); err != nil {
+		return false, errors.Wrap(err, "Failed to release worker")
+	}
 
-	// shut down the event submitter
-	close(submittedEventChan)
+	return consumeMessages, nil
+}
 
-	return submitError
+// decodeMessage decodes the payload against the schema registry, if configured. messages that can't be decoded
+// are passed to the handler as is, so as not to block the partition
+func (k *kafka) decodeMessage(event *Event) {
+	event.body = nil
+	event.contentType = ""
+
+	if k.schemaRegistryDecoder == nil {
+		return
+	}
+
+	message := event.kafkaMessage
+
+	body, contentType, err := k.schemaRegistryDecoder.Decode(message.Value)
+	if err != nil {
+		k.Logger.WarnWith("Failed to decode message using schema registry, passing raw value",
+			"topic", message.Topic,
+			"partition", message.Partition,
+			"offset", message.Offset,
+			"err", err.Error())
+
+		return
+	}
+
+	event.body = body
+	event.contentType = contentType
 }
 
 func (k *kafka) eventSubmitter(claim sarama.ConsumerGroupClaim, submittedEventChan chan *submittedEvent) {
@@ -282,10 +455,10 @@ func (k *kafka) eventSubmitter(claim sarama.ConsumerGroupClaim, submittedEventCh
 	for submittedEvent := range submittedEventChan {
 
 		// submit the event to the worker
-		_, processErr := k.SubmitEventToWorker(nil, submittedEvent.worker, &submittedEvent.event) // nolint: errcheck
+		_, processErr := k.SubmitEventToWorker(nil, submittedEvent.worker, submittedEvent.event) // nolint: errcheck
 		if processErr != nil {
 			k.Logger.DebugWith("Process error",
-				"partition", submittedEvent.event.kafkaMessage.Partition,
+				"partition", submittedEvent.event.GetShardID(),
 				"err", processErr)
 		}
 
@@ -312,6 +485,10 @@ func (k *kafka) newKafkaConfig() (*sarama.Config, error) {
 	config.Net.SASL.Enable = k.configuration.SASL.Enable
 	config.Net.SASL.User = k.configuration.SASL.User
 	config.Net.SASL.Password = k.configuration.SASL.Password
+	config.Net.SASL.Mechanism = sarama.SASLMechanism(k.configuration.SASL.Mechanism)
+	if config.Net.SASL.Mechanism == sarama.SASLTypeOAuth {
+		config.Net.SASL.TokenProvider = newOAuthTokenProvider(k.configuration)
+	}
 	config.ClientID = k.ID
 	config.Consumer.Offsets.Initial = k.configuration.initialOffset
 	config.Consumer.Offsets.AutoCommit.Enable = true
@@ -367,3 +544,25 @@ func (k *kafka) createPartitionWorkerAllocator(session sarama.ConsumerGroupSessi
 		return nil, errors.Errorf("Unknown worker allocation mode: %s", k.configuration.WorkerAllocationMode)
 	}
 }
+
+// setClaimLag sets the lag of a claim (removing it if the lag is negative) and reports the total lag of the
+// claims the trigger consumes
+func (k *kafka) setClaimLag(claim sarama.ConsumerGroupClaim, lag int64) {
+	claimKey := fmt.Sprintf("%s/%d", claim.Topic(), claim.Partition())
+
+	k.claimLagsLock.Lock()
+	defer k.claimLagsLock.Unlock()
+
+	if lag < 0 {
+		delete(k.claimLags, claimKey)
+	} else {
+		k.claimLags[claimKey] = lag
+	}
+
+	var consumerLag uint64
+	for _, claimLag := range k.claimLags {
+		consumerLag += uint64(claimLag)
+	}
+
+	atomic.StoreUint64(&k.Statistics.ConsumerLag, consumerLag)
+}
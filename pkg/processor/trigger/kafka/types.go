@@ -38,7 +38,20 @@ type Configuration struct {
 	ConsumerGroup string
 	InitialOffset string
 	SASL          struct {
-		Enable   bool
+		Enable    bool
+		Mechanism string
+		User      string
+		Password  string
+		OAuth     struct {
+			TokenURL     string
+			ClientID     string
+			ClientSecret string
+			Scopes       []string
+			Extensions   map[string]string
+		}
+	}
+	SchemaRegistry struct {
+		URL      string
 		User     string
 		Password string
 	}
@@ -130,6 +143,10 @@ func NewConfiguration(ID string,
 		return nil, errors.Wrap(err, "Failed to resolve brokers")
 	}
 
+	if err := newConfiguration.validateSASL(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate SASL configuration")
+	}
+
 	for _, durationConfigField := range []trigger.DurationConfigField{
 		{
 			Name:    "session timeout",
@@ -232,3 +249,33 @@ func (c *Configuration) resolveBrokers(brokers []string) ([]string, error) {
 
 	return nil, errors.New("Brokers must be passed either in url or attributes.brokers")
 }
+
+func (c *Configuration) validateSASL() error {
+	if !c.SASL.Enable {
+		return nil
+	}
+
+	switch c.SASL.Mechanism {
+	case "":
+		c.SASL.Mechanism = sarama.SASLTypePlaintext
+
+	case sarama.SASLTypePlaintext:
+
+	case sarama.SASLTypeOAuth:
+		if c.SASL.OAuth.TokenURL == "" {
+			return errors.New("OAuth token URL must be set when using the OAUTHBEARER mechanism")
+		}
+
+		if c.SASL.OAuth.ClientID == "" {
+			return errors.New("OAuth client ID must be set when using the OAUTHBEARER mechanism")
+		}
+
+	default:
+		return errors.Errorf("Unsupported SASL mechanism: %s (must be either %s or %s)",
+			c.SASL.Mechanism,
+			sarama.SASLTypePlaintext,
+			sarama.SASLTypeOAuth)
+	}
+
+	return nil
+}
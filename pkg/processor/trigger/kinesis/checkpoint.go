@@ -0,0 +1,322 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/nuclio/errors"
+)
+
+// shardEndCheckpoint is checkpointed for shards which were read to their end - closed by a split or a merge,
+// after which their children are read
+const shardEndCheckpoint = "SHARD_END"
+
+// the attributes of the items of the checkpoint table. the table's key is leaseGroup (partition) and
+// shardID (sort), both strings
+const (
+	leaseGroupAttribute     = "leaseGroup"
+	shardIDAttribute        = "shardID"
+	ownerAttribute          = "owner"
+	leaseExpiresAtAttribute = "leaseExpiresAt"
+	checkpointAttribute     = "checkpoint"
+)
+
+// errLeaseNotTaken is returned when the lease of a shard is held by another owner
+var errLeaseNotTaken = errors.New("Lease is held by another owner")
+
+type lease struct {
+	shardID    string
+	owner      string
+	expiresAt  time.Time
+	checkpoint string
+}
+
+func (l *lease) isHeld(now time.Time) bool {
+	return l.owner != "" && l.expiresAt.After(now)
+}
+
+// checkpointStore keeps who reads each shard of the stream (leases), and up to where it was read (checkpoints)
+type checkpointStore interface {
+
+	// getLeases returns the leases of the shards which were leased or checkpointed, by shard ID
+	getLeases() (map[string]*lease, error)
+
+	// takeLease takes the lease of a shard for owner until expiresAt, if it's free, expired, already held by
+	// owner or held by previousOwner (when stealing it). returns errLeaseNotTaken otherwise
+	takeLease(shardID string, owner string, previousOwner string, expiresAt time.Time) (*lease, error)
+
+	// releaseLease releases the lease of a shard held by owner
+	releaseLease(shardID string, owner string) error
+
+	// checkpoint records that the shard was read up to (and including) the given sequence number, as long
+	// as owner still holds its lease
+	checkpoint(shardID string, owner string, sequenceNumber string) error
+}
+
+// memoryCheckpointStore keeps leases and checkpoints in memory, for a single replica
+type memoryCheckpointStore struct {
+	lock   sync.Mutex
+	leases map[string]*lease
+}
+
+func newMemoryCheckpointStore() *memoryCheckpointStore {
+	return &memoryCheckpointStore{
+		leases: map[string]*lease{},
+	}
+}
+
+func (mcs *memoryCheckpointStore) getLeases() (map[string]*lease, error) {
+	mcs.lock.Lock()
+	defer mcs.lock.Unlock()
+
+	leases := map[string]*lease{}
+	for shardID, shardLease := range mcs.leases {
+		leaseCopy := *shardLease
+		leases[shardID] = &leaseCopy
+	}
+
+	return leases, nil
+}
+
+func (mcs *memoryCheckpointStore) takeLease(shardID string,
+	owner string,
+	previousOwner string,
+	expiresAt time.Time) (*lease, error) {
+	mcs.lock.Lock()
+	defer mcs.lock.Unlock()
+
+	shardLease, found := mcs.leases[shardID]
+	if !found {
+		shardLease = &lease{shardID: shardID}
+		mcs.leases[shardID] = shardLease
+	}
+
+	if shardLease.isHeld(time.Now()) && shardLease.owner != owner && shardLease.owner != previousOwner {
+		return nil, errLeaseNotTaken
+	}
+
+	shardLease.owner = owner
+	shardLease.expiresAt = expiresAt
+
+	leaseCopy := *shardLease
+	return &leaseCopy, nil
+}
+
+func (mcs *memoryCheckpointStore) releaseLease(shardID string, owner string) error {
+	mcs.lock.Lock()
+	defer mcs.lock.Unlock()
+
+	if shardLease, found := mcs.leases[shardID]; found && shardLease.owner == owner {
+		shardLease.owner = ""
+		shardLease.expiresAt = time.Time{}
+	}
+
+	return nil
+}
+
+func (mcs *memoryCheckpointStore) checkpoint(shardID string, owner string, sequenceNumber string) error {
+	mcs.lock.Lock()
+	defer mcs.lock.Unlock()
+
+	shardLease, found := mcs.leases[shardID]
+	if !found || shardLease.owner != owner {
+		return errLeaseNotTaken
+	}
+
+	shardLease.checkpoint = sequenceNumber
+
+	return nil
+}
+
+// dynamoDBCheckpointStore keeps leases and checkpoints in a DynamoDB table, shared by the function's replicas.
+// leases are taken and checkpoints written with conditional updates, so that only the owner of a lease
+// checkpoints its shard
+type dynamoDBCheckpointStore struct {
+	client     dynamodbiface.DynamoDBAPI
+	tableName  string
+	leaseGroup string
+}
+
+func newDynamoDBCheckpointStore(client dynamodbiface.DynamoDBAPI,
+	tableName string,
+	leaseGroup string) *dynamoDBCheckpointStore {
+	return &dynamoDBCheckpointStore{
+		client:     client,
+		tableName:  tableName,
+		leaseGroup: leaseGroup,
+	}
+}
+
+func (dcs *dynamoDBCheckpointStore) getLeases() (map[string]*lease, error) {
+	leases := map[string]*lease{}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(dcs.tableName),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("#leaseGroup = :leaseGroup"),
+		ExpressionAttributeNames: map[string]*string{
+			"#leaseGroup": aws.String(leaseGroupAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":leaseGroup": {S: aws.String(dcs.leaseGroup)},
+		},
+	}
+
+	err := dcs.client.QueryPages(queryInput, func(queryOutput *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range queryOutput.Items {
+			shardLease := dcs.itemToLease(item)
+			leases[shardLease.shardID] = shardLease
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to query leases from table %s", dcs.tableName)
+	}
+
+	return leases, nil
+}
+
+func (dcs *dynamoDBCheckpointStore) takeLease(shardID string,
+	owner string,
+	previousOwner string,
+	expiresAt time.Time) (*lease, error) {
+	conditionExpression := "attribute_not_exists(#owner) OR #owner = :owner OR #leaseExpiresAt < :now"
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":owner":          {S: aws.String(owner)},
+		":leaseExpiresAt": {N: aws.String(formatTime(expiresAt))},
+		":now":            {N: aws.String(formatTime(time.Now()))},
+	}
+
+	if previousOwner != "" {
+		conditionExpression += " OR #owner = :previousOwner"
+		expressionAttributeValues[":previousOwner"] = &dynamodb.AttributeValue{S: aws.String(previousOwner)}
+	}
+
+	updateItemOutput, err := dcs.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(dcs.tableName),
+		Key:                 dcs.getKey(shardID),
+		UpdateExpression:    aws.String("SET #owner = :owner, #leaseExpiresAt = :leaseExpiresAt"),
+		ConditionExpression: aws.String(conditionExpression),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":          aws.String(ownerAttribute),
+			"#leaseExpiresAt": aws.String(leaseExpiresAtAttribute),
+		},
+		ExpressionAttributeValues: expressionAttributeValues,
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if isAWSErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+			return nil, errLeaseNotTaken
+		}
+
+		return nil, errors.Wrapf(err, "Failed to take the lease of shard %s", shardID)
+	}
+
+	return dcs.itemToLease(updateItemOutput.Attributes), nil
+}
+
+func (dcs *dynamoDBCheckpointStore) releaseLease(shardID string, owner string) error {
+	_, err := dcs.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(dcs.tableName),
+		Key:                 dcs.getKey(shardID),
+		UpdateExpression:    aws.String("REMOVE #owner, #leaseExpiresAt"),
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":          aws.String(ownerAttribute),
+			"#leaseExpiresAt": aws.String(leaseExpiresAtAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+	})
+
+	// it was taken over already
+	if err != nil && !isAWSErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		return errors.Wrapf(err, "Failed to release the lease of shard %s", shardID)
+	}
+
+	return nil
+}
+
+func (dcs *dynamoDBCheckpointStore) checkpoint(shardID string, owner string, sequenceNumber string) error {
+	_, err := dcs.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(dcs.tableName),
+		Key:                 dcs.getKey(shardID),
+		UpdateExpression:    aws.String("SET #checkpoint = :checkpoint"),
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":      aws.String(ownerAttribute),
+			"#checkpoint": aws.String(checkpointAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner":      {S: aws.String(owner)},
+			":checkpoint": {S: aws.String(sequenceNumber)},
+		},
+	})
+	if err != nil {
+		if isAWSErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+			return errLeaseNotTaken
+		}
+
+		return errors.Wrapf(err, "Failed to checkpoint shard %s", shardID)
+	}
+
+	return nil
+}
+
+func (dcs *dynamoDBCheckpointStore) getKey(shardID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		leaseGroupAttribute: {S: aws.String(dcs.leaseGroup)},
+		shardIDAttribute:    {S: aws.String(shardID)},
+	}
+}
+
+func (dcs *dynamoDBCheckpointStore) itemToLease(item map[string]*dynamodb.AttributeValue) *lease {
+	shardLease := &lease{}
+
+	if attributeValue, found := item[shardIDAttribute]; found {
+		shardLease.shardID = aws.StringValue(attributeValue.S)
+	}
+
+	if attributeValue, found := item[ownerAttribute]; found {
+		shardLease.owner = aws.StringValue(attributeValue.S)
+	}
+
+	if attributeValue, found := item[checkpointAttribute]; found {
+		shardLease.checkpoint = aws.StringValue(attributeValue.S)
+	}
+
+	if attributeValue, found := item[leaseExpiresAtAttribute]; found {
+		if expiresAtMilliseconds, err := strconv.ParseInt(aws.StringValue(attributeValue.N), 10, 64); err == nil {
+			shardLease.expiresAt = time.Unix(0, expiresAtMilliseconds*int64(time.Millisecond))
+		}
+	}
+
+	return shardLease
+}
+
+// formatTime formats a time as the milliseconds since the epoch, as lease expirations are kept
+func formatTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
@@ -17,15 +17,61 @@ limitations under the License.
 package kinesis
 
 import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	kinesisclient "github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
-// allows accessing an amqp.Delivery
+// Event is a record read from a shard of a Kinesis stream. its headers are the record's partition key and
+// sequence number, and the shard it was read from
 type Event struct {
 	nuclio.AbstractEvent
-	body []byte
+	record     *kinesisclient.Record
+	shardID    string
+	streamName string
 }
 
 func (e *Event) GetBody() []byte {
-	return e.body
+	return e.record.Data
+}
+
+func (e *Event) GetSize() int {
+	return len(e.record.Data)
+}
+
+// GetPath returns the name of the stream
+func (e *Event) GetPath() string {
+	return e.streamName
+}
+
+// GetTimestamp returns when the record was added to the stream
+func (e *Event) GetTimestamp() time.Time {
+	if e.record.ApproximateArrivalTimestamp == nil {
+		return time.Now()
+	}
+
+	return *e.record.ApproximateArrivalTimestamp
+}
+
+func (e *Event) GetHeaders() map[string]interface{} {
+	return map[string]interface{}{
+		"X-Nuclio-Kinesis-Shard-Id":        e.shardID,
+		"X-Nuclio-Kinesis-Partition-Key":   aws.StringValue(e.record.PartitionKey),
+		"X-Nuclio-Kinesis-Sequence-Number": aws.StringValue(e.record.SequenceNumber),
+	}
+}
+
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaders()[key]
+}
+
+func (e *Event) GetHeaderString(key string) string {
+	headerValue, _ := e.GetHeader(key).(string)
+	return headerValue
+}
+
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
 }
@@ -49,7 +49,7 @@ func (f *factory) Create(parentLogger logger.Logger,
 		namedWorkerAllocators,
 		func() (worker.Allocator, error) {
 			return worker.WorkerFactorySingleton.CreateFixedPoolWorkerAllocator(triggerLogger,
-				len(configuration.Shards),
+				configuration.MaxWorkers,
 				runtimeConfiguration)
 		})
 
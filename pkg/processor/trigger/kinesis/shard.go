@@ -17,153 +17,215 @@ limitations under the License.
 package kinesis
 
 import (
+	"context"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/aws/aws-sdk-go/aws"
+	kinesisclient "github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
-	kinesisclient "github.com/sendgridlabs/go-kinesis"
 )
 
-var errIteratorExpired = errors.New("IteratorExpired")
-
-type shard struct {
+// shardReader reads a shard the trigger holds the lease of, with a worker of its own, checkpointing the
+// records it submitted to the worker
+type shardReader struct {
 	logger         logger.Logger
 	kinesisTrigger *kinesis
 	shardID        string
 	worker         *worker.Worker
+
+	// where reading continues from - after the last record read (or the continuation of the source)
+	position startingPosition
+
+	// the sequence number of the last record submitted to the worker, and the last one checkpointed
+	submittedSequenceNumber    string
+	checkpointedSequenceNumber string
+
+	// records are aggregated into a batch event across reads, if the trigger is batched
+	batchEvent              *trigger.BatchEvent
+	batchDeadline           time.Time
+	batchLastSequenceNumber string
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	// set once the shard was read to its end, before stopped is closed
+	shardEnded bool
 }
 
-func newShard(parentLogger logger.Logger, kinesisTrigger *kinesis, shardID string) (*shard, error) {
-	var err error
+func newShardReader(parentLogger logger.Logger,
+	kinesisTrigger *kinesis,
+	shardID string,
+	workerInstance *worker.Worker,
+	position startingPosition) *shardReader {
 
-	newShard := &shard{
+	newShardReader := &shardReader{
 		logger:         parentLogger.GetChild(fmt.Sprintf("shard-%s", shardID)),
 		kinesisTrigger: kinesisTrigger,
 		shardID:        shardID,
+		worker:         workerInstance,
+		position:       position,
+		stopped:        make(chan struct{}),
+	}
+
+	if position.iteratorType == kinesisclient.ShardIteratorTypeAfterSequenceNumber {
+		newShardReader.checkpointedSequenceNumber = position.sequenceNumber
 	}
 
-	newShard.worker, err = kinesisTrigger.WorkerAllocator.Allocate(0)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to allocate worker")
+	if kinesisTrigger.configuration.Batch != nil {
+		newShardReader.batchEvent = trigger.NewBatchEvent(kinesisTrigger.configuration.StreamName, 0)
 	}
 
-	return newShard, nil
+	return newShardReader
 }
 
-func (s *shard) readFromShard() error {
-	var err error
+// start reads the shard in the background, until it's read to its end or stop is called
+func (sr *shardReader) start() {
+	readContext, cancel := context.WithCancel(context.Background())
+	sr.cancel = cancel
 
-	s.logger.DebugWith("Starting to read from shard",
-		"pollingPeriod", s.kinesisTrigger.configuration.PollingPeriod,
-		"iteratorType", s.kinesisTrigger.configuration.IteratorType)
+	go sr.read(readContext)
+}
+
+// stop stops reading the shard, and waits for the records being handled to be handled. records of a batch
+// that wasn't submitted yet aren't checkpointed, and are read again by the next reader of the shard
+func (sr *shardReader) stop() {
+	sr.cancel()
+	<-sr.stopped
+}
 
-	// prepare args for get records
-	getRecordArgs := kinesisclient.NewArgs()
+func (sr *shardReader) read(readContext context.Context) {
+	defer close(sr.stopped)
 
-	var getRecordsResponse *kinesisclient.GetRecordsResp
-	lastRecordSequenceNumber := ""
+	sr.logger.DebugWith("Starting to read from shard",
+		"iteratorType", sr.position.iteratorType,
+		"sequenceNumber", sr.position.sequenceNumber)
 
 	for {
+		shardEnded, err := sr.kinesisTrigger.recordSource.readShard(readContext,
+			sr.shardID,
+			sr.position,
+			sr.handleRecords)
 
-		// get next records
-		getRecordsResponse, err = s.getNextRecords(getRecordArgs, getRecordsResponse, lastRecordSequenceNumber)
+		if readContext.Err() != nil {
+			return
+		}
 
 		if err != nil {
+			sr.logger.WarnWith("Failed to read from shard", "err", errors.GetErrorStackString(err, 5))
 
-			// if there was an error other than iterator expired, wait a bit
-			if err != errIteratorExpired {
-				s.logger.WarnWith("Failed to get next records", "err", errors.GetErrorStackString(err, 5))
-				time.Sleep(s.kinesisTrigger.configuration.pollingPeriodDuration)
+			select {
+			case <-time.After(readErrorBackoff):
+				continue
+			case <-readContext.Done():
+				return
 			}
-
-			continue
 		}
 
-		// if we got records, handle them
-		if len(getRecordsResponse.Records) > 0 {
-			for _, record := range getRecordsResponse.Records {
-				event := Event{
-					body: record.Data,
-				}
+		if shardEnded {
+			sr.submitBatchEvent()
 
-				// process the event, don't really do anything with response
-				s.kinesisTrigger.SubmitEventToWorker(nil, s.worker, &event) // nolint: errcheck
-			}
+			// the shard's children are read once it's checkpointed as ended
+			sr.checkpoint(shardEndCheckpoint)
+			sr.shardEnded = true
 
-			// save last sequence number in the batch. we might need to create a shard iterator at this
-			// sequence number
-			lastRecordSequenceNumber = getRecordsResponse.Records[len(getRecordsResponse.Records)-1].SequenceNumber
+			sr.logger.InfoWith("Shard was read to its end")
+			sr.kinesisTrigger.requestShardSync()
 
-		} else {
-			time.Sleep(s.kinesisTrigger.configuration.pollingPeriodDuration)
+			return
 		}
+
+		// the subscription expired, or the shard was read up to where it's written - continue from where
+		// reading reached
 	}
 }
 
-func (s *shard) getNextRecords(getRecordArgs *kinesisclient.RequestArgs,
-	getRecordsResponse *kinesisclient.GetRecordsResp,
-	lastRecordSequenceNumber string) (*kinesisclient.GetRecordsResp, error) {
+func (sr *shardReader) handleRecords(records []*kinesisclient.Record, continuationSequenceNumber string) {
+	for _, record := range records {
+		event := &Event{
+			record:     record,
+			shardID:    sr.shardID,
+			streamName: sr.kinesisTrigger.configuration.StreamName,
+		}
 
-	// get the shard iterator
-	shardIterator, err := s.getShardIterator(getRecordsResponse, lastRecordSequenceNumber)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get shard iterator")
-	}
+		if sr.batchEvent != nil {
+			if sr.batchEvent.Len() == 0 {
+				sr.batchDeadline = time.Now().Add(sr.kinesisTrigger.configuration.GetBatchMaxWait())
+			}
 
-	// set shard iterator
-	getRecordArgs.Add("ShardIterator", shardIterator)
+			sr.batchEvent.Add(event)
+			sr.batchLastSequenceNumber = aws.StringValue(record.SequenceNumber)
 
-	// try to get records
-	getRecordsResponse, err = s.kinesisTrigger.kinesisClient.GetRecords(getRecordArgs)
-	if err != nil {
+			if sr.batchEvent.Len() >= sr.kinesisTrigger.configuration.Batch.MaxSize {
+				sr.submitBatchEvent()
+			}
 
-		// if the error denotes an expired iterator, force recreation of iterator by nullifying the
-		// records response. getShardIterator() will produce an iterator based off of the last successful
-		// read sequence number
-		if strings.Contains(err.Error(), "Iterator expired") {
-			return nil, errIteratorExpired
+			continue
 		}
 
-		return nil, errors.Wrap(err, "Failed to get records")
+		// process the event, don't really do anything with response
+		sr.kinesisTrigger.SubmitEventToWorker(nil, sr.worker, event) // nolint: errcheck
+		sr.submittedSequenceNumber = aws.StringValue(record.SequenceNumber)
 	}
 
-	return getRecordsResponse, nil
-}
+	// a batch which didn't fill up in time is submitted as is
+	if sr.batchEvent != nil && sr.batchEvent.Len() > 0 && time.Now().After(sr.batchDeadline) {
+		sr.submitBatchEvent()
+	}
+
+	if len(records) > 0 {
+		sr.position = newStartingPositionAfter(aws.StringValue(records[len(records)-1].SequenceNumber))
+	}
+
+	// the continuation covers records which weren't delivered (e.g. were filtered), so it's both read and
+	// checkpointed when there's nothing pending before it
+	if continuationSequenceNumber != "" {
+		sr.position = newStartingPositionAfter(continuationSequenceNumber)
 
-func (s *shard) getShardIterator(lastRecordsResponse *kinesisclient.GetRecordsResp,
-	lastRecordSequenceNumber string) (string, error) {
+		if sr.batchEvent == nil || sr.batchEvent.Len() == 0 {
+			sr.submittedSequenceNumber = continuationSequenceNumber
+		}
+	}
 
-	// if there's a response we need to create the iterator from, use that first
-	if lastRecordsResponse != nil {
-		return lastRecordsResponse.NextShardIterator, nil
+	if sr.submittedSequenceNumber != "" && sr.submittedSequenceNumber != sr.checkpointedSequenceNumber {
+		sr.checkpoint(sr.submittedSequenceNumber)
 	}
+}
 
-	getShardIteratorArgs := kinesisclient.NewArgs()
-	getShardIteratorArgs.Add("StreamName", s.kinesisTrigger.configuration.StreamName)
-	getShardIteratorArgs.Add("ShardId", s.shardID)
+func (sr *shardReader) submitBatchEvent() {
+	if sr.batchEvent == nil || sr.batchEvent.Len() == 0 {
+		return
+	}
 
-	if lastRecordSequenceNumber == "" {
+	// process the event, don't really do anything with response
+	sr.kinesisTrigger.SubmitEventToWorker(nil, sr.worker, sr.batchEvent) // nolint: errcheck
+	sr.submittedSequenceNumber = sr.batchLastSequenceNumber
 
-		// if there's no records response and no record sequence number, this must be the first time. use
-		// the iterator type specified in the configuration
-		getShardIteratorArgs.Add("ShardIteratorType", s.kinesisTrigger.configuration.IteratorType)
-		s.logger.DebugWith("Creating initial iterator", "type", s.kinesisTrigger.configuration.IteratorType)
-	} else {
+	sr.batchEvent.Reset()
+}
 
-		// if a sequence number was passed, get a shard iterator at that point
-		getShardIteratorArgs.Add("ShardIteratorType", "AFTER_SEQUENCE_NUMBER")
-		getShardIteratorArgs.Add("StartingSequenceNumber", lastRecordSequenceNumber)
-		s.logger.DebugWith("Creating iterator at sequence", "seq", lastRecordSequenceNumber)
+func (sr *shardReader) checkpoint(sequenceNumber string) {
+	err := sr.kinesisTrigger.checkpointStore.checkpoint(sr.shardID, sr.kinesisTrigger.owner, sequenceNumber)
+	if err == nil {
+		sr.checkpointedSequenceNumber = sequenceNumber
+		return
 	}
 
-	getShardIteratorResponse, err := s.kinesisTrigger.kinesisClient.GetShardIterator(getShardIteratorArgs)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to get shard iterator")
+	// another replica took the shard over - stop reading it, rather than handle its records twice. the trigger
+	// releases the reader's worker on its next sync
+	if err == errLeaseNotTaken {
+		sr.logger.InfoWith("Lease of shard was taken over, stopping to read it")
+		sr.cancel()
+		sr.kinesisTrigger.requestShardSync()
+
+		return
 	}
 
-	return getShardIteratorResponse.ShardIterator, nil
+	sr.logger.WarnWith("Failed to checkpoint shard",
+		"sequenceNumber", sequenceNumber,
+		"err", errors.Cause(err).Error())
 }
@@ -0,0 +1,229 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	kinesisclient "github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// how long a registered consumer has to become active
+const consumerActivationTimeout = 2 * time.Minute
+
+// startingPosition is where a shard is read from - after a sequence number, or per an iterator type
+// (TRIM_HORIZON or LATEST)
+type startingPosition struct {
+	iteratorType   string
+	sequenceNumber string
+}
+
+func newStartingPositionAfter(sequenceNumber string) startingPosition {
+	return startingPosition{
+		iteratorType:   kinesisclient.ShardIteratorTypeAfterSequenceNumber,
+		sequenceNumber: sequenceNumber,
+	}
+}
+
+// recordsHandler is given the records read from a shard, and the sequence number reading can be continued
+// after if the source has one (which covers records not delivered to the consumer, unlike that of the
+// last record)
+type recordsHandler func(records []*kinesisclient.Record, continuationSequenceNumber string)
+
+// recordSource reads the records of shards
+type recordSource interface {
+
+	// readShard reads a shard from the given position, handing the records it reads to handleRecords. it
+	// returns true once the shard was read to its end (it was closed by a split or a merge), or false if
+	// reading stopped before it - when ctx is done, or on errors
+	readShard(ctx context.Context,
+		shardID string,
+		position startingPosition,
+		handleRecords recordsHandler) (bool, error)
+}
+
+// pollingRecordSource reads shards with GetRecords, polling shards it caught up with every polling period
+type pollingRecordSource struct {
+	client        kinesisiface.KinesisAPI
+	streamName    string
+	pollingPeriod time.Duration
+}
+
+func (prs *pollingRecordSource) readShard(ctx context.Context,
+	shardID string,
+	position startingPosition,
+	handleRecords recordsHandler) (bool, error) {
+
+	getShardIteratorInput := &kinesisclient.GetShardIteratorInput{
+		StreamName:        aws.String(prs.streamName),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: aws.String(position.iteratorType),
+	}
+
+	if position.sequenceNumber != "" {
+		getShardIteratorInput.StartingSequenceNumber = aws.String(position.sequenceNumber)
+	}
+
+	getShardIteratorOutput, err := prs.client.GetShardIteratorWithContext(ctx, getShardIteratorInput)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get shard iterator")
+	}
+
+	// a shard has no next iterator once it was read to its end
+	shardIterator := getShardIteratorOutput.ShardIterator
+	for shardIterator != nil {
+		getRecordsOutput, err := prs.client.GetRecordsWithContext(ctx, &kinesisclient.GetRecordsInput{
+			ShardIterator: shardIterator,
+		})
+		if err != nil {
+
+			// an expired iterator is recreated from the position the reader reached
+			return false, errors.Wrap(err, "Failed to get records")
+		}
+
+		handleRecords(getRecordsOutput.Records, "")
+		shardIterator = getRecordsOutput.NextShardIterator
+
+		// caught up with the shard, wait for records to be added to it
+		if len(getRecordsOutput.Records) == 0 && shardIterator != nil {
+			select {
+			case <-time.After(prs.pollingPeriod):
+			case <-ctx.Done():
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// fanOutRecordSource reads shards with enhanced fan-out - records are pushed to the consumer, with throughput
+// of its own, over subscriptions which last up to 5 minutes (after which the shard is subscribed to again)
+type fanOutRecordSource struct {
+	client      kinesisiface.KinesisAPI
+	consumerARN string
+}
+
+func (fors *fanOutRecordSource) readShard(ctx context.Context,
+	shardID string,
+	position startingPosition,
+	handleRecords recordsHandler) (bool, error) {
+
+	startingPosition := &kinesisclient.StartingPosition{
+		Type: aws.String(position.iteratorType),
+	}
+
+	if position.sequenceNumber != "" {
+		startingPosition.SequenceNumber = aws.String(position.sequenceNumber)
+	}
+
+	subscribeToShardOutput, err := fors.client.SubscribeToShardWithContext(ctx, &kinesisclient.SubscribeToShardInput{
+		ConsumerARN:      aws.String(fors.consumerARN),
+		ShardId:          aws.String(shardID),
+		StartingPosition: startingPosition,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to subscribe to shard")
+	}
+
+	eventStream := subscribeToShardOutput.GetStream()
+	defer eventStream.Close() // nolint: errcheck
+
+	for event := range eventStream.Events() {
+		subscribeToShardEvent, ok := event.(*kinesisclient.SubscribeToShardEvent)
+		if !ok {
+			continue
+		}
+
+		// there's no continuation once the shard was read to its end
+		handleRecords(subscribeToShardEvent.Records, aws.StringValue(subscribeToShardEvent.ContinuationSequenceNumber))
+		if subscribeToShardEvent.ContinuationSequenceNumber == nil {
+			return true, nil
+		}
+	}
+
+	if err := eventStream.Err(); err != nil && ctx.Err() == nil {
+		return false, errors.Wrap(err, "Subscription to shard failed")
+	}
+
+	return false, nil
+}
+
+// registerConsumer returns the ARN of the stream's consumer of the given name, registering it if it doesn't
+// exist yet and waiting for it to become active
+func registerConsumer(parentLogger logger.Logger,
+	client kinesisiface.KinesisAPI,
+	streamName string,
+	consumerName string) (string, error) {
+
+	describeStreamSummaryOutput, err := client.DescribeStreamSummary(&kinesisclient.DescribeStreamSummaryInput{
+		StreamName: aws.String(streamName),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to describe stream %s", streamName)
+	}
+
+	streamARN := describeStreamSummaryOutput.StreamDescriptionSummary.StreamARN
+	activationDeadline := time.Now().Add(consumerActivationTimeout)
+
+	for {
+		describeStreamConsumerOutput, err := client.DescribeStreamConsumer(&kinesisclient.DescribeStreamConsumerInput{
+			StreamARN:    streamARN,
+			ConsumerName: aws.String(consumerName),
+		})
+
+		switch {
+		case err == nil:
+			consumerDescription := describeStreamConsumerOutput.ConsumerDescription
+			if aws.StringValue(consumerDescription.ConsumerStatus) == kinesisclient.ConsumerStatusActive {
+				return aws.StringValue(consumerDescription.ConsumerARN), nil
+			}
+
+		case isAWSErrorCode(err, kinesisclient.ErrCodeResourceNotFoundException):
+			parentLogger.InfoWith("Registering stream consumer",
+				"streamName", streamName,
+				"consumerName", consumerName)
+
+			if _, err := client.RegisterStreamConsumer(&kinesisclient.RegisterStreamConsumerInput{
+				StreamARN:    streamARN,
+				ConsumerName: aws.String(consumerName),
+			}); err != nil && !isAWSErrorCode(err, kinesisclient.ErrCodeResourceInUseException) {
+				return "", errors.Wrapf(err, "Failed to register consumer %s", consumerName)
+			}
+
+		default:
+			return "", errors.Wrapf(err, "Failed to describe consumer %s", consumerName)
+		}
+
+		if time.Now().After(activationDeadline) {
+			return "", errors.Errorf("Consumer %s didn't become active in %s", consumerName, consumerActivationTimeout)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+func isAWSErrorCode(err error, code string) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == code
+}
@@ -17,23 +17,44 @@ limitations under the License.
 package kinesis
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	kinesisclient "github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
-	kinesisclient "github.com/sendgridlabs/go-kinesis"
 )
 
 type kinesis struct {
 	trigger.AbstractTrigger
-	event         Event
-	configuration *Configuration
-	kinesisAuth   kinesisclient.Auth
-	kinesisClient kinesisclient.KinesisClient
-	shards        []*shard
+	configuration   *Configuration
+	kinesisClient   kinesisiface.KinesisAPI
+	dynamoDBClient  dynamodbiface.DynamoDBAPI
+	checkpointStore checkpointStore
+	recordSource    recordSource
+
+	// identifies the replica in the leases it holds
+	owner string
+
+	// the readers of the shards the replica holds the leases of, by shard ID. only accessed by the goroutine
+	// syncing the shards (and by Stop, once it stopped)
+	readers map[string]*shardReader
+
+	shardSyncRequested chan struct{}
+	cancelSyncing      context.CancelFunc
+	syncingStopped     chan struct{}
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -51,56 +72,399 @@ func newTrigger(parentLogger logger.Logger,
 		return nil, errors.New("Failed to create abstract trigger")
 	}
 
-	newTrigger := &kinesis{
-		AbstractTrigger: abstractTrigger,
-		configuration:   configuration,
+	return &kinesis{
+		AbstractTrigger:    abstractTrigger,
+		configuration:      configuration,
+		owner:              getOwner(),
+		readers:            map[string]*shardReader{},
+		shardSyncRequested: make(chan struct{}, 1),
+	}, nil
+}
+
+func (k *kinesis) Start(checkpoint functionconfig.Checkpoint) error {
+	k.Logger.InfoWith("Starting",
+		"streamName", k.configuration.StreamName,
+		"shards", k.configuration.Shards,
+		"consumerName", k.configuration.ConsumerName,
+		"checkpointTable", k.configuration.CheckpointTable,
+		"owner", k.owner)
+
+	if err := k.createClients(); err != nil {
+		return errors.Wrap(err, "Failed to create clients")
 	}
-	newTrigger.kinesisAuth = kinesisclient.NewAuth(configuration.AccessKeyID,
-		configuration.SecretAccessKey,
-		"")
 
-	newTrigger.kinesisClient = kinesisclient.New(newTrigger.kinesisAuth, configuration.RegionName)
+	if k.checkpointStore == nil {
+		if k.configuration.CheckpointTable != "" {
+			k.checkpointStore = newDynamoDBCheckpointStore(k.dynamoDBClient,
+				k.configuration.CheckpointTable,
+				fmt.Sprintf("%s/%s", k.configuration.ApplicationName, k.configuration.StreamName))
+		} else {
+			k.checkpointStore = newMemoryCheckpointStore()
+		}
+	}
 
-	// iterate over shards and create
-	for _, shardID := range configuration.Shards {
+	if k.recordSource == nil {
+		if k.configuration.ConsumerName != "" {
+			consumerARN, err := registerConsumer(k.Logger,
+				k.kinesisClient,
+				k.configuration.StreamName,
+				k.configuration.ConsumerName)
+			if err != nil {
+				return errors.Wrap(err, "Failed to register stream consumer")
+			}
 
-		// create the shard
-		shard, err := newShard(newTrigger.Logger, newTrigger, shardID)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to create shard")
+			k.recordSource = &fanOutRecordSource{
+				client:      k.kinesisClient,
+				consumerARN: consumerARN,
+			}
+		} else {
+			k.recordSource = &pollingRecordSource{
+				client:        k.kinesisClient,
+				streamName:    k.configuration.StreamName,
+				pollingPeriod: k.configuration.pollingPeriodDuration,
+			}
 		}
+	}
+
+	syncContext, cancelSyncing := context.WithCancel(context.Background())
+	k.cancelSyncing = cancelSyncing
+	k.syncingStopped = make(chan struct{})
 
-		// add shard
-		newTrigger.shards = append(newTrigger.shards, shard)
+	go k.syncShardsPeriodically(syncContext)
+
+	return nil
+}
+
+// Stop stops reading the shards, releasing their leases so that other replicas take them over right away
+func (k *kinesis) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if k.cancelSyncing == nil {
+		return nil, nil
+	}
+
+	k.cancelSyncing()
+	<-k.syncingStopped
+
+	for _, reader := range k.readers {
+		k.removeReader(reader)
 	}
 
-	return newTrigger, nil
+	return nil, nil
 }
 
-func (k *kinesis) Start(checkpoint functionconfig.Checkpoint) error {
-	k.Logger.InfoWith("Starting",
-		"streamName", k.configuration.StreamName,
-		"shards", k.configuration.Shards)
+func (k *kinesis) GetConfig() map[string]interface{} {
+	return common.StructureToMap(k.configuration)
+}
 
-	for _, shardInstance := range k.shards {
+// requestShardSync makes the shards be synced now, rather than on the next period (e.g. when a shard ended,
+// so that its children are read right away)
+func (k *kinesis) requestShardSync() {
+	select {
+	case k.shardSyncRequested <- struct{}{}:
+	default:
+	}
+}
 
-		// start reading from shard
-		go func(shardInstance *shard) {
-			if err := shardInstance.readFromShard(); err != nil {
-				k.Logger.ErrorWith("Failed to read from shard", "err", err)
+func (k *kinesis) createClients() error {
+	if k.kinesisClient != nil && (k.dynamoDBClient != nil || k.configuration.CheckpointTable == "") {
+		return nil
+	}
+
+	sess, err := common.NewAWSSession(&k.configuration.AWSSessionConfiguration)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	// the clients may have been injected
+	if k.kinesisClient == nil {
+		k.kinesisClient = kinesisclient.New(sess)
+	}
+
+	if k.dynamoDBClient == nil {
+		k.dynamoDBClient = dynamodb.New(sess)
+	}
+
+	return nil
+}
+
+// syncShardsPeriodically syncs the shards the replica reads with the stream and the leases, often enough for
+// the leases it holds not to expire
+func (k *kinesis) syncShardsPeriodically(syncContext context.Context) {
+	defer close(k.syncingStopped)
+
+	ticker := time.NewTicker(k.configuration.leaseDurationDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := k.syncShards(); err != nil {
+			k.Logger.WarnWith("Failed to sync shards", "err", errors.GetErrorStackString(err, 5))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-k.shardSyncRequested:
+		case <-syncContext.Done():
+			return
+		}
+	}
+}
+
+// syncShards renews the leases of the shards the replica reads, and takes the leases of shards that should be
+// read - new shards, the children of shards which were read to their end, and shards of replicas which are gone
+// or read more than their share
+func (k *kinesis) syncShards() error {
+
+	// readers which stopped - their shard ended, or another replica took it over - free their workers
+	for _, reader := range k.readers {
+		select {
+		case <-reader.stopped:
+			k.removeReader(reader)
+		default:
+		}
+	}
+
+	shards, err := k.listShards()
+	if err != nil {
+		return errors.Wrap(err, "Failed to list shards")
+	}
+
+	leases, err := k.checkpointStore.getLeases()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get leases")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(k.configuration.leaseDurationDuration)
+	readableShards := getReadableShards(shards, leases, k.configuration.Shards)
+
+	// renew the leases of the shards being read, stopping to read those taken over
+	for shardID, reader := range k.readers {
+		if _, err := k.checkpointStore.takeLease(shardID, k.owner, "", expiresAt); err != nil {
+			k.Logger.InfoWith("Lost the lease of shard, stopping to read it",
+				"shardID", shardID,
+				"err", errors.Cause(err).Error())
+
+			k.removeReader(reader)
+		}
+	}
+
+	for _, shardToRead := range k.getShardsToRead(readableShards, leases, now) {
+		workerInstance, err := k.WorkerAllocator.Allocate(0)
+		if err != nil {
+			k.Logger.WarnWith("All workers are reading shards, more replicas or workers are needed to read the rest",
+				"maxWorkers", k.configuration.MaxWorkers,
+				"shardID", shardToRead.shardID)
+			break
+		}
+
+		shardLease, err := k.checkpointStore.takeLease(shardToRead.shardID, k.owner, shardToRead.previousOwner, expiresAt)
+		if err != nil {
+			k.WorkerAllocator.Release(workerInstance)
+
+			if err != errLeaseNotTaken {
+				return errors.Wrapf(err, "Failed to take the lease of shard %s", shardToRead.shardID)
 			}
-		}(shardInstance)
+
+			continue
+		}
+
+		position := k.getStartingPosition(shardToRead.shard, shardLease)
+
+		k.Logger.InfoWith("Reading shard",
+			"shardID", shardToRead.shardID,
+			"previousOwner", shardToRead.previousOwner,
+			"iteratorType", position.iteratorType,
+			"sequenceNumber", position.sequenceNumber)
+
+		reader := newShardReader(k.Logger, k, shardToRead.shardID, workerInstance, position)
+		k.readers[shardToRead.shardID] = reader
+
+		reader.start()
 	}
 
 	return nil
 }
 
-func (k *kinesis) Stop(force bool) (functionconfig.Checkpoint, error) {
+type shardToRead struct {
+	shardID string
+	shard   *kinesisclient.Shard
 
-	// TODO
-	return nil, nil
+	// the owner the lease is taken over from, when the shard is read by a replica reading more than its share
+	previousOwner string
 }
 
-func (k *kinesis) GetConfig() map[string]interface{} {
-	return common.StructureToMap(k.configuration)
+// getShardsToRead returns the shards the replica should start reading to read its share of the readable
+// shards - free ones first, and then (one at a time, to converge gradually) one of the replica reading the most
+func (k *kinesis) getShardsToRead(readableShards []*kinesisclient.Shard,
+	leases map[string]*lease,
+	now time.Time) []shardToRead {
+
+	var freeShards []shardToRead
+	shardsByOwner := map[string][]shardToRead{
+		k.owner: nil,
+	}
+
+	for _, shard := range readableShards {
+		shardID := aws.StringValue(shard.ShardId)
+		if _, found := k.readers[shardID]; found {
+			shardsByOwner[k.owner] = append(shardsByOwner[k.owner], shardToRead{shardID: shardID, shard: shard})
+			continue
+		}
+
+		shardLease, found := leases[shardID]
+		if !found || !shardLease.isHeld(now) || shardLease.owner == k.owner {
+			freeShards = append(freeShards, shardToRead{shardID: shardID, shard: shard})
+			continue
+		}
+
+		shardsByOwner[shardLease.owner] = append(shardsByOwner[shardLease.owner],
+			shardToRead{shardID: shardID, shard: shard, previousOwner: shardLease.owner})
+	}
+
+	// each replica reads an equal share of the shards, rounded up
+	share := (len(readableShards) + len(shardsByOwner) - 1) / len(shardsByOwner)
+	missing := share - len(k.readers)
+	if missing <= 0 {
+		return nil
+	}
+
+	if len(freeShards) > 0 {
+		if len(freeShards) > missing {
+			freeShards = freeShards[:missing]
+		}
+
+		return freeShards
+	}
+
+	// take one over from the replica reading the most, if it reads more than its share
+	var busiestOwner string
+	for owner, ownerShards := range shardsByOwner {
+		if owner != k.owner && (busiestOwner == "" || len(ownerShards) > len(shardsByOwner[busiestOwner])) {
+			busiestOwner = owner
+		}
+	}
+
+	if busiestOwner == "" || len(shardsByOwner[busiestOwner]) <= share {
+		return nil
+	}
+
+	return shardsByOwner[busiestOwner][:1]
+}
+
+// getStartingPosition returns where a shard is read from - after its checkpoint if it has one. the children of
+// split or merged shards are read from their start, so that no record is skipped between them and their parents
+func (k *kinesis) getStartingPosition(shard *kinesisclient.Shard, shardLease *lease) startingPosition {
+	if shardLease.checkpoint != "" {
+		return newStartingPositionAfter(shardLease.checkpoint)
+	}
+
+	if aws.StringValue(shard.ParentShardId) != "" {
+		return startingPosition{iteratorType: kinesisclient.ShardIteratorTypeTrimHorizon}
+	}
+
+	return startingPosition{iteratorType: k.configuration.IteratorType}
+}
+
+// removeReader stops a reader (if it didn't stop already), freeing its worker and releasing its lease
+func (k *kinesis) removeReader(reader *shardReader) {
+	reader.stop()
+
+	delete(k.readers, reader.shardID)
+	k.WorkerAllocator.Release(reader.worker)
+
+	if err := k.checkpointStore.releaseLease(reader.shardID, k.owner); err != nil {
+		k.Logger.WarnWith("Failed to release the lease of shard",
+			"shardID", reader.shardID,
+			"err", errors.Cause(err).Error())
+	}
+}
+
+func (k *kinesis) listShards() ([]*kinesisclient.Shard, error) {
+	var shards []*kinesisclient.Shard
+
+	listShardsInput := &kinesisclient.ListShardsInput{
+		StreamName: aws.String(k.configuration.StreamName),
+	}
+
+	for {
+		listShardsOutput, err := k.kinesisClient.ListShards(listShardsInput)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list the shards of stream %s", k.configuration.StreamName)
+		}
+
+		shards = append(shards, listShardsOutput.Shards...)
+
+		if listShardsOutput.NextToken == nil {
+			return shards, nil
+		}
+
+		// the stream name and the token are mutually exclusive
+		listShardsInput = &kinesisclient.ListShardsInput{
+			NextToken: listShardsOutput.NextToken,
+		}
+	}
+}
+
+// getReadableShards returns the shards which can be read, ordered by ID - those which weren't read to their end,
+// and whose parents were (so that the records of a partition key are read in order across splits and merges).
+// if shards to read are configured, only they are
+func getReadableShards(shards []*kinesisclient.Shard,
+	leases map[string]*lease,
+	configuredShardIDs []string) []*kinesisclient.Shard {
+
+	candidateShardIDs := map[string]bool{}
+	for _, shard := range shards {
+		shardID := aws.StringValue(shard.ShardId)
+
+		if len(configuredShardIDs) == 0 || common.StringInSlice(shardID, configuredShardIDs) {
+			candidateShardIDs[shardID] = true
+		}
+	}
+
+	isShardEnded := func(shardID string) bool {
+		shardLease, found := leases[shardID]
+		return found && shardLease.checkpoint == shardEndCheckpoint
+	}
+
+	var readableShards []*kinesisclient.Shard
+	for _, shard := range shards {
+		shardID := aws.StringValue(shard.ShardId)
+		if !candidateShardIDs[shardID] || isShardEnded(shardID) {
+			continue
+		}
+
+		// parents which aren't listed anymore (their records expired) or aren't read have nothing to wait for
+		parentsRead := true
+		for _, parentShardID := range []string{
+			aws.StringValue(shard.ParentShardId),
+			aws.StringValue(shard.AdjacentParentShardId),
+		} {
+			if candidateShardIDs[parentShardID] && !isShardEnded(parentShardID) {
+				parentsRead = false
+			}
+		}
+
+		if parentsRead {
+			readableShards = append(readableShards, shard)
+		}
+	}
+
+	sort.Slice(readableShards, func(i, j int) bool {
+		return aws.StringValue(readableShards[i].ShardId) < aws.StringValue(readableShards[j].ShardId)
+	})
+
+	return readableShards
+}
+
+// getOwner returns what identifies the replica in the leases it holds - the name of its pod (or container),
+// so that a restarted replica takes its leases back right away
+func getOwner() string {
+	if instanceName := os.Getenv("NUCLIO_FUNCTION_INSTANCE"); instanceName != "" {
+		return instanceName
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+
+	return "unknown"
 }
@@ -0,0 +1,507 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	kinesisclient "github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingRuntime records the bodies of the events it got
+type recordingRuntime struct {
+	runtime.Runtime
+	lock   sync.Mutex
+	bodies []string
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	rr.bodies = append(rr.bodies, string(event.GetBody()))
+
+	return nil, nil
+}
+
+func (rr *recordingRuntime) getBodies() []string {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	return append([]string{}, rr.bodies...)
+}
+
+// fakeStream serves the records of its shards through shard iterators of the form <shard ID>:<record index>.
+// closed shards have no next iterator once their records were read
+type fakeStream struct {
+	kinesisiface.KinesisAPI
+	shards  []*kinesisclient.Shard
+	records map[string][]*kinesisclient.Record
+	closed  map[string]bool
+}
+
+func newFakeStream() *fakeStream {
+	return &fakeStream{
+		records: map[string][]*kinesisclient.Record{},
+		closed:  map[string]bool{},
+	}
+}
+
+func (fs *fakeStream) addShard(shardID string, parentShardID string, closed bool, bodies ...string) {
+	shard := &kinesisclient.Shard{ShardId: aws.String(shardID)}
+	if parentShardID != "" {
+		shard.ParentShardId = aws.String(parentShardID)
+	}
+
+	fs.shards = append(fs.shards, shard)
+	fs.closed[shardID] = closed
+
+	for bodyIndex, body := range bodies {
+		fs.records[shardID] = append(fs.records[shardID], &kinesisclient.Record{
+			Data:           []byte(body),
+			PartitionKey:   aws.String("key"),
+			SequenceNumber: aws.String(fmt.Sprintf("%s-%d", shardID, bodyIndex)),
+		})
+	}
+}
+
+func (fs *fakeStream) ListShards(input *kinesisclient.ListShardsInput) (*kinesisclient.ListShardsOutput, error) {
+	return &kinesisclient.ListShardsOutput{Shards: fs.shards}, nil
+}
+
+func (fs *fakeStream) GetShardIteratorWithContext(ctx aws.Context,
+	input *kinesisclient.GetShardIteratorInput,
+	options ...request.Option) (*kinesisclient.GetShardIteratorOutput, error) {
+	shardID := aws.StringValue(input.ShardId)
+	recordIndex := 0
+
+	switch aws.StringValue(input.ShardIteratorType) {
+	case kinesisclient.ShardIteratorTypeLatest:
+		recordIndex = len(fs.records[shardID])
+	case kinesisclient.ShardIteratorTypeAfterSequenceNumber:
+		sequenceNumber := aws.StringValue(input.StartingSequenceNumber)
+		recordIndex, _ = strconv.Atoi(sequenceNumber[strings.LastIndex(sequenceNumber, "-")+1:])
+		recordIndex++
+	}
+
+	return &kinesisclient.GetShardIteratorOutput{
+		ShardIterator: aws.String(fmt.Sprintf("%s:%d", shardID, recordIndex)),
+	}, nil
+}
+
+func (fs *fakeStream) GetRecordsWithContext(ctx aws.Context,
+	input *kinesisclient.GetRecordsInput,
+	options ...request.Option) (*kinesisclient.GetRecordsOutput, error) {
+	shardIterator := strings.Split(aws.StringValue(input.ShardIterator), ":")
+	shardID := shardIterator[0]
+	recordIndex, _ := strconv.Atoi(shardIterator[1])
+
+	getRecordsOutput := &kinesisclient.GetRecordsOutput{
+		Records: fs.records[shardID][recordIndex:],
+	}
+
+	if !fs.closed[shardID] {
+		getRecordsOutput.NextShardIterator = aws.String(fmt.Sprintf("%s:%d", shardID, len(fs.records[shardID])))
+	}
+
+	return getRecordsOutput, nil
+}
+
+// fakeLeaseTable records the updates made to it, failing their conditions if asked to
+type fakeLeaseTable struct {
+	dynamodbiface.DynamoDBAPI
+	updates          []*dynamodb.UpdateItemInput
+	failConditions   bool
+	queryOutputItems []map[string]*dynamodb.AttributeValue
+}
+
+func (flt *fakeLeaseTable) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	flt.updates = append(flt.updates, input)
+
+	if flt.failConditions {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	return &dynamodb.UpdateItemOutput{
+		Attributes: map[string]*dynamodb.AttributeValue{
+			shardIDAttribute:        input.Key[shardIDAttribute],
+			ownerAttribute:          input.ExpressionAttributeValues[":owner"],
+			leaseExpiresAtAttribute: input.ExpressionAttributeValues[":leaseExpiresAt"],
+			checkpointAttribute:     {S: aws.String("shardId-0-41")},
+		},
+	}, nil
+}
+
+func (flt *fakeLeaseTable) QueryPages(input *dynamodb.QueryInput,
+	pageHandler func(*dynamodb.QueryOutput, bool) bool) error {
+	pageHandler(&dynamodb.QueryOutput{Items: flt.queryOutputItems}, true)
+	return nil
+}
+
+type kinesisTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *kinesisTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *kinesisTestSuite) TestConfiguration() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"regionName": "eu-west-1",
+		"streamName": "events",
+		"shards":     []string{"shardId-0", "shardId-1"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("eu-west-1", configuration.Region)
+	suite.Require().Equal("LATEST", configuration.IteratorType)
+	suite.Require().Equal(DefaultLeaseDuration, configuration.leaseDurationDuration)
+	suite.Require().Equal("nuclio-reader-my-trigger", configuration.ApplicationName)
+
+	// each shard is given a worker
+	suite.Require().Equal(2, configuration.MaxWorkers)
+
+	configuration, err = suite.newConfiguration(map[string]interface{}{
+		"region":          "us-east-2",
+		"streamName":      "events",
+		"consumerName":    "reader",
+		"checkpointTable": "leases",
+		"applicationName": "my-app",
+		"leaseDuration":   "10s",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("reader", configuration.ConsumerName)
+	suite.Require().Equal("my-app", configuration.ApplicationName)
+	suite.Require().Equal(10*time.Second, configuration.leaseDurationDuration)
+
+	for _, attributes := range []map[string]interface{}{
+		{"streamName": "events"},
+		{"region": "us-east-2"},
+		{"region": "us-east-2", "streamName": "events", "iteratorType": "AT_TIMESTAMP"},
+		{"region": "us-east-2", "streamName": "events", "leaseDuration": "1s"},
+	} {
+		_, err := suite.newConfiguration(attributes)
+		suite.Require().Error(err, attributes)
+	}
+}
+
+func (suite *kinesisTestSuite) TestGetReadableShards() {
+	stream := newFakeStream()
+
+	// shardId-0 was split to 2 and 3, which were merged to 4. shardId-1 was never split
+	stream.addShard("shardId-0", "", true)
+	stream.addShard("shardId-1", "", false)
+	stream.addShard("shardId-2", "shardId-0", true)
+	stream.addShard("shardId-3", "shardId-0", true)
+	stream.addShard("shardId-4", "shardId-2", false)
+	stream.shards[4].AdjacentParentShardId = aws.String("shardId-3")
+
+	getReadableShardIDs := func(leases map[string]*lease, configuredShardIDs []string) []string {
+		var shardIDs []string
+		for _, shard := range getReadableShards(stream.shards, leases, configuredShardIDs) {
+			shardIDs = append(shardIDs, aws.StringValue(shard.ShardId))
+		}
+
+		return shardIDs
+	}
+
+	// children are read once their parents were read to their end
+	suite.Require().Equal([]string{"shardId-0", "shardId-1"}, getReadableShardIDs(map[string]*lease{}, nil))
+
+	leases := map[string]*lease{
+		"shardId-0": {shardID: "shardId-0", checkpoint: shardEndCheckpoint},
+		"shardId-1": {shardID: "shardId-1", checkpoint: "shardId-1-7"},
+	}
+	suite.Require().Equal([]string{"shardId-1", "shardId-2", "shardId-3"}, getReadableShardIDs(leases, nil))
+
+	// both parents of a merged shard must be read
+	leases["shardId-2"] = &lease{shardID: "shardId-2", checkpoint: shardEndCheckpoint}
+	suite.Require().Equal([]string{"shardId-1", "shardId-3"}, getReadableShardIDs(leases, nil))
+
+	leases["shardId-3"] = &lease{shardID: "shardId-3", checkpoint: shardEndCheckpoint}
+	suite.Require().Equal([]string{"shardId-1", "shardId-4"}, getReadableShardIDs(leases, nil))
+
+	// parents which aren't read don't hold their children back
+	suite.Require().Equal([]string{"shardId-1", "shardId-2"},
+		getReadableShardIDs(map[string]*lease{}, []string{"shardId-1", "shardId-2"}))
+}
+
+func (suite *kinesisTestSuite) TestGetShardsToRead() {
+	stream := newFakeStream()
+	for shardIndex := 0; shardIndex < 4; shardIndex++ {
+		stream.addShard(fmt.Sprintf("shardId-%d", shardIndex), "", false)
+	}
+
+	now := time.Now()
+	triggerInstance := &kinesis{
+		owner:   "replica-b",
+		readers: map[string]*shardReader{},
+	}
+
+	getShardIDsToRead := func(leases map[string]*lease) []string {
+		var shardIDs []string
+		for _, shardToRead := range triggerInstance.getShardsToRead(stream.shards, leases, now) {
+			shardIDs = append(shardIDs, shardToRead.shardID+"@"+shardToRead.previousOwner)
+		}
+
+		return shardIDs
+	}
+
+	// free and expired shards are taken up to the replica's share
+	suite.Require().Equal([]string{"shardId-1@", "shardId-3@"}, getShardIDsToRead(map[string]*lease{
+		"shardId-0": {owner: "replica-a", expiresAt: now.Add(time.Minute)},
+		"shardId-2": {owner: "replica-a", expiresAt: now.Add(time.Minute)},
+		"shardId-3": {owner: "replica-c", expiresAt: now.Add(-time.Second)},
+	}))
+
+	// a shard is taken over from a replica reading more than its share, one at a time
+	leases := map[string]*lease{}
+	for _, shard := range stream.shards {
+		leases[aws.StringValue(shard.ShardId)] = &lease{owner: "replica-a", expiresAt: now.Add(time.Minute)}
+	}
+
+	suite.Require().Equal([]string{"shardId-0@replica-a"}, getShardIDsToRead(leases))
+
+	// replicas reading their share are left alone
+	leases["shardId-0"].owner = "replica-b"
+	leases["shardId-1"].owner = "replica-b"
+	triggerInstance.readers["shardId-0"] = &shardReader{}
+	triggerInstance.readers["shardId-1"] = &shardReader{}
+
+	suite.Require().Empty(getShardIDsToRead(leases))
+}
+
+func (suite *kinesisTestSuite) TestReadSplitShard() {
+	stream := newFakeStream()
+	stream.addShard("shardId-0", "", true, "parent-1", "parent-2")
+	stream.addShard("shardId-1", "shardId-0", false, "child-1")
+	stream.addShard("shardId-2", "shardId-0", false, "child-2")
+
+	triggerInstance, recordingRuntime := suite.startTrigger(stream, map[string]interface{}{
+		"iteratorType":  "TRIM_HORIZON",
+		"pollingPeriod": "10ms",
+	}, 2)
+
+	suite.Require().Eventually(func() bool {
+		return len(recordingRuntime.getBodies()) == 4
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the records of the parent are read before those of its children
+	bodies := recordingRuntime.getBodies()
+	suite.Require().Equal([]string{"parent-1", "parent-2"}, bodies[:2])
+	suite.Require().ElementsMatch([]string{"child-1", "child-2"}, bodies[2:])
+
+	suite.Require().Eventually(func() bool {
+		leases, err := triggerInstance.checkpointStore.getLeases()
+		suite.Require().NoError(err)
+
+		return leases["shardId-1"].checkpoint == "shardId-1-0" && leases["shardId-2"].checkpoint == "shardId-2-0"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	_, err := triggerInstance.Stop(false)
+	suite.Require().NoError(err)
+
+	// the leases are released on stop, keeping the checkpoints
+	leases, err := triggerInstance.checkpointStore.getLeases()
+	suite.Require().NoError(err)
+	suite.Require().Equal(shardEndCheckpoint, leases["shardId-0"].checkpoint)
+
+	for _, shardLease := range leases {
+		suite.Require().Empty(shardLease.owner)
+	}
+}
+
+func (suite *kinesisTestSuite) TestReadFromCheckpoint() {
+	stream := newFakeStream()
+	stream.addShard("shardId-0", "", false, "first", "second", "third")
+
+	checkpointStore := newMemoryCheckpointStore()
+	_, err := checkpointStore.takeLease("shardId-0", "previous-replica", "", time.Now().Add(-time.Second))
+	suite.Require().NoError(err)
+
+	err = checkpointStore.checkpoint("shardId-0", "previous-replica", "shardId-0-0")
+	suite.Require().NoError(err)
+
+	// batched, so that the records are delivered together
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"streamName":    "events",
+		"region":        "us-east-2",
+		"pollingPeriod": "10ms",
+	})
+	suite.Require().NoError(err)
+
+	configuration.Batch = &functionconfig.Batch{MaxSize: 2}
+
+	triggerInstance, recordingRuntime := suite.startTriggerWithConfiguration(stream, configuration, checkpointStore, 1)
+
+	suite.Require().Eventually(func() bool {
+		return len(recordingRuntime.getBodies()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	_, err = triggerInstance.Stop(false)
+	suite.Require().NoError(err)
+
+	// the expired lease was taken over, and the shard read after its checkpoint
+	suite.Require().Contains(recordingRuntime.getBodies()[0], `"body":"second"`)
+	suite.Require().Contains(recordingRuntime.getBodies()[0], `"body":"third"`)
+	suite.Require().NotContains(recordingRuntime.getBodies()[0], `"body":"first"`)
+
+	leases, err := checkpointStore.getLeases()
+	suite.Require().NoError(err)
+	suite.Require().Equal("shardId-0-2", leases["shardId-0"].checkpoint)
+}
+
+func (suite *kinesisTestSuite) TestDynamoDBCheckpointStore() {
+	leaseTable := &fakeLeaseTable{
+		queryOutputItems: []map[string]*dynamodb.AttributeValue{
+			{
+				shardIDAttribute:        {S: aws.String("shardId-0")},
+				ownerAttribute:          {S: aws.String("replica-a")},
+				leaseExpiresAtAttribute: {N: aws.String("1600000000000")},
+				checkpointAttribute:     {S: aws.String("shardId-0-41")},
+			},
+		},
+	}
+
+	checkpointStore := newDynamoDBCheckpointStore(leaseTable, "leases", "my-app/events")
+
+	leases, err := checkpointStore.getLeases()
+	suite.Require().NoError(err)
+	suite.Require().Equal(&lease{
+		shardID:    "shardId-0",
+		owner:      "replica-a",
+		expiresAt:  time.Unix(1600000000, 0),
+		checkpoint: "shardId-0-41",
+	}, leases["shardId-0"])
+
+	// taking a lease over from another owner allows it to be held by it
+	shardLease, err := checkpointStore.takeLease("shardId-0", "replica-b", "replica-a", time.Now())
+	suite.Require().NoError(err)
+	suite.Require().Equal("replica-b", shardLease.owner)
+	suite.Require().Equal("shardId-0-41", shardLease.checkpoint)
+
+	takeLeaseInput := leaseTable.updates[0]
+	suite.Require().Equal("leases", aws.StringValue(takeLeaseInput.TableName))
+	suite.Require().Equal("my-app/events", aws.StringValue(takeLeaseInput.Key[leaseGroupAttribute].S))
+	suite.Require().Equal("attribute_not_exists(#owner) OR #owner = :owner OR #leaseExpiresAt < :now OR #owner = :previousOwner",
+		aws.StringValue(takeLeaseInput.ConditionExpression))
+
+	// only the owner of a lease checkpoints its shard
+	leaseTable.failConditions = true
+
+	_, err = checkpointStore.takeLease("shardId-0", "replica-c", "", time.Now())
+	suite.Require().Equal(errLeaseNotTaken, err)
+
+	err = checkpointStore.checkpoint("shardId-0", "replica-c", "shardId-0-42")
+	suite.Require().Equal(errLeaseNotTaken, err)
+	suite.Require().Equal("#owner = :owner", aws.StringValue(leaseTable.updates[2].ConditionExpression))
+
+	// a lease which was taken over is released already
+	suite.Require().NoError(checkpointStore.releaseLease("shardId-0", "replica-c"))
+}
+
+func (suite *kinesisTestSuite) startTrigger(stream *fakeStream,
+	attributes map[string]interface{},
+	numWorkers int) (*kinesis, *recordingRuntime) {
+	attributes["streamName"] = "events"
+	attributes["region"] = "us-east-2"
+
+	configuration, err := suite.newConfiguration(attributes)
+	suite.Require().NoError(err)
+
+	return suite.startTriggerWithConfiguration(stream, configuration, nil, numWorkers)
+}
+
+func (suite *kinesisTestSuite) startTriggerWithConfiguration(stream *fakeStream,
+	configuration *Configuration,
+	checkpointStore checkpointStore,
+	numWorkers int) (*kinesis, *recordingRuntime) {
+	configuration.MaxWorkers = numWorkers
+	recordingRuntime := &recordingRuntime{}
+
+	var workers []*worker.Worker
+	for workerIndex := 0; workerIndex < numWorkers; workerIndex++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIndex, recordingRuntime)
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	kinesisTrigger := triggerInstance.(*kinesis)
+	kinesisTrigger.kinesisClient = stream
+
+	if checkpointStore != nil {
+		kinesisTrigger.checkpointStore = checkpointStore
+	}
+
+	err = kinesisTrigger.Start(nil)
+	suite.Require().NoError(err)
+
+	return kinesisTrigger, recordingRuntime
+}
+
+func (suite *kinesisTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	runtimeConfiguration := &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:      "reader",
+					Namespace: "nuclio",
+				},
+			},
+		},
+	}
+
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			Name:       "my-trigger",
+			Kind:       "kinesis",
+			Attributes: attributes,
+		},
+		runtimeConfiguration)
+}
+
+func TestKinesisSuite(t *testing.T) {
+	suite.Run(t, new(kinesisTestSuite))
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package kinesis
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -27,16 +29,48 @@ import (
 	"github.com/nuclio/errors"
 )
 
+const (
+	DefaultLeaseDuration = 30 * time.Second
+
+	// the shortest lease - it's renewed at a third of it
+	minLeaseDuration = 3 * time.Second
+
+	// how long to wait before reading a shard again after failing to
+	readErrorBackoff = time.Second
+)
+
 type Configuration struct {
 	trigger.Configuration
-	AccessKeyID           string
-	SecretAccessKey       string
-	RegionName            string
-	StreamName            string
-	Shards                []string
+	common.AWSSessionConfiguration `mapstructure:",squash"`
+
+	// the region, by its former name
+	RegionName string
+
+	StreamName string
+
+	// the shards read by the trigger. when not given, all the shards of the stream are read - and as shards
+	// are split and merged, their children are read once their parents were read to their end
+	Shards []string
+
 	IteratorType          string
 	PollingPeriod         string
 	pollingPeriodDuration time.Duration
+
+	// when given, records are pushed to the trigger with enhanced fan-out (SubscribeToShard) through a
+	// consumer of this name, registered if it doesn't exist yet, rather than polled
+	ConsumerName string
+
+	// a DynamoDB table in which the shards are leased and checkpointed. replicas of the function split the
+	// shards between them through it, and resume reading them from where they were checkpointed. when not
+	// given, checkpoints are kept in memory - and a single replica should read the stream
+	CheckpointTable string
+
+	// the name the leases and checkpoints of the trigger are kept under (default: <namespace>-<function>-<trigger>)
+	ApplicationName string
+
+	// how long a replica holds the lease of a shard without renewing it before other replicas may take it over
+	LeaseDuration         string
+	leaseDurationDuration time.Duration
 }
 
 func NewConfiguration(ID string,
@@ -53,6 +87,18 @@ func NewConfiguration(ID string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	if newConfiguration.Region == "" {
+		newConfiguration.Region = newConfiguration.RegionName
+	}
+
+	if newConfiguration.Region == "" {
+		return nil, errors.New("Region must be given")
+	}
+
+	if newConfiguration.StreamName == "" {
+		return nil, errors.New("Stream name must be given")
+	}
+
 	if newConfiguration.IteratorType == "" {
 		newConfiguration.IteratorType = "LATEST"
 	}
@@ -70,6 +116,31 @@ func NewConfiguration(ID string,
 		return nil, errors.Wrap(err, "Failed to parse polling period duration")
 	}
 
+	if err := newConfiguration.ParseDurationOrDefault(&trigger.DurationConfigField{
+		Name:    "lease duration",
+		Value:   newConfiguration.LeaseDuration,
+		Field:   &newConfiguration.leaseDurationDuration,
+		Default: DefaultLeaseDuration,
+	}); err != nil {
+		return nil, err
+	}
+
+	if newConfiguration.leaseDurationDuration < minLeaseDuration {
+		return nil, errors.Errorf("Lease duration must be at least %s", minLeaseDuration)
+	}
+
+	if newConfiguration.ApplicationName == "" {
+		newConfiguration.ApplicationName = fmt.Sprintf("%s-%s-%s",
+			runtimeConfiguration.Meta.Namespace,
+			runtimeConfiguration.Meta.Name,
+			newConfiguration.Name)
+	}
+
+	// each shard is read by a worker of its own, so explicitly given shards get a worker each
+	if len(newConfiguration.Shards) > newConfiguration.MaxWorkers {
+		newConfiguration.MaxWorkers = len(newConfiguration.Shards)
+	}
+
 	return &newConfiguration, nil
 }
 
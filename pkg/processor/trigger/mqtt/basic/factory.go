@@ -49,8 +49,9 @@ func (f *factory) Create(parentLogger logger.Logger,
 	workerAllocator, err := f.GetWorkerAllocator(triggerConfiguration.WorkerAllocatorName,
 		namedWorkerAllocators,
 		func() (worker.Allocator, error) {
-			return worker.WorkerFactorySingleton.CreateFixedPoolWorkerAllocator(triggerLogger,
+			return worker.WorkerFactorySingleton.CreatePoolWorkerAllocator(triggerLogger,
 				configuration.MaxWorkers,
+				configuration.WorkerAutoscaling,
 				runtimeConfiguration)
 		})
 
@@ -17,6 +17,11 @@ limitations under the License.
 package mqtt
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -62,6 +67,11 @@ func NewAbstractTrigger(parentLogger logger.Logger,
 		configuration:   configuration,
 	}
 
+	if configuration.SharedSubscriptionGroup == "" && newTrigger.mayScaleOut() {
+		newTrigger.Logger.WarnWith("Function may run more than one replica without a shared subscription " +
+			"group - each replica will receive every message")
+	}
+
 	return &newTrigger, nil
 }
 
@@ -104,7 +114,9 @@ func (t *AbstractTrigger) createClient(clientOptions *mqttclient.ClientOptions)
 		"brokerUrl", t.configuration.URL,
 		"clientID", t.configuration.ClientID,
 		"username", t.configuration.Username,
-		"protocolVersion", t.configuration.ProtocolVersion)
+		"protocolVersion", t.configuration.ProtocolVersion,
+		"sharedSubscriptionGroup", t.configuration.SharedSubscriptionGroup,
+		"tls", t.configuration.TLS.Enable)
 
 	client := mqttclient.NewClient(clientOptions)
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
@@ -128,11 +140,79 @@ func (t *AbstractTrigger) createClientOptions() (*mqttclient.ClientOptions, erro
 		clientOptions.SetPassword(t.configuration.Password)
 	}
 
-	clientOptions.SetClientID(t.configuration.ClientID)
+	clientOptions.SetClientID(t.getClientID())
+
+	if t.configuration.TLS.Enable {
+		tlsConfig, err := t.createTLSConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create TLS configuration")
+		}
+
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
 
 	return clientOptions, nil
 }
 
+// getClientID returns the client ID to connect with. replicas sharing subscriptions must connect with
+// different client IDs, or the broker will disconnect all but one of them, so the host name is appended
+func (t *AbstractTrigger) getClientID() string {
+	if t.configuration.SharedSubscriptionGroup == "" || t.configuration.ClientID == "" {
+		return t.configuration.ClientID
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Logger.WarnWith("Failed to get host name, using the client ID as is", "err", err.Error())
+		return t.configuration.ClientID
+	}
+
+	return fmt.Sprintf("%s-%s", t.configuration.ClientID, hostname)
+}
+
+func (t *AbstractTrigger) createTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.configuration.TLS.InsecureSkipVerify, // nolint: gosec
+	}
+
+	// verify the broker against the given CA, rather than the system's
+	if t.configuration.TLS.CACertPath != "" {
+		caCert, err := ioutil.ReadFile(t.configuration.TLS.CACertPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read CA certificate")
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.Errorf("No certificates found in %s", t.configuration.TLS.CACertPath)
+		}
+	}
+
+	// authenticate with a client certificate (mutual TLS)
+	if t.configuration.TLS.ClientCertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(t.configuration.TLS.ClientCertPath,
+			t.configuration.TLS.ClientKeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to load client certificate")
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// mayScaleOut returns whether the function may run more than one replica
+func (t *AbstractTrigger) mayScaleOut() bool {
+	functionSpec := t.configuration.RuntimeConfiguration.Config.Spec
+
+	if functionSpec.Replicas != nil {
+		return *functionSpec.Replicas > 1
+	}
+
+	return functionSpec.MaxReplicas != nil && *functionSpec.MaxReplicas > 1
+}
+
 func (t *AbstractTrigger) createSubscriptions(clientOptions *mqttclient.ClientOptions) error {
 	t.Logger.InfoWith("Creating subscriptions",
 		"subscriptions", t.configuration.Subscriptions)
@@ -151,7 +231,13 @@ func (t *AbstractTrigger) subscriptionsToFilters(subscriptions []Subscription) m
 
 	// add filter
 	for _, subscription := range subscriptions {
-		filters[subscription.Topic] = byte(subscription.QOS)
+		topic := subscription.Topic
+
+		if t.configuration.SharedSubscriptionGroup != "" {
+			topic = fmt.Sprintf("$share/%s/%s", t.configuration.SharedSubscriptionGroup, topic)
+		}
+
+		filters[topic] = byte(subscription.QOS)
 	}
 
 	return filters
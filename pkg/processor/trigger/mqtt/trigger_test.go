@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type mqttTestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	tempDir string
+}
+
+func (suite *mqttTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+
+	suite.tempDir, err = ioutil.TempDir("", "mqtt-test-")
+	suite.Require().NoError(err)
+}
+
+func (suite *mqttTestSuite) TearDownTest() {
+	os.RemoveAll(suite.tempDir) // nolint: errcheck
+}
+
+func (suite *mqttTestSuite) TestConfigurationValidation() {
+	for _, testCase := range []struct {
+		name          string
+		attributes    map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			attributes: map[string]interface{}{
+				"subscriptions":           []map[string]interface{}{{"topic": "sensors/+/temperature", "qos": 2}},
+				"sharedSubscriptionGroup": "my-function",
+			},
+		},
+		{
+			name:          "mqtt5",
+			attributes:    map[string]interface{}{"protocolVersion": 5},
+			expectedError: true,
+		},
+		{
+			name: "invalidQoS",
+			attributes: map[string]interface{}{
+				"subscriptions": []map[string]interface{}{{"topic": "sensors", "qos": 3}},
+			},
+			expectedError: true,
+		},
+		{
+			name:          "invalidSharedSubscriptionGroup",
+			attributes:    map[string]interface{}{"sharedSubscriptionGroup": "my/group"},
+			expectedError: true,
+		},
+		{
+			name: "clientCertWithoutKey",
+			attributes: map[string]interface{}{
+				"tls": map[string]interface{}{"enable": true, "clientCertPath": "/certs/tls.crt"},
+			},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := suite.newConfiguration(testCase.attributes)
+
+			if testCase.expectedError {
+				suite.Require().Error(err)
+			} else {
+				suite.Require().NoError(err)
+			}
+		})
+	}
+}
+
+func (suite *mqttTestSuite) TestSharedSubscriptions() {
+	trigger := suite.newTrigger(map[string]interface{}{
+		"clientID":                "my-client",
+		"sharedSubscriptionGroup": "my-function",
+	})
+
+	suite.Require().Equal(map[string]byte{
+		"$share/my-function/sensors/temperature": 1,
+		"$share/my-function/sensors/humidity":    2,
+	}, trigger.subscriptionsToFilters([]Subscription{
+		{Topic: "sensors/temperature", QOS: 1},
+		{Topic: "sensors/humidity", QOS: 2},
+	}))
+
+	// each replica connects with its own client ID
+	hostname, err := os.Hostname()
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-client-"+hostname, trigger.getClientID())
+
+	// without a group, subscriptions and the client ID are left as is
+	trigger = suite.newTrigger(map[string]interface{}{"clientID": "my-client"})
+	suite.Require().Equal(map[string]byte{"sensors/temperature": 0},
+		trigger.subscriptionsToFilters([]Subscription{{Topic: "sensors/temperature"}}))
+	suite.Require().Equal("my-client", trigger.getClientID())
+}
+
+func (suite *mqttTestSuite) TestTLSConfig() {
+	certPath, keyPath := suite.writeCertificate()
+
+	trigger := suite.newTrigger(map[string]interface{}{
+		"tls": map[string]interface{}{
+			"enable":         true,
+			"caCertPath":     certPath,
+			"clientCertPath": certPath,
+			"clientKeyPath":  keyPath,
+		},
+	})
+
+	tlsConfig, err := trigger.createTLSConfig()
+	suite.Require().NoError(err)
+	suite.Require().Len(tlsConfig.Certificates, 1)
+	suite.Require().NotNil(tlsConfig.RootCAs)
+	suite.Require().False(tlsConfig.InsecureSkipVerify)
+
+	// a CA file without certificates is rejected
+	trigger.configuration.TLS.CACertPath = keyPath
+
+	_, err = trigger.createTLSConfig()
+	suite.Require().Error(err)
+}
+
+func (suite *mqttTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			URL:        "tcp://broker:1883",
+			Attributes: attributes,
+		},
+		&runtime.Configuration{
+			Configuration: &processor.Configuration{},
+		})
+}
+
+func (suite *mqttTestSuite) newTrigger(attributes map[string]interface{}) *AbstractTrigger {
+	configuration, err := suite.newConfiguration(attributes)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewSingletonWorkerAllocator(suite.logger, nil)
+	suite.Require().NoError(err)
+
+	trigger, err := NewAbstractTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	return trigger
+}
+
+// writeCertificate writes a self-signed certificate and its key, returning their paths
+func (suite *mqttTestSuite) writeCertificate() (string, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	certificateTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mqtt-client"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader,
+		certificateTemplate,
+		certificateTemplate,
+		&privateKey.PublicKey,
+		privateKey)
+	suite.Require().NoError(err)
+
+	encodedPrivateKey, err := x509.MarshalECPrivateKey(privateKey)
+	suite.Require().NoError(err)
+
+	certPath := path.Join(suite.tempDir, "tls.crt")
+	keyPath := path.Join(suite.tempDir, "tls.key")
+
+	err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600)
+	suite.Require().NoError(err)
+
+	err = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: encodedPrivateKey}), 0600)
+	suite.Require().NoError(err)
+
+	return certPath, keyPath
+}
+
+func TestMQTTSuite(t *testing.T) {
+	suite.Run(t, new(mqttTestSuite))
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package mqtt
 
 import (
+	"strings"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -30,11 +32,27 @@ type Subscription struct {
 	QOS   int
 }
 
+// TLS holds the paths of the PEM encoded certificates to connect to the broker with (e.g. of a secret mounted
+// to the function through spec.volumesFromSecrets)
+type TLS struct {
+	Enable             bool
+	CACertPath         string
+	ClientCertPath     string
+	ClientKeyPath      string
+	InsecureSkipVerify bool
+}
+
 type Configuration struct {
 	trigger.Configuration
 	Subscriptions   []Subscription
 	ClientID        string
 	ProtocolVersion int
+
+	// if set, the topics are subscribed to as shared subscriptions of this group, so that the broker
+	// load-balances their messages between the function's replicas rather than delivering them to each
+	SharedSubscriptionGroup string
+
+	TLS TLS
 }
 
 func NewConfiguration(ID string,
@@ -54,5 +72,43 @@ func NewConfiguration(ID string,
 		newConfiguration.ProtocolVersion = 4
 	}
 
+	if err := newConfiguration.validate(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate configuration")
+	}
+
 	return &newConfiguration, nil
 }
+
+func (c *Configuration) validate() error {
+	switch c.ProtocolVersion {
+	case 3, 4:
+	case 5:
+		return errors.New("MQTT 5 is not supported, use protocol version 4 (3.1.1) - most brokers support " +
+			"shared subscriptions for it as well")
+	default:
+		return errors.Errorf("Unsupported protocol version: %d (must be either 3 or 4)", c.ProtocolVersion)
+	}
+
+	for _, subscription := range c.Subscriptions {
+		if subscription.Topic == "" {
+			return errors.New("Subscription topic must be set")
+		}
+
+		if subscription.QOS < 0 || subscription.QOS > 2 {
+			return errors.Errorf("Subscription QoS must be 0, 1 or 2, got %d for topic %s",
+				subscription.QOS,
+				subscription.Topic)
+		}
+	}
+
+	if strings.ContainsAny(c.SharedSubscriptionGroup, "/+#") {
+		return errors.Errorf("Shared subscription group must not contain '/', '+' or '#': %s",
+			c.SharedSubscriptionGroup)
+	}
+
+	if (c.TLS.ClientCertPath == "") != (c.TLS.ClientKeyPath == "") {
+		return errors.New("TLS client certificate and key must be set together")
+	}
+
+	return nil
+}
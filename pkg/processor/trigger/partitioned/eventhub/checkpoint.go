@@ -0,0 +1,103 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhub
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/nuclio/errors"
+)
+
+// checkpointStore persists the offset of the last event handled in each partition
+type checkpointStore interface {
+
+	// Load returns the checkpointed offset of a partition, or an empty string if none was checkpointed
+	Load(partitionID int) (string, error)
+
+	// Save checkpoints the offset of a partition
+	Save(partitionID int, offset string) error
+}
+
+// blobCheckpointStore keeps a blob per partition named <event hub>/<consumer group>/<partition ID>, holding the
+// offset of the last event handled in it
+type blobCheckpointStore struct {
+	container    *storage.Container
+	eventHubName string
+	consumer     string
+}
+
+func newBlobCheckpointStore(configuration *Configuration) (*blobCheckpointStore, error) {
+	client, err := storage.NewClientFromConnectionString(configuration.Checkpointing.ConnectionString)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create storage client")
+	}
+
+	blobService := client.GetBlobService()
+	container := blobService.GetContainerReference(configuration.Checkpointing.Container)
+
+	if _, err := container.CreateIfNotExists(nil); err != nil {
+		return nil, errors.Wrapf(err, "Failed to create checkpoint container %s", container.Name)
+	}
+
+	return &blobCheckpointStore{
+		container:    container,
+		eventHubName: configuration.EventHubName,
+		consumer:     configuration.ConsumerGroup,
+	}, nil
+}
+
+func (bcs *blobCheckpointStore) Load(partitionID int) (string, error) {
+	blob := bcs.getBlob(partitionID)
+
+	reader, err := blob.Get(nil)
+	if err != nil {
+		if storageError, ok := err.(storage.AzureStorageServiceError); ok &&
+			storageError.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+
+		return "", errors.Wrapf(err, "Failed to read checkpoint blob %s", blob.Name)
+	}
+
+	defer reader.Close() // nolint: errcheck
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to read checkpoint blob %s", blob.Name)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (bcs *blobCheckpointStore) Save(partitionID int, offset string) error {
+	blob := bcs.getBlob(partitionID)
+
+	if err := blob.CreateBlockBlobFromReader(bytes.NewBufferString(offset), nil); err != nil {
+		return errors.Wrapf(err, "Failed to write checkpoint blob %s", blob.Name)
+	}
+
+	return nil
+}
+
+func (bcs *blobCheckpointStore) getBlob(partitionID int) *storage.Blob {
+	return bcs.container.GetBlobReference(fmt.Sprintf("%s/%s/%d", bcs.eventHubName, bcs.consumer, partitionID))
+}
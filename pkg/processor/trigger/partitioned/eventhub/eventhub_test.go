@@ -0,0 +1,136 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhub
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/stretchr/testify/suite"
+	"pack.ag/amqp"
+)
+
+type memoryCheckpointStore struct {
+	offsets map[int]string
+	saves   int
+}
+
+func (mcs *memoryCheckpointStore) Load(partitionID int) (string, error) {
+	return mcs.offsets[partitionID], nil
+}
+
+func (mcs *memoryCheckpointStore) Save(partitionID int, offset string) error {
+	mcs.offsets[partitionID] = offset
+	mcs.saves++
+	return nil
+}
+
+type eventhubTestSuite struct {
+	suite.Suite
+}
+
+func (suite *eventhubTestSuite) TestSecretRefs() {
+	os.Setenv("NUCLIO_TEST_EVENTHUB_KEY", "key-value")                        // nolint: errcheck
+	os.Setenv("NUCLIO_TEST_STORAGE_CONNECTION", "AccountName=a;AccountKey=b") // nolint: errcheck
+	defer os.Unsetenv("NUCLIO_TEST_EVENTHUB_KEY")                             // nolint: errcheck
+	defer os.Unsetenv("NUCLIO_TEST_STORAGE_CONNECTION")                       // nolint: errcheck
+
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"sharedAccessKeyValueFrom": map[string]interface{}{
+			"name": "eventhub",
+			"key":  "NUCLIO_TEST_EVENTHUB_KEY",
+		},
+		"checkpointing": map[string]interface{}{
+			"connectionStringFrom": map[string]interface{}{
+				"name": "storage",
+				"key":  "NUCLIO_TEST_STORAGE_CONNECTION",
+			},
+			"container": "checkpoints",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("key-value", configuration.SharedAccessKeyValue)
+	suite.Require().Equal("AccountName=a;AccountKey=b", configuration.Checkpointing.ConnectionString)
+	suite.Require().Equal(defaultCheckpointInterval, configuration.Checkpointing.Interval)
+	suite.Require().Equal("$Default", configuration.ConsumerGroup)
+
+	// an unset reference fails the configuration
+	_, err = suite.newConfiguration(map[string]interface{}{
+		"sharedAccessKeyValueFrom": map[string]interface{}{
+			"key": "NUCLIO_TEST_MISSING_EVENTHUB_KEY",
+		},
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *eventhubTestSuite) TestInvalidCheckpointing() {
+	for _, checkpointing := range []map[string]interface{}{
+		{"container": "checkpoints"},
+		{"connectionString": "AccountName=a;AccountKey=b"},
+		{"connectionString": "AccountName=a;AccountKey=b", "container": "checkpoints", "interval": -1},
+	} {
+		_, err := suite.newConfiguration(map[string]interface{}{
+			"checkpointing": checkpointing,
+		})
+		suite.Require().Error(err, "checkpointing: %v", checkpointing)
+	}
+}
+
+func (suite *eventhubTestSuite) TestCheckpoint() {
+	checkpointStore := &memoryCheckpointStore{offsets: map[int]string{}}
+	testPartition := &partition{
+		partitionID: 3,
+		eventhubTrigger: &eventhub{
+			configuration: &Configuration{
+				Checkpointing: &Checkpointing{Interval: 2},
+			},
+			checkpointStore: checkpointStore,
+		},
+	}
+
+	for _, offset := range []string{"100", "200", "300"} {
+		message := &amqp.Message{Annotations: amqp.Annotations{offsetAnnotation: offset}}
+		suite.Require().NoError(testPartition.checkpoint(getMessageOffset(message)))
+	}
+
+	// only the second event reached the interval
+	suite.Require().Equal(1, checkpointStore.saves)
+	suite.Require().Equal("200", checkpointStore.offsets[3])
+
+	// messages without an offset aren't counted
+	suite.Require().NoError(testPartition.checkpoint(getMessageOffset(&amqp.Message{})))
+	suite.Require().Equal(1, checkpointStore.saves)
+
+	suite.Require().Equal("amqp.annotation.x-opt-offset > '200'", getOffsetFilter(checkpointStore.offsets[3]))
+}
+
+func (suite *eventhubTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	return NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "eventhub",
+		Attributes: attributes,
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	})
+}
+
+func TestEventhubTestSuite(t *testing.T) {
+	suite.Run(t, new(eventhubTestSuite))
+}
@@ -27,11 +27,15 @@ import (
 	"pack.ag/amqp"
 )
 
+const offsetAnnotation = "x-opt-offset"
+
 type partition struct {
 	*partitioned.AbstractPartition
-	partitionID     int
-	event           Event
-	eventhubTrigger *eventhub
+	partitionID              int
+	event                    Event
+	eventhubTrigger          *eventhub
+	eventsSinceCheckpoint    int
+	lastUncheckpointedOffset string
 }
 
 func newPartition(parentLogger logger.Logger, eventhubTrigger *eventhub, partitionID int) (*partition, error) {
@@ -64,10 +68,25 @@ func (p *partition) Read() error {
 		p.eventhubTrigger.configuration.ConsumerGroup,
 		p.partitionID)
 
-	receiver, err := p.eventhubTrigger.eventhubSession.NewReceiver(
+	linkOptions := []amqp.LinkOption{
 		amqp.LinkSourceAddress(address),
 		amqp.LinkCredit(10),
-	)
+	}
+
+	// resume after the last checkpointed event, if any
+	if checkpointStore := p.eventhubTrigger.checkpointStore; checkpointStore != nil {
+		offset, err := checkpointStore.Load(p.partitionID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load checkpoint")
+		}
+
+		if offset != "" {
+			p.Logger.DebugWith("Resuming from checkpoint", "offset", offset)
+			linkOptions = append(linkOptions, amqp.LinkSelectorFilter(getOffsetFilter(offset)))
+		}
+	}
+
+	receiver, err := p.eventhubTrigger.eventhubSession.NewReceiver(linkOptions...)
 
 	if err != nil {
 		return errors.Wrap(err, "Error creating receiver link")
@@ -95,5 +114,45 @@ func (p *partition) Read() error {
 
 		// process the event, don't really do anything with response
 		p.eventhubTrigger.SubmitEventToWorker(nil, p.Worker, &p.event) // nolint: errcheck
+
+		if err := p.checkpoint(getMessageOffset(msg)); err != nil {
+			p.Logger.WarnWith("Failed to checkpoint", "err", errors.GetErrorStackString(err, 10))
+		}
+	}
+}
+
+// checkpoint records the offset of a handled event, saving it every checkpointing interval
+func (p *partition) checkpoint(offset string) error {
+	checkpointStore := p.eventhubTrigger.checkpointStore
+	if checkpointStore == nil || offset == "" {
+		return nil
 	}
+
+	p.lastUncheckpointedOffset = offset
+	p.eventsSinceCheckpoint++
+
+	if p.eventsSinceCheckpoint < p.eventhubTrigger.configuration.Checkpointing.Interval {
+		return nil
+	}
+
+	if err := checkpointStore.Save(p.partitionID, p.lastUncheckpointedOffset); err != nil {
+		return errors.Wrap(err, "Failed to save checkpoint")
+	}
+
+	p.eventsSinceCheckpoint = 0
+
+	return nil
+}
+
+func getOffsetFilter(offset string) string {
+	return fmt.Sprintf("amqp.annotation.%s > '%s'", offsetAnnotation, offset)
+}
+
+func getMessageOffset(msg *amqp.Message) string {
+	offset, found := msg.Annotations[offsetAnnotation]
+	if !found || offset == nil {
+		return ""
+	}
+
+	return fmt.Sprint(offset)
 }
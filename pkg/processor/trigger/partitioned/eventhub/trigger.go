@@ -32,6 +32,7 @@ type eventhub struct {
 	configuration   *Configuration
 	eventhubSession *eventhubclient.Session
 	partitions      []*partition
+	checkpointStore checkpointStore
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -61,6 +62,13 @@ func newTrigger(parentLogger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create eventhub session")
 	}
 
+	if configuration.Checkpointing != nil {
+		newTrigger.checkpointStore, err = newBlobCheckpointStore(configuration)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create checkpoint store")
+		}
+	}
+
 	return newTrigger, nil
 }
 
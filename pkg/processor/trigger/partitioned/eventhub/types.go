@@ -19,20 +19,36 @@ package eventhub
 import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/trigger/partitioned"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
 )
 
+const defaultCheckpointInterval = 10
+
+// Checkpointing configures persisting the offset of the last handled event of each partition to an Azure blob
+// storage container, so that a restarted trigger resumes where it stopped rather than at the end of the stream
+type Checkpointing struct {
+	ConnectionString     string
+	ConnectionStringFrom trigger.SecretRef
+	Container            string
+
+	// the number of events handled in a partition between checkpoints
+	Interval int
+}
+
 type Configuration struct {
 	partitioned.Configuration
-	SharedAccessKeyName  string
-	SharedAccessKeyValue string
-	Namespace            string
-	EventHubName         string
-	ConsumerGroup        string
-	Partitions           []int
+	SharedAccessKeyName      string
+	SharedAccessKeyValue     string
+	SharedAccessKeyValueFrom trigger.SecretRef
+	Namespace                string
+	EventHubName             string
+	ConsumerGroup            string
+	Partitions               []int
+	Checkpointing            *Checkpointing
 }
 
 func NewConfiguration(ID string,
@@ -52,5 +68,41 @@ func NewConfiguration(ID string,
 		newConfiguration.ConsumerGroup = "$Default"
 	}
 
+	if newConfiguration.SharedAccessKeyValue == "" && newConfiguration.SharedAccessKeyValueFrom.Key != "" {
+		sharedAccessKeyValue, err := newConfiguration.SharedAccessKeyValueFrom.Resolve()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve shared access key value")
+		}
+
+		newConfiguration.SharedAccessKeyValue = sharedAccessKeyValue
+	}
+
+	if checkpointing := newConfiguration.Checkpointing; checkpointing != nil {
+		if checkpointing.ConnectionString == "" && checkpointing.ConnectionStringFrom.Key != "" {
+			connectionString, err := checkpointing.ConnectionStringFrom.Resolve()
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to resolve checkpointing connection string")
+			}
+
+			checkpointing.ConnectionString = connectionString
+		}
+
+		if checkpointing.ConnectionString == "" {
+			return nil, errors.New("Checkpointing requires a storage connection string")
+		}
+
+		if checkpointing.Container == "" {
+			return nil, errors.New("Checkpointing requires a storage container")
+		}
+
+		if checkpointing.Interval < 0 {
+			return nil, errors.New("Checkpointing interval must not be negative")
+		}
+
+		if checkpointing.Interval == 0 {
+			checkpointing.Interval = defaultCheckpointInterval
+		}
+	}
+
 	return &newConfiguration, nil
 }
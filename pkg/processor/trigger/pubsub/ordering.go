@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"sync"
+)
+
+// orderingKeyLocker serializes the processing of messages sharing an ordering key, while allowing messages with
+// different keys (or no key at all) to be processed concurrently
+type orderingKeyLocker struct {
+	lock  sync.Mutex
+	locks map[string]*orderingKeyLock
+}
+
+type orderingKeyLock struct {
+	sync.Mutex
+	references int
+}
+
+func newOrderingKeyLocker() *orderingKeyLocker {
+	return &orderingKeyLocker{
+		locks: map[string]*orderingKeyLock{},
+	}
+}
+
+// Lock blocks until no other message with the given ordering key is being processed. an empty key never blocks
+func (okl *orderingKeyLocker) Lock(orderingKey string) {
+	if orderingKey == "" {
+		return
+	}
+
+	okl.lock.Lock()
+	keyLock, found := okl.locks[orderingKey]
+	if !found {
+		keyLock = &orderingKeyLock{}
+		okl.locks[orderingKey] = keyLock
+	}
+	keyLock.references++
+	okl.lock.Unlock()
+
+	keyLock.Lock()
+}
+
+// Unlock releases the ordering key, forgetting it once no message holds or waits for it
+func (okl *orderingKeyLocker) Unlock(orderingKey string) {
+	if orderingKey == "" {
+		return
+	}
+
+	okl.lock.Lock()
+	defer okl.lock.Unlock()
+
+	keyLock := okl.locks[orderingKey]
+	keyLock.references--
+	if keyLock.references == 0 {
+		delete(okl.locks, orderingKey)
+	}
+
+	keyLock.Unlock()
+}
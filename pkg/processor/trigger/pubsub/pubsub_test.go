@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type pubsubTestSuite struct {
+	suite.Suite
+}
+
+func (suite *pubsubTestSuite) TestMaxExtension() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"maxExtension": "5m",
+		"subscriptions": []map[string]interface{}{
+			{"topic": "inherits"},
+			{"topic": "overrides", "maxExtension": "30s"},
+		},
+	})
+	suite.Require().NoError(err)
+
+	maxExtension, err := configuration.getMaxExtension(&configuration.Subscriptions[0])
+	suite.Require().NoError(err)
+	suite.Require().Equal(5*time.Minute, maxExtension)
+
+	maxExtension, err = configuration.getMaxExtension(&configuration.Subscriptions[1])
+	suite.Require().NoError(err)
+	suite.Require().Equal(30*time.Second, maxExtension)
+
+	_, err = suite.newConfiguration(map[string]interface{}{
+		"subscriptions": []map[string]interface{}{
+			{"topic": "invalid", "maxExtension": "forever"},
+		},
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *pubsubTestSuite) TestOrderingKeyLocker() {
+	locker := newOrderingKeyLocker()
+	processing := map[string]int{}
+	processingLock := sync.Mutex{}
+	waitGroup := sync.WaitGroup{}
+
+	for messageIdx := 0; messageIdx < 20; messageIdx++ {
+		orderingKey := []string{"a", "b"}[messageIdx%2]
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			locker.Lock(orderingKey)
+			defer locker.Unlock(orderingKey)
+
+			processingLock.Lock()
+			processing[orderingKey]++
+			suite.Equal(1, processing[orderingKey])
+			processingLock.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			processingLock.Lock()
+			processing[orderingKey]--
+			processingLock.Unlock()
+		}()
+	}
+
+	waitGroup.Wait()
+
+	// keys are forgotten once released
+	suite.Require().Empty(locker.locks)
+
+	// an empty key never blocks
+	locker.Lock("")
+	locker.Lock("")
+	locker.Unlock("")
+	locker.Unlock("")
+}
+
+func (suite *pubsubTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	return NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "pubsub",
+		Attributes: attributes,
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	})
+}
+
+func TestPubsubTestSuite(t *testing.T) {
+	suite.Run(t, new(pubsubTestSuite))
+}
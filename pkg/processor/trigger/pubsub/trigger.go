@@ -66,16 +66,23 @@ func newTrigger(parentLogger logger.Logger,
 func (p *pubsub) Start(checkpoint functionconfig.Checkpoint) error {
 	var err error
 
-	// TODO: find a better way to do this
-	serviceAccountFilePath := "/tmp/service-account.json"
-	if err := ioutil.WriteFile(serviceAccountFilePath,
-		[]byte(p.configuration.Credentials.Contents),
-		0600); err != nil {
-		return errors.Wrap(err, "Failed to write temporary service account")
+	credentials, err := p.configuration.Credentials.Resolve()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve credentials")
 	}
 
-	if err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", serviceAccountFilePath); err != nil {
-		return errors.Wrap(err, "Failed to set credentials env")
+	// without credentials, fall back to the application default credentials (e.g. workload identity)
+	if credentials != "" {
+
+		// TODO: find a better way to do this
+		serviceAccountFilePath := "/tmp/service-account.json"
+		if err := ioutil.WriteFile(serviceAccountFilePath, []byte(credentials), 0600); err != nil {
+			return errors.Wrap(err, "Failed to write temporary service account")
+		}
+
+		if err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", serviceAccountFilePath); err != nil {
+			return errors.Wrap(err, "Failed to set credentials env")
+		}
 	}
 
 	p.Logger.InfoWith("Starting",
@@ -160,6 +167,19 @@ func (p *pubsub) receiveFromSubscription(subscriptionConfig *Subscription) error
 	// https://godoc.org/cloud.google.com/go/pubsub#ReceiveSettings
 	subscription.ReceiveSettings.NumGoroutines = subscriptionConfig.MaxNumWorkers
 
+	// messages are received over a streaming pull, and the client keeps extending their ack deadline while they're
+	// being processed - up to the max extension
+	maxExtension, err := p.configuration.getMaxExtension(subscriptionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse max extension")
+	}
+
+	if maxExtension != 0 {
+		subscription.ReceiveSettings.MaxExtension = maxExtension
+	}
+
+	orderingKeyLocker := newOrderingKeyLocker()
+
 	// create a channel of events
 	eventsChan := make(chan *Event, subscriptionConfig.MaxNumWorkers)
 
@@ -170,11 +190,18 @@ func (p *pubsub) receiveFromSubscription(subscriptionConfig *Subscription) error
 	}
 
 	p.Logger.DebugWith("Reading from subscription",
-		"subscription.ReceiveSettings.NumGoroutines", subscription.ReceiveSettings.NumGoroutines)
+		"subscription.ReceiveSettings.NumGoroutines", subscription.ReceiveSettings.NumGoroutines,
+		"subscription.ReceiveSettings.MaxExtension", subscription.ReceiveSettings.MaxExtension,
+		"orderingKeyAttribute", subscriptionConfig.OrderingKeyAttribute)
 
 	// listen to subscribed topic messages
 	err = subscription.Receive(ctx, func(ctx context.Context, message *pubsubClient.Message) {
 
+		// wait for messages with the same ordering key to be handled
+		orderingKey := p.getOrderingKey(subscriptionConfig, message)
+		orderingKeyLocker.Lock(orderingKey)
+		defer orderingKeyLocker.Unlock(orderingKey)
+
 		// get an event
 		event := <-eventsChan
 
@@ -223,6 +250,14 @@ func (p *pubsub) getSubscriptionID(subscriptionConfig *Subscription) string {
 	return subscriptionID + "-" + xid.New().String()
 }
 
+func (p *pubsub) getOrderingKey(subscriptionConfig *Subscription, message *pubsubClient.Message) string {
+	if subscriptionConfig.OrderingKeyAttribute == "" {
+		return ""
+	}
+
+	return message.Attributes[subscriptionConfig.OrderingKeyAttribute]
+}
+
 func (p *pubsub) getAckDeadline(subscriptionConfig *Subscription) (time.Duration, error) {
 	var ackDeadlineString string
 
@@ -17,6 +17,8 @@ limitations under the License.
 package pubsub
 
 import (
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -30,6 +32,13 @@ type Subscription struct {
 	MaxNumWorkers int
 	Shared        bool
 	AckDeadline   string
+
+	// the maximum period for which the ack deadline of a message being processed is extended
+	MaxExtension string
+
+	// the name of the message attribute holding the ordering key. messages sharing an ordering key are
+	// processed one at a time
+	OrderingKeyAttribute string
 }
 
 type Configuration struct {
@@ -37,6 +46,7 @@ type Configuration struct {
 	Subscriptions []Subscription
 	ProjectID     string
 	AckDeadline   string
+	MaxExtension  string
 	Credentials   trigger.Secret
 }
 
@@ -62,7 +72,26 @@ func NewConfiguration(ID string,
 		if subscriptions.MaxNumWorkers == 0 {
 			newConfiguration.Subscriptions[subscriptionIdx].MaxNumWorkers = 1
 		}
+
+		if _, err := newConfiguration.getMaxExtension(&newConfiguration.Subscriptions[subscriptionIdx]); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse max extension of subscription %s", subscriptions.Topic)
+		}
 	}
 
 	return &newConfiguration, nil
 }
+
+// getMaxExtension returns the max extension of the subscription, falling back to that of the trigger. zero means
+// the client's default
+func (c *Configuration) getMaxExtension(subscriptionConfig *Subscription) (time.Duration, error) {
+	maxExtensionString := subscriptionConfig.MaxExtension
+	if maxExtensionString == "" {
+		maxExtensionString = c.MaxExtension
+	}
+
+	if maxExtensionString == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(maxExtensionString)
+}
@@ -18,6 +18,8 @@ package rabbitmq
 
 import (
 	"fmt"
+	"math/rand"
+	"net/http"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -27,9 +29,13 @@ import (
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/streadway/amqp"
 )
 
+// the header replies carry the handler's status code in
+const replyStatusCodeHeader = "X-Nuclio-Status-Code"
+
 type rabbitMq struct {
 	trigger.AbstractTrigger
 	event                      Event
@@ -39,6 +45,12 @@ type rabbitMq struct {
 	brokerQueue                amqp.Queue
 	brokerInputMessagesChannel <-chan amqp.Delivery
 	worker                     *worker.Worker
+
+	// when replying to messages, the broker confirms the replies published on the channel in order
+	brokerConfirmationsChannel chan amqp.Confirmation
+	publishedReplies           uint64
+
+	stopChannel chan struct{}
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -58,6 +70,7 @@ func newTrigger(parentLogger logger.Logger,
 	newTrigger := rabbitMq{
 		AbstractTrigger: abstractTrigger,
 		configuration:   configuration,
+		stopChannel:     make(chan struct{}),
 	}
 
 	return &newTrigger, nil
@@ -87,8 +100,13 @@ func (rmq *rabbitMq) Start(checkpoint functionconfig.Checkpoint) error {
 }
 
 func (rmq *rabbitMq) Stop(force bool) (functionconfig.Checkpoint, error) {
+	close(rmq.stopChannel)
+
+	// closing the connection closes the deliveries channel, and with it stops handling messages
+	if rmq.brokerConn != nil {
+		rmq.brokerConn.Close() // nolint: errcheck
+	}
 
-	// TODO
 	return nil, nil
 }
 
@@ -128,6 +146,22 @@ func (rmq *rabbitMq) createBrokerResources() error {
 
 	rmq.Logger.DebugWith("Created broker channel")
 
+	if rmq.configuration.Prefetch > 0 {
+		if err := rmq.brokerChannel.Qos(rmq.configuration.Prefetch, 0, false); err != nil {
+			return errors.Wrap(err, "Failed to set prefetch")
+		}
+	}
+
+	if rmq.configuration.ReplyToResponses {
+		if err := rmq.brokerChannel.Confirm(false); err != nil {
+			return errors.Wrap(err, "Failed to put channel in confirm mode")
+		}
+
+		// delivery tags restart with the channel
+		rmq.publishedReplies = 0
+		rmq.brokerConfirmationsChannel = rmq.brokerChannel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
 	// create exchange and queue only if user provided topics, else assuming the user did all the necessary configuration
 	// to support listening on the provided exchange and queue
 	if len(rmq.configuration.Topics) > 0 {
@@ -146,13 +180,22 @@ func (rmq *rabbitMq) createBrokerResources() error {
 
 		rmq.Logger.DebugWith("Declared exchange", "exchangeName", rmq.configuration.ExchangeName)
 
+		// quorum queues must be durable
+		durable := false
+		var queueArguments amqp.Table
+
+		if rmq.configuration.QueueType == QueueTypeQuorum {
+			durable = true
+			queueArguments = amqp.Table{"x-queue-type": QueueTypeQuorum}
+		}
+
 		rmq.brokerQueue, err = rmq.brokerChannel.QueueDeclare(
 			rmq.configuration.QueueName, // queue name (account  + function name)
-			false,                       // durable  TBD: change to true if/when we bind to persistent storage
+			durable,                     // durable
 			false,                       // delete when unused
 			false,                       // exclusive
 			false,                       // no-wait
-			nil,                         // arguments
+			queueArguments,              // arguments
 		)
 		if err != nil {
 			return errors.Wrap(err, "Failed to declare queue")
@@ -197,19 +240,192 @@ func (rmq *rabbitMq) createBrokerResources() error {
 }
 
 func (rmq *rabbitMq) handleBrokerMessages() {
-	for message := range rmq.brokerInputMessagesChannel {
+	for {
+		for message := range rmq.brokerInputMessagesChannel {
+			rmq.handleBrokerMessage(&message)
+		}
+
+		// the deliveries channel is closed along with the connection - either by stopping, or since it was lost
+		// (e.g. the broker restarted)
+		select {
+		case <-rmq.stopChannel:
+			return
+		default:
+		}
+
+		rmq.Logger.WarnWith("Lost connection to broker, reconnecting", "brokerUrl", rmq.configuration.URL)
+
+		if !rmq.reconnect() {
+			return
+		}
+	}
+}
+
+func (rmq *rabbitMq) handleBrokerMessage(message *amqp.Delivery) {
+
+	// bind to delivery
+	rmq.event.message = message
+
+	// submit to worker
+	response, submitError, processError := rmq.AllocateWorkerAndSubmitEvent(&rmq.event, nil, 10*time.Second)
+	if submitError != nil {
+		rmq.Logger.WarnWith("Failed to submit to worker", "err", submitError)
+		return
+	}
+
+	if rmq.configuration.ReplyToResponses && message.ReplyTo != "" {
+		if err := rmq.publishReply(message, response, processError); err != nil {
+			rmq.Logger.WarnWith("Failed to publish reply",
+				"replyTo", message.ReplyTo,
+				"err", errors.RootCause(err).Error())
+		}
+	}
 
-		// bind to delivery
-		rmq.event.message = &message
+	// ack the message since we didn't fail to submit
+	message.Ack(false) // nolint: errcheck
+}
+
+// reconnect creates the broker resources again, until it succeeds or the trigger is stopped. returns whether
+// it succeeded
+func (rmq *rabbitMq) reconnect() bool {
+	for attempt := 0; ; attempt++ {
+		reconnectInterval := rmq.getReconnectInterval(attempt)
+
+		select {
+		case <-rmq.stopChannel:
+			return false
+		case <-time.After(reconnectInterval):
+		}
+
+		err := rmq.createBrokerResources()
+		if err == nil {
+			rmq.Logger.InfoWith("Reconnected to broker", "brokerUrl", rmq.configuration.URL, "attempts", attempt+1)
+			return true
+		}
 
-		// submit to worker
-		_, submitError, _ := rmq.AllocateWorkerAndSubmitEvent(&rmq.event, nil, 10*time.Second)
+		rmq.Logger.WarnWith("Failed to reconnect to broker",
+			"brokerUrl", rmq.configuration.URL,
+			"attempt", attempt+1,
+			"err", errors.RootCause(err).Error())
 
-		// ack the message if we didn't fail to submit
-		if submitError == nil {
-			message.Ack(false) // nolint: errcheck
-		} else {
-			rmq.Logger.WarnWith("Failed to submit to worker", "err", submitError)
+		// the connection may have been created before failing
+		if rmq.brokerConn != nil {
+			rmq.brokerConn.Close() // nolint: errcheck
 		}
 	}
 }
+
+// getReconnectInterval returns how long to wait before a reconnect attempt - the min interval, doubled on every
+// attempt up to the max interval, and randomized down to half of it so that replicas don't reconnect in unison
+func (rmq *rabbitMq) getReconnectInterval(attempt int) time.Duration {
+	reconnectInterval := rmq.configuration.reconnectIntervalMin
+	for ; attempt > 0 && reconnectInterval < rmq.configuration.reconnectIntervalMax; attempt-- {
+		reconnectInterval *= 2
+	}
+
+	if reconnectInterval > rmq.configuration.reconnectIntervalMax {
+		reconnectInterval = rmq.configuration.reconnectIntervalMax
+	}
+
+	return reconnectInterval/2 + time.Duration(rand.Int63n(int64(reconnectInterval/2)+1))
+}
+
+// publishReply publishes the handler's response to the message's reply-to queue, through the default exchange,
+// and waits for the broker to confirm it
+func (rmq *rabbitMq) publishReply(message *amqp.Delivery, response interface{}, processError error) error {
+	reply, err := rmq.getReply(response, processError)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create reply")
+	}
+
+	reply.CorrelationId = message.CorrelationId
+
+	if err := rmq.brokerChannel.Publish("", message.ReplyTo, false, false, *reply); err != nil {
+		return errors.Wrap(err, "Failed to publish reply")
+	}
+
+	rmq.publishedReplies++
+
+	timeout := time.After(rmq.configuration.replyConfirmTimeout)
+
+	// skip confirmations of replies which timed out earlier
+	for {
+		select {
+		case confirmation, ok := <-rmq.brokerConfirmationsChannel:
+			if !ok {
+				return errors.New("Channel closed before the reply was confirmed")
+			}
+
+			if confirmation.DeliveryTag < rmq.publishedReplies {
+				continue
+			}
+
+			if !confirmation.Ack {
+				return errors.New("Broker rejected the reply")
+			}
+
+			return nil
+
+		case <-timeout:
+			return errors.Errorf("Timed out waiting for the broker to confirm the reply after %s",
+				rmq.configuration.replyConfirmTimeout)
+		}
+	}
+}
+
+func (rmq *rabbitMq) getReply(response interface{}, processError error) (*amqp.Publishing, error) {
+	reply := &amqp.Publishing{
+		Headers: amqp.Table{},
+	}
+
+	statusCode := http.StatusOK
+
+	if processError != nil {
+		reply.ContentType = "text/plain"
+		reply.Body = []byte(processError.Error())
+		statusCode = http.StatusInternalServerError
+
+		// the handler may have returned an error with a status code
+		switch typedError := processError.(type) {
+		case nuclio.ErrorWithStatusCode:
+			statusCode = typedError.StatusCode()
+		case *nuclio.ErrorWithStatusCode:
+			statusCode = typedError.StatusCode()
+		}
+	} else {
+		switch typedResponse := response.(type) {
+		case nil:
+		case nuclio.Response:
+			statusCode = rmq.populateReply(reply, &typedResponse)
+		case *nuclio.Response:
+			statusCode = rmq.populateReply(reply, typedResponse)
+		case []byte:
+			reply.Body = typedResponse
+		case string:
+			reply.Body = []byte(typedResponse)
+		default:
+			return nil, errors.Errorf("Unsupported response type: %T", response)
+		}
+	}
+
+	reply.Headers[replyStatusCodeHeader] = int32(statusCode)
+
+	return reply, nil
+}
+
+// populateReply sets the reply from a nuclio response, returning its status code
+func (rmq *rabbitMq) populateReply(reply *amqp.Publishing, response *nuclio.Response) int {
+	reply.Body = response.Body
+	reply.ContentType = response.ContentType
+
+	// amqp only supports some header value types
+	for headerKey, headerValue := range response.Headers {
+		reply.Headers[headerKey] = fmt.Sprint(headerValue)
+	}
+
+	if response.StatusCode != 0 {
+		return response.StatusCode
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rabbitmq
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+)
+
+// covers what doesn't require a broker
+type triggerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *triggerTestSuite) TestConfigurationDefaults() {
+	configuration := suite.createConfiguration(nil)
+
+	suite.Require().Equal(QueueTypeClassic, configuration.QueueType)
+	suite.Require().Equal(time.Second, configuration.reconnectIntervalMin)
+	suite.Require().Equal(time.Minute, configuration.reconnectIntervalMax)
+	suite.Require().Equal(10*time.Second, configuration.replyConfirmTimeout)
+
+	configuration = suite.createConfiguration(map[string]interface{}{
+		"queueType":            "quorum",
+		"prefetch":             8,
+		"reconnectIntervalMin": "100ms",
+		"reconnectIntervalMax": "5s",
+		"replyToResponses":     true,
+	})
+
+	suite.Require().Equal(QueueTypeQuorum, configuration.QueueType)
+	suite.Require().Equal(8, configuration.Prefetch)
+	suite.Require().Equal(100*time.Millisecond, configuration.reconnectIntervalMin)
+	suite.Require().True(configuration.ReplyToResponses)
+
+	for _, attributes := range []map[string]interface{}{
+		{"queueType": "stream"},
+		{"prefetch": -1},
+		{"reconnectIntervalMin": "soon"},
+		{"reconnectIntervalMin": "10s", "reconnectIntervalMax": "1s"},
+	} {
+		_, err := NewConfiguration("test", &functionconfig.Trigger{
+			Kind:       "rabbit-mq",
+			Attributes: attributes,
+		}, suite.createRuntimeConfiguration())
+		suite.Require().Error(err, attributes)
+	}
+}
+
+func (suite *triggerTestSuite) TestGetReconnectInterval() {
+	trigger := rabbitMq{
+		configuration: suite.createConfiguration(map[string]interface{}{
+			"reconnectIntervalMin": "1s",
+			"reconnectIntervalMax": "10s",
+		}),
+	}
+
+	for _, testCase := range []struct {
+		attempt         int
+		maxIntervalWant time.Duration
+	}{
+		{attempt: 0, maxIntervalWant: 1 * time.Second},
+		{attempt: 1, maxIntervalWant: 2 * time.Second},
+		{attempt: 3, maxIntervalWant: 8 * time.Second},
+		{attempt: 4, maxIntervalWant: 10 * time.Second},
+		{attempt: 100, maxIntervalWant: 10 * time.Second},
+	} {
+
+		// jittered down to half of the interval
+		for i := 0; i < 10; i++ {
+			reconnectInterval := trigger.getReconnectInterval(testCase.attempt)
+			suite.Require().True(reconnectInterval >= testCase.maxIntervalWant/2, reconnectInterval)
+			suite.Require().True(reconnectInterval <= testCase.maxIntervalWant, reconnectInterval)
+		}
+	}
+}
+
+func (suite *triggerTestSuite) TestGetReply() {
+	trigger := rabbitMq{}
+
+	reply, err := trigger.getReply(nuclio.Response{
+		StatusCode:  http.StatusCreated,
+		ContentType: "application/json",
+		Body:        []byte(`{"created": true}`),
+		Headers:     map[string]interface{}{"X-Count": 3},
+	}, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("application/json", reply.ContentType)
+	suite.Require().Equal(`{"created": true}`, string(reply.Body))
+	suite.Require().Equal(int32(http.StatusCreated), reply.Headers[replyStatusCodeHeader])
+	suite.Require().Equal("3", reply.Headers["X-Count"])
+
+	reply, err = trigger.getReply("hello", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("hello", string(reply.Body))
+	suite.Require().Equal(int32(http.StatusOK), reply.Headers[replyStatusCodeHeader])
+
+	reply, err = trigger.getReply(nil, nuclio.NewErrBadRequest("Bad input"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("Bad input", string(reply.Body))
+	suite.Require().Equal(int32(http.StatusBadRequest), reply.Headers[replyStatusCodeHeader])
+
+	reply, err = trigger.getReply(nil, errors.New("Failed"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int32(http.StatusInternalServerError), reply.Headers[replyStatusCodeHeader])
+
+	_, err = trigger.getReply(42, nil)
+	suite.Require().Error(err)
+}
+
+func (suite *triggerTestSuite) createConfiguration(attributes map[string]interface{}) *Configuration {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "rabbit-mq",
+		Attributes: attributes,
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	return configuration
+}
+
+func (suite *triggerTestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+}
+
+func TestTriggerTestSuite(t *testing.T) {
+	suite.Run(t, new(triggerTestSuite))
+}
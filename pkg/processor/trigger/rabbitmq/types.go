@@ -17,6 +17,8 @@ limitations under the License.
 package rabbitmq
 
 import (
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -25,11 +27,36 @@ import (
 	"github.com/nuclio/errors"
 )
 
+const (
+	QueueTypeClassic = "classic"
+	QueueTypeQuorum  = "quorum"
+)
+
 type Configuration struct {
 	trigger.Configuration
 	ExchangeName string
 	QueueName    string
 	Topics       []string
+
+	// the type of queue declared when topics are given - classic (the default) or quorum. quorum queues are
+	// replicated and durable, so they (and the messages in them) survive a broker restart
+	QueueType string
+
+	// the number of unacknowledged messages the broker delivers at a time. 0 for unlimited
+	Prefetch int
+
+	// after losing the connection, reconnecting is retried with exponential backoff (and jitter) between these
+	ReconnectIntervalMin string
+	ReconnectIntervalMax string
+
+	// publish handler responses to the queue named in the reply-to property of the messages which have it,
+	// waiting for the broker to confirm them
+	ReplyToResponses    bool
+	ReplyConfirmTimeout string
+
+	reconnectIntervalMin time.Duration
+	reconnectIntervalMax time.Duration
+	replyConfirmTimeout  time.Duration
 }
 
 func NewConfiguration(ID string,
@@ -45,7 +72,50 @@ func NewConfiguration(ID string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
-	// TODO: validate
+	switch newConfiguration.QueueType {
+	case "":
+		newConfiguration.QueueType = QueueTypeClassic
+	case QueueTypeClassic, QueueTypeQuorum:
+	default:
+		return nil, errors.Errorf("Queue type must be either %s or %s, not %s",
+			QueueTypeClassic,
+			QueueTypeQuorum,
+			newConfiguration.QueueType)
+	}
+
+	if newConfiguration.Prefetch < 0 {
+		return nil, errors.New("Prefetch must not be negative")
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "min reconnect interval",
+			Value:   newConfiguration.ReconnectIntervalMin,
+			Field:   &newConfiguration.reconnectIntervalMin,
+			Default: 1 * time.Second,
+		},
+		{
+			Name:    "max reconnect interval",
+			Value:   newConfiguration.ReconnectIntervalMax,
+			Field:   &newConfiguration.reconnectIntervalMax,
+			Default: 1 * time.Minute,
+		},
+		{
+			Name:    "reply confirm timeout",
+			Value:   newConfiguration.ReplyConfirmTimeout,
+			Field:   &newConfiguration.replyConfirmTimeout,
+			Default: 10 * time.Second,
+		},
+	} {
+		if err := newConfiguration.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, err
+		}
+	}
+
+	if newConfiguration.reconnectIntervalMin <= 0 ||
+		newConfiguration.reconnectIntervalMax < newConfiguration.reconnectIntervalMin {
+		return nil, errors.New("Reconnect intervals must be positive, and the max must not be lower than the min")
+	}
 
 	return &newConfiguration, nil
 }
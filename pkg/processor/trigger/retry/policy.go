@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Policy decides whether the outcome of processing an event should be retried, and how long to wait before
+// each retry
+type Policy struct {
+	MaxRetries           int
+	InitialInterval      time.Duration
+	Multiplier           float64
+	MaxInterval          time.Duration
+	RetryableStatusCodes []int
+	RetryableErrors      []string
+}
+
+// NewPolicy creates a policy from its configuration
+func NewPolicy(configuration *functionconfig.RetryPolicy) (*Policy, error) {
+	initialInterval, err := configuration.GetInitialInterval()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse initial interval")
+	}
+
+	maxInterval, err := configuration.GetMaxInterval()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse max interval")
+	}
+
+	return &Policy{
+		MaxRetries:           configuration.MaxRetries,
+		InitialInterval:      initialInterval,
+		Multiplier:           configuration.GetMultiplier(),
+		MaxInterval:          maxInterval,
+		RetryableStatusCodes: configuration.RetryableStatusCodes,
+		RetryableErrors:      configuration.RetryableErrors,
+	}, nil
+}
+
+// ShouldRetry returns whether an event should be processed again, given the outcome of the retries'th retry
+// (0 being the first attempt)
+func (p *Policy) ShouldRetry(retries int, response interface{}, processError error) bool {
+	if retries >= p.MaxRetries {
+		return false
+	}
+
+	return p.IsRetryable(response, processError)
+}
+
+// IsRetryable returns whether the outcome of processing an event is a failure the policy retries
+func (p *Policy) IsRetryable(response interface{}, processError error) bool {
+
+	// without filters, every error is retried (and no response is)
+	if len(p.RetryableStatusCodes) == 0 && len(p.RetryableErrors) == 0 {
+		return processError != nil
+	}
+
+	if processError == nil {
+		typedResponse, ok := response.(nuclio.Response)
+		return ok && p.isRetryableStatusCode(typedResponse.StatusCode)
+	}
+
+	if errorWithStatusCode, ok := processError.(nuclio.WithStatusCode); ok &&
+		p.isRetryableStatusCode(errorWithStatusCode.StatusCode()) {
+		return true
+	}
+
+	errorMessage := processError.Error()
+	for _, retryableError := range p.RetryableErrors {
+		if strings.Contains(errorMessage, retryableError) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetInterval returns how long to wait before the given retry (1 being the first retry)
+func (p *Policy) GetInterval(retry int) time.Duration {
+	interval := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(retry-1))
+	if interval > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+
+	return time.Duration(interval)
+}
+
+func (p *Policy) isRetryableStatusCode(statusCode int) bool {
+	for _, retryableStatusCode := range p.RetryableStatusCodes {
+		if statusCode == retryableStatusCode {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+)
+
+type policyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *policyTestSuite) TestDefaults() {
+	policy, err := NewPolicy(&functionconfig.RetryPolicy{MaxRetries: 3})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(functionconfig.DefaultRetryPolicyInitialInterval, policy.InitialInterval)
+	suite.Require().Equal(float64(functionconfig.DefaultRetryPolicyMultiplier), policy.Multiplier)
+	suite.Require().Equal(functionconfig.DefaultRetryPolicyMaxInterval, policy.MaxInterval)
+}
+
+func (suite *policyTestSuite) TestGetInterval() {
+	policy, err := NewPolicy(&functionconfig.RetryPolicy{
+		MaxRetries:      10,
+		InitialInterval: "100ms",
+		Multiplier:      3,
+		MaxInterval:     "1s",
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(100*time.Millisecond, policy.GetInterval(1))
+	suite.Require().Equal(300*time.Millisecond, policy.GetInterval(2))
+	suite.Require().Equal(900*time.Millisecond, policy.GetInterval(3))
+	suite.Require().Equal(time.Second, policy.GetInterval(4))
+	suite.Require().Equal(time.Second, policy.GetInterval(10))
+}
+
+func (suite *policyTestSuite) TestShouldRetryAllErrors() {
+	policy, err := NewPolicy(&functionconfig.RetryPolicy{MaxRetries: 2})
+	suite.Require().NoError(err)
+
+	suite.Require().True(policy.ShouldRetry(0, nil, errors.New("Failed")))
+	suite.Require().True(policy.ShouldRetry(1, nil, errors.New("Failed")))
+	suite.Require().False(policy.ShouldRetry(2, nil, errors.New("Failed")))
+
+	// responses are never retried without filters
+	suite.Require().False(policy.ShouldRetry(0, nuclio.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	suite.Require().False(policy.ShouldRetry(0, "ok", nil))
+}
+
+func (suite *policyTestSuite) TestShouldRetryFiltered() {
+	policy, err := NewPolicy(&functionconfig.RetryPolicy{
+		MaxRetries:           2,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+		RetryableErrors:      []string{"connection refused"},
+	})
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		name            string
+		response        interface{}
+		processError    error
+		expectedRetried bool
+	}{
+		{
+			name:            "retryable error status code",
+			processError:    nuclio.NewErrServiceUnavailable("Database is down"),
+			expectedRetried: true,
+		},
+		{
+			name:            "non retryable error status code",
+			processError:    nuclio.NewErrBadRequest("Malformed body"),
+			expectedRetried: false,
+		},
+		{
+			name:            "retryable error message",
+			processError:    errors.New("dial tcp 10.0.0.1:5432: connection refused"),
+			expectedRetried: true,
+		},
+		{
+			name:            "non retryable error message",
+			processError:    errors.New("Division by zero"),
+			expectedRetried: false,
+		},
+		{
+			name:            "retryable response status code",
+			response:        nuclio.Response{StatusCode: http.StatusTooManyRequests},
+			expectedRetried: true,
+		},
+		{
+			name:            "successful response",
+			response:        nuclio.Response{StatusCode: http.StatusOK},
+			expectedRetried: false,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.Require().Equal(testCase.expectedRetried,
+				policy.ShouldRetry(0, testCase.response, testCase.processError))
+		})
+	}
+}
+
+func TestPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(policyTestSuite))
+}
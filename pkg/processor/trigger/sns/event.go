@@ -0,0 +1,105 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event is an SNS notification. its headers are the message attributes, along with the message ID, topic ARN,
+// subject and timestamp of the notification
+type Event struct {
+	nuclio.AbstractEvent
+	message *message
+}
+
+// GetBody returns the message of the notification
+func (e *Event) GetBody() []byte {
+	return []byte(e.message.Message)
+}
+
+// GetSize returns the size of the message
+func (e *Event) GetSize() int {
+	return len(e.message.Message)
+}
+
+// GetURL returns the ARN of the topic
+func (e *Event) GetURL() string {
+	return e.message.TopicARN
+}
+
+// GetPath returns the ARN of the topic
+func (e *Event) GetPath() string {
+	return e.message.TopicARN
+}
+
+// GetTimestamp returns when the notification was published
+func (e *Event) GetTimestamp() time.Time {
+	timestamp, err := time.Parse(time.RFC3339, e.message.Timestamp)
+	if err != nil {
+		return time.Now()
+	}
+
+	return timestamp
+}
+
+// GetHeader returns the header by name as an interface{}
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaderString(key)
+}
+
+// GetHeaderByteSlice returns the header by name as a byte slice
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeaderString returns the header by name as a string
+func (e *Event) GetHeaderString(key string) string {
+	switch key {
+	case "MessageId":
+		return e.message.MessageID
+	case "TopicArn":
+		return e.message.TopicARN
+	case "Subject":
+		return e.message.Subject
+	case "Timestamp":
+		return e.message.Timestamp
+	}
+
+	return e.message.MessageAttributes[key].Value
+}
+
+// GetHeaders loads all headers into a map of string / interface{}
+func (e *Event) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{
+		"MessageId": e.message.MessageID,
+		"TopicArn":  e.message.TopicARN,
+		"Timestamp": e.message.Timestamp,
+	}
+
+	if e.message.Subject != "" {
+		headers["Subject"] = e.message.Subject
+	}
+
+	for attributeName, attribute := range e.message.MessageAttributes {
+		headers[attributeName] = attribute.Value
+	}
+
+	return headers
+}
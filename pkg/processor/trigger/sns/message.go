@@ -0,0 +1,209 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/nuclio/errors"
+)
+
+// matches the hosts SNS serves signing certificates and subscription confirmation URLs from,
+// e.g. sns.us-east-1.amazonaws.com
+var snsHostRegex = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+type messageAttribute struct {
+	Type  string
+	Value string
+}
+
+// message is what SNS posts to HTTP endpoints - notifications, and the confirmations of subscribing and
+// unsubscribing them
+type message struct {
+	Type              string
+	MessageID         string `json:"MessageId"`
+	Token             string
+	TopicARN          string `json:"TopicArn"`
+	Subject           string
+	Message           string
+	Timestamp         string
+	SignatureVersion  string
+	Signature         string
+	SigningCertURL    string
+	SubscribeURL      string
+	UnsubscribeURL    string
+	MessageAttributes map[string]messageAttribute
+}
+
+// getStringToSign returns what SNS signed - name / value lines of the message fields, which are ordered
+// by name and differ between notifications and confirmations
+func (m *message) getStringToSign() string {
+	var fields [][2]string
+
+	if m.Type == messageTypeNotification {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"Subject", m.Subject},
+			{"Timestamp", m.Timestamp},
+			{"TopicArn", m.TopicARN},
+			{"Type", m.Type},
+		}
+	} else {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.Timestamp},
+			{"Token", m.Token},
+			{"TopicArn", m.TopicARN},
+			{"Type", m.Type},
+		}
+	}
+
+	var stringToSign strings.Builder
+	for _, field := range fields {
+
+		// the subject is optional, and only signed when present
+		if field[0] == "Subject" && field[1] == "" {
+			continue
+		}
+
+		stringToSign.WriteString(field[0] + "\n" + field[1] + "\n")
+	}
+
+	return stringToSign.String()
+}
+
+// signatureVerifier verifies the signatures of messages with the certificates SNS signed them with, which
+// are fetched once per URL
+type signatureVerifier struct {
+	httpClient   *http.Client
+	isTrustedURL func(*url.URL) bool
+
+	certificatesLock sync.Mutex
+	certificates     map[string]*x509.Certificate
+}
+
+func newSignatureVerifier(httpClient *http.Client) *signatureVerifier {
+	return &signatureVerifier{
+		httpClient:   httpClient,
+		isTrustedURL: isSNSURL,
+		certificates: map[string]*x509.Certificate{},
+	}
+}
+
+func (sv *signatureVerifier) verify(receivedMessage *message) error {
+	var hash crypto.Hash
+	var digest []byte
+
+	stringToSign := []byte(receivedMessage.getStringToSign())
+
+	switch receivedMessage.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+		sha1Digest := sha1.Sum(stringToSign) // nolint: gosec
+		digest = sha1Digest[:]
+	case "2":
+		hash = crypto.SHA256
+		sha256Digest := sha256.Sum256(stringToSign)
+		digest = sha256Digest[:]
+	default:
+		return errors.Errorf("Unsupported signature version %s", receivedMessage.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(receivedMessage.Signature)
+	if err != nil {
+		return errors.Wrap(err, "Failed to decode signature")
+	}
+
+	certificate, err := sv.getCertificate(receivedMessage.SigningCertURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get signing certificate")
+	}
+
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("Signing certificate doesn't hold an RSA public key")
+	}
+
+	if err := rsa.VerifyPKCS1v15(publicKey, hash, digest, signature); err != nil {
+		return errors.Wrap(err, "Signature doesn't match the message")
+	}
+
+	return nil
+}
+
+func (sv *signatureVerifier) getCertificate(certificateURL string) (*x509.Certificate, error) {
+	sv.certificatesLock.Lock()
+	defer sv.certificatesLock.Unlock()
+
+	if certificate, found := sv.certificates[certificateURL]; found {
+		return certificate, nil
+	}
+
+	parsedCertificateURL, err := url.Parse(certificateURL)
+	if err != nil || !sv.isTrustedURL(parsedCertificateURL) {
+		return nil, errors.Errorf("Signing certificate URL %s isn't of SNS", certificateURL)
+	}
+
+	response, err := sv.httpClient.Get(certificateURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to download signing certificate")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Downloading signing certificate returned status %d", response.StatusCode)
+	}
+
+	encodedCertificate, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read signing certificate")
+	}
+
+	certificateBlock, _ := pem.Decode(encodedCertificate)
+	if certificateBlock == nil {
+		return nil, errors.New("Signing certificate isn't PEM encoded")
+	}
+
+	certificate, err := x509.ParseCertificate(certificateBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse signing certificate")
+	}
+
+	sv.certificates[certificateURL] = certificate
+
+	return certificate, nil
+}
+
+func isSNSURL(parsedURL *url.URL) bool {
+	return parsedURL.Scheme == "https" && snsHostRegex.MatchString(parsedURL.Hostname())
+}
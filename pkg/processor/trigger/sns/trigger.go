@@ -0,0 +1,255 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/aws/aws-sdk-go/aws"
+	snsClient "github.com/aws/aws-sdk-go/service/sns"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// SNS messages are up to 256KB, leaving room for the fields around them
+const maxMessageSize = 1024 * 1024
+
+type sns struct {
+	trigger.AbstractTrigger
+	configuration     *Configuration
+	server            *http.Server
+	httpClient        *http.Client
+	signatureVerifier *signatureVerifier
+}
+
+func newTrigger(parentLogger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration) (trigger.Trigger, error) {
+
+	// messages are posted concurrently, so they must share the workers
+	if !workerAllocator.Shareable() {
+		return nil, errors.New("SNS trigger requires a shareable worker allocator")
+	}
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(parentLogger.GetChild(configuration.ID),
+		workerAllocator,
+		&configuration.Configuration,
+		"async",
+		"sns",
+		configuration.Name)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	return &sns{
+		AbstractTrigger:   abstractTrigger,
+		configuration:     configuration,
+		httpClient:        httpClient,
+		signatureVerifier: newSignatureVerifier(httpClient),
+	}, nil
+}
+
+func (s *sns) Start(checkpoint functionconfig.Checkpoint) error {
+	s.Logger.InfoWith("Starting",
+		"listenAddress", s.configuration.URL,
+		"path", s.configuration.Path,
+		"topicARNs", s.configuration.TopicARNs)
+
+	listener, err := net.Listen("tcp", s.configuration.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on %s", s.configuration.URL)
+	}
+
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(s.configuration.Path, s.handleRequest)
+
+	s.server = &http.Server{Handler: serveMux}
+
+	go s.server.Serve(listener) // nolint: errcheck
+
+	if s.configuration.SubscribeTopicARN != "" {
+		if err := s.subscribe(); err != nil {
+			s.server.Close() // nolint: errcheck
+			return errors.Wrap(err, "Failed to subscribe to topic")
+		}
+	}
+
+	return nil
+}
+
+func (s *sns) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if s.server != nil {
+		if err := s.server.Close(); err != nil {
+			return nil, errors.Wrap(err, "Failed to stop server")
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *sns) GetConfig() map[string]interface{} {
+	return common.StructureToMap(s.configuration)
+}
+
+// subscribe subscribes the trigger's endpoint to the topic. subscribing an endpoint which is already subscribed
+// (e.g. by another replica) returns the existing subscription
+func (s *sns) subscribe() error {
+	sess, err := common.NewAWSSession(&s.configuration.AWSSessionConfiguration)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	subscriptionEndpoint, err := url.Parse(s.configuration.SubscriptionEndpoint)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse subscription endpoint")
+	}
+
+	if _, err := snsClient.New(sess).Subscribe(&snsClient.SubscribeInput{
+		TopicArn: aws.String(s.configuration.SubscribeTopicARN),
+		Protocol: aws.String(subscriptionEndpoint.Scheme),
+		Endpoint: aws.String(s.configuration.SubscriptionEndpoint),
+	}); err != nil {
+		return errors.Wrapf(err, "Failed to subscribe %s", s.configuration.SubscriptionEndpoint)
+	}
+
+	s.Logger.InfoWith("Subscribed to topic",
+		"topicARN", s.configuration.SubscribeTopicARN,
+		"endpoint", s.configuration.SubscriptionEndpoint)
+
+	return nil
+}
+
+func (s *sns) handleRequest(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(request.Body, maxMessageSize))
+	if err != nil {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	receivedMessage := message{}
+	if err := json.Unmarshal(body, &receivedMessage); err != nil {
+		s.Logger.WarnWith("Received an invalid message", "err", err.Error())
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !s.configuration.SkipSignatureVerification {
+		if err := s.signatureVerifier.verify(&receivedMessage); err != nil {
+			s.Logger.WarnWith("Rejected a message with an invalid signature",
+				"messageID", receivedMessage.MessageID,
+				"topicARN", receivedMessage.TopicARN,
+				"err", errors.Cause(err).Error())
+			responseWriter.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	if len(s.configuration.TopicARNs) > 0 &&
+		!common.StringSliceContainsString(s.configuration.TopicARNs, receivedMessage.TopicARN) {
+		s.Logger.WarnWith("Rejected a message of an unexpected topic", "topicARN", receivedMessage.TopicARN)
+		responseWriter.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch receivedMessage.Type {
+	case messageTypeNotification:
+		responseWriter.WriteHeader(s.handleNotification(&receivedMessage))
+
+	case messageTypeSubscriptionConfirmation:
+		if err := s.confirmSubscription(&receivedMessage); err != nil {
+			s.Logger.WarnWith("Failed to confirm subscription",
+				"topicARN", receivedMessage.TopicARN,
+				"err", errors.Cause(err).Error())
+			responseWriter.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.Logger.InfoWith("Confirmed subscription", "topicARN", receivedMessage.TopicARN)
+
+	case messageTypeUnsubscribeConfirmation:
+		s.Logger.InfoWith("Unsubscribed from topic", "topicARN", receivedMessage.TopicARN)
+
+	default:
+		responseWriter.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// handleNotification submits a notification to a worker, returning the status SNS is responded with. SNS
+// retries delivering the notification (according to the delivery policy of the subscription) unless it's 2xx
+func (s *sns) handleNotification(receivedMessage *message) int {
+	event := &Event{
+		message: receivedMessage,
+	}
+
+	_, submitError, processError := s.AllocateWorkerAndSubmitEvent(event, s.Logger, 10*time.Second)
+	if submitError != nil {
+		s.Logger.WarnWith("Failed to submit notification",
+			"messageID", receivedMessage.MessageID,
+			"err", submitError.Error())
+
+		return http.StatusServiceUnavailable
+	}
+
+	if processError != nil {
+		s.Logger.WarnWith("Failed to handle notification",
+			"messageID", receivedMessage.MessageID,
+			"err", processError.Error())
+
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusOK
+}
+
+// confirmSubscription visits the URL given in the confirmation request
+func (s *sns) confirmSubscription(receivedMessage *message) error {
+	subscribeURL, err := url.Parse(receivedMessage.SubscribeURL)
+	if err != nil || (!s.configuration.SkipSignatureVerification && !s.signatureVerifier.isTrustedURL(subscribeURL)) {
+		return errors.Errorf("Subscribe URL %s isn't of SNS", receivedMessage.SubscribeURL)
+	}
+
+	response, err := s.httpClient.Get(receivedMessage.SubscribeURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to visit subscribe URL")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Visiting subscribe URL returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
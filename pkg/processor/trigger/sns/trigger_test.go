@@ -0,0 +1,315 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingRuntime records the events it got, failing on "fail"
+type recordingRuntime struct {
+	runtime.Runtime
+	lock   sync.Mutex
+	events []map[string]interface{}
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	rr.events = append(rr.events, map[string]interface{}{
+		"body":    string(event.GetBody()),
+		"path":    event.GetPath(),
+		"headers": event.GetHeaders(),
+	})
+
+	if string(event.GetBody()) == "fail" {
+		return nil, errors.New("Failed")
+	}
+
+	return nil, nil
+}
+
+type snsTestSuite struct {
+	suite.Suite
+	logger              logger.Logger
+	privateKey          *rsa.PrivateKey
+	server              *httptest.Server
+	subscribeURLVisited int32
+}
+
+func (suite *snsTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+
+	suite.privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	certificateTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	encodedCertificate, err := x509.CreateCertificate(rand.Reader,
+		certificateTemplate,
+		certificateTemplate,
+		&suite.privateKey.PublicKey,
+		suite.privateKey)
+	suite.Require().NoError(err)
+
+	// serves the signing certificate and the subscribe URL
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/certificate.pem", func(responseWriter http.ResponseWriter, request *http.Request) {
+		pem.Encode(responseWriter, &pem.Block{Type: "CERTIFICATE", Bytes: encodedCertificate}) // nolint: errcheck
+	})
+	serveMux.HandleFunc("/subscribe", func(responseWriter http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&suite.subscribeURLVisited, 1)
+	})
+
+	suite.server = httptest.NewServer(serveMux)
+}
+
+func (suite *snsTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *snsTestSuite) TestConfiguration() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"subscribeTopicARN":    "arn:aws:sns:eu-west-1:123456789012:orders",
+		"subscriptionEndpoint": "https://orders.example.com/sns",
+		"topicARNs":            []string{"arn:aws:sns:eu-west-1:123456789012:orders"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(DefaultURL, configuration.URL)
+	suite.Require().Equal(DefaultPath, configuration.Path)
+	suite.Require().Equal("eu-west-1", configuration.Region)
+	suite.Require().False(configuration.SkipSignatureVerification)
+
+	for _, attributes := range []map[string]interface{}{
+		{"subscribeTopicARN": "arn:aws:sns:eu-west-1:123456789012:orders"},
+		{"subscriptionEndpoint": "https://orders.example.com/sns"},
+		{"subscribeTopicARN": "orders", "subscriptionEndpoint": "https://orders.example.com/sns"},
+		{"subscribeTopicARN": "arn:aws:sns:eu-west-1:123456789012:orders", "subscriptionEndpoint": "orders"},
+	} {
+		_, err := suite.newConfiguration(attributes)
+		suite.Require().Error(err, attributes)
+	}
+}
+
+func (suite *snsTestSuite) TestIsSNSURL() {
+	for _, testCase := range []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-abc.pem", expected: true},
+		{url: "https://sns.cn-north-1.amazonaws.com.cn/SimpleNotificationService-abc.pem", expected: true},
+		{url: "http://sns.us-east-1.amazonaws.com/SimpleNotificationService-abc.pem"},
+		{url: "https://sns.us-east-1.amazonaws.com.attacker.com/certificate.pem"},
+		{url: "https://attacker.com/sns.us-east-1.amazonaws.com"},
+	} {
+		parsedURL, err := url.Parse(testCase.url)
+		suite.Require().NoError(err)
+		suite.Require().Equal(testCase.expected, isSNSURL(parsedURL), testCase.url)
+	}
+}
+
+func (suite *snsTestSuite) TestHandleRequest() {
+	triggerInstance, recordingRuntime := suite.createTrigger(map[string]interface{}{
+		"topicARNs": []string{"arn:aws:sns:us-east-1:123456789012:orders"},
+	})
+
+	// confirming a subscription visits the subscribe URL
+	suite.Require().Equal(http.StatusOK, suite.post(triggerInstance, suite.createMessage(&message{
+		Type:         messageTypeSubscriptionConfirmation,
+		Token:        "token",
+		SubscribeURL: suite.server.URL + "/subscribe",
+	}, "2")))
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.subscribeURLVisited))
+
+	// notifications are handled, signed with either version
+	suite.Require().Equal(http.StatusOK, suite.post(triggerInstance, suite.createMessage(&message{
+		Type:    messageTypeNotification,
+		Subject: "created",
+		Message: `{"id": 1}`,
+		MessageAttributes: map[string]messageAttribute{
+			"priority": {Type: "String", Value: "high"},
+		},
+	}, "1")))
+
+	suite.Require().Len(recordingRuntime.events, 1)
+	suite.Require().Equal(`{"id": 1}`, recordingRuntime.events[0]["body"])
+	suite.Require().Equal("arn:aws:sns:us-east-1:123456789012:orders", recordingRuntime.events[0]["path"])
+	suite.Require().Equal("high", recordingRuntime.events[0]["headers"].(map[string]interface{})["priority"])
+	suite.Require().Equal("created", recordingRuntime.events[0]["headers"].(map[string]interface{})["Subject"])
+
+	// failures are reported to SNS so that it retries
+	suite.Require().Equal(http.StatusInternalServerError, suite.post(triggerInstance, suite.createMessage(&message{
+		Type:    messageTypeNotification,
+		Message: "fail",
+	}, "2")))
+
+	// a message whose signature doesn't match is rejected
+	tamperedMessage := suite.createMessage(&message{
+		Type:    messageTypeNotification,
+		Message: "original",
+	}, "2")
+	tamperedMessage.Message = "tampered"
+	suite.Require().Equal(http.StatusForbidden, suite.post(triggerInstance, tamperedMessage))
+
+	// as are the messages of other topics
+	otherTopicMessage := &message{
+		Type:     messageTypeNotification,
+		TopicARN: "arn:aws:sns:us-east-1:123456789012:other",
+		Message:  "other",
+	}
+	suite.Require().Equal(http.StatusForbidden, suite.post(triggerInstance, suite.createMessage(otherTopicMessage, "2")))
+
+	suite.Require().Len(recordingRuntime.events, 2)
+}
+
+func (suite *snsTestSuite) TestSigningCertificateMustBeOfSNS() {
+	triggerInstance, _ := suite.createTrigger(nil)
+
+	// don't trust the test server
+	triggerInstance.signatureVerifier.isTrustedURL = isSNSURL
+
+	suite.Require().Equal(http.StatusForbidden, suite.post(triggerInstance, suite.createMessage(&message{
+		Type:    messageTypeNotification,
+		Message: "hello",
+	}, "2")))
+
+	// unless verification is skipped
+	triggerInstance.configuration.SkipSignatureVerification = true
+
+	suite.Require().Equal(http.StatusOK, suite.post(triggerInstance, suite.createMessage(&message{
+		Type:    messageTypeNotification,
+		Message: "hello",
+	}, "2")))
+}
+
+func (suite *snsTestSuite) createTrigger(attributes map[string]interface{}) (*sns, *recordingRuntime) {
+	configuration, err := suite.newConfiguration(attributes)
+	suite.Require().NoError(err)
+
+	recordingRuntime := &recordingRuntime{}
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, recordingRuntime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, []*worker.Worker{workerInstance})
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	snsTrigger := triggerInstance.(*sns)
+
+	// trust the test server as SNS
+	snsTrigger.signatureVerifier.isTrustedURL = func(*url.URL) bool {
+		return true
+	}
+
+	return snsTrigger, recordingRuntime
+}
+
+// createMessage fills in the fields of a message and signs it
+func (suite *snsTestSuite) createMessage(messageToSign *message, signatureVersion string) *message {
+	if messageToSign.TopicARN == "" {
+		messageToSign.TopicARN = "arn:aws:sns:us-east-1:123456789012:orders"
+	}
+
+	messageToSign.MessageID = "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324"
+	messageToSign.Timestamp = "2020-01-01T12:00:00.000Z"
+	messageToSign.SignatureVersion = signatureVersion
+	messageToSign.SigningCertURL = suite.server.URL + "/certificate.pem"
+
+	hash := crypto.SHA256
+	if signatureVersion == "1" {
+		hash = crypto.SHA1
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(messageToSign.getStringToSign())) // nolint: errcheck
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, suite.privateKey, hash, hasher.Sum(nil))
+	suite.Require().NoError(err)
+
+	messageToSign.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	return messageToSign
+}
+
+func (suite *snsTestSuite) post(triggerInstance *sns, postedMessage *message) int {
+	body, err := json.Marshal(postedMessage)
+	suite.Require().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	request.Header.Set("x-amz-sns-message-type", postedMessage.Type)
+
+	responseRecorder := httptest.NewRecorder()
+	triggerInstance.handleRequest(responseRecorder, request)
+
+	return responseRecorder.Code
+}
+
+func (suite *snsTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	runtimeConfiguration := &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			Name:       "my-trigger",
+			Kind:       "sns",
+			Attributes: attributes,
+		},
+		runtimeConfiguration)
+}
+
+func TestSNSSuite(t *testing.T) {
+	suite.Run(t, new(snsTestSuite))
+}
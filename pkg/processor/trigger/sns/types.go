@@ -0,0 +1,115 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultURL  = ":8083"
+	DefaultPath = "/"
+)
+
+// the types of the messages SNS posts to HTTP endpoints
+const (
+	messageTypeNotification             = "Notification"
+	messageTypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	messageTypeUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+type Configuration struct {
+	trigger.Configuration
+	common.AWSSessionConfiguration `mapstructure:",squash"`
+
+	// the path SNS posts messages to
+	Path string
+
+	// when given, only the messages of these topics are accepted, and only subscriptions to them confirmed
+	TopicARNs []string
+
+	// when both are given, the endpoint (the URL at which SNS reaches the trigger) is subscribed to the topic
+	// on start. the subscription is confirmed once SNS posts its confirmation request
+	SubscribeTopicARN    string
+	SubscriptionEndpoint string
+
+	// accept messages without verifying their signatures, for SNS compatible services which don't sign them
+	// with certificates served by AWS (e.g. localstack)
+	SkipSignatureVerification bool
+}
+
+func NewConfiguration(ID string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *trigger.NewConfiguration(ID, triggerConfiguration, runtimeConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.URL == "" {
+		newConfiguration.URL = DefaultURL
+	}
+
+	if newConfiguration.Path == "" {
+		newConfiguration.Path = DefaultPath
+	}
+
+	if (newConfiguration.SubscribeTopicARN == "") != (newConfiguration.SubscriptionEndpoint == "") {
+		return nil, errors.New("Subscribing requires both a topic ARN and a subscription endpoint")
+	}
+
+	if newConfiguration.SubscribeTopicARN != "" {
+		if !strings.HasPrefix(newConfiguration.SubscriptionEndpoint, "http://") &&
+			!strings.HasPrefix(newConfiguration.SubscriptionEndpoint, "https://") {
+			return nil, errors.Errorf("Subscription endpoint must be an HTTP(S) URL, got %s",
+				newConfiguration.SubscriptionEndpoint)
+		}
+
+		if newConfiguration.Region == "" {
+			newConfiguration.Region = getRegionFromTopicARN(newConfiguration.SubscribeTopicARN)
+		}
+
+		if newConfiguration.Region == "" {
+			return nil, errors.Errorf("Invalid topic ARN %s", newConfiguration.SubscribeTopicARN)
+		}
+	}
+
+	return &newConfiguration, nil
+}
+
+// topic ARNs are of the form arn:aws:sns:<region>:<account ID>:<topic name>
+func getRegionFromTopicARN(topicARN string) string {
+	arnParts := strings.Split(topicARN, ":")
+	if len(arnParts) != 6 || arnParts[0] != "arn" || arnParts[2] != "sns" {
+		return ""
+	}
+
+	return arnParts[3]
+}
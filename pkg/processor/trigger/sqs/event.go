@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	sqsClient "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event is an SQS message. its headers are the message attributes, along with the message ID and
+// the system attributes (e.g. MessageGroupId, ApproximateReceiveCount)
+type Event struct {
+	nuclio.AbstractEvent
+	message  *sqsClient.Message
+	queueURL string
+}
+
+// GetBody returns the body of the message
+func (e *Event) GetBody() []byte {
+	return []byte(aws.StringValue(e.message.Body))
+}
+
+// GetSize returns the size of the message body
+func (e *Event) GetSize() int {
+	return len(aws.StringValue(e.message.Body))
+}
+
+// GetURL returns the URL of the queue
+func (e *Event) GetURL() string {
+	return e.queueURL
+}
+
+// GetPath returns the URL of the queue
+func (e *Event) GetPath() string {
+	return e.queueURL
+}
+
+// GetTimestamp returns when the message was sent to the queue
+func (e *Event) GetTimestamp() time.Time {
+	sentTimestamp, err := strconv.ParseInt(e.getSystemAttribute(sqsClient.MessageSystemAttributeNameSentTimestamp),
+		10,
+		64)
+	if err != nil {
+		return time.Now()
+	}
+
+	return time.Unix(0, sentTimestamp*int64(time.Millisecond))
+}
+
+// GetHeader returns the header by name as an interface{}
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaderString(key)
+}
+
+// GetHeaderByteSlice returns the header by name as a byte slice
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	if messageAttribute, found := e.message.MessageAttributes[key]; found && messageAttribute.BinaryValue != nil {
+		return messageAttribute.BinaryValue
+	}
+
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeaderString returns the header by name as a string
+func (e *Event) GetHeaderString(key string) string {
+	if key == "MessageId" {
+		return aws.StringValue(e.message.MessageId)
+	}
+
+	if messageAttribute, found := e.message.MessageAttributes[key]; found {
+		if messageAttribute.StringValue != nil {
+			return *messageAttribute.StringValue
+		}
+
+		return string(messageAttribute.BinaryValue)
+	}
+
+	return e.getSystemAttribute(key)
+}
+
+// GetHeaders loads all headers into a map of string / interface{}
+func (e *Event) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{
+		"MessageId": aws.StringValue(e.message.MessageId),
+	}
+
+	for attributeName, attributeValue := range e.message.Attributes {
+		headers[attributeName] = aws.StringValue(attributeValue)
+	}
+
+	for messageAttributeName := range e.message.MessageAttributes {
+		headers[messageAttributeName] = e.GetHeaderString(messageAttributeName)
+	}
+
+	return headers
+}
+
+func (e *Event) getSystemAttribute(name string) string {
+	return aws.StringValue(e.message.Attributes[name])
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	sqsClient "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type sqs struct {
+	trigger.AbstractTrigger
+	configuration *Configuration
+	client        sqsiface.SQSAPI
+	queueURL      string
+	fifo          bool
+
+	// the visibility timeout of received messages, extended at half of it while they're handled
+	visibilityTimeout time.Duration
+
+	// limits the number of message groups handled at the same time to the number of workers
+	workerSlots chan struct{}
+
+	cancelReceiving  context.CancelFunc
+	receivingStopped chan struct{}
+	handlingMessages sync.WaitGroup
+}
+
+// messageGroup is a sequence of messages handled in order - the messages of a FIFO queue with the same
+// message group ID, or a single message of a standard queue
+type messageGroup struct {
+	messages []*sqsClient.Message
+
+	// the number of messages at the head of the group which are done with, and no longer need their
+	// visibility extended
+	handled int32
+}
+
+func newTrigger(parentLogger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration) (trigger.Trigger, error) {
+
+	// message groups are handled concurrently, so they must share the workers
+	if !workerAllocator.Shareable() {
+		return nil, errors.New("SQS trigger requires a shareable worker allocator")
+	}
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(parentLogger.GetChild(configuration.ID),
+		workerAllocator,
+		&configuration.Configuration,
+		"async",
+		"sqs",
+		configuration.Name)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	return &sqs{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+		workerSlots:     make(chan struct{}, configuration.MaxWorkers),
+	}, nil
+}
+
+func (s *sqs) Start(checkpoint functionconfig.Checkpoint) error {
+	s.Logger.InfoWith("Starting",
+		"queueURL", s.configuration.QueueURL,
+		"queueName", s.configuration.QueueName,
+		"region", s.configuration.Region,
+		"endpoint", s.configuration.Endpoint)
+
+	// the client may have been injected
+	if s.client == nil {
+		sess, err := common.NewAWSSession(&s.configuration.AWSSessionConfiguration)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create AWS session")
+		}
+
+		s.client = sqsClient.New(sess)
+	}
+
+	if err := s.resolveQueue(); err != nil {
+		return errors.Wrap(err, "Failed to resolve queue")
+	}
+
+	s.Logger.InfoWith("Receiving messages",
+		"queueURL", s.queueURL,
+		"fifo", s.fifo,
+		"visibilityTimeout", s.visibilityTimeout)
+
+	receiveContext, cancelReceiving := context.WithCancel(context.Background())
+	s.cancelReceiving = cancelReceiving
+	s.receivingStopped = make(chan struct{})
+
+	go s.receiveMessages(receiveContext)
+
+	return nil
+}
+
+func (s *sqs) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if s.cancelReceiving == nil {
+		return nil, nil
+	}
+
+	// stop receiving, and let the messages already received finish (or, if forced, be received again once
+	// their visibility timeout passes)
+	s.cancelReceiving()
+	<-s.receivingStopped
+
+	if !force {
+		s.handlingMessages.Wait()
+	}
+
+	return nil, nil
+}
+
+func (s *sqs) GetConfig() map[string]interface{} {
+	return common.StructureToMap(s.configuration)
+}
+
+func (s *sqs) resolveQueue() error {
+	s.queueURL = s.configuration.QueueURL
+
+	if s.queueURL == "" {
+		getQueueURLOutput, err := s.client.GetQueueUrl(&sqsClient.GetQueueUrlInput{
+			QueueName: aws.String(s.configuration.QueueName),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get the URL of queue %s", s.configuration.QueueName)
+		}
+
+		s.queueURL = aws.StringValue(getQueueURLOutput.QueueUrl)
+	}
+
+	s.fifo = isFIFOQueueURL(s.queueURL)
+	s.visibilityTimeout = s.configuration.visibilityTimeout
+
+	if s.visibilityTimeout != 0 {
+		return nil
+	}
+
+	// the visibility timeout wasn't given, so messages are received with the queue's
+	getQueueAttributesOutput, err := s.client.GetQueueAttributes(&sqsClient.GetQueueAttributesInput{
+		QueueUrl:       aws.String(s.queueURL),
+		AttributeNames: aws.StringSlice([]string{sqsClient.QueueAttributeNameVisibilityTimeout}),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get queue attributes")
+	}
+
+	visibilityTimeoutSeconds, err := strconv.Atoi(
+		aws.StringValue(getQueueAttributesOutput.Attributes[sqsClient.QueueAttributeNameVisibilityTimeout]))
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse the visibility timeout of the queue")
+	}
+
+	s.visibilityTimeout = time.Duration(visibilityTimeoutSeconds) * time.Second
+
+	return nil
+}
+
+func (s *sqs) receiveMessages(receiveContext context.Context) {
+	defer close(s.receivingStopped)
+
+	receiveMessageInput := &sqsClient.ReceiveMessageInput{
+		QueueUrl:              aws.String(s.queueURL),
+		MaxNumberOfMessages:   aws.Int64(int64(s.configuration.MaxNumberOfMessages)),
+		WaitTimeSeconds:       aws.Int64(int64(s.configuration.WaitTimeSeconds)),
+		AttributeNames:        aws.StringSlice([]string{sqsClient.QueueAttributeNameAll}),
+		MessageAttributeNames: aws.StringSlice([]string{sqsClient.QueueAttributeNameAll}),
+	}
+
+	if s.configuration.visibilityTimeout != 0 {
+		receiveMessageInput.VisibilityTimeout = aws.Int64(int64(s.visibilityTimeout / time.Second))
+	}
+
+	for {
+		receiveMessageOutput, err := s.client.ReceiveMessageWithContext(receiveContext, receiveMessageInput)
+		if receiveContext.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			s.Logger.WarnWith("Failed to receive messages", "queueURL", s.queueURL, "err", err.Error())
+
+			select {
+			case <-time.After(receiveErrorBackoff):
+				continue
+			case <-receiveContext.Done():
+				return
+			}
+		}
+
+		for _, group := range s.groupMessages(receiveMessageOutput.Messages) {
+			s.handlingMessages.Add(1)
+
+			// extend the visibility of the messages from the start, as they may wait for a worker
+			groupHandled := make(chan struct{})
+			go s.extendVisibility(group, groupHandled)
+
+			// wait for a worker to be free
+			s.workerSlots <- struct{}{}
+
+			go func(group *messageGroup) {
+				defer s.handlingMessages.Done()
+				defer func() { <-s.workerSlots }()
+				defer close(groupHandled)
+
+				s.handleMessageGroup(group)
+			}(group)
+		}
+	}
+}
+
+// groupMessages splits received messages to the sequences which must be handled in order. the messages of
+// FIFO queues are grouped by their message group ID, keeping the order they were received in (SQS doesn't
+// deliver messages of a group while an earlier message of it is in flight, so a group is never split
+// across receives). the messages of standard queues aren't ordered, each is a group of its own
+func (s *sqs) groupMessages(messages []*sqsClient.Message) []*messageGroup {
+	var groups []*messageGroup
+	groupsByID := map[string]*messageGroup{}
+
+	for _, message := range messages {
+		if !s.fifo {
+			groups = append(groups, &messageGroup{messages: []*sqsClient.Message{message}})
+			continue
+		}
+
+		groupID := aws.StringValue(message.Attributes[sqsClient.MessageSystemAttributeNameMessageGroupId])
+
+		group, found := groupsByID[groupID]
+		if !found {
+			group = &messageGroup{}
+			groupsByID[groupID] = group
+			groups = append(groups, group)
+		}
+
+		group.messages = append(group.messages, message)
+	}
+
+	return groups
+}
+
+func (s *sqs) handleMessageGroup(group *messageGroup) {
+	for messageIndex, message := range group.messages {
+		if err := s.handleMessage(message); err != nil {
+
+			// make the rest of the group visible again without handling it, so that its messages are received
+			// after the failed one
+			atomic.StoreInt32(&group.handled, int32(len(group.messages)))
+			for _, unhandledMessage := range group.messages[messageIndex+1:] {
+				s.changeVisibility(unhandledMessage, 0)
+			}
+
+			return
+		}
+
+		atomic.StoreInt32(&group.handled, int32(messageIndex+1))
+	}
+}
+
+// handleMessage submits a message to a worker, deleting it from the queue once handled. messages which fail
+// are made visible again after the retry delay
+func (s *sqs) handleMessage(message *sqsClient.Message) error {
+	event := &Event{
+		message:  message,
+		queueURL: s.queueURL,
+	}
+
+	_, submitError, processError := s.AllocateWorkerAndSubmitEvent(event, s.Logger, 10*time.Second)
+	if submitError != nil || processError != nil {
+		s.Logger.WarnWith("Failed to handle message",
+			"messageID", aws.StringValue(message.MessageId),
+			"submitError", submitError,
+			"processError", processError)
+
+		s.changeVisibility(message, s.configuration.retryDelay)
+
+		if submitError != nil {
+			return submitError
+		}
+
+		return processError
+	}
+
+	if _, err := s.client.DeleteMessage(&sqsClient.DeleteMessageInput{
+		QueueUrl:      aws.String(s.queueURL),
+		ReceiptHandle: message.ReceiptHandle,
+	}); err != nil {
+		s.logReceiptHandleError("Failed to delete handled message, it will be received again", message, err)
+	}
+
+	return nil
+}
+
+// extendVisibility keeps the messages of a group that aren't handled yet hidden from other consumers, until
+// the group is handled
+func (s *sqs) extendVisibility(group *messageGroup, groupHandled chan struct{}) {
+
+	// messages of queues without a visibility timeout are never hidden
+	if s.visibilityTimeout == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.visibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, message := range group.messages[atomic.LoadInt32(&group.handled):] {
+				s.changeVisibility(message, s.visibilityTimeout)
+			}
+		case <-groupHandled:
+			return
+		}
+	}
+}
+
+func (s *sqs) changeVisibility(message *sqsClient.Message, visibilityTimeout time.Duration) {
+	if _, err := s.client.ChangeMessageVisibility(&sqsClient.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.queueURL),
+		ReceiptHandle:     message.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(int64(visibilityTimeout / time.Second)),
+	}); err != nil {
+		s.logReceiptHandleError("Failed to change message visibility", message, err)
+	}
+}
+
+func (s *sqs) logReceiptHandleError(logMessage string, message *sqsClient.Message, err error) {
+
+	// the receipt handle expires along with the visibility timeout, after which the message may have been
+	// received by another consumer
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == sqsClient.ErrCodeReceiptHandleIsInvalid {
+		logMessage += " (its visibility timeout passed)"
+	}
+
+	s.Logger.WarnWith(logMessage,
+		"messageID", aws.StringValue(message.MessageId),
+		"err", err.Error())
+}
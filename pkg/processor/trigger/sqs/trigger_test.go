@@ -0,0 +1,358 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	sqsClient "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingRuntime records the bodies of the events it got, failing on those starting with "fail"
+type recordingRuntime struct {
+	runtime.Runtime
+	lock   sync.Mutex
+	bodies []string
+}
+
+func (rr *recordingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	rr.bodies = append(rr.bodies, string(event.GetBody()))
+
+	if strings.HasPrefix(string(event.GetBody()), "fail") {
+		return nil, errors.New("Failed")
+	}
+
+	return nil, nil
+}
+
+func (rr *recordingRuntime) getBodies() []string {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	return append([]string{}, rr.bodies...)
+}
+
+// fakeQueue returns its messages to the first receive and records what's done with them. the receipt handle
+// of each message is its body
+type fakeQueue struct {
+	sqsiface.SQSAPI
+	lock                      sync.Mutex
+	messages                  []*sqsClient.Message
+	received                  bool
+	deleted                   []string
+	visibilityChanges         []string
+	receiveMessageInput       *sqsClient.ReceiveMessageInput
+	queueVisibilityTimeout    string
+	getQueueAttributesInvoked bool
+}
+
+func (fq *fakeQueue) ReceiveMessageWithContext(ctx aws.Context,
+	input *sqsClient.ReceiveMessageInput,
+	options ...request.Option) (*sqsClient.ReceiveMessageOutput, error) {
+	fq.lock.Lock()
+	fq.receiveMessageInput = input
+
+	if !fq.received {
+		fq.received = true
+		fq.lock.Unlock()
+
+		return &sqsClient.ReceiveMessageOutput{Messages: fq.messages}, nil
+	}
+
+	fq.lock.Unlock()
+
+	// long polling an empty queue
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (fq *fakeQueue) DeleteMessage(input *sqsClient.DeleteMessageInput) (*sqsClient.DeleteMessageOutput, error) {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	fq.deleted = append(fq.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqsClient.DeleteMessageOutput{}, nil
+}
+
+func (fq *fakeQueue) ChangeMessageVisibility(input *sqsClient.ChangeMessageVisibilityInput) (*sqsClient.ChangeMessageVisibilityOutput, error) {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	fq.visibilityChanges = append(fq.visibilityChanges,
+		fmt.Sprintf("%s:%d", aws.StringValue(input.ReceiptHandle), aws.Int64Value(input.VisibilityTimeout)))
+	return &sqsClient.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (fq *fakeQueue) GetQueueAttributes(input *sqsClient.GetQueueAttributesInput) (*sqsClient.GetQueueAttributesOutput, error) {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	fq.getQueueAttributesInvoked = true
+	return &sqsClient.GetQueueAttributesOutput{
+		Attributes: map[string]*string{
+			sqsClient.QueueAttributeNameVisibilityTimeout: aws.String(fq.queueVisibilityTimeout),
+		},
+	}, nil
+}
+
+func (fq *fakeQueue) getDeleted() []string {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	deleted := append([]string{}, fq.deleted...)
+	sort.Strings(deleted)
+
+	return deleted
+}
+
+func (fq *fakeQueue) getVisibilityChanges() []string {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	visibilityChanges := append([]string{}, fq.visibilityChanges...)
+	sort.Strings(visibilityChanges)
+
+	return visibilityChanges
+}
+
+type sqsTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *sqsTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *sqsTestSuite) TestConfiguration() {
+	configuration, err := suite.newConfiguration(map[string]interface{}{
+		"queueURL": "https://sqs.eu-west-1.amazonaws.com/123456789012/orders",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("eu-west-1", configuration.Region)
+	suite.Require().Equal(DefaultWaitTimeSeconds, configuration.WaitTimeSeconds)
+	suite.Require().Equal(DefaultMaxNumberOfMessages, configuration.MaxNumberOfMessages)
+	suite.Require().Equal(time.Duration(0), configuration.visibilityTimeout)
+
+	configuration, err = suite.newConfiguration(map[string]interface{}{
+		"queueName":         "orders.fifo",
+		"region":            "us-east-2",
+		"endpoint":          "http://localstack:4566",
+		"accessKeyID":       "key",
+		"secretAccessKey":   "secret",
+		"roleARN":           "arn:aws:iam::123456789012:role/reader",
+		"visibilityTimeout": "2m",
+		"retryDelay":        "10s",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("http://localstack:4566", configuration.Endpoint)
+	suite.Require().Equal("key", configuration.AccessKeyID)
+	suite.Require().Equal("arn:aws:iam::123456789012:role/reader", configuration.RoleARN)
+	suite.Require().Equal(2*time.Minute, configuration.visibilityTimeout)
+	suite.Require().Equal(10*time.Second, configuration.retryDelay)
+
+	for _, attributes := range []map[string]interface{}{
+		{},
+		{"queueURL": "https://sqs.eu-west-1.amazonaws.com/123456789012/orders", "queueName": "orders"},
+		{"queueName": "orders"},
+		{"queueName": "orders", "region": "us-east-2", "waitTimeSeconds": 21},
+		{"queueName": "orders", "region": "us-east-2", "maxNumberOfMessages": 11},
+		{"queueName": "orders", "region": "us-east-2", "visibilityTimeout": "100ms"},
+		{"queueName": "orders", "region": "us-east-2", "retryDelay": "-1s"},
+	} {
+		_, err := suite.newConfiguration(attributes)
+		suite.Require().Error(err, attributes)
+	}
+}
+
+func (suite *sqsTestSuite) TestGetRegionFromQueueURL() {
+	suite.Require().Equal("us-east-2", getRegionFromQueueURL("https://sqs.us-east-2.amazonaws.com/123456789012/q"))
+	suite.Require().Equal("us-west-1", getRegionFromQueueURL("https://us-west-1.queue.amazonaws.com/123456789012/q"))
+	suite.Require().Equal("cn-north-1", getRegionFromQueueURL("https://sqs.cn-north-1.amazonaws.com.cn/123456789012/q"))
+	suite.Require().Empty(getRegionFromQueueURL("http://localstack:4566/000000000000/q"))
+}
+
+func (suite *sqsTestSuite) TestStandardQueue() {
+	queue := &fakeQueue{
+		messages:               suite.createMessages("", "first", "fail", "third"),
+		queueVisibilityTimeout: "30",
+	}
+
+	triggerInstance, recordingRuntime := suite.startTrigger(queue, map[string]interface{}{
+		"queueURL":   "https://sqs.us-east-2.amazonaws.com/123456789012/orders",
+		"retryDelay": "5s",
+	}, 2)
+
+	suite.Require().Eventually(func() bool {
+		return len(recordingRuntime.getBodies()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	_, err := triggerInstance.Stop(false)
+	suite.Require().NoError(err)
+
+	// the queue's visibility timeout is used, and failed messages are hidden for the retry delay
+	suite.Require().True(queue.getQueueAttributesInvoked)
+	suite.Require().Equal(30*time.Second, triggerInstance.visibilityTimeout)
+	suite.Require().Nil(queue.receiveMessageInput.VisibilityTimeout)
+	suite.Require().Equal(int64(DefaultWaitTimeSeconds), aws.Int64Value(queue.receiveMessageInput.WaitTimeSeconds))
+	suite.Require().Equal([]string{"first", "third"}, queue.getDeleted())
+	suite.Require().Equal([]string{"fail:5"}, queue.getVisibilityChanges())
+}
+
+func (suite *sqsTestSuite) TestFIFOQueue() {
+	queue := &fakeQueue{
+		messages: append(suite.createMessages("a", "a1", "fail-a2", "a3"),
+			suite.createMessages("b", "b1", "b2")...),
+	}
+
+	triggerInstance, recordingRuntime := suite.startTrigger(queue, map[string]interface{}{
+		"queueURL":          "https://sqs.us-east-2.amazonaws.com/123456789012/orders.fifo",
+		"visibilityTimeout": "1s",
+	}, 2)
+
+	suite.Require().Eventually(func() bool {
+		return len(queue.getDeleted()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	_, err := triggerInstance.Stop(false)
+	suite.Require().NoError(err)
+
+	suite.Require().True(triggerInstance.fifo)
+	suite.Require().False(queue.getQueueAttributesInvoked)
+	suite.Require().Equal(int64(1), aws.Int64Value(queue.receiveMessageInput.VisibilityTimeout))
+
+	// the messages of a group are handled in order, and once one fails the rest of the group is left for
+	// after it's received again
+	var groupBodies []string
+	for _, body := range recordingRuntime.getBodies() {
+		if strings.Contains(body, "a") {
+			groupBodies = append(groupBodies, body)
+		}
+	}
+
+	suite.Require().Equal([]string{"a1", "fail-a2"}, groupBodies)
+	suite.Require().Equal([]string{"a1", "b1", "b2"}, queue.getDeleted())
+	suite.Require().Contains(queue.getVisibilityChanges(), "a3:0")
+	suite.Require().Contains(queue.getVisibilityChanges(), "fail-a2:0")
+}
+
+func (suite *sqsTestSuite) TestGroupMessages() {
+	triggerInstance := &sqs{fifo: true}
+	messages := append(suite.createMessages("a", "a1"), suite.createMessages("b", "b1")...)
+	messages = append(messages, suite.createMessages("a", "a2")...)
+
+	groups := triggerInstance.groupMessages(messages)
+	suite.Require().Len(groups, 2)
+	suite.Require().Equal([]*sqsClient.Message{messages[0], messages[2]}, groups[0].messages)
+	suite.Require().Equal([]*sqsClient.Message{messages[1]}, groups[1].messages)
+
+	// standard queues have no order to keep
+	triggerInstance.fifo = false
+	suite.Require().Len(triggerInstance.groupMessages(messages), 3)
+}
+
+func (suite *sqsTestSuite) startTrigger(queue *fakeQueue,
+	attributes map[string]interface{},
+	numWorkers int) (*sqs, *recordingRuntime) {
+	configuration, err := suite.newConfiguration(attributes)
+	suite.Require().NoError(err)
+
+	configuration.MaxWorkers = numWorkers
+	recordingRuntime := &recordingRuntime{}
+
+	var workers []*worker.Worker
+	for workerIndex := 0; workerIndex < numWorkers; workerIndex++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIndex, recordingRuntime)
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	sqsTrigger := triggerInstance.(*sqs)
+	sqsTrigger.client = queue
+
+	err = sqsTrigger.Start(nil)
+	suite.Require().NoError(err)
+
+	return sqsTrigger, recordingRuntime
+}
+
+func (suite *sqsTestSuite) createMessages(groupID string, bodies ...string) []*sqsClient.Message {
+	var messages []*sqsClient.Message
+
+	for _, body := range bodies {
+		message := &sqsClient.Message{
+			MessageId:     aws.String(body),
+			ReceiptHandle: aws.String(body),
+			Body:          aws.String(body),
+			Attributes:    map[string]*string{},
+		}
+
+		if groupID != "" {
+			message.Attributes[sqsClient.MessageSystemAttributeNameMessageGroupId] = aws.String(groupID)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+func (suite *sqsTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	runtimeConfiguration := &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			Name:       "my-trigger",
+			Kind:       "sqs",
+			Attributes: attributes,
+		},
+		runtimeConfiguration)
+}
+
+func TestSQSSuite(t *testing.T) {
+	suite.Run(t, new(sqsTestSuite))
+}
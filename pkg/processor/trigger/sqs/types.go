@@ -0,0 +1,185 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultWaitTimeSeconds     = 20
+	DefaultMaxNumberOfMessages = 10
+
+	// the limits SQS puts on receiving messages
+	maxWaitTimeSeconds   = 20
+	maxNumberOfMessages  = 10
+	maxVisibilityTimeout = 12 * time.Hour
+
+	// the names of FIFO queues must end with it
+	fifoQueueNameSuffix = ".fifo"
+
+	// how long to wait before receiving again after failing to
+	receiveErrorBackoff = time.Second
+)
+
+// matches the hosts of queue URLs, e.g. sqs.us-east-2.amazonaws.com (or the legacy us-east-2.queue.amazonaws.com)
+var queueURLHostRegex = regexp.MustCompile(`^(?:sqs\.([a-z0-9-]+)|([a-z0-9-]+)\.queue)\.amazonaws\.com(?:\.cn)?$`)
+
+type Configuration struct {
+	trigger.Configuration
+	common.AWSSessionConfiguration `mapstructure:",squash"`
+
+	// the queue messages are read from - either its URL, or its name (resolved to a URL on start)
+	QueueURL  string
+	QueueName string
+
+	// how long a receive request waits for messages to arrive (long polling), up to 20 seconds
+	WaitTimeSeconds int
+
+	// the number of messages received at a time, up to 10
+	MaxNumberOfMessages int
+
+	// how long received messages stay hidden from other consumers. when not given, the queue's visibility
+	// timeout is used. the trigger keeps extending it for the messages it hasn't finished handling
+	VisibilityTimeout string
+
+	// how long messages the function failed to handle stay hidden before they're received again
+	// (default: 0 - they're redelivered right away, subject to the queue's redrive policy)
+	RetryDelay string
+
+	visibilityTimeout time.Duration
+	retryDelay        time.Duration
+}
+
+func NewConfiguration(ID string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *trigger.NewConfiguration(ID, triggerConfiguration, runtimeConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	// the queue URL may be given as the trigger's URL
+	if newConfiguration.QueueURL == "" {
+		newConfiguration.QueueURL = newConfiguration.URL
+	}
+
+	if newConfiguration.QueueURL == "" && newConfiguration.QueueName == "" {
+		return nil, errors.New("Either a queue URL or a queue name must be given")
+	}
+
+	if newConfiguration.QueueURL != "" && newConfiguration.QueueName != "" {
+		return nil, errors.New("A queue URL and a queue name are mutually exclusive")
+	}
+
+	if newConfiguration.Region == "" {
+		newConfiguration.Region = getRegionFromQueueURL(newConfiguration.QueueURL)
+	}
+
+	if newConfiguration.Region == "" {
+		return nil, errors.New("Region must be given when it can't be inferred from the queue URL")
+	}
+
+	if newConfiguration.WaitTimeSeconds == 0 {
+		newConfiguration.WaitTimeSeconds = DefaultWaitTimeSeconds
+	}
+
+	if newConfiguration.WaitTimeSeconds < 0 || newConfiguration.WaitTimeSeconds > maxWaitTimeSeconds {
+		return nil, errors.Errorf("Wait time must be between 1 and %d seconds, got %d",
+			maxWaitTimeSeconds,
+			newConfiguration.WaitTimeSeconds)
+	}
+
+	if newConfiguration.MaxNumberOfMessages == 0 {
+		newConfiguration.MaxNumberOfMessages = DefaultMaxNumberOfMessages
+	}
+
+	if newConfiguration.MaxNumberOfMessages < 0 || newConfiguration.MaxNumberOfMessages > maxNumberOfMessages {
+		return nil, errors.Errorf("Max number of messages must be between 1 and %d, got %d",
+			maxNumberOfMessages,
+			newConfiguration.MaxNumberOfMessages)
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "visibility timeout",
+			Value:   newConfiguration.VisibilityTimeout,
+			Field:   &newConfiguration.visibilityTimeout,
+			Default: 0,
+		},
+		{
+			Name:    "retry delay",
+			Value:   newConfiguration.RetryDelay,
+			Field:   &newConfiguration.retryDelay,
+			Default: 0,
+		},
+	} {
+		if err := newConfiguration.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, err
+		}
+	}
+
+	// SQS takes both in whole seconds
+	if newConfiguration.visibilityTimeout != 0 &&
+		(newConfiguration.visibilityTimeout < time.Second || newConfiguration.visibilityTimeout > maxVisibilityTimeout) {
+		return nil, errors.Errorf("Visibility timeout must be between 1s and %s", maxVisibilityTimeout)
+	}
+
+	if newConfiguration.retryDelay < 0 || newConfiguration.retryDelay > maxVisibilityTimeout {
+		return nil, errors.Errorf("Retry delay must be between 0 and %s", maxVisibilityTimeout)
+	}
+
+	return &newConfiguration, nil
+}
+
+func getRegionFromQueueURL(queueURL string) string {
+	parsedQueueURL, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+
+	matches := queueURLHostRegex.FindStringSubmatch(parsedQueueURL.Hostname())
+	if matches == nil {
+		return ""
+	}
+
+	if matches[1] != "" {
+		return matches[1]
+	}
+
+	return matches[2]
+}
+
+func isFIFOQueueURL(queueURL string) bool {
+	return strings.HasSuffix(queueURL, fifoQueueNameSuffix)
+}
@@ -17,12 +17,20 @@ limitations under the License.
 package trigger
 
 import (
+	"fmt"
 	"runtime/debug"
-	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+	"github.com/nuclio/nuclio/pkg/processor/deadline"
+	"github.com/nuclio/nuclio/pkg/processor/inspector"
+	"github.com/nuclio/nuclio/pkg/processor/recording"
+	"github.com/nuclio/nuclio/pkg/processor/tracing"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/deadletter"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/retry"
+	"github.com/nuclio/nuclio/pkg/processor/util/ratelimit"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
 	"github.com/nuclio/errors"
@@ -51,6 +59,9 @@ type Trigger interface {
 	// get the user given ID for this trigger
 	GetID() string
 
+	// get the name of the trigger in the function's configuration
+	GetName() string
+
 	// get the class of source (sync, async, etc)
 	GetClass() string
 
@@ -88,6 +99,32 @@ type AbstractTrigger struct {
 	Statistics      Statistics
 	Namespace       string
 	FunctionName    string
+
+	// if set, failed events are retried up to DeadLetterMaxRetries times and then published to it
+	DeadLetterPublisher  deadletter.Publisher
+	DeadLetterMaxRetries int
+
+	// if set, failed events are retried according to it (before being dead-lettered)
+	RetryPolicy *retry.Policy
+
+	// if set, limits the rate at which events are dispatched to workers
+	RateLimiter *ratelimit.Limiter
+
+	// traces the handling of events, if set
+	Tracer *tracing.Tracer
+
+	// records a sample of the handled events, if set
+	EventRecorder *recording.Recorder
+
+	// retains a summary of the last invocations, if set
+	InvocationInspector *inspector.Inspector
+
+	// how events are parsed as CloudEvents
+	CloudEventsMode string
+
+	// how long the handling of an event may take before the handler is cancelled, if limited. callers may
+	// ask for less
+	EventTimeout time.Duration
 }
 
 func NewAbstractTrigger(logger logger.Logger,
@@ -107,16 +144,60 @@ func NewAbstractTrigger(logger logger.Logger,
 		configuration.WorkerAvailabilityTimeoutMilliseconds = &defaultWorkerAvailabilityTimeoutMilliseconds
 	}
 
-	return AbstractTrigger{
-		Logger:          logger,
-		ID:              configuration.ID,
-		WorkerAllocator: allocator,
-		Class:           class,
-		Kind:            kind,
-		Name:            name,
-		Namespace:       configuration.RuntimeConfiguration.Meta.Namespace,
-		FunctionName:    configuration.RuntimeConfiguration.Meta.Name,
-	}, nil
+	abstractTrigger := AbstractTrigger{
+		Logger:              logger,
+		ID:                  configuration.ID,
+		WorkerAllocator:     allocator,
+		Class:               class,
+		Kind:                kind,
+		Name:                name,
+		Namespace:           configuration.RuntimeConfiguration.Meta.Namespace,
+		FunctionName:        configuration.RuntimeConfiguration.Meta.Name,
+		Tracer:              configuration.RuntimeConfiguration.Tracer,
+		EventRecorder:       configuration.RuntimeConfiguration.EventRecorder,
+		CloudEventsMode:     configuration.CloudEvents.GetMode(),
+		InvocationInspector: configuration.RuntimeConfiguration.InvocationInspector,
+	}
+
+	if configuration.DeadLetter != nil {
+		deadLetterPublisher, err := deadletter.NewPublisher(logger, configuration.DeadLetter)
+		if err != nil {
+			return AbstractTrigger{}, errors.Wrap(err, "Failed to create dead-letter publisher")
+		}
+
+		abstractTrigger.DeadLetterPublisher = deadLetterPublisher
+		abstractTrigger.DeadLetterMaxRetries = configuration.DeadLetter.MaxRetries
+	}
+
+	if configuration.RetryPolicy != nil {
+		retryPolicy, err := retry.NewPolicy(configuration.RetryPolicy)
+		if err != nil {
+			return AbstractTrigger{}, errors.Wrap(err, "Failed to create retry policy")
+		}
+
+		abstractTrigger.RetryPolicy = retryPolicy
+	}
+
+	if configuration.RateLimit != nil {
+		abstractTrigger.RateLimiter = ratelimit.NewLimiter(configuration.RateLimit.RPS,
+			configuration.RateLimit.GetBurst())
+	}
+
+	eventTimeout, err := configuration.GetEventTimeout()
+	if err != nil {
+		return AbstractTrigger{}, errors.Wrap(err, "Failed to parse event timeout")
+	}
+
+	// the function's event timeout applies to triggers which don't have their own
+	if eventTimeout == 0 && configuration.RuntimeConfiguration.Spec.EventTimeout != "" {
+		if eventTimeout, err = configuration.RuntimeConfiguration.Spec.GetEventTimeout(); err != nil {
+			return AbstractTrigger{}, errors.Wrap(err, "Failed to parse function event timeout")
+		}
+	}
+
+	abstractTrigger.EventTimeout = eventTimeout
+
+	return abstractTrigger, nil
 }
 
 // Initialize performs post creation initializations
@@ -148,15 +229,25 @@ func (at *AbstractTrigger) AllocateWorkerAndSubmitEvent(event nuclio.Event,
 
 	defer at.HandleSubmitPanic(workerInstance, &submitError)
 
+	at.WaitForRateLimit(event)
+
+	span := at.StartEventSpan(tracing.GetTraceparent(event))
+	defer span.End()
+
 	// allocate a worker
+	allocationSpan := span.StartChild("allocate worker", tracing.SpanKindInternal)
 	workerInstance, err := at.WorkerAllocator.Allocate(timeout)
+	allocationSpan.SetError(err)
+	allocationSpan.End()
+
 	if err != nil {
 		at.UpdateStatistics(false)
+		span.SetError(err)
 
 		return nil, errors.Wrap(err, "Failed to allocate worker"), nil
 	}
 
-	response, processError = at.SubmitEventToWorker(functionLogger, workerInstance, event)
+	response, processError = at.SubmitEventToWorkerInSpan(span, functionLogger, workerInstance, event)
 
 	// release worker when we're done
 	at.WorkerAllocator.Release(workerInstance)
@@ -211,6 +302,10 @@ func (at *AbstractTrigger) GetStatistics() *Statistics {
 	// copy worker allocator statistics
 	at.Statistics.WorkerAllocatorStatistics = *at.WorkerAllocator.GetStatistics()
 
+	if at.RateLimiter != nil {
+		at.Statistics.RateLimiterStatistics = *at.RateLimiter.GetStatistics()
+	}
+
 	return &at.Statistics
 }
 
@@ -258,15 +353,162 @@ func (at *AbstractTrigger) SubmitEventToWorker(functionLogger logger.Logger,
 	workerInstance *worker.Worker,
 	event nuclio.Event) (response interface{}, processError error) {
 
+	at.WaitForRateLimit(event)
+
+	span := at.StartEventSpan(tracing.GetTraceparent(event))
+	defer span.End()
+
+	return at.SubmitEventToWorkerInSpan(span, functionLogger, workerInstance, event)
+}
+
+// SubmitEventToWorkerInSpan submits an event to a worker, tracing its handling as a child of the given span
+func (at *AbstractTrigger) SubmitEventToWorkerInSpan(span *tracing.Span,
+	functionLogger logger.Logger,
+	workerInstance *worker.Worker,
+	event nuclio.Event) (response interface{}, processError error) {
+
+	startTime := time.Now()
+
 	event, err := at.prepareEvent(event, workerInstance)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
+	handlerSpan := span.StartChild("handle event", tracing.SpanKindInternal)
+	handlerSpan.SetAttribute("nuclio.worker.index", workerInstance.GetIndex())
+	handlerSpan.SetAttribute("nuclio.event.id", string(event.GetID()))
+
+	// the handler continues the trace from its own span
+	event = tracing.WithSpan(event, handlerSpan)
+
+	// the handler is cancelled if it's still handling the event by its deadline
+	event, releaseDeadline := deadline.WithDeadline(event, at.getEventDeadline(event))
+	defer releaseDeadline()
+
 	response, processError = workerInstance.ProcessEvent(event, functionLogger)
+	handlerSpan.SetError(processError)
+	handlerSpan.End()
+
+	attempts := 1
+	if at.RetryPolicy != nil {
+		response, attempts, processError = at.retryEvent(functionLogger, workerInstance, event, response, processError)
+		span.SetAttribute("nuclio.event.attempts", attempts)
+	}
+
+	if processError != nil && at.DeadLetterPublisher != nil {
+		response, processError = at.retryOrDeadLetterEvent(functionLogger,
+			workerInstance,
+			event,
+			attempts,
+			processError)
+	}
+
+	at.EventRecorder.Record(event, at.Kind, at.Name, response, processError)
+	at.InvocationInspector.Inspect(event, at.Kind, at.Name, response, processError, time.Since(startTime))
 
 	// increment statistics based on results. if process error is nil, we successfully handled
 	at.UpdateStatistics(processError == nil)
+	span.SetError(processError)
+	return
+}
+
+// WaitForRateLimit blocks until the trigger's rate limit allows dispatching the event (a batch event takes a
+// token per record), if it has one. stream triggers dispatch the records of a shard one event at a time, so
+// waiting pauses its consumption
+func (at *AbstractTrigger) WaitForRateLimit(event nuclio.Event) {
+	if at.RateLimiter == nil {
+		return
+	}
+
+	numTokens := 1
+	if batchEvent, isBatchEvent := event.(*BatchEvent); isBatchEvent {
+		numTokens = batchEvent.Len()
+	}
+
+	// only http triggers limit per caller (validation rejects a key for other kinds), so the events of all the
+	// shards share the trigger's single bucket
+	at.RateLimiter.Wait("", numTokens)
+}
+
+// StartEventSpan starts the span of handling an event, continuing the trace of the given W3C traceparent (if
+// any). the span is nil if tracing is disabled
+func (at *AbstractTrigger) StartEventSpan(traceparent string) *tracing.Span {
+	if at.Tracer == nil {
+		return nil
+	}
+
+	spanKind := tracing.SpanKindInternal
+	switch at.Class {
+	case "sync":
+		spanKind = tracing.SpanKindServer
+	case "async":
+		spanKind = tracing.SpanKindConsumer
+	}
+
+	span := at.Tracer.StartSpan(fmt.Sprintf("%s %s", at.Kind, at.Name), spanKind, traceparent)
+	span.SetAttribute("nuclio.function.name", at.FunctionName)
+	span.SetAttribute("nuclio.function.namespace", at.Namespace)
+	span.SetAttribute("nuclio.trigger.kind", at.Kind)
+	span.SetAttribute("nuclio.trigger.name", at.Name)
+
+	return span
+}
+
+// retryEvent processes an event again, for as long as the retry policy deems its outcome a transient failure,
+// backing off between attempts. returns the outcome of the last attempt, and the number of attempts
+func (at *AbstractTrigger) retryEvent(functionLogger logger.Logger,
+	workerInstance *worker.Worker,
+	event nuclio.Event,
+	response interface{},
+	processError error) (interface{}, int, error) {
+	retries := 0
+
+	for ; at.RetryPolicy.ShouldRetry(retries, response, processError); retries++ {
+		interval := at.RetryPolicy.GetInterval(retries + 1)
+
+		at.Logger.DebugWith("Retrying event",
+			"eventID", event.GetID(),
+			"retry", retries+1,
+			"interval", interval)
+
+		time.Sleep(interval)
+		response, processError = workerInstance.ProcessEvent(event, functionLogger)
+	}
+
+	return response, retries + 1, processError
+}
+
+// retryOrDeadLetterEvent retries an event the function failed to process and, if it keeps failing, publishes
+// it to the dead-letter target. the event is still considered failed once it's dead-lettered
+func (at *AbstractTrigger) retryOrDeadLetterEvent(functionLogger logger.Logger,
+	workerInstance *worker.Worker,
+	event nuclio.Event,
+	attempts int,
+	processError error) (response interface{}, lastProcessError error) {
+	maxAttempts := attempts + at.DeadLetterMaxRetries
+	lastProcessError = processError
+
+	for ; lastProcessError != nil && attempts < maxAttempts; attempts++ {
+		response, lastProcessError = workerInstance.ProcessEvent(event, functionLogger)
+	}
+
+	if lastProcessError == nil {
+		return
+	}
+
+	record := deadletter.NewRecord(event, at.Name, at.FunctionName, attempts, lastProcessError)
+	if err := at.DeadLetterPublisher.Publish(record); err != nil {
+		at.Logger.WarnWith("Failed to publish event to dead-letter target",
+			"eventID", event.GetID(),
+			"attempts", attempts,
+			"err", err.Error())
+
+		atomic.AddUint64(&at.Statistics.EventsDeadLetterFailureTotal, 1)
+		return
+	}
+
+	atomic.AddUint64(&at.Statistics.EventsDeadLetteredTotal, 1)
 	return
 }
 
@@ -275,6 +517,24 @@ func (at *AbstractTrigger) TimeoutWorker(worker *worker.Worker) error {
 	return nil
 }
 
+// getEventDeadline returns the time by which the event must be handled - the earliest of the trigger's event
+// timeout and the deadline the caller asked for, or zero if there's neither
+func (at *AbstractTrigger) getEventDeadline(event nuclio.Event) time.Time {
+	var eventDeadline time.Time
+
+	if at.EventTimeout > 0 {
+		eventDeadline = time.Now().Add(at.EventTimeout)
+	}
+
+	if requestedDeadline, found := deadline.GetRequested(event); found {
+		if eventDeadline.IsZero() || requestedDeadline.Before(eventDeadline) {
+			eventDeadline = requestedDeadline
+		}
+	}
+
+	return eventDeadline
+}
+
 // UpdateStatistics updates the trigger statistics
 func (at *AbstractTrigger) UpdateStatistics(success bool) {
 	if success {
@@ -285,37 +545,40 @@ func (at *AbstractTrigger) UpdateStatistics(success bool) {
 }
 
 func (at *AbstractTrigger) prepareEvent(event nuclio.Event, workerInstance *worker.Worker) (nuclio.Event, error) {
+	switch at.CloudEventsMode {
 
-	// if the content type starts with application/cloudevents, the body
-	// contains a structured cloud event (a JSON encoded structure)
-	// https://github.com/cloudevents/spec/blob/master/json-format.md
-	if strings.HasPrefix(event.GetContentType(), "application/cloudevents") {
-
-		// use the structured cloudevent stored in the worker to wrap this existing event
-		structuredCloudEvent := workerInstance.GetStructuredCloudEvent()
+	// every event is a cloud event of the configured mode
+	case functionconfig.CloudEventsModeStructured:
+		structuredCloudEvent, err := at.prepareStructuredCloudEvent(event, workerInstance)
+		if err != nil {
+			return nil, err
+		}
 
-		// wrap the received event
-		if err := structuredCloudEvent.SetEvent(event); err != nil {
-			return nil, errors.Wrap(err, "Failed to wrap structured cloud event")
+		if structuredCloudEvent.GetVersion() == "" {
+			return nil, errors.New("Event isn't a structured cloud event (it has no spec version)")
 		}
 
 		return structuredCloudEvent, nil
-	}
 
-	// if body does not encode a structured cloudevent, check if this is a
-	// binary cloud event by checking the existence of the
-	// "CE-CloudEventsVersion" header
-	if event.GetHeaderString("CE-CloudEventsVersion") != "" {
+	case functionconfig.CloudEventsModeBinary:
+		return at.prepareBinaryCloudEvent(event, workerInstance)
+
+	case functionconfig.CloudEventsModeDisabled:
 
-		// use the structured cloudevent stored in the worker to wrap this existing event
-		binaryCloudEvent := workerInstance.GetBinaryCloudEvent()
+	default:
 
-		// wrap the received event
-		if err := binaryCloudEvent.SetEvent(event); err != nil {
-			return nil, errors.Wrap(err, "Failed to wrap binary cloud event")
+		// if the content type starts with application/cloudevents, the body
+		// contains a structured cloud event (a JSON encoded structure)
+		// https://github.com/cloudevents/spec/blob/master/json-format.md
+		if cloudevent.IsStructured(event) {
+			return at.prepareStructuredCloudEvent(event, workerInstance)
 		}
 
-		return binaryCloudEvent, nil
+		// if body does not encode a structured cloudevent, check if this is a
+		// binary cloud event by checking the existence of the spec version header
+		if cloudevent.IsBinary(event) {
+			return at.prepareBinaryCloudEvent(event, workerInstance)
+		}
 	}
 
 	// Not a cloud event
@@ -323,3 +586,31 @@ func (at *AbstractTrigger) prepareEvent(event nuclio.Event, workerInstance *work
 	event.SetTriggerInfoProvider(at)
 	return event, nil
 }
+
+func (at *AbstractTrigger) prepareStructuredCloudEvent(event nuclio.Event,
+	workerInstance *worker.Worker) (nuclio.Event, error) {
+
+	// use the structured cloudevent stored in the worker to wrap this existing event
+	structuredCloudEvent := workerInstance.GetStructuredCloudEvent()
+
+	// wrap the received event
+	if err := structuredCloudEvent.SetEvent(event); err != nil {
+		return nil, errors.Wrap(err, "Failed to wrap structured cloud event")
+	}
+
+	return structuredCloudEvent, nil
+}
+
+func (at *AbstractTrigger) prepareBinaryCloudEvent(event nuclio.Event,
+	workerInstance *worker.Worker) (nuclio.Event, error) {
+
+	// use the binary cloudevent stored in the worker to wrap this existing event
+	binaryCloudEvent := workerInstance.GetBinaryCloudEvent()
+
+	// wrap the received event
+	if err := binaryCloudEvent.SetEvent(event); err != nil {
+		return nil, errors.Wrap(err, "Failed to wrap binary cloud event")
+	}
+
+	return binaryCloudEvent, nil
+}
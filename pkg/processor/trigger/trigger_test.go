@@ -0,0 +1,217 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/deadletter"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/retry"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// failingRuntime fails the first failures events it processes
+type failingRuntime struct {
+	runtime.Runtime
+	failures    int
+	invocations int
+}
+
+func (fr *failingRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	fr.invocations++
+
+	if fr.invocations <= fr.failures {
+		return nil, errors.Errorf("Failed invocation %d", fr.invocations)
+	}
+
+	return "ok", nil
+}
+
+func (fr *failingRuntime) GetStatus() status.Status {
+	return status.Ready
+}
+
+type recordingPublisher struct {
+	records []*deadletter.Record
+	err     error
+}
+
+func (rp *recordingPublisher) Publish(record *deadletter.Record) error {
+	if rp.err != nil {
+		return rp.err
+	}
+
+	rp.records = append(rp.records, record)
+	return nil
+}
+
+type triggerTestSuite struct {
+	suite.Suite
+	logger    logger.Logger
+	runtime   *failingRuntime
+	worker    *worker.Worker
+	publisher *recordingPublisher
+	trigger   AbstractTrigger
+}
+
+func (suite *triggerTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *triggerTestSuite) SetupTest() {
+	var err error
+
+	suite.runtime = &failingRuntime{}
+	suite.worker, err = worker.NewWorker(suite.logger, 0, suite.runtime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewSingletonWorkerAllocator(suite.logger, suite.worker)
+	suite.Require().NoError(err)
+
+	suite.trigger, err = NewAbstractTrigger(suite.logger,
+		workerAllocator,
+		NewConfiguration("test", &functionconfig.Trigger{}, &runtime.Configuration{
+			Configuration: &processor.Configuration{},
+		}),
+		"async",
+		"test",
+		"my-trigger")
+	suite.Require().NoError(err)
+
+	suite.publisher = &recordingPublisher{}
+	suite.trigger.DeadLetterPublisher = suite.publisher
+	suite.trigger.DeadLetterMaxRetries = 2
+}
+
+func (suite *triggerTestSuite) TestEventSucceedsOnRetry() {
+	suite.runtime.failures = 2
+
+	response, processError := suite.submitEvent()
+	suite.Require().NoError(processError)
+	suite.Require().Equal("ok", response)
+	suite.Require().Equal(3, suite.runtime.invocations)
+	suite.Require().Empty(suite.publisher.records)
+	suite.Require().Equal(uint64(1), suite.trigger.Statistics.EventsHandledSuccessTotal)
+}
+
+func (suite *triggerTestSuite) TestEventDeadLettered() {
+	suite.runtime.failures = 3
+
+	_, processError := suite.submitEvent()
+	suite.Require().Error(processError)
+	suite.Require().Equal(3, suite.runtime.invocations)
+
+	// the event is dead-lettered with the last error, and is still considered failed
+	suite.Require().Len(suite.publisher.records, 1)
+	suite.Require().Equal("body", string(suite.publisher.records[0].Body))
+	suite.Require().Equal(3, suite.publisher.records[0].Attempts)
+	suite.Require().Equal("Failed invocation 3", suite.publisher.records[0].Error)
+	suite.Require().Equal("my-trigger", suite.publisher.records[0].TriggerName)
+	suite.Require().Equal(uint64(1), suite.trigger.Statistics.EventsHandledFailureTotal)
+	suite.Require().Equal(uint64(1), suite.trigger.Statistics.EventsDeadLetteredTotal)
+}
+
+func (suite *triggerTestSuite) TestDeadLetterPublishFailure() {
+	suite.runtime.failures = 3
+	suite.publisher.err = errors.New("Target unreachable")
+
+	_, processError := suite.submitEvent()
+	suite.Require().Error(processError)
+	suite.Require().Equal(uint64(0), suite.trigger.Statistics.EventsDeadLetteredTotal)
+	suite.Require().Equal(uint64(1), suite.trigger.Statistics.EventsDeadLetterFailureTotal)
+}
+
+func (suite *triggerTestSuite) TestNoRetriesWithoutDeadLetter() {
+	suite.runtime.failures = 1
+	suite.trigger.DeadLetterPublisher = nil
+
+	_, processError := suite.submitEvent()
+	suite.Require().Error(processError)
+	suite.Require().Equal(1, suite.runtime.invocations)
+}
+
+func (suite *triggerTestSuite) TestRetryPolicyBacksOff() {
+	suite.runtime.failures = 2
+	suite.trigger.DeadLetterPublisher = nil
+	suite.trigger.RetryPolicy = &retry.Policy{
+		MaxRetries:      3,
+		InitialInterval: 20 * time.Millisecond,
+		Multiplier:      2,
+		MaxInterval:     time.Second,
+	}
+
+	startTime := time.Now()
+	response, processError := suite.submitEvent()
+	suite.Require().NoError(processError)
+	suite.Require().Equal("ok", response)
+	suite.Require().Equal(3, suite.runtime.invocations)
+
+	// waited 20ms before the first retry and 40ms before the second
+	suite.Require().True(time.Since(startTime) >= 60*time.Millisecond)
+}
+
+func (suite *triggerTestSuite) TestRetryPolicyThenDeadLetter() {
+	suite.runtime.failures = 5
+	suite.trigger.DeadLetterMaxRetries = 0
+	suite.trigger.RetryPolicy = &retry.Policy{
+		MaxRetries:  2,
+		Multiplier:  1,
+		MaxInterval: time.Second,
+	}
+
+	_, processError := suite.submitEvent()
+	suite.Require().Error(processError)
+	suite.Require().Equal(3, suite.runtime.invocations)
+
+	// the event is dead-lettered once the retry policy gives up on it
+	suite.Require().Len(suite.publisher.records, 1)
+	suite.Require().Equal(3, suite.publisher.records[0].Attempts)
+	suite.Require().Equal("Failed invocation 3", suite.publisher.records[0].Error)
+}
+
+func (suite *triggerTestSuite) TestRetryPolicyNonRetryableError() {
+	suite.runtime.failures = 1
+	suite.trigger.DeadLetterPublisher = nil
+	suite.trigger.RetryPolicy = &retry.Policy{
+		MaxRetries:      3,
+		Multiplier:      1,
+		RetryableErrors: []string{"connection refused"},
+	}
+
+	_, processError := suite.submitEvent()
+	suite.Require().Error(processError)
+	suite.Require().Equal(1, suite.runtime.invocations)
+}
+
+func (suite *triggerTestSuite) submitEvent() (interface{}, error) {
+	return suite.trigger.SubmitEventToWorker(nil, suite.worker, &nuclio.MemoryEvent{Body: []byte("body")})
+}
+
+func TestTriggerTestSuite(t *testing.T) {
+	suite.Run(t, new(triggerTestSuite))
+}
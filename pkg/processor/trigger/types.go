@@ -17,12 +17,16 @@ limitations under the License.
 package trigger
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync/atomic"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/util/ratelimit"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
 	"github.com/nuclio/errors"
@@ -107,34 +111,110 @@ func (c *Configuration) ParseDurationOrDefault(durationConfigField *DurationConf
 }
 
 type Statistics struct {
-	EventsHandledSuccessTotal uint64
-	EventsHandledFailureTotal uint64
-	WorkerAllocatorStatistics worker.AllocatorStatistics
+	EventsHandledSuccessTotal    uint64
+	EventsHandledFailureTotal    uint64
+	EventsDeadLetteredTotal      uint64
+	EventsDeadLetterFailureTotal uint64
+	WorkerAllocatorStatistics    worker.AllocatorStatistics
+
+	// the events held back by the trigger's rate limit, if it has one
+	RateLimiterStatistics ratelimit.Statistics
+
+	// ConsumerLag is the number of messages the trigger has yet to receive, for triggers which report it. it's
+	// a gauge, and as such isn't diffed
+	ConsumerLag uint64
 }
 
 func (s *Statistics) DiffFrom(prev *Statistics) Statistics {
 	workerAllocatorStatisticsDiff := s.WorkerAllocatorStatistics.DiffFrom(&prev.WorkerAllocatorStatistics)
+	rateLimiterStatisticsDiff := s.RateLimiterStatistics.DiffFrom(&prev.RateLimiterStatistics)
 
 	// atomically load the counters
 	currEventsHandledSuccessTotal := atomic.LoadUint64(&s.EventsHandledSuccessTotal)
 	currEventsHandledFailureTotal := atomic.LoadUint64(&s.EventsHandledFailureTotal)
 
+	currEventsDeadLetteredTotal := atomic.LoadUint64(&s.EventsDeadLetteredTotal)
+	currEventsDeadLetterFailureTotal := atomic.LoadUint64(&s.EventsDeadLetterFailureTotal)
+
 	prevEventsHandledSuccessTotal := atomic.LoadUint64(&prev.EventsHandledSuccessTotal)
 	prevEventsHandledFailureTotal := atomic.LoadUint64(&prev.EventsHandledFailureTotal)
+	prevEventsDeadLetteredTotal := atomic.LoadUint64(&prev.EventsDeadLetteredTotal)
+	prevEventsDeadLetterFailureTotal := atomic.LoadUint64(&prev.EventsDeadLetterFailureTotal)
 
 	return Statistics{
-		EventsHandledSuccessTotal: currEventsHandledSuccessTotal - prevEventsHandledSuccessTotal,
-		EventsHandledFailureTotal: currEventsHandledFailureTotal - prevEventsHandledFailureTotal,
-		WorkerAllocatorStatistics: workerAllocatorStatisticsDiff,
+		EventsHandledSuccessTotal:    currEventsHandledSuccessTotal - prevEventsHandledSuccessTotal,
+		EventsHandledFailureTotal:    currEventsHandledFailureTotal - prevEventsHandledFailureTotal,
+		EventsDeadLetteredTotal:      currEventsDeadLetteredTotal - prevEventsDeadLetteredTotal,
+		EventsDeadLetterFailureTotal: currEventsDeadLetterFailureTotal - prevEventsDeadLetterFailureTotal,
+		WorkerAllocatorStatistics:    workerAllocatorStatisticsDiff,
+		RateLimiterStatistics:        rateLimiterStatisticsDiff,
+		ConsumerLag:                  atomic.LoadUint64(&s.ConsumerLag),
 	}
 }
 
+// ShardStatistics is the consumption state of a shard a stream trigger consumes
+type ShardStatistics struct {
+	ShardID int `json:"shardID"`
+
+	// the number of records in the shard the trigger has yet to handle
+	Lag uint64 `json:"lag"`
+
+	// the sequence number of the last record marked as handled, which is committed periodically. a replica
+	// taking over the shard resumes from the record following it
+	CommittedSequenceNumber uint64 `json:"committedSequenceNumber"`
+}
+
+// ShardStatisticsProvider is implemented by stream triggers which report the state of each of the shards
+// they currently consume
+type ShardStatisticsProvider interface {
+
+	// GetShardStatistics returns the statistics of the claimed shards, ordered by shard ID
+	GetShardStatistics() []ShardStatistics
+}
+
+// SecretRef references the key of a secret exposed to the processor - either mounted as a volume (in which case
+// MountPath holds the mount path and the key is a file under it) or injected into the environment through
+// envFromSecrets (in which case the key is the name of the environment variable)
 type SecretRef struct {
-	Name string
-	Key  string
+	Name      string
+	Key       string
+	MountPath string
+}
+
+// Resolve returns the value of the referenced key
+func (sr *SecretRef) Resolve() (string, error) {
+	if sr.Key == "" {
+		return "", errors.New("Secret reference key must be set")
+	}
+
+	if sr.MountPath != "" {
+		contents, err := ioutil.ReadFile(filepath.Join(sr.MountPath, sr.Key))
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to read secret key %s", sr.Key)
+		}
+
+		return string(contents), nil
+	}
+
+	value, found := os.LookupEnv(sr.Key)
+	if !found {
+		return "", errors.Errorf("Secret key %s is not set in the environment", sr.Key)
+	}
+
+	return value, nil
 }
 
 type Secret struct {
 	Contents  string
 	SecretRef SecretRef
 }
+
+// Resolve returns the inline contents of the secret if set, or the value of the referenced key otherwise. an empty
+// secret resolves to an empty string
+func (s *Secret) Resolve() (string, error) {
+	if s.Contents != "" || s.SecretRef.Key == "" {
+		return s.Contents, nil
+	}
+
+	return s.SecretRef.Resolve()
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type secretTestSuite struct {
+	suite.Suite
+}
+
+func (suite *secretTestSuite) TestResolveContents() {
+	secret := Secret{
+		Contents:  "inline",
+		SecretRef: SecretRef{Key: "NUCLIO_TEST_SECRET"},
+	}
+
+	value, err := secret.Resolve()
+	suite.Require().NoError(err)
+	suite.Require().Equal("inline", value)
+
+	value, err = (&Secret{}).Resolve()
+	suite.Require().NoError(err)
+	suite.Require().Empty(value)
+}
+
+func (suite *secretTestSuite) TestResolveFromEnv() {
+	os.Setenv("NUCLIO_TEST_SECRET", "from-env") // nolint: errcheck
+	defer os.Unsetenv("NUCLIO_TEST_SECRET")     // nolint: errcheck
+
+	secret := Secret{SecretRef: SecretRef{Name: "my-secret", Key: "NUCLIO_TEST_SECRET"}}
+
+	value, err := secret.Resolve()
+	suite.Require().NoError(err)
+	suite.Require().Equal("from-env", value)
+
+	_, err = (&SecretRef{Key: "NUCLIO_TEST_MISSING_SECRET"}).Resolve()
+	suite.Require().Error(err)
+}
+
+func (suite *secretTestSuite) TestResolveFromMountPath() {
+	mountPath, err := ioutil.TempDir("", "secret-test")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(mountPath) // nolint: errcheck
+
+	err = ioutil.WriteFile(filepath.Join(mountPath, "password"), []byte("from-file"), 0600)
+	suite.Require().NoError(err)
+
+	value, err := (&SecretRef{Key: "password", MountPath: mountPath}).Resolve()
+	suite.Require().NoError(err)
+	suite.Require().Equal("from-file", value)
+
+	_, err = (&SecretRef{Key: "missing", MountPath: mountPath}).Resolve()
+	suite.Require().Error(err)
+
+	_, err = (&SecretRef{MountPath: mountPath}).Resolve()
+	suite.Require().Error(err)
+}
+
+func TestSecretTestSuite(t *testing.T) {
+	suite.Run(t, new(secretTestSuite))
+}
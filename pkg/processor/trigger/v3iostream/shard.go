@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3iostream
+
+import (
+	"sync"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/v3io/v3io-go/pkg/dataplane"
+)
+
+// the shard attribute holding the sequence number of the last record written to it
+const lastSequenceNumberAttributeName = "__last_sequence_num"
+
+// shard tracks the consumption of a claimed shard - the handled records held back by the ack window, and the
+// sequence numbers its lag is calculated from
+type shard struct {
+	id                        int
+	ackWindowSize             int
+	lock                      sync.Mutex
+	unmarkedSequenceNumbers   []uint64
+	lastHandledSequenceNumber uint64
+	markedSequenceNumber      uint64
+	latestSequenceNumber      uint64
+
+	// the sequence number of the first record to handle, with seekTo "sequence" or "time"
+	seekToSequenceNumber uint64
+}
+
+func newShard(id int, ackWindowSize int) *shard {
+	return &shard{
+		id:            id,
+		ackWindowSize: ackWindowSize,
+	}
+}
+
+// handled records that the records were handled, returning the record to mark - the last one that's out of
+// the ack window - or nil if all of them are still in it
+func (s *shard) handled(records []*v3io.StreamRecord) *v3io.StreamRecord {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, record := range records {
+		s.unmarkedSequenceNumbers = append(s.unmarkedSequenceNumbers, record.SequenceNumber)
+		s.lastHandledSequenceNumber = record.SequenceNumber
+	}
+
+	if len(s.unmarkedSequenceNumbers) <= s.ackWindowSize {
+		return nil
+	}
+
+	// everything before the window may be marked, and marking a record marks those before it
+	numRecordsToMark := len(s.unmarkedSequenceNumbers) - s.ackWindowSize
+	s.markedSequenceNumber = s.unmarkedSequenceNumbers[numRecordsToMark-1]
+	s.unmarkedSequenceNumbers = append(s.unmarkedSequenceNumbers[:0], s.unmarkedSequenceNumbers[numRecordsToMark:]...)
+
+	shardID := s.id
+
+	return &v3io.StreamRecord{
+		ShardID:        &shardID,
+		SequenceNumber: s.markedSequenceNumber,
+	}
+}
+
+func (s *shard) setLatestSequenceNumber(latestSequenceNumber uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.latestSequenceNumber = latestSequenceNumber
+}
+
+// getStatistics returns the shard's statistics. until a record is handled the trigger doesn't know where in
+// the shard it's reading from, so its lag is reported as 0
+func (s *shard) getStatistics() trigger.ShardStatistics {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	shardStatistics := trigger.ShardStatistics{
+		ShardID:                 s.id,
+		CommittedSequenceNumber: s.markedSequenceNumber,
+	}
+
+	if s.lastHandledSequenceNumber != 0 && s.latestSequenceNumber > s.lastHandledSequenceNumber {
+		shardStatistics.Lag = s.latestSequenceNumber - s.lastHandledSequenceNumber
+	}
+
+	return shardStatistics
+}
@@ -17,6 +17,13 @@ limitations under the License.
 package v3iostream
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -25,13 +32,17 @@ import (
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/v3io/v3io-go/pkg/dataplane"
 	v3iohttp "github.com/v3io/v3io-go/pkg/dataplane/http"
 	"github.com/v3io/v3io-go/pkg/dataplane/streamconsumergroup"
 )
 
+// how often the latest sequence number of each claimed shard is read, to calculate its lag
+const shardLagInterval = 10 * time.Second
+
 type submittedEvent struct {
-	event  Event
+	event  nuclio.Event
 	worker *worker.Worker
 	done   chan error
 }
@@ -45,6 +56,9 @@ type v3iostream struct {
 	stopConsumptionChan       chan struct{}
 	partitionWorkerAllocator  partitionworker.Allocator
 	topic                     string
+	v3ioContainer             v3io.Container
+	shards                    map[int]*shard
+	shardsLock                sync.Mutex
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -57,6 +71,7 @@ func newTrigger(parentLogger logger.Logger,
 	newTrigger := &v3iostream{
 		configuration:       configuration,
 		stopConsumptionChan: make(chan struct{}, 1),
+		shards:              map[int]*shard{},
 		topic:               "v3io", // v3io doesn't support topics, use constant (never goes to v3io)
 	}
 
@@ -135,6 +150,14 @@ func (vs *v3iostream) Setup(session streamconsumergroup.Session) error {
 		return errors.Wrap(err, "Failed to create partition worker allocator")
 	}
 
+	vs.shardsLock.Lock()
+	defer vs.shardsLock.Unlock()
+
+	vs.shards = map[int]*shard{}
+	for _, shardID := range shardIDs {
+		vs.shards[shardID] = newShard(shardID, vs.configuration.AckWindowSize)
+	}
+
 	return nil
 }
 
@@ -144,6 +167,13 @@ func (vs *v3iostream) Cleanup(session streamconsumergroup.Session) error {
 		return errors.Wrap(err, "Failed to stop partition worker allocator")
 	}
 
+	// the shards may be claimed by other members once the session ends
+	vs.shardsLock.Lock()
+	vs.shards = map[int]*shard{}
+	vs.shardsLock.Unlock()
+
+	atomic.StoreUint64(&vs.Statistics.ConsumerLag, 0)
+
 	vs.Logger.InfoWith("Ending consumer session",
 		"claims", session.GetClaims(),
 		"memberID", session.GetMemberID(),
@@ -160,51 +190,199 @@ func (vs *v3iostream) ConsumeClaim(session streamconsumergroup.Session, claim st
 	}
 
 	submittedEventChan := make(chan *submittedEvent)
+	stopShardLagPolling := make(chan struct{})
+
+	shardInstance := vs.getShard(claim.GetShardID())
+
+	seekToSequenceNumber, err := vs.resolveSeekToSequenceNumber(claim.GetShardID())
+	if err != nil {
+		return errors.Wrapf(err, "Failed to resolve the sequence number to seek shard %d to", claim.GetShardID())
+	}
+
+	shardInstance.seekToSequenceNumber = seekToSequenceNumber
 
 	// submit the events in a goroutine so that we can unblock immediately
 	go vs.eventSubmitter(claim, submittedEventChan)
+	go vs.pollShardLag(shardInstance, stopShardLagPolling)
+
+	if vs.configuration.Batch != nil {
+		submitError = vs.consumeClaimInBatches(session, claim, shardInstance, submittedEventChan, &submittedEventInstance)
+	} else {
+		submitError = vs.consumeClaimRecords(session, claim, shardInstance, submittedEventChan, &submittedEventInstance)
+	}
+
+	vs.Logger.DebugWith("Claim consumption stopped", "shardID", claim.GetShardID())
+
+	// shut down the event submitter and the lag polling
+	close(submittedEventChan)
+	close(stopShardLagPolling)
+
+	return submitError
+}
+
+func (vs *v3iostream) consumeClaimRecords(session streamconsumergroup.Session,
+	claim streamconsumergroup.Claim,
+	shardInstance *shard,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent) error {
+	var event Event
+	records := make([]*v3io.StreamRecord, 1)
 
 	// the exit condition is that (a) the Messages() channel was closed and (b) we got a signal telling us
 	// to stop consumption
 	for recordBatch := range claim.GetRecordBatchChan() {
 		for recordIndex := 0; recordIndex < len(recordBatch.Records); recordIndex++ {
 			record := &recordBatch.Records[recordIndex]
+			records[0] = record
 
-			// allocate a worker for this topic/partition
-			workerInstance, cookie, err := vs.partitionWorkerAllocator.AllocateWorker(vs.topic, claim.GetShardID(), nil)
-			if err != nil {
-				return errors.Wrap(err, "Failed to allocate worker")
+			// records preceding the one seeked to are marked without being handled
+			if vs.configuration.precedesSeekTo(record, shardInstance.seekToSequenceNumber) {
+				vs.markRecords(session, shardInstance, records)
+				continue
 			}
 
-			submittedEventInstance.event.record = record
-			submittedEventInstance.worker = workerInstance
+			event.record = record
 
-			// handle in the goroutine so we don't block
-			submittedEventChan <- &submittedEventInstance
+			if err := vs.submitEvent(session,
+				claim,
+				shardInstance,
+				submittedEventChan,
+				submittedEventInstance,
+				&event,
+				records); err != nil {
+				return err
+			}
+		}
+	}
 
-			// wait for handling done or indication to stop
-			err = <-submittedEventInstance.done
+	return nil
+}
 
-			// we successfully submitted the message to the handler. mark it
-			if err == nil {
-				session.MarkRecord(record) // nolint: errcheck
+// consumeClaimInBatches aggregates records into a batch event, submitted once it holds the maximum number of
+// records or the maximum wait passed since its first record was read
+func (vs *v3iostream) consumeClaimInBatches(session streamconsumergroup.Session,
+	claim streamconsumergroup.Claim,
+	shardInstance *shard,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent) error {
+	var event Event
+	var batchRecords []*v3io.StreamRecord
+	var batchTimeout <-chan time.Time
+
+	batchEvent := trigger.NewBatchEvent(vs.topic, claim.GetShardID())
+	recordBatchChan := claim.GetRecordBatchChan()
+
+	submitBatchEvent := func() error {
+		if err := vs.submitEvent(session,
+			claim,
+			shardInstance,
+			submittedEventChan,
+			submittedEventInstance,
+			batchEvent,
+			batchRecords); err != nil {
+			return err
+		}
+
+		batchEvent.Reset()
+		batchRecords = batchRecords[:0]
+		batchTimeout = nil
+
+		return nil
+	}
+
+	for {
+		select {
+		case recordBatch, ok := <-recordBatchChan:
+			if !ok {
+
+				// submit what was read so far
+				if batchEvent.Len() > 0 {
+					return submitBatchEvent()
+				}
+
+				return nil
+			}
+
+			for recordIndex := 0; recordIndex < len(recordBatch.Records); recordIndex++ {
+				record := &recordBatch.Records[recordIndex]
+
+				// records preceding the one seeked to are marked without being handled
+				if vs.configuration.precedesSeekTo(record, shardInstance.seekToSequenceNumber) {
+					vs.markRecords(session, shardInstance, []*v3io.StreamRecord{record})
+					continue
+				}
+
+				batchRecords = append(batchRecords, record)
+
+				event.record = record
+				batchEvent.Add(&event)
+
+				if batchEvent.Len() >= vs.configuration.Batch.MaxSize {
+					if err := submitBatchEvent(); err != nil {
+						return err
+					}
+				}
 			}
 
-			// release the worker from whence it came
-			err = vs.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance)
-			if err != nil {
-				return errors.Wrap(err, "Failed to release worker")
+			// start waiting for the batch to fill up
+			if batchEvent.Len() > 0 && batchTimeout == nil {
+				batchTimeout = time.After(vs.configuration.GetBatchMaxWait())
 			}
 
+		case <-batchTimeout:
+			if err := submitBatchEvent(); err != nil {
+				return err
+			}
 		}
 	}
+}
 
-	vs.Logger.DebugWith("Claim consumption stopped", "shardID", claim.GetShardID())
+// submitEvent submits an event (a record, or a batch of them) to a worker and waits for it to be handled,
+// marking its records as consumed if it was
+func (vs *v3iostream) submitEvent(session streamconsumergroup.Session,
+	claim streamconsumergroup.Claim,
+	shardInstance *shard,
+	submittedEventChan chan *submittedEvent,
+	submittedEventInstance *submittedEvent,
+	event nuclio.Event,
+	records []*v3io.StreamRecord) error {
+
+	// allocate a worker for this topic/partition
+	workerInstance, cookie, err := vs.partitionWorkerAllocator.AllocateWorker(vs.topic, claim.GetShardID(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to allocate worker")
+	}
 
-	// shut down the event submitter
-	close(submittedEventChan)
+	submittedEventInstance.event = event
+	submittedEventInstance.worker = workerInstance
 
-	return submitError
+	// handle in the goroutine so we don't block
+	submittedEventChan <- submittedEventInstance
+
+	// wait for handling done or indication to stop
+	err = <-submittedEventInstance.done
+
+	// we successfully submitted the records to the handler. mark them (and with them, the records before them)
+	if err == nil {
+		vs.markRecords(session, shardInstance, records)
+	}
+
+	// release the worker from whence it came
+	err = vs.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance)
+	if err != nil {
+		return errors.Wrap(err, "Failed to release worker")
+	}
+
+	return nil
+}
+
+// markRecords marks the records as handled, other than those held back by the ack window
+func (vs *v3iostream) markRecords(session streamconsumergroup.Session,
+	shardInstance *shard,
+	records []*v3io.StreamRecord) {
+	if recordToMark := shardInstance.handled(records); recordToMark != nil {
+		session.MarkRecord(recordToMark) // nolint: errcheck
+	}
 }
 
 func (vs *v3iostream) eventSubmitter(claim streamconsumergroup.Claim, submittedEventChan chan *submittedEvent) {
@@ -215,10 +393,10 @@ func (vs *v3iostream) eventSubmitter(claim streamconsumergroup.Claim, submittedE
 	for submittedEvent := range submittedEventChan {
 
 		// submit the event to the worker
-		_, processErr := vs.SubmitEventToWorker(nil, submittedEvent.worker, &submittedEvent.event) // nolint: errcheck
+		_, processErr := vs.SubmitEventToWorker(nil, submittedEvent.worker, submittedEvent.event) // nolint: errcheck
 		if processErr != nil {
 			vs.Logger.DebugWith("Process error",
-				"shardID", submittedEvent.event.record.ShardID,
+				"shardID", submittedEvent.event.GetShardID(),
 				"err", processErr)
 		}
 
@@ -249,7 +427,7 @@ func (vs *v3iostream) newConsumerGroupMember() (streamconsumergroup.Member, erro
 		return nil, errors.Wrap(err, "Failed to create v3io session")
 	}
 
-	v3ioContainer, err := v3ioSession.NewContainer(&v3io.NewContainerInput{
+	vs.v3ioContainer, err = v3ioSession.NewContainer(&v3io.NewContainerInput{
 		ContainerName: vs.configuration.ContainerName,
 	})
 	if err != nil {
@@ -266,7 +444,7 @@ func (vs *v3iostream) newConsumerGroupMember() (streamconsumergroup.Member, erro
 	streamConsumerGroup, err := streamconsumergroup.NewStreamConsumerGroup(vs.Logger,
 		vs.configuration.ConsumerGroup,
 		vs.v3iostreamConfig,
-		v3ioContainer,
+		vs.v3ioContainer,
 		vs.configuration.StreamPath,
 		maxReplicas)
 
@@ -310,3 +488,128 @@ func (vs *v3iostream) createPartitionWorkerAllocator(session streamconsumergroup
 		return nil, errors.Errorf("Unknown worker allocation mode: %s", vs.configuration.WorkerAllocationMode)
 	}
 }
+
+// GetShardStatistics returns the lag and committed sequence number of the shards the trigger claimed
+func (vs *v3iostream) GetShardStatistics() []trigger.ShardStatistics {
+	vs.shardsLock.Lock()
+	defer vs.shardsLock.Unlock()
+
+	shardsStatistics := make([]trigger.ShardStatistics, 0, len(vs.shards))
+	for _, shardInstance := range vs.shards {
+		shardsStatistics = append(shardsStatistics, shardInstance.getStatistics())
+	}
+
+	sort.Slice(shardsStatistics, func(i, j int) bool {
+		return shardsStatistics[i].ShardID < shardsStatistics[j].ShardID
+	})
+
+	return shardsStatistics
+}
+
+// getShard returns the state of a claimed shard, creating it if the shard was claimed after the session started
+func (vs *v3iostream) getShard(shardID int) *shard {
+	vs.shardsLock.Lock()
+	defer vs.shardsLock.Unlock()
+
+	shardInstance, found := vs.shards[shardID]
+	if !found {
+		shardInstance = newShard(shardID, vs.configuration.AckWindowSize)
+		vs.shards[shardID] = shardInstance
+	}
+
+	return shardInstance
+}
+
+// pollShardLag periodically reads the latest sequence number of the shard until stopped, reporting the total
+// lag of the claimed shards as the trigger's consumer lag
+func (vs *v3iostream) pollShardLag(shardInstance *shard, stop chan struct{}) {
+	ticker := time.NewTicker(shardLagInterval)
+	defer ticker.Stop()
+
+	for {
+		latestSequenceNumber, err := vs.getShardLatestSequenceNumber(shardInstance.id)
+		if err != nil {
+			vs.Logger.DebugWith("Failed to get shard latest sequence number",
+				"shardID", shardInstance.id,
+				"err", errors.RootCause(err).Error())
+		} else {
+			shardInstance.setLatestSequenceNumber(latestSequenceNumber)
+
+			var consumerLag uint64
+			for _, shardStatistics := range vs.GetShardStatistics() {
+				consumerLag += shardStatistics.Lag
+			}
+
+			atomic.StoreUint64(&vs.Statistics.ConsumerLag, consumerLag)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (vs *v3iostream) getShardLatestSequenceNumber(shardID int) (uint64, error) {
+	response, err := vs.v3ioContainer.GetItemSync(&v3io.GetItemInput{
+		Path:           fmt.Sprintf("%s/%d", strings.TrimSuffix(vs.configuration.StreamPath, "/"), shardID),
+		AttributeNames: []string{lastSequenceNumberAttributeName},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to get shard")
+	}
+
+	defer response.Release()
+
+	return response.Output.(*v3io.GetItemOutput).Item.GetFieldUint64(lastSequenceNumberAttributeName)
+}
+
+// resolveSeekToSequenceNumber returns the sequence number of the first record of the shard to handle. with seekTo
+// "time", it's that of the first record that arrived at the shard at or after the time, or the one following the
+// latest record if none did
+func (vs *v3iostream) resolveSeekToSequenceNumber(shardID int) (uint64, error) {
+	if vs.configuration.SeekTo == "sequence" {
+		return vs.configuration.SeekToSequenceNumber, nil
+	}
+
+	if vs.configuration.SeekTo != "time" {
+		return 0, nil
+	}
+
+	shardPath := fmt.Sprintf("%s/%d", strings.TrimSuffix(vs.configuration.StreamPath, "/"), shardID)
+
+	seekShardResponse, err := vs.v3ioContainer.SeekShardSync(&v3io.SeekShardInput{
+		Path:      shardPath,
+		Type:      v3io.SeekShardInputTypeTime,
+		Timestamp: int(vs.configuration.seekToTime.Unix()),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to seek shard")
+	}
+
+	location := seekShardResponse.Output.(*v3io.SeekShardOutput).Location
+	seekShardResponse.Release()
+
+	getRecordsResponse, err := vs.v3ioContainer.GetRecordsSync(&v3io.GetRecordsInput{
+		Path:     shardPath,
+		Location: location,
+		Limit:    1,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to get records")
+	}
+
+	defer getRecordsResponse.Release()
+
+	if records := getRecordsResponse.Output.(*v3io.GetRecordsOutput).Records; len(records) > 0 {
+		return records[0].SequenceNumber, nil
+	}
+
+	latestSequenceNumber, err := vs.getShardLatestSequenceNumber(shardID)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to get the shard's latest sequence number")
+	}
+
+	return latestSequenceNumber + 1, nil
+}
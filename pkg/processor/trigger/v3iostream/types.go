@@ -49,7 +49,19 @@ type Configuration struct {
 	SequenceNumberShardWaitInterval string
 	RecordBatchSizeChan             int
 
-	seekTo v3io.SeekShardInputType
+	// with seekTo "sequence", the sequence number of the first record to handle
+	SeekToSequenceNumber uint64
+
+	// with seekTo "time", the arrival time (RFC3339) of the first record to handle
+	SeekToTime string
+
+	// the number of handled records whose sequence numbers are held back from being marked, for handlers
+	// which acknowledge records asynchronously (e.g. once a downstream write they buffer is flushed). a
+	// replica taking over the shard handles these records again
+	AckWindowSize int
+
+	seekTo     v3io.SeekShardInputType
+	seekToTime time.Time
 
 	// backwards compatibility
 	PollingIntervalMs int
@@ -76,13 +88,12 @@ func NewConfiguration(ID string,
 		newConfiguration.ReadBatchSize = 64
 	}
 
-	switch newConfiguration.SeekTo {
-	case "", "latest":
-		newConfiguration.seekTo = v3io.SeekShardInputTypeLatest
-	case "earliest":
-		newConfiguration.seekTo = v3io.SeekShardInputTypeEarliest
-	default:
-		return nil, errors.Errorf("Invalid value for seekTo: %s", newConfiguration.SeekTo)
+	if err := newConfiguration.parseSeekTo(); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse seekTo")
+	}
+
+	if newConfiguration.AckWindowSize < 0 {
+		return nil, errors.Errorf("Invalid value for ackWindowSize: %d", newConfiguration.AckWindowSize)
 	}
 
 	if newConfiguration.SeekTo == "" {
@@ -114,6 +125,48 @@ func NewConfiguration(ID string,
 	return &newConfiguration, nil
 }
 
+// parseSeekTo resolves where shards with no committed sequence number are read from. the consumer group can
+// only seek to either end of a shard, so seeking to a sequence number or time reads the shard from its earliest
+// record and skips the records preceding the requested one
+func (c *Configuration) parseSeekTo() error {
+	switch c.SeekTo {
+	case "", "latest":
+		c.seekTo = v3io.SeekShardInputTypeLatest
+	case "earliest":
+		c.seekTo = v3io.SeekShardInputTypeEarliest
+	case "sequence":
+		if c.SeekToSequenceNumber == 0 {
+			return errors.New("seekTo sequence requires seekToSequenceNumber")
+		}
+
+		c.seekTo = v3io.SeekShardInputTypeEarliest
+	case "time":
+		seekToTime, err := time.Parse(time.RFC3339, c.SeekToTime)
+		if err != nil {
+			return errors.Wrapf(err, "seekTo time requires seekToTime in RFC3339 format (got \"%s\")", c.SeekToTime)
+		}
+
+		c.seekTo = v3io.SeekShardInputTypeEarliest
+		c.seekToTime = seekToTime
+	default:
+		return errors.Errorf("Invalid value for seekTo: %s", c.SeekTo)
+	}
+
+	return nil
+}
+
+// precedesSeekTo returns whether a record precedes the one seekTo points at, and as such shouldn't be handled.
+// with seekTo "time", records don't carry their arrival time once read through the consumer group, so the
+// sequence number of the first record to handle is resolved per shard when it's claimed
+func (c *Configuration) precedesSeekTo(record *v3io.StreamRecord, seekToSequenceNumber uint64) bool {
+	switch c.SeekTo {
+	case "sequence", "time":
+		return record.SequenceNumber < seekToSequenceNumber
+	default:
+		return false
+	}
+}
+
 // Parses: https://some.address.com:8080/mycontainername/some/stream/path@consumergroup
 // into url, container name, stream path, consumer group
 func (c *Configuration) parseURLForBackwardsCompatibility() error {
@@ -0,0 +1,191 @@
+/*
+Copyright 2018 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3iostream
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/v3io/v3io-go/pkg/dataplane"
+)
+
+type configurationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *configurationTestSuite) TestSeekTo() {
+	for _, testCase := range []struct {
+		name                    string
+		attributes              map[string]interface{}
+		seekToSequenceNumber    uint64
+		expectedSeekTo          v3io.SeekShardInputType
+		expectedPrecedingRecord *v3io.StreamRecord
+		expectedHandledRecord   *v3io.StreamRecord
+		expectedError           bool
+	}{
+		{
+			name:                  "default",
+			attributes:            map[string]interface{}{},
+			expectedSeekTo:        v3io.SeekShardInputTypeLatest,
+			expectedHandledRecord: &v3io.StreamRecord{SequenceNumber: 1},
+		},
+		{
+			name:                  "earliest",
+			attributes:            map[string]interface{}{"seekTo": "earliest"},
+			expectedSeekTo:        v3io.SeekShardInputTypeEarliest,
+			expectedHandledRecord: &v3io.StreamRecord{SequenceNumber: 1},
+		},
+		{
+			name:                    "sequence",
+			attributes:              map[string]interface{}{"seekTo": "sequence", "seekToSequenceNumber": 100},
+			seekToSequenceNumber:    100,
+			expectedSeekTo:          v3io.SeekShardInputTypeEarliest,
+			expectedPrecedingRecord: &v3io.StreamRecord{SequenceNumber: 99},
+			expectedHandledRecord:   &v3io.StreamRecord{SequenceNumber: 100},
+		},
+		{
+			name:                    "time",
+			attributes:              map[string]interface{}{"seekTo": "time", "seekToTime": "2020-10-15T12:00:00Z"},
+			expectedSeekTo:          v3io.SeekShardInputTypeEarliest,
+			seekToSequenceNumber:    200,
+			expectedPrecedingRecord: &v3io.StreamRecord{SequenceNumber: 199},
+			expectedHandledRecord:   &v3io.StreamRecord{SequenceNumber: 200},
+		},
+		{
+			name:          "sequence without sequence number",
+			attributes:    map[string]interface{}{"seekTo": "sequence"},
+			expectedError: true,
+		},
+		{
+			name:          "time not in RFC3339",
+			attributes:    map[string]interface{}{"seekTo": "time", "seekToTime": "yesterday"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid",
+			attributes:    map[string]interface{}{"seekTo": "middle"},
+			expectedError: true,
+		},
+		{
+			name:          "negative ack window size",
+			attributes:    map[string]interface{}{"ackWindowSize": -1},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := suite.newConfiguration(testCase.attributes)
+			if testCase.expectedError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedSeekTo, configuration.seekTo)
+
+			if testCase.expectedPrecedingRecord != nil {
+				suite.Require().True(configuration.precedesSeekTo(testCase.expectedPrecedingRecord,
+					testCase.seekToSequenceNumber))
+			}
+
+			suite.Require().False(configuration.precedesSeekTo(testCase.expectedHandledRecord,
+				testCase.seekToSequenceNumber))
+		})
+	}
+}
+
+func (suite *configurationTestSuite) newConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	attributes["containerName"] = "bigdata"
+	attributes["streamPath"] = "/my-stream"
+	attributes["consumerGroup"] = "my-group"
+
+	return NewConfiguration("test",
+		&functionconfig.Trigger{
+			URL:        "http://v3io-webapi:8081",
+			Attributes: attributes,
+		},
+		&runtime.Configuration{
+			Configuration: &processor.Configuration{},
+		})
+}
+
+type shardTestSuite struct {
+	suite.Suite
+}
+
+func (suite *shardTestSuite) TestAckWindow() {
+	shardInstance := newShard(3, 2)
+
+	// the first records are held back by the window
+	suite.Require().Nil(shardInstance.handled(suite.createRecords(1)))
+	suite.Require().Nil(shardInstance.handled(suite.createRecords(2)))
+
+	// once the window is full, the record preceding it is marked
+	recordToMark := shardInstance.handled(suite.createRecords(3))
+	suite.Require().NotNil(recordToMark)
+	suite.Require().Equal(3, *recordToMark.ShardID)
+	suite.Require().Equal(uint64(1), recordToMark.SequenceNumber)
+
+	// a batch marks its records that are out of the window
+	recordToMark = shardInstance.handled(suite.createRecords(4, 5, 6))
+	suite.Require().NotNil(recordToMark)
+	suite.Require().Equal(uint64(4), recordToMark.SequenceNumber)
+
+	shardInstance.setLatestSequenceNumber(10)
+
+	suite.Require().Equal(trigger.ShardStatistics{
+		ShardID:                 3,
+		Lag:                     4,
+		CommittedSequenceNumber: 4,
+	}, shardInstance.getStatistics())
+}
+
+func (suite *shardTestSuite) TestNoAckWindow() {
+	shardInstance := newShard(0, 0)
+
+	// the lag isn't known until a record is handled
+	shardInstance.setLatestSequenceNumber(10)
+	suite.Require().Equal(uint64(0), shardInstance.getStatistics().Lag)
+
+	recordToMark := shardInstance.handled(suite.createRecords(7))
+	suite.Require().NotNil(recordToMark)
+	suite.Require().Equal(uint64(7), recordToMark.SequenceNumber)
+
+	suite.Require().Equal(trigger.ShardStatistics{
+		ShardID:                 0,
+		Lag:                     3,
+		CommittedSequenceNumber: 7,
+	}, shardInstance.getStatistics())
+}
+
+func (suite *shardTestSuite) createRecords(sequenceNumbers ...uint64) []*v3io.StreamRecord {
+	var records []*v3io.StreamRecord
+	for _, sequenceNumber := range sequenceNumbers {
+		records = append(records, &v3io.StreamRecord{SequenceNumber: sequenceNumber})
+	}
+
+	return records
+}
+
+func TestV3ioStreamTestSuite(t *testing.T) {
+	suite.Run(t, new(configurationTestSuite))
+	suite.Run(t, new(shardTestSuite))
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event is a single frame received on a connection
+type Event struct {
+	nuclio.AbstractEvent
+	body        []byte
+	contentType string
+	timestamp   time.Time
+
+	// the request which opened the connection
+	request *http.Request
+}
+
+// GetContentType returns text/plain for text frames and application/octet-stream for binary frames
+func (e *Event) GetContentType() string {
+	return e.contentType
+}
+
+// GetBody returns the payload of the frame
+func (e *Event) GetBody() []byte {
+	return e.body
+}
+
+// GetHeader returns the header of the request which opened the connection by name as an interface{}
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaderString(key)
+}
+
+// GetHeaderByteSlice returns the header of the request which opened the connection by name as a byte slice
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeaderString returns the header of the request which opened the connection by name as a string
+func (e *Event) GetHeaderString(key string) string {
+	return e.request.Header.Get(key)
+}
+
+// GetHeaders returns the headers of the request which opened the connection
+func (e *Event) GetHeaders() map[string]interface{} {
+	headers := map[string]interface{}{}
+	for headerKey := range e.request.Header {
+		headers[headerKey] = e.request.Header.Get(headerKey)
+	}
+
+	return headers
+}
+
+// GetMethod returns the method of the request which opened the connection
+func (e *Event) GetMethod() string {
+	return e.request.Method
+}
+
+// GetPath returns the path the connection was opened on
+func (e *Event) GetPath() string {
+	return e.request.URL.Path
+}
+
+// GetFieldString returns the query argument of the request which opened the connection by name as a string
+func (e *Event) GetFieldString(key string) string {
+	return e.request.URL.Query().Get(key)
+}
+
+// GetFieldByteSlice returns the query argument of the request which opened the connection by name as a byte slice
+func (e *Event) GetFieldByteSlice(key string) []byte {
+	return []byte(e.GetFieldString(key))
+}
+
+// GetFields returns the query arguments of the request which opened the connection
+func (e *Event) GetFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	for fieldKey := range e.request.URL.Query() {
+		fields[fieldKey] = e.GetFieldString(fieldKey)
+	}
+
+	return fields
+}
+
+// GetTimestamp returns when the frame was received
+func (e *Event) GetTimestamp() time.Time {
+	return e.timestamp
+}
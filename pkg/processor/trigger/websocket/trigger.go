@@ -0,0 +1,281 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"io"
+	"net"
+	net_http "net/http"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	net_websocket "golang.org/x/net/websocket"
+)
+
+// frame is a received or sent message, keeping whether it's text or binary
+type frame struct {
+	payloadType byte
+	data        []byte
+}
+
+var frameCodec = net_websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		sentFrame := v.(*frame)
+		return sentFrame.data, sentFrame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		receivedFrame := v.(*frame)
+		receivedFrame.data = data
+		receivedFrame.payloadType = payloadType
+		return nil
+	},
+}
+
+type websocket struct {
+	trigger.AbstractTrigger
+	configuration *Configuration
+	server        *net_http.Server
+
+	// open connections, which the server doesn't close when it's stopped
+	connectionsLock sync.Mutex
+	connections     map[*net_websocket.Conn]struct{}
+}
+
+func newTrigger(logger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration) (trigger.Trigger, error) {
+
+	// connections are handled concurrently, so they must share the workers
+	if !workerAllocator.Shareable() {
+		return nil, errors.New("Websocket trigger requires a shareable worker allocator")
+	}
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(logger,
+		workerAllocator,
+		&configuration.Configuration,
+		"sync",
+		"websocket",
+		configuration.Name)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	return &websocket{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+		connections:     map[*net_websocket.Conn]struct{}{},
+	}, nil
+}
+
+func (w *websocket) Start(checkpoint functionconfig.Checkpoint) error {
+	w.Logger.InfoWith("Starting",
+		"listenAddress", w.configuration.URL,
+		"path", w.configuration.Path,
+		"subprotocol", w.configuration.Subprotocol,
+		"maxMessageSize", w.configuration.MaxMessageSize,
+		"maxConcurrentEventsPerConnection", w.configuration.MaxConcurrentEventsPerConnection)
+
+	listener, err := net.Listen("tcp", w.configuration.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on %s", w.configuration.URL)
+	}
+
+	serveMux := net_http.NewServeMux()
+	serveMux.Handle(w.configuration.Path, net_websocket.Server{
+		Handshake: w.handshake,
+		Handler:   w.handleConnection,
+	})
+
+	w.server = &net_http.Server{Handler: serveMux}
+
+	go w.server.Serve(listener) // nolint: errcheck
+
+	return nil
+}
+
+func (w *websocket) Stop(force bool) (functionconfig.Checkpoint, error) {
+	w.Logger.Debug("Shutting down")
+
+	if w.server != nil {
+		if err := w.server.Close(); err != nil {
+			return nil, errors.Wrap(err, "Failed to stop server")
+		}
+	}
+
+	w.connectionsLock.Lock()
+	defer w.connectionsLock.Unlock()
+
+	for connection := range w.connections {
+		connection.Close() // nolint: errcheck
+	}
+
+	return nil, nil
+}
+
+func (w *websocket) GetConfig() map[string]interface{} {
+	return common.StructureToMap(w.configuration)
+}
+
+// handshake accepts connections which request the configured subprotocol, if there is one. any origin is allowed
+func (w *websocket) handshake(config *net_websocket.Config, request *net_http.Request) error {
+	if w.configuration.Subprotocol == "" {
+		config.Protocol = nil
+		return nil
+	}
+
+	for _, protocol := range config.Protocol {
+		if protocol == w.configuration.Subprotocol {
+			config.Protocol = []string{protocol}
+			return nil
+		}
+	}
+
+	return errors.Errorf("Subprotocol %s wasn't requested", w.configuration.Subprotocol)
+}
+
+// handleConnection submits each frame received on the connection as an event, until the connection is closed
+func (w *websocket) handleConnection(connection *net_websocket.Conn) {
+	connection.MaxPayloadBytes = w.configuration.MaxMessageSize
+
+	w.connectionsLock.Lock()
+	w.connections[connection] = struct{}{}
+	w.connectionsLock.Unlock()
+
+	var eventsWaitGroup sync.WaitGroup
+	var writeLock sync.Mutex
+
+	// limits the frames of the connection which are handled at the same time
+	eventSlots := make(chan struct{}, w.configuration.MaxConcurrentEventsPerConnection)
+
+	defer func() {
+		eventsWaitGroup.Wait()
+
+		w.connectionsLock.Lock()
+		delete(w.connections, connection)
+		w.connectionsLock.Unlock()
+
+		connection.Close() // nolint: errcheck
+	}()
+
+	for {
+		receivedFrame := frame{}
+		if err := frameCodec.Receive(connection, &receivedFrame); err != nil {
+
+			// the frame was discarded, but the connection can still be used
+			if err == net_websocket.ErrFrameTooLarge {
+				w.UpdateStatistics(false)
+				w.writeFrame(connection, &writeLock, &frame{
+					payloadType: net_websocket.TextFrame,
+					data:        []byte("Message exceeds the max message size"),
+				})
+
+				continue
+			}
+
+			if err != io.EOF {
+				w.Logger.DebugWith("Connection closed", "err", err.Error())
+			}
+
+			return
+		}
+
+		eventSlots <- struct{}{}
+		eventsWaitGroup.Add(1)
+
+		go func() {
+			defer func() {
+				<-eventSlots
+				eventsWaitGroup.Done()
+			}()
+
+			if responseFrame := w.handleFrame(connection.Request(), &receivedFrame); responseFrame != nil {
+				w.writeFrame(connection, &writeLock, responseFrame)
+			}
+		}()
+	}
+}
+
+// handleFrame submits the frame as an event, returning the frame to respond with (of the same type as the
+// received frame) or nil if there's nothing to respond with
+func (w *websocket) handleFrame(request *net_http.Request, receivedFrame *frame) *frame {
+	event := &Event{
+		body:        receivedFrame.data,
+		contentType: "text/plain",
+		timestamp:   time.Now(),
+		request:     request,
+	}
+
+	if receivedFrame.payloadType == net_websocket.BinaryFrame {
+		event.contentType = "application/octet-stream"
+	}
+
+	response, submitError, processError := w.AllocateWorkerAndSubmitEvent(event,
+		nil,
+		time.Duration(*w.configuration.WorkerAvailabilityTimeoutMilliseconds)*time.Millisecond)
+
+	responseFrame := &frame{payloadType: receivedFrame.payloadType}
+
+	switch {
+	case submitError != nil:
+		w.Logger.WarnWith("Failed to submit event", "err", submitError.Error())
+		responseFrame.data = []byte(submitError.Error())
+
+	case processError != nil:
+		responseFrame.data = []byte(processError.Error())
+
+	default:
+		switch typedResponse := response.(type) {
+		case nil:
+			return nil
+		case nuclio.Response:
+			responseFrame.data = typedResponse.Body
+		case *nuclio.Response:
+			responseFrame.data = typedResponse.Body
+		case []byte:
+			responseFrame.data = typedResponse
+		case string:
+			responseFrame.data = []byte(typedResponse)
+		default:
+			w.Logger.WarnWith("Unsupported response type", "type", typedResponse)
+			return nil
+		}
+	}
+
+	// errors are sent as text
+	if submitError != nil || processError != nil {
+		responseFrame.payloadType = net_websocket.TextFrame
+	}
+
+	return responseFrame
+}
+
+func (w *websocket) writeFrame(connection *net_websocket.Conn, writeLock *sync.Mutex, sentFrame *frame) {
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	if err := frameCodec.Send(connection, sentFrame); err != nil {
+		w.Logger.DebugWith("Failed to write frame", "err", err.Error())
+	}
+}
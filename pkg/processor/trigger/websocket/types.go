@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type Configuration struct {
+	trigger.Configuration
+
+	// the path connections are accepted on
+	Path string
+
+	// when set, clients must request this subprotocol (Sec-WebSocket-Protocol) to connect
+	Subprotocol string
+
+	// frames larger than this are rejected, closing the connection
+	MaxMessageSize int
+
+	// the number of frames of a single connection which are handled at the same time. above one,
+	// responses may be written in a different order than the frames were received
+	MaxConcurrentEventsPerConnection int
+}
+
+const (
+	DefaultURL                              = ":8081"
+	DefaultPath                             = "/"
+	DefaultMaxMessageSize                   = 1024 * 1024
+	DefaultMaxConcurrentEventsPerConnection = 1
+)
+
+func NewConfiguration(ID string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *trigger.NewConfiguration(ID, triggerConfiguration, runtimeConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.MaxMessageSize < 0 {
+		return nil, errors.Errorf("Max message size must not be negative, got %d", newConfiguration.MaxMessageSize)
+	}
+
+	if newConfiguration.MaxConcurrentEventsPerConnection < 0 {
+		return nil, errors.Errorf("Max concurrent events per connection must not be negative, got %d",
+			newConfiguration.MaxConcurrentEventsPerConnection)
+	}
+
+	// the HTTP trigger listens on 8080
+	if newConfiguration.URL == "" {
+		newConfiguration.URL = DefaultURL
+	}
+
+	if newConfiguration.Path == "" {
+		newConfiguration.Path = DefaultPath
+	}
+
+	if newConfiguration.MaxMessageSize == 0 {
+		newConfiguration.MaxMessageSize = DefaultMaxMessageSize
+	}
+
+	if newConfiguration.MaxConcurrentEventsPerConnection == 0 {
+		newConfiguration.MaxConcurrentEventsPerConnection = DefaultMaxConcurrentEventsPerConnection
+	}
+
+	return &newConfiguration, nil
+}
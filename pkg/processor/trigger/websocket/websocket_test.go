@@ -0,0 +1,217 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/status"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	net_websocket "golang.org/x/net/websocket"
+)
+
+// echoRuntime responds with the upper cased body, fails on "fail" and doesn't respond to "silent"
+type echoRuntime struct {
+	runtime.Runtime
+}
+
+func (er *echoRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	switch string(event.GetBody()) {
+	case "fail":
+		return nil, errors.Errorf("Failed on %s", event.GetFieldString("name"))
+	case "silent":
+		return nil, nil
+	}
+
+	return nuclio.Response{
+		Body: []byte(strings.ToUpper(string(event.GetBody()))),
+	}, nil
+}
+
+func (er *echoRuntime) GetStatus() status.Status {
+	return status.Ready
+}
+
+type websocketTestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	trigger *websocket
+	url     string
+}
+
+func (suite *websocketTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *websocketTestSuite) TearDownTest() {
+	if suite.trigger != nil {
+		_, err := suite.trigger.Stop(false)
+		suite.Require().NoError(err)
+
+		suite.trigger = nil
+	}
+}
+
+func (suite *websocketTestSuite) TestConfigurationDefaults() {
+	configuration := suite.createConfiguration(nil)
+
+	suite.Require().Equal(DefaultURL, configuration.URL)
+	suite.Require().Equal(DefaultPath, configuration.Path)
+	suite.Require().Equal(DefaultMaxMessageSize, configuration.MaxMessageSize)
+	suite.Require().Equal(DefaultMaxConcurrentEventsPerConnection, configuration.MaxConcurrentEventsPerConnection)
+
+	_, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "websocket",
+		Attributes: map[string]interface{}{"maxMessageSize": -1},
+	}, suite.createRuntimeConfiguration())
+	suite.Require().Error(err)
+}
+
+func (suite *websocketTestSuite) TestFrames() {
+	suite.startTrigger(map[string]interface{}{
+		"path":           "/ws",
+		"maxMessageSize": 16,
+	})
+
+	connection := suite.dial("/ws?name=test", "")
+	defer connection.Close() // nolint: errcheck
+
+	// responses are of the type of the received frame
+	suite.Require().Equal(&frame{payloadType: net_websocket.TextFrame, data: []byte("HELLO")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.TextFrame, data: []byte("hello")}))
+
+	suite.Require().Equal(&frame{payloadType: net_websocket.BinaryFrame, data: []byte("BINARY")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.BinaryFrame, data: []byte("binary")}))
+
+	// nothing is written for no response, so the next response is of the next frame
+	suite.sendFrame(connection, &frame{payloadType: net_websocket.TextFrame, data: []byte("silent")})
+
+	// errors are written as text, and the event has the fields of the request which opened the connection
+	suite.Require().Equal(&frame{payloadType: net_websocket.TextFrame, data: []byte("Failed on test")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.BinaryFrame, data: []byte("fail")}))
+
+	// frames above the max message size are rejected, but the connection remains usable
+	suite.Require().Equal(&frame{payloadType: net_websocket.TextFrame, data: []byte("Message exceeds the max message size")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.TextFrame, data: []byte(strings.Repeat("x", 17))}))
+
+	suite.Require().Equal(&frame{payloadType: net_websocket.TextFrame, data: []byte("AGAIN")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.TextFrame, data: []byte("again")}))
+}
+
+func (suite *websocketTestSuite) TestSubprotocol() {
+	suite.startTrigger(map[string]interface{}{
+		"subprotocol": "chat",
+	})
+
+	_, err := net_websocket.Dial(suite.url+"/", "", "http://localhost/")
+	suite.Require().Error(err)
+
+	connection := suite.dial("/", "chat")
+	defer connection.Close() // nolint: errcheck
+
+	suite.Require().Equal([]string{"chat"}, connection.Config().Protocol)
+	suite.Require().Equal(&frame{payloadType: net_websocket.TextFrame, data: []byte("HI")},
+		suite.sendAndReceive(connection, &frame{payloadType: net_websocket.TextFrame, data: []byte("hi")}))
+}
+
+func (suite *websocketTestSuite) startTrigger(attributes map[string]interface{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	address := listener.Addr().String()
+	listener.Close() // nolint: errcheck
+
+	configuration := suite.createConfiguration(attributes)
+	configuration.URL = address
+
+	var workers []*worker.Worker
+	for workerIndex := 0; workerIndex < 2; workerIndex++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIndex, &echoRuntime{})
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*websocket)
+	suite.url = "ws://" + address
+
+	err = suite.trigger.Start(nil)
+	suite.Require().NoError(err)
+}
+
+func (suite *websocketTestSuite) dial(path string, protocol string) *net_websocket.Conn {
+	connection, err := net_websocket.Dial(suite.url+path, protocol, "http://localhost/")
+	suite.Require().NoError(err)
+
+	return connection
+}
+
+func (suite *websocketTestSuite) sendFrame(connection *net_websocket.Conn, sentFrame *frame) {
+	err := frameCodec.Send(connection, sentFrame)
+	suite.Require().NoError(err)
+}
+
+func (suite *websocketTestSuite) sendAndReceive(connection *net_websocket.Conn, sentFrame *frame) *frame {
+	suite.sendFrame(connection, sentFrame)
+
+	err := connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+	suite.Require().NoError(err)
+
+	receivedFrame := frame{}
+	err = frameCodec.Receive(connection, &receivedFrame)
+	suite.Require().NoError(err)
+
+	return &receivedFrame
+}
+
+func (suite *websocketTestSuite) createConfiguration(attributes map[string]interface{}) *Configuration {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "websocket",
+		Attributes: attributes,
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	return configuration
+}
+
+func (suite *websocketTestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{},
+	}
+}
+
+func TestWebsocketSuite(t *testing.T) {
+	suite.Run(t, new(websocketTestSuite))
+}
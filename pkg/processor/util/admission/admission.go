@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+
+	// ErrQueueFull is returned when an event can't be handled nor queued
+	ErrQueueFull = errors.New("Event queue is full")
+
+	// ErrQueueTimeout is returned when an event waited in the queue for longer than the queue timeout
+	ErrQueueTimeout = errors.New("Timed out waiting in event queue")
+)
+
+// Controller limits the events handled by the function at the same time, across all of its triggers. events
+// beyond the limit wait in a bounded queue until others are done
+type Controller struct {
+	inflightSlots chan struct{}
+	queueSize     int64
+	queueTimeout  time.Duration
+	numQueued     int64
+	statistics    Statistics
+}
+
+// Statistics are counters of admission results
+type Statistics struct {
+	AdmittedImmediatelyTotal   uint64
+	AdmittedAfterQueueingTotal uint64
+	RejectedQueueFullTotal     uint64
+	RejectedQueueTimeoutTotal  uint64
+}
+
+// NewController creates a controller admitting up to maxInflightEvents events at the same time, queueing up
+// to queueSize more for up to queueTimeout
+func NewController(maxInflightEvents int, queueSize int, queueTimeout time.Duration) *Controller {
+	return &Controller{
+		inflightSlots: make(chan struct{}, maxInflightEvents),
+		queueSize:     int64(queueSize),
+		queueTimeout:  queueTimeout,
+	}
+}
+
+// Admit blocks until the event may be handled, returning ErrQueueFull if the queue is full or ErrQueueTimeout
+// if it waited in the queue for too long. an admitted event must be followed by a call to Leave once handled
+func (c *Controller) Admit() error {
+	select {
+	case c.inflightSlots <- struct{}{}:
+		atomic.AddUint64(&c.statistics.AdmittedImmediatelyTotal, 1)
+		return nil
+	default:
+	}
+
+	if atomic.AddInt64(&c.numQueued, 1) > c.queueSize {
+		atomic.AddInt64(&c.numQueued, -1)
+		atomic.AddUint64(&c.statistics.RejectedQueueFullTotal, 1)
+		return ErrQueueFull
+	}
+
+	defer atomic.AddInt64(&c.numQueued, -1)
+
+	queueTimer := time.NewTimer(c.queueTimeout)
+	defer queueTimer.Stop()
+
+	// blocked senders are released in the order they blocked, so the queue is first in first out
+	select {
+	case c.inflightSlots <- struct{}{}:
+		atomic.AddUint64(&c.statistics.AdmittedAfterQueueingTotal, 1)
+		return nil
+	case <-queueTimer.C:
+		atomic.AddUint64(&c.statistics.RejectedQueueTimeoutTotal, 1)
+		return ErrQueueTimeout
+	}
+}
+
+// Leave frees the slot of a handled event, admitting the next queued event
+func (c *Controller) Leave() {
+	<-c.inflightSlots
+}
+
+// GetNumInflight returns the number of events being handled
+func (c *Controller) GetNumInflight() int {
+	return len(c.inflightSlots)
+}
+
+// GetNumQueued returns the number of events waiting in the queue
+func (c *Controller) GetNumQueued() int {
+	return int(atomic.LoadInt64(&c.numQueued))
+}
+
+// GetStatistics returns the admission statistics
+func (c *Controller) GetStatistics() *Statistics {
+	return &c.statistics
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AdmissionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AdmissionTestSuite) TestQueueFull() {
+	controller := NewController(1, 1, time.Hour)
+
+	suite.Require().NoError(controller.Admit())
+
+	// the second event waits in the queue, the third can't be queued
+	admitted := make(chan error)
+	go func() {
+		admitted <- controller.Admit()
+	}()
+
+	suite.Require().Eventually(func() bool {
+		return controller.GetNumQueued() == 1
+	}, time.Second, time.Millisecond)
+
+	suite.Require().Equal(ErrQueueFull, controller.Admit())
+
+	// once the first leaves, the queued one is admitted
+	controller.Leave()
+
+	select {
+	case err := <-admitted:
+		suite.Require().NoError(err)
+	case <-time.After(time.Second):
+		suite.Fail("Queued event wasn't admitted")
+	}
+
+	suite.Require().Equal(1, controller.GetNumInflight())
+	suite.Require().Equal(0, controller.GetNumQueued())
+
+	statistics := controller.GetStatistics()
+	suite.Require().Equal(uint64(1), statistics.AdmittedImmediatelyTotal)
+	suite.Require().Equal(uint64(1), statistics.AdmittedAfterQueueingTotal)
+	suite.Require().Equal(uint64(1), statistics.RejectedQueueFullTotal)
+}
+
+func (suite *AdmissionTestSuite) TestQueueTimeout() {
+	controller := NewController(1, 1, 20*time.Millisecond)
+
+	suite.Require().NoError(controller.Admit())
+	suite.Require().Equal(ErrQueueTimeout, controller.Admit())
+	suite.Require().Equal(0, controller.GetNumQueued())
+	suite.Require().Equal(uint64(1), controller.GetStatistics().RejectedQueueTimeoutTotal)
+}
+
+func (suite *AdmissionTestSuite) TestNoQueue() {
+	controller := NewController(2, 0, time.Hour)
+
+	suite.Require().NoError(controller.Admit())
+	suite.Require().NoError(controller.Admit())
+	suite.Require().Equal(ErrQueueFull, controller.Admit())
+
+	controller.Leave()
+	suite.Require().NoError(controller.Admit())
+}
+
+func TestAdmissionTestSuite(t *testing.T) {
+	suite.Run(t, new(AdmissionTestSuite))
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned when an event is rejected for exceeding the rate limit
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// buckets of callers that are full (and as such, the same as new ones) are removed this often
+const bucketCleanupInterval = time.Minute
+
+// Limiter limits the rate of events with a token bucket per key (e.g. per caller), each refilled with rps tokens
+// per second up to burst. events of the empty key share a single bucket
+type Limiter struct {
+	rps           float64
+	burst         float64
+	lock          sync.Mutex
+	buckets       map[string]*bucket
+	lastCleanupAt time.Time
+	statistics    Statistics
+	now           func() time.Time
+}
+
+// Statistics are counters of the events the limiter held back
+type Statistics struct {
+
+	// events rejected, or which waited for tokens
+	LimitedTotal uint64
+
+	// the time events waited for tokens
+	WaitDurationMilliSecondsSum uint64
+}
+
+// DiffFrom returns the statistics accumulated since prev
+func (s *Statistics) DiffFrom(prev *Statistics) Statistics {
+	return Statistics{
+		LimitedTotal: atomic.LoadUint64(&s.LimitedTotal) - atomic.LoadUint64(&prev.LimitedTotal),
+		WaitDurationMilliSecondsSum: atomic.LoadUint64(&s.WaitDurationMilliSecondsSum) -
+			atomic.LoadUint64(&prev.WaitDurationMilliSecondsSum),
+	}
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// NewLimiter creates a limiter allowing rps events per second per key, and bursts of up to burst events
+func NewLimiter(rps float64, burst int) *Limiter {
+	return &Limiter{
+		rps:     rps,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key, if it has one. otherwise, it returns false along with the time
+// until the bucket has one
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucketInstance := l.getBucket(key)
+	if bucketInstance.tokens >= 1 {
+		bucketInstance.tokens--
+		return true, 0
+	}
+
+	atomic.AddUint64(&l.statistics.LimitedTotal, 1)
+
+	return false, l.getRefillDuration(1 - bucketInstance.tokens)
+}
+
+// Wait takes numTokens tokens from the bucket of the key, blocking until they're available. the tokens are taken
+// even if the bucket doesn't have them yet, so callers waiting on the same bucket are let through in the order
+// they called
+func (l *Limiter) Wait(key string, numTokens int) {
+	waitDuration := l.reserve(key, numTokens)
+	if waitDuration <= 0 {
+		return
+	}
+
+	atomic.AddUint64(&l.statistics.LimitedTotal, 1)
+	atomic.AddUint64(&l.statistics.WaitDurationMilliSecondsSum, uint64(waitDuration/time.Millisecond))
+
+	time.Sleep(waitDuration)
+}
+
+// GetStatistics returns the limiter statistics
+func (l *Limiter) GetStatistics() *Statistics {
+	return &l.statistics
+}
+
+// reserve takes numTokens tokens from the bucket of the key, returning how long until the bucket has them
+func (l *Limiter) reserve(key string, numTokens int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucketInstance := l.getBucket(key)
+	bucketInstance.tokens -= float64(numTokens)
+
+	if bucketInstance.tokens >= 0 {
+		return 0
+	}
+
+	return l.getRefillDuration(-bucketInstance.tokens)
+}
+
+// getBucket returns the bucket of the key, refilled with the tokens added since it was last used. must be called
+// while holding the lock
+func (l *Limiter) getBucket(key string) *bucket {
+	now := l.now()
+
+	l.cleanupBuckets(now)
+
+	bucketInstance, found := l.buckets[key]
+	if !found {
+		bucketInstance = &bucket{
+			tokens:    l.burst,
+			updatedAt: now,
+		}
+
+		l.buckets[key] = bucketInstance
+
+		return bucketInstance
+	}
+
+	bucketInstance.tokens = l.getRefilledTokens(bucketInstance, now)
+	bucketInstance.updatedAt = now
+
+	return bucketInstance
+}
+
+// cleanupBuckets removes the buckets which refilled since they were last used, so that the buckets of callers
+// that stopped calling don't accumulate
+func (l *Limiter) cleanupBuckets(now time.Time) {
+	if now.Sub(l.lastCleanupAt) < bucketCleanupInterval {
+		return
+	}
+
+	for key, bucketInstance := range l.buckets {
+		if l.getRefilledTokens(bucketInstance, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastCleanupAt = now
+}
+
+func (l *Limiter) getRefilledTokens(bucketInstance *bucket, now time.Time) float64 {
+	return math.Min(l.burst, bucketInstance.tokens+now.Sub(bucketInstance.updatedAt).Seconds()*l.rps)
+}
+
+func (l *Limiter) getRefillDuration(numTokens float64) time.Duration {
+	return time.Duration(numTokens / l.rps * float64(time.Second))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	now     time.Time
+	limiter *Limiter
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	suite.now = time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)
+	suite.limiter = NewLimiter(2, 3)
+	suite.limiter.now = func() time.Time {
+		return suite.now
+	}
+}
+
+func (suite *RateLimitTestSuite) TestAllow() {
+
+	// the bucket starts full, allowing a burst
+	for eventIndex := 0; eventIndex < 3; eventIndex++ {
+		allowed, _ := suite.limiter.Allow("")
+		suite.Require().True(allowed)
+	}
+
+	allowed, retryAfter := suite.limiter.Allow("")
+	suite.Require().False(allowed)
+	suite.Require().Equal(500*time.Millisecond, retryAfter)
+
+	// refilled with 2 tokens per second
+	suite.now = suite.now.Add(time.Second)
+
+	for eventIndex := 0; eventIndex < 2; eventIndex++ {
+		allowed, _ := suite.limiter.Allow("")
+		suite.Require().True(allowed)
+	}
+
+	allowed, _ = suite.limiter.Allow("")
+	suite.Require().False(allowed)
+
+	// never refilled beyond the burst
+	suite.now = suite.now.Add(time.Hour)
+
+	for eventIndex := 0; eventIndex < 3; eventIndex++ {
+		allowed, _ := suite.limiter.Allow("")
+		suite.Require().True(allowed)
+	}
+
+	allowed, _ = suite.limiter.Allow("")
+	suite.Require().False(allowed)
+
+	suite.Require().Equal(uint64(3), suite.limiter.GetStatistics().LimitedTotal)
+}
+
+func (suite *RateLimitTestSuite) TestBucketPerKey() {
+	for eventIndex := 0; eventIndex < 3; eventIndex++ {
+		allowed, _ := suite.limiter.Allow("10.0.0.1")
+		suite.Require().True(allowed)
+	}
+
+	allowed, _ := suite.limiter.Allow("10.0.0.1")
+	suite.Require().False(allowed)
+
+	// other callers have their own buckets
+	allowed, _ = suite.limiter.Allow("10.0.0.2")
+	suite.Require().True(allowed)
+
+	// full buckets are removed once the cleanup interval passed, and those still owing tokens are kept
+	suite.now = suite.now.Add(bucketCleanupInterval)
+	suite.limiter.reserve("10.0.0.1", 500)
+
+	suite.now = suite.now.Add(bucketCleanupInterval)
+	suite.limiter.Allow("10.0.0.3")
+
+	suite.Require().Len(suite.limiter.buckets, 2)
+	suite.Require().NotContains(suite.limiter.buckets, "10.0.0.2")
+}
+
+func (suite *RateLimitTestSuite) TestReserve() {
+	suite.Require().Equal(time.Duration(0), suite.limiter.reserve("", 3))
+
+	// tokens taken ahead are owed, so each caller waits for those before it
+	suite.Require().Equal(500*time.Millisecond, suite.limiter.reserve("", 1))
+	suite.Require().Equal(time.Second, suite.limiter.reserve("", 1))
+
+	// a batch takes a token per event
+	suite.Require().Equal(3*time.Second, suite.limiter.reserve("", 4))
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
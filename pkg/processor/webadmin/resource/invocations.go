@@ -0,0 +1,53 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"net/http"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/webadmin"
+	"github.com/nuclio/nuclio/pkg/restful"
+)
+
+// invocationsResource exposes the summaries of the last invocations the invocation inspector retains, keyed by
+// their IDs, so that the platform can gather those of all replicas
+type invocationsResource struct {
+	*resource
+}
+
+func (ir *invocationsResource) GetAll(request *http.Request) (map[string]restful.Attributes, error) {
+	invocations := map[string]restful.Attributes{}
+
+	for _, invocation := range ir.getProcessor().GetInvocations() {
+		invocations[invocation.ID] = common.StructureToMap(invocation)
+	}
+
+	return invocations, nil
+}
+
+// register the resource
+var invocations = &invocationsResource{
+	resource: newResource("invocations", []restful.ResourceMethod{
+		restful.ResourceMethodGetList,
+	}),
+}
+
+func init() {
+	invocations.Resource = invocations
+	invocations.Register(webadmin.WebAdminResourceRegistrySingleton)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"net/http"
+
+	"github.com/nuclio/nuclio/pkg/processor/webadmin"
+	"github.com/nuclio/nuclio/pkg/restful"
+)
+
+// statisticsResource exposes the event counters of all triggers in a single request, keyed by trigger ID, so
+// that rates can be calculated by sampling it periodically
+type statisticsResource struct {
+	*resource
+}
+
+func (sr *statisticsResource) GetAll(request *http.Request) (map[string]restful.Attributes, error) {
+	statistics := map[string]restful.Attributes{}
+
+	for _, trigger := range sr.getProcessor().GetTriggers() {
+		statistics[trigger.GetID()] = getTriggerStatisticsAttributes(trigger)
+	}
+
+	return statistics, nil
+}
+
+// register the resource
+var statistics = &statisticsResource{
+	resource: newResource("statistics", []restful.ResourceMethod{
+		restful.ResourceMethodGetList,
+	}),
+}
+
+func init() {
+	statistics.Resource = statistics
+	statistics.Register(webadmin.WebAdminResourceRegistrySingleton)
+}
@@ -18,7 +18,9 @@ package resource
 
 import (
 	"net/http"
+	"sync/atomic"
 
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/webadmin"
 	"github.com/nuclio/nuclio/pkg/restful"
 
@@ -44,6 +46,11 @@ func (tr *triggersResource) GetAll(request *http.Request) (map[string]restful.At
 		triggers[id] = configuration
 	}
 
+	// paused triggers aren't running, so only their state is reported
+	for _, triggerName := range tr.getProcessor().GetPausedTriggerNames() {
+		triggers[triggerName] = restful.Attributes{"paused": true}
+	}
+
 	return triggers, nil
 }
 
@@ -64,30 +71,80 @@ func (tr *triggersResource) GetByID(request *http.Request, id string) (restful.A
 
 // returns a list of custom routes for the resource
 func (tr *triggersResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
-
-	// just for demonstration. when stats are supported, this will be wired
 	return []restful.CustomRoute{
 		{
 			Pattern:   "/{id}/stats",
 			Method:    http.MethodGet,
 			RouteFunc: tr.getStatistics,
 		},
+		{
+			Pattern:   "/{id}/pause",
+			Method:    http.MethodPost,
+			RouteFunc: tr.pauseTrigger,
+		},
+		{
+			Pattern:   "/{id}/resume",
+			Method:    http.MethodPost,
+			RouteFunc: tr.resumeTrigger,
+		},
 	}, nil
 }
 
-func (tr *triggersResource) getStatistics(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
-	resourceID := chi.URLParam(request, "id")
+func (tr *triggersResource) pauseTrigger(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	return tr.setTriggerPaused(request, true)
+}
+
+func (tr *triggersResource) resumeTrigger(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	return tr.setTriggerPaused(request, false)
+}
+
+// setTriggerPaused stops the trigger whose name is in the path from consuming events, or has it resume. the
+// paused state isn't persisted by the processor - pausing through the platform persists it
+func (tr *triggersResource) setTriggerPaused(request *http.Request,
+	paused bool) (*restful.CustomRouteFuncResponse, error) {
+	triggerName := chi.URLParam(request, "id")
+
+	// errors with a status code (e.g. the trigger wasn't found) are returned with it
+	if err := tr.getProcessor().SetTriggerPaused(triggerName, paused); err != nil {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusInternalServerError,
+		}, err
+	}
 
 	return &restful.CustomRouteFuncResponse{
-		ResourceType: "statistics",
+		ResourceType: "trigger",
 		Resources: map[string]restful.Attributes{
-			resourceID: {"stats": "example"},
+			triggerName: {"paused": paused},
 		},
 		Single:     true,
 		StatusCode: http.StatusOK,
 	}, nil
 }
 
+func (tr *triggersResource) getStatistics(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	resourceID := chi.URLParam(request, "id")
+
+	for _, trigger := range tr.getProcessor().GetTriggers() {
+		if trigger.GetID() == resourceID {
+			return &restful.CustomRouteFuncResponse{
+				ResourceType: "statistics",
+				Resources: map[string]restful.Attributes{
+					resourceID: getTriggerStatisticsAttributes(trigger),
+				},
+				Single:     true,
+				StatusCode: http.StatusOK,
+			}, nil
+		}
+	}
+
+	return &restful.CustomRouteFuncResponse{
+		ResourceType: "statistics",
+		Single:       true,
+		StatusCode:   http.StatusNotFound,
+	}, nil
+}
+
 func (tr *triggersResource) extractIDFromConfiguration(configuration map[string]interface{}) string {
 	id := configuration["ID"].(string)
 
@@ -96,6 +153,32 @@ func (tr *triggersResource) extractIDFromConfiguration(configuration map[string]
 	return id
 }
 
+func getTriggerStatisticsAttributes(triggerInstance trigger.Trigger) restful.Attributes {
+	statistics := triggerInstance.GetStatistics()
+
+	// wrappers restarted due to failed runtime liveness checks, across the trigger's workers
+	var wrapperRestartsTotal uint64
+	for _, workerInstance := range triggerInstance.GetWorkers() {
+		wrapperRestartsTotal += atomic.LoadUint64(&workerInstance.GetRuntime().GetStatistics().WrapperRestartsTotal)
+	}
+
+	statisticsAttributes := restful.Attributes{
+		"eventsHandledSuccessTotal":    atomic.LoadUint64(&statistics.EventsHandledSuccessTotal),
+		"eventsHandledFailureTotal":    atomic.LoadUint64(&statistics.EventsHandledFailureTotal),
+		"eventsDeadLetteredTotal":      atomic.LoadUint64(&statistics.EventsDeadLetteredTotal),
+		"eventsDeadLetterFailureTotal": atomic.LoadUint64(&statistics.EventsDeadLetterFailureTotal),
+		"wrapperRestartsTotal":         wrapperRestartsTotal,
+		"eventsRateLimitedTotal":       atomic.LoadUint64(&statistics.RateLimiterStatistics.LimitedTotal),
+	}
+
+	// the shards stream triggers consume, so that their assignment across replicas can be inspected
+	if shardStatisticsProvider, isShardStatisticsProvider := triggerInstance.(trigger.ShardStatisticsProvider); isShardStatisticsProvider {
+		statisticsAttributes["shards"] = shardStatisticsProvider.GetShardStatistics()
+	}
+
+	return statisticsAttributes
+}
+
 // register the resource
 var triggers = &triggersResource{
 	resource: newResource("triggers", []restful.ResourceMethod{
@@ -0,0 +1,327 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+//
+// Adaptive pool of workers
+// Holds a fixed number of workers, of which only the active ones are allocated. The number of active workers
+// is periodically adjusted to the load - workers are activated while events wait for one, as long as handler
+// latency doesn't degrade, and deactivated when they're idle or when latency degrades
+//
+
+type AdaptivePoolConfiguration struct {
+
+	// the number of active workers doesn't drop below this
+	MinWorkers int
+
+	// how often the number of active workers is adjusted. 0 for never (it's then adjusted explicitly)
+	EvaluationInterval time.Duration
+
+	// workers aren't activated (and are deactivated) once the average handler latency rises by more than this
+	// fraction above the lowest average latency observed (e.g. 1 for 100%)
+	MaxLatencyIncrease float64
+}
+
+type adaptivePool struct {
+	logger        logger.Logger
+	configuration AdaptivePoolConfiguration
+	workers       []*Worker
+	statistics    AllocatorStatistics
+
+	// active workers which aren't allocated
+	workerChan chan *Worker
+
+	// the positions of workers in workers, and when each was last allocated (unix nano)
+	workerPositions map[*Worker]int
+	allocatedAt     []int64
+
+	lock          sync.Mutex
+	activeWorkers int
+	parkedWorkers []*Worker
+
+	// the number of allocated workers which are to be parked once released
+	workersToPark int
+
+	// measured since the last evaluation
+	latencySum      int64
+	eventsHandled   int64
+	allocationWaits int64
+	peakAllocated   int64
+
+	allocated int64
+	waiting   int64
+
+	// the lowest average latency observed, which slowly follows the average latency up so that a change
+	// in the workload (e.g. a slower dependency) doesn't deactivate workers for good
+	baselineLatency time.Duration
+}
+
+func NewAdaptivePoolWorkerAllocator(parentLogger logger.Logger,
+	workers []*Worker,
+	configuration AdaptivePoolConfiguration) (Allocator, error) {
+
+	if configuration.MinWorkers < 1 {
+		configuration.MinWorkers = 1
+	}
+
+	if configuration.MinWorkers > len(workers) {
+		configuration.MinWorkers = len(workers)
+	}
+
+	newAdaptivePool := &adaptivePool{
+		logger:          parentLogger.GetChild("adaptive_pool_allocator"),
+		configuration:   configuration,
+		workers:         workers,
+		workerChan:      make(chan *Worker, len(workers)),
+		workerPositions: map[*Worker]int{},
+		allocatedAt:     make([]int64, len(workers)),
+		activeWorkers:   configuration.MinWorkers,
+	}
+
+	// start with the minimum active, parking the rest
+	for workerPosition, workerInstance := range workers {
+		newAdaptivePool.workerPositions[workerInstance] = workerPosition
+
+		if workerPosition < configuration.MinWorkers {
+			newAdaptivePool.workerChan <- workerInstance
+		} else {
+			newAdaptivePool.parkedWorkers = append(newAdaptivePool.parkedWorkers, workerInstance)
+		}
+	}
+
+	if configuration.EvaluationInterval > 0 {
+		go newAdaptivePool.evaluatePeriodically()
+	}
+
+	return newAdaptivePool, nil
+}
+
+func (ap *adaptivePool) Allocate(timeout time.Duration) (*Worker, error) {
+	atomic.AddUint64(&ap.statistics.WorkerAllocationCount, 1)
+
+	select {
+	case workerInstance := <-ap.workerChan:
+		atomic.AddUint64(&ap.statistics.WorkerAllocationSuccessImmediateTotal, 1)
+		ap.onAllocated(workerInstance)
+
+		return workerInstance, nil
+	default:
+	}
+
+	// events waiting for a worker are what activating more workers is for
+	atomic.AddInt64(&ap.allocationWaits, 1)
+
+	if timeout == 0 {
+		atomic.AddUint64(&ap.statistics.WorkerAllocationTimeoutTotal, 1)
+		return nil, ErrNoAvailableWorkers
+	}
+
+	atomic.AddInt64(&ap.waiting, 1)
+	defer atomic.AddInt64(&ap.waiting, -1)
+
+	waitStartAt := time.Now()
+
+	select {
+	case workerInstance := <-ap.workerChan:
+		atomic.AddUint64(&ap.statistics.WorkerAllocationSuccessAfterWaitTotal, 1)
+		atomic.AddUint64(&ap.statistics.WorkerAllocationWaitDurationMilliSecondsSum,
+			uint64(time.Since(waitStartAt).Nanoseconds()/1e6))
+		ap.onAllocated(workerInstance)
+
+		return workerInstance, nil
+	case <-time.After(timeout):
+		atomic.AddUint64(&ap.statistics.WorkerAllocationTimeoutTotal, 1)
+		return nil, ErrNoAvailableWorkers
+	}
+}
+
+func (ap *adaptivePool) Release(worker *Worker) {
+	if workerPosition, found := ap.workerPositions[worker]; found {
+		latency := time.Now().UnixNano() - atomic.LoadInt64(&ap.allocatedAt[workerPosition])
+
+		atomic.AddInt64(&ap.latencySum, latency)
+		atomic.AddInt64(&ap.eventsHandled, 1)
+	}
+
+	atomic.AddInt64(&ap.allocated, -1)
+
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+
+	if ap.workersToPark > 0 {
+		ap.workersToPark--
+		ap.parkedWorkers = append(ap.parkedWorkers, worker)
+		return
+	}
+
+	ap.workerChan <- worker
+}
+
+// true if the several go routines can share this allocator
+func (ap *adaptivePool) Shareable() bool {
+	return true
+}
+
+// get direct access to all workers for things like management / housekeeping
+func (ap *adaptivePool) GetWorkers() []*Worker {
+	return ap.workers
+}
+
+func (ap *adaptivePool) GetNumWorkersAvailable() int {
+	return len(ap.workerChan)
+}
+
+// GetStatistics returns worker allocator statistics
+func (ap *adaptivePool) GetStatistics() *AllocatorStatistics {
+	return &ap.statistics
+}
+
+func (ap *adaptivePool) getNumActiveWorkers() int {
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+
+	return ap.activeWorkers
+}
+
+func (ap *adaptivePool) onAllocated(workerInstance *Worker) {
+	atomic.StoreInt64(&ap.allocatedAt[ap.workerPositions[workerInstance]], time.Now().UnixNano())
+
+	allocated := atomic.AddInt64(&ap.allocated, 1)
+
+	// keep the peak since the last evaluation
+	for {
+		peakAllocated := atomic.LoadInt64(&ap.peakAllocated)
+		if allocated <= peakAllocated || atomic.CompareAndSwapInt64(&ap.peakAllocated, peakAllocated, allocated) {
+			break
+		}
+	}
+}
+
+func (ap *adaptivePool) evaluatePeriodically() {
+	// the allocator lives as long as the processor, so the ticker is never stopped
+	ticker := time.NewTicker(ap.configuration.EvaluationInterval)
+
+	for range ticker.C {
+		ap.evaluate()
+	}
+}
+
+// evaluate adjusts the number of active workers to what was measured since it was last called
+func (ap *adaptivePool) evaluate() {
+	latencySum := atomic.SwapInt64(&ap.latencySum, 0)
+	eventsHandled := atomic.SwapInt64(&ap.eventsHandled, 0)
+	allocationWaits := atomic.SwapInt64(&ap.allocationWaits, 0)
+	peakAllocated := atomic.SwapInt64(&ap.peakAllocated, atomic.LoadInt64(&ap.allocated))
+	waiting := atomic.LoadInt64(&ap.waiting)
+
+	var averageLatency time.Duration
+	latencyDegraded := false
+
+	if eventsHandled > 0 {
+		averageLatency = time.Duration(latencySum / eventsHandled)
+
+		if ap.baselineLatency == 0 || averageLatency < ap.baselineLatency {
+			ap.baselineLatency = averageLatency
+		} else {
+			latencyDegraded = float64(averageLatency) >
+				float64(ap.baselineLatency)*(1+ap.configuration.MaxLatencyIncrease)
+
+			ap.baselineLatency += (averageLatency - ap.baselineLatency) / 20
+		}
+	}
+
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+
+	activeWorkers := ap.activeWorkers
+	targetActiveWorkers := activeWorkers
+
+	switch {
+
+	// more concurrency is making events slower (e.g. a saturated dependency or CPU), back off
+	case latencyDegraded:
+		targetActiveWorkers--
+
+	// events waited for workers - activate as many as are waiting (at least one)
+	case allocationWaits > 0 || waiting > 0:
+		targetActiveWorkers += int(waiting)
+		if targetActiveWorkers == activeWorkers {
+			targetActiveWorkers++
+		}
+
+	// some of the active workers were idle throughout
+	case int(peakAllocated) < activeWorkers-1:
+		targetActiveWorkers--
+	}
+
+	if targetActiveWorkers < ap.configuration.MinWorkers {
+		targetActiveWorkers = ap.configuration.MinWorkers
+	}
+
+	if targetActiveWorkers > len(ap.workers) {
+		targetActiveWorkers = len(ap.workers)
+	}
+
+	if targetActiveWorkers == activeWorkers {
+		return
+	}
+
+	ap.logger.DebugWith("Adjusting active workers",
+		"from", activeWorkers,
+		"to", targetActiveWorkers,
+		"averageLatency", averageLatency,
+		"baselineLatency", ap.baselineLatency,
+		"allocationWaits", allocationWaits,
+		"waiting", waiting,
+		"peakAllocated", peakAllocated)
+
+	ap.setActiveWorkers(targetActiveWorkers)
+}
+
+// setActiveWorkers activates or parks workers. must be called with the lock held
+func (ap *adaptivePool) setActiveWorkers(targetActiveWorkers int) {
+	for ; ap.activeWorkers < targetActiveWorkers; ap.activeWorkers++ {
+
+		// a worker which is yet to be parked can stay active
+		if ap.workersToPark > 0 {
+			ap.workersToPark--
+			continue
+		}
+
+		ap.workerChan <- ap.parkedWorkers[0]
+		ap.parkedWorkers = ap.parkedWorkers[1:]
+	}
+
+	for ; ap.activeWorkers > targetActiveWorkers; ap.activeWorkers-- {
+
+		// park an idle worker, or the next one to be released
+		select {
+		case workerInstance := <-ap.workerChan:
+			ap.parkedWorkers = append(ap.parkedWorkers, workerInstance)
+		default:
+			ap.workersToPark++
+		}
+	}
+}
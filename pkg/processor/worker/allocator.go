@@ -21,6 +21,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
+
 	"github.com/nuclio/logger"
 )
 
@@ -188,3 +190,39 @@ func (fp *fixedPool) GetNumWorkersAvailable() int {
 func (fp *fixedPool) GetStatistics() *AllocatorStatistics {
 	return &fp.statistics
 }
+
+//
+// Admission controlled allocator
+// Admits events through the function's admission controller before allocating a worker of the wrapped allocator
+//
+
+type admissionControlled struct {
+	Allocator
+	admissionController *admission.Controller
+}
+
+func NewAdmissionControlledWorkerAllocator(allocator Allocator, admissionController *admission.Controller) Allocator {
+	return &admissionControlled{
+		Allocator:           allocator,
+		admissionController: admissionController,
+	}
+}
+
+func (ac *admissionControlled) Allocate(timeout time.Duration) (*Worker, error) {
+	if err := ac.admissionController.Admit(); err != nil {
+		return nil, err
+	}
+
+	workerInstance, err := ac.Allocator.Allocate(timeout)
+	if err != nil {
+		ac.admissionController.Leave()
+		return nil, err
+	}
+
+	return workerInstance, nil
+}
+
+func (ac *admissionControlled) Release(worker *Worker) {
+	ac.Allocator.Release(worker)
+	ac.admissionController.Leave()
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package worker
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/processor/util/admission"
+
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
@@ -93,6 +96,152 @@ func (suite *AllocatorTestSuite) TestFixedPoolAllocator() {
 	suite.Require().True(fpa.Shareable())
 }
 
+func (suite *AllocatorTestSuite) TestAdmissionControlledAllocators() {
+	firstPool, err := NewFixedPoolWorkerAllocator(suite.logger, []*Worker{{index: 0}, {index: 1}})
+	suite.Require().NoError(err)
+
+	secondPool, err := NewFixedPoolWorkerAllocator(suite.logger, []*Worker{{index: 2}})
+	suite.Require().NoError(err)
+
+	// two events may be handled at the same time across both pools, with none queued
+	admissionController := admission.NewController(2, 0, time.Second)
+	firstAllocator := NewAdmissionControlledWorkerAllocator(firstPool, admissionController)
+	secondAllocator := NewAdmissionControlledWorkerAllocator(secondPool, admissionController)
+
+	firstWorker, err := firstAllocator.Allocate(time.Hour)
+	suite.Require().NoError(err)
+
+	secondWorker, err := secondAllocator.Allocate(time.Hour)
+	suite.Require().NoError(err)
+
+	// though the first pool has an available worker, the function is at its limit
+	_, err = firstAllocator.Allocate(time.Hour)
+	suite.Require().Equal(admission.ErrQueueFull, err)
+	suite.Require().Equal(1, firstAllocator.GetNumWorkersAvailable())
+
+	firstAllocator.Release(firstWorker)
+
+	_, err = secondAllocator.Allocate(0)
+	suite.Require().Equal(ErrNoAvailableWorkers, err)
+
+	// a failed allocation doesn't take a slot
+	suite.Require().Equal(1, admissionController.GetNumInflight())
+
+	secondAllocator.Release(secondWorker)
+	suite.Require().Equal(0, admissionController.GetNumInflight())
+}
+
+func (suite *AllocatorTestSuite) TestAdaptivePoolAllocator() {
+	workers := []*Worker{{index: 0}, {index: 1}, {index: 2}, {index: 3}}
+
+	allocator, err := NewAdaptivePoolWorkerAllocator(suite.logger, workers, AdaptivePoolConfiguration{
+		MinWorkers:         1,
+		MaxLatencyIncrease: 1,
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(allocator.Shareable())
+	suite.Require().Len(allocator.GetWorkers(), 4)
+
+	ap := allocator.(*adaptivePool)
+
+	// only the minimum is active at first
+	suite.Require().Equal(1, ap.getNumActiveWorkers())
+
+	firstWorker, err := ap.Allocate(0)
+	suite.Require().NoError(err)
+
+	_, err = ap.Allocate(10 * time.Millisecond)
+	suite.Require().Equal(ErrNoAvailableWorkers, err)
+
+	ap.Release(firstWorker)
+
+	// an event waited for a worker, so another is activated
+	ap.evaluate()
+	suite.Require().Equal(2, ap.getNumActiveWorkers())
+	suite.Require().Equal(2, ap.GetNumWorkersAvailable())
+
+	// as many are activated as there are events waiting
+	var allocatedWorkers []*Worker
+	for i := 0; i < 2; i++ {
+		allocatedWorker, err := ap.Allocate(0)
+		suite.Require().NoError(err)
+
+		allocatedWorkers = append(allocatedWorkers, allocatedWorker)
+	}
+
+	waitingAllocations := make(chan *Worker, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			allocatedWorker, _ := ap.Allocate(time.Minute)
+			waitingAllocations <- allocatedWorker
+		}()
+	}
+
+	suite.Require().Eventually(func() bool {
+		return atomic.LoadInt64(&ap.waiting) == 2
+	}, 5*time.Second, time.Millisecond)
+
+	ap.evaluate()
+	suite.Require().Equal(4, ap.getNumActiveWorkers())
+
+	for i := 0; i < 2; i++ {
+		allocatedWorkers = append(allocatedWorkers, <-waitingAllocations)
+	}
+
+	// workers which are allocated when deactivated are parked once released
+	ap.lock.Lock()
+	ap.setActiveWorkers(2)
+	ap.lock.Unlock()
+
+	for _, allocatedWorker := range allocatedWorkers {
+		ap.Release(allocatedWorker)
+	}
+
+	suite.Require().Equal(2, ap.GetNumWorkersAvailable())
+
+	// all the workers were allocated since the last evaluation
+	ap.evaluate()
+	suite.Require().Equal(2, ap.getNumActiveWorkers())
+
+	// workers which stayed idle are deactivated, down to the minimum
+	ap.evaluate()
+	suite.Require().Equal(1, ap.getNumActiveWorkers())
+	ap.evaluate()
+	suite.Require().Equal(1, ap.getNumActiveWorkers())
+	suite.Require().Equal(1, ap.GetNumWorkersAvailable())
+}
+
+func (suite *AllocatorTestSuite) TestAdaptivePoolAllocatorLatencyDegradation() {
+	workers := []*Worker{{index: 0}, {index: 1}, {index: 2}, {index: 3}}
+
+	allocator, err := NewAdaptivePoolWorkerAllocator(suite.logger, workers, AdaptivePoolConfiguration{
+		MinWorkers:         3,
+		MaxLatencyIncrease: 1,
+	})
+	suite.Require().NoError(err)
+
+	ap := allocator.(*adaptivePool)
+
+	// establish the baseline latency
+	ap.latencySum, ap.eventsHandled = int64(30*time.Millisecond), 3
+	ap.evaluate()
+	suite.Require().Equal(10*time.Millisecond, ap.baselineLatency)
+
+	// events are waiting, but latency more than doubled - back off rather than add workers
+	ap.latencySum, ap.eventsHandled, ap.allocationWaits = int64(75*time.Millisecond), 3, 5
+	ap.evaluate()
+	suite.Require().Equal(3, ap.getNumActiveWorkers())
+
+	// latency within the tolerance, so workers are added
+	ap.latencySum, ap.eventsHandled, ap.allocationWaits = int64(45*time.Millisecond), 3, 5
+	ap.evaluate()
+	suite.Require().Equal(4, ap.getNumActiveWorkers())
+
+	ap.latencySum, ap.eventsHandled, ap.allocationWaits = int64(90*time.Millisecond), 3, 5
+	ap.evaluate()
+	suite.Require().Equal(3, ap.getNumActiveWorkers())
+}
+
 func TestAllocatorTestSuite(t *testing.T) {
 	suite.Run(t, new(AllocatorTestSuite))
 }
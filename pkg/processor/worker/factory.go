@@ -19,7 +19,9 @@ package worker
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 
 	"github.com/nuclio/errors"
@@ -50,7 +52,50 @@ func (waf *Factory) CreateFixedPoolWorkerAllocator(logger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create worker allocator")
 	}
 
-	return workerAllocator, nil
+	return waf.withAdmissionControl(workerAllocator, runtimeConfiguration), nil
+}
+
+// CreatePoolWorkerAllocator creates a pool of workers which is adaptive if worker autoscaling is configured,
+// and fixed otherwise
+func (waf *Factory) CreatePoolWorkerAllocator(logger logger.Logger,
+	numWorkers int,
+	workerAutoscaling *functionconfig.WorkerAutoscaling,
+	runtimeConfiguration *runtime.Configuration) (Allocator, error) {
+	if workerAutoscaling == nil {
+		return waf.CreateFixedPoolWorkerAllocator(logger, numWorkers, runtimeConfiguration)
+	}
+
+	adaptivePoolConfiguration := AdaptivePoolConfiguration{
+		MinWorkers:         workerAutoscaling.MinWorkers,
+		EvaluationInterval: functionconfig.DefaultWorkerAutoscalingEvaluationIntervalSeconds * time.Second,
+		MaxLatencyIncrease: functionconfig.DefaultWorkerAutoscalingMaxLatencyIncreasePercent / 100.0,
+	}
+
+	if workerAutoscaling.EvaluationIntervalSeconds != 0 {
+		adaptivePoolConfiguration.EvaluationInterval = time.Duration(workerAutoscaling.EvaluationIntervalSeconds) *
+			time.Second
+	}
+
+	if workerAutoscaling.MaxLatencyIncreasePercent != 0 {
+		adaptivePoolConfiguration.MaxLatencyIncrease = float64(workerAutoscaling.MaxLatencyIncreasePercent) / 100
+	}
+
+	logger.DebugWith("Creating adaptive worker pool",
+		"num", numWorkers,
+		"configuration", adaptivePoolConfiguration)
+
+	// all the workers are created up front, as some triggers keep state per worker
+	workers, err := waf.createWorkers(logger, numWorkers, runtimeConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create workers")
+	}
+
+	workerAllocator, err := NewAdaptivePoolWorkerAllocator(logger, workers, adaptivePoolConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create worker allocator")
+	}
+
+	return waf.withAdmissionControl(workerAllocator, runtimeConfiguration), nil
 }
 
 func (waf *Factory) CreateSingletonPoolWorkerAllocator(logger logger.Logger,
@@ -68,7 +113,18 @@ func (waf *Factory) CreateSingletonPoolWorkerAllocator(logger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create worker allocator")
 	}
 
-	return workerAllocator, nil
+	return waf.withAdmissionControl(workerAllocator, runtimeConfiguration), nil
+}
+
+// withAdmissionControl wraps the allocator with the function's admission controller, if it has one, so
+// the events of all of its triggers are limited together
+func (waf *Factory) withAdmissionControl(workerAllocator Allocator,
+	runtimeConfiguration *runtime.Configuration) Allocator {
+	if runtimeConfiguration.AdmissionController == nil {
+		return workerAllocator
+	}
+
+	return NewAdmissionControlledWorkerAllocator(workerAllocator, runtimeConfiguration.AdmissionController)
 }
 
 func (waf *Factory) createWorker(parentLogger logger.Logger,
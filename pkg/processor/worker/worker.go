@@ -22,10 +22,12 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+	"github.com/nuclio/nuclio/pkg/processor/deadline"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 	"github.com/nuclio/nuclio/pkg/processor/util/clock"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
@@ -59,10 +61,18 @@ func NewWorker(parentLogger logger.Logger,
 
 // ProcessEvent sends the event to the associated runtime
 func (w *Worker) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	var response interface{}
+	var err error
+
 	w.eventTime = clock.Now()
 
-	// process the event at the runtime
-	response, err := w.runtime.ProcessEvent(event, functionLogger)
+	// process the event at the runtime, until its deadline if it has one
+	if eventDeadline, hasDeadline := deadline.Get(event); hasDeadline {
+		response, err = w.processEventUntil(event, functionLogger, eventDeadline)
+	} else {
+		response, err = w.runtime.ProcessEvent(event, functionLogger)
+	}
+
 	w.eventTime = nil
 
 	// check if there was a processing error. if so, log it
@@ -76,6 +86,8 @@ func (w *Worker) ProcessEvent(event nuclio.Event, functionLogger logger.Logger)
 			success = typedResponse.StatusCode < http.StatusBadRequest
 		case nuclio.Response:
 			success = typedResponse.StatusCode < http.StatusBadRequest
+		case *runtime.StreamedResponse:
+			success = typedResponse.StatusCode < http.StatusBadRequest
 		}
 
 		if success {
@@ -88,6 +100,62 @@ func (w *Worker) ProcessEvent(event nuclio.Event, functionLogger logger.Logger)
 	return response, err
 }
 
+// processEventUntil processes the event, cancelling its handling if it's still handled at the deadline. runtimes
+// which can be restarted are, the others' handlers are waited for (they're told through the event's context)
+func (w *Worker) processEventUntil(event nuclio.Event,
+	functionLogger logger.Logger,
+	eventDeadline time.Time) (interface{}, error) {
+
+	// e.g. a retry of an event whose deadline passed
+	remaining := time.Until(eventDeadline)
+	if remaining <= 0 {
+		return nil, deadline.ErrExceeded
+	}
+
+	type processResult struct {
+		response interface{}
+		err      error
+	}
+
+	resultChan := make(chan processResult, 1)
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				resultChan <- processResult{err: errors.Errorf("Caught panic: %s", err)}
+			}
+		}()
+
+		response, err := w.runtime.ProcessEvent(event, functionLogger)
+		resultChan <- processResult{response: response, err: err}
+	}()
+
+	deadlineTimer := time.NewTimer(remaining)
+	defer deadlineTimer.Stop()
+
+	select {
+	case result := <-resultChan:
+		return result.response, result.err
+	case <-deadlineTimer.C:
+	}
+
+	w.logger.InfoWith("Event deadline exceeded, cancelling handler",
+		"worker", w.index,
+		"eventID", event.GetID(),
+		"restart", w.runtime.SupportsRestart())
+
+	if w.runtime.SupportsRestart() {
+		if err := w.runtime.Restart(); err != nil {
+			w.logger.WarnWith("Failed to restart runtime", "worker", w.index, "err", err.Error())
+		}
+	}
+
+	// the worker may only handle another event once the handler returned
+	<-resultChan
+
+	return nil, deadline.ErrExceeded
+}
+
 // GetStatistics returns a pointer to the statistics object. This must not be modified by the reader
 func (w *Worker) GetStatistics() *Statistics {
 	return &w.statistics
@@ -18,7 +18,9 @@ package worker
 
 import (
 	"testing"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/processor/deadline"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/status"
 
@@ -100,6 +102,43 @@ func (suite *WorkerTestSuite) TestProcessEvent() {
 	suite.Require().NotNil(event.GetID())
 }
 
+func (suite *WorkerTestSuite) TestProcessEventDeadline() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 100, &mockRuntime)
+
+	// handled by the deadline
+	event, release := deadline.WithDeadline(&nuclio.AbstractEvent{}, time.Now().Add(time.Minute))
+	defer release()
+
+	mockRuntime.On("ProcessEvent", event, suite.logger).Return("response", nil).Once()
+
+	response, err := worker.ProcessEvent(event, suite.logger)
+	suite.Require().NoError(err)
+	suite.Require().Equal("response", response)
+
+	// still handled at the deadline
+	event, release = deadline.WithDeadline(&nuclio.AbstractEvent{}, time.Now().Add(50*time.Millisecond))
+	defer release()
+
+	mockRuntime.On("ProcessEvent", event, suite.logger).
+		After(500*time.Millisecond).
+		Return("late response", nil).
+		Once()
+
+	response, err = worker.ProcessEvent(event, suite.logger)
+	suite.Require().Equal(deadline.ErrExceeded, err)
+	suite.Require().Nil(response)
+
+	// past the deadline, not handled at all
+	event, release = deadline.WithDeadline(&nuclio.AbstractEvent{}, time.Now().Add(-time.Second))
+	defer release()
+
+	_, err = worker.ProcessEvent(event, suite.logger)
+	suite.Require().Equal(deadline.ErrExceeded, err)
+
+	mockRuntime.AssertExpectations(suite.T())
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestWorkerTestSuite(t *testing.T) {
@@ -28,7 +28,8 @@ import (
 )
 
 type Renderer struct {
-	output io.Writer
+	output     io.Writer
+	yamlIndent int
 }
 
 func NewRenderer(output io.Writer) *Renderer {
@@ -37,6 +38,11 @@ func NewRenderer(output io.Writer) *Renderer {
 	}
 }
 
+// SetYAMLIndent sets the indentation width of YAML output (0 - the default encoding, indented by 2)
+func (r *Renderer) SetYAMLIndent(indent int) {
+	r.yamlIndent = indent
+}
+
 func (r *Renderer) RenderTable(header []string, records [][]string) {
 	tableWriter := tablewriter.NewWriter(r.output)
 	tableWriter.SetHeader(header)
@@ -48,7 +54,14 @@ func (r *Renderer) RenderTable(header []string, records [][]string) {
 }
 
 func (r *Renderer) RenderYAML(items interface{}) error {
-	body, err := yaml.Marshal(items)
+	var body []byte
+	var err error
+
+	if r.yamlIndent != 0 {
+		body, err = marshalYAMLWithIndent(items, r.yamlIndent)
+	} else {
+		body, err = yaml.Marshal(items)
+	}
 	if err != nil {
 		return errors.Wrap(err, "Failed to render YAML")
 	}
@@ -0,0 +1,191 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// MinYAMLIndent and MaxYAMLIndent bound the indentation width of YAML output
+const (
+	MinYAMLIndent = 1
+	MaxYAMLIndent = 8
+)
+
+// marshalYAMLWithIndent encodes items as YAML, indenting each level by the given number of spaces. yaml.v2
+// always indents by 2, so the document is emitted here, leaving only the encoding of scalars to yaml.v2.
+// like the default encoding, items are encoded through their JSON representation and keys are sorted
+func marshalYAMLWithIndent(items interface{}, indent int) ([]byte, error) {
+	if indent < MinYAMLIndent || indent > MaxYAMLIndent {
+		return nil, errors.Errorf("YAML indentation must be between %d and %d, got %d",
+			MinYAMLIndent,
+			MaxYAMLIndent,
+			indent)
+	}
+
+	encodedItems, err := json.Marshal(items)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode JSON")
+	}
+
+	// JSON is YAML, and decoding it as such keeps integers as integers
+	var decodedItems interface{}
+	if err := yaml.Unmarshal(encodedItems, &decodedItems); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode JSON")
+	}
+
+	emitter := yamlEmitter{indent: indent}
+	if err := emitter.emitValue(decodedItems, 0, true); err != nil {
+		return nil, err
+	}
+
+	return []byte(emitter.builder.String()), nil
+}
+
+type yamlEmitter struct {
+	builder strings.Builder
+	indent  int
+}
+
+// emitValue emits a value whose lines start at the given column. the first line is padded only if
+// padFirstLine is set, as otherwise it continues a sequence item's "- "
+func (ye *yamlEmitter) emitValue(value interface{}, column int, padFirstLine bool) error {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		if len(typedValue) > 0 {
+			return ye.emitMapping(typedValue, column, padFirstLine)
+		}
+	case []interface{}:
+		if len(typedValue) > 0 {
+			return ye.emitSequence(typedValue, column, padFirstLine)
+		}
+	}
+
+	encodedScalar, err := ye.encodeScalar(value)
+	if err != nil {
+		return err
+	}
+
+	if padFirstLine {
+		ye.builder.WriteString(strings.Repeat(" ", column))
+	}
+
+	ye.builder.WriteString(encodedScalar + "\n")
+
+	return nil
+}
+
+func (ye *yamlEmitter) emitMapping(mapping map[interface{}]interface{}, column int, padFirstLine bool) error {
+	var keys []string
+	values := map[string]interface{}{}
+
+	for key, value := range mapping {
+		encodedKey, err := ye.encodeScalar(key)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, encodedKey)
+		values[encodedKey] = value
+	}
+	sort.Strings(keys)
+
+	for keyIdx, key := range keys {
+		if keyIdx > 0 || padFirstLine {
+			ye.builder.WriteString(strings.Repeat(" ", column))
+		}
+
+		ye.builder.WriteString(key + ":")
+
+		// collections start on the next line, a level deeper. scalars follow the key
+		if ye.isCollection(values[key]) {
+			ye.builder.WriteString("\n")
+			if err := ye.emitValue(values[key], column+ye.indent, true); err != nil {
+				return err
+			}
+		} else {
+			ye.builder.WriteString(" ")
+			if err := ye.emitValue(values[key], column, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ye *yamlEmitter) emitSequence(sequence []interface{}, column int, padFirstLine bool) error {
+	for itemIdx, item := range sequence {
+		if itemIdx > 0 || padFirstLine {
+			ye.builder.WriteString(strings.Repeat(" ", column))
+		}
+
+		// the item follows the "- ", so its lines are aligned after it
+		ye.builder.WriteString("- ")
+		if err := ye.emitValue(item, column+2, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ye *yamlEmitter) isCollection(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		return len(typedValue) > 0
+	case []interface{}:
+		return len(typedValue) > 0
+	}
+
+	return false
+}
+
+// encodeScalar encodes a scalar (or an empty collection) on a single line
+func (ye *yamlEmitter) encodeScalar(value interface{}) (string, error) {
+	encodedScalar, err := yaml.Marshal(value)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode YAML scalar")
+	}
+
+	singleLineScalar := strings.TrimSuffix(string(encodedScalar), "\n")
+	if !strings.Contains(singleLineScalar, "\n") {
+		return singleLineScalar, nil
+	}
+
+	// multi-line and long strings are encoded by yaml.v2 as indented blocks. a JSON string is a valid
+	// double-quoted YAML scalar, which is always a single line
+	stringValue, isString := value.(string)
+	if !isString {
+		return "", errors.Errorf("Unexpected multi-line encoding of %v", value)
+	}
+
+	quotedScalar := bytes.Buffer{}
+	encoder := json.NewEncoder(&quotedScalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(stringValue); err != nil {
+		return "", errors.Wrap(err, "Failed to quote YAML scalar")
+	}
+
+	return strings.TrimSuffix(quotedScalar.String(), "\n"), nil
+}
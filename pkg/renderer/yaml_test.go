@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/suite"
+)
+
+type yamlTestSuite struct {
+	suite.Suite
+}
+
+func (suite *yamlTestSuite) TestRenderYAMLWithIndent() {
+	items := map[string]interface{}{
+		"name": "my-function",
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"env": []map[string]interface{}{
+				{"name": "FIRST", "value": "a: b"},
+				{"name": "SECOND", "value": "multi\nline"},
+			},
+			"labels": map[string]string{},
+			"tags":   []string{"one", "two"},
+		},
+	}
+
+	outputBuffer := bytes.Buffer{}
+	rendererInstance := NewRenderer(&outputBuffer)
+	rendererInstance.SetYAMLIndent(4)
+
+	err := rendererInstance.RenderYAML(items)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(`name: my-function
+spec:
+    env:
+        - name: FIRST
+          value: 'a: b'
+        - name: SECOND
+          value: "multi\nline"
+    labels: {}
+    replicas: 3
+    tags:
+        - one
+        - two
+
+`, outputBuffer.String())
+
+	// the output decodes to what was encoded
+	var decodedItems, expectedItems interface{}
+	err = yaml.Unmarshal(outputBuffer.Bytes(), &decodedItems)
+	suite.Require().NoError(err)
+
+	encodedItems, err := yaml.Marshal(items)
+	suite.Require().NoError(err)
+	err = yaml.Unmarshal(encodedItems, &expectedItems)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(expectedItems, decodedItems)
+}
+
+func (suite *yamlTestSuite) TestRenderYAMLInvalidIndent() {
+	for _, indent := range []int{-1, MaxYAMLIndent + 1} {
+		rendererInstance := NewRenderer(&bytes.Buffer{})
+		rendererInstance.SetYAMLIndent(indent)
+
+		err := rendererInstance.RenderYAML(map[string]string{"name": "my-function"})
+		suite.Require().Error(err)
+	}
+}
+
+func TestYAMLTestSuite(t *testing.T) {
+	suite.Run(t, new(yamlTestSuite))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type awsSecretsManagerAttributes struct {
+
+	// the region of the secrets. if not set, that of the environment (AWS_REGION)
+	Region string
+}
+
+// awsSecretsManagerProvider reads secrets of AWS Secrets Manager with the AWS credentials of the environment
+// (e.g. the IAM role of the function's service account, through IRSA). versions are version IDs
+type awsSecretsManagerProvider struct {
+	logger logger.Logger
+	client secretsmanageriface.SecretsManagerAPI
+}
+
+func newAWSSecretsManagerProvider(parentLogger logger.Logger,
+	configuration *platformconfig.SecretProvider) (*awsSecretsManagerProvider, error) {
+	attributes := awsSecretsManagerAttributes{}
+	if err := mapstructure.Decode(configuration.Attributes, &attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	awsConfig := &aws.Config{}
+
+	if attributes.Region != "" {
+		awsConfig.Region = aws.String(attributes.Region)
+	}
+
+	// e.g. a VPC endpoint
+	if configuration.URL != "" {
+		awsConfig.Endpoint = aws.String(configuration.URL)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	return &awsSecretsManagerProvider{
+		logger: parentLogger,
+		client: secretsmanager.New(sess),
+	}, nil
+}
+
+func (asp *awsSecretsManagerProvider) GetSecret(name string, version string) (string, error) {
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	}
+
+	if version != "" {
+		input.VersionId = aws.String(version)
+	}
+
+	output, err := asp.client.GetSecretValue(input)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get value of secret %s", name)
+	}
+
+	if output.SecretString != nil {
+		return aws.StringValue(output.SecretString), nil
+	}
+
+	return string(output.SecretBinary), nil
+}
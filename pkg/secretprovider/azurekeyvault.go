@@ -0,0 +1,171 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultAzureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureKeyVaultResource    = "https://vault.azure.net"
+	azureKeyVaultAPIVersion  = "7.3"
+
+	// access tokens are renewed this long before they expire
+	azureTokenRefreshMargin = 5 * time.Minute
+)
+
+type azureKeyVaultAttributes struct {
+
+	// selects a user assigned identity, when there's more than one. if not set, AZURE_CLIENT_ID
+	ClientID string
+}
+
+// azureKeyVaultProvider reads secrets of an Azure key vault (whose URL is given, e.g.
+// https://my-vault.vault.azure.net) with an access token of the managed identity of the environment, obtained
+// from the Azure instance metadata service. versions are the IDs of the secrets' versions
+type azureKeyVaultProvider struct {
+	logger         logger.Logger
+	httpClient     *http.Client
+	url            string
+	imdsTokenURL   string
+	clientID       string
+	token          string
+	tokenExpiresAt time.Time
+	tokenLock      sync.Mutex
+	now            func() time.Time
+}
+
+func newAzureKeyVaultProvider(parentLogger logger.Logger,
+	configuration *platformconfig.SecretProvider) (*azureKeyVaultProvider, error) {
+	attributes := azureKeyVaultAttributes{}
+	if err := mapstructure.Decode(configuration.Attributes, &attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if configuration.URL == "" {
+		return nil, errors.New("Key vault URL must be set")
+	}
+
+	if attributes.ClientID == "" {
+		attributes.ClientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+
+	return &azureKeyVaultProvider{
+		logger:       parentLogger,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		url:          strings.TrimSuffix(configuration.URL, "/"),
+		imdsTokenURL: defaultAzureIMDSTokenURL,
+		clientID:     attributes.ClientID,
+		now:          time.Now,
+	}, nil
+}
+
+func (akp *azureKeyVaultProvider) GetSecret(name string, version string) (string, error) {
+	token, err := akp.getAccessToken()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get access token of managed identity")
+	}
+
+	secretPath := url.PathEscape(name)
+	if version != "" {
+		secretPath = fmt.Sprintf("%s/%s", secretPath, url.PathEscape(version))
+	}
+
+	request, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/secrets/%s?api-version=%s", akp.url, secretPath, azureKeyVaultAPIVersion),
+		nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create secret request")
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	secret := struct {
+		Value *string `json:"value"`
+	}{}
+
+	if err := doJSONRequest(akp.httpClient, request, &secret); err != nil {
+		return "", errors.Wrapf(err, "Failed to read secret %s", name)
+	}
+
+	if secret.Value == nil {
+		return "", errors.Errorf("Key vault returned no value of secret %s", name)
+	}
+
+	return *secret.Value, nil
+}
+
+func (akp *azureKeyVaultProvider) getAccessToken() (string, error) {
+	akp.tokenLock.Lock()
+	defer akp.tokenLock.Unlock()
+
+	if akp.token != "" && akp.now().Add(azureTokenRefreshMargin).Before(akp.tokenExpiresAt) {
+		return akp.token, nil
+	}
+
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", azureKeyVaultResource)
+
+	if akp.clientID != "" {
+		query.Set("client_id", akp.clientID)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?%s", akp.imdsTokenURL, query.Encode()), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create metadata service request")
+	}
+
+	request.Header.Set("Metadata", "true")
+
+	// expires_on is given as a string of seconds since the epoch
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}{}
+
+	if err := doJSONRequest(akp.httpClient, request, &token); err != nil {
+		return "", err
+	}
+
+	if token.AccessToken == "" {
+		return "", errors.New("Metadata service returned no access token")
+	}
+
+	akp.token = token.AccessToken
+	akp.tokenExpiresAt = akp.now().Add(time.Hour)
+
+	if expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64); err == nil {
+		akp.tokenExpiresAt = time.Unix(expiresOn, 0)
+	}
+
+	return akp.token, nil
+}
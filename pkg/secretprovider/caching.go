@@ -0,0 +1,89 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+type cachedSecret struct {
+	value  string
+	readAt time.Time
+}
+
+// cachingProvider caches the secrets it reads in memory, re-reading them once the refresh interval passed. if a
+// secret can't be re-read (e.g. the store is unavailable), its last value is used
+type cachingProvider struct {
+	logger          logger.Logger
+	provider        Provider
+	refreshInterval time.Duration
+	secrets         map[string]*cachedSecret
+	lock            sync.Mutex
+	now             func() time.Time
+}
+
+func newCachingProvider(parentLogger logger.Logger,
+	provider Provider,
+	refreshInterval time.Duration) *cachingProvider {
+	return &cachingProvider{
+		logger:          parentLogger,
+		provider:        provider,
+		refreshInterval: refreshInterval,
+		secrets:         map[string]*cachedSecret{},
+		now:             time.Now,
+	}
+}
+
+func (cp *cachingProvider) GetSecret(name string, version string) (string, error) {
+	cacheKey := name + "@" + version
+
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	secret, found := cp.secrets[cacheKey]
+	if found && cp.now().Before(secret.readAt.Add(cp.refreshInterval)) {
+		return secret.value, nil
+	}
+
+	value, err := cp.provider.GetSecret(name, version)
+	if err != nil {
+		if !found {
+			return "", err
+		}
+
+		cp.logger.WarnWith("Failed to re-read secret, using its last value",
+			"name", name,
+			"version", version,
+			"readAt", secret.readAt,
+			"err", err.Error())
+
+		return secret.value, nil
+	}
+
+	// the value itself is never logged
+	cp.logger.DebugWith("Read secret", "name", name, "version", version)
+
+	cp.secrets[cacheKey] = &cachedSecret{
+		value:  value,
+		readAt: cp.now(),
+	}
+
+	return value, nil
+}
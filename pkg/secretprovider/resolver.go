@@ -0,0 +1,117 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// Resolver reads the values of the external secret environment variables of functions (spec.externalSecretEnv)
+// through the secret providers of the platform configuration
+type Resolver struct {
+	logger          logger.Logger
+	providers       map[string]Provider
+	refreshInterval time.Duration
+}
+
+// NewResolver creates a resolver with the given secret providers, by name
+func NewResolver(parentLogger logger.Logger,
+	secretProviders map[string]platformconfig.SecretProvider) (*Resolver, error) {
+	newResolver := &Resolver{
+		logger:    parentLogger.GetChild("secrets"),
+		providers: map[string]Provider{},
+	}
+
+	for providerName, providerConfiguration := range secretProviders {
+		providerConfiguration := providerConfiguration
+
+		provider, err := NewProvider(newResolver.logger, providerName, &providerConfiguration)
+		if err != nil {
+			return nil, err
+		}
+
+		refreshInterval, err := GetRefreshInterval(&providerConfiguration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get refresh interval of secret provider %s", providerName)
+		}
+
+		if newResolver.refreshInterval == 0 || refreshInterval < newResolver.refreshInterval {
+			newResolver.refreshInterval = refreshInterval
+		}
+
+		newResolver.providers[providerName] = provider
+	}
+
+	return newResolver, nil
+}
+
+// HasProvider returns whether the resolver has a secret provider of the given name
+func (r *Resolver) HasProvider(providerName string) bool {
+	_, found := r.providers[providerName]
+	return found
+}
+
+// GetRefreshInterval returns how often the secrets of the most often refreshed provider are re-read, 0 if the
+// resolver has no providers
+func (r *Resolver) GetRefreshInterval() time.Duration {
+	return r.refreshInterval
+}
+
+// Resolve returns the values of the environment variables, by name. the values must never be written to the
+// function's configuration
+func (r *Resolver) Resolve(envVars []functionconfig.ExternalSecretEnvVar) (map[string]string, error) {
+	envValues := map[string]string{}
+
+	for _, envVar := range envVars {
+		externalSecret := envVar.ValueFrom.ExternalSecret
+		if externalSecret == nil {
+			return nil, errors.Errorf("Environment variable %s references no external secret", envVar.Name)
+		}
+
+		provider, found := r.providers[externalSecret.Provider]
+		if !found {
+			return nil, errors.Errorf("Environment variable %s references unknown secret provider %s",
+				envVar.Name,
+				externalSecret.Provider)
+		}
+
+		secretValue, err := provider.GetSecret(externalSecret.Name, externalSecret.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get secret of environment variable %s", envVar.Name)
+		}
+
+		if externalSecret.Key != "" {
+			secretValue, err = getSecretKey(secretValue, externalSecret.Key)
+			if err != nil {
+				return nil, errors.Wrapf(err,
+					"Failed to get key of secret %s of environment variable %s",
+					externalSecret.Name,
+					envVar.Name)
+			}
+		}
+
+		envValues[envVar.Name] = secretValue
+	}
+
+	return envValues, nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type mockSecretsManagerClient struct {
+	secretsmanageriface.SecretsManagerAPI
+	input *secretsmanager.GetSecretValueInput
+}
+
+func (msc *mockSecretsManagerClient) GetSecretValue(
+	input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	msc.input = input
+
+	return &secretsmanager.GetSecretValueOutput{
+		SecretString: aws.String(`{"username": "admin", "password": "aws-password"}`),
+	}, nil
+}
+
+type mockProvider struct {
+	calls   int
+	secrets map[string]string
+	err     error
+}
+
+func (mp *mockProvider) GetSecret(name string, version string) (string, error) {
+	mp.calls++
+
+	if mp.err != nil {
+		return "", mp.err
+	}
+
+	secretValue, found := mp.secrets[name]
+	if !found {
+		return "", errors.Errorf("Secret %s not found", name)
+	}
+
+	return secretValue, nil
+}
+
+type secretProviderTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+	now    time.Time
+}
+
+func (suite *secretProviderTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.now = time.Unix(1600000000, 0)
+}
+
+func (suite *secretProviderTestSuite) TestNewResolver() {
+	resolver, err := NewResolver(suite.logger, map[string]platformconfig.SecretProvider{
+		"vault":    {Kind: "vault", URL: "https://vault.example.com", RefreshInterval: "10m"},
+		"keyvault": {Kind: "azureKeyVault", URL: "https://my-vault.vault.azure.net", RefreshInterval: "1m"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(resolver.HasProvider("vault"))
+	suite.Require().False(resolver.HasProvider("aws"))
+	suite.Require().Equal(time.Minute, resolver.GetRefreshInterval())
+
+	for _, invalidSecretProvider := range []platformconfig.SecretProvider{
+		{Kind: "onePassword"},
+		{Kind: "azureKeyVault"},
+		{Kind: "vault", URL: "https://vault.example.com", RefreshInterval: "-1m"},
+	} {
+		_, err = NewResolver(suite.logger, map[string]platformconfig.SecretProvider{"invalid": invalidSecretProvider})
+		suite.Require().Error(err, "Secret provider: %v", invalidSecretProvider)
+	}
+}
+
+func (suite *secretProviderTestSuite) TestVaultProvider() {
+	serviceAccountTokenFile, err := ioutil.TempFile("", "nuclio-test-token-")
+	suite.Require().NoError(err)
+	defer os.Remove(serviceAccountTokenFile.Name()) // nolint: errcheck
+
+	_, err = serviceAccountTokenFile.WriteString("service-account-token\n")
+	suite.Require().NoError(err)
+	suite.Require().NoError(serviceAccountTokenFile.Close())
+
+	logins := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("team-a", request.Header.Get("X-Vault-Namespace"))
+
+		switch request.URL.Path {
+		case "/v1/auth/k8s/login":
+			login := map[string]string{}
+			suite.Require().NoError(json.NewDecoder(request.Body).Decode(&login))
+			suite.Require().Equal(map[string]string{"role": "functions", "jwt": "service-account-token"}, login)
+
+			logins++
+			responseWriter.Write([]byte(`{"auth": {"client_token": "vault-token", "lease_duration": 3600}}`)) // nolint: errcheck
+		case "/v1/kv/data/db":
+			if request.Header.Get("X-Vault-Token") != "vault-token" {
+				responseWriter.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			suite.Require().Equal("3", request.URL.Query().Get("version"))
+			responseWriter.Write([]byte(`{"data": {"data": {"password": "vault-password", "port": 5432}}}`)) // nolint: errcheck
+		default:
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := newVaultProvider(suite.logger, &platformconfig.SecretProvider{
+		Kind: "vault",
+		URL:  server.URL + "/",
+		Attributes: map[string]interface{}{
+			"mountPath":     "kv",
+			"role":          "functions",
+			"authMountPath": "k8s",
+			"namespace":     "team-a",
+		},
+	})
+	suite.Require().NoError(err)
+
+	provider.serviceAccountTokenPath = serviceAccountTokenFile.Name()
+	provider.now = func() time.Time { return suite.now }
+
+	for attempt := 0; attempt < 2; attempt++ {
+		secretValue, err := provider.GetSecret("db", "3")
+		suite.Require().NoError(err)
+		suite.Require().JSONEq(`{"password": "vault-password", "port": 5432}`, secretValue)
+	}
+
+	// the token is reused until it's about to expire
+	suite.Require().Equal(1, logins)
+
+	_, err = provider.GetSecret("unknown", "")
+	suite.Require().Error(err)
+}
+
+func (suite *secretProviderTestSuite) TestAWSSecretsManagerProvider() {
+	mockClient := &mockSecretsManagerClient{}
+
+	provider := &awsSecretsManagerProvider{
+		logger: suite.logger,
+		client: mockClient,
+	}
+
+	secretValue, err := provider.GetSecret("prod/db", "")
+	suite.Require().NoError(err)
+	suite.Require().JSONEq(`{"username": "admin", "password": "aws-password"}`, secretValue)
+	suite.Require().Equal("prod/db", aws.StringValue(mockClient.input.SecretId))
+	suite.Require().Nil(mockClient.input.VersionId)
+
+	_, err = provider.GetSecret("prod/db", "a1b2c3d4-5678-90ab-cdef-EXAMPLE11111")
+	suite.Require().NoError(err)
+	suite.Require().Equal("a1b2c3d4-5678-90ab-cdef-EXAMPLE11111", aws.StringValue(mockClient.input.VersionId))
+}
+
+func (suite *secretProviderTestSuite) TestAzureKeyVaultProvider() {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			suite.Require().Equal("true", request.Header.Get("Metadata"))
+			suite.Require().Equal("https://vault.azure.net", request.URL.Query().Get("resource"))
+			suite.Require().Equal("my-client-id", request.URL.Query().Get("client_id"))
+
+			responseWriter.Write([]byte(`{"access_token": "aad-token", "expires_on": "1600003600"}`)) // nolint: errcheck
+		case "/secrets/db-password/v2":
+			if request.Header.Get("Authorization") != "Bearer aad-token" {
+				responseWriter.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			suite.Require().Equal("7.3", request.URL.Query().Get("api-version"))
+			responseWriter.Write([]byte(`{"value": "azure-password", "id": "db-password/v2"}`)) // nolint: errcheck
+		default:
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := newAzureKeyVaultProvider(suite.logger, &platformconfig.SecretProvider{
+		Kind:       "azureKeyVault",
+		URL:        server.URL,
+		Attributes: map[string]interface{}{"clientID": "my-client-id"},
+	})
+	suite.Require().NoError(err)
+
+	provider.imdsTokenURL = server.URL + "/metadata/identity/oauth2/token"
+	provider.now = func() time.Time { return suite.now }
+
+	secretValue, err := provider.GetSecret("db-password", "v2")
+	suite.Require().NoError(err)
+	suite.Require().Equal("azure-password", secretValue)
+	suite.Require().Equal(time.Unix(1600003600, 0), provider.tokenExpiresAt)
+
+	_, err = provider.GetSecret("db-password", "")
+	suite.Require().Error(err)
+}
+
+func (suite *secretProviderTestSuite) TestCachingProvider() {
+	provider := &mockProvider{secrets: map[string]string{"db": "password"}}
+
+	cachingProvider := newCachingProvider(suite.logger, provider, time.Minute)
+	cachingProvider.now = func() time.Time { return suite.now }
+
+	for attempt := 0; attempt < 2; attempt++ {
+		secretValue, err := cachingProvider.GetSecret("db", "")
+		suite.Require().NoError(err)
+		suite.Require().Equal("password", secretValue)
+	}
+
+	suite.Require().Equal(1, provider.calls)
+
+	// re-read once the refresh interval passed
+	suite.now = suite.now.Add(time.Minute)
+	provider.secrets["db"] = "rotated-password"
+
+	secretValue, err := cachingProvider.GetSecret("db", "")
+	suite.Require().NoError(err)
+	suite.Require().Equal("rotated-password", secretValue)
+	suite.Require().Equal(2, provider.calls)
+
+	// the last value is used while the store is unavailable, but secrets never read fail
+	suite.now = suite.now.Add(time.Minute)
+	provider.err = errors.New("Store unavailable")
+
+	secretValue, err = cachingProvider.GetSecret("db", "")
+	suite.Require().NoError(err)
+	suite.Require().Equal("rotated-password", secretValue)
+
+	_, err = cachingProvider.GetSecret("db", "2")
+	suite.Require().Error(err)
+}
+
+func (suite *secretProviderTestSuite) TestResolve() {
+	resolver := &Resolver{
+		logger: suite.logger,
+		providers: map[string]Provider{
+			"vault": &mockProvider{secrets: map[string]string{
+				"db":  `{"password": "vault-password", "port": 5432}`,
+				"api": "api-token",
+			}},
+		},
+	}
+
+	newEnvVar := func(name string, externalSecret functionconfig.ExternalSecretRef) functionconfig.ExternalSecretEnvVar {
+		return functionconfig.ExternalSecretEnvVar{
+			Name:      name,
+			ValueFrom: functionconfig.ExternalSecretEnvVarSource{ExternalSecret: &externalSecret},
+		}
+	}
+
+	envValues, err := resolver.Resolve([]functionconfig.ExternalSecretEnvVar{
+		newEnvVar("DB_PASSWORD", functionconfig.ExternalSecretRef{Provider: "vault", Name: "db", Key: "password"}),
+		newEnvVar("DB_PORT", functionconfig.ExternalSecretRef{Provider: "vault", Name: "db", Key: "port"}),
+		newEnvVar("API_TOKEN", functionconfig.ExternalSecretRef{Provider: "vault", Name: "api"}),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{
+		"DB_PASSWORD": "vault-password",
+		"DB_PORT":     "5432",
+		"API_TOKEN":   "api-token",
+	}, envValues)
+
+	for _, invalidEnvVar := range []functionconfig.ExternalSecretEnvVar{
+		newEnvVar("DB_USER", functionconfig.ExternalSecretRef{Provider: "vault", Name: "db", Key: "user"}),
+		newEnvVar("API_USER", functionconfig.ExternalSecretRef{Provider: "vault", Name: "api", Key: "user"}),
+		newEnvVar("DB_PASSWORD", functionconfig.ExternalSecretRef{Provider: "aws", Name: "db"}),
+		{Name: "EMPTY"},
+	} {
+		_, err = resolver.Resolve([]functionconfig.ExternalSecretEnvVar{invalidEnvVar})
+		suite.Require().Error(err, "Env var: %s", invalidEnvVar.Name)
+	}
+}
+
+func (suite *secretProviderTestSuite) TestVaultProviderRequiresURL() {
+	vaultAddress := os.Getenv("VAULT_ADDR")
+	os.Unsetenv("VAULT_ADDR")                   // nolint: errcheck
+	defer os.Setenv("VAULT_ADDR", vaultAddress) // nolint: errcheck
+
+	_, err := newVaultProvider(suite.logger, &platformconfig.SecretProvider{Kind: "vault"})
+	suite.Require().Error(err)
+
+	os.Setenv("VAULT_ADDR", "https://vault.example.com") // nolint: errcheck
+
+	provider, err := newVaultProvider(suite.logger, &platformconfig.SecretProvider{Kind: "vault"})
+	suite.Require().NoError(err)
+	suite.Require().Equal("https://vault.example.com", provider.url)
+	suite.Require().Equal("secret", provider.attributes.MountPath)
+}
+
+func TestSecretProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(secretProviderTestSuite))
+}
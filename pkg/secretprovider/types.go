@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// Kind is the kind of store a provider reads secrets from
+type Kind string
+
+const (
+	KindVault             Kind = "vault"
+	KindAWSSecretsManager Kind = "awssecretsmanager"
+	KindAzureKeyVault     Kind = "azurekeyvault"
+)
+
+// DefaultRefreshInterval is how often secrets are re-read, unless the provider's configuration says otherwise
+const DefaultRefreshInterval = 5 * time.Minute
+
+// Provider reads secrets of an external store, with the identity of the environment it runs in (e.g. the
+// service account of the function's pod)
+type Provider interface {
+
+	// GetSecret returns the value of the given version of the secret, or of its current version if no
+	// version is given
+	GetSecret(name string, version string) (string, error)
+}
+
+// NewProvider creates a provider from its configuration, whose secrets are cached for its refresh interval
+func NewProvider(parentLogger logger.Logger,
+	name string,
+	configuration *platformconfig.SecretProvider) (Provider, error) {
+	var provider Provider
+	var err error
+
+	providerLogger := parentLogger.GetChild(name)
+
+	switch Kind(strings.ToLower(configuration.Kind)) {
+	case KindVault:
+		provider, err = newVaultProvider(providerLogger, configuration)
+	case KindAWSSecretsManager:
+		provider, err = newAWSSecretsManagerProvider(providerLogger, configuration)
+	case KindAzureKeyVault:
+		provider, err = newAzureKeyVaultProvider(providerLogger, configuration)
+	default:
+		return nil, errors.Errorf("Unknown kind of secret provider %s: %s "+
+			"(expected one of vault, awsSecretsManager or azureKeyVault)", name, configuration.Kind)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create secret provider %s", name)
+	}
+
+	refreshInterval, err := GetRefreshInterval(configuration)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get refresh interval of secret provider %s", name)
+	}
+
+	return newCachingProvider(providerLogger, provider, refreshInterval), nil
+}
+
+// GetRefreshInterval returns how often the provider's secrets are re-read
+func GetRefreshInterval(configuration *platformconfig.SecretProvider) (time.Duration, error) {
+	if configuration.RefreshInterval == "" {
+		return DefaultRefreshInterval, nil
+	}
+
+	refreshInterval, err := time.ParseDuration(configuration.RefreshInterval)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to parse refresh interval")
+	}
+
+	if refreshInterval <= 0 {
+		return 0, errors.Errorf("Refresh interval must be positive, got %s", configuration.RefreshInterval)
+	}
+
+	return refreshInterval, nil
+}
+
+// getSecretKey returns the value of the key of a secret which is a JSON object. values which aren't strings
+// are returned JSON encoded
+func getSecretKey(secretValue string, key string) (string, error) {
+	secretData := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(secretValue), &secretData); err != nil {
+		return "", errors.New("Secret is not a JSON object, so it has no keys")
+	}
+
+	keyValue, found := secretData[key]
+	if !found {
+		return "", errors.Errorf("Secret has no key %s", key)
+	}
+
+	if stringValue, isString := keyValue.(string); isString {
+		return stringValue, nil
+	}
+
+	encodedValue, err := json.Marshal(keyValue)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to encode value of key %s", key)
+	}
+
+	return string(encodedValue), nil
+}
+
+func doJSONRequest(httpClient *http.Client, request *http.Request, result interface{}) error {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Got unexpected status code: %d", response.StatusCode)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "Failed to decode response")
+	}
+
+	return nil
+}
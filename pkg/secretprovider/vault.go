@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultVaultMountPath          = "secret"
+	defaultVaultAuthMountPath      = "kubernetes"
+	defaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// vault tokens are renewed this long before they expire
+	vaultTokenRefreshMargin = time.Minute
+)
+
+type vaultAttributes struct {
+
+	// the path of the KV (version 2) secrets engine
+	MountPath string
+
+	// when a role is given, the provider logs in with the kubernetes auth method, as the service account of
+	// the function's pod. otherwise, the token is taken from VAULT_TOKEN
+	Role          string
+	AuthMountPath string
+
+	// the vault enterprise namespace, if any
+	Namespace string
+}
+
+// vaultProvider reads secrets of a KV (version 2) secrets engine of vault, as JSON objects of their keys
+type vaultProvider struct {
+	logger                  logger.Logger
+	httpClient              *http.Client
+	url                     string
+	attributes              vaultAttributes
+	serviceAccountTokenPath string
+	token                   string
+	tokenExpiresAt          time.Time
+	tokenLock               sync.Mutex
+	now                     func() time.Time
+}
+
+func newVaultProvider(parentLogger logger.Logger,
+	configuration *platformconfig.SecretProvider) (*vaultProvider, error) {
+	newVaultProvider := &vaultProvider{
+		logger:                  parentLogger,
+		httpClient:              &http.Client{Timeout: 10 * time.Second},
+		url:                     configuration.URL,
+		serviceAccountTokenPath: defaultServiceAccountTokenPath,
+		now:                     time.Now,
+	}
+
+	if err := mapstructure.Decode(configuration.Attributes, &newVaultProvider.attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newVaultProvider.url == "" {
+		newVaultProvider.url = os.Getenv("VAULT_ADDR")
+	}
+
+	if newVaultProvider.url == "" {
+		return nil, errors.New("Vault URL must be set (or VAULT_ADDR)")
+	}
+
+	newVaultProvider.url = strings.TrimSuffix(newVaultProvider.url, "/")
+
+	if newVaultProvider.attributes.MountPath == "" {
+		newVaultProvider.attributes.MountPath = defaultVaultMountPath
+	}
+
+	if newVaultProvider.attributes.AuthMountPath == "" {
+		newVaultProvider.attributes.AuthMountPath = defaultVaultAuthMountPath
+	}
+
+	return newVaultProvider, nil
+}
+
+func (vp *vaultProvider) GetSecret(name string, version string) (string, error) {
+	token, err := vp.getToken()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get vault token")
+	}
+
+	secretURL := fmt.Sprintf("%s/v1/%s/data/%s",
+		vp.url,
+		strings.Trim(vp.attributes.MountPath, "/"),
+		strings.Trim(name, "/"))
+
+	if version != "" {
+		secretURL = fmt.Sprintf("%s?%s", secretURL, url.Values{"version": []string{version}}.Encode())
+	}
+
+	request, err := http.NewRequest(http.MethodGet, secretURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create secret request")
+	}
+
+	vp.setHeaders(request, token)
+
+	secret := struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+
+	if err := doJSONRequest(vp.httpClient, request, &secret); err != nil {
+		return "", errors.Wrapf(err, "Failed to read secret %s", name)
+	}
+
+	if secret.Data.Data == nil {
+		return "", errors.Errorf("Secret %s has no data (was it deleted?)", name)
+	}
+
+	encodedData, err := json.Marshal(secret.Data.Data)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode secret data")
+	}
+
+	return string(encodedData), nil
+}
+
+func (vp *vaultProvider) getToken() (string, error) {
+	if vp.attributes.Role == "" {
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			return "", errors.New("Either a role must be set or VAULT_TOKEN")
+		}
+
+		return token, nil
+	}
+
+	vp.tokenLock.Lock()
+	defer vp.tokenLock.Unlock()
+
+	if vp.token != "" && vp.now().Add(vaultTokenRefreshMargin).Before(vp.tokenExpiresAt) {
+		return vp.token, nil
+	}
+
+	serviceAccountToken, err := ioutil.ReadFile(vp.serviceAccountTokenPath)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read service account token")
+	}
+
+	encodedLogin, err := json.Marshal(map[string]string{
+		"role": vp.attributes.Role,
+		"jwt":  strings.TrimSpace(string(serviceAccountToken)),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode login")
+	}
+
+	request, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/v1/auth/%s/login", vp.url, strings.Trim(vp.attributes.AuthMountPath, "/")),
+		bytes.NewReader(encodedLogin))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create login request")
+	}
+
+	vp.setHeaders(request, "")
+
+	login := struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+
+	if err := doJSONRequest(vp.httpClient, request, &login); err != nil {
+		return "", errors.Wrapf(err, "Failed to log in with role %s", vp.attributes.Role)
+	}
+
+	if login.Auth.ClientToken == "" {
+		return "", errors.New("Vault returned no token")
+	}
+
+	vp.token = login.Auth.ClientToken
+	vp.tokenExpiresAt = vp.now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second)
+
+	vp.logger.DebugWith("Logged in to vault", "role", vp.attributes.Role, "expiresAt", vp.tokenExpiresAt)
+
+	return vp.token, nil
+}
+
+func (vp *vaultProvider) setHeaders(request *http.Request, token string) {
+	if token != "" {
+		request.Header.Set("X-Vault-Token", token)
+	}
+
+	if vp.attributes.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", vp.attributes.Namespace)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+}
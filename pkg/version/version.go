@@ -37,6 +37,7 @@ type Info struct {
 	OS        string `json:"os"`
 	Arch      string `json:"arch"`
 	GoVersion string `json:"go_version"`
+	BuildTime string `json:"build_time,omitempty"`
 }
 
 // these global variables are initialized by the build process if the build target
@@ -47,6 +48,7 @@ var (
 	os        = ""
 	arch      = ""
 	goVersion = ""
+	buildTime = ""
 )
 
 var info Info
@@ -77,6 +79,7 @@ func Get() (*Info, error) {
 		OS:        os,
 		Arch:      arch,
 		GoVersion: goVersion,
+		BuildTime: buildTime,
 	}, nil
 }
 
@@ -87,6 +90,7 @@ func Set(info *Info) {
 	os = info.OS
 	arch = info.Arch
 	goVersion = info.GoVersion
+	buildTime = info.BuildTime
 }
 
 // SetFromEnv will update the stored version info, used primarily for tests
@@ -96,6 +100,7 @@ func SetFromEnv() {
 	arch = common.GetEnvOrDefaultString("NUCLIO_ARCH", "amd64")
 	os = common.GetEnvOrDefaultString("NUCLIO_OS", "linux")
 	goVersion = runtime.Version()
+	buildTime = common.GetEnvOrDefaultString("NUCLIO_BUILD_TIME", "")
 }
 
 // Log will log the version, or an error
@@ -0,0 +1,30 @@
+# Copyright 2017 The Nuclio Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+import os
+import time
+
+
+def handler(context, event):
+
+    # not ready until the configured time since initialization passed
+    if time.time() < context.user_data.ready_time:
+        return context.Response(body='Not ready yet', status_code=503)
+
+    return 'Ready'
+
+
+def init_context(context):
+    ready_after_seconds = float(os.environ.get('READY_AFTER_SECONDS', '0'))
+    setattr(context.user_data, 'ready_time', time.time() + ready_after_seconds)